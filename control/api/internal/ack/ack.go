@@ -0,0 +1,35 @@
+// Package ack numbers the exposure batches stored for each API key. Edge
+// nodes buffer events locally until a batch holding them is acknowledged;
+// an acknowledged batch's events are durably stored, so the buffer can be
+// truncated up to it. Batches that are never acknowledged are resent, so
+// delivery is at least once.
+package ack
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retained is how many of its most recent acknowledgements are kept per key
+const Retained = 1000
+
+// maxTokenLength matches the exposure_acks.sequence_token column
+const maxTokenLength = 200
+
+// Ack acknowledges one stored batch
+type Ack struct {
+	KeyID       string    `json:"-"`
+	Sequence    int64     `json:"ack_sequence"`             // Increases by one per batch of the key
+	Token       string    `json:"sequence_token,omitempty"` // Edge node's own buffer position, echoed back
+	StoredCount int       `json:"stored_count"`
+	FailedCount int       `json:"failed_count"` // Events refused, which resending will not store
+	AckedAt     time.Time `json:"acked_at"`
+}
+
+// ValidateToken checks a batch's sequence token, which may be empty
+func ValidateToken(token string) error {
+	if len(token) > maxTokenLength {
+		return fmt.Errorf("sequence_token must be at most %d characters", maxTokenLength)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+)
+
+// ErrPlanChanged is returned when the state changed since a plan was made,
+// so applying now would make different changes than the plan showed
+var ErrPlanChanged = errors.New("current state no longer matches the plan")
+
+// Limits of one document
+const (
+	MaxCampaigns = 100
+	MaxCreatives = 1000
+	MaxBookings  = 1000
+)
+
+// maxIDLength matches the campaign, creative and surface ID columns
+const maxIDLength = 100
+
+// maxExternalIDLength matches the external ID length accepted by the API
+const maxExternalIDLength = 255
+
+// maxPRSScore is the top of the placement readiness scale
+const maxPRSScore = 100
+
+// Document declares the desired state of campaigns, their bookings and
+// creatives. Anything a document leaves out is left as it is, with one
+// exception: a campaign that lists bookings owns its live bookings, and
+// those not listed are cancelled. Nil labels, external IDs, budgets and
+// booking lists are not managed; empty ones clear what is there.
+type Document struct {
+	Campaigns []Campaign `json:"campaigns"`
+	Creatives []Creative `json:"creatives"`
+}
+
+// Campaign declares a campaign. Bookings are identified by their surface,
+// so a campaign books each surface at most once.
+type Campaign struct {
+	CampaignID   string            `json:"campaign_id"`
+	AdvertiserID string            `json:"advertiser_id,omitempty"` // Required when the campaign lists bookings
+	Budget       *float64          `json:"budget,omitempty"`
+	Labels       labels.Set        `json:"labels,omitempty"`
+	ExternalIDs  map[string]string `json:"external_ids,omitempty"`
+	Targeting    Targeting         `json:"targeting"`
+	Bookings     []Booking         `json:"bookings,omitempty"`
+}
+
+// Targeting holds the placement requirements of a campaign's bookings;
+// bookings may override them
+type Targeting struct {
+	MinPRSScore *float64 `json:"min_prs_score,omitempty"`
+}
+
+// Booking declares a placement booking of a campaign
+type Booking struct {
+	SurfaceID      string            `json:"surface_id"`
+	BidAmountCPM   float64           `json:"bid_amount_cpm"`
+	MaxImpressions int               `json:"max_impressions"`
+	MinPRSScore    *float64          `json:"min_prs_score,omitempty"` // Defaults to the campaign's targeting
+	CreativeID     string            `json:"creative_id,omitempty"`
+	Paused         bool              `json:"paused,omitempty"`
+	Labels         labels.Set        `json:"labels,omitempty"`
+	ExternalIDs    map[string]string `json:"external_ids,omitempty"`
+}
+
+// Creative declares a creative's metadata
+type Creative struct {
+	CreativeID  string            `json:"creative_id"`
+	Labels      labels.Set        `json:"labels,omitempty"`
+	ExternalIDs map[string]string `json:"external_ids,omitempty"`
+}
+
+// Terms are the booking terms a declaration resolves to, after applying
+// the campaign's defaults
+func (c *Campaign) Terms(b *Booking) Terms {
+	terms := Terms{
+		AdvertiserID:   c.AdvertiserID,
+		BidAmountCPM:   b.BidAmountCPM,
+		MaxImpressions: b.MaxImpressions,
+		CreativeID:     b.CreativeID,
+		Paused:         b.Paused,
+	}
+	switch {
+	case b.MinPRSScore != nil:
+		terms.MinPRSScore = *b.MinPRSScore
+	case c.Targeting.MinPRSScore != nil:
+		terms.MinPRSScore = *c.Targeting.MinPRSScore
+	}
+	return terms
+}
+
+// Request is a document to plan, and unless DryRun, apply
+type Request struct {
+	Document Document
+	DryRun   bool
+	PlanHash string // If set, the hash of the plan the caller reviewed
+	OrgID    string
+	UserID   string
+}
+
+// Problem is a reason a document cannot be applied. Path locates the
+// declaration, e.g. campaigns[0].bookings[2].bid_amount_cpm.
+type Problem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// problems collects the problems of a document
+type problems []Problem
+
+func (p *problems) add(path, format string, args ...interface{}) {
+	*p = append(*p, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a document without looking at the current state,
+// returning every problem found
+func (d *Document) Validate() []Problem {
+	p := make(problems, 0)
+	if len(d.Campaigns) == 0 && len(d.Creatives) == 0 {
+		p.add("", "document declares no campaigns or creatives")
+	}
+	if len(d.Campaigns) > MaxCampaigns {
+		p.add("campaigns", "at most %d campaigns may be applied at once", MaxCampaigns)
+		return p
+	}
+	if len(d.Creatives) > MaxCreatives {
+		p.add("creatives", "at most %d creatives may be applied at once", MaxCreatives)
+		return p
+	}
+
+	creatives := make(map[string]bool, len(d.Creatives))
+	for i, creative := range d.Creatives {
+		path := fmt.Sprintf("creatives[%d]", i)
+		if !validID(creative.CreativeID) {
+			p.add(path+".creative_id", "creative_id must be 1 to %d characters", maxIDLength)
+		} else if creatives[creative.CreativeID] {
+			p.add(path+".creative_id", "creative %s is declared more than once", creative.CreativeID)
+		}
+		creatives[creative.CreativeID] = true
+		validateMetadata(&p, path, creative.Labels, creative.ExternalIDs)
+	}
+
+	campaigns := make(map[string]bool, len(d.Campaigns))
+	bookings := 0
+	for i, campaign := range d.Campaigns {
+		path := fmt.Sprintf("campaigns[%d]", i)
+		if !validID(campaign.CampaignID) {
+			p.add(path+".campaign_id", "campaign_id must be 1 to %d characters", maxIDLength)
+		} else if campaigns[campaign.CampaignID] {
+			p.add(path+".campaign_id", "campaign %s is declared more than once", campaign.CampaignID)
+		}
+		campaigns[campaign.CampaignID] = true
+		if campaign.Budget != nil && !positive(*campaign.Budget) {
+			p.add(path+".budget", "budget must be positive")
+		}
+		if score := campaign.Targeting.MinPRSScore; score != nil && !validScore(*score) {
+			p.add(path+".targeting.min_prs_score", "min_prs_score must be between 0 and %d", maxPRSScore)
+		}
+		if len(campaign.Bookings) > 0 && (campaign.AdvertiserID == "" || len(campaign.AdvertiserID) > maxIDLength) {
+			p.add(path+".advertiser_id", "advertiser_id is required for a campaign with bookings")
+		}
+		validateMetadata(&p, path, campaign.Labels, campaign.ExternalIDs)
+
+		bookings += len(campaign.Bookings)
+		surfaces := make(map[string]bool, len(campaign.Bookings))
+		for j, b := range campaign.Bookings {
+			bookingPath := fmt.Sprintf("%s.bookings[%d]", path, j)
+			if !validID(b.SurfaceID) {
+				p.add(bookingPath+".surface_id", "surface_id must be 1 to %d characters", maxIDLength)
+			} else if surfaces[b.SurfaceID] {
+				p.add(bookingPath+".surface_id", "surface %s is booked more than once by the campaign", b.SurfaceID)
+			}
+			surfaces[b.SurfaceID] = true
+			if !positive(b.BidAmountCPM) {
+				p.add(bookingPath+".bid_amount_cpm", "bid_amount_cpm must be positive")
+			}
+			if b.MaxImpressions < 0 {
+				p.add(bookingPath+".max_impressions", "max_impressions must not be negative")
+			}
+			if b.MinPRSScore != nil && !validScore(*b.MinPRSScore) {
+				p.add(bookingPath+".min_prs_score", "min_prs_score must be between 0 and %d", maxPRSScore)
+			}
+			if len(b.CreativeID) > maxIDLength {
+				p.add(bookingPath+".creative_id", "creative_id must be at most %d characters", maxIDLength)
+			}
+			validateMetadata(&p, bookingPath, b.Labels, b.ExternalIDs)
+		}
+	}
+	if bookings > MaxBookings {
+		p.add("campaigns", "document declares %d bookings, more than %d", bookings, MaxBookings)
+	}
+	return p
+}
+
+// validateMetadata checks the labels and partner IDs of one declaration
+func validateMetadata(p *problems, path string, set labels.Set, ids map[string]string) {
+	if err := set.Validate(); err != nil {
+		p.add(path+".labels", "%s", err)
+	}
+	for source, externalID := range ids {
+		if !labels.ValidKey(source) {
+			p.add(path+".external_ids", "invalid external ID source %q", source)
+		}
+		if externalID == "" || len(externalID) > maxExternalIDLength {
+			p.add(path+".external_ids", "external ID for source %q must be 1-%d characters", source, maxExternalIDLength)
+		}
+	}
+}
+
+func validID(id string) bool {
+	return id != "" && len(id) <= maxIDLength
+}
+
+func positive(value float64) bool {
+	return value > 0 && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+func validScore(score float64) bool {
+	return score >= 0 && score <= maxPRSScore
+}
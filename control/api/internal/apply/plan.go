@@ -0,0 +1,380 @@
+package apply
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+)
+
+// What applying a document does with each resource
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete" // Bookings are cancelled, keeping their history
+)
+
+// Booking statuses the diff distinguishes
+const (
+	statusPending = "pending"
+	statusPaused  = "paused"
+)
+
+// State is the current state of the resources a document declares.
+// Resources missing from the maps do not exist yet.
+type State struct {
+	Campaigns map[string]*CampaignState
+	Creatives map[string]*CreativeState
+}
+
+// CampaignState is a campaign's current settings and live bookings
+type CampaignState struct {
+	Budget      *float64
+	Labels      labels.Set
+	ExternalIDs map[string]string
+	Bookings    []BookingState // Oldest first
+}
+
+// BookingState is a live booking's current terms
+type BookingState struct {
+	BookingID   string
+	SurfaceID   string
+	Status      string
+	Terms       Terms
+	Labels      labels.Set
+	ExternalIDs map[string]string
+}
+
+// CreativeState is a creative's current metadata
+type CreativeState struct {
+	Labels      labels.Set
+	ExternalIDs map[string]string
+}
+
+// Terms are the terms of a booking a document manages
+type Terms struct {
+	AdvertiserID   string
+	BidAmountCPM   float64
+	MaxImpressions int
+	MinPRSScore    float64
+	CreativeID     string
+	Paused         bool
+}
+
+// FieldChange is one field a change sets; Old is nil for new resources
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Change is what applying a document does to one resource. The
+// declaration and current state it was computed from are kept for
+// applying it.
+type Change struct {
+	Action       string        `json:"action"`
+	ResourceType string        `json:"resource_type"`
+	ResourceID   string        `json:"resource_id,omitempty"` // Empty for bookings yet to be created
+	CampaignID   string        `json:"campaign_id,omitempty"`
+	SurfaceID    string        `json:"surface_id,omitempty"`
+	Path         string        `json:"path,omitempty"` // Declaration the change comes from; empty for deletes
+	Fields       []FieldChange `json:"fields,omitempty"`
+
+	Campaign *Campaign     `json:"-"`
+	Creative *Creative     `json:"-"`
+	Booking  *Booking      `json:"-"`
+	Terms    Terms         `json:"-"` // Booking terms to create or update to
+	Current  *BookingState `json:"-"`
+}
+
+// Sets reports whether the change sets a field
+func (c *Change) Sets(field string) bool {
+	for _, f := range c.Fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary counts a plan's changes
+type Summary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Delete    int `json:"delete"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Plan is the difference between a document and the current state. A plan
+// with problems cannot be applied. PlanHash identifies the changes, so a
+// reviewed plan can be applied only if the state has not moved since.
+type Plan struct {
+	Changes   []Change   `json:"changes"`
+	Summary   Summary    `json:"summary"`
+	Problems  []Problem  `json:"problems"`
+	PlanHash  string     `json:"plan_hash"`
+	DryRun    bool       `json:"dry_run"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// AddProblem records a reason the plan cannot be applied
+func (p *Plan) AddProblem(path, format string, args ...interface{}) {
+	p.Problems = append(p.Problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Steps returns the changes in the order they are applied: creatives and
+// campaigns, then booking cancellations, which free inventory for the
+// updates and new bookings after them
+func (p *Plan) Steps() []*Change {
+	steps := make([]*Change, 0, len(p.Changes))
+	rank := func(c *Change) int {
+		switch {
+		case c.ResourceType != labels.ResourceBooking:
+			return 0
+		case c.Action == ActionDelete:
+			return 1
+		case c.Action == ActionUpdate:
+			return 2
+		}
+		return 3
+	}
+	for r := 0; r <= 3; r++ {
+		for i := range p.Changes {
+			if rank(&p.Changes[i]) == r {
+				steps = append(steps, &p.Changes[i])
+			}
+		}
+	}
+	return steps
+}
+
+// Diff computes the plan that brings the current state to the one a
+// validated document declares
+func Diff(d *Document, state *State) *Plan {
+	plan := &Plan{Changes: make([]Change, 0), Problems: make([]Problem, 0)}
+
+	for i := range d.Creatives {
+		creative := &d.Creatives[i]
+		change := Change{
+			ResourceType: labels.ResourceCreative,
+			ResourceID:   creative.CreativeID,
+			Path:         fmt.Sprintf("creatives[%d]", i),
+			Creative:     creative,
+		}
+		current, exists := state.Creatives[creative.CreativeID]
+		if !exists {
+			current = &CreativeState{}
+		}
+		change.Fields = diffMetadata(creative.Labels, creative.ExternalIDs, current.Labels, current.ExternalIDs, exists)
+		plan.add(change, exists)
+	}
+
+	// Booking IDs derive from the surface and time, so one apply creates
+	// one booking per surface
+	created := make(map[string]string)
+	for i := range d.Campaigns {
+		campaign := &d.Campaigns[i]
+		path := fmt.Sprintf("campaigns[%d]", i)
+		current, exists := state.Campaigns[campaign.CampaignID]
+		if !exists {
+			current = &CampaignState{}
+		}
+
+		change := Change{
+			ResourceType: labels.ResourceCampaign,
+			ResourceID:   campaign.CampaignID,
+			Path:         path,
+			Campaign:     campaign,
+		}
+		if campaign.Budget != nil && (current.Budget == nil || cents(*current.Budget) != cents(*campaign.Budget)) {
+			change.Fields = append(change.Fields, FieldChange{Field: "budget", Old: current.Budget, New: *campaign.Budget})
+		}
+		change.Fields = append(change.Fields, diffMetadata(campaign.Labels, campaign.ExternalIDs, current.Labels, current.ExternalIDs, exists)...)
+		plan.add(change, exists)
+
+		if campaign.Bookings == nil {
+			continue
+		}
+		live := make(map[string][]*BookingState)
+		for j := range current.Bookings {
+			b := &current.Bookings[j]
+			live[b.SurfaceID] = append(live[b.SurfaceID], b)
+		}
+
+		for j := range campaign.Bookings {
+			b := &campaign.Bookings[j]
+			bookingPath := fmt.Sprintf("%s.bookings[%d]", path, j)
+			change := Change{
+				ResourceType: labels.ResourceBooking,
+				CampaignID:   campaign.CampaignID,
+				SurfaceID:    b.SurfaceID,
+				Path:         bookingPath,
+				Campaign:     campaign,
+				Booking:      b,
+				Terms:        campaign.Terms(b),
+			}
+
+			matches := live[b.SurfaceID]
+			if len(matches) == 0 {
+				if other, ok := created[b.SurfaceID]; ok {
+					plan.AddProblem(bookingPath, "surface %s is also booked by %s; apply one of them separately", b.SurfaceID, other)
+				}
+				created[b.SurfaceID] = bookingPath
+				change.Fields = append(diffTerms(nil, change.Terms), diffMetadata(b.Labels, b.ExternalIDs, nil, nil, false)...)
+				plan.add(change, false)
+				continue
+			}
+
+			// The oldest live booking on the surface is the declared one
+			existing := matches[0]
+			live[b.SurfaceID] = matches[1:]
+			change.ResourceID = existing.BookingID
+			change.Current = existing
+			change.Fields = append(diffTerms(&existing.Terms, change.Terms), diffMetadata(b.Labels, b.ExternalIDs, existing.Labels, existing.ExternalIDs, true)...)
+			if change.Sets("paused") && existing.Status == statusPending {
+				plan.AddProblem(bookingPath+".paused", "booking %s is pending approval and cannot be paused or resumed", existing.BookingID)
+			}
+			plan.add(change, true)
+		}
+
+		// Live bookings the campaign no longer declares are cancelled
+		for j := range current.Bookings {
+			b := &current.Bookings[j]
+			if !undeclared(live[b.SurfaceID], b) {
+				continue
+			}
+			plan.add(Change{
+				Action:       ActionDelete,
+				ResourceType: labels.ResourceBooking,
+				ResourceID:   b.BookingID,
+				CampaignID:   campaign.CampaignID,
+				SurfaceID:    b.SurfaceID,
+				Campaign:     campaign,
+				Current:      b,
+			}, true)
+		}
+	}
+
+	plan.PlanHash = hash(plan.Changes)
+	return plan
+}
+
+// add records a change to a declared resource, or counts it unchanged
+func (p *Plan) add(change Change, exists bool) {
+	switch {
+	case change.Action == ActionDelete:
+		p.Summary.Delete++
+	case !exists:
+		change.Action = ActionCreate
+		p.Summary.Create++
+	case len(change.Fields) > 0:
+		change.Action = ActionUpdate
+		p.Summary.Update++
+	default:
+		p.Summary.Unchanged++
+		return
+	}
+	p.Changes = append(p.Changes, change)
+}
+
+// undeclared reports whether a live booking was left unmatched
+func undeclared(unmatched []*BookingState, b *BookingState) bool {
+	for _, u := range unmatched {
+		if u == b {
+			return true
+		}
+	}
+	return false
+}
+
+// diffTerms lists the booking terms that differ; old is nil for new bookings
+func diffTerms(old *Terms, terms Terms) []FieldChange {
+	var fields []FieldChange
+	if old == nil {
+		fields = append(fields,
+			FieldChange{Field: "advertiser_id", New: terms.AdvertiserID},
+			FieldChange{Field: "bid_amount_cpm", New: terms.BidAmountCPM},
+			FieldChange{Field: "max_impressions", New: terms.MaxImpressions},
+			FieldChange{Field: "min_prs_score", New: terms.MinPRSScore},
+		)
+		if terms.CreativeID != "" {
+			fields = append(fields, FieldChange{Field: "creative_id", New: terms.CreativeID})
+		}
+		if terms.Paused {
+			fields = append(fields, FieldChange{Field: "paused", New: true})
+		}
+		return fields
+	}
+
+	if old.AdvertiserID != terms.AdvertiserID {
+		fields = append(fields, FieldChange{Field: "advertiser_id", Old: old.AdvertiserID, New: terms.AdvertiserID})
+	}
+	// Bids are stored to the cent and scores as 32-bit floats
+	if cents(old.BidAmountCPM) != cents(terms.BidAmountCPM) {
+		fields = append(fields, FieldChange{Field: "bid_amount_cpm", Old: old.BidAmountCPM, New: terms.BidAmountCPM})
+	}
+	if old.MaxImpressions != terms.MaxImpressions {
+		fields = append(fields, FieldChange{Field: "max_impressions", Old: old.MaxImpressions, New: terms.MaxImpressions})
+	}
+	if float32(old.MinPRSScore) != float32(terms.MinPRSScore) {
+		fields = append(fields, FieldChange{Field: "min_prs_score", Old: old.MinPRSScore, New: terms.MinPRSScore})
+	}
+	if old.CreativeID != terms.CreativeID {
+		fields = append(fields, FieldChange{Field: "creative_id", Old: old.CreativeID, New: terms.CreativeID})
+	}
+	if old.Paused != terms.Paused {
+		fields = append(fields, FieldChange{Field: "paused", Old: old.Paused, New: terms.Paused})
+	}
+	return fields
+}
+
+// diffMetadata lists declared labels and partner IDs that differ from the
+// current ones. Undeclared (nil) metadata is not managed.
+func diffMetadata(set labels.Set, ids map[string]string, oldSet labels.Set, oldIDs map[string]string, exists bool) []FieldChange {
+	var fields []FieldChange
+	if set != nil && (!exists || !equal(set, oldSet)) {
+		fields = append(fields, FieldChange{Field: "labels", Old: metadataValue(oldSet, exists), New: set})
+	}
+	if ids != nil && (!exists || !equal(ids, oldIDs)) {
+		fields = append(fields, FieldChange{Field: "external_ids", Old: metadataValue(oldIDs, exists), New: ids})
+	}
+	return fields
+}
+
+func metadataValue(values map[string]string, exists bool) interface{} {
+	if !exists {
+		return nil
+	}
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
+
+func equal(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// hash identifies a plan's changes, including the current values they
+// replace
+func hash(changes []Change) string {
+	document, _ := json.Marshal(changes)
+	sum := sha256.Sum256(document)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,207 @@
+// Package approval tracks publishers' approval of the creative each booking
+// runs. Publishers decide in Inscenium or, delegating, in their own ad ops
+// tools, which pull pending approvals and post decisions back as signed
+// callbacks.
+//
+// Decisions can conflict: a tool may decide after someone decided in
+// Inscenium, or callbacks may arrive out of order. The standing decision for
+// a booking's creative is settled by this precedence, first rule first:
+//
+//  1. A decision about a creative the booking no longer runs is stale and
+//     ignored.
+//  2. A decision made in Inscenium is an override: it outranks any decision
+//     of a delegated tool, whenever either was made.
+//  3. Between decisions of the same rank the one decided last stands, by
+//     the decision time the tool reports rather than when it arrived.
+//  4. A rejection beats an approval decided at the same instant.
+//
+// Every decision received is logged with the outcome of that precedence.
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Approval statuses. A creative is pending until a decision stands.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Decision sources, in increasing rank
+const (
+	SourceDelegated = "delegated" // A publisher's own ad ops tool, by signed callback
+	SourcePlatform  = "platform"  // A user or service account through the API
+)
+
+// Outcomes of a decision under the precedence policy
+const (
+	OutcomeApplied   = "applied"   // The decision stands
+	OutcomeOverruled = "overruled" // A decision taking precedence stands instead
+	OutcomeStale     = "stale"     // The booking runs another creative now
+)
+
+// Callback event types sent by delegated tools
+const (
+	EventApproved = "creative.approved"
+	EventRejected = "creative.rejected"
+)
+
+// Limits on decision fields, matching their columns
+const (
+	MaxReviewerLength    = 255
+	MaxExternalRefLength = 255
+	MaxReasonLength      = 2000
+)
+
+// ErrNoCreative is returned for bookings without a creative to approve
+var ErrNoCreative = errors.New("booking has no creative")
+
+// Approval is the standing approval of a booking's creative
+type Approval struct {
+	BookingID       string     `json:"booking_id"`
+	CreativeAssetID string     `json:"creative_asset_id"`
+	CampaignID      string     `json:"campaign_id,omitempty"`
+	SurfaceID       string     `json:"surface_id,omitempty"`
+	OrgID           string     `json:"org_id,omitempty"`
+	Status          string     `json:"status"`
+	Source          string     `json:"source,omitempty"`
+	Reviewer        string     `json:"reviewer,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	ExternalRef     string     `json:"external_ref,omitempty"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+	RequestedAt     time.Time  `json:"requested_at"` // When the booking was made
+}
+
+// Decision is one approval or rejection of a booking's creative
+type Decision struct {
+	DecisionID      string    `json:"decision_id"`
+	BookingID       string    `json:"booking_id"`
+	CreativeAssetID string    `json:"creative_asset_id"`
+	Status          string    `json:"decision"` // approved or rejected
+	Source          string    `json:"source"`
+	Reviewer        string    `json:"reviewer,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	ExternalRef     string    `json:"external_ref,omitempty"` // The tool's own ID for the decision
+	DecidedAt       time.Time `json:"decided_at"`
+	ReceivedAt      time.Time `json:"received_at"`
+	Outcome         string    `json:"outcome"`
+	Conflict        bool      `json:"conflict"` // It disagreed with the decision standing when it arrived
+}
+
+// Result is a decision with the approval standing after it
+type Result struct {
+	Decision *Decision `json:"decision"`
+	Approval *Approval `json:"approval"`
+}
+
+// Callback is a decision posted by a delegated tool
+type Callback struct {
+	Event           string    `json:"event"`
+	BookingID       string    `json:"booking_id"`
+	CreativeAssetID string    `json:"creative_asset_id"`
+	Reviewer        string    `json:"reviewer"`
+	Reason          string    `json:"reason"`
+	ExternalRef     string    `json:"external_ref"`
+	DecidedAt       time.Time `json:"decided_at"`
+}
+
+// Decision returns the decision a callback carries
+func (cb Callback) Decision() (*Decision, error) {
+	d := &Decision{
+		BookingID:       cb.BookingID,
+		CreativeAssetID: cb.CreativeAssetID,
+		Source:          SourceDelegated,
+		Reviewer:        cb.Reviewer,
+		Reason:          cb.Reason,
+		ExternalRef:     cb.ExternalRef,
+		DecidedAt:       cb.DecidedAt,
+	}
+	switch cb.Event {
+	case EventApproved:
+		d.Status = StatusApproved
+	case EventRejected:
+		d.Status = StatusRejected
+	default:
+		return nil, fmt.Errorf("unknown approval event %q", cb.Event)
+	}
+	if cb.CreativeAssetID == "" {
+		return nil, fmt.Errorf("creative_asset_id is required")
+	}
+	return d, d.Validate()
+}
+
+// Validate checks a decision's status and field lengths
+func (d *Decision) Validate() error {
+	if d.BookingID == "" {
+		return fmt.Errorf("booking_id is required")
+	}
+	if d.Status != StatusApproved && d.Status != StatusRejected {
+		return fmt.Errorf("decision must be %s or %s", StatusApproved, StatusRejected)
+	}
+	if len(d.Reviewer) > MaxReviewerLength {
+		return fmt.Errorf("reviewer must be at most %d characters", MaxReviewerLength)
+	}
+	if len(d.ExternalRef) > MaxExternalRefLength {
+		return fmt.Errorf("external_ref must be at most %d characters", MaxExternalRefLength)
+	}
+	if len(d.Reason) > MaxReasonLength {
+		return fmt.Errorf("reason must be at most %d characters", MaxReasonLength)
+	}
+	return nil
+}
+
+// rank orders decision sources by precedence
+func rank(source string) int {
+	if source == SourcePlatform {
+		return 1
+	}
+	return 0
+}
+
+// Resolve applies the precedence policy to a decision arriving while
+// standing is the approval of the booking's current creative. It sets the
+// decision's outcome and conflict flag, and returns the approval standing
+// after it. standing is not modified.
+func Resolve(standing Approval, d *Decision) Approval {
+	d.Conflict = false
+	if d.CreativeAssetID != standing.CreativeAssetID {
+		d.Outcome = OutcomeStale
+		return standing
+	}
+	if standing.Status != StatusPending {
+		d.Conflict = d.Status != standing.Status
+	}
+	if standing.Status != StatusPending && !precedes(d, standing) {
+		d.Outcome = OutcomeOverruled
+		return standing
+	}
+
+	d.Outcome = OutcomeApplied
+	next := standing
+	decidedAt := d.DecidedAt
+	next.Status = d.Status
+	next.Source = d.Source
+	next.Reviewer = d.Reviewer
+	next.Reason = d.Reason
+	next.ExternalRef = d.ExternalRef
+	next.DecidedAt = &decidedAt
+	return next
+}
+
+// precedes reports whether a decision takes precedence over the standing one
+func precedes(d *Decision, standing Approval) bool {
+	if rank(d.Source) != rank(standing.Source) {
+		return rank(d.Source) > rank(standing.Source)
+	}
+	if standing.DecidedAt == nil || d.DecidedAt.After(*standing.DecidedAt) {
+		return true
+	}
+	if d.DecidedAt.Before(*standing.DecidedAt) {
+		return false
+	}
+	return d.Status == StatusRejected || standing.Status == StatusApproved
+}
@@ -0,0 +1,42 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+)
+
+// Store reads the standing approval of a booking's creative
+type Store interface {
+	GetCreativeApproval(bookingID string) (*Approval, error)
+}
+
+// Dispatcher posts events to webhook subscriptions
+type Dispatcher interface {
+	Dispatch(ctx context.Context, eventType, orgID string, occurredAt time.Time, data interface{}) error
+}
+
+// Notify posts a creative.approval_requested webhook event, carrying the
+// pending Approval, for every booking created with a creative
+func Notify(bus *eventbus.Bus, store Store, dispatcher Dispatcher) {
+	eventbus.SubscribeAsync(bus, "approval_requests", 256, func(ctx context.Context, event eventbus.BookingChanged) error {
+		if event.Type != booking.EventCreated {
+			return nil
+		}
+		approval, err := store.GetCreativeApproval(event.BookingID)
+		if errors.Is(err, ErrNoCreative) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if approval == nil || approval.Status != StatusPending {
+			return nil
+		}
+		return dispatcher.Dispatch(ctx, webhook.EventCreativeApprovalRequested, event.OrgID, event.OccurredAt, approval)
+	})
+}
@@ -0,0 +1,134 @@
+// Package audio describes audio placements: slots in a podcast episode or a
+// music video's soundtrack that carry a spoken or musical mention rather
+// than a surface on screen. A slot is bounded by its surface's start and end
+// times, may constrain the loudness of creatives played in it, and its
+// exposures are measured by how much of it was listened through instead of
+// screen coverage.
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Placement types of a surface
+const (
+	PlacementVisual = "visual" // A surface on screen
+	PlacementAudio  = "audio"  // A slot in the audio track
+)
+
+// PlacementTypes lists the placement types in the order they are documented
+var PlacementTypes = []string{PlacementVisual, PlacementAudio}
+
+// QualifyingListenThrough is the share of an audio slot a listener must hear
+// for the exposure to qualify under vCPM
+const QualifyingListenThrough = 0.5
+
+// Bounds on loudness constraints
+const (
+	MinTargetLUFS   = -40.0 // Quietest integrated loudness a slot may target
+	MaxToleranceLU  = 10.0
+	MinTruePeakDBTP = -20.0 // Lowest true peak ceiling a slot may set
+)
+
+// ErrMismatch is returned when a creative does not fit an audio slot
+var ErrMismatch = errors.New("creative does not fit the audio slot")
+
+// ValidatePlacementType checks that placementType is a known placement type.
+// An empty type means visual.
+func ValidatePlacementType(placementType string) error {
+	if placementType == "" {
+		return nil
+	}
+	for _, known := range PlacementTypes {
+		if placementType == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("placement_type must be one of %s", strings.Join(PlacementTypes, ", "))
+}
+
+// Loudness constrains the creatives played in an audio slot so a mention
+// sits at the level of the programme around it
+type Loudness struct {
+	TargetLUFS      float64 `json:"target_lufs"`        // Integrated loudness creatives are mixed to, e.g. -16 for podcasts
+	ToleranceLU     float64 `json:"tolerance_lu"`       // How far a creative's integrated loudness may stray from the target
+	MaxTruePeakDBTP float64 `json:"max_true_peak_dbtp"` // Ceiling on a creative's true peak, e.g. -1
+}
+
+// Validate checks that the constraints are within bounds
+func (l *Loudness) Validate() error {
+	switch {
+	case !finite(l.TargetLUFS) || l.TargetLUFS < MinTargetLUFS || l.TargetLUFS >= 0:
+		return fmt.Errorf("loudness target_lufs must be between %g and 0", MinTargetLUFS)
+	case !finite(l.ToleranceLU) || l.ToleranceLU <= 0 || l.ToleranceLU > MaxToleranceLU:
+		return fmt.Errorf("loudness tolerance_lu must be above 0 and at most %g", MaxToleranceLU)
+	case !finite(l.MaxTruePeakDBTP) || l.MaxTruePeakDBTP < MinTruePeakDBTP || l.MaxTruePeakDBTP > 0:
+		return fmt.Errorf("loudness max_true_peak_dbtp must be between %g and 0", MinTruePeakDBTP)
+	}
+	return nil
+}
+
+// Slot is where an audio placement plays
+type Slot struct {
+	StartOffset float64   `json:"start_offset"` // Seconds into the episode or track
+	Duration    float64   `json:"duration"`     // Seconds
+	Loudness    *Loudness `json:"loudness,omitempty"`
+}
+
+// Creative is what an edge node measured of the creative it is about to play
+// in a slot. Measurements left out are not checked.
+type Creative struct {
+	Duration       float64  `json:"duration"`                  // Seconds
+	IntegratedLUFS *float64 `json:"integrated_lufs,omitempty"` // Integrated loudness
+	TruePeakDBTP   *float64 `json:"true_peak_dbtp,omitempty"`
+}
+
+// Validate checks that the measurements are finite and the duration is set
+func (c *Creative) Validate() error {
+	if !finite(c.Duration) || c.Duration <= 0 {
+		return fmt.Errorf("creative duration must be above 0")
+	}
+	if c.IntegratedLUFS != nil && !finite(*c.IntegratedLUFS) {
+		return fmt.Errorf("creative integrated_lufs must be finite")
+	}
+	if c.TruePeakDBTP != nil && !finite(*c.TruePeakDBTP) {
+		return fmt.Errorf("creative true_peak_dbtp must be finite")
+	}
+	return nil
+}
+
+// Fit checks that a creative is no longer than the slot and meets its
+// loudness constraints, returning an error wrapping ErrMismatch if not
+func (s *Slot) Fit(c Creative) error {
+	if c.Duration > s.Duration {
+		return fmt.Errorf("%w: creative runs %gs, longer than the %gs slot", ErrMismatch, c.Duration, s.Duration)
+	}
+	if s.Loudness == nil {
+		return nil
+	}
+	if c.IntegratedLUFS != nil && math.Abs(*c.IntegratedLUFS-s.Loudness.TargetLUFS) > s.Loudness.ToleranceLU {
+		return fmt.Errorf("%w: creative loudness %g LUFS is outside %g ± %g LUFS",
+			ErrMismatch, *c.IntegratedLUFS, s.Loudness.TargetLUFS, s.Loudness.ToleranceLU)
+	}
+	if c.TruePeakDBTP != nil && *c.TruePeakDBTP > s.Loudness.MaxTruePeakDBTP {
+		return fmt.Errorf("%w: creative true peak %g dBTP is above %g dBTP",
+			ErrMismatch, *c.TruePeakDBTP, s.Loudness.MaxTruePeakDBTP)
+	}
+	return nil
+}
+
+// ListenThrough is the share of a slot of duration seconds that was heard
+// over listened seconds, between 0 and 1
+func ListenThrough(listened, duration float64) float64 {
+	if duration <= 0 || !finite(listened) || listened <= 0 {
+		return 0
+	}
+	return math.Min(1, listened/duration)
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
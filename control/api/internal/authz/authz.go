@@ -0,0 +1,242 @@
+package authz
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// Permission is an action an organization may take on a resource
+type Permission string
+
+// Permissions, from weakest to strongest. Manage implies view.
+const (
+	PermissionView   Permission = "view"
+	PermissionManage Permission = "manage"
+)
+
+// Valid reports whether p is a known permission
+func (p Permission) Valid() bool {
+	return p == PermissionView || p == PermissionManage
+}
+
+// Grant lets one organization act on a resource owned by another,
+// e.g. an agency managing a brand's campaign
+type Grant struct {
+	ID           string       `json:"grant_id"`
+	ResourceType string       `json:"resource_type"`
+	ResourceID   string       `json:"resource_id"`
+	OwnerOrgID   string       `json:"owner_org_id"`
+	GranteeOrgID string       `json:"grantee_org_id"`
+	Permissions  []Permission `json:"permissions"`
+	GrantedBy    string       `json:"granted_by,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// Allows reports whether the grant includes p
+func (g *Grant) Allows(p Permission) bool {
+	for _, granted := range g.Permissions {
+		if granted == p || granted == PermissionManage {
+			return true
+		}
+	}
+	return false
+}
+
+// Store resolves resource ownership and grants
+type Store interface {
+	GetResourceOwner(resourceType, resourceID string) (string, error)
+	FindGrant(resourceType, resourceID, granteeOrgID string) (*Grant, error)
+}
+
+// Actor is the authenticated caller, a user or a service account
+type Actor struct {
+	UserID        string
+	OrgID         string
+	PrincipalType string
+	Role          string // Users only
+}
+
+// ActorFromContext reads the caller set by the auth middleware
+func ActorFromContext(c *gin.Context) Actor {
+	return Actor{
+		UserID:        c.GetString("user_id"),
+		OrgID:         c.GetString("org_id"),
+		PrincipalType: c.GetString("principal_type"),
+		Role:          c.GetString("role"),
+	}
+}
+
+// Decision records why access was allowed or denied
+type Decision struct {
+	Allowed    bool
+	OwnerOrgID string
+	GrantID    string
+	Reason     string
+}
+
+// Authorizer checks resource access for organizations
+type Authorizer struct {
+	store Store
+}
+
+// NewAuthorizer creates an authorizer backed by the given store
+func NewAuthorizer(store Store) *Authorizer {
+	return &Authorizer{store: store}
+}
+
+// Check decides whether actor may perform p on a resource. Users with the
+// admin role may act on any resource. Resources without a registered owner
+// predate organizations and are open to every caller. Every decision is
+// written to the audit log.
+func (a *Authorizer) Check(actor Actor, resourceType, resourceID string, p Permission) (Decision, error) {
+	return a.check(actor, resourceType, resourceID, p, false)
+}
+
+// CheckOwned is Check for actions as drastic as cancelling a booking: only
+// admins may take them on resources without a registered owner
+func (a *Authorizer) CheckOwned(actor Actor, resourceType, resourceID string, p Permission) (Decision, error) {
+	return a.check(actor, resourceType, resourceID, p, true)
+}
+
+// check implements Check and CheckOwned
+func (a *Authorizer) check(actor Actor, resourceType, resourceID string, p Permission, owned bool) (Decision, error) {
+	owner, err := a.store.GetResourceOwner(resourceType, resourceID)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to resolve resource owner: %w", err)
+	}
+
+	decision := Decision{OwnerOrgID: owner}
+	switch {
+	case actor.Role == user.RoleAdmin:
+		decision.Allowed = true
+		decision.Reason = "admin"
+	case owner == "" && owned:
+		decision.Reason = "unowned"
+	case owner == "":
+		decision.Allowed = true
+		decision.Reason = "unowned"
+	case actor.OrgID == owner:
+		decision.Allowed = true
+		decision.Reason = "owner"
+	case actor.OrgID == "":
+		decision.Reason = "no organization"
+	default:
+		grant, err := a.store.FindGrant(resourceType, resourceID, actor.OrgID)
+		if err != nil {
+			return Decision{}, fmt.Errorf("failed to look up grant: %w", err)
+		}
+		if grant != nil && grant.Allows(p) {
+			decision.Allowed = true
+			decision.GrantID = grant.ID
+			decision.Reason = "grant"
+		} else {
+			decision.Reason = "no grant"
+		}
+	}
+
+	audit(actor, resourceType, resourceID, p, decision)
+	return decision, nil
+}
+
+// Authorize checks access and writes a 403 or 500 response when it is not
+// granted. A nil authorizer allows everything.
+func (a *Authorizer) Authorize(c *gin.Context, resourceType, resourceID string, p Permission) bool {
+	return a.authorize(c, resourceType, resourceID, p, false)
+}
+
+// AuthorizeOwned is Authorize with CheckOwned
+func (a *Authorizer) AuthorizeOwned(c *gin.Context, resourceType, resourceID string, p Permission) bool {
+	return a.authorize(c, resourceType, resourceID, p, true)
+}
+
+// authorize implements Authorize and AuthorizeOwned
+func (a *Authorizer) authorize(c *gin.Context, resourceType, resourceID string, p Permission, owned bool) bool {
+	if a == nil || resourceID == "" {
+		return true
+	}
+
+	decision, err := a.check(ActorFromContext(c), resourceType, resourceID, p, owned)
+	if err != nil {
+		logrus.WithError(err).Error("Authorization check failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	if !decision.Allowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("not permitted to %s %s %s", p, resourceType, resourceID),
+		})
+		return false
+	}
+
+	if decision.GrantID != "" {
+		c.Set("grant_id", decision.GrantID)
+		c.Set("grant_owner_org_id", decision.OwnerOrgID)
+	}
+	return true
+}
+
+// Scope returns the organizations whose data the caller's queries may read:
+// every organization for admins, otherwise the caller's own plus the owner of
+// a resource Authorize let it reach through a grant on this request
+func Scope(c *gin.Context) tenant.Scope {
+	if c.GetString("role") == user.RoleAdmin {
+		return tenant.All()
+	}
+	return tenant.Of(c.GetString("org_id"), c.GetString("grant_owner_org_id"))
+}
+
+// Require returns middleware that checks p on the resource named by the
+// idParam path parameter. An empty resourceType is read from the
+// resource_type path parameter.
+func (a *Authorizer) Require(p Permission, resourceType, idParam string) gin.HandlerFunc {
+	return a.require(p, resourceType, idParam, false)
+}
+
+// RequireOwned is Require with CheckOwned
+func (a *Authorizer) RequireOwned(p Permission, resourceType, idParam string) gin.HandlerFunc {
+	return a.require(p, resourceType, idParam, true)
+}
+
+// require implements Require and RequireOwned
+func (a *Authorizer) require(p Permission, resourceType, idParam string, owned bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind := resourceType
+		if kind == "" {
+			kind = c.Param("resource_type")
+		}
+		if !a.authorize(c, kind, c.Param(idParam), p, owned) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// audit writes an authorization decision to the audit log
+func audit(actor Actor, resourceType, resourceID string, p Permission, decision Decision) {
+	entry := logrus.WithFields(logrus.Fields{
+		"audit":          "authz",
+		"user_id":        actor.UserID,
+		"principal_type": actor.PrincipalType,
+		"role":           actor.Role,
+		"org_id":         actor.OrgID,
+		"resource_type":  resourceType,
+		"resource_id":    resourceID,
+		"permission":     p,
+		"owner_org_id":   decision.OwnerOrgID,
+		"grant_id":       decision.GrantID,
+		"reason":         decision.Reason,
+	})
+	if decision.Allowed {
+		entry.Info("Access allowed")
+	} else {
+		entry.Warn("Access denied")
+	}
+}
@@ -0,0 +1,177 @@
+// Package billing prices impressions under a booking's billing model. CPM
+// bookings pay for every served impression; attention-billed bookings pay
+// for what was measured, so they are priced from exposure events instead.
+package billing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+)
+
+// Billing models a booking may be sold under
+const (
+	ModelCPM          = "cpm"           // The bid per thousand served impressions
+	ModelAttentionCPM = "attention_cpm" // The bid scaled by the attention band of each exposure
+	ModelVCPM         = "vcpm"          // The bid per thousand qualified exposures
+)
+
+// Models lists the billing models in the order they are documented
+var Models = []string{ModelCPM, ModelAttentionCPM, ModelVCPM}
+
+// ValidateModel checks that model is a known billing model. An empty model
+// means CPM.
+func ValidateModel(model string) error {
+	if model == "" {
+		return nil
+	}
+	for _, known := range Models {
+		if model == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("billing_model must be one of %s", strings.Join(Models, ", "))
+}
+
+// Measured reports whether a model is priced from exposure events rather
+// than served decisions
+func Measured(model string) bool {
+	return model == ModelAttentionCPM || model == ModelVCPM
+}
+
+// DefaultBands scale the bid by half below an attention score of 0.4, leave
+// it as is up to 0.7 and add half above that
+const DefaultBands = "0:0.5,0.4:1,0.7:1.5"
+
+// Band scales the bid of exposures scoring at least MinScore
+type Band struct {
+	MinScore   float64
+	Multiplier float64
+}
+
+// Bands are attention bands ordered by MinScore. The first starts at 0.
+type Bands []Band
+
+// ParseBands parses "min_score:multiplier" pairs separated by commas, e.g.
+// "0:0.5,0.4:1,0.7:1.5". Scores are between 0 and 1.
+func ParseBands(spec string) (Bands, error) {
+	var bands Bands
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		score, multiplier, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("attention band %q is not min_score:multiplier", pair)
+		}
+		minScore, err := strconv.ParseFloat(strings.TrimSpace(score), 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			return nil, fmt.Errorf("attention band %q: min_score must be between 0 and 1", pair)
+		}
+		m, err := strconv.ParseFloat(strings.TrimSpace(multiplier), 64)
+		if err != nil || m < 0 || math.IsInf(m, 0) || math.IsNaN(m) {
+			return nil, fmt.Errorf("attention band %q: multiplier must not be negative", pair)
+		}
+		bands = append(bands, Band{MinScore: minScore, Multiplier: m})
+	}
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("at least one attention band is required")
+	}
+
+	sort.Slice(bands, func(i, j int) bool { return bands[i].MinScore < bands[j].MinScore })
+	if bands[0].MinScore != 0 {
+		return nil, fmt.Errorf("the lowest attention band must start at 0")
+	}
+	for i := 1; i < len(bands); i++ {
+		if bands[i].MinScore == bands[i-1].MinScore {
+			return nil, fmt.Errorf("two attention bands start at %g", bands[i].MinScore)
+		}
+	}
+	return bands, nil
+}
+
+// Multiplier is the multiplier of the band an attention score falls in, or 1
+// without bands
+func (b Bands) Multiplier(score float64) float64 {
+	multiplier := 1.0
+	for _, band := range b {
+		if score >= band.MinScore {
+			multiplier = band.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// Max is the highest multiplier of any band, or 1 without bands
+func (b Bands) Max() float64 {
+	if len(b) == 0 {
+		return 1
+	}
+	max := 0.0
+	for _, band := range b {
+		max = math.Max(max, band.Multiplier)
+	}
+	return max
+}
+
+// Terms are the billing terms of a booking
+type Terms struct {
+	Model        string
+	CPM          float64 // Final CPM rate, or the bid until one is set
+	MinDuration  float64 // Seconds an exposure must last to qualify under vCPM
+	SlotDuration float64 // Seconds of the audio slot booked; zero for visual placements
+}
+
+// Exposure is what was measured of one exposure
+type Exposure struct {
+	Duration       float64 // Seconds
+	AttentionScore float64 // 0-1
+}
+
+// Pricing prices impressions under every billing model
+type Pricing struct {
+	Bands Bands
+}
+
+// ImpressionPrice is what one impression costs under terms, in currency units.
+// Measured models are priced at the most an exposure could cost, which is what
+// must remain of a budget before serving them.
+func (p Pricing) ImpressionPrice(terms Terms) float64 {
+	switch terms.Model {
+	case ModelAttentionCPM:
+		return terms.CPM * p.Bands.Max() / 1000
+	default:
+		return terms.CPM / 1000
+	}
+}
+
+// ExposurePrice is what a measured exposure costs under terms, in currency
+// units. Exposures of CPM bookings cost nothing: they were charged when served.
+func (p Pricing) ExposurePrice(terms Terms, exposure Exposure) float64 {
+	switch terms.Model {
+	case ModelAttentionCPM:
+		return terms.CPM * p.Bands.Multiplier(exposure.AttentionScore) / 1000
+	case ModelVCPM:
+		if !terms.Qualifies(exposure) {
+			return 0
+		}
+		return terms.CPM / 1000
+	default:
+		return 0
+	}
+}
+
+// Qualifies reports whether an exposure counts under vCPM: it must last
+// MinDuration, or for audio slots be listened through to
+// audio.QualifyingListenThrough of the slot
+func (t Terms) Qualifies(exposure Exposure) bool {
+	if t.SlotDuration > 0 {
+		return audio.ListenThrough(exposure.Duration, t.SlotDuration) >= audio.QualifyingListenThrough
+	}
+	return exposure.Duration >= t.MinDuration
+}
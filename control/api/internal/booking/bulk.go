@@ -0,0 +1,54 @@
+package booking
+
+import (
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+)
+
+// Selector picks the bookings a bulk operation applies to. Set fields are
+// combined with AND.
+type Selector struct {
+	CampaignID   string     `json:"campaign_id,omitempty"`
+	AdvertiserID string     `json:"advertiser_id,omitempty"`
+	Labels       labels.Set `json:"labels,omitempty"`
+}
+
+// Empty reports whether the selector would match every booking
+func (s Selector) Empty() bool {
+	return s.CampaignID == "" && s.AdvertiserID == "" && len(s.Labels) == 0
+}
+
+// Summary is a booking's terms and delivery so far
+type Summary struct {
+	BookingID            string  `json:"booking_id"`
+	SurfaceID            string  `json:"surface_id"`
+	AdvertiserID         string  `json:"advertiser_id"`
+	CampaignID           string  `json:"campaign_id"`
+	Status               string  `json:"status"`
+	BidAmountCPM         float64 `json:"bid_amount_cpm"`
+	EstimatedImpressions int64   `json:"estimated_impressions"`
+	ActualImpressions    int64   `json:"actual_impressions"`
+	BillingModel         string  `json:"billing_model"`
+	MeasuredSpend        float64 `json:"-"` // Spend charged on exposures of attention-billed bookings
+}
+
+// CommittedSpend is the booking's full value at its bid
+func (s Summary) CommittedSpend() float64 {
+	return s.BidAmountCPM * float64(s.EstimatedImpressions) / 1000
+}
+
+// DeliveredSpend is the value of the impressions already delivered. Bookings
+// billed on attention or viewability delivered what their exposures were
+// charged, capped at the committed spend.
+func (s Summary) DeliveredSpend() float64 {
+	if billing.Measured(s.BillingModel) {
+		return min(s.MeasuredSpend, s.CommittedSpend())
+	}
+	delivered := min(s.ActualImpressions, s.EstimatedImpressions)
+	return s.BidAmountCPM * float64(delivered) / 1000
+}
+
+// RemainingSpend is the undelivered value a pause or cancellation stops
+func (s Summary) RemainingSpend() float64 {
+	return s.CommittedSpend() - s.DeliveredSpend()
+}
@@ -0,0 +1,61 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrConflict is returned when a booking's window overlaps that of a live
+// booking of the same surface
+var ErrConflict = errors.New("surface is already booked for an overlapping window")
+
+// Window is the period a booking's placement runs, from Start up to but not
+// including End. A missing end leaves that side open, so a booking without a
+// window holds its surface indefinitely.
+type Window struct {
+	Start *time.Time `json:"start_time,omitempty"`
+	End   *time.Time `json:"end_time,omitempty"`
+}
+
+// Validate checks that the window ends after it starts
+func (w Window) Validate() error {
+	if w.Start != nil && w.End != nil && !w.End.After(*w.Start) {
+		return errors.New("end_time must be after start_time")
+	}
+	return nil
+}
+
+// String describes the window for error messages
+func (w Window) String() string {
+	switch {
+	case w.Start == nil && w.End == nil:
+		return "indefinitely"
+	case w.End == nil:
+		return "from " + w.Start.UTC().Format(time.RFC3339)
+	case w.Start == nil:
+		return "until " + w.End.UTC().Format(time.RFC3339)
+	}
+	return "from " + w.Start.UTC().Format(time.RFC3339) + " until " + w.End.UTC().Format(time.RFC3339)
+}
+
+// ConflictError names the live booking a new booking would overlap. It wraps
+// ErrConflict. The booking may belong to another organization, so its
+// message only describes the window it holds.
+type ConflictError struct {
+	BookingID    string `json:"booking_id"`
+	SurfaceID    string `json:"surface_id"`
+	AdvertiserID string `json:"advertiser_id"`
+	CampaignID   string `json:"campaign_id"`
+	Status       string `json:"status"`
+	OrgID        string `json:"-"` // Owner of the booking; empty when it has none
+	Window
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: surface %s is booked %s", ErrConflict, e.SurfaceID, e.Window)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
@@ -0,0 +1,286 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event types in a booking's lifecycle
+const (
+	EventCreated   = "created"
+	EventApproved  = "approved"
+	EventActivated = "activated"
+	EventAmended   = "amended"
+	EventPaused    = "paused"
+	EventCompleted = "completed"
+	EventExpired   = "expired"
+	EventCancelled = "cancelled"
+)
+
+// Booking statuses. A booking moves from pending to confirmed to active and
+// ends completed, cancelled or expired; confirmed and active bookings can be
+// paused on the way.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// transitions lists the statuses each status-changing event may follow, and
+// the status it moves the booking to
+var transitions = map[string]struct {
+	from []string
+	to   string
+}{
+	EventApproved:  {[]string{StatusPending, StatusPaused}, StatusConfirmed},
+	EventActivated: {[]string{StatusConfirmed, StatusPaused}, StatusActive},
+	EventPaused:    {[]string{StatusConfirmed, StatusActive}, StatusPaused},
+	EventCompleted: {[]string{StatusActive, StatusPaused}, StatusCompleted},
+	EventExpired:   {[]string{StatusPending, StatusConfirmed}, StatusExpired},
+	EventCancelled: {[]string{StatusPending, StatusConfirmed, StatusActive, StatusPaused}, StatusCancelled},
+}
+
+// Terminal reports whether nothing may follow a booking in status
+func Terminal(status string) bool {
+	return status == StatusCompleted || status == StatusCancelled || status == StatusExpired
+}
+
+// EventFor returns the event that moves a booking to status. Pending is only
+// ever a booking's initial status, so no event leads to it.
+func EventFor(status string) (string, error) {
+	for eventType, transition := range transitions {
+		if transition.to == status {
+			return eventType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no transition to status %q", ErrInvalidTransition, status)
+}
+
+// ErrInvalidTransition is returned when an event cannot be applied to a
+// booking in its current state
+var ErrInvalidTransition = errors.New("invalid booking transition")
+
+// Change holds the booking terms set by a created or amended event. Nil and
+// empty fields are left unchanged by an amendment.
+type Change struct {
+	SurfaceID      string   `json:"surface_id,omitempty"`
+	AdvertiserID   string   `json:"advertiser_id,omitempty"`
+	CampaignID     string   `json:"campaign_id,omitempty"`
+	BidAmountCPM   *float64 `json:"bid_amount_cpm,omitempty"`
+	MaxImpressions *int     `json:"max_impressions,omitempty"`
+	MinPRSScore    *float64 `json:"min_prs_score,omitempty"`
+	Status         string   `json:"status,omitempty"` // Initial status of a created booking
+	Reason         string   `json:"reason,omitempty"`
+}
+
+// Event is one immutable entry in a booking's event stream. Sequence numbers
+// start at 1 and have no gaps.
+type Event struct {
+	BookingID  string    `json:"booking_id"`
+	Sequence   int       `json:"sequence"`
+	Type       string    `json:"type"`
+	Actor      string    `json:"actor,omitempty"`
+	OrgID      string    `json:"org_id,omitempty"`
+	Data       Change    `json:"data"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// State is a booking's current state, derived by replaying its events
+type State struct {
+	BookingID      string     `json:"booking_id"`
+	SurfaceID      string     `json:"surface_id"`
+	AdvertiserID   string     `json:"advertiser_id"`
+	CampaignID     string     `json:"campaign_id"`
+	Status         string     `json:"status"`
+	BidAmountCPM   float64    `json:"bid_amount_cpm"`
+	MaxImpressions int        `json:"max_impressions"`
+	MinPRSScore    float64    `json:"min_prs_score"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	ActivatedAt    *time.Time `json:"activated_at,omitempty"` // First activation; resuming keeps it
+	EndedAt        *time.Time `json:"ended_at,omitempty"`     // When it completed or expired
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Version        int        `json:"version"` // Sequence of the last applied event
+}
+
+// Project replays events in sequence order into the booking's state. It
+// returns nil for an empty stream.
+func Project(events []Event) (*State, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	state := &State{}
+	for _, event := range events {
+		if err := state.Apply(event); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// Until returns the prefix of a stream that occurred at or before asOf
+func Until(events []Event, asOf time.Time) []Event {
+	n := 0
+	for n < len(events) && !events[n].OccurredAt.After(asOf) {
+		n++
+	}
+	return events[:n]
+}
+
+// ProjectAsOf replays the events that occurred at or before asOf, giving the
+// booking's state at that time. It returns nil if the booking did not exist yet.
+func ProjectAsOf(events []Event, asOf time.Time) (*State, error) {
+	return Project(Until(events, asOf))
+}
+
+// ProjectAll projects the streams of several bookings as of asOf. events must
+// be grouped by booking and in sequence order within each booking. Bookings
+// that did not exist at asOf are omitted.
+func ProjectAll(events []Event, asOf time.Time) ([]*State, error) {
+	states := make([]*State, 0)
+	for start := 0; start < len(events); {
+		end := start + 1
+		for end < len(events) && events[end].BookingID == events[start].BookingID {
+			end++
+		}
+		state, err := ProjectAsOf(events[start:end], asOf)
+		if err != nil {
+			return nil, fmt.Errorf("booking %s: %w", events[start].BookingID, err)
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+		start = end
+	}
+	return states, nil
+}
+
+// Apply applies the next event to the state. The state is unchanged when the
+// event is rejected.
+func (s *State) Apply(event Event) error {
+	if event.Sequence != s.Version+1 {
+		return fmt.Errorf("%w: event %d follows version %d", ErrInvalidTransition, event.Sequence, s.Version)
+	}
+	if s.Version == 0 && event.Type != EventCreated {
+		return fmt.Errorf("%w: stream must start with %s, got %s", ErrInvalidTransition, EventCreated, event.Type)
+	}
+	if s.Version > 0 {
+		if err := CheckTransition(s.Status, event.Type); err != nil {
+			return err
+		}
+	}
+
+	next := *s
+	at := event.OccurredAt
+	switch event.Type {
+	case EventCreated:
+		next.BookingID = event.BookingID
+		next.Status = StatusPending
+		next.CreatedAt = at
+		next.applyTerms(event.Data)
+		if event.Data.Status != "" {
+			next.Status = event.Data.Status
+		}
+		if next.Status == StatusConfirmed {
+			next.ConfirmedAt = &at
+		}
+	case EventApproved:
+		next.Status = StatusConfirmed
+		if next.ConfirmedAt == nil {
+			next.ConfirmedAt = &at
+		}
+	case EventActivated:
+		next.Status = StatusActive
+		if next.ActivatedAt == nil {
+			next.ActivatedAt = &at
+		}
+	case EventAmended:
+		next.applyTerms(event.Data)
+	case EventPaused:
+		next.Status = StatusPaused
+	case EventCompleted, EventExpired:
+		next.Status = transitions[event.Type].to
+		next.EndedAt = &at
+	case EventCancelled:
+		next.Status = StatusCancelled
+		next.CancelledAt = &at
+	default:
+		return fmt.Errorf("%w: unknown event type %q", ErrInvalidTransition, event.Type)
+	}
+
+	next.UpdatedAt = at
+	next.Version = event.Sequence
+	*s = next
+	return nil
+}
+
+// CheckTransition reports whether an event may follow a booking in the given
+// status. Nothing follows a completed, cancelled or expired booking. Paused
+// bookings resume by being approved or activated again, and only bookings
+// that never went live can expire.
+func CheckTransition(status, eventType string) error {
+	if Terminal(status) {
+		return fmt.Errorf("%w: booking is %s", ErrInvalidTransition, status)
+	}
+	switch eventType {
+	case EventCreated:
+		return fmt.Errorf("%w: booking already created", ErrInvalidTransition)
+	case EventAmended:
+		return nil
+	}
+
+	transition, ok := transitions[eventType]
+	if !ok {
+		return fmt.Errorf("%w: unknown event type %q", ErrInvalidTransition, eventType)
+	}
+	for _, from := range transition.from {
+		if status == from {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: cannot move a %s booking to %s", ErrInvalidTransition, status, transition.to)
+}
+
+// DeliveryEvents returns the events a booking in status goes through when it
+// counts its delivered-th impression: a confirmed booking goes live with its
+// first impression, and the impression delivering maxImpressions completes it.
+// Bookings without a cap never complete this way.
+func DeliveryEvents(status string, delivered, maxImpressions int) []string {
+	var events []string
+	if status == StatusConfirmed {
+		events = append(events, EventActivated)
+		status = StatusActive
+	}
+	if maxImpressions > 0 && delivered >= maxImpressions && CheckTransition(status, EventCompleted) == nil {
+		events = append(events, EventCompleted)
+	}
+	return events
+}
+
+// applyTerms copies the fields set in a change onto the state
+func (s *State) applyTerms(change Change) {
+	if change.SurfaceID != "" {
+		s.SurfaceID = change.SurfaceID
+	}
+	if change.AdvertiserID != "" {
+		s.AdvertiserID = change.AdvertiserID
+	}
+	if change.CampaignID != "" {
+		s.CampaignID = change.CampaignID
+	}
+	if change.BidAmountCPM != nil {
+		s.BidAmountCPM = *change.BidAmountCPM
+	}
+	if change.MaxImpressions != nil {
+		s.MaxImpressions = *change.MaxImpressions
+	}
+	if change.MinPRSScore != nil {
+		s.MinPRSScore = *change.MinPRSScore
+	}
+}
@@ -0,0 +1,55 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deliver counts impressions from to through of a booking capped at
+// maxImpressions, applying the events each one brings, and returns its state
+// after the last
+func deliver(t *testing.T, state *State, from, through, maxImpressions int) *State {
+	t.Helper()
+	for delivered := from; delivered <= through; delivered++ {
+		for _, eventType := range DeliveryEvents(state.Status, delivered, maxImpressions) {
+			event := Event{BookingID: state.BookingID, Sequence: state.Version + 1, Type: eventType, OccurredAt: time.Now()}
+			require.NoError(t, state.Apply(event), "impression %d", delivered)
+		}
+	}
+	return state
+}
+
+func TestDeliveryEvents_CompletesConfirmedBookings(t *testing.T) {
+	confirmed := func(maxImpressions int) *State {
+		state, err := Project([]Event{{
+			BookingID:  "booking_1",
+			Sequence:   1,
+			Type:       EventCreated,
+			Data:       Change{Status: StatusConfirmed, MaxImpressions: &maxImpressions},
+			OccurredAt: time.Now(),
+		}})
+		require.NoError(t, err)
+		return state
+	}
+
+	assert.Equal(t, []string{EventActivated}, DeliveryEvents(StatusConfirmed, 1, 3),
+		"A confirmed booking goes live with its first impression")
+	state := deliver(t, confirmed(3), 1, 2, 3)
+	assert.Equal(t, StatusActive, state.Status)
+	assert.NotNil(t, state.ActivatedAt)
+
+	state = deliver(t, state, 3, 3, 3)
+	assert.Equal(t, StatusCompleted, state.Status, "The impression delivering the cap completes a fresh booking")
+	assert.NotNil(t, state.EndedAt)
+
+	assert.Equal(t, []string{EventActivated, EventCompleted}, DeliveryEvents(StatusConfirmed, 1, 1),
+		"A booking capped at one impression goes live and completes with it")
+	assert.Equal(t, StatusCompleted, deliver(t, confirmed(1), 1, 1, 1).Status)
+
+	assert.Empty(t, DeliveryEvents(StatusActive, 10, 0), "Bookings without a cap never complete")
+	assert.Empty(t, DeliveryEvents(StatusPending, 3, 3), "Pending bookings cannot complete")
+	assert.Equal(t, []string{EventCompleted}, DeliveryEvents(StatusPaused, 3, 3))
+}
@@ -0,0 +1,87 @@
+package broadcast
+
+import (
+	"encoding/xml"
+	"math"
+)
+
+// ATSC 3.0 document names
+const (
+	ATSC3HELD = "held.xml"
+	ATSC3AEI  = "aei.xml"
+)
+
+// aeiTimescale is the ticks per second of AEI presentation times
+const aeiTimescale = 1000
+
+// held is an HTML Entry pages Location Description (A/337 5.3) with one
+// broadband-delivered entry package
+type held struct {
+	XMLName xml.Name `xml:"HELD"`
+	NS      string   `xml:"xmlns,attr"`
+	Package struct {
+		AppContextID      string `xml:"appContextId,attr"`
+		AppRendering      bool   `xml:"appRendering,attr"`
+		BbandEntryPageURL string `xml:"bbandEntryPageUrl,attr"`
+	} `xml:"HTMLEntryPackage"`
+}
+
+// aeiEventStream is an application event stream (A/337 6.3.2), in the DASH
+// EventStream form carried in the MPD period of the title
+type aeiEventStream struct {
+	XMLName     xml.Name   `xml:"EventStream"`
+	NS          string     `xml:"xmlns,attr"`
+	SchemeIDURI string     `xml:"schemeIdUri,attr"`
+	Value       string     `xml:"value,attr"`
+	Timescale   int        `xml:"timescale,attr"`
+	Events      []aeiEvent `xml:"Event"`
+}
+
+type aeiEvent struct {
+	PresentationTime int64  `xml:"presentationTime,attr"`
+	Duration         int64  `xml:"duration,attr"`
+	ID               int    `xml:"id,attr"`
+	Data             string `xml:",chardata"`
+}
+
+// atsc3Documents returns the HELD naming the application and the AEI
+// event stream carrying the cues
+func atsc3Documents(cues []Cue, opts Options) ([]Document, error) {
+	var entry held
+	entry.NS = "tag:atsc.org,2016:XMLSchemas/ATSC3/AppSignaling/HELD/1.0/"
+	entry.Package.AppContextID = opts.AppContextID
+	if entry.Package.AppContextID == "" {
+		entry.Package.AppContextID = DefaultAppContextID
+	}
+	entry.Package.AppRendering = true
+	entry.Package.BbandEntryPageURL = opts.AppURL
+
+	stream := aeiEventStream{
+		NS:          "urn:mpeg:dash:schema:mpd:2011",
+		SchemeIDURI: SchemeIDURI,
+		Value:       EventName,
+		Timescale:   aeiTimescale,
+		Events:      make([]aeiEvent, len(cues)),
+	}
+	for i, cue := range cues {
+		stream.Events[i] = aeiEvent{
+			PresentationTime: int64(math.Round(cue.Time * aeiTimescale)),
+			Duration:         int64(math.Round(cue.Duration * aeiTimescale)),
+			ID:               cue.EventID,
+			Data:             cue.Payload,
+		}
+	}
+
+	heldBody, err := marshalXML(entry)
+	if err != nil {
+		return nil, err
+	}
+	aeiBody, err := marshalXML(stream)
+	if err != nil {
+		return nil, err
+	}
+	return []Document{
+		{Name: ATSC3HELD, ContentType: "application/xml", Body: heldBody},
+		{Name: ATSC3AEI, ContentType: "application/xml", Body: aeiBody},
+	}, nil
+}
@@ -0,0 +1,184 @@
+// Package broadcast converts the placement windows of a title into the
+// signaling hybrid broadcast receivers act on, so broadcast partners can cue
+// placements alongside the linear feed.
+//
+// HbbTV receivers are signaled with an XML AIT launching the partner's
+// interactive application and a DSM-CC stream event per window (ETSI TS 102
+// 809). ATSC 3.0 receivers get a HELD naming the broadcaster application and
+// an application event stream carrying an AEI event per window (A/337).
+// Either way the application receives each placement's booking and surface
+// when its window starts.
+package broadcast
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+)
+
+// Signaling formats
+const (
+	FormatHbbTV = "hbbtv"
+	FormatATSC3 = "atsc3"
+)
+
+// Formats lists the signaling formats Build accepts
+var Formats = []string{FormatHbbTV, FormatATSC3}
+
+// EventName names placement events: the HbbTV stream event, and the value
+// of the ATSC 3.0 event stream
+const EventName = "inscenium_placement"
+
+// SchemeIDURI identifies the ATSC 3.0 event stream placement events are
+// carried in; applications subscribe to it
+const SchemeIDURI = "urn:inscenium:placement:2024"
+
+// DefaultAppContextID identifies the ATSC 3.0 application context unless
+// the partner names their own
+const DefaultAppContextID = "urn:inscenium:app:placements"
+
+// Window is a booked placement as it plays out in a title, in seconds of
+// media time from the start of the title
+type Window struct {
+	BookingID       string  `json:"booking_id"`
+	SurfaceID       string  `json:"surface_id"`
+	CampaignID      string  `json:"campaign_id,omitempty"`
+	CreativeAssetID string  `json:"creative_asset_id,omitempty"`
+	PlacementType   string  `json:"placement_type"`
+	Start           float64 `json:"start_time"`
+	End             float64 `json:"end_time"`
+}
+
+// Options describe the partner's interactive application receivers run to
+// render placements
+type Options struct {
+	AppURL         string // Entry page of the application
+	AppName        string
+	OrganisationID uint32 // HbbTV: DVB-registered organisation_id
+	ApplicationID  uint16 // HbbTV: application_id within the organisation
+	ComponentTag   uint8  // HbbTV: component carrying the stream event descriptors
+	AppContextID   string // ATSC 3.0: application context, DefaultAppContextID when empty
+}
+
+// Validate checks the options can be signaled
+func (o Options) Validate() error {
+	u, err := url.Parse(o.AppURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("app_url must be an absolute http or https URL")
+	}
+	if o.ApplicationID == 0 {
+		return errors.New("application_id must be between 1 and 65535")
+	}
+	return nil
+}
+
+// Cue is the event marking a window. Playout inserts HbbTV cues as stream
+// event descriptors at their time; ATSC 3.0 cues are already in the AEI.
+type Cue struct {
+	EventID   int     `json:"event_id"`
+	EventName string  `json:"event_name"`
+	BookingID string  `json:"booking_id"`
+	Time      float64 `json:"time"`     // Media seconds the event fires at
+	Duration  float64 `json:"duration"` // Seconds the window lasts
+	Payload   string  `json:"payload"`  // Private data handed to the application
+}
+
+// Document is a signaling payload delivered to receivers
+type Document struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// Signaling is everything a partner needs to signal a title's placements in
+// one format
+type Signaling struct {
+	Format    string     `json:"format"`
+	Documents []Document `json:"documents"`
+	Cues      []Cue      `json:"cues"`
+}
+
+// Document returns the document named name, if any
+func (s *Signaling) Document(name string) (Document, bool) {
+	for _, d := range s.Documents {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Document{}, false
+}
+
+// ErrUnknownFormat is returned for formats other than those in Formats
+var ErrUnknownFormat = errors.New("unknown signaling format")
+
+// Build returns the signaling of windows in format. Cues follow the order
+// of windows, numbered from 1.
+func Build(format string, windows []Window, opts Options) (*Signaling, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	cues := make([]Cue, len(windows))
+	for i, w := range windows {
+		payload, err := json.Marshal(cuePayload{
+			BookingID:       w.BookingID,
+			SurfaceID:       w.SurfaceID,
+			CreativeAssetID: w.CreativeAssetID,
+			PlacementType:   w.PlacementType,
+			Duration:        roundMillis(w.End - w.Start),
+		})
+		if err != nil {
+			return nil, err
+		}
+		cues[i] = Cue{
+			EventID:   i + 1,
+			EventName: EventName,
+			BookingID: w.BookingID,
+			Time:      roundMillis(w.Start),
+			Duration:  roundMillis(w.End - w.Start),
+			Payload:   string(payload),
+		}
+	}
+
+	var documents []Document
+	var err error
+	switch format {
+	case FormatHbbTV:
+		documents, err = hbbtvDocuments(opts)
+	case FormatATSC3:
+		documents, err = atsc3Documents(cues, opts)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Signaling{Format: format, Documents: documents, Cues: cues}, nil
+}
+
+// cuePayload is the private data of a cue. HbbTV stream event descriptors
+// have little room, so it names the placement rather than describing it.
+type cuePayload struct {
+	BookingID       string  `json:"booking_id"`
+	SurfaceID       string  `json:"surface_id"`
+	CreativeAssetID string  `json:"creative_asset_id,omitempty"`
+	PlacementType   string  `json:"placement_type,omitempty"`
+	Duration        float64 `json:"duration"`
+}
+
+// roundMillis rounds seconds to the millisecond
+func roundMillis(seconds float64) float64 {
+	return math.Round(seconds*1000) / 1000
+}
+
+// marshalXML renders v as an XML document
+func marshalXML(v interface{}) (string, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to render signaling: %w", err)
+	}
+	return xml.Header + string(body) + "\n", nil
+}
@@ -0,0 +1,131 @@
+package broadcast
+
+import (
+	"encoding/xml"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// HbbTV document names and content types
+const (
+	HbbTVAIT          = "ait.xml"
+	HbbTVStreamEvents = "stream_events.xml"
+
+	aitContentType = "application/vnd.dvb.ait+xml"
+)
+
+// streamEventID is the stream_event_id placement events are sent with
+const streamEventID = 1
+
+// aitServiceDiscovery is an XML AIT (TS 102 809 5.4) with one application
+type aitServiceDiscovery struct {
+	XMLName     xml.Name `xml:"mhp:ServiceDiscovery"`
+	MHP         string   `xml:"xmlns:mhp,attr"`
+	XSI         string   `xml:"xmlns:xsi,attr"`
+	Application struct {
+		Name       aitName `xml:"mhp:appName"`
+		Identifier struct {
+			OrgID uint32 `xml:"mhp:orgId"`
+			AppID uint16 `xml:"mhp:appId"`
+		} `xml:"mhp:applicationIdentifier"`
+		Descriptor struct {
+			Type         string `xml:"mhp:type>mhp:OtherApp"`
+			ControlCode  string `xml:"mhp:controlCode"`
+			Visibility   string `xml:"mhp:visibility"`
+			ServiceBound bool   `xml:"mhp:serviceBound"`
+			Priority     int    `xml:"mhp:priority"`
+			Version      int    `xml:"mhp:version"`
+		} `xml:"mhp:applicationDescriptor"`
+		Transport struct {
+			Type    string `xml:"xsi:type,attr"`
+			URLBase string `xml:"mhp:URLBase"`
+		} `xml:"mhp:applicationTransport"`
+		Location string `xml:"mhp:applicationLocation"`
+	} `xml:"mhp:ApplicationDiscovery>mhp:ApplicationList>mhp:Application"`
+}
+
+type aitName struct {
+	Language string `xml:"Language,attr"`
+	Name     string `xml:",chardata"`
+}
+
+// dsmccStreamEvents describes the stream events of a DSM-CC stream event
+// object (TS 102 809 8.2), which applications pass to
+// addStreamEventListener to resolve event names
+type dsmccStreamEvents struct {
+	XMLName xml.Name `xml:"dsmcc"`
+	NS      string   `xml:"xmlns,attr"`
+	Object  struct {
+		ComponentTag string `xml:"component_tag,attr"`
+		Event        struct {
+			ID   int    `xml:"stream_event_id,attr"`
+			Name string `xml:"stream_event_name,attr"`
+		} `xml:"stream_event"`
+	} `xml:"dsmcc_object"`
+}
+
+// hbbtvDocuments returns the AIT launching the application and the
+// description of the stream events cues are sent as
+func hbbtvDocuments(opts Options) ([]Document, error) {
+	base, location := splitAppURL(opts.AppURL)
+
+	var ait aitServiceDiscovery
+	ait.MHP = "urn:dvb:mhp:2009"
+	ait.XSI = "http://www.w3.org/2001/XMLSchema-instance"
+	app := &ait.Application
+	app.Name = aitName{Language: "eng", Name: appName(opts)}
+	app.Identifier.OrgID = opts.OrganisationID
+	app.Identifier.AppID = opts.ApplicationID
+	app.Descriptor.Type = "application/vnd.hbbtv.xhtml+xml"
+	app.Descriptor.ControlCode = "AUTOSTART"
+	app.Descriptor.Visibility = "VISIBLE_ALL"
+	app.Descriptor.ServiceBound = true
+	app.Descriptor.Priority = 1
+	app.Descriptor.Version = 1
+	app.Transport.Type = "mhp:HTTPTransportType"
+	app.Transport.URLBase = base
+	app.Location = location
+
+	var events dsmccStreamEvents
+	events.NS = "urn:dvb:mis:dsmcc:2009"
+	events.Object.ComponentTag = "0x" + strconv.FormatUint(uint64(opts.ComponentTag), 16)
+	events.Object.Event.ID = streamEventID
+	events.Object.Event.Name = EventName
+
+	aitBody, err := marshalXML(ait)
+	if err != nil {
+		return nil, err
+	}
+	eventsBody, err := marshalXML(events)
+	if err != nil {
+		return nil, err
+	}
+	return []Document{
+		{Name: HbbTVAIT, ContentType: aitContentType, Body: aitBody},
+		{Name: HbbTVStreamEvents, ContentType: "application/xml", Body: eventsBody},
+	}, nil
+}
+
+// splitAppURL splits an entry page URL into the AIT's URL base, ending in a
+// slash, and the location relative to it
+func splitAppURL(appURL string) (string, string) {
+	u, _ := url.Parse(appURL) // Validated by Options.Validate
+	dir, file := path.Split(u.EscapedPath())
+	if dir == "" {
+		dir = "/"
+	}
+	location := file
+	if u.RawQuery != "" {
+		location += "?" + u.RawQuery
+	}
+	return u.Scheme + "://" + u.Host + dir, location
+}
+
+// appName names the application in signaling
+func appName(opts Options) string {
+	if opts.AppName != "" {
+		return opts.AppName
+	}
+	return "Inscenium placements"
+}
@@ -0,0 +1,76 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+)
+
+// ErrExhausted is returned when a charge would take a campaign past its budget
+var ErrExhausted = errors.New("campaign budget is exhausted")
+
+// ErrUnknownBooking is returned when charging a booking that does not exist
+var ErrUnknownBooking = errors.New("booking not found")
+
+// DefaultSafetyMargin is the share of each budget withheld from the fast
+// counter, so drift between reconciliations cannot overspend
+const DefaultSafetyMargin = 0.01
+
+// Budget caps what a campaign may spend. Spent is the authoritative spend
+// recorded in Postgres.
+type Budget struct {
+	CampaignID string    `json:"campaign_id"`
+	Amount     float64   `json:"amount"`
+	Spent      float64   `json:"spent"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks that the budget is well formed
+func (b *Budget) Validate() error {
+	if b.Amount <= 0 || math.IsInf(b.Amount, 0) || math.IsNaN(b.Amount) {
+		return fmt.Errorf("amount must be positive")
+	}
+	return nil
+}
+
+// Remaining is the budget left at the authoritative spend
+func (b *Budget) Remaining() float64 {
+	return b.Amount - b.Spent
+}
+
+// Charge is what serving one impression of a booking costs its campaign.
+// Bookings billed on measured exposures cost nothing when served, but may
+// only be served while their most expensive exposure still fits.
+type Charge struct {
+	BookingID  string
+	CampaignID string
+	Terms      billing.Terms
+	Cost       int64 // Micros
+	Required   int64 // Micros that must remain for the charge to fit
+}
+
+// Amount is the charge in currency units
+func (c *Charge) Amount() float64 {
+	return FromMicros(c.Cost)
+}
+
+// Micros converts a currency amount to millionths, the unit budget counters keep
+func Micros(amount float64) int64 {
+	return int64(math.Round(amount * 1e6))
+}
+
+// FromMicros converts millionths back to a currency amount
+func FromMicros(micros int64) float64 {
+	return float64(micros) / 1e6
+}
+
+// Store reads budgets and the authoritative spend against them
+type Store interface {
+	GetBudget(campaignID string) (*Budget, error)
+	ListBudgets() ([]Budget, error)
+	GetBookingCharge(bookingID string) (*Charge, error)
+}
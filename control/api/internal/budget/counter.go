@@ -0,0 +1,146 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// errNotLoaded is returned when a campaign's counter is not in Redis yet
+var errNotLoaded = errors.New("budget counter not loaded")
+
+// unlimited marks campaigns without a budget so they skip Postgres on every charge
+const unlimited = "unlimited"
+
+// unlimitedTTL bounds how long a campaign stays marked as having no budget
+const unlimitedTTL = 5 * time.Minute
+
+// chargeScript takes cost from a counter unless less than required remains.
+// It returns nil when the counter is not loaded, otherwise
+// {1 if charged, remaining}; remaining is -1 for campaigns without a budget.
+// KEYS: counter. ARGV: cost, required.
+var chargeScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+if value == 'unlimited' then
+	return {1, -1}
+end
+local remaining = tonumber(value)
+local cost = tonumber(ARGV[1])
+if remaining < tonumber(ARGV[2]) then
+	return {0, remaining}
+end
+return {1, redis.call('DECRBY', KEYS[1], cost)}
+`)
+
+// refundScript gives cost back to a loaded counter.
+// KEYS: counter. ARGV: cost.
+var refundScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value or value == 'unlimited' then
+	return 0
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+// resetScript sets a counter only if it still holds the value reconciliation read.
+// KEYS: counter. ARGV: expected, value.
+var resetScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return 1
+`)
+
+// RedisCounter keeps each campaign's remaining budget in micros in Redis so
+// decisions can be charged atomically without a Postgres round trip
+type RedisCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCounter creates a budget counter backed by Redis
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+func counterKey(campaignID string) string {
+	return "budget:{" + campaignID + "}:remaining"
+}
+
+// charge takes cost micros from a campaign's counter if at least required
+// remain. It reports whether the charge fit and the micros remaining after it.
+func (c *RedisCounter) charge(ctx context.Context, campaignID string, cost, required int64) (bool, int64, error) {
+	result, err := chargeScript.Run(ctx, c.client, []string{counterKey(campaignID)}, cost, required).Int64Slice()
+	if errors.Is(err, redis.Nil) {
+		return false, 0, errNotLoaded
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to charge budget counter: %w", err)
+	}
+	return result[0] == 1, result[1], nil
+}
+
+// refund gives cost micros back to a campaign's counter
+func (c *RedisCounter) refund(ctx context.Context, campaignID string, cost int64) error {
+	if err := refundScript.Run(ctx, c.client, []string{counterKey(campaignID)}, cost).Err(); err != nil {
+		return fmt.Errorf("failed to refund budget counter: %w", err)
+	}
+	return nil
+}
+
+// load seeds a campaign's counter unless another instance already has.
+// A nil remaining marks the campaign as having no budget.
+func (c *RedisCounter) load(ctx context.Context, campaignID string, remaining *int64) error {
+	var err error
+	if remaining == nil {
+		err = c.client.SetNX(ctx, counterKey(campaignID), unlimited, unlimitedTTL).Err()
+	} else {
+		err = c.client.SetNX(ctx, counterKey(campaignID), *remaining, 0).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load budget counter: %w", err)
+	}
+	return nil
+}
+
+// get reads a campaign's counter. It reports false when the counter is not
+// loaded or the campaign has no budget.
+func (c *RedisCounter) get(ctx context.Context, campaignID string) (int64, bool, error) {
+	value, err := c.client.Get(ctx, counterKey(campaignID)).Result()
+	if errors.Is(err, redis.Nil) || value == unlimited {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read budget counter: %w", err)
+	}
+	remaining, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("budget counter for %s is corrupt: %w", campaignID, err)
+	}
+	return remaining, true, nil
+}
+
+// reset sets a campaign's counter if it still holds expected. It reports
+// whether the counter was set.
+func (c *RedisCounter) reset(ctx context.Context, campaignID string, expected, remaining int64) (bool, error) {
+	set, err := resetScript.Run(ctx, c.client, []string{counterKey(campaignID)}, expected, remaining).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to reset budget counter: %w", err)
+	}
+	return set == 1, nil
+}
+
+// clear drops a campaign's counter so the next charge reloads it
+func (c *RedisCounter) clear(ctx context.Context, campaignID string) error {
+	if err := c.client.Del(ctx, counterKey(campaignID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear budget counter: %w", err)
+	}
+	return nil
+}
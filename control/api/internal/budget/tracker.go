@@ -0,0 +1,275 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Tracker charges served decisions against campaign budgets. The fast path
+// is a Redis counter seeded from the spend recorded in Postgres less a
+// safety margin; without Redis, or when it fails, every charge checks
+// Postgres instead.
+type Tracker struct {
+	store   Store
+	counter *RedisCounter
+	margin  float64
+	pricing billing.Pricing
+}
+
+// NewTracker creates a budget tracker. counter may be nil to always check Postgres.
+func NewTracker(store Store, counter *RedisCounter, margin float64) *Tracker {
+	return &Tracker{store: store, counter: counter, margin: margin}
+}
+
+// SetPricing sets the attention bands attention-billed bookings are priced
+// with. Without bands their exposures are priced at the bid.
+func (t *Tracker) SetPricing(pricing billing.Pricing) {
+	t.pricing = pricing
+}
+
+// seed is the micros a campaign's counter should hold at its authoritative spend
+func (t *Tracker) seed(b *Budget) int64 {
+	return Micros(b.Amount*(1-t.margin)) - Micros(b.Spent)
+}
+
+// ImpressionCost is the budget that must remain for Charge to serve an
+// impression of a booking billed on terms
+func (t *Tracker) ImpressionCost(terms billing.Terms) float64 {
+	return t.pricing.ImpressionPrice(terms)
+}
+
+// Seed is what charges are checked against without the Redis counter, and
+// what a newly loaded counter holds: the budget less the safety margin and
+// the authoritative spend
+func (t *Tracker) Seed(b *Budget) float64 {
+	return FromMicros(t.seed(b))
+}
+
+// Charge charges one impression of a booking to its campaign's budget.
+// Campaigns without a budget are never exhausted. The returned charge is
+// recorded with the decision, or refunded if recording fails. Bookings billed
+// on measured exposures are charged nothing here, see ChargeExposure.
+func (t *Tracker) Charge(ctx context.Context, bookingID string) (*Charge, error) {
+	charge, err := t.store.GetBookingCharge(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if charge == nil {
+		return nil, ErrUnknownBooking
+	}
+	charge.Required = Micros(t.pricing.ImpressionPrice(charge.Terms))
+	if !billing.Measured(charge.Terms.Model) {
+		charge.Cost = charge.Required
+	}
+
+	if t.counter != nil {
+		err := t.chargeCounter(ctx, charge)
+		if err == nil || errors.Is(err, ErrExhausted) {
+			return charge, err
+		}
+		logrus.WithError(err).WithField("campaign_id", charge.CampaignID).Warn("Budget counter unavailable, checking Postgres")
+	}
+	return charge, t.chargeStore(charge)
+}
+
+// chargeCounter charges the Redis counter, loading it on first use
+func (t *Tracker) chargeCounter(ctx context.Context, charge *Charge) error {
+	ok, _, err := t.counter.charge(ctx, charge.CampaignID, charge.Cost, charge.Required)
+	if errors.Is(err, errNotLoaded) {
+		if err := t.load(ctx, charge.CampaignID); err != nil {
+			return err
+		}
+		ok, _, err = t.counter.charge(ctx, charge.CampaignID, charge.Cost, charge.Required)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		metrics.BudgetCharges.WithLabelValues("redis", "exhausted").Inc()
+		return ErrExhausted
+	}
+	metrics.BudgetCharges.WithLabelValues("redis", "charged").Inc()
+	return nil
+}
+
+// chargeStore checks a charge against the spend recorded in Postgres. The
+// spend is recorded with the decision, so there is nothing to decrement.
+func (t *Tracker) chargeStore(charge *Charge) error {
+	b, err := t.store.GetBudget(charge.CampaignID)
+	if err != nil {
+		return err
+	}
+	if b != nil && t.seed(b) < charge.Required {
+		metrics.BudgetCharges.WithLabelValues("postgres", "exhausted").Inc()
+		return ErrExhausted
+	}
+	metrics.BudgetCharges.WithLabelValues("postgres", "charged").Inc()
+	return nil
+}
+
+// load seeds a campaign's counter from Postgres
+func (t *Tracker) load(ctx context.Context, campaignID string) error {
+	b, err := t.store.GetBudget(campaignID)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return t.counter.load(ctx, campaignID, nil)
+	}
+	remaining := t.seed(b)
+	return t.counter.load(ctx, campaignID, &remaining)
+}
+
+// ChargeExposure charges a measured exposure of a booking billed on measured
+// exposures to its campaign's budget, taking the cost only if it fits in
+// what is left. It returns nil for other bookings, which were charged when
+// served. An exposure that does not fit returns its charge with
+// ErrExhausted; it was already shown, so it is recorded uncounted and free.
+// A returned charge is recorded with the exposure, or refunded if recording
+// fails or does not count it.
+func (t *Tracker) ChargeExposure(ctx context.Context, bookingID string, exposure billing.Exposure) (*Charge, error) {
+	charge, err := t.store.GetBookingCharge(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if charge == nil {
+		return nil, ErrUnknownBooking
+	}
+	if !billing.Measured(charge.Terms.Model) {
+		return nil, nil
+	}
+	charge.Cost = Micros(t.pricing.ExposurePrice(charge.Terms, exposure))
+	charge.Required = charge.Cost
+	if charge.Cost == 0 {
+		return charge, nil
+	}
+
+	if t.counter != nil {
+		err := t.chargeCounter(ctx, charge)
+		if err == nil || errors.Is(err, ErrExhausted) {
+			return charge, err
+		}
+		logrus.WithError(err).WithField("campaign_id", charge.CampaignID).Warn("Budget counter unavailable, checking Postgres")
+	}
+	return charge, t.chargeStore(charge)
+}
+
+// Refund returns a charge whose decision or exposure could not be recorded
+func (t *Tracker) Refund(ctx context.Context, charge *Charge) {
+	if t.counter == nil || charge.Cost == 0 {
+		return
+	}
+	if err := t.counter.refund(ctx, charge.CampaignID, charge.Cost); err != nil {
+		// Reconciliation restores it from Postgres
+		logrus.WithError(err).WithField("campaign_id", charge.CampaignID).Warn("Failed to refund budget counter")
+	}
+}
+
+// Reset drops a campaign's counter after its budget changes, so the next
+// charge reloads it
+func (t *Tracker) Reset(ctx context.Context, campaignID string) error {
+	if t.counter == nil {
+		return nil
+	}
+	return t.counter.clear(ctx, campaignID)
+}
+
+// CounterRemaining reads what a campaign's Redis counter holds. It reports
+// false when the counter is not loaded.
+func (t *Tracker) CounterRemaining(ctx context.Context, campaignID string) (float64, bool, error) {
+	if t.counter == nil {
+		return 0, false, nil
+	}
+	remaining, ok, err := t.counter.get(ctx, campaignID)
+	return FromMicros(remaining), ok, err
+}
+
+// Reconcile resets every loaded counter that drifted from the spend recorded
+// in Postgres. A counter is read before its spend, and only reset if no
+// charge touched it in between, so a reset never loses a charge made after
+// the spend was read. Charges still being recorded when the spend is read
+// are covered by the safety margin.
+func (t *Tracker) Reconcile(ctx context.Context) (int, error) {
+	if t.counter == nil {
+		return 0, nil
+	}
+	budgets, err := t.store.ListBudgets()
+	if err != nil {
+		return 0, err
+	}
+
+	corrected := 0
+	for _, listed := range budgets {
+		current, loaded, err := t.counter.get(ctx, listed.CampaignID)
+		if err != nil {
+			return corrected, err
+		}
+		if !loaded {
+			continue
+		}
+		b, err := t.store.GetBudget(listed.CampaignID)
+		if err != nil {
+			return corrected, err
+		}
+		if b == nil {
+			continue // Deleted since listing; its counter was cleared
+		}
+
+		want := t.seed(b)
+		drift := current - want
+		metrics.BudgetDrift.Observe(math.Abs(FromMicros(drift)))
+		if drift == 0 {
+			continue
+		}
+		reset, err := t.counter.reset(ctx, b.CampaignID, current, want)
+		if err != nil {
+			return corrected, err
+		}
+		if !reset {
+			continue // Charged meanwhile; the next run looks again
+		}
+		corrected++
+		metrics.BudgetCorrections.Inc()
+		logrus.WithFields(logrus.Fields{
+			"campaign_id": b.CampaignID,
+			"drift":       FromMicros(drift),
+			"remaining":   FromMicros(want),
+		}).Info("Reset drifted budget counter")
+	}
+	return corrected, nil
+}
+
+// Worker reconciles budget counters with Postgres on a schedule
+type Worker struct {
+	tracker  *Tracker
+	interval time.Duration
+}
+
+// NewWorker creates a budget reconciliation worker
+func NewWorker(tracker *Tracker, interval time.Duration) *Worker {
+	return &Worker{tracker: tracker, interval: interval}
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.tracker.Reconcile(ctx); err != nil {
+			logrus.WithError(err).Error("Budget counter reconciliation failed")
+		}
+	}
+}
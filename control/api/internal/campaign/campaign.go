@@ -0,0 +1,133 @@
+// Package campaign holds advertisers and their campaigns. Every booking
+// names a campaign, which must exist, belong to the booking's advertiser and
+// still be running when the booking is made.
+package campaign
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+)
+
+// Statuses of advertisers and campaigns. Archived ones keep their bookings
+// and history but cannot be booked.
+const (
+	StatusActive   = "active"
+	StatusArchived = "archived"
+)
+
+// maxNameLength matches the name columns
+const maxNameLength = 255
+
+// ErrNotBookable is returned when a booking names a campaign that does not
+// exist, is archived, has ended or belongs to another advertiser
+var ErrNotBookable = errors.New("campaign cannot be booked")
+
+// idPattern matches advertiser and campaign IDs, which callers choose so
+// they can keep the IDs bookings already use
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:-]{0,99}$`)
+
+// ValidateID checks an advertiser or campaign ID
+func ValidateID(field, id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%s must be 1-100 letters, digits or . _ : -, starting with a letter or digit", field)
+	}
+	return nil
+}
+
+// validateName checks a display name
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is required")
+	}
+	if len(name) > maxNameLength {
+		return errors.New("name must be at most 255 characters")
+	}
+	return nil
+}
+
+// Advertiser is a brand that campaigns are run for
+type Advertiser struct {
+	AdvertiserID string     `json:"advertiser_id"`
+	Name         string     `json:"name"`
+	OrgID        string     `json:"org_id,omitempty"` // Owning organization; empty for advertisers visible to all
+	Status       string     `json:"status"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+}
+
+// Validate checks that the advertiser can be saved
+func (a *Advertiser) Validate() error {
+	if err := ValidateID("advertiser_id", a.AdvertiserID); err != nil {
+		return err
+	}
+	return validateName(a.Name)
+}
+
+// Filter narrows a campaign listing
+type Filter struct {
+	AdvertiserID    string
+	IncludeArchived bool
+}
+
+// Campaign is a flight of bookings for an advertiser, optionally capped by a
+// budget kept with the campaign's other spend caps
+type Campaign struct {
+	CampaignID   string       `json:"campaign_id"`
+	AdvertiserID string       `json:"advertiser_id"`
+	Name         string       `json:"name"`
+	OrgID        string       `json:"org_id,omitempty"` // Owning organization
+	Status       string       `json:"status"`
+	Budget       *float64     `json:"budget,omitempty"` // Spend cap; spend is reported under /campaigns/:id/budget
+	FlightStart  *time.Time   `json:"flight_start,omitempty"`
+	FlightEnd    *time.Time   `json:"flight_end,omitempty"`    // Bookings are refused once it has passed
+	FrequencyCap *freqcap.Cap `json:"frequency_cap,omitempty"` // Exposures per viewer across the campaign's bookings
+	CreatedBy    string       `json:"created_by,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	ArchivedAt   *time.Time   `json:"archived_at,omitempty"`
+}
+
+// Validate checks that the campaign can be saved
+func (c *Campaign) Validate() error {
+	if err := ValidateID("campaign_id", c.CampaignID); err != nil {
+		return err
+	}
+	if err := ValidateID("advertiser_id", c.AdvertiserID); err != nil {
+		return err
+	}
+	if err := validateName(c.Name); err != nil {
+		return err
+	}
+	if c.Budget != nil && (*c.Budget <= 0 || math.IsInf(*c.Budget, 0) || math.IsNaN(*c.Budget)) {
+		return errors.New("budget must be positive")
+	}
+	if c.FlightStart != nil && c.FlightEnd != nil && !c.FlightEnd.After(*c.FlightStart) {
+		return errors.New("flight_end must be after flight_start")
+	}
+	if c.FrequencyCap != nil {
+		return c.FrequencyCap.Validate()
+	}
+	return nil
+}
+
+// Bookable checks that a booking for advertiserID may be made in the
+// campaign at the given time. Bookings may be made before the flight starts.
+func (c *Campaign) Bookable(advertiserID string, at time.Time) error {
+	switch {
+	case c.ArchivedAt != nil:
+		return fmt.Errorf("%w: campaign %s is archived", ErrNotBookable, c.CampaignID)
+	case c.AdvertiserID != advertiserID:
+		return fmt.Errorf("%w: campaign %s belongs to advertiser %s, not %s", ErrNotBookable, c.CampaignID, c.AdvertiserID, advertiserID)
+	case c.FlightEnd != nil && !at.Before(*c.FlightEnd):
+		return fmt.Errorf("%w: campaign %s ended at %s", ErrNotBookable, c.CampaignID, c.FlightEnd.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
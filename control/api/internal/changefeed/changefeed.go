@@ -0,0 +1,129 @@
+// Package changefeed delivers the booking and surface changes Postgres
+// announces with LISTEN/NOTIFY, so each gateway drops what it caches about
+// a row however the row was changed: through any gateway, by another
+// service or by a migration. Triggers in sgi_schema.sql notify Channel with
+// the table, operation and ID of each changed row; Postgres sends
+// notifications once the changing transaction commits and drops duplicates
+// within one transaction.
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Channel is the notification channel the triggers notify
+const Channel = "serving_changes"
+
+// Tables whose changes are announced
+const (
+	TableBookings = "placement_bookings"
+	TableSurfaces = "surfaces"
+)
+
+// pingInterval is how often an idle connection is checked, so a dead one
+// is noticed and replaced rather than silently missing notifications
+const pingInterval = 90 * time.Second
+
+// Change is a changed row
+type Change struct {
+	Table string `json:"table"`
+	Op    string `json:"op"` // insert, update or delete
+	ID    string `json:"id"` // booking_id or surface_id
+}
+
+// Handler keeps one cache of a table's rows fresh
+type Handler struct {
+	// Changed is called with each change of the table, on the listener's
+	// goroutine, so it must be quick
+	Changed func(ctx context.Context, change Change)
+	// Resync is called when the connection was lost and changes may have
+	// been missed; it should drop everything cached. Optional.
+	Resync func(ctx context.Context)
+}
+
+// Listener dispatches changes to the handlers of their table
+type Listener struct {
+	dsn      string
+	handlers map[string][]Handler
+}
+
+// NewListener creates a listener connecting to the database at dsn
+func NewListener(dsn string) *Listener {
+	return &Listener{dsn: dsn, handlers: make(map[string][]Handler)}
+}
+
+// Handle registers h for changes of table. Handlers must be registered
+// before Run.
+func (l *Listener) Handle(table string, h Handler) {
+	l.handlers[table] = append(l.handlers[table], h)
+}
+
+// Run listens until ctx is cancelled, reconnecting when the connection drops
+func (l *Listener) Run(ctx context.Context) {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			logrus.WithError(err).Warn("Change notification listener disconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			logrus.WithError(err).Warn("Change notification listener failed to connect")
+		case pq.ListenerEventReconnected:
+			logrus.Info("Change notification listener reconnected")
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		logrus.WithError(err).Error("Failed to listen for change notifications")
+		return
+	}
+	logrus.WithField("channel", Channel).Info("Listening for change notifications")
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				// Sent after reconnecting: whatever changed meanwhile is unknown
+				l.resync(ctx)
+				continue
+			}
+			l.dispatch(ctx, n.Extra)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// dispatch hands a notification's change to its table's handlers
+func (l *Listener) dispatch(ctx context.Context, payload string) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil || change.Table == "" {
+		logrus.WithField("payload", payload).Warn("Ignoring malformed change notification")
+		return
+	}
+	metrics.ChangeNotifications.WithLabelValues(change.Table).Inc()
+	for _, h := range l.handlers[change.Table] {
+		h.Changed(ctx, change)
+	}
+}
+
+// resync asks every handler to drop what it caches
+func (l *Listener) resync(ctx context.Context) {
+	metrics.ChangeFeedResyncs.Inc()
+	for _, handlers := range l.handlers {
+		for _, h := range handlers {
+			if h.Resync != nil {
+				h.Resync(ctx)
+			}
+		}
+	}
+}
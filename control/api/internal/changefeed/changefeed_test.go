@@ -0,0 +1,33 @@
+package changefeed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener_Dispatch(t *testing.T) {
+	l := NewListener("")
+	var bookings, surfaces []Change
+	resyncs := 0
+	l.Handle(TableBookings, Handler{
+		Changed: func(ctx context.Context, change Change) { bookings = append(bookings, change) },
+		Resync:  func(ctx context.Context) { resyncs++ },
+	})
+	l.Handle(TableSurfaces, Handler{
+		Changed: func(ctx context.Context, change Change) { surfaces = append(surfaces, change) },
+	})
+
+	ctx := context.Background()
+	l.dispatch(ctx, `{"table": "placement_bookings", "op": "update", "id": "booking_1"}`)
+	l.dispatch(ctx, `{"table": "surfaces", "op": "delete", "id": "surface_1"}`)
+	l.dispatch(ctx, `{"table": "titles", "op": "insert", "id": "title_1"}`)
+	l.dispatch(ctx, `not json`)
+
+	assert.Equal(t, []Change{{Table: TableBookings, Op: "update", ID: "booking_1"}}, bookings)
+	assert.Equal(t, []Change{{Table: TableSurfaces, Op: "delete", ID: "surface_1"}}, surfaces, "Should route changes by table")
+
+	l.resync(ctx)
+	assert.Equal(t, 1, resyncs, "Should ask handlers with a resync to drop their caches")
+}
@@ -0,0 +1,305 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/rollup"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+const queryTimeout = 30 * time.Second
+
+// tenantClause restricts exposure rows to the tenant scope bound by bindTenant.
+// Rows mirrored before organizations existed have an empty org_id.
+const tenantClause = "({all:UInt8} = 1 OR org_id = '' OR has({orgs:Array(String)}, org_id))"
+
+// quoteEscaper escapes organization IDs inside array parameter literals
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// bindTenant sets the query parameters of tenantClause
+func bindTenant(params map[string]string, scope tenant.Scope) map[string]string {
+	params["all"] = "0"
+	if scope.All {
+		params["all"] = "1"
+	}
+	quoted := make([]string, len(scope.Orgs))
+	for i, orgID := range scope.Orgs {
+		quoted[i] = "'" + quoteEscaper.Replace(orgID) + "'"
+	}
+	params["orgs"] = "[" + strings.Join(quoted, ",") + "]"
+	return params
+}
+
+// GetBookingMetrics aggregates the exposure metrics of a booking's events within
+// scope and time range, leaving out invalid and duplicate events as described in
+// package quality. Zero times leave the range open.
+func (c *Client) GetBookingMetrics(scope tenant.Scope, bookingID string, from, to time.Time) (*models.BookingMetrics, error) {
+	params := map[string]string{}
+	where, err := qualityWhere(quality.Filter{Tenant: scope, BookingID: bookingID, From: from, To: to}, true, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			count() AS total_impressions,
+			uniqExact(viewer_id) AS unique_viewers,
+			sum(exposure_duration) AS total_exposure_time,
+			avg(exposure_duration) AS average_exposure_time,
+			avg(instantaneous_prs) AS average_prs_score,
+			avg(attention_score) AS average_attention_score,
+			avg(screen_coverage_percentage) AS average_screen_coverage,
+			avg(listen_through) AS average_listen_through
+		FROM ` + countedEvents(where) + `
+	`
+
+	var rows []models.BookingMetrics
+	if err := c.QueryInto(ctx, query, params, &rows); err != nil {
+		return nil, fmt.Errorf("failed to query booking metrics: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	metrics := &rows[0]
+	metrics.BookingID = bookingID
+	return metrics, nil
+}
+
+// seriesBuckets maps granularities onto the function starting their buckets
+var seriesBuckets = map[string]string{
+	rollup.GranularityHour: "toStartOfHour",
+	rollup.GranularityDay:  "toStartOfDay",
+}
+
+// GetBookingSeries aggregates a booking's events within scope into the hour
+// or day buckets that have any. ClickHouse aggregates on request, so there
+// is no rolled-up-through time.
+func (c *Client) GetBookingSeries(scope tenant.Scope, bookingID, granularity string, from, to time.Time) ([]models.MetricsBucket, *time.Time, error) {
+	bucket, ok := seriesBuckets[granularity]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported series granularity %q", granularity)
+	}
+	params := map[string]string{}
+	where, err := qualityWhere(quality.Filter{Tenant: scope, BookingID: bookingID, From: from, To: to}, true, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	// Buckets are formatted as RFC 3339 so they decode into time.Time
+	query := `
+		SELECT
+			formatDateTime(` + bucket + `(event_timestamp, 'UTC'), '%Y-%m-%dT%H:%i:%SZ', 'UTC') AS bucket,
+			count() AS total_impressions,
+			uniqExact(viewer_id) AS unique_viewers,
+			sum(exposure_duration) AS total_exposure_time,
+			avg(exposure_duration) AS average_exposure_time,
+			avg(instantaneous_prs) AS average_prs_score,
+			avg(attention_score) AS average_attention_score,
+			avg(screen_coverage_percentage) AS average_screen_coverage,
+			avg(listen_through) AS average_listen_through
+		FROM ` + countedEvents(where) + `
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	buckets := make([]models.MetricsBucket, 0)
+	if err := c.QueryInto(ctx, query, params, &buckets); err != nil {
+		return nil, nil, fmt.Errorf("failed to query booking series: %w", err)
+	}
+	return buckets, nil, nil
+}
+
+// GetExposureEvents lists a booking's exposure events within scope, newest
+// first. Pages are keyed on event time and ID; times are returned to the
+// second, so the key is too.
+func (c *Client) GetExposureEvents(scope tenant.Scope, bookingID string, page pagination.Page) ([]models.ExposureEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	params := bindTenant(map[string]string{
+		"booking_id": bookingID,
+		"limit":      strconv.Itoa(page.Fetch()),
+		"offset":     strconv.Itoa(page.Offset),
+	}, scope)
+	keyset, direction := "", "DESC"
+	if page.Cursor != nil {
+		at, err := page.Cursor.Time()
+		if err != nil {
+			return nil, err
+		}
+		op := "<"
+		if page.Cursor.Before {
+			op, direction = ">", "ASC"
+		}
+		keyset = "AND (toUnixTimestamp(event_timestamp), event_id) " + op + " ({cursor_time:Int64}, {cursor_id:String})"
+		params["cursor_time"] = strconv.FormatInt(at.Unix(), 10)
+		params["cursor_id"] = page.Cursor.ID
+	}
+
+	// Timestamps are formatted as RFC 3339 so they decode into time.Time
+	query := `
+		SELECT
+			event_id,
+			viewer_id,
+			formatDateTime(event_timestamp, '%Y-%m-%dT%H:%i:%SZ', 'UTC') AS timestamp,
+			exposure_duration,
+			screen_coverage_percentage AS screen_coverage,
+			attention_score,
+			listen_through
+		FROM exposure_events
+		WHERE booking_id = {booking_id:String}
+			AND ` + tenantClause + `
+			` + keyset + `
+		ORDER BY toUnixTimestamp(event_timestamp) ` + direction + `, event_id ` + direction + `
+		LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+	`
+
+	events := make([]models.ExposureEvent, 0)
+	err := c.QueryInto(ctx, query, params, &events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exposure events: %w", err)
+	}
+
+	return events, nil
+}
+
+// reportWhere restricts exposure rows to a report's booking, time range and
+// tenant scope, bound by reportParams
+const reportWhere = `booking_id = {booking_id:String}
+			AND event_timestamp >= {from:DateTime64(3)}
+			AND event_timestamp < {to:DateTime64(3)}
+			AND ` + tenantClause
+
+// bucketFunctions maps report granularities onto ClickHouse truncation functions
+var bucketFunctions = map[string]string{
+	reporting.GranularityHour: "toStartOfHour",
+	reporting.GranularityDay:  "toStartOfDay",
+	reporting.GranularityWeek: "toMonday",
+}
+
+// reportDimensions maps report group-by names onto exposure_events columns
+var reportDimensions = map[string]string{
+	"":             "''",
+	"device_type":  "device_type",
+	"country_code": "country_code",
+	"viewer_id":    "viewer_id",
+}
+
+// checkReportSupport rejects reports that need campaign membership or booking
+// labels, which live in Postgres and are not mirrored to ClickHouse
+func checkReportSupport(q reporting.Query) error {
+	if q.CampaignID != "" {
+		return fmt.Errorf("campaign-scoped reports require the postgres analytics store")
+	}
+	if _, ok := q.LabelKey(); ok {
+		return fmt.Errorf("label grouping requires the postgres analytics store")
+	}
+	return nil
+}
+
+// EstimateExposureRows counts the exposure rows a report would scan.
+// Counting is cheap on the sorting key, so no planner estimate is needed.
+func (c *Client) EstimateExposureRows(q reporting.Query) (int64, error) {
+	if err := checkReportSupport(q); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT count() AS rows
+		FROM exposure_events
+		WHERE ` + reportWhere + `
+	`
+
+	rows, err := c.Query(ctx, query, reportParams(q))
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate report rows: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	count, _ := rows[0]["rows"].(float64)
+	return int64(count), nil
+}
+
+// GetExposureReport aggregates exposure events into time buckets and an
+// optional dimension, leaving out invalid and duplicate events
+func (c *Client) GetExposureReport(q reporting.Query) ([]map[string]interface{}, error) {
+	if err := checkReportSupport(q); err != nil {
+		return nil, err
+	}
+	bucketFunc, ok := bucketFunctions[q.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report granularity: %s", q.Granularity)
+	}
+	dimension, ok := reportDimensions[q.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report dimension: %s", q.GroupBy)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		SELECT
+			formatDateTime(%s(event_timestamp), '%%Y-%%m-%%dT%%H:%%i:%%SZ', 'UTC') AS bucket,
+			%s AS dimension,
+			count() AS impressions,
+			uniqExact(viewer_id) AS unique_viewers,
+			sum(exposure_duration) AS total_exposure_time,
+			avg(attention_score) AS average_attention_score
+		FROM %s
+		GROUP BY bucket, dimension
+		ORDER BY bucket, dimension
+		%s
+	`, bucketFunc, dimension, countedEvents(reportWhere), limitClause(q))
+
+	rows, err := c.Query(ctx, query, reportParams(q))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exposure report: %w", err)
+	}
+
+	for _, row := range rows {
+		if q.GroupBy != "" {
+			row[q.GroupBy] = row["dimension"]
+		}
+		delete(row, "dimension")
+	}
+
+	return rows, nil
+}
+
+// limitClause pages report rows; a zero limit returns everything
+func limitClause(q reporting.Query) string {
+	if q.Limit == 0 {
+		return ""
+	}
+	return fmt.Sprintf("LIMIT %d OFFSET %d", q.Limit, q.Offset)
+}
+
+// reportParams binds the common report filters as ClickHouse query parameters
+func reportParams(q reporting.Query) map[string]string {
+	return bindTenant(map[string]string{
+		"booking_id": q.BookingID,
+		"from":       q.From.UTC().Format("2006-01-02 15:04:05.000"),
+		"to":         q.To.UTC().Format("2006-01-02 15:04:05.000"),
+	}, q.Tenant)
+}
@@ -0,0 +1,152 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// Client talks to ClickHouse over its HTTP interface
+type Client struct {
+	baseURL    string
+	database   string
+	username   string
+	password   string
+	httpClient *outbound.Client
+}
+
+// Connect creates a ClickHouse client from environment variables and checks connectivity
+func Connect() (*Client, error) {
+	baseURL := os.Getenv("CLICKHOUSE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8123"
+	}
+
+	database := os.Getenv("CLICKHOUSE_DATABASE")
+	if database == "" {
+		database = "inscenium"
+	}
+
+	client := &Client{
+		baseURL:    baseURL,
+		database:   database,
+		username:   os.Getenv("CLICKHOUSE_USER"),
+		password:   os.Getenv("CLICKHOUSE_PASSWORD"),
+		httpClient: outbound.New("clickhouse", outbound.DefaultPolicy()),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping clickhouse: %w", err)
+	}
+
+	return client, nil
+}
+
+// Ping checks that ClickHouse is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "SELECT 1", nil, nil)
+	return err
+}
+
+// Query runs a SELECT and returns the rows as maps.
+// Parameters are bound server-side using ClickHouse {name:Type} placeholders.
+func (c *Client) Query(ctx context.Context, query string, params map[string]string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := c.QueryInto(ctx, query, params, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// QueryInto runs a SELECT and decodes the rows into dest, a pointer to a
+// slice. Columns are matched to struct fields by their json tags.
+func (c *Client) QueryInto(ctx context.Context, query string, params map[string]string, dest interface{}) error {
+	body, err := c.do(ctx, query+" FORMAT JSON", params, nil)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Data interface{} `json:"data"`
+	}{Data: dest}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode clickhouse response: %w", err)
+	}
+
+	return nil
+}
+
+// InsertJSONEachRow inserts rows into a table using the JSONEachRow format
+func (c *Client) InsertJSONEachRow(ctx context.Context, table string, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to encode clickhouse row: %w", err)
+		}
+	}
+
+	_, err := c.do(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table), nil, &buf)
+	return err
+}
+
+// do sends a statement to ClickHouse. Statements without a body are sent as
+// the request body; inserts carry the statement in the query string instead.
+func (c *Client) do(ctx context.Context, query string, params map[string]string, data io.Reader) ([]byte, error) {
+	values := url.Values{}
+	values.Set("database", c.database)
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+
+	body := data
+	if body == nil {
+		body = bytes.NewBufferString(query)
+		values.Set("output_format_json_quote_64bit_integers", "0")
+		// Statements are reads; inserts are not retried to avoid duplicate rows
+		ctx = outbound.Idempotent(ctx)
+	} else {
+		values.Set("query", query)
+		values.Set("date_time_input_format", "best_effort")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/?"+values.Encode(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build clickhouse request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clickhouse response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	return respBody, nil
+}
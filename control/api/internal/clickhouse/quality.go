@@ -0,0 +1,167 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+)
+
+// validEvent holds for exposure rows that are not invalid traffic, as defined
+// by package quality
+var validEvent = fmt.Sprintf(`(exposure_duration > 0
+	AND ifNull(attention_score, 0) BETWEEN 0 AND 1
+	AND ifNull(screen_coverage_percentage, 0) BETWEEN 0 AND 100
+	AND event_timestamp <= received_at + INTERVAL %d SECOND)`,
+	int(quality.FutureTolerance.Seconds()))
+
+// resendKey identifies the copies of an event stored when an edge node
+// resends a batch. Events without a device time were timed on receipt, so
+// each is its own copy.
+const resendKey = "booking_id, viewer_id, ifNull(toString(device_event_timestamp), event_id), exposure_duration"
+
+// countedEvents selects the valid exposure rows matching where, keeping the
+// first received copy of resent events. Figures are built from these rows.
+func countedEvents(where string) string {
+	return `(
+		SELECT * FROM exposure_events
+		WHERE ` + where + `
+			AND ` + validEvent + `
+		ORDER BY received_at
+		LIMIT 1 BY ` + resendKey + `
+	)`
+}
+
+// qualityWhere restricts exposure rows to a filter's booking and tenant
+// scope, and to its time range when timed is set, binding params
+func qualityWhere(f quality.Filter, timed bool, params map[string]string) (string, error) {
+	if f.CampaignID != "" {
+		return "", fmt.Errorf("campaign-scoped reports require the postgres analytics store")
+	}
+	params["booking_id"] = f.BookingID
+	bindTenant(params, f.Tenant)
+	conditions := []string{"booking_id = {booking_id:String}", tenantClause}
+	if timed && !f.From.IsZero() {
+		params["from"] = f.From.UTC().Format("2006-01-02 15:04:05.000")
+		conditions = append(conditions, "event_timestamp >= {from:DateTime64(3)}")
+	}
+	if timed && !f.To.IsZero() {
+		params["to"] = f.To.UTC().Format("2006-01-02 15:04:05.000")
+		conditions = append(conditions, "event_timestamp < {to:DateTime64(3)}")
+	}
+	return strings.Join(conditions, "\n\t\t\tAND "), nil
+}
+
+// GetDataQuality counts the events a filter's figures leave out and
+// estimates the completeness of its recent hours
+func (c *Client) GetDataQuality(f quality.Filter) (*quality.Report, error) {
+	now := time.Now().UTC()
+	params := map[string]string{}
+	where, err := qualityWhere(f, true, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			count() AS events_considered,
+			countIf(NOT ` + validEvent + `) AS invalid_excluded,
+			countIf(` + validEvent + `) - uniqExactIf((` + resendKey + `), ` + validEvent + `) AS duplicates_removed,
+			if(count() = 0, '', formatDateTime(max(received_at), '%Y-%m-%dT%H:%i:%SZ', 'UTC')) AS last_received_at
+		FROM exposure_events
+		WHERE ` + where + `
+	`
+
+	var rows []struct {
+		Considered     int64  `json:"events_considered"`
+		Invalid        int64  `json:"invalid_excluded"`
+		Duplicates     int64  `json:"duplicates_removed"`
+		LastReceivedAt string `json:"last_received_at"`
+	}
+	if err := c.QueryInto(ctx, query, params, &rows); err != nil {
+		return nil, fmt.Errorf("failed to count excluded events: %w", err)
+	}
+	if len(rows) == 0 {
+		return quality.NewReport(0, 0, 0, nil, now), nil
+	}
+
+	var lastReceivedAt *time.Time
+	if rows[0].LastReceivedAt != "" {
+		at, err := time.Parse(time.RFC3339, rows[0].LastReceivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse last receive time: %w", err)
+		}
+		lastReceivedAt = &at
+	}
+	report := quality.NewReport(rows[0].Considered, rows[0].Invalid, rows[0].Duplicates, lastReceivedAt, now)
+
+	hours := f.RecentHours(now)
+	if len(hours) == 0 {
+		return report, nil
+	}
+	estimates, err := c.completeness(ctx, f, hours, now)
+	if err != nil {
+		return nil, err
+	}
+	for i, hour := range hours {
+		report.Completeness = append(report.Completeness, quality.Hour{Hour: hour, Estimate: estimates[i]})
+	}
+	return report, nil
+}
+
+// completeness returns, for each of hours, the share of the filter's
+// reference events that arrived within the hour's elapsed time. Hours are
+// nil without reference events.
+func (c *Client) completeness(ctx context.Context, f quality.Filter, hours []time.Time, now time.Time) ([]*float64, error) {
+	params := map[string]string{}
+	where, err := qualityWhere(f, false, params)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := make([]string, len(hours))
+	for i, hour := range hours {
+		elapsed[i] = strconv.FormatFloat(quality.Elapsed(hour, now).Seconds(), 'f', -1, 64)
+	}
+	from, to := quality.ReferenceRange(now)
+	params["elapsed"] = "[" + strings.Join(elapsed, ",") + "]"
+	params["reference_from"] = from.UTC().Format("2006-01-02 15:04:05.000")
+	params["reference_to"] = to.UTC().Format("2006-01-02 15:04:05.000")
+
+	query := `
+		SELECT position, countIf(delay <= threshold) / count() AS estimate
+		FROM (
+			SELECT dateDiff('millisecond', event_timestamp, received_at) / 1000 AS delay
+			FROM exposure_events
+			WHERE ` + where + `
+				AND event_timestamp >= {reference_from:DateTime64(3)}
+				AND event_timestamp < {reference_to:DateTime64(3)}
+				AND ` + validEvent + `
+		)
+		ARRAY JOIN {elapsed:Array(Float64)} AS threshold, arrayEnumerate({elapsed:Array(Float64)}) AS position
+		GROUP BY position
+		ORDER BY position
+	`
+
+	var rows []struct {
+		Position int     `json:"position"`
+		Estimate float64 `json:"estimate"`
+	}
+	if err := c.QueryInto(ctx, query, params, &rows); err != nil {
+		return nil, fmt.Errorf("failed to estimate completeness: %w", err)
+	}
+
+	estimates := make([]*float64, len(hours))
+	for _, row := range rows {
+		if row.Position >= 1 && row.Position <= len(hours) {
+			estimate := row.Estimate
+			estimates[row.Position-1] = &estimate
+		}
+	}
+	return estimates, nil
+}
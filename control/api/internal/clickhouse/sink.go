@@ -0,0 +1,118 @@
+package clickhouse
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	exposureTable        = "exposure_events"
+	defaultSinkBuffer    = 10000
+	defaultSinkBatchSize = 500
+	defaultFlushInterval = 2 * time.Second
+)
+
+// ExposureSink mirrors exposure events into ClickHouse in batches.
+// Enqueue never blocks the request path; events are dropped when the
+// buffer is full and Postgres remains the system of record.
+type ExposureSink struct {
+	client        *Client
+	events        chan models.ExposureEvent
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewExposureSink creates a sink writing to the given client
+func NewExposureSink(client *Client) *ExposureSink {
+	return &ExposureSink{
+		client:        client,
+		events:        make(chan models.ExposureEvent, defaultSinkBuffer),
+		batchSize:     defaultSinkBatchSize,
+		flushInterval: defaultFlushInterval,
+	}
+}
+
+// Enqueue schedules an exposure event for replication
+func (s *ExposureSink) Enqueue(event models.ExposureEvent) {
+	select {
+	case s.events <- event:
+	default:
+		logrus.WithField("event_id", event.EventID).Warn("ClickHouse sink buffer full, dropping exposure event")
+	}
+}
+
+// Run flushes buffered events until the context is cancelled
+func (s *ExposureSink) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]map[string]interface{}, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := s.client.InsertJSONEachRow(flushCtx, exposureTable, batch); err != nil {
+			logrus.WithError(err).WithField("event_count", len(batch)).Error("Failed to replicate exposure events to ClickHouse")
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Drain whatever is already buffered before exiting
+			for {
+				select {
+				case event := <-s.events:
+					batch = append(batch, exposureRow(event))
+					if len(batch) >= s.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case event := <-s.events:
+			batch = append(batch, exposureRow(event))
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// exposureRow maps a stored exposure event onto the ClickHouse column names
+func exposureRow(event models.ExposureEvent) map[string]interface{} {
+	row := map[string]interface{}{
+		"event_id":          event.EventID,
+		"booking_id":        event.BookingID,
+		"org_id":            event.OrgID,
+		"viewer_id":         event.ViewerID,
+		"event_timestamp":   event.Timestamp,
+		"received_at":       event.ReceivedAt,
+		"clock_skew_ms":     event.ClockSkewMS,
+		"exposure_duration": event.ExposureDuration,
+		"attention_score":   event.AttentionScore,
+		"device_type":       event.DeviceType,
+	}
+	if event.DeviceTimestamp != nil {
+		row["device_event_timestamp"] = *event.DeviceTimestamp
+	}
+	// Audio exposures have no screen to cover
+	if event.ListenThrough != nil {
+		row["listen_through"] = *event.ListenThrough
+	} else {
+		row["screen_coverage_percentage"] = event.ScreenCoverage
+	}
+	return row
+}
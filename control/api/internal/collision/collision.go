@@ -0,0 +1,96 @@
+package collision
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrCollision is returned when a placement would crowd a shot past its rule
+var ErrCollision = errors.New("placement collides with another in the same shot")
+
+// maxConcurrentLimit bounds MaxConcurrent to something a frame could hold
+const maxConcurrentLimit = 100
+
+// maxBundleIDLength matches the placement_bookings.bundle_id column
+const maxBundleIDLength = 100
+
+// Rule limits how many booked surfaces of a shot may be on screen at the same
+// time and how close together they may be. A rule without a ShotID applies to
+// every shot of the title that has no rule of its own.
+type Rule struct {
+	TitleID       string    `json:"title_id"`
+	ShotID        string    `json:"shot_id,omitempty"`
+	MaxConcurrent int       `json:"max_concurrent"` // Booked surfaces on screen at once, including the new one; 0 is unlimited
+	MinSeparation float64   `json:"min_separation"` // Distance between surface polygons in normalized frame units; 0 allows touching
+	Reason        string    `json:"reason,omitempty"`
+	UpdatedBy     string    `json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Validate checks that the rule is well formed
+func (r *Rule) Validate() error {
+	if r.MaxConcurrent < 0 || r.MaxConcurrent > maxConcurrentLimit {
+		return fmt.Errorf("max_concurrent must be between 0 and %d", maxConcurrentLimit)
+	}
+	if r.MinSeparation < 0 || r.MinSeparation > 1 {
+		return fmt.Errorf("min_separation must be between 0 and 1")
+	}
+	if r.MaxConcurrent == 0 && r.MinSeparation == 0 {
+		return fmt.Errorf("max_concurrent or min_separation is required")
+	}
+	return nil
+}
+
+// ValidateBundleID checks a booking's bundle deal ID, which may be empty
+func ValidateBundleID(bundleID string) error {
+	if len(bundleID) > maxBundleIDLength {
+		return fmt.Errorf("bundle_id must be at most %d characters", maxBundleIDLength)
+	}
+	return nil
+}
+
+// Placement is a booking of a surface, or a live booking of another surface
+// of the same shot that is on screen at the same time
+type Placement struct {
+	BookingID  string
+	SurfaceID  string
+	CampaignID string
+	BundleID   string  // Bookings of one campaign sold together as a bundle deal
+	Separation float64 // Distance from the booked surface; negative when unknown
+}
+
+// bundledWith reports whether two placements belong to the same bundle deal
+func (p *Placement) bundledWith(other *Placement) bool {
+	return p.BundleID != "" && p.BundleID == other.BundleID && p.CampaignID == other.CampaignID
+}
+
+// Check returns ErrCollision when placing p beside others breaks the rule.
+// Other bookings of p's bundle deal are exempt: they neither count towards
+// the limit nor need to be kept apart from p.
+func (r *Rule) Check(p Placement, others []Placement) error {
+	surfaces := make(map[string]bool)
+	for i := range others {
+		other := &others[i]
+		if other.SurfaceID == p.SurfaceID || p.bundledWith(other) {
+			continue
+		}
+		if r.MinSeparation > 0 && other.Separation >= 0 && other.Separation < r.MinSeparation {
+			return fmt.Errorf("%w: surface %s is %.3f from booked surface %s (booking %s), closer than %.3f",
+				ErrCollision, p.SurfaceID, other.Separation, other.SurfaceID, other.BookingID, r.MinSeparation)
+		}
+		surfaces[other.SurfaceID] = true
+	}
+
+	if r.MaxConcurrent > 0 && len(surfaces)+1 > r.MaxConcurrent {
+		ids := make([]string, 0, len(surfaces))
+		for id := range surfaces {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return fmt.Errorf("%w: surface %s would make %d booked surfaces on screen at once, more than the %d allowed (already booked: %v)",
+			ErrCollision, p.SurfaceID, len(ids)+1, r.MaxConcurrent, ids)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// AWSKMS wraps data keys with an AWS KMS key through the KMS JSON API,
+// signed with Signature V4
+type AWSKMS struct {
+	client    *outbound.Client
+	endpoint  *url.URL
+	keyID     string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+// NewAWSKMS creates an AWS KMS client for config.AWSKeyID
+func NewAWSKMS(config Config) (*AWSKMS, error) {
+	if config.AWSKeyID == "" || config.AWSAccessKeyID == "" || config.AWSSecretAccessKey == "" {
+		return nil, fmt.Errorf("aws KMS needs a key ID and access keys")
+	}
+	region := config.AWSRegion
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := config.AWSEndpoint
+	if endpoint == "" {
+		endpoint = "https://kms." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid KMS endpoint %q", endpoint)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return &AWSKMS{
+		client:    outbound.New("kms_aws", outbound.DefaultPolicy()),
+		endpoint:  u,
+		keyID:     config.AWSKeyID,
+		region:    region,
+		accessKey: config.AWSAccessKeyID,
+		secretKey: config.AWSSecretAccessKey,
+	}, nil
+}
+
+// KeyID returns the configured KMS key
+func (k *AWSKMS) KeyID() string {
+	return k.keyID
+}
+
+// Wrap encrypts a data key with the configured KMS key
+func (k *AWSKMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var resp struct {
+		CiphertextBlob []byte
+	}
+	err := k.call(ctx, "Encrypt", map[string]interface{}{
+		"KeyId":     k.keyID,
+		"Plaintext": plaintext, // []byte is sent base64 encoded, as KMS expects
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return resp.CiphertextBlob, nil
+}
+
+// Unwrap decrypts a data key. KMS finds the key from the ciphertext, but
+// naming it makes KMS refuse ciphertexts from any other key.
+func (k *AWSKMS) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte
+	}
+	err := k.call(ctx, "Decrypt", map[string]interface{}{
+		"KeyId":          masterKeyID,
+		"CiphertextBlob": wrapped,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %q: %w", masterKeyID, err)
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends a signed KMS action. Encrypt and Decrypt have no side
+// effects, so they are retried like idempotent requests.
+func (k *AWSKMS) call(ctx context.Context, action string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(outbound.Idempotent(ctx), http.MethodPost, k.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	k.sign(req, body, time.Now().UTC())
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kms %s: status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(output)
+}
+
+// sign adds a Signature V4 Authorization header
+func (k *AWSKMS) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	canonical := strings.Join([]string{
+		req.Method,
+		k.endpoint.EscapedPath(),
+		"",
+		"content-type:" + req.Header.Get("Content-Type") + "\nhost:" + k.endpoint.Host +
+			"\nx-amz-date:" + amzDate + "\nx-amz-target:" + req.Header.Get("X-Amz-Target") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + k.region + "/kms/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+k.secretKey), date)
+	key = hmacSHA256(key, k.region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+k.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
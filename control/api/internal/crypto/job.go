@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue is the background job queue that re-encrypts columns after a rotation
+const Queue = "encryption_rotation"
+
+// rotationBatch is how many values are re-encrypted per query
+const rotationBatch = 500
+
+// Job is the payload of a rotation job
+type Job struct {
+	KeyID string `json:"key_id"` // Data key the rotation created, for logs
+}
+
+// StoredValue is one row's value of an encrypted column
+type StoredValue struct {
+	ID    string
+	Value string
+}
+
+// ColumnStore reads and rewrites values of encrypted columns
+type ColumnStore interface {
+	// StaleValues lists values not sealed with keyID, plaintext ones
+	// included, in row key order after the row key after ("" to start)
+	StaleValues(col Column, keyID, after string, limit int) ([]StoredValue, error)
+	// ReplaceValue rewrites a value unless the row changed since it was read
+	ReplaceValue(col Column, id, old, replacement string) error
+}
+
+// NewJobHandler returns a job handler that re-wraps data keys under the
+// current master key, re-encrypts every value of columns not sealed with the
+// active data key, then retires the other data keys. Re-running it is
+// harmless; values rewritten concurrently are left to the next rotation.
+func NewJobHandler(keyring *Keyring, store ColumnStore, columns []Column) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload Job
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		if keyring == nil {
+			return fmt.Errorf("rotation job %s: encryption at rest is not configured", job.ID)
+		}
+
+		// The key may have been created on another instance
+		if err := keyring.Reload(ctx); err != nil {
+			return err
+		}
+		rewrapped, err := keyring.Rewrap(ctx)
+		if err != nil {
+			return err
+		}
+
+		active := keyring.ActiveKeyID()
+		reencrypted := make(map[string]int, len(columns))
+		for _, col := range columns {
+			count, err := reencrypt(ctx, keyring, store, col, active)
+			reencrypted[col.String()] = count
+			if err != nil {
+				return err
+			}
+		}
+
+		retired, err := keyring.Retire(ctx)
+		if err != nil {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"job_id":        job.ID,
+			"key_id":        payload.KeyID,
+			"active_key_id": active,
+			"rewrapped":     rewrapped,
+			"reencrypted":   reencrypted,
+			"retired":       retired,
+		}).Info("Rotated data encryption keys")
+		return nil
+	}
+}
+
+// reencrypt seals every stale value of col with the active key
+func reencrypt(ctx context.Context, keyring *Keyring, store ColumnStore, col Column, active string) (int, error) {
+	count := 0
+	after := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		values, err := store.StaleValues(col, active, after, rotationBatch)
+		if err != nil {
+			return count, err
+		}
+		for _, v := range values {
+			plaintext, err := keyring.Open(col, v.Value)
+			if err != nil {
+				return count, fmt.Errorf("row %s: %w", v.ID, err)
+			}
+			sealed, err := keyring.Seal(col, plaintext)
+			if err != nil {
+				return count, err
+			}
+			if err := store.ReplaceValue(col, v.ID, v.Value, sealed); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(values) < rotationBatch {
+			return count, nil
+		}
+		after = values[len(values)-1].ID
+	}
+}
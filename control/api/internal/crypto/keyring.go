@@ -0,0 +1,330 @@
+package crypto
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RefreshInterval is how often a keyring reloads data keys, so every
+// instance starts sealing with a rotated key within about this long
+const RefreshInterval = time.Minute
+
+// Keyring seals and opens column values with envelope encryption: values
+// are encrypted with AES-256-GCM under data keys, which are stored wrapped
+// by a KMS master key and unwrapped once into memory. The newest unretired
+// data key seals; every data key ever created can still open.
+//
+// A nil *Keyring leaves values in plaintext, so callers need not check
+// whether encryption at rest is configured.
+type Keyring struct {
+	kms   KMS
+	store KeyStore
+
+	mu     sync.RWMutex
+	keys   map[string]*dataKey
+	stored []*DataKey
+	active string
+}
+
+// dataKey is an unwrapped data key
+type dataKey struct {
+	aead     cipher.AEAD
+	nonceKey []byte // Derives nonces of deterministic columns
+}
+
+// NewKeyring loads the stored data keys, creating the first one if there is none
+func NewKeyring(ctx context.Context, kms KMS, store KeyStore) (*Keyring, error) {
+	k := &Keyring{kms: kms, store: store, keys: make(map[string]*dataKey)}
+	if err := k.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if k.ActiveKeyID() == "" {
+		if _, err := k.Rotate(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Reload reads data keys from the store, unwrapping any not yet in memory,
+// and picks up keys created by other instances
+func (k *Keyring) Reload(ctx context.Context) error {
+	stored, err := k.store.ListDataKeys()
+	if err != nil {
+		return err
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+
+	k.mu.RLock()
+	missing := make([]*DataKey, 0)
+	for _, key := range stored {
+		if k.keys[key.ID] == nil {
+			missing = append(missing, key)
+		}
+	}
+	k.mu.RUnlock()
+
+	unwrapped := make(map[string]*dataKey, len(missing))
+	for _, key := range missing {
+		dk, err := k.unwrap(ctx, key)
+		if err != nil {
+			return err
+		}
+		unwrapped[key.ID] = dk
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for id, dk := range unwrapped {
+		k.keys[id] = dk
+	}
+	k.stored = stored
+	k.active = ""
+	for _, key := range stored {
+		if key.RetiredAt == nil {
+			k.active = key.ID
+		}
+	}
+	return nil
+}
+
+// Run reloads data keys every RefreshInterval until ctx is done
+func (k *Keyring) Run(ctx context.Context) {
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				logrus.WithError(err).Warn("Failed to reload data encryption keys")
+			}
+		}
+	}
+}
+
+// ActiveKeyID returns the data key new values are sealed with
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// MasterKeyID returns the KMS master key new data keys are wrapped with
+func (k *Keyring) MasterKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.kms.KeyID()
+}
+
+// DataKeys lists the data keys as last loaded, oldest first
+func (k *Keyring) DataKeys() []DataKey {
+	if k == nil {
+		return nil
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]DataKey, 0, len(k.stored))
+	for _, key := range k.stored {
+		keys = append(keys, *key)
+	}
+	return keys
+}
+
+// Rotate creates a data key that seals new values from now on. Values
+// sealed before keep their key until re-encrypted by a rotation job.
+func (k *Keyring) Rotate(ctx context.Context) (*DataKey, error) {
+	material := make([]byte, 32)
+	if _, err := rand.Read(material); err != nil {
+		return nil, err
+	}
+	wrapped, err := k.kms.Wrap(ctx, material)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &DataKey{
+		ID:          fmt.Sprintf("dk_%d", time.Now().UnixNano()),
+		MasterKeyID: k.kms.KeyID(),
+		WrappedKey:  wrapped,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := k.store.CreateDataKey(key); err != nil {
+		return nil, err
+	}
+
+	dk, err := newDataKey(material)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	k.keys[key.ID] = dk
+	k.stored = append(k.stored, key)
+	k.active = key.ID
+	k.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"key_id":        key.ID,
+		"master_key_id": key.MasterKeyID,
+	}).Info("Created data encryption key")
+	return key, nil
+}
+
+// Rewrap re-wraps data keys wrapped by an older master key with the current
+// one, so the old master key can be disabled in the KMS. Values need no
+// re-encryption for this. It returns how many keys were re-wrapped.
+func (k *Keyring) Rewrap(ctx context.Context) (int, error) {
+	current := k.kms.KeyID()
+	rewrapped := 0
+	for _, key := range k.DataKeys() {
+		if key.MasterKeyID == current {
+			continue
+		}
+		material, err := k.kms.Unwrap(ctx, key.MasterKeyID, key.WrappedKey)
+		if err != nil {
+			return rewrapped, err
+		}
+		wrapped, err := k.kms.Wrap(ctx, material)
+		if err != nil {
+			return rewrapped, err
+		}
+		if err := k.store.RewrapDataKey(key.ID, current, wrapped); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+	if rewrapped > 0 {
+		return rewrapped, k.Reload(ctx)
+	}
+	return 0, nil
+}
+
+// Retire marks every data key but the active one as no longer in use, once
+// rotation re-encrypted their values. Retired keys can still open values.
+func (k *Keyring) Retire(ctx context.Context) (int, error) {
+	active := k.ActiveKeyID()
+	if active == "" {
+		return 0, nil
+	}
+	retired, err := k.store.RetireDataKeys(active)
+	if err != nil {
+		return 0, err
+	}
+	return retired, k.Reload(ctx)
+}
+
+// Seal encrypts a value of col with the active data key. Empty values are
+// left empty.
+func (k *Keyring) Seal(col Column, plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	k.mu.RLock()
+	keyID := k.active
+	dk := k.keys[keyID]
+	k.mu.RUnlock()
+	if dk == nil {
+		return "", fmt.Errorf("no active data key: %w", ErrUnknownKey)
+	}
+
+	aad := []byte(col.String())
+	nonce := make([]byte, dk.aead.NonceSize())
+	if col.Deterministic {
+		mac := hmac.New(sha256.New, dk.nonceKey)
+		mac.Write(aad)
+		mac.Write([]byte{0})
+		mac.Write([]byte(plaintext))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := dk.aead.Seal(nonce, nonce, []byte(plaintext), aad)
+	return Prefix + keyID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value of col. Plaintext values, written before the column
+// was encrypted, are returned unchanged.
+func (k *Keyring) Open(col Column, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", fmt.Errorf("%s is encrypted but encryption at rest is not configured", col)
+	}
+
+	keyID, payload, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	k.mu.RLock()
+	dk := k.keys[keyID]
+	k.mu.RUnlock()
+	if dk == nil {
+		// Another instance may have rotated since we last loaded
+		if err := k.Reload(context.Background()); err != nil {
+			return "", err
+		}
+		k.mu.RLock()
+		dk = k.keys[keyID]
+		k.mu.RUnlock()
+		if dk == nil {
+			return "", fmt.Errorf("%s: %w %q", col, ErrUnknownKey, keyID)
+		}
+	}
+
+	if len(sealed) < dk.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, ciphertext := sealed[:dk.aead.NonceSize()], sealed[dk.aead.NonceSize():]
+	plaintext, err := dk.aead.Open(nil, nonce, ciphertext, []byte(col.String()))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", col, ErrMalformed)
+	}
+	return string(plaintext), nil
+}
+
+// unwrap decrypts a stored data key through the KMS
+func (k *Keyring) unwrap(ctx context.Context, key *DataKey) (*dataKey, error) {
+	material, err := k.kms.Unwrap(ctx, key.MasterKeyID, key.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("data key %s: %w", key.ID, err)
+	}
+	return newDataKey(material)
+}
+
+// newDataKey prepares a data key for sealing and opening
+func newDataKey(material []byte) (*dataKey, error) {
+	if len(material) != 32 {
+		return nil, fmt.Errorf("data key must be 32 bytes, got %d", len(material))
+	}
+	aead, err := newAEAD(material)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, material)
+	mac.Write([]byte("inscenium deterministic nonce"))
+	return &dataKey{aead: aead, nonceKey: mac.Sum(nil)}, nil
+}
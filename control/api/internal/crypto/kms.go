@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// KMS wraps and unwraps data keys with master keys that never leave it
+type KMS interface {
+	// KeyID names the master key that wraps new data keys
+	KeyID() string
+	Wrap(ctx context.Context, plaintext []byte) ([]byte, error)
+	// Unwrap decrypts a data key wrapped by the named master key
+	Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKMS keeps master keys in configuration. It suits development and
+// deployments without a cloud KMS; the keys must be kept out of the database.
+type LocalKMS struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// NewLocalKMS parses master keys given as "id:base64" pairs separated by
+// commas. Each key is 32 random bytes; the first one is active.
+func NewLocalKMS(spec string) (*LocalKMS, error) {
+	kms := &LocalKMS{keys: make(map[string]cipher.AEAD)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("local KMS key must be id:base64, got %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("local KMS key %q must be 32 bytes, base64 encoded", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := kms.keys[id]; dup {
+			return nil, fmt.Errorf("local KMS key %q is listed twice", id)
+		}
+		kms.keys[id] = aead
+		if kms.active == "" {
+			kms.active = id
+		}
+	}
+	if kms.active == "" {
+		return nil, fmt.Errorf("local KMS needs at least one master key")
+	}
+	return kms, nil
+}
+
+// KeyID returns the active master key
+func (k *LocalKMS) KeyID() string {
+	return k.active
+}
+
+// Wrap encrypts a data key with the active master key
+func (k *LocalKMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(k.active)), nil
+}
+
+// Unwrap decrypts a data key with the master key that wrapped it
+func (k *LocalKMS) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("local KMS has no master key %q", masterKeyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped data key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(masterKeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %q: %w", masterKeyID, err)
+	}
+	return plaintext, nil
+}
+
+// newAEAD creates AES-256-GCM for a 32-byte key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,498 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/apply"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// ApplyDocument plans a declarative document against the current state and,
+// unless the request is a dry run, applies the plan in the same transaction.
+// The declared campaigns' live bookings are locked while planning, so the
+// plan applied is the plan returned. If the plan has problems nothing
+// changes. A request carrying the hash of a reviewed plan fails with
+// apply.ErrPlanChanged, returning the new plan, if the state moved since.
+func (db *DB) ApplyDocument(req *apply.Request) (*apply.Plan, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadApplyState(tx, &req.Document)
+	if err != nil {
+		return nil, err
+	}
+	plan := apply.Diff(&req.Document, state)
+	plan.DryRun = req.DryRun
+	if err := checkPlan(tx, plan); err != nil {
+		return nil, err
+	}
+	if len(plan.Problems) > 0 || req.DryRun {
+		return plan, nil
+	}
+	if req.PlanHash != "" && req.PlanHash != plan.PlanHash {
+		return plan, apply.ErrPlanChanged
+	}
+
+	appliedAt := time.Now().UTC()
+	problem, err := executePlan(tx, req, plan, appliedAt)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		// The transaction is aborted; nothing was applied
+		plan.Problems = append(plan.Problems, *problem)
+		return plan, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit apply: %w", err)
+	}
+	plan.AppliedAt = &appliedAt
+	db.publish(appliedBookingChanges(req, plan, appliedAt)...)
+	return plan, nil
+}
+
+// appliedBookingChanges describes the bookings an applied plan created,
+// cancelled or updated
+func appliedBookingChanges(req *apply.Request, plan *apply.Plan, appliedAt time.Time) []eventbus.Event {
+	var events []eventbus.Event
+	for _, change := range plan.Changes {
+		if change.ResourceType != labels.ResourceBooking {
+			continue
+		}
+		event := eventbus.BookingChanged{
+			BookingID:  change.ResourceID,
+			CampaignID: change.CampaignID,
+			Type:       booking.EventAmended,
+			Actor:      req.UserID,
+			OrgID:      req.OrgID,
+			OccurredAt: appliedAt,
+		}
+		switch change.Action {
+		case apply.ActionCreate:
+			event.Type, event.Status = booking.EventCreated, booking.StatusConfirmed
+			if change.Terms.Paused {
+				event.Status = booking.StatusPaused
+			}
+		case apply.ActionDelete:
+			event.Type, event.Status = booking.EventCancelled, booking.StatusCancelled
+		case apply.ActionUpdate:
+			if change.Sets("paused") {
+				event.Type, event.Status = booking.EventApproved, booking.StatusConfirmed
+				if change.Terms.Paused {
+					event.Type, event.Status = booking.EventPaused, booking.StatusPaused
+				}
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// loadApplyState reads the current state of the resources a document
+// declares, locking the declared campaigns' live bookings
+func loadApplyState(tx *sql.Tx, d *apply.Document) (*apply.State, error) {
+	state := &apply.State{
+		Campaigns: make(map[string]*apply.CampaignState),
+		Creatives: make(map[string]*apply.CreativeState),
+	}
+	campaignIDs := make([]string, 0, len(d.Campaigns))
+	for _, c := range d.Campaigns {
+		campaignIDs = append(campaignIDs, c.CampaignID)
+	}
+	creativeIDs := make([]string, 0, len(d.Creatives))
+	for _, c := range d.Creatives {
+		creativeIDs = append(creativeIDs, c.CreativeID)
+	}
+
+	existing, err := existingResources(tx, labels.ResourceCampaign, campaignIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		state.Campaigns[id] = &apply.CampaignState{}
+	}
+	existing, err = existingResources(tx, labels.ResourceCreative, creativeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		state.Creatives[id] = &apply.CreativeState{}
+	}
+
+	rows, err := tx.Query(`
+		SELECT campaign_id, amount FROM campaign_budgets WHERE campaign_id = ANY($1)
+	`, pq.Array(campaignIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+	for rows.Next() {
+		var campaignID string
+		var amount float64
+		if err := rows.Scan(&campaignID, &amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		if c, ok := state.Campaigns[campaignID]; ok {
+			c.Budget = &amount
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+
+	rows, err = tx.Query(`
+		SELECT booking_id, surface_id, advertiser_id, campaign_id, COALESCE(creative_asset_id, ''),
+			bid_amount_cpm, COALESCE(estimated_impressions, 0), COALESCE(min_prs_score, 0), status
+		FROM placement_bookings
+		WHERE campaign_id = ANY($1) AND status IN ('pending', 'confirmed', 'active', 'paused')
+		ORDER BY booking_time, booking_id
+		FOR UPDATE
+	`, pq.Array(campaignIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings to apply: %w", err)
+	}
+	var bookingIDs []string
+	for rows.Next() {
+		var b apply.BookingState
+		var campaignID string
+		if err := rows.Scan(&b.BookingID, &b.SurfaceID, &b.Terms.AdvertiserID, &campaignID, &b.Terms.CreativeID,
+			&b.Terms.BidAmountCPM, &b.Terms.MaxImpressions, &b.Terms.MinPRSScore, &b.Status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan booking to apply: %w", err)
+		}
+		b.Terms.Paused = b.Status == booking.StatusPaused
+		if c, ok := state.Campaigns[campaignID]; ok {
+			c.Bookings = append(c.Bookings, b)
+			bookingIDs = append(bookingIDs, b.BookingID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query bookings to apply: %w", err)
+	}
+
+	campaignLabels, campaignExternalIDs, err := applyMetadata(tx, labels.ResourceCampaign, campaignIDs)
+	if err != nil {
+		return nil, err
+	}
+	for id, c := range state.Campaigns {
+		c.Labels, c.ExternalIDs = campaignLabels[id], campaignExternalIDs[id]
+	}
+	creativeLabels, creativeExternalIDs, err := applyMetadata(tx, labels.ResourceCreative, creativeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for id, c := range state.Creatives {
+		c.Labels, c.ExternalIDs = creativeLabels[id], creativeExternalIDs[id]
+	}
+	bookingLabels, bookingExternalIDs, err := applyMetadata(tx, labels.ResourceBooking, bookingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range state.Campaigns {
+		for i := range c.Bookings {
+			id := c.Bookings[i].BookingID
+			c.Bookings[i].Labels, c.Bookings[i].ExternalIDs = bookingLabels[id], bookingExternalIDs[id]
+		}
+	}
+	return state, nil
+}
+
+// existingResources returns which campaigns or creatives exist: they have
+// an owner, bookings, a budget, labels or partner IDs
+func existingResources(tx *sql.Tx, resourceType string, resourceIDs []string) ([]string, error) {
+	if len(resourceIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(`
+		SELECT resource_id FROM resource_owners WHERE resource_type = $1 AND resource_id = ANY($2)
+		UNION SELECT resource_id FROM resource_labels WHERE resource_type = $1 AND resource_id = ANY($2)
+		UNION SELECT resource_id FROM external_ids WHERE resource_type = $1 AND resource_id = ANY($2)
+		UNION SELECT campaign_id FROM placement_bookings WHERE $1 = 'campaign' AND campaign_id = ANY($2)
+		UNION SELECT campaign_id FROM campaign_budgets WHERE $1 = 'campaign' AND campaign_id = ANY($2)
+		UNION SELECT campaign_id FROM campaigns WHERE $1 = 'campaign' AND campaign_id = ANY($2)
+		UNION SELECT creative_asset_id FROM placement_bookings WHERE $1 = 'creative' AND creative_asset_id = ANY($2)
+	`, resourceType, pq.Array(resourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing %ss: %w", resourceType, err)
+	}
+	defer rows.Close()
+
+	var existing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan existing %s: %w", resourceType, err)
+		}
+		existing = append(existing, id)
+	}
+	return existing, rows.Err()
+}
+
+// applyMetadata returns the labels and partner IDs of several resources within tx
+func applyMetadata(tx *sql.Tx, resourceType string, resourceIDs []string) (map[string]labels.Set, map[string]map[string]string, error) {
+	labelSets, err := labelsFor(tx, resourceType, resourceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	externalIDs, err := externalIDsFor(tx, resourceType, resourceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return labelSets, externalIDs, nil
+}
+
+// checkPlan records the problems of applying a plan that the state shows
+// up front: unknown, merged or held-back surfaces for new bookings, and
+// partner IDs carried by other resources
+func checkPlan(tx *sql.Tx, plan *apply.Plan) error {
+	for _, change := range plan.Changes {
+		if change.Sets("external_ids") {
+			ids := declaredExternalIDs(&change)
+			for source, externalID := range ids {
+				holder, err := externalIDHolder(tx, change.ResourceType, source, externalID, change.ResourceID)
+				if err != nil {
+					return err
+				}
+				if holder != "" {
+					plan.AddProblem(change.Path+".external_ids", "%s: %s/%s is attached to %s %s", ErrExternalIDTaken, source, externalID, change.ResourceType, holder)
+				}
+			}
+		}
+		if change.ResourceType != labels.ResourceBooking || change.Action != apply.ActionCreate {
+			continue
+		}
+
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM surfaces WHERE surface_id = $1)`, change.SurfaceID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check surface: %w", err)
+		}
+		if !exists {
+			plan.AddProblem(change.Path+".surface_id", "surface %s does not exist", change.SurfaceID)
+			continue
+		}
+		err := checkMerged(tx, change.SurfaceID)
+		if err == nil {
+			err = checkHoldback(tx, change.SurfaceID)
+		}
+		if errors.Is(err, dedupe.ErrSurfaceMerged) || errors.Is(err, holdback.ErrHeldBack) {
+			plan.AddProblem(change.Path+".surface_id", "%s", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declaredExternalIDs returns the partner IDs a change declares
+func declaredExternalIDs(change *apply.Change) map[string]string {
+	switch {
+	case change.Booking != nil:
+		return change.Booking.ExternalIDs
+	case change.Creative != nil:
+		return change.Creative.ExternalIDs
+	case change.Campaign != nil:
+		return change.Campaign.ExternalIDs
+	}
+	return nil
+}
+
+// declaredLabels returns the labels a change declares
+func declaredLabels(change *apply.Change) labels.Set {
+	switch {
+	case change.Booking != nil:
+		return change.Booking.Labels
+	case change.Creative != nil:
+		return change.Creative.Labels
+	case change.Campaign != nil:
+		return change.Campaign.Labels
+	}
+	return nil
+}
+
+// executePlan makes a checked plan's changes within tx, filling in the IDs
+// of new bookings. Conflicts that only show up while writing, such as a
+// title's sellable inventory running out partway through, are returned as
+// a problem.
+func executePlan(tx *sql.Tx, req *apply.Request, plan *apply.Plan, appliedAt time.Time) (*apply.Problem, error) {
+	for _, change := range plan.Steps() {
+		var err error
+		switch {
+		case change.ResourceType != labels.ResourceBooking:
+			err = applyResource(tx, req, change, appliedAt)
+		case change.Action == apply.ActionDelete:
+			_, err = appendBookingEvent(tx, booking.Event{
+				BookingID:  change.ResourceID,
+				Type:       booking.EventCancelled,
+				Actor:      req.UserID,
+				OrgID:      req.OrgID,
+				OccurredAt: appliedAt,
+				Data:       booking.Change{Reason: "removed from applied document"},
+			})
+		case change.Action == apply.ActionUpdate:
+			err = updateBooking(tx, req, change, appliedAt)
+		default:
+			err = createBooking(tx, req, change, appliedAt)
+		}
+
+		if errors.Is(err, ErrExternalIDTaken) || errors.Is(err, holdback.ErrHeldBack) || errors.Is(err, dedupe.ErrSurfaceMerged) ||
+			errors.Is(err, collision.ErrCollision) || errors.Is(err, booking.ErrInvalidTransition) || errors.Is(err, campaign.ErrNotBookable) ||
+			errors.Is(err, booking.ErrConflict) {
+			path := change.Path
+			if path == "" {
+				path = "booking " + change.ResourceID
+			}
+			return &apply.Problem{Path: path, Message: err.Error()}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// applyResource creates or updates a campaign or creative
+func applyResource(tx *sql.Tx, req *apply.Request, change *apply.Change, appliedAt time.Time) error {
+	if change.Action == apply.ActionCreate && req.OrgID != "" {
+		if err := registerResourceOwner(tx, change.ResourceType, change.ResourceID, req.OrgID); err != nil {
+			return err
+		}
+	}
+	if change.Sets("budget") {
+		b := &budget.Budget{CampaignID: change.ResourceID, Amount: *change.Campaign.Budget, UpdatedBy: req.UserID, UpdatedAt: appliedAt}
+		if err := setBudget(tx, b); err != nil {
+			return err
+		}
+	}
+	return applyChangedMetadata(tx, change)
+}
+
+// applyChangedMetadata replaces the labels and partner IDs a change sets
+func applyChangedMetadata(tx *sql.Tx, change *apply.Change) error {
+	if change.Sets("labels") {
+		if err := setLabels(tx, change.ResourceType, change.ResourceID, declaredLabels(change)); err != nil {
+			return err
+		}
+	}
+	if change.Sets("external_ids") {
+		if err := setExternalIDs(tx, change.ResourceType, change.ResourceID, declaredExternalIDs(change)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateBooking amends, pauses or resumes a live booking and replaces its
+// creative and metadata as the change sets them
+func updateBooking(tx *sql.Tx, req *apply.Request, change *apply.Change, appliedAt time.Time) error {
+	event := booking.Event{
+		BookingID:  change.ResourceID,
+		Type:       booking.EventAmended,
+		Actor:      req.UserID,
+		OrgID:      req.OrgID,
+		OccurredAt: appliedAt,
+		Data:       booking.Change{Reason: "applied document"},
+	}
+	terms := change.Terms
+	amended := false
+	if change.Sets("advertiser_id") {
+		event.Data.AdvertiserID, amended = terms.AdvertiserID, true
+	}
+	if change.Sets("bid_amount_cpm") {
+		event.Data.BidAmountCPM, amended = &terms.BidAmountCPM, true
+	}
+	if change.Sets("max_impressions") {
+		event.Data.MaxImpressions, amended = &terms.MaxImpressions, true
+	}
+	if change.Sets("min_prs_score") {
+		event.Data.MinPRSScore, amended = &terms.MinPRSScore, true
+	}
+	if amended {
+		if _, err := appendBookingEvent(tx, event); err != nil {
+			return err
+		}
+	}
+
+	if change.Sets("creative_id") {
+		_, err := tx.Exec(`
+			UPDATE placement_bookings SET creative_asset_id = NULLIF($2, ''), updated_at = $3 WHERE booking_id = $1
+		`, change.ResourceID, terms.CreativeID, appliedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update booking creative: %w", err)
+		}
+	}
+	if change.Sets("paused") {
+		if err := setPaused(tx, req, change.ResourceID, terms.Paused, appliedAt); err != nil {
+			return err
+		}
+	}
+	return applyChangedMetadata(tx, change)
+}
+
+// createBooking books a declared surface for its campaign
+func createBooking(tx *sql.Tx, req *apply.Request, change *apply.Change, appliedAt time.Time) error {
+	terms := change.Terms
+	data := &models.NewBooking{
+		SurfaceID:       change.SurfaceID,
+		AdvertiserID:    terms.AdvertiserID,
+		CampaignID:      change.CampaignID,
+		BidAmountCPM:    terms.BidAmountCPM,
+		MaxImpressions:  terms.MaxImpressions,
+		MinPRSScore:     terms.MinPRSScore,
+		CreativeAssetID: terms.CreativeID,
+		Labels:          change.Booking.Labels,
+		ExternalIDs:     change.Booking.ExternalIDs,
+		OrgID:           req.OrgID,
+		UserID:          req.UserID,
+	}
+	// A document declaring a campaign's bookings creates the campaign
+	c := &campaign.Campaign{CampaignID: change.CampaignID, AdvertiserID: terms.AdvertiserID, CreatedBy: req.UserID}
+	if err := ensureCampaign(tx, c, req.OrgID); err != nil {
+		return err
+	}
+	bookingID, err := createPlacementBooking(tx, data, appliedAt)
+	if err != nil {
+		return err
+	}
+	change.ResourceID = bookingID
+	if terms.Paused {
+		return setPaused(tx, req, bookingID, true, appliedAt)
+	}
+	return nil
+}
+
+// setPaused pauses a booking or resumes a paused one
+func setPaused(tx *sql.Tx, req *apply.Request, bookingID string, paused bool, at time.Time) error {
+	eventType := booking.EventApproved
+	if paused {
+		eventType = booking.EventPaused
+	}
+	_, err := appendBookingEvent(tx, booking.Event{
+		BookingID:  bookingID,
+		Type:       eventType,
+		Actor:      req.UserID,
+		OrgID:      req.OrgID,
+		OccurredAt: at,
+		Data:       booking.Change{Reason: "applied document"},
+	})
+	return err
+}
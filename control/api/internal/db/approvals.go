@@ -0,0 +1,195 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/approval"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// creativeApprovalColumns select the standing approval of a booking's
+// current creative from placement_bookings joined to creative_approvals ca
+const creativeApprovalColumns = `
+	placement_bookings.booking_id, placement_bookings.creative_asset_id, placement_bookings.campaign_id,
+	placement_bookings.surface_id, COALESCE(placement_bookings.org_id, ''),
+	COALESCE(ca.status, 'pending'), COALESCE(ca.source, ''), COALESCE(ca.reviewer, ''),
+	COALESCE(ca.reason, ''), COALESCE(ca.external_ref, ''), ca.decided_at, placement_bookings.booking_time
+`
+
+// creativeApprovalJoin joins the decision standing for a booking's current
+// creative, if any
+const creativeApprovalJoin = `
+	FROM placement_bookings
+	LEFT JOIN creative_approvals ca
+		ON ca.booking_id = placement_bookings.booking_id
+		AND ca.creative_asset_id = placement_bookings.creative_asset_id
+`
+
+// ListCreativeApprovals lists the creative approvals of live bookings
+// visible within scope that are in status, oldest booking first
+func (db *DB) ListCreativeApprovals(scope tenant.Scope, status string, limit, offset int) ([]approval.Approval, error) {
+	where := query.New()
+	where.Add("COALESCE(placement_bookings.creative_asset_id, '') <> ''")
+	where.Add("placement_bookings.status IN ('pending', 'confirmed', 'active', 'paused')")
+	where.Where("COALESCE(ca.status, 'pending') = %s", status)
+	whereBookingVisible(where, scope)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+	args := append(where.Args(), limit, offset)
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT `+creativeApprovalColumns+creativeApprovalJoin+`
+		WHERE %s
+		ORDER BY placement_bookings.booking_time, placement_bookings.booking_id
+		LIMIT $%d OFFSET $%d
+	`, where.Clause(), len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query creative approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := make([]approval.Approval, 0)
+	for rows.Next() {
+		a, err := scanCreativeApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals, rows.Err()
+}
+
+// GetCreativeApproval returns the standing approval of a booking's current
+// creative. It returns ErrBookingNotFound for unknown bookings and
+// approval.ErrNoCreative for bookings without a creative.
+func (db *DB) GetCreativeApproval(bookingID string) (*approval.Approval, error) {
+	return getCreativeApproval(db, bookingID, "")
+}
+
+// getCreativeApproval reads a booking's standing approval, suffixed with
+// e.g. a locking clause
+func getCreativeApproval(q rowQueryer, bookingID, suffix string) (*approval.Approval, error) {
+	var creativeID sql.NullString
+	err := q.QueryRow(`SELECT creative_asset_id FROM placement_bookings WHERE booking_id = $1`+suffix, bookingID).Scan(&creativeID)
+	if err == sql.ErrNoRows {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking creative: %w", err)
+	}
+	if creativeID.String == "" {
+		return nil, approval.ErrNoCreative
+	}
+
+	a, err := scanCreativeApproval(q.QueryRow(`
+		SELECT `+creativeApprovalColumns+creativeApprovalJoin+`
+		WHERE placement_bookings.booking_id = $1
+	`, bookingID))
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// DecideCreativeApproval records a decision about a booking's creative and
+// settles the approval standing after it by the precedence of
+// approval.Resolve. Decisions on a booking are serialized by locking it.
+func (db *DB) DecideCreativeApproval(d *approval.Decision) (*approval.Result, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin approval transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	standing, err := getCreativeApproval(tx, d.BookingID, " FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	if d.CreativeAssetID == "" {
+		d.CreativeAssetID = standing.CreativeAssetID
+	}
+	next := approval.Resolve(*standing, d)
+
+	d.ReceivedAt = time.Now().UTC()
+	d.DecisionID = fmt.Sprintf("decision_%s_%d", d.BookingID, d.ReceivedAt.UnixNano())
+	if _, err := tx.Exec(`
+		INSERT INTO creative_approval_decisions (
+			decision_id, booking_id, creative_asset_id, decision, source, reviewer,
+			reason, external_ref, decided_at, received_at, outcome, conflict
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12)
+	`, d.DecisionID, d.BookingID, d.CreativeAssetID, d.Status, d.Source, d.Reviewer,
+		d.Reason, d.ExternalRef, d.DecidedAt, d.ReceivedAt, d.Outcome, d.Conflict); err != nil {
+		return nil, fmt.Errorf("failed to record approval decision: %w", err)
+	}
+
+	if d.Outcome == approval.OutcomeApplied {
+		if _, err := tx.Exec(`
+			INSERT INTO creative_approvals (
+				booking_id, creative_asset_id, status, source, reviewer, reason, external_ref, decided_at, updated_at
+			) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+			ON CONFLICT (booking_id, creative_asset_id) DO UPDATE SET
+				status = EXCLUDED.status,
+				source = EXCLUDED.source,
+				reviewer = EXCLUDED.reviewer,
+				reason = EXCLUDED.reason,
+				external_ref = EXCLUDED.external_ref,
+				decided_at = EXCLUDED.decided_at,
+				updated_at = EXCLUDED.updated_at
+		`, next.BookingID, next.CreativeAssetID, next.Status, next.Source, next.Reviewer,
+			next.Reason, next.ExternalRef, next.DecidedAt, d.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("failed to save creative approval: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit approval decision: %w", err)
+	}
+	return &approval.Result{Decision: d, Approval: &next}, nil
+}
+
+// ListCreativeApprovalDecisions lists the decisions received about a
+// booking's creatives, newest first
+func (db *DB) ListCreativeApprovalDecisions(bookingID string, limit int) ([]approval.Decision, error) {
+	rows, err := db.Query(`
+		SELECT decision_id, booking_id, creative_asset_id, decision, source, COALESCE(reviewer, ''),
+			COALESCE(reason, ''), COALESCE(external_ref, ''), decided_at, received_at, outcome, conflict
+		FROM creative_approval_decisions
+		WHERE booking_id = $1
+		ORDER BY received_at DESC, id DESC
+		LIMIT $2
+	`, bookingID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := make([]approval.Decision, 0)
+	for rows.Next() {
+		var d approval.Decision
+		if err := rows.Scan(&d.DecisionID, &d.BookingID, &d.CreativeAssetID, &d.Status, &d.Source, &d.Reviewer,
+			&d.Reason, &d.ExternalRef, &d.DecidedAt, &d.ReceivedAt, &d.Outcome, &d.Conflict); err != nil {
+			return nil, fmt.Errorf("failed to scan approval decision: %w", err)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+// scanCreativeApproval scans a row selected with creativeApprovalColumns
+func scanCreativeApproval(row rowScanner) (*approval.Approval, error) {
+	var a approval.Approval
+	var decidedAt sql.NullTime
+	err := row.Scan(&a.BookingID, &a.CreativeAssetID, &a.CampaignID, &a.SurfaceID, &a.OrgID,
+		&a.Status, &a.Source, &a.Reviewer, &a.Reason, &a.ExternalRef, &decidedAt, &a.RequestedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan creative approval: %w", err)
+	}
+	if decidedAt.Valid {
+		a.DecidedAt = &decidedAt.Time
+	}
+	return &a, nil
+}
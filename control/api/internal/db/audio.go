@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+)
+
+// rowQueryer is implemented by *DB and *sql.Tx
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// audioColumns writes the placement type and loudness constraints of an
+// ingested surface to their columns, NULL when unset
+func audioColumns(s *ingest.Surface) (string, sql.NullFloat64, sql.NullFloat64, sql.NullFloat64) {
+	placementType := audio.PlacementVisual
+	if s.Audio() {
+		placementType = audio.PlacementAudio
+	}
+	if s.Loudness == nil {
+		return placementType, sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return placementType,
+		sql.NullFloat64{Float64: s.Loudness.TargetLUFS, Valid: true},
+		sql.NullFloat64{Float64: s.Loudness.ToleranceLU, Valid: true},
+		sql.NullFloat64{Float64: s.Loudness.MaxTruePeakDBTP, Valid: true}
+}
+
+// audioSlot reads the slot of a surface from its columns, nil unless the
+// surface is an audio slot
+func audioSlot(placementType string, startTime, endTime float64, target, tolerance, maxTruePeak sql.NullFloat64) *audio.Slot {
+	if placementType != audio.PlacementAudio {
+		return nil
+	}
+	slot := &audio.Slot{StartOffset: startTime, Duration: endTime - startTime}
+	if target.Valid && tolerance.Valid && maxTruePeak.Valid {
+		slot.Loudness = &audio.Loudness{
+			TargetLUFS:      target.Float64,
+			ToleranceLU:     tolerance.Float64,
+			MaxTruePeakDBTP: maxTruePeak.Float64,
+		}
+	}
+	return slot
+}
+
+// getBookingAudioSlot returns the audio slot a booking placed, or nil if
+// the booking does not exist or placed a visual surface
+func getBookingAudioSlot(q rowQueryer, bookingID string) (*audio.Slot, error) {
+	var placementType string
+	var startTime, endTime float64
+	var target, tolerance, maxTruePeak sql.NullFloat64
+	err := q.QueryRow(`
+		SELECT s.placement_type, s.start_time, s.end_time,
+			s.loudness_target_lufs, s.loudness_tolerance_lu, s.max_true_peak_dbtp
+		FROM placement_bookings pb
+		JOIN surfaces s ON s.surface_id = pb.surface_id
+		WHERE pb.booking_id = $1
+	`, bookingID).Scan(&placementType, &startTime, &endTime, &target, &tolerance, &maxTruePeak)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audio slot: %w", err)
+	}
+	return audioSlot(placementType, startTime, endTime, target, tolerance, maxTruePeak), nil
+}
+
+// GetBookingAudioSlot returns the audio slot a booking placed, or nil if the
+// booking does not exist or placed a visual surface
+func (db *DB) GetBookingAudioSlot(bookingID string) (*audio.Slot, error) {
+	return getBookingAudioSlot(db, bookingID)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+)
+
+// GetSurfaceAvailability returns whether a surface is listed among
+// opportunities and booked, or nil if it does not exist
+func (db *DB) GetSurfaceAvailability(surfaceID string) (*models.Availability, error) {
+	var a models.Availability
+	var titleID, surfaceType sql.NullString
+	var prsScore sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT
+			surface_id,
+			title_id,
+			surface_type,
+			prs_score,
+			(`+holdbackClause+`
+				AND `+mergedClause+`
+				AND NOT `+db.staleClause("surfaces")+`),
+			EXISTS (
+				SELECT 1 FROM placement_bookings pb
+				WHERE pb.surface_id = surfaces.surface_id AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+			)
+		FROM surfaces
+		WHERE surface_id = $1
+	`, surfaceID).Scan(&a.SurfaceID, &titleID, &surfaceType, &prsScore, &a.Available, &a.Booked)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query surface availability: %w", err)
+	}
+	a.TitleID = titleID.String
+	a.SurfaceType = surfaceType.String
+	a.PRSScore = prsScore.Float64
+	return &a, nil
+}
+
+// GetBookingSurface returns the surface a booking is for and its status,
+// whichever organization owns it, or "" if the booking does not exist
+func (db *DB) GetBookingSurface(bookingID string) (surfaceID, status string, err error) {
+	err = db.QueryRow(`
+		SELECT surface_id, status FROM placement_bookings WHERE booking_id = $1
+	`, bookingID).Scan(&surfaceID, &status)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to query booking surface: %w", err)
+	}
+	return surfaceID, status, nil
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+)
+
+// ErrBookingNotFound is returned when appending to a booking that does not exist
+var ErrBookingNotFound = errors.New("booking not found")
+
+// queryer is implemented by *DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// execer is implemented by *DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// ListBookingEvents returns a booking's event stream in sequence order
+func (db *DB) ListBookingEvents(bookingID string) ([]booking.Event, error) {
+	return listBookingEvents(db, bookingID)
+}
+
+// GetBookingVersion returns the sequence of a booking's last event, or 0 if
+// it has no events. Long polls call it repeatedly, so it reads one index entry.
+func (db *DB) GetBookingVersion(bookingID string) (int, error) {
+	var version int
+	err := db.QueryRow(`
+		SELECT COALESCE(MAX(sequence), 0) FROM booking_events WHERE booking_id = $1
+	`, bookingID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query booking version: %w", err)
+	}
+	return version, nil
+}
+
+// ListCampaignBookingEvents returns the events up to asOf of every booking that
+// belonged to the campaign at some point, grouped by booking in sequence order.
+// Callers project the streams to find which bookings were in the campaign at asOf.
+func (db *DB) ListCampaignBookingEvents(campaignID string, asOf time.Time) ([]booking.Event, error) {
+	rows, err := db.Query(`
+		SELECT booking_id, sequence, event_type, COALESCE(actor, ''), COALESCE(org_id, ''), data, occurred_at
+		FROM booking_events
+		WHERE booking_id IN (
+				SELECT booking_id FROM booking_events
+				WHERE data->>'campaign_id' = $1 AND occurred_at <= $2
+			)
+			AND occurred_at <= $2
+		ORDER BY booking_id, sequence
+	`, campaignID, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query campaign booking events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBookingEvents(rows)
+}
+
+// BackfillBookingEvents gives every booking without an event stream a created
+// event snapshotting its current row, so history and as-of reads cover
+// bookings made before the event log existed. It returns the number backfilled.
+func (db *DB) BackfillBookingEvents() (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO booking_events (booking_id, sequence, event_type, actor, data, occurred_at)
+		SELECT b.booking_id, 1, $1, 'system',
+			jsonb_strip_nulls(jsonb_build_object(
+				'surface_id', b.surface_id,
+				'advertiser_id', b.advertiser_id,
+				'campaign_id', b.campaign_id,
+				'bid_amount_cpm', b.bid_amount_cpm,
+				'max_impressions', b.estimated_impressions,
+				'min_prs_score', b.min_prs_score,
+				'status', b.status,
+				'reason', 'backfilled from placement_bookings'
+			)),
+			COALESCE(b.booking_time, b.created_at)
+		FROM placement_bookings b
+		WHERE NOT EXISTS (SELECT 1 FROM booking_events e WHERE e.booking_id = b.booking_id)
+		ON CONFLICT (booking_id, sequence) DO NOTHING
+	`, booking.EventCreated)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill booking events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// AppendBookingEvent validates an event against the booking's projected state,
+// appends it to the stream and updates the placement_bookings projection in
+// one transaction. The event's sequence is assigned here.
+func (db *DB) AppendBookingEvent(event booking.Event) (*booking.State, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := appendBookingEvent(tx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit booking event: %w", err)
+	}
+
+	event.OccurredAt = state.UpdatedAt
+	db.publish(bookingChanged(event, state.CampaignID, state.Status))
+	return state, nil
+}
+
+// appendBookingEvent appends an event and updates the projection within a transaction
+func appendBookingEvent(tx *sql.Tx, event booking.Event) (*booking.State, error) {
+	// Lock the booking so concurrent appends get consecutive sequences
+	var created booking.Change
+	var bidAmountCPM, minPRSScore sql.NullFloat64
+	var maxImpressions sql.NullInt64
+	var status sql.NullString
+	var bookedAt time.Time
+	err := tx.QueryRow(`
+		SELECT surface_id, advertiser_id, campaign_id, bid_amount_cpm, estimated_impressions,
+			min_prs_score, status, COALESCE(booking_time, created_at)
+		FROM placement_bookings
+		WHERE booking_id = $1
+		FOR UPDATE
+	`, event.BookingID).Scan(&created.SurfaceID, &created.AdvertiserID, &created.CampaignID,
+		&bidAmountCPM, &maxImpressions, &minPRSScore, &status, &bookedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock booking: %w", err)
+	}
+
+	events, err := listBookingEvents(tx, event.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		// Bookings made before the event log existed get a created event
+		// snapshotting their current row
+		bid, impressions, prs := bidAmountCPM.Float64, int(maxImpressions.Int64), minPRSScore.Float64
+		created.BidAmountCPM, created.MaxImpressions, created.MinPRSScore = &bid, &impressions, &prs
+		created.Status = status.String
+		created.Reason = "backfilled from placement_bookings"
+		backfill := booking.Event{
+			BookingID:  event.BookingID,
+			Sequence:   1,
+			Type:       booking.EventCreated,
+			Actor:      "system",
+			Data:       created,
+			OccurredAt: bookedAt,
+		}
+		if err := insertBookingEvent(tx, backfill); err != nil {
+			return nil, err
+		}
+		events = append(events, backfill)
+	}
+
+	state, err := booking.Project(events)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Sequence = state.Version + 1
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	if err := state.Apply(event); err != nil {
+		return nil, err
+	}
+	if err := insertBookingEvent(tx, event); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE placement_bookings
+		SET surface_id = $2, advertiser_id = $3, campaign_id = $4, bid_amount_cpm = $5,
+			estimated_impressions = $6, min_prs_score = $7, status = $8, confirmation_time = $9
+		WHERE booking_id = $1
+	`, state.BookingID, state.SurfaceID, state.AdvertiserID, state.CampaignID, state.BidAmountCPM,
+		state.MaxImpressions, state.MinPRSScore, state.Status, state.ConfirmedAt)
+	if err != nil {
+		return nil, overlapError(fmt.Errorf("failed to update booking projection: %w", err))
+	}
+	return state, nil
+}
+
+// insertBookingEvent appends an event within a transaction
+func insertBookingEvent(tx *sql.Tx, event booking.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode booking event: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO booking_events (booking_id, sequence, event_type, actor, org_id, data, occurred_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+	`, event.BookingID, event.Sequence, event.Type, event.Actor, event.OrgID, data, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert booking event: %w", err)
+	}
+	return nil
+}
+
+// listBookingEvents loads a booking's event stream in sequence order
+func listBookingEvents(q queryer, bookingID string) ([]booking.Event, error) {
+	rows, err := q.Query(`
+		SELECT booking_id, sequence, event_type, COALESCE(actor, ''), COALESCE(org_id, ''), data, occurred_at
+		FROM booking_events
+		WHERE booking_id = $1
+		ORDER BY sequence
+	`, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBookingEvents(rows)
+}
+
+// scanBookingEvents scans booking_events rows
+func scanBookingEvents(rows *sql.Rows) ([]booking.Event, error) {
+	events := make([]booking.Event, 0)
+	for rows.Next() {
+		var event booking.Event
+		var data []byte
+		if err := rows.Scan(&event.BookingID, &event.Sequence, &event.Type, &event.Actor, &event.OrgID, &data, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan booking event: %w", err)
+		}
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode booking event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
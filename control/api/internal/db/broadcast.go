@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/broadcast"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// ListSignalingWindows lists the placement windows of a title's confirmed
+// and active bookings visible within scope, in order of media time
+func (db *DB) ListSignalingWindows(scope tenant.Scope, titleID string) ([]broadcast.Window, error) {
+	where := query.New()
+	where.Where("s.title_id::text = %s", titleID)
+	where.Add("placement_bookings.status IN ('confirmed', 'active')")
+	whereBookingVisible(where, scope)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT placement_bookings.booking_id, placement_bookings.surface_id,
+			COALESCE(placement_bookings.campaign_id, ''), COALESCE(placement_bookings.creative_asset_id, ''),
+			s.placement_type, s.start_time, s.end_time
+		FROM placement_bookings
+		JOIN surfaces s ON s.surface_id = placement_bookings.surface_id
+		WHERE %s
+		ORDER BY s.start_time, placement_bookings.booking_id
+	`, where.Clause()), where.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signaling windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := make([]broadcast.Window, 0)
+	for rows.Next() {
+		var w broadcast.Window
+		if err := rows.Scan(&w.BookingID, &w.SurfaceID, &w.CampaignID, &w.CreativeAssetID, &w.PlacementType, &w.Start, &w.End); err != nil {
+			return nil, fmt.Errorf("failed to scan signaling window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+)
+
+// budgetQuery selects budgets with their authoritative spend: served
+// decisions of bookings billed per impression, and measured exposures of
+// bookings billed on them
+const budgetQuery = `
+		SELECT b.campaign_id, b.amount, COALESCE(b.updated_by, ''), b.updated_at,
+			COALESCE((
+				SELECT SUM(d.spend) FROM decision_events d
+				WHERE d.campaign_id = b.campaign_id AND d.outcome = 'served'
+			), 0) + COALESCE((
+				SELECT SUM(e.spend) FROM exposure_events e
+				WHERE e.campaign_id = b.campaign_id
+			), 0)
+		FROM campaign_budgets b`
+
+func scanBudget(row interface{ Scan(...interface{}) error }) (*budget.Budget, error) {
+	var b budget.Budget
+	if err := row.Scan(&b.CampaignID, &b.Amount, &b.UpdatedBy, &b.UpdatedAt, &b.Spent); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetBudget returns a campaign's budget and spend, or nil if it has no budget
+func (db *DB) GetBudget(campaignID string) (*budget.Budget, error) {
+	b, err := scanBudget(db.QueryRow(budgetQuery+` WHERE b.campaign_id = $1`, campaignID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget: %w", err)
+	}
+	return b, nil
+}
+
+// ListBudgets returns every campaign budget with its spend
+func (db *DB) ListBudgets() ([]budget.Budget, error) {
+	rows, err := db.Query(budgetQuery + ` ORDER BY b.campaign_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := make([]budget.Budget, 0)
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+// SetBudget creates or replaces a campaign's budget
+func (db *DB) SetBudget(b *budget.Budget) error {
+	return setBudget(db, b)
+}
+
+// setBudget creates or replaces a campaign's budget, within a transaction or not
+func setBudget(e execer, b *budget.Budget) error {
+	_, err := e.Exec(`
+		INSERT INTO campaign_budgets (campaign_id, amount, updated_by, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (campaign_id) DO UPDATE SET
+			amount = EXCLUDED.amount,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, b.CampaignID, b.Amount, b.UpdatedBy, b.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save budget: %w", err)
+	}
+	return nil
+}
+
+// DeleteBudget removes a campaign's budget. It reports whether one existed.
+func (db *DB) DeleteBudget(campaignID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM campaign_budgets WHERE campaign_id = $1`, campaignID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete budget: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete budget: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetBookingCharge returns the campaign and billing terms of a booking, or
+// nil if the booking does not exist
+func (db *DB) GetBookingCharge(bookingID string) (*budget.Charge, error) {
+	charge := budget.Charge{BookingID: bookingID}
+	err := db.QueryRow(`
+		SELECT pb.campaign_id, pb.billing_model, COALESCE(pb.final_cpm_rate, pb.bid_amount_cpm),
+			COALESCE(pb.min_visibility_duration, 0),
+			CASE WHEN s.placement_type = 'audio' THEN s.end_time - s.start_time ELSE 0 END
+		FROM placement_bookings pb
+		LEFT JOIN surfaces s ON s.surface_id = pb.surface_id
+		WHERE pb.booking_id = $1
+	`, bookingID).Scan(&charge.CampaignID, &charge.Terms.Model, &charge.Terms.CPM, &charge.Terms.MinDuration, &charge.Terms.SlotDuration)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking charge: %w", err)
+	}
+	return &charge, nil
+}
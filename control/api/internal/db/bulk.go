@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// SelectBookings returns the live (pending, confirmed, active or paused)
+// bookings matching a selector that are visible within a scope, oldest
+// first. Visibility follows ListPlacementBookings; callers still check manage
+// permission per booking.
+func (db *DB) SelectBookings(scope tenant.Scope, selector booking.Selector, limit int) ([]booking.Summary, error) {
+	visibility, visibilityArgs := bookingVisibility(scope, 4)
+	labelClause, labelArgs := labelFilter(labels.ResourceBooking, "booking_id", selector.Labels, 6)
+
+	query := fmt.Sprintf(`
+		SELECT
+			booking_id, surface_id, advertiser_id, campaign_id, status,
+			bid_amount_cpm, estimated_impressions, actual_impressions, billing_model,
+			COALESCE((
+				SELECT SUM(e.spend) FROM exposure_events e
+				WHERE e.booking_id = placement_bookings.booking_id
+			), 0)
+		FROM placement_bookings
+		WHERE status IN ('pending', 'confirmed', 'active', 'paused')
+			AND ($1 = '' OR campaign_id = $1)
+			AND ($2 = '' OR advertiser_id = $2)
+			AND %s
+			AND %s
+		ORDER BY booking_time, booking_id
+		LIMIT $3
+	`, visibility, labelClause)
+
+	args := append([]interface{}{selector.CampaignID, selector.AdvertiserID, limit}, visibilityArgs...)
+	args = append(args, labelArgs...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := make([]booking.Summary, 0)
+	for rows.Next() {
+		var summary booking.Summary
+		var surfaceID, status sql.NullString
+		var bidAmountCPM sql.NullFloat64
+		var estimatedImpressions, actualImpressions sql.NullInt64
+
+		if err := rows.Scan(&summary.BookingID, &surfaceID, &summary.AdvertiserID, &summary.CampaignID, &status,
+			&bidAmountCPM, &estimatedImpressions, &actualImpressions, &summary.BillingModel, &summary.MeasuredSpend); err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		summary.SurfaceID = surfaceID.String
+		summary.Status = status.String
+		summary.BidAmountCPM = bidAmountCPM.Float64
+		summary.EstimatedImpressions = estimatedImpressions.Int64
+		summary.ActualImpressions = actualImpressions.Int64
+		bookings = append(bookings, summary)
+	}
+	return bookings, rows.Err()
+}
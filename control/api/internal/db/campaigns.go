@@ -0,0 +1,395 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/lib/pq"
+)
+
+// ErrAdvertiserExists is returned when creating an advertiser whose ID is taken
+var ErrAdvertiserExists = errors.New("advertiser already exists")
+
+// ErrCampaignExists is returned when creating a campaign whose ID is taken
+var ErrCampaignExists = errors.New("campaign already exists")
+
+const advertiserQuery = `
+		SELECT advertiser_id, name, COALESCE(org_id, ''), COALESCE(created_by, ''), created_at, updated_at, archived_at
+		FROM advertisers`
+
+// campaignQuery selects campaigns with their owning organization and budget
+const campaignQuery = `
+		SELECT c.campaign_id, c.advertiser_id, c.name, COALESCE(o.org_id, ''), b.amount,
+			c.flight_start, c.flight_end, c.frequency_cap_max, c.frequency_cap_window_hours,
+			COALESCE(c.created_by, ''), c.created_at, c.updated_at, c.archived_at
+		FROM campaigns c
+		LEFT JOIN resource_owners o ON o.resource_type = 'campaign' AND o.resource_id = c.campaign_id
+		LEFT JOIN campaign_budgets b ON b.campaign_id = c.campaign_id`
+
+func scanAdvertiser(row rowScanner) (*campaign.Advertiser, error) {
+	var a campaign.Advertiser
+	var archivedAt sql.NullTime
+	if err := row.Scan(&a.AdvertiserID, &a.Name, &a.OrgID, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt, &archivedAt); err != nil {
+		return nil, err
+	}
+	a.Status = campaign.StatusActive
+	if archivedAt.Valid {
+		a.ArchivedAt, a.Status = &archivedAt.Time, campaign.StatusArchived
+	}
+	return &a, nil
+}
+
+func scanCampaign(row rowScanner) (*campaign.Campaign, error) {
+	var c campaign.Campaign
+	var budgetAmount sql.NullFloat64
+	var flightStart, flightEnd, archivedAt sql.NullTime
+	var capMax, capWindow sql.NullInt64
+	if err := row.Scan(&c.CampaignID, &c.AdvertiserID, &c.Name, &c.OrgID, &budgetAmount,
+		&flightStart, &flightEnd, &capMax, &capWindow, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &archivedAt); err != nil {
+		return nil, err
+	}
+	c.FrequencyCap = frequencyCap(capMax, capWindow)
+	if budgetAmount.Valid {
+		c.Budget = &budgetAmount.Float64
+	}
+	if flightStart.Valid {
+		c.FlightStart = &flightStart.Time
+	}
+	if flightEnd.Valid {
+		c.FlightEnd = &flightEnd.Time
+	}
+	c.Status = campaign.StatusActive
+	if archivedAt.Valid {
+		c.ArchivedAt, c.Status = &archivedAt.Time, campaign.StatusArchived
+	}
+	return &c, nil
+}
+
+// CreateAdvertiser stores a new advertiser
+func (db *DB) CreateAdvertiser(a *campaign.Advertiser) error {
+	err := db.QueryRow(`
+		INSERT INTO advertisers (advertiser_id, name, org_id, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING created_at, updated_at
+	`, a.AdvertiserID, a.Name, a.OrgID, a.CreatedBy).Scan(&a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%w: %s", ErrAdvertiserExists, a.AdvertiserID)
+		}
+		return fmt.Errorf("failed to create advertiser: %w", err)
+	}
+	a.Status = campaign.StatusActive
+	return nil
+}
+
+// GetAdvertiser retrieves an advertiser by ID
+func (db *DB) GetAdvertiser(advertiserID string) (*campaign.Advertiser, error) {
+	a, err := scanAdvertiser(db.QueryRow(advertiserQuery+` WHERE advertiser_id = $1`, advertiserID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query advertiser: %w", err)
+	}
+	return a, nil
+}
+
+// ListAdvertisers lists the advertisers within a scope, newest first.
+// Pages are keyed on creation time and ID.
+func (db *DB) ListAdvertisers(scope tenant.Scope, includeArchived bool, page pagination.Page) ([]*campaign.Advertiser, error) {
+	var cursorTime time.Time
+	if page.Cursor != nil {
+		var err error
+		if cursorTime, err = page.Cursor.Time(); err != nil {
+			return nil, err
+		}
+	}
+	visibility, visibilityArgs := tenantFilter("org_id", scope, 4)
+	keysetClause, direction, keysetArgs := keysetFilter("created_at", "advertiser_id", page, cursorTime, 6)
+	query := fmt.Sprintf(advertiserQuery+`
+		WHERE ($1 OR archived_at IS NULL)
+			AND %s
+			AND %s
+		ORDER BY created_at %s, advertiser_id %s
+		LIMIT $2 OFFSET $3
+	`, visibility, keysetClause, direction, direction)
+
+	args := append([]interface{}{includeArchived, page.Fetch(), page.Offset}, visibilityArgs...)
+	args = append(args, keysetArgs...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query advertisers: %w", err)
+	}
+	defer rows.Close()
+
+	advertisers := make([]*campaign.Advertiser, 0)
+	for rows.Next() {
+		a, err := scanAdvertiser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan advertiser: %w", err)
+		}
+		advertisers = append(advertisers, a)
+	}
+	return advertisers, rows.Err()
+}
+
+// UpdateAdvertiser saves an advertiser's name and archived state. It
+// reports whether the advertiser exists.
+func (db *DB) UpdateAdvertiser(a *campaign.Advertiser) (bool, error) {
+	err := db.QueryRow(`
+		UPDATE advertisers SET name = $2, archived_at = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE advertiser_id = $1
+		RETURNING updated_at
+	`, a.AdvertiserID, a.Name, a.ArchivedAt).Scan(&a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update advertiser: %w", err)
+	}
+	return true, nil
+}
+
+// CreateCampaign stores a new campaign of an existing advertiser, owned by
+// c.OrgID, together with its budget
+func (db *DB) CreateCampaign(c *campaign.Campaign) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	capMax, capWindow := frequencyCapColumns(c.FrequencyCap)
+	err = tx.QueryRow(`
+		INSERT INTO campaigns (campaign_id, advertiser_id, name, flight_start, flight_end,
+			frequency_cap_max, frequency_cap_window_hours, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING created_at, updated_at
+	`, c.CampaignID, c.AdvertiserID, c.Name, c.FlightStart, c.FlightEnd, capMax, capWindow, c.CreatedBy).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%w: %s", ErrCampaignExists, c.CampaignID)
+		}
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	if c.OrgID != "" {
+		if err := registerResourceOwner(tx, "campaign", c.CampaignID, c.OrgID); err != nil {
+			return err
+		}
+	}
+	if c.Budget != nil {
+		b := &budget.Budget{CampaignID: c.CampaignID, Amount: *c.Budget, UpdatedBy: c.CreatedBy, UpdatedAt: c.CreatedAt}
+		if err := setBudget(tx, b); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit campaign: %w", err)
+	}
+	c.Status = campaign.StatusActive
+	return nil
+}
+
+// GetCampaign retrieves a campaign by ID
+func (db *DB) GetCampaign(campaignID string) (*campaign.Campaign, error) {
+	c, err := scanCampaign(db.QueryRow(campaignQuery+` WHERE c.campaign_id = $1`, campaignID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query campaign: %w", err)
+	}
+	return c, nil
+}
+
+// ListCampaigns lists the campaigns within a scope or shared with one of its
+// organizations, newest first. Pages are keyed on creation time and ID.
+func (db *DB) ListCampaigns(scope tenant.Scope, filter campaign.Filter, page pagination.Page) ([]*campaign.Campaign, error) {
+	var cursorTime time.Time
+	if page.Cursor != nil {
+		var err error
+		if cursorTime, err = page.Cursor.Time(); err != nil {
+			return nil, err
+		}
+	}
+	owned, visibilityArgs := tenantFilter("o.org_id", scope, 6)
+	keysetClause, direction, keysetArgs := keysetFilter("c.created_at", "c.campaign_id", page, cursorTime, 8)
+	query := fmt.Sprintf(campaignQuery+`
+		WHERE ($1 = '' OR c.advertiser_id = $1)
+			AND ($2 OR c.archived_at IS NULL)
+			AND (
+				%s
+				OR c.campaign_id IN (
+					SELECT resource_id FROM resource_grants
+					WHERE resource_type = 'campaign' AND grantee_org_id = ANY($7) AND revoked_at IS NULL
+				)
+			)
+			AND %s
+		ORDER BY c.created_at %s, c.campaign_id %s
+		LIMIT $3 OFFSET $4
+	`, owned, keysetClause, direction, direction)
+
+	args := append([]interface{}{filter.AdvertiserID, filter.IncludeArchived, page.Fetch(), page.Offset}, visibilityArgs...)
+	args = append(args, keysetArgs...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := make([]*campaign.Campaign, 0)
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+// UpdateCampaign saves a campaign's name, flight dates, frequency cap and
+// archived state, and b as its budget unless nil. It reports whether the
+// campaign exists.
+func (db *DB) UpdateCampaign(c *campaign.Campaign, b *budget.Budget) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	capMax, capWindow := frequencyCapColumns(c.FrequencyCap)
+	err = tx.QueryRow(`
+		UPDATE campaigns SET name = $2, flight_start = $3, flight_end = $4, archived_at = $5,
+			frequency_cap_max = $6, frequency_cap_window_hours = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1
+		RETURNING updated_at
+	`, c.CampaignID, c.Name, c.FlightStart, c.FlightEnd, c.ArchivedAt, capMax, capWindow).Scan(&c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update campaign: %w", err)
+	}
+	if b != nil {
+		if err := setBudget(tx, b); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit campaign: %w", err)
+	}
+	return true, nil
+}
+
+// checkCampaign refuses a booking whose campaign does not exist, belongs to
+// another advertiser, has ended, or whose campaign or advertiser is
+// archived. The campaign is locked until tx ends so it cannot be archived
+// meanwhile.
+func checkCampaign(tx *sql.Tx, booking *models.NewBooking, bookedAt time.Time) error {
+	c := campaign.Campaign{CampaignID: booking.CampaignID}
+	var flightEnd, archivedAt, advertiserArchivedAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT c.advertiser_id, c.flight_end, c.archived_at, a.archived_at
+		FROM campaigns c
+		JOIN advertisers a ON a.advertiser_id = c.advertiser_id
+		WHERE c.campaign_id = $1
+		FOR SHARE OF c
+	`, booking.CampaignID).Scan(&c.AdvertiserID, &flightEnd, &archivedAt, &advertiserArchivedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: campaign %s does not exist", campaign.ErrNotBookable, booking.CampaignID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check campaign: %w", err)
+	}
+	if advertiserArchivedAt.Valid {
+		return fmt.Errorf("%w: advertiser %s is archived", campaign.ErrNotBookable, c.AdvertiserID)
+	}
+	if flightEnd.Valid {
+		c.FlightEnd = &flightEnd.Time
+	}
+	if archivedAt.Valid {
+		c.ArchivedAt = &archivedAt.Time
+	}
+	return c.Bookable(booking.AdvertiserID, bookedAt)
+}
+
+// ensureCampaign creates a campaign that documents and imports declare
+// implicitly, owned by orgID. Its advertiser must already exist; an
+// existing campaign is left as it is.
+func ensureCampaign(tx *sql.Tx, c *campaign.Campaign, orgID string) error {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM advertisers WHERE advertiser_id = $1)`, c.AdvertiserID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check advertiser: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: advertiser %s does not exist", campaign.ErrNotBookable, c.AdvertiserID)
+	}
+
+	name := c.Name
+	if name == "" {
+		name = c.CampaignID
+	}
+	_, err = tx.Exec(`
+		INSERT INTO campaigns (campaign_id, advertiser_id, name, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (campaign_id) DO NOTHING
+	`, c.CampaignID, c.AdvertiserID, name, c.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	if orgID != "" {
+		return registerResourceOwner(tx, "campaign", c.CampaignID, orgID)
+	}
+	return nil
+}
+
+// BackfillCampaigns creates the advertisers and campaigns named by bookings
+// made before they were tracked, so those campaigns can still be booked. An
+// advertiser belongs to the organization of its bookings when they all
+// share one. It returns the number of campaigns created.
+func (db *DB) BackfillCampaigns() (int64, error) {
+	_, err := db.Exec(`
+		INSERT INTO advertisers (advertiser_id, name, org_id, created_by)
+		SELECT advertiser_id, advertiser_id,
+			CASE WHEN COUNT(DISTINCT org_id) = 1 AND COUNT(org_id) = COUNT(*) THEN MIN(org_id) END,
+			'system'
+		FROM placement_bookings
+		WHERE advertiser_id IS NOT NULL AND advertiser_id <> ''
+		GROUP BY advertiser_id
+		ON CONFLICT (advertiser_id) DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill advertisers: %w", err)
+	}
+
+	// A campaign booked for several advertisers keeps the earliest booking's
+	result, err := db.Exec(`
+		INSERT INTO campaigns (campaign_id, advertiser_id, name, created_by)
+		SELECT DISTINCT ON (campaign_id) campaign_id, advertiser_id, campaign_id, 'system'
+		FROM placement_bookings
+		WHERE campaign_id IS NOT NULL AND campaign_id <> '' AND advertiser_id IS NOT NULL AND advertiser_id <> ''
+		ORDER BY campaign_id, booking_time
+		ON CONFLICT (campaign_id) DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill campaigns: %w", err)
+	}
+	backfilled, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill campaigns: %w", err)
+	}
+	return backfilled, nil
+}
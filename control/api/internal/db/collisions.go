@@ -0,0 +1,205 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+)
+
+// collisionRuleColumns are scanned by scanCollisionRule
+const collisionRuleColumns = `r.title_id::text, r.shot_id, r.max_concurrent, r.min_separation, r.reason, r.updated_by, r.updated_at`
+
+// ListCollisionRules returns a title's collision rules, its default rule first
+func (db *DB) ListCollisionRules(titleID string) ([]collision.Rule, error) {
+	rows, err := db.Query(`
+		SELECT `+collisionRuleColumns+`
+		FROM collision_rules r
+		WHERE r.title_id::text = $1
+		ORDER BY r.shot_id
+	`, titleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collision rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]collision.Rule, 0)
+	for rows.Next() {
+		rule, err := scanCollisionRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+// SetCollisionRule creates or replaces a title's default rule, or the rule of
+// one of its shots. It returns ErrTitleNotFound when the title, or the shot,
+// does not exist.
+func (db *DB) SetCollisionRule(rule *collision.Rule) error {
+	result, err := db.Exec(`
+		INSERT INTO collision_rules (title_id, shot_id, max_concurrent, min_separation, reason, updated_by, updated_at)
+		SELECT t.id, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7 FROM titles t
+		WHERE t.id::text = $1
+			AND ($2 = '' OR EXISTS (SELECT 1 FROM shots sh WHERE sh.title_id = t.id AND sh.shot_id = $2))
+		ON CONFLICT (title_id, shot_id) DO UPDATE SET
+			max_concurrent = EXCLUDED.max_concurrent,
+			min_separation = EXCLUDED.min_separation,
+			reason = EXCLUDED.reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, rule.TitleID, rule.ShotID, rule.MaxConcurrent, rule.MinSeparation, rule.Reason, rule.UpdatedBy, rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save collision rule: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save collision rule: %w", err)
+	}
+	if affected == 0 {
+		return ErrTitleNotFound
+	}
+	return nil
+}
+
+// DeleteCollisionRule removes a title's default rule, or the rule of one of
+// its shots. It reports whether one existed.
+func (db *DB) DeleteCollisionRule(titleID, shotID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM collision_rules WHERE title_id::text = $1 AND shot_id = $2`, titleID, shotID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete collision rule: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete collision rule: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// CheckServeCollision returns collision.ErrCollision when serving a booking
+// would break the collision rule of its surface's shot. Rules may have been
+// set after bookings were made, so the earliest bookings win: only live
+// bookings made before this one count against it.
+func (db *DB) CheckServeCollision(bookingID string) error {
+	var p collision.Placement
+	var bundleID sql.NullString
+	var bookedAt time.Time
+	err := db.QueryRow(`
+		SELECT booking_id, surface_id, campaign_id, bundle_id, COALESCE(booking_time, created_at)
+		FROM placement_bookings
+		WHERE booking_id = $1
+	`, bookingID).Scan(&p.BookingID, &p.SurfaceID, &p.CampaignID, &bundleID, &bookedAt)
+	if err == sql.ErrNoRows {
+		return nil // Unknown bookings are refused elsewhere
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query booking: %w", err)
+	}
+	p.BundleID = bundleID.String
+
+	rule, err := collisionRuleFor(db, p.SurfaceID, false)
+	if err != nil || rule == nil {
+		return err
+	}
+	others, err := concurrentPlacements(db, p.SurfaceID, &p.BookingID, bookedAt)
+	if err != nil {
+		return err
+	}
+	return rule.Check(p, others)
+}
+
+// checkCollision rejects booking a surface that would break the collision
+// rule of its shot. It locks the rule so concurrent bookings of the shot
+// cannot both take its last slot.
+func checkCollision(tx *sql.Tx, booking *models.NewBooking) error {
+	rule, err := collisionRuleFor(tx, booking.SurfaceID, true)
+	if err != nil || rule == nil {
+		return err
+	}
+	others, err := concurrentPlacements(tx, booking.SurfaceID, nil, time.Time{})
+	if err != nil {
+		return err
+	}
+	return rule.Check(collision.Placement{
+		SurfaceID:  booking.SurfaceID,
+		CampaignID: booking.CampaignID,
+		BundleID:   booking.BundleID,
+	}, others)
+}
+
+// collisionRuleFor returns the rule of a surface's shot, or of its title when
+// the shot has none, or nil when neither has a rule
+func collisionRuleFor(q queryer, surfaceID string, lock bool) (*collision.Rule, error) {
+	query := `
+		SELECT ` + collisionRuleColumns + `
+		FROM surfaces s
+		JOIN shots sh ON sh.id = s.shot_id
+		JOIN collision_rules r ON r.title_id = s.title_id AND r.shot_id IN (sh.shot_id, '')
+		WHERE s.surface_id = $1
+		ORDER BY r.shot_id DESC
+		LIMIT 1`
+	if lock {
+		query += ` FOR UPDATE OF r`
+	}
+	rows, err := q.Query(query, surfaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collision rule: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanCollisionRule(rows)
+}
+
+// concurrentPlacements lists live bookings of the other surfaces of a
+// surface's shot that are on screen at the same time as it. With
+// bookingID set, only bookings made before that booking are listed.
+func concurrentPlacements(q queryer, surfaceID string, bookingID *string, bookedAt time.Time) ([]collision.Placement, error) {
+	rows, err := q.Query(`
+		SELECT pb.booking_id, pb.surface_id, pb.campaign_id, COALESCE(pb.bundle_id, ''),
+			COALESCE(ST_Distance(s.geometry, o.geometry), -1)
+		FROM surfaces s
+		JOIN surfaces o ON o.shot_id = s.shot_id AND o.surface_id <> s.surface_id
+			AND o.start_time < s.end_time AND s.start_time < o.end_time
+		JOIN placement_bookings pb ON pb.surface_id = o.surface_id
+		WHERE s.surface_id = $1
+			AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+			AND ($2::text IS NULL OR (COALESCE(pb.booking_time, pb.created_at), pb.booking_id) < ($3, $2::text))
+		ORDER BY COALESCE(pb.booking_time, pb.created_at), pb.booking_id
+	`, surfaceID, bookingID, bookedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query concurrent placements: %w", err)
+	}
+	defer rows.Close()
+
+	placements := make([]collision.Placement, 0)
+	for rows.Next() {
+		var p collision.Placement
+		if err := rows.Scan(&p.BookingID, &p.SurfaceID, &p.CampaignID, &p.BundleID, &p.Separation); err != nil {
+			return nil, fmt.Errorf("failed to scan concurrent placement: %w", err)
+		}
+		placements = append(placements, p)
+	}
+	return placements, rows.Err()
+}
+
+// scanCollisionRule reads collisionRuleColumns
+func scanCollisionRule(row rowScanner) (*collision.Rule, error) {
+	var rule collision.Rule
+	var reason, updatedBy sql.NullString
+	var updatedAt sql.NullTime
+	if err := row.Scan(&rule.TitleID, &rule.ShotID, &rule.MaxConcurrent, &rule.MinSeparation,
+		&reason, &updatedBy, &updatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan collision rule: %w", err)
+	}
+	rule.Reason = reason.String
+	rule.UpdatedBy = updatedBy.String
+	rule.UpdatedAt = updatedAt.Time
+	return &rule, nil
+}
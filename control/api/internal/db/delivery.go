@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordDecisionEvent records the outcome of a placement decision for a
+// surface. Served decisions carry the spend charged to their campaign.
+func (db *DB) RecordDecisionEvent(event map[string]interface{}) (string, error) {
+	eventID := fmt.Sprintf("decision_%s_%d", event["surface_id"], time.Now().UnixNano())
+
+	eventTimestamp, ok := event["event_timestamp"].(time.Time)
+	if !ok {
+		eventTimestamp = time.Now()
+	}
+
+	query := `
+		INSERT INTO decision_events (
+			event_id, surface_id, title_id, booking_id,
+			outcome, error_code, event_timestamp, campaign_id, spend
+		) VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9)
+	`
+
+	campaignID, _ := event["campaign_id"].(string)
+	spend, _ := event["spend"].(float64)
+	_, err := db.Exec(query,
+		eventID,
+		event["surface_id"],
+		event["title_id"],
+		event["booking_id"],
+		event["outcome"],
+		event["error_code"],
+		eventTimestamp,
+		campaignID,
+		spend,
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to record decision event: %w", err)
+	}
+
+	return eventID, nil
+}
+
+// GetFillRates computes fill and serve-error rates per surface since the given time
+func (db *DB) GetFillRates(titleID string, since time.Time) ([]map[string]interface{}, error) {
+	query := `
+		SELECT
+			title_id,
+			surface_id,
+			COUNT(*) AS opportunities,
+			COUNT(*) FILTER (WHERE outcome = 'served') AS served,
+			COUNT(*) FILTER (WHERE outcome = 'error') AS errors
+		FROM decision_events
+		WHERE ($1 = '' OR title_id = $1)
+			AND event_timestamp >= $2
+		GROUP BY title_id, surface_id
+		ORDER BY title_id, surface_id
+	`
+
+	rows, err := db.Query(query, titleID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fill rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var title, surfaceID string
+		var opportunities, served, errors int64
+
+		if err := rows.Scan(&title, &surfaceID, &opportunities, &served, &errors); err != nil {
+			return nil, fmt.Errorf("failed to scan fill rate: %w", err)
+		}
+
+		rates = append(rates, map[string]interface{}{
+			"title_id":      title,
+			"surface_id":    surfaceID,
+			"opportunities": opportunities,
+			"served":        served,
+			"errors":        errors,
+		})
+	}
+
+	return rates, rows.Err()
+}
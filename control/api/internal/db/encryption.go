@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/crypto"
+)
+
+// Columns encrypted at rest
+var (
+	// ViewerIDColumn holds viewer hashes. It is deterministic so exposure
+	// queries can still count unique viewers and group by viewer.
+	ViewerIDColumn = crypto.Column{Table: "exposure_events", Column: "viewer_id", IDColumn: "id", Deterministic: true}
+	// ManifestViewerIDColumn holds the viewers personalized playlists were
+	// served to. It is deterministic so a viewer's serves can be looked up.
+	ManifestViewerIDColumn = crypto.Column{Table: "manifest_serves", Column: "viewer_id", IDColumn: "id", Deterministic: true}
+	// ConsentStringColumn holds the viewer's consent string, e.g. a TCF string
+	ConsentStringColumn = crypto.Column{Table: "exposure_events", Column: "consent_string", IDColumn: "id"}
+	// KeySecretHashColumn holds service account key hashes
+	KeySecretHashColumn = crypto.Column{Table: "service_account_keys", Column: "secret_hash", IDColumn: "key_id"}
+	// WebhookSecretColumn holds the secrets webhook deliveries are signed with
+	WebhookSecretColumn = crypto.Column{Table: "webhook_subscriptions", Column: "secret", IDColumn: "subscription_id"}
+	// ReportWebhookSecretColumn holds the secrets async report callbacks are signed with
+	ReportWebhookSecretColumn = crypto.Column{Table: "report_jobs", Column: "webhook_secret", IDColumn: "job_id"}
+	// ExportHashKeyColumn holds the keys organizations' restricted export columns are hashed with
+	ExportHashKeyColumn = crypto.Column{Table: "export_policies", Column: "hash_key", IDColumn: "org_id"}
+)
+
+// EncryptedColumns lists every column sealed by the field keyring, in the
+// order rotation re-encrypts them
+var EncryptedColumns = []crypto.Column{KeySecretHashColumn, WebhookSecretColumn, ReportWebhookSecretColumn, ExportHashKeyColumn, ConsentStringColumn, ViewerIDColumn, ManifestViewerIDColumn}
+
+// SetFieldEncryption seals EncryptedColumns with keyring on write and opens
+// them on read. Values written before are read as plaintext until a rotation
+// job encrypts them.
+func (db *DB) SetFieldEncryption(keyring *crypto.Keyring) {
+	db.fields = keyring
+}
+
+// ListDataKeys lists the stored data encryption keys
+func (db *DB) ListDataKeys() ([]*crypto.DataKey, error) {
+	rows, err := db.Query(`
+		SELECT key_id, master_key_id, wrapped_key, created_at, rewrapped_at, retired_at
+		FROM encryption_keys
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query encryption keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*crypto.DataKey, 0)
+	for rows.Next() {
+		var key crypto.DataKey
+		var rewrappedAt, retiredAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.MasterKeyID, &key.WrappedKey, &key.CreatedAt, &rewrappedAt, &retiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan encryption key: %w", err)
+		}
+		if rewrappedAt.Valid {
+			key.RewrappedAt = &rewrappedAt.Time
+		}
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateDataKey stores a new wrapped data key
+func (db *DB) CreateDataKey(key *crypto.DataKey) error {
+	_, err := db.Exec(`
+		INSERT INTO encryption_keys (key_id, master_key_id, wrapped_key, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, key.ID, key.MasterKeyID, key.WrappedKey, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create encryption key: %w", err)
+	}
+	return nil
+}
+
+// RewrapDataKey replaces a data key's wrapping after a master key rotation
+func (db *DB) RewrapDataKey(keyID, masterKeyID string, wrapped []byte) error {
+	_, err := db.Exec(`
+		UPDATE encryption_keys
+		SET master_key_id = $2, wrapped_key = $3, rewrapped_at = CURRENT_TIMESTAMP
+		WHERE key_id = $1
+	`, keyID, masterKeyID, wrapped)
+	if err != nil {
+		return fmt.Errorf("failed to rewrap encryption key: %w", err)
+	}
+	return nil
+}
+
+// RetireDataKeys retires every unretired data key created before exceptKeyID
+func (db *DB) RetireDataKeys(exceptKeyID string) (int, error) {
+	result, err := db.Exec(`
+		UPDATE encryption_keys SET retired_at = CURRENT_TIMESTAMP
+		WHERE retired_at IS NULL
+			AND key_id <> $1
+			AND created_at < (SELECT created_at FROM encryption_keys WHERE key_id = $1)
+	`, exceptKeyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retire encryption keys: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retire encryption keys: %w", err)
+	}
+	return int(affected), nil
+}
+
+// StaleValues lists values of col not sealed with keyID, in row key order
+func (db *DB) StaleValues(col crypto.Column, keyID, after string, limit int) ([]crypto.StoredValue, error) {
+	cursor := ""
+	args := []interface{}{crypto.KeyPattern(keyID), limit}
+	if after != "" {
+		cursor = fmt.Sprintf("AND %s > $3", col.IDColumn)
+		args = append(args, after)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[2]s::text, %[3]s
+		FROM %[1]s
+		WHERE %[3]s IS NOT NULL AND %[3]s <> '' AND %[3]s NOT LIKE $1
+			%[4]s
+		ORDER BY %[2]s
+		LIMIT $2
+	`, col.Table, col.IDColumn, col.Column, cursor)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", col, err)
+	}
+	defer rows.Close()
+
+	values := make([]crypto.StoredValue, 0)
+	for rows.Next() {
+		var v crypto.StoredValue
+		if err := rows.Scan(&v.ID, &v.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", col, err)
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
+
+// ReplaceValue rewrites one value of col if it still holds old
+func (db *DB) ReplaceValue(col crypto.Column, id, old, replacement string) error {
+	query := fmt.Sprintf(`UPDATE %[1]s SET %[3]s = $3 WHERE %[2]s = $1 AND %[3]s = $2`, col.Table, col.IDColumn, col.Column)
+	if _, err := db.Exec(query, id, old, replacement); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", col, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+)
+
+// SetEventBus publishes committed changes to bookings and surfaces on bus
+func (db *DB) SetEventBus(bus *eventbus.Bus) {
+	db.bus = bus
+}
+
+// publish hands events to the event bus, if one is set. Call it only once
+// the transaction making the changes has committed.
+func (db *DB) publish(events ...eventbus.Event) {
+	for _, event := range events {
+		db.bus.Publish(context.Background(), event)
+	}
+}
+
+// bookingChanged describes an event appended to a booking's stream
+func bookingChanged(event booking.Event, campaignID, status string) eventbus.BookingChanged {
+	if campaignID == "" {
+		campaignID = event.Data.CampaignID
+	}
+	return eventbus.BookingChanged{
+		BookingID:  event.BookingID,
+		CampaignID: campaignID,
+		Type:       event.Type,
+		Status:     status,
+		Actor:      event.Actor,
+		OrgID:      event.OrgID,
+		OccurredAt: event.OccurredAt,
+	}
+}
+
+// bookingsCreated describes bookings created together for a campaign.
+// createPlacementBooking always confirms new bookings.
+func bookingsCreated(bookingIDs []string, campaignID, actor, orgID string, at time.Time) []eventbus.Event {
+	events := make([]eventbus.Event, 0, len(bookingIDs))
+	for _, bookingID := range bookingIDs {
+		events = append(events, eventbus.BookingChanged{
+			BookingID:  bookingID,
+			CampaignID: campaignID,
+			Type:       booking.EventCreated,
+			Status:     booking.StatusConfirmed,
+			Actor:      actor,
+			OrgID:      orgID,
+			OccurredAt: at,
+		})
+	}
+	return events
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/export"
+)
+
+// exportPolicyColumns are the columns scanned by scanExportPolicy
+const exportPolicyColumns = `p.org_id, p.columns, p.hash_key, p.updated_by, p.updated_at`
+
+// GetExportPolicy returns an organization's export policy, or nil if it has none
+func (db *DB) GetExportPolicy(orgID string) (*export.Policy, error) {
+	return db.scanExportPolicy(db.QueryRow(`
+		SELECT `+exportPolicyColumns+` FROM export_policies p WHERE p.org_id = $1
+	`, orgID))
+}
+
+// ExportPolicy returns the export policy of the organization owning a booking
+func (db *DB) ExportPolicy(q export.Query) (*export.Policy, error) {
+	return db.scanExportPolicy(db.QueryRow(`
+		SELECT `+exportPolicyColumns+`
+		FROM export_policies p
+		JOIN placement_bookings b ON b.org_id = p.org_id
+		WHERE b.booking_id = $1
+	`, q.BookingID))
+}
+
+// LogExportPolicy returns the export policy of the organization owning an advertiser
+func (db *DB) LogExportPolicy(q export.LogQuery) (*export.Policy, error) {
+	return db.scanExportPolicy(db.QueryRow(`
+		SELECT `+exportPolicyColumns+`
+		FROM export_policies p
+		JOIN advertisers a ON a.org_id = p.org_id
+		WHERE a.advertiser_id = $1
+	`, q.AdvertiserID))
+}
+
+// SetExportPolicy stores an organization's export policy. The hash key is
+// only stored with the first policy, so hashed values stay comparable
+// across exports when the restricted columns change.
+func (db *DB) SetExportPolicy(policy *export.Policy) error {
+	columns, err := json.Marshal(policy.Columns)
+	if err != nil {
+		return fmt.Errorf("failed to encode export policy: %w", err)
+	}
+	hashKey, err := db.fields.Seal(ExportHashKeyColumn, policy.HashKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt export hash key: %w", err)
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO export_policies (org_id, columns, hash_key, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET columns = EXCLUDED.columns, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, policy.OrgID, columns, hashKey, policy.UpdatedBy).Scan(&policy.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set export policy: %w", err)
+	}
+	return nil
+}
+
+// DeleteExportPolicy lifts an organization's export restrictions
+func (db *DB) DeleteExportPolicy(orgID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM export_policies WHERE org_id = $1`, orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete export policy: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete export policy: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// scanExportPolicy scans an export_policies row, opening its hash key, or
+// returns nil if there is none
+func (db *DB) scanExportPolicy(row rowScanner) (*export.Policy, error) {
+	var policy export.Policy
+	var columns []byte
+	var updatedBy sql.NullString
+	err := row.Scan(&policy.OrgID, &columns, &policy.HashKey, &updatedBy, &policy.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export policy: %w", err)
+	}
+	if err := json.Unmarshal(columns, &policy.Columns); err != nil {
+		return nil, fmt.Errorf("failed to decode export policy of %s: %w", policy.OrgID, err)
+	}
+	if policy.HashKey, err = db.fields.Open(ExportHashKeyColumn, policy.HashKey); err != nil {
+		return nil, fmt.Errorf("failed to decrypt export hash key of %s: %w", policy.OrgID, err)
+	}
+	policy.UpdatedBy = updatedBy.String
+	return &policy, nil
+}
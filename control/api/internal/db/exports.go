@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+)
+
+// StreamExposureEvents reads the events of an export in event time order,
+// handing each to fn as it is scanned rather than collecting them
+func (db *DB) StreamExposureEvents(q export.Query, fn func(row *export.Row) error) error {
+	where := query.New()
+	where.Equal("booking_id", q.BookingID)
+	where.AtLeast("event_timestamp", q.From)
+	where.Where("event_timestamp < %s", q.To)
+	whereTenant(where, "org_id", q.Tenant)
+	if err := where.Err(); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT
+			event_id, booking_id, viewer_id, session_id, client_event_id,
+			event_timestamp, device_event_timestamp, received_at, exposure_duration,
+			screen_coverage_percentage, attention_score, listen_through, instantaneous_prs,
+			device_type, counted
+		FROM exposure_events
+		WHERE %s
+		ORDER BY event_timestamp, event_id
+	`, where.Clause()), where.Args()...)
+	if err != nil {
+		return fmt.Errorf("failed to query exposure events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row export.Row
+		if err := rows.Scan(&row.EventID, &row.BookingID, &row.ViewerID, &row.SessionID, &row.ClientEventID,
+			&row.EventTimestamp, &row.DeviceTimestamp, &row.ReceivedAt, &row.ExposureDuration,
+			&row.ScreenCoverage, &row.AttentionScore, &row.ListenThrough, &row.InstantaneousPRS,
+			&row.DeviceType, &row.Counted); err != nil {
+			return fmt.Errorf("failed to scan exposure event: %w", err)
+		}
+		if row.ViewerID, err = db.fields.Open(ViewerIDColumn, row.ViewerID); err != nil {
+			return fmt.Errorf("failed to decrypt viewer ID of %s: %w", row.EventID, err)
+		}
+
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CreateEventExport records a pending async export and returns its ID
+func (db *DB) CreateEventExport(e *export.Export) (string, error) {
+	exportID := fmt.Sprintf("export_%s_%d", e.Query.BookingID, time.Now().UnixNano())
+
+	tenantScope, err := json.Marshal(e.Query.Tenant)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode export scope: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO event_exports (export_id, booking_id, format, from_time, to_time, tenant_scope, status, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, exportID, e.Query.BookingID, e.Format, e.Query.From, e.Query.To, tenantScope, export.StatusPending, e.RequestedBy)
+	if err != nil {
+		return "", fmt.Errorf("failed to create event export: %w", err)
+	}
+	return exportID, nil
+}
+
+// GetEventExport retrieves an async export, or nil when there is none
+func (db *DB) GetEventExport(exportID string) (*export.Export, error) {
+	var e export.Export
+	var tenantScope []byte
+	var requestedBy, objectKey, message sql.NullString
+	var completedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT export_id, booking_id, format, from_time, to_time, tenant_scope, status, requested_by,
+			row_count, size_bytes, object_key, error, created_at, completed_at
+		FROM event_exports
+		WHERE export_id = $1
+	`, exportID).Scan(&e.ID, &e.Query.BookingID, &e.Format, &e.Query.From, &e.Query.To, &tenantScope, &e.Status, &requestedBy,
+		&e.RowCount, &e.Size, &objectKey, &message, &e.CreatedAt, &completedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event export: %w", err)
+	}
+	if err := json.Unmarshal(tenantScope, &e.Query.Tenant); err != nil {
+		return nil, fmt.Errorf("failed to decode export scope: %w", err)
+	}
+	e.RequestedBy = requestedBy.String
+	e.ObjectKey = objectKey.String
+	e.Error = message.String
+	e.CompletedAt = nullTime(completedAt)
+	return &e, nil
+}
+
+// StartEventExport marks an export running
+func (db *DB) StartEventExport(exportID string) error {
+	_, err := db.Exec(`
+		UPDATE event_exports SET status = $2, started_at = CURRENT_TIMESTAMP WHERE export_id = $1
+	`, exportID, export.StatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to start event export: %w", err)
+	}
+	return nil
+}
+
+// CompleteEventExport marks an export completed with the storage key of its file
+func (db *DB) CompleteEventExport(exportID, objectKey string, rows, size int64) error {
+	_, err := db.Exec(`
+		UPDATE event_exports
+		SET status = $2, object_key = $3, row_count = $4, size_bytes = $5, completed_at = CURRENT_TIMESTAMP
+		WHERE export_id = $1
+	`, exportID, export.StatusCompleted, objectKey, rows, size)
+	if err != nil {
+		return fmt.Errorf("failed to complete event export: %w", err)
+	}
+	return nil
+}
+
+// FailEventExport records an export failure
+func (db *DB) FailEventExport(exportID, message string) error {
+	_, err := db.Exec(`
+		UPDATE event_exports SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP WHERE export_id = $1
+	`, exportID, export.StatusFailed, message)
+	if err != nil {
+		return fmt.Errorf("failed to mark event export failed: %w", err)
+	}
+	return nil
+}
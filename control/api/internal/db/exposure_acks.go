@@ -0,0 +1,91 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/ack"
+)
+
+// RecordExposureAck acknowledges a stored batch with the key's next sequence,
+// which it sets on a. It must only be called once the batch's events are
+// committed. Acknowledgements older than the key's last ack.Retained are
+// dropped.
+func (db *DB) RecordExposureAck(a *ack.Ack) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if a.AckedAt.IsZero() {
+		a.AckedAt = time.Now().UTC()
+	}
+	// The upsert locks the key's stream, so concurrent batches of one key
+	// are numbered one after another
+	err = tx.QueryRow(`
+		INSERT INTO exposure_ack_streams (key_id, last_sequence, updated_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key_id)
+		DO UPDATE SET last_sequence = exposure_ack_streams.last_sequence + 1, updated_at = EXCLUDED.updated_at
+		RETURNING last_sequence
+	`, a.KeyID, a.AckedAt).Scan(&a.Sequence)
+	if err != nil {
+		return fmt.Errorf("failed to advance acknowledgement sequence: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO exposure_acks (key_id, sequence, sequence_token, stored_count, failed_count, acked_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+	`, a.KeyID, a.Sequence, a.Token, a.StoredCount, a.FailedCount, a.AckedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record acknowledgement: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM exposure_acks WHERE key_id = $1 AND sequence <= $2`, a.KeyID, a.Sequence-ack.Retained)
+	if err != nil {
+		return fmt.Errorf("failed to prune acknowledgements: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit acknowledgement: %w", err)
+	}
+	return nil
+}
+
+// ListExposureAcks returns a key's last acknowledgement sequence, 0 when it
+// has none, and up to limit of its acknowledgements after sequence after,
+// oldest first
+func (db *DB) ListExposureAcks(keyID string, after int64, limit int) (int64, []ack.Ack, error) {
+	var last int64
+	err := db.QueryRow(`SELECT last_sequence FROM exposure_ack_streams WHERE key_id = $1`, keyID).Scan(&last)
+	if err == sql.ErrNoRows {
+		return 0, []ack.Ack{}, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query acknowledgement sequence: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT sequence, COALESCE(sequence_token, ''), stored_count, failed_count, acked_at
+		FROM exposure_acks
+		WHERE key_id = $1 AND sequence > $2
+		ORDER BY sequence
+		LIMIT $3
+	`, keyID, after, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query acknowledgements: %w", err)
+	}
+	defer rows.Close()
+
+	acks := make([]ack.Ack, 0)
+	for rows.Next() {
+		a := ack.Ack{KeyID: keyID}
+		if err := rows.Scan(&a.Sequence, &a.Token, &a.StoredCount, &a.FailedCount, &a.AckedAt); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan acknowledgement: %w", err)
+		}
+		acks = append(acks, a)
+	}
+	return last, acks, rows.Err()
+}
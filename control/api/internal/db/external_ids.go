@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ErrExternalIDTaken is returned when an external ID is already attached to
+// another resource of the same type within the same source
+var ErrExternalIDTaken = errors.New("external ID already in use")
+
+// uniqueViolation is the Postgres error code for unique constraint failures
+const uniqueViolation = "23505"
+
+// foreignKeyViolation is the Postgres error code for references to missing rows
+const foreignKeyViolation = "23503"
+
+// SetExternalIDs replaces the partner IDs attached to a resource, keyed by source
+func (db *DB) SetExternalIDs(resourceType, resourceID string, ids map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setExternalIDs(tx, resourceType, resourceID, ids); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit external IDs: %w", err)
+	}
+	return nil
+}
+
+// setExternalIDs replaces a resource's partner IDs within a transaction
+func setExternalIDs(tx *sql.Tx, resourceType, resourceID string, ids map[string]string) error {
+	if _, err := tx.Exec(`DELETE FROM external_ids WHERE resource_type = $1 AND resource_id = $2`, resourceType, resourceID); err != nil {
+		return fmt.Errorf("failed to clear external IDs: %w", err)
+	}
+
+	for source, externalID := range ids {
+		_, err := tx.Exec(`
+			INSERT INTO external_ids (resource_type, resource_id, source, external_id)
+			VALUES ($1, $2, $3, $4)
+		`, resourceType, resourceID, source, externalID)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return fmt.Errorf("%w: %s/%s", ErrExternalIDTaken, source, externalID)
+			}
+			return fmt.Errorf("failed to set external ID %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// GetExternalIDs retrieves the partner IDs attached to a resource, keyed by source
+func (db *DB) GetExternalIDs(resourceType, resourceID string) (map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT source, external_id
+		FROM external_ids
+		WHERE resource_type = $1 AND resource_id = $2
+	`, resourceType, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query external IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]string)
+	for rows.Next() {
+		var source, externalID string
+		if err := rows.Scan(&source, &externalID); err != nil {
+			return nil, fmt.Errorf("failed to scan external ID: %w", err)
+		}
+		ids[source] = externalID
+	}
+
+	return ids, rows.Err()
+}
+
+// externalIDsFor retrieves the partner IDs of several resources of one
+// type, keyed by resource ID and then source
+func externalIDsFor(q queryer, resourceType string, resourceIDs []string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(resourceIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT resource_id, source, external_id
+		FROM external_ids
+		WHERE resource_type = $1 AND resource_id = ANY($2)
+	`, resourceType, pq.Array(resourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query external IDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resourceID, source, externalID string
+		if err := rows.Scan(&resourceID, &source, &externalID); err != nil {
+			return nil, fmt.Errorf("failed to scan external ID: %w", err)
+		}
+		if result[resourceID] == nil {
+			result[resourceID] = make(map[string]string)
+		}
+		result[resourceID][source] = externalID
+	}
+	return result, rows.Err()
+}
+
+// externalIDHolder returns the resource other than resourceID carrying a
+// partner ID within a transaction, or "" if none does
+func externalIDHolder(tx *sql.Tx, resourceType, source, externalID, resourceID string) (string, error) {
+	var holder string
+	err := tx.QueryRow(`
+		SELECT resource_id FROM external_ids
+		WHERE resource_type = $1 AND source = $2 AND external_id = $3 AND resource_id <> $4
+	`, resourceType, source, externalID, resourceID).Scan(&holder)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check external ID: %w", err)
+	}
+	return holder, nil
+}
+
+// FindByExternalID returns the ID of the resource carrying a partner ID, or "" if none does
+func (db *DB) FindByExternalID(resourceType, source, externalID string) (string, error) {
+	var resourceID string
+	err := db.QueryRow(`
+		SELECT resource_id
+		FROM external_ids
+		WHERE resource_type = $1 AND source = $2 AND external_id = $3
+	`, resourceType, source, externalID).Scan(&resourceID)
+	if err == sql.ErrNoRows {
+		return "", nil // Not found
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up external ID: %w", err)
+	}
+	return resourceID, nil
+}
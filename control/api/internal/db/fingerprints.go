@@ -0,0 +1,348 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/fingerprint"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+)
+
+// ErrCutNotFound is returned when remapping to a cut with no uploaded fingerprints
+var ErrCutNotFound = errors.New("cut not found")
+
+// SetCutFingerprints replaces the shot fingerprints of a title's cut. The
+// first cut uploaded for a title is the baseline surfaces are aligned to.
+func (db *DB) SetCutFingerprints(titleID, cutID string, shots []fingerprint.Shot) (*fingerprint.Cut, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, titleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline bool
+	err = tx.QueryRow(`
+		INSERT INTO title_cuts (title_id, cut_id, applied_at)
+		SELECT $1, $2, CASE WHEN EXISTS (
+			SELECT 1 FROM title_cuts WHERE title_id = $1 AND applied_at IS NOT NULL
+		) THEN NULL ELSE CURRENT_TIMESTAMP END
+		ON CONFLICT (title_id, cut_id) DO NOTHING
+		RETURNING applied_at IS NOT NULL
+	`, titleKey, cutID).Scan(&baseline)
+	switch {
+	case err == sql.ErrNoRows:
+		// The cut was uploaded before
+	case err != nil:
+		return nil, fmt.Errorf("failed to save cut: %w", err)
+	case !baseline:
+		// A new edit may move or drop what the surfaces were found on
+		if _, err := queueRevalidation(tx, titleKey, ingest.ReasonCutDelivered); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM shot_fingerprints WHERE title_id = $1 AND cut_id = $2`, titleKey, cutID); err != nil {
+		return nil, fmt.Errorf("failed to clear fingerprints: %w", err)
+	}
+	for _, shot := range shots {
+		_, err := tx.Exec(`
+			INSERT INTO shot_fingerprints (title_id, cut_id, shot_id, start_time, end_time, phash)
+			VALUES ($1, $2, $3, $4, $5, lower($6))
+		`, titleKey, cutID, shot.ShotID, shot.StartTime, shot.EndTime, shot.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save fingerprint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit fingerprints: %w", err)
+	}
+	return db.GetCut(titleID, cutID)
+}
+
+// ListCuts returns a title's cuts, most recently created first
+func (db *DB) ListCuts(titleID string) ([]fingerprint.Cut, error) {
+	rows, err := db.Query(cutQuery+` WHERE c.title_id::text = $1 ORDER BY c.created_at DESC, c.cut_id`, titleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cuts: %w", err)
+	}
+	defer rows.Close()
+
+	cuts := make([]fingerprint.Cut, 0)
+	for rows.Next() {
+		cut, err := scanCut(rows)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, *cut)
+	}
+	return cuts, rows.Err()
+}
+
+// GetCut returns a title's cut, or nil if it has none by that ID
+func (db *DB) GetCut(titleID, cutID string) (*fingerprint.Cut, error) {
+	rows, err := db.Query(cutQuery+` WHERE c.title_id::text = $1 AND c.cut_id = $2`, titleID, cutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cut: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanCut(rows)
+}
+
+const cutQuery = `
+	SELECT c.title_id::text, c.cut_id, c.applied_at, c.last_remap, c.created_at,
+		(SELECT COUNT(*) FROM shot_fingerprints f WHERE f.title_id = c.title_id AND f.cut_id = c.cut_id)
+	FROM title_cuts c`
+
+func scanCut(rows *sql.Rows) (*fingerprint.Cut, error) {
+	var cut fingerprint.Cut
+	var appliedAt sql.NullTime
+	var report []byte
+	if err := rows.Scan(&cut.TitleID, &cut.CutID, &appliedAt, &report, &cut.CreatedAt, &cut.Shots); err != nil {
+		return nil, fmt.Errorf("failed to scan cut: %w", err)
+	}
+	if appliedAt.Valid {
+		cut.AppliedAt = &appliedAt.Time
+	}
+	if report != nil {
+		cut.Report = &fingerprint.Report{}
+		if err := json.Unmarshal(report, cut.Report); err != nil {
+			return nil, fmt.Errorf("failed to decode remap report: %w", err)
+		}
+	}
+	return &cut, nil
+}
+
+// RemapTitleCut aligns a title's surfaces from its applied cut to another cut
+// by shot fingerprint. Surface IDs do not change, so bookings stay attached.
+// Matched shots take the new cut's IDs and times and their surfaces shift with
+// them; surfaces whose shot is not in the new cut are withdrawn (PRS 0), which
+// booking reconciliation reports. With dryRun nothing is changed.
+func (db *DB) RemapTitleCut(titleID, cutID string, maxDistance int, dryRun bool) (*fingerprint.Report, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the title serializes remaps and fingerprint uploads
+	titleKey, err := lockTitle(tx, titleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM title_cuts WHERE title_id = $1 AND cut_id = $2)`, titleKey, cutID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cut: %w", err)
+	}
+	if !exists {
+		return nil, ErrCutNotFound
+	}
+
+	var baseline string
+	err = tx.QueryRow(`
+		SELECT cut_id FROM title_cuts
+		WHERE title_id = $1 AND applied_at IS NOT NULL
+		ORDER BY applied_at DESC
+		LIMIT 1
+	`, titleKey).Scan(&baseline)
+	if err == sql.ErrNoRows {
+		return nil, fingerprint.ErrNoBaseline
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied cut: %w", err)
+	}
+	if baseline == cutID {
+		return nil, fingerprint.ErrCutApplied
+	}
+
+	from, err := listFingerprints(tx, titleKey, baseline)
+	if err != nil {
+		return nil, err
+	}
+	to, err := listFingerprints(tx, titleKey, cutID)
+	if err != nil {
+		return nil, err
+	}
+	alignment := fingerprint.Align(from, to, maxDistance)
+
+	ranges, err := listSurfaceRanges(tx, titleKey)
+	if err != nil {
+		return nil, err
+	}
+	changes := fingerprint.Plan(alignment, ranges)
+	report := fingerprint.Summarize(titleID, baseline, cutID, alignment, changes, dryRun, time.Now().UTC())
+	if dryRun {
+		return report, nil
+	}
+
+	if err := applyShotAlignment(tx, titleKey, alignment); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		switch change.Outcome {
+		case fingerprint.OutcomeShifted:
+			_, err = tx.Exec(`UPDATE surfaces SET start_time = $2, end_time = $3 WHERE surface_id = $1`,
+				change.SurfaceID, change.NewStartTime, change.NewEndTime)
+		case fingerprint.OutcomeWithdrawn:
+			_, err = tx.Exec(`
+				UPDATE surfaces
+				SET metadata = COALESCE(metadata, '{}'::jsonb)
+						|| jsonb_build_object('withdrawn_by_cut', $2::text, 'prs_score_before_withdrawal', prs_score),
+					prs_score = 0
+				WHERE surface_id = $1 AND prs_score > 0
+			`, change.SurfaceID, cutID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to remap surface %s: %w", change.SurfaceID, err)
+		}
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode remap report: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE title_cuts SET applied_at = $3, last_remap = $4 WHERE title_id = $1 AND cut_id = $2`,
+		titleKey, cutID, report.RemappedAt, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark cut applied: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit remap: %w", err)
+	}
+	return report, nil
+}
+
+// applyShotAlignment renames and retimes a title's shot rows to the new cut.
+// Rows are parked under unique temporary IDs first so new shot IDs can reuse
+// old ones; shots missing from the new cut are retired under their row ID.
+func applyShotAlignment(tx *sql.Tx, titleKey int, alignment fingerprint.Alignment) error {
+	rows, err := tx.Query(`SELECT id, shot_id FROM shots WHERE title_id = $1 FOR UPDATE`, titleKey)
+	if err != nil {
+		return fmt.Errorf("failed to lock shots: %w", err)
+	}
+	rowIDs := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var shotID string
+		if err := rows.Scan(&id, &shotID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan shot: %w", err)
+		}
+		rowIDs[shotID] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock shots: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE shots SET shot_id = 'remap_' || id WHERE title_id = $1`, titleKey); err != nil {
+		return fmt.Errorf("failed to park shots: %w", err)
+	}
+
+	added := alignment.Added
+	for fromID, match := range alignment.Matches {
+		id, ok := rowIDs[fromID]
+		if !ok {
+			// Fingerprinted but never ingested
+			added = append(added, match.To)
+			continue
+		}
+		_, err := tx.Exec(`UPDATE shots SET shot_id = $2, start_time = $3, end_time = $4 WHERE id = $1`,
+			id, match.To.ShotID, match.To.StartTime, match.To.EndTime)
+		if err != nil {
+			return fmt.Errorf("failed to remap shot %s: %w", fromID, err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE shots SET shot_id = 'retired_' || id WHERE title_id = $1 AND shot_id = 'remap_' || id`, titleKey); err != nil {
+		return fmt.Errorf("failed to retire shots: %w", err)
+	}
+
+	for _, shot := range added {
+		_, err := tx.Exec(`INSERT INTO shots (title_id, shot_id, start_time, end_time) VALUES ($1, $2, $3, $4)`,
+			titleKey, shot.ShotID, shot.StartTime, shot.EndTime)
+		if err != nil {
+			return fmt.Errorf("failed to add shot %s: %w", shot.ShotID, err)
+		}
+	}
+	return nil
+}
+
+// lockTitle locks a title row and returns its key
+func lockTitle(tx *sql.Tx, titleID string) (int, error) {
+	var titleKey int
+	err := tx.QueryRow(`SELECT id FROM titles WHERE id::text = $1 FOR UPDATE`, titleID).Scan(&titleKey)
+	if err == sql.ErrNoRows {
+		return 0, ErrTitleNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock title: %w", err)
+	}
+	return titleKey, nil
+}
+
+// listFingerprints loads a cut's shot fingerprints in time order
+func listFingerprints(tx *sql.Tx, titleKey int, cutID string) ([]fingerprint.Shot, error) {
+	rows, err := tx.Query(`
+		SELECT shot_id, start_time, end_time, phash
+		FROM shot_fingerprints
+		WHERE title_id = $1 AND cut_id = $2
+		ORDER BY start_time, shot_id
+	`, titleKey, cutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	shots := make([]fingerprint.Shot, 0)
+	for rows.Next() {
+		var shot fingerprint.Shot
+		if err := rows.Scan(&shot.ShotID, &shot.StartTime, &shot.EndTime, &shot.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan fingerprint: %w", err)
+		}
+		shots = append(shots, shot)
+	}
+	return shots, rows.Err()
+}
+
+// listSurfaceRanges loads the time ranges of a title's surfaces
+func listSurfaceRanges(tx *sql.Tx, titleKey int) ([]fingerprint.SurfaceRange, error) {
+	rows, err := tx.Query(`
+		SELECT s.surface_id, sh.shot_id, s.start_time, s.end_time
+		FROM surfaces s
+		JOIN shots sh ON sh.id = s.shot_id
+		WHERE s.title_id = $1
+		ORDER BY s.surface_id
+		FOR UPDATE OF s
+	`, titleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query surfaces: %w", err)
+	}
+	defer rows.Close()
+
+	ranges := make([]fingerprint.SurfaceRange, 0)
+	for rows.Next() {
+		var r fingerprint.SurfaceRange
+		if err := rows.Scan(&r.SurfaceID, &r.ShotID, &r.StartTime, &r.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan surface: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
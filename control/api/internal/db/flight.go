@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/flight"
+)
+
+// ListFlightDueBookings returns live bookings whose flight window has ended
+// by now and confirmed bookings whose window has started, earliest first
+func (db *DB) ListFlightDueBookings(now time.Time, limit int) ([]flight.Booking, error) {
+	rows, err := db.Query(`
+		SELECT booking_id, status, start_time, end_time
+		FROM placement_bookings
+		WHERE (status IN ('pending', 'confirmed', 'active', 'paused') AND end_time <= $1)
+			OR (status = 'confirmed' AND start_time <= $1)
+		ORDER BY LEAST(start_time, end_time), booking_id
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookings due a flight transition: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := make([]flight.Booking, 0)
+	for rows.Next() {
+		var b flight.Booking
+		var start, end sql.NullTime
+		if err := rows.Scan(&b.BookingID, &b.Status, &start, &end); err != nil {
+			return nil, fmt.Errorf("failed to scan flight booking: %w", err)
+		}
+		b.Start, b.End = nullTime(start), nullTime(end)
+		bookings = append(bookings, b)
+	}
+	return bookings, rows.Err()
+}
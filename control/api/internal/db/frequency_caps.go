@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+)
+
+// frequencyCap reads a cap from its columns, nil when unset
+func frequencyCap(max, windowHours sql.NullInt64) *freqcap.Cap {
+	if !max.Valid || !windowHours.Valid {
+		return nil
+	}
+	return &freqcap.Cap{MaxExposures: int(max.Int64), WindowHours: int(windowHours.Int64)}
+}
+
+// frequencyCapColumns writes a cap to its columns, NULL when unset
+func frequencyCapColumns(c *freqcap.Cap) (sql.NullInt64, sql.NullInt64) {
+	if c == nil {
+		return sql.NullInt64{}, sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(c.MaxExposures), Valid: true}, sql.NullInt64{Int64: int64(c.WindowHours), Valid: true}
+}
+
+// GetFrequencyRules returns the frequency caps of a campaign and, when
+// bookingID is set, of that booking of it, or nil if either does not exist.
+// An empty campaignID takes the booking's campaign.
+func (db *DB) GetFrequencyRules(campaignID, bookingID string) (*freqcap.Rules, error) {
+	rules := &freqcap.Rules{CampaignID: campaignID, BookingID: bookingID}
+	var campaignMax, campaignWindow, bookingMax, bookingWindow sql.NullInt64
+	var err error
+	if bookingID == "" {
+		err = db.QueryRow(`
+			SELECT frequency_cap_max, frequency_cap_window_hours FROM campaigns WHERE campaign_id = $1
+		`, campaignID).Scan(&campaignMax, &campaignWindow)
+	} else {
+		err = db.QueryRow(`
+			SELECT b.campaign_id, c.frequency_cap_max, c.frequency_cap_window_hours,
+				b.frequency_cap_max, b.frequency_cap_window_hours
+			FROM placement_bookings b
+			LEFT JOIN campaigns c ON c.campaign_id = b.campaign_id
+			WHERE b.booking_id = $1 AND ($2 = '' OR b.campaign_id = $2)
+		`, bookingID, campaignID).Scan(&rules.CampaignID, &campaignMax, &campaignWindow, &bookingMax, &bookingWindow)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frequency caps: %w", err)
+	}
+	rules.Campaign = frequencyCap(campaignMax, campaignWindow)
+	rules.Booking = frequencyCap(bookingMax, bookingWindow)
+	return rules, nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+)
+
+// staleCondition matches surfaces of table that are queued for re-validation
+// or, when the stale-after age staleAfterSecs is positive, were last validated
+// longer ago than it
+func staleCondition(table, staleAfterSecs string) string {
+	return fmt.Sprintf(`(%[1]s.revalidation_requested_at IS NOT NULL
+				OR (%[2]s > 0 AND COALESCE(%[1]s.last_validated_at, %[1]s.created_at, CURRENT_TIMESTAMP)
+					< CURRENT_TIMESTAMP - make_interval(secs => %[2]s)))`, table, staleAfterSecs)
+}
+
+// staleClause is staleCondition for the configured stale-after age
+func (db *DB) staleClause(table string) string {
+	return staleCondition(table, strconv.FormatInt(int64(db.staleAfter/time.Second), 10))
+}
+
+// SetSurfaceStaleAfter sets how long after its last validation a surface goes
+// stale. Stale surfaces are left out of available inventory and flagged on
+// the bookings that use them. Zero only treats queued surfaces as stale.
+func (db *DB) SetSurfaceStaleAfter(staleAfter time.Duration) {
+	db.staleAfter = staleAfter
+}
+
+// queueRevalidation queues every surface of a title for re-validation and
+// returns how many were queued. Surfaces already queued keep their original
+// request time and reason.
+func queueRevalidation(tx *sql.Tx, titleKey int, reason string) (int, error) {
+	result, err := tx.Exec(`
+		UPDATE surfaces SET
+			revalidation_requested_at = COALESCE(revalidation_requested_at, CURRENT_TIMESTAMP),
+			revalidation_reason = COALESCE(revalidation_reason, $2)
+		WHERE title_id = $1
+	`, titleKey, reason)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue surfaces for re-validation: %w", err)
+	}
+	queued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue surfaces for re-validation: %w", err)
+	}
+	return int(queued), nil
+}
+
+// RequestRevalidation queues every surface of a title for re-validation and
+// returns how many were queued
+func (db *DB) RequestRevalidation(titleID string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, titleID)
+	if err != nil {
+		return 0, err
+	}
+	queued, err := queueRevalidation(tx, titleKey, ingest.ReasonRequested)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit re-validation request: %w", err)
+	}
+	return queued, nil
+}
+
+// ListRevalidations returns stale surfaces, of one title unless titleID is
+// empty. Surfaces with live bookings come first, then those waiting longest.
+func (db *DB) ListRevalidations(titleID string, limit int) ([]ingest.Revalidation, error) {
+	rows, err := db.Query(`
+		SELECT surface_id, title_id, reason, last_validated_at, requested_at, live_bookings FROM (
+			SELECT s.surface_id, s.title_id::text AS title_id,
+				COALESCE(s.revalidation_reason, $2) AS reason,
+				COALESCE(s.last_validated_at, s.created_at, CURRENT_TIMESTAMP) AS last_validated_at,
+				s.revalidation_requested_at AS requested_at,
+				(SELECT COUNT(*) FROM placement_bookings pb
+					WHERE pb.surface_id = s.surface_id
+						AND pb.status IN ('pending', 'confirmed', 'active')) AS live_bookings
+			FROM surfaces s
+			WHERE ($1 = '' OR s.title_id::text = $1)
+				AND `+db.staleClause("s")+`
+				AND NOT EXISTS (SELECT 1 FROM surface_merges m WHERE m.surface_id = s.surface_id)
+		) stale
+		ORDER BY live_bookings DESC, COALESCE(requested_at, last_validated_at), surface_id
+		LIMIT $3
+	`, titleID, ingest.ReasonAge, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query re-validation queue: %w", err)
+	}
+	defer rows.Close()
+
+	queue := make([]ingest.Revalidation, 0)
+	for rows.Next() {
+		var r ingest.Revalidation
+		var requestedAt sql.NullTime
+		if err := rows.Scan(&r.SurfaceID, &r.TitleID, &r.Reason, &r.LastValidatedAt, &requestedAt, &r.LiveBookings); err != nil {
+			return nil, fmt.Errorf("failed to scan re-validation: %w", err)
+		}
+		if requestedAt.Valid {
+			r.RequestedAt = &requestedAt.Time
+		}
+		queue = append(queue, r)
+	}
+	return queue, rows.Err()
+}
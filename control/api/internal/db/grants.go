@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/lib/pq"
+)
+
+// GetResourceOwner returns the organization owning a resource, or "" if unowned
+func (db *DB) GetResourceOwner(resourceType, resourceID string) (string, error) {
+	var orgID string
+	err := db.QueryRow(`
+		SELECT org_id FROM resource_owners
+		WHERE resource_type = $1 AND resource_id = $2
+	`, resourceType, resourceID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return "", nil // Unowned
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query resource owner: %w", err)
+	}
+	return orgID, nil
+}
+
+// registerResourceOwner records the owning organization of a resource unless
+// it already has one
+func registerResourceOwner(tx *sql.Tx, resourceType, resourceID, orgID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO resource_owners (resource_type, resource_id, org_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (resource_type, resource_id) DO NOTHING
+	`, resourceType, resourceID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to register resource owner: %w", err)
+	}
+	return nil
+}
+
+// registerBookingOwner assigns a new booking to the organization owning its
+// campaign, claiming the campaign for orgID if it has no owner yet. Bookings
+// made by an agency under a grant therefore belong to the brand. The owner is
+// also stamped on the booking row, which tenant-scoped queries filter on.
+func registerBookingOwner(tx *sql.Tx, bookingID, campaignID, orgID string) error {
+	if err := registerResourceOwner(tx, "campaign", campaignID, orgID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO resource_owners (resource_type, resource_id, org_id)
+		SELECT 'booking', $1, org_id FROM resource_owners
+		WHERE resource_type = 'campaign' AND resource_id = $2
+		ON CONFLICT (resource_type, resource_id) DO NOTHING
+	`, bookingID, campaignID)
+	if err != nil {
+		return fmt.Errorf("failed to register booking owner: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE placement_bookings SET org_id = o.org_id
+		FROM resource_owners o
+		WHERE o.resource_type = 'booking' AND o.resource_id = $1 AND placement_bookings.booking_id = $1
+	`, bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to set booking organization: %w", err)
+	}
+	return nil
+}
+
+// FindGrant returns the active grant of a resource to an organization, if any.
+// Grants on a campaign also cover the bookings in it; a grant on the booking
+// itself takes precedence.
+func (db *DB) FindGrant(resourceType, resourceID, granteeOrgID string) (*authz.Grant, error) {
+	query := `
+		SELECT grant_id, resource_type, resource_id, owner_org_id, grantee_org_id, permissions, granted_by, created_at
+		FROM resource_grants
+		WHERE grantee_org_id = $3 AND revoked_at IS NULL
+			AND (
+				(resource_type = $1 AND resource_id = $2)
+				OR ($1 = 'booking' AND resource_type = 'campaign' AND resource_id = (
+					SELECT campaign_id FROM placement_bookings WHERE booking_id = $2
+				))
+			)
+		ORDER BY resource_type = $1 DESC
+		LIMIT 1
+	`
+
+	grant, err := scanGrant(db.QueryRow(query, resourceType, resourceID, granteeOrgID))
+	if err == sql.ErrNoRows {
+		return nil, nil // No grant
+	}
+	return grant, err
+}
+
+// CreateGrant shares a resource with another organization, replacing any
+// active grant between the same pair
+func (db *DB) CreateGrant(grant *authz.Grant) (string, error) {
+	grantID := fmt.Sprintf("grant_%s_%d", grant.GranteeOrgID, time.Now().UnixNano())
+
+	permissions := make([]string, len(grant.Permissions))
+	for i, p := range grant.Permissions {
+		permissions[i] = string(p)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE resource_grants SET revoked_at = CURRENT_TIMESTAMP
+		WHERE resource_type = $1 AND resource_id = $2 AND grantee_org_id = $3 AND revoked_at IS NULL
+	`, grant.ResourceType, grant.ResourceID, grant.GranteeOrgID)
+	if err != nil {
+		return "", fmt.Errorf("failed to replace grant: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO resource_grants (
+			grant_id, resource_type, resource_id, owner_org_id, grantee_org_id, permissions, granted_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, grantID, grant.ResourceType, grant.ResourceID, grant.OwnerOrgID, grant.GranteeOrgID, pq.Array(permissions), grant.GrantedBy)
+	if err != nil {
+		return "", fmt.Errorf("failed to create grant: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit grant: %w", err)
+	}
+	return grantID, nil
+}
+
+// ListGrants returns active grants given or received by an organization
+func (db *DB) ListGrants(orgID string) ([]*authz.Grant, error) {
+	rows, err := db.Query(`
+		SELECT grant_id, resource_type, resource_id, owner_org_id, grantee_org_id, permissions, granted_by, created_at
+		FROM resource_grants
+		WHERE (owner_org_id = $1 OR grantee_org_id = $1) AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := make([]*authz.Grant, 0)
+	for rows.Next() {
+		grant, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// RevokeGrant revokes a grant given by ownerOrgID and reports whether one was revoked
+func (db *DB) RevokeGrant(grantID, ownerOrgID string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE resource_grants SET revoked_at = CURRENT_TIMESTAMP
+		WHERE grant_id = $1 AND owner_org_id = $2 AND revoked_at IS NULL
+	`, grantID, ownerOrgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke grant: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke grant: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanGrant scans a resource_grants row into a Grant
+func scanGrant(row rowScanner) (*authz.Grant, error) {
+	var grant authz.Grant
+	var permissions []string
+	var grantedBy sql.NullString
+
+	err := row.Scan(&grant.ID, &grant.ResourceType, &grant.ResourceID, &grant.OwnerOrgID, &grant.GranteeOrgID, pq.Array(&permissions), &grantedBy, &grant.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan grant: %w", err)
+	}
+
+	for _, p := range permissions {
+		grant.Permissions = append(grant.Permissions, authz.Permission(p))
+	}
+	grant.GrantedBy = grantedBy.String
+
+	return &grant, nil
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/lib/pq"
+)
+
+// ErrTitleNotFound is returned when configuring a title that does not exist
+var ErrTitleNotFound = errors.New("title not found")
+
+// holdbackClause excludes surfaces held back from sale: those reserved by ID
+// and those of titles whose sellable capacity is fully booked
+const holdbackClause = `NOT EXISTS (
+				SELECT 1 FROM inventory_holdbacks h
+				WHERE h.title_id = surfaces.title_id AND surfaces.surface_id = ANY(h.surface_ids)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM holdback_utilization u
+				WHERE u.title_id = surfaces.title_id AND u.booked_surfaces >= u.sellable_capacity
+			)`
+
+// GetHoldback returns a title's hold-back rule, or nil if it has none
+func (db *DB) GetHoldback(titleID string) (*holdback.Rule, error) {
+	var rule holdback.Rule
+	var reason, updatedBy sql.NullString
+	err := db.QueryRow(`
+		SELECT title_id::text, percent, surface_ids, reason, updated_by, updated_at
+		FROM inventory_holdbacks
+		WHERE title_id::text = $1
+	`, titleID).Scan(&rule.TitleID, &rule.Percent, pq.Array(&rule.SurfaceIDs), &reason, &updatedBy, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hold-back: %w", err)
+	}
+	rule.Reason = reason.String
+	rule.UpdatedBy = updatedBy.String
+	return &rule, nil
+}
+
+// SetHoldback creates or replaces a title's hold-back rule
+func (db *DB) SetHoldback(rule *holdback.Rule) error {
+	surfaceIDs := rule.SurfaceIDs
+	if surfaceIDs == nil {
+		surfaceIDs = []string{}
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO inventory_holdbacks (title_id, percent, surface_ids, reason, updated_by, updated_at)
+		SELECT id, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6 FROM titles WHERE id::text = $1
+		ON CONFLICT (title_id) DO UPDATE SET
+			percent = EXCLUDED.percent,
+			surface_ids = EXCLUDED.surface_ids,
+			reason = EXCLUDED.reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, rule.TitleID, rule.Percent, pq.Array(surfaceIDs), rule.Reason, rule.UpdatedBy, rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save hold-back: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save hold-back: %w", err)
+	}
+	if affected == 0 {
+		return ErrTitleNotFound
+	}
+	return nil
+}
+
+// DeleteHoldback removes a title's hold-back rule. It reports whether one existed.
+func (db *DB) DeleteHoldback(titleID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM inventory_holdbacks WHERE title_id::text = $1`, titleID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete hold-back: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete hold-back: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ListHoldbackUtilization reports booked versus sellable inventory for every
+// title with a hold-back rule, or only for titleID when it is set
+func (db *DB) ListHoldbackUtilization(titleID string) ([]holdback.Utilization, error) {
+	rows, err := db.Query(`
+		SELECT title_id::text, percent, total_surfaces, reserved_surfaces,
+			sellable_capacity, booked_surfaces, reserved_booked
+		FROM holdback_utilization
+		WHERE ($1 = '' OR title_id::text = $1)
+		ORDER BY title_id
+	`, titleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hold-back utilization: %w", err)
+	}
+	defer rows.Close()
+
+	report := make([]holdback.Utilization, 0)
+	for rows.Next() {
+		var u holdback.Utilization
+		if err := rows.Scan(&u.TitleID, &u.Percent, &u.TotalSurfaces, &u.ReservedSurfaces,
+			&u.SellableCapacity, &u.BookedSurfaces, &u.ReservedBooked); err != nil {
+			return nil, fmt.Errorf("failed to scan hold-back utilization: %w", err)
+		}
+		u.Finish()
+		report = append(report, u)
+	}
+	return report, rows.Err()
+}
+
+// checkHoldback rejects booking a surface that is reserved, or that would
+// take its title past its sellable capacity. It locks the title's rule so
+// concurrent bookings cannot both take the last sellable surface.
+func checkHoldback(tx *sql.Tx, surfaceID string) error {
+	var titleID int
+	var reserved bool
+	err := tx.QueryRow(`
+		SELECT h.title_id, s.surface_id = ANY(h.surface_ids)
+		FROM surfaces s
+		JOIN inventory_holdbacks h ON h.title_id = s.title_id
+		WHERE s.surface_id = $1
+		FOR UPDATE OF h
+	`, surfaceID).Scan(&titleID, &reserved)
+	if err == sql.ErrNoRows {
+		return nil // No rule for the surface's title
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check hold-back: %w", err)
+	}
+	if reserved {
+		return fmt.Errorf("%w: surface %s is reserved", holdback.ErrHeldBack, surfaceID)
+	}
+
+	// A surface that is already booked does not use more capacity
+	var exhausted bool
+	err = tx.QueryRow(`
+		SELECT u.booked_surfaces >= u.sellable_capacity
+			AND NOT EXISTS (
+				SELECT 1 FROM placement_bookings pb
+				WHERE pb.surface_id = $2 AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+			)
+		FROM holdback_utilization u
+		WHERE u.title_id = $1
+	`, titleID, surfaceID).Scan(&exhausted)
+	if err != nil {
+		return fmt.Errorf("failed to check hold-back capacity: %w", err)
+	}
+	if exhausted {
+		return fmt.Errorf("%w: the title's sellable inventory is fully booked", holdback.ErrHeldBack)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/idempotency"
+)
+
+// ReserveIdempotencyKey claims an idempotency key unless it already holds an
+// unexpired record, which is returned instead. Expired keys are pruned on the way.
+func (db *DB) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (*idempotency.Record, error) {
+	if _, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return nil, fmt.Errorf("failed to prune idempotency keys: %w", err)
+	}
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING
+	`, key, fingerprint, time.Now().UTC().Add(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if affected > 0 {
+		return nil, nil
+	}
+
+	record := &idempotency.Record{}
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+	err = db.QueryRowContext(ctx, `
+		SELECT fingerprint, status, content_type, body FROM idempotency_keys WHERE key = $1
+	`, key).Scan(&record.Fingerprint, &status, &contentType, &body)
+	if err == sql.ErrNoRows {
+		// Released since the insert; report it in progress so the client retries
+		return &idempotency.Record{Fingerprint: fingerprint}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query idempotency key: %w", err)
+	}
+	if status.Valid {
+		record.Response = &idempotency.Response{Status: int(status.Int64), ContentType: contentType.String, Body: body}
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding a key
+func (db *DB) CompleteIdempotencyKey(ctx context.Context, key string, record *idempotency.Record, ttl time.Duration) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status = $2, content_type = $3, body = $4, expires_at = $5
+		WHERE key = $1
+	`, key, record.Response.Status, record.Response.ContentType, record.Response.Body, time.Now().UTC().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes an idempotency key
+func (db *DB) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
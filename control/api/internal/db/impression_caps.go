@@ -0,0 +1,90 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/impcap"
+)
+
+// impressionCapQuery selects bookings' max_impressions with the decisions
+// served and the unexpired edge leases against them
+const impressionCapQuery = `
+		SELECT b.booking_id, COALESCE(b.estimated_impressions, 0),
+			(
+				SELECT COUNT(*) FROM decision_events d
+				WHERE d.booking_id = b.booking_id AND d.outcome = 'served'
+			),
+			COALESCE((
+				SELECT SUM(l.held) FROM impression_leases l
+				WHERE l.booking_id = b.booking_id AND l.expires_at > NOW()
+			), 0)
+		FROM placement_bookings b`
+
+func scanImpressionCap(row interface{ Scan(...interface{}) error }) (*impcap.Cap, error) {
+	var c impcap.Cap
+	if err := row.Scan(&c.BookingID, &c.Max, &c.Served, &c.Leased); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetImpressionCap returns a booking's impression cap and delivery against
+// it, or nil if the booking does not exist
+func (db *DB) GetImpressionCap(bookingID string) (*impcap.Cap, error) {
+	c, err := scanImpressionCap(db.QueryRow(impressionCapQuery+` WHERE b.booking_id = $1`, bookingID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impression cap: %w", err)
+	}
+	return c, nil
+}
+
+// ListImpressionCaps returns the caps of every capped booking still delivering
+func (db *DB) ListImpressionCaps() ([]impcap.Cap, error) {
+	rows, err := db.Query(impressionCapQuery + `
+		WHERE b.estimated_impressions > 0 AND b.status NOT IN ('completed', 'cancelled', 'expired')
+		ORDER BY b.booking_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impression caps: %w", err)
+	}
+	defer rows.Close()
+
+	caps := make([]impcap.Cap, 0)
+	for rows.Next() {
+		c, err := scanImpressionCap(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impression cap: %w", err)
+		}
+		caps = append(caps, *c)
+	}
+	return caps, rows.Err()
+}
+
+// SaveImpressionLease records what an edge node holds of a booking and until when
+func (db *DB) SaveImpressionLease(bookingID, nodeID string, held int64, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO impression_leases (booking_id, node_id, held, expires_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (booking_id, node_id) DO UPDATE SET
+			held = EXCLUDED.held,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = EXCLUDED.updated_at
+	`, bookingID, nodeID, held, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save impression lease: %w", err)
+	}
+	return nil
+}
+
+// DeleteImpressionLease removes an edge node's lease on a booking
+func (db *DB) DeleteImpressionLease(bookingID, nodeID string) error {
+	_, err := db.Exec(`DELETE FROM impression_leases WHERE booking_id = $1 AND node_id = $2`, bookingID, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete impression lease: %w", err)
+	}
+	return nil
+}
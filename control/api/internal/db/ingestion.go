@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+)
+
+const ingestionStageColumns = `
+	title_id::text, stage, status, run_id, COALESCE(message, ''),
+	started_at, completed_at, updated_at
+`
+
+// RecordIngestionStage applies a pipeline callback to a title's stage. A new
+// run of the uploaded stage resets the stages after it. The callback is
+// skipped when the stage has since seen a later one or it belongs to an
+// earlier run; the returned flag reports whether it was applied.
+func (db *DB) RecordIngestionStage(titleID string, event ingest.StageEvent) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, titleID)
+	if err != nil {
+		return false, err
+	}
+	stages, err := ingestionStages(tx, titleKey)
+	if err != nil {
+		return false, err
+	}
+
+	var current, uploaded *ingest.StageState
+	for i := range stages {
+		switch stages[i].Stage {
+		case event.Stage:
+			current = &stages[i]
+		case ingest.StageUploaded:
+			uploaded = &stages[i]
+		}
+	}
+	if event.Stage == ingest.StageUploaded {
+		uploaded = current
+	}
+	if current != nil && current.UpdatedAt != nil && event.OccurredAt.Before(*current.UpdatedAt) {
+		return false, nil
+	}
+	if event.Superseded(uploaded) {
+		return false, nil
+	}
+
+	if event.Restarts(uploaded) {
+		if _, err := tx.Exec(`DELETE FROM title_ingestion_stages WHERE title_id = $1 AND stage <> $2`, titleKey, ingest.StageUploaded); err != nil {
+			return false, fmt.Errorf("failed to reset ingestion stages: %w", err)
+		}
+	}
+
+	next := ingest.Apply(current, event)
+	_, err = tx.Exec(`
+		INSERT INTO title_ingestion_stages (title_id, stage, status, run_id, message, started_at, completed_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+		ON CONFLICT (title_id, stage) DO UPDATE SET
+			status = EXCLUDED.status,
+			run_id = EXCLUDED.run_id,
+			message = EXCLUDED.message,
+			started_at = EXCLUDED.started_at,
+			completed_at = EXCLUDED.completed_at,
+			updated_at = EXCLUDED.updated_at
+	`, titleKey, next.Stage, next.Status, next.RunID, next.Message, next.StartedAt, next.CompletedAt, next.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to save ingestion stage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit ingestion stage: %w", err)
+	}
+	return true, nil
+}
+
+// GetIngestionStages returns the stages a title's pipeline has reported on
+func (db *DB) GetIngestionStages(titleID string) ([]ingest.StageState, error) {
+	var titleKey int
+	err := db.QueryRow(`SELECT id FROM titles WHERE id::text = $1`, titleID).Scan(&titleKey)
+	if err == sql.ErrNoRows {
+		return nil, ErrTitleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get title: %w", err)
+	}
+	return ingestionStages(db, titleKey)
+}
+
+// ListOpenIngestions returns the reported stages of titles not yet
+// published, keyed by title ID, oldest activity first
+func (db *DB) ListOpenIngestions(limit int) (map[string][]ingest.StageState, error) {
+	rows, err := db.Query(`
+		SELECT `+ingestionStageColumns+`
+		FROM title_ingestion_stages
+		WHERE title_id IN (
+			SELECT title_id
+			FROM title_ingestion_stages
+			GROUP BY title_id
+			HAVING NOT bool_or(stage = $1 AND status = $2)
+			ORDER BY max(updated_at)
+			LIMIT $3
+		)
+	`, ingest.StagePublished, ingest.StatusCompleted, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open ingestions: %w", err)
+	}
+	defer rows.Close()
+
+	titles := make(map[string][]ingest.StageState)
+	for rows.Next() {
+		titleID, state, err := scanIngestionStage(rows)
+		if err != nil {
+			return nil, err
+		}
+		titles[titleID] = append(titles[titleID], state)
+	}
+	return titles, rows.Err()
+}
+
+// ingestionStages loads a title's reported stages
+func ingestionStages(q queryer, titleKey int) ([]ingest.StageState, error) {
+	rows, err := q.Query(`SELECT `+ingestionStageColumns+` FROM title_ingestion_stages WHERE title_id = $1`, titleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingestion stages: %w", err)
+	}
+	defer rows.Close()
+
+	stages := make([]ingest.StageState, 0, len(ingest.Stages))
+	for rows.Next() {
+		_, state, err := scanIngestionStage(rows)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, state)
+	}
+	return stages, rows.Err()
+}
+
+func scanIngestionStage(rows *sql.Rows) (string, ingest.StageState, error) {
+	var titleID string
+	var state ingest.StageState
+	var startedAt, completedAt, updatedAt sql.NullTime
+	err := rows.Scan(&titleID, &state.Stage, &state.Status, &state.RunID, &state.Message, &startedAt, &completedAt, &updatedAt)
+	if err != nil {
+		return "", state, fmt.Errorf("failed to scan ingestion stage: %w", err)
+	}
+	if startedAt.Valid {
+		state.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		state.CompletedAt = &completedAt.Time
+	}
+	if updatedAt.Valid {
+		state.UpdatedAt = &updatedAt.Time
+	}
+	return titleID, state, nil
+}
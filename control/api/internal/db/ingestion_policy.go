@@ -0,0 +1,52 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
+)
+
+// GetIngestionPolicy returns the ingestion policy operators set, or nil if none is set
+func (db *DB) GetIngestionPolicy() (*throttle.Policy, error) {
+	var policy throttle.Policy
+	var reason, updatedBy sql.NullString
+	var updatedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT max_batch_size, min_interval_ms, reason, updated_by, updated_at FROM ingestion_policy
+	`).Scan(&policy.MaxBatchSize, &policy.MinIntervalMS, &reason, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingestion policy: %w", err)
+	}
+	policy.Reason, policy.UpdatedBy, policy.UpdatedAt = reason.String, updatedBy.String, nullTime(updatedAt)
+	return &policy, nil
+}
+
+// SetIngestionPolicy stores the ingestion policy, filling in when it was set
+func (db *DB) SetIngestionPolicy(policy *throttle.Policy) error {
+	var updatedAt sql.NullTime
+	err := db.QueryRow(`
+		INSERT INTO ingestion_policy (singleton, max_batch_size, min_interval_ms, reason, updated_by, updated_at)
+		VALUES (true, $1, $2, NULLIF($3, ''), NULLIF($4, ''), CURRENT_TIMESTAMP)
+		ON CONFLICT (singleton) DO UPDATE
+		SET max_batch_size = EXCLUDED.max_batch_size, min_interval_ms = EXCLUDED.min_interval_ms,
+			reason = EXCLUDED.reason, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, policy.MaxBatchSize, policy.MinIntervalMS, policy.Reason, policy.UpdatedBy).Scan(&updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set ingestion policy: %w", err)
+	}
+	policy.UpdatedAt = nullTime(updatedAt)
+	return nil
+}
+
+// DeleteIngestionPolicy returns edge nodes to the default policy
+func (db *DB) DeleteIngestionPolicy() error {
+	if _, err := db.Exec(`DELETE FROM ingestion_policy`); err != nil {
+		return fmt.Errorf("failed to delete ingestion policy: %w", err)
+	}
+	return nil
+}
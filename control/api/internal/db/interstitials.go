@@ -0,0 +1,84 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+)
+
+// renderedAssetJoin joins the output of the latest completed render of
+// each booking players can load, as rendered.output_uri
+var renderedAssetJoin = `
+	LEFT JOIN LATERAL (
+		SELECT r.output_uri FROM render_jobs r
+		WHERE r.booking_id = placement_bookings.booking_id AND r.status = '` + render.StatusCompleted + `'
+			AND r.output_uri ~ '^https?://'
+		ORDER BY r.updated_at DESC, r.id DESC
+		LIMIT 1
+	) rendered ON true
+`
+
+// GetBookingInterstitial reads a booking's interstitial setting, or returns
+// nil if its placements are not interstitials
+func (db *DB) GetBookingInterstitial(bookingID string) (*decorate.Interstitial, error) {
+	var i decorate.Interstitial
+	var offset sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT bi.booking_id, COALESCE(bi.asset_uri, ''), bi.resume_offset, COALESCE(rendered.output_uri, ''),
+			COALESCE(bi.updated_by, ''), bi.updated_at
+		FROM booking_interstitials bi
+		JOIN placement_bookings ON placement_bookings.booking_id = bi.booking_id
+		`+renderedAssetJoin+`
+		WHERE bi.booking_id = $1
+	`, bookingID).Scan(&i.BookingID, &i.AssetURI, &offset, &i.RenderedAssetURI, &i.UpdatedBy, &i.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking interstitial: %w", err)
+	}
+	if offset.Valid {
+		i.ResumeOffset = &offset.Float64
+	}
+	return &i, nil
+}
+
+// SetBookingInterstitial makes a booking's placements interstitials, or
+// replaces their setting, setting UpdatedAt. It returns ErrBookingNotFound
+// for unknown bookings.
+func (db *DB) SetBookingInterstitial(i *decorate.Interstitial) error {
+	err := db.QueryRow(`
+		INSERT INTO booking_interstitials (booking_id, asset_uri, resume_offset, updated_by, updated_at)
+		SELECT booking_id, NULLIF($2, ''), $3, NULLIF($4, ''), CURRENT_TIMESTAMP
+		FROM placement_bookings WHERE booking_id = $1
+		ON CONFLICT (booking_id) DO UPDATE SET
+			asset_uri = EXCLUDED.asset_uri,
+			resume_offset = EXCLUDED.resume_offset,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, i.BookingID, i.AssetURI, i.ResumeOffset, i.UpdatedBy).Scan(&i.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrBookingNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set booking interstitial: %w", err)
+	}
+	return nil
+}
+
+// DeleteBookingInterstitial has a booking's placements only tagged again,
+// reporting whether they were interstitials
+func (db *DB) DeleteBookingInterstitial(bookingID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM booking_interstitials WHERE booking_id = $1`, bookingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete booking interstitial: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete booking interstitial: %w", err)
+	}
+	return affected > 0, nil
+}
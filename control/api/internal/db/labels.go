@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/lib/pq"
+)
+
+// SetLabels replaces all labels on a resource
+func (db *DB) SetLabels(resourceType, resourceID string, set labels.Set) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setLabels(tx, resourceType, resourceID, set); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit labels: %w", err)
+	}
+	return nil
+}
+
+// setLabels replaces a resource's labels within a transaction
+func setLabels(tx *sql.Tx, resourceType, resourceID string, set labels.Set) error {
+	if _, err := tx.Exec(`DELETE FROM resource_labels WHERE resource_type = $1 AND resource_id = $2`, resourceType, resourceID); err != nil {
+		return fmt.Errorf("failed to clear labels: %w", err)
+	}
+
+	for key, value := range set {
+		_, err := tx.Exec(`
+			INSERT INTO resource_labels (resource_type, resource_id, label_key, label_value)
+			VALUES ($1, $2, $3, $4)
+		`, resourceType, resourceID, key, value)
+		if err != nil {
+			return fmt.Errorf("failed to set label %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// GetLabels retrieves the labels on a resource
+func (db *DB) GetLabels(resourceType, resourceID string) (labels.Set, error) {
+	all, err := db.getLabelsFor(resourceType, []string{resourceID})
+	if err != nil {
+		return nil, err
+	}
+	if set, ok := all[resourceID]; ok {
+		return set, nil
+	}
+	return labels.Set{}, nil
+}
+
+// getLabelsFor retrieves labels for several resources of one type, keyed by resource ID
+func (db *DB) getLabelsFor(resourceType string, resourceIDs []string) (map[string]labels.Set, error) {
+	return labelsFor(db, resourceType, resourceIDs)
+}
+
+// labelsFor retrieves labels for several resources of one type, within a
+// transaction or not
+func labelsFor(q queryer, resourceType string, resourceIDs []string) (map[string]labels.Set, error) {
+	result := make(map[string]labels.Set)
+	if len(resourceIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT resource_id, label_key, label_value
+		FROM resource_labels
+		WHERE resource_type = $1 AND resource_id = ANY($2)
+	`, resourceType, pq.Array(resourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var resourceID, key, value string
+		if err := rows.Scan(&resourceID, &key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		if result[resourceID] == nil {
+			result[resourceID] = labels.Set{}
+		}
+		result[resourceID][key] = value
+	}
+
+	return result, rows.Err()
+}
+
+// labelSet returns a resource's labels from a getLabelsFor result, or an
+// empty set when it has none
+func labelSet(all map[string]labels.Set, resourceID string) labels.Set {
+	if set, ok := all[resourceID]; ok {
+		return set
+	}
+	return labels.Set{}
+}
+
+// labelFilter builds a condition restricting idColumn to resources carrying
+// every label in the selector. Placeholders are numbered from firstArg.
+func labelFilter(resourceType, idColumn string, selector labels.Set, firstArg int) (string, []interface{}) {
+	if len(selector) == 0 {
+		return "TRUE", nil
+	}
+	b := query.From(firstArg)
+	return labelCondition(b, resourceType, idColumn, selector), b.Args()
+}
+
+// whereLabels restricts idColumn to resources carrying every label in the
+// selector
+func whereLabels(b *query.Builder, resourceType, idColumn string, selector labels.Set) {
+	if len(selector) > 0 {
+		b.Add(labelCondition(b, resourceType, idColumn, selector))
+	}
+}
+
+// labelCondition binds a non-empty selector and returns the condition of
+// labelFilter
+func labelCondition(b *query.Builder, resourceType, idColumn string, selector labels.Set) string {
+	typeArg := b.Bind(resourceType)
+	matches := make([]string, 0, len(selector))
+	for _, key := range selector.Keys() {
+		matches = append(matches, fmt.Sprintf("(label_key = %s AND label_value = %s)", b.Bind(key), b.Bind(selector[key])))
+	}
+
+	return fmt.Sprintf(`%s IN (
+			SELECT resource_id FROM resource_labels
+			WHERE resource_type = %s AND (%s)
+			GROUP BY resource_id
+			HAVING COUNT(*) = %d
+		)`, query.Column(idColumn), typeArg, strings.Join(matches, " OR "), len(selector))
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/export"
+)
+
+// StreamLogRecords reads an advertiser's decisions and impressions of a day
+// in event time order, handing each to fn as it is scanned
+func (db *DB) StreamLogRecords(q export.LogQuery, fn func(r *export.LogRecord) error) error {
+	from, to := q.Range()
+	rows, err := db.Query(`
+		SELECT
+			'decision', d.event_id, d.event_timestamp, b.advertiser_id, b.campaign_id, b.booking_id,
+			b.creative_asset_id, d.surface_id, d.title_id, b.billing_model, d.outcome, d.error_code,
+			NULL, NULL::real, NULL::real, NULL::real, NULL::real, NULL::boolean, d.spend
+		FROM decision_events d
+		JOIN placement_bookings b ON b.booking_id = d.booking_id
+		WHERE b.advertiser_id = $1 AND d.event_timestamp >= $2 AND d.event_timestamp < $3
+		UNION ALL
+		SELECT
+			'impression', e.event_id, e.event_timestamp, b.advertiser_id, b.campaign_id, b.booking_id,
+			b.creative_asset_id, b.surface_id, s.title_id::text, b.billing_model, NULL, NULL,
+			e.device_type, e.exposure_duration, e.screen_coverage_percentage, e.attention_score, e.listen_through, e.counted, e.spend
+		FROM exposure_events e
+		JOIN placement_bookings b ON b.booking_id = e.booking_id
+		LEFT JOIN surfaces s ON s.surface_id = b.surface_id
+		WHERE b.advertiser_id = $1 AND e.event_timestamp >= $2 AND e.event_timestamp < $3
+		ORDER BY 3, 2
+	`, q.AdvertiserID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to query log-level records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r export.LogRecord
+		if err := rows.Scan(&r.RecordType, &r.EventID, &r.EventTime, &r.AdvertiserID, &r.CampaignID, &r.BookingID,
+			&r.CreativeID, &r.SurfaceID, &r.TitleID, &r.BillingModel, &r.Outcome, &r.ErrorCode,
+			&r.DeviceType, &r.ExposureDuration, &r.ScreenCoverage, &r.AttentionScore, &r.ListenThrough, &r.Counted, &r.Spend); err != nil {
+			return fmt.Errorf("failed to scan log-level record: %w", err)
+		}
+		if err := fn(&r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const logExportColumns = `advertiser_id, day, format, status, row_count, size_bytes, object_key, error, created_at, completed_at`
+
+// ClaimLogExports records a pending file for a day of each advertiser with
+// decisions or impressions on it, unless one was already written or is
+// being written, and returns the claimed files. Failed files are claimed
+// again.
+func (db *DB) ClaimLogExports(day time.Time, format string) ([]*export.LogExport, error) {
+	from, to := export.LogQuery{Day: day}.Range()
+	rows, err := db.Query(`
+		INSERT INTO log_level_exports (advertiser_id, day, format, status)
+		SELECT advertiser_id, $1::date, $3, $4
+		FROM (
+			SELECT b.advertiser_id
+			FROM decision_events d
+			JOIN placement_bookings b ON b.booking_id = d.booking_id
+			WHERE d.event_timestamp >= $1 AND d.event_timestamp < $2
+			UNION
+			SELECT b.advertiser_id
+			FROM exposure_events e
+			JOIN placement_bookings b ON b.booking_id = e.booking_id
+			WHERE e.event_timestamp >= $1 AND e.event_timestamp < $2
+		) active
+		ON CONFLICT (advertiser_id, day) DO UPDATE
+		SET status = EXCLUDED.status, format = EXCLUDED.format, error = NULL, object_key = NULL,
+			row_count = 0, size_bytes = 0, created_at = CURRENT_TIMESTAMP, started_at = NULL, completed_at = NULL
+		WHERE log_level_exports.status = $5
+		RETURNING `+logExportColumns, from, to, format, export.StatusPending, export.StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim log-level exports: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []*export.LogExport
+	for rows.Next() {
+		e, err := scanLogExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, e)
+	}
+	return claimed, rows.Err()
+}
+
+// GetLogExport retrieves an advertiser's file for a day, or nil when there is none
+func (db *DB) GetLogExport(advertiserID string, day time.Time) (*export.LogExport, error) {
+	e, err := scanLogExport(db.QueryRow(`
+		SELECT `+logExportColumns+`
+		FROM log_level_exports
+		WHERE advertiser_id = $1 AND day = $2::date
+	`, advertiserID, export.LogDay(day)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListLogExports lists an advertiser's files for the days from from to to
+// inclusive, newest first
+func (db *DB) ListLogExports(advertiserID string, from, to time.Time) ([]*export.LogExport, error) {
+	rows, err := db.Query(`
+		SELECT `+logExportColumns+`
+		FROM log_level_exports
+		WHERE advertiser_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY day DESC
+	`, advertiserID, export.LogDay(from), export.LogDay(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log-level exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []*export.LogExport{}
+	for rows.Next() {
+		e, err := scanLogExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// StartLogExport marks a file being written
+func (db *DB) StartLogExport(advertiserID string, day time.Time) error {
+	_, err := db.Exec(`
+		UPDATE log_level_exports SET status = $3, started_at = CURRENT_TIMESTAMP
+		WHERE advertiser_id = $1 AND day = $2::date
+	`, advertiserID, export.LogDay(day), export.StatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to start log-level export: %w", err)
+	}
+	return nil
+}
+
+// CompleteLogExport marks a file written with its storage key
+func (db *DB) CompleteLogExport(advertiserID string, day time.Time, objectKey string, rows, size int64) error {
+	_, err := db.Exec(`
+		UPDATE log_level_exports
+		SET status = $3, object_key = $4, row_count = $5, size_bytes = $6, completed_at = CURRENT_TIMESTAMP
+		WHERE advertiser_id = $1 AND day = $2::date
+	`, advertiserID, export.LogDay(day), export.StatusCompleted, objectKey, rows, size)
+	if err != nil {
+		return fmt.Errorf("failed to complete log-level export: %w", err)
+	}
+	return nil
+}
+
+// FailLogExport records a file failing to be written
+func (db *DB) FailLogExport(advertiserID string, day time.Time, message string) error {
+	_, err := db.Exec(`
+		UPDATE log_level_exports SET status = $3, error = $4, completed_at = CURRENT_TIMESTAMP
+		WHERE advertiser_id = $1 AND day = $2::date
+	`, advertiserID, export.LogDay(day), export.StatusFailed, message)
+	if err != nil {
+		return fmt.Errorf("failed to mark log-level export failed: %w", err)
+	}
+	return nil
+}
+
+func scanLogExport(row rowScanner) (*export.LogExport, error) {
+	var e export.LogExport
+	var objectKey, message sql.NullString
+	var completedAt sql.NullTime
+	err := row.Scan(&e.AdvertiserID, &e.Day, &e.Format, &e.Status, &e.RowCount, &e.Size,
+		&objectKey, &message, &e.CreatedAt, &completedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan log-level export: %w", err)
+	}
+	e.Day = export.LogDay(e.Day)
+	e.Date = e.Day.Format(export.LogDateLayout)
+	e.ObjectKey = objectKey.String
+	e.Error = message.String
+	e.CompletedAt = nullTime(completedAt)
+	return &e, nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/manifestlog"
+	"github.com/lib/pq"
+)
+
+const manifestCaptureColumns = `capture_id, session_id, title_id, org_id, content_type, original_size_bytes, decorated_size_bytes, placements, created_at, expires_at`
+
+// CreateManifestCapture indexes a captured playlist
+func (db *DB) CreateManifestCapture(c *manifestlog.Capture) error {
+	_, err := db.Exec(`
+		INSERT INTO manifest_captures (`+manifestCaptureColumns+`)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10)
+	`, c.CaptureID, c.SessionID, c.TitleID, c.OrgID, c.ContentType, c.OriginalSize, c.DecoratedSize,
+		c.Placements, c.CreatedAt, c.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest capture: %w", err)
+	}
+	return nil
+}
+
+// GetManifestCapture retrieves a capture that has not expired, or nil when
+// there is none
+func (db *DB) GetManifestCapture(captureID string) (*manifestlog.Capture, error) {
+	c, err := scanManifestCapture(db.QueryRow(`
+		SELECT `+manifestCaptureColumns+`
+		FROM manifest_captures
+		WHERE capture_id = $1 AND expires_at > $2
+	`, captureID, time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListManifestCaptures lists the unexpired captures of a playback session
+// or title, newest first
+func (db *DB) ListManifestCaptures(q manifestlog.Query) ([]*manifestlog.Capture, error) {
+	rows, err := db.Query(`
+		SELECT `+manifestCaptureColumns+`
+		FROM manifest_captures
+		WHERE ($1 = '' OR session_id = $1) AND ($2 = '' OR title_id = $2) AND expires_at > $3
+		ORDER BY created_at DESC, capture_id
+		LIMIT $4
+	`, q.SessionID, q.TitleID, time.Now().UTC(), q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifest captures: %w", err)
+	}
+	defer rows.Close()
+
+	captures := []*manifestlog.Capture{}
+	for rows.Next() {
+		c, err := scanManifestCapture(rows)
+		if err != nil {
+			return nil, err
+		}
+		captures = append(captures, c)
+	}
+	return captures, rows.Err()
+}
+
+// ExpiredManifestCaptures returns the IDs of up to limit captures that
+// expired before the given time
+func (db *DB) ExpiredManifestCaptures(before time.Time, limit int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT capture_id FROM manifest_captures
+		WHERE expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired manifest captures: %w", err)
+	}
+	defer rows.Close()
+
+	var captureIDs []string
+	for rows.Next() {
+		var captureID string
+		if err := rows.Scan(&captureID); err != nil {
+			return nil, fmt.Errorf("failed to scan manifest capture: %w", err)
+		}
+		captureIDs = append(captureIDs, captureID)
+	}
+	return captureIDs, rows.Err()
+}
+
+// DeleteManifestCaptures removes captures whose manifests were deleted
+func (db *DB) DeleteManifestCaptures(captureIDs []string) error {
+	_, err := db.Exec(`DELETE FROM manifest_captures WHERE capture_id = ANY($1)`, pq.Array(captureIDs))
+	if err != nil {
+		return fmt.Errorf("failed to delete manifest captures: %w", err)
+	}
+	return nil
+}
+
+func scanManifestCapture(row rowScanner) (*manifestlog.Capture, error) {
+	var c manifestlog.Capture
+	var sessionID, orgID sql.NullString
+	err := row.Scan(&c.CaptureID, &sessionID, &c.TitleID, &orgID, &c.ContentType, &c.OriginalSize, &c.DecoratedSize,
+		&c.Placements, &c.CreatedAt, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan manifest capture: %w", err)
+	}
+	c.SessionID = sessionID.String
+	c.OrgID = orgID.String
+	return &c, nil
+}
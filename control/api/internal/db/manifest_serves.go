@@ -0,0 +1,31 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/lib/pq"
+)
+
+// CreateManifestServe records a personalized playlist, sealing its viewer ID
+func (db *DB) CreateManifestServe(serve *decorate.Serve) error {
+	viewerID, err := db.fields.Seal(ManifestViewerIDColumn, serve.ViewerID)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt viewer ID: %w", err)
+	}
+	filtered, err := json.Marshal(serve.Filtered)
+	if err != nil {
+		return fmt.Errorf("failed to encode filtered placements: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO manifest_serves (serve_id, title_id, viewer_id, session_id, org_id, consent, booking_ids, filtered, served_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9)
+	`, serve.ServeID, serve.TitleID, viewerID, serve.SessionID, serve.OrgID, serve.Consent,
+		pq.Array(serve.BookingIDs), filtered, serve.ServedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest serve: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// ListManifestPlacements lists the placements of a title's confirmed and
+// active bookings visible within scope, in order of media time, as they
+// are signaled in its playlists. Placements of bookings with an
+// interstitial setting carry its asset, or else their latest render's.
+func (db *DB) ListManifestPlacements(scope tenant.Scope, titleID string) ([]decorate.Placement, error) {
+	where := query.New()
+	where.Where("s.title_id::text = %s", titleID)
+	where.Add("placement_bookings.status IN ('confirmed', 'active')")
+	whereBookingVisible(where, scope)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT placement_bookings.booking_id, placement_bookings.surface_id,
+			COALESCE(s.surface_type, s.placement_type), COALESCE(s.prs_score, 0), s.start_time, s.end_time,
+			CASE WHEN bi.booking_id IS NOT NULL THEN COALESCE(bi.asset_uri, rendered.output_uri, '') ELSE '' END,
+			bi.resume_offset
+		FROM placement_bookings
+		JOIN surfaces s ON s.surface_id = placement_bookings.surface_id
+		LEFT JOIN booking_interstitials bi ON bi.booking_id = placement_bookings.booking_id
+		%s
+		WHERE %s
+		ORDER BY s.start_time, placement_bookings.booking_id
+	`, renderedAssetJoin, where.Clause()), where.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query manifest placements: %w", err)
+	}
+	defer rows.Close()
+
+	placements := make([]decorate.Placement, 0)
+	for rows.Next() {
+		var p decorate.Placement
+		var offset sql.NullFloat64
+		if err := rows.Scan(&p.BookingID, &p.SurfaceID, &p.SurfaceType, &p.PRSScore, &p.Start, &p.End, &p.AssetURI, &offset); err != nil {
+			return nil, fmt.Errorf("failed to scan manifest placement: %w", err)
+		}
+		if offset.Valid {
+			p.ResumeOffset = &offset.Float64
+		}
+		placements = append(placements, p)
+	}
+	return placements, rows.Err()
+}
+
+// GetBookingTitle returns the title a booking's surface is in, or "" when
+// the booking does not exist
+func (db *DB) GetBookingTitle(bookingID string) (string, error) {
+	var titleID string
+	err := db.QueryRow(`
+		SELECT s.title_id::text
+		FROM placement_bookings pb
+		JOIN surfaces s ON s.surface_id = pb.surface_id
+		WHERE pb.booking_id = $1
+	`, bookingID).Scan(&titleID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get booking title: %w", err)
+	}
+	return titleID, nil
+}
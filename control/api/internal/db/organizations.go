@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/lib/pq"
+)
+
+// ErrOrganizationExists is returned when creating an organization whose ID is taken
+var ErrOrganizationExists = errors.New("organization already exists")
+
+// CreateOrganization stores a new organization
+func (db *DB) CreateOrganization(o *organization.Organization) error {
+	err := db.QueryRow(`
+		INSERT INTO organizations (org_id, name, created_by)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING created_at
+	`, o.ID, o.Name, o.CreatedBy).Scan(&o.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%w: %s", ErrOrganizationExists, o.ID)
+		}
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// GetOrganization retrieves an organization by ID
+func (db *DB) GetOrganization(orgID string) (*organization.Organization, error) {
+	o, err := scanOrganization(db.QueryRow(`
+		SELECT org_id, name, created_by, created_at FROM organizations WHERE org_id = $1
+	`, orgID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return o, err
+}
+
+// ListOrganizations lists organizations by ID
+func (db *DB) ListOrganizations() ([]*organization.Organization, error) {
+	rows, err := db.Query(`SELECT org_id, name, created_by, created_at FROM organizations ORDER BY org_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := make([]*organization.Organization, 0)
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// ListOrganizationMembers lists the users who may sign in to an organization
+// by username: those whose own organization it is, and added members
+func (db *DB) ListOrganizationMembers(orgID string) ([]organization.Member, error) {
+	rows, err := db.Query(`
+		SELECT username, true, created_by, created_at FROM users WHERE org_id = $1
+		UNION ALL
+		SELECT m.username, false, m.added_by, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.username = m.username
+		WHERE m.org_id = $1 AND u.org_id IS DISTINCT FROM $1
+		ORDER BY 1
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]organization.Member, 0)
+	for rows.Next() {
+		m := organization.Member{OrgID: orgID}
+		var addedBy sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&m.Username, &m.Default, &addedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		m.AddedBy = addedBy.String
+		m.CreatedAt = createdAt.Time
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddOrganizationMember lets a user sign in to an organization. Adding an
+// existing member changes nothing; m is filled in from the stored membership.
+func (db *DB) AddOrganizationMember(m *organization.Member) error {
+	var addedBy sql.NullString
+	err := db.QueryRow(`
+		INSERT INTO organization_members (org_id, username, added_by)
+		VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (org_id, username) DO UPDATE SET added_by = organization_members.added_by
+		RETURNING added_by, created_at
+	`, m.OrgID, m.Username, m.AddedBy).Scan(&addedBy, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+	m.AddedBy = addedBy.String
+	return nil
+}
+
+// RemoveOrganizationMember removes an added member and reports whether they were one
+func (db *DB) RemoveOrganizationMember(orgID, username string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM organization_members WHERE org_id = $1 AND username = $2`, orgID, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// IsOrganizationMember reports whether a user may sign in to an organization,
+// being their own or one they were added to
+func (db *DB) IsOrganizationMember(orgID, username string) (bool, error) {
+	var member bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE username = $2 AND org_id = $1)
+			OR EXISTS (SELECT 1 FROM organization_members WHERE org_id = $1 AND username = $2)
+	`, orgID, username).Scan(&member)
+	if err != nil {
+		return false, fmt.Errorf("failed to check organization membership: %w", err)
+	}
+	return member, nil
+}
+
+// scanOrganization scans an organizations row into an Organization
+func scanOrganization(row rowScanner) (*organization.Organization, error) {
+	var o organization.Organization
+	var createdBy sql.NullString
+	if err := row.Scan(&o.ID, &o.Name, &createdBy, &o.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan organization: %w", err)
+	}
+	o.CreatedBy = createdBy.String
+	return &o, nil
+}
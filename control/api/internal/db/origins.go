@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/origins"
+)
+
+// AddAllowedOrigin allows an origin to call a route group for an
+// organization. Allowing it again changes nothing; o is filled in from the
+// stored origin.
+func (db *DB) AddAllowedOrigin(o *origins.Origin) error {
+	var createdBy sql.NullString
+	err := db.QueryRow(`
+		INSERT INTO cors_origins (org_id, route_group, origin, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (org_id, route_group, origin) DO UPDATE SET created_by = cors_origins.created_by
+		RETURNING created_by, created_at
+	`, o.OrgID, o.Group, o.Origin, o.CreatedBy).Scan(&createdBy, &o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add allowed origin: %w", err)
+	}
+	o.CreatedBy = createdBy.String
+	return nil
+}
+
+// ListOrganizationOrigins lists the origins allowed for an organization by group and origin
+func (db *DB) ListOrganizationOrigins(orgID string) ([]origins.Origin, error) {
+	return db.queryAllowedOrigins(`WHERE org_id = $1`, orgID)
+}
+
+// ListAllowedOrigins lists the origins allowed for every organization
+func (db *DB) ListAllowedOrigins() ([]origins.Origin, error) {
+	return db.queryAllowedOrigins(``)
+}
+
+// queryAllowedOrigins lists the cors_origins rows matching where
+func (db *DB) queryAllowedOrigins(where string, args ...interface{}) ([]origins.Origin, error) {
+	rows, err := db.Query(`
+		SELECT org_id, route_group, origin, created_by, created_at
+		FROM cors_origins
+		`+where+`
+		ORDER BY org_id, route_group, origin
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allowed origins: %w", err)
+	}
+	defer rows.Close()
+
+	list := make([]origins.Origin, 0)
+	for rows.Next() {
+		var o origins.Origin
+		var createdBy sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&o.OrgID, &o.Group, &o.Origin, &createdBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan allowed origin: %w", err)
+		}
+		o.CreatedBy = createdBy.String
+		o.CreatedAt = createdAt.Time
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// RemoveAllowedOrigin stops an origin calling a route group for an
+// organization and reports whether it was allowed
+func (db *DB) RemoveAllowedOrigin(orgID, group, origin string) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM cors_origins WHERE org_id = $1 AND route_group = $2 AND origin = $3
+	`, orgID, group, origin)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove allowed origin: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove allowed origin: %w", err)
+	}
+	return affected > 0, nil
+}
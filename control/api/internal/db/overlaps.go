@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// exclusionViolation is the Postgres error code for exclusion constraint
+// violations, raised by placement_bookings_no_overlap
+const exclusionViolation = "23P01"
+
+// checkOverlap returns a *booking.ConflictError when the booking's window
+// overlaps that of a live booking of its surface. Bookings of a surface take
+// turns on an advisory lock held until tx ends, so two cannot both pass the
+// check; the placement_bookings_no_overlap constraint backs it up.
+func checkOverlap(tx *sql.Tx, b *models.NewBooking) error {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('booking:' || $1))`, b.SurfaceID); err != nil {
+		return fmt.Errorf("failed to lock surface bookings: %w", err)
+	}
+
+	conflict := booking.ConflictError{SurfaceID: b.SurfaceID}
+	var start, end sql.NullTime
+	err := tx.QueryRow(`
+		SELECT booking_id, advertiser_id, campaign_id, status, COALESCE(org_id, ''), start_time, end_time
+		FROM placement_bookings
+		WHERE surface_id = $1
+			AND status IN ('pending', 'confirmed', 'active', 'paused')
+			AND tsrange(start_time, end_time) && tsrange($2::timestamp, $3::timestamp)
+		ORDER BY COALESCE(booking_time, created_at), booking_id
+		LIMIT 1
+	`, b.SurfaceID, utcTime(b.StartTime), utcTime(b.EndTime)).Scan(
+		&conflict.BookingID, &conflict.AdvertiserID, &conflict.CampaignID, &conflict.Status, &conflict.OrgID, &start, &end)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check overlapping bookings: %w", err)
+	}
+	conflict.Start, conflict.End = nullTime(start), nullTime(end)
+	return &conflict
+}
+
+// overlapError reports a write to placement_bookings refused by
+// placement_bookings_no_overlap as booking.ErrConflict
+func overlapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == exclusionViolation {
+		return fmt.Errorf("%w: %s", booking.ErrConflict, pqErr.Detail)
+	}
+	return err
+}
+
+// utcTime converts an optional time to UTC for a TIMESTAMP column
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// nullTime returns a scanned optional time
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
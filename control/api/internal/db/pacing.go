@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+)
+
+// errNotDelivered is returned by deliverImpression when the booking has
+// delivered its max_impressions or has ended
+var errNotDelivered = errors.New("impression not delivered")
+
+// deliverImpression counts one delivered impression of a booking within tx.
+// The update locks the booking's row until tx ends, so concurrent exposures
+// cannot deliver past the cap. A confirmed booking goes live with its first
+// counted impression and the impression delivering the cap completes it,
+// both within tx; pending bookings cannot complete and simply stop counting.
+// Impressions reaching a pacing threshold or a milestone count are reported
+// too. The changes to publish once tx commits are returned.
+func deliverImpression(tx *sql.Tx, bookingID string) ([]eventbus.Event, error) {
+	plan := pacing.Plan{BookingID: bookingID}
+	var status string
+	var campaignID, orgID sql.NullString
+	var start, end sql.NullTime
+	err := tx.QueryRow(`
+		UPDATE placement_bookings
+		SET actual_impressions = COALESCE(actual_impressions, 0) + 1
+		WHERE booking_id = $1
+			AND status NOT IN ('completed', 'cancelled', 'expired')
+			AND (COALESCE(estimated_impressions, 0) <= 0 OR COALESCE(actual_impressions, 0) < estimated_impressions)
+		RETURNING actual_impressions, COALESCE(estimated_impressions, 0), status,
+			campaign_id, org_id, pacing, start_time, end_time
+	`, bookingID).Scan(&plan.Delivered, &plan.MaxImpressions, &status,
+		&campaignID, &orgID, &plan.Curve, &start, &end)
+	if err == sql.ErrNoRows {
+		return nil, errNotDelivered
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to count delivered impression: %w", err)
+	}
+	plan.Start, plan.End = nullTime(start), nullTime(end)
+
+	var events []eventbus.Event
+	threshold := pacing.ThresholdReached(plan.Delivered, plan.MaxImpressions)
+	milestone := pacing.Milestone(plan.Delivered)
+	if threshold > 0 || milestone {
+		now := time.Now()
+		events = append(events, eventbus.DeliveryReached{
+			BookingID:        bookingID,
+			CampaignID:       campaignID.String,
+			OrgID:            orgID.String,
+			Delivered:        plan.Delivered,
+			MaxImpressions:   plan.MaxImpressions,
+			ThresholdPercent: threshold,
+			Milestone:        milestone,
+			Pacing:           pacing.Curve(plan.Curve),
+			Target:           plan.Target(now),
+			OccurredAt:       now,
+		})
+	}
+
+	for _, eventType := range booking.DeliveryEvents(status, int(plan.Delivered), int(plan.MaxImpressions)) {
+		event := booking.Event{
+			BookingID: bookingID,
+			Type:      eventType,
+			Actor:     "system",
+		}
+		if eventType == booking.EventCompleted {
+			event.Data.Reason = "max_impressions delivered"
+		}
+		state, err := appendBookingEvent(tx, event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move delivering booking to %s: %w", eventType, err)
+		}
+		event.OccurredAt = state.UpdatedAt
+		events = append(events, bookingChanged(event, state.CampaignID, state.Status))
+	}
+	return events, nil
+}
+
+// GetBookingPacing returns a booking's pacing and the impressions it has
+// delivered, or nil if it does not exist
+func (db *DB) GetBookingPacing(bookingID string) (*pacing.Plan, error) {
+	plan := &pacing.Plan{BookingID: bookingID}
+	var start, end sql.NullTime
+	err := db.QueryRow(`
+		SELECT pacing, COALESCE(estimated_impressions, 0), COALESCE(actual_impressions, 0), start_time, end_time
+		FROM placement_bookings
+		WHERE booking_id = $1
+	`, bookingID).Scan(&plan.Curve, &plan.MaxImpressions, &plan.Delivered, &start, &end)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking pacing: %w", err)
+	}
+	plan.Start, plan.End = nullTime(start), nullTime(end)
+	return plan, nil
+}
+
+// CheckPacing returns pacing.ErrAheadOfPace when serving an evenly paced
+// booking now would take it past its pace. Unknown bookings pass; the
+// decision's other checks report them.
+func (db *DB) CheckPacing(bookingID string) error {
+	plan, err := db.GetBookingPacing(bookingID)
+	if err != nil || plan == nil {
+		return err
+	}
+	return plan.Check(time.Now().UTC())
+}
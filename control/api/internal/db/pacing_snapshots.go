@@ -0,0 +1,172 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/pacinglog"
+	"github.com/lib/pq"
+)
+
+// pacingSnapshotBatch bounds the snapshots inserted by one statement
+const pacingSnapshotBatch = 500
+
+const pacingSnapshotColumns = `booking_id, campaign_id, taken_at, pacing, max_impressions, delivered, start_time, end_time,
+	impression_cost, budget_amount, budget_spent, budget_seed, counter_remaining`
+
+// ListPacingInputs lists every confirmed and active booking with its
+// pacing, billing terms and its campaign's budget and authoritative spend
+func (db *DB) ListPacingInputs() ([]pacinglog.Snapshot, error) {
+	rows, err := db.Query(`
+		SELECT pb.booking_id, COALESCE(pb.campaign_id, ''), pb.pacing,
+			COALESCE(pb.estimated_impressions, 0), COALESCE(pb.actual_impressions, 0), pb.start_time, pb.end_time,
+			pb.billing_model, COALESCE(pb.final_cpm_rate, pb.bid_amount_cpm),
+			COALESCE(pb.min_visibility_duration, 0),
+			CASE WHEN s.placement_type = 'audio' THEN s.end_time - s.start_time ELSE 0 END,
+			b.amount, COALESCE(b.spent, 0)
+		FROM placement_bookings pb
+		LEFT JOIN surfaces s ON s.surface_id = pb.surface_id
+		LEFT JOIN (` + budgetQuery + `
+		) b(campaign_id, amount, updated_by, updated_at, spent) ON b.campaign_id = pb.campaign_id
+		WHERE pb.status IN ('confirmed', 'active')
+		ORDER BY pb.booking_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pacing inputs: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]pacinglog.Snapshot, 0)
+	for rows.Next() {
+		var s pacinglog.Snapshot
+		var start, end sql.NullTime
+		var amount sql.NullFloat64
+		if err := rows.Scan(&s.BookingID, &s.CampaignID, &s.Pacing, &s.MaxImpressions, &s.Delivered, &start, &end,
+			&s.Terms.Model, &s.Terms.CPM, &s.Terms.MinDuration, &s.Terms.SlotDuration, &amount, &s.BudgetSpent); err != nil {
+			return nil, fmt.Errorf("failed to scan pacing inputs: %w", err)
+		}
+		s.Start, s.End = nullTime(start), nullTime(end)
+		if amount.Valid {
+			s.BudgetAmount = &amount.Float64
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// CreatePacingSnapshots records snapshots, in batches
+func (db *DB) CreatePacingSnapshots(snapshots []pacinglog.Snapshot) error {
+	for len(snapshots) > 0 {
+		batch := snapshots
+		if len(batch) > pacingSnapshotBatch {
+			batch = batch[:pacingSnapshotBatch]
+		}
+		snapshots = snapshots[len(batch):]
+
+		values := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*13)
+		for i, s := range batch {
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13)
+			args = append(args, s.BookingID, s.CampaignID, s.TakenAt, s.Pacing, s.MaxImpressions, s.Delivered,
+				s.Start, s.End, s.ImpressionCost, s.BudgetAmount, s.BudgetSpent, s.BudgetSeed, s.CounterRemaining)
+		}
+		_, err := db.Exec(`INSERT INTO pacing_snapshots (`+pacingSnapshotColumns+`) VALUES `+strings.Join(values, ", "), args...)
+		if err != nil {
+			return fmt.Errorf("failed to create pacing snapshots: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeletePacingSnapshots deletes the snapshots taken before a time and
+// returns how many were deleted
+func (db *DB) DeletePacingSnapshots(before time.Time) (int, error) {
+	result, err := db.Exec(`DELETE FROM pacing_snapshots WHERE taken_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete pacing snapshots: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete pacing snapshots: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// ListPacingSnapshots lists a booking's snapshots taken from from until to,
+// oldest first
+func (db *DB) ListPacingSnapshots(bookingID string, from, to time.Time) ([]pacinglog.Snapshot, error) {
+	rows, err := db.Query(`
+		SELECT `+pacingSnapshotColumns+`
+		FROM pacing_snapshots
+		WHERE booking_id = $1 AND taken_at >= $2 AND taken_at < $3
+		ORDER BY taken_at
+	`, bookingID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pacing snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]pacinglog.Snapshot, 0)
+	for rows.Next() {
+		var s pacinglog.Snapshot
+		var campaignID sql.NullString
+		var start, end sql.NullTime
+		var amount, seed, counter sql.NullFloat64
+		if err := rows.Scan(&s.BookingID, &campaignID, &s.TakenAt, &s.Pacing, &s.MaxImpressions, &s.Delivered,
+			&start, &end, &s.ImpressionCost, &amount, &s.BudgetSpent, &seed, &counter); err != nil {
+			return nil, fmt.Errorf("failed to scan pacing snapshot: %w", err)
+		}
+		s.CampaignID = campaignID.String
+		s.Start, s.End = nullTime(start), nullTime(end)
+		s.BudgetAmount, s.BudgetSeed, s.CounterRemaining = nullFloat(amount), nullFloat(seed), nullFloat(counter)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// ListPacingDeliveries counts a booking's served decisions and their spend
+// from each of times to the next, the last until to
+func (db *DB) ListPacingDeliveries(bookingID string, times []time.Time, to time.Time) ([]pacinglog.Delivery, error) {
+	deliveries := make([]pacinglog.Delivery, len(times))
+	if len(times) == 0 {
+		return deliveries, nil
+	}
+	bounds := make([]string, len(times))
+	for i, t := range times {
+		bounds[i] = t.UTC().Format(time.RFC3339Nano)
+	}
+
+	rows, err := db.Query(`
+		SELECT width_bucket(event_timestamp, $2::timestamp[]), COUNT(*), COALESCE(SUM(spend), 0)
+		FROM decision_events
+		WHERE booking_id = $1 AND outcome = 'served' AND event_timestamp >= $3 AND event_timestamp < $4
+		GROUP BY 1
+	`, bookingID, pq.Array(bounds), times[0], to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pacing deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket int
+		var d pacinglog.Delivery
+		if err := rows.Scan(&bucket, &d.Served, &d.Spend); err != nil {
+			return nil, fmt.Errorf("failed to scan pacing delivery: %w", err)
+		}
+		if bucket >= 1 && bucket <= len(deliveries) {
+			deliveries[bucket-1] = d
+		}
+	}
+	return deliveries, rows.Err()
+}
+
+func nullFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+)
+
+// keysetFilter builds the condition and sort direction that page through a
+// listing ordered by sortColumn then idColumn, both descending: rows after
+// the page's cursor in that order, or before it nearest first when paging
+// back. sortKey is the cursor's parsed sort key. Placeholders are numbered
+// from firstArg.
+func keysetFilter(sortColumn, idColumn string, page pagination.Page, sortKey interface{}, firstArg int) (string, string, []interface{}) {
+	b := query.From(firstArg)
+	direction := whereKeyset(b, sortColumn, idColumn, page, sortKey)
+	return b.Clause(), direction, b.Args()
+}
+
+// whereKeyset adds the condition of keysetFilter and returns the sort
+// direction
+func whereKeyset(b *query.Builder, sortColumn, idColumn string, page pagination.Page, sortKey interface{}) string {
+	if page.Cursor == nil {
+		return query.Descending
+	}
+	return b.After(sortColumn, idColumn, sortKey, page.Cursor.ID, page.Cursor.Before)
+}
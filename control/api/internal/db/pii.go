@@ -0,0 +1,67 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+)
+
+// RecordPIIViolations adds one request's violations to the caller's daily counts
+func (db *DB) RecordPIIViolations(orgID, principalID, keyID string, violations []pii.Violation) error {
+	type countKey struct{ field, kind, action string }
+	counts := make(map[countKey]int)
+	for _, v := range violations {
+		counts[countKey{v.Field, v.Kind, v.Action}]++
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for key, count := range counts {
+		_, err := tx.Exec(`
+			INSERT INTO pii_violations (day, principal_id, key_id, org_id, field, kind, action, count, last_seen_at)
+			VALUES ((CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::date, $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+			ON CONFLICT (day, principal_id, key_id, field, kind, action)
+			DO UPDATE SET count = pii_violations.count + EXCLUDED.count, last_seen_at = EXCLUDED.last_seen_at
+		`, principalID, keyID, orgID, key.field, key.kind, key.action, count)
+		if err != nil {
+			return fmt.Errorf("failed to record PII violations: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit PII violations: %w", err)
+	}
+	return nil
+}
+
+// ListPIIViolations lists a principal's daily violation counts per key since a day, newest first
+func (db *DB) ListPIIViolations(principalID string, since time.Time) ([]pii.DailyCount, error) {
+	rows, err := db.Query(`
+		SELECT day, key_id, field, kind, action, count, last_seen_at
+		FROM pii_violations
+		WHERE principal_id = $1 AND day >= $2
+		ORDER BY day DESC, key_id, field, kind
+	`, principalID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query PII violations: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]pii.DailyCount, 0)
+	for rows.Next() {
+		var count pii.DailyCount
+		var day time.Time
+		if err := rows.Scan(&day, &count.KeyID, &count.Field, &count.Kind, &count.Action, &count.Count, &count.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan PII violations: %w", err)
+		}
+		count.Day = day.Format("2006-01-02")
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
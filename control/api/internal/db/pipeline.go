@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/lib/pq"
+)
+
+// UpsertShots saves a pipeline run's shot boundaries for a title. Shots are
+// keyed by shot ID, so a repeated delivery leaves the same rows.
+func (db *DB) UpsertShots(list *ingest.ShotList) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, list.TitleID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := upsertShots(tx, titleKey, list.Shots); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit shots: %w", err)
+	}
+	return len(list.Shots), nil
+}
+
+// SaveSceneGraph replaces the scene graph of a shot. The shot and every
+// surface the graph links to must belong to the title.
+func (db *DB) SaveSceneGraph(graph *ingest.SceneGraph) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, graph.TitleID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM shots WHERE title_id = $1 AND shot_id = $2)`, titleKey, graph.ShotID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query shot: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: unknown shot %s", ingest.ErrInvalidBatch, graph.ShotID)
+	}
+
+	if surfaceIDs := graph.SurfaceIDs(); len(surfaceIDs) > 0 {
+		var unknown sql.NullString
+		err := tx.QueryRow(`
+			SELECT linked.surface_id FROM unnest($2::text[]) AS linked(surface_id)
+			WHERE NOT EXISTS (SELECT 1 FROM surfaces s WHERE s.surface_id = linked.surface_id AND s.title_id = $1)
+			LIMIT 1
+		`, titleKey, pq.Array(surfaceIDs)).Scan(&unknown)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to query surfaces: %w", err)
+		}
+		if unknown.Valid {
+			return fmt.Errorf("%w: surface %s is not a surface of the title", ingest.ErrInvalidBatch, unknown.String)
+		}
+	}
+
+	nodes, err := json.Marshal(graph.Nodes)
+	if err != nil {
+		return fmt.Errorf("failed to encode scene graph nodes: %w", err)
+	}
+	edges := []byte("[]")
+	if len(graph.Edges) > 0 {
+		if edges, err = json.Marshal(graph.Edges); err != nil {
+			return fmt.Errorf("failed to encode scene graph edges: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO shot_scene_graphs (title_id, shot_id, run_id, nodes, edges, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (title_id, shot_id) DO UPDATE SET
+			run_id = EXCLUDED.run_id,
+			nodes = EXCLUDED.nodes,
+			edges = EXCLUDED.edges,
+			updated_at = EXCLUDED.updated_at
+	`, titleKey, graph.ShotID, graph.RunID, nodes, edges)
+	if err != nil {
+		return fmt.Errorf("failed to save scene graph: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scene graph: %w", err)
+	}
+	return nil
+}
+
+// SaveQCReport records a pipeline run's quality checks for a title. A check
+// is keyed by run, name and subject, so re-sending a report overwrites it.
+func (db *DB) SaveQCReport(report *ingest.QCReport) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	titleKey, err := lockTitle(tx, report.TitleID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, check := range report.Checks {
+		var score sql.NullFloat64
+		if check.Score != nil {
+			score = sql.NullFloat64{Float64: *check.Score, Valid: true}
+		}
+		_, err := tx.Exec(`
+			INSERT INTO title_qc_results (title_id, run_id, check_name, subject, status, score, message, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), CURRENT_TIMESTAMP)
+			ON CONFLICT (title_id, run_id, check_name, subject) DO UPDATE SET
+				status = EXCLUDED.status,
+				score = EXCLUDED.score,
+				message = EXCLUDED.message,
+				updated_at = EXCLUDED.updated_at
+		`, titleKey, report.RunID, check.Check, check.Subject(), check.Status, score, check.Message)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save QC check %s: %w", check.Check, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit QC report: %w", err)
+	}
+	return len(report.Checks), nil
+}
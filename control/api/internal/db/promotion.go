@@ -0,0 +1,446 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/promotion"
+	"github.com/lib/pq"
+)
+
+// ExportBundle describes campaigns, their live bookings and the creatives
+// those use for import into another environment
+func (db *DB) ExportBundle(source string, campaignIDs []string) (*promotion.Bundle, error) {
+	bundle := &promotion.Bundle{
+		FormatVersion: promotion.FormatVersion,
+		Source:        source,
+		ExportedAt:    time.Now().UTC(),
+		Campaigns:     make([]promotion.Campaign, 0, len(campaignIDs)),
+		Creatives:     make([]promotion.Creative, 0),
+		Bookings:      make([]promotion.Booking, 0),
+	}
+
+	rows, err := db.Query(`
+		SELECT pb.booking_id, pb.surface_id, COALESCE(s.title_id::text, ''), COALESCE(s.surface_type, ''),
+			pb.advertiser_id, pb.campaign_id, COALESCE(pb.creative_asset_id, ''), pb.bid_amount_cpm,
+			COALESCE(pb.estimated_impressions, 0), COALESCE(pb.min_prs_score, 0)
+		FROM placement_bookings pb
+		LEFT JOIN surfaces s ON s.surface_id = pb.surface_id
+		WHERE pb.campaign_id = ANY($1) AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+		ORDER BY pb.campaign_id, pb.booking_time, pb.booking_id
+		LIMIT $2
+	`, pq.Array(campaignIDs), promotion.MaxBookings+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b promotion.Booking
+		if err := rows.Scan(&b.BookingID, &b.SurfaceID, &b.TitleID, &b.SurfaceType, &b.AdvertiserID, &b.CampaignID,
+			&b.CreativeAssetID, &b.BidAmountCPM, &b.MaxImpressions, &b.MinPRSScore); err != nil {
+			return nil, fmt.Errorf("failed to scan booking for export: %w", err)
+		}
+		bundle.Bookings = append(bundle.Bookings, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query bookings for export: %w", err)
+	}
+	if len(bundle.Bookings) > promotion.MaxBookings {
+		return nil, fmt.Errorf("campaigns have more than %d live bookings; export fewer campaigns at a time", promotion.MaxBookings)
+	}
+
+	bookingIDs := make([]string, 0, len(bundle.Bookings))
+	var creativeIDs []string
+	seenCreatives := make(map[string]bool)
+	for _, b := range bundle.Bookings {
+		bookingIDs = append(bookingIDs, b.BookingID)
+		if b.CreativeAssetID != "" && !seenCreatives[b.CreativeAssetID] {
+			seenCreatives[b.CreativeAssetID] = true
+			creativeIDs = append(creativeIDs, b.CreativeAssetID)
+		}
+	}
+
+	bookingLabels, bookingIDsBySource, err := db.exportMetadata(labels.ResourceBooking, bookingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bundle.Bookings {
+		bundle.Bookings[i].Labels = bookingLabels[bundle.Bookings[i].BookingID]
+		bundle.Bookings[i].ExternalIDs = bookingIDsBySource[bundle.Bookings[i].BookingID]
+	}
+
+	campaignLabels, campaignExternalIDs, err := db.exportMetadata(labels.ResourceCampaign, campaignIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, campaignID := range campaignIDs {
+		campaign := promotion.Campaign{
+			CampaignID:  campaignID,
+			Labels:      campaignLabels[campaignID],
+			ExternalIDs: campaignExternalIDs[campaignID],
+		}
+		stored, err := db.GetCampaign(campaignID)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			campaign.AdvertiserID, campaign.Name = stored.AdvertiserID, stored.Name
+		}
+		b, err := db.GetBudget(campaignID)
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			campaign.Budget = &b.Amount
+		}
+		bundle.Campaigns = append(bundle.Campaigns, campaign)
+	}
+
+	creativeLabels, creativeExternalIDs, err := db.exportMetadata(labels.ResourceCreative, creativeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, creativeID := range creativeIDs {
+		bundle.Creatives = append(bundle.Creatives, promotion.Creative{
+			CreativeID:  creativeID,
+			Labels:      creativeLabels[creativeID],
+			ExternalIDs: creativeExternalIDs[creativeID],
+		})
+	}
+
+	return bundle, nil
+}
+
+// exportMetadata returns the labels and partner IDs of several resources of one type
+func (db *DB) exportMetadata(resourceType string, resourceIDs []string) (map[string]labels.Set, map[string]map[string]string, error) {
+	labelSets, err := db.getLabelsFor(resourceType, resourceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	externalIDs, err := externalIDsFor(db, resourceType, resourceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return labelSets, externalIDs, nil
+}
+
+// ImportBundle creates a bundle's campaigns, creatives and bookings in one
+// transaction. Every resource is checked against this environment first;
+// if any cannot be imported the manifest lists the problems and nothing
+// changes. Bookings imported from the same source before are skipped, so
+// a bundle can be imported again after fixing a problem. Dry runs make the
+// same changes and roll them back.
+func (db *DB) ImportBundle(imp *promotion.Import) (*promotion.Manifest, error) {
+	manifest := promotion.NewManifest(imp)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported, err := importedBookings(tx, imp.Bundle.Source)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImport(tx, imp, imported, manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Problems) > 0 {
+		return manifest, nil
+	}
+
+	problem, err := applyImport(tx, imp, imported, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		// The transaction is aborted; report what failed against a clean manifest
+		manifest = promotion.NewManifest(imp)
+		manifest.Problems = append(manifest.Problems, *problem)
+		return manifest, nil
+	}
+	if imp.DryRun {
+		return manifest, nil
+	}
+
+	manifest.ImportID = fmt.Sprintf("import_%d", manifest.ImportedAt.UnixNano())
+	document, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import manifest: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO promotion_imports (import_id, source, org_id, imported_by, manifest, imported_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+	`, manifest.ImportID, manifest.Source, imp.OrgID, imp.ImportedBy, document, manifest.ImportedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record import: %w", err)
+	}
+	for _, m := range manifest.Bookings {
+		if m.Action != promotion.ActionCreate {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO promoted_bookings (source, source_booking_id, booking_id, import_id)
+			VALUES ($1, $2, $3, $4)
+		`, manifest.Source, m.SourceID, m.TargetID, manifest.ImportID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record promoted booking: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	// applyImport maps the bundle's bookings in order, one mapping each
+	for i, m := range manifest.Bookings {
+		if m.Action == promotion.ActionCreate {
+			campaignID := imp.IDMap.Campaign(imp.Bundle.Bookings[i].CampaignID)
+			db.publish(bookingsCreated([]string{m.TargetID}, campaignID, imp.ImportedBy, imp.OrgID, manifest.ImportedAt)...)
+		}
+	}
+	return manifest, nil
+}
+
+// importedBookings maps the source booking IDs already imported from a
+// source to the bookings they became
+func importedBookings(tx *sql.Tx, source string) (map[string]string, error) {
+	rows, err := tx.Query(`
+		SELECT source_booking_id, booking_id FROM promoted_bookings WHERE source = $1
+	`, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promoted bookings: %w", err)
+	}
+	defer rows.Close()
+
+	imported := make(map[string]string)
+	for rows.Next() {
+		var sourceID, bookingID string
+		if err := rows.Scan(&sourceID, &bookingID); err != nil {
+			return nil, fmt.Errorf("failed to scan promoted booking: %w", err)
+		}
+		imported[sourceID] = bookingID
+	}
+	return imported, rows.Err()
+}
+
+// checkImport records the problems of importing a bundle here: unknown,
+// merged or held-back surfaces, and partner IDs taken by other resources
+func checkImport(tx *sql.Tx, imp *promotion.Import, imported map[string]string, manifest *promotion.Manifest) error {
+	for _, campaign := range imp.Bundle.Campaigns {
+		targetID := imp.IDMap.Campaign(campaign.CampaignID)
+		if err := checkExternalIDs(tx, labels.ResourceCampaign, campaign.CampaignID, targetID, campaign.ExternalIDs, manifest); err != nil {
+			return err
+		}
+	}
+	for _, creative := range imp.Bundle.Creatives {
+		targetID := imp.IDMap.Creative(creative.CreativeID)
+		if err := checkExternalIDs(tx, labels.ResourceCreative, creative.CreativeID, targetID, creative.ExternalIDs, manifest); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range imp.Bundle.Bookings {
+		if _, ok := imported[b.BookingID]; ok {
+			continue
+		}
+		surfaceID := imp.IDMap.Surface(b.SurfaceID)
+
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM surfaces WHERE surface_id = $1)`, surfaceID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check surface: %w", err)
+		}
+		if !exists {
+			manifest.AddProblem(promotion.ResourceBooking, b.BookingID, "surface %s does not exist here; map it in id_map.surfaces", surfaceID)
+			continue
+		}
+		err := checkMerged(tx, surfaceID)
+		if err == nil {
+			err = checkHoldback(tx, surfaceID)
+		}
+		if errors.Is(err, dedupe.ErrSurfaceMerged) || errors.Is(err, holdback.ErrHeldBack) {
+			manifest.AddProblem(promotion.ResourceBooking, b.BookingID, "%s", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// A new booking has no ID yet, so any holder of its partner IDs is another resource
+		if err := checkExternalIDs(tx, labels.ResourceBooking, b.BookingID, "", b.ExternalIDs, manifest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkExternalIDs records partner IDs held by a resource other than targetID
+func checkExternalIDs(tx *sql.Tx, resourceType, sourceID, targetID string, ids map[string]string, manifest *promotion.Manifest) error {
+	for source, externalID := range ids {
+		holder, err := externalIDHolder(tx, resourceType, source, externalID, targetID)
+		if err != nil {
+			return err
+		}
+		if holder == "" {
+			continue
+		}
+		manifest.AddProblem(resourceType, sourceID, "%s: %s/%s is attached to %s %s", ErrExternalIDTaken, source, externalID, resourceType, holder)
+	}
+	return nil
+}
+
+// applyImport writes a checked bundle within tx, filling in the manifest.
+// Conflicts that only show up while writing, such as a title's sellable
+// inventory running out partway through, are returned as a problem.
+func applyImport(tx *sql.Tx, imp *promotion.Import, imported map[string]string, manifest *promotion.Manifest) (*promotion.Problem, error) {
+	for _, c := range imp.Bundle.Campaigns {
+		targetID := imp.IDMap.Campaign(c.CampaignID)
+		action, err := importAction(tx, labels.ResourceCampaign, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if c.AdvertiserID != "" {
+			err := ensureCampaign(tx, &campaign.Campaign{CampaignID: targetID, AdvertiserID: c.AdvertiserID, Name: c.Name, CreatedBy: imp.ImportedBy}, imp.OrgID)
+			if errors.Is(err, campaign.ErrNotBookable) {
+				return &promotion.Problem{ResourceType: promotion.ResourceCampaign, SourceID: c.CampaignID, Message: err.Error()}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := registerResourceOwner(tx, labels.ResourceCampaign, targetID, imp.OrgID); err != nil {
+			return nil, err
+		}
+		if err := importMetadata(tx, labels.ResourceCampaign, targetID, c.Labels, c.ExternalIDs); err != nil {
+			return nil, err
+		}
+		if c.Budget != nil {
+			b := &budget.Budget{CampaignID: targetID, Amount: *c.Budget, UpdatedBy: imp.ImportedBy, UpdatedAt: manifest.ImportedAt}
+			if err := setBudget(tx, b); err != nil {
+				return nil, err
+			}
+		}
+		manifest.Campaigns = append(manifest.Campaigns, promotion.Mapping{SourceID: c.CampaignID, TargetID: targetID, Action: action})
+	}
+
+	for _, creative := range imp.Bundle.Creatives {
+		targetID := imp.IDMap.Creative(creative.CreativeID)
+		action, err := importAction(tx, labels.ResourceCreative, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if err := registerResourceOwner(tx, labels.ResourceCreative, targetID, imp.OrgID); err != nil {
+			return nil, err
+		}
+		if err := importMetadata(tx, labels.ResourceCreative, targetID, creative.Labels, creative.ExternalIDs); err != nil {
+			return nil, err
+		}
+		manifest.Creatives = append(manifest.Creatives, promotion.Mapping{SourceID: creative.CreativeID, TargetID: targetID, Action: action})
+	}
+
+	for _, b := range imp.Bundle.Bookings {
+		if bookingID, ok := imported[b.BookingID]; ok {
+			manifest.Bookings = append(manifest.Bookings, promotion.Mapping{SourceID: b.BookingID, TargetID: bookingID, Action: promotion.ActionSkip})
+			continue
+		}
+
+		data := &models.NewBooking{
+			SurfaceID:      imp.IDMap.Surface(b.SurfaceID),
+			AdvertiserID:   b.AdvertiserID,
+			CampaignID:     imp.IDMap.Campaign(b.CampaignID),
+			BidAmountCPM:   b.BidAmountCPM,
+			MaxImpressions: b.MaxImpressions,
+			MinPRSScore:    b.MinPRSScore,
+			Labels:         b.Labels,
+			ExternalIDs:    b.ExternalIDs,
+			OrgID:          imp.OrgID,
+			UserID:         imp.ImportedBy,
+		}
+		if b.CreativeAssetID != "" {
+			data.CreativeAssetID = imp.IDMap.Creative(b.CreativeAssetID)
+		}
+		// Bundles exported before campaigns were tracked name only the booking's advertiser
+		err := ensureCampaign(tx, &campaign.Campaign{CampaignID: data.CampaignID, AdvertiserID: data.AdvertiserID, CreatedBy: imp.ImportedBy}, imp.OrgID)
+		var bookingID string
+		if err == nil {
+			bookingID, err = createPlacementBooking(tx, data, manifest.ImportedAt)
+		}
+		if errors.Is(err, ErrExternalIDTaken) || errors.Is(err, holdback.ErrHeldBack) || errors.Is(err, dedupe.ErrSurfaceMerged) ||
+			errors.Is(err, collision.ErrCollision) || errors.Is(err, campaign.ErrNotBookable) || errors.Is(err, booking.ErrConflict) {
+			return &promotion.Problem{ResourceType: promotion.ResourceBooking, SourceID: b.BookingID, Message: err.Error()}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		manifest.Bookings = append(manifest.Bookings, promotion.Mapping{SourceID: b.BookingID, TargetID: bookingID, Action: promotion.ActionCreate})
+	}
+	return nil, nil
+}
+
+// importAction reports whether a campaign or creative already exists here
+func importAction(tx *sql.Tx, resourceType, resourceID string) (string, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM resource_owners WHERE resource_type = $1 AND resource_id = $2)
+			OR ($1 = 'campaign' AND EXISTS (SELECT 1 FROM placement_bookings WHERE campaign_id = $2))
+			OR ($1 = 'campaign' AND EXISTS (SELECT 1 FROM campaigns WHERE campaign_id = $2))
+			OR ($1 = 'creative' AND EXISTS (SELECT 1 FROM placement_bookings WHERE creative_asset_id = $2))
+	`, resourceType, resourceID).Scan(&exists)
+	if err != nil {
+		return "", fmt.Errorf("failed to check existing %s: %w", resourceType, err)
+	}
+	if exists {
+		return promotion.ActionUpdate, nil
+	}
+	return promotion.ActionCreate, nil
+}
+
+// importMetadata replaces the labels and partner IDs a bundle carries for a resource
+func importMetadata(tx *sql.Tx, resourceType, resourceID string, set labels.Set, ids map[string]string) error {
+	if len(set) > 0 {
+		if err := setLabels(tx, resourceType, resourceID, set); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		if err := setExternalIDs(tx, resourceType, resourceID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPromotionImport returns the manifest of an import made by an
+// organization, or nil if there is none
+func (db *DB) GetPromotionImport(orgID, importID string) (*promotion.Manifest, error) {
+	var document []byte
+	err := db.QueryRow(`
+		SELECT manifest FROM promotion_imports
+		WHERE import_id = $1 AND COALESCE(org_id, '') = $2
+	`, importID, orgID).Scan(&document)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+
+	var manifest promotion.Manifest
+	if err := json.Unmarshal(document, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode import manifest: %w", err)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/lib/pq"
+)
+
+// validEvent holds for exposure_events rows that are not invalid traffic, as
+// defined by package quality
+var validEvent = fmt.Sprintf(`(exposure_duration > 0
+	AND COALESCE(attention_score, 0) BETWEEN 0 AND 1
+	AND COALESCE(screen_coverage_percentage, 0) BETWEEN 0 AND 100
+	AND (received_at IS NULL OR event_timestamp <= received_at + interval '%d seconds'))`,
+	int(quality.FutureTolerance.Seconds()))
+
+// duplicateEvent holds for exposure_events rows that repeat an earlier row of
+// their booking, as stored when an edge node resends a batch. Events without
+// a device time were timed on receipt, so resent copies cannot be told apart.
+const duplicateEvent = `EXISTS (
+	SELECT 1 FROM exposure_events earlier
+	WHERE earlier.booking_id = exposure_events.booking_id
+		AND earlier.viewer_id = exposure_events.viewer_id
+		AND earlier.device_event_timestamp = exposure_events.device_event_timestamp
+		AND earlier.exposure_duration = exposure_events.exposure_duration
+		AND earlier.id < exposure_events.id
+)`
+
+// countedEvent keeps the exposure_events rows analytics figures are built from
+var countedEvent = "(" + validEvent + " AND NOT " + duplicateEvent + ")"
+
+// whereQualityFilter restricts exposure_events to a filter's booking or
+// campaign and tenant scope, and to its time range when timed is set
+func whereQualityFilter(b *query.Builder, f quality.Filter, timed bool) {
+	if f.BookingID != "" {
+		b.Equal("booking_id", f.BookingID)
+	}
+	if f.CampaignID != "" {
+		b.Where("booking_id IN (SELECT booking_id FROM placement_bookings WHERE campaign_id = %s)", f.CampaignID)
+	}
+	if timed && !f.From.IsZero() {
+		b.AtLeast("event_timestamp", f.From)
+	}
+	if timed && !f.To.IsZero() {
+		b.Where("event_timestamp < %s", f.To)
+	}
+	whereTenant(b, "org_id", f.Tenant)
+}
+
+// GetDataQuality counts the events a filter's figures leave out and
+// estimates the completeness of its recent hours
+func (db *DB) GetDataQuality(f quality.Filter) (*quality.Report, error) {
+	now := time.Now().UTC()
+
+	where := query.New()
+	whereQualityFilter(where, f, true)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT %s),
+			COUNT(*) FILTER (WHERE %s AND %s),
+			MAX(received_at)
+		FROM exposure_events
+		WHERE %s
+	`, validEvent, validEvent, duplicateEvent, where.Clause())
+
+	var considered, invalid, duplicates int64
+	var lastReceivedAt sql.NullTime
+	if err := db.QueryRow(stmt, where.Args()...).Scan(&considered, &invalid, &duplicates, &lastReceivedAt); err != nil {
+		return nil, fmt.Errorf("failed to count excluded events: %w", err)
+	}
+	report := quality.NewReport(considered, invalid, duplicates, nullTime(lastReceivedAt), now)
+
+	hours := f.RecentHours(now)
+	if len(hours) == 0 {
+		return report, nil
+	}
+	estimates, err := db.completeness(f, hours, now)
+	if err != nil {
+		return nil, err
+	}
+	for i, hour := range hours {
+		report.Completeness = append(report.Completeness, quality.Hour{Hour: hour, Estimate: estimates[i]})
+	}
+	return report, nil
+}
+
+// completeness returns, for each of hours, the share of the filter's
+// reference events that arrived within the hour's elapsed time
+func (db *DB) completeness(f quality.Filter, hours []time.Time, now time.Time) ([]*float64, error) {
+	elapsed := make([]float64, len(hours))
+	for i, hour := range hours {
+		elapsed[i] = quality.Elapsed(hour, now).Seconds()
+	}
+	from, to := quality.ReferenceRange(now)
+
+	where := query.New()
+	whereQualityFilter(where, f, false)
+	where.AtLeast("event_timestamp", from)
+	where.Where("event_timestamp < %s", to)
+	where.Add("received_at IS NOT NULL")
+	where.Add(validEvent)
+	elapsedArg := where.Bind(pq.Array(elapsed))
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(r.delay) FILTER (WHERE r.delay <= t.elapsed)::float8 / NULLIF(COUNT(r.delay), 0)
+		FROM unnest(%s::float8[]) WITH ORDINALITY AS t(elapsed, position)
+		LEFT JOIN (
+			SELECT EXTRACT(EPOCH FROM received_at - event_timestamp) AS delay
+			FROM exposure_events
+			WHERE %s
+		) r ON TRUE
+		GROUP BY t.position
+		ORDER BY t.position
+	`, elapsedArg, where.Clause())
+
+	rows, err := db.Query(stmt, where.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate completeness: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := make([]*float64, 0, len(hours))
+	for rows.Next() {
+		var estimate sql.NullFloat64
+		if err := rows.Scan(&estimate); err != nil {
+			return nil, fmt.Errorf("failed to scan completeness: %w", err)
+		}
+		if estimate.Valid {
+			estimates = append(estimates, &estimate.Float64)
+		} else {
+			estimates = append(estimates, nil)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(estimates) != len(hours) {
+		return nil, fmt.Errorf("failed to estimate completeness: got %d hours, want %d", len(estimates), len(hours))
+	}
+	return estimates, nil
+}
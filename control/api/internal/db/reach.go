@@ -0,0 +1,221 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/reach"
+	"github.com/inscenium/inscenium/control/api/internal/sketch"
+)
+
+// reachRollup names the reach sketches' row in exposure_rollup_state
+const reachRollup = "reach"
+
+// RollUpReach recomputes the day sketches of the campaigns and days of the
+// events received since the last run, up to through. Runs are serialized by
+// locking the rollup state, like RollUpExposures.
+func (db *DB) RollUpReach(through time.Time) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin reach rollup transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO exposure_rollup_state (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, reachRollup); err != nil {
+		return 0, fmt.Errorf("failed to create reach rollup state: %w", err)
+	}
+	var since time.Time
+	if err := tx.QueryRow(`SELECT rolled_up_through FROM exposure_rollup_state WHERE name = $1 FOR UPDATE`, reachRollup).Scan(&since); err != nil {
+		return 0, fmt.Errorf("failed to lock reach rollup state: %w", err)
+	}
+	if !through.After(since) {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(`CREATE TEMP TABLE reach_touched (campaign_id VARCHAR(100), bucket TIMESTAMP) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("failed to create reach work table: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO reach_touched
+		SELECT DISTINCT pb.campaign_id, date_trunc('day', exposure_events.event_timestamp)
+		FROM exposure_events
+		JOIN placement_bookings pb ON pb.booking_id = exposure_events.booking_id
+		WHERE (exposure_events.received_at > $1 AND exposure_events.received_at <= $2)
+			OR (exposure_events.received_at IS NULL AND exposure_events.event_timestamp > $1 AND exposure_events.event_timestamp <= $2)
+	`, since, through); err != nil {
+		return 0, fmt.Errorf("failed to find touched reach days: %w", err)
+	}
+
+	// One row per viewer of each touched campaign day, folded into sketches
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT t.campaign_id, t.bucket, exposure_events.viewer_id, COUNT(*), MAX(exposure_events.org_id)
+		FROM reach_touched t
+		JOIN placement_bookings pb ON pb.campaign_id = t.campaign_id
+		JOIN exposure_events
+			ON exposure_events.booking_id = pb.booking_id
+			AND exposure_events.event_timestamp >= t.bucket
+			AND exposure_events.event_timestamp < t.bucket + interval '1 day'
+		WHERE %s
+		GROUP BY t.campaign_id, t.bucket, exposure_events.viewer_id
+	`, countedEvent))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read touched reach days: %w", err)
+	}
+	type dayKey struct {
+		campaignID string
+		bucket     time.Time
+	}
+	days := make(map[dayKey]*reach.Day)
+	orgs := make(map[dayKey]string)
+	for rows.Next() {
+		var key dayKey
+		var viewerID string
+		var impressions int64
+		var orgID sql.NullString
+		if err := rows.Scan(&key.campaignID, &key.bucket, &viewerID, &impressions, &orgID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan reach viewer: %w", err)
+		}
+		d, ok := days[key]
+		if !ok {
+			d = &reach.Day{CampaignID: key.campaignID, Day: key.bucket, Viewers: sketch.New()}
+			days[key] = d
+		}
+		d.Impressions += impressions
+		d.Viewers.Add(viewerID)
+		if orgID.String > orgs[key] {
+			orgs[key] = orgID.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	// Days whose events are no longer counted keep no sketch
+	if _, err := tx.Exec(`
+		DELETE FROM campaign_reach_daily r USING reach_touched t
+		WHERE r.campaign_id = t.campaign_id AND r.bucket = t.bucket
+	`); err != nil {
+		return 0, fmt.Errorf("failed to clear reach days: %w", err)
+	}
+	for key, d := range days {
+		viewers, err := d.Viewers.MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO campaign_reach_daily (campaign_id, bucket, org_id, impressions, viewers, updated_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, NOW())
+		`, d.CampaignID, d.Day, orgs[key], d.Impressions, viewers); err != nil {
+			return 0, fmt.Errorf("failed to save reach day: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE exposure_rollup_state SET rolled_up_through = $2, updated_at = NOW() WHERE name = $1
+	`, reachRollup, through); err != nil {
+		return 0, fmt.Errorf("failed to save reach rollup state: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reach rollup: %w", err)
+	}
+	return len(days), nil
+}
+
+// GetReachDays reads the day sketches of a query's campaigns within its
+// range and tenant scope, along with when they were last brought up to date
+func (db *DB) GetReachDays(q reach.Query) ([]reach.Day, *time.Time, error) {
+	where := query.New()
+	where.AnyOf("campaign_id", q.CampaignIDs)
+	where.AtLeast("bucket", q.From)
+	where.Where("bucket < %s", q.To)
+	whereTenant(where, "org_id", q.Tenant)
+	if err := where.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT campaign_id, bucket, impressions, viewers
+		FROM campaign_reach_daily
+		WHERE %s
+		ORDER BY campaign_id, bucket
+	`, where.Clause()), where.Args()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query reach days: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]reach.Day, 0)
+	for rows.Next() {
+		var d reach.Day
+		var viewers []byte
+		if err := rows.Scan(&d.CampaignID, &d.Day, &d.Impressions, &viewers); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan reach day: %w", err)
+		}
+		d.Viewers = sketch.New()
+		if err := d.Viewers.UnmarshalBinary(viewers); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode reach sketch of %s on %s: %w", d.CampaignID, d.Day.Format(time.DateOnly), err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var through sql.NullTime
+	err = db.QueryRow(`SELECT rolled_up_through FROM exposure_rollup_state WHERE name = $1`, reachRollup).Scan(&through)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("failed to get reach rollup state: %w", err)
+	}
+	return days, nullTime(through), nil
+}
+
+// GetFrequencyDistribution counts, per campaign of a query, the viewers its
+// counted events within the range and tenant scope reached each number of
+// times, up to reach.MaxFrequency
+func (db *DB) GetFrequencyDistribution(q reach.Query) (map[string]map[int]int64, error) {
+	where := query.New()
+	where.AnyOf("pb.campaign_id", q.CampaignIDs)
+	where.AtLeast("exposure_events.event_timestamp", q.From)
+	where.Where("exposure_events.event_timestamp < %s", q.To)
+	where.Add(countedEvent)
+	whereTenant(where, "exposure_events.org_id", q.Tenant)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT campaign_id, LEAST(exposures, %d), COUNT(*)
+		FROM (
+			SELECT pb.campaign_id, exposure_events.viewer_id, COUNT(*) AS exposures
+			FROM exposure_events
+			JOIN placement_bookings pb ON pb.booking_id = exposure_events.booking_id
+			WHERE %s
+			GROUP BY pb.campaign_id, exposure_events.viewer_id
+		) viewers
+		GROUP BY 1, 2
+	`, reach.MaxFrequency, where.Clause()), where.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frequency distribution: %w", err)
+	}
+	defer rows.Close()
+
+	frequencies := make(map[string]map[int]int64)
+	for rows.Next() {
+		var campaignID string
+		var frequency int
+		var viewers int64
+		if err := rows.Scan(&campaignID, &frequency, &viewers); err != nil {
+			return nil, fmt.Errorf("failed to scan frequency: %w", err)
+		}
+		if frequencies[campaignID] == nil {
+			frequencies[campaignID] = make(map[int]int64)
+		}
+		frequencies[campaignID][frequency] = viewers
+	}
+	return frequencies, rows.Err()
+}
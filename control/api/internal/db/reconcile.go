@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/reconcile"
+)
+
+// FindBookingIssues compares live bookings against SGI inventory, the rights
+// ledger, ownership records and surface freshness. A booking may appear once
+// per issue kind.
+func (db *DB) FindBookingIssues(orgID string, holdTTL time.Duration, limit int) ([]reconcile.Issue, error) {
+	query := `
+		WITH live AS (
+			SELECT b.booking_id, b.surface_id, b.campaign_id, b.status,
+				COALESCE(b.booking_time, b.created_at) AS booking_time, b.min_prs_score,
+				bo.org_id AS booking_org, co.org_id AS campaign_org
+			FROM placement_bookings b
+			LEFT JOIN resource_owners bo ON bo.resource_type = 'booking' AND bo.resource_id = b.booking_id
+			LEFT JOIN resource_owners co ON co.resource_type = 'campaign' AND co.resource_id = b.campaign_id
+			WHERE b.status IN ('pending', 'confirmed', 'active')
+				AND ($1::text = $2::text OR bo.org_id IS NULL OR bo.org_id = $1)
+		)
+		SELECT kind, booking_id, surface_id, campaign_id, status, booking_time, detail FROM (
+			SELECT $3::text AS kind, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time, '' AS detail
+			FROM live l
+			WHERE NOT EXISTS (SELECT 1 FROM surfaces s WHERE s.surface_id = l.surface_id)
+
+			UNION ALL
+			SELECT $4::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time, ''
+			FROM live l JOIN surfaces s ON s.surface_id = l.surface_id
+			WHERE s.prs_score <= 0
+
+			UNION ALL
+			SELECT $5::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time,
+				format('surface PRS %s is below the booking minimum %s', s.prs_score, l.min_prs_score)
+			FROM live l JOIN surfaces s ON s.surface_id = l.surface_id
+			WHERE s.prs_score > 0 AND s.prs_score < l.min_prs_score
+
+			UNION ALL
+			SELECT $6::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time, ''
+			FROM live l
+			WHERE EXISTS (SELECT 1 FROM rights_ledger r WHERE r.surface_id = l.surface_id)
+				AND NOT EXISTS (
+					SELECT 1 FROM rights_ledger r
+					WHERE r.surface_id = l.surface_id
+						AND (r.valid_from IS NULL OR r.valid_from <= CURRENT_TIMESTAMP)
+						AND (r.valid_until IS NULL OR r.valid_until > CURRENT_TIMESTAMP)
+				)
+
+			UNION ALL
+			SELECT $12::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time,
+				CASE WHEN s.revalidation_requested_at IS NOT NULL
+					THEN format('queued for re-validation (%s) since %s', s.revalidation_reason, s.revalidation_requested_at)
+					ELSE format('last validated %s', s.last_validated_at)
+				END
+			FROM live l JOIN surfaces s ON s.surface_id = l.surface_id
+			WHERE s.prs_score > 0 AND ` + staleCondition("s", "$13::bigint") + `
+
+			UNION ALL
+			SELECT $7::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time, ''
+			FROM live l
+			WHERE l.booking_org IS NOT NULL AND l.campaign_org IS NULL
+
+			UNION ALL
+			SELECT $8::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time,
+				format('booking owned by %s, campaign by %s', l.booking_org, l.campaign_org)
+			FROM live l
+			WHERE l.booking_org <> l.campaign_org
+
+			UNION ALL
+			SELECT $9::text, l.booking_id, l.surface_id, l.campaign_id, l.status, l.booking_time,
+				format('pending since %s', l.booking_time)
+			FROM live l
+			WHERE l.status = 'pending' AND l.booking_time < CURRENT_TIMESTAMP - make_interval(secs => $10)
+		) issues
+		ORDER BY booking_time, booking_id, kind
+		LIMIT $11
+	`
+
+	rows, err := db.Query(query, orgID, reconcile.AllOrganizations,
+		reconcile.KindSurfaceMissing, reconcile.KindSurfaceWithdrawn, reconcile.KindSurfaceBelowMinPRS,
+		reconcile.KindRightsExpired, reconcile.KindCampaignUnowned, reconcile.KindOwnerMismatch,
+		reconcile.KindHoldExpired, holdTTL.Seconds(), limit, reconcile.KindSurfaceStale, int64(db.staleAfter/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := make([]reconcile.Issue, 0)
+	for rows.Next() {
+		var issue reconcile.Issue
+		if err := rows.Scan(&issue.Kind, &issue.BookingID, &issue.SurfaceID, &issue.CampaignID,
+			&issue.Status, &issue.BookedAt, &issue.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan booking issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, rows.Err()
+}
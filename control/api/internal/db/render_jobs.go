@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/lib/pq"
+)
+
+const renderJobColumns = `
+	job_id, COALESCE(booking_id, ''), COALESCE(surface_id, ''), COALESCE(worker_id, ''),
+	status, progress, COALESCE(output_uri, ''), COALESCE(error, ''), COALESCE(watermark_id, 0),
+	created_at, updated_at, last_event_at
+`
+
+// ErrWatermarkTaken is returned when saving a render job with a watermark ID
+// another job already has
+var ErrWatermarkTaken = errors.New("watermark ID already issued")
+
+// renderJobWatermarkIndex is the unique index on render job watermark IDs
+const renderJobWatermarkIndex = "idx_render_jobs_watermark"
+
+// GetRenderJob retrieves a render job by ID
+func (db *DB) GetRenderJob(jobID string) (*render.Job, error) {
+	query := `SELECT ` + renderJobColumns + ` FROM render_jobs WHERE job_id = $1`
+
+	job, err := scanRenderJob(db.QueryRow(query, jobID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return job, err
+}
+
+// ListRenderJobs lists render jobs for a booking, newest first
+func (db *DB) ListRenderJobs(bookingID string, limit, offset int) ([]*render.Job, error) {
+	query := `
+		SELECT ` + renderJobColumns + `
+		FROM render_jobs
+		WHERE booking_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := db.Query(query, bookingID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query render jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*render.Job, 0)
+	for rows.Next() {
+		job, err := scanRenderJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// SaveRenderJob writes the state produced by a callback. The write is skipped
+// when the stored job has since moved to a terminal status or seen a later
+// callback, so concurrent deliveries cannot roll a job back; the returned
+// flag reports whether the job was written. A job keeps the watermark ID it
+// was first saved with, which is set on job once written.
+func (db *DB) SaveRenderJob(job *render.Job) (bool, error) {
+	query := `
+		INSERT INTO render_jobs (
+			job_id, booking_id, surface_id, worker_id, status, progress,
+			output_uri, error, created_at, last_event_at, watermark_id
+		) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, NULLIF($14, 0))
+		ON CONFLICT (job_id) DO UPDATE SET
+			booking_id = EXCLUDED.booking_id,
+			surface_id = EXCLUDED.surface_id,
+			worker_id = EXCLUDED.worker_id,
+			status = EXCLUDED.status,
+			progress = EXCLUDED.progress,
+			output_uri = EXCLUDED.output_uri,
+			error = EXCLUDED.error,
+			last_event_at = EXCLUDED.last_event_at,
+			watermark_id = COALESCE(render_jobs.watermark_id, EXCLUDED.watermark_id),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE render_jobs.last_event_at <= EXCLUDED.last_event_at
+			AND render_jobs.status NOT IN ($11, $12, $13)
+		RETURNING COALESCE(watermark_id, 0)
+	`
+
+	var watermarkID int64
+	err := db.QueryRow(query,
+		job.ID,
+		job.BookingID,
+		job.SurfaceID,
+		job.WorkerID,
+		job.Status,
+		job.Progress,
+		job.OutputURI,
+		job.Error,
+		job.CreatedAt,
+		job.LastEventAt,
+		render.StatusCompleted,
+		render.StatusFailed,
+		render.StatusCancelled,
+		int64(job.WatermarkID),
+	).Scan(&watermarkID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == renderJobWatermarkIndex {
+		return false, ErrWatermarkTaken
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save render job: %w", err)
+	}
+	job.WatermarkID = uint32(watermarkID)
+	return true, nil
+}
+
+// ClaimWebhookNonce records a callback nonce, returning false if it was
+// already seen. Expired nonces are pruned on the way.
+func (db *DB) ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error) {
+	if _, err := db.Exec(`DELETE FROM webhook_nonces WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return false, fmt.Errorf("failed to prune webhook nonces: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_nonces (source, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, nonce) DO NOTHING
+	`
+
+	result, err := db.Exec(query, source, nonce, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook nonce: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook nonce: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// scanRenderJob scans a render_jobs row selected with renderJobColumns
+func scanRenderJob(row rowScanner) (*render.Job, error) {
+	var job render.Job
+	var watermarkID int64
+
+	err := row.Scan(
+		&job.ID, &job.BookingID, &job.SurfaceID, &job.WorkerID,
+		&job.Status, &job.Progress, &job.OutputURI, &job.Error, &watermarkID,
+		&job.CreatedAt, &job.UpdatedAt, &job.LastEventAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan render job: %w", err)
+	}
+
+	job.WatermarkID = uint32(watermarkID)
+	return &job, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+)
+
+// GetReportPrivacy returns an organization's report privacy threshold, or nil if it has none
+func (db *DB) GetReportPrivacy(orgID string) (*reporting.Privacy, error) {
+	var privacy reporting.Privacy
+	err := db.QueryRow(`
+		SELECT min_audience, mode FROM report_privacy_settings WHERE org_id = $1
+	`, orgID).Scan(&privacy.MinAudience, &privacy.Mode)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report privacy settings: %w", err)
+	}
+	return &privacy, nil
+}
+
+// SetReportPrivacy stores an organization's report privacy threshold
+func (db *DB) SetReportPrivacy(orgID string, privacy reporting.Privacy, updatedBy string) error {
+	_, err := db.Exec(`
+		INSERT INTO report_privacy_settings (org_id, min_audience, mode, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET min_audience = EXCLUDED.min_audience, mode = EXCLUDED.mode,
+			updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`, orgID, privacy.MinAudience, privacy.Mode, updatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to set report privacy settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteReportPrivacy returns an organization to the default threshold
+func (db *DB) DeleteReportPrivacy(orgID string) error {
+	if _, err := db.Exec(`DELETE FROM report_privacy_settings WHERE org_id = $1`, orgID); err != nil {
+		return fmt.Errorf("failed to delete report privacy settings: %w", err)
+	}
+	return nil
+}
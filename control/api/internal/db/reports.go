@@ -0,0 +1,299 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/lib/pq"
+)
+
+// reportDimensions maps report group-by names onto exposure_events columns
+var reportDimensions = map[string]string{
+	"":             "''",
+	"device_type":  "COALESCE(device_type, '')",
+	"country_code": "COALESCE(country_code, '')",
+	"viewer_id":    "viewer_id",
+}
+
+// reportScope is the WHERE clause shared by report queries: a single booking
+// or every booking in a campaign, within a time range and the requester's
+// tenant scope ($5 and $6, bound by reportArgs)
+const reportScope = `
+	($1 = '' OR booking_id = $1)
+	AND ($2 = '' OR booking_id IN (SELECT booking_id FROM placement_bookings WHERE campaign_id = $2))
+	AND event_timestamp >= $3 AND event_timestamp < $4
+	AND ($5 OR COALESCE(org_id, '') = '' OR org_id = ANY($6))
+`
+
+// reportArgs binds the placeholders of reportScope
+func reportArgs(q reporting.Query) []interface{} {
+	return []interface{}{q.BookingID, q.CampaignID, q.From, q.To, q.Tenant.All, pq.Array(q.Tenant.Orgs)}
+}
+
+// reportDimension returns the SQL expression grouped on for q.GroupBy.
+// Label dimensions read the key from placeholder $10.
+func reportDimension(q reporting.Query) (string, error) {
+	if _, ok := q.LabelKey(); ok {
+		return `COALESCE((
+			SELECT label_value FROM resource_labels rl
+			WHERE rl.resource_type = 'booking' AND rl.resource_id = exposure_events.booking_id AND rl.label_key = $10
+		), '')`, nil
+	}
+
+	dimension, ok := reportDimensions[q.GroupBy]
+	if !ok {
+		return "", fmt.Errorf("unsupported report dimension: %s", q.GroupBy)
+	}
+	return dimension, nil
+}
+
+// EstimateExposureRows asks the Postgres planner how many exposure rows a report would scan
+func (db *DB) EstimateExposureRows(q reporting.Query) (int64, error) {
+	query := `
+		EXPLAIN (FORMAT JSON)
+		SELECT 1 FROM exposure_events
+		WHERE ` + reportScope
+
+	var planJSON []byte
+	if err := db.QueryRow(query, reportArgs(q)...).Scan(&planJSON); err != nil {
+		return 0, fmt.Errorf("failed to explain report query: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(planJSON, &plans); err != nil || len(plans) == 0 {
+		return 0, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+
+	return int64(plans[0].Plan.PlanRows), nil
+}
+
+// GetExposureReport aggregates exposure events into time buckets and an
+// optional dimension, leaving out invalid and duplicate events
+func (db *DB) GetExposureReport(q reporting.Query) ([]map[string]interface{}, error) {
+	dimension, err := reportDimension(q)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			date_trunc($7, event_timestamp) AS bucket,
+			%s AS dimension,
+			COUNT(*) AS impressions,
+			COUNT(DISTINCT viewer_id) AS unique_viewers,
+			COALESCE(SUM(exposure_duration), 0) AS total_exposure_time,
+			COALESCE(AVG(attention_score), 0) AS average_attention_score
+		FROM exposure_events
+		WHERE %s
+			AND %s
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+		LIMIT NULLIF($8, 0) OFFSET $9
+	`, dimension, reportScope, countedEvent)
+
+	args := append(reportArgs(q), q.Granularity, q.Limit, q.Offset)
+	if labelKey, ok := q.LabelKey(); ok {
+		args = append(args, labelKey)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exposure report: %w", err)
+	}
+	defer rows.Close()
+
+	report := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var bucket time.Time
+		var dimensionValue string
+		var impressions, uniqueViewers int64
+		var totalExposure, avgAttention float64
+
+		if err := rows.Scan(&bucket, &dimensionValue, &impressions, &uniqueViewers, &totalExposure, &avgAttention); err != nil {
+			return nil, fmt.Errorf("failed to scan report row: %w", err)
+		}
+
+		row := map[string]interface{}{
+			"bucket":                  bucket.UTC().Format(time.RFC3339),
+			"impressions":             impressions,
+			"unique_viewers":          uniqueViewers,
+			"total_exposure_time":     totalExposure,
+			"average_attention_score": avgAttention,
+		}
+		if q.GroupBy == "viewer_id" {
+			if dimensionValue, err = db.fields.Open(ViewerIDColumn, dimensionValue); err != nil {
+				return nil, fmt.Errorf("failed to decrypt viewer ID: %w", err)
+			}
+		}
+		if q.GroupBy != "" {
+			row[q.GroupBy] = dimensionValue
+		}
+		report = append(report, row)
+	}
+
+	return report, rows.Err()
+}
+
+// CreateReportJob stores a pending async report job, sealing its webhook
+// secret, and returns its ID
+func (db *DB) CreateReportJob(job *reporting.Job) (string, error) {
+	jobID := fmt.Sprintf("report_%s_%d", job.Query.Scope(), time.Now().UnixNano())
+
+	params, err := json.Marshal(job.Query)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report query: %w", err)
+	}
+	secret, err := db.fields.Seal(ReportWebhookSecretColumn, job.WebhookSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt report webhook secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO report_jobs (job_id, status, params, webhook_url, webhook_secret, requested_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`
+
+	if _, err := db.Exec(query, jobID, reporting.JobPending, params, job.WebhookURL, secret, job.RequestedBy); err != nil {
+		return "", fmt.Errorf("failed to create report job: %w", err)
+	}
+
+	return jobID, nil
+}
+
+// GetReportJob retrieves an async report job without its result rows
+func (db *DB) GetReportJob(jobID string) (*reporting.Job, error) {
+	query := `
+		SELECT ` + reportJobColumns + `
+		FROM report_jobs
+		WHERE job_id = $1
+	`
+
+	job, err := db.scanReportJob(db.QueryRow(query, jobID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return job, err
+}
+
+// GetReportJobResult retrieves a page of the result rows of a job completed
+// before results moved to object storage
+func (db *DB) GetReportJobResult(jobID string, limit, offset int) ([]map[string]interface{}, error) {
+	query := `
+		SELECT COALESCE(jsonb_agg(row ORDER BY ordinality), '[]'::jsonb)
+		FROM (
+			SELECT row, ordinality
+			FROM report_jobs, jsonb_array_elements(result) WITH ORDINALITY AS r(row, ordinality)
+			WHERE job_id = $1
+			ORDER BY ordinality
+			LIMIT $2 OFFSET $3
+		) page
+	`
+
+	var resultJSON []byte
+	if err := db.QueryRow(query, jobID, limit, offset).Scan(&resultJSON); err != nil {
+		return nil, fmt.Errorf("failed to query report result: %w", err)
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	if err := json.Unmarshal(resultJSON, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode report result: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ClaimReportJob marks the oldest pending job as running and returns it. A
+// job left running for longer than staleAfter is claimed again, since the
+// worker that claimed it presumably died. SKIP LOCKED lets several gateway
+// instances run workers concurrently.
+func (db *DB) ClaimReportJob(staleAfter time.Duration) (*reporting.Job, error) {
+	query := `
+		UPDATE report_jobs
+		SET status = $1, started_at = CURRENT_TIMESTAMP, attempts = attempts + 1
+		WHERE job_id = (
+			SELECT job_id FROM report_jobs
+			WHERE status = $2
+				OR (status = $1 AND started_at < CURRENT_TIMESTAMP - $3 * INTERVAL '1 second')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + reportJobColumns + `
+	`
+
+	job, err := db.scanReportJob(db.QueryRow(query, reporting.JobRunning, reporting.JobPending, staleAfter.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil // Nothing pending
+	}
+	return job, err
+}
+
+// CompleteReportJob marks a job completed with the storage key of its result rows
+func (db *DB) CompleteReportJob(jobID, resultKey string, rowCount int) error {
+	query := `
+		UPDATE report_jobs
+		SET status = $2, result_key = $3, row_count = $4, completed_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1
+	`
+
+	if _, err := db.Exec(query, jobID, reporting.JobCompleted, resultKey, rowCount); err != nil {
+		return fmt.Errorf("failed to complete report job: %w", err)
+	}
+	return nil
+}
+
+// FailReportJob records a job failure
+func (db *DB) FailReportJob(jobID string, message string) error {
+	query := `
+		UPDATE report_jobs
+		SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1
+	`
+
+	if _, err := db.Exec(query, jobID, reporting.JobFailed, message); err != nil {
+		return fmt.Errorf("failed to mark report job failed: %w", err)
+	}
+	return nil
+}
+
+// reportJobColumns are the report_jobs columns read by scanReportJob
+const reportJobColumns = `job_id, status, params, webhook_url, webhook_secret, attempts, requested_by, row_count, error, created_at, completed_at, result_key`
+
+// scanReportJob scans a report_jobs row into a Job, opening its webhook secret
+func (db *DB) scanReportJob(row *sql.Row) (*reporting.Job, error) {
+	var job reporting.Job
+	var params []byte
+	var webhookURL, webhookSecret, requestedBy, errMsg, resultKey sql.NullString
+	var completedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.Status, &params, &webhookURL, &webhookSecret, &job.Attempts, &requestedBy, &job.RowCount, &errMsg, &job.CreatedAt, &completedAt, &resultKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan report job: %w", err)
+	}
+
+	if err := json.Unmarshal(params, &job.Query); err != nil {
+		return nil, fmt.Errorf("failed to decode report query: %w", err)
+	}
+	job.WebhookURL = webhookURL.String
+	if job.WebhookSecret, err = db.fields.Open(ReportWebhookSecretColumn, webhookSecret.String); err != nil {
+		return nil, fmt.Errorf("failed to decrypt report webhook secret of %s: %w", job.ID, err)
+	}
+	job.RequestedBy = requestedBy.String
+	job.Error = errMsg.String
+	job.ResultKey = resultKey.String
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+
+	return &job, nil
+}
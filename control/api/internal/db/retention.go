@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/optimizer"
+)
+
+// GetTitleRetention returns a title's retention curve, or nil if it has none
+func (db *DB) GetTitleRetention(titleID string) (*optimizer.Curve, error) {
+	curve := optimizer.Curve{TitleID: titleID}
+	var points []byte
+	var periodStart, periodEnd sql.NullTime
+	var updatedBy sql.NullString
+	err := db.QueryRow(`
+		SELECT starts, points, period_start, period_end, updated_by, updated_at
+		FROM title_retention
+		WHERE title_id::text = $1
+	`, titleID).Scan(&curve.Starts, &points, &periodStart, &periodEnd, &updatedBy, &curve.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query title retention: %w", err)
+	}
+	if err := json.Unmarshal(points, &curve.Points); err != nil {
+		return nil, fmt.Errorf("failed to decode title retention: %w", err)
+	}
+	if periodStart.Valid {
+		curve.PeriodStart = &periodStart.Time
+	}
+	if periodEnd.Valid {
+		curve.PeriodEnd = &periodEnd.Time
+	}
+	curve.UpdatedBy = updatedBy.String
+	return &curve, nil
+}
+
+// SetTitleRetention creates or replaces a title's retention curve
+func (db *DB) SetTitleRetention(curve *optimizer.Curve) error {
+	points, err := json.Marshal(curve.Points)
+	if err != nil {
+		return fmt.Errorf("failed to encode title retention: %w", err)
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO title_retention (title_id, starts, points, period_start, period_end, updated_by, updated_at)
+		SELECT id, $2, $3, $4, $5, NULLIF($6, ''), $7 FROM titles WHERE id::text = $1
+		ON CONFLICT (title_id) DO UPDATE SET
+			starts = EXCLUDED.starts,
+			points = EXCLUDED.points,
+			period_start = EXCLUDED.period_start,
+			period_end = EXCLUDED.period_end,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, curve.TitleID, curve.Starts, points, curve.PeriodStart, curve.PeriodEnd, curve.UpdatedBy, curve.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save title retention: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save title retention: %w", err)
+	}
+	if affected == 0 {
+		return ErrTitleNotFound
+	}
+	return nil
+}
+
+// ListPlanCandidates lists the surfaces of a title that may be booked now:
+// those listed among opportunities and without a live booking
+func (db *DB) ListPlanCandidates(titleID string) ([]optimizer.Candidate, error) {
+	rows, err := db.Query(`
+		SELECT surface_id, COALESCE(surface_type, ''), placement_type, start_time, end_time, COALESCE(prs_score, 0)
+		FROM surfaces
+		WHERE title_id::text = $1
+			AND `+holdbackClause+`
+			AND `+mergedClause+`
+			AND NOT `+db.staleClause("surfaces")+`
+			AND NOT EXISTS (
+				SELECT 1 FROM placement_bookings pb
+				WHERE pb.surface_id = surfaces.surface_id AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+			)
+		ORDER BY start_time, surface_id
+	`, titleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query plan candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]optimizer.Candidate, 0)
+	for rows.Next() {
+		var c optimizer.Candidate
+		if err := rows.Scan(&c.SurfaceID, &c.SurfaceType, &c.PlacementType, &c.StartTime, &c.EndTime, &c.PRSScore); err != nil {
+			return nil, fmt.Errorf("failed to scan plan candidate: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/rollup"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// exposureRollup names the rollups' row in exposure_rollup_state
+const exposureRollup = "exposures"
+
+// rollupTables maps granularities onto their rollup table and bucket width
+var rollupTables = map[string]struct{ table, interval string }{
+	rollup.GranularityHour: {"exposure_rollups_hourly", "1 hour"},
+	rollup.GranularityDay:  {"exposure_rollups_daily", "1 day"},
+}
+
+// RollUpExposures recomputes the hour and day buckets of the events received
+// since the last run, up to through. Runs are serialized by locking the
+// rollup state, so gateways may all run the worker.
+func (db *DB) RollUpExposures(through time.Time) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin rollup transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO exposure_rollup_state (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, exposureRollup); err != nil {
+		return 0, fmt.Errorf("failed to create rollup state: %w", err)
+	}
+	var since time.Time
+	if err := tx.QueryRow(`SELECT rolled_up_through FROM exposure_rollup_state WHERE name = $1 FOR UPDATE`, exposureRollup).Scan(&since); err != nil {
+		return 0, fmt.Errorf("failed to lock rollup state: %w", err)
+	}
+	if !through.After(since) {
+		return 0, nil
+	}
+
+	// Hours touched by events received in the run; events stored before
+	// receipt times were recorded count by their event time
+	if _, err := tx.Exec(`CREATE TEMP TABLE rollup_touched (booking_id VARCHAR(100), bucket TIMESTAMP) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("failed to create rollup work table: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO rollup_touched
+		SELECT DISTINCT booking_id, date_trunc('hour', event_timestamp)
+		FROM exposure_events
+		WHERE (received_at > $1 AND received_at <= $2)
+			OR (received_at IS NULL AND event_timestamp > $1 AND event_timestamp <= $2)
+	`, since, through); err != nil {
+		return 0, fmt.Errorf("failed to find touched rollup buckets: %w", err)
+	}
+
+	rewritten := 0
+	for _, granularity := range []string{rollup.GranularityHour, rollup.GranularityDay} {
+		rt := rollupTables[granularity]
+		touched := fmt.Sprintf(`(SELECT DISTINCT booking_id, date_trunc('%s', bucket) AS bucket FROM rollup_touched) t`, granularity)
+
+		if _, err := tx.Exec(fmt.Sprintf(`
+			DELETE FROM %s r USING %s
+			WHERE r.booking_id = t.booking_id AND r.bucket = t.bucket
+		`, rt.table, touched)); err != nil {
+			return 0, fmt.Errorf("failed to clear %s rollups: %w", granularity, err)
+		}
+
+		result, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO %s (
+				booking_id, bucket, org_id, impressions, unique_viewers, exposure_time,
+				prs_sum, prs_count, attention_sum, attention_count,
+				coverage_sum, coverage_count, listen_through_sum, listen_through_count, updated_at
+			)
+			SELECT
+				t.booking_id, t.bucket, MAX(exposure_events.org_id), COUNT(*), COUNT(DISTINCT viewer_id),
+				SUM(exposure_duration),
+				COALESCE(SUM(instantaneous_prs), 0), COUNT(instantaneous_prs),
+				COALESCE(SUM(attention_score), 0), COUNT(attention_score),
+				COALESCE(SUM(screen_coverage_percentage), 0), COUNT(screen_coverage_percentage),
+				COALESCE(SUM(listen_through), 0), COUNT(listen_through),
+				NOW()
+			FROM exposure_events
+			JOIN %s
+				ON t.booking_id = exposure_events.booking_id
+				AND exposure_events.event_timestamp >= t.bucket
+				AND exposure_events.event_timestamp < t.bucket + interval '%s'
+			WHERE %s
+			GROUP BY t.booking_id, t.bucket
+		`, rt.table, touched, rt.interval, countedEvent))
+		if err != nil {
+			return 0, fmt.Errorf("failed to roll up %s buckets: %w", granularity, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s rollups: %w", granularity, err)
+		}
+		rewritten += int(n)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE exposure_rollup_state SET rolled_up_through = $2, updated_at = NOW() WHERE name = $1
+	`, exposureRollup, through); err != nil {
+		return 0, fmt.Errorf("failed to save rollup state: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit rollup: %w", err)
+	}
+	return rewritten, nil
+}
+
+// GetBookingSeries reads a booking's hour or day buckets with events from
+// its rollups within scope, along with when the rollups were last brought up
+// to date
+func (db *DB) GetBookingSeries(scope tenant.Scope, bookingID, granularity string, from, to time.Time) ([]models.MetricsBucket, *time.Time, error) {
+	rt, ok := rollupTables[granularity]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported rollup granularity %q", granularity)
+	}
+
+	where := query.New()
+	where.Equal("booking_id", bookingID)
+	where.AtLeast("bucket", from)
+	where.Where("bucket < %s", to)
+	whereTenant(where, "org_id", scope)
+	if err := where.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT
+			bucket, impressions, unique_viewers, exposure_time,
+			exposure_time / NULLIF(impressions, 0),
+			COALESCE(prs_sum / NULLIF(prs_count, 0), 0),
+			COALESCE(attention_sum / NULLIF(attention_count, 0), 0),
+			COALESCE(coverage_sum / NULLIF(coverage_count, 0), 0),
+			listen_through_sum / NULLIF(listen_through_count, 0)
+		FROM %s
+		WHERE %s
+		ORDER BY bucket
+	`, rt.table, where.Clause()), where.Args()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query %s rollups: %w", granularity, err)
+	}
+	defer rows.Close()
+
+	buckets := make([]models.MetricsBucket, 0)
+	for rows.Next() {
+		var b models.MetricsBucket
+		var averageExposure sql.NullFloat64
+		if err := rows.Scan(&b.Bucket, &b.TotalImpressions, &b.UniqueViewers, &b.TotalExposureTime, &averageExposure,
+			&b.AveragePRSScore, &b.AverageAttentionScore, &b.AverageScreenCoverage, &b.AverageListenThrough); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan %s rollup: %w", granularity, err)
+		}
+		b.AverageExposureTime = averageExposure.Float64
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var through sql.NullTime
+	err = db.QueryRow(`SELECT rolled_up_through FROM exposure_rollup_state WHERE name = $1`, exposureRollup).Scan(&through)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+	return buckets, nullTime(through), nil
+}
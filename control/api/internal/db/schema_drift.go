@@ -0,0 +1,35 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ListColumnTypes returns the data types of the columns of tables in the
+// current schema, by table and column name. Tables that do not exist are
+// left out.
+func (db *DB) ListColumnTypes(tables []string) (map[string]map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT table_name, column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ANY($1)
+	`, pq.Array(tables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column types: %w", err)
+	}
+	defer rows.Close()
+
+	types := make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan column type: %w", err)
+		}
+		if types[table] == nil {
+			types[table] = make(map[string]string)
+		}
+		types[table][column] = dataType
+	}
+	return types, rows.Err()
+}
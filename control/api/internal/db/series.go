@@ -0,0 +1,324 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/series"
+	"github.com/lib/pq"
+)
+
+// SetSeries creates a series or renames it
+func (db *DB) SetSeries(s *series.Series) error {
+	err := db.QueryRow(`
+		INSERT INTO series (series_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (series_id) DO UPDATE SET name = EXCLUDED.name
+		RETURNING created_at
+	`, s.SeriesID, s.Name).Scan(&s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save series: %w", err)
+	}
+	return nil
+}
+
+// GetSeries returns a series with its episodes in order, or nil if it does not exist
+func (db *DB) GetSeries(seriesID string) (*series.Series, error) {
+	s := series.Series{SeriesID: seriesID}
+	err := db.QueryRow(`SELECT name, created_at FROM series WHERE series_id = $1`, seriesID).Scan(&s.Name, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT e.title_id::text, e.season_number, e.episode_number, t.title
+		FROM title_episodes e
+		JOIN titles t ON t.id = e.title_id
+		WHERE e.series_id = $1
+		ORDER BY e.season_number, e.episode_number
+	`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episodes: %w", err)
+	}
+	defer rows.Close()
+
+	s.Episodes = make([]series.Episode, 0)
+	for rows.Next() {
+		e := series.Episode{SeriesID: seriesID}
+		if err := rows.Scan(&e.TitleID, &e.SeasonNumber, &e.EpisodeNumber, &e.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		s.Episodes = append(s.Episodes, e)
+	}
+	return &s, rows.Err()
+}
+
+// SetEpisode places a title in a series, moving it if it was in another
+func (db *DB) SetEpisode(e *series.Episode) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM series WHERE series_id = $1)`, e.SeriesID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to query series: %w", err)
+	}
+	if !exists {
+		return series.ErrSeriesNotFound
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO title_episodes (title_id, series_id, season_number, episode_number)
+		SELECT id, $2, $3, $4 FROM titles WHERE id::text = $1
+		ON CONFLICT (title_id) DO UPDATE SET
+			series_id = EXCLUDED.series_id,
+			season_number = EXCLUDED.season_number,
+			episode_number = EXCLUDED.episode_number
+	`, e.TitleID, e.SeriesID, e.SeasonNumber, e.EpisodeNumber)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: season %d episode %d", series.ErrEpisodeTaken, e.SeasonNumber, e.EpisodeNumber)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save episode: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save episode: %w", err)
+	}
+	if affected == 0 {
+		return ErrTitleNotFound
+	}
+	return nil
+}
+
+// CreateSeriesBooking books every live, unmerged surface of the booking's
+// surface type and minimum PRS score in the series' episodes, or in one
+// season, in a single transaction. Surfaces that are held back, or that the
+// campaign already has a live booking on, are skipped and reported. Stale
+// surfaces are not matched at all.
+func (db *DB) CreateSeriesBooking(b *series.Booking) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the series so concurrent fan-outs see each other's bookings
+	err = tx.QueryRow(`SELECT series_id FROM series WHERE series_id = $1 FOR UPDATE`, b.SeriesID).Scan(&b.SeriesID)
+	if err == sql.ErrNoRows {
+		return series.ErrSeriesNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock series: %w", err)
+	}
+
+	var season sql.NullInt64
+	if b.SeasonNumber != nil {
+		season = sql.NullInt64{Int64: int64(*b.SeasonNumber), Valid: true}
+	}
+	rows, err := tx.Query(`
+		SELECT surfaces.surface_id, e.title_id::text, e.season_number, e.episode_number,
+			EXISTS (
+				SELECT 1 FROM placement_bookings pb
+				WHERE pb.surface_id = surfaces.surface_id AND pb.campaign_id = $5
+					AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+			)
+		FROM surfaces
+		JOIN title_episodes e ON e.title_id = surfaces.title_id
+		WHERE e.series_id = $1
+			AND ($2::int IS NULL OR e.season_number = $2)
+			AND surfaces.surface_type = $3
+			AND surfaces.prs_score > 0
+			AND surfaces.prs_score >= $4
+			AND `+mergedClause+`
+			AND NOT `+db.staleClause("surfaces")+`
+		ORDER BY e.season_number, e.episode_number, surfaces.surface_id
+		LIMIT $6
+	`, b.SeriesID, season, b.SurfaceType, b.MinPRSScore, b.CampaignID, series.MaxFanOut+1)
+	if err != nil {
+		return fmt.Errorf("failed to query series surfaces: %w", err)
+	}
+	type match struct {
+		item   series.Item
+		booked bool
+	}
+	var matches []match
+	for rows.Next() {
+		var m match
+		if err := rows.Scan(&m.item.SurfaceID, &m.item.TitleID, &m.item.SeasonNumber, &m.item.EpisodeNumber, &m.booked); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan series surface: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query series surfaces: %w", err)
+	}
+	if len(matches) > series.MaxFanOut {
+		return fmt.Errorf("series booking matches more than %d surfaces; narrow it by season or min_prs_score", series.MaxFanOut)
+	}
+
+	b.CreatedAt = time.Now().UTC()
+	b.SeriesBookingID = fmt.Sprintf("series_booking_%s_%d", b.SeriesID, b.CreatedAt.UnixNano())
+	b.Bookings = make([]series.Item, 0, len(matches))
+	b.Skipped = make([]series.Skip, 0)
+	for _, m := range matches {
+		if m.booked {
+			b.Skipped = append(b.Skipped, series.Skip{SurfaceID: m.item.SurfaceID, TitleID: m.item.TitleID, Reason: series.SkipBooked})
+			continue
+		}
+		bookingID, err := createPlacementBooking(tx, &models.NewBooking{
+			SurfaceID:      m.item.SurfaceID,
+			AdvertiserID:   b.AdvertiserID,
+			CampaignID:     b.CampaignID,
+			BidAmountCPM:   b.BidAmountCPM,
+			MaxImpressions: b.MaxImpressions,
+			MinPRSScore:    b.MinPRSScore,
+			OrgID:          b.OrgID,
+			UserID:         b.CreatedBy,
+			BundleID:       b.SeriesBookingID, // A series booking is sold as one bundle deal
+		}, b.CreatedAt)
+		if errors.Is(err, holdback.ErrHeldBack) {
+			b.Skipped = append(b.Skipped, series.Skip{SurfaceID: m.item.SurfaceID, TitleID: m.item.TitleID, Reason: series.SkipHeldBack})
+			continue
+		}
+		if errors.Is(err, collision.ErrCollision) {
+			b.Skipped = append(b.Skipped, series.Skip{SurfaceID: m.item.SurfaceID, TitleID: m.item.TitleID, Reason: series.SkipCollides})
+			continue
+		}
+		if errors.Is(err, booking.ErrConflict) {
+			b.Skipped = append(b.Skipped, series.Skip{SurfaceID: m.item.SurfaceID, TitleID: m.item.TitleID, Reason: series.SkipBooked})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.item.BookingID = bookingID
+		b.Bookings = append(b.Bookings, m.item)
+	}
+	if len(b.Bookings) == 0 {
+		return series.ErrNoSurfaces
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO series_bookings (
+			series_booking_id, series_id, season_number, surface_type, min_prs_score,
+			advertiser_id, campaign_id, bid_amount_cpm, max_impressions, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11)
+	`, b.SeriesBookingID, b.SeriesID, season, b.SurfaceType, b.MinPRSScore,
+		b.AdvertiserID, b.CampaignID, b.BidAmountCPM, b.MaxImpressions, b.CreatedBy, b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create series booking: %w", err)
+	}
+	for _, item := range b.Bookings {
+		_, err := tx.Exec(`
+			INSERT INTO series_booking_items (series_booking_id, booking_id, title_id, season_number, episode_number)
+			VALUES ($1, $2, $3::int, $4, $5)
+		`, b.SeriesBookingID, item.BookingID, item.TitleID, item.SeasonNumber, item.EpisodeNumber)
+		if err != nil {
+			return fmt.Errorf("failed to record series booking item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit series booking: %w", err)
+	}
+
+	bookingIDs := make([]string, 0, len(b.Bookings))
+	for _, item := range b.Bookings {
+		bookingIDs = append(bookingIDs, item.BookingID)
+	}
+	db.publish(bookingsCreated(bookingIDs, b.CampaignID, b.CreatedBy, b.OrgID, b.CreatedAt)...)
+	return nil
+}
+
+// GetSeriesBooking returns a series booking with the surface bookings it
+// made, or nil if it does not exist
+func (db *DB) GetSeriesBooking(seriesBookingID string) (*series.Booking, error) {
+	b := series.Booking{SeriesBookingID: seriesBookingID}
+	var season sql.NullInt64
+	var createdBy sql.NullString
+	err := db.QueryRow(`
+		SELECT series_id, season_number, surface_type, min_prs_score, advertiser_id,
+			campaign_id, bid_amount_cpm, max_impressions, created_by, created_at
+		FROM series_bookings
+		WHERE series_booking_id = $1
+	`, seriesBookingID).Scan(&b.SeriesID, &season, &b.SurfaceType, &b.MinPRSScore, &b.AdvertiserID,
+		&b.CampaignID, &b.BidAmountCPM, &b.MaxImpressions, &createdBy, &b.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series booking: %w", err)
+	}
+	if season.Valid {
+		n := int(season.Int64)
+		b.SeasonNumber = &n
+	}
+	b.CreatedBy = createdBy.String
+
+	rows, err := db.Query(`
+		SELECT i.booking_id, pb.surface_id, i.title_id::text, i.season_number, i.episode_number
+		FROM series_booking_items i
+		JOIN placement_bookings pb ON pb.booking_id = i.booking_id
+		WHERE i.series_booking_id = $1
+		ORDER BY i.season_number, i.episode_number, pb.surface_id
+	`, seriesBookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series booking items: %w", err)
+	}
+	defer rows.Close()
+
+	b.Bookings = make([]series.Item, 0)
+	for rows.Next() {
+		var item series.Item
+		if err := rows.Scan(&item.BookingID, &item.SurfaceID, &item.TitleID, &item.SeasonNumber, &item.EpisodeNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan series booking item: %w", err)
+		}
+		b.Bookings = append(b.Bookings, item)
+	}
+	return &b, rows.Err()
+}
+
+// GetSeriesBookingDelivery breaks a series booking's exposures down by the
+// episodes its surfaces were in when it was made
+func (db *DB) GetSeriesBookingDelivery(seriesBookingID string) (*series.Delivery, error) {
+	rows, err := db.Query(`
+		SELECT i.title_id::text, i.season_number, i.episode_number,
+			COUNT(DISTINCT i.booking_id),
+			COUNT(x.id),
+			COUNT(DISTINCT x.viewer_id),
+			COALESCE(SUM(x.exposure_duration), 0)
+		FROM series_booking_items i
+		LEFT JOIN exposure_events x ON x.booking_id = i.booking_id
+		WHERE i.series_booking_id = $1
+		GROUP BY i.title_id, i.season_number, i.episode_number
+		ORDER BY i.season_number, i.episode_number
+	`, seriesBookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series booking delivery: %w", err)
+	}
+	defer rows.Close()
+
+	var episodes []series.EpisodeDelivery
+	for rows.Next() {
+		var e series.EpisodeDelivery
+		if err := rows.Scan(&e.TitleID, &e.SeasonNumber, &e.EpisodeNumber, &e.Bookings,
+			&e.Impressions, &e.UniqueViewers, &e.ExposureTime); err != nil {
+			return nil, fmt.Errorf("failed to scan series booking delivery: %w", err)
+		}
+		episodes = append(episodes, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query series booking delivery: %w", err)
+	}
+	return series.Summarize(seriesBookingID, episodes), nil
+}
@@ -0,0 +1,349 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/serviceaccount"
+	"github.com/lib/pq"
+)
+
+// CreateServiceAccount stores a new service account with its first key and returns the account ID
+func (db *DB) CreateServiceAccount(account *serviceaccount.Account, key *serviceaccount.Key) (string, error) {
+	accountID := fmt.Sprintf("sa_%d", time.Now().UnixNano())
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO service_accounts (account_id, name, org_id, scopes, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+	`, accountID, account.Name, account.OrgID, pq.Array(account.Scopes), account.CreatedBy)
+	if err != nil {
+		return "", fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	if err := db.insertServiceAccountKey(tx, accountID, key); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit service account: %w", err)
+	}
+	return accountID, nil
+}
+
+// GetServiceAccount retrieves a service account by ID
+func (db *DB) GetServiceAccount(accountID string) (*serviceaccount.Account, error) {
+	query := `
+		SELECT account_id, name, org_id, scopes, created_by, created_at, disabled_at
+		FROM service_accounts
+		WHERE account_id = $1
+	`
+
+	account, err := scanServiceAccount(db.QueryRow(query, accountID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return account, err
+}
+
+// ListServiceAccounts lists the service accounts belonging to an organization
+func (db *DB) ListServiceAccounts(orgID string) ([]*serviceaccount.Account, error) {
+	rows, err := db.Query(`
+		SELECT account_id, name, org_id, scopes, created_by, created_at, disabled_at
+		FROM service_accounts
+		WHERE COALESCE(org_id, '') = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]*serviceaccount.Account, 0)
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// DisableServiceAccount disables an account, rejecting all of its keys
+func (db *DB) DisableServiceAccount(accountID string) error {
+	_, err := db.Exec(`
+		UPDATE service_accounts SET disabled_at = CURRENT_TIMESTAMP
+		WHERE account_id = $1 AND disabled_at IS NULL
+	`, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	}
+	return nil
+}
+
+// CreateServiceAccountKey adds a key to an existing account, e.g. when rotating
+func (db *DB) CreateServiceAccountKey(accountID string, key *serviceaccount.Key) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.insertServiceAccountKey(tx, accountID, key); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit service account key: %w", err)
+	}
+	return nil
+}
+
+// insertServiceAccountKey stores a key hash, sealed when encryption is
+// configured, within a transaction, numbering it after the account's other
+// keys. The account row is locked so keys issued at once get distinct versions.
+func (db *DB) insertServiceAccountKey(tx *sql.Tx, accountID string, key *serviceaccount.Key) error {
+	secretHash, err := db.fields.Seal(KeySecretHashColumn, key.SecretHash)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt service account key: %w", err)
+	}
+
+	if _, err := tx.Exec(`SELECT 1 FROM service_accounts WHERE account_id = $1 FOR UPDATE`, accountID); err != nil {
+		return fmt.Errorf("failed to lock service account: %w", err)
+	}
+	err = tx.QueryRow(`
+		INSERT INTO service_account_keys (key_id, account_id, version, secret_hash, json_case)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4
+		FROM service_account_keys
+		WHERE account_id = $2
+		RETURNING version, created_at
+	`, key.ID, accountID, secretHash, key.JSONCase).Scan(&key.Version, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create service account key: %w", err)
+	}
+	key.AccountID = accountID
+	return nil
+}
+
+// ListServiceAccountKeys lists an account's keys, without secrets
+func (db *DB) ListServiceAccountKeys(accountID string) ([]*serviceaccount.Key, error) {
+	rows, err := db.Query(`
+		SELECT key_id, account_id, version, secret_hash, json_case, replaced_by, created_at, last_used_at, expires_at, revoked_at
+		FROM service_account_keys
+		WHERE account_id = $1
+		ORDER BY version DESC
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service account keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*serviceaccount.Key, 0)
+	for rows.Next() {
+		key, err := db.scanServiceAccountKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeServiceAccountKey revokes one of an account's keys and reports whether it was active
+func (db *DB) RevokeServiceAccountKey(accountID, keyID string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE service_account_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE account_id = $1 AND key_id = $2 AND revoked_at IS NULL
+	`, accountID, keyID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke service account key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke service account key: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RotateServiceAccountKey issues key to replace one of an account's active
+// keys, which stays valid until expiresAt. It reports false if the old key
+// is unknown, revoked or expired, and returns serviceaccount.ErrAlreadyRotated
+// if it was rotated before.
+func (db *DB) RotateServiceAccountKey(accountID, keyID string, key *serviceaccount.Key, expiresAt time.Time) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var replacedBy sql.NullString
+	err = tx.QueryRow(`
+		SELECT replaced_by FROM service_account_keys
+		WHERE account_id = $1 AND key_id = $2 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		FOR UPDATE
+	`, accountID, keyID).Scan(&replacedBy)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock service account key: %w", err)
+	}
+	if replacedBy.Valid {
+		return false, serviceaccount.ErrAlreadyRotated
+	}
+
+	if err := db.insertServiceAccountKey(tx, accountID, key); err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		UPDATE service_account_keys SET replaced_by = $2, expires_at = $3
+		WHERE key_id = $1
+	`, keyID, key.ID, expiresAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to expire rotated service account key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit service account key rotation: %w", err)
+	}
+	return true, nil
+}
+
+// WarnExpiringServiceAccountKeys marks the rotated keys of enabled accounts
+// that expire by before and were not announced yet, and publishes a
+// KeyExpiring event for each. Marking and selecting is one statement, so
+// each key is announced by one gateway only.
+func (db *DB) WarnExpiringServiceAccountKeys(before time.Time) (int, error) {
+	rows, err := db.Query(`
+		UPDATE service_account_keys k SET expiry_warned_at = CURRENT_TIMESTAMP
+		FROM service_accounts a
+		WHERE a.account_id = k.account_id AND a.disabled_at IS NULL
+		  AND k.revoked_at IS NULL AND k.expiry_warned_at IS NULL
+		  AND k.expires_at <= $1 AND k.expires_at > CURRENT_TIMESTAMP
+		RETURNING k.account_id, a.name, a.org_id, k.key_id, k.version, k.replaced_by, k.last_used_at, k.expires_at
+	`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark expiring service account keys: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now().UTC()
+	var events []eventbus.Event
+	for rows.Next() {
+		var event eventbus.KeyExpiring
+		var orgID, replacedBy sql.NullString
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&event.AccountID, &event.AccountName, &orgID, &event.KeyID, &event.Version, &replacedBy, &lastUsedAt, &event.ExpiresAt); err != nil {
+			return 0, fmt.Errorf("failed to scan expiring service account key: %w", err)
+		}
+		event.OrgID = orgID.String
+		event.ReplacedBy = replacedBy.String
+		if lastUsedAt.Valid {
+			event.LastUsedAt = &lastUsedAt.Time
+		}
+		event.OccurredAt = now
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	db.publish(events...)
+	return len(events), nil
+}
+
+// GetServiceAccountKey retrieves a key and its account for authentication
+func (db *DB) GetServiceAccountKey(keyID string) (*serviceaccount.Account, *serviceaccount.Key, error) {
+	key, err := db.scanServiceAccountKey(db.QueryRow(`
+		SELECT key_id, account_id, version, secret_hash, json_case, replaced_by, created_at, last_used_at, expires_at, revoked_at
+		FROM service_account_keys
+		WHERE key_id = $1
+	`, keyID))
+	if err == sql.ErrNoRows {
+		return nil, nil, nil // Unknown key
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, err := db.GetServiceAccount(key.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, key, nil
+}
+
+// TouchServiceAccountKey records that a key was just used
+func (db *DB) TouchServiceAccountKey(keyID string) error {
+	_, err := db.Exec(`UPDATE service_account_keys SET last_used_at = CURRENT_TIMESTAMP WHERE key_id = $1`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to update service account key: %w", err)
+	}
+	return nil
+}
+
+// scanServiceAccount scans a service_accounts row into an Account
+func scanServiceAccount(row rowScanner) (*serviceaccount.Account, error) {
+	var account serviceaccount.Account
+	var orgID, createdBy sql.NullString
+	var disabledAt sql.NullTime
+
+	err := row.Scan(&account.ID, &account.Name, &orgID, pq.Array(&account.Scopes), &createdBy, &account.CreatedAt, &disabledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan service account: %w", err)
+	}
+
+	account.OrgID = orgID.String
+	account.CreatedBy = createdBy.String
+	if disabledAt.Valid {
+		account.DisabledAt = &disabledAt.Time
+	}
+	return &account, nil
+}
+
+// scanServiceAccountKey scans a service_account_keys row into a Key,
+// opening its secret hash
+func (db *DB) scanServiceAccountKey(row rowScanner) (*serviceaccount.Key, error) {
+	var key serviceaccount.Key
+	var replacedBy sql.NullString
+	var lastUsedAt, expiresAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.AccountID, &key.Version, &key.SecretHash, &key.JSONCase, &replacedBy,
+		&key.CreatedAt, &lastUsedAt, &expiresAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan service account key: %w", err)
+	}
+	if key.SecretHash, err = db.fields.Open(KeySecretHashColumn, key.SecretHash); err != nil {
+		return nil, fmt.Errorf("failed to decrypt service account key %s: %w", key.ID, err)
+	}
+
+	key.ReplacedBy = replacedBy.String
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/session"
+)
+
+// SaveRefreshToken stores a session under the hash of its refresh token.
+// Expired tokens and revocations are pruned on the way.
+func (db *DB) SaveRefreshToken(ctx context.Context, tokenHash string, s *session.Session) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to prune refresh tokens: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM revoked_sessions WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to prune revoked sessions: %w", err)
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, username, org_id, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	`, tokenHash, s.ID, s.Username, s.OrgID, s.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// TakeRefreshToken deletes a refresh token and returns its session, or nil
+// when the token is unknown, already used or expired
+func (db *DB) TakeRefreshToken(ctx context.Context, tokenHash string) (*session.Session, error) {
+	var s session.Session
+	err := db.QueryRowContext(ctx, `
+		DELETE FROM refresh_tokens WHERE token_hash = $1
+		RETURNING session_id, username, COALESCE(org_id, ''), expires_at
+	`, tokenHash).Scan(&s.ID, &s.Username, &s.OrgID, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take refresh token: %w", err)
+	}
+	if s.Expired(time.Now().UTC()) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// RevokeSession lists a session as revoked until its access tokens expire
+func (db *DB) RevokeSession(ctx context.Context, sessionID string, until time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO revoked_sessions (session_id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at)
+	`, sessionID, until.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// SessionRevoked reports whether a session is on the revocation list
+func (db *DB) SessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	var revoked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM revoked_sessions
+			WHERE session_id = $1 AND expires_at > CURRENT_TIMESTAMP
+		)
+	`, sessionID).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session revocation: %w", err)
+	}
+	return revoked, nil
+}
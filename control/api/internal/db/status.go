@@ -0,0 +1,133 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/status"
+)
+
+// healthRank orders component statuses so concurrent instances recording the
+// same check keep the worst
+const healthRank = `CASE %s WHEN 'operational' THEN 0 WHEN 'degraded' THEN 2 WHEN 'outage' THEN 3 ELSE 1 END`
+
+// CountDecisionOutcomes counts placement decisions since the given time and
+// those that ended in a serve error
+func (db *DB) CountDecisionOutcomes(since time.Time) (int, int, error) {
+	var total, errors int
+	err := db.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE outcome = 'error')
+		FROM decision_events
+		WHERE event_timestamp >= $1
+	`, since).Scan(&total, &errors)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count decision outcomes: %w", err)
+	}
+	return total, errors, nil
+}
+
+// CountReportJobs counts report jobs finished since the given time and those
+// still pending since before overdueBefore
+func (db *DB) CountReportJobs(since, overdueBefore time.Time) (*status.ReportJobCounts, error) {
+	var counts status.ReportJobCounts
+	err := db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE status = $1 AND completed_at >= $4),
+			COUNT(*) FILTER (WHERE status = $2 AND completed_at >= $4),
+			COUNT(*) FILTER (WHERE status = $3 AND created_at < $5)
+		FROM report_jobs
+		WHERE completed_at >= $4 OR status = $3
+	`, reporting.JobCompleted, reporting.JobFailed, reporting.JobPending, since, overdueBefore).Scan(&counts.Completed, &counts.Failed, &counts.Overdue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count report jobs: %w", err)
+	}
+	return &counts, nil
+}
+
+// RecordHealthChecks stores component checks in one transaction. A check
+// already recorded for the same component and time keeps the worse status.
+func (db *DB) RecordHealthChecks(checks []status.Check) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		INSERT INTO component_health_checks (component, checked_at, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (component, checked_at) DO UPDATE SET status = EXCLUDED.status
+		WHERE %s > %s
+	`, fmt.Sprintf(healthRank, "EXCLUDED.status"), fmt.Sprintf(healthRank, "component_health_checks.status"))
+	for _, check := range checks {
+		if _, err := tx.Exec(query, check.Component, check.CheckedAt, check.Status); err != nil {
+			return fmt.Errorf("failed to record health check: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit health checks: %w", err)
+	}
+	return nil
+}
+
+// PruneHealthChecks removes checks made before the given time
+func (db *DB) PruneHealthChecks(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM component_health_checks WHERE checked_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune health checks: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListHealthChecks returns every component's checks since the given time,
+// oldest first
+func (db *DB) ListHealthChecks(since time.Time) ([]status.Check, error) {
+	rows, err := db.Query(`
+		SELECT component, status, checked_at
+		FROM component_health_checks
+		WHERE checked_at >= $1
+		ORDER BY checked_at
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query health checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := make([]status.Check, 0)
+	for rows.Next() {
+		var check status.Check
+		if err := rows.Scan(&check.Component, &check.Status, &check.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan health check: %w", err)
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
+// TallyHealthChecks counts each component's checks since the given time and
+// those in outage
+func (db *DB) TallyHealthChecks(since time.Time) (map[string]status.Tally, error) {
+	rows, err := db.Query(`
+		SELECT component, COUNT(*), COUNT(*) FILTER (WHERE status = $2)
+		FROM component_health_checks
+		WHERE checked_at >= $1
+		GROUP BY component
+	`, since, status.StatusOutage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally health checks: %w", err)
+	}
+	defer rows.Close()
+
+	tallies := make(map[string]status.Tally)
+	for rows.Next() {
+		var component string
+		var tally status.Tally
+		if err := rows.Scan(&component, &tally.Checks, &tally.Outages); err != nil {
+			return nil, fmt.Errorf("failed to scan health check tally: %w", err)
+		}
+		tallies[component] = tally
+	}
+	return tallies, rows.Err()
+}
@@ -0,0 +1,310 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/support"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+)
+
+// supportEventColumns are the exposure event columns support looks at,
+// scanned by scanSupportEvent
+const supportEventColumns = `
+	event_id, booking_id, viewer_id, COALESCE(client_event_id, ''), event_timestamp, device_event_timestamp,
+	COALESCE(clock_skew_ms, 0), exposure_duration, screen_coverage_percentage, listen_through, attention_score,
+	COALESCE(device_type, ''), COALESCE(org_id, ''), counted
+`
+
+// scanSupportEvent scans supportEventColumns, decrypting the viewer ID
+func (db *DB) scanSupportEvent(row rowScanner) (*models.ExposureEvent, error) {
+	var event models.ExposureEvent
+	var deviceTime sql.NullTime
+	var screenCoverage, listenThrough, attentionScore sql.NullFloat64
+
+	err := row.Scan(&event.EventID, &event.BookingID, &event.ViewerID, &event.ClientEventID, &event.Timestamp, &deviceTime,
+		&event.ClockSkewMS, &event.ExposureDuration, &screenCoverage, &listenThrough, &attentionScore,
+		&event.DeviceType, &event.OrgID, &event.Counted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan exposure event: %w", err)
+	}
+	if event.ViewerID, err = db.fields.Open(ViewerIDColumn, event.ViewerID); err != nil {
+		return nil, fmt.Errorf("failed to decrypt viewer ID of %s: %w", event.EventID, err)
+	}
+	event.DeviceTimestamp = nullTime(deviceTime)
+	event.ScreenCoverage = screenCoverage.Float64
+	event.AttentionScore = attentionScore.Float64
+	if listenThrough.Valid {
+		event.ListenThrough = &listenThrough.Float64
+	}
+	return &event, nil
+}
+
+// GetExposureEvent retrieves an exposure event by ID, whatever its
+// organization
+func (db *DB) GetExposureEvent(eventID string) (*models.ExposureEvent, error) {
+	event, err := db.scanSupportEvent(db.QueryRow(`
+		SELECT `+supportEventColumns+`
+		FROM exposure_events
+		WHERE event_id = $1
+	`, eventID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return event, err
+}
+
+// ListViewerExposureEvents lists a viewer's latest exposure events across
+// every booking. Viewer IDs are sealed deterministically, so the sealed
+// hash is looked up directly.
+func (db *DB) ListViewerExposureEvents(viewerID string, limit int) ([]models.ExposureEvent, error) {
+	sealed, err := db.fields.Seal(ViewerIDColumn, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt viewer ID: %w", err)
+	}
+	rows, err := db.Query(`
+		SELECT `+supportEventColumns+`
+		FROM exposure_events
+		WHERE viewer_id = $1
+		ORDER BY event_timestamp DESC, event_id DESC
+		LIMIT $2
+	`, sealed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query viewer exposure events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.ExposureEvent, 0)
+	for rows.Next() {
+		event, err := db.scanSupportEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+// ListSurfaceBookings lists the latest bookings of a surface, whatever
+// their organization
+func (db *DB) ListSurfaceBookings(surfaceID string, limit int) ([]models.Booking, error) {
+	rows, err := db.Query(`
+		SELECT booking_id, COALESCE(advertiser_id, ''), COALESCE(campaign_id, ''), bid_amount_cpm,
+			estimated_impressions, COALESCE(actual_impressions, 0), status, booking_time, COALESCE(org_id, '')
+		FROM placement_bookings
+		WHERE surface_id = $1
+		ORDER BY booking_time DESC, booking_id DESC
+		LIMIT $2
+	`, surfaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query surface bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := make([]models.Booking, 0)
+	for rows.Next() {
+		b := models.Booking{SurfaceID: surfaceID}
+		var bid sql.NullFloat64
+		var estimated, delivered sql.NullInt64
+		var status sql.NullString
+		var bookedAt sql.NullTime
+		if err := rows.Scan(&b.BookingID, &b.AdvertiserID, &b.CampaignID, &bid, &estimated, &delivered, &status, &bookedAt, &b.OrgID); err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		b.BidAmountCPM = bid.Float64
+		b.EstimatedImpressions = estimated.Int64
+		b.ActualImpressions = &delivered.Int64
+		b.Status = status.String
+		b.BookingTime = bookedAt.Time
+		bookings = append(bookings, b)
+	}
+	return bookings, rows.Err()
+}
+
+// ListBookingWebhookDeliveries lists the latest webhook deliveries of
+// events about a booking, to any subscription
+func (db *DB) ListBookingWebhookDeliveries(bookingID string, limit int) ([]webhook.Delivery, error) {
+	rows, err := db.Query(`
+		SELECT delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+			response_status, last_error, created_at, last_attempt_at, delivered_at
+		FROM webhook_deliveries
+		WHERE payload->'data'->>'booking_id' = $1
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT $2
+	`, bookingID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ListOrgErrors lists what went wrong for an organization since a time,
+// newest first: webhook deliveries retrying or given up on, failed event
+// exports, log-level files and renders, and requests rejected for carrying
+// personal data
+func (db *DB) ListOrgErrors(orgID string, since time.Time, limit int) ([]support.Error, error) {
+	rows, err := db.Query(`
+		SELECT source, id, booking_id, message, occurred_at FROM (
+			SELECT 'webhook_delivery' AS source, d.delivery_id AS id,
+				COALESCE(d.payload->'data'->>'booking_id', '') AS booking_id,
+				d.event_type || ' to ' || s.url || ': ' || COALESCE(d.last_error, d.status) AS message,
+				COALESCE(d.last_attempt_at, d.created_at) AS occurred_at
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+			WHERE s.org_id = $1 AND d.status IN ('retrying', 'failed')
+				AND COALESCE(d.last_attempt_at, d.created_at) >= $2
+			UNION ALL
+			SELECT 'event_export', e.export_id, e.booking_id, COALESCE(e.error, 'failed'),
+				COALESCE(e.completed_at, e.started_at, e.created_at)
+			FROM event_exports e
+			JOIN placement_bookings b ON b.booking_id = e.booking_id
+			WHERE b.org_id = $1 AND e.status = 'failed'
+				AND COALESCE(e.completed_at, e.started_at, e.created_at) >= $2
+			UNION ALL
+			SELECT 'log_level_export', l.advertiser_id || '/' || to_char(l.day, 'YYYY-MM-DD'), '', COALESCE(l.error, 'failed'),
+				COALESCE(l.completed_at, l.started_at, l.created_at)
+			FROM log_level_exports l
+			JOIN advertisers a ON a.advertiser_id = l.advertiser_id
+			WHERE a.org_id = $1 AND l.status = 'failed'
+				AND COALESCE(l.completed_at, l.started_at, l.created_at) >= $2
+			UNION ALL
+			SELECT 'render_job', r.job_id, r.booking_id, COALESCE(r.error, 'failed'), r.updated_at
+			FROM render_jobs r
+			JOIN placement_bookings b ON b.booking_id = r.booking_id
+			WHERE b.org_id = $1 AND r.status = 'failed' AND r.updated_at >= $2
+			UNION ALL
+			SELECT 'pii_rejection', p.principal_id, '',
+				p.count || ' request(s) with ' || p.kind || ' in ' || p.field || ' rejected', p.last_seen_at
+			FROM pii_violations p
+			WHERE p.org_id = $1 AND p.action = 'reject' AND p.last_seen_at >= $2
+		) errors
+		ORDER BY occurred_at DESC, source, id
+		LIMIT $3
+	`, orgID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization errors: %w", err)
+	}
+	defer rows.Close()
+
+	errs := make([]support.Error, 0)
+	for rows.Next() {
+		var e support.Error
+		var bookingID sql.NullString
+		if err := rows.Scan(&e.Source, &e.ID, &bookingID, &e.Message, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization error: %w", err)
+		}
+		e.BookingID = bookingID.String
+		errs = append(errs, e)
+	}
+	return errs, rows.Err()
+}
+
+// ListWebhookStatus summarizes the deliveries of each of an organization's
+// webhook subscriptions: how many were created since a time by status, when
+// one was last delivered and the latest one retrying or given up on
+func (db *DB) ListWebhookStatus(orgID string, since time.Time) ([]support.WebhookStatus, error) {
+	subscriptions, err := db.ListWebhookSubscriptions(orgID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]support.WebhookStatus, 0, len(subscriptions))
+	byID := make(map[string]*support.WebhookStatus, len(subscriptions))
+	for _, s := range subscriptions {
+		statuses = append(statuses, support.WebhookStatus{Subscription: s, Counts: map[string]int{}})
+	}
+	for i := range statuses {
+		byID[statuses[i].Subscription.ID] = &statuses[i]
+	}
+	if len(statuses) == 0 {
+		return statuses, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT d.subscription_id, d.status, COUNT(*)
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+		WHERE COALESCE(s.org_id, '') = $1 AND d.created_at >= $2
+		GROUP BY d.subscription_id, d.status
+	`, orgID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subscriptionID, status string
+		var count int
+		if err := rows.Scan(&subscriptionID, &status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery count: %w", err)
+		}
+		if s := byID[subscriptionID]; s != nil {
+			s.Counts[status] = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT d.subscription_id, MAX(d.delivered_at)
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+		WHERE COALESCE(s.org_id, '') = $1 AND d.status = 'delivered'
+		GROUP BY d.subscription_id
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subscriptionID string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&subscriptionID, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan last webhook delivery: %w", err)
+		}
+		if s := byID[subscriptionID]; s != nil {
+			s.LastDeliveredAt = nullTime(deliveredAt)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT DISTINCT ON (d.subscription_id)
+			d.delivery_id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.response_status, d.last_error, d.created_at, d.last_attempt_at, d.delivered_at
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+		WHERE COALESCE(s.org_id, '') = $1 AND d.status IN ('retrying', 'failed')
+		ORDER BY d.subscription_id, COALESCE(d.last_attempt_at, d.created_at) DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		if s := byID[delivery.SubscriptionID]; s != nil {
+			s.LastFailure = delivery
+		}
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,486 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/lib/pq"
+)
+
+// ErrSurfaceNotFound is returned when merging a surface that does not exist
+var ErrSurfaceNotFound = errors.New("surface not found")
+
+// mergedClause excludes surfaces that were merged into another
+const mergedClause = `NOT EXISTS (
+				SELECT 1 FROM surface_merges m WHERE m.surface_id = surfaces.surface_id
+			)`
+
+// duplicatePairsQuery finds pairs of unmerged surfaces of a title whose
+// polygons and screen time overlap past the thresholds. When $2 is set, only
+// pairs involving one of those surfaces are returned.
+const duplicatePairsQuery = `
+	WITH candidates AS (
+		SELECT s.surface_id, s.shot_id, s.start_time, s.end_time, s.geometry,
+			COALESCE(s.prs_score, 0) AS prs_score, COALESCE(s.created_at, CURRENT_TIMESTAMP) AS created_at,
+			(SELECT COUNT(*) FROM placement_bookings pb
+				WHERE pb.surface_id = s.surface_id
+					AND pb.status IN ('pending', 'confirmed', 'active', 'paused')) AS live_bookings
+		FROM surfaces s
+		WHERE s.title_id::text = $1
+			AND s.geometry IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM surface_merges m WHERE m.surface_id = s.surface_id)
+	)
+	SELECT * FROM (
+		SELECT a.surface_id AS a_id, a.prs_score AS a_prs, a.live_bookings AS a_live, a.created_at AS a_created,
+			b.surface_id AS b_id, b.prs_score AS b_prs, b.live_bookings AS b_live, b.created_at AS b_created,
+			COALESCE(ST_Area(ST_Intersection(a.geometry, b.geometry))
+				/ NULLIF(ST_Area(ST_Union(a.geometry, b.geometry)), 0), 0) AS geometry_iou,
+			CASE WHEN a.shot_id = b.shot_id THEN 1 ELSE
+				COALESCE(GREATEST(0, LEAST(a.end_time, b.end_time) - GREATEST(a.start_time, b.start_time))
+					/ NULLIF(LEAST(a.end_time - a.start_time, b.end_time - b.start_time), 0), 0)
+			END AS time_overlap
+		FROM candidates a
+		JOIN candidates b ON a.surface_id < b.surface_id
+			AND (a.shot_id = b.shot_id OR (a.start_time <= b.end_time AND b.start_time <= a.end_time))
+			AND ST_Intersects(a.geometry, b.geometry)
+		WHERE $2::text[] IS NULL OR a.surface_id = ANY($2) OR b.surface_id = ANY($2)
+	) pairs
+	WHERE geometry_iou >= $3 AND time_overlap >= $4
+	ORDER BY a_id, b_id`
+
+// IngestSurfaces upserts a pipeline run's shots and surfaces for a title and
+// warns about ingested surfaces that duplicate another live surface
+func (db *DB) IngestSurfaces(batch *ingest.Batch, thresholds dedupe.Thresholds) (*ingest.Result, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var titleID int
+	err = tx.QueryRow(`SELECT id FROM titles WHERE id::text = $1`, batch.TitleID).Scan(&titleID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTitleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query title: %w", err)
+	}
+
+	shotIDs, err := upsertShots(tx, titleID, batch.Shots)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range batch.Surfaces {
+		s := &batch.Surfaces[i]
+		shotID, ok := shotIDs[s.ShotID]
+		if !ok {
+			err := tx.QueryRow(`SELECT id FROM shots WHERE title_id = $1 AND shot_id = $2`, titleID, s.ShotID).Scan(&shotID)
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("%w: surface %s references unknown shot %s", ingest.ErrInvalidBatch, s.SurfaceID, s.ShotID)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to query shot: %w", err)
+			}
+			shotIDs[s.ShotID] = shotID
+		}
+
+		var restrictions sql.NullString
+		if s.Restrictions != nil {
+			encoded, err := json.Marshal(s.Restrictions)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode restrictions of surface %s: %w", s.SurfaceID, err)
+			}
+			restrictions = sql.NullString{String: string(encoded), Valid: true}
+		}
+
+		placementType, loudnessTarget, loudnessTolerance, maxTruePeak := audioColumns(s)
+
+		// Re-ingesting a surface updates it in place but never moves it to
+		// another title, and counts as validating it again. Audio slots have
+		// no geometry, so they are never flagged as duplicates.
+		result, err := tx.Exec(`
+			INSERT INTO surfaces (
+				surface_id, title_id, shot_id, start_time, end_time, geometry,
+				surface_type, area_pixels, prs_score, visibility_score, stability_score, restrictions,
+				placement_type, loudness_target_lufs, loudness_tolerance_lu, max_true_peak_dbtp
+			) VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_GeomFromText(NULLIF($6, '')), 4326), NULLIF($7, ''), $8, $9, $10, $11,
+				COALESCE($12::jsonb, '[]'), $13, $14, $15, $16)
+			ON CONFLICT (surface_id) DO UPDATE SET
+				shot_id = EXCLUDED.shot_id,
+				start_time = EXCLUDED.start_time,
+				end_time = EXCLUDED.end_time,
+				placement_type = EXCLUDED.placement_type,
+				geometry = EXCLUDED.geometry,
+				loudness_target_lufs = EXCLUDED.loudness_target_lufs,
+				loudness_tolerance_lu = EXCLUDED.loudness_tolerance_lu,
+				max_true_peak_dbtp = EXCLUDED.max_true_peak_dbtp,
+				surface_type = EXCLUDED.surface_type,
+				area_pixels = EXCLUDED.area_pixels,
+				prs_score = EXCLUDED.prs_score,
+				visibility_score = EXCLUDED.visibility_score,
+				stability_score = EXCLUDED.stability_score,
+				restrictions = COALESCE($12::jsonb, surfaces.restrictions),
+				last_validated_at = CURRENT_TIMESTAMP,
+				revalidation_requested_at = NULL,
+				revalidation_reason = NULL
+			WHERE surfaces.title_id = EXCLUDED.title_id
+		`, s.SurfaceID, titleID, shotID, s.StartTime, s.EndTime, s.WKT(), s.SurfaceType,
+			s.AreaPixels, s.PRSScore, s.VisibilityScore, s.StabilityScore, restrictions,
+			placementType, loudnessTarget, loudnessTolerance, maxTruePeak)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save surface %s: %w", s.SurfaceID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to save surface %s: %w", s.SurfaceID, err)
+		}
+		if affected == 0 {
+			return nil, fmt.Errorf("%w: surface %s belongs to another title", ingest.ErrInvalidBatch, s.SurfaceID)
+		}
+	}
+
+	ingested := batch.SurfaceIDs()
+	pairs, err := findDuplicatePairs(tx, batch.TitleID, ingested, thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ingestion: %w", err)
+	}
+
+	inBatch := make(map[string]bool, len(ingested))
+	for _, id := range ingested {
+		inBatch[id] = true
+	}
+	warnings := make([]dedupe.Warning, 0, len(pairs))
+	for _, pair := range pairs {
+		surface, other := pair.A.SurfaceID, pair.B.SurfaceID
+		if !inBatch[surface] {
+			surface, other = other, surface
+		}
+		warnings = append(warnings, dedupe.Warning{
+			SurfaceID:   surface,
+			DuplicateOf: other,
+			GeometryIoU: pair.GeometryIoU,
+			TimeOverlap: pair.TimeOverlap,
+		})
+	}
+
+	db.publish(eventbus.SurfacesIngested{
+		TitleID:    batch.TitleID,
+		Shots:      len(batch.Shots),
+		Surfaces:   len(batch.Surfaces),
+		Duplicates: len(warnings),
+	})
+	return &ingest.Result{
+		TitleID:           batch.TitleID,
+		Shots:             len(batch.Shots),
+		Surfaces:          len(batch.Surfaces),
+		DuplicateWarnings: warnings,
+	}, nil
+}
+
+// upsertShots saves shot boundaries of a title, keyed by shot ID, and
+// returns the row ID of each
+func upsertShots(tx *sql.Tx, titleKey int, shots []ingest.Shot) (map[string]int, error) {
+	shotIDs := make(map[string]int, len(shots))
+	for _, shot := range shots {
+		confidence := shot.Confidence
+		if confidence == 0 {
+			confidence = 1
+		}
+		var id int
+		err := tx.QueryRow(`
+			INSERT INTO shots (title_id, shot_id, start_time, end_time, confidence)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (title_id, shot_id) DO UPDATE SET
+				start_time = EXCLUDED.start_time,
+				end_time = EXCLUDED.end_time,
+				confidence = EXCLUDED.confidence
+			RETURNING id
+		`, titleKey, shot.ShotID, shot.StartTime, shot.EndTime, confidence).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save shot %s: %w", shot.ShotID, err)
+		}
+		shotIDs[shot.ShotID] = id
+	}
+	return shotIDs, nil
+}
+
+// FindDuplicatePairs returns pairs of a title's unmerged surfaces that overlap
+// past the thresholds, limited to pairs involving surfaceIDs when it is set
+func (db *DB) FindDuplicatePairs(titleID string, surfaceIDs []string, thresholds dedupe.Thresholds) ([]dedupe.Pair, error) {
+	return findDuplicatePairs(db, titleID, surfaceIDs, thresholds)
+}
+
+func findDuplicatePairs(q queryer, titleID string, surfaceIDs []string, thresholds dedupe.Thresholds) ([]dedupe.Pair, error) {
+	var filter interface{}
+	if surfaceIDs != nil {
+		filter = pq.Array(surfaceIDs)
+	}
+
+	rows, err := q.Query(duplicatePairsQuery, titleID, filter, thresholds.MinGeometryIoU, thresholds.MinTimeOverlap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate surfaces: %w", err)
+	}
+	defer rows.Close()
+
+	pairs := make([]dedupe.Pair, 0)
+	for rows.Next() {
+		var p dedupe.Pair
+		if err := rows.Scan(&p.A.SurfaceID, &p.A.PRSScore, &p.A.LiveBookings, &p.A.CreatedAt,
+			&p.B.SurfaceID, &p.B.PRSScore, &p.B.LiveBookings, &p.B.CreatedAt,
+			&p.GeometryIoU, &p.TimeOverlap); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate surfaces: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// ReplaceDuplicateClusters replaces a title's recorded duplicate clusters
+func (db *DB) ReplaceDuplicateClusters(titleID string, clusters []dedupe.Cluster) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM surface_duplicates WHERE title_id::text = $1`, titleID); err != nil {
+		return fmt.Errorf("failed to clear duplicate clusters: %w", err)
+	}
+
+	for _, cluster := range clusters {
+		for _, m := range cluster.Members {
+			_, err := tx.Exec(`
+				INSERT INTO surface_duplicates (cluster_id, title_id, surface_id, canonical, geometry_iou, time_overlap, detected_at)
+				SELECT $1, id, $3, $4, $5, $6, $7 FROM titles WHERE id::text = $2
+			`, cluster.ID, titleID, m.SurfaceID, m.Canonical, m.GeometryIoU, m.TimeOverlap, cluster.DetectedAt)
+			if err != nil {
+				return fmt.Errorf("failed to save duplicate cluster: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit duplicate clusters: %w", err)
+	}
+	return nil
+}
+
+// ListDuplicateClusters returns the recorded duplicate clusters of a title,
+// or of every title when titleID is empty
+func (db *DB) ListDuplicateClusters(titleID string) ([]dedupe.Cluster, error) {
+	rows, err := db.Query(`
+		SELECT d.cluster_id, d.title_id::text, d.surface_id, d.canonical, d.geometry_iou, d.time_overlap, d.detected_at,
+			COALESCE(s.prs_score, 0), COALESCE(s.created_at, d.detected_at),
+			(SELECT COUNT(*) FROM placement_bookings pb
+				WHERE pb.surface_id = d.surface_id
+					AND pb.status IN ('pending', 'confirmed', 'active', 'paused'))
+		FROM surface_duplicates d
+		JOIN surfaces s ON s.surface_id = d.surface_id
+		WHERE ($1 = '' OR d.title_id::text = $1)
+		ORDER BY d.cluster_id, d.canonical DESC, d.surface_id
+	`, titleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate clusters: %w", err)
+	}
+	defer rows.Close()
+
+	clusters := make([]dedupe.Cluster, 0)
+	for rows.Next() {
+		var cluster dedupe.Cluster
+		var m dedupe.Member
+		if err := rows.Scan(&cluster.ID, &cluster.TitleID, &m.SurfaceID, &m.Canonical, &m.GeometryIoU, &m.TimeOverlap,
+			&cluster.DetectedAt, &m.PRSScore, &m.CreatedAt, &m.LiveBookings); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate cluster: %w", err)
+		}
+
+		if n := len(clusters); n == 0 || clusters[n-1].ID != cluster.ID {
+			clusters = append(clusters, cluster)
+		}
+		last := &clusters[len(clusters)-1]
+		if m.Canonical {
+			last.CanonicalSurfaceID = m.SurfaceID
+		}
+		last.Members = append(last.Members, m)
+	}
+	return clusters, rows.Err()
+}
+
+// MergeSurfaces folds duplicate surfaces into a canonical surface of the same
+// title. Live bookings on the duplicates move to the canonical surface through
+// an amended event, so their history records the move. Merged surfaces are kept,
+// with their ended bookings and exposure history, but are no longer sold.
+func (db *DB) MergeSurfaces(merge *dedupe.Merge) (*dedupe.MergeResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock every surface involved so concurrent merges cannot chain through them
+	all := append([]string{merge.IntoSurfaceID}, merge.SurfaceIDs...)
+	rows, err := tx.Query(`
+		SELECT s.surface_id, s.title_id, m.merged_into
+		FROM surfaces s
+		LEFT JOIN surface_merges m ON m.surface_id = s.surface_id
+		WHERE s.surface_id = ANY($1)
+		ORDER BY s.surface_id
+		FOR UPDATE OF s
+	`, pq.Array(all))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock surfaces: %w", err)
+	}
+	titles := make(map[string]int, len(all))
+	mergedInto := make(map[string]string)
+	for rows.Next() {
+		var surfaceID string
+		var titleID int
+		var into sql.NullString
+		if err := rows.Scan(&surfaceID, &titleID, &into); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan surface: %w", err)
+		}
+		titles[surfaceID] = titleID
+		if into.Valid {
+			mergedInto[surfaceID] = into.String
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock surfaces: %w", err)
+	}
+
+	for _, id := range all {
+		if _, ok := titles[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSurfaceNotFound, id)
+		}
+		if into, ok := mergedInto[id]; ok {
+			return nil, fmt.Errorf("%w: surface %s was already merged into %s", dedupe.ErrInvalidMerge, id, into)
+		}
+		if titles[id] != titles[merge.IntoSurfaceID] {
+			return nil, fmt.Errorf("%w: surface %s belongs to another title", dedupe.ErrInvalidMerge, id)
+		}
+	}
+
+	var bookingIDs []string
+	moved := make(map[string]int)
+	rows, err = tx.Query(`
+		SELECT booking_id, surface_id FROM placement_bookings
+		WHERE surface_id = ANY($1) AND status IN ('pending', 'confirmed', 'active', 'paused')
+		ORDER BY booking_id
+	`, pq.Array(merge.SurfaceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings to move: %w", err)
+	}
+	for rows.Next() {
+		var id, surfaceID string
+		if err := rows.Scan(&id, &surfaceID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookingIDs = append(bookingIDs, id)
+		moved[surfaceID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query bookings to move: %w", err)
+	}
+
+	mergedAt := time.Now().UTC()
+	for _, bookingID := range bookingIDs {
+		_, err := appendBookingEvent(tx, booking.Event{
+			BookingID:  bookingID,
+			Type:       booking.EventAmended,
+			Actor:      merge.Actor,
+			OrgID:      merge.OrgID,
+			OccurredAt: mergedAt,
+			Data: booking.Change{
+				SurfaceID: merge.IntoSurfaceID,
+				Reason:    "duplicate surface merged into " + merge.IntoSurfaceID,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to move booking %s: %w", bookingID, err)
+		}
+	}
+
+	for _, id := range merge.SurfaceIDs {
+		_, err := tx.Exec(`
+			INSERT INTO surface_merges (surface_id, merged_into, merged_by, bookings_moved, merged_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		`, id, merge.IntoSurfaceID, merge.Actor, moved[id], mergedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record surface merge: %w", err)
+		}
+	}
+
+	// Surfaces merged earlier into one of the duplicates now point at the canonical surface
+	_, err = tx.Exec(`UPDATE surface_merges SET merged_into = $1 WHERE merged_into = ANY($2)`,
+		merge.IntoSurfaceID, pq.Array(merge.SurfaceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to repoint earlier merges: %w", err)
+	}
+
+	// Clusters involving these surfaces are stale until the next dedupe run
+	_, err = tx.Exec(`
+		DELETE FROM surface_duplicates
+		WHERE cluster_id IN (SELECT cluster_id FROM surface_duplicates WHERE surface_id = ANY($1))
+	`, pq.Array(all))
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear duplicate clusters: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit surface merge: %w", err)
+	}
+
+	if bookingIDs == nil {
+		bookingIDs = []string{}
+	}
+	for _, bookingID := range bookingIDs {
+		db.publish(eventbus.BookingChanged{
+			BookingID:  bookingID,
+			Type:       booking.EventAmended,
+			Actor:      merge.Actor,
+			OrgID:      merge.OrgID,
+			OccurredAt: mergedAt,
+		})
+	}
+	db.publish(eventbus.SurfacesMerged{
+		IntoSurfaceID: merge.IntoSurfaceID,
+		Merged:        merge.SurfaceIDs,
+		BookingsMoved: bookingIDs,
+		Actor:         merge.Actor,
+		MergedAt:      mergedAt,
+	})
+	return &dedupe.MergeResult{
+		IntoSurfaceID: merge.IntoSurfaceID,
+		Merged:        merge.SurfaceIDs,
+		BookingsMoved: bookingIDs,
+		MergedAt:      mergedAt,
+	}, nil
+}
+
+// checkMerged rejects booking a surface that was merged into another
+func checkMerged(tx *sql.Tx, surfaceID string) error {
+	var into string
+	err := tx.QueryRow(`SELECT merged_into FROM surface_merges WHERE surface_id = $1`, surfaceID).Scan(&into)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check surface merges: %w", err)
+	}
+	return fmt.Errorf("%w: surface %s was merged into %s", dedupe.ErrSurfaceMerged, surfaceID, into)
+}
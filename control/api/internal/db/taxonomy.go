@@ -0,0 +1,62 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/taxonomy"
+)
+
+// GetTaxonomy returns an organization's aliases of every kind
+func (db *DB) GetTaxonomy(orgID string) (*taxonomy.Taxonomy, error) {
+	rows, err := db.Query(`
+		SELECT kind, alias, canonical FROM taxonomy_aliases
+		WHERE org_id = $1
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query taxonomy aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]taxonomy.Aliases)
+	for rows.Next() {
+		var kind, alias, canonical string
+		if err := rows.Scan(&kind, &alias, &canonical); err != nil {
+			return nil, fmt.Errorf("failed to scan taxonomy alias: %w", err)
+		}
+		if aliases[kind] == nil {
+			aliases[kind] = taxonomy.Aliases{}
+		}
+		aliases[kind][alias] = canonical
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read taxonomy aliases: %w", err)
+	}
+	return taxonomy.New(orgID, aliases), nil
+}
+
+// SetTaxonomyAliases replaces an organization's aliases of one kind
+func (db *DB) SetTaxonomyAliases(orgID, kind string, aliases taxonomy.Aliases, updatedBy string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM taxonomy_aliases WHERE org_id = $1 AND kind = $2`, orgID, kind); err != nil {
+		return fmt.Errorf("failed to clear taxonomy aliases: %w", err)
+	}
+	for alias, canonical := range aliases {
+		_, err := tx.Exec(`
+			INSERT INTO taxonomy_aliases (org_id, kind, alias, canonical, updated_by)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		`, orgID, kind, alias, canonical, updatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to save taxonomy alias %s: %w", alias, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit taxonomy aliases: %w", err)
+	}
+	return nil
+}
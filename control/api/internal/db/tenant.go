@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/lib/pq"
+)
+
+// tenantFilter builds a condition restricting the org_id column orgColumn to
+// a scope. Rows of no organization are in every scope. Placeholders are
+// numbered from firstArg.
+func tenantFilter(orgColumn string, scope tenant.Scope, firstArg int) (string, []interface{}) {
+	b := query.From(firstArg)
+	clause, _ := tenantCondition(b, orgColumn, scope)
+	return clause, b.Args()
+}
+
+// whereTenant restricts the org_id column orgColumn to a scope
+func whereTenant(b *query.Builder, orgColumn string, scope tenant.Scope) {
+	clause, _ := tenantCondition(b, orgColumn, scope)
+	b.Add(clause)
+}
+
+// tenantCondition binds a scope and returns the condition restricting
+// orgColumn to it, along with the placeholder of its organizations
+func tenantCondition(b *query.Builder, orgColumn string, scope tenant.Scope) (string, string) {
+	all, orgs := b.Bind(scope.All), b.Bind(pq.Array(scope.Orgs))
+	column := query.Column(orgColumn)
+	return fmt.Sprintf("(%s OR COALESCE(%s, '') = '' OR %s = ANY(%s))", all, column, column, orgs), orgs
+}
+
+// bookingVisibility builds a condition restricting placement_bookings rows to
+// those within a scope or shared with one of its organizations, directly or
+// through their campaign. Placeholders are numbered from firstArg.
+func bookingVisibility(scope tenant.Scope, firstArg int) (string, []interface{}) {
+	b := query.From(firstArg)
+	return bookingCondition(b, scope), b.Args()
+}
+
+// whereBookingVisible restricts placement_bookings rows to those visible
+// within a scope
+func whereBookingVisible(b *query.Builder, scope tenant.Scope) {
+	b.Add(bookingCondition(b, scope))
+}
+
+// bookingCondition binds a scope and returns the condition of
+// bookingVisibility
+func bookingCondition(b *query.Builder, scope tenant.Scope) string {
+	owned, orgs := tenantCondition(b, "placement_bookings.org_id", scope)
+	return fmt.Sprintf(`(
+				%s
+				OR placement_bookings.booking_id IN (
+					SELECT resource_id FROM resource_grants
+					WHERE resource_type = 'booking' AND grantee_org_id = ANY(%s) AND revoked_at IS NULL
+				)
+				OR placement_bookings.campaign_id IN (
+					SELECT resource_id FROM resource_grants
+					WHERE resource_type = 'campaign' AND grantee_org_id = ANY(%s) AND revoked_at IS NULL
+				)
+			)`, owned, orgs, orgs)
+}
+
+// BackfillTenants copies the owning organization of bookings made before
+// placement_bookings.org_id existed from resource_owners, and that of their
+// exposure events from the bookings. It returns the number of bookings updated.
+func (db *DB) BackfillTenants() (int64, error) {
+	result, err := db.Exec(`
+		UPDATE placement_bookings b SET org_id = o.org_id
+		FROM resource_owners o
+		WHERE o.resource_type = 'booking' AND o.resource_id = b.booking_id AND b.org_id IS NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill booking organizations: %w", err)
+	}
+	backfilled, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill booking organizations: %w", err)
+	}
+
+	_, err = db.Exec(`
+		UPDATE exposure_events e SET org_id = b.org_id
+		FROM placement_bookings b
+		WHERE b.booking_id = e.booking_id AND e.org_id IS NULL AND b.org_id IS NOT NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill exposure event organizations: %w", err)
+	}
+	return backfilled, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/thumbnail"
+)
+
+// thumbnailVersionColumn selects the thumbnail version of each row of surfaces
+const thumbnailVersionColumn = `(SELECT version FROM surface_thumbnails t WHERE t.surface_id = surfaces.surface_id) AS thumbnail_version`
+
+// thumbnailURL links to a surface's thumbnail, or is empty without one
+func thumbnailURL(surfaceID string, version sql.NullString) string {
+	if !version.Valid {
+		return ""
+	}
+	return thumbnail.Path(surfaceID, version.String)
+}
+
+// SaveSurfaceThumbnail records the reference frame stored for a surface and
+// fills in its title and upload time. It returns the version it replaced,
+// or "" if the surface had none.
+func (db *DB) SaveSurfaceThumbnail(src *thumbnail.Source) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT title_id::text FROM surfaces WHERE surface_id = $1 FOR UPDATE`, src.SurfaceID).Scan(&src.TitleID)
+	if err == sql.ErrNoRows {
+		return "", ErrSurfaceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lock surface: %w", err)
+	}
+
+	var previous sql.NullString
+	err = tx.QueryRow(`SELECT version FROM surface_thumbnails WHERE surface_id = $1`, src.SurfaceID).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to query thumbnail: %w", err)
+	}
+
+	src.UploadedAt = time.Now().UTC()
+	_, err = tx.Exec(`
+		INSERT INTO surface_thumbnails (surface_id, version, content_type, width, height, byte_size, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (surface_id) DO UPDATE SET
+			version = EXCLUDED.version,
+			content_type = EXCLUDED.content_type,
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			byte_size = EXCLUDED.byte_size,
+			uploaded_at = EXCLUDED.uploaded_at
+	`, src.SurfaceID, src.Version, src.ContentType, src.Width, src.Height, src.ByteSize, src.UploadedAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit thumbnail: %w", err)
+	}
+	return previous.String, nil
+}
+
+// GetSurfaceThumbnail returns the reference frame stored for a surface, or
+// nil if it has none
+func (db *DB) GetSurfaceThumbnail(surfaceID string) (*thumbnail.Source, error) {
+	src := &thumbnail.Source{SurfaceID: surfaceID}
+	err := db.QueryRow(`
+		SELECT s.title_id::text, t.version, t.content_type, t.width, t.height, t.byte_size, t.uploaded_at
+		FROM surface_thumbnails t
+		JOIN surfaces s ON s.surface_id = t.surface_id
+		WHERE t.surface_id = $1
+	`, surfaceID).Scan(&src.TitleID, &src.Version, &src.ContentType, &src.Width, &src.Height, &src.ByteSize, &src.UploadedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+	return src, nil
+}
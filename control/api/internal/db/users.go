@@ -0,0 +1,191 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/lib/pq"
+)
+
+// ErrUserExists is returned when creating a user whose username is taken
+var ErrUserExists = errors.New("username already in use")
+
+// userColumns are selected by every user query. locked_until is only read
+// while the lock holds, so an expired lock reads as unlocked.
+const userColumns = `
+	username, org_id, role, password_hash, created_by, created_at, updated_at, last_login_at,
+	failed_logins, CASE WHEN locked_until > CURRENT_TIMESTAMP THEN locked_until END, disabled_at
+`
+
+// CreateUser stores a new user
+func (db *DB) CreateUser(u *user.User) error {
+	_, err := db.Exec(`
+		INSERT INTO users (username, org_id, role, password_hash, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''))
+	`, u.Username, u.OrgID, u.Role, u.PasswordHash, u.CreatedBy)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%w: %s", ErrUserExists, u.Username)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUser retrieves a user by username
+func (db *DB) GetUser(username string) (*user.User, error) {
+	u, err := scanUser(db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return u, err
+}
+
+// ListUsers lists users by username, only those of an organization when orgID is set
+func (db *DB) ListUsers(orgID string) ([]*user.User, error) {
+	rows, err := db.Query(`
+		SELECT `+userColumns+`
+		FROM users
+		WHERE $1 = '' OR org_id = $1
+		ORDER BY username
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*user.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateUser saves a user's organization, role, password hash, lock and
+// disabled state, and reports whether the user exists
+func (db *DB) UpdateUser(u *user.User) (bool, error) {
+	var disabledAt, lockedUntil sql.NullTime
+	if u.DisabledAt != nil {
+		disabledAt = sql.NullTime{Time: *u.DisabledAt, Valid: true}
+	}
+	if u.LockedUntil != nil {
+		lockedUntil = sql.NullTime{Time: *u.LockedUntil, Valid: true}
+	}
+
+	result, err := db.Exec(`
+		UPDATE users
+		SET org_id = NULLIF($2, ''), role = $3, password_hash = $4, failed_logins = $5, locked_until = $6,
+		    disabled_at = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE username = $1
+	`, u.Username, u.OrgID, u.Role, u.PasswordHash, u.FailedLogins, lockedUntil, disabledAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update user: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteUser deletes a user and reports whether they existed
+func (db *DB) DeleteUser(username string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM users WHERE username = $1`, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RecordLoginFailure counts a failed login against a user, locking the
+// account once lockout.Threshold failures follow each other. It returns when
+// the lock ends if the account is now locked. Failures before an expired lock
+// are forgotten.
+func (db *DB) RecordLoginFailure(username string, lockout user.Lockout) (*time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := db.QueryRow(`
+		WITH failures AS (
+			SELECT username,
+			       CASE WHEN locked_until <= CURRENT_TIMESTAMP THEN 1 ELSE failed_logins + 1 END AS failed_logins
+			FROM users
+			WHERE username = $1
+			FOR UPDATE
+		)
+		UPDATE users u
+		SET failed_logins = f.failed_logins,
+		    locked_until = CASE
+		        WHEN u.locked_until > CURRENT_TIMESTAMP THEN u.locked_until
+		        WHEN f.failed_logins >= $2 THEN CURRENT_TIMESTAMP + make_interval(secs => $3)
+		    END
+		FROM failures f
+		WHERE u.username = f.username
+		RETURNING u.locked_until
+	`, username, lockout.Threshold, lockout.Duration.Seconds()).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil // Deleted meanwhile
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if lockedUntil.Valid {
+		return &lockedUntil.Time, nil
+	}
+	return nil, nil
+}
+
+// RecordLogin records a successful login, clearing earlier failures
+func (db *DB) RecordLogin(username string) error {
+	_, err := db.Exec(`
+		UPDATE users SET last_login_at = CURRENT_TIMESTAMP, failed_logins = 0, locked_until = NULL
+		WHERE username = $1
+	`, username)
+	if err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	return nil
+}
+
+// scanUser scans a users row selected with userColumns into a User
+func scanUser(row rowScanner) (*user.User, error) {
+	var u user.User
+	var orgID, createdBy sql.NullString
+	var lastLoginAt, lockedUntil, disabledAt sql.NullTime
+
+	err := row.Scan(&u.Username, &orgID, &u.Role, &u.PasswordHash, &createdBy, &u.CreatedAt, &u.UpdatedAt, &lastLoginAt,
+		&u.FailedLogins, &lockedUntil, &disabledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	u.OrgID = orgID.String
+	u.CreatedBy = createdBy.String
+	if lastLoginAt.Valid {
+		u.LastLoginAt = &lastLoginAt.Time
+	}
+	if lockedUntil.Valid {
+		u.LockedUntil = &lockedUntil.Time
+	}
+	if disabledAt.Valid {
+		u.DisabledAt = &disabledAt.Time
+	}
+	return &u, nil
+}
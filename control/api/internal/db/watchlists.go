@@ -0,0 +1,315 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/watchlist"
+	"github.com/lib/pq"
+)
+
+// ListWatchlists returns a user's watchlists, most recently changed first,
+// with their item counts but not their items
+func (db *DB) ListWatchlists(ownerID string) ([]watchlist.Watchlist, error) {
+	rows, err := db.Query(`
+		SELECT w.watchlist_id, w.name, w.owner_id, COALESCE(w.org_id, ''), w.created_at, w.updated_at,
+			(SELECT COUNT(*) FROM watchlist_items i WHERE i.watchlist_id = w.watchlist_id)
+		FROM watchlists w
+		WHERE w.owner_id = $1
+		ORDER BY w.updated_at DESC, w.watchlist_id
+	`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := make([]watchlist.Watchlist, 0)
+	for rows.Next() {
+		var w watchlist.Watchlist
+		if err := rows.Scan(&w.WatchlistID, &w.Name, &w.OwnerID, &w.OrgID, &w.CreatedAt, &w.UpdatedAt, &w.ItemCount); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		lists = append(lists, w)
+	}
+	return lists, rows.Err()
+}
+
+// CreateWatchlist creates an empty watchlist and sets its ID
+func (db *DB) CreateWatchlist(w *watchlist.Watchlist) error {
+	w.CreatedAt = time.Now().UTC()
+	w.UpdatedAt = w.CreatedAt
+	w.WatchlistID = fmt.Sprintf("watchlist_%d", w.CreatedAt.UnixNano())
+	_, err := db.Exec(`
+		INSERT INTO watchlists (watchlist_id, name, owner_id, org_id, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $5)
+	`, w.WatchlistID, w.Name, w.OwnerID, w.OrgID, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create watchlist: %w", err)
+	}
+	return nil
+}
+
+// GetWatchlist returns a watchlist with its items and their surfaces in the
+// order they were added, or nil if it does not exist
+func (db *DB) GetWatchlist(watchlistID string) (*watchlist.Watchlist, error) {
+	w := watchlist.Watchlist{WatchlistID: watchlistID}
+	err := db.QueryRow(`
+		SELECT name, owner_id, COALESCE(org_id, ''), created_at, updated_at
+		FROM watchlists
+		WHERE watchlist_id = $1
+	`, watchlistID).Scan(&w.Name, &w.OwnerID, &w.OrgID, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT i.surface_id, COALESCE(i.note, ''), i.added_at,
+			surfaces.title_id, surfaces.shot_id, surfaces.start_time, surfaces.end_time,
+			(surfaces.end_time - surfaces.start_time), surfaces.surface_type, surfaces.prs_score,
+			surfaces.visibility_score, surfaces.area_pixels, surfaces.area_world_m2, surfaces.created_at,
+			`+thumbnailVersionColumn+`, surfaces.placement_type, surfaces.loudness_target_lufs,
+			surfaces.loudness_tolerance_lu, surfaces.max_true_peak_dbtp
+		FROM watchlist_items i
+		JOIN surfaces ON surfaces.surface_id = i.surface_id
+		WHERE i.watchlist_id = $1
+		ORDER BY i.added_at, i.surface_id
+	`, watchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	defer rows.Close()
+
+	w.Items = make([]watchlist.Item, 0)
+	for rows.Next() {
+		var item watchlist.Item
+		var titleID, shotID, surfaceType, thumbnailVersion sql.NullString
+		var startTime, endTime, duration, prsScore, visibilityScore, areaPixels, areaWorldM2 sql.NullFloat64
+		var createdAt sql.NullTime
+		var placementType string
+		var loudnessTarget, loudnessTolerance, maxTruePeak sql.NullFloat64
+		if err := rows.Scan(&item.SurfaceID, &item.Note, &item.AddedAt,
+			&titleID, &shotID, &startTime, &endTime, &duration, &surfaceType, &prsScore,
+			&visibilityScore, &areaPixels, &areaWorldM2, &createdAt, &thumbnailVersion,
+			&placementType, &loudnessTarget, &loudnessTolerance, &maxTruePeak); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		item.Surface = &models.Surface{
+			SurfaceID:       item.SurfaceID,
+			TitleID:         titleID.String,
+			ShotID:          shotID.String,
+			StartTime:       startTime.Float64,
+			EndTime:         endTime.Float64,
+			Duration:        duration.Float64,
+			SurfaceType:     surfaceType.String,
+			PlacementType:   placementType,
+			Audio:           audioSlot(placementType, startTime.Float64, endTime.Float64, loudnessTarget, loudnessTolerance, maxTruePeak),
+			PRSScore:        prsScore.Float64,
+			VisibilityScore: visibilityScore.Float64,
+			ThumbnailURL:    thumbnailURL(item.SurfaceID, thumbnailVersion),
+			CreatedAt:       createdAt.Time,
+		}
+		if areaPixels.Valid {
+			item.Surface.AreaPixels = &areaPixels.Float64
+		}
+		if areaWorldM2.Valid {
+			item.Surface.AreaWorldM2 = &areaWorldM2.Float64
+		}
+		w.Items = append(w.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	w.ItemCount = len(w.Items)
+	return &w, nil
+}
+
+// DeleteWatchlist removes a watchlist and its items. It reports whether one existed.
+func (db *DB) DeleteWatchlist(watchlistID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM watchlists WHERE watchlist_id = $1`, watchlistID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// SetWatchlistItem adds a surface to a watchlist, or replaces the note of one
+// already on it, and sets the item's AddedAt
+func (db *DB) SetWatchlistItem(watchlistID string, item *watchlist.Item) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the watchlist so concurrent adds cannot take it past MaxItems
+	err = tx.QueryRow(`SELECT watchlist_id FROM watchlists WHERE watchlist_id = $1 FOR UPDATE`, watchlistID).Scan(&watchlistID)
+	if err == sql.ErrNoRows {
+		return watchlist.ErrWatchlistNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock watchlist: %w", err)
+	}
+
+	var exists, listed bool
+	var count int
+	err = tx.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM surfaces WHERE surface_id = $2),
+			EXISTS (SELECT 1 FROM watchlist_items WHERE watchlist_id = $1 AND surface_id = $2),
+			(SELECT COUNT(*) FROM watchlist_items WHERE watchlist_id = $1)
+	`, watchlistID, item.SurfaceID).Scan(&exists, &listed, &count)
+	if err != nil {
+		return fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	if !exists {
+		return ErrSurfaceNotFound
+	}
+	if !listed && count >= watchlist.MaxItems {
+		return fmt.Errorf("%w: it holds %d surfaces", watchlist.ErrFull, watchlist.MaxItems)
+	}
+
+	now := time.Now().UTC()
+	err = tx.QueryRow(`
+		INSERT INTO watchlist_items (watchlist_id, surface_id, note, added_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (watchlist_id, surface_id) DO UPDATE SET note = EXCLUDED.note
+		RETURNING added_at
+	`, watchlistID, item.SurfaceID, item.Note, now).Scan(&item.AddedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save watchlist item: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE watchlists SET updated_at = $2 WHERE watchlist_id = $1`, watchlistID, now); err != nil {
+		return fmt.Errorf("failed to update watchlist: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit watchlist item: %w", err)
+	}
+	return nil
+}
+
+// DeleteWatchlistItem removes a surface from a watchlist. It reports whether
+// the surface was on it.
+func (db *DB) DeleteWatchlistItem(watchlistID, surfaceID string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM watchlist_items WHERE watchlist_id = $1 AND surface_id = $2`, watchlistID, surfaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watchlist item: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watchlist item: %w", err)
+	}
+	if affected > 0 {
+		if _, err := db.Exec(`UPDATE watchlists SET updated_at = CURRENT_TIMESTAMP WHERE watchlist_id = $1`, watchlistID); err != nil {
+			return true, fmt.Errorf("failed to update watchlist: %w", err)
+		}
+	}
+	return affected > 0, nil
+}
+
+// BookWatchlist books the selected surfaces of a watchlist at the booking's
+// terms in a single transaction. Surfaces that are held back, merged, or that
+// the campaign already has a live booking on are skipped and reported.
+func (db *DB) BookWatchlist(b *watchlist.Booking) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the watchlist so a double submit sees the first one's bookings
+	err = tx.QueryRow(`SELECT watchlist_id FROM watchlists WHERE watchlist_id = $1 FOR UPDATE`, b.WatchlistID).Scan(&b.WatchlistID)
+	if err == sql.ErrNoRows {
+		return watchlist.ErrWatchlistNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock watchlist: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT pb.surface_id
+		FROM placement_bookings pb
+		WHERE pb.surface_id = ANY($1) AND pb.campaign_id = $2
+			AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+	`, pq.Array(b.SurfaceIDs), b.CampaignID)
+	if err != nil {
+		return fmt.Errorf("failed to query live bookings: %w", err)
+	}
+	booked := make(map[string]bool)
+	for rows.Next() {
+		var surfaceID string
+		if err := rows.Scan(&surfaceID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan live booking: %w", err)
+		}
+		booked[surfaceID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query live bookings: %w", err)
+	}
+
+	b.CreatedAt = time.Now().UTC()
+	b.Bookings = make([]watchlist.Booked, 0, len(b.SurfaceIDs))
+	b.Skipped = make([]watchlist.Skip, 0)
+	for _, surfaceID := range b.SurfaceIDs {
+		if booked[surfaceID] {
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipBooked})
+			continue
+		}
+		bookingID, err := createPlacementBooking(tx, &models.NewBooking{
+			SurfaceID:      surfaceID,
+			AdvertiserID:   b.AdvertiserID,
+			CampaignID:     b.CampaignID,
+			BidAmountCPM:   b.BidAmountCPM,
+			MaxImpressions: b.MaxImpressions,
+			OrgID:          b.OrgID,
+			UserID:         b.CreatedBy,
+		}, b.CreatedAt)
+		switch {
+		case errors.Is(err, holdback.ErrHeldBack):
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipHeldBack})
+			continue
+		case errors.Is(err, dedupe.ErrSurfaceMerged):
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipMerged})
+			continue
+		case errors.Is(err, collision.ErrCollision):
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipCollides})
+			continue
+		case errors.Is(err, booking.ErrConflict):
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipBooked})
+			continue
+		case err != nil:
+			return err
+		}
+		b.Bookings = append(b.Bookings, watchlist.Booked{BookingID: bookingID, SurfaceID: surfaceID})
+	}
+	if len(b.Bookings) == 0 {
+		return watchlist.ErrNoSurfaces
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit watchlist booking: %w", err)
+	}
+
+	bookingIDs := make([]string, 0, len(b.Bookings))
+	for _, booked := range b.Bookings {
+		bookingIDs = append(bookingIDs, booked.BookingID)
+	}
+	db.publish(bookingsCreated(bookingIDs, b.CampaignID, b.CreatedBy, b.OrgID, b.CreatedAt)...)
+	return nil
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/watermark"
+)
+
+// FindWatermark returns the render job and booking a watermark was issued
+// to, or nil when there is none or its booking is not visible within scope
+func (db *DB) FindWatermark(scope tenant.Scope, watermarkID uint32) (*watermark.Mark, error) {
+	where := query.New()
+	where.Where("r.watermark_id = %s", int64(watermarkID))
+	whereBookingVisible(where, scope)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	mark := watermark.Mark{WatermarkID: watermarkID}
+	err := db.QueryRow(fmt.Sprintf(`
+		SELECT r.job_id, r.booking_id
+		FROM render_jobs r
+		JOIN placement_bookings ON placement_bookings.booking_id = r.booking_id
+		WHERE %s
+	`, where.Clause()), where.Args()...).Scan(&mark.JobID, &mark.BookingID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find watermark: %w", err)
+	}
+	return &mark, nil
+}
+
+// RecordWatermarkVerification logs a sighting of a watermark, setting its ID
+// and recording time
+func (db *DB) RecordWatermarkVerification(v *watermark.Verification) error {
+	v.RecordedAt = time.Now().UTC()
+	v.VerificationID = fmt.Sprintf("verification_%d_%d", v.WatermarkID, v.RecordedAt.UnixNano())
+
+	_, err := db.Exec(`
+		INSERT INTO watermark_verifications (
+			verification_id, watermark_id, job_id, booking_id, source,
+			confidence, captured_at, recorded_by, recorded_at
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9)
+	`, v.VerificationID, int64(v.WatermarkID), v.JobID, v.BookingID, v.Source,
+		v.Confidence, v.CapturedAt, v.RecordedBy, v.RecordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record watermark verification: %w", err)
+	}
+	return nil
+}
+
+// ListWatermarkVerifications lists the verifications of a booking, newest
+// capture first
+func (db *DB) ListWatermarkVerifications(bookingID string, limit, offset int) ([]watermark.Verification, error) {
+	rows, err := db.Query(`
+		SELECT verification_id, watermark_id, job_id, booking_id, COALESCE(source, ''),
+			confidence, captured_at, COALESCE(recorded_by, ''), recorded_at
+		FROM watermark_verifications
+		WHERE booking_id = $1
+		ORDER BY captured_at DESC, verification_id DESC
+		LIMIT $2 OFFSET $3
+	`, bookingID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watermark verifications: %w", err)
+	}
+	defer rows.Close()
+
+	verifications := make([]watermark.Verification, 0)
+	for rows.Next() {
+		var v watermark.Verification
+		var watermarkID int64
+		if err := rows.Scan(&v.VerificationID, &watermarkID, &v.JobID, &v.BookingID, &v.Source,
+			&v.Confidence, &v.CapturedAt, &v.RecordedBy, &v.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watermark verification: %w", err)
+		}
+		v.WatermarkID = uint32(watermarkID)
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
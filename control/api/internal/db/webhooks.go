@@ -0,0 +1,264 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/lib/pq"
+)
+
+// webhookSubscriptionColumns are selected by every subscription query, in scan order
+const webhookSubscriptionColumns = `subscription_id, org_id, url, event_types, description, secret, active, created_by, created_at, updated_at`
+
+// CreateWebhookSubscription stores a subscription, sealing its secret, and
+// sets its ID and timestamps
+func (db *DB) CreateWebhookSubscription(subscription *webhook.Subscription) error {
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = subscription.CreatedAt
+	subscription.ID = fmt.Sprintf("whsub_%d", subscription.CreatedAt.UnixNano())
+
+	secret, err := db.fields.Seal(WebhookSecretColumn, subscription.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO webhook_subscriptions (subscription_id, org_id, url, event_types, description, secret, active, created_by, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9, $9)
+	`, subscription.ID, subscription.OrgID, subscription.URL, pq.Array(subscription.EventTypes), subscription.Description,
+		secret, subscription.Active, subscription.CreatedBy, subscription.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookSubscription retrieves a subscription with its secret
+func (db *DB) GetWebhookSubscription(subscriptionID string) (*webhook.Subscription, error) {
+	subscription, err := db.scanWebhookSubscription(db.QueryRow(`
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE subscription_id = $1
+	`, subscriptionID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return subscription, err
+}
+
+// ListWebhookSubscriptions lists the subscriptions belonging to an organization
+func (db *DB) ListWebhookSubscriptions(orgID string) ([]*webhook.Subscription, error) {
+	return db.queryWebhookSubscriptions(`
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE COALESCE(org_id, '') = $1
+		ORDER BY created_at DESC
+	`, orgID)
+}
+
+// ListWebhookTargets lists the active subscriptions of an organization
+// asking for eventType
+func (db *DB) ListWebhookTargets(orgID, eventType string) ([]*webhook.Subscription, error) {
+	return db.queryWebhookSubscriptions(`
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE COALESCE(org_id, '') = $1 AND active AND $2 = ANY(event_types)
+		ORDER BY created_at
+	`, orgID, eventType)
+}
+
+// UpdateWebhookSubscription saves a subscription's URL, event types,
+// description and active flag
+func (db *DB) UpdateWebhookSubscription(subscription *webhook.Subscription) error {
+	subscription.UpdatedAt = time.Now()
+	_, err := db.Exec(`
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, description = NULLIF($4, ''), active = $5, updated_at = $6
+		WHERE subscription_id = $1
+	`, subscription.ID, subscription.URL, pq.Array(subscription.EventTypes), subscription.Description, subscription.Active, subscription.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription and its delivery log.
+// Jobs still queued for its deliveries find nothing to send.
+func (db *DB) DeleteWebhookSubscription(subscriptionID string) error {
+	_, err := db.Exec(`DELETE FROM webhook_subscriptions WHERE subscription_id = $1`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// CreateWebhookDelivery stores a delivery and sets its ID and creation time
+func (db *DB) CreateWebhookDelivery(delivery *webhook.Delivery) error {
+	delivery.CreatedAt = time.Now()
+	delivery.ID = fmt.Sprintf("whdel_%s_%d", delivery.SubscriptionID, delivery.CreatedAt.UnixNano())
+
+	_, err := db.Exec(`
+		INSERT INTO webhook_deliveries (delivery_id, subscription_id, event_id, event_type, payload, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, delivery.ID, delivery.SubscriptionID, delivery.EventID, delivery.EventType, []byte(delivery.Payload), delivery.Status, delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDelivery retrieves a delivery by ID
+func (db *DB) GetWebhookDelivery(deliveryID string) (*webhook.Delivery, error) {
+	delivery, err := scanWebhookDelivery(db.QueryRow(`
+		SELECT delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+			response_status, last_error, created_at, last_attempt_at, delivered_at
+		FROM webhook_deliveries
+		WHERE delivery_id = $1
+	`, deliveryID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	return delivery, err
+}
+
+// RecordWebhookAttempt records the outcome of a delivery. attempts of zero
+// means the delivery was settled without being sent.
+func (db *DB) RecordWebhookAttempt(deliveryID, status string, attempts, responseStatus int, lastError string, attemptedAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $2,
+			attempts = GREATEST(attempts, $3),
+			response_status = NULLIF($4, 0),
+			last_error = NULLIF($5, ''),
+			last_attempt_at = CASE WHEN $3 > 0 THEN $6 ELSE last_attempt_at END,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN $6 ELSE delivered_at END
+		WHERE delivery_id = $1
+	`, deliveryID, status, attempts, responseStatus, lastError, attemptedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries lists a subscription's deliveries, newest first,
+// optionally only those with status
+func (db *DB) ListWebhookDeliveries(subscriptionID, status string, page pagination.Page) ([]webhook.Delivery, error) {
+	var cursorTime time.Time
+	if page.Cursor != nil {
+		var err error
+		if cursorTime, err = page.Cursor.Time(); err != nil {
+			return nil, err
+		}
+	}
+	where := query.New()
+	where.Equal("subscription_id", subscriptionID)
+	if status != "" {
+		where.Equal("status", status)
+	}
+	direction := whereKeyset(where, "created_at", "delivery_id", page, cursorTime)
+	limit, offset := where.Bind(page.Fetch()), where.Bind(page.Offset)
+	if err := where.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+			response_status, last_error, created_at, last_attempt_at, delivered_at
+		FROM webhook_deliveries
+		WHERE %s
+		ORDER BY created_at %s, delivery_id %s
+		LIMIT %s OFFSET %s
+	`, where.Clause(), direction, direction, limit, offset)
+
+	rows, err := db.Query(stmt, where.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// queryWebhookSubscriptions runs a subscription query and scans every row
+func (db *DB) queryWebhookSubscriptions(stmt string, args ...interface{}) ([]*webhook.Subscription, error) {
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*webhook.Subscription, 0)
+	for rows.Next() {
+		subscription, err := db.scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// scanWebhookSubscription scans a webhook_subscriptions row into a
+// Subscription, opening its secret
+func (db *DB) scanWebhookSubscription(row rowScanner) (*webhook.Subscription, error) {
+	var subscription webhook.Subscription
+	var orgID, description, createdBy sql.NullString
+
+	err := row.Scan(&subscription.ID, &orgID, &subscription.URL, pq.Array(&subscription.EventTypes), &description,
+		&subscription.Secret, &subscription.Active, &createdBy, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+	}
+	if subscription.Secret, err = db.fields.Open(WebhookSecretColumn, subscription.Secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret of %s: %w", subscription.ID, err)
+	}
+
+	subscription.OrgID = orgID.String
+	subscription.Description = description.String
+	subscription.CreatedBy = createdBy.String
+	return &subscription, nil
+}
+
+// scanWebhookDelivery scans a webhook_deliveries row into a Delivery
+func scanWebhookDelivery(row rowScanner) (*webhook.Delivery, error) {
+	var delivery webhook.Delivery
+	var payload []byte
+	var responseStatus sql.NullInt64
+	var lastError sql.NullString
+	var lastAttemptAt, deliveredAt sql.NullTime
+
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status,
+		&delivery.Attempts, &responseStatus, &lastError, &delivery.CreatedAt, &lastAttemptAt, &deliveredAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+
+	delivery.Payload = payload
+	delivery.ResponseStatus = int(responseStatus.Int64)
+	delivery.LastError = lastError.String
+	if lastAttemptAt.Valid {
+		delivery.LastAttemptAt = &lastAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,101 @@
+// Package decorate signals a title's booked placements in the playlists
+// CDNs and SSAI vendors send the gateway, so they can call the API rather
+// than embed the edge manifest package. Placements are written as
+// EXT-X-DATERANGE tags by edge/manifest; this package maps bookings onto
+// them and fetches playlists from partners' origins.
+package decorate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/edge/manifest"
+)
+
+// ContentTypeHLS is the media type of HLS playlists
+const ContentTypeHLS = "application/vnd.apple.mpegurl"
+
+// DefaultProxyURL is where decorated master playlists point their
+// renditions by default: the gateway's own decorating endpoint, on the
+// host the master playlist was served from
+const DefaultProxyURL = "/api/v1/manifests/decorate"
+
+// MaxManifestBytes bounds a playlist sent or fetched for decoration
+const MaxManifestBytes = 4 << 20
+
+// ErrInvalidManifest is returned for playlists that cannot be decorated
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// Placement is a booked placement as it plays out in a title, in seconds
+// of media time from the start of the title
+type Placement struct {
+	BookingID   string
+	SurfaceID   string
+	SurfaceType string // e.g. "wall" or "screen"; the placement type for audio slots
+	PRSScore    float64
+	Start       float64
+	End         float64
+	// AssetURI, if set, is the creative rendition played as an HLS
+	// Interstitial, skipping ResumeOffset seconds of the title when it
+	// ends, or the whole placement when that is nil
+	AssetURI     string
+	ResumeOffset *float64
+}
+
+// Metadata returns the placement as signaled for a title starting at
+// programStart
+func (p Placement) Metadata(programStart time.Time) manifest.PlacementMetadata {
+	m := manifest.PlacementMetadata{
+		ID:            p.BookingID,
+		StartTime:     programStart.Add(time.Duration(p.Start * float64(time.Second))),
+		Duration:      max(p.End-p.Start, 0),
+		SurfaceID:     p.SurfaceID,
+		PRSScore:      p.PRSScore,
+		PlacementType: p.SurfaceType,
+	}
+	if p.AssetURI != "" {
+		m.Interstitial = &manifest.Interstitial{AssetURI: p.AssetURI, ResumeOffset: m.Duration}
+		if p.ResumeOffset != nil {
+			m.Interstitial.ResumeOffset = *p.ResumeOffset
+		}
+	}
+	return m
+}
+
+// IsLive reports whether a media playlist may still change: it has no
+// EXT-X-ENDLIST and is not of the VOD type. Playlists that cannot be
+// parsed are taken to be live.
+func IsLive(playlist []byte) bool {
+	mp, err := manifest.NewManifestProcessor(string(playlist), time.Unix(0, 0))
+	return err != nil || mp.Live()
+}
+
+// HLS returns a media playlist with an EXT-X-DATERANGE tag for each
+// placement playing within it, and the IDs of the placements signaled.
+// Placements with an asset are HLS Interstitials too. The title
+// starts at programStart, which START-DATE attributes are given relative
+// to. Undated playlists are taken to begin with the title; segments of
+// playlists dated with EXT-X-PROGRAM-DATE-TIME, such as live ones, play at
+// their date.
+func HLS(playlist []byte, placements []Placement, programStart time.Time) ([]byte, []string, error) {
+	mp, err := manifest.NewManifestProcessor(string(playlist), programStart)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	metadata := make([]manifest.PlacementMetadata, 0, len(placements))
+	signaled := make([]string, 0, len(placements))
+	for _, p := range placements {
+		m := p.Metadata(programStart)
+		if _, ok := mp.SegmentFor(m); ok {
+			signaled = append(signaled, p.BookingID)
+		}
+		metadata = append(metadata, m)
+	}
+	decorated, err := mp.InjectPlacementMetadata(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(decorated), signaled, nil
+}
@@ -0,0 +1,109 @@
+package decorate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// ErrHostNotAllowed is returned for manifest URLs outside the allowed origins
+var ErrHostNotAllowed = errors.New("manifest host is not allowed")
+
+// ErrOrigin is returned when an origin does not serve a usable playlist
+var ErrOrigin = errors.New("manifest origin error")
+
+// maxRedirects bounds the redirects followed fetching one playlist
+const maxRedirects = 5
+
+// Fetcher reads playlists from partners' origins. Only hosts on its allow
+// list are fetched, redirects included, so callers cannot make the
+// gateway request internal services.
+type Fetcher struct {
+	client *outbound.Client
+	hosts  []string
+}
+
+// NewFetcher creates a fetcher allowed to read from hosts. An entry
+// starting with a dot, such as ".cdn.example.com", allows every subdomain.
+// With no hosts, nothing is fetched.
+func NewFetcher(hosts []string) *Fetcher {
+	policy := outbound.DefaultPolicy()
+	policy.Timeout = 10 * time.Second
+	f := &Fetcher{client: outbound.New("manifest_origin", policy)}
+	for _, host := range hosts {
+		f.hosts = append(f.hosts, strings.ToLower(host))
+	}
+	f.client.SetRedirectPolicy(func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("%w: too many redirects", ErrOrigin)
+		}
+		if !f.Allowed(req.URL) {
+			return fmt.Errorf("%w: redirected to %s", ErrHostNotAllowed, req.URL.Hostname())
+		}
+		return nil
+	})
+	return f
+}
+
+// Enabled reports whether any host may be fetched from
+func (f *Fetcher) Enabled() bool {
+	return len(f.hosts) > 0
+}
+
+// Allowed reports whether u is an http(s) URL on an allowed host
+func (f *Fetcher) Allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.hosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch reads the playlist at rawURL, of at most MaxManifestBytes
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid URL", ErrHostNotAllowed)
+	}
+	if !f.Allowed(u) {
+		return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Hostname())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid URL", ErrHostNotAllowed)
+	}
+	req.Header.Set("Accept", ContentTypeHLS+", */*;q=0.5")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrHostNotAllowed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOrigin, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s answered %d", ErrOrigin, u.Hostname(), resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxManifestBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOrigin, err)
+	}
+	if len(body) > MaxManifestBytes {
+		return nil, fmt.Errorf("%w: playlist is larger than %d bytes", ErrOrigin, MaxManifestBytes)
+	}
+	return body, nil
+}
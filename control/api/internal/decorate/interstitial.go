@@ -0,0 +1,47 @@
+package decorate
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxResumeOffset bounds how much of a title an interstitial may skip
+const MaxResumeOffset = 3600.0
+
+// Interstitial has a booking's placements played as Apple HLS
+// Interstitials: besides being tagged, they instruct players supporting
+// them to load the booked creative rendition. The asset is AssetURI, or
+// the latest completed render of the booking when it is empty.
+type Interstitial struct {
+	BookingID        string    `json:"booking_id"`
+	AssetURI         string    `json:"asset_uri,omitempty"`          // HLS playlist of the creative rendition
+	ResumeOffset     *float64  `json:"resume_offset,omitempty"`      // Seconds of the title skipped when it ends; nil skips the placement
+	RenderedAssetURI string    `json:"rendered_asset_uri,omitempty"` // Latest completed render, played when AssetURI is empty
+	UpdatedBy        string    `json:"updated_by,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Validate checks the asset URI and resume offset
+func (i *Interstitial) Validate() error {
+	if i.AssetURI != "" {
+		if !PlayableAssetURI(i.AssetURI) {
+			return fmt.Errorf("asset_uri must be an absolute http or https URL")
+		}
+		if strings.ContainsAny(i.AssetURI, "\"\r\n") {
+			return fmt.Errorf("asset_uri may not contain quotes or line breaks")
+		}
+	}
+	if i.ResumeOffset != nil && (*i.ResumeOffset < 0 || *i.ResumeOffset > MaxResumeOffset) {
+		return fmt.Errorf("resume_offset must be between 0 and %g seconds", MaxResumeOffset)
+	}
+	return nil
+}
+
+// PlayableAssetURI reports whether players can load an asset from uri:
+// renders written to object storage, say, cannot be
+func PlayableAssetURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
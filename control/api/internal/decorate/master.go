@@ -0,0 +1,124 @@
+package decorate
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/inscenium/inscenium/edge/manifest"
+)
+
+// MaxRenditions bounds the media playlists of a master playlist decorated
+// in one request
+const MaxRenditions = 50
+
+// Rendition is a media playlist of a master playlist, at its absolute URL
+type Rendition struct {
+	manifest.Rendition
+	URL string
+}
+
+// IsMaster reports whether playlist is an HLS master playlist
+func IsMaster(playlist []byte) bool {
+	return manifest.IsMasterPlaylist(string(playlist))
+}
+
+// Renditions parses a master playlist served from base and returns its
+// renditions with their URIs resolved against base, which may be nil when
+// they are all absolute
+func Renditions(playlist []byte, base *url.URL) (*manifest.MasterPlaylist, []Rendition, error) {
+	master, err := manifest.ParseMasterPlaylist(string(playlist))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	renditions := master.Renditions()
+	if len(renditions) > MaxRenditions {
+		return nil, nil, fmt.Errorf("%w: master playlist references more than %d playlists", ErrInvalidManifest, MaxRenditions)
+	}
+
+	resolved := make([]Rendition, 0, len(renditions))
+	for _, r := range renditions {
+		u, err := url.Parse(r.URI)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid rendition URI %q", ErrInvalidManifest, r.URI)
+		}
+		if !u.IsAbs() {
+			if base == nil {
+				return nil, nil, fmt.Errorf("%w: relative rendition URI %q needs the master playlist's url or base_url", ErrInvalidManifest, r.URI)
+			}
+			u = base.ResolveReference(u)
+		}
+		resolved = append(resolved, Rendition{Rendition: r, URL: u.String()})
+	}
+	return master, resolved, nil
+}
+
+// Proxy returns a master playlist whose renditions point at proxyURL
+// instead, which decorates each as it is fetched: the rendition's absolute
+// URL is passed as url, along with params. Every rendition is thus
+// decorated for the same title and program start, so players on any of
+// them see the same placements. It returns how many renditions were
+// rewritten.
+func Proxy(playlist []byte, base *url.URL, proxyURL string, params url.Values) ([]byte, int, error) {
+	master, renditions, err := Renditions(playlist, base)
+	if err != nil {
+		return nil, 0, err
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid manifest proxy URL: %w", err)
+	}
+
+	byURI := make(map[string]string, len(renditions))
+	for _, r := range renditions {
+		query := url.Values{}
+		for key, values := range params {
+			query[key] = values
+		}
+		query.Set("url", r.URL)
+		u := *proxy
+		u.RawQuery = query.Encode()
+		byURI[r.URI] = u.String()
+	}
+	rewritten, err := master.RewriteURIs(func(r manifest.Rendition) (string, error) {
+		return byURI[r.URI], nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return []byte(rewritten), len(renditions), nil
+}
+
+// HLSRenditions decorates the media playlists of one master playlist
+// consistently, with the placements every one of them can signal (see
+// manifest.InjectAcrossRenditions), and returns them in order along with
+// the IDs of the placements signaled
+func HLSRenditions(playlists [][]byte, placements []Placement, programStart time.Time) ([][]byte, []string, error) {
+	processors := make([]*manifest.ManifestProcessor, 0, len(playlists))
+	for i, playlist := range playlists {
+		mp, err := manifest.NewManifestProcessor(string(playlist), programStart)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: rendition %d: %v", ErrInvalidManifest, i+1, err)
+		}
+		processors = append(processors, mp)
+	}
+
+	metadata := make([]manifest.PlacementMetadata, 0, len(placements))
+	for _, p := range placements {
+		metadata = append(metadata, p.Metadata(programStart))
+	}
+	decorated, signaled, err := manifest.InjectAcrossRenditions(processors, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([][]byte, len(decorated))
+	for i, playlist := range decorated {
+		result[i] = []byte(playlist)
+	}
+	ids := make([]string, 0, len(signaled))
+	for _, p := range signaled {
+		ids = append(ids, p.ID)
+	}
+	return result, ids, nil
+}
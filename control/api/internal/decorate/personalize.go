@@ -0,0 +1,106 @@
+package decorate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+)
+
+// Reasons a placement is left out of a viewer's playlist
+const (
+	FilteredFrequencyCap = "frequency_cap" // The viewer has seen the booking or its campaign as often as it is capped at
+	FilteredConsent      = "consent"       // The booking is capped, which needs the consent the viewer has not given to count their exposures
+	FilteredUnavailable  = "unavailable"   // The viewer's exposures could not be counted
+)
+
+// CapChecker decides whether a viewer may see another exposure of a booking
+type CapChecker interface {
+	Check(ctx context.Context, viewerID, campaignID, bookingID string) (*freqcap.Decision, error)
+}
+
+// Viewer is who a playlist is personalized for
+type Viewer struct {
+	ID      string // Viewer hash
+	Consent bool   // Whether the viewer consented to their exposures being counted
+}
+
+// Personalize returns the placements viewer may be shown, in order, and
+// why each other booking was left out. Bookings without frequency caps are
+// shown to every viewer. Capped bookings are shown to viewers who consented
+// and are under their caps; without consent their exposures are not
+// counted, so they are left out rather than shown uncapped. Bookings whose
+// caps cannot be checked are left out.
+func Personalize(ctx context.Context, placements []Placement, viewer Viewer, caps CapChecker) ([]Placement, map[string]string, error) {
+	allowed := make([]Placement, 0, len(placements))
+	reasons := make(map[string]string) // By booking checked; empty when shown
+	for _, p := range placements {
+		reason, checked := reasons[p.BookingID]
+		if !checked {
+			var err error
+			if reason, err = filterReason(ctx, p.BookingID, viewer, caps); err != nil {
+				return nil, nil, err
+			}
+			reasons[p.BookingID] = reason
+		}
+		if reason == "" {
+			allowed = append(allowed, p)
+		}
+	}
+
+	filtered := make(map[string]string)
+	for bookingID, reason := range reasons {
+		if reason != "" {
+			filtered[bookingID] = reason
+		}
+	}
+	return allowed, filtered, nil
+}
+
+// filterReason returns why a booking is left out of viewer's playlist, or
+// "" when it is shown
+func filterReason(ctx context.Context, bookingID string, viewer Viewer, caps CapChecker) (string, error) {
+	decision, err := caps.Check(ctx, viewer.ID, "", bookingID)
+	switch {
+	case errors.Is(err, freqcap.ErrNotFound), errors.Is(err, freqcap.ErrUnavailable):
+		// Bookings cancelled since the placements were listed are not found
+		return FilteredUnavailable, nil
+	case err != nil:
+		return "", fmt.Errorf("failed to check frequency caps of %s: %w", bookingID, err)
+	case len(decision.Limits) == 0:
+		return "", nil
+	case !viewer.Consent:
+		return FilteredConsent, nil
+	case !decision.Allowed:
+		return FilteredFrequencyCap, nil
+	}
+	return "", nil
+}
+
+// Serve records a playlist personalized for a viewer, so exposures can be
+// attributed to the manifest that signaled them
+type Serve struct {
+	ServeID    string            `json:"serve_id"`
+	TitleID    string            `json:"title_id"`
+	ViewerID   string            `json:"viewer_id,omitempty"` // Only kept with the viewer's consent
+	SessionID  string            `json:"session_id,omitempty"`
+	OrgID      string            `json:"org_id,omitempty"`
+	Consent    bool              `json:"consent"`
+	BookingIDs []string          `json:"booking_ids"` // Placements signaled
+	Filtered   map[string]string `json:"filtered"`    // Reasons bookings were left out, by booking ID
+	ServedAt   time.Time         `json:"served_at"`
+}
+
+// NewServeID returns a random serve identifier
+func NewServeID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("decorate: failed to generate id: %v", err))
+	}
+	return "serve_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,182 @@
+package dedupe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrSurfaceMerged is returned when booking a surface that was merged into another
+var ErrSurfaceMerged = errors.New("surface has been merged")
+
+// ErrInvalidMerge is returned when surfaces cannot be merged
+var ErrInvalidMerge = errors.New("invalid surface merge")
+
+// maxMergeSurfaces bounds the surfaces one merge may fold into another
+const maxMergeSurfaces = 100
+
+// Thresholds decide when two surfaces of a title are the same physical object
+type Thresholds struct {
+	MinGeometryIoU float64 // Intersection over union of the surface polygons
+	MinTimeOverlap float64 // Shared screen time over the shorter surface's duration
+}
+
+// DefaultThresholds returns thresholds tuned for re-runs of the vision pipeline
+func DefaultThresholds() Thresholds {
+	return Thresholds{MinGeometryIoU: 0.6, MinTimeOverlap: 0.5}
+}
+
+// Surface is a candidate duplicate and the facts used to pick the canonical one
+type Surface struct {
+	SurfaceID    string    `json:"surface_id"`
+	PRSScore     float64   `json:"prs_score"`
+	LiveBookings int       `json:"live_bookings"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Pair is two surfaces that overlap past the thresholds
+type Pair struct {
+	A           Surface
+	B           Surface
+	GeometryIoU float64
+	TimeOverlap float64
+}
+
+// Warning flags a newly ingested surface that duplicates an existing one
+type Warning struct {
+	SurfaceID   string  `json:"surface_id"`
+	DuplicateOf string  `json:"duplicate_of"`
+	GeometryIoU float64 `json:"geometry_iou"`
+	TimeOverlap float64 `json:"time_overlap"`
+}
+
+// Member is a surface in a duplicate cluster. The overlaps are those of its
+// strongest link to another member.
+type Member struct {
+	Surface
+	Canonical   bool    `json:"canonical"`
+	GeometryIoU float64 `json:"geometry_iou"`
+	TimeOverlap float64 `json:"time_overlap"`
+}
+
+// Cluster is a group of surfaces that are likely the same physical object
+type Cluster struct {
+	ID                 string    `json:"cluster_id"`
+	TitleID            string    `json:"title_id"`
+	CanonicalSurfaceID string    `json:"canonical_surface_id"`
+	Members            []Member  `json:"members"`
+	DetectedAt         time.Time `json:"detected_at"`
+}
+
+// Group clusters surfaces connected by duplicate pairs. Each cluster keeps the
+// surface with the most live bookings as canonical, then the highest PRS
+// score, then the oldest, so merging moves as few bookings as possible.
+func Group(titleID string, pairs []Pair, detectedAt time.Time) []Cluster {
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	members := make(map[string]*Member)
+	link := func(s Surface, pair Pair) {
+		m, ok := members[s.SurfaceID]
+		if !ok {
+			m = &Member{Surface: s}
+			members[s.SurfaceID] = m
+			parent[s.SurfaceID] = s.SurfaceID
+		}
+		if pair.GeometryIoU > m.GeometryIoU {
+			m.GeometryIoU, m.TimeOverlap = pair.GeometryIoU, pair.TimeOverlap
+		}
+	}
+	for _, pair := range pairs {
+		link(pair.A, pair)
+		link(pair.B, pair)
+		if a, b := find(pair.A.SurfaceID), find(pair.B.SurfaceID); a != b {
+			parent[b] = a
+		}
+	}
+
+	groups := make(map[string][]Member)
+	for id, m := range members {
+		root := find(id)
+		groups[root] = append(groups[root], *m)
+	}
+
+	clusters := make([]Cluster, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return better(group[i].Surface, group[j].Surface) })
+		group[0].Canonical = true
+		clusters = append(clusters, Cluster{
+			ID:                 "dup_" + group[0].SurfaceID,
+			TitleID:            titleID,
+			CanonicalSurfaceID: group[0].SurfaceID,
+			Members:            group,
+			DetectedAt:         detectedAt,
+		})
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].ID < clusters[j].ID })
+	return clusters
+}
+
+// better reports whether a should be kept over b
+func better(a, b Surface) bool {
+	if a.LiveBookings != b.LiveBookings {
+		return a.LiveBookings > b.LiveBookings
+	}
+	if a.PRSScore != b.PRSScore {
+		return a.PRSScore > b.PRSScore
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.SurfaceID < b.SurfaceID
+}
+
+// Merge folds duplicate surfaces into a canonical one
+type Merge struct {
+	IntoSurfaceID string   `json:"into_surface_id"`
+	SurfaceIDs    []string `json:"surface_ids"`
+	Actor         string   `json:"-"`
+	OrgID         string   `json:"-"`
+}
+
+// Validate checks that the merge is well formed
+func (m *Merge) Validate() error {
+	if m.IntoSurfaceID == "" {
+		return fmt.Errorf("into_surface_id is required")
+	}
+	if len(m.SurfaceIDs) == 0 {
+		return fmt.Errorf("surface_ids is required")
+	}
+	if len(m.SurfaceIDs) > maxMergeSurfaces {
+		return fmt.Errorf("at most %d surfaces may be merged at once", maxMergeSurfaces)
+	}
+	seen := make(map[string]bool, len(m.SurfaceIDs))
+	for _, id := range m.SurfaceIDs {
+		if id == "" {
+			return fmt.Errorf("surface_ids must not contain empty IDs")
+		}
+		if id == m.IntoSurfaceID {
+			return fmt.Errorf("surface %s cannot be merged into itself", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("surface %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// MergeResult reports what a merge changed
+type MergeResult struct {
+	IntoSurfaceID string    `json:"into_surface_id"`
+	Merged        []string  `json:"merged"`
+	BookingsMoved []string  `json:"bookings_moved"`
+	MergedAt      time.Time `json:"merged_at"`
+}
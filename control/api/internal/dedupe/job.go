@@ -0,0 +1,55 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue is the background job queue that clusters a title's duplicate surfaces
+const Queue = "surface_dedupe"
+
+// Job is the payload of a dedupe job
+type Job struct {
+	TitleID string `json:"title_id"`
+}
+
+// Store finds duplicate pairs and records the clusters they form
+type Store interface {
+	FindDuplicatePairs(titleID string, surfaceIDs []string, thresholds Thresholds) ([]Pair, error)
+	ReplaceDuplicateClusters(titleID string, clusters []Cluster) error
+}
+
+// NewJobHandler returns a job handler that replaces a title's duplicate
+// clusters with those found now. Re-running it is harmless.
+func NewJobHandler(store Store, thresholds Thresholds) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload Job
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		if payload.TitleID == "" {
+			return fmt.Errorf("dedupe job %s has no title_id", job.ID)
+		}
+
+		pairs, err := store.FindDuplicatePairs(payload.TitleID, nil, thresholds)
+		if err != nil {
+			return err
+		}
+		clusters := Group(payload.TitleID, pairs, time.Now().UTC())
+		if err := store.ReplaceDuplicateClusters(payload.TitleID, clusters); err != nil {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"job_id":   job.ID,
+			"title_id": payload.TitleID,
+			"pairs":    len(pairs),
+			"clusters": len(clusters),
+		}).Info("Clustered duplicate surfaces")
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// deregisterTimeout bounds deregistration at shutdown
+const deregisterTimeout = 5 * time.Second
+
+// HealthCheck reports whether the gateway can serve requests
+type HealthCheck func(ctx context.Context) error
+
+// Agent keeps the gateway's registration current: it checks the gateway's
+// health every interval, reports it to the registry and deregisters on
+// shutdown
+type Agent struct {
+	registry Registry
+	driver   string
+	check    HealthCheck
+	interval time.Duration
+}
+
+// NewAgent creates a registration agent
+func NewAgent(registry Registry, driver string, check HealthCheck, interval time.Duration) *Agent {
+	return &Agent{registry: registry, driver: driver, check: check, interval: interval}
+}
+
+// Run reports immediately and then every interval until ctx is cancelled,
+// then deregisters
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.report(ctx)
+
+		select {
+		case <-ctx.Done():
+			a.deregister()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) report(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, a.interval)
+	defer cancel()
+
+	healthy, output := true, "ready"
+	if err := a.check(checkCtx); err != nil {
+		healthy, output = false, err.Error()
+		logrus.WithError(err).Warn("Gateway is unhealthy, reporting it to service discovery")
+	}
+
+	if err := a.registry.Report(checkCtx, healthy, output); err != nil {
+		metrics.DiscoveryReports.WithLabelValues(a.driver, "failed").Inc()
+		logrus.WithError(err).WithField("driver", a.driver).Error("Failed to report to service discovery")
+		return
+	}
+	outcome := "healthy"
+	if !healthy {
+		outcome = "unhealthy"
+	}
+	metrics.DiscoveryReports.WithLabelValues(a.driver, outcome).Inc()
+}
+
+func (a *Agent) deregister() {
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+
+	if err := a.registry.Deregister(ctx); err != nil {
+		logrus.WithError(err).WithField("driver", a.driver).Error("Failed to deregister from service discovery")
+		return
+	}
+	logrus.WithField("driver", a.driver).Info("Deregistered from service discovery")
+}
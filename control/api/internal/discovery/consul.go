@@ -0,0 +1,149 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// consulMinDeregisterAfter is the shortest DeregisterCriticalServiceAfter Consul honours
+const consulMinDeregisterAfter = time.Minute
+
+// Consul registers the gateway with the local Consul agent. Health is
+// reported through a TTL check, so an instance that stops reporting turns
+// critical and is eventually removed by Consul itself.
+type Consul struct {
+	client   *outbound.Client
+	addr     *url.URL
+	token    string
+	instance Instance
+	interval time.Duration
+
+	mu         sync.Mutex
+	registered bool
+}
+
+// NewConsul creates a Consul registry
+func NewConsul(config Config, instance Instance) (*Consul, error) {
+	if config.ConsulAddr == "" {
+		return nil, fmt.Errorf("consul discovery needs the agent address")
+	}
+	addr, err := url.Parse(config.ConsulAddr)
+	if err != nil || addr.Host == "" {
+		return nil, fmt.Errorf("invalid consul address %q", config.ConsulAddr)
+	}
+	return &Consul{
+		client:   outbound.New("discovery_consul", outbound.DefaultPolicy()),
+		addr:     addr,
+		token:    config.ConsulToken,
+		instance: instance,
+		interval: config.Interval,
+	}, nil
+}
+
+// checkID names the instance's TTL check
+func (r *Consul) checkID() string {
+	return "inscenium:" + r.instance.ID
+}
+
+// Report registers the service on first use, or after the agent lost it,
+// and updates its TTL check
+func (r *Consul) Report(ctx context.Context, healthy bool, output string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.registered {
+		if err := r.register(ctx); err != nil {
+			return err
+		}
+		r.registered = true
+	}
+
+	status := "passing"
+	if !healthy {
+		status = "critical"
+	}
+	err := r.put(ctx, "/v1/agent/check/update/"+url.PathEscape(r.checkID()), map[string]string{
+		"Status": status,
+		"Output": output,
+	})
+	if err != nil {
+		// The agent may have restarted without our registration
+		r.registered = false
+		return err
+	}
+	return nil
+}
+
+func (r *Consul) register(ctx context.Context) error {
+	tags := append([]string{"version:" + r.instance.Version}, r.instance.Tags...)
+	if r.instance.Region != "" {
+		tags = append(tags, "region:"+r.instance.Region)
+	}
+	deregisterAfter := max(10*r.interval, consulMinDeregisterAfter)
+
+	return r.put(ctx, "/v1/agent/service/register", map[string]interface{}{
+		"ID":      r.instance.ID,
+		"Name":    r.instance.Service,
+		"Address": r.instance.Address,
+		"Port":    r.instance.Port,
+		"Tags":    tags,
+		"Meta":    r.instance.Meta(),
+		"Check": map[string]interface{}{
+			"CheckID":                        r.checkID(),
+			"Name":                           "Gateway readiness",
+			"TTL":                            (3 * r.interval).String(),
+			"DeregisterCriticalServiceAfter": deregisterAfter.String(),
+		},
+	})
+}
+
+// Deregister removes the service and its check from the agent
+func (r *Consul) Deregister(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registered = false
+	return r.put(ctx, "/v1/agent/service/deregister/"+url.PathEscape(r.instance.ID), nil)
+}
+
+// put sends an agent API request
+func (r *Consul) put(ctx context.Context, path string, body interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	u := *r.addr
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.token != "" {
+		req.Header.Set("X-Consul-Token", r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("consul %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
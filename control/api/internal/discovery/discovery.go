@@ -0,0 +1,132 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Discovery drivers
+const (
+	DriverNone       = "none"
+	DriverConsul     = "consul"
+	DriverKubernetes = "kubernetes"
+)
+
+// DefaultServiceName is the name edge nodes and SDKs resolve the control plane by
+const DefaultServiceName = "inscenium-api-gateway"
+
+// Config selects and configures the service registry the gateway announces
+// itself to
+type Config struct {
+	Driver      string // none, consul or kubernetes
+	ServiceName string
+	// InstanceID identifies this gateway in the registry; the hostname (the
+	// pod name on Kubernetes) if empty
+	InstanceID string
+	// Address edge nodes connect to; the hostname's first address if empty
+	Address string
+	Port    int
+	Region  string
+	Version string
+	Tags    []string
+	// Interval between health reports; registrations of an instance that
+	// stops reporting expire after a few intervals
+	Interval time.Duration
+
+	// ConsulAddr is the local Consul agent, e.g. http://127.0.0.1:8500
+	ConsulAddr  string
+	ConsulToken string
+
+	// KubernetesNamespace holds the EndpointSlices; the pod's namespace if empty
+	KubernetesNamespace string
+	// KubernetesPodUID makes the pod own its EndpointSlice, so the slice is
+	// garbage collected with the pod even if the gateway never deregisters
+	KubernetesPodUID string
+}
+
+// Instance is a gateway as announced to a registry
+type Instance struct {
+	ID      string
+	Service string
+	Address string
+	Port    int
+	Region  string
+	Version string
+	Tags    []string
+}
+
+// Registry announces the gateway to a service discovery system
+type Registry interface {
+	// Report registers the instance if needed and records whether it is
+	// healthy; unhealthy instances stay registered but are not resolved
+	Report(ctx context.Context, healthy bool, output string) error
+	// Deregister removes the instance
+	Deregister(ctx context.Context) error
+}
+
+// New creates the registry selected by config, or nil if discovery is disabled
+func New(config Config) (Registry, error) {
+	if config.Driver == "" || config.Driver == DriverNone {
+		return nil, nil
+	}
+	instance, err := newInstance(config)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Driver {
+	case DriverConsul:
+		return NewConsul(config, instance)
+	case DriverKubernetes:
+		return NewKubernetes(config, instance)
+	default:
+		return nil, fmt.Errorf("unknown discovery driver %q", config.Driver)
+	}
+}
+
+// newInstance fills in the instance's identity from config and the host
+func newInstance(config Config) (Instance, error) {
+	if config.Port <= 0 {
+		return Instance{}, fmt.Errorf("discovery needs the gateway's port")
+	}
+	instance := Instance{
+		ID:      config.InstanceID,
+		Service: config.ServiceName,
+		Address: config.Address,
+		Port:    config.Port,
+		Region:  config.Region,
+		Version: config.Version,
+		Tags:    config.Tags,
+	}
+	if instance.Service == "" {
+		instance.Service = DefaultServiceName
+	}
+	if instance.ID == "" || instance.Address == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return Instance{}, fmt.Errorf("failed to get hostname: %w", err)
+		}
+		if instance.ID == "" {
+			instance.ID = hostname
+		}
+		if instance.Address == "" {
+			addrs, err := net.LookupHost(hostname)
+			if err != nil || len(addrs) == 0 {
+				return Instance{}, fmt.Errorf("failed to resolve %s, set the discovery address: %w", hostname, err)
+			}
+			instance.Address = addrs[0]
+		}
+	}
+	return instance, nil
+}
+
+// Meta is the metadata SDKs filter instances by
+func (i Instance) Meta() map[string]string {
+	meta := map[string]string{"version": i.Version}
+	if i.Region != "" {
+		meta["region"] = i.Region
+	}
+	return meta
+}
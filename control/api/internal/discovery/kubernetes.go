@@ -0,0 +1,217 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// serviceAccountDir holds the credentials Kubernetes mounts into every pod
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// errKubernetesNotFound is returned for requests on objects that do not exist
+var errKubernetesNotFound = errors.New("kubernetes object not found")
+
+// Labels on the EndpointSlices the gateway publishes
+const (
+	kubernetesServiceLabel   = "kubernetes.io/service-name"
+	kubernetesManagedByLabel = "endpointslice.kubernetes.io/managed-by"
+	kubernetesManager        = "inscenium.io/gateway"
+)
+
+// Kubernetes publishes the gateway as an EndpointSlice of a selector-less
+// Service named after the service, so in-cluster clients resolve it through
+// the Service's DNS name and out-of-cluster SDKs through the EndpointSlice
+// API. The endpoint's ready condition follows the health reports.
+type Kubernetes struct {
+	client    *outbound.Client
+	apiServer string
+	namespace string
+	tokenFile string
+	podUID    string
+	instance  Instance
+}
+
+// NewKubernetes creates a Kubernetes registry using the pod's service account
+func NewKubernetes(config Config, instance Instance) (*Kubernetes, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("kubernetes discovery must run inside a cluster")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("cluster CA contains no certificates")
+	}
+	namespace := config.KubernetesNamespace
+	if namespace == "" {
+		raw, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pod namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(raw))
+	}
+
+	client := outbound.New("discovery_kubernetes", outbound.DefaultPolicy())
+	client.SetTransport(&http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}})
+	return &Kubernetes{
+		client:    client,
+		apiServer: "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		tokenFile: serviceAccountDir + "/token",
+		podUID:    config.KubernetesPodUID,
+		instance:  instance,
+	}, nil
+}
+
+// sliceName names the instance's EndpointSlice
+func (r *Kubernetes) sliceName() string {
+	return dnsName(r.instance.Service + "-" + r.instance.ID)
+}
+
+// slicePath addresses the instance's EndpointSlice
+func (r *Kubernetes) slicePath() string {
+	return "/apis/discovery.k8s.io/v1/namespaces/" + url.PathEscape(r.namespace) +
+		"/endpointslices/" + url.PathEscape(r.sliceName())
+}
+
+// Report applies the instance's EndpointSlice with server-side apply, which
+// creates it on first use
+func (r *Kubernetes) Report(ctx context.Context, healthy bool, output string) error {
+	body, err := json.Marshal(r.endpointSlice(healthy))
+	if err != nil {
+		return err
+	}
+	query := url.Values{"fieldManager": {"inscenium-gateway"}, "force": {"true"}}
+	return r.do(outbound.Idempotent(ctx), http.MethodPatch, r.slicePath()+"?"+query.Encode(), body)
+}
+
+// Deregister deletes the instance's EndpointSlice
+func (r *Kubernetes) Deregister(ctx context.Context) error {
+	err := r.do(ctx, http.MethodDelete, r.slicePath(), nil)
+	if errors.Is(err, errKubernetesNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *Kubernetes) endpointSlice(healthy bool) map[string]interface{} {
+	labels := map[string]string{
+		kubernetesServiceLabel:   r.instance.Service,
+		kubernetesManagedByLabel: kubernetesManager,
+		"inscenium.io/version":   labelValue(r.instance.Version),
+	}
+	if r.instance.Region != "" {
+		labels["inscenium.io/region"] = labelValue(r.instance.Region)
+	}
+	metadata := map[string]interface{}{
+		"name":        r.sliceName(),
+		"namespace":   r.namespace,
+		"labels":      labels,
+		"annotations": map[string]string{"inscenium.io/tags": strings.Join(r.instance.Tags, ",")},
+	}
+	if r.podUID != "" {
+		metadata["ownerReferences"] = []map[string]interface{}{{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"name":       r.instance.ID,
+			"uid":        r.podUID,
+		}}
+	}
+
+	addressType := "FQDN"
+	if ip := net.ParseIP(r.instance.Address); ip != nil {
+		addressType = "IPv6"
+		if ip.To4() != nil {
+			addressType = "IPv4"
+		}
+	}
+	return map[string]interface{}{
+		"apiVersion":  "discovery.k8s.io/v1",
+		"kind":        "EndpointSlice",
+		"metadata":    metadata,
+		"addressType": addressType,
+		"endpoints": []map[string]interface{}{{
+			"addresses":  []string{r.instance.Address},
+			"conditions": map[string]bool{"ready": healthy, "serving": healthy, "terminating": false},
+		}},
+		"ports": []map[string]interface{}{{
+			"name":     "http",
+			"port":     r.instance.Port,
+			"protocol": "TCP",
+		}},
+	}
+}
+
+// do sends an API server request authenticated with the service account
+// token, which is reread every time because Kubernetes rotates it
+func (r *Kubernetes) do(ctx context.Context, method, path string, body []byte) error {
+	token, err := os.ReadFile(r.tokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.apiServer+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.GetBody = nil
+		req.ContentLength = 0
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/apply-patch+yaml")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errKubernetesNotFound
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kubernetes %s %s returned %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+var (
+	invalidDNSChars   = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// dnsName turns s into a valid object name
+func dnsName(s string) string {
+	name := strings.Trim(invalidDNSChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+// labelValue turns s into a valid label value, e.g. "1.4.0+a1b2" into "1.4.0_a1b2"
+func labelValue(s string) string {
+	value := invalidLabelChars.ReplaceAllString(s, "_")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "._-")
+}
@@ -0,0 +1,185 @@
+// Package eventbus lets modules react to domain changes, such as a booking
+// changing status or surfaces being ingested, without the code making the
+// change calling each of them. Repositories publish typed events once their
+// transaction commits; subscribers handle them either synchronously, on the
+// publisher's goroutine, or asynchronously from a buffered queue of their own.
+//
+// The bus is in-process only: events are not persisted, and subscribers in
+// other gateway replicas do not see them.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBuffer is the queue length of asynchronous subscribers that set none
+const DefaultBuffer = 1000
+
+// Event is a domain event. Its name identifies the type to subscribers.
+type Event interface {
+	EventName() string
+}
+
+// subscription is one subscriber's registration for one event type
+type subscription struct {
+	subscriber string
+	handle     func(ctx context.Context, event Event) error
+	queue      chan Event // Nil for synchronous subscribers
+}
+
+// Bus delivers published events to the subscribers of their type
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[string][]*subscription
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// New creates an empty bus
+func New() *Bus {
+	return &Bus{subs: make(map[string][]*subscription)}
+}
+
+// Subscribe registers fn to handle every event of type E on the publisher's
+// goroutine, before Publish returns. Synchronous subscribers must be quick
+// and must not publish events themselves; their errors are logged and do
+// not affect the publisher.
+func Subscribe[E Event](b *Bus, subscriber string, fn func(ctx context.Context, event E) error) {
+	b.add(&subscription{subscriber: subscriber, handle: typed(fn)}, eventName[E]())
+}
+
+// SubscribeAsync registers fn to handle every event of type E on a goroutine
+// of its own, in publication order. Events published while buffer events
+// are already waiting are dropped rather than slowing the publisher.
+func SubscribeAsync[E Event](b *Bus, subscriber string, buffer int, fn func(ctx context.Context, event E) error) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	sub := &subscription{subscriber: subscriber, handle: typed(fn), queue: make(chan Event, buffer)}
+	if b.add(sub, eventName[E]()) {
+		b.wg.Add(1)
+		go b.drain(sub)
+	}
+}
+
+// Publish hands an event to its subscribers. It never fails: the change the
+// event describes has already happened.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	name := event.EventName()
+	metrics.DomainEventsPublished.WithLabelValues(name).Inc()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs[name] {
+		if sub.queue == nil {
+			deliver(ctx, sub, event)
+			continue
+		}
+		select {
+		case sub.queue <- event:
+			metrics.DomainEventQueueDepth.WithLabelValues(sub.subscriber).Set(float64(len(sub.queue)))
+		default:
+			metrics.DomainEventDeliveries.WithLabelValues(sub.subscriber, name, "dropped").Inc()
+			logrus.WithFields(logrus.Fields{
+				"subscriber": sub.subscriber,
+				"event":      name,
+			}).Warn("Event subscriber queue full, dropping event")
+		}
+	}
+}
+
+// Close stops accepting events and waits until asynchronous subscribers
+// have handled the events already queued for them
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for _, sub := range subs {
+			if sub.queue != nil {
+				close(sub.queue)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// add registers a subscription unless the bus is closed
+func (b *Bus) add(sub *subscription, name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.subs[name] = append(b.subs[name], sub)
+	return true
+}
+
+// drain handles an asynchronous subscriber's queue until the bus closes
+func (b *Bus) drain(sub *subscription) {
+	defer b.wg.Done()
+	for event := range sub.queue {
+		metrics.DomainEventQueueDepth.WithLabelValues(sub.subscriber).Set(float64(len(sub.queue)))
+		deliver(context.Background(), sub, event)
+	}
+}
+
+// deliver runs one subscriber on one event, recording the outcome. A
+// panicking subscriber counts as failed.
+func deliver(ctx context.Context, sub *subscription, event Event) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("subscriber panicked: %v", r)
+			}
+		}()
+		return sub.handle(ctx, event)
+	}()
+	metrics.DomainEventDeliverySeconds.WithLabelValues(sub.subscriber).Observe(time.Since(start).Seconds())
+
+	outcome := "delivered"
+	if err != nil {
+		outcome = "failed"
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"subscriber": sub.subscriber,
+			"event":      event.EventName(),
+		}).Error("Event subscriber failed")
+	}
+	metrics.DomainEventDeliveries.WithLabelValues(sub.subscriber, event.EventName(), outcome).Inc()
+}
+
+// typed adapts a subscriber of one event type to the bus. Subscriptions are
+// keyed by event name, so the assertion only fails if two types share one.
+func typed[E Event](fn func(ctx context.Context, event E) error) func(ctx context.Context, event Event) error {
+	return func(ctx context.Context, event Event) error {
+		e, ok := event.(E)
+		if !ok {
+			return fmt.Errorf("unexpected event type %T", event)
+		}
+		return fn(ctx, e)
+	}
+}
+
+// eventName names the event type E
+func eventName[E Event]() string {
+	var zero E
+	return zero.EventName()
+}
@@ -0,0 +1,89 @@
+package eventbus
+
+import "time"
+
+// Event names
+const (
+	NameBookingChanged   = "booking.changed"
+	NameSurfacesIngested = "surfaces.ingested"
+	NameSurfacesMerged   = "surfaces.merged"
+	NameDeliveryReached  = "delivery.reached"
+	NameKeyExpiring      = "service_account_key.expiring"
+)
+
+// BookingChanged is published when an event is appended to a booking's
+// stream: the booking was created, changed status or had its terms amended
+type BookingChanged struct {
+	BookingID  string    `json:"booking_id"`
+	CampaignID string    `json:"campaign_id,omitempty"`
+	Type       string    `json:"type"`             // The booking event type, such as created or cancelled
+	Status     string    `json:"status,omitempty"` // Status after the change, when known
+	Actor      string    `json:"actor,omitempty"`
+	OrgID      string    `json:"org_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EventName implements Event
+func (BookingChanged) EventName() string { return NameBookingChanged }
+
+// SurfacesIngested is published when a batch of shots and surfaces of a
+// title is stored
+type SurfacesIngested struct {
+	TitleID    string `json:"title_id"`
+	Shots      int    `json:"shots"`
+	Surfaces   int    `json:"surfaces"`
+	Duplicates int    `json:"duplicates"` // Surfaces reported as likely duplicates
+}
+
+// EventName implements Event
+func (SurfacesIngested) EventName() string { return NameSurfacesIngested }
+
+// SurfacesMerged is published when duplicate surfaces are folded into a
+// canonical one. The moved bookings are also published as BookingChanged.
+type SurfacesMerged struct {
+	IntoSurfaceID string    `json:"into_surface_id"`
+	Merged        []string  `json:"merged"`
+	BookingsMoved []string  `json:"bookings_moved"`
+	Actor         string    `json:"actor,omitempty"`
+	MergedAt      time.Time `json:"merged_at"`
+}
+
+// EventName implements Event
+func (SurfacesMerged) EventName() string { return NameSurfacesMerged }
+
+// DeliveryReached is published when a counted exposure takes a booking's
+// delivered impressions to a pacing threshold, a share of its
+// max_impressions, or to a milestone count. See pacing.ThresholdReached and
+// pacing.Milestone.
+type DeliveryReached struct {
+	BookingID        string    `json:"booking_id"`
+	CampaignID       string    `json:"campaign_id,omitempty"`
+	OrgID            string    `json:"org_id,omitempty"`
+	Delivered        int64     `json:"delivered"`
+	MaxImpressions   int64     `json:"max_impressions,omitempty"`   // Zero for uncapped bookings
+	ThresholdPercent int       `json:"threshold_percent,omitempty"` // Set when a pacing threshold was reached
+	Milestone        bool      `json:"milestone,omitempty"`         // Set when delivered is a milestone count
+	Pacing           string    `json:"pacing"`
+	Target           int64     `json:"target"` // Impressions the booking's pacing allowed by now, -1 without a target
+	OccurredAt       time.Time `json:"occurred_at"`
+}
+
+// EventName implements Event
+func (DeliveryReached) EventName() string { return NameDeliveryReached }
+
+// KeyExpiring is published once for each rotated service-account key, some
+// time before its overlap with the key replacing it ends
+type KeyExpiring struct {
+	AccountID   string     `json:"account_id"`
+	AccountName string     `json:"account_name"`
+	OrgID       string     `json:"org_id,omitempty"`
+	KeyID       string     `json:"key_id"`
+	Version     int        `json:"version"`
+	ReplacedBy  string     `json:"replaced_by,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"` // When the key was last used, to tell whether clients moved over
+	ExpiresAt   time.Time  `json:"expires_at"`
+	OccurredAt  time.Time  `json:"occurred_at"`
+}
+
+// EventName implements Event
+func (KeyExpiring) EventName() string { return NameKeyExpiring }
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvWriter writes a header line and one line per row. Times are RFC 3339
+// in UTC and nulls are empty fields.
+type csvWriter[R any] struct {
+	schema []column[R]
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+func newCSVWriter[R any](w io.Writer, schema []column[R]) *csvWriter[R] {
+	return &csvWriter[R]{schema: schema, w: csv.NewWriter(w), record: make([]string, len(schema))}
+}
+
+func (c *csvWriter[R]) writeHeader() error {
+	c.header = true
+	for i, col := range c.schema {
+		c.record[i] = col.name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter[R]) Write(row *R) error {
+	if !c.header {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	for i, col := range c.schema {
+		v := col.get(row)
+		switch {
+		case v.null:
+			c.record[i] = ""
+		case col.kind == kindTime:
+			c.record[i] = v.at.UTC().Format(time.RFC3339Nano)
+		case col.kind == kindFloat:
+			c.record[i] = strconv.FormatFloat(v.num, 'f', -1, 64)
+		case col.kind == kindBool:
+			c.record[i] = strconv.FormatBool(v.flag)
+		default:
+			c.record[i] = v.str
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+// Close writes the header of an empty export and flushes
+func (c *csvWriter[R]) Close() error {
+	if !c.header {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
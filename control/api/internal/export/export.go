@@ -0,0 +1,201 @@
+// Package export writes raw exposure events out as CSV or Parquet for
+// analysts, and daily log-level files of decisions and impressions for
+// advertisers' auditors. Events are written as they are read, so an export
+// streams without holding the booking's events in memory: CSV row by row,
+// Parquet a row group at a time.
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// Supported formats
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Query selects the exposure events of a booking whose event times fall
+// within a range, limited to the organizations of the requester's scope
+type Query struct {
+	BookingID string       `json:"booking_id"`
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	Tenant    tenant.Scope `json:"tenant"`
+}
+
+// Validate checks the query's range
+func (q Query) Validate() error {
+	if q.BookingID == "" {
+		return fmt.Errorf("booking_id is required")
+	}
+	if !q.To.After(q.From) {
+		return fmt.Errorf("to must be after from")
+	}
+	return nil
+}
+
+// Row is an exported exposure event. Viewer IDs are exported decrypted;
+// consent strings and spend never are.
+type Row struct {
+	EventID          string
+	BookingID        string
+	ViewerID         string
+	SessionID        *string
+	ClientEventID    *string
+	EventTimestamp   time.Time
+	DeviceTimestamp  *time.Time
+	ReceivedAt       *time.Time
+	ExposureDuration float64
+	ScreenCoverage   *float64
+	AttentionScore   *float64
+	ListenThrough    *float64
+	InstantaneousPRS *float64
+	DeviceType       *string
+	Counted          bool
+}
+
+// Source streams the events of a query in event time order, calling fn
+// for each until it returns an error, and finds the export policy of the
+// organization owning them
+type Source interface {
+	StreamExposureEvents(q Query, fn func(row *Row) error) error
+	// ExportPolicy returns the policy of the booking's organization, or nil
+	ExportPolicy(q Query) (*Policy, error)
+}
+
+// recordWriter encodes records in an export format. Close writes any
+// buffered rows and the format's trailer; it does not close the underlying
+// writer.
+type recordWriter[R any] interface {
+	Write(r *R) error
+	Close() error
+}
+
+// newRecordWriter creates a writer of format onto w with the columns of
+// schema that policy allows. Every export file is written through it, so
+// each format and export path applies the policy of the data's owner.
+func newRecordWriter[R any](format string, w io.Writer, schema []column[R], policy *Policy) (recordWriter[R], error) {
+	schema = restrict(schema, policy)
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, schema), nil
+	case FormatParquet:
+		return newParquetWriter(w, schema), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (use csv or parquet)", format)
+	}
+}
+
+// Write exports the events of q from source onto w, returning how many
+// rows it wrote
+func Write(w io.Writer, format string, source Source, q Query) (int64, error) {
+	policy, err := source.ExportPolicy(q)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := newRecordWriter(format, w, columns, policy)
+	if err != nil {
+		return 0, err
+	}
+	var rows int64
+	err = source.StreamExposureEvents(q, func(row *Row) error {
+		rows++
+		return writer.Write(row)
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// ContentType returns the media type of format
+func ContentType(format string) string {
+	if format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Filename names the export of a booking's events
+func Filename(bookingID, format string) string {
+	return "exposure_events_" + bookingID + "." + format
+}
+
+// kind is the type of an exported column
+type kind int
+
+const (
+	kindString kind = iota
+	kindTime
+	kindFloat
+	kindBool
+)
+
+// cell is one column's value in a row
+type cell struct {
+	str  string
+	at   time.Time
+	num  float64
+	flag bool
+	null bool
+}
+
+// column is an exported column of records of type R, in the order
+// columns are written
+type column[R any] struct {
+	name     string
+	kind     kind
+	optional bool
+	get      func(r *R) cell
+}
+
+var columns = []column[Row]{
+	{"event_id", kindString, false, func(r *Row) cell { return cell{str: r.EventID} }},
+	{"booking_id", kindString, false, func(r *Row) cell { return cell{str: r.BookingID} }},
+	{"viewer_id", kindString, false, func(r *Row) cell { return cell{str: r.ViewerID} }},
+	{"session_id", kindString, true, func(r *Row) cell { return optionalString(r.SessionID) }},
+	{"client_event_id", kindString, true, func(r *Row) cell { return optionalString(r.ClientEventID) }},
+	{"event_timestamp", kindTime, false, func(r *Row) cell { return cell{at: r.EventTimestamp} }},
+	{"device_event_timestamp", kindTime, true, func(r *Row) cell { return optionalTime(r.DeviceTimestamp) }},
+	{"received_at", kindTime, true, func(r *Row) cell { return optionalTime(r.ReceivedAt) }},
+	{"exposure_duration", kindFloat, false, func(r *Row) cell { return cell{num: r.ExposureDuration} }},
+	{"screen_coverage", kindFloat, true, func(r *Row) cell { return optionalFloat(r.ScreenCoverage) }},
+	{"attention_score", kindFloat, true, func(r *Row) cell { return optionalFloat(r.AttentionScore) }},
+	{"listen_through", kindFloat, true, func(r *Row) cell { return optionalFloat(r.ListenThrough) }},
+	{"instantaneous_prs", kindFloat, true, func(r *Row) cell { return optionalFloat(r.InstantaneousPRS) }},
+	{"device_type", kindString, true, func(r *Row) cell { return optionalString(r.DeviceType) }},
+	{"counted", kindBool, false, func(r *Row) cell { return cell{flag: r.Counted} }},
+}
+
+func optionalString(s *string) cell {
+	if s == nil {
+		return cell{null: true}
+	}
+	return cell{str: *s}
+}
+
+func optionalTime(t *time.Time) cell {
+	if t == nil {
+		return cell{null: true}
+	}
+	return cell{at: *t}
+}
+
+func optionalFloat(f *float64) cell {
+	if f == nil {
+		return cell{null: true}
+	}
+	return cell{num: *f}
+}
+
+func optionalBool(b *bool) cell {
+	if b == nil {
+		return cell{null: true}
+	}
+	return cell{flag: *b}
+}
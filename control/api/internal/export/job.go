@@ -0,0 +1,127 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue is the background job queue that writes async exports to object storage
+const Queue = "event_export"
+
+// Async export statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Export is an async export of a booking's events
+type Export struct {
+	ID          string     `json:"export_id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	Query       Query      `json:"query"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	RowCount    int64      `json:"row_count"`
+	Size        int64      `json:"size_bytes"`
+	ObjectKey   string     `json:"-"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ObjectKey is the object storage key of an export's file
+func ObjectKey(exportID, format string) string {
+	return "exports/" + exportID + "." + format
+}
+
+// Job is the payload of an export job
+type Job struct {
+	ExportID string `json:"export_id"`
+}
+
+// Store persists async exports
+type Store interface {
+	Source
+	GetEventExport(exportID string) (*Export, error)
+	StartEventExport(exportID string) error
+	CompleteEventExport(exportID, objectKey string, rows, size int64) error
+	FailEventExport(exportID, message string) error
+}
+
+// NewJobHandler returns a job handler that writes an export to a temporary
+// file and uploads it to objects. A failed export is recorded rather than
+// retried, since it would fail again for the same events.
+func NewJobHandler(store Store, objects storage.Store) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload Job
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		e, err := store.GetEventExport(payload.ExportID)
+		if err != nil {
+			return err
+		}
+		if e == nil || e.Status == StatusCompleted || e.Status == StatusFailed {
+			return nil // Deleted, or a previous attempt finished before its lease expired
+		}
+		if err := store.StartEventExport(e.ID); err != nil {
+			return err
+		}
+
+		logger := logrus.WithFields(logrus.Fields{
+			"export_id":  e.ID,
+			"booking_id": e.Query.BookingID,
+			"format":     e.Format,
+		})
+		started := time.Now()
+		key := ObjectKey(e.ID, e.Format)
+		rows, size, err := writeObject(ctx, store, objects, e, key)
+		if err != nil {
+			logger.WithError(err).Error("Async event export failed")
+			return store.FailEventExport(e.ID, err.Error())
+		}
+		if err := store.CompleteEventExport(e.ID, key, rows, size); err != nil {
+			return err
+		}
+		metrics.EventExportRows.WithLabelValues(e.Format, "async").Add(float64(rows))
+
+		logger.WithFields(logrus.Fields{
+			"rows":     rows,
+			"bytes":    size,
+			"duration": time.Since(started),
+		}).Info("Exported exposure events")
+		return nil
+	}
+}
+
+// writeObject exports e's events into a temporary file and uploads it
+func writeObject(ctx context.Context, source Source, objects storage.Store, e *Export, key string) (int64, int64, error) {
+	f, err := os.CreateTemp("", "export-*."+e.Format)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	rows, err := Write(f, e.Format, source, e.Query)
+	if err != nil {
+		return 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to size export file: %w", err)
+	}
+	if err := storage.Upload(ctx, objects, key, f, info.Size(), ContentType(e.Format)); err != nil {
+		return 0, 0, err
+	}
+	return rows, info.Size(), nil
+}
@@ -0,0 +1,369 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// LogQueue is the background job queue that writes daily log-level files
+const LogQueue = "log_level_export"
+
+// LogSchemaVersion is the version of the log-level columns. It changes
+// whenever a column is renamed, retyped or removed; new columns are only
+// ever appended.
+const LogSchemaVersion = "1"
+
+// LogDateLayout formats the UTC day of a log-level file
+const LogDateLayout = "2006-01-02"
+
+// Log-level record types
+const (
+	RecordDecision   = "decision"
+	RecordImpression = "impression"
+)
+
+// LogSettleDelay is how long after a UTC day ends its log-level files are
+// written, so late exposure beacons and resends make it into them
+const LogSettleDelay = 2 * time.Hour
+
+// LogBackfillDays is how many days back the scheduler looks for files that
+// were never written, or failed, for instance while no gateway was running
+const LogBackfillDays = 7
+
+// LogRecord is one row of a log-level file: a served decision or an
+// impression of one of the advertiser's bookings. Viewer IDs, sessions and
+// consent strings are never included.
+type LogRecord struct {
+	RecordType       string
+	EventID          string
+	EventTime        time.Time
+	AdvertiserID     string
+	CampaignID       string
+	BookingID        string
+	CreativeID       *string
+	SurfaceID        string
+	TitleID          *string
+	BillingModel     string
+	Outcome          *string
+	ErrorCode        *string
+	DeviceType       *string
+	ExposureDuration *float64
+	ScreenCoverage   *float64
+	AttentionScore   *float64
+	ListenThrough    *float64
+	Counted          *bool
+	Spend            float64
+}
+
+// LogQuery selects an advertiser's records whose event times fall on a UTC day
+type LogQuery struct {
+	AdvertiserID string
+	Day          time.Time
+}
+
+// Range returns the bounds of the query's day
+func (q LogQuery) Range() (time.Time, time.Time) {
+	from := LogDay(q.Day)
+	return from, from.Add(24 * time.Hour)
+}
+
+// LogDay truncates t to the start of its UTC day
+func LogDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// LogSource streams the records of a query in event time order, calling fn
+// for each until it returns an error, and finds the export policy of the
+// organization owning them
+type LogSource interface {
+	StreamLogRecords(q LogQuery, fn func(r *LogRecord) error) error
+	// LogExportPolicy returns the policy of the advertiser's organization, or nil
+	LogExportPolicy(q LogQuery) (*Policy, error)
+}
+
+// WriteLog writes the records of q from source onto w, returning how many
+// rows it wrote
+func WriteLog(w io.Writer, format string, source LogSource, q LogQuery) (int64, error) {
+	policy, err := source.LogExportPolicy(q)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := newRecordWriter(format, w, logColumns, policy)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	err = source.StreamLogRecords(q, func(r *LogRecord) error {
+		rows++
+		return writer.Write(r)
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// LogObjectKey is the object storage key of an advertiser's file for a day
+func LogObjectKey(advertiserID string, day time.Time, format string) string {
+	return "log-level/" + advertiserID + "/" + day.UTC().Format(LogDateLayout) + "." + format
+}
+
+// LogFilename names an advertiser's file for a day
+func LogFilename(advertiserID string, day time.Time, format string) string {
+	return "log_level_" + advertiserID + "_" + day.UTC().Format(LogDateLayout) + "." + format
+}
+
+var logColumns = []column[LogRecord]{
+	{"record_type", kindString, false, func(r *LogRecord) cell { return cell{str: r.RecordType} }},
+	{"event_id", kindString, false, func(r *LogRecord) cell { return cell{str: r.EventID} }},
+	{"event_time", kindTime, false, func(r *LogRecord) cell { return cell{at: r.EventTime} }},
+	{"advertiser_id", kindString, false, func(r *LogRecord) cell { return cell{str: r.AdvertiserID} }},
+	{"campaign_id", kindString, false, func(r *LogRecord) cell { return cell{str: r.CampaignID} }},
+	{"booking_id", kindString, false, func(r *LogRecord) cell { return cell{str: r.BookingID} }},
+	{"creative_id", kindString, true, func(r *LogRecord) cell { return optionalString(r.CreativeID) }},
+	{"surface_id", kindString, false, func(r *LogRecord) cell { return cell{str: r.SurfaceID} }},
+	{"title_id", kindString, true, func(r *LogRecord) cell { return optionalString(r.TitleID) }},
+	{"billing_model", kindString, false, func(r *LogRecord) cell { return cell{str: r.BillingModel} }},
+	{"outcome", kindString, true, func(r *LogRecord) cell { return optionalString(r.Outcome) }},
+	{"error_code", kindString, true, func(r *LogRecord) cell { return optionalString(r.ErrorCode) }},
+	{"device_type", kindString, true, func(r *LogRecord) cell { return optionalString(r.DeviceType) }},
+	{"exposure_seconds", kindFloat, true, func(r *LogRecord) cell { return optionalFloat(r.ExposureDuration) }},
+	{"screen_coverage", kindFloat, true, func(r *LogRecord) cell { return optionalFloat(r.ScreenCoverage) }},
+	{"attention_score", kindFloat, true, func(r *LogRecord) cell { return optionalFloat(r.AttentionScore) }},
+	{"listen_through", kindFloat, true, func(r *LogRecord) cell { return optionalFloat(r.ListenThrough) }},
+	{"counted", kindBool, true, func(r *LogRecord) cell { return optionalBool(r.Counted) }},
+	{"spend", kindFloat, false, func(r *LogRecord) cell { return cell{num: r.Spend} }},
+}
+
+// logDescriptions document the log-level columns
+var logDescriptions = map[string]string{
+	"record_type":      "decision for a served placement decision, impression for an exposure beacon",
+	"event_id":         "Unique ID of the decision or impression",
+	"event_time":       "When the event happened, corrected for device clock skew, in UTC",
+	"advertiser_id":    "Advertiser the booking was made for",
+	"campaign_id":      "Campaign of the booking",
+	"booking_id":       "Booking that was served or exposed",
+	"creative_id":      "Creative asset of the booking",
+	"surface_id":       "Placement surface within the title",
+	"title_id":         "Title the surface appears in",
+	"billing_model":    "How the booking is charged: cpm, attention_cpm or vcpm",
+	"outcome":          "Outcome of a decision; empty for impressions",
+	"error_code":       "Why a decision failed; empty otherwise",
+	"device_type":      "Device the impression was seen on, e.g. mobile, desktop or tv",
+	"exposure_seconds": "Seconds the placement was on screen or heard",
+	"screen_coverage":  "Percentage of the screen the placement covered; empty for audio",
+	"attention_score":  "Attention from 0 to 1, where the player measures it",
+	"listen_through":   "Share of an audio slot heard, from 0 to 1; empty for visual placements",
+	"counted":          "Whether the impression was delivered and charged; false past a booking's impression cap or a campaign's budget",
+	"spend":            "What the event cost: decisions carry CPM spend, impressions attention and viewable spend",
+}
+
+// logTypes name column kinds in the schema documentation
+var logTypes = map[kind]string{
+	kindString: "string",
+	kindTime:   "timestamp",
+	kindFloat:  "double",
+	kindBool:   "boolean",
+}
+
+// LogColumn documents one column of log-level files
+type LogColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Nullable    bool   `json:"nullable"`
+	Description string `json:"description"`
+}
+
+// LogSchema documents the columns of log-level files in the order they are
+// written. Timestamps are RFC 3339 in CSV and microsecond timestamps in
+// Parquet; empty CSV fields are nulls.
+func LogSchema() []LogColumn {
+	schema := make([]LogColumn, len(logColumns))
+	for i, col := range logColumns {
+		schema[i] = LogColumn{
+			Name:        col.name,
+			Type:        logTypes[col.kind],
+			Nullable:    col.optional,
+			Description: logDescriptions[col.name],
+		}
+	}
+	return schema
+}
+
+// LogExport is an advertiser's log-level file for a UTC day
+type LogExport struct {
+	AdvertiserID string     `json:"advertiser_id"`
+	Day          time.Time  `json:"-"`
+	Date         string     `json:"date"`
+	Format       string     `json:"format"`
+	Status       string     `json:"status"`
+	RowCount     int64      `json:"row_count"`
+	Size         int64      `json:"size_bytes"`
+	ObjectKey    string     `json:"-"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// LogJob is the payload of a log-level export job
+type LogJob struct {
+	AdvertiserID string `json:"advertiser_id"`
+	Date         string `json:"date"`
+}
+
+// LogStore persists log-level files
+type LogStore interface {
+	LogSource
+	ClaimLogExports(day time.Time, format string) ([]*LogExport, error)
+	GetLogExport(advertiserID string, day time.Time) (*LogExport, error)
+	StartLogExport(advertiserID string, day time.Time) error
+	CompleteLogExport(advertiserID string, day time.Time, objectKey string, rows, size int64) error
+	FailLogExport(advertiserID string, day time.Time, message string) error
+}
+
+// LogScheduler queues the log-level files of every advertiser with
+// decisions or impressions on each settled day. Files are claimed in
+// Postgres, so however many gateways run the scheduler each is written once.
+type LogScheduler struct {
+	store    LogStore
+	jobs     jobqueue.Queue
+	interval time.Duration
+	format   string
+}
+
+// NewLogScheduler creates a scheduler writing files in format
+func NewLogScheduler(store LogStore, jobs jobqueue.Queue, interval time.Duration, format string) *LogScheduler {
+	return &LogScheduler{store: store, jobs: jobs, interval: interval, format: format}
+}
+
+// Run schedules immediately and then every interval until ctx is cancelled
+func (s *LogScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.schedule(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedule claims and queues the unwritten files of the settled days up to
+// LogBackfillDays back
+func (s *LogScheduler) schedule(ctx context.Context, now time.Time) {
+	latest := LogDay(now.Add(-LogSettleDelay)).AddDate(0, 0, -1)
+	for i := 0; i < LogBackfillDays; i++ {
+		day := latest.AddDate(0, 0, -i)
+		claimed, err := s.store.ClaimLogExports(day, s.format)
+		if err != nil {
+			logrus.WithError(err).WithField("date", day.Format(LogDateLayout)).Error("Failed to claim log-level exports")
+			return
+		}
+		for _, e := range claimed {
+			job := LogJob{AdvertiserID: e.AdvertiserID, Date: e.Date}
+			if _, err := s.jobs.Enqueue(ctx, LogQueue, job, jobqueue.EnqueueOptions{}); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"advertiser_id": e.AdvertiserID,
+					"date":          e.Date,
+				}).Error("Failed to queue log-level export")
+				// Failed files are claimed again on the next run
+				if err := s.store.FailLogExport(e.AdvertiserID, day, "failed to queue export"); err != nil {
+					logrus.WithError(err).Error("Failed to mark log-level export failed")
+				}
+			}
+		}
+		if len(claimed) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"date":        day.Format(LogDateLayout),
+				"advertisers": len(claimed),
+			}).Info("Queued log-level exports")
+		}
+	}
+}
+
+// NewLogJobHandler returns a job handler that writes an advertiser's file
+// for a day to a temporary file and uploads it to objects. A failed file
+// is recorded and claimed again by the scheduler's next run.
+func NewLogJobHandler(store LogStore, objects storage.Store) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload LogJob
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		day, err := time.Parse(LogDateLayout, payload.Date)
+		if err != nil {
+			return fmt.Errorf("invalid log-level export date %q: %w", payload.Date, err)
+		}
+		e, err := store.GetLogExport(payload.AdvertiserID, day)
+		if err != nil {
+			return err
+		}
+		if e == nil || e.Status == StatusCompleted || e.Status == StatusFailed {
+			return nil // A previous attempt finished before its lease expired
+		}
+		if err := store.StartLogExport(e.AdvertiserID, day); err != nil {
+			return err
+		}
+
+		logger := logrus.WithFields(logrus.Fields{
+			"advertiser_id": e.AdvertiserID,
+			"date":          e.Date,
+			"format":        e.Format,
+		})
+		started := time.Now()
+		key := LogObjectKey(e.AdvertiserID, day, e.Format)
+		rows, size, err := writeLogObject(ctx, store, objects, LogQuery{AdvertiserID: e.AdvertiserID, Day: day}, e.Format, key)
+		if err != nil {
+			logger.WithError(err).Error("Log-level export failed")
+			return store.FailLogExport(e.AdvertiserID, day, err.Error())
+		}
+		if err := store.CompleteLogExport(e.AdvertiserID, day, key, rows, size); err != nil {
+			return err
+		}
+		metrics.EventExportRows.WithLabelValues(e.Format, "log_level").Add(float64(rows))
+
+		logger.WithFields(logrus.Fields{
+			"rows":     rows,
+			"bytes":    size,
+			"duration": time.Since(started),
+		}).Info("Exported log-level data")
+		return nil
+	}
+}
+
+// writeLogObject writes the records of q into a temporary file and uploads it
+func writeLogObject(ctx context.Context, source LogSource, objects storage.Store, q LogQuery, format, key string) (int64, int64, error) {
+	f, err := os.CreateTemp("", "log-level-*."+format)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	rows, err := WriteLog(f, format, source, q)
+	if err != nil {
+		return 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to size export file: %w", err)
+	}
+	if err := storage.Upload(ctx, objects, key, f, info.Size(), ContentType(format)); err != nil {
+		return 0, 0, err
+	}
+	return rows, info.Size(), nil
+}
@@ -0,0 +1,401 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// RowGroupSize is the number of rows buffered before a Parquet row group is
+// written out
+const RowGroupSize = 50000
+
+// Parquet physical types, converted types, encodings and codecs used by the
+// writer, as numbered in parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	pageData = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// physicalTypes maps column kinds onto Parquet physical types
+var physicalTypes = map[kind]int32{
+	kindString: parquetByteArray,
+	kindTime:   parquetInt64,
+	kindFloat:  parquetDouble,
+	kindBool:   parquetBoolean,
+}
+
+// parquetWriter writes a Parquet file with one gzip-compressed, PLAIN
+// encoded data page per column per row group. Optional columns carry RLE
+// definition levels; the schema is flat, so there are no repetition levels.
+type parquetWriter[R any] struct {
+	schema  []column[R]
+	w       io.Writer
+	offset  int64
+	started bool
+	err     error
+
+	columns []*columnBuffer
+	rows    int
+	total   int64
+	groups  []rowGroupMeta
+}
+
+// columnBuffer holds a column's values for the row group being filled
+type columnBuffer struct {
+	values  bytes.Buffer
+	defined []bool
+	bits    byte // Pending boolean bits, least significant first
+	nbits   int
+}
+
+type columnChunkMeta struct {
+	offset       int64
+	values       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroupMeta struct {
+	rows    int64
+	size    int64
+	columns []columnChunkMeta
+}
+
+func newParquetWriter[R any](w io.Writer, schema []column[R]) *parquetWriter[R] {
+	p := &parquetWriter[R]{schema: schema, w: w, columns: make([]*columnBuffer, len(schema))}
+	for i := range p.columns {
+		p.columns[i] = &columnBuffer{}
+	}
+	return p
+}
+
+func (p *parquetWriter[R]) write(data []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	p.err = err
+}
+
+func (p *parquetWriter[R]) Write(row *R) error {
+	if !p.started {
+		p.started = true
+		p.write(parquetMagic)
+	}
+	for i, col := range p.schema {
+		buf := p.columns[i]
+		v := col.get(row)
+		if col.optional {
+			buf.defined = append(buf.defined, !v.null)
+			if v.null {
+				continue
+			}
+		}
+		switch col.kind {
+		case kindString:
+			binary.Write(&buf.values, binary.LittleEndian, uint32(len(v.str)))
+			buf.values.WriteString(v.str)
+		case kindTime:
+			binary.Write(&buf.values, binary.LittleEndian, v.at.UnixMicro())
+		case kindFloat:
+			binary.Write(&buf.values, binary.LittleEndian, math.Float64bits(v.num))
+		case kindBool:
+			if v.flag {
+				buf.bits |= 1 << buf.nbits
+			}
+			buf.nbits++
+			if buf.nbits == 8 {
+				buf.values.WriteByte(buf.bits)
+				buf.bits, buf.nbits = 0, 0
+			}
+		}
+	}
+	p.rows++
+	if p.rows == RowGroupSize {
+		p.flushRowGroup()
+	}
+	return p.err
+}
+
+// flushRowGroup writes the buffered rows as a row group
+func (p *parquetWriter[R]) flushRowGroup() {
+	group := rowGroupMeta{rows: int64(p.rows), columns: make([]columnChunkMeta, len(p.schema))}
+	for i, col := range p.schema {
+		buf := p.columns[i]
+		if buf.nbits > 0 {
+			buf.values.WriteByte(buf.bits)
+			buf.bits, buf.nbits = 0, 0
+		}
+
+		var page bytes.Buffer
+		if col.optional {
+			levels := encodeDefinitionLevels(buf.defined)
+			binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+			page.Write(levels)
+		}
+		page.Write(buf.values.Bytes())
+
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		zw.Write(page.Bytes())
+		if err := zw.Close(); err != nil && p.err == nil {
+			p.err = err
+		}
+
+		header := pageHeader(page.Len(), compressed.Len(), p.rows)
+		chunk := columnChunkMeta{
+			offset:       p.offset,
+			values:       int64(p.rows),
+			uncompressed: int64(len(header) + page.Len()),
+			compressed:   int64(len(header) + compressed.Len()),
+		}
+		p.write(header)
+		p.write(compressed.Bytes())
+
+		group.columns[i] = chunk
+		group.size += chunk.uncompressed
+		buf.values.Reset()
+		buf.defined = buf.defined[:0]
+	}
+	p.groups = append(p.groups, group)
+	p.total += int64(p.rows)
+	p.rows = 0
+}
+
+// Close writes the last row group and the file metadata
+func (p *parquetWriter[R]) Close() error {
+	if !p.started {
+		p.started = true
+		p.write(parquetMagic)
+	}
+	if p.rows > 0 {
+		p.flushRowGroup()
+	}
+	footer := p.fileMetadata()
+	p.write(footer)
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(footer)))
+	p.write(length)
+	p.write(parquetMagic)
+	if p.err != nil {
+		return fmt.Errorf("failed to write parquet export: %w", p.err)
+	}
+	return nil
+}
+
+// encodeDefinitionLevels encodes levels of bit width 1 as RLE runs of the
+// RLE/bit-packing hybrid
+func encodeDefinitionLevels(defined []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defined); {
+		run := 1
+		for i+run < len(defined) && defined[i+run] == defined[i] {
+			run++
+		}
+		out = binary.AppendUvarint(out, uint64(run)<<1)
+		if defined[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i += run
+	}
+	return out
+}
+
+// pageHeader encodes the PageHeader of a data page
+func pageHeader(uncompressed, compressed, values int) []byte {
+	var t compactWriter
+	t.i32(1, pageData)
+	t.i32(2, int32(uncompressed))
+	t.i32(3, int32(compressed))
+	t.beginStruct(5)
+	t.i32(1, int32(values))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.endStruct()
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// fileMetadata encodes the FileMetaData footer
+func (p *parquetWriter[R]) fileMetadata() []byte {
+	var t compactWriter
+	t.i32(1, 1)
+
+	t.list(2, compactStruct, len(p.schema)+1)
+	t.beginElement()
+	t.str(4, "schema")
+	t.i32(5, int32(len(p.schema)))
+	t.endStruct()
+	for _, col := range p.schema {
+		t.beginElement()
+		t.i32(1, physicalTypes[col.kind])
+		repetition := int32(repetitionRequired)
+		if col.optional {
+			repetition = repetitionOptional
+		}
+		t.i32(3, repetition)
+		t.str(4, col.name)
+		switch col.kind {
+		case kindString:
+			t.i32(6, convertedUTF8)
+		case kindTime:
+			t.i32(6, convertedTimestampMicros)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, p.total)
+
+	t.list(4, compactStruct, len(p.groups))
+	for _, group := range p.groups {
+		t.beginElement()
+		t.list(1, compactStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			col := p.schema[i]
+			t.beginElement()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, physicalTypes[col.kind])
+			if col.optional {
+				t.list(2, compactI32, 2)
+				t.elementI32(encodingPlain)
+				t.elementI32(encodingRLE)
+			} else {
+				t.list(2, compactI32, 1)
+				t.elementI32(encodingPlain)
+			}
+			t.list(3, compactBinary, 1)
+			t.elementStr(col.name)
+			t.i32(4, codecGzip)
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.uncompressed)
+			t.i64(7, chunk.compressed)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.rows)
+		t.endStruct()
+	}
+
+	t.str(6, "inscenium control api")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// Thrift compact protocol types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes the subset of the Thrift compact protocol the
+// Parquet footer and page headers need
+type compactWriter struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *compactWriter) uvarint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *compactWriter) varint(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *compactWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *compactWriter) i32(id int16, v int32) {
+	t.field(id, compactI32)
+	t.varint(int64(v))
+}
+
+func (t *compactWriter) i64(id int16, v int64) {
+	t.field(id, compactI64)
+	t.varint(v)
+}
+
+func (t *compactWriter) str(id int16, s string) {
+	t.field(id, compactBinary)
+	t.elementStr(s)
+}
+
+func (t *compactWriter) list(id int16, elem byte, size int) {
+	t.field(id, compactList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(size))
+	}
+}
+
+func (t *compactWriter) elementI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *compactWriter) elementStr(s string) {
+	t.uvarint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// beginStruct starts a struct field; beginElement a struct in a list
+func (t *compactWriter) beginStruct(id int16) {
+	t.field(id, compactStruct)
+	t.beginElement()
+}
+
+func (t *compactWriter) beginElement() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *compactWriter) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop ends the fields of a struct
+func (t *compactWriter) stop() {
+	t.buf.WriteByte(0)
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions an export policy takes on a restricted column
+const (
+	ActionStrip = "strip" // Leave the column out of the file
+	ActionHash  = "hash"  // Replace values with a keyed hash, the same for the same value within the organization
+)
+
+// DeviceColumns are the device-level columns data processing agreements
+// most often restrict
+var DeviceColumns = []string{"viewer_id", "session_id", "client_event_id", "device_event_timestamp", "device_type"}
+
+// Policy restricts the columns an organization's data is exported with, as
+// its data processing agreement requires. Every export of the
+// organization's events and log-level records applies it: writers are only
+// created through newRecordWriter, which takes the policy of the data's
+// owner from its source.
+type Policy struct {
+	OrgID     string            `json:"org_id"`
+	Columns   map[string]string `json:"columns"` // Column name to ActionStrip or ActionHash
+	HashKey   string            `json:"-"`       // Keys hashed values, so they cannot be looked up from known IDs
+	UpdatedBy string            `json:"updated_by,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Validate checks that the policy names exported columns and that only
+// string columns are hashed, so a column keeps its type
+func (p *Policy) Validate() error {
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("columns must restrict at least one column")
+	}
+	kinds := columnKinds()
+	for name, action := range p.Columns {
+		k, ok := kinds[name]
+		if !ok {
+			return fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(RestrictableColumns(), ", "))
+		}
+		switch action {
+		case ActionStrip:
+		case ActionHash:
+			if k != kindString {
+				return fmt.Errorf("column %q is not a string column and can only be stripped", name)
+			}
+		default:
+			return fmt.Errorf("unknown action %q for column %q, expected %s or %s", action, name, ActionStrip, ActionHash)
+		}
+	}
+	return nil
+}
+
+// RestrictableColumns lists the columns of every export, sorted
+func RestrictableColumns() []string {
+	kinds := columnKinds()
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// columnKinds maps the columns of every export to their kind. Exports
+// sharing a column name give it the same kind.
+func columnKinds() map[string]kind {
+	kinds := make(map[string]kind, len(columns)+len(logColumns))
+	for _, col := range columns {
+		kinds[col.name] = col.kind
+	}
+	for _, col := range logColumns {
+		kinds[col.name] = col.kind
+	}
+	return kinds
+}
+
+// NewHashKey generates the key of an organization's hashed columns
+func NewHashKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate export hash key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// restrict returns schema with the policy applied: stripped columns are
+// left out and hashed ones carry the hex HMAC-SHA256 of their values. Nulls
+// stay null. A nil policy leaves schema as it is.
+func restrict[R any](schema []column[R], p *Policy) []column[R] {
+	if p == nil || len(p.Columns) == 0 {
+		return schema
+	}
+	restricted := make([]column[R], 0, len(schema))
+	for _, col := range schema {
+		switch p.Columns[col.name] {
+		case ActionStrip:
+			continue
+		case ActionHash:
+			get, key := col.get, []byte(p.HashKey)
+			col.get = func(r *R) cell {
+				v := get(r)
+				if v.null {
+					return v
+				}
+				mac := hmac.New(sha256.New, key)
+				mac.Write([]byte(v.str))
+				return cell{str: hex.EncodeToString(mac.Sum(nil))}
+			}
+		}
+		restricted = append(restricted, col)
+	}
+	return restricted
+}
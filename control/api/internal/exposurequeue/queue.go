@@ -0,0 +1,120 @@
+// Package exposurequeue buffers batches of exposure events between the API
+// and the database, so bursts from edge devices are absorbed by a durable
+// queue and written at the pace its consumers keep rather than all at once.
+//
+// Queue is the seam for brokers such as Kafka or NATS: an implementation
+// publishes batches to a topic or subject and runs a consumer handing each
+// one to a Recorder. JobQueue, backed by the gateway's job queue on Postgres
+// or Redis, is the implementation bundled today.
+package exposurequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Backends
+const (
+	BackendNone     = "none"     // Batches are written while the request waits
+	BackendJobQueue = "jobqueue" // Batches are queued on the gateway's job queue
+)
+
+// QueueName is the job queue JobQueue publishes batches on
+const QueueName = "exposure_batches"
+
+// DefaultMaxAttempts is how many times a queued batch is written before its
+// events are written one by one and those still failing are dropped
+const DefaultMaxAttempts = 5
+
+// Batch is a validated batch of exposure events waiting to be recorded.
+// Timestamps are already corrected for device clock skew.
+type Batch struct {
+	ID         string                 `json:"batch_id"`
+	KeyID      string                 `json:"key_id,omitempty"` // API key that sent the batch, if any
+	Events     []models.ExposureEvent `json:"events"`
+	ReceivedAt time.Time              `json:"received_at"`
+}
+
+// Queue durably buffers exposure batches until a consumer records them
+type Queue interface {
+	// Publish returns once the batch is durable; its events may not be
+	// recorded yet
+	Publish(ctx context.Context, batch *Batch) error
+}
+
+// Failure is an event of a batch that could not be recorded
+type Failure struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Recorder records a batch's events. With isolate set, a batch that cannot
+// be written at once is written event by event and the events that still
+// fail are returned; otherwise any failure fails the whole batch, leaving
+// nothing recorded.
+type Recorder func(ctx context.Context, events []models.ExposureEvent, isolate bool) ([]Failure, error)
+
+// ErrUnavailable is returned when batches cannot be queued at all
+var ErrUnavailable = errors.New("exposure queue unavailable")
+
+// JobQueue publishes batches as jobs on a jobqueue.Queue
+type JobQueue struct {
+	queue       jobqueue.Queue
+	maxAttempts int
+}
+
+// NewJobQueue creates a queue publishing batches as jobs on queue
+func NewJobQueue(queue jobqueue.Queue) *JobQueue {
+	return &JobQueue{queue: queue, maxAttempts: DefaultMaxAttempts}
+}
+
+// Publish enqueues the batch as one job
+func (q *JobQueue) Publish(ctx context.Context, batch *Batch) error {
+	if q.queue == nil {
+		return ErrUnavailable
+	}
+	if _, err := q.queue.Enqueue(ctx, QueueName, batch, jobqueue.EnqueueOptions{MaxAttempts: q.maxAttempts}); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// NewJobHandler returns a job handler recording queued batches. A batch is
+// written in one transaction, so a failed attempt stores nothing and the
+// retry cannot count an event twice. The last attempt writes the events one
+// by one instead, so a single bad event cannot hold back the others forever;
+// events failing even then are logged and dropped.
+func NewJobHandler(record Recorder) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var batch Batch
+		if err := job.Decode(&batch); err != nil {
+			return err
+		}
+
+		isolate := job.Attempts >= job.MaxAttempts
+		failures, err := record(ctx, batch.Events, isolate)
+		if err != nil {
+			return err
+		}
+
+		fields := logrus.Fields{
+			"job_id":   job.ID,
+			"batch_id": batch.ID,
+			"events":   len(batch.Events),
+			"lag_ms":   time.Since(batch.ReceivedAt).Milliseconds(),
+		}
+		if len(failures) > 0 {
+			fields["failures"] = failures
+			logrus.WithFields(fields).Error("Dropped exposure events that could not be recorded")
+			return nil
+		}
+		logrus.WithFields(fields).Debug("Recorded queued exposure batch")
+		return nil
+	}
+}
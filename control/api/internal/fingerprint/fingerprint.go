@@ -0,0 +1,282 @@
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrNoBaseline is returned when remapping a title that has no applied cut
+var ErrNoBaseline = errors.New("title has no applied cut to remap from")
+
+// ErrCutApplied is returned when remapping to the cut surfaces are already aligned to
+var ErrCutApplied = errors.New("cut is already applied")
+
+// DefaultMaxDistance is the largest Hamming distance, out of 64 bits, at
+// which two shot hashes are taken to be the same shot
+const DefaultMaxDistance = 10
+
+// maxCutShots bounds the shots one cut may carry
+const maxCutShots = 20000
+
+// maxShotIDLength matches the shots table column
+const maxShotIDLength = 50
+
+// Surface remap outcomes
+const (
+	OutcomeUnchanged = "unchanged"
+	OutcomeShifted   = "shifted"
+	OutcomeWithdrawn = "withdrawn" // The surface's shot is not in the new cut
+)
+
+// Shot is a shot's perceptual hash in one cut of a title. Hash is a 64-bit
+// perceptual hash of the shot's keyframes as 16 hex digits.
+type Shot struct {
+	ShotID    string  `json:"shot_id"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Hash      string  `json:"phash"`
+}
+
+// Cut is one delivered edit of a title. Surfaces are aligned to the cut
+// applied most recently.
+type Cut struct {
+	TitleID   string     `json:"title_id"`
+	CutID     string     `json:"cut_id"`
+	Shots     int        `json:"shots"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Report    *Report    `json:"last_remap,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ValidateShots checks a cut's uploaded fingerprints
+func ValidateShots(shots []Shot) error {
+	if len(shots) == 0 {
+		return fmt.Errorf("shots is required")
+	}
+	if len(shots) > maxCutShots {
+		return fmt.Errorf("at most %d shots may be uploaded per cut", maxCutShots)
+	}
+	seen := make(map[string]bool, len(shots))
+	for _, shot := range shots {
+		if shot.ShotID == "" {
+			return fmt.Errorf("shots must have a shot_id")
+		}
+		if len(shot.ShotID) > maxShotIDLength {
+			return fmt.Errorf("shot_id %s is longer than %d characters", shot.ShotID, maxShotIDLength)
+		}
+		if seen[shot.ShotID] {
+			return fmt.Errorf("shot %s is listed twice", shot.ShotID)
+		}
+		seen[shot.ShotID] = true
+		if shot.EndTime < shot.StartTime {
+			return fmt.Errorf("shot %s ends before it starts", shot.ShotID)
+		}
+		if _, err := ParseHash(shot.Hash); err != nil {
+			return fmt.Errorf("shot %s: %w", shot.ShotID, err)
+		}
+	}
+	return nil
+}
+
+// ParseHash parses a 64-bit perceptual hash written as 16 hex digits
+func ParseHash(hash string) (uint64, error) {
+	if len(hash) != 16 {
+		return 0, fmt.Errorf("phash must be 16 hex digits")
+	}
+	value, err := strconv.ParseUint(hash, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("phash must be 16 hex digits")
+	}
+	return value, nil
+}
+
+// Distance is the Hamming distance between two hashes
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Match pairs a shot of the applied cut with the same shot in the new cut
+type Match struct {
+	From     Shot    `json:"from"`
+	To       Shot    `json:"to"`
+	Distance int     `json:"distance"`
+	Offset   float64 `json:"offset"` // Seconds added to times in the shot
+}
+
+// Map shifts a time range in the matched shot to the new cut, clamped to the
+// new shot's bounds. It reports false when nothing of the range is left.
+func (m Match) Map(start, end float64) (float64, float64, bool) {
+	newStart := math.Max(start+m.Offset, m.To.StartTime)
+	newEnd := math.Min(end+m.Offset, m.To.EndTime)
+	if newEnd < newStart || (newEnd == newStart && end > start) {
+		return 0, 0, false
+	}
+	return newStart, newEnd, true
+}
+
+// Alignment maps the applied cut's shots onto a new cut
+type Alignment struct {
+	Matches   map[string]Match // Keyed by the applied cut's shot ID
+	Unmatched []string         // Applied cut shots missing from the new cut
+	Added     []Shot           // New cut shots matching no applied cut shot
+}
+
+// Align matches shots of the applied cut to shots of a new cut by perceptual
+// hash. Closest hashes are matched first, and among equally close candidates
+// the one nearest in time, so re-ordered scenes still align. Each shot is
+// matched at most once. Shots must have passed ValidateShots.
+func Align(from, to []Shot, maxDistance int) Alignment {
+	type candidate struct {
+		from, to int
+		distance int
+		drift    float64
+	}
+
+	fromHashes := hashes(from)
+	toHashes := hashes(to)
+	var candidates []candidate
+	for i := range from {
+		for j := range to {
+			d := Distance(fromHashes[i], toHashes[j])
+			if d <= maxDistance {
+				candidates = append(candidates, candidate{i, j, d, math.Abs(to[j].StartTime - from[i].StartTime)})
+			}
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].distance != candidates[b].distance {
+			return candidates[a].distance < candidates[b].distance
+		}
+		if candidates[a].drift != candidates[b].drift {
+			return candidates[a].drift < candidates[b].drift
+		}
+		if candidates[a].from != candidates[b].from {
+			return candidates[a].from < candidates[b].from
+		}
+		return candidates[a].to < candidates[b].to
+	})
+
+	alignment := Alignment{Matches: make(map[string]Match)}
+	usedTo := make(map[int]bool)
+	for _, c := range candidates {
+		if _, done := alignment.Matches[from[c.from].ShotID]; done || usedTo[c.to] {
+			continue
+		}
+		usedTo[c.to] = true
+		alignment.Matches[from[c.from].ShotID] = Match{
+			From:     from[c.from],
+			To:       to[c.to],
+			Distance: c.distance,
+			Offset:   to[c.to].StartTime - from[c.from].StartTime,
+		}
+	}
+
+	for _, shot := range from {
+		if _, ok := alignment.Matches[shot.ShotID]; !ok {
+			alignment.Unmatched = append(alignment.Unmatched, shot.ShotID)
+		}
+	}
+	for j, shot := range to {
+		if !usedTo[j] {
+			alignment.Added = append(alignment.Added, shot)
+		}
+	}
+	return alignment
+}
+
+func hashes(shots []Shot) []uint64 {
+	values := make([]uint64, len(shots))
+	for i, shot := range shots {
+		values[i], _ = ParseHash(shot.Hash)
+	}
+	return values
+}
+
+// SurfaceRange is a surface's time range in the applied cut
+type SurfaceRange struct {
+	SurfaceID string
+	ShotID    string
+	StartTime float64
+	EndTime   float64
+}
+
+// SurfaceChange is how a remap moves one surface
+type SurfaceChange struct {
+	SurfaceID    string  `json:"surface_id"`
+	ShotID       string  `json:"shot_id"`
+	NewShotID    string  `json:"new_shot_id,omitempty"`
+	OldStartTime float64 `json:"old_start_time"`
+	OldEndTime   float64 `json:"old_end_time"`
+	NewStartTime float64 `json:"new_start_time"`
+	NewEndTime   float64 `json:"new_end_time"`
+	Outcome      string  `json:"outcome"`
+}
+
+// Report describes a remap of a title's surfaces from one cut to another
+type Report struct {
+	TitleID        string          `json:"title_id"`
+	FromCut        string          `json:"from_cut"`
+	ToCut          string          `json:"to_cut"`
+	DryRun         bool            `json:"dry_run"`
+	MatchedShots   int             `json:"matched_shots"`
+	UnmatchedShots []string        `json:"unmatched_shots"` // Applied cut shots missing from the new cut
+	AddedShots     int             `json:"added_shots"`     // New cut shots with no counterpart
+	Counts         map[string]int  `json:"counts"`
+	Surfaces       []SurfaceChange `json:"surfaces"`
+	RemappedAt     time.Time       `json:"remapped_at"`
+}
+
+// Plan works out how each surface moves under an alignment
+func Plan(alignment Alignment, surfaces []SurfaceRange) []SurfaceChange {
+	changes := make([]SurfaceChange, 0, len(surfaces))
+	for _, s := range surfaces {
+		change := SurfaceChange{
+			SurfaceID:    s.SurfaceID,
+			ShotID:       s.ShotID,
+			OldStartTime: s.StartTime,
+			OldEndTime:   s.EndTime,
+			Outcome:      OutcomeWithdrawn,
+		}
+		if match, ok := alignment.Matches[s.ShotID]; ok {
+			if start, end, ok := match.Map(s.StartTime, s.EndTime); ok {
+				change.NewShotID = match.To.ShotID
+				change.NewStartTime, change.NewEndTime = start, end
+				change.Outcome = OutcomeShifted
+				if start == s.StartTime && end == s.EndTime {
+					change.Outcome = OutcomeUnchanged
+				}
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Summarize builds a remap report
+func Summarize(titleID, fromCut, toCut string, alignment Alignment, changes []SurfaceChange, dryRun bool, at time.Time) *Report {
+	counts := map[string]int{OutcomeUnchanged: 0, OutcomeShifted: 0, OutcomeWithdrawn: 0}
+	for _, change := range changes {
+		counts[change.Outcome]++
+	}
+	unmatched := alignment.Unmatched
+	if unmatched == nil {
+		unmatched = []string{}
+	}
+	return &Report{
+		TitleID:        titleID,
+		FromCut:        fromCut,
+		ToCut:          toCut,
+		DryRun:         dryRun,
+		MatchedShots:   len(alignment.Matches),
+		UnmatchedShots: unmatched,
+		AddedShots:     len(alignment.Added),
+		Counts:         counts,
+		Surfaces:       changes,
+		RemappedAt:     at,
+	}
+}
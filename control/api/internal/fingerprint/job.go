@@ -0,0 +1,60 @@
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue is the background job queue that remaps a title's surfaces to a new cut
+const Queue = "surface_remap"
+
+// Job is the payload of a remap job
+type Job struct {
+	TitleID     string `json:"title_id"`
+	CutID       string `json:"cut_id"`
+	MaxDistance int    `json:"max_distance"`
+}
+
+// Store remaps a title's surfaces to a cut
+type Store interface {
+	RemapTitleCut(titleID, cutID string, maxDistance int, dryRun bool) (*Report, error)
+}
+
+// NewJobHandler returns a job handler that remaps surfaces to a new cut.
+// Re-running it after the cut was applied does nothing.
+func NewJobHandler(store Store) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload Job
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+		if payload.TitleID == "" || payload.CutID == "" {
+			return fmt.Errorf("remap job %s needs title_id and cut_id", job.ID)
+		}
+		if payload.MaxDistance <= 0 {
+			payload.MaxDistance = DefaultMaxDistance
+		}
+
+		report, err := store.RemapTitleCut(payload.TitleID, payload.CutID, payload.MaxDistance, false)
+		if errors.Is(err, ErrCutApplied) {
+			return nil // A previous attempt committed before its lease expired
+		}
+		if err != nil {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"job_id":    job.ID,
+			"title_id":  report.TitleID,
+			"from_cut":  report.FromCut,
+			"to_cut":    report.ToCut,
+			"shifted":   report.Counts[OutcomeShifted],
+			"withdrawn": report.Counts[OutcomeWithdrawn],
+		}).Info("Remapped surfaces to new cut")
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+// Package flight moves bookings through their flight windows: confirmed
+// bookings go live at their start_time, and bookings still live at their
+// end_time end there, expired if they never went live and completed
+// otherwise.
+package flight
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is how often bookings are checked against their flight windows
+const DefaultInterval = time.Minute
+
+// batchSize bounds the bookings moved per scheduled run; the rest are moved
+// by the next one
+const batchSize = 1000
+
+// Booking is a live booking and its flight window. Nil ends are open.
+type Booking struct {
+	BookingID string
+	Status    string
+	Start     *time.Time
+	End       *time.Time
+}
+
+// Store lists the bookings whose flight window has started or ended and
+// appends the events moving them
+type Store interface {
+	ListFlightDueBookings(now time.Time, limit int) ([]Booking, error)
+	AppendBookingEvent(event booking.Event) (*booking.State, error)
+}
+
+// Due returns the event a booking's flight window calls for at now and the
+// reason to record with it, or an empty event type if there is none. Pending
+// bookings must still be approved to go live, but expire like confirmed ones.
+func Due(b Booking, now time.Time) (eventType, reason string) {
+	if booking.Terminal(b.Status) {
+		return "", ""
+	}
+	if b.End != nil && !now.Before(*b.End) {
+		if booking.CheckTransition(b.Status, booking.EventExpired) == nil {
+			return booking.EventExpired, "flight ended before the booking went live"
+		}
+		return booking.EventCompleted, "flight ended"
+	}
+	if b.Status == booking.StatusConfirmed && b.Start != nil && !now.Before(*b.Start) {
+		return booking.EventActivated, "flight started"
+	}
+	return "", ""
+}
+
+// Worker applies the transitions of due bookings on a schedule
+type Worker struct {
+	store    Store
+	interval time.Duration
+}
+
+// NewWorker creates a flight worker
+func NewWorker(store Store, interval time.Duration) *Worker {
+	return &Worker{store: store, interval: interval}
+}
+
+// Run moves due bookings immediately and then every interval until ctx is
+// cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.move(time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// move appends the event each due booking calls for. Gateways running the
+// worker side by side race for the same bookings; the loser's event is
+// rejected as an invalid transition and skipped.
+func (w *Worker) move(now time.Time) {
+	bookings, err := w.store.ListFlightDueBookings(now, batchSize)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list bookings due a flight transition")
+		return
+	}
+
+	moved := 0
+	for _, b := range bookings {
+		eventType, reason := Due(b, now)
+		if eventType == "" {
+			continue
+		}
+		state, err := w.store.AppendBookingEvent(booking.Event{
+			BookingID: b.BookingID,
+			Type:      eventType,
+			Actor:     "system",
+			Data:      booking.Change{Reason: reason},
+		})
+		if errors.Is(err, booking.ErrInvalidTransition) {
+			logrus.WithError(err).WithField("booking_id", b.BookingID).Debug("Booking moved before its flight transition")
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"booking_id": b.BookingID,
+				"event":      eventType,
+			}).Error("Failed to apply flight transition")
+			continue
+		}
+		moved++
+		logrus.WithFields(logrus.Fields{
+			"booking_id": b.BookingID,
+			"event":      eventType,
+			"status":     state.Status,
+		}).Info("Moved booking through its flight window")
+	}
+	if moved > 0 {
+		logrus.WithField("moved", moved).Debug("Applied flight transitions")
+	}
+}
@@ -0,0 +1,84 @@
+package flight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockStore lists every booking as due and applies events through the
+// booking state machine
+type mockStore struct {
+	bookings map[string]*Booking
+	events   []booking.Event
+}
+
+func (m *mockStore) ListFlightDueBookings(now time.Time, limit int) ([]Booking, error) {
+	due := make([]Booking, 0)
+	for _, b := range m.bookings {
+		due = append(due, *b)
+	}
+	return due, nil
+}
+
+func (m *mockStore) AppendBookingEvent(event booking.Event) (*booking.State, error) {
+	b := m.bookings[event.BookingID]
+	if err := booking.CheckTransition(b.Status, event.Type); err != nil {
+		return nil, err
+	}
+	b.Status = map[string]string{
+		booking.EventActivated: booking.StatusActive,
+		booking.EventCompleted: booking.StatusCompleted,
+		booking.EventExpired:   booking.StatusExpired,
+	}[event.Type]
+	m.events = append(m.events, event)
+	return &booking.State{BookingID: b.BookingID, Status: b.Status}, nil
+}
+
+func TestWorker_MovesBookingsThroughFlightWindows(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		ts := now.Add(offset)
+		return &ts
+	}
+
+	store := &mockStore{bookings: map[string]*Booking{
+		"started":           {BookingID: "started", Status: booking.StatusConfirmed, Start: at(-time.Minute), End: at(time.Hour)},
+		"not_started":       {BookingID: "not_started", Status: booking.StatusConfirmed, Start: at(time.Minute), End: at(time.Hour)},
+		"open_window":       {BookingID: "open_window", Status: booking.StatusConfirmed},
+		"pending_started":   {BookingID: "pending_started", Status: booking.StatusPending, Start: at(-time.Minute)},
+		"never_live":        {BookingID: "never_live", Status: booking.StatusConfirmed, Start: at(-2 * time.Hour), End: at(-time.Hour)},
+		"pending_ended":     {BookingID: "pending_ended", Status: booking.StatusPending, End: at(0)},
+		"live_ended":        {BookingID: "live_ended", Status: booking.StatusActive, Start: at(-2 * time.Hour), End: at(-time.Hour)},
+		"paused_ended":      {BookingID: "paused_ended", Status: booking.StatusPaused, End: at(-time.Hour)},
+		"already_cancelled": {BookingID: "already_cancelled", Status: booking.StatusCancelled, End: at(-time.Hour)},
+	}}
+	NewWorker(store, time.Minute).move(now)
+
+	expected := map[string]string{
+		"started":           booking.StatusActive,
+		"not_started":       booking.StatusConfirmed,
+		"open_window":       booking.StatusConfirmed,
+		"pending_started":   booking.StatusPending,
+		"never_live":        booking.StatusExpired,
+		"pending_ended":     booking.StatusExpired,
+		"live_ended":        booking.StatusCompleted,
+		"paused_ended":      booking.StatusCompleted,
+		"already_cancelled": booking.StatusCancelled,
+	}
+	for id, status := range expected {
+		assert.Equal(t, status, store.bookings[id].Status, id)
+	}
+	require.Len(t, store.events, 5)
+	for _, event := range store.events {
+		assert.Equal(t, "system", event.Actor)
+		assert.NotEmpty(t, event.Data.Reason)
+	}
+
+	// Nothing is due again once the bookings moved
+	NewWorker(store, time.Minute).move(now)
+	assert.Len(t, store.events, 5)
+}
@@ -0,0 +1,126 @@
+package freqcap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Counter counts a viewer's exposures under a cap until its window expires
+type Counter interface {
+	// Count returns the exposures counted under key and when they expire,
+	// zero when none are
+	Count(ctx context.Context, key string) (int64, time.Time, error)
+	// Add counts an exposure under key, starting a window on the first
+	Add(ctx context.Context, key string, window time.Duration) error
+}
+
+// addScript counts an exposure and starts the window on the first.
+// KEYS: count. ARGV: window ms.
+var addScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// countScript reads a count with its remaining window, {0, -2} when there is none.
+// KEYS: count.
+var countScript = redis.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// RedisCounter keeps viewer counts in Redis, shared by every gateway instance
+type RedisCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCounter creates a frequency counter backed by Redis
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+// Count returns the exposures counted under key and when they expire
+func (r *RedisCounter) Count(ctx context.Context, key string) (int64, time.Time, error) {
+	result, err := countScript.Run(ctx, r.client, []string{key}).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to read frequency count: %w", err)
+	}
+	if result[0] == 0 || result[1] < 0 {
+		return result[0], time.Time{}, nil
+	}
+	return result[0], time.Now().Add(time.Duration(result[1]) * time.Millisecond), nil
+}
+
+// Add counts an exposure under key
+func (r *RedisCounter) Add(ctx context.Context, key string, window time.Duration) error {
+	if err := addScript.Run(ctx, r.client, []string{key}, window.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("failed to count frequency: %w", err)
+	}
+	return nil
+}
+
+// MemoryCounter keeps viewer counts in process memory. Each gateway instance
+// counts on its own, so use RedisCounter behind a load balancer.
+type MemoryCounter struct {
+	mu     sync.Mutex
+	counts map[string]*count
+	swept  time.Time
+}
+
+type count struct {
+	exposures int64
+	expiresAt time.Time
+}
+
+// NewMemoryCounter creates an in-memory frequency counter
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{counts: make(map[string]*count)}
+}
+
+// Count returns the exposures counted under key and when they expire
+func (m *MemoryCounter) Count(ctx context.Context, key string) (int64, time.Time, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counts[key]
+	if !ok || !now.Before(c.expiresAt) {
+		return 0, time.Time{}, nil
+	}
+	return c.exposures, c.expiresAt, nil
+}
+
+// Add counts an exposure under key
+func (m *MemoryCounter) Add(ctx context.Context, key string, window time.Duration) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	c, ok := m.counts[key]
+	if !ok || !now.Before(c.expiresAt) {
+		c = &count{expiresAt: now.Add(window)}
+		m.counts[key] = c
+	}
+	c.exposures++
+	return nil
+}
+
+// sweep drops expired counts, at most once a minute
+func (m *MemoryCounter) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for key, c := range m.counts {
+		if !now.Before(c.expiresAt) {
+			delete(m.counts, key)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package freqcap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Enforcer counts recorded exposures per viewer against the caps of their
+// booking and its campaign, and decides whether a viewer may see another.
+// Caps are cached for RulesCacheTTL, so exposures of uncapped bookings cost
+// no query.
+type Enforcer struct {
+	store   Store
+	counter Counter
+
+	mu    sync.Mutex
+	rules map[string]cachedRules
+}
+
+type cachedRules struct {
+	rules    *Rules
+	loadedAt time.Time
+}
+
+// NewEnforcer creates a frequency cap enforcer
+func NewEnforcer(store Store, counter Counter) *Enforcer {
+	return &Enforcer{store: store, counter: counter, rules: make(map[string]cachedRules)}
+}
+
+// countKey names a viewer's count under one cap. Viewer IDs are hashed so
+// they are not kept in Redis in the clear.
+func countKey(scope, id, viewerID string) string {
+	sum := sha256.Sum256([]byte(viewerID))
+	return "freqcap:" + scope + ":" + id + ":" + hex.EncodeToString(sum[:16])
+}
+
+// getRules loads the caps of a campaign and booking, cached for RulesCacheTTL
+func (e *Enforcer) getRules(campaignID, bookingID string) (*Rules, error) {
+	key := campaignID + "/" + bookingID
+	e.mu.Lock()
+	cached, ok := e.rules[key]
+	e.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < RulesCacheTTL {
+		return cached.rules, nil
+	}
+
+	rules, err := e.store.GetFrequencyRules(campaignID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.rules[key] = cachedRules{rules: rules, loadedAt: time.Now()}
+	e.mu.Unlock()
+	return rules, nil
+}
+
+// Invalidate drops the cached caps of a booking, so a change to them made
+// anywhere applies at once rather than within RulesCacheTTL
+func (e *Enforcer) Invalidate(bookingID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key := range e.rules {
+		if strings.HasSuffix(key, "/"+bookingID) {
+			delete(e.rules, key)
+		}
+	}
+}
+
+// InvalidateAll drops every cached cap
+func (e *Enforcer) InvalidateAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = make(map[string]cachedRules)
+}
+
+// scopedCap is a cap with the ID its counts are kept under
+type scopedCap struct {
+	scope, id string
+	cap       *Cap
+}
+
+// caps lists the caps of rules
+func (r *Rules) caps() []scopedCap {
+	var caps []scopedCap
+	if r.Campaign != nil {
+		caps = append(caps, scopedCap{ScopeCampaign, r.CampaignID, r.Campaign})
+	}
+	if r.Booking != nil && r.BookingID != "" {
+		caps = append(caps, scopedCap{ScopeBooking, r.BookingID, r.Booking})
+	}
+	return caps
+}
+
+// Check decides whether a viewer may see another exposure of a campaign and,
+// when bookingID is set, of that booking of it. Viewers without exposures,
+// and campaigns and bookings without caps, are always allowed.
+func (e *Enforcer) Check(ctx context.Context, viewerID, campaignID, bookingID string) (*Decision, error) {
+	rules, err := e.getRules(campaignID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		return nil, ErrNotFound
+	}
+
+	decision := &Decision{ViewerID: viewerID, CampaignID: campaignID, BookingID: bookingID, Allowed: true, Limits: []Limit{}}
+	for _, c := range rules.caps() {
+		exposures, expiresAt, err := e.counter.Count(ctx, countKey(c.scope, c.id, viewerID))
+		if err != nil {
+			logrus.WithError(err).WithField("campaign_id", campaignID).Warn("Frequency counter unavailable")
+			return nil, ErrUnavailable
+		}
+		limit := Limit{
+			Scope:        c.scope,
+			MaxExposures: c.cap.MaxExposures,
+			WindowHours:  c.cap.WindowHours,
+			Exposures:    exposures,
+			Remaining:    int64(c.cap.MaxExposures) - exposures,
+		}
+		if limit.Remaining <= 0 {
+			limit.Remaining = 0
+			decision.Allowed = false
+		}
+		if !expiresAt.IsZero() {
+			resetsAt := expiresAt.UTC()
+			limit.ResetsAt = &resetsAt
+		}
+		decision.Limits = append(decision.Limits, limit)
+	}
+	return decision, nil
+}
+
+// RecordExposure counts a recorded exposure of a booking against the caps of
+// the booking and its campaign
+func (e *Enforcer) RecordExposure(ctx context.Context, viewerID, bookingID string) error {
+	rules, err := e.getRules("", bookingID)
+	if err != nil || rules == nil {
+		return err
+	}
+	for _, c := range rules.caps() {
+		if err := e.counter.Add(ctx, countKey(c.scope, c.id, viewerID), c.cap.Window()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package freqcap limits how many exposures of a campaign, or of one of its
+// bookings, a viewer sees within a window, e.g. three per 24 hours. Edge
+// nodes ask before rendering a placement; recorded exposures are counted per
+// viewer in Redis with the window as their expiry.
+package freqcap
+
+import (
+	"errors"
+	"time"
+)
+
+// Scopes a frequency cap applies to
+const (
+	ScopeCampaign = "campaign" // Exposures of any of the campaign's bookings
+	ScopeBooking  = "booking"
+)
+
+const (
+	// MaxExposures bounds a cap's max_exposures
+	MaxExposures = 1000
+	// MaxWindowHours bounds a cap's window to 30 days
+	MaxWindowHours = 720
+	// RulesCacheTTL is how long the caps of a booking or campaign are cached,
+	// and so how long a change takes to apply
+	RulesCacheTTL = time.Minute
+)
+
+// ErrNotFound is returned when checking a campaign, or a booking of it,
+// that does not exist
+var ErrNotFound = errors.New("campaign or booking not found")
+
+// ErrUnavailable is returned when viewer counts cannot be read
+var ErrUnavailable = errors.New("frequency counter unavailable")
+
+// Cap allows a viewer MaxExposures exposures within WindowHours of their
+// first one. A MaxExposures of 0 removes a cap when updating a campaign.
+type Cap struct {
+	MaxExposures int `json:"max_exposures"`
+	WindowHours  int `json:"window_hours"`
+}
+
+// Validate checks a cap set on a booking or campaign
+func (c *Cap) Validate() error {
+	if c.MaxExposures < 1 || c.MaxExposures > MaxExposures {
+		return errors.New("frequency_cap.max_exposures must be between 1 and 1000")
+	}
+	if c.WindowHours < 1 || c.WindowHours > MaxWindowHours {
+		return errors.New("frequency_cap.window_hours must be between 1 and 720")
+	}
+	return nil
+}
+
+// Window is how long a viewer's count lasts from their first exposure
+func (c *Cap) Window() time.Duration {
+	return time.Duration(c.WindowHours) * time.Hour
+}
+
+// Rules are the caps an exposure of a booking is counted against
+type Rules struct {
+	CampaignID string
+	BookingID  string // Empty when only the campaign is checked
+	Campaign   *Cap
+	Booking    *Cap
+}
+
+// Limit is where a viewer stands against one cap
+type Limit struct {
+	Scope        string     `json:"scope"`
+	MaxExposures int        `json:"max_exposures"`
+	WindowHours  int        `json:"window_hours"`
+	Exposures    int64      `json:"exposures"` // Counted in the current window
+	Remaining    int64      `json:"remaining"`
+	ResetsAt     *time.Time `json:"resets_at,omitempty"` // End of the current window; unset before the first exposure
+}
+
+// Decision is whether a viewer may see another exposure of a campaign
+type Decision struct {
+	ViewerID   string  `json:"viewer_id"`
+	CampaignID string  `json:"campaign_id"`
+	BookingID  string  `json:"booking_id,omitempty"`
+	Allowed    bool    `json:"allowed"`
+	Limits     []Limit `json:"limits"` // Empty when neither the campaign nor the booking is capped
+}
+
+// Store reads the frequency caps of campaigns and bookings
+type Store interface {
+	// GetFrequencyRules returns the caps of a campaign and, when bookingID is
+	// set, of that booking of it, or nil if either does not exist. An empty
+	// campaignID takes the booking's campaign.
+	GetFrequencyRules(campaignID, bookingID string) (*Rules, error)
+}
@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/exposurequeue"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
+	"github.com/inscenium/inscenium/control/api/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// measurementServer implements InsceniumMeasurementService. Metrics and
+// event reads are left unimplemented; they are served over HTTP.
+type measurementServer struct {
+	pb.UnimplementedInsceniumMeasurementServiceServer
+	s *Server
+}
+
+// exposureRequest converts an exposure into the request POST
+// /events/exposure takes, so both are validated alike. event_id is the
+// sender's own ID, kept across resends.
+func exposureRequest(e *pb.ExposureEvent) schema.ExposureRequest {
+	return schema.ExposureRequest{
+		BookingID:        e.GetBookingId(),
+		ViewerID:         e.GetViewerId(),
+		ClientEventID:    e.GetEventId(),
+		ExposureDuration: e.GetExposureDuration().AsDuration().Seconds(),
+		ScreenCoverage:   float64(e.GetScreenCoveragePercentage()),
+		AttentionScore:   float64(e.GetAttentionScore()),
+		DeviceType:       e.GetDeviceType(),
+		Timestamp:        timeOf(e.GetTimestamp()),
+	}
+}
+
+// exposureEvent converts a validated exposure into the event stored by the
+// database. Without a device event time the receive time is used.
+func exposureEvent(exposure schema.ExposureRequest, receivedAt time.Time) models.ExposureEvent {
+	timestamp := receivedAt
+	if exposure.Timestamp != nil && !exposure.Timestamp.IsZero() {
+		timestamp = *exposure.Timestamp
+	}
+	return models.ExposureEvent{
+		BookingID:        exposure.BookingID,
+		ViewerID:         exposure.ViewerID,
+		ClientEventID:    exposure.ClientEventID,
+		Timestamp:        timestamp,
+		DeviceTimestamp:  exposure.Timestamp,
+		ReceivedAt:       receivedAt,
+		ExposureDuration: exposure.ExposureDuration,
+		ScreenCoverage:   exposure.ScreenCoverage,
+		AttentionScore:   exposure.AttentionScore,
+		DeviceType:       exposure.DeviceType,
+		Counted:          true,
+	}
+}
+
+// RecordExposure records one exposure like POST /events/exposure, of a
+// booking the caller may view. A resend of an exposure already recorded
+// succeeds without recording it again.
+func (m *measurementServer) RecordExposure(ctx context.Context, req *pb.ExposureEvent) (*pb.RecordExposureResponse, error) {
+	receivedAt := time.Now().UTC()
+
+	exposure := exposureRequest(req)
+	if err := schema.Validate(&exposure); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := m.s.authorize(ctx, labels.ResourceBooking, exposure.BookingID, authz.PermissionView); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"booking_id":        exposure.BookingID,
+		"exposure_duration": exposure.ExposureDuration,
+		"screen_coverage":   exposure.ScreenCoverage,
+	}).Info("Recording exposure event")
+
+	event := exposureEvent(exposure, receivedAt)
+	eventID, err := m.s.placements.RecordExposure(ctx, &event)
+	if errors.Is(err, db.ErrDuplicateExposure) {
+		return &pb.RecordExposureResponse{
+			Success: true,
+			Message: "Exposure already recorded; resend ignored",
+		}, nil
+	}
+	if err != nil {
+		return nil, internalError(err, "Failed to record exposure event")
+	}
+
+	return &pb.RecordExposureResponse{
+		Success: true,
+		Message: "Exposure recorded successfully",
+		EventId: eventID,
+	}, nil
+}
+
+// mayRecordExposures reports whether the caller may record exposures of a
+// booking, which takes the same permission as viewing it. Decisions are kept
+// in allowed, so a batch checks each booking once.
+func (s *Server) mayRecordExposures(ctx context.Context, bookingID string, allowed map[string]bool) (bool, error) {
+	if s.authz == nil {
+		return true, nil
+	}
+	if ok, decided := allowed[bookingID]; decided {
+		return ok, nil
+	}
+	decision, err := s.authz.Check(actorFrom(ctx), labels.ResourceBooking, bookingID, authz.PermissionView)
+	if err != nil {
+		return false, err
+	}
+	allowed[bookingID] = decision.Allowed
+	return decision.Allowed, nil
+}
+
+// BatchRecordExposures records a batch like POST /events/exposure/batch
+// without an exposure queue: events are validated and authorized one by one
+// and the rest written together, so an event at fault is reported without
+// holding back the others. Failed events are reported in batch order by their
+// event_id, or #<index> when they have none, alongside their error.
+func (m *measurementServer) BatchRecordExposures(ctx context.Context, req *pb.BatchRecordExposuresRequest) (*pb.BatchRecordExposuresResponse, error) {
+	receivedAt := time.Now().UTC()
+
+	policy := throttle.DefaultPolicy()
+	if m.s.ingestion != nil {
+		policy = m.s.ingestion.Current()
+	}
+	if len(req.GetEvents()) > policy.MaxBatchSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "Batch exceeds the ingestion policy's max_batch_size of %d", policy.MaxBatchSize)
+	}
+
+	logrus.WithField("event_count", len(req.GetEvents())).Info("Recording batch exposure events")
+
+	failures := make([]exposurequeue.Failure, 0)
+	events := make([]models.ExposureEvent, 0, len(req.GetEvents()))
+	indexes := make([]int, 0, len(req.GetEvents())) // Batch index of each valid event
+	allowed := make(map[string]bool)
+	for i, e := range req.GetEvents() {
+		exposure := exposureRequest(e)
+		if err := schema.Validate(&exposure); err != nil {
+			failures = append(failures, exposurequeue.Failure{Index: i, Error: err.Error()})
+			continue
+		}
+		ok, err := m.s.mayRecordExposures(ctx, exposure.BookingID, allowed)
+		if err != nil {
+			return nil, internalError(err, "Authorization check failed")
+		}
+		if !ok {
+			failures = append(failures, exposurequeue.Failure{Index: i, Error: "Not permitted to record exposures of booking " + exposure.BookingID})
+			continue
+		}
+		events = append(events, exposureEvent(exposure, receivedAt))
+		indexes = append(indexes, i)
+	}
+
+	// Resent beacons seen within the window are dropped before anything is
+	// charged
+	fresh, freshIndexes := events[:0:0], indexes[:0:0]
+	for i, duplicate := range m.s.placements.CheckResends(ctx, events) {
+		if !duplicate {
+			fresh, freshIndexes = append(fresh, events[i]), append(freshIndexes, indexes[i])
+		}
+	}
+
+	recorded, err := m.s.placements.RecordExposures(ctx, fresh, true)
+	if err != nil {
+		return nil, internalError(err, "Failed to record exposure batch")
+	}
+	for _, f := range recorded.Failures {
+		f.Index = freshIndexes[f.Index]
+		failures = append(failures, f)
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	response := &pb.BatchRecordExposuresResponse{
+		ProcessedCount: int32(len(recorded.Events)),
+		FailedCount:    int32(len(failures)),
+	}
+	for _, f := range failures {
+		id := req.GetEvents()[f.Index].GetEventId()
+		if id == "" {
+			id = fmt.Sprintf("#%d", f.Index)
+		}
+		response.FailedEventIds = append(response.FailedEventIds, id)
+		response.ErrorMessages = append(response.ErrorMessages, f.Error)
+	}
+	return response, nil
+}
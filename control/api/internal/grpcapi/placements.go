@@ -0,0 +1,234 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes of ListPlacementOpportunities, those of GET /opportunities
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// placementStatuses maps booking statuses to graph.proto's PlacementStatus
+var placementStatuses = map[string]pb.PlacementStatus{
+	booking.StatusPending:   pb.PlacementStatus_PLACEMENT_STATUS_PENDING,
+	booking.StatusConfirmed: pb.PlacementStatus_PLACEMENT_STATUS_CONFIRMED,
+	booking.StatusActive:    pb.PlacementStatus_PLACEMENT_STATUS_ACTIVE,
+	booking.StatusPaused:    pb.PlacementStatus_PLACEMENT_STATUS_PAUSED,
+	booking.StatusCompleted: pb.PlacementStatus_PLACEMENT_STATUS_COMPLETED,
+	booking.StatusCancelled: pb.PlacementStatus_PLACEMENT_STATUS_CANCELLED,
+	booking.StatusExpired:   pb.PlacementStatus_PLACEMENT_STATUS_EXPIRED,
+}
+
+// placementServer implements InsceniumPlacementService. Opportunity
+// lookups, cancellation and update streams are left unimplemented; they are
+// served over HTTP.
+type placementServer struct {
+	pb.UnimplementedInsceniumPlacementServiceServer
+	s *Server
+}
+
+// ListPlacementOpportunities lists opportunities like GET /opportunities.
+// filters is the label selector and page_token a cursor from a previous
+// page's next_page_token.
+func (p *placementServer) ListPlacementOpportunities(ctx context.Context, req *pb.ListPlacementOpportunitiesRequest) (*pb.ListPlacementOpportunitiesResponse, error) {
+	page := pagination.Page{Limit: defaultPageSize}
+	if size := int(req.GetPageSize()); size >= 1 && size <= maxPageSize {
+		page.Limit = size
+	}
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := pagination.Decode(token)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid page_token")
+		}
+		page.Cursor = cursor
+	}
+	selector := labels.Set(req.GetFilters())
+	if err := selector.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	surfaces, err := p.s.placements.ListOpportunities(req.GetTitleId(), float64(req.GetMinPrsScore()), selector, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid page_token")
+	}
+	if errors.Is(err, query.ErrInvalidFilter) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, internalError(err, "Failed to get placement opportunities")
+	}
+	surfaces, next, _ := pagination.Window(surfaces, page, func(s models.Surface) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.FloatKey(s.PRSScore), ID: s.SurfaceID}
+	})
+
+	response := &pb.ListPlacementOpportunitiesResponse{
+		Opportunities: make([]*pb.PlacementOpportunity, 0, len(surfaces)),
+		NextPageToken: next,
+		TotalCount:    int32(len(surfaces)),
+	}
+	for _, s := range surfaces {
+		response.Opportunities = append(response.Opportunities, opportunity(s))
+	}
+	return response, nil
+}
+
+// opportunity converts a surface into its graph.proto form
+func opportunity(s models.Surface) *pb.PlacementOpportunity {
+	o := &pb.PlacementOpportunity{
+		SurfaceId:       s.SurfaceID,
+		TitleId:         s.TitleID,
+		ShotId:          s.ShotID,
+		StartTime:       seconds(s.StartTime),
+		EndTime:         seconds(s.EndTime),
+		Duration:        seconds(s.Duration),
+		PrsScore:        float32(s.PRSScore),
+		VisibilityScore: float32(s.VisibilityScore),
+		Metadata:        s.Labels,
+		CreatedAt:       timestamppb.New(s.CreatedAt),
+	}
+	if s.SurfaceType != "" {
+		o.SurfaceTypes = []string{s.SurfaceType}
+	}
+	return o
+}
+
+// seconds converts a content position in seconds to a Duration
+func seconds(s float64) *durationpb.Duration {
+	return durationpb.New(time.Duration(s * float64(time.Second)))
+}
+
+// BookPlacement books a surface like POST /bookings. placement_id names the
+// surface.
+func (p *placementServer) BookPlacement(ctx context.Context, req *pb.BookPlacementRequest) (*pb.BookPlacementResponse, error) {
+	request := schema.BookingRequest{
+		SurfaceID:      req.GetPlacementId(),
+		AdvertiserID:   req.GetAdvertiserId(),
+		CampaignID:     req.GetCampaignId(),
+		BidAmountCPM:   float64(req.GetBidAmountCpm()),
+		MaxImpressions: int(req.GetMaxImpressions()),
+		MinPRSScore:    float64(req.GetMinPrsScore()),
+		StartTime:      timeOf(req.GetStartTime()),
+		EndTime:        timeOf(req.GetEndTime()),
+	}
+	if err := schema.Validate(&request); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := (booking.Window{Start: request.StartTime, End: request.EndTime}).Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := pacing.Validate(request.Pacing, request.MaxImpressions, request.StartTime, request.EndTime); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := p.s.authorize(ctx, labels.ResourceCampaign, request.CampaignID, authz.PermissionManage); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"surface_id":    request.SurfaceID,
+		"advertiser_id": request.AdvertiserID,
+		"campaign_id":   request.CampaignID,
+		"bid_cpm":       request.BidAmountCPM,
+	}).Info("Booking placement")
+
+	identity := identityFrom(ctx)
+	bookingID, err := p.s.placements.CreateBooking(&models.NewBooking{
+		SurfaceID:      request.SurfaceID,
+		AdvertiserID:   request.AdvertiserID,
+		CampaignID:     request.CampaignID,
+		BidAmountCPM:   request.BidAmountCPM,
+		MaxImpressions: request.MaxImpressions,
+		MinPRSScore:    request.MinPRSScore,
+		StartTime:      request.StartTime,
+		EndTime:        request.EndTime,
+		OrgID:          identity.OrgID,
+		UserID:         identity.UserID,
+	})
+	if errors.Is(err, booking.ErrConflict) || errors.Is(err, db.ErrExternalIDTaken) || errors.Is(err, holdback.ErrHeldBack) ||
+		errors.Is(err, dedupe.ErrSurfaceMerged) || errors.Is(err, collision.ErrCollision) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, campaign.ErrNotBookable) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, internalError(err, "Failed to create placement booking")
+	}
+
+	return &pb.BookPlacementResponse{
+		BookingId:            bookingID,
+		Status:               pb.PlacementStatus_PLACEMENT_STATUS_CONFIRMED, // New bookings skip pending approval
+		Message:              "Placement booked successfully",
+		ConfirmationTime:     timestamppb.Now(),
+		FinalCpmRate:         req.GetBidAmountCpm(),
+		EstimatedImpressions: req.GetMaxImpressions(),
+	}, nil
+}
+
+// timeOf converts an optional Timestamp, leaving unset ones open-ended
+func timeOf(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// GetBookingStatus returns a booking like GET /bookings/:id, to callers
+// that may view it
+func (p *placementServer) GetBookingStatus(ctx context.Context, req *pb.GetBookingStatusRequest) (*pb.BookPlacementResponse, error) {
+	id := req.GetBookingId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "booking_id is required")
+	}
+	decision, err := p.s.authorize(ctx, labels.ResourceBooking, id, authz.PermissionView)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := p.s.placements.GetBooking(scopeOf(actorFrom(ctx), decision), id)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to get placement booking")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+	if b == nil {
+		return nil, status.Error(codes.NotFound, "Booking not found")
+	}
+
+	response := &pb.BookPlacementResponse{
+		BookingId:            b.BookingID,
+		Status:               placementStatuses[b.Status],
+		FinalCpmRate:         float32(b.BidAmountCPM),
+		EstimatedImpressions: int32(b.EstimatedImpressions),
+	}
+	if b.FinalCPMRate != nil {
+		response.FinalCpmRate = float32(*b.FinalCPMRate)
+	}
+	if b.ConfirmationTime != nil {
+		response.ConfirmationTime = timestamppb.New(*b.ConfirmationTime)
+	}
+	return response, nil
+}
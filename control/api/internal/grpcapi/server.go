@@ -0,0 +1,336 @@
+// Package grpcapi serves graph.proto's placement and measurement services
+// over gRPC. Calls go through the same placement.Service as the HTTP
+// handlers, and are authenticated, scoped, rate limited, authorized and
+// scanned for personal data as the matching HTTP routes are, so both
+// transports behave the same.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/ratelimit"
+	"github.com/inscenium/inscenium/control/api/internal/serviceaccount"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/inscenium/inscenium/control/api/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Authenticator verifies the bearer token a call carries, returning
+// middleware.ErrInvalidToken for credentials that do not authenticate a caller
+type Authenticator func(ctx context.Context, token string) (*middleware.Identity, error)
+
+// PolicySource returns the ingestion policy exposure batches are held to
+type PolicySource interface {
+	Current() throttle.Policy
+}
+
+// methodScopes is the scope each method requires, that of its HTTP route.
+// Methods missing here are refused.
+var methodScopes = map[string]string{
+	pb.InsceniumPlacementService_ListPlacementOpportunities_FullMethodName: "sgi:read",
+	pb.InsceniumPlacementService_GetPlacementOpportunity_FullMethodName:    "sgi:read",
+	pb.InsceniumPlacementService_StreamPlacementUpdates_FullMethodName:     "sgi:read",
+	pb.InsceniumPlacementService_BookPlacement_FullMethodName:              "bookings:write",
+	pb.InsceniumPlacementService_CancelBooking_FullMethodName:              "bookings:write",
+	pb.InsceniumPlacementService_GetBookingStatus_FullMethodName:           "bookings:read",
+	pb.InsceniumMeasurementService_RecordExposure_FullMethodName:           "events:write",
+	pb.InsceniumMeasurementService_BatchRecordExposures_FullMethodName:     "events:write",
+	pb.InsceniumMeasurementService_GetAggregateMetrics_FullMethodName:      "analytics:read",
+	pb.InsceniumMeasurementService_GetExposureEvents_FullMethodName:        "analytics:read",
+	pb.InsceniumMeasurementService_StreamMetrics_FullMethodName:            "analytics:read",
+}
+
+// Server implements both services on a placement.Service
+type Server struct {
+	placements   *placement.Service
+	authenticate Authenticator
+	authz        *authz.Authorizer
+	bucket       ratelimit.Limiter
+	quota        ratelimit.Limiter
+	pii          *pii.Policy
+	piiRecorder  middleware.PIIRecorder
+	ingestion    PolicySource
+}
+
+// NewServer creates a server recording and booking through placements and
+// authenticating calls with authenticate
+func NewServer(placements *placement.Service, authenticate Authenticator) *Server {
+	return &Server{placements: placements, authenticate: authenticate}
+}
+
+// SetAuthorizer checks that callers may act on the bookings and campaigns
+// they name
+func (s *Server) SetAuthorizer(authorizer *authz.Authorizer) {
+	s.authz = authorizer
+}
+
+// SetRateLimits takes every call from its caller's token bucket and its
+// organization's daily quota, shared with the HTTP routes. Either may be nil.
+func (s *Server) SetRateLimits(bucket, quota ratelimit.Limiter) {
+	s.bucket, s.quota = bucket, quota
+}
+
+// SetPIIPolicy scans exposure events for personal data. recorder may be nil.
+func (s *Server) SetPIIPolicy(policy *pii.Policy, recorder middleware.PIIRecorder) {
+	s.pii, s.piiRecorder = policy, recorder
+}
+
+// SetIngestionPolicy holds exposure batches to the policy's max_batch_size
+func (s *Server) SetIngestionPolicy(source PolicySource) {
+	s.ingestion = source
+}
+
+// GRPCServer creates a gRPC server with both services registered behind the
+// server's interceptors
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	g := grpc.NewServer(opts...)
+	pb.RegisterInsceniumPlacementServiceServer(g, &placementServer{s: s})
+	pb.RegisterInsceniumMeasurementServiceServer(g, &measurementServer{s: s})
+	return g
+}
+
+// identityKey keys the caller's identity in a call's context
+type identityKey struct{}
+
+// identityFrom returns the caller admitted by the interceptors
+func identityFrom(ctx context.Context) *middleware.Identity {
+	identity, _ := ctx.Value(identityKey{}).(*middleware.Identity)
+	if identity == nil {
+		return &middleware.Identity{}
+	}
+	return identity
+}
+
+// actorFrom returns the caller as authz sees it
+func actorFrom(ctx context.Context) authz.Actor {
+	identity := identityFrom(ctx)
+	return authz.Actor{
+		UserID:        identity.UserID,
+		OrgID:         identity.OrgID,
+		PrincipalType: identity.PrincipalType,
+		Role:          identity.Role,
+	}
+}
+
+// scopeOf returns the organizations whose data a caller may read, like
+// authz.Scope: every organization for admins, otherwise the caller's own plus
+// the owner of a resource the decision let it reach through a grant
+func scopeOf(actor authz.Actor, decision authz.Decision) tenant.Scope {
+	if actor.Role == user.RoleAdmin {
+		return tenant.All()
+	}
+	if decision.GrantID != "" {
+		return tenant.Of(actor.OrgID, decision.OwnerOrgID)
+	}
+	return tenant.Of(actor.OrgID)
+}
+
+// unaryInterceptor admits a call and scans its request for personal data
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.admit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if message, ok := req.(proto.Message); ok && methodScopes[info.FullMethod] == "events:write" {
+		if err := s.scanPII(ctx, info.FullMethod, message); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// admittedStream carries the context of an admitted streaming call
+type admittedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *admittedStream) Context() context.Context {
+	return a.ctx
+}
+
+// streamInterceptor admits a streaming call
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.admit(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &admittedStream{ServerStream: ss, ctx: ctx})
+}
+
+// admit authenticates a call from its authorization metadata, checks the
+// scope its method requires and takes it from the rate limits, returning the
+// context the method runs with
+func (s *Server) admit(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Authorization metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization metadata format")
+	}
+
+	identity, err := s.authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, middleware.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	scope, known := methodScopes[method]
+	if !known || !serviceaccount.Allows(identity.Scopes, scope) {
+		logrus.WithFields(logrus.Fields{
+			"audit":          "scope",
+			"user_id":        identity.UserID,
+			"principal_type": identity.PrincipalType,
+			"role":           identity.Role,
+			"scope":          scope,
+			"method":         method,
+		}).Warn("Caller missing scope")
+		return nil, status.Error(codes.PermissionDenied, "Missing required scope: "+scope)
+	}
+
+	account := identity.OrgID
+	if account == "" {
+		account = identity.UserID
+	}
+	if err := limit(ctx, s.bucket, "rate", identity.UserID); err != nil {
+		return nil, err
+	}
+	if err := limit(ctx, s.quota, "quota", account); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// limit takes a call from one limiter, refusing it with ResourceExhausted
+// when the limit is used up. A limiter that fails lets the call through.
+func limit(ctx context.Context, limiter ratelimit.Limiter, name, key string) error {
+	if limiter == nil {
+		return nil
+	}
+	taken, err := limiter.Take(ctx, key)
+	if err != nil {
+		logrus.WithError(err).WithField("limit", name).Warn("Rate limiter unavailable, allowing request")
+		return nil
+	}
+	if taken.Allowed {
+		return nil
+	}
+
+	metrics.RateLimited.WithLabelValues(name).Inc()
+	message := "Rate limit exceeded"
+	if name == "quota" {
+		message = "Daily request quota exceeded"
+	}
+	return status.Error(codes.ResourceExhausted, message)
+}
+
+// scanPII applies the PII policy to a request as it would to the same event
+// posted as JSON, refusing it when a field's action is reject and masking
+// the request in place otherwise. Violations are logged and recorded
+// against the caller's API key.
+func (s *Server) scanPII(ctx context.Context, method string, req proto.Message) error {
+	if s.pii == nil || s.pii.Empty() {
+		return nil
+	}
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(req)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to scan payload for personal data")
+		return status.Error(codes.Internal, "Internal server error")
+	}
+	result, body, err := s.pii.Scan(body)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to scan payload for personal data")
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	if len(result.Violations) > 0 {
+		s.recordPIIViolations(ctx, method, result)
+	}
+	if result.Rejected {
+		return status.Error(codes.InvalidArgument, "Payload contains personal data")
+	}
+	if result.Masked {
+		proto.Reset(req)
+		if err := protojson.Unmarshal(body, req); err != nil {
+			logrus.WithError(err).Error("Failed to apply masked payload")
+			return status.Error(codes.Internal, "Internal server error")
+		}
+	}
+	return nil
+}
+
+// recordPIIViolations logs, counts and stores the violations of one call.
+// Values are never logged.
+func (s *Server) recordPIIViolations(ctx context.Context, method string, result *pii.Result) {
+	identity := identityFrom(ctx)
+	keyLabel := identity.KeyID
+	if keyLabel == "" {
+		keyLabel = middleware.PrincipalUser
+	}
+	for _, v := range result.Violations {
+		metrics.PIIViolations.WithLabelValues(keyLabel, v.Field, v.Kind, v.Action).Inc()
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "pii",
+		"user_id":    identity.UserID,
+		"key_id":     identity.KeyID,
+		"method":     method,
+		"violations": result.Violations,
+		"rejected":   result.Rejected,
+	}).Warn("Personal data found in payload")
+
+	if s.piiRecorder == nil {
+		return
+	}
+	if err := s.piiRecorder.RecordPIIViolations(identity.OrgID, identity.UserID, identity.KeyID, result.Violations); err != nil {
+		logrus.WithError(err).Warn("Failed to record PII violations")
+	}
+}
+
+// authorize checks p on a resource like authz.Authorizer.Authorize, refusing
+// the call with PermissionDenied. A nil authorizer allows everything.
+func (s *Server) authorize(ctx context.Context, resourceType, resourceID string, p authz.Permission) (authz.Decision, error) {
+	if s.authz == nil || resourceID == "" {
+		return authz.Decision{Allowed: true}, nil
+	}
+	decision, err := s.authz.Check(actorFrom(ctx), resourceType, resourceID, p)
+	if err != nil {
+		return decision, internalError(err, "Authorization check failed")
+	}
+	if !decision.Allowed {
+		return decision, status.Errorf(codes.PermissionDenied, "not permitted to %s %s %s", p, resourceType, resourceID)
+	}
+	return decision, nil
+}
+
+// internalError logs err and hides it from the caller, like the HTTP
+// handlers' 500 responses
+func internalError(err error, message string) error {
+	logrus.WithError(err).Error(message)
+	return status.Error(codes.Internal, "Internal server error")
+}
@@ -0,0 +1,224 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type mockStore struct {
+	bookings []*models.NewBooking
+	events   []models.ExposureEvent
+}
+
+func (m *mockStore) GetPlacementOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]models.Surface, error) {
+	return []models.Surface{{SurfaceID: "surface_1", TitleID: titleID, PRSScore: 90, SurfaceType: "wall"}}, nil
+}
+
+func (m *mockStore) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
+	return nil, nil
+}
+
+func (m *mockStore) CreatePlacementBooking(booking *models.NewBooking) (string, error) {
+	m.bookings = append(m.bookings, booking)
+	return "booking_new", nil
+}
+
+func (m *mockStore) GetPlacementBooking(scope tenant.Scope, bookingID string) (*models.Booking, error) {
+	if !scope.Allows("org_agency") {
+		return nil, nil
+	}
+	return &models.Booking{BookingID: bookingID, Status: "paused", OrgID: "org_agency"}, nil
+}
+
+func (m *mockStore) ListPlacementBookings(scope tenant.Scope, campaignID string, selector labels.Set, limit, offset int) ([]models.Booking, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RecordExposureEvent(event *models.ExposureEvent) (string, error) {
+	m.events = append(m.events, *event)
+	return "event_" + event.ViewerID, nil
+}
+
+func (m *mockStore) RecordExposureEvents(events []*models.ExposureEvent) ([]string, error) {
+	eventIDs := make([]string, len(events))
+	for i, event := range events {
+		eventIDs[i], _ = m.RecordExposureEvent(event)
+	}
+	return eventIDs, nil
+}
+
+// mockOwners owns resources by organization, without grants
+type mockOwners map[string]string
+
+func (m mockOwners) GetResourceOwner(resourceType, resourceID string) (string, error) {
+	return m[resourceType+"/"+resourceID], nil
+}
+
+func (m mockOwners) FindGrant(resourceType, resourceID, granteeOrgID string) (*authz.Grant, error) {
+	return nil, nil
+}
+
+// tokens authenticates the test callers
+var tokens = map[string]*middleware.Identity{
+	"edge":    {PrincipalType: middleware.PrincipalServiceAccount, UserID: "sa_edge", OrgID: "org_agency", Scopes: []string{"events:write"}},
+	"planner": {PrincipalType: middleware.PrincipalServiceAccount, UserID: "sa_planner", OrgID: "org_agency", Scopes: []string{"sgi:read", "bookings:write", "bookings:read"}},
+}
+
+// dial serves a Server over an in-memory connection, set up further by
+// configure, and returns clients of both services
+func dial(t *testing.T, store *mockStore, configure ...func(*Server)) (pb.InsceniumPlacementServiceClient, pb.InsceniumMeasurementServiceClient) {
+	t.Helper()
+	s := NewServer(placement.NewService(store), func(ctx context.Context, token string) (*middleware.Identity, error) {
+		if identity, ok := tokens[token]; ok {
+			return identity, nil
+		}
+		return nil, middleware.ErrInvalidToken
+	})
+	s.SetAuthorizer(authz.NewAuthorizer(mockOwners{
+		"campaign/campaign_own":   "org_agency",
+		"booking/booking_own":     "org_agency",
+		"booking/booking_other":   "org_other",
+		"campaign/campaign_other": "org_other",
+	}))
+	for _, c := range configure {
+		c(s)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := s.GRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewInsceniumPlacementServiceClient(conn), pb.NewInsceniumMeasurementServiceClient(conn)
+}
+
+// as returns a context calling with token
+func as(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestServer_AuthenticatesAndScopesCalls(t *testing.T) {
+	placements, measurements := dial(t, &mockStore{})
+
+	_, err := placements.ListPlacementOpportunities(context.Background(), &pb.ListPlacementOpportunitiesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Calls without a token should be refused")
+
+	_, err = placements.ListPlacementOpportunities(as("forged"), &pb.ListPlacementOpportunitiesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = placements.ListPlacementOpportunities(as("edge"), &pb.ListPlacementOpportunitiesRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Callers should need the scope of the matching HTTP route")
+	assert.Contains(t, status.Convert(err).Message(), "sgi:read")
+
+	_, err = measurements.GetAggregateMetrics(as("edge"), &pb.GetAggregateMetricsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Scopes should be checked before unimplemented methods")
+
+	listed, err := placements.ListPlacementOpportunities(as("planner"), &pb.ListPlacementOpportunitiesRequest{TitleId: "title_1"})
+	require.NoError(t, err)
+	require.Len(t, listed.Opportunities, 1)
+	assert.Equal(t, "surface_1", listed.Opportunities[0].SurfaceId)
+	assert.Equal(t, []string{"wall"}, listed.Opportunities[0].SurfaceTypes)
+}
+
+func TestServer_BooksPlacements(t *testing.T) {
+	store := &mockStore{}
+	placements, _ := dial(t, store)
+
+	_, err := placements.BookPlacement(as("planner"), &pb.BookPlacementRequest{AdvertiserId: "adv_1", CampaignId: "campaign_own", BidAmountCpm: 5})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Bookings should be validated like POST /bookings")
+
+	_, err = placements.BookPlacement(as("planner"), &pb.BookPlacementRequest{PlacementId: "surface_1", AdvertiserId: "adv_1", CampaignId: "campaign_other", BidAmountCpm: 5})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only the campaign's organization should book for it")
+	assert.Empty(t, store.bookings)
+
+	booked, err := placements.BookPlacement(as("planner"), &pb.BookPlacementRequest{PlacementId: "surface_1", AdvertiserId: "adv_1", CampaignId: "campaign_own", BidAmountCpm: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "booking_new", booked.BookingId)
+	assert.Equal(t, pb.PlacementStatus_PLACEMENT_STATUS_CONFIRMED, booked.Status)
+	require.Len(t, store.bookings, 1)
+	assert.Equal(t, "org_agency", store.bookings[0].OrgID)
+
+	booking, err := placements.GetBookingStatus(as("planner"), &pb.GetBookingStatusRequest{BookingId: "booking_own"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.PlacementStatus_PLACEMENT_STATUS_PAUSED, booking.Status)
+
+	_, err = placements.GetBookingStatus(as("planner"), &pb.GetBookingStatusRequest{BookingId: "booking_other"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_RecordsExposuresOfOwnBookings(t *testing.T) {
+	store := &mockStore{}
+	_, measurements := dial(t, store)
+	exposure := func(bookingID, viewerID string) *pb.ExposureEvent {
+		return &pb.ExposureEvent{BookingId: bookingID, ViewerId: viewerID, ExposureDuration: durationpb.New(5e9)}
+	}
+
+	_, err := measurements.RecordExposure(as("edge"), exposure("booking_other", "viewer_1"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Exposures of another organization's booking should be refused")
+	assert.Empty(t, store.events)
+
+	_, err = measurements.RecordExposure(as("edge"), &pb.ExposureEvent{BookingId: "booking_own"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	recorded, err := measurements.RecordExposure(as("edge"), exposure("booking_own", "viewer_1"))
+	require.NoError(t, err)
+	assert.Equal(t, "event_viewer_1", recorded.EventId)
+	require.Len(t, store.events, 1)
+	assert.Equal(t, 5.0, store.events[0].ExposureDuration)
+
+	batch, err := measurements.BatchRecordExposures(as("edge"), &pb.BatchRecordExposuresRequest{Events: []*pb.ExposureEvent{
+		exposure("booking_own", "viewer_2"),
+		{EventId: "beacon_1", BookingId: "booking_other", ViewerId: "viewer_3", ExposureDuration: durationpb.New(5e9)},
+		{BookingId: "booking_own"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), batch.ProcessedCount)
+	assert.Equal(t, int32(2), batch.FailedCount)
+	assert.Equal(t, []string{"beacon_1", "#2"}, batch.FailedEventIds, "Failures should be reported in batch order")
+	assert.Len(t, batch.ErrorMessages, 2)
+	assert.Len(t, store.events, 2)
+}
+
+func TestServer_ScansExposuresForPersonalData(t *testing.T) {
+	store := &mockStore{}
+	_, measurements := dial(t, store, func(s *Server) {
+		policy, err := pii.ParsePolicy("viewer_id=reject,device_type=mask", pii.ActionMask)
+		require.NoError(t, err)
+		s.SetPIIPolicy(policy, nil)
+	})
+
+	_, err := measurements.RecordExposure(as("edge"), &pb.ExposureEvent{BookingId: "booking_own", ViewerId: "jane@example.com", ExposureDuration: durationpb.New(5e9)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Rejected fields should refuse the call")
+	assert.Empty(t, store.events)
+
+	_, err = measurements.RecordExposure(as("edge"), &pb.ExposureEvent{BookingId: "booking_own", ViewerId: "viewer_1", DeviceType: "jane@example.com", ExposureDuration: durationpb.New(5e9)})
+	require.NoError(t, err)
+	require.Len(t, store.events, 1)
+	assert.NotContains(t, store.events[0].DeviceType, "jane@example.com", "Masked fields should be stored masked")
+	assert.Equal(t, "viewer_1", store.events[0].ViewerID)
+}
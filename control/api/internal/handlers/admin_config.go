@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/settings"
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigSource lists the gateway's resolved configuration, secrets redacted
+type ConfigSource interface {
+	Path() string
+	Settings() []settings.Setting
+}
+
+// BuildInfo identifies the running gateway binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+}
+
+// ConfigHandler shows support the effective configuration without shell
+// access to the pods
+type ConfigHandler struct {
+	source    ConfigSource
+	build     BuildInfo
+	startedAt time.Time
+}
+
+// NewConfigHandler creates a config handler
+func NewConfigHandler(source ConfigSource, build BuildInfo) *ConfigHandler {
+	return &ConfigHandler{source: source, build: build, startedAt: time.Now().UTC()}
+}
+
+// GetConfig handles GET /admin/config. Each setting reports whether it came
+// from the environment, the config file or its default.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"audit":   "admin_config",
+		"user_id": c.GetString("user_id"),
+	}).Info("Configuration viewed")
+
+	c.JSON(http.StatusOK, gin.H{
+		"build":       h.build,
+		"started_at":  h.startedAt,
+		"config_file": h.source.Path(),
+		"settings":    h.source.Settings(),
+	})
+}
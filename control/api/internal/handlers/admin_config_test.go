@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	environment := map[string]string{
+		"API_PORT":         "9090",
+		"JWT_SECRET":       "s3cret",
+		"POSTGRES_DSN":     "postgresql://inscenium:hunter2@db:5432/inscenium",
+		"RATE_LIMIT_BURST": "lots",
+	}
+	loader := settings.NewLoader(map[string]string{
+		"API_PORT":  "7070",
+		"LOG_LEVEL": "DEBUG",
+	}, func(key string) (string, bool) {
+		value, ok := environment[key]
+		return value, ok
+	})
+	loader.String("API_PORT", "8080")
+	loader.String("LOG_LEVEL", "INFO")
+	loader.String("JWT_SECRET", "dev-secret-key")
+	loader.String("POSTGRES_DSN", "")
+	loader.String("STORAGE_ACCESS_KEY_ID", "")
+	loader.Int("RATE_LIMIT_BURST", 100)
+	loader.Duration("BOOKING_POLL_INTERVAL", time.Second)
+
+	handler := NewConfigHandler(loader, BuildInfo{Version: "1.2.3"})
+	router := gin.New()
+	router.Use(withOrg("support@inscenium.io", ""))
+	router.GET("/admin/config", middleware.RequireAdmin([]string{"support@inscenium.io"}), handler.GetConfig)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "s3cret", "Should never show secrets")
+	assert.NotContains(t, resp.Body.String(), "hunter2", "Should remove passwords from URLs")
+
+	var response struct {
+		Build    BuildInfo          `json:"build"`
+		Settings []settings.Setting `json:"settings"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "1.2.3", response.Build.Version)
+
+	byKey := map[string]settings.Setting{}
+	for _, setting := range response.Settings {
+		byKey[setting.Key] = setting
+	}
+	assert.Equal(t, settings.Setting{Key: "API_PORT", Value: "9090", Source: settings.SourceEnv}, byKey["API_PORT"], "Should let the environment override the file")
+	assert.Equal(t, settings.Setting{Key: "LOG_LEVEL", Value: "DEBUG", Source: settings.SourceFile}, byKey["LOG_LEVEL"])
+	assert.Equal(t, settings.Setting{Key: "JWT_SECRET", Value: "[redacted]", Source: settings.SourceEnv, Secret: true}, byKey["JWT_SECRET"])
+	assert.Equal(t, "postgresql://inscenium:xxxxx@db:5432/inscenium", byKey["POSTGRES_DSN"].Value)
+	assert.False(t, byKey["STORAGE_ACCESS_KEY_ID"].Secret, "Should show key IDs")
+	assert.Equal(t, settings.Setting{Key: "RATE_LIMIT_BURST", Value: "100", Source: settings.SourceDefault, Invalid: true}, byKey["RATE_LIMIT_BURST"], "Should flag values that fell back to the default")
+	assert.Equal(t, settings.Setting{Key: "BOOKING_POLL_INTERVAL", Value: "1s", Source: settings.SourceDefault}, byKey["BOOKING_POLL_INTERVAL"])
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		principal      string
+		userID         string
+		scopes         []string
+		expectedStatus int
+		description    string
+	}{
+		{"admin user", middleware.PrincipalUser, "support@inscenium.io", nil, http.StatusOK, "Should admit listed users"},
+		{"other user", middleware.PrincipalUser, "buyer@acme.com", nil, http.StatusForbidden, "Should reject users not listed"},
+		{"scoped service account", middleware.PrincipalServiceAccount, "sa:sa_ops", []string{"admin:read"}, http.StatusOK, "Should admit service accounts with admin:read"},
+		{"unscoped service account", middleware.PrincipalServiceAccount, "sa:sa_buyer", []string{"bookings:*"}, http.StatusForbidden, "Should reject other service accounts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("principal_type", tt.principal)
+				c.Set("user_id", tt.userID)
+				c.Set("scopes", tt.scopes)
+				c.Next()
+			})
+			router.GET("/admin/config", middleware.RequireAdmin([]string{"support@inscenium.io"}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
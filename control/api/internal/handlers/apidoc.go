@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// apiDocCacheTTL is how long clients may cache the spec
+const apiDocCacheTTL = 300
+
+// swaggerUIVersion pins the Swagger UI release the docs page loads
+const swaggerUIVersion = "5.17.14"
+
+// APIDocHandler serves the OpenAPI spec and, outside production, Swagger UI
+type APIDocHandler struct {
+	spec []byte
+	etag string
+
+	// routes maps each documented route template, e.g. /api/v1/bookings/{}
+	// for /bookings/{booking_id}, to its methods in upper case
+	routes map[string]map[string]bool
+}
+
+// NewAPIDocHandler creates an API doc handler from the OpenAPI document in
+// YAML. The document is converted to JSON once, here.
+func NewAPIDocHandler(spec []byte) (*APIDocHandler, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if _, ok := document["openapi"]; !ok {
+		return nil, fmt.Errorf("OpenAPI spec has no openapi version")
+	}
+
+	body, err := json.Marshal(jsonCompatible(document))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI spec: %w", err)
+	}
+	sum := sha256.Sum256(body)
+
+	h := &APIDocHandler{
+		spec:   body,
+		etag:   `"` + hex.EncodeToString(sum[:8]) + `"`,
+		routes: make(map[string]map[string]bool),
+	}
+	basePath := serverPath(document["servers"])
+	paths, _ := document["paths"].(map[string]interface{})
+	for path, item := range paths {
+		operations, _ := item.(map[string]interface{})
+		methods := make(map[string]bool)
+		for method := range operations {
+			methods[strings.ToUpper(method)] = true
+		}
+		// System endpoints override the servers to be served outside /api/v1
+		base := basePath
+		if servers, ok := operations["servers"]; ok {
+			base = serverPath(servers)
+		}
+		h.routes[pathTemplate(base+path)] = methods
+	}
+	return h, nil
+}
+
+// serverPath returns the path of the first of an OpenAPI servers list, e.g.
+// /api/v1 for http://localhost:8080/api/v1. The first server is the local
+// one, whose paths match the gateway's routes.
+func serverPath(servers interface{}) string {
+	list, _ := servers.([]interface{})
+	if len(list) == 0 {
+		return ""
+	}
+	server, _ := list[0].(map[string]interface{})
+	u, err := url.Parse(fmt.Sprint(server["url"]))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+// jsonCompatible converts YAML maps with non-string keys, such as unquoted
+// response codes, into maps JSON can encode
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// GetSpec handles GET /openapi.json
+func (h *APIDocHandler) GetSpec(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", apiDocCacheTTL))
+	c.Header("ETag", h.etag)
+	if strings.TrimPrefix(c.GetHeader("If-None-Match"), "W/") == h.etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// SwaggerUI handles GET /docs with a Swagger UI page for the served spec.
+// The page loads Swagger UI from a CDN, so it is only routed outside production.
+func (h *APIDocHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(fmt.Sprintf(swaggerUIPage, swaggerUIVersion, swaggerUIVersion)))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Inscenium API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@%s/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// UndocumentedRoutes lists routes, as "METHOD /path", that the spec does not
+// describe
+func (h *APIDocHandler) UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	var missing []string
+	for _, route := range routes {
+		if !h.routes[pathTemplate(route.Path)][route.Method] {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// pathTemplate drops parameter names from a gin route or OpenAPI path, so
+// /bookings/:id and /bookings/{booking_id} both become /bookings/{}
+func pathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") || strings.HasPrefix(segment, "{") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
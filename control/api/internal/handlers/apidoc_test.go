@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIDocHandler_GetSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := os.ReadFile("../../openapi.yaml")
+	require.NoError(t, err)
+	handler, err := NewAPIDocHandler(spec)
+	require.NoError(t, err, "openapi.yaml should parse")
+
+	router := gin.New()
+	router.GET("/openapi.json", handler.GetSpec)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "application/json")
+
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &document))
+	assert.Equal(t, "3.0.3", document["openapi"])
+	paths, ok := document["paths"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, paths, "/opportunities")
+	assert.Contains(t, paths, "/openapi.json")
+
+	etag := resp.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Empty(t, resp.Body.String())
+}
+
+func TestAPIDocHandler_InvalidSpec(t *testing.T) {
+	_, err := NewAPIDocHandler([]byte("paths: [unterminated"))
+	assert.Error(t, err)
+	_, err = NewAPIDocHandler([]byte("info:\n  title: Not OpenAPI\n"))
+	assert.Error(t, err, "A document without an openapi version should be refused")
+}
+
+func TestAPIDocHandler_NonStringKeys(t *testing.T) {
+	handler, err := NewAPIDocHandler([]byte(`
+openapi: 3.0.3
+paths:
+  /health:
+    get:
+      responses:
+        200:
+          description: OK
+`))
+	require.NoError(t, err)
+
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(handler.spec, &document))
+	responses := document["paths"].(map[string]interface{})["/health"].(map[string]interface{})["get"].(map[string]interface{})["responses"]
+	assert.Contains(t, responses, "200", "Unquoted response codes should become string keys")
+}
+
+func TestAPIDocHandler_UndocumentedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler, err := NewAPIDocHandler([]byte(`
+openapi: 3.0.3
+servers:
+  - url: http://localhost:8080/api/v1
+paths:
+  /health:
+    servers:
+      - url: http://localhost:8080
+    get: {}
+  /bookings/{booking_id}:
+    get: {}
+`))
+	require.NoError(t, err)
+
+	noop := func(c *gin.Context) {}
+	router := gin.New()
+	router.GET("/health", noop)
+	router.GET("/metrics", noop)
+	router.GET("/api/v1/bookings/:id", noop)
+	router.DELETE("/api/v1/bookings/:id", noop)
+	router.GET("/bookings/:id", noop)
+
+	assert.Equal(t, []string{
+		"DELETE /api/v1/bookings/:id",
+		"GET /bookings/:id",
+		"GET /metrics",
+	}, handler.UndocumentedRoutes(router.Routes()))
+}
+
+func TestAPIDocHandler_SwaggerUI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler, err := NewAPIDocHandler([]byte("openapi: 3.0.3\n"))
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/docs", handler.SwaggerUI)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, resp.Body.String(), `url: "/openapi.json"`)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/apply"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// ApplyStore plans declarative documents against the current state and applies them
+type ApplyStore interface {
+	ApplyDocument(req *apply.Request) (*apply.Plan, error)
+}
+
+// ApplyHandler manages campaigns, bookings and creatives from declarative
+// documents, for buyers who keep their campaigns as code
+type ApplyHandler struct {
+	db    ApplyStore
+	authz *authz.Authorizer
+}
+
+// NewApplyHandler creates a new apply handler
+func NewApplyHandler(store ApplyStore) *ApplyHandler {
+	return &ApplyHandler{db: store}
+}
+
+// SetAuthorizer requires manage access to every campaign and creative a
+// document declares
+func (h *ApplyHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// Apply handles POST /apply. With dry_run it returns the plan without
+// changing anything; with the plan_hash of a reviewed plan it applies only
+// if the plan is still the same.
+func (h *ApplyHandler) Apply(c *gin.Context) {
+	var req struct {
+		Document *apply.Document `json:"document" binding:"required"`
+		DryRun   bool            `json:"dry_run"`
+		PlanHash string          `json:"plan_hash"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if problems := req.Document.Validate(); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Document is invalid", "problems": problems})
+		return
+	}
+	for _, campaign := range req.Document.Campaigns {
+		if !h.authz.Authorize(c, labels.ResourceCampaign, campaign.CampaignID, authz.PermissionManage) {
+			return
+		}
+	}
+	for _, creative := range req.Document.Creatives {
+		if !h.authz.Authorize(c, labels.ResourceCreative, creative.CreativeID, authz.PermissionManage) {
+			return
+		}
+	}
+
+	applyReq := &apply.Request{
+		Document: *req.Document,
+		DryRun:   req.DryRun || c.Query("dry_run") == "true",
+		PlanHash: req.PlanHash,
+		OrgID:    c.GetString("org_id"),
+		UserID:   c.GetString("user_id"),
+	}
+	plan, err := h.db.ApplyDocument(applyReq)
+	if errors.Is(err, apply.ErrPlanChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "plan": plan})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to apply document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if len(plan.Problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Document cannot be applied", "plan": plan})
+		return
+	}
+	if applyReq.DryRun {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":     "apply",
+		"user_id":   applyReq.UserID,
+		"org_id":    applyReq.OrgID,
+		"plan_hash": plan.PlanHash,
+		"created":   plan.Summary.Create,
+		"updated":   plan.Summary.Update,
+		"deleted":   plan.Summary.Delete,
+	}).Info("Applied document")
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/apply"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockApplyStore plans documents against an in-memory state
+type MockApplyStore struct {
+	state       *apply.State
+	requests    []*apply.Request
+	problems    []apply.Problem // Found in the target state
+	shouldError bool
+}
+
+func (m *MockApplyStore) ApplyDocument(req *apply.Request) (*apply.Plan, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.requests = append(m.requests, req)
+	state := m.state
+	if state == nil {
+		state = &apply.State{}
+	}
+	plan := apply.Diff(&req.Document, state)
+	plan.DryRun = req.DryRun
+	plan.Problems = append(plan.Problems, m.problems...)
+	if len(plan.Problems) > 0 || req.DryRun {
+		return plan, nil
+	}
+	if req.PlanHash != "" && req.PlanHash != plan.PlanHash {
+		return plan, apply.ErrPlanChanged
+	}
+	appliedAt := time.Now().UTC()
+	plan.AppliedAt = &appliedAt
+	return plan, nil
+}
+
+func liveState() *apply.State {
+	budget := 1000.0
+	return &apply.State{
+		Campaigns: map[string]*apply.CampaignState{
+			"camp_1": {
+				Budget: &budget,
+				Labels: labels.Set{"team": "sports"},
+				Bookings: []apply.BookingState{
+					{BookingID: "booking_s1_1", SurfaceID: "surface_1", Status: "confirmed",
+						Terms:  apply.Terms{AdvertiserID: "adv_1", BidAmountCPM: 5, MaxImpressions: 1000, MinPRSScore: 70},
+						Labels: labels.Set{"tier": "gold"}},
+					{BookingID: "booking_s2_1", SurfaceID: "surface_2", Status: "confirmed",
+						Terms: apply.Terms{AdvertiserID: "adv_1", BidAmountCPM: 4, MaxImpressions: 500, MinPRSScore: 70}},
+					{BookingID: "booking_s3_1", SurfaceID: "surface_3", Status: "pending",
+						Terms: apply.Terms{AdvertiserID: "adv_1", BidAmountCPM: 3, MaxImpressions: 100, MinPRSScore: 70}},
+				},
+			},
+		},
+	}
+}
+
+func postApply(t *testing.T, handler *ApplyHandler, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.POST("/apply", handler.Apply)
+
+	req := httptest.NewRequest(http.MethodPost, "/apply", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func decodePlan(t *testing.T, resp *httptest.ResponseRecorder) apply.Plan {
+	var plan apply.Plan
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &plan))
+	return plan
+}
+
+func TestApplyHandler_Plan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("new campaign", func(t *testing.T) {
+		store := &MockApplyStore{}
+		resp := postApply(t, NewApplyHandler(store), `{"dry_run": true, "document": {
+			"creatives": [{"creative_id": "cr_1", "labels": {"format": "billboard"}}],
+			"campaigns": [{"campaign_id": "camp_new", "advertiser_id": "adv_1", "budget": 2500,
+				"targeting": {"min_prs_score": 80},
+				"bookings": [
+					{"surface_id": "surface_1", "bid_amount_cpm": 5, "max_impressions": 1000, "creative_id": "cr_1"},
+					{"surface_id": "surface_2", "bid_amount_cpm": 4, "min_prs_score": 60, "paused": true}
+				]}]}}`)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		plan := decodePlan(t, resp)
+		assert.True(t, plan.DryRun)
+		assert.Nil(t, plan.AppliedAt)
+		assert.Equal(t, apply.Summary{Create: 4}, plan.Summary)
+		require.Len(t, plan.Changes, 4)
+		assert.Equal(t, labels.ResourceCreative, plan.Changes[0].ResourceType)
+		assert.Equal(t, labels.ResourceCampaign, plan.Changes[1].ResourceType)
+		assert.True(t, plan.Changes[1].Sets("budget"))
+
+		first := plan.Changes[2]
+		assert.Equal(t, apply.ActionCreate, first.Action)
+		assert.Empty(t, first.ResourceID)
+		assert.Equal(t, "campaigns[0].bookings[0]", first.Path)
+		assert.Contains(t, first.Fields, apply.FieldChange{Field: "min_prs_score", New: 80.0}, "Should apply the campaign's targeting")
+		assert.Contains(t, first.Fields, apply.FieldChange{Field: "creative_id", New: "cr_1"})
+		second := plan.Changes[3]
+		assert.Contains(t, second.Fields, apply.FieldChange{Field: "min_prs_score", New: 60.0}, "Should let a booking override the targeting")
+		assert.Contains(t, second.Fields, apply.FieldChange{Field: "paused", New: true})
+		assert.NotEmpty(t, plan.PlanHash)
+		assert.Equal(t, "org_a", store.requests[0].OrgID)
+	})
+
+	t.Run("changes to live campaign", func(t *testing.T) {
+		store := &MockApplyStore{state: liveState()}
+		resp := postApply(t, NewApplyHandler(store), `{"dry_run": true, "document": {"campaigns": [{
+			"campaign_id": "camp_1", "advertiser_id": "adv_1", "budget": 1000, "labels": {"team": "sports"},
+			"targeting": {"min_prs_score": 70},
+			"bookings": [
+				{"surface_id": "surface_1", "bid_amount_cpm": 6.5, "max_impressions": 1000},
+				{"surface_id": "surface_3", "bid_amount_cpm": 3.001, "max_impressions": 100},
+				{"surface_id": "surface_4", "bid_amount_cpm": 2}
+			]}]}}`)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		plan := decodePlan(t, resp)
+		assert.Equal(t, apply.Summary{Create: 1, Update: 1, Delete: 1, Unchanged: 2}, plan.Summary,
+			"Campaign and the surface_3 booking (bid equal to the cent) are unchanged")
+
+		byAction := make(map[string]apply.Change)
+		for _, change := range plan.Changes {
+			byAction[change.Action] = change
+		}
+		update := byAction[apply.ActionUpdate]
+		assert.Equal(t, "booking_s1_1", update.ResourceID)
+		assert.Equal(t, []apply.FieldChange{{Field: "bid_amount_cpm", Old: 5.0, New: 6.5}}, update.Fields,
+			"Should leave undeclared booking labels alone")
+		assert.Equal(t, "booking_s2_1", byAction[apply.ActionDelete].ResourceID, "Should cancel undeclared bookings")
+		assert.Equal(t, "surface_4", byAction[apply.ActionCreate].SurfaceID)
+	})
+
+	t.Run("bookings not managed", func(t *testing.T) {
+		store := &MockApplyStore{state: liveState()}
+		resp := postApply(t, NewApplyHandler(store), `{"dry_run": true, "document": {"campaigns": [{
+			"campaign_id": "camp_1", "budget": 1500, "labels": {}}]}}`)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		plan := decodePlan(t, resp)
+		require.Len(t, plan.Changes, 1, "Should not touch bookings of a campaign that lists none")
+		assert.Equal(t, apply.ActionUpdate, plan.Changes[0].Action)
+		assert.True(t, plan.Changes[0].Sets("budget"))
+		assert.True(t, plan.Changes[0].Sets("labels"), "Should clear labels declared empty")
+	})
+}
+
+func TestApplyHandler_Apply(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	document := `{"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1",
+		"bookings": [{"surface_id": "surface_1", "bid_amount_cpm": 6, "max_impressions": 1000, "min_prs_score": 70}]}]}`
+
+	store := &MockApplyStore{state: liveState()}
+	handler := NewApplyHandler(store)
+	resp := postApply(t, handler, `{"dry_run": true, "document": `+document+`}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	reviewed := decodePlan(t, resp)
+
+	resp = postApply(t, handler, `{"plan_hash": "`+reviewed.PlanHash+`", "document": `+document+`}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	applied := decodePlan(t, resp)
+	assert.False(t, applied.DryRun)
+	assert.NotNil(t, applied.AppliedAt)
+	assert.Equal(t, reviewed.PlanHash, applied.PlanHash)
+
+	// Another change lands between review and apply
+	store.state.Campaigns["camp_1"].Bookings[0].Terms.BidAmountCPM = 5.5
+	resp = postApply(t, handler, `{"plan_hash": "`+reviewed.PlanHash+`", "document": `+document+`}`)
+	assert.Equal(t, http.StatusConflict, resp.Code, "Should refuse a plan that no longer matches the state")
+}
+
+func TestApplyHandler_Problems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		store          *MockApplyStore
+		expectedStatus int
+		expectedPath   string
+		description    string
+	}{
+		{
+			name:           "missing document",
+			body:           `{"dry_run": true}`,
+			store:          &MockApplyStore{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a document",
+		},
+		{
+			name:           "surface booked twice",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1", "bookings": [{"surface_id": "s1", "bid_amount_cpm": 1}, {"surface_id": "s1", "bid_amount_cpm": 2}]}]}}`,
+			store:          &MockApplyStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[0].bookings[1].surface_id",
+			description:    "Should identify bookings by surface within a campaign",
+		},
+		{
+			name:           "missing advertiser",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "bookings": [{"surface_id": "s1", "bid_amount_cpm": 1}]}]}}`,
+			store:          &MockApplyStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[0].advertiser_id",
+			description:    "Should require an advertiser for bookings",
+		},
+		{
+			name:           "bad bid",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1", "bookings": [{"surface_id": "s1", "bid_amount_cpm": 0}]}]}}`,
+			store:          &MockApplyStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[0].bookings[0].bid_amount_cpm",
+			description:    "Should reject bids that are not positive",
+		},
+		{
+			name:           "surface created by two campaigns",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1", "bookings": [{"surface_id": "s9", "bid_amount_cpm": 1}]}, {"campaign_id": "camp_2", "advertiser_id": "adv_1", "bookings": [{"surface_id": "s9", "bid_amount_cpm": 1}]}]}}`,
+			store:          &MockApplyStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[1].bookings[0]",
+			description:    "Should create one booking per surface per apply",
+		},
+		{
+			name:           "pausing pending booking",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1", "targeting": {"min_prs_score": 70}, "bookings": [{"surface_id": "surface_3", "bid_amount_cpm": 3, "max_impressions": 100, "paused": true}]}]}}`,
+			store:          &MockApplyStore{state: liveState()},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[0].bookings[0].paused",
+			description:    "Should not pause bookings awaiting approval",
+		},
+		{
+			name:           "state problems",
+			body:           `{"document": {"campaigns": [{"campaign_id": "camp_1", "advertiser_id": "adv_1", "bookings": [{"surface_id": "s_gone", "bid_amount_cpm": 1}]}]}}`,
+			store:          &MockApplyStore{problems: []apply.Problem{{Path: "campaigns[0].bookings[0].surface_id", Message: "surface s_gone does not exist"}}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPath:   "campaigns[0].bookings[0].surface_id",
+			description:    "Should return problems found in the current state",
+		},
+		{
+			name:           "store error",
+			body:           `{"document": {"creatives": [{"creative_id": "cr_1"}]}}`,
+			store:          &MockApplyStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postApply(t, NewApplyHandler(tt.store), tt.body)
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedPath == "" {
+				return
+			}
+
+			var response struct {
+				Problems []apply.Problem `json:"problems"`
+				Plan     *apply.Plan     `json:"plan"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			problems := response.Problems
+			if response.Plan != nil {
+				problems = response.Plan.Problems
+			}
+			paths := make([]string, 0, len(problems))
+			for _, p := range problems {
+				paths = append(paths, p.Path)
+			}
+			assert.Contains(t, paths, tt.expectedPath, tt.description)
+		})
+	}
+}
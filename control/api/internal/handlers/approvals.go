@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/approval"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// approvalWebhookSource namespaces approval callback nonces
+const approvalWebhookSource = "approval"
+
+// approvalDecisionsShown bounds the decision log returned with an approval
+const approvalDecisionsShown = 20
+
+// ApprovalStore persists creative approvals, their decisions and webhook
+// nonces
+type ApprovalStore interface {
+	ListCreativeApprovals(scope tenant.Scope, status string, limit, offset int) ([]approval.Approval, error)
+	GetCreativeApproval(bookingID string) (*approval.Approval, error)
+	DecideCreativeApproval(d *approval.Decision) (*approval.Result, error)
+	ListCreativeApprovalDecisions(bookingID string, limit int) ([]approval.Decision, error)
+	ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// ApprovalHandler exports creative approvals to publishers' ad ops tools and
+// takes their decisions back, alongside decisions made in Inscenium
+type ApprovalHandler struct {
+	db       ApprovalStore
+	verifier *webhook.Verifier
+}
+
+// NewApprovalHandler creates a new approval handler. Delegated callbacks are
+// rejected unless the verifier has at least one secret.
+func NewApprovalHandler(store ApprovalStore, verifier *webhook.Verifier) *ApprovalHandler {
+	return &ApprovalHandler{db: store, verifier: verifier}
+}
+
+// decideRequest is a decision made through the API
+type decideRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approved rejected"`
+	Reason   string `json:"reason"`
+}
+
+// ListApprovals handles GET /approvals, the creatives of live bookings
+// awaiting a decision unless another status is asked for
+func (h *ApprovalHandler) ListApprovals(c *gin.Context) {
+	status := c.DefaultQuery("status", approval.StatusPending)
+	if status != approval.StatusPending && status != approval.StatusApproved && status != approval.StatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved or rejected"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	approvals, err := h.db.ListCreativeApprovals(authz.Scope(c), status, limit, offset)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list creative approvals")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals":   approvals,
+		"status":      status,
+		"total_count": len(approvals),
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetApproval handles GET /bookings/:id/approval
+func (h *ApprovalHandler) GetApproval(c *gin.Context) {
+	bookingID := c.Param("id")
+	standing, err := h.db.GetCreativeApproval(bookingID)
+	if err != nil {
+		h.approvalError(c, bookingID, err)
+		return
+	}
+
+	decisions, err := h.db.ListCreativeApprovalDecisions(bookingID, approvalDecisionsShown)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to list approval decisions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approval":  standing,
+		"decisions": decisions,
+	})
+}
+
+// DecideApproval handles PUT /bookings/:id/approval. A decision made in
+// Inscenium overrides any made by a delegated tool.
+func (h *ApprovalHandler) DecideApproval(c *gin.Context) {
+	var req decideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d := &approval.Decision{
+		BookingID: c.Param("id"),
+		Status:    req.Decision,
+		Source:    approval.SourcePlatform,
+		Reviewer:  authz.ActorFromContext(c).UserID,
+		Reason:    req.Reason,
+		DecidedAt: time.Now().UTC(),
+	}
+	h.decide(c, d)
+}
+
+// ApprovalCallback handles POST /webhooks/approvals, decisions posted by a
+// publisher's ad ops tool. Deliveries are signed like render callbacks.
+func (h *ApprovalHandler) ApprovalCallback(c *gin.Context) {
+	if !h.verifier.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Approval webhooks are not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(body) > maxCallbackBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Callback body too large"})
+		return
+	}
+
+	nonce := c.GetHeader(webhook.HeaderNonce)
+	signedAt, err := h.verifier.Verify(c.GetHeader(webhook.HeaderTimestamp), nonce, c.GetHeader(webhook.HeaderSignature), body)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"audit":     "webhook",
+			"source":    approvalWebhookSource,
+			"client_ip": c.ClientIP(),
+		}).WithError(err).Warn("Rejected approval callback signature")
+		metrics.ApprovalDecisions.WithLabelValues(approval.SourceDelegated, callbackRejected).Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	claimed, err := h.db.ClaimWebhookNonce(approvalWebhookSource, nonce, signedAt.Add(h.verifier.Tolerance()))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to record approval callback nonce")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !claimed {
+		logrus.WithFields(logrus.Fields{
+			"audit":  "webhook",
+			"source": approvalWebhookSource,
+			"nonce":  nonce,
+		}).Warn("Rejected replayed approval callback")
+		metrics.ApprovalDecisions.WithLabelValues(approval.SourceDelegated, callbackRejected).Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": "Nonce already used"})
+		return
+	}
+
+	var callback approval.Callback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body"})
+		return
+	}
+	if callback.DecidedAt.IsZero() {
+		callback.DecidedAt = signedAt
+	}
+	d, err := callback.Decision()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d.DecidedAt = d.DecidedAt.UTC()
+	h.decide(c, d)
+}
+
+// decide records a decision and responds with its outcome and the approval
+// standing after it
+func (h *ApprovalHandler) decide(c *gin.Context, d *approval.Decision) {
+	if err := d.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.db.DecideCreativeApproval(d)
+	if err != nil {
+		h.approvalError(c, d.BookingID, err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "approval",
+		"booking_id":  d.BookingID,
+		"creative_id": d.CreativeAssetID,
+		"decision":    d.Status,
+		"source":      d.Source,
+		"reviewer":    d.Reviewer,
+		"outcome":     d.Outcome,
+		"conflict":    d.Conflict,
+	}).Info("Recorded creative approval decision")
+	metrics.ApprovalDecisions.WithLabelValues(d.Source, d.Outcome).Inc()
+
+	c.JSON(http.StatusOK, result)
+}
+
+// approvalError responds to a failure reading or deciding an approval
+func (h *ApprovalHandler) approvalError(c *gin.Context, bookingID string, err error) {
+	switch {
+	case errors.Is(err, db.ErrBookingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errors.Is(err, approval.ErrNoCreative):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Booking has no creative to approve"})
+	default:
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to get creative approval")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
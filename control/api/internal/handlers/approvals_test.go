@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/approval"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testApprovalSecret = "approval-secret"
+
+// MockApprovalStore keeps approvals in memory, settling decisions with
+// approval.Resolve as the database does
+type MockApprovalStore struct {
+	approvals map[string]approval.Approval
+	decisions []approval.Decision
+	nonces    map[string]bool
+	scope     tenant.Scope
+}
+
+func newMockApprovalStore(approvals ...approval.Approval) *MockApprovalStore {
+	m := &MockApprovalStore{approvals: map[string]approval.Approval{}, nonces: map[string]bool{}}
+	for _, a := range approvals {
+		m.approvals[a.BookingID] = a
+	}
+	return m
+}
+
+func (m *MockApprovalStore) ListCreativeApprovals(scope tenant.Scope, status string, limit, offset int) ([]approval.Approval, error) {
+	m.scope = scope
+	approvals := make([]approval.Approval, 0)
+	for _, a := range m.approvals {
+		if a.Status == status && a.CreativeAssetID != "" {
+			approvals = append(approvals, a)
+		}
+	}
+	return approvals, nil
+}
+
+func (m *MockApprovalStore) GetCreativeApproval(bookingID string) (*approval.Approval, error) {
+	a, ok := m.approvals[bookingID]
+	if !ok {
+		return nil, db.ErrBookingNotFound
+	}
+	if a.CreativeAssetID == "" {
+		return nil, approval.ErrNoCreative
+	}
+	return &a, nil
+}
+
+func (m *MockApprovalStore) DecideCreativeApproval(d *approval.Decision) (*approval.Result, error) {
+	standing, err := m.GetCreativeApproval(d.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if d.CreativeAssetID == "" {
+		d.CreativeAssetID = standing.CreativeAssetID
+	}
+	next := approval.Resolve(*standing, d)
+	d.ReceivedAt = time.Now().UTC()
+	d.DecisionID = "decision_" + d.BookingID
+	m.decisions = append(m.decisions, *d)
+	m.approvals[d.BookingID] = next
+	return &approval.Result{Decision: d, Approval: &next}, nil
+}
+
+func (m *MockApprovalStore) ListCreativeApprovalDecisions(bookingID string, limit int) ([]approval.Decision, error) {
+	decisions := make([]approval.Decision, 0)
+	for _, d := range m.decisions {
+		if d.BookingID == bookingID {
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions, nil
+}
+
+func (m *MockApprovalStore) ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error) {
+	if m.nonces[source+"/"+nonce] {
+		return false, nil
+	}
+	m.nonces[source+"/"+nonce] = true
+	return true, nil
+}
+
+// signedApprovalCallback builds an approval callback signed with secret
+func signedApprovalCallback(secret, nonce string, body string) *http.Request {
+	req := signedCallback(secret, nonce, time.Now(), body)
+	req.URL.Path = "/webhooks/approvals"
+	return req
+}
+
+func approvalCallbackBody(t *testing.T, event, creativeID string, decidedAt time.Time) string {
+	body, err := json.Marshal(gin.H{
+		"event":             event,
+		"booking_id":        "booking_123",
+		"creative_asset_id": creativeID,
+		"reviewer":          "adops@publisher.example",
+		"external_ref":      "gam-42",
+		"decided_at":        decidedAt,
+	})
+	require.NoError(t, err)
+	return string(body)
+}
+
+func newApprovalRouter(store *MockApprovalStore) *gin.Engine {
+	handler := NewApprovalHandler(store, webhook.NewVerifier([]string{testApprovalSecret}, webhook.DefaultTolerance))
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("org_id", "org_a"); c.Set("user_id", "user_1") })
+	router.GET("/approvals", handler.ListApprovals)
+	router.GET("/bookings/:id/approval", handler.GetApproval)
+	router.PUT("/bookings/:id/approval", handler.DecideApproval)
+	router.POST("/webhooks/approvals", handler.ApprovalCallback)
+	return router
+}
+
+func TestApprovalHandler_ApprovalCallbackPrecedence(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newMockApprovalStore(approval.Approval{BookingID: "booking_123", CreativeAssetID: "creative_b", Status: approval.StatusPending})
+	router := newApprovalRouter(store)
+
+	tests := []struct {
+		name             string
+		request          func() *http.Request
+		expectedStatus   int
+		expectedOutcome  string
+		expectedConflict bool
+		expectedStanding string
+		description      string
+	}{
+		{
+			name: "first delegated decision",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "n1", approvalCallbackBody(t, approval.EventApproved, "creative_b", base))
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeApplied,
+			expectedStanding: approval.StatusApproved,
+			description:      "Should apply a decision on a pending creative",
+		},
+		{
+			name: "older delegated decision arriving late",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "n2", approvalCallbackBody(t, approval.EventRejected, "creative_b", base.Add(-time.Minute)))
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeOverruled,
+			expectedConflict: true,
+			expectedStanding: approval.StatusApproved,
+			description:      "Should order delegated decisions by when they were made, not received",
+		},
+		{
+			name: "rejection at the same instant",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "n3", approvalCallbackBody(t, approval.EventRejected, "creative_b", base))
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeApplied,
+			expectedConflict: true,
+			expectedStanding: approval.StatusRejected,
+			description:      "Should let a rejection beat an approval decided at the same instant",
+		},
+		{
+			name: "decision about a replaced creative",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "n4", approvalCallbackBody(t, approval.EventApproved, "creative_a", base.Add(time.Hour)))
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeStale,
+			expectedStanding: approval.StatusRejected,
+			description:      "Should ignore decisions about a creative the booking no longer runs",
+		},
+		{
+			name: "platform override",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPut, "/bookings/booking_123/approval", bytes.NewBufferString(`{"decision":"approved","reason":"Cleared by legal"}`))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeApplied,
+			expectedConflict: true,
+			expectedStanding: approval.StatusApproved,
+			description:      "Should let a decision made in Inscenium override a delegated one",
+		},
+		{
+			name: "newer delegated decision after an override",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "n5", approvalCallbackBody(t, approval.EventRejected, "creative_b", time.Now().Add(time.Minute)))
+			},
+			expectedStatus:   http.StatusOK,
+			expectedOutcome:  approval.OutcomeOverruled,
+			expectedConflict: true,
+			expectedStanding: approval.StatusApproved,
+			description:      "Should keep a platform decision over any delegated one, however recent",
+		},
+	}
+
+	for _, tt := range tests {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, tt.request())
+		require.Equal(t, tt.expectedStatus, resp.Code, tt.name+": "+resp.Body.String())
+
+		var result approval.Result
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		assert.Equal(t, tt.expectedOutcome, result.Decision.Outcome, tt.description)
+		assert.Equal(t, tt.expectedConflict, result.Decision.Conflict, tt.description)
+		assert.Equal(t, tt.expectedStanding, result.Approval.Status, tt.description)
+	}
+
+	standing := store.approvals["booking_123"]
+	assert.Equal(t, approval.SourcePlatform, standing.Source)
+	assert.Equal(t, "user_1", standing.Reviewer, "Platform decisions should be attributed to the caller")
+	assert.Equal(t, "Cleared by legal", standing.Reason)
+	require.Len(t, store.decisions, len(tests), "Every decision should be logged, whatever its outcome")
+	assert.Equal(t, "gam-42", store.decisions[0].ExternalRef)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/bookings/booking_123/approval", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var response struct {
+		Approval  approval.Approval   `json:"approval"`
+		Decisions []approval.Decision `json:"decisions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, approval.StatusApproved, response.Approval.Status)
+	assert.Len(t, response.Decisions, len(tests))
+}
+
+func TestApprovalHandler_ApprovalCallbackRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockApprovalStore(
+		approval.Approval{BookingID: "booking_123", CreativeAssetID: "creative_b", Status: approval.StatusPending},
+		approval.Approval{BookingID: "booking_bare", Status: approval.StatusPending},
+	)
+	router := newApprovalRouter(store)
+	body := approvalCallbackBody(t, approval.EventApproved, "creative_b", time.Now())
+
+	tests := []struct {
+		name           string
+		request        func() *http.Request
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "wrong secret",
+			request:        func() *http.Request { return signedApprovalCallback("other-secret", "r1", body) },
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject callbacks not signed with the shared secret",
+		},
+		{
+			name:           "first delivery",
+			request:        func() *http.Request { return signedApprovalCallback(testApprovalSecret, "r2", body) },
+			expectedStatus: http.StatusOK,
+			description:    "Should accept a signed callback",
+		},
+		{
+			name:           "replayed nonce",
+			request:        func() *http.Request { return signedApprovalCallback(testApprovalSecret, "r2", body) },
+			expectedStatus: http.StatusConflict,
+			description:    "Should reject a replayed nonce",
+		},
+		{
+			name: "unknown event",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "r3", approvalCallbackBody(t, "creative.maybe", "creative_b", time.Now()))
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown events",
+		},
+		{
+			name: "unknown booking",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "r4", `{"event":"creative.approved","booking_id":"booking_missing","creative_asset_id":"creative_b"}`)
+			},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown bookings",
+		},
+		{
+			name: "booking without a creative",
+			request: func() *http.Request {
+				return signedApprovalCallback(testApprovalSecret, "r5", `{"event":"creative.approved","booking_id":"booking_bare","creative_asset_id":"creative_b"}`)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should refuse decisions for bookings without a creative",
+		},
+	}
+
+	for _, tt := range tests {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, tt.request())
+		assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+	}
+	assert.Len(t, store.decisions, 1, "Only the accepted callback should be recorded")
+
+	handler := NewApprovalHandler(store, webhook.NewVerifier(nil, webhook.DefaultTolerance))
+	disabled := gin.New()
+	disabled.POST("/webhooks/approvals", handler.ApprovalCallback)
+	resp := httptest.NewRecorder()
+	disabled.ServeHTTP(resp, signedApprovalCallback(testApprovalSecret, "r6", body))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code, "Should refuse callbacks when no secret is configured")
+}
+
+func TestApprovalHandler_ListApprovals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockApprovalStore(
+		approval.Approval{BookingID: "booking_1", CreativeAssetID: "creative_1", Status: approval.StatusPending},
+		approval.Approval{BookingID: "booking_2", CreativeAssetID: "creative_2", Status: approval.StatusApproved},
+	)
+	router := newApprovalRouter(store)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/approvals", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var response struct {
+		Approvals []approval.Approval `json:"approvals"`
+		Status    string              `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, approval.StatusPending, response.Status, "Should export pending approvals by default")
+	require.Len(t, response.Approvals, 1)
+	assert.Equal(t, "booking_1", response.Approvals[0].BookingID)
+	assert.Equal(t, tenant.Of("org_a", ""), store.scope, "Approvals should be listed within the caller's scope")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/approvals?status=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/session"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// UserStore persists users and their login attempts
+type UserStore interface {
+	CreateUser(u *user.User) error
+	GetUser(username string) (*user.User, error)
+	ListUsers(orgID string) ([]*user.User, error)
+	UpdateUser(u *user.User) (bool, error)
+	DeleteUser(username string) (bool, error)
+	RecordLoginFailure(username string, lockout user.Lockout) (*time.Time, error)
+	RecordLogin(username string) error
+	IsOrganizationMember(orgID, username string) (bool, error)
+}
+
+// AuthHandler signs users in with their username and password, and keeps
+// them signed in with refresh tokens
+type AuthHandler struct {
+	db        UserStore
+	sessions  session.Store
+	jwtSecret []byte
+	lockout   user.Lockout
+	lifetimes session.Lifetimes
+}
+
+// NewAuthHandler creates an auth handler issuing access tokens signed with
+// jwtSecret and refresh tokens kept in sessions
+func NewAuthHandler(store UserStore, sessions session.Store, jwtSecret string, lockout user.Lockout, lifetimes session.Lifetimes) *AuthHandler {
+	return &AuthHandler{db: store, sessions: sessions, jwtSecret: []byte(jwtSecret), lockout: lockout, lifetimes: lifetimes}
+}
+
+// Login handles POST /auth/login. Unknown, disabled and locked accounts are
+// refused exactly like wrong passwords, so callers cannot tell them apart.
+// The access token carries the user's role, so a new role applies from the
+// next login or refresh. Users who belong to several organizations choose
+// one with org_id; without it they sign in to their own.
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		OrgID    string `json:"org_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.db.GetUser(req.Username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"audit":     "login",
+		"username":  req.Username,
+		"client_ip": c.ClientIP(),
+	})
+	switch {
+	case u == nil || u.DisabledAt != nil:
+		user.VerifyPassword(req.Password, "")
+		log.Warn("Login refused for unknown or disabled user")
+		h.refuse(c)
+		return
+	case u.Locked():
+		user.VerifyPassword(req.Password, "")
+		log.WithField("locked_until", u.LockedUntil).Warn("Login refused for locked user")
+		h.refuse(c)
+		return
+	case !user.VerifyPassword(req.Password, u.PasswordHash):
+		lockedUntil, err := h.db.RecordLoginFailure(u.Username, h.lockout)
+		if err != nil {
+			log.WithError(err).Error("Failed to record login failure")
+		}
+		if lockedUntil != nil {
+			log.WithField("locked_until", lockedUntil).Warn("Locked user after repeated login failures")
+		} else {
+			log.Warn("Login refused for wrong password")
+		}
+		h.refuse(c)
+		return
+	}
+
+	member, ok := h.checkMembership(c, u, req.OrgID)
+	if !ok {
+		return
+	}
+	if !member {
+		log.WithField("org_id", req.OrgID).Warn("Login refused for organization the user does not belong to")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of organization " + req.OrgID})
+		return
+	}
+
+	if err := h.db.RecordLogin(u.Username); err != nil {
+		log.WithError(err).Warn("Failed to record login")
+	}
+
+	sessionID, err := session.NewID()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate session ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	s := &session.Session{
+		ID:        sessionID,
+		Username:  u.Username,
+		OrgID:     req.OrgID,
+		ExpiresAt: time.Now().UTC().Add(h.lifetimes.Refresh),
+	}
+	if !h.issueTokens(c, u, s) {
+		return
+	}
+	log.WithField("session_id", s.ID).Info("User logged in")
+}
+
+// Refresh handles POST /auth/refresh, exchanging a refresh token for a new
+// access token and refresh token. Each refresh token is used once; the
+// session keeps the expiry of its login. The user is read again, so a new
+// role applies, and a disabled user or one removed from the organization
+// they signed in to is refused.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"audit":     "refresh",
+		"client_ip": c.ClientIP(),
+	})
+	s, ok := h.takeSession(c, req.RefreshToken)
+	if !ok {
+		return
+	}
+	if s == nil {
+		log.Warn("Refresh refused for unknown, used or expired token")
+		h.refuseRefresh(c)
+		return
+	}
+	log = log.WithFields(logrus.Fields{"username": s.Username, "session_id": s.ID})
+
+	u, err := h.db.GetUser(s.Username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if u == nil || u.DisabledAt != nil {
+		log.Warn("Refresh refused for deleted or disabled user")
+		h.refuseRefresh(c)
+		return
+	}
+	member, ok := h.checkMembership(c, u, s.OrgID)
+	if !ok {
+		return
+	}
+	if !member {
+		log.WithField("org_id", s.OrgID).Warn("Refresh refused for user no longer in the organization")
+		h.refuseRefresh(c)
+		return
+	}
+
+	if !h.issueTokens(c, u, s) {
+		return
+	}
+	log.Info("Refreshed tokens")
+}
+
+// Logout handles POST /auth/logout, ending the session of a refresh token.
+// The token can no longer be used, and the session's access tokens are
+// refused until they would have expired anyway. Unknown tokens are ignored,
+// so signing out twice succeeds.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, ok := h.takeSession(c, req.RefreshToken)
+	if !ok {
+		return
+	}
+	if s != nil {
+		if err := h.sessions.Revoke(c.Request.Context(), s.ID, time.Now().UTC().Add(h.lifetimes.Access)); err != nil {
+			logrus.WithError(err).Error("Failed to revoke session")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"audit":      "logout",
+			"username":   s.Username,
+			"session_id": s.ID,
+			"client_ip":  c.ClientIP(),
+		}).Info("User logged out")
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// takeSession uses up a refresh token, returning its session or nil when the
+// token is not a live one. It writes a 500 response and returns false when
+// the store fails.
+func (h *AuthHandler) takeSession(c *gin.Context, token string) (*session.Session, bool) {
+	if !session.ValidToken(token) {
+		return nil, true
+	}
+	s, err := h.sessions.Take(c.Request.Context(), session.HashToken(token))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to take refresh token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return s, true
+}
+
+// checkMembership reports whether u may sign in to orgID; their own
+// organization, or none chosen, always passes. It writes a 500 response and
+// returns false when the store fails.
+func (h *AuthHandler) checkMembership(c *gin.Context, u *user.User, orgID string) (bool, bool) {
+	if orgID == "" || orgID == u.OrgID {
+		return true, true
+	}
+	member, err := h.db.IsOrganizationMember(orgID, u.Username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check organization membership")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false, false
+	}
+	return member, true
+}
+
+// issueTokens answers with a new access token and refresh token of session
+// s, returning false after writing an error response instead. Access tokens
+// do not outlive their session, and are scoped to the organization chosen at
+// login or else the user's own.
+func (h *AuthHandler) issueTokens(c *gin.Context, u *user.User, s *session.Session) bool {
+	now := time.Now().UTC()
+	expiresAt := now.Add(h.lifetimes.Access)
+	if s.ExpiresAt.Before(expiresAt) {
+		expiresAt = s.ExpiresAt
+	}
+
+	claims := jwt.MapClaims{
+		"sub":  u.Username,
+		"exp":  expiresAt.Unix(),
+		"iat":  now.Unix(),
+		"aud":  middleware.TokenAudience,
+		"role": u.Role,
+		"sid":  s.ID,
+	}
+	orgID := s.OrgID
+	if orgID == "" {
+		orgID = u.OrgID
+	}
+	if orgID != "" {
+		claims["org_id"] = orgID
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.jwtSecret)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to sign JWT token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return false
+	}
+
+	refreshToken, refreshHash, err := session.GenerateToken()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate refresh token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return false
+	}
+	if err := h.sessions.Save(c.Request.Context(), refreshHash, s); err != nil {
+		logrus.WithError(err).Error("Failed to save refresh token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":              token,
+		"token_type":         "Bearer",
+		"expires_in":         int(expiresAt.Sub(now).Round(time.Second).Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_in": int(s.ExpiresAt.Sub(now).Round(time.Second).Seconds()),
+		"user":               u.Username,
+		"role":               u.Role,
+		"org_id":             orgID,
+	})
+	return true
+}
+
+// refuseRefresh answers a refresh with a token that cannot be used
+func (h *AuthHandler) refuseRefresh(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+}
+
+// refuse answers a failed login
+func (h *AuthHandler) refuse(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+}
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/session"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUserStore backs both AuthHandler and UserHandler
+type MockUserStore struct {
+	users       map[string]*user.User
+	members     map[string]map[string]bool // Added members by organization
+	shouldError bool
+}
+
+func newMockUserStore() *MockUserStore {
+	return &MockUserStore{users: map[string]*user.User{}, members: map[string]map[string]bool{}}
+}
+
+// addUser stores a user with a hashed password and the default role
+func (m *MockUserStore) addUser(t *testing.T, username, password, orgID string) *user.User {
+	passwordHash, err := user.HashPassword(password)
+	require.NoError(t, err)
+	u := &user.User{Username: username, OrgID: orgID, Role: user.DefaultRole, PasswordHash: passwordHash, CreatedAt: time.Now()}
+	m.users[username] = u
+	return u
+}
+
+func (m *MockUserStore) CreateUser(u *user.User) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if _, ok := m.users[u.Username]; ok {
+		return db.ErrUserExists
+	}
+	u.CreatedAt = time.Now()
+	m.users[u.Username] = u
+	return nil
+}
+
+func (m *MockUserStore) GetUser(username string) (*user.User, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	u, ok := m.users[username]
+	if !ok {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (m *MockUserStore) ListUsers(orgID string) ([]*user.User, error) {
+	users := make([]*user.User, 0)
+	for _, u := range m.users {
+		if orgID == "" || u.OrgID == orgID {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (m *MockUserStore) UpdateUser(u *user.User) (bool, error) {
+	if _, ok := m.users[u.Username]; !ok {
+		return false, nil
+	}
+	u.UpdatedAt = time.Now()
+	m.users[u.Username] = u
+	return true, nil
+}
+
+func (m *MockUserStore) DeleteUser(username string) (bool, error) {
+	if _, ok := m.users[username]; !ok {
+		return false, nil
+	}
+	delete(m.users, username)
+	return true, nil
+}
+
+func (m *MockUserStore) RecordLoginFailure(username string, lockout user.Lockout) (*time.Time, error) {
+	u := m.users[username]
+	u.FailedLogins++
+	if u.FailedLogins >= lockout.Threshold {
+		lockedUntil := time.Now().Add(lockout.Duration)
+		u.LockedUntil = &lockedUntil
+	}
+	return u.LockedUntil, nil
+}
+
+func (m *MockUserStore) RecordLogin(username string) error {
+	u := m.users[username]
+	now := time.Now()
+	u.LastLoginAt = &now
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	return nil
+}
+
+func (m *MockUserStore) IsOrganizationMember(orgID, username string) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	if u, ok := m.users[username]; ok && u.OrgID == orgID {
+		return true, nil
+	}
+	return m.members[orgID][username], nil
+}
+
+// MockSessionStore keeps refresh tokens and revoked sessions in memory
+type MockSessionStore struct {
+	tokens      map[string]session.Session
+	revoked     map[string]time.Time
+	shouldError bool
+}
+
+func newMockSessionStore() *MockSessionStore {
+	return &MockSessionStore{tokens: map[string]session.Session{}, revoked: map[string]time.Time{}}
+}
+
+func (m *MockSessionStore) Save(ctx context.Context, tokenHash string, s *session.Session) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	m.tokens[tokenHash] = *s
+	return nil
+}
+
+func (m *MockSessionStore) Take(ctx context.Context, tokenHash string) (*session.Session, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	s, ok := m.tokens[tokenHash]
+	delete(m.tokens, tokenHash)
+	if !ok || s.Expired(time.Now()) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (m *MockSessionStore) Revoke(ctx context.Context, sessionID string, until time.Time) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	m.revoked[sessionID] = until
+	return nil
+}
+
+func (m *MockSessionStore) Revoked(ctx context.Context, sessionID string) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	until, ok := m.revoked[sessionID]
+	return ok && time.Now().Before(until), nil
+}
+
+func login(router *gin.Engine, username, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.addUser(t, "alice", "correct horse battery", "org_1")
+	handler := NewAuthHandler(store, newMockSessionStore(), "test-secret", user.Lockout{Threshold: 3, Duration: time.Minute}, session.DefaultLifetimes())
+
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+	router.GET("/whoami", middleware.Authenticate("test-secret", nil, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "org_id": c.GetString("org_id")})
+	})
+
+	resp := login(router, "alice", "correct horse battery")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var issued struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &issued))
+	assert.Equal(t, 900, issued.ExpiresIn, "Access tokens should be short-lived")
+	assert.NotNil(t, store.users["alice"].LastLoginAt, "A login should be recorded")
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"user_id":"alice","org_id":"org_1"}`, resp.Body.String(), "The token should carry the user's own organization")
+
+	// Browsers offer the token as a subprotocol of WebSocket handshakes
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Protocol", "inscenium.watch.v1, bearer."+issued.Token)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Without a token the Bearer challenge is sent
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, `Bearer realm="inscenium-api"`, resp.Header().Get("WWW-Authenticate"))
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		status   int
+	}{
+		{"wrong password", "alice", "not the password", http.StatusUnauthorized},
+		{"unknown user", "mallory", "correct horse battery", http.StatusUnauthorized},
+		{"missing password", "alice", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := login(router, tt.username, tt.password)
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.JSONEq(t, `{"error":"Invalid credentials"}`, resp.Body.String(), "Failures should not say why")
+			}
+		})
+	}
+}
+
+func TestAuthHandler_LoginLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.addUser(t, "bob", "correct horse battery", "")
+	disabled := store.addUser(t, "carol", "correct horse battery", "")
+	now := time.Now()
+	disabled.DisabledAt = &now
+	handler := NewAuthHandler(store, newMockSessionStore(), "test-secret", user.Lockout{Threshold: 3, Duration: time.Minute}, session.DefaultLifetimes())
+
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+
+	assert.Equal(t, http.StatusUnauthorized, login(router, "carol", "correct horse battery").Code, "Disabled users should not log in")
+
+	assert.Equal(t, http.StatusUnauthorized, login(router, "bob", "guess 1").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(router, "bob", "guess 2").Code)
+	assert.Equal(t, 2, store.users["bob"].FailedLogins)
+	assert.Nil(t, store.users["bob"].LockedUntil)
+
+	assert.Equal(t, http.StatusUnauthorized, login(router, "bob", "guess 3").Code)
+	require.NotNil(t, store.users["bob"].LockedUntil, "The third failure should lock the account")
+
+	resp := login(router, "bob", "correct horse battery")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "A locked account should refuse even the right password")
+	assert.JSONEq(t, `{"error":"Invalid credentials"}`, resp.Body.String())
+	assert.Equal(t, 3, store.users["bob"].FailedLogins, "Attempts while locked should not be counted")
+
+	store.users["bob"].LockedUntil = nil // The lock expired
+	assert.Equal(t, http.StatusOK, login(router, "bob", "correct horse battery").Code)
+	assert.Equal(t, 0, store.users["bob"].FailedLogins, "A login should clear earlier failures")
+
+	store.shouldError = true
+	assert.Equal(t, http.StatusInternalServerError, login(router, "bob", "correct horse battery").Code)
+}
+
+func TestAuthHandler_LoginRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	for username, role := range map[string]string{
+		"root": user.RoleAdmin, "ada": user.RoleAdvertiser, "pat": user.RolePublisher, "ann": user.RoleAnalyst,
+	} {
+		store.addUser(t, username, "correct horse battery", "org_brand").Role = role
+	}
+	store.users["root"].OrgID = "org_ops"
+	handler := NewAuthHandler(store, newMockSessionStore(), "test-secret", user.DefaultLockout(), session.DefaultLifetimes())
+	authorizer := authz.NewAuthorizer(&MockGrantStore{owners: map[string]string{
+		"booking/booking_brand": "org_brand",
+		"booking/booking_other": "org_other",
+	}})
+
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/bookings", middleware.Authenticate("test-secret", nil, nil), middleware.RequireScope("bookings:write"), ok)
+	router.DELETE("/bookings/:id", middleware.Authenticate("test-secret", nil, nil), middleware.RequireScope("bookings:write"),
+		authorizer.RequireOwned(authz.PermissionManage, labels.ResourceBooking, "id"), ok)
+
+	tokens := map[string]string{}
+	for username := range store.users {
+		resp := login(router, username, "correct horse battery")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var issued struct {
+			Token string `json:"token"`
+			Role  string `json:"role"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &issued))
+		assert.Equal(t, store.users[username].Role, issued.Role)
+		tokens[username] = issued.Token
+	}
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "ada", "aud": middleware.TokenAudience, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	require.NoError(t, err)
+	tokens["legacy"] = legacy
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		username       string
+		expectedStatus int
+		description    string
+	}{
+		{"advertiser books", http.MethodPost, "/bookings", "ada", http.StatusOK, "Should let advertisers book placements"},
+		{"admin books", http.MethodPost, "/bookings", "root", http.StatusOK, "Should let admins book placements"},
+		{"publisher books", http.MethodPost, "/bookings", "pat", http.StatusForbidden, "Should not let publishers book placements"},
+		{"analyst books", http.MethodPost, "/bookings", "ann", http.StatusForbidden, "Should not let analysts book placements"},
+		{"token without role", http.MethodPost, "/bookings", "legacy", http.StatusUnauthorized, "Should reject tokens issued before roles"},
+		{"advertiser cancels own", http.MethodDelete, "/bookings/booking_brand", "ada", http.StatusOK, "Should let advertisers cancel their organization's bookings"},
+		{"advertiser cancels other", http.MethodDelete, "/bookings/booking_other", "ada", http.StatusForbidden, "Should not let advertisers cancel other organizations' bookings"},
+		{"advertiser cancels unowned", http.MethodDelete, "/bookings/booking_legacy", "ada", http.StatusForbidden, "Should not let advertisers cancel bookings no one owns"},
+		{"admin cancels other", http.MethodDelete, "/bookings/booking_other", "root", http.StatusOK, "Should let admins cancel any booking"},
+		{"admin cancels unowned", http.MethodDelete, "/bookings/booking_legacy", "root", http.StatusOK, "Should let admins cancel bookings no one owns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tokens[tt.username])
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
+
+// tokenPair is the response of a login or refresh
+type tokenPair struct {
+	Token            string `json:"token"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+	Role             string `json:"role"`
+	OrgID            string `json:"org_id"`
+}
+
+func postRefreshToken(router *gin.Engine, path, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAuthHandler_RefreshLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.addUser(t, "alice", "correct horse battery", "org_1")
+	sessions := newMockSessionStore()
+	handler := NewAuthHandler(store, sessions, "test-secret", user.DefaultLockout(), session.Lifetimes{Access: time.Minute, Refresh: time.Hour})
+
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+	router.POST("/auth/refresh", handler.Refresh)
+	router.POST("/auth/logout", handler.Logout)
+	router.GET("/whoami", middleware.Authenticate("test-secret", nil, sessions), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "role": c.GetString("role")})
+	})
+	whoami := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder) tokenPair {
+		var pair tokenPair
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &pair))
+		return pair
+	}
+
+	resp := login(router, "alice", "correct horse battery")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	first := decode(resp)
+	assert.Equal(t, 60, first.ExpiresIn)
+	assert.Equal(t, 3600, first.RefreshExpiresIn)
+	assert.True(t, strings.HasPrefix(first.RefreshToken, session.TokenPrefix))
+	assert.Equal(t, http.StatusOK, whoami(first.Token).Code)
+
+	// A new role applies from the next refresh
+	store.users["alice"].Role = user.RoleAdvertiser
+	resp = postRefreshToken(router, "/auth/refresh", first.RefreshToken)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	second := decode(resp)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken, "Refresh tokens should rotate")
+	assert.Equal(t, user.RoleAdvertiser, second.Role)
+	assert.LessOrEqual(t, second.RefreshExpiresIn, first.RefreshExpiresIn, "Refreshing should not extend the session")
+	assert.JSONEq(t, `{"user_id":"alice","role":"advertiser"}`, whoami(second.Token).Body.String())
+
+	resp = postRefreshToken(router, "/auth/refresh", first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "A refresh token should only be used once")
+	assert.JSONEq(t, `{"error":"Invalid refresh token"}`, resp.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(router, "/auth/refresh", "not-a-token").Code)
+	assert.Equal(t, http.StatusBadRequest, postRefreshToken(router, "/auth/refresh", "").Code)
+
+	// Signing out revokes the refresh token and the session's access tokens
+	assert.Equal(t, http.StatusNoContent, postRefreshToken(router, "/auth/logout", second.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(router, "/auth/refresh", second.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, whoami(second.Token).Code, "Access tokens of a revoked session should be refused")
+	assert.Equal(t, http.StatusUnauthorized, whoami(first.Token).Code)
+	assert.Equal(t, http.StatusNoContent, postRefreshToken(router, "/auth/logout", second.RefreshToken).Code, "Signing out twice should succeed")
+
+	// Other sessions of the user are unaffected
+	other := decode(login(router, "alice", "correct horse battery"))
+	assert.Equal(t, http.StatusOK, whoami(other.Token).Code)
+
+	// Disabled users cannot refresh
+	now := time.Now()
+	store.users["alice"].DisabledAt = &now
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(router, "/auth/refresh", other.RefreshToken).Code)
+
+	// Tokens issued before sessions carry no session ID
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice", "aud": middleware.TokenAudience, "role": user.RoleAnalyst, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, whoami(legacy).Code)
+
+	sessions.shouldError = true
+	assert.Equal(t, http.StatusInternalServerError, whoami(other.Token).Code)
+	assert.Equal(t, http.StatusInternalServerError, postRefreshToken(router, "/auth/refresh", other.RefreshToken).Code)
+}
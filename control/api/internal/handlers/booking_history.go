@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/sirupsen/logrus"
+)
+
+// BookingEventStore persists the append-only booking event stream
+type BookingEventStore interface {
+	ListBookingEvents(bookingID string) ([]booking.Event, error)
+	GetBookingVersion(bookingID string) (int, error)
+	ListCampaignBookingEvents(campaignID string, asOf time.Time) ([]booking.Event, error)
+	AppendBookingEvent(event booking.Event) (*booking.State, error)
+}
+
+// parseAsOf reads the as_of query parameter: an RFC 3339 timestamp, or a date
+// meaning the end of that day in UTC. ok is false if a response was written.
+func parseAsOf(c *gin.Context) (asOf time.Time, set, ok bool) {
+	value := c.Query("as_of")
+	if value == "" {
+		return time.Time{}, false, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), true, true
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day.Add(24*time.Hour - time.Nanosecond), true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+	return time.Time{}, false, false
+}
+
+// BookingHistoryHandler serves booking event streams
+type BookingHistoryHandler struct {
+	db BookingEventStore
+}
+
+// NewBookingHistoryHandler creates a booking history handler
+func NewBookingHistoryHandler(store BookingEventStore) *BookingHistoryHandler {
+	return &BookingHistoryHandler{db: store}
+}
+
+// GetHistory handles GET /bookings/:id/history
+func (h *BookingHistoryHandler) GetHistory(c *gin.Context) {
+	bookingID := c.Param("id")
+	asOf, filtered, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.db.ListBookingEvents(bookingID)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to list booking events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if filtered {
+		events = booking.Until(events, asOf)
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking history not found"})
+		return
+	}
+
+	state, err := booking.Project(events)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Booking event stream is inconsistent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id": bookingID,
+		"events":     events,
+		"state":      state,
+	})
+}
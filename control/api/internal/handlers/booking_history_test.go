@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBookingEventStore struct {
+	mu          sync.Mutex // Long polls read while tests append
+	events      map[string][]booking.Event
+	shouldError bool
+}
+
+func (m *MockBookingEventStore) ListBookingEvents(bookingID string) ([]booking.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.events[bookingID], nil
+}
+
+func (m *MockBookingEventStore) GetBookingVersion(bookingID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shouldError {
+		return 0, assert.AnError
+	}
+	return len(m.events[bookingID]), nil
+}
+
+func (m *MockBookingEventStore) ListCampaignBookingEvents(campaignID string, asOf time.Time) ([]booking.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	ids := make([]string, 0, len(m.events))
+	for id := range m.events {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]booking.Event, 0)
+	for _, id := range ids {
+		events := booking.Until(m.events[id], asOf)
+		for _, event := range events {
+			if event.Data.CampaignID == campaignID {
+				result = append(result, events...)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *MockBookingEventStore) AppendBookingEvent(event booking.Event) (*booking.State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	events, ok := m.events[event.BookingID]
+	if !ok {
+		return nil, db.ErrBookingNotFound
+	}
+	state, err := booking.Project(events)
+	if err != nil {
+		return nil, err
+	}
+	event.Sequence = state.Version + 1
+	event.OccurredAt = time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	if err := state.Apply(event); err != nil {
+		return nil, err
+	}
+	m.events[event.BookingID] = append(events, event)
+	return state, nil
+}
+
+func bookingStream(bookingID string, types ...string) []booking.Event {
+	bid, impressions := 5.5, 1000
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	events := make([]booking.Event, 0, len(types))
+	for i, eventType := range types {
+		event := booking.Event{
+			BookingID:  bookingID,
+			Sequence:   i + 1,
+			Type:       eventType,
+			Actor:      "user_1",
+			OccurredAt: start.Add(time.Duration(i) * time.Hour),
+		}
+		if eventType == booking.EventCreated {
+			event.Data = booking.Change{SurfaceID: "surface_001", CampaignID: "camp_1", BidAmountCPM: &bid, MaxImpressions: &impressions}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestBookingHistoryHandler_GetHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	raised := 7.25
+	amended := bookingStream("booking_1", booking.EventCreated, booking.EventApproved, booking.EventAmended, booking.EventPaused)
+	amended[2].Data = booking.Change{BidAmountCPM: &raised, Reason: "renegotiated"}
+
+	tests := []struct {
+		name           string
+		bookingID      string
+		query          string
+		store          *MockBookingEventStore
+		expectedStatus int
+		expectedEvents int
+		expectedState  string
+		expectedBid    float64
+		description    string
+	}{
+		{
+			name:           "full lifecycle",
+			bookingID:      "booking_1",
+			store:          &MockBookingEventStore{events: map[string][]booking.Event{"booking_1": amended}},
+			expectedStatus: http.StatusOK,
+			expectedEvents: 4,
+			expectedState:  booking.StatusPaused,
+			expectedBid:    7.25,
+			description:    "Should return every event and the projected state",
+		},
+		{
+			name:           "as of",
+			bookingID:      "booking_1",
+			query:          "?as_of=2024-03-01T10:00:00Z",
+			store:          &MockBookingEventStore{events: map[string][]booking.Event{"booking_1": amended}},
+			expectedStatus: http.StatusOK,
+			expectedEvents: 2,
+			expectedState:  booking.StatusConfirmed,
+			expectedBid:    5.5,
+			description:    "Should only replay events up to as_of",
+		},
+		{
+			name:      "cancelled",
+			bookingID: "booking_2",
+			store: &MockBookingEventStore{events: map[string][]booking.Event{
+				"booking_2": bookingStream("booking_2", booking.EventCreated, booking.EventCancelled),
+			}},
+			expectedStatus: http.StatusOK,
+			expectedEvents: 2,
+			expectedState:  booking.StatusCancelled,
+			expectedBid:    5.5,
+			description:    "Should project a cancelled booking",
+		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_missing",
+			store:          &MockBookingEventStore{events: map[string][]booking.Event{}},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 without events",
+		},
+		{
+			name:      "inconsistent stream",
+			bookingID: "booking_3",
+			store: &MockBookingEventStore{events: map[string][]booking.Event{
+				"booking_3": bookingStream("booking_3", booking.EventCreated, booking.EventCancelled, booking.EventApproved),
+			}},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should refuse to project events after cancellation",
+		},
+		{
+			name:           "store error",
+			bookingID:      "booking_1",
+			store:          &MockBookingEventStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewBookingHistoryHandler(tt.store)
+			router := gin.New()
+			router.GET("/bookings/:id/history", handler.GetHistory)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/"+tt.bookingID+"/history"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				BookingID string          `json:"booking_id"`
+				Events    []booking.Event `json:"events"`
+				State     booking.State   `json:"state"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.bookingID, response.BookingID)
+			assert.Len(t, response.Events, tt.expectedEvents, tt.description)
+			assert.Equal(t, tt.expectedState, response.State.Status, tt.description)
+			assert.Equal(t, tt.expectedBid, response.State.BidAmountCPM)
+			assert.Equal(t, tt.expectedEvents, response.State.Version)
+		})
+	}
+}
+
+func TestPlacementHandler_CancelBookingRecordsEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		bookingID      string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "cancel confirmed booking",
+			bookingID:      "booking_1",
+			expectedStatus: http.StatusOK,
+			description:    "Should append a cancelled event",
+		},
+		{
+			name:           "cancel twice",
+			bookingID:      "booking_2",
+			expectedStatus: http.StatusConflict,
+			description:    "Should reject cancelling a cancelled booking",
+		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_missing",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown bookings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockBookingEventStore{events: map[string][]booking.Event{
+				"booking_1": bookingStream("booking_1", booking.EventCreated, booking.EventApproved),
+				"booking_2": bookingStream("booking_2", booking.EventCreated, booking.EventCancelled),
+			}}
+			handler := NewPlacementHandler(nil)
+			handler.SetBookingEvents(store)
+			router := gin.New()
+			router.DELETE("/bookings/:id", withOrg("user_1", "org_a"), handler.CancelBooking)
+
+			req := httptest.NewRequest(http.MethodDelete, "/bookings/"+tt.bookingID+"?reason=campaign+pulled", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, "2024-03-02T12:00:00Z", response["cancelled_at"])
+
+			events := store.events[tt.bookingID]
+			last := events[len(events)-1]
+			assert.Equal(t, booking.EventCancelled, last.Type)
+			assert.Equal(t, 3, last.Sequence)
+			assert.Equal(t, "user_1", last.Actor)
+			assert.Equal(t, "org_a", last.OrgID)
+			assert.Equal(t, "campaign pulled", last.Data.Reason)
+		})
+	}
+}
+
+func TestPlacementHandler_GetBookingAsOf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Created 09:00, approved 10:00, paused 11:00, cancelled 12:00 on 2024-03-01
+	store := &MockBookingEventStore{events: map[string][]booking.Event{
+		"booking_1": bookingStream("booking_1", booking.EventCreated, booking.EventApproved, booking.EventPaused, booking.EventCancelled),
+	}}
+
+	tests := []struct {
+		name           string
+		asOf           string
+		expectedStatus int
+		expectedState  string
+		description    string
+	}{
+		{
+			name:           "before creation",
+			asOf:           "2024-03-01T08:59:59Z",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 before the booking existed",
+		},
+		{
+			name:           "while pending",
+			asOf:           "2024-03-01T09:30:00Z",
+			expectedStatus: http.StatusOK,
+			expectedState:  booking.StatusPending,
+			description:    "Should project only the created event",
+		},
+		{
+			name:           "at the pause",
+			asOf:           "2024-03-01T12:00:00+01:00",
+			expectedStatus: http.StatusOK,
+			expectedState:  booking.StatusPaused,
+			description:    "Should include events at exactly as_of, honouring the offset",
+		},
+		{
+			name:           "end of day",
+			asOf:           "2024-03-01",
+			expectedStatus: http.StatusOK,
+			expectedState:  booking.StatusCancelled,
+			description:    "Should treat a date as the end of that day",
+		},
+		{
+			name:           "invalid",
+			asOf:           "yesterday",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unparseable as_of values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPlacementHandler(nil)
+			handler.SetBookingEvents(store)
+			router := gin.New()
+			router.GET("/bookings/:id", handler.GetBooking)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/booking_1?as_of="+url.QueryEscape(tt.asOf), nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				AsOf    time.Time     `json:"as_of"`
+				Booking booking.State `json:"booking"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedState, response.Booking.Status, tt.description)
+			assert.Equal(t, "booking_1", response.Booking.BookingID)
+		})
+	}
+}
+
+func TestPlacementHandler_ListBookingsAsOf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// booking_2 moves from camp_1 to camp_2 at 10:00
+	moved := bookingStream("booking_2", booking.EventCreated, booking.EventAmended)
+	moved[1].Data = booking.Change{CampaignID: "camp_2"}
+	later := bookingStream("booking_3", booking.EventCreated)
+	later[0].OccurredAt = time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+
+	store := &MockBookingEventStore{events: map[string][]booking.Event{
+		"booking_1": bookingStream("booking_1", booking.EventCreated, booking.EventApproved),
+		"booking_2": moved,
+		"booking_3": later,
+	}}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedBookings []string
+		description      string
+	}{
+		{
+			name:             "before the move",
+			query:            "?campaign_id=camp_1&as_of=2024-03-01T09:30:00Z",
+			expectedStatus:   http.StatusOK,
+			expectedBookings: []string{"booking_1", "booking_2"},
+			description:      "Should include bookings in the campaign at as_of",
+		},
+		{
+			name:             "after the move",
+			query:            "?campaign_id=camp_1&as_of=2024-03-31",
+			expectedStatus:   http.StatusOK,
+			expectedBookings: []string{"booking_1", "booking_3"},
+			description:      "Should drop bookings moved to another campaign",
+		},
+		{
+			name:             "paged",
+			query:            "?campaign_id=camp_1&as_of=2024-03-31&limit=1&offset=1",
+			expectedStatus:   http.StatusOK,
+			expectedBookings: []string{"booking_3"},
+			description:      "Should page projected bookings",
+		},
+		{
+			name:           "missing campaign",
+			query:          "?as_of=2024-03-31",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require campaign_id with as_of",
+		},
+		{
+			name:           "with labels",
+			query:          "?campaign_id=camp_1&as_of=2024-03-31&labels=env=prod",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject label filters with as_of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPlacementHandler(nil)
+			handler.SetBookingEvents(store)
+			router := gin.New()
+			router.GET("/bookings", handler.ListBookings)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Bookings []booking.State `json:"bookings"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			ids := make([]string, 0, len(response.Bookings))
+			for _, b := range response.Bookings {
+				ids = append(ids, b.BookingID)
+			}
+			assert.Equal(t, tt.expectedBookings, ids, tt.description)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Long poll limits used when none are configured
+const (
+	defaultPollMaxWait  = 30 * time.Second
+	defaultPollInterval = time.Second
+)
+
+// SetLongPoll bounds how long GET /bookings/:id may wait for a change and
+// how often the event stream is checked meanwhile
+func (h *PlacementHandler) SetLongPoll(maxWait, interval time.Duration) {
+	h.pollMaxWait = maxWait
+	h.pollInterval = interval
+}
+
+// SetEventBus wakes long polls as soon as a booking changes in this process,
+// rather than at the next poll interval. Changes made through other
+// gateways are seen at the interval, or sooner when Postgres announces them
+// (see WakeBooking).
+func (h *PlacementHandler) SetEventBus(bus *eventbus.Bus) {
+	h.changes = &bookingWaiters{waiters: make(map[string]map[chan struct{}]bool)}
+	eventbus.Subscribe(bus, "booking_long_poll", func(ctx context.Context, event eventbus.BookingChanged) error {
+		h.changes.wake(event.BookingID)
+		return nil
+	})
+}
+
+// WakeBooking releases the long polls waiting on a booking changed through
+// another gateway, as announced by Postgres change notifications
+func (h *PlacementHandler) WakeBooking(bookingID string) {
+	h.changes.wake(bookingID)
+}
+
+// WakeAllBookings releases every long poll to check its booking again, after
+// change notifications may have been missed
+func (h *PlacementHandler) WakeAllBookings() {
+	h.changes.wakeAll()
+}
+
+// bookingWaiters tracks the long polls waiting on each booking
+type bookingWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]bool
+}
+
+// wait returns a channel closed when the booking next changes, and a
+// function to stop waiting. A nil set of waiters returns a nil channel,
+// which never fires.
+func (w *bookingWaiters) wait(bookingID string) (<-chan struct{}, func()) {
+	if w == nil {
+		return nil, func() {}
+	}
+	ch := make(chan struct{})
+	w.mu.Lock()
+	if w.waiters[bookingID] == nil {
+		w.waiters[bookingID] = make(map[chan struct{}]bool)
+	}
+	w.waiters[bookingID][ch] = true
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.waiters[bookingID][ch] {
+			delete(w.waiters[bookingID], ch)
+			if len(w.waiters[bookingID]) == 0 {
+				delete(w.waiters, bookingID)
+			}
+		}
+	}
+}
+
+// wake releases every long poll waiting on the booking
+func (w *bookingWaiters) wake(bookingID string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.waiters[bookingID] {
+		close(ch)
+	}
+	delete(w.waiters, bookingID)
+}
+
+// wakeAll releases every long poll
+func (w *bookingWaiters) wakeAll() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for bookingID, waiters := range w.waiters {
+		for ch := range waiters {
+			close(ch)
+		}
+		delete(w.waiters, bookingID)
+	}
+}
+
+// bookingETag is the entity tag of a booking at an event stream version
+func bookingETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseNewerThan reads the version a caller already has, from the
+// If-Event-Newer-Than header, the newer_than query parameter or an
+// If-None-Match entity tag. set is false if none was sent; ok is false if
+// a response was written.
+func parseNewerThan(c *gin.Context) (version int, set, ok bool) {
+	value := c.GetHeader("If-Event-Newer-Than")
+	if value == "" {
+		value = c.Query("newer_than")
+	}
+	if value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "If-Event-Newer-Than must be an event sequence number"})
+			return 0, false, false
+		}
+		return version, true, true
+	}
+
+	// Entity tags this API did not issue make the request unconditional
+	tag := strings.TrimPrefix(c.GetHeader("If-None-Match"), "W/")
+	if version, err := strconv.Atoi(strings.Trim(tag, `"`)); err == nil && version >= 0 {
+		return version, true, true
+	}
+	return 0, false, true
+}
+
+// parseWait reads the wait query parameter in seconds, capped at the
+// handler's maximum. ok is false if a response was written.
+func (h *PlacementHandler) parseWait(c *gin.Context) (time.Duration, bool) {
+	value := c.Query("wait")
+	if value == "" {
+		return 0, true
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wait must be a whole number of seconds"})
+		return 0, false
+	}
+	maxWait := h.pollMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultPollMaxWait
+	}
+	return min(time.Duration(seconds)*time.Second, maxWait), true
+}
+
+// pollBooking answers a conditional read of a booking: its current state if
+// its event stream is past newerThan, otherwise 304 Not Modified once wait
+// has passed without a new event. The stream is checked every poll
+// interval, so changes made through any gateway are seen, and as soon as
+// the event bus reports a change made through this one or Postgres
+// announces one.
+func (h *PlacementHandler) pollBooking(c *gin.Context, id string, newerThan int, wait time.Duration) {
+	if h.events == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "long polling is not available"})
+		return
+	}
+	interval := h.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	deadline := time.Now().Add(wait)
+	waited := false
+	stop := func() {}
+	defer func() { stop() }()
+	for {
+		// Watch for changes before reading the version, so none is missed in between
+		stop()
+		var changed <-chan struct{}
+		changed, stop = h.changes.wait(id)
+		version, err := h.events.GetBookingVersion(id)
+		if err != nil {
+			logrus.WithError(err).WithField("booking_id", id).Error("Failed to get booking version")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if version == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		if version > newerThan {
+			if waited {
+				metrics.BookingLongPolls.WithLabelValues("changed").Inc()
+			}
+			h.renderBookingState(c, id)
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if waited {
+				metrics.BookingLongPolls.WithLabelValues("timeout").Inc()
+			}
+			c.Header("ETag", bookingETag(version))
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		waited = true
+		timer := time.NewTimer(min(interval, remaining))
+		select {
+		case <-c.Request.Context().Done():
+			timer.Stop()
+			metrics.BookingLongPolls.WithLabelValues("disconnected").Inc()
+			return
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// renderBookingState writes a booking's current state, projected from its
+// event stream, tagged with the stream's version
+func (h *PlacementHandler) renderBookingState(c *gin.Context, id string) {
+	events, err := h.events.ListBookingEvents(id)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to list booking events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	state, err := booking.Project(events)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Booking event stream is inconsistent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	c.Header("ETag", bookingETag(state.Version))
+	c.JSON(http.StatusOK, gin.H{"booking": state})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementHandler_GetBookingConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		url            string
+		headers        map[string]string
+		events         bool
+		expectedStatus int
+		expectedETag   string
+		description    string
+	}{
+		{
+			name:           "newer event",
+			url:            "/bookings/booking_1?newer_than=1",
+			events:         true,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+			description:    "Should return the booking once its stream is past the caller's version",
+		},
+		{
+			name:           "header",
+			url:            "/bookings/booking_1",
+			headers:        map[string]string{"If-Event-Newer-Than": "2"},
+			events:         true,
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"2"`,
+			description:    "Should answer 304 at once without wait",
+		},
+		{
+			name:           "entity tag",
+			url:            "/bookings/booking_1",
+			headers:        map[string]string{"If-None-Match": `W/"2"`},
+			events:         true,
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"2"`,
+			description:    "Should accept the booking's entity tag",
+		},
+		{
+			name:           "foreign entity tag",
+			url:            "/bookings/booking_1",
+			headers:        map[string]string{"If-None-Match": `"abc"`},
+			events:         true,
+			expectedStatus: http.StatusOK,
+			description:    "Should ignore entity tags it did not issue",
+		},
+		{
+			name:           "wait without version",
+			url:            "/bookings/booking_1?wait=10",
+			events:         true,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+			description:    "Should return the current state and its version at once",
+		},
+		{
+			name:           "unknown booking",
+			url:            "/bookings/booking_2?wait=10",
+			events:         true,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for bookings without events",
+		},
+		{
+			name:           "bad version",
+			url:            "/bookings/booking_1",
+			headers:        map[string]string{"If-Event-Newer-Than": "latest"},
+			events:         true,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject versions that are not sequence numbers",
+		},
+		{
+			name:           "bad wait",
+			url:            "/bookings/booking_1?wait=soon",
+			events:         true,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject waits that are not seconds",
+		},
+		{
+			name:           "as_of",
+			url:            "/bookings/booking_1?as_of=2024-03-01&wait=10",
+			events:         true,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should not wait for changes to the past",
+		},
+		{
+			name:           "no event store",
+			url:            "/bookings/booking_1?newer_than=1",
+			expectedStatus: http.StatusNotImplemented,
+			description:    "Should need the event stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{booking: &models.Booking{BookingID: "booking_1"}})}
+			if tt.events {
+				handler.SetBookingEvents(&MockBookingEventStore{events: map[string][]booking.Event{
+					"booking_1": bookingStream("booking_1", booking.EventCreated, booking.EventApproved),
+				}})
+			}
+			router := gin.New()
+			router.GET("/bookings/:id", handler.GetBooking)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Equal(t, tt.expectedETag, resp.Header().Get("ETag"), tt.description)
+		})
+	}
+}
+
+func TestPlacementHandler_GetBookingLongPoll(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockBookingEventStore{events: map[string][]booking.Event{
+		"booking_1": bookingStream("booking_1", booking.EventCreated, booking.EventApproved),
+	}}
+	handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{})}
+	handler.SetBookingEvents(store)
+	handler.SetLongPoll(2*time.Second, 10*time.Millisecond)
+	router := gin.New()
+	router.GET("/bookings/:id", handler.GetBooking)
+
+	t.Run("change while waiting", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_, err := store.AppendBookingEvent(booking.Event{BookingID: "booking_1", Type: booking.EventPaused})
+			assert.NoError(t, err)
+		}()
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodGet, "/bookings/booking_1?wait=30", nil)
+		req.Header.Set("If-None-Match", `"2"`)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code, "Should return as soon as the booking changes")
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+
+		var response struct {
+			Booking booking.State `json:"booking"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, booking.StatusPaused, response.Booking.Status)
+		assert.Equal(t, 3, response.Booking.Version)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		handler.SetLongPoll(100*time.Millisecond, 10*time.Millisecond)
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodGet, "/bookings/booking_1?wait=30", nil)
+		req.Header.Set("If-Event-Newer-Than", "3")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusNotModified, resp.Code, "Should answer 304 when the wait runs out")
+		assert.Less(t, time.Since(start), time.Second, "Should cap wait at the configured maximum")
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+	})
+
+	t.Run("change published on the event bus", func(t *testing.T) {
+		bus := eventbus.New()
+		defer bus.Close()
+		handler.SetEventBus(bus)
+		// Without the bus the change would only be seen after the whole wait
+		handler.SetLongPoll(5*time.Second, time.Minute)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_, err := store.AppendBookingEvent(booking.Event{BookingID: "booking_1", Type: booking.EventApproved})
+			assert.NoError(t, err)
+			bus.Publish(context.Background(), eventbus.BookingChanged{BookingID: "booking_1", Type: booking.EventApproved})
+		}()
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodGet, "/bookings/booking_1?wait=30", nil)
+		req.Header.Set("If-Event-Newer-Than", "3")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code, "Should return as soon as the change is published")
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, `"4"`, resp.Header().Get("ETag"))
+	})
+	t.Run("change announced by postgres", func(t *testing.T) {
+		// Made through another gateway: nothing is published on this one's bus
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_, err := store.AppendBookingEvent(booking.Event{BookingID: "booking_1", Type: booking.EventActivated})
+			assert.NoError(t, err)
+			handler.WakeBooking("booking_1")
+		}()
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodGet, "/bookings/booking_1?wait=30", nil)
+		req.Header.Set("If-Event-Newer-Than", "4")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code, "Should return as soon as the change is announced")
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, `"5"`, resp.Header().Get("ETag"))
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// statusRequest is the payload of PATCH /bookings/:id/status
+type statusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UpdateBookingStatus handles PATCH /bookings/:id/status. It records the
+// lifecycle event leading to the requested status, refusing transitions the
+// booking's current status does not allow with 409. Like DELETE /bookings/:id,
+// cancelling a booking no organization owns takes an admin.
+func (h *PlacementHandler) UpdateBookingStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	var req statusRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	eventType, err := booking.EventFor(req.Status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if eventType == booking.EventCancelled && !h.authz.AuthorizeOwned(c, labels.ResourceBooking, id, authz.PermissionManage) {
+		return
+	}
+	if h.events == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Status changes are not available"})
+		return
+	}
+
+	state, err := h.events.AppendBookingEvent(booking.Event{
+		BookingID: id,
+		Type:      eventType,
+		Actor:     c.GetString("user_id"),
+		OrgID:     c.GetString("org_id"),
+		Data:      booking.Change{Reason: req.Reason},
+	})
+	if errors.Is(err, db.ErrBookingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if errors.Is(err, booking.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to change booking status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"booking_id": id,
+		"event":      eventType,
+		"status":     state.Status,
+	}).Info("Changed booking status")
+
+	c.Header("ETag", bookingETag(state.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"event":   eventType,
+		"booking": state,
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementHandler_UpdateBookingStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		stream         []string
+		body           string
+		expectedStatus int
+		expectedState  string
+		description    string
+	}{
+		{"confirm", []string{booking.EventCreated}, `{"status":"confirmed"}`, http.StatusOK, booking.StatusConfirmed, "Should approve pending bookings"},
+		{"activate", []string{booking.EventCreated, booking.EventApproved}, `{"status":"active"}`, http.StatusOK, booking.StatusActive, "Should activate confirmed bookings"},
+		{"resume", []string{booking.EventCreated, booking.EventApproved, booking.EventActivated, booking.EventPaused}, `{"status":"active"}`, http.StatusOK, booking.StatusActive, "Should resume paused bookings"},
+		{"complete", []string{booking.EventCreated, booking.EventApproved, booking.EventActivated}, `{"status":"completed"}`, http.StatusOK, booking.StatusCompleted, "Should complete active bookings"},
+		{"expire", []string{booking.EventCreated, booking.EventApproved}, `{"status":"expired","reason":"flight ended"}`, http.StatusOK, booking.StatusExpired, "Should expire bookings that never went live"},
+		{"cancel", []string{booking.EventCreated, booking.EventApproved, booking.EventActivated}, `{"status":"cancelled"}`, http.StatusOK, booking.StatusCancelled, "Should cancel live bookings"},
+		{"skip confirmation", []string{booking.EventCreated}, `{"status":"active"}`, http.StatusConflict, "", "Should not activate pending bookings"},
+		{"complete before activation", []string{booking.EventCreated, booking.EventApproved}, `{"status":"completed"}`, http.StatusConflict, "", "Should not complete bookings that never ran"},
+		{"expire active", []string{booking.EventCreated, booking.EventApproved, booking.EventActivated}, `{"status":"expired"}`, http.StatusConflict, "", "Should not expire bookings that went live"},
+		{"reopen", []string{booking.EventCreated, booking.EventCancelled}, `{"status":"confirmed"}`, http.StatusConflict, "", "Should not change ended bookings"},
+		{"back to pending", []string{booking.EventCreated, booking.EventApproved}, `{"status":"pending"}`, http.StatusBadRequest, "", "Should refuse statuses no transition leads to"},
+		{"unknown status", []string{booking.EventCreated}, `{"status":"archived"}`, http.StatusBadRequest, "", "Should refuse unknown statuses"},
+		{"missing status", []string{booking.EventCreated}, `{"reason":"why"}`, http.StatusBadRequest, "", "Should require a status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockBookingEventStore{events: map[string][]booking.Event{
+				"booking_1": bookingStream("booking_1", tt.stream...),
+			}}
+			handler := NewPlacementHandler(nil)
+			handler.SetBookingEvents(store)
+			router := gin.New()
+			router.PATCH("/bookings/:id/status", handler.UpdateBookingStatus)
+
+			req := httptest.NewRequest(http.MethodPatch, "/bookings/booking_1/status", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Len(t, store.events["booking_1"], len(tt.stream), "Should not record refused transitions")
+				return
+			}
+			var response struct {
+				Booking booking.State `json:"booking"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedState, response.Booking.Status, tt.description)
+			assert.Equal(t, bookingETag(len(tt.stream)+1), resp.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestPlacementHandler_UpdateBookingStatusNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewPlacementHandler(nil)
+	handler.SetBookingEvents(&MockBookingEventStore{events: map[string][]booking.Event{}})
+	router := gin.New()
+	router.PATCH("/bookings/:id/status", handler.UpdateBookingStatus)
+
+	req := httptest.NewRequest(http.MethodPatch, "/bookings/missing/status", bytes.NewBufferString(`{"status":"confirmed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
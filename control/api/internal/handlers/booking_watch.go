@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchProtocol is the WebSocket subprotocol spoken on GET /watch
+const WatchProtocol = "inscenium.watch.v1"
+
+// maxWatchesPerConnection bounds the bookings and campaigns one connection
+// may watch at once
+const maxWatchesPerConnection = 200
+
+// Watch message types
+const (
+	watchSubscribe    = "subscribe"
+	watchUnsubscribe  = "unsubscribe"
+	watchSubscribed   = "subscribed"
+	watchUnsubscribed = "unsubscribed"
+	watchEvent        = "event"
+	watchError        = "error"
+	watchResync       = "resync"
+)
+
+// watchStream is the push stream of GET /watch. Clients that fell behind
+// are told to resync: reload the bookings they show, as after reconnecting.
+var watchStream = pushStream{
+	name:     "watch",
+	protocol: WatchProtocol,
+	resync:   func() interface{} { return watchMessage{Type: watchResync} },
+}
+
+// watchRequest is a message from a client, watching or unwatching one
+// booking or one campaign. ID is echoed in the reply.
+type watchRequest struct {
+	Type       string `json:"type"`
+	ID         string `json:"id,omitempty"`
+	BookingID  string `json:"booking_id,omitempty"`
+	CampaignID string `json:"campaign_id,omitempty"`
+}
+
+// watchMessage is a message to a client: a reply to one of its requests
+// or an event of a watched booking
+type watchMessage struct {
+	Type       string      `json:"type"`
+	ID         string      `json:"id,omitempty"`
+	BookingID  string      `json:"booking_id,omitempty"`
+	CampaignID string      `json:"campaign_id,omitempty"`
+	Event      string      `json:"event,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// WatchHandler pushes booking changes to dashboards over WebSocket. Each
+// connection multiplexes watches on any number of bookings and campaigns,
+// each authorized like a read of the resource.
+type WatchHandler struct {
+	authz        *authz.Authorizer
+	pingInterval time.Duration
+
+	mu        sync.RWMutex
+	bookings  map[string]map[*watcher]bool
+	campaigns map[string]map[*watcher]bool
+}
+
+// watcher is one client connection
+type watcher struct {
+	*pushConn
+
+	// Guarded by the handler's mu
+	bookings  map[string]bool
+	campaigns map[string]bool
+}
+
+// NewWatchHandler creates a watch handler pushing the booking changes
+// published on bus. Only changes made through this gateway are published
+// on its bus.
+func NewWatchHandler(bus *eventbus.Bus) *WatchHandler {
+	h := &WatchHandler{
+		pingInterval: defaultPushPingInterval,
+		bookings:     make(map[string]map[*watcher]bool),
+		campaigns:    make(map[string]map[*watcher]bool),
+	}
+	eventbus.SubscribeAsync(bus, "booking_watch", 0, func(ctx context.Context, event eventbus.BookingChanged) error {
+		h.push(event)
+		return nil
+	})
+	return h
+}
+
+// SetAuthorizer requires view permission on each watched booking or campaign
+func (h *WatchHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// SetPingInterval sets how often idle connections are pinged; clients that
+// answer nothing for two intervals are disconnected
+func (h *WatchHandler) SetPingInterval(interval time.Duration) {
+	h.pingInterval = interval
+}
+
+// Watch handles GET /watch, a WebSocket connection. Clients send
+// {"type": "subscribe", "booking_id": ...} or "campaign_id", and
+// "unsubscribe" likewise, and receive {"type": "event", "event":
+// "booking.changed", "data": ...} for each change of a watched booking or
+// of a booking in a watched campaign.
+func (h *WatchHandler) Watch(c *gin.Context) {
+	actor := authz.ActorFromContext(c)
+	conn, ok := upgradePush(c, watchStream, h.pingInterval)
+	if !ok {
+		return
+	}
+	metrics.WatchConnections.Inc()
+	defer metrics.WatchConnections.Dec()
+
+	w := &watcher{pushConn: conn, bookings: make(map[string]bool), campaigns: make(map[string]bool)}
+	h.read(w, actor)
+	h.unwatchAll(w)
+	w.finish()
+}
+
+// read handles a client's requests until it disconnects
+func (h *WatchHandler) read(w *watcher, actor authz.Actor) {
+	for {
+		_, data, err := w.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				logrus.WithError(err).Debug("Watch connection lost")
+			}
+			return
+		}
+
+		var req watchRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			w.enqueue(watchMessage{Type: watchError, Error: "messages must be JSON objects"})
+			continue
+		}
+		w.enqueue(h.handle(w, actor, req))
+	}
+}
+
+// handle applies one request and returns its reply
+func (h *WatchHandler) handle(w *watcher, actor authz.Actor, req watchRequest) watchMessage {
+	reply := watchMessage{ID: req.ID, BookingID: req.BookingID, CampaignID: req.CampaignID}
+	if (req.BookingID == "") == (req.CampaignID == "") {
+		reply.Type = watchError
+		reply.Error = "exactly one of booking_id and campaign_id is required"
+		return reply
+	}
+	resourceType, resourceID := labels.ResourceBooking, req.BookingID
+	if req.CampaignID != "" {
+		resourceType, resourceID = labels.ResourceCampaign, req.CampaignID
+	}
+
+	switch req.Type {
+	case watchSubscribe:
+		if h.authz != nil {
+			decision, err := h.authz.Check(actor, resourceType, resourceID, authz.PermissionView)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to authorize watch")
+				reply.Type = watchError
+				reply.Error = "Internal server error"
+				return reply
+			}
+			if !decision.Allowed {
+				reply.Type = watchError
+				reply.Error = fmt.Sprintf("not permitted to %s %s %s", authz.PermissionView, resourceType, resourceID)
+				return reply
+			}
+		}
+		if !h.watch(w, resourceType, resourceID) {
+			reply.Type = watchError
+			reply.Error = fmt.Sprintf("a connection may watch at most %d bookings and campaigns", maxWatchesPerConnection)
+			return reply
+		}
+		reply.Type = watchSubscribed
+	case watchUnsubscribe:
+		h.unwatch(w, resourceType, resourceID)
+		reply.Type = watchUnsubscribed
+	default:
+		reply.Type = watchError
+		reply.Error = "type must be subscribe or unsubscribe"
+	}
+	return reply
+}
+
+// watch adds a watch, unless the connection has too many already
+func (h *WatchHandler) watch(w *watcher, resourceType, resourceID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	index, watched := h.bookings, w.bookings
+	if resourceType == labels.ResourceCampaign {
+		index, watched = h.campaigns, w.campaigns
+	}
+	if watched[resourceID] {
+		return true
+	}
+	if len(w.bookings)+len(w.campaigns) >= maxWatchesPerConnection {
+		return false
+	}
+	watched[resourceID] = true
+	if index[resourceID] == nil {
+		index[resourceID] = make(map[*watcher]bool)
+	}
+	index[resourceID][w] = true
+	return true
+}
+
+// unwatch removes a watch
+func (h *WatchHandler) unwatch(w *watcher, resourceType, resourceID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	index, watched := h.bookings, w.bookings
+	if resourceType == labels.ResourceCampaign {
+		index, watched = h.campaigns, w.campaigns
+	}
+	removeWatch(index, watched, w, resourceID)
+}
+
+// unwatchAll removes every watch of a connection
+func (h *WatchHandler) unwatchAll(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for bookingID := range w.bookings {
+		removeWatch(h.bookings, w.bookings, w, bookingID)
+	}
+	for campaignID := range w.campaigns {
+		removeWatch(h.campaigns, w.campaigns, w, campaignID)
+	}
+}
+
+// removeWatch drops resourceID from a connection's watches and the index
+func removeWatch(index map[string]map[*watcher]bool, watched map[string]bool, w *watcher, resourceID string) {
+	delete(watched, resourceID)
+	delete(index[resourceID], w)
+	if len(index[resourceID]) == 0 {
+		delete(index, resourceID)
+	}
+}
+
+// push sends a booking change to the connections watching the booking or
+// its campaign, once each
+func (h *WatchHandler) push(event eventbus.BookingChanged) {
+	h.mu.RLock()
+	targets := make(map[*watcher]bool, len(h.bookings[event.BookingID]))
+	for w := range h.bookings[event.BookingID] {
+		targets[w] = true
+	}
+	if event.CampaignID != "" {
+		for w := range h.campaigns[event.CampaignID] {
+			targets[w] = true
+		}
+	}
+	h.mu.RUnlock()
+
+	message := watchMessage{Type: watchEvent, Event: event.EventName(), Data: event}
+	for w := range targets {
+		if w.enqueue(message) {
+			metrics.WatchEvents.WithLabelValues("sent").Inc()
+		} else {
+			metrics.WatchEvents.WithLabelValues("dropped").Inc()
+		}
+	}
+}
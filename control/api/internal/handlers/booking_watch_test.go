@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchHandler_Watch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bus := eventbus.New()
+	defer bus.Close()
+	handler := NewWatchHandler(bus)
+	handler.SetAuthorizer(authz.NewAuthorizer(&MockGrantStore{owners: map[string]string{
+		"booking/booking_1":   "org_brand",
+		"campaign/campaign_1": "org_brand",
+		"campaign/campaign_9": "org_rival",
+	}}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_1")
+		c.Set("org_id", "org_brand")
+		c.Next()
+	})
+	router.GET("/watch", handler.Watch)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/watch")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode, "Should only serve WebSocket handshakes")
+
+	conn, _, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/watch", nil, []string{WatchProtocol})
+	require.NoError(t, err)
+	defer conn.Close(websocket.CloseNormal, "")
+	assert.Equal(t, WatchProtocol, conn.Subprotocol)
+
+	request := func(req watchRequest) watchMessage {
+		require.NoError(t, conn.WriteJSON(req))
+		return receiveWatch(t, conn)
+	}
+
+	reply := request(watchRequest{Type: "subscribe", ID: "1", BookingID: "booking_1"})
+	assert.Equal(t, watchMessage{Type: "subscribed", ID: "1", BookingID: "booking_1"}, reply)
+	reply = request(watchRequest{Type: "subscribe", ID: "2", CampaignID: "campaign_9"})
+	assert.Equal(t, "error", reply.Type, "Should refuse to watch another organization's campaign")
+	assert.Equal(t, "not permitted to view campaign campaign_9", reply.Error)
+	reply = request(watchRequest{Type: "subscribe", ID: "3", CampaignID: "campaign_1"})
+	assert.Equal(t, "subscribed", reply.Type)
+	reply = request(watchRequest{Type: "subscribe", BookingID: "booking_1", CampaignID: "campaign_1"})
+	assert.Equal(t, "error", reply.Type, "Should watch one booking or campaign per request")
+
+	ctx := context.Background()
+	bus.Publish(ctx, eventbus.BookingChanged{BookingID: "booking_1", CampaignID: "campaign_1", Type: "approved", Status: "approved"})
+	event := receiveWatch(t, conn)
+	assert.Equal(t, "event", event.Type)
+	assert.Equal(t, eventbus.NameBookingChanged, event.Event)
+	data := event.Data.(map[string]interface{})
+	assert.Equal(t, "booking_1", data["booking_id"])
+	assert.Equal(t, "approved", data["status"])
+
+	// Watched both directly and through its campaign, booking_1 was sent once;
+	// booking_3 is not watched, booking_2 is through its campaign
+	bus.Publish(ctx, eventbus.BookingChanged{BookingID: "booking_3", CampaignID: "campaign_9", Type: "created"})
+	bus.Publish(ctx, eventbus.BookingChanged{BookingID: "booking_2", CampaignID: "campaign_1", Type: "created"})
+	event = receiveWatch(t, conn)
+	assert.Equal(t, "booking_2", event.Data.(map[string]interface{})["booking_id"])
+
+	reply = request(watchRequest{Type: "unsubscribe", CampaignID: "campaign_1"})
+	assert.Equal(t, "unsubscribed", reply.Type)
+	bus.Publish(ctx, eventbus.BookingChanged{BookingID: "booking_2", CampaignID: "campaign_1", Type: "cancelled"})
+	bus.Publish(ctx, eventbus.BookingChanged{BookingID: "booking_1", CampaignID: "campaign_1", Type: "cancelled"})
+	event = receiveWatch(t, conn)
+	assert.Equal(t, "booking_1", event.Data.(map[string]interface{})["booking_id"], "Should stop pushing unwatched campaigns")
+	assert.Equal(t, "cancelled", event.Data.(map[string]interface{})["type"])
+}
+
+// receiveWatch reads the next message of a watch connection
+func receiveWatch(t *testing.T, conn *websocket.Conn) watchMessage {
+	t.Helper()
+	var message watchMessage
+	done := make(chan error, 1)
+	go func() { done <- conn.ReadJSON(&message) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a watch message")
+	}
+	return message
+}
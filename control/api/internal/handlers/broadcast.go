@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/broadcast"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// SignalingStore lists the placement windows broadcast signaling is built from
+type SignalingStore interface {
+	ListSignalingWindows(scope tenant.Scope, titleID string) ([]broadcast.Window, error)
+}
+
+// BroadcastHandler exports placement windows as hybrid broadcast signaling
+type BroadcastHandler struct {
+	db SignalingStore
+}
+
+// NewBroadcastHandler creates a new broadcast signaling handler
+func NewBroadcastHandler(store SignalingStore) *BroadcastHandler {
+	return &BroadcastHandler{db: store}
+}
+
+// GetSignaling handles GET /titles/:title_id/signaling/:format. The partner's
+// application is described by the app_url, app_name, organisation_id,
+// application_id, component_tag and app_context_id query parameters. With
+// ?document= the named document is returned on its own, ready to hand to a
+// playout system or multiplexer.
+func (h *BroadcastHandler) GetSignaling(c *gin.Context) {
+	titleID := c.Param("title_id")
+	format := c.Param("format")
+	if !validSignalingFormat(format) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown signaling format", "formats": broadcast.Formats})
+		return
+	}
+
+	opts := broadcast.Options{
+		AppURL:       c.Query("app_url"),
+		AppName:      c.Query("app_name"),
+		AppContextID: c.Query("app_context_id"),
+	}
+	orgID, err := strconv.ParseUint(c.DefaultQuery("organisation_id", "0"), 0, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organisation_id must be a 32-bit unsigned integer"})
+		return
+	}
+	appID, err := strconv.ParseUint(c.DefaultQuery("application_id", "1"), 0, 16)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "application_id must be between 1 and 65535"})
+		return
+	}
+	componentTag, err := strconv.ParseUint(c.DefaultQuery("component_tag", "0"), 0, 8)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "component_tag must be between 0 and 255"})
+		return
+	}
+	opts.OrganisationID, opts.ApplicationID, opts.ComponentTag = uint32(orgID), uint16(appID), uint8(componentTag)
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	windows, err := h.db.ListSignalingWindows(authz.Scope(c), titleID)
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to list signaling windows")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	signaling, err := broadcast.Build(format, windows, opts)
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to build broadcast signaling")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if name := c.Query("document"); name != "" {
+		document, ok := signaling.Document(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown signaling document"})
+			return
+		}
+		c.Data(http.StatusOK, document.ContentType+"; charset=utf-8", []byte(document.Body))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"title_id":  titleID,
+		"format":    signaling.Format,
+		"windows":   windows,
+		"cues":      signaling.Cues,
+		"documents": signaling.Documents,
+	})
+}
+
+// validSignalingFormat reports whether format names a signaling format
+func validSignalingFormat(format string) bool {
+	for _, known := range broadcast.Formats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
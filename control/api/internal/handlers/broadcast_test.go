@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/broadcast"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSignalingStore serves fixed windows per title
+type MockSignalingStore struct {
+	windows map[string][]broadcast.Window
+	scope   tenant.Scope
+}
+
+func (m *MockSignalingStore) ListSignalingWindows(scope tenant.Scope, titleID string) ([]broadcast.Window, error) {
+	m.scope = scope
+	windows := m.windows[titleID]
+	if windows == nil {
+		windows = []broadcast.Window{}
+	}
+	return windows, nil
+}
+
+func TestBroadcastHandler_GetSignaling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockSignalingStore{windows: map[string][]broadcast.Window{
+		"42": {
+			{BookingID: "booking_1", SurfaceID: "surface_1", PlacementType: "visual", Start: 12.5, End: 18},
+			{BookingID: "booking_2", SurfaceID: "surface_2", PlacementType: "audio", Start: 60, End: 75.25},
+		},
+	}}
+	handler := NewBroadcastHandler(store)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("org_id", "org_a") })
+	router.GET("/titles/:title_id/signaling/:format", handler.GetSignaling)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	resp := get("/titles/42/signaling/hbbtv?app_url=https://apps.example.com/hbbtv/index.html&organisation_id=0x17&application_id=5&component_tag=0x10")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var signaling struct {
+		Format    string               `json:"format"`
+		Cues      []broadcast.Cue      `json:"cues"`
+		Documents []broadcast.Document `json:"documents"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &signaling))
+	assert.Equal(t, tenant.Of("org_a", ""), store.scope, "Windows should be listed within the caller's scope")
+	assert.Equal(t, "hbbtv", signaling.Format)
+	require.Len(t, signaling.Cues, 2)
+	assert.Equal(t, 1, signaling.Cues[0].EventID)
+	assert.Equal(t, 12.5, signaling.Cues[0].Time)
+	assert.Equal(t, 5.5, signaling.Cues[0].Duration)
+	assert.JSONEq(t, `{"booking_id":"booking_1","surface_id":"surface_1","placement_type":"visual","duration":5.5}`, signaling.Cues[0].Payload)
+	require.Len(t, signaling.Documents, 2)
+
+	resp = get("/titles/42/signaling/hbbtv?app_url=https://apps.example.com/hbbtv/index.html&organisation_id=23&application_id=5&document=ait.xml")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "application/vnd.dvb.ait+xml")
+	var ait struct {
+		OrgID    uint32 `xml:"ApplicationDiscovery>ApplicationList>Application>applicationIdentifier>orgId"`
+		AppID    uint16 `xml:"ApplicationDiscovery>ApplicationList>Application>applicationIdentifier>appId"`
+		URLBase  string `xml:"ApplicationDiscovery>ApplicationList>Application>applicationTransport>URLBase"`
+		Location string `xml:"ApplicationDiscovery>ApplicationList>Application>applicationLocation"`
+	}
+	require.NoError(t, xml.Unmarshal(resp.Body.Bytes(), &ait))
+	assert.Equal(t, uint32(23), ait.OrgID)
+	assert.Equal(t, uint16(5), ait.AppID)
+	assert.Equal(t, "https://apps.example.com/hbbtv/", ait.URLBase)
+	assert.Equal(t, "index.html", ait.Location)
+
+	resp = get("/titles/42/signaling/atsc3?app_url=https://apps.example.com/atsc/&document=aei.xml")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var aei struct {
+		SchemeIDURI string `xml:"schemeIdUri,attr"`
+		Events      []struct {
+			PresentationTime int64  `xml:"presentationTime,attr"`
+			Duration         int64  `xml:"duration,attr"`
+			Data             string `xml:",chardata"`
+		} `xml:"Event"`
+	}
+	require.NoError(t, xml.Unmarshal(resp.Body.Bytes(), &aei))
+	assert.Equal(t, broadcast.SchemeIDURI, aei.SchemeIDURI)
+	require.Len(t, aei.Events, 2)
+	assert.Equal(t, int64(60000), aei.Events[1].PresentationTime)
+	assert.Equal(t, int64(15250), aei.Events[1].Duration)
+	assert.Contains(t, aei.Events[1].Data, `"booking_id":"booking_2"`)
+
+	resp = get("/titles/43/signaling/atsc3?app_url=https://apps.example.com/atsc/")
+	require.Equal(t, http.StatusOK, resp.Code, "A title without bookings should still be signaled")
+	assert.Contains(t, resp.Body.String(), `"cues":[]`)
+
+	assert.Equal(t, http.StatusNotFound, get("/titles/42/signaling/dvb-i?app_url=https://apps.example.com/").Code)
+	assert.Equal(t, http.StatusNotFound, get("/titles/42/signaling/hbbtv?app_url=https://apps.example.com/&document=held.xml").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/titles/42/signaling/hbbtv").Code, "app_url should be required")
+	assert.Equal(t, http.StatusBadRequest, get("/titles/42/signaling/hbbtv?app_url=ftp://apps.example.com/").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/titles/42/signaling/hbbtv?app_url=https://apps.example.com/&application_id=0").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/titles/42/signaling/hbbtv?app_url=https://apps.example.com/&component_tag=256").Code)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// BudgetStore persists campaign budgets
+type BudgetStore interface {
+	GetBudget(campaignID string) (*budget.Budget, error)
+	SetBudget(b *budget.Budget) error
+	DeleteBudget(campaignID string) (bool, error)
+}
+
+// BudgetCounter is the fast spend counter kept in front of the budget store
+type BudgetCounter interface {
+	Reset(ctx context.Context, campaignID string) error
+	CounterRemaining(ctx context.Context, campaignID string) (float64, bool, error)
+}
+
+// BudgetHandler manages campaign spend caps
+type BudgetHandler struct {
+	db      BudgetStore
+	counter BudgetCounter
+}
+
+// NewBudgetHandler creates a budget handler
+func NewBudgetHandler(store BudgetStore, counter BudgetCounter) *BudgetHandler {
+	return &BudgetHandler{db: store, counter: counter}
+}
+
+// budgetRequest is the body of PUT /campaigns/:campaign_id/budget
+type budgetRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// GetBudget handles GET /campaigns/:campaign_id/budget
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	h.respond(c, c.Param("campaign_id"))
+}
+
+// SetBudget handles PUT /campaigns/:campaign_id/budget
+func (h *BudgetHandler) SetBudget(c *gin.Context) {
+	var req budgetRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	b := &budget.Budget{
+		CampaignID: c.Param("campaign_id"),
+		Amount:     req.Amount,
+		UpdatedBy:  c.GetString("user_id"),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if err := b.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.SetBudget(b); err != nil {
+		logrus.WithError(err).Error("Failed to save budget")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	h.resetCounter(c, b.CampaignID)
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "budget",
+		"campaign_id": b.CampaignID,
+		"amount":      b.Amount,
+		"user_id":     b.UpdatedBy,
+		"org_id":      c.GetString("org_id"),
+	}).Info("Set campaign budget")
+
+	h.respond(c, b.CampaignID)
+}
+
+// DeleteBudget handles DELETE /campaigns/:campaign_id/budget
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	campaignID := c.Param("campaign_id")
+
+	deleted, err := h.db.DeleteBudget(campaignID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete budget")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign has no budget"})
+		return
+	}
+	h.resetCounter(c, campaignID)
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "budget",
+		"campaign_id": campaignID,
+		"user_id":     c.GetString("user_id"),
+		"org_id":      c.GetString("org_id"),
+	}).Info("Removed campaign budget")
+
+	c.Status(http.StatusNoContent)
+}
+
+// resetCounter drops the campaign's fast counter so it reloads the new budget.
+// If that fails the counter keeps the old budget until it is reconciled.
+func (h *BudgetHandler) resetCounter(c *gin.Context, campaignID string) {
+	if err := h.counter.Reset(c.Request.Context(), campaignID); err != nil {
+		logrus.WithError(err).WithField("campaign_id", campaignID).Warn("Failed to reset budget counter")
+	}
+}
+
+// respond writes a budget with its authoritative and counted remaining spend
+func (h *BudgetHandler) respond(c *gin.Context, campaignID string) {
+	b, err := h.db.GetBudget(campaignID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get budget")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign has no budget"})
+		return
+	}
+
+	counter := gin.H{"loaded": false}
+	remaining, loaded, err := h.counter.CounterRemaining(c.Request.Context(), campaignID)
+	if err != nil {
+		logrus.WithError(err).WithField("campaign_id", campaignID).Warn("Failed to read budget counter")
+	} else if loaded {
+		counter = gin.H{"loaded": true, "remaining": remaining}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget":    b,
+		"remaining": b.Remaining(),
+		"counter":   counter,
+	})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBudgetStore backs both BudgetHandler and budget.Tracker
+type MockBudgetStore struct {
+	budgets     map[string]*budget.Budget
+	charges     map[string]*budget.Charge // booking ID -> charge
+	shouldError bool
+}
+
+func (m *MockBudgetStore) GetBudget(campaignID string) (*budget.Budget, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.budgets[campaignID], nil
+}
+
+func (m *MockBudgetStore) ListBudgets() ([]budget.Budget, error) {
+	budgets := make([]budget.Budget, 0, len(m.budgets))
+	for _, b := range m.budgets {
+		budgets = append(budgets, *b)
+	}
+	return budgets, nil
+}
+
+func (m *MockBudgetStore) SetBudget(b *budget.Budget) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if existing, ok := m.budgets[b.CampaignID]; ok {
+		b.Spent = existing.Spent
+	}
+	m.budgets[b.CampaignID] = b
+	return nil
+}
+
+func (m *MockBudgetStore) DeleteBudget(campaignID string) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	_, ok := m.budgets[campaignID]
+	delete(m.budgets, campaignID)
+	return ok, nil
+}
+
+func (m *MockBudgetStore) GetBookingCharge(bookingID string) (*budget.Charge, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	charge, ok := m.charges[bookingID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *charge // The tracker prices the charge it is given
+	return &copied, nil
+}
+
+// newMockBudgetStore has a campaign with a budget of 100 of which 98.99 is
+// spent, and bookings of it and of a campaign without a budget at 10 CPM.
+// booking_3 and booking_4 of the first campaign are billed on attention and
+// viewability, and booking_5 on listen-through of a 30 second audio slot.
+func newMockBudgetStore() *MockBudgetStore {
+	return &MockBudgetStore{
+		budgets: map[string]*budget.Budget{
+			"campaign_1": {CampaignID: "campaign_1", Amount: 100, Spent: 98.99},
+		},
+		charges: map[string]*budget.Charge{
+			"booking_1": {BookingID: "booking_1", CampaignID: "campaign_1", Terms: billing.Terms{Model: billing.ModelCPM, CPM: 10}},
+			"booking_2": {BookingID: "booking_2", CampaignID: "campaign_2", Terms: billing.Terms{Model: billing.ModelCPM, CPM: 10}},
+			"booking_3": {BookingID: "booking_3", CampaignID: "campaign_1", Terms: billing.Terms{Model: billing.ModelAttentionCPM, CPM: 10}},
+			"booking_4": {BookingID: "booking_4", CampaignID: "campaign_1", Terms: billing.Terms{Model: billing.ModelVCPM, CPM: 10, MinDuration: 2}},
+			"booking_5": {BookingID: "booking_5", CampaignID: "campaign_1", Terms: billing.Terms{Model: billing.ModelVCPM, CPM: 10, MinDuration: 2, SlotDuration: 30}},
+		},
+	}
+}
+
+func TestBudgetHandler_SetBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name              string
+		campaignID        string
+		body              string
+		expectedStatus    int
+		expectedRemaining float64
+		description       string
+	}{
+		{
+			name:              "raise budget",
+			campaignID:        "campaign_1",
+			body:              `{"amount": 150}`,
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: 51.01,
+			description:       "Should keep the spend recorded so far",
+		},
+		{
+			name:              "new budget",
+			campaignID:        "campaign_2",
+			body:              `{"amount": 500}`,
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: 500,
+			description:       "Should cap a campaign without a budget",
+		},
+		{
+			name:           "zero amount",
+			campaignID:     "campaign_1",
+			body:           `{"amount": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a positive amount",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockBudgetStore()
+			router := gin.New()
+			router.Use(withOrg("user_1", "org_brand"))
+			router.PUT("/campaigns/:campaign_id/budget", NewBudgetHandler(store, budget.NewTracker(store, nil, budget.DefaultSafetyMargin)).SetBudget)
+
+			req := httptest.NewRequest(http.MethodPut, "/campaigns/"+tt.campaignID+"/budget", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Budget    budget.Budget `json:"budget"`
+				Remaining float64       `json:"remaining"`
+				Counter   struct {
+					Loaded bool `json:"loaded"`
+				} `json:"counter"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, "user_1", response.Budget.UpdatedBy)
+			assert.InDelta(t, tt.expectedRemaining, response.Remaining, 0.0001, tt.description)
+			assert.False(t, response.Counter.Loaded, "Should report no counter without Redis")
+		})
+	}
+}
+
+func TestBudgetHandler_DeleteBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockBudgetStore()
+	handler := NewBudgetHandler(store, budget.NewTracker(store, nil, budget.DefaultSafetyMargin))
+	router := gin.New()
+	router.GET("/campaigns/:campaign_id/budget", handler.GetBudget)
+	router.DELETE("/campaigns/:campaign_id/budget", handler.DeleteBudget)
+
+	for _, step := range []struct {
+		method         string
+		expectedStatus int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodDelete, http.StatusNoContent},
+		{http.MethodDelete, http.StatusNotFound},
+		{http.MethodGet, http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(step.method, "/campaigns/campaign_1/budget", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, step.expectedStatus, resp.Code, "%s", step.method)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBulkBookings bounds the bookings one bulk operation may touch
+const maxBulkBookings = 1000
+
+// Bulk operation outcomes per booking
+const (
+	bulkApplied    = "applied"
+	bulkWouldApply = "would_apply"
+	bulkSkipped    = "skipped"
+	bulkFailed     = "failed"
+)
+
+// bulkAction is the lifecycle event a bulk action records and the status it
+// leaves. Owned actions skip bookings without an owner unless an admin acts.
+type bulkAction struct {
+	event  string
+	status string
+	owned  bool
+}
+
+var bulkActions = map[string]bulkAction{
+	"pause":  {booking.EventPaused, booking.StatusPaused, false},
+	"cancel": {booking.EventCancelled, booking.StatusCancelled, true},
+}
+
+// BulkBookingStore selects bookings and records lifecycle events for them
+type BulkBookingStore interface {
+	SelectBookings(scope tenant.Scope, selector booking.Selector, limit int) ([]booking.Summary, error)
+	AppendBookingEvent(event booking.Event) (*booking.State, error)
+}
+
+// BulkBookingHandler pauses or cancels many bookings at once
+type BulkBookingHandler struct {
+	db    BulkBookingStore
+	authz *authz.Authorizer
+}
+
+// NewBulkBookingHandler creates a bulk booking handler
+func NewBulkBookingHandler(store BulkBookingStore) *BulkBookingHandler {
+	return &BulkBookingHandler{db: store}
+}
+
+// SetAuthorizer requires manage permission on every booking changed
+func (h *BulkBookingHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// bulkRequest is the body of POST /bookings/bulk/:action
+type bulkRequest struct {
+	CampaignID   string     `json:"campaign_id"`
+	AdvertiserID string     `json:"advertiser_id"`
+	Labels       labels.Set `json:"labels"`
+	DryRun       bool       `json:"dry_run"`
+	Reason       string     `json:"reason"`
+}
+
+// bulkResult is the outcome of a bulk operation for one booking
+type bulkResult struct {
+	booking.Summary
+	NewStatus      string  `json:"new_status,omitempty"`
+	RemainingSpend float64 `json:"remaining_spend"`
+	Outcome        string  `json:"outcome"`
+	Reason         string  `json:"reason,omitempty"`
+}
+
+// spendImpact totals the spend of the bookings a bulk operation affects
+type spendImpact struct {
+	Committed float64 `json:"committed"`
+	Delivered float64 `json:"delivered"`
+	Released  float64 `json:"released"` // Undelivered spend no longer due
+}
+
+// ApplyBulk handles POST /bookings/bulk/:action. With dry_run it reports the
+// bookings that would change and the spend impact without changing anything.
+func (h *BulkBookingHandler) ApplyBulk(c *gin.Context) {
+	action := c.Param("action")
+	op, ok := bulkActions[action]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown bulk action %q", action)})
+		return
+	}
+
+	var req bulkRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Labels.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	selector := booking.Selector{CampaignID: req.CampaignID, AdvertiserID: req.AdvertiserID, Labels: req.Labels}
+	if selector.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign_id, advertiser_id or labels is required"})
+		return
+	}
+	if !h.authz.Authorize(c, labels.ResourceCampaign, selector.CampaignID, authz.PermissionManage) {
+		return
+	}
+
+	orgID := c.GetString("org_id")
+	matched, err := h.db.SelectBookings(authz.Scope(c), selector, maxBulkBookings+1)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to select bookings for bulk operation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if len(matched) > maxBulkBookings {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("selector matches more than %d live bookings; narrow it", maxBulkBookings),
+		})
+		return
+	}
+
+	actor := authz.ActorFromContext(c)
+	results := make([]bulkResult, 0, len(matched))
+	counts := make(map[string]int)
+	var impact spendImpact
+	for _, summary := range matched {
+		result := h.apply(actor, summary, op, req)
+		results = append(results, result)
+		counts[result.Outcome]++
+		if result.Outcome == bulkApplied || result.Outcome == bulkWouldApply {
+			impact.Committed += summary.CommittedSpend()
+			impact.Delivered += summary.DeliveredSpend()
+			impact.Released += summary.RemainingSpend()
+		}
+	}
+
+	if !req.DryRun {
+		logrus.WithFields(logrus.Fields{
+			"audit":         "bulk_booking",
+			"action":        action,
+			"user_id":       actor.UserID,
+			"org_id":        orgID,
+			"campaign_id":   selector.CampaignID,
+			"advertiser_id": selector.AdvertiserID,
+			"labels":        selector.Labels,
+			"applied":       counts[bulkApplied],
+			"failed":        counts[bulkFailed],
+		}).Info("Applied bulk booking action")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":       action,
+		"dry_run":      req.DryRun,
+		"matched":      len(matched),
+		"counts":       counts,
+		"spend_impact": impact,
+		"bookings":     results,
+	})
+}
+
+// apply pauses or cancels one booking, or previews doing so on a dry run
+func (h *BulkBookingHandler) apply(actor authz.Actor, summary booking.Summary, op bulkAction, req bulkRequest) bulkResult {
+	result := bulkResult{Summary: summary, RemainingSpend: summary.RemainingSpend()}
+
+	if err := booking.CheckTransition(summary.Status, op.event); err != nil {
+		result.Outcome, result.Reason = bulkSkipped, err.Error()
+		return result
+	}
+	if h.authz != nil {
+		check := h.authz.Check
+		if op.owned {
+			check = h.authz.CheckOwned
+		}
+		decision, err := check(actor, labels.ResourceBooking, summary.BookingID, authz.PermissionManage)
+		if err != nil {
+			logrus.WithError(err).WithField("booking_id", summary.BookingID).Error("Authorization check failed")
+			result.Outcome, result.Reason = bulkFailed, "authorization check failed"
+			return result
+		}
+		if !decision.Allowed {
+			result.Outcome, result.Reason = bulkSkipped, "not permitted to manage booking"
+			return result
+		}
+	}
+
+	if req.DryRun {
+		result.NewStatus, result.Outcome = op.status, bulkWouldApply
+		return result
+	}
+
+	state, err := h.db.AppendBookingEvent(booking.Event{
+		BookingID: summary.BookingID,
+		Type:      op.event,
+		Actor:     actor.UserID,
+		OrgID:     actor.OrgID,
+		Data:      booking.Change{Reason: req.Reason},
+	})
+	if errors.Is(err, booking.ErrInvalidTransition) {
+		// The booking changed since it was selected
+		result.Outcome, result.Reason = bulkSkipped, err.Error()
+		return result
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", summary.BookingID).Error("Failed to apply bulk booking action")
+		result.Outcome, result.Reason = bulkFailed, "internal error"
+		return result
+	}
+
+	result.NewStatus, result.Outcome = state.Status, bulkApplied
+	return result
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBulkBookingStore struct {
+	bookings    []booking.Summary
+	appended    []booking.Event
+	selector    booking.Selector
+	scope       tenant.Scope
+	shouldError bool
+}
+
+func (m *MockBulkBookingStore) SelectBookings(scope tenant.Scope, selector booking.Selector, limit int) ([]booking.Summary, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.scope, m.selector = scope, selector
+	if len(m.bookings) > limit {
+		return m.bookings[:limit], nil
+	}
+	return m.bookings, nil
+}
+
+func (m *MockBulkBookingStore) AppendBookingEvent(event booking.Event) (*booking.State, error) {
+	for _, summary := range m.bookings {
+		if summary.BookingID != event.BookingID {
+			continue
+		}
+		if err := booking.CheckTransition(summary.Status, event.Type); err != nil {
+			return nil, err
+		}
+		m.appended = append(m.appended, event)
+		status := booking.StatusCancelled
+		if event.Type == booking.EventPaused {
+			status = booking.StatusPaused
+		}
+		return &booking.State{BookingID: event.BookingID, Status: status}, nil
+	}
+	return nil, assert.AnError
+}
+
+func TestBulkBookingHandler_ApplyBulk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	campaign := []booking.Summary{
+		// 5.00 CPM x 10,000 = 50.00 committed, 2,000 delivered = 10.00
+		{BookingID: "booking_1", CampaignID: "camp_1", Status: booking.StatusConfirmed, BidAmountCPM: 5, EstimatedImpressions: 10000, ActualImpressions: 2000},
+		// 2.50 CPM x 4,000 = 10.00 committed, nothing delivered
+		{BookingID: "booking_2", CampaignID: "camp_1", Status: booking.StatusPending, BidAmountCPM: 2.5, EstimatedImpressions: 4000},
+		// 4.00 CPM x 1,000 = 4.00 committed, over-delivered
+		{BookingID: "booking_3", CampaignID: "camp_1", Status: booking.StatusPaused, BidAmountCPM: 4, EstimatedImpressions: 1000, ActualImpressions: 1500},
+	}
+
+	tests := []struct {
+		name             string
+		action           string
+		body             map[string]interface{}
+		bookings         []booking.Summary
+		shouldError      bool
+		expectedStatus   int
+		expectedCounts   map[string]int
+		expectedReleased float64
+		expectedAppended []string
+		description      string
+	}{
+		{
+			name:             "dry run cancel",
+			action:           "cancel",
+			body:             map[string]interface{}{"campaign_id": "camp_1", "dry_run": true},
+			bookings:         campaign,
+			expectedStatus:   http.StatusOK,
+			expectedCounts:   map[string]int{bulkWouldApply: 3},
+			expectedReleased: 50,
+			expectedAppended: []string{},
+			description:      "Should preview every live booking without changing any",
+		},
+		{
+			name:             "cancel",
+			action:           "cancel",
+			body:             map[string]interface{}{"campaign_id": "camp_1", "reason": "campaign pulled"},
+			bookings:         campaign,
+			expectedStatus:   http.StatusOK,
+			expectedCounts:   map[string]int{bulkApplied: 3},
+			expectedReleased: 50,
+			expectedAppended: []string{"booking_1", "booking_2", "booking_3"},
+			description:      "Should cancel every live booking",
+		},
+		{
+			name:             "pause",
+			action:           "pause",
+			body:             map[string]interface{}{"advertiser_id": "adv_1", "labels": map[string]string{"team": "sports"}},
+			bookings:         campaign,
+			expectedStatus:   http.StatusOK,
+			expectedCounts:   map[string]int{bulkApplied: 1, bulkSkipped: 2},
+			expectedReleased: 40,
+			expectedAppended: []string{"booking_1"},
+			description:      "Should only pause confirmed or active bookings",
+		},
+		{
+			name:           "empty selector",
+			action:         "cancel",
+			body:           map[string]interface{}{"dry_run": true},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse to select every booking",
+		},
+		{
+			name:           "unknown action",
+			action:         "archive",
+			body:           map[string]interface{}{"campaign_id": "camp_1"},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should reject unknown actions",
+		},
+		{
+			name:           "too many bookings",
+			action:         "cancel",
+			body:           map[string]interface{}{"campaign_id": "camp_1"},
+			bookings:       make([]booking.Summary, maxBulkBookings+1),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should ask for a narrower selector",
+		},
+		{
+			name:           "store error",
+			action:         "cancel",
+			body:           map[string]interface{}{"campaign_id": "camp_1"},
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockBulkBookingStore{bookings: tt.bookings, shouldError: tt.shouldError}
+			handler := NewBulkBookingHandler(store)
+			router := gin.New()
+			router.POST("/bookings/bulk/:action", withOrg("user_1", "org_a"), handler.ApplyBulk)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/bookings/bulk/"+tt.action, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Empty(t, store.appended, "Should not change bookings on error")
+				return
+			}
+
+			var response struct {
+				DryRun      bool           `json:"dry_run"`
+				Matched     int            `json:"matched"`
+				Counts      map[string]int `json:"counts"`
+				SpendImpact spendImpact    `json:"spend_impact"`
+				Bookings    []bulkResult   `json:"bookings"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, len(tt.bookings), response.Matched)
+			assert.Equal(t, tt.expectedCounts, response.Counts, tt.description)
+			assert.InDelta(t, tt.expectedReleased, response.SpendImpact.Released, 0.001)
+			assert.Len(t, response.Bookings, len(tt.bookings))
+
+			appended := make([]string, 0, len(store.appended))
+			for _, event := range store.appended {
+				appended = append(appended, event.BookingID)
+				assert.Equal(t, "user_1", event.Actor)
+				assert.Equal(t, "org_a", event.OrgID)
+			}
+			assert.Equal(t, tt.expectedAppended, appended, tt.description)
+
+			if tt.body["advertiser_id"] != nil {
+				assert.Equal(t, "adv_1", store.selector.AdvertiserID)
+				assert.Equal(t, "sports", store.selector.Labels["team"])
+			}
+			for _, result := range response.Bookings {
+				if result.Outcome == bulkWouldApply || result.Outcome == bulkApplied {
+					assert.NotEmpty(t, result.NewStatus)
+				}
+			}
+		})
+	}
+}
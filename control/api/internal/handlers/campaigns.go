@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// CampaignStore persists advertisers and their campaigns
+type CampaignStore interface {
+	CreateAdvertiser(a *campaign.Advertiser) error
+	GetAdvertiser(advertiserID string) (*campaign.Advertiser, error)
+	ListAdvertisers(scope tenant.Scope, includeArchived bool, page pagination.Page) ([]*campaign.Advertiser, error)
+	UpdateAdvertiser(a *campaign.Advertiser) (bool, error)
+	CreateCampaign(c *campaign.Campaign) error
+	GetCampaign(campaignID string) (*campaign.Campaign, error)
+	ListCampaigns(scope tenant.Scope, filter campaign.Filter, page pagination.Page) ([]*campaign.Campaign, error)
+	UpdateCampaign(c *campaign.Campaign, b *budget.Budget) (bool, error)
+}
+
+// CampaignHandler manages advertisers and campaigns. Advertisers are
+// visible to their organization; campaigns are checked against their owner
+// and grants by the authorizer in front of the per-campaign routes.
+type CampaignHandler struct {
+	db      CampaignStore
+	counter BudgetCounter
+}
+
+// NewCampaignHandler creates a campaign handler
+func NewCampaignHandler(store CampaignStore) *CampaignHandler {
+	return &CampaignHandler{db: store}
+}
+
+// SetBudgetCounter resets the fast spend counter of campaigns whose budget changes
+func (h *CampaignHandler) SetBudgetCounter(counter BudgetCounter) {
+	h.counter = counter
+}
+
+// archive sets or clears an archived timestamp
+func archive(archivedAt *time.Time, archived bool) *time.Time {
+	switch {
+	case archived && archivedAt == nil:
+		now := time.Now().UTC()
+		return &now
+	case !archived:
+		return nil
+	}
+	return archivedAt
+}
+
+// loadAdvertiser fetches the advertiser named in the path, answering 404
+// for advertisers outside the caller's organization
+func (h *CampaignHandler) loadAdvertiser(c *gin.Context) (*campaign.Advertiser, bool) {
+	a, err := h.db.GetAdvertiser(c.Param("advertiser_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get advertiser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if a == nil || !authz.Scope(c).Allows(a.OrgID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Advertiser not found"})
+		return nil, false
+	}
+	return a, true
+}
+
+// saveAdvertiser writes an updated advertiser and responds with it
+func (h *CampaignHandler) saveAdvertiser(c *gin.Context, a *campaign.Advertiser, action string) {
+	updated, err := h.db.UpdateAdvertiser(a)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to update advertiser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Advertiser not found"})
+		return
+	}
+	a.Status = campaign.StatusActive
+	if a.ArchivedAt != nil {
+		a.Status = campaign.StatusArchived
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":         "advertiser",
+		"advertiser_id": a.AdvertiserID,
+		"status":        a.Status,
+		"user_id":       c.GetString("user_id"),
+		"org_id":        c.GetString("org_id"),
+	}).Info(action)
+
+	c.JSON(http.StatusOK, a)
+}
+
+// CreateAdvertiser handles POST /advertisers. The advertiser belongs to the
+// caller's organization.
+func (h *CampaignHandler) CreateAdvertiser(c *gin.Context) {
+	var req struct {
+		AdvertiserID string `json:"advertiser_id" binding:"required"`
+		Name         string `json:"name" binding:"required"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a := &campaign.Advertiser{
+		AdvertiserID: req.AdvertiserID,
+		Name:         req.Name,
+		OrgID:        c.GetString("org_id"),
+		CreatedBy:    c.GetString("user_id"),
+	}
+	if err := a.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.CreateAdvertiser(a); err != nil {
+		if errors.Is(err, db.ErrAdvertiserExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Advertiser already exists"})
+			return
+		}
+		logrus.WithError(err).Error("Failed to create advertiser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create advertiser"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":         "advertiser",
+		"advertiser_id": a.AdvertiserID,
+		"user_id":       a.CreatedBy,
+		"org_id":        a.OrgID,
+	}).Info("Created advertiser")
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// ListAdvertisers handles GET /advertisers, newest first
+func (h *CampaignHandler) ListAdvertisers(c *gin.Context) {
+	page, ok := parsePage(c, 20, 100)
+	if !ok {
+		return
+	}
+	includeArchived := c.Query("include_archived") == "true"
+
+	advertisers, err := h.db.ListAdvertisers(authz.Scope(c), includeArchived, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list advertisers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	advertisers, next, prev := pagination.Window(advertisers, page, func(a *campaign.Advertiser) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(a.CreatedAt), ID: a.AdvertiserID}
+	})
+
+	response := gin.H{
+		"advertisers": advertisers,
+		"total_count": len(advertisers),
+		"limit":       page.Limit,
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAdvertiser handles GET /advertisers/:advertiser_id
+func (h *CampaignHandler) GetAdvertiser(c *gin.Context) {
+	a, ok := h.loadAdvertiser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// UpdateAdvertiser handles PATCH /advertisers/:advertiser_id
+func (h *CampaignHandler) UpdateAdvertiser(c *gin.Context) {
+	var req struct {
+		Name     *string `json:"name"`
+		Archived *bool   `json:"archived"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a, ok := h.loadAdvertiser(c)
+	if !ok {
+		return
+	}
+	if req.Name != nil {
+		a.Name = *req.Name
+	}
+	if req.Archived != nil {
+		a.ArchivedAt = archive(a.ArchivedAt, *req.Archived)
+	}
+	if err := a.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.saveAdvertiser(c, a, "Updated advertiser")
+}
+
+// ArchiveAdvertiser handles DELETE /advertisers/:advertiser_id. Its
+// campaigns can no longer be booked; existing bookings are unaffected.
+func (h *CampaignHandler) ArchiveAdvertiser(c *gin.Context) {
+	a, ok := h.loadAdvertiser(c)
+	if !ok {
+		return
+	}
+	a.ArchivedAt = archive(a.ArchivedAt, true)
+	h.saveAdvertiser(c, a, "Archived advertiser")
+}
+
+// loadCampaign fetches the campaign named in the path
+func (h *CampaignHandler) loadCampaign(c *gin.Context) (*campaign.Campaign, bool) {
+	camp, err := h.db.GetCampaign(c.Param("campaign_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if camp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+	return camp, true
+}
+
+// saveCampaign writes an updated campaign, with b as its budget unless nil,
+// and responds with it
+func (h *CampaignHandler) saveCampaign(c *gin.Context, camp *campaign.Campaign, b *budget.Budget, action string) {
+	updated, err := h.db.UpdateCampaign(camp, b)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to update campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if b != nil {
+		h.resetCounter(c, camp.CampaignID)
+	}
+	camp.Status = campaign.StatusActive
+	if camp.ArchivedAt != nil {
+		camp.Status = campaign.StatusArchived
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "campaign",
+		"campaign_id": camp.CampaignID,
+		"status":      camp.Status,
+		"user_id":     c.GetString("user_id"),
+		"org_id":      c.GetString("org_id"),
+	}).Info(action)
+
+	c.JSON(http.StatusOK, camp)
+}
+
+// resetCounter drops the campaign's fast spend counter so it reloads the new budget
+func (h *CampaignHandler) resetCounter(c *gin.Context, campaignID string) {
+	if h.counter == nil {
+		return
+	}
+	if err := h.counter.Reset(c.Request.Context(), campaignID); err != nil {
+		logrus.WithError(err).WithField("campaign_id", campaignID).Warn("Failed to reset budget counter")
+	}
+}
+
+// CreateCampaign handles POST /campaigns. The campaign belongs to the
+// caller's organization and must be for an active advertiser it can see.
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req struct {
+		CampaignID   string       `json:"campaign_id" binding:"required"`
+		AdvertiserID string       `json:"advertiser_id" binding:"required"`
+		Name         string       `json:"name" binding:"required"`
+		Budget       *float64     `json:"budget"`
+		FlightStart  *time.Time   `json:"flight_start"`
+		FlightEnd    *time.Time   `json:"flight_end"`
+		FrequencyCap *freqcap.Cap `json:"frequency_cap"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	camp := &campaign.Campaign{
+		CampaignID:   req.CampaignID,
+		AdvertiserID: req.AdvertiserID,
+		Name:         req.Name,
+		OrgID:        c.GetString("org_id"),
+		Budget:       req.Budget,
+		FlightStart:  req.FlightStart,
+		FlightEnd:    req.FlightEnd,
+		FrequencyCap: req.FrequencyCap,
+		CreatedBy:    c.GetString("user_id"),
+	}
+	if err := camp.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a, err := h.db.GetAdvertiser(camp.AdvertiserID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get advertiser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if a == nil || !authz.Scope(c).Allows(a.OrgID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "advertiser " + camp.AdvertiserID + " does not exist"})
+		return
+	}
+	if a.ArchivedAt != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "advertiser " + camp.AdvertiserID + " is archived"})
+		return
+	}
+
+	if err := h.db.CreateCampaign(camp); err != nil {
+		if errors.Is(err, db.ErrCampaignExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Campaign already exists"})
+			return
+		}
+		logrus.WithError(err).Error("Failed to create campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+		return
+	}
+	if camp.Budget != nil {
+		h.resetCounter(c, camp.CampaignID)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":         "campaign",
+		"campaign_id":   camp.CampaignID,
+		"advertiser_id": camp.AdvertiserID,
+		"user_id":       camp.CreatedBy,
+		"org_id":        camp.OrgID,
+	}).Info("Created campaign")
+
+	c.JSON(http.StatusCreated, camp)
+}
+
+// ListCampaigns handles GET /campaigns, listing the caller's campaigns and
+// those shared with its organization, newest first
+func (h *CampaignHandler) ListCampaigns(c *gin.Context) {
+	page, ok := parsePage(c, 20, 100)
+	if !ok {
+		return
+	}
+	filter := campaign.Filter{
+		AdvertiserID:    c.Query("advertiser_id"),
+		IncludeArchived: c.Query("include_archived") == "true",
+	}
+
+	campaigns, err := h.db.ListCampaigns(authz.Scope(c), filter, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list campaigns")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	campaigns, next, prev := pagination.Window(campaigns, page, func(camp *campaign.Campaign) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(camp.CreatedAt), ID: camp.CampaignID}
+	})
+
+	response := gin.H{
+		"campaigns":   campaigns,
+		"total_count": len(campaigns),
+		"limit":       page.Limit,
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCampaign handles GET /campaigns/:campaign_id
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	camp, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, camp)
+}
+
+// UpdateCampaign handles PATCH /campaigns/:campaign_id. A campaign's
+// advertiser cannot change; its budget is removed under /budget, and its
+// frequency cap with a max_exposures of 0.
+func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
+	var req struct {
+		Name         *string      `json:"name"`
+		Budget       *float64     `json:"budget"`
+		FlightStart  *time.Time   `json:"flight_start"`
+		FlightEnd    *time.Time   `json:"flight_end"`
+		FrequencyCap *freqcap.Cap `json:"frequency_cap"`
+		Archived     *bool        `json:"archived"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	camp, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+	if req.Name != nil {
+		camp.Name = *req.Name
+	}
+	if req.Budget != nil {
+		camp.Budget = req.Budget
+	}
+	if req.FlightStart != nil {
+		camp.FlightStart = req.FlightStart
+	}
+	if req.FlightEnd != nil {
+		camp.FlightEnd = req.FlightEnd
+	}
+	if req.FrequencyCap != nil {
+		camp.FrequencyCap = req.FrequencyCap
+		if req.FrequencyCap.MaxExposures == 0 {
+			camp.FrequencyCap = nil
+		}
+	}
+	if req.Archived != nil {
+		camp.ArchivedAt = archive(camp.ArchivedAt, *req.Archived)
+	}
+	if err := camp.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var b *budget.Budget
+	if req.Budget != nil {
+		b = &budget.Budget{
+			CampaignID: camp.CampaignID,
+			Amount:     *req.Budget,
+			UpdatedBy:  c.GetString("user_id"),
+			UpdatedAt:  time.Now().UTC(),
+		}
+	}
+	h.saveCampaign(c, camp, b, "Updated campaign")
+}
+
+// ArchiveCampaign handles DELETE /campaigns/:campaign_id. The campaign can
+// no longer be booked; existing bookings and its budget are unaffected.
+func (h *CampaignHandler) ArchiveCampaign(c *gin.Context) {
+	camp, ok := h.loadCampaign(c)
+	if !ok {
+		return
+	}
+	camp.ArchivedAt = archive(camp.ArchivedAt, true)
+	h.saveCampaign(c, camp, nil, "Archived campaign")
+}
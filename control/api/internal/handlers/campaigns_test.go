@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockCampaignStore keeps advertisers and campaigns in memory
+type MockCampaignStore struct {
+	advertisers map[string]*campaign.Advertiser
+	campaigns   map[string]*campaign.Campaign
+	budgets     map[string]*budget.Budget // Budgets saved by UpdateCampaign
+	shouldError bool
+}
+
+func newMockCampaignStore() *MockCampaignStore {
+	return &MockCampaignStore{
+		advertisers: map[string]*campaign.Advertiser{},
+		campaigns:   map[string]*campaign.Campaign{},
+		budgets:     map[string]*budget.Budget{},
+	}
+}
+
+func (m *MockCampaignStore) CreateAdvertiser(a *campaign.Advertiser) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if _, ok := m.advertisers[a.AdvertiserID]; ok {
+		return db.ErrAdvertiserExists
+	}
+	a.CreatedAt, a.UpdatedAt, a.Status = time.Now(), time.Now(), campaign.StatusActive
+	stored := *a
+	m.advertisers[a.AdvertiserID] = &stored
+	return nil
+}
+
+func (m *MockCampaignStore) GetAdvertiser(advertiserID string) (*campaign.Advertiser, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	a, ok := m.advertisers[advertiserID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (m *MockCampaignStore) ListAdvertisers(scope tenant.Scope, includeArchived bool, page pagination.Page) ([]*campaign.Advertiser, error) {
+	advertisers := make([]*campaign.Advertiser, 0)
+	for _, a := range m.advertisers {
+		if scope.Allows(a.OrgID) && (includeArchived || a.ArchivedAt == nil) {
+			advertisers = append(advertisers, a)
+		}
+	}
+	sort.Slice(advertisers, func(i, j int) bool { return advertisers[i].AdvertiserID < advertisers[j].AdvertiserID })
+	return advertisers, nil
+}
+
+func (m *MockCampaignStore) UpdateAdvertiser(a *campaign.Advertiser) (bool, error) {
+	if _, ok := m.advertisers[a.AdvertiserID]; !ok {
+		return false, nil
+	}
+	stored := *a
+	m.advertisers[a.AdvertiserID] = &stored
+	return true, nil
+}
+
+func (m *MockCampaignStore) CreateCampaign(c *campaign.Campaign) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if _, ok := m.campaigns[c.CampaignID]; ok {
+		return db.ErrCampaignExists
+	}
+	c.CreatedAt, c.UpdatedAt, c.Status = time.Now(), time.Now(), campaign.StatusActive
+	stored := *c
+	m.campaigns[c.CampaignID] = &stored
+	return nil
+}
+
+func (m *MockCampaignStore) GetCampaign(campaignID string) (*campaign.Campaign, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	c, ok := m.campaigns[campaignID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (m *MockCampaignStore) ListCampaigns(scope tenant.Scope, filter campaign.Filter, page pagination.Page) ([]*campaign.Campaign, error) {
+	campaigns := make([]*campaign.Campaign, 0)
+	for _, c := range m.campaigns {
+		if scope.Allows(c.OrgID) && (filter.AdvertiserID == "" || c.AdvertiserID == filter.AdvertiserID) &&
+			(filter.IncludeArchived || c.ArchivedAt == nil) {
+			campaigns = append(campaigns, c)
+		}
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].CampaignID < campaigns[j].CampaignID })
+	return campaigns, nil
+}
+
+func (m *MockCampaignStore) UpdateCampaign(c *campaign.Campaign, b *budget.Budget) (bool, error) {
+	if _, ok := m.campaigns[c.CampaignID]; !ok {
+		return false, nil
+	}
+	stored := *c
+	m.campaigns[c.CampaignID] = &stored
+	if b != nil {
+		m.budgets[b.CampaignID] = b
+	}
+	return true, nil
+}
+
+func TestCampaignHandler_Advertisers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockCampaignStore()
+	store.advertisers["adv_other"] = &campaign.Advertiser{AdvertiserID: "adv_other", Name: "Other", OrgID: "org_other", Status: campaign.StatusActive}
+	handler := NewCampaignHandler(store)
+
+	router := gin.New()
+	router.Use(withOrg("alice", "org_brand"))
+	router.POST("/advertisers", handler.CreateAdvertiser)
+	router.GET("/advertisers", handler.ListAdvertisers)
+	router.GET("/advertisers/:advertiser_id", handler.GetAdvertiser)
+	router.PATCH("/advertisers/:advertiser_id", handler.UpdateAdvertiser)
+	router.DELETE("/advertisers/:advertiser_id", handler.ArchiveAdvertiser)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/advertisers", `{"advertiser_id":"adv_1","name":"Acme"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, "org_brand", store.advertisers["adv_1"].OrgID, "Should belong to the caller's organization")
+	assert.Equal(t, "alice", store.advertisers["adv_1"].CreatedBy)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/advertisers", `{"advertiser_id":"adv_1","name":"Again"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/advertisers", `{"advertiser_id":"bad id","name":"Acme"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/advertisers", `{"advertiser_id":"adv_2","name":"  "}`).Code)
+
+	var listed struct {
+		Advertisers []campaign.Advertiser `json:"advertisers"`
+	}
+	resp = do(http.MethodGet, "/advertisers", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	require.Len(t, listed.Advertisers, 1, "Should only list the caller's organization's advertisers")
+	assert.Equal(t, "adv_1", listed.Advertisers[0].AdvertiserID)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/advertisers?cursor=bogus", "").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/advertisers/adv_1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/advertisers/adv_other", "").Code, "Should hide other organizations' advertisers")
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/advertisers/adv_other", "").Code)
+
+	resp = do(http.MethodPatch, "/advertisers/adv_1", `{"name":"Acme Corp"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "Acme Corp", store.advertisers["adv_1"].Name)
+
+	resp = do(http.MethodDelete, "/advertisers/adv_1", "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotNil(t, store.advertisers["adv_1"].ArchivedAt)
+	assert.Contains(t, resp.Body.String(), `"status":"archived"`)
+	resp = do(http.MethodGet, "/advertisers", "")
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	assert.Empty(t, listed.Advertisers, "Should leave archived advertisers out by default")
+	resp = do(http.MethodGet, "/advertisers?include_archived=true", "")
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	assert.Len(t, listed.Advertisers, 1)
+
+	resp = do(http.MethodPatch, "/advertisers/adv_1", `{"archived":false}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Nil(t, store.advertisers["adv_1"].ArchivedAt, "Should restore the advertiser")
+}
+
+func TestCampaignHandler_Campaigns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockCampaignStore()
+	store.advertisers["adv_1"] = &campaign.Advertiser{AdvertiserID: "adv_1", Name: "Acme", OrgID: "org_brand"}
+	store.advertisers["adv_other"] = &campaign.Advertiser{AdvertiserID: "adv_other", Name: "Other", OrgID: "org_other"}
+	archivedAt := time.Now()
+	store.advertisers["adv_old"] = &campaign.Advertiser{AdvertiserID: "adv_old", Name: "Old", OrgID: "org_brand", ArchivedAt: &archivedAt}
+	handler := NewCampaignHandler(store)
+
+	router := gin.New()
+	router.Use(withOrg("alice", "org_brand"))
+	router.POST("/campaigns", handler.CreateCampaign)
+	router.GET("/campaigns", handler.ListCampaigns)
+	router.GET("/campaigns/:campaign_id", handler.GetCampaign)
+	router.PATCH("/campaigns/:campaign_id", handler.UpdateCampaign)
+	router.DELETE("/campaigns/:campaign_id", handler.ArchiveCampaign)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "unknown advertiser",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_missing","name":"Spring"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should refuse campaigns of advertisers that do not exist",
+		},
+		{
+			name:           "other organization's advertiser",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_other","name":"Spring"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should refuse campaigns of advertisers the caller cannot see",
+		},
+		{
+			name:           "archived advertiser",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_old","name":"Spring"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should refuse campaigns of archived advertisers",
+		},
+		{
+			name:           "flight ends before it starts",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_1","name":"Spring","flight_start":"2024-05-01T00:00:00Z","flight_end":"2024-04-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse a flight ending before it starts",
+		},
+		{
+			name:           "negative budget",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_1","name":"Spring","budget":-5}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse budgets that are not positive",
+		},
+		{
+			name:           "frequency cap without a window",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_1","name":"Spring","frequency_cap":{"max_exposures":3}}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse frequency caps without a window",
+		},
+		{
+			name:           "missing name",
+			body:           `{"campaign_id":"camp_1","advertiser_id":"adv_1"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(http.MethodPost, "/campaigns", tt.body)
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Empty(t, store.campaigns)
+		})
+	}
+
+	resp := do(http.MethodPost, "/campaigns", `{"campaign_id":"camp_1","advertiser_id":"adv_1","name":"Spring","budget":5000,"flight_start":"2024-04-01T00:00:00Z","flight_end":"2024-05-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	stored := store.campaigns["camp_1"]
+	assert.Equal(t, "org_brand", stored.OrgID, "Should belong to the caller's organization")
+	require.NotNil(t, stored.Budget)
+	assert.Equal(t, 5000.0, *stored.Budget)
+	require.NotNil(t, stored.FlightEnd)
+	assert.Equal(t, "2024-05-01T00:00:00Z", stored.FlightEnd.Format(time.RFC3339))
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/campaigns", `{"campaign_id":"camp_1","advertiser_id":"adv_1","name":"Again"}`).Code)
+
+	store.campaigns["camp_other"] = &campaign.Campaign{CampaignID: "camp_other", AdvertiserID: "adv_other", Name: "Other", OrgID: "org_other"}
+	var listed struct {
+		Campaigns []campaign.Campaign `json:"campaigns"`
+	}
+	resp = do(http.MethodGet, "/campaigns?advertiser_id=adv_1", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	require.Len(t, listed.Campaigns, 1)
+	assert.Equal(t, "camp_1", listed.Campaigns[0].CampaignID)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/campaigns/camp_1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/campaigns/camp_missing", "").Code)
+
+	resp = do(http.MethodPatch, "/campaigns/camp_1", `{"name":"Spring launch","budget":7500}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "Spring launch", store.campaigns["camp_1"].Name)
+	require.Contains(t, store.budgets, "camp_1", "Should save a changed budget")
+	assert.Equal(t, 7500.0, store.budgets["camp_1"].Amount)
+	assert.Equal(t, "alice", store.budgets["camp_1"].UpdatedBy)
+
+	resp = do(http.MethodPatch, "/campaigns/camp_1", `{"flight_end":"2024-03-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "Should check the flight with the stored start")
+
+	resp = do(http.MethodPatch, "/campaigns/camp_1", `{"frequency_cap":{"max_exposures":3,"window_hours":24}}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotNil(t, store.campaigns["camp_1"].FrequencyCap)
+	assert.Equal(t, 3, store.campaigns["camp_1"].FrequencyCap.MaxExposures)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/campaigns/camp_1", `{"frequency_cap":{"max_exposures":3,"window_hours":721}}`).Code)
+	resp = do(http.MethodPatch, "/campaigns/camp_1", `{"frequency_cap":{"max_exposures":0}}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Nil(t, store.campaigns["camp_1"].FrequencyCap, "A max_exposures of 0 should remove the cap")
+
+	resp = do(http.MethodDelete, "/campaigns/camp_1", "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.NotNil(t, store.campaigns["camp_1"].ArchivedAt)
+	assert.Contains(t, resp.Body.String(), `"status":"archived"`)
+	resp = do(http.MethodGet, "/campaigns", "")
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	assert.Empty(t, listed.Campaigns, "Should leave archived campaigns out by default")
+}
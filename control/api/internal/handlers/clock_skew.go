@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"time"
+)
+
+// clockSkewTolerance is the skew below which device clocks are trusted as-is.
+// Differences smaller than this are dominated by network latency rather than
+// a misconfigured device clock.
+const clockSkewTolerance = 2 * time.Second
+
+// ExposureTimestamps holds the raw and skew-corrected times of an exposure
+type ExposureTimestamps struct {
+	Raw        *time.Time    // Event time as reported by the device
+	Corrected  time.Time     // Event time on the server clock, used for rollups
+	ReceivedAt time.Time     // Time the request reached the gateway
+	Skew       time.Duration // Estimated server clock minus device clock
+}
+
+// estimateClockSkew estimates how far the device clock is behind the server
+// clock, using the device's wall clock at send time and the receive time.
+func estimateClockSkew(deviceClock *time.Time, receivedAt time.Time) time.Duration {
+	if deviceClock == nil || deviceClock.IsZero() {
+		return 0
+	}
+
+	skew := receivedAt.Sub(*deviceClock)
+	if skew > -clockSkewTolerance && skew < clockSkewTolerance {
+		return 0
+	}
+	return skew
+}
+
+// correctExposureTimestamps resolves the timestamps stored for an exposure.
+// Without a device event time the receive time is used; without a device
+// clock the event time is taken at face value.
+func correctExposureTimestamps(eventTime, deviceClock *time.Time, receivedAt time.Time) ExposureTimestamps {
+	ts := ExposureTimestamps{
+		ReceivedAt: receivedAt,
+		Corrected:  receivedAt,
+	}
+
+	if eventTime == nil || eventTime.IsZero() {
+		return ts
+	}
+
+	ts.Raw = eventTime
+	ts.Skew = estimateClockSkew(deviceClock, receivedAt)
+	ts.Corrected = eventTime.Add(ts.Skew)
+	return ts
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// CollisionStore persists per-shot collision rules
+type CollisionStore interface {
+	ListCollisionRules(titleID string) ([]collision.Rule, error)
+	SetCollisionRule(rule *collision.Rule) error
+	DeleteCollisionRule(titleID, shotID string) (bool, error)
+}
+
+// CollisionHandler manages how crowded a shot's booked placements may be
+type CollisionHandler struct {
+	db CollisionStore
+}
+
+// NewCollisionHandler creates a collision rule handler
+func NewCollisionHandler(store CollisionStore) *CollisionHandler {
+	return &CollisionHandler{db: store}
+}
+
+// collisionRuleRequest is the body of PUT /inventory/collision-rules/:title_id
+// and of PUT /inventory/collision-rules/:title_id/shots/:shot_id
+type collisionRuleRequest struct {
+	MaxConcurrent int     `json:"max_concurrent"`
+	MinSeparation float64 `json:"min_separation"`
+	Reason        string  `json:"reason"`
+}
+
+// ListRules handles GET /inventory/collision-rules/:title_id
+func (h *CollisionHandler) ListRules(c *gin.Context) {
+	titleID := c.Param("title_id")
+
+	rules, err := h.db.ListCollisionRules(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list collision rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"title_id":    titleID,
+		"rules":       rules,
+		"total_count": len(rules),
+	})
+}
+
+// SetRule handles PUT /inventory/collision-rules/:title_id, the default rule
+// of the title's shots, and PUT /inventory/collision-rules/:title_id/shots/:shot_id.
+// Bookings made before the rule are kept; the earliest of them are served first.
+func (h *CollisionHandler) SetRule(c *gin.Context) {
+	var req collisionRuleRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &collision.Rule{
+		TitleID:       c.Param("title_id"),
+		ShotID:        c.Param("shot_id"),
+		MaxConcurrent: req.MaxConcurrent,
+		MinSeparation: req.MinSeparation,
+		Reason:        req.Reason,
+		UpdatedBy:     c.GetString("user_id"),
+		UpdatedAt:     time.Now().UTC(),
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.SetCollisionRule(rule)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		message := "Title not found"
+		if rule.ShotID != "" {
+			message = "Title or shot not found"
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save collision rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":          "collision_rule",
+		"title_id":       rule.TitleID,
+		"shot_id":        rule.ShotID,
+		"max_concurrent": rule.MaxConcurrent,
+		"min_separation": rule.MinSeparation,
+		"user_id":        rule.UpdatedBy,
+		"org_id":         c.GetString("org_id"),
+	}).Info("Set collision rule")
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /inventory/collision-rules/:title_id and
+// DELETE /inventory/collision-rules/:title_id/shots/:shot_id. A shot without
+// a rule of its own falls back to the title's default.
+func (h *CollisionHandler) DeleteRule(c *gin.Context) {
+	titleID, shotID := c.Param("title_id"), c.Param("shot_id")
+
+	deleted, err := h.db.DeleteCollisionRule(titleID, shotID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete collision rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No collision rule found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "collision_rule",
+		"title_id": titleID,
+		"shot_id":  shotID,
+		"user_id":  c.GetString("user_id"),
+		"org_id":   c.GetString("org_id"),
+	}).Info("Removed collision rule")
+
+	c.Status(http.StatusNoContent)
+}
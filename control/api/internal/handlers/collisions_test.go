@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCollisionStore struct {
+	rules       map[string]*collision.Rule // Keyed by title_id/shot_id
+	shouldError bool
+}
+
+func (m *MockCollisionStore) ListCollisionRules(titleID string) ([]collision.Rule, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	rules := make([]collision.Rule, 0)
+	for _, rule := range m.rules {
+		if rule.TitleID == titleID {
+			rules = append(rules, *rule)
+		}
+	}
+	return rules, nil
+}
+
+func (m *MockCollisionStore) SetCollisionRule(rule *collision.Rule) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if rule.TitleID == "missing" || rule.ShotID == "missing" {
+		return db.ErrTitleNotFound
+	}
+	m.rules[rule.TitleID+"/"+rule.ShotID] = rule
+	return nil
+}
+
+func (m *MockCollisionStore) DeleteCollisionRule(titleID, shotID string) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	_, ok := m.rules[titleID+"/"+shotID]
+	delete(m.rules, titleID+"/"+shotID)
+	return ok, nil
+}
+
+func TestCollisionHandler_Rules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockCollisionStore{rules: map[string]*collision.Rule{}}
+	handler := NewCollisionHandler(store)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "planner")
+		c.Next()
+	})
+	router.GET("/inventory/collision-rules/:title_id", handler.ListRules)
+	router.PUT("/inventory/collision-rules/:title_id", handler.SetRule)
+	router.DELETE("/inventory/collision-rules/:title_id", handler.DeleteRule)
+	router.PUT("/inventory/collision-rules/:title_id/shots/:shot_id", handler.SetRule)
+	router.DELETE("/inventory/collision-rules/:title_id/shots/:shot_id", handler.DeleteRule)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send(http.MethodPut, "/inventory/collision-rules/1", `{"max_concurrent":2,"reason":"Keep dialogue scenes clean"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, 2, store.rules["1/"].MaxConcurrent)
+	assert.Equal(t, "planner", store.rules["1/"].UpdatedBy)
+
+	resp = send(http.MethodPut, "/inventory/collision-rules/1/shots/shot_007", `{"max_concurrent":1,"min_separation":0.1}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var rule collision.Rule
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &rule))
+	assert.Equal(t, "shot_007", rule.ShotID)
+	assert.Equal(t, 0.1, rule.MinSeparation)
+
+	resp = send(http.MethodGet, "/inventory/collision-rules/1", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"total_count":2`)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"no limits", "/inventory/collision-rules/1", `{"reason":"nothing"}`, http.StatusBadRequest},
+		{"negative concurrency", "/inventory/collision-rules/1", `{"max_concurrent":-1}`, http.StatusBadRequest},
+		{"separation past the frame", "/inventory/collision-rules/1", `{"min_separation":1.5}`, http.StatusBadRequest},
+		{"unknown title", "/inventory/collision-rules/missing", `{"max_concurrent":1}`, http.StatusNotFound},
+		{"unknown shot", "/inventory/collision-rules/1/shots/missing", `{"max_concurrent":1}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, send(http.MethodPut, tt.path, tt.body).Code)
+		})
+	}
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/inventory/collision-rules/1/shots/shot_007", "").Code)
+	assert.NotContains(t, store.rules, "1/shot_007")
+	assert.Contains(t, store.rules, "1/", "Deleting a shot's rule should keep the title default")
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/inventory/collision-rules/1/shots/shot_007", "").Code)
+
+	store.shouldError = true
+	assert.Equal(t, http.StatusInternalServerError, send(http.MethodGet, "/inventory/collision-rules/1", "").Code)
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/impcap"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// Placement decision outcomes
+const (
+	DecisionServed   = "served"
+	DecisionUnfilled = "unfilled"
+	DecisionError    = "error"
+)
+
+// Serve error codes counted in inscenium_placement_serve_errors_total. Any
+// other code is counted as ServeErrorOther, so clients cannot create metric
+// series; decision_events keeps the code as reported.
+const (
+	ServeErrorTimeout      = "timeout"
+	ServeErrorCreativeLoad = "creative_load_failed"
+	ServeErrorRender       = "render_failed"
+	ServeErrorPlayback     = "playback_failed"
+	ServeErrorNetwork      = "network_error"
+	ServeErrorUnknown      = "unknown"
+	ServeErrorOther        = "other"
+)
+
+// serveErrorCodes are the error codes counted under their own name
+var serveErrorCodes = map[string]bool{
+	ServeErrorTimeout: true, ServeErrorCreativeLoad: true, ServeErrorRender: true, ServeErrorPlayback: true,
+	ServeErrorNetwork: true, ServeErrorUnknown: true,
+}
+
+// serveErrorLabel returns the metric label of an error code reported by a client
+func serveErrorLabel(code string) string {
+	if serveErrorCodes[code] {
+		return code
+	}
+	return ServeErrorOther
+}
+
+// maxFillRateWindow bounds how far back fill-rate queries may look
+const maxFillRateWindow = 7 * 24 * time.Hour
+
+// DeliveryStore persists placement decisions and computes delivery rates
+type DeliveryStore interface {
+	RecordDecisionEvent(event map[string]interface{}) (string, error)
+	GetFillRates(titleID string, since time.Time) ([]map[string]interface{}, error)
+}
+
+// BudgetTracker charges served decisions against campaign budgets
+type BudgetTracker interface {
+	Charge(ctx context.Context, bookingID string) (*budget.Charge, error)
+	Refund(ctx context.Context, charge *budget.Charge)
+}
+
+// ImpressionCapEnforcer holds served decisions to their booking's max_impressions
+type ImpressionCapEnforcer interface {
+	Take(ctx context.Context, bookingID, nodeID string) (*impcap.Reservation, error)
+	GiveBack(ctx context.Context, r *impcap.Reservation)
+}
+
+// CollisionChecker refuses served decisions that would crowd a shot past its collision rule
+type CollisionChecker interface {
+	CheckServeCollision(bookingID string) error
+}
+
+// PacingChecker refuses served decisions of evenly paced bookings ahead of their pace
+type PacingChecker interface {
+	CheckPacing(bookingID string) error
+}
+
+// AudioSlotSource looks up the audio slot a booking placed, nil for
+// bookings of visual surfaces
+type AudioSlotSource interface {
+	GetBookingAudioSlot(bookingID string) (*audio.Slot, error)
+}
+
+// DeliveryHandler tracks placement decision outcomes per surface
+type DeliveryHandler struct {
+	db         DeliveryStore
+	budgets    BudgetTracker
+	caps       ImpressionCapEnforcer
+	collisions CollisionChecker
+	pacing     PacingChecker
+	slots      AudioSlotSource
+}
+
+// NewDeliveryHandler creates a new delivery handler
+func NewDeliveryHandler(store DeliveryStore) *DeliveryHandler {
+	return &DeliveryHandler{db: store}
+}
+
+// SetBudgetTracker charges served decisions to their campaign's budget
+func (h *DeliveryHandler) SetBudgetTracker(tracker BudgetTracker) {
+	h.budgets = tracker
+}
+
+// SetImpressionCaps refuses served decisions past their booking's max_impressions
+func (h *DeliveryHandler) SetImpressionCaps(enforcer ImpressionCapEnforcer) {
+	h.caps = enforcer
+}
+
+// SetCollisionChecker refuses served decisions whose booking collides with
+// an earlier booking of the same shot
+func (h *DeliveryHandler) SetCollisionChecker(checker CollisionChecker) {
+	h.collisions = checker
+}
+
+// SetPacing refuses served decisions of evenly paced bookings that have
+// delivered their share of max_impressions so far
+func (h *DeliveryHandler) SetPacing(checker PacingChecker) {
+	h.pacing = checker
+}
+
+// SetAudioSlots checks served decisions of audio slots against the slot's
+// length and loudness constraints
+func (h *DeliveryHandler) SetAudioSlots(source AudioSlotSource) {
+	h.slots = source
+}
+
+// RecordDecision handles POST /events/decision. Served decisions of audio
+// slots may describe the creative to play, which must fit the slot, and are
+// answered with the slot.
+func (h *DeliveryHandler) RecordDecision(c *gin.Context) {
+	var req struct {
+		SurfaceID string          `json:"surface_id" binding:"required"`
+		TitleID   string          `json:"title_id" binding:"required"`
+		Outcome   string          `json:"outcome" binding:"required,oneof=served unfilled error"`
+		BookingID string          `json:"booking_id"`
+		NodeID    string          `json:"node_id"` // Edge node that served from its lease
+		ErrorCode string          `json:"error_code" binding:"max=50"`
+		Timestamp *time.Time      `json:"timestamp"`
+		Creative  *audio.Creative `json:"creative"` // Measured creative of audio slots
+	}
+
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Creative != nil {
+		if err := req.Creative.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if req.Outcome == DecisionServed && req.BookingID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id is required for served decisions"})
+		return
+	}
+	if req.Outcome == DecisionError && req.ErrorCode == "" {
+		req.ErrorCode = ServeErrorUnknown
+	}
+
+	eventTimestamp := time.Now().UTC()
+	if req.Timestamp != nil {
+		eventTimestamp = *req.Timestamp
+	}
+
+	event := map[string]interface{}{
+		"surface_id":      req.SurfaceID,
+		"title_id":        req.TitleID,
+		"booking_id":      req.BookingID,
+		"outcome":         req.Outcome,
+		"error_code":      req.ErrorCode,
+		"event_timestamp": eventTimestamp,
+	}
+
+	// Served decisions are checked against their audio slot, their shot's
+	// collision rule and their booking's pacing, then take an impression and
+	// are charged before they are recorded, so a creative that does not fit
+	// its slot, or a placement that would crowd the frame, is ahead of its
+	// pace, is past its cap or whose campaign budget is spent is refused and
+	// should not render
+	var slot *audio.Slot
+	if req.Outcome == DecisionServed && h.slots != nil {
+		var err error
+		slot, err = h.slots.GetBookingAudioSlot(req.BookingID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to look up audio slot")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+		if slot != nil && req.Creative != nil {
+			if err := slot.Fit(*req.Creative); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "slot_mismatch": true, "audio": slot})
+				return
+			}
+		}
+	}
+	if req.Outcome == DecisionServed && h.collisions != nil {
+		err := h.collisions.CheckServeCollision(req.BookingID)
+		switch {
+		case errors.Is(err, collision.ErrCollision):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "collision": true})
+			return
+		case err != nil:
+			logrus.WithError(err).Error("Failed to check placement collisions")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+	}
+	if req.Outcome == DecisionServed && h.pacing != nil {
+		err := h.pacing.CheckPacing(req.BookingID)
+		switch {
+		case errors.Is(err, pacing.ErrAheadOfPace):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "ahead_of_pace": true})
+			return
+		case err != nil:
+			logrus.WithError(err).Error("Failed to check booking pacing")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+	}
+
+	var reservation *impcap.Reservation
+	if req.Outcome == DecisionServed && h.caps != nil {
+		var err error
+		reservation, err = h.caps.Take(c.Request.Context(), req.BookingID, req.NodeID)
+		switch {
+		case errors.Is(err, impcap.ErrCapReached):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "cap_reached": true})
+			return
+		case errors.Is(err, impcap.ErrUnknownBooking):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id does not exist"})
+			return
+		case err != nil:
+			logrus.WithError(err).Error("Failed to check impression cap")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+	}
+
+	var charge *budget.Charge
+	if req.Outcome == DecisionServed && h.budgets != nil {
+		var err error
+		charge, err = h.budgets.Charge(c.Request.Context(), req.BookingID)
+		if err != nil && reservation != nil {
+			h.caps.GiveBack(c.Request.Context(), reservation)
+		}
+		switch {
+		case errors.Is(err, budget.ErrExhausted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "budget_exhausted": true})
+			return
+		case errors.Is(err, budget.ErrUnknownBooking):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id does not exist"})
+			return
+		case err != nil:
+			logrus.WithError(err).Error("Failed to charge campaign budget")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+		event["campaign_id"] = charge.CampaignID
+		event["spend"] = charge.Amount()
+	}
+
+	eventID, err := h.db.RecordDecisionEvent(event)
+	if err != nil {
+		if charge != nil {
+			h.budgets.Refund(c.Request.Context(), charge)
+		}
+		if reservation != nil {
+			h.caps.GiveBack(c.Request.Context(), reservation)
+		}
+		logrus.WithError(err).Error("Failed to record decision event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	metrics.PlacementOpportunities.WithLabelValues(req.TitleID).Inc()
+	switch req.Outcome {
+	case DecisionServed:
+		metrics.PlacementDecisionsServed.WithLabelValues(req.TitleID).Inc()
+	case DecisionError:
+		metrics.PlacementServeErrors.WithLabelValues(req.TitleID, serveErrorLabel(req.ErrorCode)).Inc()
+	}
+
+	response := gin.H{
+		"success":  true,
+		"event_id": eventID,
+	}
+	if slot != nil {
+		response["audio"] = slot
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetFillRates handles GET /publisher/fill-rates
+func (h *DeliveryHandler) GetFillRates(c *gin.Context) {
+	titleID := c.Query("title_id")
+
+	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window <= 0 || window > maxFillRateWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration between 0 and 168h"})
+		return
+	}
+
+	rows, err := h.db.GetFillRates(titleID, time.Now().Add(-window))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get fill rates")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	surfaces := make([]gin.H, 0, len(rows))
+	titles := make(map[string]*deliveryTotals)
+	titleOrder := make([]string, 0)
+
+	for _, row := range rows {
+		title, _ := row["title_id"].(string)
+		opportunities := toInt64(row["opportunities"])
+		served := toInt64(row["served"])
+		errors := toInt64(row["errors"])
+
+		surfaces = append(surfaces, gin.H{
+			"title_id":      title,
+			"surface_id":    row["surface_id"],
+			"opportunities": opportunities,
+			"served":        served,
+			"errors":        errors,
+			"fill_rate":     ratio(served, opportunities),
+			"error_rate":    ratio(errors, opportunities),
+		})
+
+		totals, ok := titles[title]
+		if !ok {
+			totals = &deliveryTotals{}
+			titles[title] = totals
+			titleOrder = append(titleOrder, title)
+		}
+		totals.opportunities += opportunities
+		totals.served += served
+		totals.errors += errors
+	}
+
+	titleRates := make([]gin.H, 0, len(titleOrder))
+	for _, title := range titleOrder {
+		totals := titles[title]
+		titleRates = append(titleRates, gin.H{
+			"title_id":      title,
+			"opportunities": totals.opportunities,
+			"served":        totals.served,
+			"errors":        totals.errors,
+			"fill_rate":     ratio(totals.served, totals.opportunities),
+			"error_rate":    ratio(totals.errors, totals.opportunities),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":   window.String(),
+		"surfaces": surfaces,
+		"titles":   titleRates,
+	})
+}
+
+// deliveryTotals accumulates decision counts for a title
+type deliveryTotals struct {
+	opportunities int64
+	served        int64
+	errors        int64
+}
+
+// ratio returns n/d, or 0 when there were no opportunities
+func ratio(n, d int64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// toInt64 reads a count from a store row
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,632 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
+	"github.com/inscenium/inscenium/control/api/internal/impcap"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDeliveryStore struct {
+	events      []map[string]interface{}
+	rates       []map[string]interface{}
+	since       time.Time
+	shouldError bool
+}
+
+func (m *MockDeliveryStore) RecordDecisionEvent(event map[string]interface{}) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	m.events = append(m.events, event)
+	return "decision_123", nil
+}
+
+func (m *MockDeliveryStore) GetFillRates(titleID string, since time.Time) ([]map[string]interface{}, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.since = since
+	return m.rates, nil
+}
+
+func TestDeliveryHandler_RecordDecision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		shouldError    bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "served decision",
+			body:           `{"surface_id":"surface_1","title_id":"title_1","outcome":"served","booking_id":"booking_1"}`,
+			expectedStatus: http.StatusCreated,
+			description:    "Should record served decisions",
+		},
+		{
+			name:           "unfilled decision",
+			body:           `{"surface_id":"surface_1","title_id":"title_1","outcome":"unfilled"}`,
+			expectedStatus: http.StatusCreated,
+			description:    "Should record unfilled decisions",
+		},
+		{
+			name:           "served without booking",
+			body:           `{"surface_id":"surface_1","title_id":"title_1","outcome":"served"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a booking for served decisions",
+		},
+		{
+			name:           "unknown outcome",
+			body:           `{"surface_id":"surface_1","title_id":"title_1","outcome":"maybe"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown outcomes",
+		},
+		{
+			name:           "store error",
+			body:           `{"surface_id":"surface_1","title_id":"title_1","outcome":"error","error_code":"timeout"}`,
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewDeliveryHandler(&MockDeliveryStore{shouldError: tt.shouldError})
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
+
+func TestDeliveryHandler_RecordDecisionErrorCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewDeliveryHandler(&MockDeliveryStore{})
+	router := gin.New()
+	router.POST("/events/decision", handler.RecordDecision)
+
+	counted := func(titleID, code string) float64 {
+		var m dto.Metric
+		require.NoError(t, metrics.PlacementServeErrors.WithLabelValues(titleID, code).Write(&m))
+		return m.GetCounter().GetValue()
+	}
+
+	for _, code := range []string{"timeout", "<script>", "crash-0x7f3a", ""} {
+		body := `{"surface_id":"surface_1","title_id":"title_codes","outcome":"error","error_code":"` + code + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusCreated, resp.Code)
+	}
+
+	assert.Equal(t, 1.0, counted("title_codes", ServeErrorTimeout), "Should count known codes under their name")
+	assert.Equal(t, 2.0, counted("title_codes", ServeErrorOther), "Should count free-form codes as other")
+	assert.Equal(t, 1.0, counted("title_codes", ServeErrorUnknown), "Should count missing codes as unknown")
+}
+
+func TestDeliveryHandler_RecordDecisionBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		bookingID      string
+		spent          float64
+		expectedStatus int
+		expectedSpend  float64
+		description    string
+	}{
+		{
+			name:           "within budget",
+			bookingID:      "booking_1",
+			spent:          98.99,
+			expectedStatus: http.StatusCreated,
+			expectedSpend:  0.01,
+			description:    "Should charge the impression to the campaign",
+		},
+		{
+			name:           "inside safety margin",
+			bookingID:      "booking_1",
+			spent:          98.995,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse spend the safety margin withholds",
+		},
+		{
+			name:           "campaign without budget",
+			bookingID:      "booking_2",
+			expectedStatus: http.StatusCreated,
+			expectedSpend:  0.01,
+			description:    "Should not limit campaigns without a budget",
+		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_404",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject served decisions for unknown bookings",
+		},
+		{
+			name:           "attention billed",
+			bookingID:      "booking_3",
+			spent:          98.98,
+			expectedStatus: http.StatusCreated,
+			expectedSpend:  0,
+			description:    "Should leave charging attention-billed impressions to their exposures",
+		},
+		{
+			name:           "attention billed near budget",
+			bookingID:      "booking_3",
+			spent:          98.99,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse to serve unless the most an exposure could cost still fits",
+		},
+	}
+
+	bands, err := billing.ParseBands(billing.DefaultBands)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budgets := newMockBudgetStore()
+			budgets.budgets["campaign_1"].Spent = tt.spent
+			store := &MockDeliveryStore{}
+			handler := NewDeliveryHandler(store)
+			tracker := budget.NewTracker(budgets, nil, budget.DefaultSafetyMargin)
+			tracker.SetPricing(billing.Pricing{Bands: bands})
+			handler.SetBudgetTracker(tracker)
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			body := `{"surface_id":"surface_1","title_id":"title_1","outcome":"served","booking_id":"` + tt.bookingID + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusCreated {
+				assert.Empty(t, store.events, "Should not record refused decisions")
+				return
+			}
+			require.Len(t, store.events, 1)
+			assert.InDelta(t, tt.expectedSpend, store.events[0]["spend"], 0.000001)
+		})
+	}
+}
+
+func TestDeliveryHandler_RecordDecisionImpressionCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		bookingID      string
+		served         int64
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "under cap",
+			bookingID:      "booking_1",
+			served:         89,
+			expectedStatus: http.StatusCreated,
+			description:    "Should serve while impressions are left",
+		},
+		{
+			name:           "leases take the rest",
+			bookingID:      "booking_1",
+			served:         90,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse impressions leased to edge nodes",
+		},
+		{
+			name:           "uncapped booking",
+			bookingID:      "booking_2",
+			expectedStatus: http.StatusCreated,
+			description:    "Should not limit bookings without max_impressions",
+		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_404",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject served decisions for unknown bookings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps := newMockImpressionCapStore()
+			caps.caps["booking_1"].Served = tt.served
+			store := &MockDeliveryStore{}
+			handler := NewDeliveryHandler(store)
+			handler.SetImpressionCaps(impcap.NewEnforcer(caps, nil, impcap.DefaultLeaseTTL))
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			body := `{"surface_id":"surface_1","title_id":"title_1","outcome":"served","booking_id":"` + tt.bookingID + `","node_id":"edge_1"}`
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusConflict {
+				assert.Contains(t, resp.Body.String(), `"cap_reached":true`)
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				assert.Empty(t, store.events, "Should not record refused decisions")
+			}
+		})
+	}
+}
+
+// MockCollisionChecker applies a rule to a booking and the earlier bookings
+// of its shot
+type MockCollisionChecker struct {
+	rule      collision.Rule
+	placement collision.Placement
+	earlier   []collision.Placement
+}
+
+func (m *MockCollisionChecker) CheckServeCollision(bookingID string) error {
+	if bookingID != m.placement.BookingID {
+		return nil
+	}
+	return m.rule.Check(m.placement, m.earlier)
+}
+
+func TestDeliveryHandler_RecordDecisionCollision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	booking := collision.Placement{BookingID: "booking_3", SurfaceID: "surface_3", CampaignID: "camp_3", BundleID: "bundle_a"}
+	apart := collision.Placement{BookingID: "booking_1", SurfaceID: "surface_1", CampaignID: "camp_1", Separation: 0.4}
+	near := collision.Placement{BookingID: "booking_2", SurfaceID: "surface_2", CampaignID: "camp_2", Separation: 0.05}
+	bundled := collision.Placement{BookingID: "booking_4", SurfaceID: "surface_4", CampaignID: "camp_3", BundleID: "bundle_a", Separation: 0.01}
+	otherBundle := collision.Placement{BookingID: "booking_5", SurfaceID: "surface_5", CampaignID: "camp_5", BundleID: "bundle_a", Separation: 0.3}
+	sameSurface := collision.Placement{BookingID: "booking_6", SurfaceID: "surface_3", CampaignID: "camp_6"}
+
+	tests := []struct {
+		name           string
+		rule           collision.Rule
+		earlier        []collision.Placement
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "first booking of the shot",
+			rule:           collision.Rule{MaxConcurrent: 1},
+			expectedStatus: http.StatusCreated,
+			description:    "Should serve the earliest booking of a crowded shot",
+		},
+		{
+			name:           "too many on screen",
+			rule:           collision.Rule{MaxConcurrent: 2},
+			earlier:        []collision.Placement{apart, otherBundle},
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse a booking past the shot's concurrency limit",
+		},
+		{
+			name:           "within the limit",
+			rule:           collision.Rule{MaxConcurrent: 2},
+			earlier:        []collision.Placement{apart, sameSurface},
+			expectedStatus: http.StatusCreated,
+			description:    "Should count surfaces, not bookings of the same surface",
+		},
+		{
+			name:           "bundle deal",
+			rule:           collision.Rule{MaxConcurrent: 2, MinSeparation: 0.1},
+			earlier:        []collision.Placement{apart, bundled},
+			expectedStatus: http.StatusCreated,
+			description:    "Should exempt the campaign's bookings of the same bundle deal",
+		},
+		{
+			name:           "too close",
+			rule:           collision.Rule{MinSeparation: 0.1},
+			earlier:        []collision.Placement{apart, near},
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse a placement closer than the minimum separation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockDeliveryStore{}
+			handler := NewDeliveryHandler(store)
+			handler.SetCollisionChecker(&MockCollisionChecker{rule: tt.rule, placement: booking, earlier: tt.earlier})
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			body := `{"surface_id":"surface_3","title_id":"title_1","outcome":"served","booking_id":"booking_3"}`
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusConflict {
+				assert.Contains(t, resp.Body.String(), `"collision":true`)
+				assert.Empty(t, store.events, "Should not record refused decisions")
+			}
+		})
+	}
+}
+
+// MockPacingChecker paces one booking evenly
+type MockPacingChecker struct {
+	plan pacing.Plan
+	err  error
+}
+
+func (m *MockPacingChecker) CheckPacing(bookingID string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if bookingID != m.plan.BookingID {
+		return nil
+	}
+	return m.plan.Check(time.Now().UTC())
+}
+
+func TestDeliveryHandler_RecordDecisionPacing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	start := time.Now().UTC().Add(-time.Hour)
+	end := start.Add(4 * time.Hour)
+	even := pacing.Plan{BookingID: "booking_3", Curve: pacing.Even, MaxImpressions: 400, Start: &start, End: &end}
+
+	tests := []struct {
+		name           string
+		plan           pacing.Plan
+		delivered      int64
+		err            error
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "behind pace",
+			plan:           even,
+			delivered:      60,
+			expectedStatus: http.StatusCreated,
+			description:    "Should serve an evenly paced booking behind its pace",
+		},
+		{
+			name:           "ahead of pace",
+			plan:           even,
+			delivered:      150,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse an evenly paced booking that delivered its share so far",
+		},
+		{
+			name:           "asap",
+			plan:           pacing.Plan{BookingID: "booking_3", Curve: pacing.ASAP, MaxImpressions: 400, Start: &start, End: &end},
+			delivered:      300,
+			expectedStatus: http.StatusCreated,
+			description:    "Should serve ASAP bookings as fast as decisions allow",
+		},
+		{
+			name:           "lookup failure",
+			err:            assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 when pacing cannot be checked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.plan
+			plan.Delivered = tt.delivered
+			store := &MockDeliveryStore{}
+			handler := NewDeliveryHandler(store)
+			handler.SetPacing(&MockPacingChecker{plan: plan, err: tt.err})
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			body := `{"surface_id":"surface_3","title_id":"title_1","outcome":"served","booking_id":"booking_3"}`
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusConflict {
+				assert.Contains(t, resp.Body.String(), `"ahead_of_pace":true`)
+				assert.Empty(t, store.events, "Should not record refused decisions")
+			}
+		})
+	}
+}
+
+// MockAudioSlotSource places booking_audio in a 30 second podcast slot
+type MockAudioSlotSource struct {
+	err error
+}
+
+func (m *MockAudioSlotSource) GetBookingAudioSlot(bookingID string) (*audio.Slot, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if bookingID != "booking_audio" {
+		return nil, nil
+	}
+	return &audio.Slot{
+		StartOffset: 312,
+		Duration:    30,
+		Loudness:    &audio.Loudness{TargetLUFS: -16, ToleranceLU: 1.5, MaxTruePeakDBTP: -1},
+	}, nil
+}
+
+func TestDeliveryHandler_RecordDecisionAudioSlot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		bookingID      string
+		creative       string
+		err            error
+		expectedStatus int
+		expectSlot     bool
+		description    string
+	}{
+		{
+			name:           "fits",
+			bookingID:      "booking_audio",
+			creative:       `{"duration": 28, "integrated_lufs": -16.8, "true_peak_dbtp": -1.5}`,
+			expectedStatus: http.StatusCreated,
+			expectSlot:     true,
+			description:    "Should serve a creative that fits the slot and answer with the slot",
+		},
+		{
+			name:           "unmeasured",
+			bookingID:      "booking_audio",
+			expectedStatus: http.StatusCreated,
+			expectSlot:     true,
+			description:    "Should serve audio decisions without a creative",
+		},
+		{
+			name:           "too long",
+			bookingID:      "booking_audio",
+			creative:       `{"duration": 31}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse a creative longer than the slot",
+		},
+		{
+			name:           "too quiet",
+			bookingID:      "booking_audio",
+			creative:       `{"duration": 28, "integrated_lufs": -23}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse a creative outside the slot's loudness tolerance",
+		},
+		{
+			name:           "peaks too high",
+			bookingID:      "booking_audio",
+			creative:       `{"duration": 28, "integrated_lufs": -16, "true_peak_dbtp": 0}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse a creative whose true peak exceeds the slot's ceiling",
+		},
+		{
+			name:           "invalid creative",
+			bookingID:      "booking_audio",
+			creative:       `{"duration": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require the creative's duration",
+		},
+		{
+			name:           "visual booking",
+			bookingID:      "booking_3",
+			creative:       `{"duration": 60}`,
+			expectedStatus: http.StatusCreated,
+			description:    "Should not check creatives of visual placements",
+		},
+		{
+			name:           "lookup failure",
+			bookingID:      "booking_audio",
+			err:            assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 when the slot cannot be looked up",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockDeliveryStore{}
+			handler := NewDeliveryHandler(store)
+			handler.SetAudioSlots(&MockAudioSlotSource{err: tt.err})
+			router := gin.New()
+			router.POST("/events/decision", handler.RecordDecision)
+
+			body := `{"surface_id":"surface_201","title_id":"title_1","outcome":"served","booking_id":"` + tt.bookingID + `"`
+			if tt.creative != "" {
+				body += `,"creative":` + tt.creative
+			}
+			req := httptest.NewRequest(http.MethodPost, "/events/decision", strings.NewReader(body+"}"))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			var response struct {
+				SlotMismatch bool        `json:"slot_mismatch"`
+				Audio        *audio.Slot `json:"audio"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			switch tt.expectedStatus {
+			case http.StatusCreated:
+				assert.Equal(t, tt.expectSlot, response.Audio != nil, tt.description)
+				assert.Len(t, store.events, 1)
+			case http.StatusConflict:
+				assert.True(t, response.SlotMismatch, tt.description)
+				require.NotNil(t, response.Audio, "Refusals should describe the slot")
+				assert.Equal(t, 30.0, response.Audio.Duration)
+				assert.Empty(t, store.events, "Should not record refused decisions")
+			}
+		})
+	}
+}
+
+func TestDeliveryHandler_GetFillRates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockDeliveryStore{
+		rates: []map[string]interface{}{
+			{"title_id": "title_1", "surface_id": "surface_1", "opportunities": int64(100), "served": int64(80), "errors": int64(5)},
+			{"title_id": "title_1", "surface_id": "surface_2", "opportunities": int64(100), "served": int64(40), "errors": int64(15)},
+			{"title_id": "title_2", "surface_id": "surface_3", "opportunities": int64(0), "served": int64(0), "errors": int64(0)},
+		},
+	}
+	handler := NewDeliveryHandler(store)
+	router := gin.New()
+	router.GET("/publisher/fill-rates", handler.GetFillRates)
+
+	req := httptest.NewRequest(http.MethodGet, "/publisher/fill-rates?window=30m", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.WithinDuration(t, time.Now().Add(-30*time.Minute), store.since, time.Minute)
+
+	var response struct {
+		Surfaces []map[string]interface{} `json:"surfaces"`
+		Titles   []map[string]interface{} `json:"titles"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+
+	require.Len(t, response.Surfaces, 3)
+	assert.InDelta(t, 0.8, response.Surfaces[0]["fill_rate"], 0.0001)
+	assert.InDelta(t, 0.15, response.Surfaces[1]["error_rate"], 0.0001)
+	assert.InDelta(t, 0.0, response.Surfaces[2]["fill_rate"], 0.0001)
+
+	require.Len(t, response.Titles, 2)
+	assert.Equal(t, "title_1", response.Titles[0]["title_id"])
+	assert.InDelta(t, 0.6, response.Titles[0]["fill_rate"], 0.0001)
+	assert.InDelta(t, 0.1, response.Titles[0]["error_rate"], 0.0001)
+
+	for _, window := range []string{"bogus", "-1h", "720h"} {
+		req := httptest.NewRequest(http.MethodGet, "/publisher/fill-rates?window="+window, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "Should reject window %s", window)
+	}
+}
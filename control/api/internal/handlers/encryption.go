@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/crypto"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// EncryptionKeyring manages the data keys sealing columns encrypted at rest
+type EncryptionKeyring interface {
+	MasterKeyID() string
+	ActiveKeyID() string
+	DataKeys() []crypto.DataKey
+	Reload(ctx context.Context) error
+	Rotate(ctx context.Context) (*crypto.DataKey, error)
+}
+
+// EncryptionHandler exposes data key status and rotation
+type EncryptionHandler struct {
+	keyring EncryptionKeyring
+	jobs    JobEnqueuer
+}
+
+// NewEncryptionHandler creates an encryption handler. keyring is nil when
+// encryption at rest is not configured.
+func NewEncryptionHandler(keyring EncryptionKeyring, jobs JobEnqueuer) *EncryptionHandler {
+	return &EncryptionHandler{keyring: keyring, jobs: jobs}
+}
+
+// rotateRequest is the body of POST /encryption/rotate
+type rotateRequest struct {
+	// KeepDataKey re-encrypts with the current data key instead of a new
+	// one, e.g. to encrypt values written before encryption was enabled or
+	// to re-wrap data keys after the KMS master key changed
+	KeepDataKey bool `json:"keep_data_key"`
+}
+
+// available rejects requests when encryption at rest is not configured
+func (h *EncryptionHandler) available(c *gin.Context) bool {
+	if h.keyring == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Encryption at rest is not configured"})
+		return false
+	}
+	return true
+}
+
+// ListKeys handles GET /encryption/keys
+func (h *EncryptionHandler) ListKeys(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	if err := h.keyring.Reload(c.Request.Context()); err != nil {
+		logrus.WithError(err).Error("Failed to load data encryption keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	columns := make([]string, 0, len(db.EncryptedColumns))
+	for _, col := range db.EncryptedColumns {
+		columns = append(columns, col.String())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"master_key_id":     h.keyring.MasterKeyID(),
+		"active_key_id":     h.keyring.ActiveKeyID(),
+		"keys":              h.keyring.DataKeys(),
+		"encrypted_columns": columns,
+	})
+}
+
+// Rotate handles POST /encryption/rotate by creating a data key and
+// scheduling a job that re-encrypts existing values with it
+func (h *EncryptionHandler) Rotate(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	if h.jobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Background jobs are not available"})
+		return
+	}
+
+	var req rotateRequest
+	if c.Request.ContentLength != 0 {
+		if err := schema.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	keyID := h.keyring.ActiveKeyID()
+	if !req.KeepDataKey {
+		key, err := h.keyring.Rotate(c.Request.Context())
+		if err != nil {
+			logrus.WithError(err).Error("Failed to create data encryption key")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create data key"})
+			return
+		}
+		keyID = key.ID
+	}
+
+	jobID, err := h.jobs.Enqueue(c.Request.Context(), crypto.Queue, crypto.Job{KeyID: keyID}, jobqueue.EnqueueOptions{})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to schedule encryption key rotation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":         "encryption",
+		"user_id":       c.GetString("user_id"),
+		"key_id":        keyID,
+		"new_data_key":  !req.KeepDataKey,
+		"master_key_id": h.keyring.MasterKeyID(),
+		"job_id":        jobID,
+	}).Info("Scheduled encryption key rotation")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":        jobID,
+		"queue":         crypto.Queue,
+		"active_key_id": keyID,
+		"new_data_key":  !req.KeepDataKey,
+	})
+}
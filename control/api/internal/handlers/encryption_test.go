@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/crypto"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDataKeyStore struct {
+	keys []*crypto.DataKey
+}
+
+func (m *MockDataKeyStore) ListDataKeys() ([]*crypto.DataKey, error) {
+	keys := make([]*crypto.DataKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (m *MockDataKeyStore) CreateDataKey(key *crypto.DataKey) error {
+	copied := *key
+	// Keys created within one clock tick must still sort in creation order
+	if n := len(m.keys); n > 0 && !copied.CreatedAt.After(m.keys[n-1].CreatedAt) {
+		copied.CreatedAt = m.keys[n-1].CreatedAt.Add(time.Microsecond)
+	}
+	m.keys = append(m.keys, &copied)
+	return nil
+}
+
+func (m *MockDataKeyStore) RewrapDataKey(keyID, masterKeyID string, wrapped []byte) error {
+	for _, key := range m.keys {
+		if key.ID == keyID {
+			key.MasterKeyID = masterKeyID
+			key.WrappedKey = wrapped
+		}
+	}
+	return nil
+}
+
+func (m *MockDataKeyStore) RetireDataKeys(exceptKeyID string) (int, error) {
+	retired := 0
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.ID != exceptKeyID && key.RetiredAt == nil {
+			key.RetiredAt = &now
+			retired++
+		}
+	}
+	return retired, nil
+}
+
+func newTestKeyring(t *testing.T) *crypto.Keyring {
+	kms, err := crypto.NewLocalKMS("master_1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	keyring, err := crypto.NewKeyring(context.Background(), kms, &MockDataKeyStore{})
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestKeyring_SealOpen(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	sealed, err := keyring.Seal(db.ConsentStringColumn, "CPXxRfAPXxRfAAfKABENB-CgAAAAAAAAAAYgAAAAAAAA")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, crypto.Prefix+keyring.ActiveKeyID()+":"))
+	again, err := keyring.Seal(db.ConsentStringColumn, "CPXxRfAPXxRfAAfKABENB-CgAAAAAAAAAAYgAAAAAAAA")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "Randomized columns should not repeat ciphertexts")
+
+	viewer, err := keyring.Seal(db.ViewerIDColumn, "viewer_hash_1")
+	require.NoError(t, err)
+	viewerAgain, err := keyring.Seal(db.ViewerIDColumn, "viewer_hash_1")
+	require.NoError(t, err)
+	assert.Equal(t, viewer, viewerAgain, "Deterministic columns should repeat ciphertexts for equal values")
+
+	opened, err := keyring.Open(db.ViewerIDColumn, viewer)
+	require.NoError(t, err)
+	assert.Equal(t, "viewer_hash_1", opened)
+
+	plain, err := keyring.Open(db.ViewerIDColumn, "legacy_viewer_hash")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy_viewer_hash", plain, "Values written before encryption should read back unchanged")
+
+	_, err = keyring.Open(db.KeySecretHashColumn, viewer)
+	assert.ErrorIs(t, err, crypto.ErrMalformed, "A value should not open as another column")
+
+	var disabled *crypto.Keyring
+	unsealed, err := disabled.Seal(db.ViewerIDColumn, "viewer_hash_1")
+	require.NoError(t, err)
+	assert.Equal(t, "viewer_hash_1", unsealed, "A nil keyring should store plaintext")
+}
+
+func TestEncryptionHandler_ListKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		configured     bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "configured",
+			configured:     true,
+			expectedStatus: http.StatusOK,
+			description:    "Should list data keys and encrypted columns",
+		},
+		{
+			name:           "not configured",
+			expectedStatus: http.StatusServiceUnavailable,
+			description:    "Should return 503 without a KMS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keyring EncryptionKeyring
+			if tt.configured {
+				keyring = newTestKeyring(t)
+			}
+			handler := NewEncryptionHandler(keyring, &MockJobEnqueuer{})
+			router := gin.New()
+			router.GET("/encryption/keys", handler.ListKeys)
+
+			req := httptest.NewRequest(http.MethodGet, "/encryption/keys", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				MasterKeyID      string           `json:"master_key_id"`
+				ActiveKeyID      string           `json:"active_key_id"`
+				Keys             []crypto.DataKey `json:"keys"`
+				EncryptedColumns []string         `json:"encrypted_columns"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, "master_1", response.MasterKeyID)
+			require.Len(t, response.Keys, 1)
+			assert.Equal(t, response.Keys[0].ID, response.ActiveKeyID)
+			assert.NotContains(t, resp.Body.String(), "wrapped", "Key material should never be returned")
+			assert.Contains(t, response.EncryptedColumns, "exposure_events.viewer_id")
+		})
+	}
+}
+
+func TestEncryptionHandler_Rotate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		queueError     bool
+		expectedStatus int
+		expectNewKey   bool
+		description    string
+	}{
+		{
+			name:           "new data key",
+			expectedStatus: http.StatusAccepted,
+			expectNewKey:   true,
+			description:    "Should create a data key and schedule re-encryption",
+		},
+		{
+			name:           "keep data key",
+			body:           `{"keep_data_key": true}`,
+			expectedStatus: http.StatusAccepted,
+			description:    "Should schedule re-encryption with the current data key",
+		},
+		{
+			name:           "invalid body",
+			body:           `{"keep_data_key": "yes"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject a malformed body",
+		},
+		{
+			name:           "queue error",
+			queueError:     true,
+			expectedStatus: http.StatusInternalServerError,
+			expectNewKey:   true,
+			description:    "Should return 500 when the job cannot be scheduled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := newTestKeyring(t)
+			before := keyring.ActiveKeyID()
+			oldValue, err := keyring.Seal(db.ViewerIDColumn, "viewer_hash_1")
+			require.NoError(t, err)
+
+			jobs := &MockJobEnqueuer{shouldError: tt.queueError}
+			handler := NewEncryptionHandler(keyring, jobs)
+			router := gin.New()
+			router.POST("/encryption/rotate", handler.Rotate)
+
+			req := httptest.NewRequest(http.MethodPost, "/encryption/rotate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Equal(t, tt.expectNewKey, keyring.ActiveKeyID() != before, tt.description)
+
+			opened, err := keyring.Open(db.ViewerIDColumn, oldValue)
+			require.NoError(t, err, "Values sealed before a rotation should still open")
+			assert.Equal(t, "viewer_hash_1", opened)
+			if tt.expectedStatus != http.StatusAccepted {
+				return
+			}
+
+			require.Len(t, jobs.jobs, 1)
+			assert.Equal(t, crypto.Queue, jobs.queues[0])
+			assert.Equal(t, crypto.Job{KeyID: keyring.ActiveKeyID()}, jobs.jobs[0])
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportPolicyStore keeps the columns each organization's data may not be exported with
+type ExportPolicyStore interface {
+	GetExportPolicy(orgID string) (*export.Policy, error)
+	SetExportPolicy(policy *export.Policy) error
+	DeleteExportPolicy(orgID string) (bool, error)
+}
+
+// SetPolicies lets organizations manage their export policies. Exports
+// apply the policies whether or not this is set: they are read from each
+// export's source.
+func (h *ExportHandler) SetPolicies(store ExportPolicyStore) {
+	h.policies = store
+}
+
+// exportPolicyResponse describes an organization's export policy; an
+// organization without one restricts no columns
+func exportPolicyResponse(orgID string, policy *export.Policy) gin.H {
+	response := gin.H{
+		"org_id":               orgID,
+		"columns":              map[string]string{},
+		"restrictable_columns": export.RestrictableColumns(),
+		"device_columns":       export.DeviceColumns,
+	}
+	if policy != nil {
+		response["columns"] = policy.Columns
+		response["updated_by"] = policy.UpdatedBy
+		response["updated_at"] = policy.UpdatedAt
+	}
+	return response
+}
+
+// GetExportPolicy handles GET /analytics/export-policy
+func (h *ExportHandler) GetExportPolicy(c *gin.Context) {
+	if h.policies == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Export policies are not enabled"})
+		return
+	}
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.policies.GetExportPolicy(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get export policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exportPolicyResponse(orgID, policy))
+}
+
+// UpdateExportPolicy handles PUT /analytics/export-policy, replacing the
+// columns the organization's data may not be exported with, e.g.
+// {"columns": {"viewer_id": "hash", "device_type": "strip"}}
+func (h *ExportHandler) UpdateExportPolicy(c *gin.Context) {
+	if h.policies == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Export policies are not enabled"})
+		return
+	}
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Columns map[string]string `json:"columns" binding:"required"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy := &export.Policy{OrgID: orgID, Columns: req.Columns, UpdatedBy: c.GetString("user_id")}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashKey, err := export.NewHashKey()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate export hash key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	policy.HashKey = hashKey // Kept only if the organization had no policy yet
+	if err := h.policies.SetExportPolicy(policy); err != nil {
+		logrus.WithError(err).Error("Failed to set export policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "export_policy",
+		"user_id": policy.UpdatedBy,
+		"org_id":  orgID,
+		"columns": policy.Columns,
+	}).Info("Updated export policy")
+
+	c.JSON(http.StatusOK, exportPolicyResponse(orgID, policy))
+}
+
+// DeleteExportPolicy handles DELETE /analytics/export-policy, lifting the
+// organization's export restrictions
+func (h *ExportHandler) DeleteExportPolicy(c *gin.Context) {
+	if h.policies == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Export policies are not enabled"})
+		return
+	}
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.policies.DeleteExportPolicy(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete export policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization has no export policy"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "export_policy",
+		"user_id": c.GetString("user_id"),
+		"org_id":  orgID,
+	}).Info("Deleted export policy")
+
+	c.JSON(http.StatusOK, exportPolicyResponse(orgID, nil))
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// exportStatusTrailer reports whether a streamed export completed, since
+// its status code is sent before the first event is read
+const exportStatusTrailer = "X-Export-Status"
+
+// ExportStore streams exposure events and persists async exports
+type ExportStore interface {
+	export.Source
+	EstimateExposureRows(q reporting.Query) (int64, error)
+	CreateEventExport(e *export.Export) (string, error)
+	GetEventExport(exportID string) (*export.Export, error)
+	FailEventExport(exportID, message string) error
+}
+
+// ExportHandler exports raw exposure events for analysts
+type ExportHandler struct {
+	store       ExportStore
+	maxSyncRows int64
+	jobs        JobEnqueuer
+	files       storage.Store
+	urlTTL      time.Duration
+	policies    ExportPolicyStore
+}
+
+// NewExportHandler creates an export handler streaming exports of up to
+// maxSyncRows estimated events
+func NewExportHandler(store ExportStore, maxSyncRows int64) *ExportHandler {
+	return &ExportHandler{store: store, maxSyncRows: maxSyncRows}
+}
+
+// EnableAsync writes async exports to files in the background, handing
+// out download URLs that expire after urlTTL
+func (h *ExportHandler) EnableAsync(jobs JobEnqueuer, files storage.Store, urlTTL time.Duration) {
+	h.jobs = jobs
+	h.files = files
+	h.urlTTL = urlTTL
+}
+
+// parseExportQuery reads an export's format and range from the query
+// string. The range defaults to the last 7 days.
+func parseExportQuery(c *gin.Context) (export.Query, string, error) {
+	now := time.Now().UTC()
+	q := export.Query{
+		BookingID: c.Param("booking_id"),
+		From:      now.Add(-7 * 24 * time.Hour),
+		To:        now,
+	}
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatParquet {
+		return q, format, errors.New("format must be csv or parquet")
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return q, format, errors.New("invalid from parameter, expected RFC3339")
+		}
+		q.From = parsed
+	}
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return q, format, errors.New("invalid to parameter, expected RFC3339")
+		}
+		q.To = parsed
+	}
+	return q, format, q.Validate()
+}
+
+// ExportEvents handles GET /analytics/export/:booking_id, streaming the
+// booking's events as CSV or Parquet, or with async=true writing them to
+// object storage in the background
+func (h *ExportHandler) ExportEvents(c *gin.Context) {
+	q, format, err := parseExportQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.Tenant = authz.Scope(c)
+
+	if c.Query("async") == "true" {
+		h.createExport(c, q, format)
+		return
+	}
+
+	estimatedRows, err := h.store.EstimateExposureRows(reporting.Query{
+		BookingID: q.BookingID,
+		From:      q.From,
+		To:        q.To,
+		Tenant:    q.Tenant,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to estimate export size")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if estimatedRows > h.maxSyncRows {
+		hint := "Narrow the time range"
+		if h.jobs != nil {
+			hint += ", or export it in the background with async=true"
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":          "Export is too large to stream (estimated " + strconv.FormatInt(estimatedRows, 10) + " events, limit " + strconv.FormatInt(h.maxSyncRows, 10) + ")",
+			"hint":           hint,
+			"estimated_rows": estimatedRows,
+		})
+		return
+	}
+
+	h.stream(c, q, format)
+}
+
+// stream writes an export as the response body, chunked and, for CSV
+// requested by a client accepting it, gzip-encoded. Parquet pages are
+// already compressed.
+func (h *ExportHandler) stream(c *gin.Context, q export.Query, format string) {
+	header := c.Writer.Header()
+	header.Set("Content-Type", export.ContentType(format))
+	header.Set("Content-Disposition", `attachment; filename="`+export.Filename(q.BookingID, format)+`"`)
+	header.Set("Cache-Control", "no-store")
+	header.Set("Trailer", exportStatusTrailer)
+
+	var body io.Writer = &flushWriter{w: c.Writer}
+	var zw *gzip.Writer
+	if format == export.FormatCSV && strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		zw = gzip.NewWriter(body)
+		body = zw
+	}
+	c.Status(http.StatusOK)
+
+	rows, err := export.Write(body, format, &cancellable{Source: h.store, ctx: c.Request.Context()}, q)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"booking_id": q.BookingID,
+		"format":     format,
+		"rows":       rows,
+	})
+	metrics.EventExportRows.WithLabelValues(format, "stream").Add(float64(rows))
+	if err != nil {
+		logger.WithError(err).Warn("Streamed event export did not complete")
+		header.Set(exportStatusTrailer, export.StatusFailed)
+		return
+	}
+	header.Set(exportStatusTrailer, export.StatusCompleted)
+	logger.Info("Streamed event export")
+}
+
+// createExport records an async export and queues the job writing it
+func (h *ExportHandler) createExport(c *gin.Context, q export.Query, format string) {
+	if h.jobs == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Async exports are not enabled"})
+		return
+	}
+
+	e := &export.Export{
+		Format:      format,
+		Query:       q,
+		RequestedBy: c.GetString("user_id"),
+	}
+	exportID, err := h.store.CreateEventExport(e)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create event export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export"})
+		return
+	}
+	if _, err := h.jobs.Enqueue(c.Request.Context(), export.Queue, export.Job{ExportID: exportID}, jobqueue.EnqueueOptions{}); err != nil {
+		logrus.WithError(err).WithField("export_id", exportID).Error("Failed to queue event export")
+		if err := h.store.FailEventExport(exportID, "failed to queue export"); err != nil {
+			logrus.WithError(err).WithField("export_id", exportID).Error("Failed to mark event export failed")
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"export_id":  exportID,
+		"booking_id": q.BookingID,
+		"format":     format,
+	}).Info("Created async event export")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"export_id":  exportID,
+		"status":     export.StatusPending,
+		"status_url": "/api/v1/analytics/exports/" + exportID,
+	})
+}
+
+// loadExport fetches an async export and checks it belongs to the caller
+func (h *ExportHandler) loadExport(c *gin.Context) (*export.Export, bool) {
+	if h.jobs == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Async exports are not enabled"})
+		return nil, false
+	}
+
+	e, err := h.store.GetEventExport(c.Param("export_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get event export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if e == nil || (e.RequestedBy != "" && e.RequestedBy != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return nil, false
+	}
+	return e, true
+}
+
+// GetExport handles GET /analytics/exports/:export_id. Completed exports
+// carry a signed object storage URL, or the API path serving the file for
+// storage drivers that cannot sign URLs.
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	e, ok := h.loadExport(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"export_id":  e.ID,
+		"status":     e.Status,
+		"format":     e.Format,
+		"booking_id": e.Query.BookingID,
+		"from":       e.Query.From.UTC().Format(time.RFC3339),
+		"to":         e.Query.To.UTC().Format(time.RFC3339),
+		"row_count":  e.RowCount,
+		"size_bytes": e.Size,
+		"created_at": e.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if e.CompletedAt != nil {
+		response["completed_at"] = e.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	if e.Error != "" {
+		response["error"] = e.Error
+	}
+	if e.Status == export.StatusCompleted {
+		url, err := storage.SignedURL(h.files, e.ObjectKey, h.urlTTL)
+		switch {
+		case err == nil:
+			response["download_url"] = url
+			response["expires_at"] = time.Now().Add(h.urlTTL).UTC().Format(time.RFC3339)
+		case errors.Is(err, storage.ErrNotSignable):
+			response["download_url"] = "/api/v1/analytics/exports/" + e.ID + "/download"
+		default:
+			logrus.WithError(err).WithField("export_id", e.ID).Error("Failed to sign export URL")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadExport handles GET /analytics/exports/:export_id/download
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	e, ok := h.loadExport(c)
+	if !ok {
+		return
+	}
+	if e.Status != export.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Export is not ready",
+			"status": e.Status,
+		})
+		return
+	}
+
+	file, err := h.files.Get(c.Request.Context(), e.ObjectKey)
+	if err != nil {
+		logrus.WithError(err).WithField("export_id", e.ID).Error("Failed to read export file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, e.Size, export.ContentType(e.Format), file, map[string]string{
+		"Content-Disposition": `attachment; filename="` + export.Filename(e.Query.BookingID, e.Format) + `"`,
+	})
+}
+
+// flushWriter flushes each write to the client, so exports are sent as
+// they are encoded rather than buffered by the server
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.w.Flush()
+	return n, err
+}
+
+// cancellable stops streaming events once the client has gone
+type cancellable struct {
+	export.Source
+	ctx context.Context
+}
+
+func (s *cancellable) StreamExposureEvents(q export.Query, fn func(row *export.Row) error) error {
+	return s.Source.StreamExposureEvents(q, func(row *export.Row) error {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		return fn(row)
+	})
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockExportStore struct {
+	rows          []export.Row
+	estimatedRows int64
+	exports       map[string]*export.Export
+	lastQuery     export.Query
+	policy        *export.Policy // Of the organization owning every booking
+}
+
+func (m *MockExportStore) ExportPolicy(q export.Query) (*export.Policy, error) {
+	return m.policy, nil
+}
+
+func (m *MockExportStore) GetExportPolicy(orgID string) (*export.Policy, error) {
+	if m.policy == nil || m.policy.OrgID != orgID {
+		return nil, nil
+	}
+	return m.policy, nil
+}
+
+func (m *MockExportStore) SetExportPolicy(policy *export.Policy) error {
+	if m.policy != nil && m.policy.OrgID == policy.OrgID {
+		policy.HashKey = m.policy.HashKey
+	}
+	policy.UpdatedAt = time.Now()
+	m.policy = policy
+	return nil
+}
+
+func (m *MockExportStore) DeleteExportPolicy(orgID string) (bool, error) {
+	if m.policy == nil || m.policy.OrgID != orgID {
+		return false, nil
+	}
+	m.policy = nil
+	return true, nil
+}
+
+func (m *MockExportStore) StreamExposureEvents(q export.Query, fn func(row *export.Row) error) error {
+	m.lastQuery = q
+	for i := range m.rows {
+		if err := fn(&m.rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockExportStore) EstimateExposureRows(q reporting.Query) (int64, error) {
+	return m.estimatedRows, nil
+}
+
+func (m *MockExportStore) CreateEventExport(e *export.Export) (string, error) {
+	if m.exports == nil {
+		m.exports = make(map[string]*export.Export)
+	}
+	e.ID = "export_1"
+	e.Status = export.StatusPending
+	m.exports[e.ID] = e
+	return e.ID, nil
+}
+
+func (m *MockExportStore) GetEventExport(exportID string) (*export.Export, error) {
+	return m.exports[exportID], nil
+}
+
+func (m *MockExportStore) FailEventExport(exportID, message string) error {
+	m.exports[exportID].Status = export.StatusFailed
+	m.exports[exportID].Error = message
+	return nil
+}
+
+func exportRows() []export.Row {
+	at := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	attention := 0.75
+	device := "ctv"
+	return []export.Row{
+		{EventID: "evt_1", BookingID: "booking_123", ViewerID: "viewer_1", EventTimestamp: at, ExposureDuration: 2.5, AttentionScore: &attention, DeviceType: &device, Counted: true},
+		{EventID: "evt_2", BookingID: "booking_123", ViewerID: "viewer_2", EventTimestamp: at.Add(time.Minute), ExposureDuration: 1},
+	}
+}
+
+func TestExportHandler_ExportEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		acceptEncoding string
+		estimatedRows  int64
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "csv",
+			query:          "?from=2024-01-01T00:00:00Z&to=2024-01-03T00:00:00Z",
+			expectedStatus: http.StatusOK,
+			description:    "Should stream events as CSV by default",
+		},
+		{
+			name:           "gzip csv",
+			query:          "?format=csv",
+			acceptEncoding: "gzip, deflate",
+			expectedStatus: http.StatusOK,
+			description:    "Should gzip CSV for clients accepting it",
+		},
+		{
+			name:           "parquet",
+			query:          "?format=parquet",
+			acceptEncoding: "gzip",
+			expectedStatus: http.StatusOK,
+			description:    "Should stream a Parquet file without encoding it again",
+		},
+		{
+			name:           "unsupported format",
+			query:          "?format=xlsx",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject formats other than csv and parquet",
+		},
+		{
+			name:           "inverted range",
+			query:          "?from=2024-01-03T00:00:00Z&to=2024-01-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject a range ending before it starts",
+		},
+		{
+			name:           "too large to stream",
+			query:          "?format=csv",
+			estimatedRows:  5000,
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should point large exports at async mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockExportStore{rows: exportRows(), estimatedRows: tt.estimatedRows}
+			handler := NewExportHandler(store, 1000)
+			handler.EnableAsync(&MockJobEnqueuer{}, nil, time.Hour)
+
+			router := gin.New()
+			router.GET("/analytics/export/:booking_id", handler.ExportEvents)
+
+			req := httptest.NewRequest(http.MethodGet, "/analytics/export/booking_123"+tt.query, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Contains(t, response["hint"], "async=true")
+				return
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "booking_123", store.lastQuery.BookingID)
+			assert.Contains(t, resp.Header().Get("Content-Disposition"), "exposure_events_booking_123.")
+			assert.Equal(t, export.StatusCompleted, resp.Result().Trailer.Get("X-Export-Status"))
+
+			body := resp.Body.Bytes()
+			if bytes.HasPrefix(body, []byte("PAR1")) {
+				assert.Empty(t, resp.Header().Get("Content-Encoding"))
+				assert.True(t, bytes.HasSuffix(body, []byte("PAR1")), "Parquet files end with their magic")
+				return
+			}
+			var reader io.Reader = bytes.NewReader(body)
+			if tt.acceptEncoding != "" {
+				require.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+				zr, err := gzip.NewReader(reader)
+				require.NoError(t, err)
+				reader = zr
+			}
+			records, err := csv.NewReader(reader).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 3)
+			assert.Equal(t, "event_id", records[0][0])
+			assert.Equal(t, []string{"evt_1", "booking_123", "viewer_1", "", "", "2024-01-02T10:00:00Z", "", "", "2.5", "", "0.75", "", "", "ctv", "true"}, records[1])
+		})
+	}
+}
+
+func TestExportHandler_ExportPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockExportStore{rows: exportRows()}
+	handler := NewExportHandler(store, 1000)
+	handler.SetPolicies(store)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_1")
+		if c.GetHeader("X-Test-Org") != "" {
+			c.Set("org_id", c.GetHeader("X-Test-Org"))
+		}
+	})
+	router.GET("/analytics/export/:booking_id", handler.ExportEvents)
+	router.GET("/analytics/export-policy", handler.GetExportPolicy)
+	router.PUT("/analytics/export-policy", handler.UpdateExportPolicy)
+	router.DELETE("/analytics/export-policy", handler.DeleteExportPolicy)
+
+	do := func(method, path, body, org string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if org != "" {
+			req.Header.Set("X-Test-Org", org)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/analytics/export-policy", "", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code, "Should require an organization")
+
+	for name, body := range map[string]string{
+		"unknown column":    `{"columns": {"password": "strip"}}`,
+		"unknown action":    `{"columns": {"viewer_id": "mask"}}`,
+		"hashed non-string": `{"columns": {"exposure_duration": "hash"}}`,
+		"empty":             `{"columns": {}}`,
+	} {
+		resp = do(http.MethodPut, "/analytics/export-policy", body, "org_a")
+		assert.Equal(t, http.StatusBadRequest, resp.Code, name)
+	}
+
+	resp = do(http.MethodPut, "/analytics/export-policy", `{"columns": {"viewer_id": "hash", "device_type": "strip"}}`, "org_a")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotNil(t, store.policy)
+	assert.Len(t, store.policy.HashKey, 64, "Should generate a hash key")
+	assert.NotContains(t, resp.Body.String(), store.policy.HashKey, "Should never return the hash key")
+
+	resp = do(http.MethodGet, "/analytics/export-policy", "", "org_a")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, map[string]interface{}{"viewer_id": "hash", "device_type": "strip"}, response["columns"])
+	assert.Equal(t, "user_1", response["updated_by"])
+	assert.Contains(t, response["restrictable_columns"], "session_id")
+
+	resp = do(http.MethodGet, "/analytics/export/booking_123", "", "org_b")
+	require.Equal(t, http.StatusOK, resp.Code)
+	records, err := csv.NewReader(bytes.NewReader(resp.Body.Bytes())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.NotContains(t, records[0], "device_type", "Should strip restricted columns from exports of the owner's data")
+	assert.Equal(t, "viewer_id", records[0][2])
+	assert.Len(t, records[1][2], 64, "Should hash restricted columns")
+	assert.NotEqual(t, "viewer_1", records[1][2])
+
+	resp = do(http.MethodDelete, "/analytics/export-policy", "", "org_a")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = do(http.MethodDelete, "/analytics/export-policy", "", "org_a")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestExportHandler_AsyncExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	files, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	store := &MockExportStore{rows: exportRows()}
+	jobs := &MockJobEnqueuer{}
+	handler := NewExportHandler(store, 1000)
+	handler.EnableAsync(jobs, files, time.Hour)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_1")
+		c.Next()
+	})
+	router.GET("/analytics/export/:booking_id", handler.ExportEvents)
+	router.GET("/analytics/exports/:export_id", handler.GetExport)
+	router.GET("/analytics/exports/:export_id/download", handler.DownloadExport)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/export/booking_123?format=parquet&async=true", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	require.Equal(t, []string{export.Queue}, jobs.queues)
+	assert.Equal(t, export.Job{ExportID: "export_1"}, jobs.jobs[0])
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/exports/export_1/download", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusConflict, resp.Code, "Should not serve an export before it completes")
+
+	// Run the job as a worker would
+	payload, err := json.Marshal(jobs.jobs[0])
+	require.NoError(t, err)
+	run := export.NewJobHandler(&MockExportJobStore{MockExportStore: store}, files)
+	require.NoError(t, run(context.Background(), &jobqueue.Job{ID: "job_1", Queue: export.Queue, Payload: payload}))
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/exports/export_1", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, export.StatusCompleted, status["status"])
+	assert.Equal(t, float64(2), status["row_count"])
+	assert.Equal(t, "/api/v1/analytics/exports/export_1/download", status["download_url"], "Local storage cannot sign URLs")
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/exports/export_1/download", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/vnd.apache.parquet", resp.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(resp.Body.Bytes(), []byte("PAR1")))
+	assert.EqualValues(t, status["size_bytes"], resp.Body.Len())
+
+	router = gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_2")
+		c.Next()
+	})
+	router.GET("/analytics/exports/:export_id", handler.GetExport)
+	req = httptest.NewRequest(http.MethodGet, "/analytics/exports/export_1", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code, "Should hide other users' exports")
+}
+
+// MockExportJobStore records the progress of async exports
+type MockExportJobStore struct {
+	*MockExportStore
+}
+
+func (m *MockExportJobStore) StartEventExport(exportID string) error {
+	m.exports[exportID].Status = export.StatusRunning
+	return nil
+}
+
+func (m *MockExportJobStore) CompleteEventExport(exportID, objectKey string, rows, size int64) error {
+	e := m.exports[exportID]
+	e.Status = export.StatusCompleted
+	e.ObjectKey = objectKey
+	e.RowCount = rows
+	e.Size = size
+	return nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// ExternalIDStore reads, replaces and resolves partner IDs on resources
+type ExternalIDStore interface {
+	GetExternalIDs(resourceType, resourceID string) (map[string]string, error)
+	SetExternalIDs(resourceType, resourceID string, ids map[string]string) error
+	FindByExternalID(resourceType, source, externalID string) (string, error)
+}
+
+// externalIDResourceTypes are the resources partners reference by their own IDs
+var externalIDResourceTypes = map[string]bool{
+	labels.ResourceCampaign: true,
+	labels.ResourceBooking:  true,
+	labels.ResourceCreative: true,
+}
+
+// maxExternalIDLength bounds the length of a partner ID
+const maxExternalIDLength = 255
+
+// ExternalIDHandler manages partner IDs on campaigns, bookings and creatives
+type ExternalIDHandler struct {
+	db    ExternalIDStore
+	authz *authz.Authorizer
+}
+
+// NewExternalIDHandler creates a new external ID handler
+func NewExternalIDHandler(store ExternalIDStore) *ExternalIDHandler {
+	return &ExternalIDHandler{db: store}
+}
+
+// SetAuthorizer requires view access to resources resolved by external ID
+func (h *ExternalIDHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// validateExternalIDs checks that sources are well-formed namespaces and IDs are non-empty
+func validateExternalIDs(ids map[string]string) error {
+	for source, externalID := range ids {
+		if !labels.ValidKey(source) {
+			return fmt.Errorf("invalid external ID source %q", source)
+		}
+		if externalID == "" || len(externalID) > maxExternalIDLength {
+			return fmt.Errorf("external ID for source %q must be 1-%d characters", source, maxExternalIDLength)
+		}
+	}
+	return nil
+}
+
+// externalIDResourceType reads and validates the resource type from the path
+func externalIDResourceType(c *gin.Context) (string, bool) {
+	resourceType := c.Param("resource_type")
+	if !externalIDResourceTypes[resourceType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource_type must be one of campaign, booking or creative"})
+		return "", false
+	}
+	return resourceType, true
+}
+
+// GetExternalIDs handles GET /external-ids/:resource_type/:resource_id
+func (h *ExternalIDHandler) GetExternalIDs(c *gin.Context) {
+	resourceType, ok := externalIDResourceType(c)
+	if !ok {
+		return
+	}
+	resourceID := c.Param("resource_id")
+
+	ids, err := h.db.GetExternalIDs(resourceType, resourceID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get external IDs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"external_ids":  ids,
+	})
+}
+
+// SetExternalIDs handles PUT /external-ids/:resource_type/:resource_id, replacing all partner IDs
+func (h *ExternalIDHandler) SetExternalIDs(c *gin.Context) {
+	resourceType, ok := externalIDResourceType(c)
+	if !ok {
+		return
+	}
+	resourceID := c.Param("resource_id")
+
+	var req struct {
+		ExternalIDs map[string]string `json:"external_ids"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExternalIDs == nil {
+		req.ExternalIDs = map[string]string{}
+	}
+	if err := validateExternalIDs(req.ExternalIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.SetExternalIDs(resourceType, resourceID, req.ExternalIDs); err != nil {
+		if errors.Is(err, db.ErrExternalIDTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Failed to set external IDs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set external IDs"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"sources":       len(req.ExternalIDs),
+	}).Info("Set external IDs")
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"external_ids":  req.ExternalIDs,
+	})
+}
+
+// LookupExternalID handles GET /external-ids/:resource_type?source=&external_id=
+func (h *ExternalIDHandler) LookupExternalID(c *gin.Context) {
+	resourceType, ok := externalIDResourceType(c)
+	if !ok {
+		return
+	}
+
+	source := c.Query("source")
+	externalID := c.Query("external_id")
+	if source == "" || externalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source and external_id are required"})
+		return
+	}
+
+	resourceID, err := h.db.FindByExternalID(resourceType, source, externalID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to look up external ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if resourceID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No resource with that external ID"})
+		return
+	}
+	if !h.authz.Authorize(c, resourceType, resourceID, authz.PermissionView) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"source":        source,
+		"external_id":   externalID,
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockExternalIDStore enforces per-source uniqueness like the database does
+type MockExternalIDStore struct {
+	ids         map[string]map[string]string // resource type/id -> source -> external ID
+	shouldError bool
+}
+
+func (m *MockExternalIDStore) GetExternalIDs(resourceType, resourceID string) (map[string]string, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	if ids, ok := m.ids[resourceType+"/"+resourceID]; ok {
+		return ids, nil
+	}
+	return map[string]string{}, nil
+}
+
+func (m *MockExternalIDStore) SetExternalIDs(resourceType, resourceID string, ids map[string]string) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	for source, externalID := range ids {
+		owner, err := m.FindByExternalID(resourceType, source, externalID)
+		if err != nil {
+			return err
+		}
+		if owner != "" && owner != resourceID {
+			return fmt.Errorf("%w: %s/%s", db.ErrExternalIDTaken, source, externalID)
+		}
+	}
+	m.ids[resourceType+"/"+resourceID] = ids
+	return nil
+}
+
+func (m *MockExternalIDStore) FindByExternalID(resourceType, source, externalID string) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	prefix := resourceType + "/"
+	for key, ids := range m.ids {
+		if strings.HasPrefix(key, prefix) && ids[source] == externalID {
+			return strings.TrimPrefix(key, prefix), nil
+		}
+	}
+	return "", nil
+}
+
+func TestExternalIDHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockExternalIDStore{
+		ids: map[string]map[string]string{
+			"campaign/campaign_1": {"gam": "order-42"},
+		},
+	}
+	handler := NewExternalIDHandler(store)
+	router := gin.New()
+	router.GET("/external-ids/:resource_type", handler.LookupExternalID)
+	router.GET("/external-ids/:resource_type/:resource_id", handler.GetExternalIDs)
+	router.PUT("/external-ids/:resource_type/:resource_id", handler.SetExternalIDs)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{"set on new campaign", http.MethodPut, "/external-ids/campaign/campaign_2", `{"external_ids":{"gam":"order-43","dv360":"io-7"}}`, http.StatusOK, "Should attach partner IDs"},
+		{"duplicate within source", http.MethodPut, "/external-ids/campaign/campaign_3", `{"external_ids":{"gam":"order-42"}}`, http.StatusConflict, "Should enforce uniqueness per source"},
+		{"same id different source", http.MethodPut, "/external-ids/campaign/campaign_3", `{"external_ids":{"dv360":"order-42"}}`, http.StatusOK, "Should allow the same ID in another source"},
+		{"same id different type", http.MethodPut, "/external-ids/creative/creative_1", `{"external_ids":{"gam":"order-42"}}`, http.StatusOK, "Should namespace IDs per resource type"},
+		{"invalid source", http.MethodPut, "/external-ids/booking/booking_1", `{"external_ids":{"Bad Source":"1"}}`, http.StatusBadRequest, "Should reject malformed sources"},
+		{"empty id", http.MethodPut, "/external-ids/booking/booking_1", `{"external_ids":{"gam":""}}`, http.StatusBadRequest, "Should reject empty IDs"},
+		{"unsupported type", http.MethodGet, "/external-ids/surface/surface_1", "", http.StatusBadRequest, "Should reject resources without external IDs"},
+		{"lookup found", http.MethodGet, "/external-ids/campaign?source=gam&external_id=order-42", "", http.StatusOK, "Should resolve partner IDs"},
+		{"lookup missing", http.MethodGet, "/external-ids/campaign?source=gam&external_id=order-99", "", http.StatusNotFound, "Should return 404 for unknown IDs"},
+		{"lookup without source", http.MethodGet, "/external-ids/campaign?external_id=order-42", "", http.StatusBadRequest, "Should require a source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/external-ids/campaign?source=gam&external_id=order-42", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "campaign_1", response["resource_id"])
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/fingerprint"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// maxCutIDLength bounds cut IDs, matching the title_cuts column
+const maxCutIDLength = 100
+
+// FingerprintStore stores cut fingerprints and remaps surfaces between cuts
+type FingerprintStore interface {
+	SetCutFingerprints(titleID, cutID string, shots []fingerprint.Shot) (*fingerprint.Cut, error)
+	ListCuts(titleID string) ([]fingerprint.Cut, error)
+	GetCut(titleID, cutID string) (*fingerprint.Cut, error)
+	RemapTitleCut(titleID, cutID string, maxDistance int, dryRun bool) (*fingerprint.Report, error)
+}
+
+// FingerprintHandler aligns surfaces to re-delivered edits of a title
+type FingerprintHandler struct {
+	db   FingerprintStore
+	jobs JobEnqueuer
+}
+
+// NewFingerprintHandler creates a fingerprint handler
+func NewFingerprintHandler(store FingerprintStore) *FingerprintHandler {
+	return &FingerprintHandler{db: store}
+}
+
+// SetJobQueue enables remap jobs; without it only dry runs are available
+func (h *FingerprintHandler) SetJobQueue(jobs JobEnqueuer) {
+	h.jobs = jobs
+}
+
+// fingerprintRequest is the body of PUT /titles/:title_id/cuts/:cut_id/fingerprints
+type fingerprintRequest struct {
+	Shots []fingerprint.Shot `json:"shots"`
+}
+
+// remapRequest is the body of POST /titles/:title_id/cuts/:cut_id/remap
+type remapRequest struct {
+	DryRun      bool `json:"dry_run"`
+	MaxDistance int  `json:"max_distance"`
+}
+
+// SetFingerprints handles PUT /titles/:title_id/cuts/:cut_id/fingerprints
+func (h *FingerprintHandler) SetFingerprints(c *gin.Context) {
+	titleID, cutID := c.Param("title_id"), c.Param("cut_id")
+	if len(cutID) > maxCutIDLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cut_id is too long"})
+		return
+	}
+
+	var req fingerprintRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := fingerprint.ValidateShots(req.Shots); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cut, err := h.db.SetCutFingerprints(titleID, cutID, req.Shots)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save cut fingerprints")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"title_id": titleID,
+		"cut_id":   cutID,
+		"shots":    len(req.Shots),
+		"baseline": cut.AppliedAt != nil,
+	}).Info("Saved cut fingerprints")
+
+	c.JSON(http.StatusOK, cut)
+}
+
+// ListCuts handles GET /titles/:title_id/cuts
+func (h *FingerprintHandler) ListCuts(c *gin.Context) {
+	cuts, err := h.db.ListCuts(c.Param("title_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list cuts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cuts":        cuts,
+		"total_count": len(cuts),
+	})
+}
+
+// GetCut handles GET /titles/:title_id/cuts/:cut_id
+func (h *FingerprintHandler) GetCut(c *gin.Context) {
+	cut, err := h.db.GetCut(c.Param("title_id"), c.Param("cut_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get cut")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if cut == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cut not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cut)
+}
+
+// Remap handles POST /titles/:title_id/cuts/:cut_id/remap. A dry run returns
+// the remap report without changing anything; otherwise a remap job is
+// scheduled and its report is kept on the cut.
+func (h *FingerprintHandler) Remap(c *gin.Context) {
+	titleID, cutID := c.Param("title_id"), c.Param("cut_id")
+
+	var req remapRequest
+	if c.Request.ContentLength != 0 {
+		if err := schema.BindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.MaxDistance < 0 || req.MaxDistance > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_distance must be between 0 and 64"})
+		return
+	}
+	if req.MaxDistance == 0 {
+		req.MaxDistance = fingerprint.DefaultMaxDistance
+	}
+
+	if req.DryRun {
+		report, err := h.db.RemapTitleCut(titleID, cutID, req.MaxDistance, true)
+		if !h.remapError(c, err) {
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	if h.jobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Background jobs are not available"})
+		return
+	}
+	cut, err := h.db.GetCut(titleID, cutID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get cut")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if cut == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cut not found"})
+		return
+	}
+
+	job := fingerprint.Job{TitleID: titleID, CutID: cutID, MaxDistance: req.MaxDistance}
+	jobID, err := h.jobs.Enqueue(c.Request.Context(), fingerprint.Queue, job, jobqueue.EnqueueOptions{})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to schedule surface remap")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "surface_remap",
+		"title_id": titleID,
+		"cut_id":   cutID,
+		"job_id":   jobID,
+		"user_id":  c.GetString("user_id"),
+		"org_id":   c.GetString("org_id"),
+	}).Info("Scheduled surface remap")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":   jobID,
+		"queue":    fingerprint.Queue,
+		"title_id": titleID,
+		"cut_id":   cutID,
+	})
+}
+
+// remapError writes the response for a failed remap. It reports whether err was nil.
+func (h *FingerprintHandler) remapError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, db.ErrTitleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+	case errors.Is(err, db.ErrCutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cut not found"})
+	case errors.Is(err, fingerprint.ErrNoBaseline), errors.Is(err, fingerprint.ErrCutApplied):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error("Failed to remap surfaces")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+	return false
+}
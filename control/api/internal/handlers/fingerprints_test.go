@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/fingerprint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockFingerprintStore keeps one title's cuts and remaps with the real alignment
+type MockFingerprintStore struct {
+	cuts        map[string][]fingerprint.Shot
+	applied     string
+	surfaces    []fingerprint.SurfaceRange
+	shouldError bool
+}
+
+func (m *MockFingerprintStore) SetCutFingerprints(titleID, cutID string, shots []fingerprint.Shot) (*fingerprint.Cut, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	if titleID != "1" {
+		return nil, db.ErrTitleNotFound
+	}
+	m.cuts[cutID] = shots
+	if m.applied == "" {
+		m.applied = cutID
+	}
+	return m.GetCut(titleID, cutID)
+}
+
+func (m *MockFingerprintStore) ListCuts(titleID string) ([]fingerprint.Cut, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	cuts := make([]fingerprint.Cut, 0)
+	for cutID := range m.cuts {
+		cut, _ := m.GetCut(titleID, cutID)
+		cuts = append(cuts, *cut)
+	}
+	return cuts, nil
+}
+
+func (m *MockFingerprintStore) GetCut(titleID, cutID string) (*fingerprint.Cut, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	shots, ok := m.cuts[cutID]
+	if !ok {
+		return nil, nil
+	}
+	cut := &fingerprint.Cut{TitleID: titleID, CutID: cutID, Shots: len(shots)}
+	if cutID == m.applied {
+		at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+		cut.AppliedAt = &at
+	}
+	return cut, nil
+}
+
+func (m *MockFingerprintStore) RemapTitleCut(titleID, cutID string, maxDistance int, dryRun bool) (*fingerprint.Report, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	to, ok := m.cuts[cutID]
+	if !ok {
+		return nil, db.ErrCutNotFound
+	}
+	if m.applied == "" {
+		return nil, fingerprint.ErrNoBaseline
+	}
+	if m.applied == cutID {
+		return nil, fingerprint.ErrCutApplied
+	}
+	alignment := fingerprint.Align(m.cuts[m.applied], to, maxDistance)
+	changes := fingerprint.Plan(alignment, m.surfaces)
+	return fingerprint.Summarize(titleID, m.applied, cutID, alignment, changes, dryRun, time.Now().UTC()), nil
+}
+
+// newMockFingerprintStore has a theatrical cut and a TV edit that drops the
+// second shot, moves the fourth ahead of the third and re-encodes everything
+func newMockFingerprintStore() *MockFingerprintStore {
+	return &MockFingerprintStore{
+		cuts: map[string][]fingerprint.Shot{
+			"theatrical": {
+				{ShotID: "shot_001", StartTime: 0, EndTime: 10, Hash: "f0f0f0f0f0f0f0f0"},
+				{ShotID: "shot_002", StartTime: 10, EndTime: 25, Hash: "0123456789abcdef"},
+				{ShotID: "shot_003", StartTime: 25, EndTime: 40, Hash: "ffff0000ffff0000"},
+				{ShotID: "shot_004", StartTime: 40, EndTime: 50, Hash: "00ff00ff00ff00ff"},
+			},
+			"tv_edit": {
+				// Re-encoding flips a couple of bits per hash
+				{ShotID: "shot_001", StartTime: 0, EndTime: 10, Hash: "f0f0f0f0f0f0f0f1"},
+				{ShotID: "shot_002", StartTime: 10, EndTime: 20, Hash: "00ff00ff00ff00fc"},
+				{ShotID: "shot_003", StartTime: 20, EndTime: 33, Hash: "ffff0000ffff0003"},
+			},
+		},
+		applied: "theatrical",
+		surfaces: []fingerprint.SurfaceRange{
+			{SurfaceID: "surface_001", ShotID: "shot_001", StartTime: 2, EndTime: 8},
+			{SurfaceID: "surface_002", ShotID: "shot_002", StartTime: 12, EndTime: 20},
+			{SurfaceID: "surface_003", ShotID: "shot_003", StartTime: 30, EndTime: 39},
+			{SurfaceID: "surface_004", ShotID: "shot_004", StartTime: 42, EndTime: 48},
+		},
+	}
+}
+
+func TestFingerprintHandler_SetFingerprints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		titleID          string
+		body             string
+		expectedStatus   int
+		expectedBaseline bool
+		description      string
+	}{
+		{
+			name:             "first cut is the baseline",
+			titleID:          "1",
+			body:             `{"shots": [{"shot_id": "shot_001", "start_time": 0, "end_time": 4.5, "phash": "8f3c00ff12ab9e01"}]}`,
+			expectedStatus:   http.StatusOK,
+			expectedBaseline: true,
+			description:      "Should apply the first cut uploaded for a title",
+		},
+		{
+			name:           "bad hash",
+			titleID:        "1",
+			body:           `{"shots": [{"shot_id": "shot_001", "start_time": 0, "end_time": 4.5, "phash": "xyz"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject hashes that are not 16 hex digits",
+		},
+		{
+			name:           "no shots",
+			titleID:        "1",
+			body:           `{"shots": []}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require shots",
+		},
+		{
+			name:           "unknown title",
+			titleID:        "99",
+			body:           `{"shots": [{"shot_id": "shot_001", "start_time": 0, "end_time": 4.5, "phash": "8f3c00ff12ab9e01"}]}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for an unknown title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockFingerprintStore{cuts: map[string][]fingerprint.Shot{}}
+			router := gin.New()
+			router.PUT("/titles/:title_id/cuts/:cut_id/fingerprints", NewFingerprintHandler(store).SetFingerprints)
+
+			req := httptest.NewRequest(http.MethodPut, "/titles/"+tt.titleID+"/cuts/theatrical/fingerprints", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusOK {
+				var cut fingerprint.Cut
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &cut))
+				assert.Equal(t, 1, cut.Shots)
+				assert.Equal(t, tt.expectedBaseline, cut.AppliedAt != nil, tt.description)
+			}
+		})
+	}
+}
+
+func TestFingerprintHandler_RemapDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockFingerprintStore()
+	router := gin.New()
+	router.POST("/titles/:title_id/cuts/:cut_id/remap", NewFingerprintHandler(store).Remap)
+
+	req := httptest.NewRequest(http.MethodPost, "/titles/1/cuts/tv_edit/remap", bytes.NewBufferString(`{"dry_run": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var report fingerprint.Report
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, "theatrical", report.FromCut)
+	assert.Equal(t, 3, report.MatchedShots)
+	assert.Equal(t, []string{"shot_002"}, report.UnmatchedShots, "The dropped shot should not match")
+	assert.Equal(t, map[string]int{
+		fingerprint.OutcomeUnchanged: 1,
+		fingerprint.OutcomeShifted:   2,
+		fingerprint.OutcomeWithdrawn: 1,
+	}, report.Counts)
+
+	changes := make(map[string]fingerprint.SurfaceChange)
+	for _, change := range report.Surfaces {
+		changes[change.SurfaceID] = change
+	}
+	assert.Equal(t, fingerprint.OutcomeUnchanged, changes["surface_001"].Outcome)
+	assert.Equal(t, fingerprint.OutcomeWithdrawn, changes["surface_002"].Outcome)
+
+	// The old fourth shot now plays second, 30 seconds earlier
+	assert.Equal(t, "shot_002", changes["surface_004"].NewShotID)
+	assert.Equal(t, 12.0, changes["surface_004"].NewStartTime)
+	assert.Equal(t, 18.0, changes["surface_004"].NewEndTime)
+
+	// The third shot was shortened, so the surface is clamped to its new end
+	assert.Equal(t, "shot_003", changes["surface_003"].NewShotID)
+	assert.Equal(t, 25.0, changes["surface_003"].NewStartTime)
+	assert.Equal(t, 33.0, changes["surface_003"].NewEndTime)
+}
+
+func TestFingerprintHandler_Remap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		cutID          string
+		body           string
+		withQueue      bool
+		noBaseline     bool
+		expectedStatus int
+		expectedJobs   int
+		description    string
+	}{
+		{
+			name:           "schedule remap",
+			cutID:          "tv_edit",
+			withQueue:      true,
+			expectedStatus: http.StatusAccepted,
+			expectedJobs:   1,
+			description:    "Should schedule a remap job",
+		},
+		{
+			name:           "unknown cut",
+			cutID:          "directors_cut",
+			withQueue:      true,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for a cut without fingerprints",
+		},
+		{
+			name:           "no job queue",
+			cutID:          "tv_edit",
+			expectedStatus: http.StatusServiceUnavailable,
+			description:    "Should return 503 without background jobs",
+		},
+		{
+			name:           "dry run of applied cut",
+			cutID:          "theatrical",
+			body:           `{"dry_run": true}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should refuse to remap to the applied cut",
+		},
+		{
+			name:           "dry run without baseline",
+			cutID:          "tv_edit",
+			body:           `{"dry_run": true}`,
+			noBaseline:     true,
+			expectedStatus: http.StatusConflict,
+			description:    "Should need an applied cut to remap from",
+		},
+		{
+			name:           "max distance out of range",
+			cutID:          "tv_edit",
+			body:           `{"dry_run": true, "max_distance": 65}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject distances beyond 64 bits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockFingerprintStore()
+			if tt.noBaseline {
+				store.applied = ""
+			}
+			jobs := &MockJobEnqueuer{}
+			handler := NewFingerprintHandler(store)
+			if tt.withQueue {
+				handler.SetJobQueue(jobs)
+			}
+			router := gin.New()
+			router.POST("/titles/:title_id/cuts/:cut_id/remap", handler.Remap)
+
+			req := httptest.NewRequest(http.MethodPost, "/titles/1/cuts/"+tt.cutID+"/remap", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			require.Len(t, jobs.jobs, tt.expectedJobs)
+			if tt.expectedJobs > 0 {
+				assert.Equal(t, fingerprint.Queue, jobs.queues[0])
+				assert.Equal(t, fingerprint.Job{TitleID: "1", CutID: "tv_edit", MaxDistance: fingerprint.DefaultMaxDistance}, jobs.jobs[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/sirupsen/logrus"
+)
+
+// FrequencyChecker decides whether a viewer may see another exposure of a campaign
+type FrequencyChecker interface {
+	Check(ctx context.Context, viewerID, campaignID, bookingID string) (*freqcap.Decision, error)
+}
+
+// FrequencyHandler answers edge nodes asking whether a viewer has reached a
+// campaign's frequency cap before they render a placement
+type FrequencyHandler struct {
+	checker FrequencyChecker
+}
+
+// NewFrequencyHandler creates a frequency cap handler
+func NewFrequencyHandler(checker FrequencyChecker) *FrequencyHandler {
+	return &FrequencyHandler{checker: checker}
+}
+
+// CheckFrequency handles GET /frequency/:viewer_id/:campaign_id, also
+// checking the cap of the booking named by booking_id when given. A capped
+// viewer is answered with 200 and "allowed": false.
+func (h *FrequencyHandler) CheckFrequency(c *gin.Context) {
+	campaignID := c.Param("campaign_id")
+	if err := campaign.ValidateID("campaign_id", campaignID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision, err := h.checker.Check(c.Request.Context(), c.Param("viewer_id"), campaignID, c.Query("booking_id"))
+	switch {
+	case errors.Is(err, freqcap.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign or booking not found"})
+		return
+	case errors.Is(err, freqcap.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to check frequency cap")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockFrequencyStore holds the caps of campaigns and their bookings
+type MockFrequencyStore struct {
+	campaigns map[string]*freqcap.Cap // Campaign caps by campaign; nil values are uncapped
+	bookings  map[string]string       // Campaign of each booking
+	caps      map[string]*freqcap.Cap // Booking caps by booking
+}
+
+func (m *MockFrequencyStore) GetFrequencyRules(campaignID, bookingID string) (*freqcap.Rules, error) {
+	if bookingID != "" {
+		bookingCampaign, ok := m.bookings[bookingID]
+		if !ok || (campaignID != "" && campaignID != bookingCampaign) {
+			return nil, nil
+		}
+		campaignID = bookingCampaign
+	}
+	campaignCap, ok := m.campaigns[campaignID]
+	if !ok {
+		return nil, nil
+	}
+	return &freqcap.Rules{CampaignID: campaignID, BookingID: bookingID, Campaign: campaignCap, Booking: m.caps[bookingID]}, nil
+}
+
+// failingFrequencyCounter cannot reach its counts
+type failingFrequencyCounter struct{}
+
+func (failingFrequencyCounter) Count(ctx context.Context, key string) (int64, time.Time, error) {
+	return 0, time.Time{}, assert.AnError
+}
+
+func (failingFrequencyCounter) Add(ctx context.Context, key string, window time.Duration) error {
+	return assert.AnError
+}
+
+func TestFrequencyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockFrequencyStore{
+		campaigns: map[string]*freqcap.Cap{
+			"camp_capped":   {MaxExposures: 3, WindowHours: 24},
+			"camp_uncapped": nil,
+		},
+		bookings: map[string]string{"booking_a": "camp_capped", "booking_b": "camp_capped", "booking_c": "camp_uncapped"},
+		caps:     map[string]*freqcap.Cap{"booking_b": {MaxExposures: 1, WindowHours: 1}},
+	}
+	enforcer := freqcap.NewEnforcer(store, freqcap.NewMemoryCounter())
+	placements := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{})}
+	placements.SetFrequencyCounter(enforcer)
+
+	router := gin.New()
+	router.POST("/events/exposure", placements.RecordExposure)
+	router.GET("/frequency/:viewer_id/:campaign_id", NewFrequencyHandler(enforcer).CheckFrequency)
+	expose := func(viewerID, bookingID string) {
+		body := `{"booking_id":"` + bookingID + `","viewer_id":"` + viewerID + `","exposure_duration":4.5}`
+		req := httptest.NewRequest(http.MethodPost, "/events/exposure", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	}
+	check := func(path string) (int, freqcap.Decision) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var decision freqcap.Decision
+		if resp.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &decision))
+		}
+		return resp.Code, decision
+	}
+
+	status, decision := check("/frequency/viewer_1/camp_capped")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, decision.Allowed, "A viewer without exposures should be allowed")
+	require.Len(t, decision.Limits, 1)
+	assert.Equal(t, int64(3), decision.Limits[0].Remaining)
+	assert.Nil(t, decision.Limits[0].ResetsAt, "No window should have started")
+
+	// The campaign's cap counts exposures of all its bookings
+	expose("viewer_1", "booking_a")
+	expose("viewer_1", "booking_b")
+	status, decision = check("/frequency/viewer_1/camp_capped")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(2), decision.Limits[0].Exposures)
+	require.NotNil(t, decision.Limits[0].ResetsAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *decision.Limits[0].ResetsAt, time.Minute)
+
+	status, decision = check("/frequency/viewer_1/camp_capped?booking_id=booking_b")
+	require.Equal(t, http.StatusOK, status)
+	assert.False(t, decision.Allowed, "The booking's own cap should apply when it is named")
+	require.Len(t, decision.Limits, 2)
+	assert.Equal(t, freqcap.ScopeBooking, decision.Limits[1].Scope)
+	assert.Equal(t, int64(0), decision.Limits[1].Remaining)
+
+	expose("viewer_1", "booking_a")
+	status, decision = check("/frequency/viewer_1/camp_capped")
+	require.Equal(t, http.StatusOK, status)
+	assert.False(t, decision.Allowed, "Should refuse a viewer at the campaign's cap")
+	assert.Equal(t, int64(0), decision.Limits[0].Remaining)
+
+	status, decision = check("/frequency/viewer_2/camp_capped")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, decision.Allowed, "Viewers should be counted apart")
+
+	expose("viewer_1", "booking_c")
+	status, decision = check("/frequency/viewer_1/camp_uncapped?booking_id=booking_c")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, decision.Allowed, "Uncapped campaigns should always be allowed")
+	assert.Empty(t, decision.Limits)
+
+	status, _ = check("/frequency/viewer_1/camp_missing")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = check("/frequency/viewer_1/camp_uncapped?booking_id=booking_a")
+	assert.Equal(t, http.StatusNotFound, status, "Should not check a booking of another campaign")
+	status, _ = check("/frequency/viewer_1/bad%20id")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	router = gin.New()
+	router.GET("/frequency/:viewer_id/:campaign_id", NewFrequencyHandler(freqcap.NewEnforcer(store, failingFrequencyCounter{})).CheckFrequency)
+	status, _ = check("/frequency/viewer_1/camp_capped")
+	assert.Equal(t, http.StatusServiceUnavailable, status, "Should not guess when counts cannot be read")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// GrantStore persists cross-organization grants
+type GrantStore interface {
+	GetResourceOwner(resourceType, resourceID string) (string, error)
+	CreateGrant(grant *authz.Grant) (string, error)
+	ListGrants(orgID string) ([]*authz.Grant, error)
+	RevokeGrant(grantID, ownerOrgID string) (bool, error)
+}
+
+// grantableResourceTypes are the resources an organization can share
+var grantableResourceTypes = map[string]bool{
+	labels.ResourceCampaign: true,
+	labels.ResourceBooking:  true,
+	labels.ResourceCreative: true,
+}
+
+// GrantHandler lets organizations share resources with each other
+type GrantHandler struct {
+	db GrantStore
+}
+
+// NewGrantHandler creates a new grant handler
+func NewGrantHandler(store GrantStore) *GrantHandler {
+	return &GrantHandler{db: store}
+}
+
+// callerOrg returns the caller's organization, writing a 403 when there is none
+func callerOrg(c *gin.Context) (string, bool) {
+	orgID := c.GetString("org_id")
+	if orgID == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token is not scoped to an organization"})
+		return "", false
+	}
+	return orgID, true
+}
+
+// CreateGrant handles POST /grants
+func (h *GrantHandler) CreateGrant(c *gin.Context) {
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ResourceType string             `json:"resource_type" binding:"required"`
+		ResourceID   string             `json:"resource_id" binding:"required"`
+		GranteeOrgID string             `json:"grantee_org_id" binding:"required"`
+		Permissions  []authz.Permission `json:"permissions" binding:"required,min=1"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !grantableResourceTypes[req.ResourceType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource_type must be one of campaign, booking or creative"})
+		return
+	}
+	for _, p := range req.Permissions {
+		if !p.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "permissions must be view or manage"})
+			return
+		}
+	}
+	if req.GranteeOrgID == orgID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot grant access to your own organization"})
+		return
+	}
+
+	// Only the owner can share a resource; grants are not transitive
+	owner, err := h.db.GetResourceOwner(req.ResourceType, req.ResourceID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get resource owner")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if owner != orgID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owning organization can share this resource"})
+		return
+	}
+
+	grant := &authz.Grant{
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		OwnerOrgID:   orgID,
+		GranteeOrgID: req.GranteeOrgID,
+		Permissions:  req.Permissions,
+		GrantedBy:    c.GetString("user_id"),
+	}
+
+	grantID, err := h.db.CreateGrant(grant)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create grant")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create grant"})
+		return
+	}
+	grant.ID = grantID
+
+	logrus.WithFields(logrus.Fields{
+		"audit":          "grant",
+		"grant_id":       grantID,
+		"user_id":        grant.GrantedBy,
+		"org_id":         orgID,
+		"grantee_org_id": grant.GranteeOrgID,
+		"resource_type":  grant.ResourceType,
+		"resource_id":    grant.ResourceID,
+		"permissions":    grant.Permissions,
+	}).Info("Granted resource access")
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// ListGrants handles GET /grants
+func (h *GrantHandler) ListGrants(c *gin.Context) {
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	grants, err := h.db.ListGrants(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list grants")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	given := make([]*authz.Grant, 0)
+	received := make([]*authz.Grant, 0)
+	for _, grant := range grants {
+		if grant.OwnerOrgID == orgID {
+			given = append(given, grant)
+		} else {
+			received = append(received, grant)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"given":    given,
+		"received": received,
+	})
+}
+
+// RevokeGrant handles DELETE /grants/:grant_id
+func (h *GrantHandler) RevokeGrant(c *gin.Context) {
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+	grantID := c.Param("grant_id")
+
+	revoked, err := h.db.RevokeGrant(grantID, orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to revoke grant")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Grant not found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "grant",
+		"grant_id": grantID,
+		"user_id":  c.GetString("user_id"),
+		"org_id":   orgID,
+	}).Info("Revoked resource access")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"grant_id": grantID,
+	})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockGrantStore backs both GrantHandler and authz.Authorizer
+type MockGrantStore struct {
+	owners      map[string]string // resource type/id -> org
+	grants      []*authz.Grant
+	shouldError bool
+}
+
+func (m *MockGrantStore) GetResourceOwner(resourceType, resourceID string) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	return m.owners[resourceType+"/"+resourceID], nil
+}
+
+func (m *MockGrantStore) FindGrant(resourceType, resourceID, granteeOrgID string) (*authz.Grant, error) {
+	for _, grant := range m.grants {
+		if grant.ResourceType == resourceType && grant.ResourceID == resourceID && grant.GranteeOrgID == granteeOrgID {
+			return grant, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockGrantStore) CreateGrant(grant *authz.Grant) (string, error) {
+	grant.ID = "grant_123"
+	m.grants = append(m.grants, grant)
+	return grant.ID, nil
+}
+
+func (m *MockGrantStore) ListGrants(orgID string) ([]*authz.Grant, error) {
+	return m.grants, nil
+}
+
+func (m *MockGrantStore) RevokeGrant(grantID, ownerOrgID string) (bool, error) {
+	for i, grant := range m.grants {
+		if grant.ID == grantID && grant.OwnerOrgID == ownerOrgID {
+			m.grants = append(m.grants[:i], m.grants[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// withOrg simulates the auth middleware for a user in an organization
+func withOrg(userID, orgID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		if orgID != "" {
+			c.Set("org_id", orgID)
+		}
+		c.Next()
+	}
+}
+
+func TestGrantHandler_CreateGrant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		orgID          string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "owner shares campaign",
+			orgID:          "org_brand",
+			body:           `{"resource_type":"campaign","resource_id":"campaign_1","grantee_org_id":"org_agency","permissions":["manage"]}`,
+			expectedStatus: http.StatusCreated,
+			description:    "Should let the owning org grant access",
+		},
+		{
+			name:           "non-owner cannot share",
+			orgID:          "org_agency",
+			body:           `{"resource_type":"campaign","resource_id":"campaign_1","grantee_org_id":"org_other","permissions":["view"]}`,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should not allow re-sharing by grantees",
+		},
+		{
+			name:           "unknown permission",
+			orgID:          "org_brand",
+			body:           `{"resource_type":"campaign","resource_id":"campaign_1","grantee_org_id":"org_agency","permissions":["delete"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown permissions",
+		},
+		{
+			name:           "surface not grantable",
+			orgID:          "org_brand",
+			body:           `{"resource_type":"surface","resource_id":"surface_1","grantee_org_id":"org_agency","permissions":["view"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject resource types that cannot be shared",
+		},
+		{
+			name:           "token without organization",
+			orgID:          "",
+			body:           `{"resource_type":"campaign","resource_id":"campaign_1","grantee_org_id":"org_agency","permissions":["view"]}`,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should require an org-scoped token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockGrantStore{owners: map[string]string{"campaign/campaign_1": "org_brand"}}
+			handler := NewGrantHandler(store)
+			router := gin.New()
+			router.Use(withOrg("user_1", tt.orgID))
+			router.POST("/grants", handler.CreateGrant)
+
+			req := httptest.NewRequest(http.MethodPost, "/grants", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusCreated {
+				require.Len(t, store.grants, 1)
+				assert.Equal(t, "org_brand", store.grants[0].OwnerOrgID)
+				assert.Equal(t, "user_1", store.grants[0].GrantedBy)
+			}
+		})
+	}
+}
+
+func TestGrantHandler_ListAndRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockGrantStore{
+		grants: []*authz.Grant{
+			{ID: "grant_1", OwnerOrgID: "org_brand", GranteeOrgID: "org_agency"},
+			{ID: "grant_2", OwnerOrgID: "org_other", GranteeOrgID: "org_brand"},
+		},
+	}
+	handler := NewGrantHandler(store)
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_brand"))
+	router.GET("/grants", handler.ListGrants)
+	router.DELETE("/grants/:grant_id", handler.RevokeGrant)
+
+	req := httptest.NewRequest(http.MethodGet, "/grants", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var response map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Len(t, response["given"], 1)
+	assert.Len(t, response["received"], 1)
+
+	req = httptest.NewRequest(http.MethodDelete, "/grants/grant_2", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code, "Should not revoke grants given by other orgs")
+
+	req = httptest.NewRequest(http.MethodDelete, "/grants/grant_1", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code, "Should revoke own grants")
+}
+
+func TestAuthorizer_ReportAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockGrantStore{
+		owners: map[string]string{"campaign/campaign_1": "org_brand"},
+		grants: []*authz.Grant{
+			{ID: "grant_1", ResourceType: "campaign", ResourceID: "campaign_1", OwnerOrgID: "org_brand", GranteeOrgID: "org_agency", Permissions: []authz.Permission{authz.PermissionView}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		orgID          string
+		query          string
+		expectedStatus int
+		description    string
+	}{
+		{"owner", "org_brand", "?campaign_id=campaign_1", http.StatusOK, "Should allow the owning org"},
+		{"grantee", "org_agency", "?campaign_id=campaign_1", http.StatusOK, "Should allow orgs with a view grant"},
+		{"other org", "org_other", "?campaign_id=campaign_1", http.StatusForbidden, "Should deny orgs without a grant"},
+		{"no org", "", "?campaign_id=campaign_1", http.StatusForbidden, "Should deny unscoped tokens on owned resources"},
+		{"unowned", "org_other", "?campaign_id=campaign_legacy", http.StatusOK, "Should allow resources without an owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReportHandler(&MockReportStore{estimatedRows: 10}, reporting.NewGuard(reporting.DefaultLimits()))
+			handler.SetAuthorizer(authz.NewAuthorizer(store))
+
+			router := gin.New()
+			router.Use(withOrg("user_1", tt.orgID))
+			router.GET("/analytics/report", handler.GetExposureReport)
+
+			req := httptest.NewRequest(http.MethodGet, "/analytics/report"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+
+	// A view grant does not allow managing the campaign
+	authorizer := authz.NewAuthorizer(store)
+	decision, err := authorizer.Check(authz.Actor{OrgID: "org_agency"}, "campaign", "campaign_1", authz.PermissionManage)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+
+	decision, err = authorizer.Check(authz.Actor{OrgID: "org_agency"}, "campaign", "campaign_1", authz.PermissionView)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "grant_1", decision.GrantID)
+}
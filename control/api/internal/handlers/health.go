@@ -35,7 +35,7 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 	allHealthy := true
 
 	// Check database connection
-	if h.db != nil {
+	if h.db != nil && h.db.DB != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
 
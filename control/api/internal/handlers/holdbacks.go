@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// HoldbackStore persists inventory hold-back rules
+type HoldbackStore interface {
+	GetHoldback(titleID string) (*holdback.Rule, error)
+	SetHoldback(rule *holdback.Rule) error
+	DeleteHoldback(titleID string) (bool, error)
+	ListHoldbackUtilization(titleID string) ([]holdback.Utilization, error)
+}
+
+// HoldbackHandler manages inventory reserved from sale
+type HoldbackHandler struct {
+	db HoldbackStore
+}
+
+// NewHoldbackHandler creates a hold-back handler
+func NewHoldbackHandler(store HoldbackStore) *HoldbackHandler {
+	return &HoldbackHandler{db: store}
+}
+
+// holdbackRequest is the body of PUT /inventory/holdbacks/:title_id
+type holdbackRequest struct {
+	Percent    float64  `json:"percent"`
+	SurfaceIDs []string `json:"surface_ids"`
+	Reason     string   `json:"reason"`
+}
+
+// ListUtilization handles GET /inventory/holdbacks
+func (h *HoldbackHandler) ListUtilization(c *gin.Context) {
+	report, err := h.db.ListHoldbackUtilization("")
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list hold-back utilization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"titles":      report,
+		"total_count": len(report),
+	})
+}
+
+// GetHoldback handles GET /inventory/holdbacks/:title_id
+func (h *HoldbackHandler) GetHoldback(c *gin.Context) {
+	titleID := c.Param("title_id")
+
+	rule, err := h.db.GetHoldback(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get hold-back")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if rule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title has no hold-back"})
+		return
+	}
+
+	h.respond(c, http.StatusOK, rule)
+}
+
+// SetHoldback handles PUT /inventory/holdbacks/:title_id
+func (h *HoldbackHandler) SetHoldback(c *gin.Context) {
+	var req holdbackRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &holdback.Rule{
+		TitleID:    c.Param("title_id"),
+		Percent:    req.Percent,
+		SurfaceIDs: req.SurfaceIDs,
+		Reason:     req.Reason,
+		UpdatedBy:  c.GetString("user_id"),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.SetHoldback(rule)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save hold-back")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "holdback",
+		"title_id":    rule.TitleID,
+		"user_id":     rule.UpdatedBy,
+		"org_id":      c.GetString("org_id"),
+		"percent":     rule.Percent,
+		"surface_ids": rule.SurfaceIDs,
+	}).Info("Set inventory hold-back")
+
+	h.respond(c, http.StatusOK, rule)
+}
+
+// DeleteHoldback handles DELETE /inventory/holdbacks/:title_id
+func (h *HoldbackHandler) DeleteHoldback(c *gin.Context) {
+	titleID := c.Param("title_id")
+
+	deleted, err := h.db.DeleteHoldback(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete hold-back")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title has no hold-back"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "holdback",
+		"title_id": titleID,
+		"user_id":  c.GetString("user_id"),
+		"org_id":   c.GetString("org_id"),
+	}).Info("Removed inventory hold-back")
+
+	c.Status(http.StatusNoContent)
+}
+
+// respond writes a rule together with its title's current utilization
+func (h *HoldbackHandler) respond(c *gin.Context, status int, rule *holdback.Rule) {
+	report, err := h.db.ListHoldbackUtilization(rule.TitleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get hold-back utilization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := gin.H{"holdback": rule}
+	if len(report) > 0 {
+		response["utilization"] = report[0]
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockHoldbackStore struct {
+	rules       map[string]*holdback.Rule
+	utilization []holdback.Utilization
+	shouldError bool
+}
+
+func (m *MockHoldbackStore) GetHoldback(titleID string) (*holdback.Rule, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.rules[titleID], nil
+}
+
+func (m *MockHoldbackStore) SetHoldback(rule *holdback.Rule) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if rule.TitleID == "missing" {
+		return db.ErrTitleNotFound
+	}
+	m.rules[rule.TitleID] = rule
+	return nil
+}
+
+func (m *MockHoldbackStore) DeleteHoldback(titleID string) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	_, ok := m.rules[titleID]
+	delete(m.rules, titleID)
+	return ok, nil
+}
+
+func (m *MockHoldbackStore) ListHoldbackUtilization(titleID string) ([]holdback.Utilization, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	report := make([]holdback.Utilization, 0)
+	for _, u := range m.utilization {
+		if titleID == "" || u.TitleID == titleID {
+			report = append(report, u)
+		}
+	}
+	return report, nil
+}
+
+func newMockHoldbackStore() *MockHoldbackStore {
+	usage := holdback.Utilization{TitleID: "1", Percent: 20, TotalSurfaces: 12, ReservedSurfaces: 2, SellableCapacity: 8, BookedSurfaces: 8}
+	usage.Finish()
+	return &MockHoldbackStore{
+		rules: map[string]*holdback.Rule{
+			"1": {TitleID: "1", Percent: 20, SurfaceIDs: []string{"surface_001", "surface_002"}},
+		},
+		utilization: []holdback.Utilization{usage},
+	}
+}
+
+func TestHoldbackHandler_SetHoldback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		titleID        string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "percent and surfaces",
+			titleID:        "1",
+			body:           `{"percent":20,"surface_ids":["surface_001","surface_002"],"reason":"finale"}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should save the rule and return utilization",
+		},
+		{
+			name:           "surfaces only",
+			titleID:        "2",
+			body:           `{"surface_ids":["surface_101"]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should allow reserving specific surfaces",
+		},
+		{
+			name:           "empty rule",
+			titleID:        "1",
+			body:           `{"reason":"nothing"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require percent or surface_ids",
+		},
+		{
+			name:           "percent out of range",
+			titleID:        "1",
+			body:           `{"percent":120}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject percent above 100",
+		},
+		{
+			name:           "duplicate surface",
+			titleID:        "1",
+			body:           `{"surface_ids":["surface_001","surface_001"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject duplicate surfaces",
+		},
+		{
+			name:           "unknown title",
+			titleID:        "missing",
+			body:           `{"percent":10}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown titles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockHoldbackStore()
+			handler := NewHoldbackHandler(store)
+			router := gin.New()
+			router.PUT("/inventory/holdbacks/:title_id", withOrg("user_1", "org_a"), handler.SetHoldback)
+
+			req := httptest.NewRequest(http.MethodPut, "/inventory/holdbacks/"+tt.titleID, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			rule := store.rules[tt.titleID]
+			require.NotNil(t, rule)
+			assert.Equal(t, "user_1", rule.UpdatedBy)
+			assert.False(t, rule.UpdatedAt.IsZero())
+
+			var response map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Contains(t, response, "holdback")
+			if tt.titleID == "1" {
+				var usage holdback.Utilization
+				require.NoError(t, json.Unmarshal(response["utilization"], &usage))
+				assert.True(t, usage.Exhausted)
+				assert.Equal(t, 1.0, usage.Utilization)
+			}
+		})
+	}
+}
+
+func TestHoldbackHandler_GetAndDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		shouldError    bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "get rule",
+			method:         http.MethodGet,
+			path:           "/inventory/holdbacks/1",
+			expectedStatus: http.StatusOK,
+			description:    "Should return the title's rule",
+		},
+		{
+			name:           "get missing rule",
+			method:         http.MethodGet,
+			path:           "/inventory/holdbacks/2",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for titles without a rule",
+		},
+		{
+			name:           "list utilization",
+			method:         http.MethodGet,
+			path:           "/inventory/holdbacks",
+			expectedStatus: http.StatusOK,
+			description:    "Should report utilization for every title",
+		},
+		{
+			name:           "delete rule",
+			method:         http.MethodDelete,
+			path:           "/inventory/holdbacks/1",
+			expectedStatus: http.StatusNoContent,
+			description:    "Should remove the rule",
+		},
+		{
+			name:           "delete missing rule",
+			method:         http.MethodDelete,
+			path:           "/inventory/holdbacks/2",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for titles without a rule",
+		},
+		{
+			name:           "store error",
+			method:         http.MethodGet,
+			path:           "/inventory/holdbacks",
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockHoldbackStore()
+			store.shouldError = tt.shouldError
+			handler := NewHoldbackHandler(store)
+			router := gin.New()
+			router.GET("/inventory/holdbacks", handler.ListUtilization)
+			router.GET("/inventory/holdbacks/:title_id", handler.GetHoldback)
+			router.DELETE("/inventory/holdbacks/:title_id", handler.DeleteHoldback)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.method == http.MethodDelete && tt.expectedStatus == http.StatusNoContent {
+				assert.NotContains(t, store.rules, "1")
+			}
+			if tt.path == "/inventory/holdbacks" && tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Titles []holdback.Utilization `json:"titles"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				require.Len(t, response.Titles, 1)
+				assert.Equal(t, 8, response.Titles[0].SellableCapacity)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/idempotency"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+func newMockIdempotencyStore() *MockIdempotencyStore {
+	return &MockIdempotencyStore{records: map[string]*idempotency.Record{}}
+}
+
+func (m *MockIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[key]; ok {
+		return record, nil
+	}
+	m.records[key] = &idempotency.Record{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (m *MockIdempotencyStore) Complete(ctx context.Context, key string, record *idempotency.Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = record
+	return nil
+}
+
+func (m *MockIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+const testExposure = `{"booking_id":"booking_123","viewer_id":"viewer_456","exposure_duration":5.2}`
+
+func newIdempotentRouter(mockDB *MockPlacementDB, store idempotency.Store) *gin.Engine {
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	idempotent := middleware.Idempotency(store, time.Hour)
+	router.POST("/bookings", idempotent, handler.BookPlacement)
+	router.POST("/events/exposure", idempotent, handler.RecordExposure)
+	return router
+}
+
+func postIdempotent(router *gin.Engine, path, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("exposure retry", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		first := postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		require.Equal(t, http.StatusCreated, first.Code)
+		retry := postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+
+		assert.Equal(t, http.StatusCreated, retry.Code, "Should replay the original status")
+		assert.Equal(t, first.Body.String(), retry.Body.String(), "Should replay the original body")
+		assert.Equal(t, "true", retry.Header().Get(middleware.ReplayedHeader))
+		assert.Empty(t, first.Header().Get(middleware.ReplayedHeader))
+		assert.Len(t, mockDB.events, 1, "Should record the exposure once")
+	})
+
+	t.Run("booking retry", func(t *testing.T) {
+		mockDB := &MockPlacementDB{bookingID: "booking_1"}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+		body := `{"surface_id":"surface_001","advertiser_id":"adv_1","campaign_id":"camp_1","bid_amount_cpm":4.5}`
+
+		first := postIdempotent(router, "/bookings", "user_1", "key-1", body)
+		require.Equal(t, http.StatusCreated, first.Code)
+		mockDB.created = nil
+		retry := postIdempotent(router, "/bookings", "user_1", "key-1", body)
+
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Nil(t, mockDB.created, "Should not create the booking again")
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		postIdempotent(router, "/events/exposure", "user_1", "", testExposure)
+		postIdempotent(router, "/events/exposure", "user_1", "", testExposure)
+		assert.Len(t, mockDB.events, 2, "Should leave requests without a key alone")
+	})
+
+	t.Run("other caller", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		resp := postIdempotent(router, "/events/exposure", "user_2", "key-1", testExposure)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Empty(t, resp.Header().Get(middleware.ReplayedHeader), "Should scope keys to the caller")
+		assert.Len(t, mockDB.events, 2)
+	})
+}
+
+func TestIdempotency_Refused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("different payload", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		resp := postIdempotent(router, "/events/exposure", "user_1", "key-1", strings.Replace(testExposure, "5.2", "6.0", 1))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, "Should refuse a reused key with another payload")
+		assert.Len(t, mockDB.events, 1)
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		store := newMockIdempotencyStore()
+		router := newIdempotentRouter(mockDB, store)
+		fingerprint := idempotency.Fingerprint(http.MethodPost, "/events/exposure", []byte(testExposure))
+		store.records["user_1:POST /events/exposure:key-1"] = &idempotency.Record{Fingerprint: fingerprint}
+
+		resp := postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		assert.Equal(t, http.StatusConflict, resp.Code, "Should not run a retry alongside the first request")
+		assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		assert.Empty(t, mockDB.events)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		mockDB := &MockPlacementDB{}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		resp := postIdempotent(router, "/events/exposure", "user_1", "key 1", testExposure)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Empty(t, mockDB.events)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		mockDB := &MockPlacementDB{shouldError: true}
+		router := newIdempotentRouter(mockDB, newMockIdempotencyStore())
+
+		failed := postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		require.Equal(t, http.StatusInternalServerError, failed.Code)
+
+		mockDB.shouldError = false
+		retry := postIdempotent(router, "/events/exposure", "user_1", "key-1", testExposure)
+		assert.Equal(t, http.StatusCreated, retry.Code, "Should let a failed request be retried")
+		assert.Empty(t, retry.Header().Get(middleware.ReplayedHeader))
+		assert.Len(t, mockDB.events, 1)
+	})
+}
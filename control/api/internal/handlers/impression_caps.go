@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/impcap"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// ImpressionLeaser grants edge nodes local quotas of capped bookings
+type ImpressionLeaser interface {
+	Lease(ctx context.Context, bookingID, nodeID string, want int64) (*impcap.Lease, error)
+	Release(ctx context.Context, bookingID, nodeID string) (int64, error)
+	Status(ctx context.Context, bookingID string) (*impcap.Cap, *impcap.CounterStatus, error)
+}
+
+// ImpressionCapHandler serves impression leases to edge nodes and cap status
+type ImpressionCapHandler struct {
+	leaser ImpressionLeaser
+}
+
+// NewImpressionCapHandler creates an impression cap handler
+func NewImpressionCapHandler(leaser ImpressionLeaser) *ImpressionCapHandler {
+	return &ImpressionCapHandler{leaser: leaser}
+}
+
+// leaseRequest is the body of POST /edge/leases
+type leaseRequest struct {
+	BookingID   string `json:"booking_id" binding:"required"`
+	NodeID      string `json:"node_id" binding:"required"`
+	Impressions int64  `json:"impressions" binding:"required"`
+}
+
+// Lease handles POST /edge/leases
+func (h *ImpressionCapHandler) Lease(c *gin.Context) {
+	var req leaseRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Impressions < 1 || req.Impressions > impcap.MaxLeaseRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "impressions must be between 1 and 10000"})
+		return
+	}
+
+	lease, err := h.leaser.Lease(c.Request.Context(), req.BookingID, req.NodeID, req.Impressions)
+	switch {
+	case errors.Is(err, impcap.ErrUnknownBooking):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	case errors.Is(err, impcap.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to lease impressions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"booking_id": lease.BookingID,
+		"node_id":    lease.NodeID,
+		"granted":    lease.Granted,
+		"held":       lease.Held,
+	}).Debug("Leased impressions")
+
+	c.JSON(http.StatusOK, lease)
+}
+
+// Release handles DELETE /edge/leases/:booking_id/:node_id
+func (h *ImpressionCapHandler) Release(c *gin.Context) {
+	bookingID := c.Param("booking_id")
+	nodeID := c.Param("node_id")
+
+	returned, err := h.leaser.Release(c.Request.Context(), bookingID, nodeID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to release impression lease")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id": bookingID,
+		"node_id":    nodeID,
+		"returned":   returned,
+	})
+}
+
+// GetImpressions handles GET /bookings/:id/impressions
+func (h *ImpressionCapHandler) GetImpressions(c *gin.Context) {
+	capped, counter, err := h.leaser.Status(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get impression cap")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if capped == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	response := gin.H{
+		"cap":     capped,
+		"capped":  capped.Capped(),
+		"counter": gin.H{"loaded": false},
+	}
+	if capped.Capped() {
+		response["available"] = capped.Available()
+	}
+	if counter != nil {
+		response["counter"] = gin.H{
+			"loaded":    true,
+			"remaining": counter.Remaining,
+			"served":    counter.Served,
+			"leased":    counter.Leased,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/impcap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockImpressionCapStore backs impcap.Enforcer
+type MockImpressionCapStore struct {
+	caps        map[string]*impcap.Cap
+	leases      map[string]int64 // booking ID/node ID -> held
+	shouldError bool
+}
+
+func newMockImpressionCapStore() *MockImpressionCapStore {
+	return &MockImpressionCapStore{
+		caps: map[string]*impcap.Cap{
+			"booking_1": {BookingID: "booking_1", Max: 100, Served: 40, Leased: 10},
+			"booking_2": {BookingID: "booking_2"},
+		},
+		leases: map[string]int64{},
+	}
+}
+
+func (m *MockImpressionCapStore) GetImpressionCap(bookingID string) (*impcap.Cap, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	if c, ok := m.caps[bookingID]; ok {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *MockImpressionCapStore) ListImpressionCaps() ([]impcap.Cap, error) {
+	caps := make([]impcap.Cap, 0, len(m.caps))
+	for _, c := range m.caps {
+		caps = append(caps, *c)
+	}
+	return caps, nil
+}
+
+func (m *MockImpressionCapStore) SaveImpressionLease(bookingID, nodeID string, held int64, expiresAt time.Time) error {
+	m.leases[bookingID+"/"+nodeID] = held
+	return nil
+}
+
+func (m *MockImpressionCapStore) DeleteImpressionLease(bookingID, nodeID string) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	delete(m.leases, bookingID+"/"+nodeID)
+	return nil
+}
+
+func TestImpressionCapHandler_Lease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		unlimited      bool
+		description    string
+	}{
+		{
+			name:           "uncapped booking",
+			body:           `{"booking_id":"booking_2","node_id":"edge_1","impressions":50}`,
+			expectedStatus: http.StatusOK,
+			unlimited:      true,
+			description:    "Should grant an unlimited lease without a counter",
+		},
+		{
+			name:           "capped booking without counter",
+			body:           `{"booking_id":"booking_1","node_id":"edge_1","impressions":50}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			description:    "Should refuse leases on capped bookings without Redis",
+		},
+		{
+			name:           "unknown booking",
+			body:           `{"booking_id":"booking_404","node_id":"edge_1","impressions":50}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should reject leases on unknown bookings",
+		},
+		{
+			name:           "too many impressions",
+			body:           `{"booking_id":"booking_1","node_id":"edge_1","impressions":10001}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should bound the impressions one request may lease",
+		},
+		{
+			name:           "missing node",
+			body:           `{"booking_id":"booking_1","impressions":5}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require the leasing node",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewImpressionCapHandler(impcap.NewEnforcer(newMockImpressionCapStore(), nil, impcap.DefaultLeaseTTL))
+			router := gin.New()
+			router.POST("/edge/leases", handler.Lease)
+
+			req := httptest.NewRequest(http.MethodPost, "/edge/leases", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var lease impcap.Lease
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &lease))
+			assert.Equal(t, tt.unlimited, lease.Unlimited)
+			assert.True(t, lease.ExpiresAt.After(time.Now()), "Should tell the node when its lease ends")
+		})
+	}
+}
+
+func TestImpressionCapHandler_Release(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockImpressionCapStore()
+	store.leases["booking_1/edge_1"] = 5
+	handler := NewImpressionCapHandler(impcap.NewEnforcer(store, nil, impcap.DefaultLeaseTTL))
+	router := gin.New()
+	router.DELETE("/edge/leases/:booking_id/:node_id", handler.Release)
+
+	req := httptest.NewRequest(http.MethodDelete, "/edge/leases/booking_1/edge_1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, store.leases, "booking_1/edge_1", "Should drop the recorded lease")
+}
+
+func TestImpressionCapHandler_GetImpressions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name              string
+		bookingID         string
+		shouldError       bool
+		expectedStatus    int
+		expectedAvailable interface{}
+		description       string
+	}{
+		{
+			name:              "capped booking",
+			bookingID:         "booking_1",
+			expectedStatus:    http.StatusOK,
+			expectedAvailable: float64(50),
+			description:       "Should count leased impressions as taken",
+		},
+		{
+			name:           "uncapped booking",
+			bookingID:      "booking_2",
+			expectedStatus: http.StatusOK,
+			description:    "Should report uncapped bookings without availability",
+		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_404",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown bookings",
+		},
+		{
+			name:           "store error",
+			bookingID:      "booking_1",
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 when the store fails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockImpressionCapStore()
+			store.shouldError = tt.shouldError
+			handler := NewImpressionCapHandler(impcap.NewEnforcer(store, nil, impcap.DefaultLeaseTTL))
+			router := gin.New()
+			router.GET("/bookings/:id/impressions", handler.GetImpressions)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/"+tt.bookingID+"/impressions", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedAvailable, body["available"], tt.description)
+			assert.Equal(t, map[string]interface{}{"loaded": false}, body["counter"])
+		})
+	}
+}
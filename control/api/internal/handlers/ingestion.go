@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// ingestionScanLimit bounds the unpublished titles GET /ingestion filters
+const ingestionScanLimit = 5000
+
+// IngestionStore records pipeline callbacks and reads titles' progress
+type IngestionStore interface {
+	RecordIngestionStage(titleID string, event ingest.StageEvent) (bool, error)
+	GetIngestionStages(titleID string) ([]ingest.StageState, error)
+	ListOpenIngestions(limit int) (map[string][]ingest.StageState, error)
+}
+
+// IngestionHandler tracks where each title stands in the SGI pipeline
+type IngestionHandler struct {
+	db         IngestionStore
+	stuckAfter time.Duration
+}
+
+// NewIngestionHandler creates an ingestion handler. Stages running, or
+// waiting for the previous one, longer than stuckAfter are reported stuck.
+func NewIngestionHandler(store IngestionStore, stuckAfter time.Duration) *IngestionHandler {
+	return &IngestionHandler{db: store, stuckAfter: stuckAfter}
+}
+
+// RecordStage handles POST /titles/:title_id/ingestion/events, the callback
+// the pipeline sends as a title enters, finishes or fails a stage
+func (h *IngestionHandler) RecordStage(c *gin.Context) {
+	titleID := c.Param("title_id")
+
+	var event ingest.StageEvent
+	if err := schema.BindJSON(c, &event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := event.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	applied, err := h.db.RecordIngestionStage(titleID, event)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to record ingestion stage")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"title_id": titleID,
+		"stage":    event.Stage,
+		"status":   event.Status,
+		"run_id":   event.RunID,
+	})
+	// Late callbacks are expected with retries; acknowledge them so the
+	// pipeline stops resending
+	if !applied {
+		logger.Info("Ignored stale ingestion callback")
+	} else if event.Status == ingest.StatusFailed {
+		logger.WithField("message", event.Message).Warn("Title ingestion stage failed")
+	} else {
+		logger.Info("Recorded ingestion stage")
+	}
+
+	h.renderProgress(c, titleID, gin.H{"applied": applied})
+}
+
+// GetProgress handles GET /titles/:title_id/ingestion
+func (h *IngestionHandler) GetProgress(c *gin.Context) {
+	h.renderProgress(c, c.Param("title_id"), nil)
+}
+
+func (h *IngestionHandler) renderProgress(c *gin.Context, titleID string, extra gin.H) {
+	stages, err := h.db.GetIngestionStages(titleID)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to get ingestion stages")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	progress := ingest.NewProgress(titleID, stages, h.stuckAfter, time.Now())
+	if extra == nil {
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+	extra["progress"] = progress
+	c.JSON(http.StatusOK, extra)
+}
+
+// ListProgress handles GET /ingestion: titles not yet published, filtered
+// by state (pending, processing, failed or stuck), least recently updated
+// first
+func (h *IngestionHandler) ListProgress(c *gin.Context) {
+	state := c.Query("state")
+	switch state {
+	case "", ingest.StatePending, ingest.StateProcessing, ingest.StateFailed, ingest.StateStuck:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state must be pending, processing, failed or stuck"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	titles, err := h.db.ListOpenIngestions(ingestionScanLimit)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list ingestions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	now := time.Now()
+	results := make([]*ingest.Progress, 0, len(titles))
+	for titleID, stages := range titles {
+		progress := ingest.NewProgress(titleID, stages, h.stuckAfter, now)
+		if state == "" || progress.State == state {
+			results = append(results, progress)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i].UpdatedAt, results[j].UpdatedAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"titles":      results,
+		"total_count": len(results),
+	})
+}
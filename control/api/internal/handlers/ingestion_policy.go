@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
+	"github.com/sirupsen/logrus"
+)
+
+// IngestionPolicySource returns the ingestion policy in force
+type IngestionPolicySource interface {
+	Current() throttle.Policy
+}
+
+// IngestionPolicyStore persists the ingestion policy operators set
+type IngestionPolicyStore interface {
+	SetIngestionPolicy(policy *throttle.Policy) error
+	DeleteIngestionPolicy() error
+}
+
+// IngestionPolicyHandler lets operators throttle edge nodes' exposure
+// batches centrally, and edge nodes poll what they may send
+type IngestionPolicyHandler struct {
+	db     IngestionPolicyStore
+	policy *throttle.Cache
+}
+
+// NewIngestionPolicyHandler creates a new ingestion policy handler
+func NewIngestionPolicyHandler(store IngestionPolicyStore, policy *throttle.Cache) *IngestionPolicyHandler {
+	return &IngestionPolicyHandler{db: store, policy: policy}
+}
+
+// GetPolicy handles GET /events/exposure/policy, which edge SDKs poll, and
+// GET /admin/ingestion-policy
+func (h *IngestionPolicyHandler) GetPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, h.policy.Current())
+}
+
+// SetPolicy handles PUT /admin/ingestion-policy
+func (h *IngestionPolicyHandler) SetPolicy(c *gin.Context) {
+	var req struct {
+		MaxBatchSize  int    `json:"max_batch_size" binding:"required"`
+		MinIntervalMS int64  `json:"min_interval_ms"`
+		Reason        string `json:"reason"` // Shown to edge operators, e.g. the incident
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := throttle.Policy{
+		MaxBatchSize:  req.MaxBatchSize,
+		MinIntervalMS: req.MinIntervalMS,
+		Reason:        req.Reason,
+		UpdatedBy:     c.GetString("user_id"),
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.SetIngestionPolicy(&policy); err != nil {
+		logrus.WithError(err).Error("Failed to set ingestion policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	h.policy.Invalidate()
+
+	logrus.WithFields(logrus.Fields{
+		"audit":           "ingestion_policy",
+		"user_id":         policy.UpdatedBy,
+		"max_batch_size":  policy.MaxBatchSize,
+		"min_interval_ms": policy.MinIntervalMS,
+		"reason":          policy.Reason,
+	}).Info("Updated ingestion policy")
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ResetPolicy handles DELETE /admin/ingestion-policy, returning edge nodes
+// to the default policy
+func (h *IngestionPolicyHandler) ResetPolicy(c *gin.Context) {
+	if err := h.db.DeleteIngestionPolicy(); err != nil {
+		logrus.WithError(err).Error("Failed to reset ingestion policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	h.policy.Invalidate()
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "ingestion_policy",
+		"user_id": c.GetString("user_id"),
+	}).Info("Reset ingestion policy to the default")
+
+	c.JSON(http.StatusOK, throttle.DefaultPolicy())
+}
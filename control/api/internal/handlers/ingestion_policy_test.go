@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockIngestionPolicyStore keeps the policy operators set in memory
+type MockIngestionPolicyStore struct {
+	policy      *throttle.Policy
+	shouldError bool
+}
+
+func (m *MockIngestionPolicyStore) GetIngestionPolicy() (*throttle.Policy, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.policy, nil
+}
+
+func (m *MockIngestionPolicyStore) SetIngestionPolicy(policy *throttle.Policy) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	now := time.Now()
+	policy.UpdatedAt = &now
+	stored := *policy
+	m.policy = &stored
+	return nil
+}
+
+func (m *MockIngestionPolicyStore) DeleteIngestionPolicy() error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	m.policy = nil
+	return nil
+}
+
+func TestIngestionPolicyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockIngestionPolicyStore{}
+	handler := NewIngestionPolicyHandler(store, throttle.NewCache(store))
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "ops") })
+	router.GET("/events/exposure/policy", handler.GetPolicy)
+	router.PUT("/admin/ingestion-policy", handler.SetPolicy)
+	router.DELETE("/admin/ingestion-policy", handler.ResetPolicy)
+	do := func(method, path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		return resp.Code, response
+	}
+
+	status, response := do(http.MethodGet, "/events/exposure/policy", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(throttle.MaxBatchSize), response["max_batch_size"])
+	assert.Equal(t, float64(0), response["min_interval_ms"])
+	assert.Equal(t, true, response["default"])
+
+	status, response = do(http.MethodPut, "/admin/ingestion-policy", `{"max_batch_size":50,"min_interval_ms":30000,"reason":"INC-42 ClickHouse lag"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ops", response["updated_by"])
+	assert.Contains(t, response, "updated_at")
+
+	status, response = do(http.MethodGet, "/events/exposure/policy", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(50), response["max_batch_size"], "A change should apply at once on this instance")
+	assert.Equal(t, float64(30000), response["min_interval_ms"])
+	assert.Equal(t, "INC-42 ClickHouse lag", response["reason"])
+	assert.Equal(t, false, response["default"])
+
+	for _, body := range []string{
+		`{}`,
+		`{"max_batch_size":0}`,
+		`{"max_batch_size":1001}`,
+		`{"max_batch_size":50,"min_interval_ms":-1}`,
+		`{"max_batch_size":50,"min_interval_ms":3600001}`,
+		`{"max_batch_size":50,"reason":"` + strings.Repeat("x", 256) + `"}`,
+	} {
+		status, _ = do(http.MethodPut, "/admin/ingestion-policy", body)
+		assert.Equal(t, http.StatusBadRequest, status, body)
+	}
+	assert.Equal(t, 50, store.policy.MaxBatchSize, "Invalid policies should not be stored")
+
+	status, response = do(http.MethodDelete, "/admin/ingestion-policy", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, response["default"])
+	_, response = do(http.MethodGet, "/events/exposure/policy", "")
+	assert.Equal(t, float64(throttle.MaxBatchSize), response["max_batch_size"], "Resetting should restore the default")
+
+	store.shouldError = true
+	status, _ = do(http.MethodPut, "/admin/ingestion-policy", `{"max_batch_size":50}`)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	status, _ = do(http.MethodDelete, "/admin/ingestion-policy", "")
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestPlacementHandler_BatchIngestionPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockIngestionPolicyStore{policy: &throttle.Policy{MaxBatchSize: 2, MinIntervalMS: 5000, Reason: "incident"}}
+	handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{})}
+	handler.SetIngestionPolicy(throttle.NewCache(store))
+	router := gin.New()
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+
+	send := func(events int) (int, map[string]interface{}) {
+		exposures := make([]string, events)
+		for i := range exposures {
+			exposures[i] = `{"booking_id": "booking_123", "viewer_id": "viewer_456", "exposure_duration": 5.2}`
+		}
+		req := httptest.NewRequest(http.MethodPost, "/events/exposure/batch", strings.NewReader(`{"events": [`+strings.Join(exposures, ",")+`]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		return resp.Code, response
+	}
+
+	status, response := send(2)
+	require.Equal(t, http.StatusCreated, status)
+	policy, ok := response["ingestion_policy"].(map[string]interface{})
+	require.True(t, ok, "Batch responses should advertise the ingestion policy")
+	assert.Equal(t, float64(2), policy["max_batch_size"])
+	assert.Equal(t, float64(5000), policy["min_interval_ms"])
+
+	status, response = send(3)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status, "Should refuse batches larger than the policy allows")
+	assert.Contains(t, response, "ingestion_policy", "Refusals should carry the policy so nodes can split the batch")
+
+	// Without a configured source the default policy applies
+	handler = &PlacementHandler{placements: placement.NewService(&MockPlacementDB{})}
+	router = gin.New()
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+	status, response = send(3)
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, true, response["ingestion_policy"].(map[string]interface{})["default"])
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockIngestionStore struct {
+	stages      map[string][]ingest.StageState
+	shouldError bool
+}
+
+func (m *MockIngestionStore) RecordIngestionStage(titleID string, event ingest.StageEvent) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	stages, ok := m.stages[titleID]
+	if !ok {
+		return false, db.ErrTitleNotFound
+	}
+
+	var current, uploaded *ingest.StageState
+	for i := range stages {
+		if stages[i].Stage == event.Stage {
+			current = &stages[i]
+		}
+		if stages[i].Stage == ingest.StageUploaded {
+			uploaded = &stages[i]
+		}
+	}
+	if current != nil && event.OccurredAt.Before(*current.UpdatedAt) {
+		return false, nil
+	}
+	if event.Superseded(uploaded) {
+		return false, nil
+	}
+
+	next := ingest.Apply(current, event)
+	kept := make([]ingest.StageState, 0, len(stages)+1)
+	restarts := event.Restarts(uploaded)
+	for _, state := range stages {
+		if state.Stage == event.Stage || (restarts && state.Stage != ingest.StageUploaded) {
+			continue
+		}
+		kept = append(kept, state)
+	}
+	m.stages[titleID] = append(kept, next)
+	return true, nil
+}
+
+func (m *MockIngestionStore) GetIngestionStages(titleID string) ([]ingest.StageState, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	stages, ok := m.stages[titleID]
+	if !ok {
+		return nil, db.ErrTitleNotFound
+	}
+	return stages, nil
+}
+
+func (m *MockIngestionStore) ListOpenIngestions(limit int) (map[string][]ingest.StageState, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	titles := make(map[string][]ingest.StageState)
+	for titleID, stages := range m.stages {
+		published := false
+		for _, state := range stages {
+			published = published || (state.Stage == ingest.StagePublished && state.Status == ingest.StatusCompleted)
+		}
+		if len(stages) > 0 && !published {
+			titles[titleID] = stages
+		}
+	}
+	return titles, nil
+}
+
+func hoursAgo(hours int) *time.Time {
+	t := time.Now().Add(-time.Duration(hours) * time.Hour)
+	return &t
+}
+
+func newMockIngestionStore() *MockIngestionStore {
+	return &MockIngestionStore{
+		stages: map[string][]ingest.StageState{
+			"1": {},
+			// Shots detected 5 hours ago and nothing since
+			"2": {
+				{Stage: ingest.StageUploaded, Status: ingest.StatusCompleted, RunID: "run_a", StartedAt: hoursAgo(6), CompletedAt: hoursAgo(6), UpdatedAt: hoursAgo(6)},
+				{Stage: ingest.StageShotsDetected, Status: ingest.StatusCompleted, RunID: "run_a", StartedAt: hoursAgo(6), CompletedAt: hoursAgo(5), UpdatedAt: hoursAgo(5)},
+			},
+			"3": {
+				{Stage: ingest.StageUploaded, Status: ingest.StatusCompleted, RunID: "run_b", StartedAt: hoursAgo(1), CompletedAt: hoursAgo(1), UpdatedAt: hoursAgo(1)},
+				{Stage: ingest.StageShotsDetected, Status: ingest.StatusFailed, RunID: "run_b", Message: "decoder crashed", UpdatedAt: hoursAgo(1)},
+			},
+		},
+	}
+}
+
+func TestIngestionHandler_RecordStage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		titleID        string
+		body           string
+		shouldError    bool
+		expectedStatus int
+		expectedState  string
+		applied        bool
+		description    string
+	}{
+		{"upload running", "1", `{"stage":"uploaded","status":"running","run_id":"run_c"}`, false, http.StatusOK, ingest.StateProcessing, true, "Should start tracking the title"},
+		{"next stage completed", "2", `{"stage":"surfaces_extracted","status":"completed","run_id":"run_a"}`, false, http.StatusOK, ingest.StateProcessing, true, "Should move the title to the next stage"},
+		{"stale callback", "2", `{"stage":"shots_detected","status":"running","run_id":"run_a","occurred_at":"2020-01-01T00:00:00Z"}`, false, http.StatusOK, ingest.StateStuck, false, "Should acknowledge callbacks older than the stage"},
+		{"earlier run", "3", `{"stage":"shots_detected","status":"completed","run_id":"run_a"}`, false, http.StatusOK, ingest.StateFailed, false, "Should ignore callbacks from replaced runs"},
+		{"unknown stage", "1", `{"stage":"rendered","status":"completed"}`, false, http.StatusBadRequest, "", false, "Should reject unknown stages"},
+		{"unknown status", "1", `{"stage":"scored","status":"paused"}`, false, http.StatusBadRequest, "", false, "Should reject unknown statuses"},
+		{"missing title", "missing", `{"stage":"uploaded","status":"running"}`, false, http.StatusNotFound, "", false, "Should return 404 for unknown titles"},
+		{"database error", "1", `{"stage":"uploaded","status":"running"}`, true, http.StatusInternalServerError, "", false, "Should surface store failures"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockIngestionStore()
+			store.shouldError = tt.shouldError
+			handler := NewIngestionHandler(store, 2*time.Hour)
+			router := gin.New()
+			router.POST("/titles/:title_id/ingestion/events", handler.RecordStage)
+
+			req := httptest.NewRequest(http.MethodPost, "/titles/"+tt.titleID+"/ingestion/events", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Applied  bool            `json:"applied"`
+				Progress ingest.Progress `json:"progress"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.applied, response.Applied, tt.description)
+			assert.Equal(t, tt.expectedState, response.Progress.State, tt.description)
+		})
+	}
+}
+
+func TestIngestionHandler_RestartResetsStages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockIngestionStore()
+	handler := NewIngestionHandler(store, 2*time.Hour)
+	router := gin.New()
+	router.POST("/titles/:title_id/ingestion/events", handler.RecordStage)
+
+	req := httptest.NewRequest(http.MethodPost, "/titles/3/ingestion/events", bytes.NewBufferString(`{"stage":"uploaded","status":"running","run_id":"run_c"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var response struct {
+		Progress ingest.Progress `json:"progress"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, ingest.StateProcessing, response.Progress.State)
+	assert.Equal(t, ingest.StageUploaded, response.Progress.CurrentStage)
+	assert.Equal(t, ingest.StatusPending, response.Progress.Stages[1].Status, "Should clear the failure of the previous run")
+}
+
+func TestIngestionHandler_GetProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		titleID         string
+		expectedStatus  int
+		expectedState   string
+		expectedCurrent string
+		expectedDone    int
+		description     string
+	}{
+		{"not started", "1", http.StatusOK, ingest.StatePending, ingest.StageUploaded, 0, "Should report titles without callbacks as pending"},
+		{"stuck", "2", http.StatusOK, ingest.StateStuck, ingest.StageSurfacesExtracted, 2, "Should flag stages waiting longer than the threshold"},
+		{"failed", "3", http.StatusOK, ingest.StateFailed, ingest.StageShotsDetected, 1, "Should report the failed stage"},
+		{"missing title", "missing", http.StatusNotFound, "", "", 0, "Should return 404 for unknown titles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewIngestionHandler(newMockIngestionStore(), 2*time.Hour)
+			router := gin.New()
+			router.GET("/titles/:title_id/ingestion", handler.GetProgress)
+
+			req := httptest.NewRequest(http.MethodGet, "/titles/"+tt.titleID+"/ingestion", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var progress ingest.Progress
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &progress))
+			assert.Equal(t, tt.expectedState, progress.State, tt.description)
+			assert.Equal(t, tt.expectedCurrent, progress.CurrentStage, tt.description)
+			assert.Equal(t, tt.expectedDone, progress.CompletedStages, tt.description)
+			assert.Len(t, progress.Stages, len(ingest.Stages), "Should list every stage")
+		})
+	}
+}
+
+func TestIngestionHandler_ListProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTitles []string
+		description    string
+	}{
+		{"all", "", http.StatusOK, []string{"2", "3"}, "Should list unpublished titles, least recently updated first"},
+		{"stuck", "?state=stuck", http.StatusOK, []string{"2"}, "Should filter by state"},
+		{"failed", "?state=failed", http.StatusOK, []string{"3"}, "Should filter by state"},
+		{"limited", "?limit=1", http.StatusOK, []string{"2"}, "Should honour the limit"},
+		{"invalid state", "?state=published", http.StatusBadRequest, nil, "Should reject unknown states"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewIngestionHandler(newMockIngestionStore(), 2*time.Hour)
+			router := gin.New()
+			router.GET("/ingestion", handler.ListProgress)
+
+			req := httptest.NewRequest(http.MethodGet, "/ingestion"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Titles []ingest.Progress `json:"titles"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			titleIDs := make([]string, 0, len(response.Titles))
+			for _, progress := range response.Titles {
+				titleIDs = append(titleIDs, progress.TitleID)
+			}
+			assert.Equal(t, tt.expectedTitles, titleIDs, tt.description)
+		})
+	}
+}
+
+func TestIngestionHandler_ListProgressError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewIngestionHandler(&MockIngestionStore{shouldError: true}, 2*time.Hour)
+	router := gin.New()
+	router.GET("/ingestion", handler.ListProgress)
+
+	req := httptest.NewRequest(http.MethodGet, "/ingestion", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// InterstitialStore keeps which bookings' placements are HLS Interstitials
+type InterstitialStore interface {
+	GetBookingInterstitial(bookingID string) (*decorate.Interstitial, error)
+	SetBookingInterstitial(i *decorate.Interstitial) error
+	DeleteBookingInterstitial(bookingID string) (bool, error)
+}
+
+// InterstitialHandler turns bookings' placements into HLS Interstitials,
+// which decorated playlists instruct players to load the creative of
+type InterstitialHandler struct {
+	db InterstitialStore
+}
+
+// NewInterstitialHandler creates a new interstitial handler
+func NewInterstitialHandler(store InterstitialStore) *InterstitialHandler {
+	return &InterstitialHandler{db: store}
+}
+
+// GetInterstitial handles GET /bookings/:id/interstitial
+func (h *InterstitialHandler) GetInterstitial(c *gin.Context) {
+	bookingID := c.Param("id")
+	interstitial, err := h.db.GetBookingInterstitial(bookingID)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to get booking interstitial")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if interstitial == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking placements are not interstitials"})
+		return
+	}
+
+	c.JSON(http.StatusOK, interstitial)
+}
+
+// SetInterstitial handles PUT /bookings/:id/interstitial, e.g.
+// {"asset_uri": "https://cdn.example.com/creative/index.m3u8", "resume_offset": 0}.
+// Without asset_uri the booking's latest completed render is played, once
+// there is one players can load.
+func (h *InterstitialHandler) SetInterstitial(c *gin.Context) {
+	var req struct {
+		AssetURI     string   `json:"asset_uri"`
+		ResumeOffset *float64 `json:"resume_offset"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	interstitial := &decorate.Interstitial{
+		BookingID:    c.Param("id"),
+		AssetURI:     req.AssetURI,
+		ResumeOffset: req.ResumeOffset,
+		UpdatedBy:    authz.ActorFromContext(c).UserID,
+	}
+	if err := interstitial.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.SetBookingInterstitial(interstitial)
+	if errors.Is(err, db.ErrBookingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", interstitial.BookingID).Error("Failed to set booking interstitial")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "booking_interstitial",
+		"user_id":    interstitial.UpdatedBy,
+		"booking_id": interstitial.BookingID,
+		"asset_uri":  interstitial.AssetURI,
+	}).Info("Set booking interstitial")
+
+	c.JSON(http.StatusOK, interstitial)
+}
+
+// DeleteInterstitial handles DELETE /bookings/:id/interstitial, leaving the
+// booking's placements only tagged
+func (h *InterstitialHandler) DeleteInterstitial(c *gin.Context) {
+	bookingID := c.Param("id")
+	deleted, err := h.db.DeleteBookingInterstitial(bookingID)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to delete booking interstitial")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking placements are not interstitials"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "booking_interstitial",
+		"user_id":    c.GetString("user_id"),
+		"booking_id": bookingID,
+	}).Info("Deleted booking interstitial")
+
+	c.Status(http.StatusNoContent)
+}
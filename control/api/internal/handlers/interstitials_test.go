@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/edge/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockInterstitialStore struct {
+	bookings      map[string]bool
+	interstitials map[string]*decorate.Interstitial
+}
+
+func (m *MockInterstitialStore) GetBookingInterstitial(bookingID string) (*decorate.Interstitial, error) {
+	return m.interstitials[bookingID], nil
+}
+
+func (m *MockInterstitialStore) SetBookingInterstitial(i *decorate.Interstitial) error {
+	if !m.bookings[i.BookingID] {
+		return db.ErrBookingNotFound
+	}
+	i.UpdatedAt = time.Now()
+	m.interstitials[i.BookingID] = i
+	return nil
+}
+
+func (m *MockInterstitialStore) DeleteBookingInterstitial(bookingID string) (bool, error) {
+	_, ok := m.interstitials[bookingID]
+	delete(m.interstitials, bookingID)
+	return ok, nil
+}
+
+func TestInterstitialHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockInterstitialStore{bookings: map[string]bool{"booking_1": true}, interstitials: map[string]*decorate.Interstitial{}}
+	handler := NewInterstitialHandler(store)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user_1")
+	})
+	router.GET("/bookings/:id/interstitial", handler.GetInterstitial)
+	router.PUT("/bookings/:id/interstitial", handler.SetInterstitial)
+	router.DELETE("/bookings/:id/interstitial", handler.DeleteInterstitial)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/bookings/booking_1/interstitial", "").Code)
+
+	for name, body := range map[string]string{
+		"relative asset":  `{"asset_uri": "/creative/index.m3u8"}`,
+		"storage asset":   `{"asset_uri": "s3://renders/booking_1.m3u8"}`,
+		"quoted asset":    `{"asset_uri": "https://cdn.example.com/\"x\".m3u8"}`,
+		"negative offset": `{"resume_offset": -1}`,
+		"too long offset": `{"resume_offset": 7200}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/bookings/booking_1/interstitial", body).Code, name)
+	}
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/bookings/booking_2/interstitial", `{}`).Code, "Should refuse unknown bookings")
+
+	resp := do(http.MethodPut, "/bookings/booking_1/interstitial", `{"asset_uri": "https://cdn.example.com/creative/index.m3u8", "resume_offset": 0}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	saved := store.interstitials["booking_1"]
+	require.NotNil(t, saved)
+	assert.Equal(t, "user_1", saved.UpdatedBy)
+	require.NotNil(t, saved.ResumeOffset)
+	assert.Zero(t, *saved.ResumeOffset)
+
+	resp = do(http.MethodGet, "/bookings/booking_1/interstitial", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var got decorate.Interstitial
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	assert.Equal(t, "https://cdn.example.com/creative/index.m3u8", got.AssetURI)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/bookings/booking_1/interstitial", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/bookings/booking_1/interstitial", "").Code)
+}
+
+func TestManifestHandler_Interstitials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	resume := 0.0
+	store := &MockManifestStore{placements: map[string][]decorate.Placement{
+		"42": {
+			{BookingID: "booking_1", SurfaceID: "surface_1", SurfaceType: "screen", PRSScore: 87.5, Start: 1, End: 4,
+				AssetURI: "https://cdn.example.com/booking_1/index.m3u8"},
+			{BookingID: "booking_2", SurfaceID: "surface_2", SurfaceType: "wall", PRSScore: 91, Start: 7.5, End: 12.5,
+				AssetURI: "https://cdn.example.com/booking_2/index.m3u8", ResumeOffset: &resume},
+			{BookingID: "booking_3", SurfaceID: "surface_3", SurfaceType: "wall", PRSScore: 80, Start: 13, End: 15},
+		},
+	}}
+	handler := NewManifestHandler(store, decorate.NewFetcher(nil), nil)
+	router := gin.New()
+	router.POST("/manifests/decorate", handler.Decorate)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/manifests/decorate?title_id=42", strings.NewReader(decoratePlaylist))
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	placements, err := manifest.ExtractDateRangeMetadata(resp.Body.String())
+	require.NoError(t, err)
+	require.Len(t, placements, 3)
+	require.NotNil(t, placements[0].Interstitial)
+	assert.Equal(t, manifest.Interstitial{AssetURI: "https://cdn.example.com/booking_1/index.m3u8", ResumeOffset: 3}, *placements[0].Interstitial,
+		"Should skip the placement by default")
+	require.NotNil(t, placements[1].Interstitial)
+	assert.Zero(t, placements[1].Interstitial.ResumeOffset)
+	assert.Nil(t, placements[2].Interstitial, "Should only tag bookings without an interstitial")
+	assert.Equal(t, 2, strings.Count(resp.Body.String(), `CLASS="com.apple.hls.interstitial"`))
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// LabelStore reads and replaces labels on resources
+type LabelStore interface {
+	GetLabels(resourceType, resourceID string) (labels.Set, error)
+	SetLabels(resourceType, resourceID string, set labels.Set) error
+}
+
+// LabelHandler manages free-form labels on campaigns, bookings, creatives and surfaces
+type LabelHandler struct {
+	db LabelStore
+}
+
+// NewLabelHandler creates a new label handler
+func NewLabelHandler(store LabelStore) *LabelHandler {
+	return &LabelHandler{db: store}
+}
+
+// parseLabelSelector reads the "labels" query parameter (e.g. team=sports,region=emea),
+// writing a 400 response and returning false when it is malformed
+func parseLabelSelector(c *gin.Context) (labels.Set, bool) {
+	selector, err := labels.ParseSelector(c.Query("labels"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return selector, true
+}
+
+// resourceParams reads and validates the labelled resource from the path
+func resourceParams(c *gin.Context) (string, string, bool) {
+	resourceType := c.Param("resource_type")
+	if !labels.ValidResourceType(resourceType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource_type must be one of campaign, booking, creative or surface"})
+		return "", "", false
+	}
+	return resourceType, c.Param("resource_id"), true
+}
+
+// GetLabels handles GET /labels/:resource_type/:resource_id
+func (h *LabelHandler) GetLabels(c *gin.Context) {
+	resourceType, resourceID, ok := resourceParams(c)
+	if !ok {
+		return
+	}
+
+	set, err := h.db.GetLabels(resourceType, resourceID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get labels")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"labels":        set,
+	})
+}
+
+// SetLabels handles PUT /labels/:resource_type/:resource_id, replacing all labels
+func (h *LabelHandler) SetLabels(c *gin.Context) {
+	resourceType, resourceID, ok := resourceParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Labels labels.Set `json:"labels"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Labels == nil {
+		req.Labels = labels.Set{}
+	}
+	if err := req.Labels.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"labels":        req.Labels.String(),
+	}).Info("Setting labels")
+
+	if err := h.db.SetLabels(resourceType, resourceID, req.Labels); err != nil {
+		logrus.WithError(err).Error("Failed to set labels")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set labels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"labels":        req.Labels,
+	})
+}
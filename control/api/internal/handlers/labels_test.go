@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockLabelStore struct {
+	labels      map[string]labels.Set
+	shouldError bool
+}
+
+func (m *MockLabelStore) GetLabels(resourceType, resourceID string) (labels.Set, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	if set, ok := m.labels[resourceType+"/"+resourceID]; ok {
+		return set, nil
+	}
+	return labels.Set{}, nil
+}
+
+func (m *MockLabelStore) SetLabels(resourceType, resourceID string, set labels.Set) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	m.labels[resourceType+"/"+resourceID] = set
+	return nil
+}
+
+func TestLabelHandler_SetLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		shouldError    bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "label a campaign",
+			path:           "/labels/campaign/campaign_1",
+			body:           `{"labels":{"team":"sports","region":"emea"}}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should replace labels on a campaign",
+		},
+		{
+			name:           "clear labels",
+			path:           "/labels/surface/surface_1",
+			body:           `{"labels":{}}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should allow removing all labels",
+		},
+		{
+			name:           "unknown resource type",
+			path:           "/labels/viewer/viewer_1",
+			body:           `{"labels":{"team":"sports"}}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unlabelled resource types",
+		},
+		{
+			name:           "invalid key",
+			path:           "/labels/booking/booking_1",
+			body:           `{"labels":{"Not A Key":"x"}}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject malformed label keys",
+		},
+		{
+			name:           "store error",
+			path:           "/labels/creative/creative_1",
+			body:           `{"labels":{"team":"sports"}}`,
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockLabelStore{labels: map[string]labels.Set{}, shouldError: tt.shouldError}
+			handler := NewLabelHandler(store)
+			router := gin.New()
+			router.PUT("/labels/:resource_type/:resource_id", handler.SetLabels)
+			router.GET("/labels/:resource_type/:resource_id", handler.GetLabels)
+
+			req := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+
+			if tt.expectedStatus == http.StatusOK {
+				req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				var response struct {
+					Labels map[string]string `json:"labels"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+
+				var sent struct {
+					Labels map[string]string `json:"labels"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(tt.body), &sent))
+				assert.Equal(t, sent.Labels, response.Labels)
+			}
+		})
+	}
+}
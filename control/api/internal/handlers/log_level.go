@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// maxLogListDays bounds the days one listing of log-level files covers
+const maxLogListDays = 366
+
+// LogLevelStore reads advertisers and their log-level files
+type LogLevelStore interface {
+	GetAdvertiser(advertiserID string) (*campaign.Advertiser, error)
+	ListLogExports(advertiserID string, from, to time.Time) ([]*export.LogExport, error)
+	GetLogExport(advertiserID string, day time.Time) (*export.LogExport, error)
+}
+
+// LogLevelHandler serves the daily log-level files written for each
+// advertiser's auditors
+type LogLevelHandler struct {
+	db     LogLevelStore
+	files  storage.Store
+	urlTTL time.Duration
+}
+
+// NewLogLevelHandler creates a log-level handler handing out download URLs
+// of files that expire after urlTTL
+func NewLogLevelHandler(store LogLevelStore, files storage.Store, urlTTL time.Duration) *LogLevelHandler {
+	return &LogLevelHandler{db: store, files: files, urlTTL: urlTTL}
+}
+
+// Schema handles GET /log-level/schema, documenting the columns of
+// log-level files
+func (h *LogLevelHandler) Schema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":      export.LogSchemaVersion,
+		"record_types": []string{export.RecordDecision, export.RecordImpression},
+		"formats":      []string{export.FormatCSV, export.FormatParquet},
+		"columns":      export.LogSchema(),
+	})
+}
+
+// loadAdvertiser fetches the advertiser of the request, hiding those
+// outside the caller's organizations
+func (h *LogLevelHandler) loadAdvertiser(c *gin.Context) (*campaign.Advertiser, bool) {
+	a, err := h.db.GetAdvertiser(c.Param("advertiser_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get advertiser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if a == nil || !authz.Scope(c).Allows(a.OrgID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Advertiser not found"})
+		return nil, false
+	}
+	return a, true
+}
+
+// ListFiles handles GET /advertisers/:advertiser_id/log-level?from=&to=,
+// listing the advertiser's files for the days from from to to inclusive.
+// The range defaults to the last 30 days.
+func (h *LogLevelHandler) ListFiles(c *gin.Context) {
+	to := export.LogDay(time.Now())
+	from := to.AddDate(0, 0, -29)
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(export.LogDateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(export.LogDateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+	if to.Sub(from) >= maxLogListDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a listing may cover at most 366 days"})
+		return
+	}
+
+	a, ok := h.loadAdvertiser(c)
+	if !ok {
+		return
+	}
+	files, err := h.db.ListLogExports(a.AdvertiserID, from, to)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list log-level exports")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"advertiser_id": a.AdvertiserID,
+		"from":          from.Format(export.LogDateLayout),
+		"to":            to.Format(export.LogDateLayout),
+		"files":         files,
+	})
+}
+
+// loadFile fetches the advertiser's file for the day of the request
+func (h *LogLevelHandler) loadFile(c *gin.Context) (*export.LogExport, bool) {
+	day, err := time.Parse(export.LogDateLayout, c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return nil, false
+	}
+	a, ok := h.loadAdvertiser(c)
+	if !ok {
+		return nil, false
+	}
+	e, err := h.db.GetLogExport(a.AdvertiserID, day)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get log-level export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if e == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No log-level file for " + day.Format(export.LogDateLayout)})
+		return nil, false
+	}
+	return e, true
+}
+
+// GetFile handles GET /advertisers/:advertiser_id/log-level/:date. Written
+// files carry a signed object storage URL, or the API path serving the
+// file for storage drivers that cannot sign URLs.
+func (h *LogLevelHandler) GetFile(c *gin.Context) {
+	e, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"advertiser_id":  e.AdvertiserID,
+		"date":           e.Date,
+		"format":         e.Format,
+		"status":         e.Status,
+		"schema_version": export.LogSchemaVersion,
+		"row_count":      e.RowCount,
+		"size_bytes":     e.Size,
+		"created_at":     e.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if e.CompletedAt != nil {
+		response["completed_at"] = e.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	if e.Error != "" {
+		response["error"] = e.Error
+	}
+	if e.Status == export.StatusCompleted {
+		url, err := storage.SignedURL(h.files, e.ObjectKey, h.urlTTL)
+		switch {
+		case err == nil:
+			response["download_url"] = url
+			response["expires_at"] = time.Now().Add(h.urlTTL).UTC().Format(time.RFC3339)
+		case errors.Is(err, storage.ErrNotSignable):
+			response["download_url"] = "/api/v1/advertisers/" + e.AdvertiserID + "/log-level/" + e.Date + "/download"
+		default:
+			logrus.WithError(err).WithField("advertiser_id", e.AdvertiserID).Error("Failed to sign log-level file URL")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		h.audit(c, e, "Issued log-level file URL")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadFile handles GET /advertisers/:advertiser_id/log-level/:date/download
+func (h *LogLevelHandler) DownloadFile(c *gin.Context) {
+	e, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	if e.Status != export.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Log-level file is not ready",
+			"status": e.Status,
+		})
+		return
+	}
+
+	file, err := h.files.Get(c.Request.Context(), e.ObjectKey)
+	if err != nil {
+		logrus.WithError(err).WithField("advertiser_id", e.AdvertiserID).Error("Failed to read log-level file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer file.Close()
+
+	h.audit(c, e, "Downloaded log-level file")
+	c.DataFromReader(http.StatusOK, e.Size, export.ContentType(e.Format), file, map[string]string{
+		"Content-Disposition": `attachment; filename="` + export.LogFilename(e.AdvertiserID, e.Day, e.Format) + `"`,
+	})
+}
+
+// audit records who was handed an advertiser's log-level data
+func (h *LogLevelHandler) audit(c *gin.Context, e *export.LogExport, action string) {
+	logrus.WithFields(logrus.Fields{
+		"audit":         "log_level",
+		"advertiser_id": e.AdvertiserID,
+		"date":          e.Date,
+		"user_id":       c.GetString("user_id"),
+		"org_id":        c.GetString("org_id"),
+	}).Info(action)
+}
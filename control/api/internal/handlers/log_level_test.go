@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/export"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockLogLevelStore struct {
+	advertisers map[string]*campaign.Advertiser
+	records     []export.LogRecord
+	exports     map[string]*export.LogExport // advertiser_id/date -> file
+	policies    map[string]*export.Policy    // By organization
+}
+
+func (m *MockLogLevelStore) LogExportPolicy(q export.LogQuery) (*export.Policy, error) {
+	if advertiser := m.advertisers[q.AdvertiserID]; advertiser != nil {
+		return m.policies[advertiser.OrgID], nil
+	}
+	return nil, nil
+}
+
+func logExportKey(advertiserID string, day time.Time) string {
+	return advertiserID + "/" + day.Format(export.LogDateLayout)
+}
+
+func (m *MockLogLevelStore) GetAdvertiser(advertiserID string) (*campaign.Advertiser, error) {
+	return m.advertisers[advertiserID], nil
+}
+
+func (m *MockLogLevelStore) ListLogExports(advertiserID string, from, to time.Time) ([]*export.LogExport, error) {
+	files := []*export.LogExport{}
+	for _, e := range m.exports {
+		if e.AdvertiserID == advertiserID && !e.Day.Before(from) && !e.Day.After(to) {
+			files = append(files, e)
+		}
+	}
+	return files, nil
+}
+
+func (m *MockLogLevelStore) GetLogExport(advertiserID string, day time.Time) (*export.LogExport, error) {
+	return m.exports[logExportKey(advertiserID, day)], nil
+}
+
+func (m *MockLogLevelStore) StreamLogRecords(q export.LogQuery, fn func(r *export.LogRecord) error) error {
+	for i := range m.records {
+		if m.records[i].AdvertiserID != q.AdvertiserID {
+			continue
+		}
+		if err := fn(&m.records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockLogLevelStore) ClaimLogExports(day time.Time, format string) ([]*export.LogExport, error) {
+	return nil, nil
+}
+
+func (m *MockLogLevelStore) StartLogExport(advertiserID string, day time.Time) error {
+	m.exports[logExportKey(advertiserID, day)].Status = export.StatusRunning
+	return nil
+}
+
+func (m *MockLogLevelStore) CompleteLogExport(advertiserID string, day time.Time, objectKey string, rows, size int64) error {
+	e := m.exports[logExportKey(advertiserID, day)]
+	e.Status = export.StatusCompleted
+	e.ObjectKey = objectKey
+	e.RowCount = rows
+	e.Size = size
+	return nil
+}
+
+func (m *MockLogLevelStore) FailLogExport(advertiserID string, day time.Time, message string) error {
+	e := m.exports[logExportKey(advertiserID, day)]
+	e.Status = export.StatusFailed
+	e.Error = message
+	return nil
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	served, creative, title := "served", "creative_1", "12"
+	attention, seconds, counted := 0.75, 2.5, true
+	device := "ctv"
+	store := &MockLogLevelStore{
+		advertisers: map[string]*campaign.Advertiser{
+			"adv_1": {AdvertiserID: "adv_1", OrgID: "org_a"},
+		},
+		records: []export.LogRecord{
+			{RecordType: export.RecordDecision, EventID: "dec_1", EventTime: day.Add(10 * time.Hour), AdvertiserID: "adv_1", CampaignID: "campaign_1", BookingID: "booking_1",
+				CreativeID: &creative, SurfaceID: "surface_1", TitleID: &title, BillingModel: "cpm", Outcome: &served, Spend: 0.012},
+			{RecordType: export.RecordImpression, EventID: "evt_1", EventTime: day.Add(10*time.Hour + time.Second), AdvertiserID: "adv_1", CampaignID: "campaign_1", BookingID: "booking_1",
+				CreativeID: &creative, SurfaceID: "surface_1", TitleID: &title, BillingModel: "cpm", DeviceType: &device, ExposureDuration: &seconds, AttentionScore: &attention, Counted: &counted},
+		},
+		exports: map[string]*export.LogExport{
+			"adv_1/2024-01-02": {AdvertiserID: "adv_1", Day: day, Date: "2024-01-02", Format: export.FormatCSV, Status: export.StatusPending},
+		},
+	}
+	files, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	handler := NewLogLevelHandler(store, files, time.Hour)
+
+	newRouter := func(orgID string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", "auditor_1")
+			c.Set("org_id", orgID)
+			c.Next()
+		})
+		router.GET("/log-level/schema", handler.Schema)
+		router.GET("/advertisers/:advertiser_id/log-level", handler.ListFiles)
+		router.GET("/advertisers/:advertiser_id/log-level/:date", handler.GetFile)
+		router.GET("/advertisers/:advertiser_id/log-level/:date/download", handler.DownloadFile)
+		return router
+	}
+	router := newRouter("org_a")
+	get := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		resp := get(router, "/log-level/schema")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Version string             `json:"version"`
+			Columns []export.LogColumn `json:"columns"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, export.LogSchemaVersion, body.Version)
+		require.NotEmpty(t, body.Columns)
+		assert.Equal(t, export.LogColumn{Name: "record_type", Type: "string", Description: body.Columns[0].Description}, body.Columns[0])
+		for _, col := range body.Columns {
+			assert.NotEmpty(t, col.Description, "Column %s should be documented", col.Name)
+			assert.NotEqual(t, "viewer_id", col.Name, "Should never export viewer IDs")
+		}
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		resp := get(router, "/advertisers/adv_1/log-level/2024-01-02/download")
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	// Write the file as a worker would
+	payload, err := json.Marshal(export.LogJob{AdvertiserID: "adv_1", Date: "2024-01-02"})
+	require.NoError(t, err)
+	run := export.NewLogJobHandler(store, files)
+	require.NoError(t, run(context.Background(), &jobqueue.Job{ID: "job_1", Queue: export.LogQueue, Payload: payload}))
+
+	t.Run("list", func(t *testing.T) {
+		resp := get(router, "/advertisers/adv_1/log-level?from=2024-01-01&to=2024-01-31")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Files []export.LogExport `json:"files"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		require.Len(t, body.Files, 1)
+		assert.Equal(t, "2024-01-02", body.Files[0].Date)
+		assert.Equal(t, export.StatusCompleted, body.Files[0].Status)
+		assert.EqualValues(t, 2, body.Files[0].RowCount)
+
+		resp = get(router, "/advertisers/adv_1/log-level?from=2024-01-31&to=2024-01-01")
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		resp = get(router, "/advertisers/adv_1/log-level?from=2023-01-01&to=2024-01-31")
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "Should bound the days listed")
+	})
+
+	t.Run("download", func(t *testing.T) {
+		resp := get(router, "/advertisers/adv_1/log-level/2024-01-02")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var status map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+		assert.Equal(t, "/api/v1/advertisers/adv_1/log-level/2024-01-02/download", status["download_url"], "Local storage cannot sign URLs")
+
+		resp = get(router, "/advertisers/adv_1/log-level/2024-01-02/download")
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Header().Get("Content-Disposition"), "log_level_adv_1_2024-01-02.csv")
+		records, err := csv.NewReader(strings.NewReader(resp.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, "record_type", records[0][0])
+		assert.Equal(t, []string{"decision", "dec_1", "2024-01-02T10:00:00Z", "adv_1", "campaign_1", "booking_1", "creative_1", "surface_1", "12", "cpm", "served", "", "", "", "", "", "", "", "0.012"}, records[1])
+		assert.Equal(t, []string{"impression", "evt_1", "2024-01-02T10:00:01Z", "adv_1", "campaign_1", "booking_1", "creative_1", "surface_1", "12", "cpm", "", "", "ctv", "2.5", "", "0.75", "", "true", "0"}, records[2])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(router, "/advertisers/adv_1/log-level/yesterday").Code)
+		assert.Equal(t, http.StatusNotFound, get(router, "/advertisers/adv_1/log-level/2024-01-03").Code)
+		assert.Equal(t, http.StatusNotFound, get(router, "/advertisers/adv_2/log-level").Code)
+		assert.Equal(t, http.StatusNotFound, get(newRouter("org_b"), "/advertisers/adv_1/log-level/2024-01-02").Code,
+			"Should hide other organizations' advertisers")
+	})
+}
+
+func TestLogLevelExportPolicy(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	device, creative := "ctv", "creative_1"
+	store := &MockLogLevelStore{
+		advertisers: map[string]*campaign.Advertiser{"adv_1": {AdvertiserID: "adv_1", OrgID: "org_a"}},
+		records: []export.LogRecord{
+			{RecordType: export.RecordImpression, EventID: "evt_1", EventTime: day.Add(time.Hour), AdvertiserID: "adv_1", CampaignID: "campaign_1",
+				BookingID: "booking_1", CreativeID: &creative, SurfaceID: "surface_1", BillingModel: "cpm", DeviceType: &device},
+		},
+		policies: map[string]*export.Policy{
+			"org_a": {OrgID: "org_a", Columns: map[string]string{"device_type": export.ActionStrip, "creative_id": export.ActionHash}, HashKey: "key"},
+		},
+	}
+
+	var out strings.Builder
+	rows, err := export.WriteLog(&out, export.FormatCSV, store, export.LogQuery{AdvertiserID: "adv_1", Day: day})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, rows)
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.NotContains(t, records[0], "device_type", "Stripped columns should be left out")
+	assert.Len(t, records[0], len(export.LogSchema())-1)
+	assert.Equal(t, "creative_id", records[0][6])
+	assert.Len(t, records[1][6], 64, "Hashed columns should hold a hex HMAC")
+	assert.NotContains(t, out.String(), creative)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/manifestlog"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// ManifestCaptureStore reads the index of captured playlists
+type ManifestCaptureStore interface {
+	ListManifestCaptures(q manifestlog.Query) ([]*manifestlog.Capture, error)
+	GetManifestCapture(captureID string) (*manifestlog.Capture, error)
+}
+
+// ManifestCaptureHandler lets support look up the playlists the gateway
+// was given and served, byte for byte
+type ManifestCaptureHandler struct {
+	db    ManifestCaptureStore
+	files storage.Store
+}
+
+// NewManifestCaptureHandler creates a manifest capture handler
+func NewManifestCaptureHandler(store ManifestCaptureStore, files storage.Store) *ManifestCaptureHandler {
+	return &ManifestCaptureHandler{db: store, files: files}
+}
+
+// ListCaptures handles GET /admin/manifest-captures?session_id=&title_id=,
+// listing the unexpired captures of a playback session or title
+func (h *ManifestCaptureHandler) ListCaptures(c *gin.Context) {
+	q := manifestlog.Query{SessionID: c.Query("session_id"), TitleID: c.Query("title_id")}
+	if q.SessionID == "" && q.TitleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id or title_id is required"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+	q.Limit = limit
+
+	captures, err := h.db.ListManifestCaptures(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list manifest captures")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"captures": captures,
+		"count":    len(captures),
+		"limit":    limit,
+	})
+}
+
+// loadCapture fetches the capture of the request
+func (h *ManifestCaptureHandler) loadCapture(c *gin.Context) (*manifestlog.Capture, bool) {
+	capture, err := h.db.GetManifestCapture(c.Param("capture_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get manifest capture")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if capture == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manifest capture not found or expired"})
+		return nil, false
+	}
+	return capture, true
+}
+
+// GetCapture handles GET /admin/manifest-captures/:capture_id
+func (h *ManifestCaptureHandler) GetCapture(c *gin.Context) {
+	capture, ok := h.loadCapture(c)
+	if !ok {
+		return
+	}
+
+	manifests := gin.H{}
+	for _, kind := range manifestlog.Kinds {
+		manifests[kind] = "/admin/manifest-captures/" + capture.CaptureID + "/" + kind
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"capture":   capture,
+		"manifests": manifests,
+	})
+}
+
+// GetManifest handles GET /admin/manifest-captures/:capture_id/:kind,
+// serving the original or decorated manifest exactly as it was captured
+func (h *ManifestCaptureHandler) GetManifest(c *gin.Context) {
+	kind := c.Param("kind")
+	if kind != manifestlog.KindOriginal && kind != manifestlog.KindDecorated {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be original or decorated"})
+		return
+	}
+	capture, ok := h.loadCapture(c)
+	if !ok {
+		return
+	}
+
+	size := capture.OriginalSize
+	if kind == manifestlog.KindDecorated {
+		size = capture.DecoratedSize
+	}
+	file, err := h.files.Get(c.Request.Context(), manifestlog.ObjectKey(capture.CaptureID, kind))
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manifest capture not found or expired"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("capture_id", capture.CaptureID).Error("Failed to read captured manifest")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer file.Close()
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "manifest_capture",
+		"capture_id": capture.CaptureID,
+		"title_id":   capture.TitleID,
+		"kind":       kind,
+		"user_id":    c.GetString("user_id"),
+	}).Info("Read captured manifest")
+	c.DataFromReader(http.StatusOK, size, capture.ContentType, file, map[string]string{
+		"Cache-Control": "no-store",
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/manifestlog"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockManifestCaptureStore struct {
+	mu       sync.Mutex
+	captures map[string]*manifestlog.Capture
+	now      time.Time
+}
+
+func (m *MockManifestCaptureStore) CreateManifestCapture(c *manifestlog.Capture) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *c
+	m.captures[c.CaptureID] = &copied
+	return nil
+}
+
+func (m *MockManifestCaptureStore) GetManifestCapture(captureID string) (*manifestlog.Capture, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.captures[captureID]
+	if c == nil || !c.ExpiresAt.After(m.now) {
+		return nil, nil
+	}
+	return c, nil
+}
+
+func (m *MockManifestCaptureStore) ListManifestCaptures(q manifestlog.Query) ([]*manifestlog.Capture, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	captures := []*manifestlog.Capture{}
+	for _, c := range m.captures {
+		if (q.SessionID == "" || c.SessionID == q.SessionID) && (q.TitleID == "" || c.TitleID == q.TitleID) && c.ExpiresAt.After(m.now) {
+			captures = append(captures, c)
+		}
+	}
+	sort.Slice(captures, func(i, j int) bool { return captures[i].CreatedAt.After(captures[j].CreatedAt) })
+	if len(captures) > q.Limit {
+		captures = captures[:q.Limit]
+	}
+	return captures, nil
+}
+
+func (m *MockManifestCaptureStore) ExpiredManifestCaptures(before time.Time, limit int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var expired []string
+	for id, c := range m.captures {
+		if !c.ExpiresAt.After(before) && len(expired) < limit {
+			expired = append(expired, id)
+		}
+	}
+	return expired, nil
+}
+
+func (m *MockManifestCaptureStore) DeleteManifestCaptures(captureIDs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range captureIDs {
+		delete(m.captures, id)
+	}
+	return nil
+}
+
+func TestManifestCaptureHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockManifestCaptureStore{captures: map[string]*manifestlog.Capture{}, now: time.Now()}
+	files, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	assert.Nil(t, manifestlog.NewRecorder(manifestlog.Config{TTL: time.Hour}, store, files), "Should not capture at 0 percent")
+	assert.False(t, (*manifestlog.Recorder)(nil).Sampled("session_1"))
+
+	recorder := manifestlog.NewRecorder(manifestlog.Config{Percent: 100, TTL: time.Hour}, store, files)
+	require.True(t, recorder.Sampled("session_1"))
+
+	original := "#EXTM3U\r\n#EXTINF:10.0,\r\nsegment_000.m4s\r\n"
+	decorated := "#EXTM3U\n#EXT-X-DATERANGE:ID=\"placement_1\"\n#EXTINF:10.0,\nsegment_000.m4s\n"
+	capture := &manifestlog.Capture{SessionID: "session_1", TitleID: "42", OrgID: "org_a", ContentType: "application/vnd.apple.mpegurl", Placements: 1}
+	require.NoError(t, recorder.Record(context.Background(), capture, []byte(original), []byte(decorated)))
+	require.NotEmpty(t, capture.CaptureID)
+	assert.EqualValues(t, len(decorated), capture.DecoratedSize)
+
+	handler := NewManifestCaptureHandler(store, files)
+	router := gin.New()
+	router.GET("/admin/manifest-captures", handler.ListCaptures)
+	router.GET("/admin/manifest-captures/:capture_id", handler.GetCapture)
+	router.GET("/admin/manifest-captures/:capture_id/:kind", handler.GetManifest)
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	t.Run("list", func(t *testing.T) {
+		resp := get("/admin/manifest-captures?session_id=session_1")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Captures []manifestlog.Capture `json:"captures"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		require.Len(t, body.Captures, 1)
+		assert.Equal(t, capture.CaptureID, body.Captures[0].CaptureID)
+		assert.Equal(t, "42", body.Captures[0].TitleID)
+
+		resp = get("/admin/manifest-captures?title_id=43")
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"count":0`)
+
+		assert.Equal(t, http.StatusBadRequest, get("/admin/manifest-captures").Code, "Should require a session or title")
+	})
+
+	t.Run("exact bytes", func(t *testing.T) {
+		resp := get("/admin/manifest-captures/" + capture.CaptureID)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "/admin/manifest-captures/"+capture.CaptureID+"/decorated")
+
+		for kind, want := range map[string]string{manifestlog.KindOriginal: original, manifestlog.KindDecorated: decorated} {
+			resp := get(fmt.Sprintf("/admin/manifest-captures/%s/%s", capture.CaptureID, kind))
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, want, resp.Body.String(), "Should serve the %s manifest byte for byte", kind)
+			assert.Equal(t, "application/vnd.apple.mpegurl", resp.Header().Get("Content-Type"))
+		}
+		assert.Equal(t, http.StatusBadRequest, get("/admin/manifest-captures/"+capture.CaptureID+"/patched").Code)
+		assert.Equal(t, http.StatusNotFound, get("/admin/manifest-captures/capture_unknown").Code)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		janitor := manifestlog.NewJanitor(store, files, time.Hour)
+		deleted, err := janitor.Purge(context.Background(), time.Now())
+		require.NoError(t, err)
+		assert.Zero(t, deleted, "Should keep captures until they expire")
+
+		store.now = time.Now().Add(2 * time.Hour)
+		assert.Equal(t, http.StatusNotFound, get("/admin/manifest-captures/"+capture.CaptureID).Code)
+
+		deleted, err = janitor.Purge(context.Background(), store.now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Empty(t, store.captures)
+		_, err = files.Get(context.Background(), manifestlog.ObjectKey(capture.CaptureID, manifestlog.KindOriginal))
+		assert.ErrorIs(t, err, storage.ErrNotFound, "Should delete the captured manifests")
+	})
+}
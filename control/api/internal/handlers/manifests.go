@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/control/api/internal/manifestcache"
+	"github.com/inscenium/inscenium/control/api/internal/manifestlog"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// captureTimeout bounds storing a sampled playlist after it was served
+const captureTimeout = 30 * time.Second
+
+// ManifestStore lists the placements signaled in a title's playlists
+type ManifestStore interface {
+	ListManifestPlacements(scope tenant.Scope, titleID string) ([]decorate.Placement, error)
+}
+
+// ManifestServeStore records the playlists personalized for viewers
+type ManifestServeStore interface {
+	CreateManifestServe(serve *decorate.Serve) error
+}
+
+// ManifestHandler decorates partners' playlists with the placements booked
+// in their title
+type ManifestHandler struct {
+	db       ManifestStore
+	fetcher  *decorate.Fetcher
+	captures *manifestlog.Recorder
+	proxyURL string
+	caps     decorate.CapChecker
+	serves   ManifestServeStore
+	cache    manifestcache.Cache
+}
+
+// NewManifestHandler creates a manifest handler fetching playlists with
+// fetcher and keeping a sample of them with captures, which may be nil
+func NewManifestHandler(store ManifestStore, fetcher *decorate.Fetcher, captures *manifestlog.Recorder) *ManifestHandler {
+	return &ManifestHandler{db: store, fetcher: fetcher, captures: captures, proxyURL: decorate.DefaultProxyURL}
+}
+
+// SetProxyURL sets the URL master playlists' renditions are pointed at,
+// which must reach Decorate
+func (h *ManifestHandler) SetProxyURL(proxyURL string) {
+	h.proxyURL = proxyURL
+}
+
+// SetCache keeps decorated VOD playlists in cache, which may be nil to
+// decorate every request
+func (h *ManifestHandler) SetCache(cache manifestcache.Cache) {
+	h.cache = cache
+}
+
+// SetPersonalization enables Personalize, filtering placements by viewers'
+// frequency caps with caps and recording each playlist served in serves
+func (h *ManifestHandler) SetPersonalization(caps decorate.CapChecker, serves ManifestServeStore) {
+	h.caps = caps
+	h.serves = serves
+}
+
+// Decorate handles POST /manifests/decorate?title_id=, and GET with url
+// for players following a decorated master playlist. The body is an HLS
+// playlist, unless url names one to fetch from an allowed origin. Media
+// playlists are returned with an EXT-X-DATERANGE tag for each booked
+// placement of the title; program_start (RFC 3339, default the Unix epoch)
+// is when the title starts, which undated playlists begin with, and
+// session_id the playback session, used to sample playlists kept for
+// support. Live playlists are placed by their EXT-X-PROGRAM-DATE-TIME.
+// Decorated VOD playlists are cached with the placements they signal, and
+// those fetched from url are served from the cache without fetching them.
+// Master playlists are handled by decorateMaster.
+func (h *ManifestHandler) Decorate(c *gin.Context) {
+	titleID := c.Query("title_id")
+	if titleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title_id is required"})
+		return
+	}
+	programStart, ok := parseProgramStart(c)
+	if !ok {
+		return
+	}
+
+	// Sampled sessions are always decorated, so captures hold what was fetched
+	sessionID := c.Query("session_id")
+	sampled := h.captures.Sampled(sessionID)
+	cached := h.cache != nil && !sampled
+
+	// Playlists fetched from url are looked up before they are fetched
+	var placements []decorate.Placement
+	key := ""
+	if rawURL := c.Query("url"); cached && rawURL != "" {
+		if placements, ok = h.listPlacements(c, titleID); !ok {
+			return
+		}
+		key = manifestcache.Key(manifestcache.BookingSet(placements), programStart, manifestcache.Source(rawURL))
+		if h.serveCached(c, titleID, key) {
+			return
+		}
+	}
+
+	playlist, ok := h.readPlaylist(c)
+	if !ok {
+		return
+	}
+	if decorate.IsMaster(playlist) {
+		h.decorateMaster(c, titleID, programStart, playlist)
+		return
+	}
+
+	if placements == nil {
+		if placements, ok = h.listPlacements(c, titleID); !ok {
+			return
+		}
+	}
+	if cached && key == "" {
+		key = manifestcache.Key(manifestcache.BookingSet(placements), programStart, manifestcache.BodySource(playlist))
+		if h.serveCached(c, titleID, key) {
+			return
+		}
+	}
+	decorated, signaled, err := decorate.HLS(playlist, placements, programStart)
+	if errors.Is(err, decorate.ErrInvalidManifest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to decorate manifest")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	entry := manifestcache.NewEntry(decorated, signaled)
+	if cached && !decorate.IsLive(playlist) {
+		if err := h.cache.Set(c.Request.Context(), titleID, key, entry); err != nil {
+			logrus.WithError(err).WithField("title_id", titleID).Warn("Failed to cache decorated manifest")
+		}
+	}
+	if sampled {
+		h.capture(c, titleID, playlist, decorated, len(signaled))
+	}
+	writePlaylist(c, entry)
+}
+
+// listPlacements lists the placements of a title visible to the caller
+func (h *ManifestHandler) listPlacements(c *gin.Context, titleID string) ([]decorate.Placement, bool) {
+	placements, err := h.db.ListManifestPlacements(authz.Scope(c), titleID)
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to list manifest placements")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if placements == nil {
+		placements = []decorate.Placement{}
+	}
+	return placements, true
+}
+
+// serveCached serves the cached playlist of a title under key, reporting
+// whether there was one. Playlists are decorated anew when the cache
+// cannot be read.
+func (h *ManifestHandler) serveCached(c *gin.Context, titleID, key string) bool {
+	entry, err := h.cache.Get(c.Request.Context(), titleID, key)
+	if err != nil {
+		metrics.ManifestCacheLookups.WithLabelValues("error").Inc()
+		logrus.WithError(err).WithField("title_id", titleID).Warn("Failed to read manifest cache")
+		return false
+	}
+	if entry == nil {
+		metrics.ManifestCacheLookups.WithLabelValues("miss").Inc()
+		return false
+	}
+	metrics.ManifestCacheLookups.WithLabelValues("hit").Inc()
+	c.Header("X-Inscenium-Cache", "hit")
+	writePlaylist(c, entry)
+	return true
+}
+
+// writePlaylist sends a decorated media playlist tagged with an ETag of its
+// bytes, or 304 Not Modified to clients holding it already. Bookings can
+// change at any time, so clients must revalidate before reusing it.
+func writePlaylist(c *gin.Context, entry *manifestcache.Entry) {
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", entry.ETag)
+	c.Header("X-Inscenium-Placements", strconv.Itoa(len(entry.Placements)))
+	if strings.TrimPrefix(c.GetHeader("If-None-Match"), "W/") == entry.ETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, decorate.ContentTypeHLS, entry.Playlist)
+}
+
+// capture keeps a decorated playlist of a sampled session without delaying
+// the response
+func (h *ManifestHandler) capture(c *gin.Context, titleID string, playlist, decorated []byte, signaled int) {
+	capture := &manifestlog.Capture{
+		SessionID:   c.Query("session_id"),
+		TitleID:     titleID,
+		OrgID:       c.GetString("org_id"),
+		ContentType: decorate.ContentTypeHLS,
+		Placements:  signaled,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+		defer cancel()
+		if err := h.captures.Record(ctx, capture, playlist, decorated); err != nil {
+			logrus.WithError(err).WithField("title_id", titleID).Warn("Failed to capture decorated manifest")
+		}
+	}()
+}
+
+// Personalize handles GET /manifests/:title_id/personalized?viewer_id=,
+// decorating a media playlist for one viewer: placements of bookings the
+// viewer is capped on, or that are capped when consent is not true, are
+// left out (see decorate.Personalize). The playlist is fetched from url or
+// sent as the body, and program_start and session_id are read as by
+// Decorate. Each playlist served is recorded for attribution, returned in
+// the X-Inscenium-Serve-ID header. Master playlists are returned with their
+// renditions pointed back here for the same viewer.
+func (h *ManifestHandler) Personalize(c *gin.Context) {
+	if h.caps == nil || h.serves == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Personalized manifests are not enabled"})
+		return
+	}
+	titleID := c.Param("title_id")
+	viewer := decorate.Viewer{ID: strings.TrimSpace(c.Query("viewer_id"))}
+	if viewer.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "viewer_id is required"})
+		return
+	}
+	if value := c.Query("consent"); value != "" {
+		var err error
+		if viewer.Consent, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "consent must be true or false"})
+			return
+		}
+	}
+	programStart, ok := parseProgramStart(c)
+	if !ok {
+		return
+	}
+
+	playlist, ok := h.readPlaylist(c)
+	if !ok {
+		return
+	}
+	if decorate.IsMaster(playlist) {
+		base, ok := h.masterBase(c)
+		if !ok {
+			return
+		}
+		params := url.Values{
+			"viewer_id":     {viewer.ID},
+			"consent":       {strconv.FormatBool(viewer.Consent)},
+			"program_start": {programStart.UTC().Format(time.RFC3339Nano)},
+		}
+		if sessionID := c.Query("session_id"); sessionID != "" {
+			params.Set("session_id", sessionID)
+		}
+		h.proxyMaster(c, titleID, playlist, base, c.Request.URL.Path, params)
+		return
+	}
+
+	placements, ok := h.listPlacements(c, titleID)
+	if !ok {
+		return
+	}
+	allowed, filtered, err := decorate.Personalize(c.Request.Context(), placements, viewer, h.caps)
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to check viewer's frequency caps")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	decorated, signaled, err := decorate.HLS(playlist, allowed, programStart)
+	if errors.Is(err, decorate.ErrInvalidManifest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to decorate manifest")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	serve := &decorate.Serve{
+		ServeID:    decorate.NewServeID(),
+		TitleID:    titleID,
+		SessionID:  c.Query("session_id"),
+		OrgID:      c.GetString("org_id"),
+		Consent:    viewer.Consent,
+		BookingIDs: signaled,
+		Filtered:   filtered,
+		ServedAt:   time.Now().UTC(),
+	}
+	if viewer.Consent {
+		serve.ViewerID = viewer.ID
+	}
+	// The playlist is served even if the serve cannot be recorded
+	if err := h.serves.CreateManifestServe(serve); err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to record manifest serve")
+	} else {
+		c.Header("X-Inscenium-Serve-ID", serve.ServeID)
+	}
+
+	if h.captures.Sampled(serve.SessionID) {
+		h.capture(c, titleID, playlist, decorated, len(signaled))
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Inscenium-Placements", strconv.Itoa(len(signaled)))
+	c.Header("X-Inscenium-Filtered", strconv.Itoa(len(filtered)))
+	c.Data(http.StatusOK, decorate.ContentTypeHLS, decorated)
+}
+
+// decorateMaster handles master playlists. By default the playlist is
+// returned with each rendition pointed at the decorating proxy for the
+// same title, program start and session, so every bitrate, audio and
+// I-frame playlist a player picks is decorated alike. With
+// renditions=decorate the gateway fetches every rendition itself and
+// returns them decorated with the placements all of them can signal.
+// Relative rendition URIs are resolved against url, or base_url for a
+// playlist sent as the body.
+func (h *ManifestHandler) decorateMaster(c *gin.Context, titleID string, programStart time.Time, playlist []byte) {
+	base, ok := h.masterBase(c)
+	if !ok {
+		return
+	}
+
+	switch mode := c.DefaultQuery("renditions", "proxy"); mode {
+	case "proxy":
+		params := url.Values{"title_id": {titleID}, "program_start": {programStart.UTC().Format(time.RFC3339Nano)}}
+		if sessionID := c.Query("session_id"); sessionID != "" {
+			params.Set("session_id", sessionID)
+		}
+		h.proxyMaster(c, titleID, playlist, base, h.proxyURL, params)
+	case "decorate":
+		h.decorateRenditions(c, titleID, programStart, playlist, base)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "renditions must be proxy or decorate"})
+	}
+}
+
+// masterBase returns the URL a master playlist's relative rendition URIs
+// are resolved against, reporting a bad request when renditions cannot be
+// fetched
+func (h *ManifestHandler) masterBase(c *gin.Context) (*url.URL, bool) {
+	if !h.fetcher.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fetching manifests is not enabled, decorate each media playlist"})
+		return nil, false
+	}
+	rawBase := c.Query("url")
+	if rawBase == "" {
+		rawBase = c.Query("base_url")
+	}
+	if rawBase == "" {
+		return nil, true
+	}
+	base, err := url.Parse(rawBase)
+	if err != nil || !base.IsAbs() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "base_url must be an absolute URL"})
+		return nil, false
+	}
+	return base, true
+}
+
+// proxyMaster returns a master playlist with each rendition pointed at
+// proxyURL with params
+func (h *ManifestHandler) proxyMaster(c *gin.Context, titleID string, playlist []byte, base *url.URL, proxyURL string, params url.Values) {
+	rewritten, renditions, err := decorate.Proxy(playlist, base, proxyURL, params)
+	if errors.Is(err, decorate.ErrInvalidManifest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to rewrite master playlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.Header("X-Inscenium-Renditions", strconv.Itoa(renditions))
+	c.Data(http.StatusOK, decorate.ContentTypeHLS, rewritten)
+}
+
+// decorateRenditions fetches and decorates every rendition of a master
+// playlist with the same placements
+func (h *ManifestHandler) decorateRenditions(c *gin.Context, titleID string, programStart time.Time, playlist []byte, base *url.URL) {
+	_, renditions, err := decorate.Renditions(playlist, base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	playlists := make([][]byte, 0, len(renditions))
+	for _, r := range renditions {
+		media, err := h.fetcher.Fetch(c.Request.Context(), r.URL)
+		switch {
+		case errors.Is(err, decorate.ErrHostNotAllowed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logrus.WithError(err).WithField("url", r.URL).Warn("Failed to fetch rendition")
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		playlists = append(playlists, media)
+	}
+
+	placements, err := h.db.ListManifestPlacements(authz.Scope(c), titleID)
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to list manifest placements")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	decorated, signaled, err := decorate.HLSRenditions(playlists, placements, programStart)
+	if errors.Is(err, decorate.ErrInvalidManifest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Error("Failed to decorate renditions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	results := make([]gin.H, 0, len(renditions))
+	for i, r := range renditions {
+		results = append(results, gin.H{
+			"kind":      r.Kind,
+			"uri":       r.URI,
+			"url":       r.URL,
+			"bandwidth": r.Bandwidth,
+			"playlist":  string(decorated[i]),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"placement_ids": signaled, "renditions": results})
+}
+
+// parseProgramStart reads the program_start query parameter, the Unix
+// epoch when unset, reporting a bad request when it is invalid
+func parseProgramStart(c *gin.Context) (time.Time, bool) {
+	value := c.Query("program_start")
+	if value == "" {
+		return time.Unix(0, 0).UTC(), true
+	}
+	programStart, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "program_start must be an RFC 3339 time"})
+		return time.Time{}, false
+	}
+	return programStart, true
+}
+
+// readPlaylist returns the playlist sent in the body or fetched from url
+func (h *ManifestHandler) readPlaylist(c *gin.Context) ([]byte, bool) {
+	if rawURL := c.Query("url"); rawURL != "" {
+		if !h.fetcher.Enabled() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fetching manifests is not enabled, send the playlist as the body"})
+			return nil, false
+		}
+		playlist, err := h.fetcher.Fetch(c.Request.Context(), rawURL)
+		switch {
+		case errors.Is(err, decorate.ErrHostNotAllowed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		case err != nil:
+			logrus.WithError(err).WithField("url", rawURL).Warn("Failed to fetch manifest")
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return playlist, true
+	}
+
+	playlist, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, decorate.MaxManifestBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Manifest is too large"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read manifest"})
+		return nil, false
+	}
+	if len(playlist) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the playlist as the body, or its url"})
+		return nil, false
+	}
+	return playlist, true
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/inscenium/inscenium/control/api/internal/manifestcache"
+	"github.com/inscenium/inscenium/control/api/internal/manifestlog"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/edge/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockManifestStore struct {
+	placements map[string][]decorate.Placement
+}
+
+func (m *MockManifestStore) ListManifestPlacements(scope tenant.Scope, titleID string) ([]decorate.Placement, error) {
+	return m.placements[titleID], nil
+}
+
+const decoratePlaylist = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:6
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXTINF:6.006,
+segment_000.ts
+#EXTINF:6.006,
+segment_001.ts
+#EXTINF:4.0,
+segment_002.ts
+#EXT-X-ENDLIST
+`
+
+const decorateMaster = `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,AUDIO="aac"
+low.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AUDIO="aac"
+high.m3u8
+`
+
+func TestManifestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockManifestStore{placements: map[string][]decorate.Placement{
+		"42": {
+			{BookingID: "booking_1", SurfaceID: "surface_1", SurfaceType: "wall", PRSScore: 87.5, Start: 7.5, End: 12.5},
+			{BookingID: "booking_2", SurfaceID: "surface_2", SurfaceType: "screen", PRSScore: 91, Start: 20, End: 24},
+		},
+	}}
+	captureStore := &MockManifestCaptureStore{captures: map[string]*manifestlog.Capture{}, now: time.Now()}
+	files, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vod/42.m3u8", "/vod/42/low.m3u8", "/vod/42/audio/en.m3u8":
+			w.Header().Set("Content-Type", decorate.ContentTypeHLS)
+			w.Write([]byte(decoratePlaylist))
+		case "/vod/42/high.m3u8":
+			// A shorter rendition, which cannot signal booking_1
+			w.Write([]byte("#EXTM3U\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:6.0,\nhigh_000.ts\n#EXT-X-ENDLIST\n"))
+		case "/vod/42/master.m3u8":
+			w.Write([]byte(decorateMaster))
+		case "/moved.m3u8":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer origin.Close()
+
+	handler := NewManifestHandler(store, decorate.NewFetcher([]string{"127.0.0.1"}),
+		manifestlog.NewRecorder(manifestlog.Config{Percent: 100, TTL: time.Hour}, captureStore, files))
+	router := gin.New()
+	router.POST("/manifests/decorate", handler.Decorate)
+	router.GET("/manifests/decorate", handler.Decorate)
+	post := func(query, body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/manifests/decorate?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", decorate.ContentTypeHLS)
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("body", func(t *testing.T) {
+		resp := post("title_id=42&session_id=session_1", decoratePlaylist)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Equal(t, decorate.ContentTypeHLS, resp.Header().Get("Content-Type"))
+		assert.Equal(t, "1", resp.Header().Get("X-Inscenium-Placements"), "Should leave out placements past the end")
+
+		body := resp.Body.String()
+		assert.Contains(t, body, `#EXT-X-DATERANGE:ID="booking_1",START-DATE="1970-01-01T00:00:07.5Z",DURATION=5,X-INSCENIUM-SURFACE-ID="surface_1",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="wall"`+"\n#EXTINF:6.006,\nsegment_001.ts")
+		assert.NotContains(t, body, "booking_2")
+
+		placements, err := manifest.ExtractDateRangeMetadata(body)
+		require.NoError(t, err)
+		require.Len(t, placements, 1)
+		assert.Equal(t, "surface_1", placements[0].SurfaceID)
+
+		require.Eventually(t, func() bool {
+			captures, _ := captureStore.ListManifestCaptures(manifestlog.Query{SessionID: "session_1", Limit: 10})
+			return len(captures) == 1 && captures[0].Placements == 1
+		}, time.Second, 10*time.Millisecond, "Should capture sampled sessions")
+	})
+
+	t.Run("program start", func(t *testing.T) {
+		resp := post("title_id=42&program_start=2026-03-14T20:00:00Z", decoratePlaylist)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `START-DATE="2026-03-14T20:00:07.5Z"`)
+
+		live := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:310\n#EXT-X-PROGRAM-DATE-TIME:2026-03-14T20:00:06Z\n" +
+			"#EXTINF:6.0,\nlive_310.ts\n#EXTINF:6.0,\nlive_311.ts\n#EXTINF:6.0,\nlive_312.ts\n"
+		resp = post("title_id=42&program_start=2026-03-14T20:00:00Z", live)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("X-Inscenium-Placements"), "Should place live segments by their date")
+		assert.Contains(t, resp.Body.String(), `X-INSCENIUM-PLACEMENT-TYPE="screen"`+"\n#EXTINF:6.0,\nlive_312.ts")
+
+		resp = post("title_id=7", decoratePlaylist)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, decoratePlaylist, resp.Body.String(), "Titles without bookings are returned unchanged")
+		assert.Equal(t, "0", resp.Header().Get("X-Inscenium-Placements"))
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		resp := post("title_id=42&url="+origin.URL+"/vod/42.m3u8", "")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), `ID="booking_1"`)
+
+		assert.Equal(t, http.StatusBadGateway, post("title_id=42&url="+origin.URL+"/missing.m3u8", "").Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&url=http://localhost:1/internal", "").Code,
+			"Should only fetch from allowed hosts")
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&url="+origin.URL+"/moved.m3u8", "").Code,
+			"Should not follow redirects to other hosts")
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&url=file:///etc/passwd", "").Code)
+
+		closed := NewManifestHandler(store, decorate.NewFetcher(nil), nil)
+		router := gin.New()
+		router.POST("/manifests/decorate", closed.Decorate)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/manifests/decorate?title_id=42&url="+origin.URL+"/vod/42.m3u8", nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "Should not fetch without allowed hosts")
+	})
+
+	t.Run("master", func(t *testing.T) {
+		master := origin.URL + "/vod/42/master.m3u8"
+		handler.SetProxyURL("/manifests/decorate")
+		resp := post("title_id=42&session_id=session_2&program_start=2026-03-14T20:00:00Z&url="+url.QueryEscape(master), "")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Equal(t, "3", resp.Header().Get("X-Inscenium-Renditions"))
+		body := resp.Body.String()
+		low := "/manifests/decorate?program_start=2026-03-14T20%3A00%3A00Z&session_id=session_2&title_id=42&url=" + url.QueryEscape(origin.URL+"/vod/42/low.m3u8")
+		assert.Contains(t, body, "\n"+low+"\n", "Should point variants at the proxy")
+		assert.Contains(t, body, `URI="/manifests/decorate?`, "Should point audio renditions at the proxy")
+
+		// Players follow the rewritten URIs
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, low, nil))
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), `ID="booking_1",START-DATE="2026-03-14T20:00:07.5Z"`)
+
+		resp = post("title_id=42&base_url="+url.QueryEscape(master), decorateMaster)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), url.QueryEscape(origin.URL+"/vod/42/high.m3u8"), "Should resolve URIs against base_url")
+
+		resp = post("title_id=42&renditions=decorate&url="+url.QueryEscape(master), "")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var result struct {
+			PlacementIDs []string `json:"placement_ids"`
+			Renditions   []struct {
+				Kind     string `json:"kind"`
+				URI      string `json:"uri"`
+				Playlist string `json:"playlist"`
+			} `json:"renditions"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		assert.Empty(t, result.PlacementIDs, "Should only signal placements every rendition can")
+		require.Len(t, result.Renditions, 3)
+		assert.Equal(t, "high.m3u8", result.Renditions[2].URI)
+		for _, r := range result.Renditions {
+			assert.NotContains(t, r.Playlist, "booking_1")
+		}
+
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&renditions=all&url="+url.QueryEscape(master), "").Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&base_url=/vod/42/", decorateMaster).Code)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("", decoratePlaylist).Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42", "").Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42&program_start=yesterday", decoratePlaylist).Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42", "<MPD/>").Code)
+		assert.Equal(t, http.StatusBadRequest, post("title_id=42", "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n").Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post("title_id=42", "#EXTM3U\n"+strings.Repeat("#", decorate.MaxManifestBytes)).Code)
+	})
+}
+
+type MockManifestServeStore struct {
+	serves []*decorate.Serve
+}
+
+func (m *MockManifestServeStore) CreateManifestServe(serve *decorate.Serve) error {
+	m.serves = append(m.serves, serve)
+	return nil
+}
+
+func TestManifestHandler_Personalize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockManifestStore{placements: map[string][]decorate.Placement{
+		"42": {
+			{BookingID: "booking_a", SurfaceID: "surface_1", Start: 1, End: 3},
+			{BookingID: "booking_b", SurfaceID: "surface_2", Start: 5, End: 7},
+			{BookingID: "booking_c", SurfaceID: "surface_3", Start: 9, End: 11},
+		},
+	}}
+	frequency := &MockFrequencyStore{
+		campaigns: map[string]*freqcap.Cap{"camp_capped": {MaxExposures: 3, WindowHours: 24}, "camp_uncapped": nil},
+		bookings:  map[string]string{"booking_a": "camp_capped", "booking_b": "camp_capped", "booking_c": "camp_uncapped"},
+		caps:      map[string]*freqcap.Cap{"booking_b": {MaxExposures: 1, WindowHours: 1}},
+	}
+	enforcer := freqcap.NewEnforcer(frequency, freqcap.NewMemoryCounter())
+	require.NoError(t, enforcer.RecordExposure(context.Background(), "viewer_1", "booking_b"))
+	serves := &MockManifestServeStore{}
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(decorateMaster))
+	}))
+	defer origin.Close()
+
+	handler := NewManifestHandler(store, decorate.NewFetcher([]string{"127.0.0.1"}), nil)
+	router := gin.New()
+	router.GET("/manifests/:title_id/personalized", handler.Personalize)
+	get := func(query, body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/manifests/42/personalized?"+query, strings.NewReader(body)))
+		return resp
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get("viewer_id=viewer_1", decoratePlaylist).Code, "Should need frequency caps")
+	handler.SetPersonalization(enforcer, serves)
+
+	resp := get("viewer_id=viewer_1&consent=true&session_id=session_1", decoratePlaylist)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "2", resp.Header().Get("X-Inscenium-Placements"))
+	assert.Equal(t, "1", resp.Header().Get("X-Inscenium-Filtered"))
+	assert.Equal(t, "private, no-store", resp.Header().Get("Cache-Control"))
+	body := resp.Body.String()
+	assert.Contains(t, body, `ID="booking_a"`)
+	assert.NotContains(t, body, `ID="booking_b"`, "Should leave out bookings the viewer is capped on")
+	assert.Contains(t, body, `ID="booking_c"`)
+
+	require.Len(t, serves.serves, 1)
+	serve := serves.serves[0]
+	assert.Equal(t, serve.ServeID, resp.Header().Get("X-Inscenium-Serve-ID"))
+	assert.Equal(t, "viewer_1", serve.ViewerID)
+	assert.Equal(t, "session_1", serve.SessionID)
+	assert.Equal(t, []string{"booking_a", "booking_c"}, serve.BookingIDs)
+	assert.Equal(t, map[string]string{"booking_b": decorate.FilteredFrequencyCap}, serve.Filtered)
+
+	resp = get("viewer_id=viewer_2", decoratePlaylist)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "1", resp.Header().Get("X-Inscenium-Placements"), "Should only signal uncapped bookings without consent")
+	assert.Contains(t, resp.Body.String(), `ID="booking_c"`)
+	serve = serves.serves[1]
+	assert.Empty(t, serve.ViewerID, "Should not keep viewers without consent")
+	assert.False(t, serve.Consent)
+	assert.Equal(t, decorate.FilteredConsent, serve.Filtered["booking_a"])
+
+	resp = get("viewer_id=viewer_1&consent=true&url="+url.QueryEscape(origin.URL+"/vod/42/master.m3u8"), "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), "/manifests/42/personalized?consent=true&program_start=1970-01-01T00%3A00%3A00Z&url=",
+		"Should point renditions back at the viewer's playlist")
+	assert.Contains(t, resp.Body.String(), "&viewer_id=viewer_1\n")
+	assert.Len(t, serves.serves, 2, "Should only record media playlists")
+
+	assert.Equal(t, http.StatusBadRequest, get("", decoratePlaylist).Code)
+	assert.Equal(t, http.StatusBadRequest, get("viewer_id=viewer_1&consent=maybe", decoratePlaylist).Code)
+	assert.Equal(t, http.StatusBadRequest, get("viewer_id=viewer_1&program_start=yesterday", decoratePlaylist).Code)
+}
+
+func TestManifestHandler_Cache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockManifestStore{placements: map[string][]decorate.Placement{
+		"42": {{BookingID: "booking_1", SurfaceID: "surface_1", Start: 7.5, End: 12.5}},
+	}}
+	fetches := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(decoratePlaylist))
+	}))
+	defer origin.Close()
+
+	cache := manifestcache.NewMemoryCache(time.Minute)
+	handler := NewManifestHandler(store, decorate.NewFetcher([]string{"127.0.0.1"}), nil)
+	handler.SetCache(cache)
+	router := gin.New()
+	router.GET("/manifests/decorate", handler.Decorate)
+	router.POST("/manifests/decorate", handler.Decorate)
+	get := func(etag string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/manifests/decorate?title_id=42&url="+url.QueryEscape(origin.URL+"/vod/42.m3u8"), nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get("")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	etag := resp.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "private, no-cache", resp.Header().Get("Cache-Control"))
+	assert.Empty(t, resp.Header().Get("X-Inscenium-Cache"))
+	decorated := resp.Body.String()
+
+	resp = get("")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "hit", resp.Header().Get("X-Inscenium-Cache"))
+	assert.Equal(t, decorated, resp.Body.String())
+	assert.Equal(t, etag, resp.Header().Get("ETag"))
+	assert.Equal(t, "1", resp.Header().Get("X-Inscenium-Placements"))
+	assert.Equal(t, 1, fetches, "Should not fetch cached playlists")
+
+	resp = get(etag)
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Empty(t, resp.Body.String())
+
+	store.placements["42"] = append(store.placements["42"], decorate.Placement{BookingID: "booking_2", SurfaceID: "surface_2", Start: 1, End: 2})
+	resp = get(etag)
+	require.Equal(t, http.StatusOK, resp.Code, "Should not serve playlists decorated with other bookings")
+	assert.Empty(t, resp.Header().Get("X-Inscenium-Cache"))
+	assert.NotEqual(t, etag, resp.Header().Get("ETag"))
+	assert.Contains(t, resp.Body.String(), `ID="booking_2"`)
+	assert.Equal(t, 2, fetches)
+
+	require.NoError(t, cache.Invalidate(context.Background(), "42"))
+	resp = get("")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("X-Inscenium-Cache"), "Should drop invalidated titles")
+	assert.Equal(t, 3, fetches)
+
+	live := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:310\n#EXTINF:6.0,\nlive_310.ts\n"
+	for i := 0; i < 2; i++ {
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/manifests/decorate?title_id=42", strings.NewReader(live)))
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("X-Inscenium-Cache"), "Should not cache live playlists")
+	}
+	for _, want := range []string{"", "hit"} {
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/manifests/decorate?title_id=42", strings.NewReader(decoratePlaylist)))
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, want, resp.Header().Get("X-Inscenium-Cache"), "Should cache playlists sent as the body by their contents")
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/mirror"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror_ReplaysScrubbedReads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	replays := make(chan *http.Request, 10)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replays <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer shadow.Close()
+
+	policy, err := pii.ParsePolicy(pii.DefaultFields, pii.ActionMask)
+	require.NoError(t, err)
+	m, err := mirror.New(mirror.Config{URL: shadow.URL + "/", Percent: 100, Token: "staging-token"}, policy)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	router := gin.New()
+	router.Use(middleware.Mirror(m))
+	router.GET("/api/v1/bookings", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"bookings": []string{}}) })
+	router.POST("/api/v1/bookings", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bookings?campaign_id=campaign_1&viewer_id=viewer_9&q=jane@example.com", nil)
+	req.Header.Set("Authorization", "Bearer production-token")
+	req.Header.Set("Cookie", "session=abc")
+	req.Header.Set("X-Request-ID", "req-1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"bookings":[]}`, resp.Body.String(), "Mirroring should not change the primary response")
+
+	var replay *http.Request
+	select {
+	case replay = <-replays:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The read request was not mirrored")
+	}
+	assert.Equal(t, "/api/v1/bookings", replay.URL.Path)
+	assert.Equal(t, "campaign_1", replay.URL.Query().Get("campaign_id"))
+	assert.Equal(t, pii.Mask("viewer_9"), replay.URL.Query().Get("viewer_id"), "Fields in the PII policy should be masked")
+	assert.Equal(t, pii.Mask("jane@example.com"), replay.URL.Query().Get("q"), "Personal data should be masked")
+	assert.Equal(t, "Bearer staging-token", replay.Header.Get("Authorization"), "Production credentials should be replaced")
+	assert.Empty(t, replay.Header.Get("Cookie"))
+	assert.Equal(t, "req-1", replay.Header.Get("X-Request-ID"))
+	assert.Equal(t, "true", replay.Header.Get(mirror.Header))
+
+	// Writes and replays are never mirrored
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/bookings", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/bookings", nil)
+	req.Header.Set(mirror.Header, "true")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case replay = <-replays:
+		t.Fatalf("Unexpected replay of %s %s", replay.Method, replay.URL)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestMirror_ShadowFailureLeavesPrimaryAlone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	shadow.Close() // Nothing listens on the shadow URL
+
+	m, err := mirror.New(mirror.Config{URL: shadow.URL, Percent: 100}, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	router := gin.New()
+	router.Use(middleware.Mirror(m))
+	router.GET("/api/v1/opportunities", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/opportunities", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+	}
+}
+
+func TestMirrorConfig_Validate(t *testing.T) {
+	assert.NoError(t, mirror.Config{URL: "https://staging.example.com", Percent: 5}.Validate())
+	assert.Error(t, mirror.Config{URL: "staging.example.com", Percent: 5}.Validate())
+	assert.Error(t, mirror.Config{URL: "https://staging.example.com", Percent: 0}.Validate())
+	assert.Error(t, mirror.Config{URL: "https://staging.example.com", Percent: 150}.Validate())
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/changefeed"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// OpportunitiesProtocol is the WebSocket subprotocol spoken on GET /ws/opportunities
+const OpportunitiesProtocol = "inscenium.opportunities.v1"
+
+// opportunitiesStream is the push stream of GET /ws/opportunities.
+// Clients that fell behind are told to resync like after missed
+// notifications.
+var opportunitiesStream = pushStream{
+	name:     "opportunities",
+	protocol: OpportunitiesProtocol,
+	resync:   func() interface{} { return availabilityMessage{Type: "resync", OccurredAt: time.Now().UTC()} },
+}
+
+// opportunityChangeBuffer is how many announced changes may wait to be
+// looked up before streams are told to resync instead
+const opportunityChangeBuffer = 1000
+
+// Availability changes
+const (
+	availabilityAvailable = "available" // A surface was added or changed and can be booked
+	availabilityWithdrawn = "withdrawn" // A surface was held back, merged or went stale
+	availabilityBooked    = "booked"    // A booking was made on a surface
+	availabilityReleased  = "released"  // A booking on a surface was cancelled, completed or expired
+)
+
+// OpportunityStreamStore reads what announced changes did to availability
+type OpportunityStreamStore interface {
+	GetSurfaceAvailability(surfaceID string) (*models.Availability, error)
+	GetBookingSurface(bookingID string) (surfaceID, status string, err error)
+}
+
+// availabilityMessage is a message to a client
+type availabilityMessage struct {
+	Type        string               `json:"type"` // availability, or resync when changes may have been missed
+	Change      string               `json:"change,omitempty"`
+	Opportunity *models.Availability `json:"opportunity,omitempty"`
+	OccurredAt  time.Time            `json:"occurred_at"`
+}
+
+// opportunityFilter selects the surfaces a stream is told about
+type opportunityFilter struct {
+	titleID      string
+	minPRS       float64
+	surfaceTypes map[string]bool // Empty for every type
+}
+
+// matches reports whether a surface passes the filter
+func (f opportunityFilter) matches(a *models.Availability) bool {
+	if f.titleID != "" && a.TitleID != f.titleID {
+		return false
+	}
+	if a.PRSScore < f.minPRS {
+		return false
+	}
+	return len(f.surfaceTypes) == 0 || f.surfaceTypes[a.SurfaceType]
+}
+
+// opportunityStream is one client connection
+type opportunityStream struct {
+	*pushConn
+	filter opportunityFilter
+}
+
+// OpportunityStreamHandler pushes changes in placement availability to
+// programmatic buyers over WebSocket. Changes are those Postgres announces,
+// so every gateway instance sees every change whichever one made it.
+type OpportunityStreamHandler struct {
+	db           OpportunityStreamStore
+	pingInterval time.Duration
+	changes      chan changefeed.Change // Nil until Listen
+
+	mu      sync.RWMutex
+	streams map[*opportunityStream]bool
+}
+
+// NewOpportunityStreamHandler creates an opportunity stream handler
+func NewOpportunityStreamHandler(store OpportunityStreamStore) *OpportunityStreamHandler {
+	return &OpportunityStreamHandler{
+		db:           store,
+		pingInterval: defaultPushPingInterval,
+		streams:      make(map[*opportunityStream]bool),
+	}
+}
+
+// SetPingInterval sets how often idle connections are pinged; clients that
+// answer nothing for two intervals are disconnected
+func (h *OpportunityStreamHandler) SetPingInterval(interval time.Duration) {
+	h.pingInterval = interval
+}
+
+// Listen streams the surface and booking changes announced to listener.
+// Without it no stream can be opened.
+func (h *OpportunityStreamHandler) Listen(listener *changefeed.Listener) {
+	h.changes = make(chan changefeed.Change, opportunityChangeBuffer)
+	handler := changefeed.Handler{
+		Changed: func(ctx context.Context, change changefeed.Change) {
+			select {
+			case h.changes <- change:
+			default:
+				// Looking changes up fell behind; clients reload rather than miss some
+				logrus.WithField("table", change.Table).Warn("Opportunity stream fell behind, asking clients to resync")
+				h.resync()
+			}
+		},
+		Resync: func(ctx context.Context) { h.resync() },
+	}
+	listener.Handle(changefeed.TableSurfaces, handler)
+	listener.Handle(changefeed.TableBookings, handler)
+	go h.announceChanges()
+}
+
+// Stream handles GET /ws/opportunities?title_id=&min_prs=&surface_type=, a
+// WebSocket connection receiving {"type": "availability", "change": ...,
+// "opportunity": ...} for each change of a matching surface.
+// surface_type may list several types separated by commas.
+func (h *OpportunityStreamHandler) Stream(c *gin.Context) {
+	if h.changes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Opportunity streams require change notifications"})
+		return
+	}
+
+	filter := opportunityFilter{titleID: c.Query("title_id")}
+	if value := c.Query("min_prs"); value != "" {
+		minPRS, err := strconv.ParseFloat(value, 64)
+		if err != nil || minPRS < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_prs parameter"})
+			return
+		}
+		filter.minPRS = minPRS
+	}
+	for _, surfaceType := range splitList(c.Query("surface_type")) {
+		if filter.surfaceTypes == nil {
+			filter.surfaceTypes = make(map[string]bool)
+		}
+		filter.surfaceTypes[surfaceType] = true
+	}
+
+	conn, ok := upgradePush(c, opportunitiesStream, h.pingInterval)
+	if !ok {
+		return
+	}
+	metrics.OpportunityStreams.Inc()
+	defer metrics.OpportunityStreams.Dec()
+
+	stream := &opportunityStream{pushConn: conn, filter: filter}
+	h.mu.Lock()
+	h.streams[stream] = true
+	h.mu.Unlock()
+
+	// Clients send nothing but pongs and the closing handshake
+	for {
+		if _, _, err := conn.conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	h.mu.Lock()
+	delete(h.streams, stream)
+	h.mu.Unlock()
+	stream.finish()
+}
+
+// announceChanges looks up what each announced change did to availability
+// and tells the streams it matches
+func (h *OpportunityStreamHandler) announceChanges() {
+	for change := range h.changes {
+		h.mu.RLock()
+		listening := len(h.streams) > 0
+		h.mu.RUnlock()
+		if !listening {
+			continue
+		}
+
+		kind, opportunity, err := h.availabilityChange(change)
+		if err != nil {
+			logrus.WithError(err).WithField("id", change.ID).Warn("Failed to look up availability change")
+			continue
+		}
+		if opportunity == nil {
+			continue
+		}
+		h.broadcast(availabilityMessage{Type: "availability", Change: kind, Opportunity: opportunity, OccurredAt: time.Now().UTC()})
+	}
+}
+
+// availabilityChange works out what a change did to a surface's
+// availability. A nil opportunity means it did nothing worth announcing.
+func (h *OpportunityStreamHandler) availabilityChange(change changefeed.Change) (string, *models.Availability, error) {
+	surfaceID := change.ID
+	var kind string
+	switch change.Table {
+	case changefeed.TableSurfaces:
+		if change.Op == "delete" {
+			return "", nil, nil // Surfaces are withdrawn before they are ever deleted
+		}
+	case changefeed.TableBookings:
+		var status string
+		var err error
+		surfaceID, status, err = h.db.GetBookingSurface(change.ID)
+		if err != nil || surfaceID == "" {
+			return "", nil, err
+		}
+		switch {
+		case change.Op == "insert":
+			kind = availabilityBooked
+		case change.Op == "update" && booking.Terminal(status):
+			kind = availabilityReleased
+		default:
+			return "", nil, nil // Amendments and approvals leave availability alone
+		}
+	default:
+		return "", nil, nil
+	}
+
+	opportunity, err := h.db.GetSurfaceAvailability(surfaceID)
+	if err != nil || opportunity == nil {
+		return "", nil, err
+	}
+	if kind == "" {
+		kind = availabilityAvailable
+		if !opportunity.Available {
+			kind = availabilityWithdrawn
+		}
+	}
+	return kind, opportunity, nil
+}
+
+// broadcast sends an availability change to the streams it matches
+func (h *OpportunityStreamHandler) broadcast(message availabilityMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sent := false
+	for stream := range h.streams {
+		if stream.filter.matches(message.Opportunity) {
+			stream.enqueue(message)
+			sent = true
+		}
+	}
+	if sent {
+		metrics.OpportunityAnnouncements.WithLabelValues(message.Change).Inc()
+	}
+}
+
+// resync tells every stream that changes may have been missed, so clients
+// reload opportunities from GET /opportunities
+func (h *OpportunityStreamHandler) resync() {
+	message := opportunitiesStream.resync()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for stream := range h.streams {
+		stream.enqueue(message)
+	}
+}
+
+// splitList splits a comma-separated query parameter, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
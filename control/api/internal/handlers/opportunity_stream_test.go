@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/changefeed"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOpportunityStreamStore struct {
+	mu       sync.Mutex
+	surfaces map[string]*models.Availability
+	bookings map[string][2]string // booking_id -> surface_id, status
+}
+
+func (m *MockOpportunityStreamStore) GetSurfaceAvailability(surfaceID string) (*models.Availability, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.surfaces[surfaceID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (m *MockOpportunityStreamStore) GetBookingSurface(bookingID string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.bookings[bookingID]
+	return b[0], b[1], nil
+}
+
+func TestOpportunityStreamHandler_Stream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockOpportunityStreamStore{
+		surfaces: map[string]*models.Availability{
+			"surface_hi":    {SurfaceID: "surface_hi", TitleID: "title_1", SurfaceType: "billboard", PRSScore: 0.9, Available: true},
+			"surface_lo":    {SurfaceID: "surface_lo", TitleID: "title_1", SurfaceType: "billboard", PRSScore: 0.2, Available: true},
+			"surface_other": {SurfaceID: "surface_other", TitleID: "title_2", SurfaceType: "billboard", PRSScore: 0.9, Available: true},
+			"surface_wall":  {SurfaceID: "surface_wall", TitleID: "title_1", SurfaceType: "wall", PRSScore: 0.95, Available: false},
+		},
+		bookings: map[string][2]string{
+			"booking_1": {"surface_hi", "pending"},
+		},
+	}
+	handler := NewOpportunityStreamHandler(store)
+
+	router := gin.New()
+	router.GET("/ws/opportunities", handler.Stream)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/opportunities"
+
+	_, resp, err := websocket.Dial(url, nil, []string{OpportunitiesProtocol})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "Should need change notifications")
+
+	// Listen without a database: changes are fed in below as the listener would
+	handler.Listen(changefeed.NewListener(""))
+
+	_, resp, err = websocket.Dial(url+"?min_prs=high", nil, []string{OpportunitiesProtocol})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	conn, _, err := websocket.Dial(url+"?title_id=title_1&min_prs=0.5&surface_type=billboard,wall", nil, []string{OpportunitiesProtocol})
+	require.NoError(t, err)
+	defer conn.Close(websocket.CloseNormal, "")
+	assert.Equal(t, OpportunitiesProtocol, conn.Subprotocol)
+	require.Eventually(t, func() bool {
+		handler.mu.RLock()
+		defer handler.mu.RUnlock()
+		return len(handler.streams) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Filtered out by title and PRS, then a new surface and a withdrawn one
+	handler.changes <- changefeed.Change{Table: changefeed.TableSurfaces, Op: "insert", ID: "surface_other"}
+	handler.changes <- changefeed.Change{Table: changefeed.TableSurfaces, Op: "insert", ID: "surface_lo"}
+	handler.changes <- changefeed.Change{Table: changefeed.TableSurfaces, Op: "insert", ID: "surface_hi"}
+	message := receiveAvailability(t, conn)
+	assert.Equal(t, "availability", message.Type)
+	assert.Equal(t, "available", message.Change)
+	assert.Equal(t, "surface_hi", message.Opportunity.SurfaceID)
+
+	handler.changes <- changefeed.Change{Table: changefeed.TableSurfaces, Op: "update", ID: "surface_wall"}
+	message = receiveAvailability(t, conn)
+	assert.Equal(t, "withdrawn", message.Change, "Should announce surfaces no longer listed")
+	assert.Equal(t, "surface_wall", message.Opportunity.SurfaceID)
+
+	// Bookings announce their surface
+	store.mu.Lock()
+	store.surfaces["surface_hi"].Booked = true
+	store.mu.Unlock()
+	handler.changes <- changefeed.Change{Table: changefeed.TableBookings, Op: "insert", ID: "booking_1"}
+	message = receiveAvailability(t, conn)
+	assert.Equal(t, "booked", message.Change)
+	assert.True(t, message.Opportunity.Booked)
+
+	// An approval leaves availability alone, so the surface's next change comes first
+	handler.changes <- changefeed.Change{Table: changefeed.TableBookings, Op: "update", ID: "booking_1"}
+	handler.changes <- changefeed.Change{Table: changefeed.TableSurfaces, Op: "update", ID: "surface_hi"}
+	message = receiveAvailability(t, conn)
+	assert.Equal(t, "available", message.Change, "Should skip booking changes that leave availability alone")
+
+	store.mu.Lock()
+	store.bookings["booking_1"] = [2]string{"surface_hi", "cancelled"}
+	store.surfaces["surface_hi"].Booked = false
+	store.mu.Unlock()
+	handler.changes <- changefeed.Change{Table: changefeed.TableBookings, Op: "update", ID: "booking_1"}
+	message = receiveAvailability(t, conn)
+	assert.Equal(t, "released", message.Change)
+	assert.False(t, message.Opportunity.Booked)
+
+	handler.resync()
+	message = receiveAvailability(t, conn)
+	assert.Equal(t, "resync", message.Type)
+}
+
+// receiveAvailability reads the next message of an opportunity stream
+func receiveAvailability(t *testing.T, conn *websocket.Conn) availabilityMessage {
+	t.Helper()
+	done := make(chan []byte, 1)
+	go func() {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			data = nil
+		}
+		done <- data
+	}()
+	var message availabilityMessage
+	select {
+	case data := <-done:
+		require.NotNil(t, data, "Stream closed")
+		require.NoError(t, json.Unmarshal(data, &message))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an availability change")
+	}
+	return message
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/optimizer"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// OptimizerStore persists titles' retention curves and lists the surfaces
+// a plan may recommend
+type OptimizerStore interface {
+	GetTitleRetention(titleID string) (*optimizer.Curve, error)
+	SetTitleRetention(curve *optimizer.Curve) error
+	ListPlanCandidates(titleID string) ([]optimizer.Candidate, error)
+}
+
+// OptimizerHandler keeps titles' retention curves and recommends the
+// placement windows to book with a budget
+type OptimizerHandler struct {
+	db OptimizerStore
+}
+
+// NewOptimizerHandler creates an optimizer handler
+func NewOptimizerHandler(store OptimizerStore) *OptimizerHandler {
+	return &OptimizerHandler{db: store}
+}
+
+// retentionRequest is the body of PUT /titles/:title_id/retention
+type retentionRequest struct {
+	Starts      int64             `json:"starts"`
+	Points      []optimizer.Point `json:"points"`
+	PeriodStart *time.Time        `json:"period_start"`
+	PeriodEnd   *time.Time        `json:"period_end"`
+}
+
+// GetRetention handles GET /titles/:title_id/retention
+func (h *OptimizerHandler) GetRetention(c *gin.Context) {
+	curve, err := h.db.GetTitleRetention(c.Param("title_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get title retention")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if curve == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title has no retention curve"})
+		return
+	}
+
+	c.JSON(http.StatusOK, curve)
+}
+
+// SetRetention handles PUT /titles/:title_id/retention
+func (h *OptimizerHandler) SetRetention(c *gin.Context) {
+	var req retentionRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	curve := &optimizer.Curve{
+		TitleID:     c.Param("title_id"),
+		Starts:      req.Starts,
+		Points:      req.Points,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		UpdatedBy:   c.GetString("user_id"),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if err := curve.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.SetTitleRetention(curve)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save title retention")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "title_retention",
+		"title_id": curve.TitleID,
+		"user_id":  curve.UpdatedBy,
+		"org_id":   c.GetString("org_id"),
+		"starts":   curve.Starts,
+		"points":   len(curve.Points),
+	}).Info("Set title retention")
+
+	c.JSON(http.StatusOK, curve)
+}
+
+// Plan handles POST /titles/:title_id/placement-plan. It recommends the
+// title's bookable windows that buy the most qualified impressions for the
+// budget, projected from its retention curve.
+func (h *OptimizerHandler) Plan(c *gin.Context) {
+	var req optimizer.Request
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	titleID := c.Param("title_id")
+	curve, err := h.db.GetTitleRetention(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get title retention")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if curve == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": optimizer.ErrNoRetention.Error()})
+		return
+	}
+
+	candidates, err := h.db.ListPlanCandidates(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list plan candidates")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, optimizer.Optimize(curve, candidates, req))
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/optimizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOptimizerStore struct {
+	curves     map[string]*optimizer.Curve
+	candidates map[string][]optimizer.Candidate
+}
+
+func (m *MockOptimizerStore) GetTitleRetention(titleID string) (*optimizer.Curve, error) {
+	return m.curves[titleID], nil
+}
+
+func (m *MockOptimizerStore) SetTitleRetention(curve *optimizer.Curve) error {
+	if curve.TitleID == "missing" {
+		return db.ErrTitleNotFound
+	}
+	m.curves[curve.TitleID] = curve
+	return nil
+}
+
+func (m *MockOptimizerStore) ListPlanCandidates(titleID string) ([]optimizer.Candidate, error) {
+	return m.candidates[titleID], nil
+}
+
+func TestOptimizerHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockOptimizerStore{
+		curves: map[string]*optimizer.Curve{},
+		candidates: map[string][]optimizer.Candidate{"42": {
+			{SurfaceID: "surf_low_prs", StartTime: 30, EndTime: 40, PRSScore: 50, PlacementType: "visual"},
+			{SurfaceID: "surf_early", StartTime: 60, EndTime: 70, PRSScore: 80, PlacementType: "visual", SurfaceType: "wall"},
+			{SurfaceID: "surf_brief", StartTime: 100, EndTime: 101, PRSScore: 90, PlacementType: "visual"},
+			{SurfaceID: "surf_audio", StartTime: 1200, EndTime: 1230, PRSScore: 90, PlacementType: "audio"},
+			{SurfaceID: "surf_late", StartTime: 3000, EndTime: 3010, PRSScore: 85, PlacementType: "visual"},
+		}},
+	}
+	handler := NewOptimizerHandler(store)
+	router := gin.New()
+	router.GET("/titles/:title_id/retention", handler.GetRetention)
+	router.PUT("/titles/:title_id/retention", handler.SetRetention)
+	router.POST("/titles/:title_id/placement-plan", handler.Plan)
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	plan := func(body gin.H) optimizer.Plan {
+		resp := send(http.MethodPost, "/titles/42/placement-plan", body)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var p optimizer.Plan
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &p))
+		return p
+	}
+
+	t.Run("retention", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/titles/42/placement-plan", gin.H{"budget": 100, "bid_amount_cpm": 10}).Code,
+			"Should not plan without a retention curve")
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/titles/42/retention", nil).Code)
+
+		// 100,000 sessions, 60% still watching after 10 minutes and 30% at the hour
+		curve := gin.H{"starts": 100000, "points": []gin.H{{"position": 0, "retention": 1}, {"position": 600, "retention": 0.6}, {"position": 3600, "retention": 0.3}}}
+		resp := send(http.MethodPut, "/titles/42/retention", curve)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		assert.Equal(t, http.StatusOK, send(http.MethodGet, "/titles/42/retention", nil).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodPut, "/titles/missing/retention", curve).Code)
+
+		for _, points := range [][]gin.H{
+			{},
+			{{"position": 10, "retention": 1}},
+			{{"position": 0, "retention": 1}, {"position": 0, "retention": 0.5}},
+			{{"position": 0, "retention": 1.5}},
+		} {
+			assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/titles/42/retention", gin.H{"starts": 10, "points": points}).Code, "%v", points)
+		}
+		assert.Equal(t, 0.8, store.curves["42"].At(300), "Should interpolate between points")
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		p := plan(gin.H{"budget": 1200, "bid_amount_cpm": 10})
+		require.Len(t, p.Windows, 2)
+		early, audio := p.Windows[0], p.Windows[1]
+		assert.Equal(t, "surf_early", early.SurfaceID, "Should take the window most viewers reach first")
+		assert.EqualValues(t, 96000, early.ProjectedImpressions)
+		assert.EqualValues(t, 95866, early.ProjectedQualified)
+		assert.Equal(t, 960.0, early.Cost)
+		assert.Zero(t, early.MaxImpressions)
+
+		assert.Equal(t, "surf_audio", audio.SurfaceID, "Should qualify audio slots at half their length")
+		assert.Equal(t, 240.0, audio.Cost, "Should cap the last window to the budget left")
+		assert.Equal(t, 24000, audio.MaxImpressions)
+		assert.Equal(t, 1200.0, p.Spend)
+		assert.EqualValues(t, 100000, p.RetentionStarts)
+		assert.Equal(t, optimizer.DefaultMinPRSScore, p.Request.MinPRSScore)
+
+		p = plan(gin.H{"budget": 5000, "bid_amount_cpm": 10, "max_windows": 1})
+		require.Len(t, p.Windows, 1)
+		assert.Equal(t, 960.0, p.Spend, "Should not spread the budget past max_windows")
+
+		p = plan(gin.H{"budget": 5000, "bid_amount_cpm": 10, "min_prs_score": 40, "billing_model": "vcpm"})
+		ids := []string{}
+		for _, w := range p.Windows {
+			ids = append(ids, w.SurfaceID)
+		}
+		assert.Equal(t, []string{"surf_low_prs", "surf_early", "surf_audio", "surf_late"}, ids, "Should leave out windows too brief to qualify")
+		assert.InDelta(t, float64(p.ProjectedQualified)*10/1000, p.Spend, 0.05, "vCPM charges qualified impressions")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, body := range []gin.H{
+			{"bid_amount_cpm": 10},
+			{"budget": 100},
+			{"budget": 100, "bid_amount_cpm": 10, "billing_model": "attention_cpm"},
+			{"budget": 100, "bid_amount_cpm": 10, "max_windows": optimizer.MaxWindows + 1},
+		} {
+			assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/titles/42/placement-plan", body).Code, "%v", body)
+		}
+	})
+}
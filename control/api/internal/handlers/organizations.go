@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// OrganizationStore persists organizations and who may sign in to them
+type OrganizationStore interface {
+	CreateOrganization(o *organization.Organization) error
+	GetOrganization(orgID string) (*organization.Organization, error)
+	ListOrganizations() ([]*organization.Organization, error)
+	ListOrganizationMembers(orgID string) ([]organization.Member, error)
+	AddOrganizationMember(m *organization.Member) error
+	RemoveOrganizationMember(orgID, username string) (bool, error)
+	GetUser(username string) (*user.User, error)
+}
+
+// OrganizationHandler lets admins manage organizations and their members
+type OrganizationHandler struct {
+	db OrganizationStore
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(store OrganizationStore) *OrganizationHandler {
+	return &OrganizationHandler{db: store}
+}
+
+// loadOrganization fetches the organization named in the path
+func (h *OrganizationHandler) loadOrganization(c *gin.Context) (*organization.Organization, bool) {
+	o, err := h.db.GetOrganization(c.Param("org_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get organization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if o == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+	return o, true
+}
+
+// CreateOrganization handles POST /admin/organizations
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req struct {
+		OrgID string `json:"org_id" binding:"required"`
+		Name  string `json:"name" binding:"required"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	o := &organization.Organization{ID: req.OrgID, Name: req.Name, CreatedBy: c.GetString("user_id")}
+	if err := o.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.CreateOrganization(o); err != nil {
+		if errors.Is(err, db.ErrOrganizationExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Organization already exists"})
+			return
+		}
+		logrus.WithError(err).Error("Failed to create organization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "organization",
+		"org_id":  o.ID,
+		"user_id": o.CreatedBy,
+	}).Info("Created organization")
+
+	c.JSON(http.StatusCreated, o)
+}
+
+// ListOrganizations handles GET /admin/organizations
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.db.ListOrganizations()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list organizations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organizations": orgs,
+		"total_count":   len(orgs),
+	})
+}
+
+// GetOrganization handles GET /admin/organizations/:org_id, listing the
+// users who may sign in to it
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	o, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.db.ListOrganizationMembers(o.ID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list organization members")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": o,
+		"members":      members,
+	})
+}
+
+// AddMember handles PUT /admin/organizations/:org_id/members/:username,
+// letting the user sign in to the organization besides their own
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	o, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	u, err := h.db.GetUser(c.Param("username"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if u == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	m := &organization.Member{OrgID: o.ID, Username: u.Username, AddedBy: c.GetString("user_id")}
+	if u.OrgID == o.ID {
+		// Already theirs; listed like ListOrganizationMembers does
+		m.Default, m.AddedBy, m.CreatedAt = true, u.CreatedBy, u.CreatedAt
+	} else {
+		if err := h.db.AddOrganizationMember(m); err != nil {
+			logrus.WithError(err).Error("Failed to add organization member")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "organization",
+		"org_id":   o.ID,
+		"username": u.Username,
+		"user_id":  c.GetString("user_id"),
+	}).Info("Added organization member")
+
+	c.JSON(http.StatusOK, m)
+}
+
+// RemoveMember handles DELETE /admin/organizations/:org_id/members/:username.
+// A user's own organization is changed on the user instead. Tokens already
+// issued for the organization stay valid until they expire, but cannot be
+// refreshed.
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	orgID, username := c.Param("org_id"), c.Param("username")
+
+	u, err := h.db.GetUser(username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if u != nil && u.OrgID == orgID {
+		c.JSON(http.StatusConflict, gin.H{"error": "This is the user's own organization; change org_id on the user instead"})
+		return
+	}
+
+	removed, err := h.db.RemoveOrganizationMember(orgID, username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to remove organization member")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membership not found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "organization",
+		"org_id":   orgID,
+		"username": username,
+		"user_id":  c.GetString("user_id"),
+	}).Info("Removed organization member")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"org_id":   orgID,
+		"username": username,
+	})
+}
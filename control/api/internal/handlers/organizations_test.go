@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/session"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOrganizationStore keeps organizations beside MockUserStore's users and memberships
+type MockOrganizationStore struct {
+	*MockUserStore
+	orgs map[string]*organization.Organization
+}
+
+func newMockOrganizationStore() *MockOrganizationStore {
+	return &MockOrganizationStore{MockUserStore: newMockUserStore(), orgs: map[string]*organization.Organization{}}
+}
+
+func (m *MockOrganizationStore) CreateOrganization(o *organization.Organization) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if _, ok := m.orgs[o.ID]; ok {
+		return db.ErrOrganizationExists
+	}
+	o.CreatedAt = time.Now()
+	m.orgs[o.ID] = o
+	return nil
+}
+
+func (m *MockOrganizationStore) GetOrganization(orgID string) (*organization.Organization, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.orgs[orgID], nil
+}
+
+func (m *MockOrganizationStore) ListOrganizations() ([]*organization.Organization, error) {
+	orgs := make([]*organization.Organization, 0, len(m.orgs))
+	for _, o := range m.orgs {
+		orgs = append(orgs, o)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs, nil
+}
+
+func (m *MockOrganizationStore) ListOrganizationMembers(orgID string) ([]organization.Member, error) {
+	members := make([]organization.Member, 0)
+	for _, u := range m.users {
+		if u.OrgID == orgID {
+			members = append(members, organization.Member{OrgID: orgID, Username: u.Username, Default: true})
+		} else if m.members[orgID][u.Username] {
+			members = append(members, organization.Member{OrgID: orgID, Username: u.Username})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+	return members, nil
+}
+
+func (m *MockOrganizationStore) AddOrganizationMember(member *organization.Member) error {
+	if m.members[member.OrgID] == nil {
+		m.members[member.OrgID] = map[string]bool{}
+	}
+	m.members[member.OrgID][member.Username] = true
+	member.CreatedAt = time.Now()
+	return nil
+}
+
+func (m *MockOrganizationStore) RemoveOrganizationMember(orgID, username string) (bool, error) {
+	if !m.members[orgID][username] {
+		return false, nil
+	}
+	delete(m.members[orgID], username)
+	return true, nil
+}
+
+func TestOrganizationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockOrganizationStore()
+	store.addUser(t, "alice", "correct horse battery", "org_agency")
+	store.addUser(t, "bob", "correct horse battery", "org_brand")
+	handler := NewOrganizationHandler(store)
+
+	router := gin.New()
+	router.Use(withOrg("root", ""))
+	router.POST("/admin/organizations", handler.CreateOrganization)
+	router.GET("/admin/organizations", handler.ListOrganizations)
+	router.GET("/admin/organizations/:org_id", handler.GetOrganization)
+	router.PUT("/admin/organizations/:org_id/members/:username", handler.AddMember)
+	router.DELETE("/admin/organizations/:org_id/members/:username", handler.RemoveMember)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/admin/organizations", `{"org_id":"org_brand","name":"Brand Co"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, "root", store.orgs["org_brand"].CreatedBy)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/admin/organizations", `{"org_id":"org_brand","name":"Again"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/organizations", `{"org_id":"org brand","name":"Spaces"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/organizations", `{"org_id":"org_x"}`).Code)
+
+	resp = do(http.MethodGet, "/admin/organizations", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"total_count":1`)
+
+	// Alice of the agency may also sign in to the brand
+	resp = do(http.MethodPut, "/admin/organizations/org_brand/members/alice", "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.True(t, store.members["org_brand"]["alice"])
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/admin/organizations/org_brand/members/mallory", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/admin/organizations/org_unknown/members/alice", "").Code)
+
+	resp = do(http.MethodGet, "/admin/organizations/org_brand", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var got struct {
+		Members []organization.Member `json:"members"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Len(t, got.Members, 2)
+	assert.Equal(t, "alice", got.Members[0].Username)
+	assert.False(t, got.Members[0].Default)
+	assert.Equal(t, "bob", got.Members[1].Username)
+	assert.True(t, got.Members[1].Default, "The users whose own organization it is should be listed")
+
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/admin/organizations/org_brand/members/bob", "").Code,
+		"A user's own organization should be changed on the user")
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/organizations/org_brand/members/alice", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/organizations/org_brand/members/alice", "").Code)
+}
+
+func TestAuthHandler_LoginOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.addUser(t, "alice", "correct horse battery", "org_agency")
+	store.members["org_brand"] = map[string]bool{"alice": true}
+	handler := NewAuthHandler(store, newMockSessionStore(), "test-secret", user.DefaultLockout(), session.DefaultLifetimes())
+
+	router := gin.New()
+	router.POST("/auth/login", handler.Login)
+	router.POST("/auth/refresh", handler.Refresh)
+	router.GET("/whoami", middleware.Authenticate("test-secret", nil, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"org_id": c.GetString("org_id")})
+	})
+	loginTo := func(orgID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"username": "alice", "password": "correct horse battery", "org_id": orgID})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	whoami := func(token string) string {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Body.String()
+	}
+
+	resp := loginTo("")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var own tokenPair
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &own))
+	assert.Equal(t, "org_agency", own.OrgID, "Without org_id users sign in to their own organization")
+	assert.JSONEq(t, `{"org_id":"org_agency"}`, whoami(own.Token))
+
+	resp = loginTo("org_brand")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var brand tokenPair
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &brand))
+	assert.Equal(t, "org_brand", brand.OrgID)
+	assert.JSONEq(t, `{"org_id":"org_brand"}`, whoami(brand.Token))
+
+	resp = loginTo("org_other")
+	assert.Equal(t, http.StatusForbidden, resp.Code, "Users should not sign in to organizations they do not belong to")
+
+	// The organization is kept across refreshes until the user leaves it
+	resp = postRefreshToken(router, "/auth/refresh", brand.RefreshToken)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &brand))
+	assert.Equal(t, "org_brand", brand.OrgID)
+
+	delete(store.members["org_brand"], "alice")
+	assert.Equal(t, http.StatusUnauthorized, postRefreshToken(router, "/auth/refresh", brand.RefreshToken).Code)
+	assert.Equal(t, http.StatusOK, postRefreshToken(router, "/auth/refresh", own.RefreshToken).Code)
+}
+
+func TestPlacementHandler_TenantScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorizer := authz.NewAuthorizer(&MockGrantStore{
+		owners: map[string]string{"booking/booking_brand": "org_brand", "booking/booking_other": "org_other"},
+		grants: []*authz.Grant{{
+			ID: "grant_1", ResourceType: "booking", ResourceID: "booking_brand",
+			OwnerOrgID: "org_brand", GranteeOrgID: "org_agency", Permissions: []authz.Permission{authz.PermissionView},
+		}},
+	})
+
+	tests := []struct {
+		name      string
+		role      string
+		bookingID string
+		status    int
+		scope     tenant.Scope
+	}{
+		{"own organization only", "", "booking_unowned", http.StatusOK, tenant.Of("org_agency")},
+		{"grant adds the owner", "", "booking_brand", http.StatusOK, tenant.Of("org_agency", "org_brand")},
+		{"other organization refused", "", "booking_other", http.StatusForbidden, tenant.Scope{}},
+		{"admins see every organization", user.RoleAdmin, "booking_other", http.StatusOK, tenant.All()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{metrics: &models.BookingMetrics{BookingID: tt.bookingID}}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+
+			router := gin.New()
+			router.Use(withOrg("alice", "org_agency"), func(c *gin.Context) {
+				c.Set("role", tt.role)
+			})
+			router.GET("/analytics/metrics/:booking_id",
+				authorizer.Require(authz.PermissionView, labels.ResourceBooking, "booking_id"), handler.GetMetrics)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/metrics/"+tt.bookingID, nil))
+			require.Equal(t, tt.status, resp.Code, resp.Body.String())
+			assert.Equal(t, tt.scope, mockDB.scope)
+		})
+	}
+}
+
+func TestPlacementHandler_ExposureTenantScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	handler.SetAuthorizer(authz.NewAuthorizer(&MockGrantStore{
+		owners: map[string]string{"booking/booking_own": "org_agency", "booking/booking_other": "org_other"},
+	}))
+
+	router := gin.New()
+	router.Use(withOrg("edge", "org_agency"))
+	router.POST("/events/exposure", handler.RecordExposure)
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post("/events/exposure", `{"booking_id": "booking_other", "viewer_id": "viewer_1", "exposure_duration": 5}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code, "Exposures of another organization's booking should be refused")
+	assert.Empty(t, mockDB.events)
+
+	resp = post("/events/exposure", `{"booking_id": "booking_own", "viewer_id": "viewer_1", "exposure_duration": 5}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = post("/events/exposure/batch", `{"events": [
+		{"booking_id": "booking_other", "viewer_id": "viewer_2", "exposure_duration": 5},
+		{"booking_id": "booking_own", "viewer_id": "viewer_3", "exposure_duration": 5}
+	]}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, []interface{}{float64(0)}, response["failed_indexes"], "Only the other organization's event should be refused")
+	assert.Equal(t, float64(1), response["processed_count"])
+	require.Len(t, mockDB.events, 2)
+	for _, event := range mockDB.events {
+		assert.Equal(t, "booking_own", event.BookingID)
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/origins"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// OriginStore persists the browser origins allowed for each organization
+type OriginStore interface {
+	GetOrganization(orgID string) (*organization.Organization, error)
+	AddAllowedOrigin(o *origins.Origin) error
+	ListOrganizationOrigins(orgID string) ([]origins.Origin, error)
+	RemoveAllowedOrigin(orgID, group, origin string) (bool, error)
+}
+
+// OriginCache is reloaded once allowed origins change
+type OriginCache interface {
+	Invalidate()
+}
+
+// OriginHandler lets admins allow browser origins to call the API for an
+// organization at runtime
+type OriginHandler struct {
+	db    OriginStore
+	cache OriginCache
+}
+
+// NewOriginHandler creates a new origin handler; cache may be nil when CORS is off
+func NewOriginHandler(store OriginStore, cache OriginCache) *OriginHandler {
+	return &OriginHandler{db: store, cache: cache}
+}
+
+// loadOrganization checks the organization named in the path exists
+func (h *OriginHandler) loadOrganization(c *gin.Context) (string, bool) {
+	o, err := h.db.GetOrganization(c.Param("org_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get organization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return "", false
+	}
+	if o == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return "", false
+	}
+	return o.ID, true
+}
+
+// changed reloads the cached origins and audit-logs a change
+func (h *OriginHandler) changed(c *gin.Context, o origins.Origin, message string) {
+	if h.cache != nil {
+		h.cache.Invalidate()
+	}
+	logrus.WithFields(logrus.Fields{
+		"audit":   "cors",
+		"org_id":  o.OrgID,
+		"group":   o.Group,
+		"origin":  o.Origin,
+		"user_id": c.GetString("user_id"),
+	}).Info(message)
+}
+
+// ListOrigins handles GET /admin/organizations/:org_id/origins
+func (h *OriginHandler) ListOrigins(c *gin.Context) {
+	orgID, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.db.ListOrganizationOrigins(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list allowed origins")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"origins":     list,
+		"total_count": len(list),
+	})
+}
+
+// AddOrigin handles POST /admin/organizations/:org_id/origins
+func (h *OriginHandler) AddOrigin(c *gin.Context) {
+	var req struct {
+		Origin string `json:"origin" binding:"required"`
+		Group  string `json:"group"` // api (default) or public
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o := origins.Origin{Origin: req.Origin, Group: req.Group, CreatedBy: c.GetString("user_id")}
+	if err := o.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	orgID, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+	o.OrgID = orgID
+
+	if err := h.db.AddAllowedOrigin(&o); err != nil {
+		logrus.WithError(err).Error("Failed to add allowed origin")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	h.changed(c, o, "Allowed origin")
+
+	c.JSON(http.StatusOK, o)
+}
+
+// RemoveOrigin handles DELETE /admin/organizations/:org_id/origins?origin=&group=
+func (h *OriginHandler) RemoveOrigin(c *gin.Context) {
+	o := origins.Origin{OrgID: c.Param("org_id"), Origin: c.Query("origin"), Group: c.Query("group")}
+	if err := o.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	removed, err := h.db.RemoveAllowedOrigin(o.OrgID, o.Group, o.Origin)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to remove allowed origin")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Origin not allowed"})
+		return
+	}
+	h.changed(c, o, "Removed allowed origin")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"org_id":  o.OrgID,
+		"group":   o.Group,
+		"origin":  o.Origin,
+	})
+}
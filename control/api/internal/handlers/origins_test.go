@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/origins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOriginStore keeps allowed origins beside MockOrganizationStore's organizations
+type MockOriginStore struct {
+	*MockOrganizationStore
+	origins []origins.Origin
+	loads   int
+}
+
+func (m *MockOriginStore) AddAllowedOrigin(o *origins.Origin) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	for _, existing := range m.origins {
+		if existing.OrgID == o.OrgID && existing.Group == o.Group && existing.Origin == o.Origin {
+			*o = existing
+			return nil
+		}
+	}
+	o.CreatedAt = time.Now()
+	m.origins = append(m.origins, *o)
+	return nil
+}
+
+func (m *MockOriginStore) ListOrganizationOrigins(orgID string) ([]origins.Origin, error) {
+	list := make([]origins.Origin, 0)
+	for _, o := range m.origins {
+		if o.OrgID == orgID {
+			list = append(list, o)
+		}
+	}
+	return list, nil
+}
+
+func (m *MockOriginStore) ListAllowedOrigins() ([]origins.Origin, error) {
+	m.loads++
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return append([]origins.Origin(nil), m.origins...), nil
+}
+
+func (m *MockOriginStore) RemoveAllowedOrigin(orgID, group, origin string) (bool, error) {
+	for i, o := range m.origins {
+		if o.OrgID == orgID && o.Group == group && o.Origin == origin {
+			m.origins = append(m.origins[:i], m.origins[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestOriginHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockOriginStore{MockOrganizationStore: newMockOrganizationStore()}
+	store.orgs["org_brand"] = &organization.Organization{ID: "org_brand", Name: "Brand Co"}
+	cache := origins.NewCache(store, time.Hour)
+	handler := NewOriginHandler(store, cache)
+
+	router := gin.New()
+	router.Use(withOrg("root", ""))
+	router.GET("/admin/organizations/:org_id/origins", handler.ListOrigins)
+	router.POST("/admin/organizations/:org_id/origins", handler.AddOrigin)
+	router.DELETE("/admin/organizations/:org_id/origins", handler.RemoveOrigin)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.False(t, cache.Allowed(origins.GroupAPI, "https://dash.brand.example"))
+
+	resp := do(http.MethodPost, "/admin/organizations/org_brand/origins", `{"origin":"https://Dash.Brand.example/"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Len(t, store.origins, 1)
+	assert.Equal(t, "https://dash.brand.example", store.origins[0].Origin, "Origins should be lowercased")
+	assert.Equal(t, origins.GroupAPI, store.origins[0].Group)
+	assert.Equal(t, "root", store.origins[0].CreatedBy)
+	assert.True(t, cache.Allowed(origins.GroupAPI, "https://dash.brand.example"), "A change should reload the cache")
+	assert.False(t, cache.Allowed(origins.GroupPublic, "https://dash.brand.example"))
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/organizations/org_brand/origins", `{"origin":"https://dash.brand.example"}`).Code)
+	assert.Len(t, store.origins, 1, "Allowing an origin again should change nothing")
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/organizations/org_brand/origins", `{"origin":"https://player.brand.example:8443","group":"public"}`).Code)
+
+	for _, body := range []string{
+		`{"origin":"https://dash.brand.example","group":"admin"}`,
+		`{"origin":"https://dash.brand.example/app"}`,
+		`{"origin":"https://*.brand.example"}`,
+		`{"origin":"ftp://brand.example"}`,
+		`{"origin":"brand.example"}`,
+		`{}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/organizations/org_brand/origins", body).Code, body)
+	}
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/organizations/org_unknown/origins", `{"origin":"https://a.example"}`).Code)
+
+	resp = do(http.MethodGet, "/admin/organizations/org_brand/origins", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"total_count":2`)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/organizations/org_unknown/origins", "").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/organizations/org_brand/origins?origin=https://dash.brand.example", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/organizations/org_brand/origins?origin=https://dash.brand.example", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodDelete, "/admin/organizations/org_brand/origins", "").Code)
+	assert.False(t, cache.Allowed(origins.GroupAPI, "https://dash.brand.example"))
+	assert.True(t, cache.Allowed(origins.GroupPublic, "https://player.brand.example:8443"))
+
+	// A failing store keeps the origins last loaded
+	cache.Invalidate()
+	store.shouldError = true
+	assert.True(t, cache.Allowed(origins.GroupPublic, "https://player.brand.example:8443"))
+	loads := store.loads
+	cache.Allowed(origins.GroupPublic, "https://player.brand.example:8443")
+	assert.Equal(t, loads, store.loads, "A failed load should not be retried before the TTL passes")
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockOriginStore{
+		MockOrganizationStore: newMockOrganizationStore(),
+		origins: []origins.Origin{
+			{OrgID: "org_brand", Origin: "https://dash.brand.example", Group: origins.GroupAPI},
+			{OrgID: "org_brand", Origin: "https://ops.brand.example", Group: origins.GroupAdmin},
+		},
+	}
+	router := gin.New()
+	router.Use(middleware.CORS(map[string]origins.Policy{
+		origins.GroupPublic: {Origins: []string{"*"}},
+		origins.GroupAPI:    {Origins: []string{"https://*.inscenium.example"}, Credentials: true},
+		origins.GroupAdmin:  {Origins: []string{"https://console.inscenium.example"}, Credentials: true},
+	}, origins.NewCache(store, time.Hour)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/v1/events/exposure", ok)
+	router.GET("/api/v1/bookings", ok)
+	router.GET("/admin/config", ok)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		allowed     bool
+		credentials bool
+		description string
+	}{
+		{
+			name:        "beacon from any site",
+			method:      http.MethodPost,
+			path:        "/api/v1/events/exposure",
+			origin:      "https://publisher.example",
+			allowed:     true,
+			description: "Should let players on any site report exposures, without credentials",
+		},
+		{
+			name:        "configured dashboard",
+			method:      http.MethodGet,
+			path:        "/api/v1/bookings",
+			origin:      "https://app.inscenium.example",
+			allowed:     true,
+			credentials: true,
+			description: "Should match configured wildcard origins",
+		},
+		{
+			name:        "organization's dashboard",
+			method:      http.MethodGet,
+			path:        "/api/v1/bookings",
+			origin:      "https://dash.brand.example",
+			allowed:     true,
+			credentials: true,
+			description: "Should allow origins organizations were allowed at runtime",
+		},
+		{
+			name:        "unknown site",
+			method:      http.MethodGet,
+			path:        "/api/v1/bookings",
+			origin:      "https://publisher.example",
+			description: "Should not let other sites call the API",
+		},
+		{
+			name:        "operators' console",
+			method:      http.MethodGet,
+			path:        "/admin/config",
+			origin:      "https://console.inscenium.example",
+			allowed:     true,
+			credentials: true,
+			description: "Should let configured origins call admin endpoints",
+		},
+		{
+			name:        "organization origin on admin",
+			method:      http.MethodGet,
+			path:        "/admin/config",
+			origin:      "https://ops.brand.example",
+			description: "Should not open admin endpoints to origins allowed for an organization",
+		},
+		{
+			name:        "dashboard on admin",
+			method:      http.MethodGet,
+			path:        "/admin/config",
+			origin:      "https://app.inscenium.example",
+			description: "Should not apply the api group's policy to admin endpoints",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			if tt.allowed {
+				assert.Equal(t, tt.origin, resp.Header().Get("Access-Control-Allow-Origin"), tt.description)
+			} else {
+				assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"), tt.description)
+			}
+			assert.Equal(t, tt.credentials, resp.Header().Get("Access-Control-Allow-Credentials") == "true", tt.description)
+		})
+	}
+
+	// Preflight requests are answered without reaching the route
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/bookings", nil)
+	req.Header.Set("Origin", "https://dash.brand.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://dash.brand.example", resp.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/pacinglog"
+	"github.com/sirupsen/logrus"
+)
+
+// PacingReplayStore reads the pacing snapshots of a booking and what it
+// served between them
+type PacingReplayStore interface {
+	ListPacingSnapshots(bookingID string, from, to time.Time) ([]pacinglog.Snapshot, error)
+	ListPacingDeliveries(bookingID string, times []time.Time, to time.Time) ([]pacinglog.Delivery, error)
+}
+
+// PacingReplayHandler replays the decision controller's pacing and budget
+// decisions over recorded snapshots
+type PacingReplayHandler struct {
+	db PacingReplayStore
+}
+
+// NewPacingReplayHandler creates a pacing replay handler
+func NewPacingReplayHandler(store PacingReplayStore) *PacingReplayHandler {
+	return &PacingReplayHandler{db: store}
+}
+
+// replaySummary totals a replay and marks when it first went wrong
+type replaySummary struct {
+	Snapshots         int            `json:"snapshots"`
+	Decisions         map[string]int `json:"decisions"` // Steps by decision
+	Served            int64          `json:"served"`
+	Spend             float64        `json:"spend"`
+	ServedWhenRefused int64          `json:"served_when_refused"` // Served from snapshots the replay refused at
+	FirstAheadOfPace  *time.Time     `json:"first_ahead_of_pace,omitempty"`
+	FirstExhausted    *time.Time     `json:"first_budget_exhausted,omitempty"`
+	FirstOverspent    *time.Time     `json:"first_overspent,omitempty"`
+}
+
+// summarize totals steps
+func summarize(steps []pacinglog.Step) replaySummary {
+	summary := replaySummary{Snapshots: len(steps), Decisions: make(map[string]int)}
+	for i := range steps {
+		step := &steps[i]
+		summary.Decisions[step.Decision]++
+		summary.Served += step.Served
+		summary.Spend += step.Spend
+		if step.Decision != pacinglog.DecisionServe {
+			summary.ServedWhenRefused += step.Served
+		}
+		if step.Decision == pacinglog.DecisionAheadOfPace && summary.FirstAheadOfPace == nil {
+			summary.FirstAheadOfPace = &step.At
+		}
+		if step.Budget == pacinglog.BudgetExhausted && summary.FirstExhausted == nil {
+			summary.FirstExhausted = &step.At
+		}
+		if step.Overspent && summary.FirstOverspent == nil {
+			summary.FirstOverspent = &step.At
+		}
+	}
+	return summary
+}
+
+// parseReplayRange reads a replay's range from the query string. It
+// defaults to the last pacinglog.DefaultRange and may not exceed
+// pacinglog.MaxRange.
+func parseReplayRange(c *gin.Context) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to parameter, expected RFC3339")
+		}
+	}
+	from = to.Add(-pacinglog.DefaultRange)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from parameter, expected RFC3339")
+		}
+	}
+	switch {
+	case !to.After(from):
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	case to.Sub(from) > pacinglog.MaxRange:
+		return time.Time{}, time.Time{}, errors.New("range may not exceed 7 days")
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+// Replay handles GET /bookings/:id/pacing/replay. It replays the pacing and
+// budget checks of a served decision at each snapshot of the booking taken
+// from from until to, with the inputs the controller saw, and what was
+// actually served until the next snapshot.
+func (h *PacingReplayHandler) Replay(c *gin.Context) {
+	bookingID := c.Param("id")
+	from, to, err := parseReplayRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshots, err := h.db.ListPacingSnapshots(bookingID, from, to)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to list pacing snapshots")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	times := make([]time.Time, len(snapshots))
+	for i, s := range snapshots {
+		times[i] = s.TakenAt
+	}
+	delivered, err := h.db.ListPacingDeliveries(bookingID, times, to)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to list pacing deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	steps := pacinglog.Replay(snapshots, delivered)
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id": bookingID,
+		"from":       from,
+		"to":         to,
+		"steps":      steps,
+		"count":      len(steps),
+		"summary":    summarize(steps),
+	})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/pacinglog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPacingReplayStore struct {
+	snapshots   []pacinglog.Snapshot
+	deliveries  []pacinglog.Delivery
+	from, to    time.Time
+	times       []time.Time
+	shouldError bool
+}
+
+func (m *MockPacingReplayStore) ListPacingSnapshots(bookingID string, from, to time.Time) ([]pacinglog.Snapshot, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.from, m.to = from, to
+	return m.snapshots, nil
+}
+
+func (m *MockPacingReplayStore) ListPacingDeliveries(bookingID string, times []time.Time, to time.Time) ([]pacinglog.Delivery, error) {
+	m.times = times
+	return m.deliveries, nil
+}
+
+func TestPacingReplayHandler_Replay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	halfway := start.Add(5 * time.Hour)
+	amount, seed, counter := 100.0, 94.0, 0.001
+
+	store := &MockPacingReplayStore{
+		snapshots: []pacinglog.Snapshot{
+			// Delivered 600 of a 500 target
+			{BookingID: "booking_1", TakenAt: halfway, Pacing: "even", MaxImpressions: 1000, Delivered: 600, Start: &start, End: &end},
+			// Behind pace, but the counter holds less than an impression costs
+			{BookingID: "booking_1", TakenAt: halfway.Add(time.Minute), Pacing: "even", MaxImpressions: 1000, Delivered: 400,
+				Start: &start, End: &end, ImpressionCost: 0.005, BudgetAmount: &amount, BudgetSpent: 101, BudgetSeed: &seed, CounterRemaining: &counter},
+			// As fast as decisions allow, without a budget
+			{BookingID: "booking_1", TakenAt: halfway.Add(2 * time.Minute), Pacing: "asap", ImpressionCost: 0.005},
+		},
+		deliveries: []pacinglog.Delivery{{Served: 3, Spend: 0.015}, {Served: 2, Spend: 0.01}, {Served: 1, Spend: 0.005}},
+	}
+	handler := NewPacingReplayHandler(store)
+	router := gin.New()
+	router.GET("/bookings/:id/pacing/replay", handler.Replay)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/bookings/booking_1/pacing/replay?from=2026-03-01T04:00:00Z&to=2026-03-01T06:00:00Z", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, start.Add(4*time.Hour), store.from)
+	assert.Equal(t, start.Add(6*time.Hour), store.to)
+	assert.Equal(t, []time.Time{halfway, halfway.Add(time.Minute), halfway.Add(2 * time.Minute)}, store.times)
+
+	var resp struct {
+		Count   int              `json:"count"`
+		Steps   []pacinglog.Step `json:"steps"`
+		Summary replaySummary    `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 3, resp.Count)
+
+	ahead := resp.Steps[0]
+	assert.Equal(t, int64(500), ahead.Target)
+	assert.Equal(t, pacinglog.PacingAhead, ahead.Pacing)
+	assert.Equal(t, pacinglog.BudgetUnlimited, ahead.Budget)
+	assert.Equal(t, pacinglog.DecisionAheadOfPace, ahead.Decision)
+	assert.Equal(t, int64(3), ahead.Served)
+
+	exhausted := resp.Steps[1]
+	assert.Equal(t, pacinglog.PacingBehind, exhausted.Pacing)
+	assert.Equal(t, pacinglog.SourceRedis, exhausted.BudgetSource, "A loaded counter is checked rather than the seed")
+	require.NotNil(t, exhausted.BudgetRemaining)
+	assert.Equal(t, counter, *exhausted.BudgetRemaining)
+	assert.Equal(t, pacinglog.BudgetExhausted, exhausted.Budget)
+	assert.Equal(t, pacinglog.DecisionBudgetExhausted, exhausted.Decision)
+	assert.True(t, exhausted.Overspent)
+	assert.Equal(t, &counter, exhausted.Inputs.CounterRemaining, "The inputs are returned as they were recorded")
+
+	unpaced := resp.Steps[2]
+	assert.Equal(t, int64(-1), unpaced.Target)
+	assert.Equal(t, pacinglog.PacingUnpaced, unpaced.Pacing)
+	assert.Equal(t, pacinglog.DecisionServe, unpaced.Decision)
+
+	assert.Equal(t, int64(6), resp.Summary.Served)
+	assert.Equal(t, int64(5), resp.Summary.ServedWhenRefused)
+	assert.Equal(t, map[string]int{pacinglog.DecisionAheadOfPace: 1, pacinglog.DecisionBudgetExhausted: 1, pacinglog.DecisionServe: 1}, resp.Summary.Decisions)
+	require.NotNil(t, resp.Summary.FirstOverspent)
+	assert.True(t, resp.Summary.FirstOverspent.Equal(halfway.Add(time.Minute)))
+
+	t.Run("seed without counter", func(t *testing.T) {
+		s := store.snapshots[1]
+		s.CounterRemaining = nil
+		step := pacinglog.Decide(s)
+		assert.Equal(t, pacinglog.SourcePostgres, step.BudgetSource)
+		assert.Equal(t, pacinglog.BudgetCharged, step.Budget)
+		assert.Equal(t, pacinglog.DecisionServe, step.Decision)
+	})
+
+	for name, query := range map[string]string{
+		"bad from":       "?from=yesterday",
+		"bad to":         "?to=2026-03-01",
+		"empty range":    "?from=2026-03-01T06:00:00Z&to=2026-03-01T06:00:00Z",
+		"range too long": "?from=2026-03-01T00:00:00Z&to=2026-03-09T00:00:00Z",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bookings/booking_1/pacing/replay"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+
+	t.Run("store error", func(t *testing.T) {
+		store.shouldError = true
+		defer func() { store.shouldError = false }()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bookings/booking_1/pacing/replay", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIIDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{"email", "jane.doe@example.com", []string{pii.KindEmail}},
+		{"email inside id", "viewer-jane.doe+tv@example.co.uk", []string{pii.KindEmail}},
+		{"phone", "+1 (415) 555-0134", []string{pii.KindPhone}},
+		{"bare phone", "4155550134", []string{pii.KindPhone}},
+		{"advertising id", "6D92078A-8246-4BA4-AE5B-76104861E7DC", []string{pii.KindDeviceID}},
+		{"mac address", "a4:5e:60:c2:11:9f", []string{pii.KindDeviceID}},
+		{"imei", "490154203237518", []string{pii.KindDeviceID}},
+		{"sha256", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", nil},
+		{"opaque id", "viewer_456", nil},
+		{"short number", "session-20240512", nil},
+		{"device type", "smart_tv", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, nilIfEmpty(pii.Detect(tt.value)))
+		})
+	}
+}
+
+func nilIfEmpty(kinds []string) []string {
+	if len(kinds) == 0 {
+		return nil
+	}
+	return kinds
+}
+
+func TestScanPII(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		fields         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedViewer string
+		violations     int
+		description    string
+	}{
+		{
+			name:           "clean payload",
+			fields:         pii.DefaultFields,
+			path:           "/events/exposure",
+			body:           `{"booking_id":"booking_123","viewer_id":"viewer_456","exposure_duration":5.2}`,
+			expectedStatus: http.StatusCreated,
+			expectedViewer: "viewer_456",
+			description:    "Should pass payloads without personal data through",
+		},
+		{
+			name:           "masked email",
+			fields:         pii.DefaultFields,
+			path:           "/events/exposure",
+			body:           `{"booking_id":"booking_123","viewer_id":"jane@example.com","exposure_duration":5.2}`,
+			expectedStatus: http.StatusCreated,
+			expectedViewer: pii.Mask("jane@example.com"),
+			violations:     1,
+			description:    "Should hash personal data in masked fields",
+		},
+		{
+			name:           "rejected email",
+			fields:         "viewer_id=reject",
+			path:           "/events/exposure",
+			body:           `{"booking_id":"booking_123","viewer_id":"jane@example.com","exposure_duration":5.2}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			violations:     1,
+			description:    "Should refuse payloads with personal data in rejected fields",
+		},
+		{
+			name:           "reported phone",
+			fields:         "viewer_id=report",
+			path:           "/events/exposure",
+			body:           `{"booking_id":"booking_123","viewer_id":"+14155550134","exposure_duration":5.2}`,
+			expectedStatus: http.StatusCreated,
+			expectedViewer: "+14155550134",
+			violations:     1,
+			description:    "Should accept reported fields unchanged",
+		},
+		{
+			name:           "camelCase field",
+			fields:         "viewer_id=reject",
+			path:           "/events/exposure",
+			body:           `{"bookingId":"booking_123","viewerId":"jane@example.com","exposureDuration":5.2}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			violations:     1,
+			description:    "Should match camelCase spellings of scanned fields",
+		},
+		{
+			name:           "unscanned field",
+			fields:         "session_id",
+			path:           "/events/exposure",
+			body:           `{"booking_id":"booking_123","viewer_id":"jane@example.com","exposure_duration":5.2}`,
+			expectedStatus: http.StatusCreated,
+			expectedViewer: "jane@example.com",
+			description:    "Should leave fields outside the policy alone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := pii.ParsePolicy(tt.fields, pii.ActionMask)
+			require.NoError(t, err)
+
+			store := newMockServiceAccountStore()
+			mockDB := &MockPlacementDB{}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+			router := gin.New()
+			router.Use(withOrg("user_1", "org_a"))
+			router.POST("/events/exposure", middleware.ScanPII(policy, store), handler.RecordExposure)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Len(t, store.violations["user_1"], tt.violations, tt.description)
+			assert.NotContains(t, resp.Body.String(), "jane@example.com", "Responses should never echo personal data")
+
+			if tt.expectedStatus == http.StatusCreated {
+				require.Len(t, mockDB.events, 1)
+				assert.Equal(t, tt.expectedViewer, mockDB.events[0].ViewerID)
+			} else {
+				assert.Empty(t, mockDB.events)
+			}
+		})
+	}
+}
+
+func TestParsePIIPolicy(t *testing.T) {
+	_, err := pii.ParsePolicy(pii.DefaultFields, "drop")
+	assert.Error(t, err, "Should reject unknown default actions")
+
+	_, err = pii.ParsePolicy("viewer_id=drop", pii.ActionMask)
+	assert.Error(t, err, "Should reject unknown field actions")
+
+	policy, err := pii.ParsePolicy("", pii.ActionMask)
+	require.NoError(t, err)
+	assert.True(t, policy.Empty())
+}
+
+func TestServiceAccountHandler_ListPIIViolations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockServiceAccountStore()
+	keyID, _ := store.addAccount(t, "sa_render", "org_a", "events:write")
+	store.addAccount(t, "sa_other", "org_b", "events:write")
+	require.NoError(t, store.RecordPIIViolations("org_a", "sa:sa_render", keyID, []pii.Violation{
+		{Path: "viewer_id", Field: "viewer_id", Kind: pii.KindEmail, Action: pii.ActionMask},
+	}))
+
+	handler := NewServiceAccountHandler(store)
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.GET("/service-accounts/:account_id/pii-violations", handler.ListPIIViolations)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedTotal  float64
+		description    string
+	}{
+		{"default window", "/service-accounts/sa_render/pii-violations", http.StatusOK, 1, "Should list the account's violations"},
+		{"custom window", "/service-accounts/sa_render/pii-violations?days=7", http.StatusOK, 1, "Should accept a window in days"},
+		{"invalid window", "/service-accounts/sa_render/pii-violations?days=365", http.StatusBadRequest, 0, "Should reject windows over 90 days"},
+		{"other org's account", "/service-accounts/sa_other/pii-violations", http.StatusNotFound, 0, "Should hide accounts of other organizations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, "sa_render", response["account_id"])
+			assert.Equal(t, tt.expectedTotal, response["total_count"])
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/thumbnail"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// pipelineWebhookSource namespaces pipeline callback nonces
+const pipelineWebhookSource = "pipeline"
+
+// maxPipelineCallbackBytes bounds the size of a pipeline callback body;
+// surface batches with polygons are the largest
+const maxPipelineCallbackBytes = 32 << 20
+
+// callbackInvalid is recorded for signed callbacks whose payload is refused
+const callbackInvalid = "invalid"
+
+// PipelineStore persists the results the vision pipeline pushes
+type PipelineStore interface {
+	UpsertShots(list *ingest.ShotList) (int, error)
+	IngestSurfaces(batch *ingest.Batch, thresholds dedupe.Thresholds) (*ingest.Result, error)
+	SaveSceneGraph(graph *ingest.SceneGraph) error
+	SaveQCReport(report *ingest.QCReport) (int, error)
+	SaveSurfaceThumbnail(src *thumbnail.Source) (string, error)
+	ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// PipelineHandler receives signed callbacks from the vision pipeline: shot
+// lists, surface batches, scene graphs, QC results and reference frames.
+// Every write is an upsert keyed by IDs from the payload, so redelivering it
+// is harmless.
+type PipelineHandler struct {
+	db         PipelineStore
+	verifier   *webhook.Verifier
+	jobs       JobEnqueuer
+	objects    storage.Store
+	thresholds dedupe.Thresholds
+}
+
+// NewPipelineHandler creates a pipeline callback handler. Callbacks are
+// rejected unless the verifier has at least one secret.
+func NewPipelineHandler(store PipelineStore, verifier *webhook.Verifier) *PipelineHandler {
+	return &PipelineHandler{db: store, verifier: verifier, thresholds: dedupe.DefaultThresholds()}
+}
+
+// SetJobQueue schedules dedupe jobs when a surface batch contains duplicates
+func (h *PipelineHandler) SetJobQueue(jobs JobEnqueuer) {
+	h.jobs = jobs
+}
+
+// SetThumbnailStorage stores uploaded reference frames in object storage.
+// Thumbnail callbacks are refused without it.
+func (h *PipelineHandler) SetThumbnailStorage(store storage.Store) {
+	h.objects = store
+}
+
+// ShotsCallback handles POST /webhooks/pipeline/shots
+func (h *PipelineHandler) ShotsCallback(c *gin.Context) {
+	var list ingest.ShotList
+	if !h.readCallback(c, ingest.CallbackShots, &list) {
+		return
+	}
+	if err := list.Validate(); err != nil {
+		h.invalid(c, ingest.CallbackShots, err)
+		return
+	}
+
+	shots, err := h.db.UpsertShots(&list)
+	if !h.stored(c, ingest.CallbackShots, list.TitleID, err) {
+		return
+	}
+
+	h.applied(c, ingest.CallbackShots, list.TitleID, list.RunID, gin.H{"shots": shots})
+}
+
+// SurfacesCallback handles POST /webhooks/pipeline/surfaces, the signed
+// equivalent of POST /surfaces
+func (h *PipelineHandler) SurfacesCallback(c *gin.Context) {
+	var batch ingest.Batch
+	if !h.readCallback(c, ingest.CallbackSurfaces, &batch) {
+		return
+	}
+	if err := batch.Validate(); err != nil {
+		h.invalid(c, ingest.CallbackSurfaces, err)
+		return
+	}
+
+	result, err := h.db.IngestSurfaces(&batch, h.thresholds)
+	if !h.stored(c, ingest.CallbackSurfaces, batch.TitleID, err) {
+		return
+	}
+
+	response := gin.H{
+		"shots":              result.Shots,
+		"surfaces":           result.Surfaces,
+		"duplicate_warnings": result.DuplicateWarnings,
+	}
+	if len(result.DuplicateWarnings) > 0 {
+		if jobID := scheduleDedupe(c, h.jobs, result.TitleID); jobID != "" {
+			response["dedupe_job_id"] = jobID
+		}
+	}
+	h.applied(c, ingest.CallbackSurfaces, batch.TitleID, "", response)
+}
+
+// SceneGraphCallback handles POST /webhooks/pipeline/scene-graphs
+func (h *PipelineHandler) SceneGraphCallback(c *gin.Context) {
+	var graph ingest.SceneGraph
+	if !h.readCallback(c, ingest.CallbackSceneGraph, &graph) {
+		return
+	}
+	if err := graph.Validate(); err != nil {
+		h.invalid(c, ingest.CallbackSceneGraph, err)
+		return
+	}
+
+	err := h.db.SaveSceneGraph(&graph)
+	if !h.stored(c, ingest.CallbackSceneGraph, graph.TitleID, err) {
+		return
+	}
+
+	h.applied(c, ingest.CallbackSceneGraph, graph.TitleID, graph.RunID, gin.H{
+		"shot_id": graph.ShotID,
+		"nodes":   len(graph.Nodes),
+		"edges":   len(graph.Edges),
+	})
+}
+
+// QCCallback handles POST /webhooks/pipeline/qc
+func (h *PipelineHandler) QCCallback(c *gin.Context) {
+	var report ingest.QCReport
+	if !h.readCallback(c, ingest.CallbackQC, &report) {
+		return
+	}
+	if err := report.Validate(); err != nil {
+		h.invalid(c, ingest.CallbackQC, err)
+		return
+	}
+
+	checks, err := h.db.SaveQCReport(&report)
+	if !h.stored(c, ingest.CallbackQC, report.TitleID, err) {
+		return
+	}
+
+	failed := report.Failed()
+	if failed > 0 {
+		logrus.WithFields(logrus.Fields{
+			"title_id": report.TitleID,
+			"run_id":   report.RunID,
+			"failed":   failed,
+		}).Warn("Pipeline QC checks failed")
+	}
+	h.applied(c, ingest.CallbackQC, report.TitleID, report.RunID, gin.H{
+		"checks": checks,
+		"failed": failed,
+	})
+}
+
+// ThumbnailCallback handles PUT /webhooks/pipeline/surfaces/:surface_id/thumbnail.
+// The body is the surface's reference frame as a JPEG or PNG image, signed
+// like the JSON callbacks. Uploading a new frame replaces the previous one.
+func (h *PipelineHandler) ThumbnailCallback(c *gin.Context) {
+	if h.objects == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Thumbnail storage is not configured"})
+		return
+	}
+	body, ok := h.readSigned(c, ingest.CallbackThumbnail, thumbnail.MaxSourceBytes)
+	if !ok {
+		return
+	}
+
+	surfaceID := c.Param("surface_id")
+	src, err := thumbnail.Inspect(surfaceID, body)
+	if err != nil {
+		h.invalid(c, ingest.CallbackThumbnail, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := thumbnail.SourceKey(surfaceID, src.Version)
+	if err := h.objects.Put(ctx, key, body, src.ContentType); err != nil {
+		logrus.WithError(err).WithField("surface_id", surfaceID).Error("Failed to store thumbnail source")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	previous, err := h.db.SaveSurfaceThumbnail(src)
+	if err != nil {
+		if errors.Is(err, db.ErrSurfaceNotFound) {
+			h.deleteThumbnail(c, surfaceID, src.Version)
+		}
+		h.stored(c, ingest.CallbackThumbnail, src.TitleID, err)
+		return
+	}
+	if previous != "" && previous != src.Version {
+		h.deleteThumbnail(c, surfaceID, previous)
+	}
+
+	h.applied(c, ingest.CallbackThumbnail, src.TitleID, "", gin.H{
+		"surface_id":    surfaceID,
+		"version":       src.Version,
+		"width":         src.Width,
+		"height":        src.Height,
+		"thumbnail_url": thumbnail.Path(surfaceID, src.Version),
+	})
+}
+
+// deleteThumbnail removes a thumbnail version's source and variants from
+// object storage. Failures only leave orphaned objects behind, so they are
+// logged rather than returned.
+func (h *PipelineHandler) deleteThumbnail(c *gin.Context, surfaceID, version string) {
+	keys := []string{thumbnail.SourceKey(surfaceID, version)}
+	for _, width := range thumbnail.Widths {
+		keys = append(keys, thumbnail.VariantKey(surfaceID, version, width))
+	}
+	for _, key := range keys {
+		err := h.objects.Delete(c.Request.Context(), key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			logrus.WithError(err).WithField("key", key).Warn("Failed to delete thumbnail object")
+		}
+	}
+}
+
+// readCallback verifies a callback's signature and nonce, then decodes its
+// body into v, refusing fields the payload does not define. It writes the
+// error response and returns false when the callback is refused.
+func (h *PipelineHandler) readCallback(c *gin.Context, kind string, v interface{}) bool {
+	body, ok := h.readSigned(c, kind, maxPipelineCallbackBytes)
+	if !ok {
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		h.invalid(c, kind, fmt.Errorf("invalid JSON body: %w", err))
+		return false
+	}
+	return true
+}
+
+// readSigned reads a callback body of at most limit bytes and verifies its
+// signature and nonce. It writes the error response and returns false when
+// the callback is refused.
+func (h *PipelineHandler) readSigned(c *gin.Context, kind string, limit int64) ([]byte, bool) {
+	if !h.verifier.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Pipeline webhooks are not configured"})
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return nil, false
+	}
+	if int64(len(body)) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Callback body too large"})
+		return nil, false
+	}
+
+	nonce := c.GetHeader(webhook.HeaderNonce)
+	signedAt, err := h.verifier.Verify(c.GetHeader(webhook.HeaderTimestamp), nonce, c.GetHeader(webhook.HeaderSignature), body)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"audit":     "webhook",
+			"source":    pipelineWebhookSource,
+			"kind":      kind,
+			"client_ip": c.ClientIP(),
+		}).WithError(err).Warn("Rejected pipeline callback signature")
+		metrics.PipelineCallbacks.WithLabelValues(kind, callbackRejected).Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	claimed, err := h.db.ClaimWebhookNonce(pipelineWebhookSource, nonce, signedAt.Add(h.verifier.Tolerance()))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to record pipeline callback nonce")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if !claimed {
+		logrus.WithFields(logrus.Fields{
+			"audit":  "webhook",
+			"source": pipelineWebhookSource,
+			"kind":   kind,
+			"nonce":  nonce,
+		}).Warn("Rejected replayed pipeline callback")
+		metrics.PipelineCallbacks.WithLabelValues(kind, callbackRejected).Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": "Nonce already used"})
+		return nil, false
+	}
+	return body, true
+}
+
+// stored maps a store error to a response, returning true if there was none
+func (h *PipelineHandler) stored(c *gin.Context, kind, titleID string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, db.ErrTitleNotFound):
+		metrics.PipelineCallbacks.WithLabelValues(kind, callbackInvalid).Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+	case errors.Is(err, db.ErrSurfaceNotFound):
+		metrics.PipelineCallbacks.WithLabelValues(kind, callbackInvalid).Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Surface not found"})
+	case errors.Is(err, ingest.ErrInvalidBatch):
+		h.invalid(c, kind, err)
+	default:
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"title_id": titleID,
+			"kind":     kind,
+		}).Error("Failed to store pipeline callback")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+	return false
+}
+
+// invalid refuses a signed callback whose payload does not match its schema
+func (h *PipelineHandler) invalid(c *gin.Context, kind string, err error) {
+	metrics.PipelineCallbacks.WithLabelValues(kind, callbackInvalid).Inc()
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// applied acknowledges a stored callback
+func (h *PipelineHandler) applied(c *gin.Context, kind, titleID, runID string, response gin.H) {
+	logrus.WithFields(logrus.Fields{
+		"kind":     kind,
+		"title_id": titleID,
+		"run_id":   runID,
+	}).Info("Applied pipeline callback")
+	metrics.PipelineCallbacks.WithLabelValues(kind, callbackApplied).Inc()
+
+	response["kind"] = kind
+	response["title_id"] = titleID
+	response["applied"] = true
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/thumbnail"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPipelineSecret = "pipeline-secret"
+
+type MockPipelineStore struct {
+	shots       map[string]ingest.Shot
+	graphs      map[string]*ingest.SceneGraph
+	checks      map[string]ingest.QCCheck
+	thumbnails  map[string]*thumbnail.Source
+	nonces      map[string]bool
+	shouldError bool
+}
+
+func newMockPipelineStore() *MockPipelineStore {
+	return &MockPipelineStore{
+		shots:  map[string]ingest.Shot{"shot_001": {ShotID: "shot_001"}},
+		graphs: map[string]*ingest.SceneGraph{},
+		checks: map[string]ingest.QCCheck{},
+		nonces: map[string]bool{},
+		thumbnails: map[string]*thumbnail.Source{
+			"surface_001": nil, // Detected, no reference frame yet
+		},
+	}
+}
+
+func (m *MockPipelineStore) UpsertShots(list *ingest.ShotList) (int, error) {
+	if m.shouldError {
+		return 0, assert.AnError
+	}
+	if list.TitleID != "1" {
+		return 0, db.ErrTitleNotFound
+	}
+	for _, shot := range list.Shots {
+		m.shots[shot.ShotID] = shot
+	}
+	return len(list.Shots), nil
+}
+
+func (m *MockPipelineStore) IngestSurfaces(batch *ingest.Batch, thresholds dedupe.Thresholds) (*ingest.Result, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return &ingest.Result{TitleID: batch.TitleID, Shots: len(batch.Shots), Surfaces: len(batch.Surfaces), DuplicateWarnings: []dedupe.Warning{}}, nil
+}
+
+func (m *MockPipelineStore) SaveSceneGraph(graph *ingest.SceneGraph) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if _, ok := m.shots[graph.ShotID]; !ok {
+		return ingest.ErrInvalidBatch
+	}
+	m.graphs[graph.ShotID] = graph
+	return nil
+}
+
+func (m *MockPipelineStore) SaveQCReport(report *ingest.QCReport) (int, error) {
+	if m.shouldError {
+		return 0, assert.AnError
+	}
+	for _, check := range report.Checks {
+		m.checks[report.RunID+"/"+check.Check+"/"+check.Subject()] = check
+	}
+	return len(report.Checks), nil
+}
+
+func (m *MockPipelineStore) SaveSurfaceThumbnail(src *thumbnail.Source) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	previous, ok := m.thumbnails[src.SurfaceID]
+	if !ok {
+		return "", db.ErrSurfaceNotFound
+	}
+	src.TitleID = "1"
+	src.UploadedAt = time.Now()
+	m.thumbnails[src.SurfaceID] = src
+	if previous == nil {
+		return "", nil
+	}
+	return previous.Version, nil
+}
+
+func (m *MockPipelineStore) GetSurfaceThumbnail(surfaceID string) (*thumbnail.Source, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.thumbnails[surfaceID], nil
+}
+
+func (m *MockPipelineStore) ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error) {
+	if m.nonces[source+"/"+nonce] {
+		return false, nil
+	}
+	m.nonces[source+"/"+nonce] = true
+	return true, nil
+}
+
+func newPipelineRouter(store *MockPipelineStore, secrets ...string) *gin.Engine {
+	handler := NewPipelineHandler(store, webhook.NewVerifier(secrets, webhook.DefaultTolerance))
+	router := gin.New()
+	router.POST("/webhooks/pipeline/shots", handler.ShotsCallback)
+	router.POST("/webhooks/pipeline/surfaces", handler.SurfacesCallback)
+	router.POST("/webhooks/pipeline/scene-graphs", handler.SceneGraphCallback)
+	router.POST("/webhooks/pipeline/qc", handler.QCCallback)
+	return router
+}
+
+// signedPipelineCallback builds a pipeline callback to path signed with secret
+func signedPipelineCallback(path, secret, nonce string, body string) *http.Request {
+	req := signedCallback(secret, nonce, time.Now(), body)
+	req.URL.Path = path
+	return req
+}
+
+func TestPipelineHandler_Callbacks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "shot list",
+			path:           "/webhooks/pipeline/shots",
+			body:           `{"title_id":"1","run_id":"run_7","shots":[{"shot_id":"shot_002","start_time":4.5,"end_time":9}]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should upsert the run's shots",
+		},
+		{
+			name:           "shot list for unknown title",
+			path:           "/webhooks/pipeline/shots",
+			body:           `{"title_id":"404","shots":[{"shot_id":"shot_002","start_time":4.5,"end_time":9}]}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown titles",
+		},
+		{
+			name:           "shot ending before it starts",
+			path:           "/webhooks/pipeline/shots",
+			body:           `{"title_id":"1","shots":[{"shot_id":"shot_002","start_time":9,"end_time":4.5}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should validate shot bounds",
+		},
+		{
+			name:           "unknown field",
+			path:           "/webhooks/pipeline/shots",
+			body:           `{"title_id":"1","shots":[{"shot_id":"shot_002","start":4.5,"end_time":9}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse fields outside the schema",
+		},
+		{
+			name:           "surface batch",
+			path:           "/webhooks/pipeline/surfaces",
+			body:           `{"title_id":"1","surfaces":[{"surface_id":"surface_9","shot_id":"shot_001","start_time":1,"end_time":2,"polygon":[[0,0],[1,0],[1,1]]}]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should ingest surfaces like POST /surfaces",
+		},
+		{
+			name:           "scene graph",
+			path:           "/webhooks/pipeline/scene-graphs",
+			body:           `{"title_id":"1","shot_id":"shot_001","run_id":"run_7","nodes":[{"node_id":"n1","label":"table","confidence":0.9},{"node_id":"n2","label":"lamp","confidence":0.8}],"edges":[{"from":"n2","to":"n1","relation":"on"}]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should store the shot's scene graph",
+		},
+		{
+			name:           "scene graph edge to unknown node",
+			path:           "/webhooks/pipeline/scene-graphs",
+			body:           `{"title_id":"1","shot_id":"shot_001","nodes":[{"node_id":"n1","label":"table","confidence":0.9}],"edges":[{"from":"n1","to":"n3","relation":"on"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse edges between unknown nodes",
+		},
+		{
+			name:           "scene graph of unknown shot",
+			path:           "/webhooks/pipeline/scene-graphs",
+			body:           `{"title_id":"1","shot_id":"shot_404","nodes":[{"node_id":"n1","label":"table","confidence":0.9}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should refuse graphs of shots the title does not have",
+		},
+		{
+			name:           "QC report",
+			path:           "/webhooks/pipeline/qc",
+			body:           `{"title_id":"1","run_id":"run_7","checks":[{"check":"tracking_stability","status":"failed","surface_id":"surface_9","score":0.2},{"check":"color_space","status":"passed"}]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should record the run's checks",
+		},
+		{
+			name:           "QC report without run",
+			path:           "/webhooks/pipeline/qc",
+			body:           `{"title_id":"1","checks":[{"check":"color_space","status":"passed"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require run_id to key the checks",
+		},
+		{
+			name:           "QC check with unknown status",
+			path:           "/webhooks/pipeline/qc",
+			body:           `{"title_id":"1","run_id":"run_7","checks":[{"check":"color_space","status":"maybe"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should validate check statuses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newPipelineRouter(newMockPipelineStore(), testPipelineSecret)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, signedPipelineCallback(tt.path, testPipelineSecret, "nonce-1", tt.body))
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, true, response["applied"])
+				assert.Equal(t, "1", response["title_id"])
+			}
+		})
+	}
+}
+
+func TestPipelineHandler_Signature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body := `{"title_id":"1","shots":[{"shot_id":"shot_002","start_time":4.5,"end_time":9}]}`
+
+	t.Run("unconfigured", func(t *testing.T) {
+		router := newPipelineRouter(newMockPipelineStore())
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, signedPipelineCallback("/webhooks/pipeline/shots", testPipelineSecret, "nonce-1", body))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.Code, "Should refuse callbacks without secrets")
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		router := newPipelineRouter(newMockPipelineStore(), testPipelineSecret)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, signedPipelineCallback("/webhooks/pipeline/shots", testRenderSecret, "nonce-1", body))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "Should not accept render farm secrets")
+	})
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		store := newMockPipelineStore()
+		router := newPipelineRouter(store, testPipelineSecret)
+
+		first := httptest.NewRecorder()
+		router.ServeHTTP(first, signedPipelineCallback("/webhooks/pipeline/shots", testPipelineSecret, "nonce-1", body))
+		require.Equal(t, http.StatusOK, first.Code)
+
+		replay := httptest.NewRecorder()
+		router.ServeHTTP(replay, signedPipelineCallback("/webhooks/pipeline/shots", testPipelineSecret, "nonce-1", body))
+		assert.Equal(t, http.StatusConflict, replay.Code, "Should reject reused nonces")
+
+		retry := httptest.NewRecorder()
+		router.ServeHTTP(retry, signedPipelineCallback("/webhooks/pipeline/shots", testPipelineSecret, "nonce-2", body))
+		assert.Equal(t, http.StatusOK, retry.Code, "Should accept a redelivery with a fresh nonce")
+		assert.Len(t, store.shots, 2, "Should upsert redelivered shots in place")
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		store := newMockPipelineStore()
+		store.shouldError = true
+		router := newPipelineRouter(store, testPipelineSecret)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, signedPipelineCallback("/webhooks/pipeline/shots", testPipelineSecret, "nonce-1", body))
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
@@ -1,22 +1,151 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/ack"
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
 	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/exposurequeue"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/resend"
+	"github.com/inscenium/inscenium/control/api/internal/rollup"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/throttle"
 	"github.com/sirupsen/logrus"
 )
 
-// PlacementHandler handles placement-related requests
+// AnalyticsStore serves the reporting queries behind the analytics endpoints
+type AnalyticsStore interface {
+	models.AnalyticsRepository
+}
+
+// ExposureAckStore numbers the exposure batches stored for each API key
+type ExposureAckStore interface {
+	RecordExposureAck(a *ack.Ack) error
+	ListExposureAcks(keyID string, after int64, limit int) (int64, []ack.Ack, error)
+}
+
+// PlacementHandler handles placement-related requests. Opportunities,
+// bookings and exposure events are served by the placement service; the
+// handler only parses requests and writes responses.
 type PlacementHandler struct {
-	db *db.DB
+	placements *placement.Service
+	analytics  AnalyticsStore
+	events     BookingEventStore
+	acks       ExposureAckStore
+	ingestion  IngestionPolicySource
+	authz      *authz.Authorizer
+
+	exposureQueue exposurequeue.Queue
+
+	pollMaxWait  time.Duration
+	pollInterval time.Duration
+	changes      *bookingWaiters // Wakes long polls on changes published or announced by Postgres
+
+	// mockData serves sample opportunities when the database has none
+	mockData bool
 }
 
 // NewPlacementHandler creates a new placement handler
 func NewPlacementHandler(database *db.DB) *PlacementHandler {
-	return &PlacementHandler{db: database}
+	return &PlacementHandler{placements: placement.NewService(database), analytics: database}
+}
+
+// Placements returns the service the handler serves placements with, for
+// callers outside HTTP such as the exposure queue's consumers
+func (h *PlacementHandler) Placements() *placement.Service {
+	return h.placements
+}
+
+// SetAnalyticsStore routes reporting queries to a different store
+func (h *PlacementHandler) SetAnalyticsStore(store AnalyticsStore) {
+	h.analytics = store
+}
+
+// SetAuthorizer enforces organization ownership and grants on bookings and campaigns
+func (h *PlacementHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// SetMockData serves sample opportunities when the database returns none.
+// It is meant for local development only.
+func (h *PlacementHandler) SetMockData(enabled bool) {
+	h.mockData = enabled
+}
+
+// SetExposureSink mirrors recorded exposure events to the given sink
+func (h *PlacementHandler) SetExposureSink(sink placement.Sink) {
+	h.placements.SetSink(sink)
+}
+
+// SetBookingEvents records booking lifecycle changes in the given event store
+func (h *PlacementHandler) SetBookingEvents(store BookingEventStore) {
+	h.events = store
+}
+
+// SetExposureBiller charges exposures of bookings billed on attention or
+// viewability as they are recorded
+func (h *PlacementHandler) SetExposureBiller(biller placement.Biller) {
+	h.placements.SetBiller(biller)
+}
+
+// SetExposureAcks acknowledges batches of exposure events sent with an API
+// key, so edge nodes know which buffered events are stored
+func (h *PlacementHandler) SetExposureAcks(store ExposureAckStore) {
+	h.acks = store
+}
+
+// SetIngestionPolicy advertises the ingestion policy in batch responses and
+// refuses batches larger than it allows
+func (h *PlacementHandler) SetIngestionPolicy(source IngestionPolicySource) {
+	h.ingestion = source
+}
+
+// SetExposureQueue queues exposure batches to be recorded by the queue's
+// consumers instead of while the request waits
+func (h *PlacementHandler) SetExposureQueue(queue exposurequeue.Queue) {
+	h.exposureQueue = queue
+}
+
+// SetResendWindow drops exposure beacons whose client_event_id was seen for
+// the same booking and viewer within the window
+func (h *PlacementHandler) SetResendWindow(window resend.Window) {
+	h.placements.SetResendWindow(window)
+}
+
+// SetFrequencyCounter counts recorded exposures against the frequency caps
+// of their booking and campaign
+func (h *PlacementHandler) SetFrequencyCounter(counter placement.FrequencyCounter) {
+	h.placements.SetFrequencyCounter(counter)
+}
+
+// billingModel names the billing model of a booking, which is CPM unless set
+func billingModel(model string) string {
+	if model == "" {
+		return billing.ModelCPM
+	}
+	return model
 }
 
 // PlacementOpportunity represents a placement opportunity (simplified)
@@ -35,59 +164,115 @@ type PlacementOpportunity struct {
 func (h *PlacementHandler) ListOpportunities(c *gin.Context) {
 	titleID := c.Query("title_id")
 	minPRSStr := c.DefaultQuery("min_prs", "0")
-	
+
 	minPRS, err := strconv.ParseFloat(minPRSStr, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_prs parameter"})
 		return
 	}
 
+	page, ok := parsePage(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	selector, ok := parseLabelSelector(c)
+	if !ok {
+		return
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"title_id": titleID,
 		"min_prs":  minPRS,
+		"labels":   selector.String(),
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"cursor":   page.Cursor != nil,
 	}).Info("Listing placement opportunities")
 
-	// TODO: Implement actual database query
-	// For now, return mock data
-	opportunities := []PlacementOpportunity{
+	opportunities, err := h.placements.ListOpportunities(titleID, minPRS, selector, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if errors.Is(err, query.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get placement opportunities")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if opportunities == nil {
+		opportunities = []models.Surface{}
+	}
+	opportunities, next, prev := pagination.Window(opportunities, page, opportunityCursor)
+
+	// Sample inventory is only ever served when DEV_MOCK_DATA opts in
+	if len(opportunities) == 0 && h.mockData && page.Offset == 0 && page.Cursor == nil && len(selector) == 0 {
+		opportunities = mockOpportunities(titleID, minPRS)
+	}
+
+	response := gin.H{
+		"opportunities": opportunities,
+		"total_count":   len(opportunities),
+		"limit":         page.Limit,
+		"offset":        page.Offset,
+		"filters": gin.H{
+			"title_id": titleID,
+			"min_prs":  minPRS,
+			"labels":   selector,
+		},
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
+
+// sampleCreatedAt is when the sample opportunities claim to have been detected
+var sampleCreatedAt = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+// mockOpportunities returns sample opportunities for local development
+// without a populated database
+func mockOpportunities(titleID string, minPRS float64) []models.Surface {
+	samples := []models.Surface{
 		{
-			ID:          "surface_001",
-			TitleID:     titleID,
-			ShotID:      "shot_001", 
-			StartTime:   5.2,
-			EndTime:     12.8,
-			PRSScore:    87.5,
-			SurfaceType: "wall",
-			CreatedAt:   "2024-01-15T10:30:00Z",
+			SurfaceID:       "surface_001",
+			TitleID:         titleID,
+			ShotID:          "shot_001",
+			StartTime:       5.2,
+			EndTime:         12.8,
+			Duration:        7.6,
+			SurfaceType:     "wall",
+			PlacementType:   audio.PlacementVisual,
+			PRSScore:        87.5,
+			VisibilityScore: 92.1,
+			CreatedAt:       sampleCreatedAt,
+			Labels:          labels.Set{},
 		},
 		{
-			ID:          "surface_002",
-			TitleID:     titleID,
-			ShotID:      "shot_002",
-			StartTime:   15.1,
-			EndTime:     23.4,
-			PRSScore:    92.1,
-			SurfaceType: "table",
-			CreatedAt:   "2024-01-15T10:30:00Z",
+			SurfaceID:       "surface_002",
+			TitleID:         titleID,
+			ShotID:          "shot_002",
+			StartTime:       15.1,
+			EndTime:         23.4,
+			Duration:        8.3,
+			SurfaceType:     "table",
+			PlacementType:   audio.PlacementVisual,
+			PRSScore:        92.1,
+			VisibilityScore: 88.7,
+			CreatedAt:       sampleCreatedAt,
+			Labels:          labels.Set{},
 		},
 	}
 
-	// Filter by minimum PRS score
-	filtered := make([]PlacementOpportunity, 0)
-	for _, opp := range opportunities {
+	filtered := make([]models.Surface, 0, len(samples))
+	for _, opp := range samples {
 		if opp.PRSScore >= minPRS {
 			filtered = append(filtered, opp)
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"opportunities": filtered,
-		"total_count":   len(filtered),
-		"filters": gin.H{
-			"title_id": titleID,
-			"min_prs":  minPRS,
-		},
-	})
+	return filtered
 }
 
 // GetOpportunity handles GET /opportunities/:id
@@ -113,19 +298,44 @@ func (h *PlacementHandler) GetOpportunity(c *gin.Context) {
 
 // BookPlacement handles POST /bookings
 func (h *PlacementHandler) BookPlacement(c *gin.Context) {
-	var booking struct {
-		SurfaceID     string  `json:"surface_id" binding:"required"`
-		AdvertiserID  string  `json:"advertiser_id" binding:"required"`
-		CampaignID    string  `json:"campaign_id" binding:"required"`
-		BidAmountCPM  float64 `json:"bid_amount_cpm" binding:"required"`
-		MaxImpressions int    `json:"max_impressions"`
-		MinPRSScore   float64 `json:"min_prs_score"`
+	var booking schema.BookingRequest
+	if err := schema.BindJSON(c, &booking); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if err := c.ShouldBindJSON(&booking); err != nil {
+	if err := booking.Labels.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateExternalIDs(booking.ExternalIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := billing.ValidateModel(booking.BillingModel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := collision.ValidateBundleID(booking.BundleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := bookingWindow(&booking).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pacing.Validate(booking.Pacing, booking.MaxImpressions, booking.StartTime, booking.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if booking.FrequencyCap != nil {
+		if err := booking.FrequencyCap.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.authz.Authorize(c, labels.ResourceCampaign, booking.CampaignID, authz.PermissionManage) {
+		return
+	}
 
 	logrus.WithFields(logrus.Fields{
 		"surface_id":    booking.SurfaceID,
@@ -134,155 +344,724 @@ func (h *PlacementHandler) BookPlacement(c *gin.Context) {
 		"bid_cpm":       booking.BidAmountCPM,
 	}).Info("Booking placement")
 
-	// Create booking data map
-	bookingData := map[string]interface{}{
-		"surface_id":      booking.SurfaceID,
-		"advertiser_id":   booking.AdvertiserID,
-		"campaign_id":     booking.CampaignID,
-		"bid_amount_cpm":  booking.BidAmountCPM,
-		"max_impressions": booking.MaxImpressions,
-		"min_prs_score":   booking.MinPRSScore,
+	bookingID, err := h.placements.CreateBooking(&models.NewBooking{
+		SurfaceID:      booking.SurfaceID,
+		AdvertiserID:   booking.AdvertiserID,
+		CampaignID:     booking.CampaignID,
+		BidAmountCPM:   booking.BidAmountCPM,
+		MaxImpressions: booking.MaxImpressions,
+		MinPRSScore:    booking.MinPRSScore,
+		BillingModel:   booking.BillingModel,
+		BundleID:       booking.BundleID,
+		Pacing:         booking.Pacing,
+		FrequencyCap:   booking.FrequencyCap,
+		StartTime:      booking.StartTime,
+		EndTime:        booking.EndTime,
+		Labels:         booking.Labels,
+		ExternalIDs:    booking.ExternalIDs,
+		OrgID:          c.GetString("org_id"),
+		UserID:         c.GetString("user_id"),
+	})
+	if respondBookingConflict(c, err) {
+		return
+	}
+	if errors.Is(err, db.ErrExternalIDTaken) || errors.Is(err, holdback.ErrHeldBack) || errors.Is(err, dedupe.ErrSurfaceMerged) ||
+		errors.Is(err, collision.ErrCollision) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, campaign.ErrNotBookable) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
 	}
-
-	bookingID, err := h.db.CreatePlacementBooking(bookingData)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to create placement booking")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"booking_id":            bookingID,
-		"status":                "confirmed",
-		"message":               "Placement booked successfully",
-		"confirmation_time":     "2024-01-15T10:35:00Z",
-		"final_cmp_rate":        booking.BidAmountCPM,
-		"estimated_impressions": booking.MaxImpressions,
+	schema.Render(c, http.StatusCreated, schema.BookingConfirmation{
+		BookingID:            bookingID,
+		Status:               "confirmed", // New bookings skip pending approval
+		Message:              "Placement booked successfully",
+		ConfirmationTime:     time.Now().UTC().Format(time.RFC3339),
+		FinalCPMRate:         booking.BidAmountCPM,
+		BillingModel:         billingModel(booking.BillingModel),
+		BundleID:             booking.BundleID,
+		Pacing:               pacing.Curve(booking.Pacing),
+		FrequencyCap:         booking.FrequencyCap,
+		StartTime:            booking.StartTime,
+		EndTime:              booking.EndTime,
+		EstimatedImpressions: booking.MaxImpressions,
+	})
+}
+
+// bookingWindow returns the exposure window of a booking request
+func bookingWindow(req *schema.BookingRequest) booking.Window {
+	return booking.Window{Start: req.StartTime, End: req.EndTime}
+}
+
+// respondBookingConflict writes a 409 for a booking refused for overlapping a
+// live booking of its surface, naming that booking when it is known and the
+// caller may read it; other organizations' bookings are reduced to their
+// window. It reports whether err was such a conflict.
+func respondBookingConflict(c *gin.Context, err error) bool {
+	var conflict *booking.ConflictError
+	if errors.As(err, &conflict) {
+		if !authz.Scope(c).Allows(conflict.OrgID) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicting_window": conflict.Window})
+			return true
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicting_booking": conflict, "conflicting_window": conflict.Window})
+		return true
+	}
+	if errors.Is(err, booking.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// ListBookings handles GET /bookings
+func (h *PlacementHandler) ListBookings(c *gin.Context) {
+	campaignID := c.Query("campaign_id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	selector, ok := parseLabelSelector(c)
+	if !ok {
+		return
+	}
+	asOf, historical, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	if historical && (campaignID == "" || len(selector) > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "as_of requires campaign_id and cannot be combined with labels"})
+		return
+	}
+
+	if !h.authz.Authorize(c, labels.ResourceCampaign, campaignID, authz.PermissionView) {
+		return
+	}
+
+	if historical {
+		h.listBookingsAsOf(c, campaignID, asOf, limit, offset)
+		return
+	}
+
+	bookings, err := h.placements.ListBookings(authz.Scope(c), campaignID, selector, limit, offset)
+	if errors.Is(err, query.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list placement bookings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings":    bookings,
+		"total_count": len(bookings),
+		"limit":       limit,
+		"offset":      offset,
+		"filters": gin.H{
+			"campaign_id": campaignID,
+			"labels":      selector,
+		},
 	})
 }
 
-// GetBooking handles GET /bookings/:id
+// listBookingsAsOf lists a campaign's bookings as they stood at asOf, projected
+// from the booking event streams
+func (h *PlacementHandler) listBookingsAsOf(c *gin.Context, campaignID string, asOf time.Time, limit, offset int) {
+	if h.events == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "as_of reads are not available"})
+		return
+	}
+
+	events, err := h.events.ListCampaignBookingEvents(campaignID, asOf)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list campaign booking events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	states, err := booking.ProjectAll(events, asOf)
+	if err != nil {
+		logrus.WithError(err).WithField("campaign_id", campaignID).Error("Booking event stream is inconsistent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Bookings may have moved between campaigns; keep those in it at asOf
+	bookings := make([]*booking.State, 0, len(states))
+	for _, state := range states {
+		if state.CampaignID == campaignID {
+			bookings = append(bookings, state)
+		}
+	}
+	total := len(bookings)
+	if offset > total {
+		offset = total
+	}
+	bookings = bookings[offset:min(offset+limit, total)]
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings":    bookings,
+		"total_count": total,
+		"limit":       limit,
+		"offset":      offset,
+		"as_of":       asOf,
+		"filters": gin.H{
+			"campaign_id": campaignID,
+		},
+	})
+}
+
+// GetBooking handles GET /bookings/:id. It returns the booking as stored,
+// within the caller's tenant scope. With as_of it returns the booking's
+// state at that time, projected from its event stream. Given the version a
+// caller has (If-Event-Newer-Than or If-None-Match), it returns the current
+// state only once the stream is past it, waiting up to wait seconds.
 func (h *PlacementHandler) GetBooking(c *gin.Context) {
 	id := c.Param("id")
 
-	logrus.WithField("booking_id", id).Info("Getting booking status")
+	asOf, historical, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	newerThan, conditional, ok := parseNewerThan(c)
+	if !ok {
+		return
+	}
+	wait, ok := h.parseWait(c)
+	if !ok {
+		return
+	}
+	if historical {
+		if conditional || wait > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "as_of cannot be combined with waiting for changes"})
+			return
+		}
+		h.getBookingAsOf(c, id, asOf)
+		return
+	}
+	if conditional || wait > 0 {
+		h.pollBooking(c, id, newerThan, wait)
+		return
+	}
+
+	b, err := h.placements.GetBooking(authz.Scope(c), id)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to get placement booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"booking": b})
+}
+
+// getBookingAsOf returns a booking's state at asOf
+func (h *PlacementHandler) getBookingAsOf(c *gin.Context, id string, asOf time.Time) {
+	if h.events == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "as_of reads are not available"})
+		return
+	}
+
+	events, err := h.events.ListBookingEvents(id)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to list booking events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	state, err := booking.ProjectAsOf(events, asOf)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Booking event stream is inconsistent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking did not exist at as_of"})
+		return
+	}
 
-	// TODO: Implement actual database lookup
 	c.JSON(http.StatusOK, gin.H{
-		"booking_id":            id,
-		"status":               "active",
-		"placement_id":         "surface_001",
-		"confirmation_time":    "2024-01-15T10:35:00Z",
-		"final_cpm_rate":       5.50,
-		"estimated_impressions": 1000,
-		"actual_impressions":    847,
+		"as_of":   asOf,
+		"booking": state,
 	})
 }
 
-// CancelBooking handles DELETE /bookings/:id
+// CancelBooking handles DELETE /bookings/:id. The route only lets admins
+// cancel bookings outside the caller's organization and its grants.
 func (h *PlacementHandler) CancelBooking(c *gin.Context) {
 	id := c.Param("id")
 
 	logrus.WithField("booking_id", id).Info("Cancelling booking")
 
-	// TODO: Implement actual cancellation logic
+	if h.events == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Booking cancellation is not available"})
+		return
+	}
+
+	state, err := h.events.AppendBookingEvent(booking.Event{
+		BookingID: id,
+		Type:      booking.EventCancelled,
+		Actor:     c.GetString("user_id"),
+		OrgID:     c.GetString("org_id"),
+		Data:      booking.Change{Reason: c.Query("reason")},
+	})
+	if errors.Is(err, db.ErrBookingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if errors.Is(err, booking.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", id).Error("Failed to cancel booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":      true,
 		"message":      "Booking cancelled successfully",
-		"cancelled_at": "2024-01-15T11:00:00Z",
+		"cancelled_at": state.CancelledAt.Format(time.RFC3339),
 	})
 }
 
-// RecordExposure handles POST /events/exposure
-func (h *PlacementHandler) RecordExposure(c *gin.Context) {
-	var exposure struct {
-		BookingID        string  `json:"booking_id" binding:"required"`
-		ViewerID         string  `json:"viewer_id" binding:"required"`
-		ExposureDuration float64 `json:"exposure_duration" binding:"required"`
-		ScreenCoverage   float64 `json:"screen_coverage"`
-		AttentionScore   float64 `json:"attention_score"`
+// exposureEvent converts an exposure request into the event stored by the database
+func exposureEvent(exposure schema.ExposureRequest, ts ExposureTimestamps) models.ExposureEvent {
+	return models.ExposureEvent{
+		BookingID:        exposure.BookingID,
+		ViewerID:         exposure.ViewerID,
+		ClientEventID:    exposure.ClientEventID,
+		Timestamp:        ts.Corrected,
+		DeviceTimestamp:  ts.Raw,
+		ReceivedAt:       ts.ReceivedAt,
+		ClockSkewMS:      ts.Skew.Milliseconds(),
+		ExposureDuration: exposure.ExposureDuration,
+		ScreenCoverage:   exposure.ScreenCoverage,
+		AttentionScore:   exposure.AttentionScore,
+		DeviceType:       exposure.DeviceType,
+		ConsentString:    exposure.ConsentString,
+		Counted:          true,
+	}
+}
+
+// mayRecordExposures reports whether actor may record exposures of a
+// booking, which takes the same permission as reading its metrics: the
+// booking's organization, a grant from it, or the admin role. Decisions are
+// kept in allowed, so a batch checks each booking once.
+func (h *PlacementHandler) mayRecordExposures(actor authz.Actor, bookingID string, allowed map[string]bool) (bool, error) {
+	if h.authz == nil {
+		return true, nil
 	}
+	if ok, decided := allowed[bookingID]; decided {
+		return ok, nil
+	}
+	decision, err := h.authz.Check(actor, labels.ResourceBooking, bookingID, authz.PermissionView)
+	if err != nil {
+		return false, err
+	}
+	allowed[bookingID] = decision.Allowed
+	return decision.Allowed, nil
+}
+
+// RecordExposure handles POST /events/exposure. Exposures may only be
+// recorded for bookings the caller may read, so one organization cannot add
+// to another's figures.
+func (h *PlacementHandler) RecordExposure(c *gin.Context) {
+	receivedAt := time.Now().UTC()
 
-	if err := c.ShouldBindJSON(&exposure); err != nil {
+	var exposure schema.ExposureRequest
+	if err := schema.BindJSON(c, &exposure); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !h.authz.Authorize(c, labels.ResourceBooking, exposure.BookingID, authz.PermissionView) {
+		return
+	}
+
+	ts := correctExposureTimestamps(exposure.Timestamp, exposure.DeviceClock, receivedAt)
 
 	logrus.WithFields(logrus.Fields{
 		"booking_id":        exposure.BookingID,
 		"exposure_duration": exposure.ExposureDuration,
 		"screen_coverage":   exposure.ScreenCoverage,
+		"clock_skew_ms":     ts.Skew.Milliseconds(),
 	}).Info("Recording exposure event")
 
-	// TODO: Implement actual event recording
-	eventID := "event_" + exposure.BookingID + "_001"
+	event := exposureEvent(exposure, ts)
+	eventID, err := h.placements.RecordExposure(c.Request.Context(), &event)
+	if errors.Is(err, db.ErrDuplicateExposure) {
+		c.JSON(http.StatusOK, gin.H{
+			"success":   true,
+			"duplicate": true,
+			"message":   "Exposure already recorded; resend ignored",
+		})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to record exposure event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record exposure"})
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"success":  true,
-		"event_id": eventID,
-		"message":  "Exposure recorded successfully",
-	})
+	response := gin.H{
+		"success":         true,
+		"event_id":        eventID,
+		"counted":         event.Counted,
+		"message":         "Exposure recorded successfully",
+		"event_timestamp": ts.Corrected.Format(time.RFC3339Nano),
+		"clock_skew_ms":   ts.Skew.Milliseconds(),
+	}
+	if event.ListenThrough != nil {
+		response["listen_through"] = *event.ListenThrough
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
-// BatchRecordExposures handles POST /events/exposure/batch
+// BatchRecordExposures handles POST /events/exposure/batch. Events are
+// validated one by one, so an invalid event is reported in failures without
+// holding back the rest. With an exposure queue the valid events are queued
+// and the batch is answered 202 once they are durable; otherwise they are
+// written together and answered 201.
 func (h *PlacementHandler) BatchRecordExposures(c *gin.Context) {
+	receivedAt := time.Now().UTC()
+
 	var batch struct {
-		Events []map[string]interface{} `json:"events" binding:"required"`
+		Events        []json.RawMessage `json:"events" binding:"required"`
+		DeviceClock   *time.Time        `json:"device_clock"`
+		SequenceToken string            `json:"sequence_token"` // Edge node's buffer position, echoed in the acknowledgement
 	}
 
-	if err := c.ShouldBindJSON(&batch); err != nil {
+	if err := schema.BindJSON(c, &batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ack.ValidateToken(batch.SequenceToken); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Nothing of a batch past the policy is stored, so the node splits it
+	// and sends the parts at the policy's pace
+	policy := throttle.DefaultPolicy()
+	if h.ingestion != nil {
+		policy = h.ingestion.Current()
+	}
+	if len(batch.Events) > policy.MaxBatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":            "Batch exceeds the ingestion policy's max_batch_size",
+			"ingestion_policy": policy,
+		})
+		return
+	}
+
 	logrus.WithField("event_count", len(batch.Events)).Info("Recording batch exposure events")
 
-	// TODO: Implement actual batch processing
-	c.JSON(http.StatusCreated, gin.H{
-		"processed_count": len(batch.Events),
-		"failed_count":    0,
-		"message":        "Batch processed successfully",
-	})
+	failures := make([]exposurequeue.Failure, 0)
+	events := make([]models.ExposureEvent, 0, len(batch.Events))
+	indexes := make([]int, 0, len(batch.Events)) // Batch index of each valid event
+	actor, allowed := authz.ActorFromContext(c), make(map[string]bool)
+	for i, raw := range batch.Events {
+		var exposure schema.ExposureRequest
+		if err := schema.Decode(c, raw, &exposure); err != nil {
+			failures = append(failures, exposurequeue.Failure{Index: i, Error: err.Error()})
+			continue
+		}
+		ok, err := h.mayRecordExposures(actor, exposure.BookingID, allowed)
+		if err != nil {
+			logrus.WithError(err).Error("Authorization check failed")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if !ok {
+			failures = append(failures, exposurequeue.Failure{Index: i, Error: "Not permitted to record exposures of booking " + exposure.BookingID})
+			continue
+		}
+
+		// A per-event device clock wins over the batch-level one
+		deviceClock := exposure.DeviceClock
+		if deviceClock == nil {
+			deviceClock = batch.DeviceClock
+		}
+		events = append(events, exposureEvent(exposure, correctExposureTimestamps(exposure.Timestamp, deviceClock, receivedAt)))
+		indexes = append(indexes, i)
+	}
+
+	// Resent beacons seen within the window are dropped before anything is
+	// charged or queued
+	duplicates := 0
+	fresh, freshIndexes := events[:0:0], indexes[:0:0]
+	for i, duplicate := range h.placements.CheckResends(c.Request.Context(), events) {
+		if duplicate {
+			duplicates++
+			continue
+		}
+		fresh, freshIndexes = append(fresh, events[i]), append(freshIndexes, indexes[i])
+	}
+	events, indexes = fresh, freshIndexes
+
+	status := http.StatusCreated
+	response := gin.H{"ingestion_policy": policy}
+	stored := 0
+	if h.exposureQueue != nil {
+		queued := &exposurequeue.Batch{
+			ID:         fmt.Sprintf("batch_%d", receivedAt.UnixNano()),
+			KeyID:      c.GetString("key_id"),
+			Events:     events,
+			ReceivedAt: receivedAt,
+		}
+		if len(events) > 0 {
+			if err := h.exposureQueue.Publish(c.Request.Context(), queued); err != nil {
+				h.placements.ForgetResends(c.Request.Context(), events)
+				logrus.WithError(err).WithField("event_count", len(events)).Error("Failed to queue exposure batch")
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Exposure queue unavailable; retry the batch"})
+				return
+			}
+		}
+		status = http.StatusAccepted
+		stored = len(events)
+		response["batch_id"] = queued.ID
+		response["queued_count"] = stored
+		response["message"] = "Batch queued for recording"
+	} else {
+		recorded, err := h.placements.RecordExposures(c.Request.Context(), events, true)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to record exposure batch")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record exposures"})
+			return
+		}
+		uncounted := 0
+		for _, f := range recorded.Failures {
+			f.Index = indexes[f.Index]
+			failures = append(failures, f)
+		}
+		for _, event := range recorded.Events {
+			if !event.Counted {
+				uncounted++
+			}
+		}
+		duplicates += len(recorded.Duplicates)
+		stored = len(recorded.Events)
+		response["processed_count"] = stored
+		response["uncounted_count"] = uncounted
+		response["message"] = "Batch processed successfully"
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	failedIndexes := make([]int, len(failures))
+	for i, f := range failures {
+		failedIndexes[i] = f.Index
+	}
+	response["duplicate_count"] = duplicates
+	response["failed_count"] = len(failures)
+	response["failed_indexes"] = failedIndexes
+	response["failures"] = failures
+
+	// Only acknowledged once the events are stored, or durably queued; a
+	// batch whose acknowledgement is lost is resent, which stores its events
+	// again unless they carry client event IDs
+	if keyID := c.GetString("key_id"); h.acks != nil && keyID != "" {
+		a := &ack.Ack{KeyID: keyID, Token: batch.SequenceToken, StoredCount: stored, FailedCount: len(failures)}
+		if err := h.acks.RecordExposureAck(a); err != nil {
+			logrus.WithError(err).WithField("key_id", keyID).Error("Failed to acknowledge exposure batch")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		response["ack_sequence"] = a.Sequence
+		if a.Token != "" {
+			response["sequence_token"] = a.Token
+		}
+	}
+
+	c.JSON(status, response)
 }
 
-// GetMetrics handles GET /analytics/metrics/:booking_id
+// ListExposureAcks handles GET /events/exposure/acks. It returns the API
+// key's last acknowledgement sequence and pages through its
+// acknowledgements after the sequence in after, oldest first.
+func (h *PlacementHandler) ListExposureAcks(c *gin.Context) {
+	keyID := c.GetString("key_id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Acknowledgements are kept per API key; authenticate with a service account key"})
+		return
+	}
+	if h.acks == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Acknowledgements are not available"})
+		return
+	}
+
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a non-negative acknowledgement sequence"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > ack.Retained {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(ack.Retained)})
+		return
+	}
+
+	last, acks, err := h.acks.ListExposureAcks(keyID, after, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("key_id", keyID).Error("Failed to list exposure acknowledgements")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := gin.H{
+		"last_sequence": last,
+		"acks":          acks,
+		"total_count":   len(acks),
+	}
+	if len(acks) == limit && acks[len(acks)-1].Sequence < last {
+		response["next_after"] = acks[len(acks)-1].Sequence
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMetrics handles GET /analytics/metrics/:booking_id. With from, to or
+// granularity the figures cover that range, aligned to its buckets, and a
+// series breaks them down by hour or day.
 func (h *PlacementHandler) GetMetrics(c *gin.Context) {
 	bookingID := c.Param("booking_id")
 
+	var series *rollup.Range
+	if c.Query("from") != "" || c.Query("to") != "" || c.Query("granularity") != "" {
+		r, err := parseMetricsRange(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		series = &r
+	}
+
 	logrus.WithField("booking_id", bookingID).Info("Getting analytics metrics")
 
-	// TODO: Implement actual metrics calculation
-	c.JSON(http.StatusOK, gin.H{
-		"booking_id":              bookingID,
-		"total_impressions":       847,
-		"unique_viewers":          623,
-		"total_exposure_time":     4235.6,
-		"average_exposure_time":   5.2,
-		"average_prs_score":       89.3,
-		"average_attention_score": 0.74,
-		"average_screen_coverage": 23.8,
-	})
+	scope := authz.Scope(c)
+	filter := quality.Filter{Tenant: scope, BookingID: bookingID}
+	if series != nil {
+		filter.From, filter.To = series.From, series.To
+	}
+	metrics, err := h.analytics.GetBookingMetrics(scope, bookingID, filter.From, filter.To)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get analytics metrics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if metrics == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	if series != nil {
+		buckets, rolledUpThrough, err := h.analytics.GetBookingSeries(scope, bookingID, series.Granularity, series.From, series.To)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to get analytics series")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		metrics.From, metrics.To = &series.From, &series.To
+		metrics.Granularity = series.Granularity
+		metrics.Series = rollup.Fill(*series, buckets)
+		metrics.RolledUpThrough = rolledUpThrough
+	}
+
+	metrics.DataQuality, err = h.analytics.GetDataQuality(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get data quality")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// parseMetricsRange reads the range of a metrics series. It defaults to the
+// last 7 days at daily granularity.
+func parseMetricsRange(c *gin.Context) (rollup.Range, error) {
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return rollup.Range{}, errors.New("invalid to parameter, expected RFC3339")
+		}
+		to = parsed
+	}
+	from := to.Add(-7 * 24 * time.Hour)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return rollup.Range{}, errors.New("invalid from parameter, expected RFC3339")
+		}
+		from = parsed
+	}
+	return rollup.NewRange(from, to, c.DefaultQuery("granularity", rollup.GranularityDay))
 }
 
-// GetExposureEvents handles GET /analytics/events/:booking_id
+// GetExposureEvents handles GET /analytics/events/:booking_id. Every stored
+// event is listed, including those data_quality reports as left out of the
+// booking's figures.
 func (h *PlacementHandler) GetExposureEvents(c *gin.Context) {
 	bookingID := c.Param("booking_id")
 
+	page, ok := parsePage(c, 100, 1000)
+	if !ok {
+		return
+	}
+
 	logrus.WithField("booking_id", bookingID).Info("Getting exposure events")
 
-	// TODO: Implement actual event retrieval
-	c.JSON(http.StatusOK, gin.H{
-		"booking_id": bookingID,
-		"events": []gin.H{
-			{
-				"event_id":          "event_001",
-				"viewer_id":         "viewer_abc123",
-				"timestamp":         "2024-01-15T10:45:00Z",
-				"exposure_duration": 6.2,
-				"screen_coverage":   25.4,
-				"attention_score":   0.82,
-			},
-		},
-		"total_count": 1,
+	scope := authz.Scope(c)
+	events, err := h.analytics.GetExposureEvents(scope, bookingID, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if errors.Is(err, query.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get exposure events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	report, err := h.analytics.GetDataQuality(quality.Filter{Tenant: scope, BookingID: bookingID})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get data quality")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	events, next, prev := pagination.Window(events, page, func(e models.ExposureEvent) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(e.Timestamp), ID: e.EventID}
 	})
-}
\ No newline at end of file
+
+	response := gin.H{
+		"booking_id":   bookingID,
+		"events":       events,
+		"total_count":  len(events),
+		"limit":        page.Limit,
+		"offset":       page.Offset,
+		"data_quality": report,
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
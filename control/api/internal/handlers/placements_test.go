@@ -2,14 +2,34 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/ack"
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/collision"
 	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/exposurequeue"
+	"github.com/inscenium/inscenium/control/api/internal/holdback"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/resend"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,74 +37,253 @@ import (
 // MockPlacementDB extends MockDB for placement-specific methods
 type MockPlacementDB struct {
 	*db.DB
-	opportunities []map[string]interface{}
-	opportunity   map[string]interface{}
-	booking       map[string]interface{}
+	opportunities []models.Surface
+	opportunity   *models.Surface
+	booking       *models.Booking
+	created       *models.NewBooking
 	bookingID     string
+	events        []models.ExposureEvent
+	metrics       *models.BookingMetrics
+	metricsRange  [2]time.Time
+	series        []models.MetricsBucket
+	seriesQuery   string // Granularity, from and to of the last series asked for
+	rolledUpTo    *time.Time
+	bookings      []models.Booking
+	selector      labels.Set
+	scope         tenant.Scope
+	limit         int
+	offset        int
+	cursor        *pagination.Cursor
+	createErr     error
+	listErr       error
+	quality       *quality.Report
+	qualityFilter quality.Filter
+	qualityErr    error
 	shouldError   bool
+	goneBooking   string // Booking recording fails for, as if they did not exist
 }
 
-func (m *MockPlacementDB) GetPlacementOpportunities(titleID string, minPRS float64, limit, offset int) ([]map[string]interface{}, error) {
+func (m *MockPlacementDB) GetPlacementOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]models.Surface, error) {
 	if m.shouldError {
 		return nil, assert.AnError
 	}
-	return m.opportunities, nil
+	m.limit, m.offset, m.cursor = page.Limit, page.Offset, page.Cursor
+	if page.Cursor == nil {
+		return m.opportunities, nil
+	}
+
+	// Emulate the keyset query over opportunities sorted by PRS score descending
+	key, err := page.Cursor.Float()
+	if err != nil {
+		return nil, err
+	}
+	var rows []models.Surface
+	for _, s := range m.opportunities {
+		after := s.PRSScore < key || s.PRSScore == key && s.SurfaceID < page.Cursor.ID
+		before := s.PRSScore > key || s.PRSScore == key && s.SurfaceID > page.Cursor.ID
+		if page.Cursor.Before && before {
+			rows = append([]models.Surface{s}, rows...)
+		} else if !page.Cursor.Before && after {
+			rows = append(rows, s)
+		}
+	}
+	if len(rows) > page.Fetch() {
+		rows = rows[:page.Fetch()]
+	}
+	return rows, nil
 }
 
-func (m *MockPlacementDB) GetPlacementOpportunity(surfaceID string) (map[string]interface{}, error) {
+func (m *MockPlacementDB) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
 	if m.shouldError {
 		return nil, assert.AnError
 	}
 	return m.opportunity, nil
 }
 
-func (m *MockPlacementDB) CreatePlacementBooking(booking map[string]interface{}) (string, error) {
+func (m *MockPlacementDB) CreatePlacementBooking(booking *models.NewBooking) (string, error) {
 	if m.shouldError {
 		return "", assert.AnError
 	}
+	m.created = booking
+	if m.createErr != nil {
+		return "", m.createErr
+	}
 	return m.bookingID, nil
 }
 
-func (m *MockPlacementDB) GetPlacementBooking(bookingID string) (map[string]interface{}, error) {
+func (m *MockPlacementDB) GetPlacementBooking(scope tenant.Scope, bookingID string) (*models.Booking, error) {
 	if m.shouldError {
 		return nil, assert.AnError
 	}
+	m.scope = scope
 	return m.booking, nil
 }
 
+func (m *MockPlacementDB) ListPlacementBookings(scope tenant.Scope, campaignID string, selector labels.Set, limit, offset int) ([]models.Booking, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.scope, m.selector = scope, selector
+	return m.bookings, m.listErr
+}
+
+func (m *MockPlacementDB) RecordExposureEvent(event *models.ExposureEvent) (string, error) {
+	eventIDs, err := m.RecordExposureEvents([]*models.ExposureEvent{event})
+	if err != nil {
+		return "", err
+	}
+	if eventIDs[0] == "" {
+		return "", db.ErrDuplicateExposure
+	}
+	return eventIDs[0], nil
+}
+
+func (m *MockPlacementDB) RecordExposureEvents(events []*models.ExposureEvent) ([]string, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	// Mimics the unique constraint on resend keys
+	stored := make(map[string]bool)
+	for _, event := range m.events {
+		stored[resend.Key(event.BookingID, event.ViewerID, event.ClientEventID)] = true
+	}
+	eventIDs := make([]string, len(events))
+	for i, event := range events {
+		if m.goneBooking != "" && event.BookingID == m.goneBooking {
+			return nil, db.ErrBookingNotFound
+		}
+		if key := resend.Key(event.BookingID, event.ViewerID, event.ClientEventID); key != "" {
+			if stored[key] {
+				continue
+			}
+			stored[key] = true
+		}
+		eventIDs[i] = "event_" + event.BookingID
+	}
+	for i, event := range events {
+		if eventIDs[i] != "" {
+			m.events = append(m.events, *event)
+		}
+	}
+	return eventIDs, nil
+}
+
+func (m *MockPlacementDB) GetBookingMetrics(scope tenant.Scope, bookingID string, from, to time.Time) (*models.BookingMetrics, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.scope = scope
+	m.metricsRange = [2]time.Time{from, to}
+	return m.metrics, nil
+}
+
+func (m *MockPlacementDB) GetBookingSeries(scope tenant.Scope, bookingID, granularity string, from, to time.Time) ([]models.MetricsBucket, *time.Time, error) {
+	m.seriesQuery = granularity + " " + from.Format(time.RFC3339) + " " + to.Format(time.RFC3339)
+	return m.series, m.rolledUpTo, nil
+}
+
+func (m *MockPlacementDB) GetExposureEvents(scope tenant.Scope, bookingID string, page pagination.Page) ([]models.ExposureEvent, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.scope = scope
+	return m.events, nil
+}
+
+func (m *MockPlacementDB) GetDataQuality(f quality.Filter) (*quality.Report, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.qualityFilter = f
+	if m.qualityErr != nil {
+		return nil, m.qualityErr
+	}
+	if m.quality == nil {
+		return quality.NewReport(0, 0, 0, nil, time.Now()), nil
+	}
+	return m.quality, nil
+}
+
+type MockExposureSink struct {
+	events []models.ExposureEvent
+}
+
+func (m *MockExposureSink) Enqueue(event models.ExposureEvent) {
+	m.events = append(m.events, event)
+}
+
 func TestPlacementHandler_ListOpportunities(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
+	stored := []models.Surface{
+		{SurfaceID: "surface_db_1", TitleID: "title_001", PRSScore: 91.0, SurfaceType: "wall"},
+	}
+
 	tests := []struct {
-		name           string
-		queryParams    string
-		expectedStatus int
-		description    string
+		name            string
+		queryParams     string
+		mockDB          *MockPlacementDB
+		mockData        bool
+		expectedStatus  int
+		expectedSurface []string
+		description     string
 	}{
 		{
-			name:           "list opportunities with no filters",
-			queryParams:    "",
-			expectedStatus: http.StatusOK,
-			description:    "Should return mock opportunities",
+			name:            "list opportunities from the database",
+			queryParams:     "?title_id=title_001&min_prs=80",
+			mockDB:          &MockPlacementDB{opportunities: stored},
+			expectedStatus:  http.StatusOK,
+			expectedSurface: []string{"surface_db_1"},
+			description:     "Should return the stored opportunities",
 		},
 		{
-			name:           "list opportunities with title filter",
-			queryParams:    "?title_id=title_001&min_prs=80",
-			expectedStatus: http.StatusOK,
-			description:    "Should filter opportunities by title and PRS score",
+			name:            "empty database",
+			queryParams:     "",
+			mockDB:          &MockPlacementDB{},
+			expectedStatus:  http.StatusOK,
+			expectedSurface: []string{},
+			description:     "Should never serve sample inventory by default",
+		},
+		{
+			name:            "empty database with mock data enabled",
+			queryParams:     "?min_prs=90",
+			mockDB:          &MockPlacementDB{},
+			mockData:        true,
+			expectedStatus:  http.StatusOK,
+			expectedSurface: []string{"surface_002"},
+			description:     "Should serve filtered sample inventory when DEV_MOCK_DATA is set",
+		},
+		{
+			name:            "stored opportunities with mock data enabled",
+			queryParams:     "",
+			mockDB:          &MockPlacementDB{opportunities: stored},
+			mockData:        true,
+			expectedStatus:  http.StatusOK,
+			expectedSurface: []string{"surface_db_1"},
+			description:     "Should prefer stored opportunities over samples",
 		},
 		{
 			name:           "list opportunities with invalid min_prs",
 			queryParams:    "?min_prs=invalid",
+			mockDB:         &MockPlacementDB{},
 			expectedStatus: http.StatusBadRequest,
 			description:    "Should return error for invalid min_prs parameter",
 		},
+		{
+			name:           "database error",
+			queryParams:    "",
+			mockDB:         &MockPlacementDB{shouldError: true},
+			mockData:       true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should not hide database failures behind sample data",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			// Setup handler with mock database
+			handler := &PlacementHandler{placements: placement.NewService(tt.mockDB)}
+			handler.SetMockData(tt.mockData)
 			router := gin.New()
 			router.GET("/opportunities", handler.ListOpportunities)
 
@@ -98,35 +297,118 @@ func TestPlacementHandler_ListOpportunities(t *testing.T) {
 			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
 
 			if tt.expectedStatus == http.StatusOK {
-				// Parse response
-				var response map[string]interface{}
-				err := json.Unmarshal(resp.Body.Bytes(), &response)
-				require.NoError(t, err)
+				var response struct {
+					Opportunities []map[string]interface{} `json:"opportunities"`
+					TotalCount    int                      `json:"total_count"`
+					Limit         int                      `json:"limit"`
+					Offset        int                      `json:"offset"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
 
-				// Check response structure
-				assert.Contains(t, response, "opportunities")
-				assert.Contains(t, response, "total_count")
-				assert.Contains(t, response, "filters")
-
-				// Validate opportunities array
-				opportunities, ok := response["opportunities"].([]interface{})
-				assert.True(t, ok, "Opportunities should be an array")
-
-				// Validate each opportunity has required fields
-				for i, opp := range opportunities {
-					oppMap, ok := opp.(map[string]interface{})
-					assert.True(t, ok, "Opportunity %d should be object", i)
-					
-					assert.Contains(t, oppMap, "id", "Opportunity %d should have id", i)
-					assert.Contains(t, oppMap, "title_id", "Opportunity %d should have title_id", i)
-					assert.Contains(t, oppMap, "prs_score", "Opportunity %d should have prs_score", i)
-					assert.Contains(t, oppMap, "surface_type", "Opportunity %d should have surface_type", i)
+				surfaces := make([]string, 0, len(response.Opportunities))
+				for _, opp := range response.Opportunities {
+					surfaces = append(surfaces, opp["surface_id"].(string))
 				}
+				assert.Equal(t, tt.expectedSurface, surfaces, tt.description)
+				assert.Equal(t, len(tt.expectedSurface), response.TotalCount)
+				assert.Equal(t, 20, response.Limit)
 			}
 		})
 	}
 }
 
+func TestPlacementHandler_ListOpportunitiesPaging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	router := gin.New()
+	router.GET("/opportunities", handler.ListOpportunities)
+
+	tests := []struct {
+		queryParams    string
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{"?limit=50&offset=100", 50, 100},
+		{"?limit=1000&offset=-5", 20, 0},
+		{"?limit=abc", 20, 0},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/opportunities"+tt.queryParams, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, tt.expectedLimit, mockDB.limit, "Should pass the limit of %s to the database", tt.queryParams)
+		assert.Equal(t, tt.expectedOffset, mockDB.offset, "Should pass the offset of %s to the database", tt.queryParams)
+	}
+}
+
+func TestPlacementHandler_ListOpportunitiesCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{opportunities: []models.Surface{
+		{SurfaceID: "surface_a", PRSScore: 90},
+		{SurfaceID: "surface_c", PRSScore: 80},
+		{SurfaceID: "surface_b", PRSScore: 80},
+		{SurfaceID: "surface_d", PRSScore: 70},
+		{SurfaceID: "surface_e", PRSScore: 60},
+	}}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	router := gin.New()
+	router.GET("/opportunities", handler.ListOpportunities)
+
+	type page struct {
+		Opportunities []models.Surface `json:"opportunities"`
+		NextCursor    string           `json:"next_cursor"`
+		PrevCursor    string           `json:"prev_cursor"`
+	}
+	get := func(query string) page {
+		req := httptest.NewRequest(http.MethodGet, "/opportunities"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code, query)
+
+		var p page
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &p))
+		return p
+	}
+	ids := func(p page) []string {
+		var ids []string
+		for _, s := range p.Opportunities {
+			ids = append(ids, s.SurfaceID)
+		}
+		return ids
+	}
+
+	first := get("?limit=2")
+	assert.Equal(t, []string{"surface_a", "surface_c"}, ids(first))
+	assert.Empty(t, first.PrevCursor, "The first page should have no previous page")
+	require.NotEmpty(t, first.NextCursor)
+
+	second := get("?limit=2&offset=40&cursor=" + first.NextCursor)
+	assert.Equal(t, []string{"surface_b", "surface_d"}, ids(second), "Ties on PRS score should break on surface ID")
+	assert.Equal(t, 0, mockDB.offset, "A cursor should override the offset")
+	require.NotEmpty(t, second.PrevCursor)
+	require.NotEmpty(t, second.NextCursor)
+
+	last := get("?limit=2&cursor=" + second.NextCursor)
+	assert.Equal(t, []string{"surface_e"}, ids(last))
+	assert.Empty(t, last.NextCursor, "The last page should have no next page")
+
+	back := get("?limit=2&cursor=" + second.PrevCursor)
+	assert.Equal(t, ids(first), ids(back), "Paging back should return the previous page in listing order")
+	assert.Empty(t, back.PrevCursor)
+	assert.Equal(t, first.NextCursor, back.NextCursor)
+
+	req := httptest.NewRequest(http.MethodGet, "/opportunities?cursor=not-a-cursor", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "A malformed cursor should be rejected")
+}
+
 func TestPlacementHandler_GetOpportunity(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -244,12 +526,143 @@ func TestPlacementHandler_BookPlacement(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			description:    "Should return 400 for invalid data types",
 		},
+		{
+			name: "attention billing",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"billing_model":  "attention_cpm",
+			},
+			mockDB:         &MockPlacementDB{bookingID: "booking_123"},
+			expectedStatus: http.StatusCreated,
+			description:    "Should accept an attention billing model",
+		},
+		{
+			name: "unknown billing model",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"billing_model":  "cpc",
+			},
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown billing models",
+		},
+		{
+			name: "invalid label key",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"labels":         map[string]string{"Bad Key": "x"},
+			},
+			mockDB: &MockPlacementDB{
+				bookingID: "booking_123",
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should return 400 for malformed labels",
+		},
+		{
+			name:        "held back surface",
+			requestBody: validBooking,
+			mockDB: &MockPlacementDB{
+				createErr: fmt.Errorf("%w: surface surface_001 is reserved", holdback.ErrHeldBack),
+			},
+			expectedStatus: http.StatusConflict,
+			description:    "Should return 409 for inventory held back from sale",
+		},
+		{
+			name:        "crowded shot",
+			requestBody: validBooking,
+			mockDB: &MockPlacementDB{
+				createErr: fmt.Errorf("%w: surface surface_001 would make 3 booked surfaces on screen at once", collision.ErrCollision),
+			},
+			expectedStatus: http.StatusConflict,
+			description:    "Should return 409 for a booking breaking its shot's collision rule",
+		},
+		{
+			name:        "overlapping window",
+			requestBody: validBooking,
+			mockDB: &MockPlacementDB{
+				createErr: &booking.ConflictError{BookingID: "booking_1", SurfaceID: "surface_001", AdvertiserID: "advertiser_9"},
+			},
+			expectedStatus: http.StatusConflict,
+			description:    "Should return 409 for a booking overlapping a live booking of the surface",
+		},
+		{
+			name: "window ending before it starts",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"start_time":     "2024-03-01T00:00:00Z",
+				"end_time":       "2024-02-01T00:00:00Z",
+			},
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject exposure windows that end before they start",
+		},
+		{
+			name: "even pacing without a window",
+			requestBody: map[string]interface{}{
+				"surface_id":      "surface_001",
+				"advertiser_id":   "advertiser_123",
+				"campaign_id":     "campaign_456",
+				"bid_amount_cpm":  5.50,
+				"max_impressions": 1000,
+				"pacing":          "even",
+			},
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject even pacing with no window to spread impressions over",
+		},
+		{
+			name: "unknown pacing",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"pacing":         "front_loaded",
+			},
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject pacing curves other than asap and even",
+		},
+		{
+			name: "frequency cap out of range",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+				"frequency_cap":  map[string]interface{}{"max_exposures": 0, "window_hours": 24},
+			},
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject frequency caps allowing no exposures",
+		},
+		{
+			name:        "unknown campaign",
+			requestBody: validBooking,
+			mockDB: &MockPlacementDB{
+				createErr: fmt.Errorf("%w: campaign campaign_456 does not exist", campaign.ErrNotBookable),
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should return 422 for a booking naming a campaign that cannot be booked",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup handler with mock database
-			handler := &PlacementHandler{db: tt.mockDB}
+			handler := &PlacementHandler{placements: placement.NewService(tt.mockDB)}
 			router := gin.New()
 			router.POST("/bookings", handler.BookPlacement)
 
@@ -280,120 +693,375 @@ func TestPlacementHandler_BookPlacement(t *testing.T) {
 				// Validate values
 				assert.Equal(t, "confirmed", response["status"])
 				assert.NotEmpty(t, response["booking_id"])
+				assert.Equal(t, "asap", response["pacing"])
 			}
 		})
 	}
 }
 
-func TestPlacementHandler_GetBooking(t *testing.T) {
+func TestPlacementHandler_BookPlacementConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	body := `{"surface_id": "surface_001", "advertiser_id": "advertiser_123", "campaign_id": "campaign_456",
+		"bid_amount_cpm": 5.5, "start_time": "2024-04-01T00:00:00Z", "end_time": "2024-05-01T00:00:00Z"}`
+
+	tests := []struct {
+		name        string
+		callerOrg   string
+		named       bool
+		description string
+	}{
+		{"own organization", "org_a", true, "Should name a conflicting booking the caller may read"},
+		{"other organization", "org_b", false, "Should only give the window of another organization's booking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{
+				createErr: &booking.ConflictError{
+					BookingID:    "booking_1",
+					SurfaceID:    "surface_001",
+					AdvertiserID: "advertiser_9",
+					CampaignID:   "campaign_9",
+					Status:       booking.StatusConfirmed,
+					OrgID:        "org_a",
+					Window:       booking.Window{Start: &start},
+				},
+			}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+			router := gin.New()
+			router.POST("/bookings", withOrg("user_1", tt.callerOrg), handler.BookPlacement)
+
+			req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusConflict, resp.Code)
+			require.NotNil(t, mockDB.created)
+			assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), mockDB.created.StartTime.UTC())
+			assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), mockDB.created.EndTime.UTC())
+
+			var response struct {
+				Error       string                 `json:"error"`
+				Conflicting map[string]interface{} `json:"conflicting_booking"`
+				Window      map[string]interface{} `json:"conflicting_window"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.NotContains(t, response.Error, "booking_1", "The message should not name the booking")
+			assert.NotContains(t, response.Error, "advertiser_9", "The message should not name the advertiser")
+			assert.Equal(t, "2024-03-01T00:00:00Z", response.Window["start_time"], tt.description)
+			assert.NotContains(t, response.Window, "end_time")
+			if !tt.named {
+				assert.Nil(t, response.Conflicting, tt.description)
+				assert.NotContains(t, resp.Body.String(), "campaign_9", tt.description)
+				return
+			}
+			assert.Equal(t, "booking_1", response.Conflicting["booking_id"], tt.description)
+			assert.Equal(t, "advertiser_9", response.Conflicting["advertiser_id"])
+			assert.NotContains(t, response.Conflicting, "org_id")
+		})
+	}
+}
+
+func TestPlacementHandler_DeprecatedFieldAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		method             string
+		url                string
+		requestBody        map[string]interface{}
+		expectedStatus     int
+		expectedFields     map[string]interface{}
+		expectedDeprecated []string
+		description        string
+	}{
+		{
+			name:   "booking confirmation",
+			method: http.MethodPost,
+			url:    "/bookings",
+			requestBody: map[string]interface{}{
+				"surface_id":     "surface_001",
+				"advertiser_id":  "advertiser_123",
+				"campaign_id":    "campaign_456",
+				"bid_amount_cpm": 5.50,
+			},
+			expectedStatus:     http.StatusCreated,
+			expectedFields:     map[string]interface{}{"final_cpm_rate": 5.5, "final_cmp_rate": 5.5},
+			expectedDeprecated: []string{"final_cmp_rate=final_cpm_rate"},
+			description:        "Should emit the canonical CPM field alongside the misspelled one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{bookingID: "booking_123"})}
+			router := gin.New()
+			router.POST("/bookings", handler.BookPlacement)
+			router.GET("/bookings/:id", handler.GetBooking)
+
+			var requestBody []byte
+			if tt.requestBody != nil {
+				requestBody, _ = json.Marshal(tt.requestBody)
+			}
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			for field, value := range tt.expectedFields {
+				assert.Equal(t, value, response[field], tt.description)
+			}
+			assert.Equal(t, tt.expectedDeprecated, resp.Header().Values(schema.DeprecatedFieldsHeader))
+		})
+	}
+}
+
+func TestPlacementHandler_JSONCaseNegotiation(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	tests := []struct {
 		name           string
-		bookingID      string
+		jsonCase       string
+		body           string
 		expectedStatus int
+		expected       *models.NewBooking
 		description    string
 	}{
 		{
-			name:           "get existing booking",
-			bookingID:      "booking_123",
-			expectedStatus: http.StatusOK,
-			description:    "Should return booking status",
+			name:           "snake_case",
+			jsonCase:       schema.CaseAny,
+			body:           `{"surface_id":"surface_001","advertiser_id":"adv_1","campaign_id":"camp_1","bid_amount_cpm":4.5}`,
+			expectedStatus: http.StatusCreated,
+			expected:       &models.NewBooking{SurfaceID: "surface_001", AdvertiserID: "adv_1", CampaignID: "camp_1", BidAmountCPM: 4.5},
+			description:    "Should still accept canonical names",
 		},
 		{
-			name:           "get booking with any ID",
-			bookingID:      "custom_booking_456",
-			expectedStatus: http.StatusOK,
-			description:    "Should return mock booking data for any ID",
+			name:           "camelCase",
+			jsonCase:       schema.CaseAny,
+			body:           `{"surfaceId":"surface_001","advertiserId":"adv_1","campaignId":"camp_1","bidAmountCpm":4.5,"maxImpressions":500}`,
+			expectedStatus: http.StatusCreated,
+			expected:       &models.NewBooking{SurfaceID: "surface_001", AdvertiserID: "adv_1", CampaignID: "camp_1", BidAmountCPM: 4.5, MaxImpressions: 500},
+			description:    "Should accept camelCase names",
+		},
+		{
+			name:           "mixed with acronyms",
+			jsonCase:       schema.CaseAny,
+			body:           `{"surfaceID":"surface_001","advertiser_id":"adv_1","CampaignId":"camp_1","bidAmountCPM":4.5,"minPRSScore":70}`,
+			expectedStatus: http.StatusCreated,
+			expected:       &models.NewBooking{SurfaceID: "surface_001", AdvertiserID: "adv_1", CampaignID: "camp_1", BidAmountCPM: 4.5, MinPRSScore: 70},
+			description:    "Should accept a mix of conventions in one payload",
+		},
+		{
+			name:           "both spellings",
+			jsonCase:       schema.CaseAny,
+			body:           `{"surface_id":"surface_001","surfaceId":"surface_999","advertiser_id":"adv_1","campaign_id":"camp_1","bid_amount_cpm":4.5}`,
+			expectedStatus: http.StatusCreated,
+			expected:       &models.NewBooking{SurfaceID: "surface_001", AdvertiserID: "adv_1", CampaignID: "camp_1", BidAmountCPM: 4.5},
+			description:    "Should prefer the canonical spelling when both are sent",
+		},
+		{
+			name:           "map keys are data",
+			jsonCase:       schema.CaseAny,
+			body:           `{"surfaceId":"surface_001","advertiserId":"adv_1","campaignId":"camp_1","bidAmountCpm":4.5,"externalIds":{"dv360":"io_1"},"labels":{"team":"sports"}}`,
+			expectedStatus: http.StatusCreated,
+			expected: &models.NewBooking{
+				SurfaceID:    "surface_001",
+				AdvertiserID: "adv_1",
+				CampaignID:   "camp_1",
+				BidAmountCPM: 4.5,
+				ExternalIDs:  map[string]string{"dv360": "io_1"},
+				Labels:       labels.Set{"team": "sports"},
+			},
+			description: "Should rename fields holding maps without touching their keys",
+		},
+		{
+			name:           "camelCase from snake key",
+			jsonCase:       schema.CaseSnake,
+			body:           `{"surfaceId":"surface_001","advertiserId":"adv_1","campaignId":"camp_1","bidAmountCpm":4.5}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject camelCase unless the key allows it",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			store := &MockPlacementDB{bookingID: "booking_123"}
+			handler := &PlacementHandler{placements: placement.NewService(store)}
 			router := gin.New()
-			router.GET("/bookings/:id", handler.GetBooking)
+			router.POST("/bookings", func(c *gin.Context) { c.Set("json_case", tt.jsonCase) }, handler.BookPlacement)
 
-			// Execute request
-			url := "/bookings/" + tt.bookingID
-			req := httptest.NewRequest(http.MethodGet, url, nil)
+			req := httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
 			resp := httptest.NewRecorder()
 			router.ServeHTTP(resp, req)
 
-			// Assert status code
-			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expected != nil {
+				assert.Equal(t, tt.expected, store.created, tt.description)
+			}
+		})
+	}
+}
 
-			if tt.expectedStatus == http.StatusOK {
-				// Parse response
-				var response map[string]interface{}
-				err := json.Unmarshal(resp.Body.Bytes(), &response)
-				require.NoError(t, err)
+func TestPlacementHandler_BatchRecordExposuresCamelCase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
 
-				// Check required fields
-				assert.Contains(t, response, "booking_id")
-				assert.Contains(t, response, "status")
-				assert.Contains(t, response, "placement_id")
-				assert.Contains(t, response, "confirmation_time")
+	store := &MockPlacementDB{}
+	handler := &PlacementHandler{placements: placement.NewService(store)}
+	router := gin.New()
+	router.POST("/events/exposure/batch", func(c *gin.Context) { c.Set("json_case", schema.CaseAny) }, handler.BatchRecordExposures)
+
+	// Nested events mix conventions and the batch-level clock is camelCase
+	body := `{
+		"deviceClock": "2024-01-15T10:00:00Z",
+		"events": [
+			{"bookingId": "booking_1", "viewerId": "viewer_1", "exposureDuration": 2.5, "timestamp": "2024-01-15T09:59:00Z"},
+			{"booking_id": "booking_2", "viewer_id": "viewer_2", "exposureDuration": 1.5, "screenCoverage": 0.4}
+		]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/events/exposure/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Len(t, store.events, 2)
+	assert.Equal(t, "booking_1", store.events[0].BookingID)
+	assert.Equal(t, 2.5, store.events[0].ExposureDuration)
+	assert.Equal(t, 0.4, store.events[1].ScreenCoverage)
+	assert.NotNil(t, store.events[0].DeviceTimestamp)
+	assert.NotEqual(t, int64(0), store.events[0].ClockSkewMS, "Should apply the camelCase batch device clock")
+}
 
-				// Validate booking ID matches request
-				assert.Equal(t, tt.bookingID, response["booking_id"])
-			}
-		})
+func TestPlacementHandler_ListBookings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{
+		bookings: []models.Booking{
+			{BookingID: "booking_1", Labels: labels.Set{"team": "sports"}},
+		},
 	}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	router := gin.New()
+	router.GET("/bookings", handler.ListBookings)
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings?campaign_id=campaign_456&labels=team=sports,region=emea", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, labels.Set{"team": "sports", "region": "emea"}, mockDB.selector)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, float64(1), response["total_count"])
+
+	req = httptest.NewRequest(http.MethodGet, "/bookings?labels=team=sports,team=news", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "Should reject duplicate selector keys")
+
+	mockDB.listErr = fmt.Errorf("%w: a value contains a NUL byte", query.ErrInvalidFilter)
+	req = httptest.NewRequest(http.MethodGet, "/bookings?campaign_id=campaign%00", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "Should reject filter values that cannot be bound")
 }
 
-func TestPlacementHandler_CancelBooking(t *testing.T) {
+func TestPlacementHandler_GetBooking(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
+	confirmed := time.Date(2024, 1, 15, 10, 35, 0, 0, time.UTC)
+	delivered := int64(847)
+	stored := &models.Booking{
+		BookingID:            "booking_123",
+		SurfaceID:            "surface_001",
+		Status:               "paused",
+		ConfirmationTime:     &confirmed,
+		EstimatedImpressions: 1000,
+		ActualImpressions:    &delivered,
+		OrgID:                "org_a",
+	}
+
 	tests := []struct {
 		name           string
-		bookingID      string
+		booking        *models.Booking
+		shouldError    bool
 		expectedStatus int
 		description    string
 	}{
 		{
-			name:           "cancel existing booking",
-			bookingID:      "booking_123",
+			name:           "stored booking",
+			booking:        stored,
 			expectedStatus: http.StatusOK,
-			description:    "Should cancel booking successfully",
+			description:    "Should return the booking as stored",
+		},
+		{
+			name:           "missing booking",
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for bookings that do not exist or are outside the caller's scope",
+		},
+		{
+			name:           "database error",
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should handle database errors",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			mockDB := &MockPlacementDB{booking: tt.booking, shouldError: tt.shouldError}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB)}
 			router := gin.New()
-			router.DELETE("/bookings/:id", handler.CancelBooking)
+			router.GET("/bookings/:id", withOrg("user_1", "org_a"), handler.GetBooking)
 
-			// Execute request
-			url := "/bookings/" + tt.bookingID
-			req := httptest.NewRequest(http.MethodDelete, url, nil)
+			req := httptest.NewRequest(http.MethodGet, "/bookings/booking_123", nil)
 			resp := httptest.NewRecorder()
 			router.ServeHTTP(resp, req)
 
-			// Assert status code
-			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.shouldError {
+				return
+			}
+			assert.Equal(t, tenant.Of("org_a", ""), mockDB.scope, "Should read within the caller's scope")
 
 			if tt.expectedStatus == http.StatusOK {
-				// Parse response
-				var response map[string]interface{}
-				err := json.Unmarshal(resp.Body.Bytes(), &response)
-				require.NoError(t, err)
-
-				// Check response structure
-				assert.Contains(t, response, "success")
-				assert.Contains(t, response, "message")
-				assert.Contains(t, response, "cancelled_at")
-
-				// Validate success
-				assert.Equal(t, true, response["success"])
+				var response struct {
+					Booking models.Booking `json:"booking"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, "booking_123", response.Booking.BookingID)
+				assert.Equal(t, "paused", response.Booking.Status, "Should report the stored status")
+				assert.Equal(t, "surface_001", response.Booking.SurfaceID)
+				require.NotNil(t, response.Booking.ActualImpressions)
+				assert.Equal(t, delivered, *response.Booking.ActualImpressions)
 			}
 		})
 	}
 }
 
+func TestPlacementHandler_CancelBooking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewPlacementHandler(nil)
+	router := gin.New()
+	router.DELETE("/bookings/:id", handler.CancelBooking)
+
+	req := httptest.NewRequest(http.MethodDelete, "/bookings/booking_123", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotImplemented, resp.Code, "Should not report a cancellation it cannot record")
+}
+
 func TestPlacementHandler_RecordExposure(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -408,12 +1076,14 @@ func TestPlacementHandler_RecordExposure(t *testing.T) {
 	tests := []struct {
 		name           string
 		requestBody    map[string]interface{}
+		mockDB         *MockPlacementDB
 		expectedStatus int
 		description    string
 	}{
 		{
 			name:           "successful exposure recording",
 			requestBody:    validExposure,
+			mockDB:         &MockPlacementDB{},
 			expectedStatus: http.StatusCreated,
 			description:    "Should record exposure successfully",
 		},
@@ -423,15 +1093,23 @@ func TestPlacementHandler_RecordExposure(t *testing.T) {
 				"booking_id": "booking_123",
 				// Missing viewer_id and exposure_duration
 			},
+			mockDB:         &MockPlacementDB{},
 			expectedStatus: http.StatusBadRequest,
 			description:    "Should return 400 for missing required fields",
 		},
+		{
+			name:           "database error",
+			requestBody:    validExposure,
+			mockDB:         &MockPlacementDB{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on database error",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			// Setup handler with mock database
+			handler := &PlacementHandler{placements: placement.NewService(tt.mockDB)}
 			router := gin.New()
 			router.POST("/events/exposure", handler.RecordExposure)
 
@@ -487,12 +1165,14 @@ func TestPlacementHandler_BatchRecordExposures(t *testing.T) {
 	tests := []struct {
 		name           string
 		requestBody    map[string]interface{}
+		mockDB         *MockPlacementDB
 		expectedStatus int
 		description    string
 	}{
 		{
 			name:           "successful batch recording",
 			requestBody:    validBatch,
+			mockDB:         &MockPlacementDB{},
 			expectedStatus: http.StatusCreated,
 			description:    "Should record batch exposures successfully",
 		},
@@ -501,6 +1181,7 @@ func TestPlacementHandler_BatchRecordExposures(t *testing.T) {
 			requestBody: map[string]interface{}{
 				"invalid": "data",
 			},
+			mockDB:         &MockPlacementDB{},
 			expectedStatus: http.StatusBadRequest,
 			description:    "Should return 400 for missing events array",
 		},
@@ -508,8 +1189,8 @@ func TestPlacementHandler_BatchRecordExposures(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			// Setup handler with mock database
+			handler := &PlacementHandler{placements: placement.NewService(tt.mockDB)}
 			router := gin.New()
 			router.POST("/events/exposure/batch", handler.BatchRecordExposures)
 
@@ -545,27 +1226,430 @@ func TestPlacementHandler_BatchRecordExposures(t *testing.T) {
 	}
 }
 
+func TestPlacementHandler_BatchRecordExposuresFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{goneBooking: "booking_gone"}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	router := gin.New()
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+
+	body := `{"events": [
+		{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5},
+		{"booking_id": "booking_123", "exposure_duration": 5},
+		{"booking_id": "booking_gone", "viewer_id": "viewer_3", "exposure_duration": 5},
+		{"booking_id": "booking_123", "viewer_id": "viewer_4", "exposure_duration": 5}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/events/exposure/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Code, "Invalid events should not fail the batch")
+
+	var response struct {
+		ProcessedCount int                     `json:"processed_count"`
+		FailedCount    int                     `json:"failed_count"`
+		FailedIndexes  []int                   `json:"failed_indexes"`
+		Failures       []exposurequeue.Failure `json:"failures"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.ProcessedCount)
+	assert.Equal(t, 2, response.FailedCount)
+	assert.Equal(t, []int{1, 2}, response.FailedIndexes)
+	require.Len(t, response.Failures, 2)
+	assert.Contains(t, response.Failures[0].Error, "ViewerID", "Validation failures should name the field")
+	assert.Equal(t, "Unknown booking_id", response.Failures[1].Error)
+
+	require.Len(t, mockDB.events, 2)
+	assert.Equal(t, "viewer_1", mockDB.events[0].ViewerID)
+	assert.Equal(t, "viewer_4", mockDB.events[1].ViewerID)
+}
+
+func TestPlacementHandler_RecordExposureResends(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		name    string
+		resends resend.Window
+	}{
+		{"window", resend.NewMemoryWindow(time.Minute)},
+		{"postgres fallback", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+			if tc.resends != nil {
+				handler.SetResendWindow(tc.resends)
+			}
+			router := gin.New()
+			router.POST("/events/exposure", handler.RecordExposure)
+			router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+
+			post := func(path, body string) (int, map[string]interface{}) {
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				return resp.Code, response
+			}
+
+			single := `{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5, "client_event_id": "beacon_1"}`
+			status, _ := post("/events/exposure", single)
+			require.Equal(t, http.StatusCreated, status)
+			status, response := post("/events/exposure", single)
+			require.Equal(t, http.StatusOK, status, "A resend should be acknowledged, not recorded")
+			assert.Equal(t, true, response["duplicate"])
+
+			status, response = post("/events/exposure/batch", `{"events": [
+				{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5, "client_event_id": "beacon_1"},
+				{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5, "client_event_id": "beacon_2"},
+				{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5, "client_event_id": "beacon_2"},
+				{"booking_id": "booking_123", "viewer_id": "viewer_2", "exposure_duration": 5, "client_event_id": "beacon_2"},
+				{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5}
+			]}`)
+			require.Equal(t, http.StatusCreated, status)
+			assert.Equal(t, float64(3), response["processed_count"])
+			assert.Equal(t, float64(2), response["duplicate_count"])
+			assert.Equal(t, float64(0), response["failed_count"], "Duplicates should not be failures")
+			assert.Len(t, mockDB.events, 4)
+		})
+	}
+}
+
+// MockExposureQueue keeps published batches in memory
+type MockExposureQueue struct {
+	batches []*exposurequeue.Batch
+	err     error
+}
+
+func (m *MockExposureQueue) Publish(ctx context.Context, batch *exposurequeue.Batch) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func TestPlacementHandler_BatchRecordExposuresQueued(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{}
+	queue := &MockExposureQueue{}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	handler.SetExposureQueue(queue)
+	router := gin.New()
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+
+	send := func() (int, map[string]interface{}) {
+		body := `{"events": [
+			{"booking_id": "booking_123", "viewer_id": "viewer_1", "exposure_duration": 5},
+			{"viewer_id": "viewer_2", "exposure_duration": 5}
+		]}`
+		req := httptest.NewRequest(http.MethodPost, "/events/exposure/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		return resp.Code, response
+	}
+
+	status, response := send()
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, float64(1), response["queued_count"])
+	assert.Equal(t, []interface{}{float64(1)}, response["failed_indexes"])
+	assert.NotEmpty(t, response["batch_id"])
+	require.Len(t, queue.batches, 1)
+	require.Len(t, queue.batches[0].Events, 1, "Only valid events should be queued")
+	assert.Empty(t, mockDB.events, "Queued events should be recorded by the consumer")
+
+	// The consumer records the batch as it was queued
+	failures, err := handler.Placements().RecordQueuedExposures(context.Background(), queue.batches[0].Events, false)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	require.Len(t, mockDB.events, 1)
+	assert.Equal(t, "viewer_1", mockDB.events[0].ViewerID)
+
+	queue.err = exposurequeue.ErrUnavailable
+	status, _ = send()
+	assert.Equal(t, http.StatusServiceUnavailable, status, "Batches that cannot be queued should be retried")
+}
+
+func TestPlacementHandler_RecordExposuresAllOrNothing(t *testing.T) {
+	biller := &mockExposureBiller{charge: &budget.Charge{CampaignID: "campaign_1", Cost: 15000}}
+	mockDB := &MockPlacementDB{goneBooking: "booking_gone"}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+	handler.SetExposureBiller(biller)
+
+	events := []models.ExposureEvent{
+		{BookingID: "booking_123", ViewerID: "viewer_1", Counted: true},
+		{BookingID: "booking_gone", ViewerID: "viewer_2", Counted: true},
+	}
+	_, err := handler.Placements().RecordExposures(context.Background(), events, false)
+	assert.ErrorIs(t, err, db.ErrBookingNotFound)
+	assert.Empty(t, mockDB.events, "Nothing should be recorded unless isolating failures")
+	assert.Len(t, biller.refunded, 2, "Every charge should be refunded")
+
+	recorded, err := handler.Placements().RecordExposures(context.Background(), events, true)
+	require.NoError(t, err)
+	require.Len(t, recorded.Events, 1)
+	assert.Equal(t, "event_booking_123", recorded.Events[0].EventID)
+	assert.Equal(t, []exposurequeue.Failure{{Index: 1, Error: "Unknown booking_id"}}, recorded.Failures)
+}
+
+// MockExposureAckStore numbers batches per key in memory
+type MockExposureAckStore struct {
+	acks map[string][]ack.Ack
+}
+
+func (m *MockExposureAckStore) RecordExposureAck(a *ack.Ack) error {
+	if m.acks == nil {
+		m.acks = make(map[string][]ack.Ack)
+	}
+	a.Sequence = int64(len(m.acks[a.KeyID]) + 1)
+	m.acks[a.KeyID] = append(m.acks[a.KeyID], *a)
+	return nil
+}
+
+func (m *MockExposureAckStore) ListExposureAcks(keyID string, after int64, limit int) (int64, []ack.Ack, error) {
+	all := m.acks[keyID]
+	acks := make([]ack.Ack, 0)
+	for _, a := range all {
+		if a.Sequence > after && len(acks) < limit {
+			acks = append(acks, a)
+		}
+	}
+	return int64(len(all)), acks, nil
+}
+
+func TestPlacementHandler_ExposureAcks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{})}
+	handler.SetExposureAcks(&MockExposureAckStore{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if keyID := c.GetHeader("X-Test-Key"); keyID != "" {
+			c.Set("key_id", keyID)
+		}
+	})
+	router.POST("/events/exposure/batch", handler.BatchRecordExposures)
+	router.GET("/events/exposure/acks", handler.ListExposureAcks)
+
+	send := func(keyID, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/events/exposure/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if keyID != "" {
+			req.Header.Set("X-Test-Key", keyID)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		return resp.Code, response
+	}
+	list := func(keyID, query string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/events/exposure/acks"+query, nil)
+		if keyID != "" {
+			req.Header.Set("X-Test-Key", keyID)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		return resp.Code, response
+	}
+
+	batch := func(token string) string {
+		return `{"events": [{"booking_id": "booking_123", "viewer_id": "viewer_456", "exposure_duration": 5.2}], "sequence_token": "` + token + `"}`
+	}
+
+	status, response := send("key_a", batch("offset-10"))
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, float64(1), response["ack_sequence"])
+	assert.Equal(t, "offset-10", response["sequence_token"])
+
+	status, response = send("key_a", batch("offset-20"))
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, float64(2), response["ack_sequence"], "Sequences should increase per batch")
+
+	status, response = send("key_b", batch(""))
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, float64(1), response["ack_sequence"], "Each key should have its own sequence")
+	assert.NotContains(t, response, "sequence_token")
+
+	status, response = send("", batch(""))
+	require.Equal(t, http.StatusCreated, status)
+	assert.NotContains(t, response, "ack_sequence", "Only batches sent with an API key should be acknowledged")
+
+	status, _ = send("key_a", batch(strings.Repeat("x", 201)))
+	assert.Equal(t, http.StatusBadRequest, status, "Should reject overlong sequence tokens")
+
+	status, response = list("key_a", "?limit=1")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(2), response["last_sequence"])
+	acks := response["acks"].([]interface{})
+	require.Len(t, acks, 1)
+	assert.Equal(t, "offset-10", acks[0].(map[string]interface{})["sequence_token"])
+	assert.Equal(t, float64(1), response["next_after"])
+
+	status, response = list("key_a", "?after=1&limit=1")
+	require.Equal(t, http.StatusOK, status)
+	acks = response["acks"].([]interface{})
+	require.Len(t, acks, 1)
+	assert.Equal(t, float64(2), acks[0].(map[string]interface{})["ack_sequence"])
+	assert.NotContains(t, response, "next_after", "The last page should not point further")
+
+	status, _ = list("key_a", "?after=-1")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = list("", "")
+	assert.Equal(t, http.StatusBadRequest, status, "Acknowledgements should only be listed for API keys")
+}
+
+func TestPlacementHandler_RecordExposureClockSkew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name          string
+		timestamp     *time.Time
+		deviceClock   *time.Time
+		expectedSkew  time.Duration
+		expectRawTime bool
+		description   string
+	}{
+		{
+			name:          "device clock one hour behind",
+			timestamp:     timePtr(now.Add(-time.Hour - 30*time.Second)),
+			deviceClock:   timePtr(now.Add(-time.Hour)),
+			expectedSkew:  time.Hour,
+			expectRawTime: true,
+			description:   "Should shift event time forward by the estimated skew",
+		},
+		{
+			name:          "device clock within tolerance",
+			timestamp:     timePtr(now.Add(-30 * time.Second)),
+			deviceClock:   timePtr(now.Add(-500 * time.Millisecond)),
+			expectedSkew:  0,
+			expectRawTime: true,
+			description:   "Should trust device clock when skew is within tolerance",
+		},
+		{
+			name:          "no device clock",
+			timestamp:     timePtr(now.Add(-30 * time.Second)),
+			expectedSkew:  0,
+			expectRawTime: true,
+			description:   "Should use event time as-is without a device clock",
+		},
+		{
+			name:          "no timestamps",
+			expectedSkew:  0,
+			expectRawTime: false,
+			description:   "Should fall back to receive time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB)}
+			router := gin.New()
+			router.POST("/events/exposure", handler.RecordExposure)
+
+			body := map[string]interface{}{
+				"booking_id":        "booking_123",
+				"viewer_id":         "viewer_456",
+				"exposure_duration": 5.2,
+			}
+			if tt.timestamp != nil {
+				body["timestamp"] = tt.timestamp.Format(time.RFC3339Nano)
+			}
+			if tt.deviceClock != nil {
+				body["device_clock"] = tt.deviceClock.Format(time.RFC3339Nano)
+			}
+			requestBody, _ := json.Marshal(body)
+
+			req := httptest.NewRequest(http.MethodPost, "/events/exposure", bytes.NewReader(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusCreated, resp.Code, tt.description)
+			require.Len(t, mockDB.events, 1)
+			event := mockDB.events[0]
+
+			skewMs := event.ClockSkewMS
+			assert.InDelta(t, tt.expectedSkew.Milliseconds(), skewMs, 1000, tt.description)
+
+			corrected := event.Timestamp
+			if tt.expectRawTime {
+				require.NotNil(t, event.DeviceTimestamp, "Raw device timestamp should be stored")
+				raw := *event.DeviceTimestamp
+				assert.True(t, raw.Equal(*tt.timestamp))
+				assert.Equal(t, time.Duration(skewMs)*time.Millisecond, corrected.Sub(raw).Truncate(time.Millisecond))
+			} else {
+				assert.Nil(t, event.DeviceTimestamp)
+				assert.Equal(t, event.ReceivedAt, corrected)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
 func TestPlacementHandler_GetMetrics(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	tests := []struct {
 		name           string
 		bookingID      string
+		mockDB         *MockPlacementDB
 		expectedStatus int
 		description    string
 	}{
 		{
-			name:           "get metrics for booking",
-			bookingID:      "booking_123",
+			name:      "get metrics for booking",
+			bookingID: "booking_123",
+			mockDB: &MockPlacementDB{
+				metrics: &models.BookingMetrics{
+					BookingID:             "booking_123",
+					TotalImpressions:      847,
+					UniqueViewers:         623,
+					TotalExposureTime:     4235.6,
+					AverageExposureTime:   5.2,
+					AveragePRSScore:       89.3,
+					AverageAttentionScore: 0.74,
+					AverageScreenCoverage: 23.8,
+				},
+			},
 			expectedStatus: http.StatusOK,
 			description:    "Should return analytics metrics",
 		},
+		{
+			name:           "unknown booking",
+			bookingID:      "booking_999",
+			mockDB:         &MockPlacementDB{},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 when the store has no metrics",
+		},
+		{
+			name:           "store error",
+			bookingID:      "booking_123",
+			mockDB:         &MockPlacementDB{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on analytics store error",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup handler
-			handler := NewPlacementHandler(nil)
+			// Setup handler with mock analytics store
+			handler := &PlacementHandler{placements: placement.NewService(tt.mockDB), analytics: tt.mockDB}
 			router := gin.New()
 			router.GET("/analytics/metrics/:booking_id", handler.GetMetrics)
 
@@ -601,6 +1685,346 @@ func TestPlacementHandler_GetMetrics(t *testing.T) {
 	}
 }
 
+func TestPlacementHandler_GetMetricsSeries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rolledUpTo := time.Date(2026, 3, 2, 11, 55, 0, 0, time.UTC)
+	mockDB := &MockPlacementDB{
+		metrics: &models.BookingMetrics{BookingID: "booking_123", TotalImpressions: 30},
+		series: []models.MetricsBucket{
+			{Bucket: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), TotalImpressions: 10, UniqueViewers: 4},
+			{Bucket: time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC), TotalImpressions: 20, UniqueViewers: 7},
+		},
+		rolledUpTo: &rolledUpTo,
+		quality:    quality.NewReport(30, 0, 0, nil, rolledUpTo),
+	}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+	router := gin.New()
+	router.GET("/analytics/metrics/:booking_id", handler.GetMetrics)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/metrics/booking_123?from=2026-03-02T08:30:00Z&to=2026-03-02T11:10:00Z&granularity=hour", nil))
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var response struct {
+		TotalImpressions int64                  `json:"total_impressions"`
+		From             string                 `json:"from"`
+		To               string                 `json:"to"`
+		Granularity      string                 `json:"granularity"`
+		RolledUpThrough  string                 `json:"rolled_up_through"`
+		Series           []models.MetricsBucket `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "2026-03-02T08:00:00Z", response.From, "Should align from down to the hour")
+	assert.Equal(t, "2026-03-02T12:00:00Z", response.To, "Should align to up to the hour")
+	assert.Equal(t, "hour", response.Granularity)
+	assert.Equal(t, "2026-03-02T11:55:00Z", response.RolledUpThrough)
+	assert.Equal(t, "hour 2026-03-02T08:00:00Z 2026-03-02T12:00:00Z", mockDB.seriesQuery)
+	assert.Equal(t, [2]time.Time{time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)}, mockDB.metricsRange,
+		"Totals should cover the same range as the series")
+	assert.Equal(t, mockDB.metricsRange[0], mockDB.qualityFilter.From)
+
+	require.Len(t, response.Series, 4, "Should return a bucket for every hour, empty or not")
+	impressions := []int64{}
+	for _, b := range response.Series {
+		impressions = append(impressions, b.TotalImpressions)
+	}
+	assert.Equal(t, []int64{0, 10, 0, 20}, impressions)
+	assert.EqualValues(t, 7, response.Series[3].UniqueViewers)
+
+	tests := []struct {
+		name        string
+		url         string
+		description string
+	}{
+		{"unknown granularity", "?granularity=minute", "Should reject granularities without rollups"},
+		{"bad time", "?from=yesterday", "Should reject times that are not RFC 3339"},
+		{"inverted range", "?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z", "Should reject ranges ending before they start"},
+		{"too many buckets", "?from=2020-01-01T00:00:00Z&to=2026-01-01T00:00:00Z&granularity=hour", "Should bound the buckets of a series"},
+	}
+	for _, tt := range tests {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/metrics/booking_123"+tt.url, nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, tt.description)
+	}
+
+	mockDB.metrics = &models.BookingMetrics{BookingID: "booking_123", TotalImpressions: 30}
+	mockDB.metricsRange = [2]time.Time{}
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/metrics/booking_123", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), `"series"`, "Should return totals alone without a range")
+	assert.True(t, mockDB.metricsRange[0].IsZero() && mockDB.metricsRange[1].IsZero(), "Totals should cover the whole booking without a range")
+}
+
+func TestPlacementHandler_DataQuality(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	receivedAt := time.Date(2026, 3, 2, 9, 59, 0, 0, time.UTC)
+	estimate := 0.8
+	report := quality.NewReport(120, 7, 3, &receivedAt, receivedAt.Add(time.Minute))
+	report.Completeness = []quality.Hour{{Hour: receivedAt.Truncate(time.Hour), Estimate: &estimate}}
+
+	tests := []struct {
+		name        string
+		url         string
+		description string
+	}{
+		{
+			name:        "metrics",
+			url:         "/analytics/metrics/booking_123",
+			description: "Should describe the events behind the booking's metrics",
+		},
+		{
+			name:        "events",
+			url:         "/analytics/events/booking_123",
+			description: "Should describe the booking's events alongside the listing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{
+				metrics: &models.BookingMetrics{BookingID: "booking_123", TotalImpressions: 110},
+				quality: report,
+			}
+			handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("org_id", "org_1")
+				c.Next()
+			})
+			router.GET("/analytics/metrics/:booking_id", handler.GetMetrics)
+			router.GET("/analytics/events/:booking_id", handler.GetExposureEvents)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code, tt.description)
+
+			var response struct {
+				DataQuality map[string]interface{} `json:"data_quality"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, "booking_123", mockDB.qualityFilter.BookingID)
+			assert.Equal(t, []string{"org_1"}, mockDB.qualityFilter.Tenant.Orgs)
+			assert.True(t, mockDB.qualityFilter.From.IsZero(), "Should describe the whole booking")
+			assert.EqualValues(t, 120, response.DataQuality["events_considered"])
+			assert.EqualValues(t, 7, response.DataQuality["invalid_excluded"])
+			assert.EqualValues(t, 3, response.DataQuality["duplicates_removed"])
+			assert.EqualValues(t, 110, response.DataQuality["events_counted"])
+			assert.Equal(t, "2026-03-02T09:59:00Z", response.DataQuality["last_received_at"])
+			assert.Equal(t, "2026-03-02T10:00:00Z", response.DataQuality["last_rollup_at"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"hour": "2026-03-02T09:00:00Z", "estimate": 0.8}}, response.DataQuality["completeness"])
+
+			mockDB.qualityErr = assert.AnError
+			resp = httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, http.StatusInternalServerError, resp.Code, "Should fail when data quality cannot be read")
+		})
+	}
+}
+
+func TestPlacementHandler_RecordExposureMirrorsToSink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDB := &MockPlacementDB{}
+	sink := &MockExposureSink{}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+	handler.SetExposureSink(sink)
+
+	router := gin.New()
+	router.POST("/events/exposure", handler.RecordExposure)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"booking_id":        "booking_123",
+		"viewer_id":         "viewer_456",
+		"exposure_duration": 5.2,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/events/exposure", bytes.NewReader(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, "event_booking_123", sink.events[0].EventID)
+}
+
+// mockExposureBiller charges every exposure the same and keeps its refunds
+type mockExposureBiller struct {
+	charge   *budget.Charge
+	refunded []*budget.Charge
+}
+
+func (m *mockExposureBiller) ChargeExposure(ctx context.Context, bookingID string, exposure billing.Exposure) (*budget.Charge, error) {
+	return m.charge, nil
+}
+
+func (m *mockExposureBiller) Refund(ctx context.Context, charge *budget.Charge) {
+	m.refunded = append(m.refunded, charge)
+}
+
+func TestPlacementHandler_RecordExposureBilling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		bookingID        string
+		duration         float64
+		attention        float64
+		expectedCampaign string
+		expectedSpend    float64
+		description      string
+	}{
+		{
+			name:          "cpm",
+			bookingID:     "booking_1",
+			duration:      5,
+			attention:     0.9,
+			expectedSpend: 0,
+			description:   "Should not charge exposures of bookings charged when served",
+		},
+		{
+			name:             "attention high band",
+			bookingID:        "booking_3",
+			duration:         5,
+			attention:        0.8,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0.015,
+			description:      "Should scale the CPM by the attention band",
+		},
+		{
+			name:             "attention low band",
+			bookingID:        "booking_3",
+			duration:         5,
+			attention:        0.2,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0.005,
+			description:      "Should discount exposures that held little attention",
+		},
+		{
+			name:             "vcpm qualified",
+			bookingID:        "booking_4",
+			duration:         2,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0.01,
+			description:      "Should charge exposures lasting the booking's minimum visibility",
+		},
+		{
+			name:             "vcpm unqualified",
+			bookingID:        "booking_4",
+			duration:         1.5,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0,
+			description:      "Should not charge exposures too short to qualify",
+		},
+		{
+			name:             "vcpm audio listened through",
+			bookingID:        "booking_5",
+			duration:         15,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0.01,
+			description:      "Should charge audio exposures heard through half their slot",
+		},
+		{
+			name:             "vcpm audio skipped",
+			bookingID:        "booking_5",
+			duration:         10,
+			expectedCampaign: "campaign_1",
+			expectedSpend:    0,
+			description:      "Should qualify audio exposures on listen-through, not the minimum visibility",
+		},
+	}
+
+	bands, err := billing.ParseBands(billing.DefaultBands)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockPlacementDB{}
+			budgets := newMockBudgetStore()
+			budgets.budgets["campaign_1"].Spent = 90
+			tracker := budget.NewTracker(budgets, nil, budget.DefaultSafetyMargin)
+			tracker.SetPricing(billing.Pricing{Bands: bands})
+			handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+			handler.SetExposureBiller(tracker)
+
+			router := gin.New()
+			router.POST("/events/exposure", handler.RecordExposure)
+
+			requestBody, _ := json.Marshal(map[string]interface{}{
+				"booking_id":        tt.bookingID,
+				"viewer_id":         "viewer_456",
+				"exposure_duration": tt.duration,
+				"attention_score":   tt.attention,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/events/exposure", bytes.NewReader(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusCreated, resp.Code, tt.description)
+			require.Len(t, mockDB.events, 1)
+			assert.Equal(t, tt.expectedCampaign, mockDB.events[0].CampaignID, tt.description)
+			assert.InDelta(t, tt.expectedSpend, mockDB.events[0].Spend, 0.000001, tt.description)
+			assert.True(t, mockDB.events[0].Counted, tt.description)
+		})
+	}
+
+	// Exposures past the campaign's budget were shown, so they are recorded,
+	// but uncounted and free
+	exhaustedDB := &MockPlacementDB{}
+	exhausted := &PlacementHandler{placements: placement.NewService(exhaustedDB), analytics: exhaustedDB}
+	exhaustedTracker := budget.NewTracker(newMockBudgetStore(), nil, budget.DefaultSafetyMargin)
+	exhaustedTracker.SetPricing(billing.Pricing{Bands: bands})
+	exhausted.SetExposureBiller(exhaustedTracker)
+	exhaustedRouter := gin.New()
+	exhaustedRouter.POST("/events/exposure", exhausted.RecordExposure)
+
+	req := httptest.NewRequest(http.MethodPost, "/events/exposure", strings.NewReader(`{"booking_id":"booking_3","viewer_id":"viewer_456","exposure_duration":5,"attention_score":0.8}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	exhaustedRouter.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"counted":false`)
+	require.Len(t, exhaustedDB.events, 1)
+	assert.Equal(t, "campaign_1", exhaustedDB.events[0].CampaignID)
+	assert.Zero(t, exhaustedDB.events[0].Spend)
+	assert.False(t, exhaustedDB.events[0].Counted)
+
+	// Charges of exposures that could not be recorded are refunded
+	biller := &mockExposureBiller{charge: &budget.Charge{CampaignID: "campaign_1", Cost: 15000}}
+	failingDB := &MockPlacementDB{shouldError: true}
+	failing := &PlacementHandler{placements: placement.NewService(failingDB), analytics: failingDB}
+	failing.SetExposureBiller(biller)
+	failingRouter := gin.New()
+	failingRouter.POST("/events/exposure", failing.RecordExposure)
+
+	req = httptest.NewRequest(http.MethodPost, "/events/exposure", strings.NewReader(`{"booking_id":"booking_3","viewer_id":"viewer_456","exposure_duration":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	failingRouter.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, []*budget.Charge{biller.charge}, biller.refunded)
+
+	// Exposures that cannot be priced are not recorded unbilled
+	budgets := newMockBudgetStore()
+	budgets.shouldError = true
+	mockDB := &MockPlacementDB{}
+	handler := &PlacementHandler{placements: placement.NewService(mockDB), analytics: mockDB}
+	handler.SetExposureBiller(budget.NewTracker(budgets, nil, budget.DefaultSafetyMargin))
+	router := gin.New()
+	router.POST("/events/exposure", handler.RecordExposure)
+
+	req = httptest.NewRequest(http.MethodPost, "/events/exposure", strings.NewReader(`{"booking_id":"booking_3","viewer_id":"viewer_456","exposure_duration":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Empty(t, mockDB.events)
+}
+
 func TestNewPlacementHandler(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -627,7 +2051,7 @@ func TestNewPlacementHandler(t *testing.T) {
 				assert.Nil(t, handler)
 			} else {
 				assert.NotNil(t, handler)
-				assert.Equal(t, tt.database, handler.db)
+				assert.Equal(t, placement.NewService(tt.database), handler.placements)
 			}
 		})
 	}
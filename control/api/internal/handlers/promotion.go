@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/promotion"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// maxExportCampaigns bounds the campaigns of one export
+const maxExportCampaigns = 100
+
+// PromotionStore exports campaigns from this environment and imports them from others
+type PromotionStore interface {
+	ExportBundle(source string, campaignIDs []string) (*promotion.Bundle, error)
+	ImportBundle(imp *promotion.Import) (*promotion.Manifest, error)
+	GetPromotionImport(orgID, importID string) (*promotion.Manifest, error)
+}
+
+// PromotionHandler promotes campaigns between environments, e.g. from
+// staging to production
+type PromotionHandler struct {
+	db          PromotionStore
+	environment string
+	authz       *authz.Authorizer
+}
+
+// NewPromotionHandler creates a promotion handler; environment names the
+// source of the bundles it exports
+func NewPromotionHandler(store PromotionStore, environment string) *PromotionHandler {
+	return &PromotionHandler{db: store, environment: environment}
+}
+
+// SetAuthorizer requires view access to exported campaigns and manage
+// access to the campaigns an import writes to
+func (h *PromotionHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// Export handles GET /promotion/export?campaign_id=...
+func (h *PromotionHandler) Export(c *gin.Context) {
+	var campaignIDs []string
+	seen := make(map[string]bool)
+	for _, value := range c.QueryArray("campaign_id") {
+		for _, campaignID := range strings.Split(value, ",") {
+			campaignID = strings.TrimSpace(campaignID)
+			if campaignID != "" && !seen[campaignID] {
+				seen[campaignID] = true
+				campaignIDs = append(campaignIDs, campaignID)
+			}
+		}
+	}
+	if len(campaignIDs) == 0 || len(campaignIDs) > maxExportCampaigns {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign_id must name 1 to 100 campaigns"})
+		return
+	}
+	for _, campaignID := range campaignIDs {
+		if !h.authz.Authorize(c, labels.ResourceCampaign, campaignID, authz.PermissionView) {
+			return
+		}
+	}
+
+	bundle, err := h.db.ExportBundle(h.environment, campaignIDs)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to export campaigns")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":     "promotion_export",
+		"user_id":   c.GetString("user_id"),
+		"campaigns": campaignIDs,
+		"bookings":  len(bundle.Bookings),
+	}).Info("Exported campaigns for promotion")
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// Import handles POST /promotion/import
+func (h *PromotionHandler) Import(c *gin.Context) {
+	var req struct {
+		Bundle *promotion.Bundle `json:"bundle" binding:"required"`
+		IDMap  promotion.IDMap   `json:"id_map"`
+		DryRun bool              `json:"dry_run"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imp := &promotion.Import{
+		Bundle:     *req.Bundle,
+		IDMap:      req.IDMap,
+		DryRun:     req.DryRun || c.Query("dry_run") == "true",
+		OrgID:      c.GetString("org_id"),
+		ImportedBy: c.GetString("user_id"),
+	}
+	if problems := imp.Validate(); len(problems) > 0 {
+		manifest := promotion.NewManifest(imp)
+		manifest.Problems = problems
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Bundle cannot be imported", "manifest": manifest})
+		return
+	}
+	for _, campaign := range imp.Bundle.Campaigns {
+		if !h.authz.Authorize(c, labels.ResourceCampaign, imp.IDMap.Campaign(campaign.CampaignID), authz.PermissionManage) {
+			return
+		}
+	}
+
+	manifest, err := h.db.ImportBundle(imp)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to import bundle")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if len(manifest.Problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Bundle cannot be imported", "manifest": manifest})
+		return
+	}
+	if imp.DryRun {
+		c.JSON(http.StatusOK, manifest)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":     "promotion_import",
+		"user_id":   imp.ImportedBy,
+		"org_id":    imp.OrgID,
+		"import_id": manifest.ImportID,
+		"source":    manifest.Source,
+		"bookings":  len(manifest.Bookings),
+	}).Info("Imported promoted campaigns")
+
+	c.JSON(http.StatusCreated, manifest)
+}
+
+// GetImport handles GET /promotion/imports/:import_id
+func (h *PromotionHandler) GetImport(c *gin.Context) {
+	manifest, err := h.db.GetPromotionImport(c.GetString("org_id"), c.Param("import_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get import")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if manifest == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	c.JSON(http.StatusOK, manifest)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/promotion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPromotionStore struct {
+	bundle      *promotion.Bundle
+	exported    []string
+	imported    *promotion.Import
+	problems    []promotion.Problem
+	imports     map[string]*promotion.Manifest
+	shouldError bool
+}
+
+func (m *MockPromotionStore) ExportBundle(source string, campaignIDs []string) (*promotion.Bundle, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.exported = campaignIDs
+	bundle := *m.bundle
+	bundle.Source = source
+	return &bundle, nil
+}
+
+func (m *MockPromotionStore) ImportBundle(imp *promotion.Import) (*promotion.Manifest, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.imported = imp
+	manifest := promotion.NewManifest(imp)
+	if len(m.problems) > 0 {
+		manifest.Problems = m.problems
+		return manifest, nil
+	}
+	for _, b := range imp.Bundle.Bookings {
+		manifest.Bookings = append(manifest.Bookings, promotion.Mapping{
+			SourceID: b.BookingID,
+			TargetID: "booking_" + imp.IDMap.Surface(b.SurfaceID) + "_1",
+			Action:   promotion.ActionCreate,
+		})
+	}
+	if !imp.DryRun {
+		manifest.ImportID = "import_1"
+	}
+	return manifest, nil
+}
+
+func (m *MockPromotionStore) GetPromotionImport(orgID, importID string) (*promotion.Manifest, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.imports[importID], nil
+}
+
+func testBundle() *promotion.Bundle {
+	budget := 5000.0
+	return &promotion.Bundle{
+		FormatVersion: promotion.FormatVersion,
+		Source:        "staging",
+		Campaigns:     []promotion.Campaign{{CampaignID: "camp_1", Budget: &budget}},
+		Creatives:     []promotion.Creative{{CreativeID: "creative_1"}},
+		Bookings: []promotion.Booking{
+			{BookingID: "booking_a", SurfaceID: "surface_stg_1", AdvertiserID: "adv_1", CampaignID: "camp_1", CreativeAssetID: "creative_1", BidAmountCPM: 4.5},
+			{BookingID: "booking_b", SurfaceID: "surface_stg_2", AdvertiserID: "adv_1", CampaignID: "camp_1", BidAmountCPM: 3},
+		},
+	}
+}
+
+func TestPromotionHandler_Export(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		store          *MockPromotionStore
+		expectedStatus int
+		expectedIDs    []string
+		description    string
+	}{
+		{
+			name:           "campaigns",
+			query:          "?campaign_id=camp_1,camp_2&campaign_id=camp_1",
+			store:          &MockPromotionStore{bundle: testBundle()},
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"camp_1", "camp_2"},
+			description:    "Should export each named campaign once",
+		},
+		{
+			name:           "no campaign",
+			store:          &MockPromotionStore{bundle: testBundle()},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a campaign",
+		},
+		{
+			name:           "store error",
+			query:          "?campaign_id=camp_1",
+			store:          &MockPromotionStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPromotionHandler(tt.store, "staging")
+			router := gin.New()
+			router.GET("/promotion/export", handler.Export)
+
+			req := httptest.NewRequest(http.MethodGet, "/promotion/export"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var bundle promotion.Bundle
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &bundle))
+			assert.Equal(t, tt.expectedIDs, tt.store.exported)
+			assert.Equal(t, "staging", bundle.Source)
+			assert.Len(t, bundle.Bookings, 2)
+		})
+	}
+}
+
+func TestPromotionHandler_Import(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	withBundle := func(change func(b *promotion.Bundle)) *promotion.Bundle {
+		b := testBundle()
+		change(b)
+		return b
+	}
+
+	tests := []struct {
+		name           string
+		bundle         *promotion.Bundle
+		idMap          promotion.IDMap
+		dryRun         bool
+		store          *MockPromotionStore
+		expectedStatus int
+		expectImport   bool
+		description    string
+	}{
+		{
+			name:           "import",
+			bundle:         testBundle(),
+			idMap:          promotion.IDMap{Surfaces: map[string]string{"surface_stg_1": "surface_prd_1", "surface_stg_2": "surface_prd_2"}},
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusCreated,
+			expectImport:   true,
+			description:    "Should import a valid bundle and return its manifest",
+		},
+		{
+			name:           "dry run",
+			bundle:         testBundle(),
+			dryRun:         true,
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusOK,
+			expectImport:   true,
+			description:    "Should preview an import",
+		},
+		{
+			name:           "missing bundle",
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a bundle",
+		},
+		{
+			name:           "unknown format",
+			bundle:         withBundle(func(b *promotion.Bundle) { b.FormatVersion = 99 }),
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should reject bundles of another format version",
+		},
+		{
+			name:           "campaign not in bundle",
+			bundle:         withBundle(func(b *promotion.Bundle) { b.Bookings[1].CampaignID = "camp_missing" }),
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should reject bookings of campaigns the bundle does not carry",
+		},
+		{
+			name:           "surface booked twice",
+			bundle:         testBundle(),
+			idMap:          promotion.IDMap{Surfaces: map[string]string{"surface_stg_1": "surface_prd_1", "surface_stg_2": "surface_prd_1"}},
+			store:          &MockPromotionStore{},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should reject mapping two bookings onto one surface",
+		},
+		{
+			name:   "target problems",
+			bundle: testBundle(),
+			store: &MockPromotionStore{problems: []promotion.Problem{
+				{ResourceType: promotion.ResourceBooking, SourceID: "booking_a", Message: "surface surface_stg_1 does not exist here"},
+			}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectImport:   true,
+			description:    "Should return the problems found in the target environment",
+		},
+		{
+			name:           "store error",
+			bundle:         testBundle(),
+			store:          &MockPromotionStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPromotionHandler(tt.store, "production")
+			router := gin.New()
+			router.Use(withOrg("user_1", "org_a"))
+			router.POST("/promotion/import", handler.Import)
+
+			body, err := json.Marshal(map[string]interface{}{"bundle": tt.bundle, "id_map": tt.idMap, "dry_run": tt.dryRun})
+			require.NoError(t, err)
+			if tt.bundle == nil {
+				body = []byte(`{"dry_run": true}`)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/promotion/import", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Equal(t, tt.expectImport, tt.store.imported != nil, tt.description)
+
+			switch tt.expectedStatus {
+			case http.StatusCreated, http.StatusOK:
+				var manifest promotion.Manifest
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &manifest))
+				assert.Equal(t, tt.dryRun, manifest.DryRun)
+				assert.Equal(t, tt.dryRun, manifest.ImportID == "")
+				require.Len(t, manifest.Bookings, 2)
+				assert.Equal(t, "booking_a", manifest.Bookings[0].SourceID)
+				assert.Equal(t, "booking_"+tt.idMap.Surface("surface_stg_1")+"_1", manifest.Bookings[0].TargetID)
+				assert.Equal(t, "org_a", tt.store.imported.OrgID)
+			case http.StatusUnprocessableEntity:
+				var response struct {
+					Manifest promotion.Manifest `json:"manifest"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.NotEmpty(t, response.Manifest.Problems, tt.description)
+			}
+		})
+	}
+}
+
+func TestPromotionHandler_GetImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockPromotionStore{imports: map[string]*promotion.Manifest{
+		"import_1": {ImportID: "import_1", Source: "staging"},
+	}}
+	handler := NewPromotionHandler(store, "production")
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.GET("/promotion/imports/:import_id", handler.GetImport)
+
+	tests := []struct {
+		name           string
+		importID       string
+		expectedStatus int
+		description    string
+	}{
+		{"existing import", "import_1", http.StatusOK, "Should return the import's manifest"},
+		{"unknown import", "import_2", http.StatusNotFound, "Should return 404 for unknown imports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/promotion/imports/"+tt.importID, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/reach"
+	"github.com/sirupsen/logrus"
+)
+
+// ReachStore reads campaigns' daily viewer sketches and frequency
+// distributions
+type ReachStore interface {
+	GetReachDays(q reach.Query) ([]reach.Day, *time.Time, error)
+	GetFrequencyDistribution(q reach.Query) (map[string]map[int]int64, error)
+}
+
+// SetReach enables reach and frequency reports from store
+func (h *ReportHandler) SetReach(store ReachStore) {
+	h.reach = store
+}
+
+// parseReachQuery reads a reach report's campaigns and range from the query
+// string. The range defaults to the last 30 days.
+func parseReachQuery(c *gin.Context) (reach.Query, error) {
+	now := time.Now().UTC()
+	from, to := now.Add(-30*24*time.Hour), now
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return reach.Query{}, errors.New("invalid from parameter, expected RFC3339")
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return reach.Query{}, errors.New("invalid to parameter, expected RFC3339")
+		}
+		to = parsed
+	}
+
+	var campaignIDs []string
+	for _, value := range c.QueryArray("campaign_id") {
+		for _, id := range strings.Split(value, ",") {
+			campaignIDs = append(campaignIDs, strings.TrimSpace(id))
+		}
+	}
+	return reach.NewQuery(campaignIDs, from, to)
+}
+
+// GetReach handles GET /analytics/reach. It reports each campaign's reach,
+// average frequency, frequency distribution and daily new reach over whole
+// UTC days, and the overlap of every pair of campaigns.
+func (h *ReportHandler) GetReach(c *gin.Context) {
+	if h.reach == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reach reports are not enabled"})
+		return
+	}
+	q, err := parseReachQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range q.CampaignIDs {
+		if !h.authz.Authorize(c, labels.ResourceCampaign, id, authz.PermissionView) {
+			return
+		}
+	}
+	q.Tenant = authz.Scope(c)
+	privacy, _, err := h.privacyFor(c)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report privacy settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	q.Privacy = privacy
+
+	days, through, err := h.reach.GetReachDays(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get reach days")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	frequencies, err := h.reach.GetFrequencyDistribution(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get frequency distribution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	report := reach.Build(q, days, frequencies)
+	report.RolledUpThrough = through
+	c.JSON(http.StatusOK, report)
+}
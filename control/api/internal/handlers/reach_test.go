@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/reach"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/sketch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReachStore struct {
+	days        []reach.Day
+	frequencies map[string]map[int]int64
+	query       reach.Query
+}
+
+func (m *MockReachStore) GetReachDays(q reach.Query) ([]reach.Day, *time.Time, error) {
+	m.query = q
+	days := make([]reach.Day, 0)
+	for _, d := range m.days {
+		if !d.Day.Before(q.From) && d.Day.Before(q.To) {
+			days = append(days, d)
+		}
+	}
+	return days, nil, nil
+}
+
+func (m *MockReachStore) GetFrequencyDistribution(q reach.Query) (map[string]map[int]int64, error) {
+	return m.frequencies, nil
+}
+
+// reachDay sketches viewers from to to of a campaign's day
+func reachDay(campaignID string, day time.Time, from, to int) reach.Day {
+	viewers := sketch.New()
+	for i := from; i < to; i++ {
+		viewers.Add(fmt.Sprintf("viewer_%d", i))
+	}
+	return reach.Day{CampaignID: campaignID, Day: day, Impressions: int64(to-from) * 2, Viewers: viewers}
+}
+
+func TestReportHandler_GetReach(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	first := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	store := &MockReachStore{
+		days: []reach.Day{
+			reachDay("camp_a", second, 10000, 30000),
+			reachDay("camp_a", first, 0, 20000),
+			reachDay("camp_b", first, 20000, 50000),
+		},
+		frequencies: map[string]map[int]int64{
+			"camp_a": {1: 15000, 2: 14900, reach.MaxFrequency: 100},
+		},
+	}
+	handler := NewReportHandler(&MockReportStore{}, reporting.NewGuard(reporting.DefaultLimits()))
+	router := gin.New()
+	router.GET("/analytics/reach", handler.GetReach)
+	get := func(query string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/reach?"+query, nil))
+		return resp
+	}
+	getReport := func(query string) reach.Report {
+		resp := get(query)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var report reach.Report
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+		return report
+	}
+	near := func(expected, got int64) {
+		assert.InDelta(t, expected, got, 4*sketch.StandardError*float64(expected), "expected about %d", expected)
+	}
+
+	assert.Equal(t, http.StatusNotImplemented, get("campaign_id=camp_a").Code)
+	handler.SetReach(store)
+
+	t.Run("reach", func(t *testing.T) {
+		report := getReport("campaign_id=camp_a,camp_b&from=2026-03-01T12:00:00Z&to=2026-03-02T06:00:00Z")
+		assert.Equal(t, first, store.query.From, "Should cover whole UTC days")
+		assert.Equal(t, second.Add(24*time.Hour), store.query.To)
+
+		require.Len(t, report.Campaigns, 2)
+		a, b := report.Campaigns[0], report.Campaigns[1]
+		assert.EqualValues(t, 80000, a.Impressions)
+		near(30000, a.Reach)
+		assert.InDelta(t, 80000.0/30000, a.AverageFrequency, 0.1)
+		require.Len(t, a.Days, 2)
+		assert.Equal(t, first, a.Days[0].Day, "Should order days")
+		near(20000, a.Days[0].NewReach)
+		near(10000, a.Days[1].NewReach)
+		assert.Equal(t, a.Reach, a.Days[1].CumulativeReach)
+		require.Len(t, a.Frequency, 3)
+		assert.Equal(t, "10+", a.Frequency[2].Frequency)
+		assert.InDelta(t, 0.5, a.Frequency[0].Share, 0.001)
+		assert.Empty(t, b.Frequency)
+
+		near(50000, report.CombinedReach)
+		require.Len(t, report.Overlaps, 1)
+		assert.Equal(t, [2]string{"camp_a", "camp_b"}, report.Overlaps[0].CampaignIDs)
+		near(10000, report.Overlaps[0].Overlap)
+
+		report = getReport("campaign_id=camp_a&campaign_id=camp_b&from=2026-03-02T00:00:00Z&to=2026-03-03T00:00:00Z")
+		near(20000, report.Campaigns[0].Reach)
+		assert.Empty(t, report.Campaigns[1].Days)
+		require.Len(t, report.Overlaps, 1)
+		assert.Zero(t, report.Overlaps[0].Overlap)
+	})
+
+	t.Run("overlap", func(t *testing.T) {
+		store.days = append(store.days, reachDay("camp_c", first, 15000, 25000))
+		report := getReport("campaign_id=camp_a,camp_c&from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z")
+		require.Len(t, report.Overlaps, 1)
+		near(5000, report.Overlaps[0].Overlap)
+		near(25000, report.Overlaps[0].Reach)
+		assert.InDelta(t, 0.5, report.Overlaps[0].Shares[1], 0.1)
+	})
+
+	t.Run("privacy", func(t *testing.T) {
+		handler.SetPrivacy(&MockReportPrivacyStore{settings: map[string]reporting.Privacy{}}, reporting.Privacy{MinAudience: 1000, Mode: reporting.PrivacyAggregate})
+		defer handler.SetPrivacy(nil, reporting.Privacy{})
+		report := getReport("campaign_id=camp_a,camp_b&from=2026-03-02T00:00:00Z&to=2026-03-03T00:00:00Z")
+		assert.Len(t, report.Campaigns[0].Frequency, 2, "Should leave out frequencies reaching too few viewers")
+		assert.Empty(t, report.Overlaps)
+		require.NotNil(t, report.Privacy)
+		assert.Equal(t, 2, report.Privacy.SuppressedRows)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, query := range []string{
+			"",
+			"campaign_id=camp_a,camp_a",
+			"campaign_id=camp_a,",
+			"campaign_id=c1,c2,c3,c4,c5,c6,c7,c8,c9,c10,c11",
+			"campaign_id=camp_a&from=yesterday",
+			"campaign_id=camp_a&from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z",
+			"campaign_id=camp_a&from=2024-01-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+		} {
+			assert.Equal(t, http.StatusBadRequest, get(query).Code, query)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/reconcile"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHoldTTL bounds the hold TTL a reconciliation request may ask for
+const maxHoldTTL = 30 * 24 * time.Hour
+
+// ReconciliationStore finds drift between bookings and inventory
+type ReconciliationStore interface {
+	FindBookingIssues(orgID string, holdTTL time.Duration, limit int) ([]reconcile.Issue, error)
+}
+
+// ReconciliationHandler reports bookings that no longer match SGI inventory
+type ReconciliationHandler struct {
+	db      ReconciliationStore
+	holdTTL time.Duration
+}
+
+// NewReconciliationHandler creates a reconciliation handler. holdTTL is the
+// default age after which pending bookings are reported.
+func NewReconciliationHandler(store ReconciliationStore, holdTTL time.Duration) *ReconciliationHandler {
+	if holdTTL <= 0 {
+		holdTTL = reconcile.DefaultHoldTTL
+	}
+	return &ReconciliationHandler{db: store, holdTTL: holdTTL}
+}
+
+// GetReconciliation handles GET /bookings/reconciliation
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	holdTTL := h.holdTTL
+	if value := c.Query("hold_ttl"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > maxHoldTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hold_ttl must be a duration between 0 and 720h"})
+			return
+		}
+		holdTTL = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 || limit > 5000 {
+		limit = 500
+	}
+
+	report, err := reconcile.Run(h.db, c.GetString("org_id"), holdTTL, limit)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to reconcile bookings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"org_id": c.GetString("org_id"),
+		"issues": len(report.Issues),
+	}).Info("Reconciled bookings")
+
+	c.JSON(http.StatusOK, report)
+}
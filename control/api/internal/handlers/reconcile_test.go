@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/reconcile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReconciliationStore struct {
+	issues      []reconcile.Issue
+	shouldError bool
+
+	orgID   string
+	holdTTL time.Duration
+	limit   int
+}
+
+func (m *MockReconciliationStore) FindBookingIssues(orgID string, holdTTL time.Duration, limit int) ([]reconcile.Issue, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	m.orgID, m.holdTTL, m.limit = orgID, holdTTL, limit
+	if len(m.issues) > limit {
+		return m.issues[:limit], nil
+	}
+	return m.issues, nil
+}
+
+func TestReconciliationHandler_GetReconciliation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bookedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	issues := []reconcile.Issue{
+		{Kind: reconcile.KindSurfaceWithdrawn, BookingID: "booking_1", SurfaceID: "surface_001", CampaignID: "camp_1", Status: "confirmed", BookedAt: bookedAt},
+		{Kind: reconcile.KindHoldExpired, BookingID: "booking_2", SurfaceID: "surface_002", CampaignID: "camp_1", Status: "pending", BookedAt: bookedAt},
+		{Kind: reconcile.KindHoldExpired, BookingID: "booking_3", SurfaceID: "surface_003", CampaignID: "camp_2", Status: "pending", BookedAt: bookedAt},
+	}
+
+	tests := []struct {
+		name              string
+		query             string
+		store             *MockReconciliationStore
+		expectedStatus    int
+		expectedHoldTTL   time.Duration
+		expectedIssues    int
+		expectedTruncated bool
+		description       string
+	}{
+		{
+			name:            "default hold TTL",
+			store:           &MockReconciliationStore{issues: issues},
+			expectedStatus:  http.StatusOK,
+			expectedHoldTTL: 48 * time.Hour,
+			expectedIssues:  3,
+			description:     "Should report every issue with the configured hold TTL",
+		},
+		{
+			name:            "custom hold TTL",
+			query:           "?hold_ttl=2h",
+			store:           &MockReconciliationStore{issues: issues},
+			expectedStatus:  http.StatusOK,
+			expectedHoldTTL: 2 * time.Hour,
+			expectedIssues:  3,
+			description:     "Should pass the requested hold TTL to the store",
+		},
+		{
+			name:              "truncated",
+			query:             "?limit=2",
+			store:             &MockReconciliationStore{issues: issues},
+			expectedStatus:    http.StatusOK,
+			expectedHoldTTL:   48 * time.Hour,
+			expectedIssues:    2,
+			expectedTruncated: true,
+			description:       "Should flag reports cut short by the limit",
+		},
+		{
+			name:           "invalid hold TTL",
+			query:          "?hold_ttl=forever",
+			store:          &MockReconciliationStore{},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unparseable hold TTLs",
+		},
+		{
+			name:           "store error",
+			store:          &MockReconciliationStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReconciliationHandler(tt.store, 48*time.Hour)
+			router := gin.New()
+			router.GET("/bookings/reconciliation", withOrg("user_1", "org_a"), handler.GetReconciliation)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/reconciliation"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "org_a", tt.store.orgID)
+			assert.Equal(t, tt.expectedHoldTTL, tt.store.holdTTL)
+
+			var report reconcile.Report
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+			assert.Len(t, report.Issues, tt.expectedIssues, tt.description)
+			assert.Equal(t, tt.expectedTruncated, report.Truncated, tt.description)
+
+			for _, issue := range report.Issues {
+				assert.NotEmpty(t, issue.Severity, "Every issue should carry a severity")
+				assert.NotEmpty(t, issue.Remediation.Action, "Every issue should suggest a remediation")
+			}
+			assert.Equal(t, reconcile.SeverityError, report.Issues[0].Severity)
+			assert.Equal(t, "rebook", report.Issues[0].Remediation.Action)
+			if !tt.expectedTruncated {
+				assert.Equal(t, map[string]int{reconcile.KindSurfaceWithdrawn: 1, reconcile.KindHoldExpired: 2}, report.Counts)
+			}
+		})
+	}
+}
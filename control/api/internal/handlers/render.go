@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/inscenium/inscenium/control/api/internal/watermark"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// renderWebhookSource namespaces render callback nonces
+const renderWebhookSource = "render"
+
+// maxCallbackBytes bounds the size of a render callback body
+const maxCallbackBytes = 64 << 10
+
+// maxWatermarkAttempts bounds the watermark IDs tried for a job whose ID
+// collides with another job's
+const maxWatermarkAttempts = 3
+
+// Render callback outcomes recorded in metrics
+const (
+	callbackApplied  = "applied"
+	callbackIgnored  = "ignored"
+	callbackRejected = "rejected"
+)
+
+// RenderJobStore persists render jobs and webhook nonces
+type RenderJobStore interface {
+	GetRenderJob(jobID string) (*render.Job, error)
+	ListRenderJobs(bookingID string, limit, offset int) ([]*render.Job, error)
+	SaveRenderJob(job *render.Job) (bool, error)
+	ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// RenderHandler receives render farm callbacks and serves render job status
+type RenderHandler struct {
+	db       RenderJobStore
+	verifier *webhook.Verifier
+	authz    *authz.Authorizer
+}
+
+// NewRenderHandler creates a new render handler. Callbacks are rejected
+// unless the verifier has at least one secret.
+func NewRenderHandler(store RenderJobStore, verifier *webhook.Verifier) *RenderHandler {
+	return &RenderHandler{db: store, verifier: verifier}
+}
+
+// SetAuthorizer requires view access to a job's booking to read it
+func (h *RenderHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// RenderCallback handles POST /webhooks/render. Workers sign each delivery
+// attempt with a fresh nonce; the signature replaces bearer authentication.
+func (h *RenderHandler) RenderCallback(c *gin.Context) {
+	if !h.verifier.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Render webhooks are not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(body) > maxCallbackBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Callback body too large"})
+		return
+	}
+
+	nonce := c.GetHeader(webhook.HeaderNonce)
+	signedAt, err := h.verifier.Verify(c.GetHeader(webhook.HeaderTimestamp), nonce, c.GetHeader(webhook.HeaderSignature), body)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"audit":     "webhook",
+			"source":    renderWebhookSource,
+			"client_ip": c.ClientIP(),
+		}).WithError(err).Warn("Rejected render callback signature")
+		metrics.RenderCallbacks.WithLabelValues("", callbackRejected).Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	claimed, err := h.db.ClaimWebhookNonce(renderWebhookSource, nonce, signedAt.Add(h.verifier.Tolerance()))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to record render callback nonce")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !claimed {
+		logrus.WithFields(logrus.Fields{
+			"audit":  "webhook",
+			"source": renderWebhookSource,
+			"nonce":  nonce,
+		}).Warn("Rejected replayed render callback")
+		metrics.RenderCallbacks.WithLabelValues("", callbackRejected).Inc()
+		c.JSON(http.StatusConflict, gin.H{"error": "Nonce already used"})
+		return
+	}
+
+	var event render.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body"})
+		return
+	}
+	if event.JobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "job_id is required"})
+		return
+	}
+	if !render.ValidEvent(event.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + event.Type})
+		return
+	}
+	if event.Type == render.EventCompleted && event.OutputURI == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "output_uri is required for job.completed"})
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = signedAt
+	}
+
+	h.applyEvent(c, event)
+}
+
+// applyEvent moves the job through the state transition for event
+func (h *RenderHandler) applyEvent(c *gin.Context, event render.Event) {
+	logger := logrus.WithFields(logrus.Fields{
+		"job_id": event.JobID,
+		"event":  event.Type,
+	})
+
+	job, err := h.db.GetRenderJob(event.JobID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get render job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Late progress after a newer callback is expected with at-least-once
+	// delivery; acknowledge it so the worker stops retrying.
+	if job != nil && event.OccurredAt.Before(job.LastEventAt) {
+		h.ignoreEvent(c, logger, job, event, "stale")
+		return
+	}
+
+	next, err := render.Apply(job, event)
+	if err != nil {
+		var transitionErr *render.TransitionError
+		if errors.As(err, &transitionErr) {
+			logger.WithField("status", transitionErr.From).Warn("Rejected render job transition")
+			metrics.RenderCallbacks.WithLabelValues(event.Type, callbackRejected).Inc()
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  err.Error(),
+				"job_id": event.JobID,
+				"status": transitionErr.From,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.saveRenderJob(next)
+	if err != nil {
+		logger.WithError(err).Error("Failed to save render job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !saved {
+		h.ignoreEvent(c, logger, job, event, "superseded")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"status":   next.Status,
+		"progress": next.Progress,
+	}).Info("Applied render callback")
+	metrics.RenderCallbacks.WithLabelValues(event.Type, callbackApplied).Inc()
+
+	response := gin.H{
+		"job_id":  next.ID,
+		"status":  next.Status,
+		"applied": true,
+	}
+	if next.WatermarkID != 0 {
+		response["watermark_id"] = next.WatermarkID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// saveRenderJob saves a job, issuing the watermark its output carries once
+// it has a booking
+func (h *RenderHandler) saveRenderJob(job *render.Job) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		if job.BookingID != "" && job.WatermarkID == 0 {
+			id, err := watermark.NewID()
+			if err != nil {
+				return false, err
+			}
+			job.WatermarkID = id
+		}
+		saved, err := h.db.SaveRenderJob(job)
+		if errors.Is(err, db.ErrWatermarkTaken) && attempt < maxWatermarkAttempts {
+			job.WatermarkID = 0
+			continue
+		}
+		return saved, err
+	}
+}
+
+// ignoreEvent acknowledges a callback that no longer changes the job
+func (h *RenderHandler) ignoreEvent(c *gin.Context, logger *logrus.Entry, job *render.Job, event render.Event, reason string) {
+	logger.WithField("reason", reason).Info("Ignored render callback")
+	metrics.RenderCallbacks.WithLabelValues(event.Type, callbackIgnored).Inc()
+
+	response := gin.H{
+		"job_id":  event.JobID,
+		"applied": false,
+		"reason":  reason,
+	}
+	if job != nil {
+		response["status"] = job.Status
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRenderJob handles GET /render-jobs/:job_id
+func (h *RenderHandler) GetRenderJob(c *gin.Context) {
+	job, err := h.db.GetRenderJob(c.Param("job_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get render job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Render job not found"})
+		return
+	}
+
+	if !h.authz.Authorize(c, labels.ResourceBooking, job.BookingID, authz.PermissionView) {
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListRenderJobs handles GET /render-jobs?booking_id=
+func (h *RenderHandler) ListRenderJobs(c *gin.Context) {
+	bookingID := c.Query("booking_id")
+	if bookingID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	if !h.authz.Authorize(c, labels.ResourceBooking, bookingID, authz.PermissionView) {
+		return
+	}
+
+	jobs, err := h.db.ListRenderJobs(bookingID, limit, offset)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list render jobs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id":  bookingID,
+		"render_jobs": jobs,
+		"total_count": len(jobs),
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRenderSecret = "render-secret"
+
+type MockRenderJobStore struct {
+	jobs        map[string]*render.Job
+	nonces      map[string]bool
+	shouldError bool
+}
+
+func newMockRenderJobStore() *MockRenderJobStore {
+	return &MockRenderJobStore{jobs: map[string]*render.Job{}, nonces: map[string]bool{}}
+}
+
+func (m *MockRenderJobStore) GetRenderJob(jobID string) (*render.Job, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.jobs[jobID], nil
+}
+
+func (m *MockRenderJobStore) ListRenderJobs(bookingID string, limit, offset int) ([]*render.Job, error) {
+	jobs := make([]*render.Job, 0)
+	for _, job := range m.jobs {
+		if job.BookingID == bookingID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MockRenderJobStore) SaveRenderJob(job *render.Job) (bool, error) {
+	m.jobs[job.ID] = job
+	return true, nil
+}
+
+func (m *MockRenderJobStore) ClaimWebhookNonce(source, nonce string, expiresAt time.Time) (bool, error) {
+	if m.nonces[source+"/"+nonce] {
+		return false, nil
+	}
+	m.nonces[source+"/"+nonce] = true
+	return true, nil
+}
+
+// signedCallback builds a render callback signed with secret at signedAt
+func signedCallback(secret, nonce string, signedAt time.Time, body string) *http.Request {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/render", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderTimestamp, timestamp)
+	req.Header.Set(webhook.HeaderNonce, nonce)
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, timestamp, nonce, []byte(body)))
+	return req
+}
+
+func TestRenderHandler_RenderCallbackSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	body := `{"event":"job.queued","job_id":"render_1","booking_id":"booking_123"}`
+
+	tests := []struct {
+		name           string
+		request        func() *http.Request
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "valid signature",
+			request:        func() *http.Request { return signedCallback(testRenderSecret, "nonce-1", now, body) },
+			expectedStatus: http.StatusOK,
+			description:    "Should accept callbacks signed with the shared secret",
+		},
+		{
+			name:           "rotated secret",
+			request:        func() *http.Request { return signedCallback("previous-secret", "nonce-2", now, body) },
+			expectedStatus: http.StatusOK,
+			description:    "Should accept any active secret during rotation",
+		},
+		{
+			name:           "wrong secret",
+			request:        func() *http.Request { return signedCallback("other-secret", "nonce-3", now, body) },
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject signatures made with an unknown secret",
+		},
+		{
+			name: "tampered body",
+			request: func() *http.Request {
+				req := signedCallback(testRenderSecret, "nonce-4", now, body)
+				req.Body = http.NoBody
+				return req
+			},
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject bodies that do not match the signature",
+		},
+		{
+			name:           "stale timestamp",
+			request:        func() *http.Request { return signedCallback(testRenderSecret, "nonce-5", now.Add(-time.Hour), body) },
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject timestamps outside the tolerance",
+		},
+		{
+			name: "missing headers",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/webhooks/render", bytes.NewBufferString(body))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should reject unsigned callbacks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := webhook.NewVerifier([]string{testRenderSecret, "previous-secret"}, webhook.DefaultTolerance)
+			handler := NewRenderHandler(newMockRenderJobStore(), verifier)
+			router := gin.New()
+			router.POST("/webhooks/render", handler.RenderCallback)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, tt.request())
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
+
+func TestRenderHandler_RenderCallbackReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockRenderJobStore()
+	handler := NewRenderHandler(store, webhook.NewVerifier([]string{testRenderSecret}, webhook.DefaultTolerance))
+	router := gin.New()
+	router.POST("/webhooks/render", handler.RenderCallback)
+
+	body := `{"event":"job.started","job_id":"render_1"}`
+	now := time.Now()
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, signedCallback(testRenderSecret, "nonce-1", now, body))
+	assert.Equal(t, http.StatusOK, resp.Code, "Should accept the first delivery")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, signedCallback(testRenderSecret, "nonce-1", now, body))
+	assert.Equal(t, http.StatusConflict, resp.Code, "Should reject a replayed nonce")
+}
+
+func TestRenderHandler_RenderCallbackTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	base := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	at := func(offset int) string {
+		return base.Add(time.Duration(offset) * time.Second).Format(time.RFC3339)
+	}
+
+	store := newMockRenderJobStore()
+	handler := NewRenderHandler(store, webhook.NewVerifier([]string{testRenderSecret}, webhook.DefaultTolerance))
+	router := gin.New()
+	router.POST("/webhooks/render", handler.RenderCallback)
+
+	steps := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedApplied bool
+		jobStatus       string
+		description     string
+	}{
+		{"queued", `{"event":"job.queued","job_id":"render_1","booking_id":"booking_123","occurred_at":"` + at(0) + `"}`, http.StatusOK, true, render.StatusQueued, "Should create the job on its first callback"},
+		{"started", `{"event":"job.started","job_id":"render_1","worker_id":"gpu-07","occurred_at":"` + at(1) + `"}`, http.StatusOK, true, render.StatusRunning, "Should move queued jobs to running"},
+		{"progress", `{"event":"job.progress","job_id":"render_1","progress":0.5,"occurred_at":"` + at(3) + `"}`, http.StatusOK, true, render.StatusRunning, "Should record progress"},
+		{"stale progress", `{"event":"job.progress","job_id":"render_1","progress":0.25,"occurred_at":"` + at(2) + `"}`, http.StatusOK, false, render.StatusRunning, "Should acknowledge but ignore out-of-order callbacks"},
+		{"completed without output", `{"event":"job.completed","job_id":"render_1","occurred_at":"` + at(4) + `"}`, http.StatusBadRequest, false, "", "Should require an output URI on completion"},
+		{"completed", `{"event":"job.completed","job_id":"render_1","output_uri":"s3://renders/render_1.mp4","occurred_at":"` + at(5) + `"}`, http.StatusOK, true, render.StatusCompleted, "Should complete running jobs"},
+		{"failed after completion", `{"event":"job.failed","job_id":"render_1","error":"oom","occurred_at":"` + at(6) + `"}`, http.StatusConflict, false, "", "Should refuse to leave a terminal status"},
+		{"unknown event", `{"event":"job.exploded","job_id":"render_1"}`, http.StatusBadRequest, false, "", "Should reject unknown event types"},
+	}
+
+	var watermarkID interface{}
+	for i, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, signedCallback(testRenderSecret, "nonce-"+strconv.Itoa(i), time.Now(), step.body))
+
+			assert.Equal(t, step.expectedStatus, resp.Code, step.description)
+
+			if step.expectedStatus == http.StatusOK {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, step.expectedApplied, response["applied"])
+				assert.Equal(t, step.jobStatus, response["status"])
+				if step.expectedApplied {
+					if watermarkID == nil {
+						watermarkID = response["watermark_id"]
+					}
+					assert.NotNil(t, response["watermark_id"], "Jobs with a booking should carry a watermark")
+					assert.Equal(t, watermarkID, response["watermark_id"], "A job's watermark should not change")
+				}
+			}
+		})
+	}
+
+	job := store.jobs["render_1"]
+	require.NotNil(t, job)
+	assert.Equal(t, render.StatusCompleted, job.Status)
+	assert.Equal(t, "booking_123", job.BookingID)
+	assert.Equal(t, "gpu-07", job.WorkerID)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.Equal(t, "s3://renders/render_1.mp4", job.OutputURI)
+	assert.NotZero(t, job.WatermarkID)
+}
+
+func TestRenderHandler_RenderCallbackDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewRenderHandler(newMockRenderJobStore(), webhook.NewVerifier([]string{""}, webhook.DefaultTolerance))
+	router := gin.New()
+	router.POST("/webhooks/render", handler.RenderCallback)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, signedCallback("", "nonce-1", time.Now(), `{"event":"job.queued","job_id":"render_1"}`))
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code, "Should refuse callbacks when no secret is configured")
+}
+
+func TestRenderHandler_GetRenderJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockRenderJobStore()
+	store.jobs["render_1"] = &render.Job{ID: "render_1", BookingID: "booking_123", Status: render.StatusRunning}
+
+	handler := NewRenderHandler(store, webhook.NewVerifier(nil, webhook.DefaultTolerance))
+	router := gin.New()
+	router.GET("/render-jobs", handler.ListRenderJobs)
+	router.GET("/render-jobs/:job_id", handler.GetRenderJob)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		description    string
+	}{
+		{"existing job", "/render-jobs/render_1", http.StatusOK, "Should return the job"},
+		{"unknown job", "/render-jobs/render_missing", http.StatusNotFound, "Should return 404 for unknown jobs"},
+		{"list by booking", "/render-jobs?booking_id=booking_123", http.StatusOK, "Should list a booking's jobs"},
+		{"list without booking", "/render-jobs", http.StatusBadRequest, "Should require booking_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
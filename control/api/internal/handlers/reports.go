@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// ReportStore is the subset of analytics operations used by ReportHandler
+type ReportStore interface {
+	EstimateExposureRows(q reporting.Query) (int64, error)
+	GetExposureReport(q reporting.Query) ([]map[string]interface{}, error)
+	GetDataQuality(f quality.Filter) (*quality.Report, error)
+}
+
+// ReportJobStore persists asynchronous report jobs
+type ReportJobStore interface {
+	CreateReportJob(job *reporting.Job) (string, error)
+	GetReportJob(jobID string) (*reporting.Job, error)
+	GetReportJobResult(jobID string, limit, offset int) ([]map[string]interface{}, error)
+}
+
+// ReportPrivacyStore keeps each organization's minimum report audience
+type ReportPrivacyStore interface {
+	GetReportPrivacy(orgID string) (*reporting.Privacy, error)
+	SetReportPrivacy(orgID string, privacy reporting.Privacy, updatedBy string) error
+	DeleteReportPrivacy(orgID string) error
+}
+
+// ReportHandler handles aggregated exposure reports
+type ReportHandler struct {
+	store          ReportStore
+	guard          *reporting.Guard
+	jobs           ReportJobStore
+	results        storage.Store
+	asyncGuard     *reporting.Guard
+	authz          *authz.Authorizer
+	privacy        ReportPrivacyStore
+	defaultPrivacy reporting.Privacy
+	reach          ReachStore
+	allowInsecure  bool
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(store ReportStore, guard *reporting.Guard) *ReportHandler {
+	return &ReportHandler{store: store, guard: guard}
+}
+
+// SetResultStorage reads async report results from object storage
+func (h *ReportHandler) SetResultStorage(store storage.Store) {
+	h.results = store
+}
+
+// EnableAsync allows heavy reports to be submitted as background jobs,
+// checked against the (typically looser) async guard
+func (h *ReportHandler) EnableAsync(jobs ReportJobStore, asyncGuard *reporting.Guard) {
+	h.jobs = jobs
+	h.asyncGuard = asyncGuard
+}
+
+// SetAllowInsecure accepts plain HTTP report webhook URLs, e.g. outside production
+func (h *ReportHandler) SetAllowInsecure(allow bool) {
+	h.allowInsecure = allow
+}
+
+// SetAuthorizer requires view access to the reported booking or campaign
+func (h *ReportHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// SetPrivacy enforces a minimum audience on grouped reports, per
+// organization from store and defaults for organizations without their own
+func (h *ReportHandler) SetPrivacy(store ReportPrivacyStore, defaults reporting.Privacy) {
+	h.privacy = store
+	h.defaultPrivacy = defaults
+}
+
+// privacyFor returns the caller's organization's minimum audience, or nil
+// when thresholds are not enforced
+func (h *ReportHandler) privacyFor(c *gin.Context) (*reporting.Privacy, bool, error) {
+	if h.privacy == nil {
+		return nil, false, nil
+	}
+	privacy, err := h.privacy.GetReportPrivacy(c.GetString("org_id"))
+	if err != nil || privacy != nil {
+		return privacy, false, err
+	}
+	defaults := h.defaultPrivacy
+	return &defaults, true, nil
+}
+
+// applyPrivacy sets the caller's minimum audience on a query and rejects
+// group-bys it rules out, writing the error response when it returns false
+func (h *ReportHandler) applyPrivacy(c *gin.Context, q *reporting.Query) bool {
+	privacy, _, err := h.privacyFor(c)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report privacy settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	q.Privacy = privacy
+	if err := q.CheckPrivacy(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// authorizeQuery checks the caller may view the report's booking or campaign
+// and scopes the query to the organizations whose events it may count
+func (h *ReportHandler) authorizeQuery(c *gin.Context, q *reporting.Query) bool {
+	resourceType, resourceID := labels.ResourceCampaign, q.CampaignID
+	if q.BookingID != "" {
+		resourceType, resourceID = labels.ResourceBooking, q.BookingID
+	}
+	if !h.authz.Authorize(c, resourceType, resourceID, authz.PermissionView) {
+		return false
+	}
+	q.Tenant = authz.Scope(c)
+	return true
+}
+
+// parseReportQuery reads report parameters from the query string.
+// The range defaults to the last 7 days at daily granularity.
+func parseReportQuery(c *gin.Context) (reporting.Query, error) {
+	now := time.Now().UTC()
+	q := reporting.Query{
+		BookingID:   c.Query("booking_id"),
+		CampaignID:  c.Query("campaign_id"),
+		From:        now.Add(-7 * 24 * time.Hour),
+		To:          now,
+		Granularity: c.DefaultQuery("granularity", reporting.GranularityDay),
+		GroupBy:     c.Query("group_by"),
+	}
+
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return q, errors.New("invalid from parameter, expected RFC3339")
+		}
+		q.From = parsed
+	}
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return q, errors.New("invalid to parameter, expected RFC3339")
+		}
+		q.To = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > 10000 {
+		limit = 1000
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	q.Limit = limit
+	q.Offset = offset
+
+	return q, q.Validate()
+}
+
+// respondLimitError writes a cost-limit rejection, pointing at async mode when available
+func (h *ReportHandler) respondLimitError(c *gin.Context, err error, estimate reporting.CostEstimate, async bool) {
+	var limitErr *reporting.LimitError
+	if !errors.As(err, &limitErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hint := limitErr.Hint
+	if !async && h.jobs != nil {
+		hint += ", or submit it as an async report (POST /api/v1/analytics/reports)"
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error": limitErr.Error(),
+		"hint":  hint,
+		"cost":  estimate,
+	})
+}
+
+// GetExposureReport handles GET /analytics/report
+func (h *ReportHandler) GetExposureReport(c *gin.Context) {
+	q, err := parseReportQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.authorizeQuery(c, &q) || !h.applyPrivacy(c, &q) {
+		return
+	}
+
+	estimatedRows, err := h.store.EstimateExposureRows(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to estimate report cost")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	q, estimate, err := h.guard.Check(q, estimatedRows)
+	if err != nil {
+		h.respondLimitError(c, err, estimate, false)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"booking_id":     q.BookingID,
+		"campaign_id":    q.CampaignID,
+		"granularity":    q.Granularity,
+		"group_by":       q.GroupBy,
+		"estimated_rows": estimate.EstimatedRows,
+	}).Info("Running exposure report")
+
+	rows, err := h.store.GetExposureReport(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to run exposure report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	hasMore := len(rows) == q.Limit // Before thresholding drops rows from the page
+	rows, privacy := reporting.ApplyPrivacy(q, rows)
+
+	report, err := h.store.GetDataQuality(quality.Filter{
+		Tenant:     q.Tenant,
+		BookingID:  q.BookingID,
+		CampaignID: q.CampaignID,
+		From:       q.From,
+		To:         q.To,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report data quality")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := gin.H{
+		"booking_id":   q.BookingID,
+		"campaign_id":  q.CampaignID,
+		"from":         q.From.UTC().Format(time.RFC3339),
+		"to":           q.To.UTC().Format(time.RFC3339),
+		"granularity":  q.Granularity,
+		"group_by":     q.GroupBy,
+		"rows":         rows,
+		"limit":        q.Limit,
+		"offset":       q.Offset,
+		"has_more":     hasMore,
+		"cost":         estimate,
+		"data_quality": report,
+	}
+	if privacy != nil {
+		response["privacy"] = privacy
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateReportJob handles POST /analytics/reports
+func (h *ReportHandler) CreateReportJob(c *gin.Context) {
+	if h.jobs == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Async reports are not enabled"})
+		return
+	}
+
+	var req struct {
+		BookingID   string    `json:"booking_id"`
+		CampaignID  string    `json:"campaign_id"`
+		From        time.Time `json:"from" binding:"required"`
+		To          time.Time `json:"to" binding:"required"`
+		Granularity string    `json:"granularity"`
+		GroupBy     string    `json:"group_by"`
+		WebhookURL  string    `json:"webhook_url"`
+	}
+
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.WebhookURL != "" {
+		// The callback links to the report, so it follows the https rule of webhook subscriptions
+		if err := webhook.ValidateURL(req.WebhookURL, h.allowInsecure); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "webhook_" + err.Error()})
+			return
+		}
+	}
+
+	q := reporting.Query{
+		BookingID:   req.BookingID,
+		CampaignID:  req.CampaignID,
+		From:        req.From,
+		To:          req.To,
+		Granularity: req.Granularity,
+		GroupBy:     req.GroupBy,
+	}
+	if q.Granularity == "" {
+		q.Granularity = reporting.GranularityDay
+	}
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.authorizeQuery(c, &q) || !h.applyPrivacy(c, &q) {
+		return
+	}
+
+	estimatedRows, err := h.store.EstimateExposureRows(q)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to estimate report cost")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	q, estimate, err := h.asyncGuard.Check(q, estimatedRows)
+	if err != nil {
+		h.respondLimitError(c, err, estimate, true)
+		return
+	}
+
+	job := &reporting.Job{
+		Query:       q,
+		WebhookURL:  req.WebhookURL,
+		RequestedBy: c.GetString("user_id"),
+	}
+	if job.WebhookURL != "" {
+		if job.WebhookSecret, err = webhook.GenerateSecret(); err != nil {
+			logrus.WithError(err).Error("Failed to generate report webhook secret")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+
+	jobID, err := h.jobs.CreateReportJob(job)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create report job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report job"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"job_id":         jobID,
+		"booking_id":     q.BookingID,
+		"campaign_id":    q.CampaignID,
+		"estimated_rows": estimate.EstimatedRows,
+	}).Info("Created async report job")
+
+	response := gin.H{
+		"job_id":     jobID,
+		"status":     reporting.JobPending,
+		"status_url": "/api/v1/analytics/reports/" + jobID,
+		"cost":       estimate,
+	}
+	if job.WebhookSecret != "" {
+		// Shown once, like a subscription secret; it verifies the callback's signature
+		response["webhook_secret"] = job.WebhookSecret
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// loadReportJob fetches a job and checks it belongs to the caller
+func (h *ReportHandler) loadReportJob(c *gin.Context) (*reporting.Job, bool) {
+	if h.jobs == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Async reports are not enabled"})
+		return nil, false
+	}
+
+	job, err := h.jobs.GetReportJob(c.Param("job_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+
+	if job == nil || (job.RequestedBy != "" && job.RequestedBy != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report job not found"})
+		return nil, false
+	}
+
+	return job, true
+}
+
+// GetReportJob handles GET /analytics/reports/:job_id
+func (h *ReportHandler) GetReportJob(c *gin.Context) {
+	job, ok := h.loadReportJob(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"query":      job.Query,
+		"row_count":  job.RowCount,
+		"created_at": job.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if job.CompletedAt != nil {
+		response["completed_at"] = job.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	if job.Status == reporting.JobCompleted {
+		response["download_url"] = reporting.DownloadPath(job.ID)
+	}
+	if job.Error != "" {
+		response["error"] = job.Error
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readResult reads a page of a job's result rows from object storage, or
+// from Postgres for jobs completed before results moved there
+func (h *ReportHandler) readResult(c *gin.Context, job *reporting.Job, limit, offset int) ([]map[string]interface{}, error) {
+	if job.ResultKey == "" || h.results == nil {
+		return h.jobs.GetReportJobResult(job.ID, limit, offset)
+	}
+	result, err := h.results.Get(c.Request.Context(), job.ResultKey)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	return reporting.ReadResultPage(result, limit, offset)
+}
+
+// DownloadReportJob handles GET /analytics/reports/:job_id/download
+func (h *ReportHandler) DownloadReportJob(c *gin.Context) {
+	job, ok := h.loadReportJob(c)
+	if !ok {
+		return
+	}
+
+	if job.Status != reporting.JobCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Report is not ready",
+			"status": job.Status,
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > 10000 {
+		limit = 1000
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	rows, err := h.readResult(c, job, limit, offset)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report result")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":      job.ID,
+		"rows":        rows,
+		"total_count": job.RowCount,
+		"limit":       limit,
+		"offset":      offset,
+		"has_more":    offset+len(rows) < job.RowCount,
+	})
+}
+
+// privacyResponse describes an organization's minimum report audience
+func privacyResponse(orgID string, privacy *reporting.Privacy, isDefault bool) gin.H {
+	return gin.H{
+		"org_id":       orgID,
+		"min_audience": privacy.MinAudience,
+		"mode":         privacy.Mode,
+		"default":      isDefault,
+	}
+}
+
+// GetPrivacySettings handles GET /analytics/privacy
+func (h *ReportHandler) GetPrivacySettings(c *gin.Context) {
+	if h.privacy == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Report privacy thresholds are not enabled"})
+		return
+	}
+
+	privacy, isDefault, err := h.privacyFor(c)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get report privacy settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, privacyResponse(c.GetString("org_id"), privacy, isDefault))
+}
+
+// UpdatePrivacySettings handles PUT /analytics/privacy
+func (h *ReportHandler) UpdatePrivacySettings(c *gin.Context) {
+	if h.privacy == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Report privacy thresholds are not enabled"})
+		return
+	}
+
+	var req struct {
+		MinAudience *int   `json:"min_audience" binding:"required"`
+		Mode        string `json:"mode"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	privacy := reporting.Privacy{MinAudience: *req.MinAudience, Mode: req.Mode}
+	if privacy.Mode == "" {
+		privacy.Mode = h.defaultPrivacy.Mode
+	}
+	if err := privacy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orgID := c.GetString("org_id")
+	if err := h.privacy.SetReportPrivacy(orgID, privacy, c.GetString("user_id")); err != nil {
+		logrus.WithError(err).Error("Failed to set report privacy settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":        "report_privacy",
+		"user_id":      c.GetString("user_id"),
+		"org_id":       orgID,
+		"min_audience": privacy.MinAudience,
+		"mode":         privacy.Mode,
+	}).Info("Updated report privacy threshold")
+
+	c.JSON(http.StatusOK, privacyResponse(orgID, &privacy, false))
+}
+
+// ResetPrivacySettings handles DELETE /analytics/privacy, returning the
+// organization to the default threshold
+func (h *ReportHandler) ResetPrivacySettings(c *gin.Context) {
+	if h.privacy == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Report privacy thresholds are not enabled"})
+		return
+	}
+
+	orgID := c.GetString("org_id")
+	if err := h.privacy.DeleteReportPrivacy(orgID); err != nil {
+		logrus.WithError(err).Error("Failed to reset report privacy settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "report_privacy",
+		"user_id": c.GetString("user_id"),
+		"org_id":  orgID,
+	}).Info("Reset report privacy threshold to the default")
+
+	defaults := h.defaultPrivacy
+	c.JSON(http.StatusOK, privacyResponse(orgID, &defaults, true))
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/schemadrift"
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaDriftHandler compares the typed models with the database on demand,
+// as the gateway does on startup
+type SchemaDriftHandler struct {
+	db schemadrift.ColumnStore
+}
+
+// NewSchemaDriftHandler creates a schema drift handler
+func NewSchemaDriftHandler(store schemadrift.ColumnStore) *SchemaDriftHandler {
+	return &SchemaDriftHandler{db: store}
+}
+
+// GetDrift handles GET /admin/schema-drift, listing the columns the typed
+// models read that the database is missing or has with another type
+func (h *SchemaDriftHandler) GetDrift(c *gin.Context) {
+	report, err := schemadrift.Check(h.db)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check schema drift")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
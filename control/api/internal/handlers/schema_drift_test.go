@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/schemadrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockColumnStore struct {
+	types map[string]map[string]string
+	err   error
+}
+
+func (m *MockColumnStore) ListColumnTypes(tables []string) (map[string]map[string]string, error) {
+	return m.types, m.err
+}
+
+func TestSchemaDriftHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Every expected column with the type its model reads, but for one missing column
+	types := map[string]map[string]string{}
+	for _, c := range schemadrift.Expected(schemadrift.Models) {
+		if types[c.Table] == nil {
+			types[c.Table] = map[string]string{}
+		}
+		if c.Table == "placement_bookings" && c.Name == "pacing" {
+			continue
+		}
+		types[c.Table][c.Name] = map[string]string{
+			"text": "text", "integer": "bigint", "float": "real", "boolean": "boolean", "timestamp": "timestamp without time zone", "json": "jsonb",
+		}[c.Families[0]]
+	}
+	store := &MockColumnStore{types: types}
+	router := gin.New()
+	router.GET("/admin/schema-drift", NewSchemaDriftHandler(store).GetDrift)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/schema-drift", nil))
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var report schemadrift.Report
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.False(t, report.OK)
+	assert.Equal(t, 1, report.Critical)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, schemadrift.MissingColumn, report.Findings[0].Kind)
+	assert.Equal(t, "pacing", report.Findings[0].Column)
+
+	store.types["placement_bookings"]["pacing"] = "character varying"
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/schema-drift", nil))
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.True(t, report.OK)
+	assert.Empty(t, report.Findings)
+
+	store.err = errors.New("connection refused")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/schema-drift", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/series"
+	"github.com/sirupsen/logrus"
+)
+
+// SeriesStore persists series, their episodes and series-level bookings
+type SeriesStore interface {
+	SetSeries(s *series.Series) error
+	GetSeries(seriesID string) (*series.Series, error)
+	SetEpisode(e *series.Episode) error
+	CreateSeriesBooking(b *series.Booking) error
+	GetSeriesBooking(seriesBookingID string) (*series.Booking, error)
+	GetSeriesBookingDelivery(seriesBookingID string) (*series.Delivery, error)
+}
+
+// SeriesHandler manages the series, season and episode hierarchy of titles
+// and bookings that span it
+type SeriesHandler struct {
+	db    SeriesStore
+	authz *authz.Authorizer
+}
+
+// NewSeriesHandler creates a series handler
+func NewSeriesHandler(store SeriesStore) *SeriesHandler {
+	return &SeriesHandler{db: store}
+}
+
+// SetAuthorizer enforces organization ownership and grants on the booked campaigns
+func (h *SeriesHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// seriesRequest is the body of PUT /series/:series_id
+type seriesRequest struct {
+	Name string `json:"name"`
+}
+
+// episodeRequest is the body of PUT /series/:series_id/episodes/:title_id
+type episodeRequest struct {
+	SeasonNumber  int `json:"season_number"`
+	EpisodeNumber int `json:"episode_number"`
+}
+
+// seriesBookingRequest is the body of POST /series/:series_id/bookings
+type seriesBookingRequest struct {
+	SeasonNumber   *int    `json:"season_number"`
+	SurfaceType    string  `json:"surface_type"`
+	MinPRSScore    float64 `json:"min_prs_score"`
+	AdvertiserID   string  `json:"advertiser_id"`
+	CampaignID     string  `json:"campaign_id"`
+	BidAmountCPM   float64 `json:"bid_amount_cpm"`
+	MaxImpressions int     `json:"max_impressions"`
+}
+
+// SetSeries handles PUT /series/:series_id
+func (h *SeriesHandler) SetSeries(c *gin.Context) {
+	var req seriesRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s := &series.Series{SeriesID: c.Param("series_id"), Name: req.Name}
+	if err := s.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.SetSeries(s); err != nil {
+		logrus.WithError(err).Error("Failed to save series")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	h.respondSeries(c, s.SeriesID)
+}
+
+// GetSeries handles GET /series/:series_id
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	h.respondSeries(c, c.Param("series_id"))
+}
+
+// SetEpisode handles PUT /series/:series_id/episodes/:title_id
+func (h *SeriesHandler) SetEpisode(c *gin.Context) {
+	var req episodeRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	episode := &series.Episode{
+		TitleID:       c.Param("title_id"),
+		SeriesID:      c.Param("series_id"),
+		SeasonNumber:  req.SeasonNumber,
+		EpisodeNumber: req.EpisodeNumber,
+	}
+	if err := episode.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.SetEpisode(episode)
+	switch {
+	case errors.Is(err, series.ErrSeriesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	case errors.Is(err, db.ErrTitleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	case errors.Is(err, series.ErrEpisodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to save episode")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, episode)
+}
+
+// BookSeries handles POST /series/:series_id/bookings. It books the surface
+// type in every matching episode and returns the placement bookings made.
+func (h *SeriesHandler) BookSeries(c *gin.Context) {
+	var req seriesBookingRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	b := &series.Booking{
+		SeriesID:       c.Param("series_id"),
+		SeasonNumber:   req.SeasonNumber,
+		SurfaceType:    req.SurfaceType,
+		MinPRSScore:    req.MinPRSScore,
+		AdvertiserID:   req.AdvertiserID,
+		CampaignID:     req.CampaignID,
+		BidAmountCPM:   req.BidAmountCPM,
+		MaxImpressions: req.MaxImpressions,
+		CreatedBy:      c.GetString("user_id"),
+		OrgID:          c.GetString("org_id"),
+	}
+	if err := b.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.authz.Authorize(c, labels.ResourceCampaign, b.CampaignID, authz.PermissionManage) {
+		return
+	}
+
+	err := h.db.CreateSeriesBooking(b)
+	switch {
+	case errors.Is(err, series.ErrSeriesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	case errors.Is(err, series.ErrNoSurfaces):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "skipped": b.Skipped})
+		return
+	case errors.Is(err, campaign.ErrNotBookable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to create series booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":             "series_booking",
+		"series_booking_id": b.SeriesBookingID,
+		"series_id":         b.SeriesID,
+		"campaign_id":       b.CampaignID,
+		"bookings":          len(b.Bookings),
+		"skipped":           len(b.Skipped),
+		"user_id":           b.CreatedBy,
+		"org_id":            b.OrgID,
+	}).Info("Booked series")
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// GetSeriesBooking handles GET /series/:series_id/bookings/:series_booking_id
+func (h *SeriesHandler) GetSeriesBooking(c *gin.Context) {
+	b, ok := h.loadBooking(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// GetDelivery handles GET /series/:series_id/bookings/:series_booking_id/delivery
+func (h *SeriesHandler) GetDelivery(c *gin.Context) {
+	b, ok := h.loadBooking(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.db.GetSeriesBookingDelivery(b.SeriesBookingID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get series booking delivery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// loadBooking fetches the series booking named in the path and checks the
+// caller may view its campaign. It writes the response when it returns false.
+func (h *SeriesHandler) loadBooking(c *gin.Context) (*series.Booking, bool) {
+	b, err := h.db.GetSeriesBooking(c.Param("series_booking_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get series booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if b == nil || b.SeriesID != c.Param("series_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series booking not found"})
+		return nil, false
+	}
+	if !h.authz.Authorize(c, labels.ResourceCampaign, b.CampaignID, authz.PermissionView) {
+		return nil, false
+	}
+	return b, true
+}
+
+// respondSeries writes a series with its episodes
+func (h *SeriesHandler) respondSeries(c *gin.Context, seriesID string) {
+	s, err := h.db.GetSeries(seriesID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get series")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if s == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/series"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seriesSurface is a surface in the mock store's catalog
+type seriesSurface struct {
+	surfaceID   string
+	titleID     string
+	surfaceType string
+	prsScore    float64
+	heldBack    bool
+}
+
+// MockSeriesStore fans series bookings out over an in-memory catalog
+type MockSeriesStore struct {
+	series      map[string]*series.Series
+	surfaces    []seriesSurface
+	bookings    map[string]*series.Booking
+	impressions map[string]int64 // booking ID -> impressions
+	shouldError bool
+}
+
+func (m *MockSeriesStore) SetSeries(s *series.Series) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if existing, ok := m.series[s.SeriesID]; ok {
+		existing.Name = s.Name
+		return nil
+	}
+	m.series[s.SeriesID] = &series.Series{SeriesID: s.SeriesID, Name: s.Name, Episodes: []series.Episode{}}
+	return nil
+}
+
+func (m *MockSeriesStore) GetSeries(seriesID string) (*series.Series, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.series[seriesID], nil
+}
+
+func (m *MockSeriesStore) SetEpisode(e *series.Episode) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	s, ok := m.series[e.SeriesID]
+	if !ok {
+		return series.ErrSeriesNotFound
+	}
+	if e.TitleID == "99" {
+		return db.ErrTitleNotFound
+	}
+	for _, other := range s.Episodes {
+		if other.TitleID != e.TitleID && other.SeasonNumber == e.SeasonNumber && other.EpisodeNumber == e.EpisodeNumber {
+			return series.ErrEpisodeTaken
+		}
+	}
+	s.Episodes = append(s.Episodes, *e)
+	return nil
+}
+
+func (m *MockSeriesStore) CreateSeriesBooking(b *series.Booking) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	s, ok := m.series[b.SeriesID]
+	if !ok {
+		return series.ErrSeriesNotFound
+	}
+	b.SeriesBookingID = "series_booking_1"
+	b.Bookings = []series.Item{}
+	b.Skipped = []series.Skip{}
+	for _, episode := range s.Episodes {
+		if b.SeasonNumber != nil && episode.SeasonNumber != *b.SeasonNumber {
+			continue
+		}
+		for _, surface := range m.surfaces {
+			if surface.titleID != episode.TitleID || surface.surfaceType != b.SurfaceType || surface.prsScore < b.MinPRSScore {
+				continue
+			}
+			if surface.heldBack {
+				b.Skipped = append(b.Skipped, series.Skip{SurfaceID: surface.surfaceID, TitleID: surface.titleID, Reason: series.SkipHeldBack})
+				continue
+			}
+			b.Bookings = append(b.Bookings, series.Item{
+				BookingID:     "booking_" + surface.surfaceID,
+				SurfaceID:     surface.surfaceID,
+				TitleID:       episode.TitleID,
+				SeasonNumber:  episode.SeasonNumber,
+				EpisodeNumber: episode.EpisodeNumber,
+			})
+		}
+	}
+	if len(b.Bookings) == 0 {
+		return series.ErrNoSurfaces
+	}
+	m.bookings[b.SeriesBookingID] = b
+	return nil
+}
+
+func (m *MockSeriesStore) GetSeriesBooking(seriesBookingID string) (*series.Booking, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.bookings[seriesBookingID], nil
+}
+
+func (m *MockSeriesStore) GetSeriesBookingDelivery(seriesBookingID string) (*series.Delivery, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	var episodes []series.EpisodeDelivery
+	for _, item := range m.bookings[seriesBookingID].Bookings {
+		n := len(episodes)
+		if n == 0 || episodes[n-1].TitleID != item.TitleID {
+			episodes = append(episodes, series.EpisodeDelivery{TitleID: item.TitleID, SeasonNumber: item.SeasonNumber, EpisodeNumber: item.EpisodeNumber})
+			n++
+		}
+		episodes[n-1].Bookings++
+		episodes[n-1].Impressions += m.impressions[item.BookingID]
+	}
+	return series.Summarize(seriesBookingID, episodes), nil
+}
+
+// newMockSeriesStore has a series with two episodes in season 1 and one in season 2
+func newMockSeriesStore() *MockSeriesStore {
+	return &MockSeriesStore{
+		series: map[string]*series.Series{
+			"show_1": {SeriesID: "show_1", Name: "The Show", Episodes: []series.Episode{
+				{TitleID: "1", SeriesID: "show_1", SeasonNumber: 1, EpisodeNumber: 1},
+				{TitleID: "2", SeriesID: "show_1", SeasonNumber: 1, EpisodeNumber: 2},
+				{TitleID: "3", SeriesID: "show_1", SeasonNumber: 2, EpisodeNumber: 1},
+			}},
+		},
+		surfaces: []seriesSurface{
+			{surfaceID: "surface_101", titleID: "1", surfaceType: "billboard", prsScore: 85},
+			{surfaceID: "surface_102", titleID: "1", surfaceType: "table", prsScore: 90},
+			{surfaceID: "surface_201", titleID: "2", surfaceType: "billboard", prsScore: 80},
+			{surfaceID: "surface_202", titleID: "2", surfaceType: "billboard", prsScore: 60},
+			{surfaceID: "surface_301", titleID: "3", surfaceType: "billboard", prsScore: 88, heldBack: true},
+			{surfaceID: "surface_302", titleID: "3", surfaceType: "billboard", prsScore: 92},
+		},
+		bookings:    map[string]*series.Booking{},
+		impressions: map[string]int64{"booking_surface_101": 1200, "booking_surface_201": 800, "booking_surface_302": 500},
+	}
+}
+
+func TestSeriesHandler_SetEpisode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		seriesID       string
+		titleID        string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "new episode",
+			seriesID:       "show_1",
+			titleID:        "4",
+			body:           `{"season_number": 2, "episode_number": 2}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should add the title to the series",
+		},
+		{
+			name:           "slot taken",
+			seriesID:       "show_1",
+			titleID:        "4",
+			body:           `{"season_number": 1, "episode_number": 2}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should reject a season and episode another title has",
+		},
+		{
+			name:           "episode zero",
+			seriesID:       "show_1",
+			titleID:        "4",
+			body:           `{"season_number": 1, "episode_number": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require episode numbers from 1",
+		},
+		{
+			name:           "unknown series",
+			seriesID:       "show_2",
+			titleID:        "4",
+			body:           `{"season_number": 1, "episode_number": 1}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for an unknown series",
+		},
+		{
+			name:           "unknown title",
+			seriesID:       "show_1",
+			titleID:        "99",
+			body:           `{"season_number": 3, "episode_number": 1}`,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for an unknown title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.PUT("/series/:series_id/episodes/:title_id", NewSeriesHandler(newMockSeriesStore()).SetEpisode)
+
+			req := httptest.NewRequest(http.MethodPut, "/series/"+tt.seriesID+"/episodes/"+tt.titleID, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+}
+
+func TestSeriesHandler_BookSeries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		orgID            string
+		body             string
+		expectedStatus   int
+		expectedBookings []string
+		expectedSkipped  []string
+		description      string
+	}{
+		{
+			name:             "whole series",
+			body:             `{"surface_type": "billboard", "min_prs_score": 70, "advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus:   http.StatusCreated,
+			expectedBookings: []string{"surface_101", "surface_201", "surface_302"},
+			expectedSkipped:  []string{"surface_301"},
+			description:      "Should book matching surfaces in every episode and skip held back ones",
+		},
+		{
+			name:             "one season",
+			body:             `{"season_number": 1, "surface_type": "billboard", "min_prs_score": 70, "advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus:   http.StatusCreated,
+			expectedBookings: []string{"surface_101", "surface_201"},
+			expectedSkipped:  []string{},
+			description:      "Should only book episodes of the season",
+		},
+		{
+			name:           "nothing matches",
+			body:           `{"surface_type": "ceiling", "advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus: http.StatusConflict,
+			description:    "Should not create an empty series booking",
+		},
+		{
+			name:           "no surface type",
+			body:           `{"advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a surface type",
+		},
+		{
+			name:           "season zero",
+			body:           `{"season_number": 0, "surface_type": "billboard", "advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require season numbers from 1",
+		},
+		{
+			name:           "campaign of another org",
+			orgID:          "org_other",
+			body:           `{"surface_type": "billboard", "advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5}`,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should require managing the campaign",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := tt.orgID
+			if orgID == "" {
+				orgID = "org_brand"
+			}
+			handler := NewSeriesHandler(newMockSeriesStore())
+			handler.SetAuthorizer(authz.NewAuthorizer(&MockGrantStore{owners: map[string]string{"campaign/campaign_1": "org_brand"}}))
+			router := gin.New()
+			router.Use(withOrg("user_1", orgID))
+			router.POST("/series/:series_id/bookings", handler.BookSeries)
+
+			req := httptest.NewRequest(http.MethodPost, "/series/show_1/bookings", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var b series.Booking
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &b))
+			booked := make([]string, 0, len(b.Bookings))
+			for _, item := range b.Bookings {
+				booked = append(booked, item.SurfaceID)
+			}
+			skipped := make([]string, 0, len(b.Skipped))
+			for _, skip := range b.Skipped {
+				skipped = append(skipped, skip.SurfaceID)
+			}
+			assert.Equal(t, tt.expectedBookings, booked, tt.description)
+			assert.Equal(t, tt.expectedSkipped, skipped, tt.description)
+			assert.Equal(t, "user_1", b.CreatedBy)
+		})
+	}
+}
+
+func TestSeriesHandler_GetDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockSeriesStore()
+	b := &series.Booking{SeriesID: "show_1", SurfaceType: "billboard", MinPRSScore: 70, AdvertiserID: "adv_1", CampaignID: "campaign_1", BidAmountCPM: 12.5}
+	require.NoError(t, store.CreateSeriesBooking(b))
+
+	router := gin.New()
+	router.GET("/series/:series_id/bookings/:series_booking_id/delivery", NewSeriesHandler(store).GetDelivery)
+
+	req := httptest.NewRequest(http.MethodGet, "/series/show_1/bookings/series_booking_1/delivery", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var delivery series.Delivery
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &delivery))
+	require.Len(t, delivery.Episodes, 3)
+	assert.Equal(t, int64(1200), delivery.Episodes[0].Impressions)
+	assert.Equal(t, 2, delivery.Episodes[2].SeasonNumber)
+	assert.Equal(t, series.Totals{Bookings: 3, Impressions: 2500}, delivery.Totals)
+
+	// The booking belongs to another series
+	req = httptest.NewRequest(http.MethodGet, "/series/show_2/bookings/series_booking_1/delivery", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
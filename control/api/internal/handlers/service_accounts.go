@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/serviceaccount"
+	"github.com/sirupsen/logrus"
+)
+
+// ServiceAccountStore persists service accounts and their keys
+type ServiceAccountStore interface {
+	CreateServiceAccount(account *serviceaccount.Account, key *serviceaccount.Key) (string, error)
+	GetServiceAccount(accountID string) (*serviceaccount.Account, error)
+	ListServiceAccounts(orgID string) ([]*serviceaccount.Account, error)
+	DisableServiceAccount(accountID string) error
+	CreateServiceAccountKey(accountID string, key *serviceaccount.Key) error
+	ListServiceAccountKeys(accountID string) ([]*serviceaccount.Key, error)
+	RotateServiceAccountKey(accountID, keyID string, key *serviceaccount.Key, expiresAt time.Time) (bool, error)
+	RevokeServiceAccountKey(accountID, keyID string) (bool, error)
+	ListPIIViolations(principalID string, since time.Time) ([]pii.DailyCount, error)
+}
+
+// ServiceAccountHandler manages service accounts for the caller's organization
+type ServiceAccountHandler struct {
+	db       ServiceAccountStore
+	rotation serviceaccount.Rotation
+}
+
+// NewServiceAccountHandler creates a new service account handler
+func NewServiceAccountHandler(store ServiceAccountStore) *ServiceAccountHandler {
+	return &ServiceAccountHandler{db: store, rotation: serviceaccount.DefaultRotation()}
+}
+
+// SetRotation sets the default overlap of rotated keys with their replacement
+func (h *ServiceAccountHandler) SetRotation(rotation serviceaccount.Rotation) {
+	h.rotation = rotation
+}
+
+// newKey generates a key accepting request bodies in the given case
+// convention, returning it with the token shown to the caller once
+func newKey(jsonCase string) (*serviceaccount.Key, string, error) {
+	keyID, token, secretHash, err := serviceaccount.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if jsonCase == "" {
+		jsonCase = schema.CaseSnake
+	}
+	return &serviceaccount.Key{ID: keyID, SecretHash: secretHash, JSONCase: jsonCase}, token, nil
+}
+
+// validateJSONCase checks a requested key case convention
+func validateJSONCase(jsonCase string) error {
+	if !schema.ValidCase(jsonCase) {
+		return fmt.Errorf("unknown json_case %q, expected %s or %s", jsonCase, schema.CaseSnake, schema.CaseAny)
+	}
+	return nil
+}
+
+// validateScopes checks that every requested scope can be granted
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !serviceaccount.ValidScope(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+// loadAccount fetches the account named in the path and checks it belongs to the caller's organization
+func (h *ServiceAccountHandler) loadAccount(c *gin.Context) (*serviceaccount.Account, bool) {
+	account, err := h.db.GetServiceAccount(c.Param("account_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get service account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+
+	if account == nil || account.OrgID != c.GetString("org_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return nil, false
+	}
+	return account, true
+}
+
+// CreateServiceAccount handles POST /service-accounts
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req struct {
+		Name     string   `json:"name" binding:"required"`
+		Scopes   []string `json:"scopes" binding:"required,min=1"`
+		JSONCase string   `json:"json_case"` // For the first key
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateScopes(req.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateJSONCase(req.JSONCase); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, token, err := newKey(req.JSONCase)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	account := &serviceaccount.Account{
+		Name:      req.Name,
+		OrgID:     c.GetString("org_id"),
+		Scopes:    req.Scopes,
+		CreatedBy: c.GetString("user_id"),
+	}
+
+	accountID, err := h.db.CreateServiceAccount(account, key)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create service account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+	account.ID = accountID
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "service_account",
+		"account_id": accountID,
+		"key_id":     key.ID,
+		"user_id":    account.CreatedBy,
+		"org_id":     account.OrgID,
+		"scopes":     account.Scopes,
+		"json_case":  key.JSONCase,
+	}).Info("Created service account")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"account":   account,
+		"key_id":    key.ID,
+		"json_case": key.JSONCase,
+		"token":     token,
+		"message":   "Store this token now; it cannot be retrieved again",
+	})
+}
+
+// ListServiceAccounts handles GET /service-accounts
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	accounts, err := h.db.ListServiceAccounts(c.GetString("org_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list service accounts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_accounts": accounts,
+		"total_count":      len(accounts),
+	})
+}
+
+// DisableServiceAccount handles DELETE /service-accounts/:account_id
+func (h *ServiceAccountHandler) DisableServiceAccount(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DisableServiceAccount(account.ID); err != nil {
+		logrus.WithError(err).Error("Failed to disable service account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "service_account",
+		"account_id": account.ID,
+		"user_id":    c.GetString("user_id"),
+	}).Info("Disabled service account")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"account_id": account.ID,
+	})
+}
+
+// ListKeys handles GET /service-accounts/:account_id/keys
+func (h *ServiceAccountHandler) ListKeys(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.db.ListServiceAccountKeys(account.ID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list service account keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account_id": account.ID,
+		"keys":       keys,
+	})
+}
+
+// IssueKey handles POST /service-accounts/:account_id/keys. The new key is
+// active immediately and other keys stay valid until revoked; see RotateKey
+// to have the old one expire instead. The optional body sets the key's
+// json_case; changing it means issuing a new key.
+func (h *ServiceAccountHandler) IssueKey(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		JSONCase string `json:"json_case"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateJSONCase(req.JSONCase); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, token, err := newKey(req.JSONCase)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if err := h.db.CreateServiceAccountKey(account.ID, key); err != nil {
+		logrus.WithError(err).Error("Failed to create service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "service_account",
+		"account_id": account.ID,
+		"key_id":     key.ID,
+		"json_case":  key.JSONCase,
+		"user_id":    c.GetString("user_id"),
+	}).Info("Issued service account key")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"account_id": account.ID,
+		"key_id":     key.ID,
+		"version":    key.Version,
+		"json_case":  key.JSONCase,
+		"token":      token,
+		"message":    "Store this token now; it cannot be retrieved again",
+	})
+}
+
+// RotateKey handles POST /service-accounts/:account_id/keys/:key_id/rotate,
+// replacing a key without downtime: the new key is active immediately and
+// the old one stays valid for the overlap (e.g. {"overlap": "72h"}, by
+// default the configured one), after which it expires. Webhook
+// subscriptions to api_key.expiring hear about it before then. The new key
+// accepts the old one's json_case unless the body sets another.
+func (h *ServiceAccountHandler) RotateKey(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Overlap  string `json:"overlap"`
+		JSONCase string `json:"json_case"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	overlap := h.rotation.Overlap
+	if req.Overlap != "" {
+		parsed, err := time.ParseDuration(req.Overlap)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "overlap must be a duration such as 72h"})
+			return
+		}
+		overlap = parsed
+	}
+	if err := serviceaccount.ValidateOverlap(overlap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateJSONCase(req.JSONCase); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	keys, err := h.db.ListServiceAccountKeys(account.ID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list service account keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	now := time.Now().UTC()
+	var old *serviceaccount.Key
+	for _, key := range keys {
+		if key.ID == c.Param("key_id") && key.Active(now) {
+			old = key
+		}
+	}
+	if old == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	if old.ReplacedBy != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Key has already been rotated", "replaced_by": old.ReplacedBy})
+		return
+	}
+
+	jsonCase := req.JSONCase
+	if jsonCase == "" {
+		jsonCase = old.JSONCase
+	}
+	key, token, err := newKey(jsonCase)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	expiresAt := now.Add(overlap)
+	rotated, err := h.db.RotateServiceAccountKey(account.ID, old.ID, key, expiresAt)
+	if errors.Is(err, serviceaccount.ErrAlreadyRotated) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Key has already been rotated"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to rotate service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !rotated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":       "service_account",
+		"account_id":  account.ID,
+		"key_id":      key.ID,
+		"version":     key.Version,
+		"rotated_key": old.ID,
+		"expires_at":  expiresAt,
+		"json_case":   key.JSONCase,
+		"user_id":     c.GetString("user_id"),
+	}).Info("Rotated service account key")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"account_id": account.ID,
+		"key_id":     key.ID,
+		"version":    key.Version,
+		"json_case":  key.JSONCase,
+		"token":      token,
+		"rotated": gin.H{
+			"key_id":     old.ID,
+			"version":    old.Version,
+			"expires_at": expiresAt,
+		},
+		"message": "Store this token now; it cannot be retrieved again. The old key stays valid until it expires.",
+	})
+}
+
+// RevokeKey handles DELETE /service-accounts/:account_id/keys/:key_id
+func (h *ServiceAccountHandler) RevokeKey(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+	keyID := c.Param("key_id")
+
+	revoked, err := h.db.RevokeServiceAccountKey(account.ID, keyID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to revoke service account key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "service_account",
+		"account_id": account.ID,
+		"key_id":     keyID,
+		"user_id":    c.GetString("user_id"),
+	}).Info("Revoked service account key")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"key_id":  keyID,
+	})
+}
+
+// ListPIIViolations handles GET /service-accounts/:account_id/pii-violations,
+// the personal data each of the account's keys sent per day (?days=30, at most 90)
+func (h *ServiceAccountHandler) ListPIIViolations(c *gin.Context) {
+	account, ok := h.loadAccount(c)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+		return
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	counts, err := h.db.ListPIIViolations(account.PrincipalID(), since)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list PII violations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	total := int64(0)
+	for _, count := range counts {
+		total += count.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account_id":  account.ID,
+		"since":       since.Format("2006-01-02"),
+		"violations":  counts,
+		"total_count": total,
+	})
+}
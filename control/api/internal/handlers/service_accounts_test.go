@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/middleware"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/placement"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/serviceaccount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockServiceAccountStore backs both ServiceAccountHandler and the auth middleware
+type MockServiceAccountStore struct {
+	accounts    map[string]*serviceaccount.Account
+	keys        map[string]*serviceaccount.Key
+	violations  map[string][]pii.DailyCount // By principal ID
+	shouldError bool
+}
+
+func newMockServiceAccountStore() *MockServiceAccountStore {
+	return &MockServiceAccountStore{
+		accounts:   map[string]*serviceaccount.Account{},
+		keys:       map[string]*serviceaccount.Key{},
+		violations: map[string][]pii.DailyCount{},
+	}
+}
+
+func (m *MockServiceAccountStore) CreateServiceAccount(account *serviceaccount.Account, key *serviceaccount.Key) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	account.ID = "sa_123"
+	m.accounts[account.ID] = account
+	key.AccountID = account.ID
+	m.keys[key.ID] = key
+	return account.ID, nil
+}
+
+func (m *MockServiceAccountStore) GetServiceAccount(accountID string) (*serviceaccount.Account, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.accounts[accountID], nil
+}
+
+func (m *MockServiceAccountStore) ListServiceAccounts(orgID string) ([]*serviceaccount.Account, error) {
+	accounts := make([]*serviceaccount.Account, 0)
+	for _, account := range m.accounts {
+		if account.OrgID == orgID {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *MockServiceAccountStore) DisableServiceAccount(accountID string) error {
+	now := time.Now()
+	m.accounts[accountID].DisabledAt = &now
+	return nil
+}
+
+func (m *MockServiceAccountStore) CreateServiceAccountKey(accountID string, key *serviceaccount.Key) error {
+	key.AccountID = accountID
+	for _, other := range m.keys {
+		if other.AccountID == accountID && other.Version >= key.Version {
+			key.Version = other.Version + 1
+		}
+	}
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *MockServiceAccountStore) RotateServiceAccountKey(accountID, keyID string, key *serviceaccount.Key, expiresAt time.Time) (bool, error) {
+	if m.shouldError {
+		return false, assert.AnError
+	}
+	old, ok := m.keys[keyID]
+	if !ok || old.AccountID != accountID || !old.Active(time.Now()) {
+		return false, nil
+	}
+	if old.ReplacedBy != "" {
+		return false, serviceaccount.ErrAlreadyRotated
+	}
+	if err := m.CreateServiceAccountKey(accountID, key); err != nil {
+		return false, err
+	}
+	old.ReplacedBy, old.ExpiresAt = key.ID, &expiresAt
+	return true, nil
+}
+
+func (m *MockServiceAccountStore) ListServiceAccountKeys(accountID string) ([]*serviceaccount.Key, error) {
+	keys := make([]*serviceaccount.Key, 0)
+	for _, key := range m.keys {
+		if key.AccountID == accountID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MockServiceAccountStore) RevokeServiceAccountKey(accountID, keyID string) (bool, error) {
+	key, ok := m.keys[keyID]
+	if !ok || key.AccountID != accountID || key.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return true, nil
+}
+
+func (m *MockServiceAccountStore) GetServiceAccountKey(keyID string) (*serviceaccount.Account, *serviceaccount.Key, error) {
+	if m.shouldError {
+		return nil, nil, assert.AnError
+	}
+	key, ok := m.keys[keyID]
+	if !ok {
+		return nil, nil, nil
+	}
+	return m.accounts[key.AccountID], key, nil
+}
+
+func (m *MockServiceAccountStore) RecordPIIViolations(orgID, principalID, keyID string, violations []pii.Violation) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	day := time.Now().UTC().Format("2006-01-02")
+	for _, v := range violations {
+		m.violations[principalID] = append(m.violations[principalID], pii.DailyCount{
+			Day: day, KeyID: keyID, Field: v.Field, Kind: v.Kind, Action: v.Action, Count: 1, LastSeenAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+func (m *MockServiceAccountStore) ListPIIViolations(principalID string, since time.Time) ([]pii.DailyCount, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.violations[principalID], nil
+}
+
+func (m *MockServiceAccountStore) TouchServiceAccountKey(keyID string) error {
+	now := time.Now()
+	m.keys[keyID].LastUsedAt = &now
+	return nil
+}
+
+// addAccount registers an account with one key and returns the key's token
+func (m *MockServiceAccountStore) addAccount(t *testing.T, accountID, orgID string, scopes ...string) (string, string) {
+	keyID, token, secretHash, err := serviceaccount.GenerateKey()
+	require.NoError(t, err)
+
+	m.accounts[accountID] = &serviceaccount.Account{ID: accountID, Name: accountID, OrgID: orgID, Scopes: scopes}
+	m.keys[keyID] = &serviceaccount.Key{ID: keyID, AccountID: accountID, Version: 1, SecretHash: secretHash}
+	return keyID, token
+}
+
+func TestServiceAccountHandler_CreateServiceAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		store          *MockServiceAccountStore
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "valid account",
+			body:           `{"name":"render-farm","scopes":["bookings:read","events:write"]}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusCreated,
+			description:    "Should create an account and return its first token",
+		},
+		{
+			name:           "wildcard scope",
+			body:           `{"name":"ci","scopes":["metadata:*"]}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusCreated,
+			description:    "Should accept resource wildcards",
+		},
+		{
+			name:           "unknown scope",
+			body:           `{"name":"ci","scopes":["bookings:delete"]}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject scopes that do not exist",
+		},
+		{
+			name:           "camelCase key",
+			body:           `{"name":"partner","scopes":["events:write"],"json_case":"any"}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusCreated,
+			description:    "Should issue a key accepting camelCase bodies",
+		},
+		{
+			name:           "unknown json_case",
+			body:           `{"name":"partner","scopes":["events:write"],"json_case":"kebab"}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown case conventions",
+		},
+		{
+			name:           "no scopes",
+			body:           `{"name":"ci","scopes":[]}`,
+			store:          newMockServiceAccountStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require at least one scope",
+		},
+		{
+			name:           "store error",
+			body:           `{"name":"ci","scopes":["sgi:read"]}`,
+			store:          &MockServiceAccountStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewServiceAccountHandler(tt.store)
+			router := gin.New()
+			router.POST("/service-accounts", withOrg("user_1", "org_a"), handler.CreateServiceAccount)
+
+			req := httptest.NewRequest(http.MethodPost, "/service-accounts", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.True(t, strings.HasPrefix(response["token"].(string), serviceaccount.TokenPrefix))
+				assert.Equal(t, "org_a", tt.store.accounts["sa_123"].OrgID)
+				assert.Equal(t, "user_1", tt.store.accounts["sa_123"].CreatedBy)
+				assert.NotContains(t, resp.Body.String(), "secret_hash")
+				for _, key := range tt.store.keys {
+					assert.Equal(t, response["json_case"], key.JSONCase)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceAccountHandler_Keys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockServiceAccountStore()
+	oldKeyID, _ := store.addAccount(t, "sa_render", "org_a", "events:write")
+	store.addAccount(t, "sa_other", "org_b", "events:write")
+
+	handler := NewServiceAccountHandler(store)
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.GET("/service-accounts/:account_id/keys", handler.ListKeys)
+	router.POST("/service-accounts/:account_id/keys", handler.IssueKey)
+	router.DELETE("/service-accounts/:account_id/keys/:key_id", handler.RevokeKey)
+	router.DELETE("/service-accounts/:account_id", handler.DisableServiceAccount)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		description    string
+	}{
+		{"issue key", http.MethodPost, "/service-accounts/sa_render/keys", http.StatusCreated, "Should issue an additional key"},
+		{"list keys", http.MethodGet, "/service-accounts/sa_render/keys", http.StatusOK, "Should list the account's keys"},
+		{"revoke old key", http.MethodDelete, "/service-accounts/sa_render/keys/" + oldKeyID, http.StatusOK, "Should revoke a key"},
+		{"revoke twice", http.MethodDelete, "/service-accounts/sa_render/keys/" + oldKeyID, http.StatusNotFound, "Should not revoke a key twice"},
+		{"other org's account", http.MethodGet, "/service-accounts/sa_other/keys", http.StatusNotFound, "Should hide accounts of other organizations"},
+		{"disable other org's account", http.MethodDelete, "/service-accounts/sa_other", http.StatusNotFound, "Should not disable other organizations' accounts"},
+		{"unknown account", http.MethodPost, "/service-accounts/sa_missing/keys", http.StatusNotFound, "Should return 404 for unknown accounts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+
+	keys, err := store.ListServiceAccountKeys("sa_render")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Nil(t, store.accounts["sa_other"].DisabledAt)
+}
+
+func TestServiceAccountHandler_RotateKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockServiceAccountStore()
+	oldKeyID, oldToken := store.addAccount(t, "sa_partner", "org_a", "bookings:read")
+	store.keys[oldKeyID].JSONCase = schema.CaseAny
+	revokedKeyID, _ := store.addAccount(t, "sa_revoked", "org_a", "bookings:read")
+	revokedAt := time.Now()
+	store.keys[revokedKeyID].RevokedAt = &revokedAt
+
+	handler := NewServiceAccountHandler(store)
+	handler.SetRotation(serviceaccount.Rotation{Overlap: 48 * time.Hour, Warning: time.Hour})
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.POST("/service-accounts/:account_id/keys/:key_id/rotate", handler.RotateKey)
+
+	rotate := func(accountID, keyID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/service-accounts/"+accountID+"/keys/"+keyID+"/rotate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	tests := []struct {
+		name           string
+		accountID      string
+		keyID          string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{"invalid overlap", "sa_partner", oldKeyID, `{"overlap":"a week"}`, http.StatusBadRequest, "Should reject overlaps that are not durations"},
+		{"overlap too long", "sa_partner", oldKeyID, `{"overlap":"2400h"}`, http.StatusBadRequest, "Should cap the overlap"},
+		{"revoked key", "sa_revoked", revokedKeyID, "", http.StatusNotFound, "Should not rotate revoked keys"},
+		{"other account's key", "sa_revoked", oldKeyID, "", http.StatusNotFound, "Should not rotate keys of other accounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedStatus, rotate(tt.accountID, tt.keyID, tt.body).Code, tt.description)
+		})
+	}
+
+	before := time.Now()
+	resp := rotate("sa_partner", oldKeyID, "")
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var response struct {
+		KeyID    string `json:"key_id"`
+		Version  int    `json:"version"`
+		JSONCase string `json:"json_case"`
+		Token    string `json:"token"`
+		Rotated  struct {
+			KeyID     string    `json:"key_id"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"rotated"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Version, "The new key should be numbered after the old one")
+	assert.Equal(t, schema.CaseAny, response.JSONCase, "The new key should keep the old one's json_case")
+	assert.Equal(t, oldKeyID, response.Rotated.KeyID)
+	assert.WithinDuration(t, before.Add(48*time.Hour), response.Rotated.ExpiresAt, time.Minute, "Should default to the configured overlap")
+	assert.Equal(t, response.KeyID, store.keys[oldKeyID].ReplacedBy)
+
+	assert.Equal(t, http.StatusConflict, rotate("sa_partner", oldKeyID, "").Code, "Should not rotate a key twice")
+
+	resp = rotate("sa_partner", response.KeyID, `{"overlap":"1h","json_case":"snake"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"version":3`)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *store.keys[response.KeyID].ExpiresAt, time.Minute)
+
+	// Rotated keys authenticate until they expire, telling clients when
+	auth := gin.New()
+	auth.Use(middleware.Authenticate("test-secret", store, nil))
+	auth.GET("/bookings", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"key_version": c.GetInt("key_version")})
+	})
+	for version, token := range map[int]string{1: oldToken, 2: response.Token} {
+		req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		auth.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"key_version":`+strconv.Itoa(version)+`}`, resp.Body.String())
+		assert.NotEmpty(t, resp.Header().Get("X-Inscenium-Key-Expires"))
+	}
+
+	expired := time.Now().Add(-time.Second)
+	store.keys[oldKeyID].ExpiresAt = &expired
+	req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+	req.Header.Set("Authorization", "Bearer "+oldToken)
+	resp = httptest.NewRecorder()
+	auth.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "Should reject keys whose overlap has ended")
+}
+
+func TestServiceAccountAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockServiceAccountStore()
+	_, readerToken := store.addAccount(t, "sa_reader", "org_a", "bookings:read")
+	_, writerToken := store.addAccount(t, "sa_writer", "org_a", "bookings:*")
+	revokedKeyID, revokedToken := store.addAccount(t, "sa_revoked", "org_a", "*")
+	revokedAt := time.Now()
+	store.keys[revokedKeyID].RevokedAt = &revokedAt
+	_, disabledToken := store.addAccount(t, "sa_disabled", "org_a", "*")
+	store.accounts["sa_disabled"].DisabledAt = &revokedAt
+
+	router := gin.New()
+	router.Use(middleware.Authenticate("test-secret", store, nil))
+	identity := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "org_id": c.GetString("org_id")})
+	}
+	router.GET("/bookings", middleware.RequireScope("bookings:read"), identity)
+	router.POST("/bookings", middleware.RequireScope("bookings:write"), identity)
+	router.GET("/service-accounts", middleware.RequireUser(), identity)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		description    string
+	}{
+		{"read with read scope", http.MethodGet, "/bookings", readerToken, http.StatusOK, "Should allow routes covered by a scope"},
+		{"write with read scope", http.MethodPost, "/bookings", readerToken, http.StatusForbidden, "Should reject routes outside the granted scopes"},
+		{"write with wildcard", http.MethodPost, "/bookings", writerToken, http.StatusOK, "Should allow resource wildcards"},
+		{"user-only route", http.MethodGet, "/service-accounts", writerToken, http.StatusForbidden, "Should keep service accounts off user-only routes"},
+		{"revoked key", http.MethodGet, "/bookings", revokedToken, http.StatusUnauthorized, "Should reject revoked keys"},
+		{"disabled account", http.MethodGet, "/bookings", disabledToken, http.StatusUnauthorized, "Should reject disabled accounts"},
+		{"wrong secret", http.MethodGet, "/bookings", readerToken[:len(readerToken)-4] + "0000", http.StatusUnauthorized, "Should reject tampered secrets"},
+		{"malformed key", http.MethodGet, "/bookings", serviceaccount.TokenPrefix + "garbage", http.StatusUnauthorized, "Should reject malformed keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+	req.Header.Set("Authorization", "Bearer "+readerToken)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "sa:sa_reader", response["user_id"])
+	assert.Equal(t, "org_a", response["org_id"])
+}
+
+func TestServiceAccountJSONCase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockServiceAccountStore()
+	_, snakeToken := store.addAccount(t, "sa_snake", "org_a", "bookings:write")
+	camelKeyID, camelToken := store.addAccount(t, "sa_camel", "org_a", "bookings:write")
+	store.keys[camelKeyID].JSONCase = schema.CaseAny
+
+	handler := &PlacementHandler{placements: placement.NewService(&MockPlacementDB{bookingID: "booking_123"})}
+	router := gin.New()
+	router.Use(middleware.Authenticate("test-secret", store, nil))
+	router.POST("/bookings", handler.BookPlacement)
+
+	camelBody := `{"surfaceId":"surface_001","advertiserId":"adv_1","campaignId":"camp_1","bidAmountCPM":4.5}`
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		description    string
+	}{
+		{"camelCase with snake key", snakeToken, http.StatusBadRequest, "Should only accept snake_case from default keys"},
+		{"camelCase with any key", camelToken, http.StatusCreated, "Should accept camelCase from keys configured for it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(camelBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if resp.Code == http.StatusCreated {
+				assert.Contains(t, resp.Body.String(), `"final_cpm_rate":4.5`)
+				assert.NotContains(t, resp.Body.String(), "finalCpmRate")
+			}
+		})
+	}
+}
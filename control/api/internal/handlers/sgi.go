@@ -1,17 +1,31 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/audio"
 	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
 	"github.com/sirupsen/logrus"
 )
 
+// SGIStore is the subset of database operations used by SGIHandler
+type SGIStore interface {
+	models.OpportunityRepository
+}
+
 // SGIHandler handles Scene Graph Intelligence requests
 type SGIHandler struct {
-	db *db.DB
+	db       SGIStore
+	taxonomy TaxonomyReader
 }
 
 // NewSGIHandler creates a new SGI handler
@@ -19,66 +33,139 @@ func NewSGIHandler(database *db.DB) *SGIHandler {
 	return &SGIHandler{db: database}
 }
 
-// ListOpportunities handles GET /sgi/opportunities
+// SetTaxonomy shows surface types and restrictions in the caller's
+// organization's aliases
+func (h *SGIHandler) SetTaxonomy(store TaxonomyReader) {
+	h.taxonomy = store
+}
+
+// ListOpportunities handles GET /opportunities
 func (h *SGIHandler) ListOpportunities(c *gin.Context) {
 	titleID := c.Query("title_id")
-	minPRSStr := c.DefaultQuery("min_prs", "0")
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
+	minPRSStr := schema.Query(c, "min_prs_score", "min_prs", "0")
 
 	minPRS, err := strconv.ParseFloat(minPRSStr, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_prs parameter"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_prs_score parameter"})
 		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
+	page, ok := parsePage(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	selector, ok := parseLabelSelector(c)
+	if !ok {
+		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	t, ok := callerTaxonomy(c, h.taxonomy)
+	if !ok {
+		return
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"title_id": titleID,
-		"min_prs":  minPRS,
-		"limit":    limit,
-		"offset":   offset,
+		"title_id":      titleID,
+		"min_prs_score": minPRS,
+		"labels":        selector.String(),
+		"limit":         page.Limit,
+		"offset":        page.Offset,
+		"cursor":        page.Cursor != nil,
 	}).Info("Listing placement opportunities")
 
-	opportunities, err := h.db.GetPlacementOpportunities(titleID, minPRS, limit, offset)
+	opportunities, err := h.db.GetPlacementOpportunities(titleID, minPRS, selector, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if errors.Is(err, query.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get placement opportunities")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	opportunities, next, prev := pagination.Window(opportunities, page, opportunityCursor)
 
 	// If no database results, return mock data for development
-	if len(opportunities) == 0 {
+	if len(opportunities) == 0 && len(selector) == 0 && page.Cursor == nil {
 		opportunities = h.getMockOpportunities(titleID, minPRS)
 	}
+	aliasSurfaces(t, opportunities)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"opportunities": opportunities,
 		"total_count":   len(opportunities),
-		"limit":         limit,
-		"offset":        offset,
+		"limit":         page.Limit,
+		"offset":        page.Offset,
 		"filters": gin.H{
-			"title_id": titleID,
-			"min_prs":  minPRS,
+			"title_id":      titleID,
+			"min_prs_score": minPRS,
+			"min_prs":       minPRS, // Deprecated alias of min_prs_score
+			"labels":        selector,
 		},
-	})
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
+
+// opportunityCursor keys opportunity pages on PRS score and surface ID
+func opportunityCursor(s models.Surface) pagination.Cursor {
+	return pagination.Cursor{Key: pagination.FloatKey(s.PRSScore), ID: s.SurfaceID}
 }
 
-// GetOpportunity handles GET /sgi/opportunities/:surface_id
+// parsePage reads the limit and cursor query parameters, or the deprecated
+// offset, writing a 400 response and returning false for a malformed cursor.
+// Out of range limits fall back to defaultLimit.
+func parsePage(c *gin.Context, defaultLimit, maxLimit int) (pagination.Page, bool) {
+	page := pagination.Page{Limit: defaultLimit}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit >= 1 && limit <= maxLimit {
+		page.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := pagination.Decode(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+			return page, false
+		}
+		page.Cursor = cursor
+		return page, true
+	}
+	if value, ok := c.GetQuery("offset"); ok {
+		schema.Deprecate(c, "query", "offset", "cursor")
+		if offset, err := strconv.Atoi(value); err == nil && offset > 0 {
+			page.Offset = offset
+		}
+	}
+	return page, true
+}
+
+// setCursors adds the cursors to the pages around a listing's page to its
+// response, leaving out those there is no page for
+func setCursors(response gin.H, next, prev string) {
+	if next != "" {
+		response["next_cursor"] = next
+	}
+	if prev != "" {
+		response["prev_cursor"] = prev
+	}
+}
+
+// GetOpportunity handles GET /opportunities/:surface_id
 func (h *SGIHandler) GetOpportunity(c *gin.Context) {
 	surfaceID := c.Param("surface_id")
 
 	logrus.WithField("surface_id", surfaceID).Info("Getting placement opportunity")
 
+	t, ok := callerTaxonomy(c, h.taxonomy)
+	if !ok {
+		return
+	}
+
 	opportunity, err := h.db.GetPlacementOpportunity(surfaceID)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get placement opportunity")
@@ -90,55 +177,62 @@ func (h *SGIHandler) GetOpportunity(c *gin.Context) {
 		// Return mock data for development
 		opportunity = h.getMockOpportunity(surfaceID)
 	}
+	aliasSurface(t, opportunity)
 
 	c.JSON(http.StatusOK, opportunity)
 }
 
 // getMockOpportunities returns mock opportunities for development
-func (h *SGIHandler) getMockOpportunities(titleID string, minPRS float64) []map[string]interface{} {
-	mockOpportunities := []map[string]interface{}{
+func (h *SGIHandler) getMockOpportunities(titleID string, minPRS float64) []models.Surface {
+	mockOpportunities := []models.Surface{
 		{
-			"surface_id":       "surface_001",
-			"title_id":         titleID,
-			"shot_id":          "shot_001",
-			"start_time":       5.2,
-			"end_time":         12.8,
-			"duration":         7.6,
-			"prs_score":        87.5,
-			"surface_type":     "wall",
-			"visibility_score": 92.1,
-			"created_at":       "2024-01-15T10:30:00Z",
+			SurfaceID:       "surface_001",
+			TitleID:         titleID,
+			ShotID:          "shot_001",
+			StartTime:       5.2,
+			EndTime:         12.8,
+			Duration:        7.6,
+			PRSScore:        87.5,
+			SurfaceType:     "wall",
+			PlacementType:   audio.PlacementVisual,
+			VisibilityScore: 92.1,
+			CreatedAt:       sampleCreatedAt,
+			Labels:          labels.Set{},
 		},
 		{
-			"surface_id":       "surface_002",
-			"title_id":         titleID,
-			"shot_id":          "shot_002",
-			"start_time":       15.1,
-			"end_time":         23.4,
-			"duration":         8.3,
-			"prs_score":        92.1,
-			"surface_type":     "table",
-			"visibility_score": 88.7,
-			"created_at":       "2024-01-15T10:30:00Z",
+			SurfaceID:       "surface_002",
+			TitleID:         titleID,
+			ShotID:          "shot_002",
+			StartTime:       15.1,
+			EndTime:         23.4,
+			Duration:        8.3,
+			PRSScore:        92.1,
+			SurfaceType:     "table",
+			PlacementType:   audio.PlacementVisual,
+			VisibilityScore: 88.7,
+			CreatedAt:       sampleCreatedAt,
+			Labels:          labels.Set{},
 		},
 		{
-			"surface_id":       "surface_003",
-			"title_id":         titleID,
-			"shot_id":          "shot_003",
-			"start_time":       28.7,
-			"end_time":         35.2,
-			"duration":         6.5,
-			"prs_score":        79.3,
-			"surface_type":     "screen",
-			"visibility_score": 85.4,
-			"created_at":       "2024-01-15T10:30:00Z",
+			SurfaceID:       "surface_003",
+			TitleID:         titleID,
+			ShotID:          "shot_003",
+			StartTime:       28.7,
+			EndTime:         35.2,
+			Duration:        6.5,
+			PRSScore:        79.3,
+			SurfaceType:     "screen",
+			PlacementType:   audio.PlacementVisual,
+			VisibilityScore: 85.4,
+			CreatedAt:       sampleCreatedAt,
+			Labels:          labels.Set{},
 		},
 	}
 
 	// Filter by minimum PRS score
-	filtered := make([]map[string]interface{}, 0)
+	filtered := make([]models.Surface, 0)
 	for _, opp := range mockOpportunities {
-		if score, ok := opp["prs_score"].(float64); ok && score >= minPRS {
+		if opp.PRSScore >= minPRS {
 			filtered = append(filtered, opp)
 		}
 	}
@@ -147,30 +241,26 @@ func (h *SGIHandler) getMockOpportunities(titleID string, minPRS float64) []map[
 }
 
 // getMockOpportunity returns a mock opportunity for development
-func (h *SGIHandler) getMockOpportunity(surfaceID string) map[string]interface{} {
-	return map[string]interface{}{
-		"surface_id":       surfaceID,
-		"title_id":         "title_001",
-		"shot_id":          "shot_001",
-		"start_time":       5.2,
-		"end_time":         12.8,
-		"duration":         7.6,
-		"prs_score":        87.5,
-		"surface_type":     "wall",
-		"visibility_score": 92.1,
-		"area_pixels":      25680.0,
-		"area_world_m2":    1.2,
-		"restrictions":     `["family-friendly"]`,
-		"geometry": map[string]interface{}{
-			"bounds_3d": map[string]interface{}{
-				"min_x": 0.1,
-				"min_y": 0.2,
-				"min_z": 5.0,
-				"max_x": 1.8,
-				"max_y": 1.5,
-				"max_z": 5.1,
-			},
+func (h *SGIHandler) getMockOpportunity(surfaceID string) *models.Surface {
+	areaPixels, areaWorldM2 := 25680.0, 1.2
+	return &models.Surface{
+		SurfaceID:       surfaceID,
+		TitleID:         "title_001",
+		ShotID:          "shot_001",
+		StartTime:       5.2,
+		EndTime:         12.8,
+		Duration:        7.6,
+		PRSScore:        87.5,
+		SurfaceType:     "wall",
+		PlacementType:   audio.PlacementVisual,
+		VisibilityScore: 92.1,
+		AreaPixels:      &areaPixels,
+		AreaWorldM2:     &areaWorldM2,
+		Restrictions:    json.RawMessage(`["family-friendly"]`),
+		Geometry: &models.Geometry{
+			Bounds3D: json.RawMessage(`{"min_x":0.1,"min_y":0.2,"min_z":5.0,"max_x":1.8,"max_y":1.5,"max_z":5.1}`),
 		},
-		"created_at": "2024-01-15T10:30:00Z",
+		CreatedAt: sampleCreatedAt,
+		Labels:    labels.Set{},
 	}
-}
\ No newline at end of file
+}
@@ -4,30 +4,35 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 type MockDB struct {
 	*db.DB
-	opportunities []map[string]interface{}
-	opportunity   map[string]interface{}
+	opportunities []models.Surface
+	opportunity   *models.Surface
+	selector      labels.Set
 	shouldError   bool
 }
 
-func (m *MockDB) GetPlacementOpportunities(titleID string, minPRS float64, limit, offset int) ([]map[string]interface{}, error) {
+func (m *MockDB) GetPlacementOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]models.Surface, error) {
 	if m.shouldError {
 		return nil, assert.AnError
 	}
+	m.selector = selector
 	return m.opportunities, nil
 }
 
-func (m *MockDB) GetPlacementOpportunity(surfaceID string) (map[string]interface{}, error) {
+func (m *MockDB) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
 	if m.shouldError {
 		return nil, assert.AnError
 	}
@@ -49,16 +54,16 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 			name:        "list opportunities with no filters",
 			queryParams: "",
 			mockDB: &MockDB{
-				opportunities: []map[string]interface{}{
+				opportunities: []models.Surface{
 					{
-						"surface_id": "surface_001",
-						"title_id":   "title_001",
-						"prs_score":  87.5,
+						SurfaceID: "surface_001",
+						TitleID:   "title_001",
+						PRSScore:  87.5,
 					},
 					{
-						"surface_id": "surface_002", 
-						"title_id":   "title_001",
-						"prs_score":  92.1,
+						SurfaceID: "surface_002", 
+						TitleID:   "title_001",
+						PRSScore:  92.1,
 					},
 				},
 				shouldError: false,
@@ -71,11 +76,11 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 			name:        "list opportunities with title filter",
 			queryParams: "?title_id=title_001&min_prs=80",
 			mockDB: &MockDB{
-				opportunities: []map[string]interface{}{
+				opportunities: []models.Surface{
 					{
-						"surface_id": "surface_001",
-						"title_id":   "title_001",
-						"prs_score":  87.5,
+						SurfaceID: "surface_001",
+						TitleID:   "title_001",
+						PRSScore:  87.5,
 					},
 				},
 				shouldError: false,
@@ -88,7 +93,7 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 			name:        "list opportunities with invalid min_prs",
 			queryParams: "?min_prs=invalid",
 			mockDB: &MockDB{
-				opportunities: []map[string]interface{}{},
+				opportunities: []models.Surface{},
 				shouldError:   false,
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -99,7 +104,7 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 			name:        "database error",
 			queryParams: "",
 			mockDB: &MockDB{
-				opportunities: []map[string]interface{}{},
+				opportunities: []models.Surface{},
 				shouldError:   true,
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -110,13 +115,35 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 			name:        "empty database returns mock data",
 			queryParams: "",
 			mockDB: &MockDB{
-				opportunities: []map[string]interface{}{}, // Empty result triggers mock data
+				opportunities: []models.Surface{}, // Empty result triggers mock data
 				shouldError:   false,
 			},
 			expectedStatus: http.StatusOK,
 			expectedCount:  3, // Mock data has 3 opportunities
 			description:    "Should return mock data when database is empty",
 		},
+		{
+			name:        "label selector with no matches",
+			queryParams: "?labels=team=sports,region=emea",
+			mockDB: &MockDB{
+				opportunities: []models.Surface{},
+				shouldError:   false,
+			},
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+			description:    "Should not fall back to mock data when filtering by labels",
+		},
+		{
+			name:        "invalid label selector",
+			queryParams: "?labels=team",
+			mockDB: &MockDB{
+				opportunities: []models.Surface{},
+				shouldError:   false,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCount:  0,
+			description:    "Should reject selectors without key=value pairs",
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,6 +195,76 @@ func TestSGIHandler_ListOpportunities(t *testing.T) {
 	}
 }
 
+func TestSGIHandler_DeprecatedNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		url                string
+		expectedDeprecated []string
+		expectedLink       string
+		description        string
+	}{
+		{
+			name:        "canonical route and parameter",
+			url:         "/api/v1/opportunities?min_prs_score=80",
+			description: "Should not flag canonical names",
+		},
+		{
+			name:               "deprecated query parameter",
+			url:                "/api/v1/opportunities?min_prs=80",
+			expectedDeprecated: []string{"min_prs=min_prs_score"},
+			description:        "Should accept min_prs and flag it",
+		},
+		{
+			name:         "deprecated route",
+			url:          "/api/v1/sgi/opportunities",
+			expectedLink: `</api/v1/opportunities>; rel="successor-version"`,
+			description:  "Should serve the old route and link to its successor",
+		},
+		{
+			name:         "deprecated route with path parameter",
+			url:          "/api/v1/sgi/opportunities/surface_001",
+			expectedLink: `</api/v1/opportunities/surface_001>; rel="successor-version"`,
+			description:  "Should fill path parameters into the successor link",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &SGIHandler{db: &MockDB{
+				opportunities: []models.Surface{{SurfaceID: "surface_001", PRSScore: 87.5}},
+				opportunity:   &models.Surface{SurfaceID: "surface_001"},
+			}}
+			router := gin.New()
+			router.GET("/api/v1/opportunities", handler.ListOpportunities)
+			router.GET("/api/v1/opportunities/:surface_id", handler.GetOpportunity)
+			router.GET("/api/v1/sgi/opportunities", schema.DeprecatedRoute("/api/v1/opportunities"), handler.ListOpportunities)
+			router.GET("/api/v1/sgi/opportunities/:surface_id", schema.DeprecatedRoute("/api/v1/opportunities/:surface_id"), handler.GetOpportunity)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code, tt.description)
+			assert.Equal(t, tt.expectedDeprecated, resp.Header().Values(schema.DeprecatedFieldsHeader), tt.description)
+			if tt.expectedLink != "" {
+				assert.Equal(t, "true", resp.Header().Get("Deprecation"))
+				assert.Equal(t, tt.expectedLink, resp.Header().Get("Link"))
+			} else {
+				assert.Empty(t, resp.Header().Get("Deprecation"))
+			}
+
+			if tt.expectedDeprecated != nil {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				filters := response["filters"].(map[string]interface{})
+				assert.Equal(t, 80.0, filters["min_prs_score"])
+			}
+		})
+	}
+}
+
 func TestSGIHandler_GetOpportunity(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -182,11 +279,11 @@ func TestSGIHandler_GetOpportunity(t *testing.T) {
 			name:      "get existing opportunity",
 			surfaceID: "surface_001",
 			mockDB: &MockDB{
-				opportunity: map[string]interface{}{
-					"surface_id":       "surface_001",
-					"title_id":         "title_001",
-					"prs_score":        87.5,
-					"visibility_score": 92.1,
+				opportunity: &models.Surface{
+					SurfaceID:       "surface_001",
+					TitleID:         "title_001",
+					PRSScore:        87.5,
+					VisibilityScore: 92.1,
 				},
 				shouldError: false,
 			},
@@ -297,19 +394,15 @@ func TestSGIHandler_getMockOpportunities(t *testing.T) {
 
 			// Validate each opportunity has required fields
 			for i, opp := range opportunities {
-				assert.Contains(t, opp, "surface_id", "Opportunity %d should have surface_id", i)
-				assert.Contains(t, opp, "title_id", "Opportunity %d should have title_id", i) 
-				assert.Contains(t, opp, "prs_score", "Opportunity %d should have prs_score", i)
-				assert.Contains(t, opp, "surface_type", "Opportunity %d should have surface_type", i)
-				assert.Contains(t, opp, "visibility_score", "Opportunity %d should have visibility_score", i)
+				assert.NotEmpty(t, opp.SurfaceID, "Opportunity %d should have surface_id", i)
+				assert.NotEmpty(t, opp.SurfaceType, "Opportunity %d should have surface_type", i)
+				assert.Greater(t, opp.VisibilityScore, 0.0, "Opportunity %d should have visibility_score", i)
 
 				// Validate title_id matches request
-				assert.Equal(t, tt.titleID, opp["title_id"], "Title ID should match request")
+				assert.Equal(t, tt.titleID, opp.TitleID, "Title ID should match request")
 
 				// Validate PRS score meets minimum requirement
-				prsScore, ok := opp["prs_score"].(float64)
-				assert.True(t, ok, "PRS score should be float64")
-				assert.GreaterOrEqual(t, prsScore, tt.minPRS, "PRS score should meet minimum requirement")
+				assert.GreaterOrEqual(t, opp.PRSScore, tt.minPRS, "PRS score should meet minimum requirement")
 			}
 		})
 	}
@@ -335,30 +428,22 @@ func TestSGIHandler_getMockOpportunity(t *testing.T) {
 			opportunity := handler.getMockOpportunity(tt.surfaceID)
 
 			// Validate required fields
-			assert.Contains(t, opportunity, "surface_id")
-			assert.Contains(t, opportunity, "title_id")
-			assert.Contains(t, opportunity, "prs_score")
-			assert.Contains(t, opportunity, "surface_type")
-			assert.Contains(t, opportunity, "visibility_score")
-			assert.Contains(t, opportunity, "geometry")
+			assert.NotEmpty(t, opportunity.TitleID)
+			assert.NotEmpty(t, opportunity.SurfaceType)
 
 			// Validate surface_id matches request
-			assert.Equal(t, tt.surfaceID, opportunity["surface_id"])
+			assert.Equal(t, tt.surfaceID, opportunity.SurfaceID)
 
 			// Validate numeric fields are reasonable
-			prsScore, ok := opportunity["prs_score"].(float64)
-			assert.True(t, ok, "PRS score should be float64")
-			assert.Greater(t, prsScore, 0.0, "PRS score should be positive")
-			assert.LessOrEqual(t, prsScore, 100.0, "PRS score should be <= 100")
-
-			visibilityScore, ok := opportunity["visibility_score"].(float64)
-			assert.True(t, ok, "Visibility score should be float64")
-			assert.Greater(t, visibilityScore, 0.0, "Visibility score should be positive")
+			assert.Greater(t, opportunity.PRSScore, 0.0, "PRS score should be positive")
+			assert.LessOrEqual(t, opportunity.PRSScore, 100.0, "PRS score should be <= 100")
+			assert.Greater(t, opportunity.VisibilityScore, 0.0, "Visibility score should be positive")
 
 			// Validate geometry object
-			geometry, ok := opportunity["geometry"].(map[string]interface{})
-			assert.True(t, ok, "Geometry should be object")
-			assert.Contains(t, geometry, "bounds_3d")
+			require.NotNil(t, opportunity.Geometry, "Geometry should be object")
+			var bounds map[string]float64
+			require.NoError(t, json.Unmarshal(opportunity.Geometry.Bounds3D, &bounds))
+			assert.Contains(t, bounds, "min_x")
 		})
 	}
 }
@@ -400,9 +485,9 @@ func BenchmarkSGIHandler_ListOpportunities(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	
 	mockDB := &MockDB{
-		opportunities: []map[string]interface{}{
-			{"surface_id": "surface_001", "prs_score": 87.5},
-			{"surface_id": "surface_002", "prs_score": 92.1},
+		opportunities: []models.Surface{
+			{SurfaceID: "surface_001", PRSScore: 87.5},
+			{SurfaceID: "surface_002", PRSScore: 92.1},
 		},
 		shouldError: false,
 	}
@@ -425,9 +510,9 @@ func BenchmarkSGIHandler_GetOpportunity(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	
 	mockDB := &MockDB{
-		opportunity: map[string]interface{}{
-			"surface_id": "surface_001",
-			"prs_score":  87.5,
+		opportunity: &models.Surface{
+			SurfaceID: "surface_001",
+			PRSScore:  87.5,
 		},
 		shouldError: false,
 	}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// statusCacheTTL is how long a status summary is served before it is rebuilt,
+// which bounds the load the public endpoint puts on the database
+const statusCacheTTL = 30 * time.Second
+
+// StatusStore reads the component health history
+type StatusStore interface {
+	ListHealthChecks(since time.Time) ([]status.Check, error)
+	TallyHealthChecks(since time.Time) (map[string]status.Tally, error)
+}
+
+// StatusHandler serves the public status page data
+type StatusHandler struct {
+	db         StatusStore
+	staleAfter time.Duration
+
+	mu       sync.Mutex
+	cached   *status.Summary
+	cachedAt time.Time
+}
+
+// NewStatusHandler creates a status handler. Components not checked within
+// three check intervals are reported unknown.
+func NewStatusHandler(store StatusStore, checkInterval time.Duration) *StatusHandler {
+	return &StatusHandler{db: store, staleAfter: 3 * checkInterval}
+}
+
+// GetStatus handles GET /status. It needs no authentication and only shows
+// statuses, uptime and incident times, never what went wrong.
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	summary, err := h.summary(time.Now().UTC())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to build status summary")
+		// Without the history, the API's own storage is what is failing
+		c.JSON(http.StatusServiceUnavailable, unavailableSummary())
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(statusCacheTTL.Seconds())))
+	c.JSON(http.StatusOK, summary)
+}
+
+// summary returns the cached summary, rebuilding it once it is statusCacheTTL
+// old. A rebuild that fails falls back on the cached summary while there is one.
+func (h *StatusHandler) summary(now time.Time) (*status.Summary, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && now.Sub(h.cachedAt) < statusCacheTTL {
+		return h.cached, nil
+	}
+
+	summary, err := h.build(now)
+	if err != nil {
+		if h.cached != nil {
+			logrus.WithError(err).Warn("Failed to rebuild status summary, serving the last one")
+			return h.cached, nil
+		}
+		return nil, err
+	}
+	h.cached, h.cachedAt = summary, now
+	return summary, nil
+}
+
+func (h *StatusHandler) build(now time.Time) (*status.Summary, error) {
+	checks, err := h.db.ListHealthChecks(now.Add(-status.IncidentWindow))
+	if err != nil {
+		return nil, err
+	}
+	tallies := make(map[string]map[string]status.Tally, len(status.UptimeWindows))
+	for _, window := range status.UptimeWindows {
+		if tallies[window.Name], err = h.db.TallyHealthChecks(now.Add(-window.Duration)); err != nil {
+			return nil, err
+		}
+	}
+	return status.Summarize(checks, tallies, h.staleAfter, now), nil
+}
+
+// unavailableSummary reports the API out and every other component unknown
+func unavailableSummary() *status.Summary {
+	summary := status.Summarize(nil, nil, 0, time.Now().UTC())
+	summary.Status = status.StatusOutage
+	for i := range summary.Components {
+		if summary.Components[i].Name == status.ComponentAPI {
+			summary.Components[i].Status = status.StatusOutage
+		}
+	}
+	return summary
+}
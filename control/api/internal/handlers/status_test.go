@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockStatusStore struct {
+	checks      []status.Check
+	tallies     map[string]status.Tally
+	reads       int
+	shouldError bool
+}
+
+func (m *MockStatusStore) ListHealthChecks(since time.Time) ([]status.Check, error) {
+	m.reads++
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.checks, nil
+}
+
+func (m *MockStatusStore) TallyHealthChecks(since time.Time) (map[string]status.Tally, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.tallies, nil
+}
+
+func getStatus(t *testing.T, handler *StatusHandler) (int, status.Summary) {
+	router := gin.New()
+	router.GET("/status", handler.GetStatus)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var summary status.Summary
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &summary))
+	return resp.Code, summary
+}
+
+func TestStatusHandler_GetStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	at := func(minutesAgo int) time.Time { return now.Add(-time.Duration(minutesAgo) * time.Minute) }
+	store := &MockStatusStore{
+		checks: []status.Check{
+			{Component: status.ComponentAPI, Status: status.StatusOperational, CheckedAt: at(3)},
+			{Component: status.ComponentAPI, Status: status.StatusOperational, CheckedAt: at(0)},
+			{Component: status.ComponentExports, Status: status.StatusDegraded, CheckedAt: at(4)},
+			{Component: status.ComponentExports, Status: status.StatusOutage, CheckedAt: at(3)},
+			{Component: status.ComponentExports, Status: status.StatusOperational, CheckedAt: at(2)},
+			{Component: status.ComponentExports, Status: status.StatusDegraded, CheckedAt: at(0)},
+			{Component: status.ComponentDecisioning, Status: status.StatusOperational, CheckedAt: at(60)},
+		},
+		tallies: map[string]status.Tally{
+			status.ComponentAPI:     {Checks: 2},
+			status.ComponentExports: {Checks: 4, Outages: 1},
+		},
+	}
+	handler := NewStatusHandler(store, time.Minute)
+
+	code, summary := getStatus(t, handler)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, status.StatusDegraded, summary.Status, "The page should show the worst component status")
+	require.Len(t, summary.Components, len(status.Components))
+
+	components := make(map[string]status.Component)
+	for _, component := range summary.Components {
+		components[component.Name] = component
+	}
+
+	api := components[status.ComponentAPI]
+	assert.Equal(t, status.StatusOperational, api.Status)
+	assert.Empty(t, api.Incidents)
+	require.NotNil(t, api.Uptime["24h"])
+	assert.Equal(t, 100.0, *api.Uptime["24h"])
+
+	exports := components[status.ComponentExports]
+	assert.Equal(t, status.StatusDegraded, exports.Status)
+	require.Len(t, exports.Incidents, 2)
+	assert.Nil(t, exports.Incidents[0].ResolvedAt, "The newest incident should still be ongoing")
+	assert.Equal(t, status.StatusOutage, exports.Incidents[1].Status, "An incident should report its worst status")
+	assert.True(t, exports.Incidents[1].StartedAt.Equal(at(4)))
+	require.NotNil(t, exports.Incidents[1].ResolvedAt)
+	assert.True(t, exports.Incidents[1].ResolvedAt.Equal(at(2)))
+	assert.Equal(t, 75.0, *exports.Uptime["24h"])
+
+	assert.Equal(t, status.StatusUnknown, components[status.ComponentDecisioning].Status, "A component without recent checks should be unknown")
+	assert.Equal(t, status.StatusUnknown, components[status.ComponentIngestion].Status)
+	assert.Nil(t, components[status.ComponentIngestion].Uptime["24h"])
+
+	// Summaries are cached, and served while the history cannot be read
+	store.shouldError = true
+	code, _ = getStatus(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, store.reads, "A fresh summary should be served from cache")
+
+	handler.cachedAt = now.Add(-time.Hour)
+	code, cached := getStatus(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, summary.Status, cached.Status, "A failed rebuild should serve the last summary")
+}
+
+func TestStatusHandler_GetStatusUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewStatusHandler(&MockStatusStore{shouldError: true}, time.Minute)
+
+	code, summary := getStatus(t, handler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, status.StatusOutage, summary.Status)
+	for _, component := range summary.Components {
+		if component.Name == status.ComponentAPI {
+			assert.Equal(t, status.StatusOutage, component.Status)
+		} else {
+			assert.Equal(t, status.StatusUnknown, component.Status)
+		}
+	}
+}
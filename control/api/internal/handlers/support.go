@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/inscenium/inscenium/control/api/internal/support"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// SupportStore reads the records support troubleshoots with, across
+// organizations
+type SupportStore interface {
+	GetPlacementBooking(scope tenant.Scope, bookingID string) (*models.Booking, error)
+	GetExposureEvent(eventID string) (*models.ExposureEvent, error)
+	GetPlacementOpportunity(surfaceID string) (*models.Surface, error)
+	GetCampaign(campaignID string) (*campaign.Campaign, error)
+	GetAdvertiser(advertiserID string) (*campaign.Advertiser, error)
+	GetOrganization(orgID string) (*organization.Organization, error)
+	ListBookingEvents(bookingID string) ([]booking.Event, error)
+	GetExposureEvents(scope tenant.Scope, bookingID string, page pagination.Page) ([]models.ExposureEvent, error)
+	ListRenderJobs(bookingID string, limit, offset int) ([]*render.Job, error)
+	ListBookingWebhookDeliveries(bookingID string, limit int) ([]webhook.Delivery, error)
+	ListSurfaceBookings(surfaceID string, limit int) ([]models.Booking, error)
+	ListViewerExposureEvents(viewerID string, limit int) ([]models.ExposureEvent, error)
+	ListOrgErrors(orgID string, since time.Time, limit int) ([]support.Error, error)
+	ListWebhookStatus(orgID string, since time.Time) ([]support.WebhookStatus, error)
+}
+
+// SupportHandler serves the views support staff troubleshoot with. Every
+// view reads across organizations, so they are only routed to admins.
+type SupportHandler struct {
+	db SupportStore
+}
+
+// NewSupportHandler creates a support handler
+func NewSupportHandler(store SupportStore) *SupportHandler {
+	return &SupportHandler{db: store}
+}
+
+// Lookup handles GET /admin/support/lookup/:id?kind=, finding what the ID
+// names - a booking, an exposure event, a surface or a viewer hash - and
+// returning each match stitched together with its related records. kind
+// restricts the lookup to one kind of record.
+func (h *SupportHandler) Lookup(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	kinds := support.Kinds
+	if kind := c.Query("kind"); kind != "" {
+		valid := false
+		for _, k := range support.Kinds {
+			valid = valid || k == kind
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be one of " + strings.Join(support.Kinds, ", ")})
+			return
+		}
+		kinds = []string{kind}
+	}
+
+	matches := make([]support.Match, 0, 1)
+	for _, kind := range kinds {
+		match, err := h.lookup(kind, id)
+		if err != nil {
+			logrus.WithError(err).WithField("kind", kind).Error("Failed to look up support ID")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+
+	found := make([]string, 0, len(matches))
+	for _, m := range matches {
+		found = append(found, m.Kind)
+	}
+	logrus.WithFields(logrus.Fields{
+		"audit":   "support_lookup",
+		"id":      id,
+		"matches": found,
+		"user_id": c.GetString("user_id"),
+	}).Info("Looked up ID for support")
+
+	if len(matches) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No " + strings.Join(kinds, ", ") + " has this ID"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
+
+// lookup finds the record of kind with id and stitches its related records
+// to it, or returns nil when there is none
+func (h *SupportHandler) lookup(kind, id string) (*support.Match, error) {
+	switch kind {
+	case support.KindBooking:
+		b, err := h.db.GetPlacementBooking(tenant.All(), id)
+		if err != nil || b == nil {
+			return nil, err
+		}
+		return h.bookingMatch(b)
+
+	case support.KindEvent:
+		event, err := h.db.GetExposureEvent(id)
+		if err != nil || event == nil {
+			return nil, err
+		}
+		match := &support.Match{Kind: kind, Event: event}
+		if match.Booking, err = h.db.GetPlacementBooking(tenant.All(), event.BookingID); err != nil {
+			return nil, err
+		}
+		if match.Booking != nil && match.Booking.SurfaceID != "" {
+			if match.Surface, err = h.db.GetPlacementOpportunity(match.Booking.SurfaceID); err != nil {
+				return nil, err
+			}
+		}
+		return match, nil
+
+	case support.KindSurface:
+		surface, err := h.db.GetPlacementOpportunity(id)
+		if err != nil || surface == nil {
+			return nil, err
+		}
+		match := &support.Match{Kind: kind, Surface: surface}
+		if match.Bookings, err = h.db.ListSurfaceBookings(id, support.RelatedLimit); err != nil {
+			return nil, err
+		}
+		return match, nil
+
+	case support.KindViewer:
+		events, err := h.db.ListViewerExposureEvents(id, support.RelatedLimit)
+		if err != nil || len(events) == 0 {
+			return nil, err
+		}
+		return &support.Match{Kind: kind, Events: events}, nil
+	}
+	return nil, nil
+}
+
+// bookingMatch stitches a booking to its surface, campaign, advertiser,
+// latest history, exposures, renders and webhook deliveries
+func (h *SupportHandler) bookingMatch(b *models.Booking) (*support.Match, error) {
+	match := &support.Match{Kind: support.KindBooking, Booking: b}
+	var err error
+	if b.SurfaceID != "" {
+		if match.Surface, err = h.db.GetPlacementOpportunity(b.SurfaceID); err != nil {
+			return nil, err
+		}
+	}
+	if b.CampaignID != "" {
+		if match.Campaign, err = h.db.GetCampaign(b.CampaignID); err != nil {
+			return nil, err
+		}
+	}
+	if b.AdvertiserID != "" {
+		if match.Advertiser, err = h.db.GetAdvertiser(b.AdvertiserID); err != nil {
+			return nil, err
+		}
+	}
+
+	history, err := h.db.ListBookingEvents(b.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > support.RelatedLimit {
+		history = history[len(history)-support.RelatedLimit:]
+	}
+	match.History = history
+
+	events, err := h.db.GetExposureEvents(tenant.All(), b.BookingID, pagination.Page{Limit: support.RelatedLimit})
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > support.RelatedLimit {
+		events = events[:support.RelatedLimit]
+	}
+	match.Events = events
+
+	if match.RenderJobs, err = h.db.ListRenderJobs(b.BookingID, support.RelatedLimit, 0); err != nil {
+		return nil, err
+	}
+	if match.Deliveries, err = h.db.ListBookingWebhookDeliveries(b.BookingID, support.RelatedLimit); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// parseSupportWindow reads the window query parameter of the organization
+// views, reporting a bad request when it is invalid
+func parseSupportWindow(c *gin.Context) (time.Duration, bool) {
+	window, err := time.ParseDuration(c.DefaultQuery("window", support.DefaultErrorWindow.String()))
+	if err != nil || window <= 0 || window > support.MaxErrorWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration between 0 and " + support.MaxErrorWindow.String()})
+		return 0, false
+	}
+	return window, true
+}
+
+// loadSupportOrg checks that the organization of the request exists
+func (h *SupportHandler) loadSupportOrg(c *gin.Context) (string, bool) {
+	orgID := c.Param("org_id")
+	org, err := h.db.GetOrganization(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get organization")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return "", false
+	}
+	if org == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return "", false
+	}
+	return orgID, true
+}
+
+// ListOrgErrors handles GET /admin/support/organizations/:org_id/errors?window=&limit=,
+// listing what went wrong for the organization within window, newest first
+func (h *SupportHandler) ListOrgErrors(c *gin.Context) {
+	window, ok := parseSupportWindow(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(support.DefaultErrorLimit)))
+	if err != nil || limit < 1 || limit > support.MaxErrorLimit {
+		limit = support.DefaultErrorLimit
+	}
+	orgID, ok := h.loadSupportOrg(c)
+	if !ok {
+		return
+	}
+
+	since := time.Now().Add(-window)
+	errs, err := h.db.ListOrgErrors(orgID, since, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("org_id", orgID).Error("Failed to list organization errors")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"org_id": orgID,
+		"since":  since.UTC(),
+		"errors": errs,
+		"count":  len(errs),
+		"limit":  limit,
+	})
+}
+
+// ListWebhookStatus handles GET /admin/support/organizations/:org_id/webhooks?window=,
+// summarizing the deliveries of each of the organization's webhook
+// subscriptions within window
+func (h *SupportHandler) ListWebhookStatus(c *gin.Context) {
+	window, ok := parseSupportWindow(c)
+	if !ok {
+		return
+	}
+	orgID, ok := h.loadSupportOrg(c)
+	if !ok {
+		return
+	}
+
+	since := time.Now().Add(-window)
+	statuses, err := h.db.ListWebhookStatus(orgID, since)
+	if err != nil {
+		logrus.WithError(err).WithField("org_id", orgID).Error("Failed to summarize webhook deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"org_id":        orgID,
+		"since":         since.UTC(),
+		"subscriptions": statuses,
+		"count":         len(statuses),
+	})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/organization"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/inscenium/inscenium/control/api/internal/support"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSupportStore struct {
+	bookings  map[string]*models.Booking
+	events    map[string]*models.ExposureEvent
+	surfaces  map[string]*models.Surface
+	campaigns map[string]*campaign.Campaign
+	history   map[string][]booking.Event
+	errors    map[string][]support.Error
+	webhooks  map[string][]support.WebhookStatus
+	since     time.Time
+}
+
+func (m *MockSupportStore) GetPlacementBooking(scope tenant.Scope, bookingID string) (*models.Booking, error) {
+	return m.bookings[bookingID], nil
+}
+
+func (m *MockSupportStore) GetExposureEvent(eventID string) (*models.ExposureEvent, error) {
+	return m.events[eventID], nil
+}
+
+func (m *MockSupportStore) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
+	return m.surfaces[surfaceID], nil
+}
+
+func (m *MockSupportStore) GetCampaign(campaignID string) (*campaign.Campaign, error) {
+	return m.campaigns[campaignID], nil
+}
+
+func (m *MockSupportStore) GetAdvertiser(advertiserID string) (*campaign.Advertiser, error) {
+	return nil, nil
+}
+
+func (m *MockSupportStore) GetOrganization(orgID string) (*organization.Organization, error) {
+	if orgID != "org_1" {
+		return nil, nil
+	}
+	return &organization.Organization{ID: orgID}, nil
+}
+
+func (m *MockSupportStore) ListBookingEvents(bookingID string) ([]booking.Event, error) {
+	return m.history[bookingID], nil
+}
+
+func (m *MockSupportStore) GetExposureEvents(scope tenant.Scope, bookingID string, page pagination.Page) ([]models.ExposureEvent, error) {
+	events := []models.ExposureEvent{}
+	for _, e := range m.events {
+		if e.BookingID == bookingID && len(events) < page.Fetch() {
+			events = append(events, *e)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockSupportStore) ListRenderJobs(bookingID string, limit, offset int) ([]*render.Job, error) {
+	return []*render.Job{}, nil
+}
+
+func (m *MockSupportStore) ListBookingWebhookDeliveries(bookingID string, limit int) ([]webhook.Delivery, error) {
+	return []webhook.Delivery{}, nil
+}
+
+func (m *MockSupportStore) ListSurfaceBookings(surfaceID string, limit int) ([]models.Booking, error) {
+	bookings := []models.Booking{}
+	for _, b := range m.bookings {
+		if b.SurfaceID == surfaceID {
+			bookings = append(bookings, *b)
+		}
+	}
+	return bookings, nil
+}
+
+func (m *MockSupportStore) ListViewerExposureEvents(viewerID string, limit int) ([]models.ExposureEvent, error) {
+	events := []models.ExposureEvent{}
+	for _, e := range m.events {
+		if e.ViewerID == viewerID {
+			events = append(events, *e)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockSupportStore) ListOrgErrors(orgID string, since time.Time, limit int) ([]support.Error, error) {
+	m.since = since
+	errs := m.errors[orgID]
+	if len(errs) > limit {
+		errs = errs[:limit]
+	}
+	return errs, nil
+}
+
+func (m *MockSupportStore) ListWebhookStatus(orgID string, since time.Time) ([]support.WebhookStatus, error) {
+	m.since = since
+	return m.webhooks[orgID], nil
+}
+
+func TestSupportHandler_Lookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockSupportStore{
+		bookings: map[string]*models.Booking{
+			"booking_1": {BookingID: "booking_1", SurfaceID: "surface_1", CampaignID: "campaign_1", OrgID: "org_1"},
+		},
+		events: map[string]*models.ExposureEvent{
+			"event_1": {EventID: "event_1", BookingID: "booking_1", ViewerID: "viewer_1"},
+			"event_2": {EventID: "event_2", BookingID: "booking_1", ViewerID: "viewer_1"},
+		},
+		surfaces:  map[string]*models.Surface{"surface_1": {SurfaceID: "surface_1", TitleID: "title_1"}},
+		campaigns: map[string]*campaign.Campaign{"campaign_1": {CampaignID: "campaign_1"}},
+		history: map[string][]booking.Event{
+			"booking_1": {{BookingID: "booking_1", Sequence: 1, Type: booking.EventCreated}},
+		},
+	}
+	router := gin.New()
+	router.GET("/admin/support/lookup/:id", NewSupportHandler(store).Lookup)
+	lookup := func(path string) (*httptest.ResponseRecorder, []support.Match) {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Matches []support.Match `json:"matches"`
+		}
+		if resp.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		}
+		return resp, body.Matches
+	}
+
+	resp, matches := lookup("/admin/support/lookup/booking_1")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Len(t, matches, 1)
+	m := matches[0]
+	assert.Equal(t, support.KindBooking, m.Kind)
+	require.NotNil(t, m.Surface)
+	assert.Equal(t, "title_1", m.Surface.TitleID)
+	require.NotNil(t, m.Campaign)
+	assert.Len(t, m.History, 1)
+	assert.Len(t, m.Events, 2)
+
+	resp, matches = lookup("/admin/support/lookup/event_1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Len(t, matches, 1)
+	assert.Equal(t, support.KindEvent, matches[0].Kind)
+	require.NotNil(t, matches[0].Booking, "Should stitch the event's booking")
+	assert.Equal(t, "booking_1", matches[0].Booking.BookingID)
+	assert.NotNil(t, matches[0].Surface)
+
+	resp, matches = lookup("/admin/support/lookup/surface_1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Len(t, matches, 1)
+	assert.Equal(t, support.KindSurface, matches[0].Kind)
+	assert.Len(t, matches[0].Bookings, 1)
+
+	resp, matches = lookup("/admin/support/lookup/viewer_1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Len(t, matches, 1)
+	assert.Equal(t, support.KindViewer, matches[0].Kind)
+	assert.Len(t, matches[0].Events, 2)
+
+	resp, _ = lookup("/admin/support/lookup/booking_1?kind=viewer")
+	assert.Equal(t, http.StatusNotFound, resp.Code, "Should only look up the kind asked for")
+	resp, _ = lookup("/admin/support/lookup/booking_1?kind=campaign")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, _ = lookup("/admin/support/lookup/unknown")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestSupportHandler_Organizations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockSupportStore{
+		errors: map[string][]support.Error{"org_1": {
+			{Source: support.SourceWebhookDelivery, ID: "whdel_1", Message: "endpoint returned 500", OccurredAt: time.Now()},
+			{Source: support.SourceRenderJob, ID: "job_1", BookingID: "booking_1", Message: "timed out", OccurredAt: time.Now()},
+		}},
+		webhooks: map[string][]support.WebhookStatus{"org_1": {
+			{Subscription: &webhook.Subscription{ID: "whsub_1"}, Counts: map[string]int{webhook.DeliveryFailed: 2}},
+		}},
+	}
+	handler := NewSupportHandler(store)
+	router := gin.New()
+	router.GET("/admin/support/organizations/:org_id/errors", handler.ListOrgErrors)
+	router.GET("/admin/support/organizations/:org_id/webhooks", handler.ListWebhookStatus)
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	resp := get("/admin/support/organizations/org_1/errors?window=1h&limit=1")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var errs struct {
+		Errors []support.Error `json:"errors"`
+		Count  int             `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &errs))
+	assert.Equal(t, 1, errs.Count)
+	assert.Equal(t, support.SourceWebhookDelivery, errs.Errors[0].Source)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), store.since, time.Minute)
+
+	resp = get("/admin/support/organizations/org_1/webhooks")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var webhooks struct {
+		Subscriptions []support.WebhookStatus `json:"subscriptions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &webhooks))
+	require.Len(t, webhooks.Subscriptions, 1)
+	assert.Equal(t, 2, webhooks.Subscriptions[0].Counts[webhook.DeliveryFailed])
+	assert.WithinDuration(t, time.Now().Add(-support.DefaultErrorWindow), store.since, time.Minute)
+
+	for _, window := range []string{"soon", "-1h", "1000h"} {
+		assert.Equal(t, http.StatusBadRequest, get("/admin/support/organizations/org_1/errors?window="+window).Code, window)
+	}
+	assert.Equal(t, http.StatusNotFound, get("/admin/support/organizations/org_2/errors").Code)
+	assert.Equal(t, http.StatusNotFound, get("/admin/support/organizations/org_2/webhooks").Code)
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// SurfaceStore ingests surfaces and tracks their duplicates and freshness
+type SurfaceStore interface {
+	IngestSurfaces(batch *ingest.Batch, thresholds dedupe.Thresholds) (*ingest.Result, error)
+	ListDuplicateClusters(titleID string) ([]dedupe.Cluster, error)
+	MergeSurfaces(merge *dedupe.Merge) (*dedupe.MergeResult, error)
+	ListRevalidations(titleID string, limit int) ([]ingest.Revalidation, error)
+	RequestRevalidation(titleID string) (int, error)
+}
+
+// JobEnqueuer schedules background jobs
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, queue string, payload interface{}, opts jobqueue.EnqueueOptions) (string, error)
+}
+
+// SurfaceHandler handles surface ingestion from the vision pipeline and
+// deduplication of the surfaces it produces
+type SurfaceHandler struct {
+	db         SurfaceStore
+	jobs       JobEnqueuer
+	taxonomy   TaxonomyReader
+	thresholds dedupe.Thresholds
+}
+
+// NewSurfaceHandler creates a surface handler
+func NewSurfaceHandler(store SurfaceStore) *SurfaceHandler {
+	return &SurfaceHandler{db: store, thresholds: dedupe.DefaultThresholds()}
+}
+
+// SetJobQueue enables dedupe jobs, scheduled on request and after ingesting duplicates
+func (h *SurfaceHandler) SetJobQueue(jobs JobEnqueuer) {
+	h.jobs = jobs
+}
+
+// SetTaxonomy translates the caller's organization's surface types and
+// restriction categories into canonical terms on ingest
+func (h *SurfaceHandler) SetTaxonomy(store TaxonomyReader) {
+	h.taxonomy = store
+}
+
+// dedupeRequest is the body of POST /surfaces/dedupe
+type dedupeRequest struct {
+	TitleID string `json:"title_id"`
+}
+
+// Ingest handles POST /surfaces. Surfaces that duplicate another live surface
+// of the title are stored but reported in duplicate_warnings. Surface types
+// and restrictions may use the caller's organization's aliases.
+func (h *SurfaceHandler) Ingest(c *gin.Context) {
+	var batch ingest.Batch
+	if err := schema.BindJSON(c, &batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := batch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, ok := callerTaxonomy(c, h.taxonomy)
+	if !ok {
+		return
+	}
+	canonicalBatch(t, &batch)
+
+	result, err := h.db.IngestSurfaces(&batch, h.thresholds)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if errors.Is(err, ingest.ErrInvalidBatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to ingest surfaces")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"title_id":   result.TitleID,
+		"shots":      result.Shots,
+		"surfaces":   result.Surfaces,
+		"duplicates": len(result.DuplicateWarnings),
+	}).Info("Ingested surfaces")
+
+	response := gin.H{
+		"title_id":           result.TitleID,
+		"shots":              result.Shots,
+		"surfaces":           result.Surfaces,
+		"duplicate_warnings": result.DuplicateWarnings,
+	}
+	if len(result.DuplicateWarnings) > 0 {
+		if jobID := scheduleDedupe(c, h.jobs, result.TitleID); jobID != "" {
+			response["dedupe_job_id"] = jobID
+		}
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// scheduleDedupe schedules a dedupe job for a title after ingesting
+// duplicates and returns its ID. Ingestion already succeeded, so a
+// scheduling failure is only logged.
+func scheduleDedupe(c *gin.Context, jobs JobEnqueuer, titleID string) string {
+	if jobs == nil {
+		return ""
+	}
+	jobID, err := jobs.Enqueue(c.Request.Context(), dedupe.Queue, dedupe.Job{TitleID: titleID}, jobqueue.EnqueueOptions{})
+	if err != nil {
+		logrus.WithError(err).WithField("title_id", titleID).Warn("Failed to schedule surface dedupe")
+		return ""
+	}
+	return jobID
+}
+
+// ListDuplicates handles GET /surfaces/duplicates
+func (h *SurfaceHandler) ListDuplicates(c *gin.Context) {
+	titleID := c.Query("title_id")
+
+	clusters, err := h.db.ListDuplicateClusters(titleID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list duplicate surfaces")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":    clusters,
+		"total_count": len(clusters),
+		"title_id":    titleID,
+	})
+}
+
+// RunDedupe handles POST /surfaces/dedupe by scheduling a dedupe job for a title
+func (h *SurfaceHandler) RunDedupe(c *gin.Context) {
+	if h.jobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Background jobs are not available"})
+		return
+	}
+
+	var req dedupeRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TitleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title_id is required"})
+		return
+	}
+
+	jobID, err := h.jobs.Enqueue(c.Request.Context(), dedupe.Queue, dedupe.Job{TitleID: req.TitleID}, jobqueue.EnqueueOptions{})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to schedule surface dedupe")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":   jobID,
+		"queue":    dedupe.Queue,
+		"title_id": req.TitleID,
+	})
+}
+
+// Merge handles POST /surfaces/merge
+func (h *SurfaceHandler) Merge(c *gin.Context) {
+	var merge dedupe.Merge
+	if err := schema.BindJSON(c, &merge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := merge.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	merge.Actor = c.GetString("user_id")
+	merge.OrgID = c.GetString("org_id")
+
+	result, err := h.db.MergeSurfaces(&merge)
+	if errors.Is(err, db.ErrSurfaceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, dedupe.ErrInvalidMerge) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to merge surfaces")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":           "surface_merge",
+		"into_surface_id": result.IntoSurfaceID,
+		"merged":          result.Merged,
+		"bookings_moved":  result.BookingsMoved,
+		"user_id":         merge.Actor,
+		"org_id":          merge.OrgID,
+	}).Info("Merged duplicate surfaces")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListRevalidations handles GET /surfaces/revalidation: the surfaces the
+// pipeline should validate again, those with live bookings first
+func (h *SurfaceHandler) ListRevalidations(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+	titleID := c.Query("title_id")
+
+	queue, err := h.db.ListRevalidations(titleID, limit)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list surfaces due for re-validation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"surfaces":    queue,
+		"total_count": len(queue),
+		"title_id":    titleID,
+	})
+}
+
+// RequestRevalidation handles POST /titles/:title_id/revalidation by queueing
+// every surface of the title. Queued surfaces leave available inventory until
+// the pipeline ingests them again.
+func (h *SurfaceHandler) RequestRevalidation(c *gin.Context) {
+	titleID := c.Param("title_id")
+
+	queued, err := h.db.RequestRevalidation(titleID)
+	if errors.Is(err, db.ErrTitleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title not found"})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to queue surfaces for re-validation")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "surface_revalidation",
+		"title_id": titleID,
+		"queued":   queued,
+		"user_id":  c.GetString("user_id"),
+	}).Info("Queued surfaces for re-validation")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"title_id": titleID,
+		"queued":   queued,
+		"reason":   ingest.ReasonRequested,
+	})
+}
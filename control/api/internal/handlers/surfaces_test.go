@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSurfaceStore struct {
+	warnings      []dedupe.Warning
+	clusters      []dedupe.Cluster
+	ingested      *ingest.Batch
+	merged        *dedupe.Merge
+	revalidations []ingest.Revalidation
+	queuedTitle   string
+	ingestErr     error
+	mergeErr      error
+	shouldError   bool
+}
+
+func (m *MockSurfaceStore) IngestSurfaces(batch *ingest.Batch, thresholds dedupe.Thresholds) (*ingest.Result, error) {
+	if m.ingestErr != nil {
+		return nil, m.ingestErr
+	}
+	m.ingested = batch
+	return &ingest.Result{
+		TitleID:           batch.TitleID,
+		Shots:             len(batch.Shots),
+		Surfaces:          len(batch.Surfaces),
+		DuplicateWarnings: m.warnings,
+	}, nil
+}
+
+func (m *MockSurfaceStore) ListDuplicateClusters(titleID string) ([]dedupe.Cluster, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.clusters, nil
+}
+
+func (m *MockSurfaceStore) MergeSurfaces(merge *dedupe.Merge) (*dedupe.MergeResult, error) {
+	if m.mergeErr != nil {
+		return nil, m.mergeErr
+	}
+	m.merged = merge
+	return &dedupe.MergeResult{
+		IntoSurfaceID: merge.IntoSurfaceID,
+		Merged:        merge.SurfaceIDs,
+		BookingsMoved: []string{"booking_1"},
+	}, nil
+}
+
+func (m *MockSurfaceStore) ListRevalidations(titleID string, limit int) ([]ingest.Revalidation, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	queue := make([]ingest.Revalidation, 0)
+	for _, r := range m.revalidations {
+		if (titleID == "" || r.TitleID == titleID) && len(queue) < limit {
+			queue = append(queue, r)
+		}
+	}
+	return queue, nil
+}
+
+func (m *MockSurfaceStore) RequestRevalidation(titleID string) (int, error) {
+	if m.shouldError {
+		return 0, assert.AnError
+	}
+	if titleID != "1" {
+		return 0, db.ErrTitleNotFound
+	}
+	m.queuedTitle = titleID
+	return 3, nil
+}
+
+type MockJobEnqueuer struct {
+	queues      []string
+	jobs        []interface{}
+	shouldError bool
+}
+
+func (m *MockJobEnqueuer) Enqueue(ctx context.Context, queue string, payload interface{}, opts jobqueue.EnqueueOptions) (string, error) {
+	if m.shouldError {
+		return "", assert.AnError
+	}
+	m.queues = append(m.queues, queue)
+	m.jobs = append(m.jobs, payload)
+	return fmt.Sprintf("job_%d", len(m.jobs)), nil
+}
+
+func TestSurfaceHandler_Ingest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	square := [][2]float64{{0.1, 0.1}, {0.4, 0.1}, {0.4, 0.3}, {0.1, 0.3}}
+	batch := map[string]interface{}{
+		"title_id": "1",
+		"shots":    []map[string]interface{}{{"shot_id": "shot_001", "start_time": 0, "end_time": 12.5}},
+		"surfaces": []map[string]interface{}{{
+			"surface_id": "surface_101",
+			"shot_id":    "shot_001",
+			"start_time": 2.0,
+			"end_time":   9.5,
+			"polygon":    square,
+			"prs_score":  0.82,
+		}},
+	}
+	duplicate := dedupe.Warning{SurfaceID: "surface_101", DuplicateOf: "surface_001", GeometryIoU: 0.91, TimeOverlap: 1}
+
+	tests := []struct {
+		name           string
+		body           map[string]interface{}
+		warnings       []dedupe.Warning
+		ingestErr      error
+		expectedStatus int
+		expectedJobs   int
+		description    string
+	}{
+		{
+			name:           "no duplicates",
+			body:           batch,
+			expectedStatus: http.StatusCreated,
+			description:    "Should store the surfaces without scheduling a dedupe",
+		},
+		{
+			name:           "duplicate warning",
+			body:           batch,
+			warnings:       []dedupe.Warning{duplicate},
+			expectedStatus: http.StatusCreated,
+			expectedJobs:   1,
+			description:    "Should warn about the duplicate and schedule a dedupe for the title",
+		},
+		{
+			name: "polygon too small",
+			body: map[string]interface{}{
+				"title_id": "1",
+				"surfaces": []map[string]interface{}{{"surface_id": "surface_101", "shot_id": "shot_001", "polygon": square[:2]}},
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject polygons with fewer than 3 vertices",
+		},
+		{
+			name: "audio slot with a polygon",
+			body: map[string]interface{}{
+				"title_id": "1",
+				"surfaces": []map[string]interface{}{{"surface_id": "surface_101", "shot_id": "shot_001", "placement_type": "audio",
+					"start_time": 2.0, "end_time": 17.0, "polygon": square}},
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should not place audio slots on screen",
+		},
+		{
+			name: "loudness of a visual surface",
+			body: map[string]interface{}{
+				"title_id": "1",
+				"surfaces": []map[string]interface{}{{"surface_id": "surface_101", "shot_id": "shot_001", "polygon": square,
+					"loudness": map[string]interface{}{"target_lufs": -16, "tolerance_lu": 1, "max_true_peak_dbtp": -1}}},
+			},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should only constrain the loudness of audio slots",
+		},
+		{
+			name:           "unknown title",
+			body:           batch,
+			ingestErr:      db.ErrTitleNotFound,
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for an unknown title",
+		},
+		{
+			name:           "surface of another title",
+			body:           batch,
+			ingestErr:      fmt.Errorf("%w: surface surface_101 belongs to another title", ingest.ErrInvalidBatch),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should not move surfaces between titles",
+		},
+		{
+			name:           "store error",
+			body:           batch,
+			ingestErr:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockSurfaceStore{warnings: tt.warnings, ingestErr: tt.ingestErr}
+			jobs := &MockJobEnqueuer{}
+			handler := NewSurfaceHandler(store)
+			handler.SetJobQueue(jobs)
+			router := gin.New()
+			router.POST("/surfaces", handler.Ingest)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/surfaces", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			assert.Len(t, jobs.jobs, tt.expectedJobs, tt.description)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				Surfaces          int              `json:"surfaces"`
+				DuplicateWarnings []dedupe.Warning `json:"duplicate_warnings"`
+				DedupeJobID       string           `json:"dedupe_job_id"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, 1, response.Surfaces)
+			assert.Len(t, response.DuplicateWarnings, len(tt.warnings))
+			assert.Equal(t, "POLYGON((0.1 0.1, 0.4 0.1, 0.4 0.3, 0.1 0.3, 0.1 0.1))", store.ingested.Surfaces[0].WKT())
+			if tt.expectedJobs > 0 {
+				assert.Equal(t, "job_1", response.DedupeJobID)
+				assert.Equal(t, dedupe.Job{TitleID: "1"}, jobs.jobs[0])
+			}
+		})
+	}
+}
+
+func TestSurfaceHandler_IngestAudio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	slot := func(loudness string) string {
+		return `{"title_id": "1", "surfaces": [{"surface_id": "surface_201", "shot_id": "segment_004",
+			"placement_type": "audio", "start_time": 312.0, "end_time": 342.0, "surface_type": "host_read"` + loudness + `}]}`
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "audio slot",
+			body:           slot(`, "loudness": {"target_lufs": -16, "tolerance_lu": 1.5, "max_true_peak_dbtp": -1}`),
+			expectedStatus: http.StatusCreated,
+			description:    "Should store audio slots without a polygon",
+		},
+		{
+			name:           "unconstrained loudness",
+			body:           slot(""),
+			expectedStatus: http.StatusCreated,
+			description:    "Should accept audio slots without loudness constraints",
+		},
+		{
+			name:           "loudness out of range",
+			body:           slot(`, "loudness": {"target_lufs": 6, "tolerance_lu": 1.5, "max_true_peak_dbtp": -1}`),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject a target louder than full scale",
+		},
+		{
+			name: "empty slot",
+			body: `{"title_id": "1", "surfaces": [{"surface_id": "surface_201", "shot_id": "segment_004",
+				"placement_type": "audio", "start_time": 312.0, "end_time": 312.0}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject audio slots without a length",
+		},
+		{
+			name:           "unknown placement type",
+			body:           `{"title_id": "1", "surfaces": [{"surface_id": "surface_201", "shot_id": "segment_004", "placement_type": "haptic"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject unknown placement types",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockSurfaceStore{}
+			router := gin.New()
+			router.POST("/surfaces", NewSurfaceHandler(store).Ingest)
+
+			req := httptest.NewRequest(http.MethodPost, "/surfaces", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusCreated {
+				require.Len(t, store.ingested.Surfaces, 1)
+				assert.True(t, store.ingested.Surfaces[0].Audio())
+				assert.Empty(t, store.ingested.Surfaces[0].WKT(), "Audio slots have no geometry")
+			}
+		})
+	}
+}
+
+func TestSurfaceHandler_RunDedupe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		withQueue      bool
+		queueError     bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "scheduled",
+			body:           `{"title_id": "1"}`,
+			withQueue:      true,
+			expectedStatus: http.StatusAccepted,
+			description:    "Should schedule a dedupe job for the title",
+		},
+		{
+			name:           "missing title",
+			body:           `{}`,
+			withQueue:      true,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require title_id",
+		},
+		{
+			name:           "no job queue",
+			body:           `{"title_id": "1"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			description:    "Should return 503 without background jobs",
+		},
+		{
+			name:           "queue error",
+			body:           `{"title_id": "1"}`,
+			withQueue:      true,
+			queueError:     true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 when the job cannot be scheduled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSurfaceHandler(&MockSurfaceStore{})
+			jobs := &MockJobEnqueuer{shouldError: tt.queueError}
+			if tt.withQueue {
+				handler.SetJobQueue(jobs)
+			}
+			router := gin.New()
+			router.POST("/surfaces/dedupe", handler.RunDedupe)
+
+			req := httptest.NewRequest(http.MethodPost, "/surfaces/dedupe", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus == http.StatusAccepted {
+				require.Len(t, jobs.jobs, 1)
+				assert.Equal(t, dedupe.Queue, jobs.queues[0])
+				assert.Equal(t, dedupe.Job{TitleID: "1"}, jobs.jobs[0])
+			}
+		})
+	}
+}
+
+func TestSurfaceHandler_ListDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pairs := []dedupe.Pair{
+		{A: dedupe.Surface{SurfaceID: "surface_001", PRSScore: 0.7}, B: dedupe.Surface{SurfaceID: "surface_101", PRSScore: 0.8}, GeometryIoU: 0.9, TimeOverlap: 1},
+		{A: dedupe.Surface{SurfaceID: "surface_101", PRSScore: 0.8}, B: dedupe.Surface{SurfaceID: "surface_201", PRSScore: 0.6, LiveBookings: 1}, GeometryIoU: 0.7, TimeOverlap: 0.8},
+		{A: dedupe.Surface{SurfaceID: "surface_005"}, B: dedupe.Surface{SurfaceID: "surface_105"}, GeometryIoU: 0.95, TimeOverlap: 1},
+	}
+	store := &MockSurfaceStore{clusters: dedupe.Group("1", pairs, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))}
+	router := gin.New()
+	router.GET("/surfaces/duplicates", NewSurfaceHandler(store).ListDuplicates)
+
+	req := httptest.NewRequest(http.MethodGet, "/surfaces/duplicates?title_id=1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var response struct {
+		Clusters   []dedupe.Cluster `json:"clusters"`
+		TotalCount int              `json:"total_count"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	require.Equal(t, 2, response.TotalCount, "Should group transitively linked surfaces together")
+
+	// The booked surface is kept so merging moves no bookings; ties fall to PRS score, then ID
+	assert.Equal(t, "surface_005", response.Clusters[0].CanonicalSurfaceID)
+	assert.Len(t, response.Clusters[0].Members, 2)
+	assert.Equal(t, "surface_201", response.Clusters[1].CanonicalSurfaceID)
+	require.Len(t, response.Clusters[1].Members, 3)
+	assert.True(t, response.Clusters[1].Members[0].Canonical)
+	assert.Equal(t, "surface_101", response.Clusters[1].Members[1].SurfaceID)
+
+	store.shouldError = true
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestSurfaceHandler_Merge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mergeErr       error
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "merge",
+			body:           `{"into_surface_id": "surface_001", "surface_ids": ["surface_101", "surface_201"]}`,
+			expectedStatus: http.StatusOK,
+			description:    "Should merge the duplicates and report the moved bookings",
+		},
+		{
+			name:           "merge into itself",
+			body:           `{"into_surface_id": "surface_001", "surface_ids": ["surface_001"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject merging a surface into itself",
+		},
+		{
+			name:           "no duplicates",
+			body:           `{"into_surface_id": "surface_001", "surface_ids": []}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require surfaces to merge",
+		},
+		{
+			name:           "unknown surface",
+			body:           `{"into_surface_id": "surface_001", "surface_ids": ["surface_999"]}`,
+			mergeErr:       fmt.Errorf("%w: surface_999", db.ErrSurfaceNotFound),
+			expectedStatus: http.StatusNotFound,
+			description:    "Should return 404 for unknown surfaces",
+		},
+		{
+			name:           "already merged",
+			body:           `{"into_surface_id": "surface_001", "surface_ids": ["surface_101"]}`,
+			mergeErr:       fmt.Errorf("%w: surface surface_101 was already merged into surface_002", dedupe.ErrInvalidMerge),
+			expectedStatus: http.StatusConflict,
+			description:    "Should return 409 for surfaces that cannot be merged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockSurfaceStore{mergeErr: tt.mergeErr}
+			router := gin.New()
+			router.POST("/surfaces/merge", withOrg("user_1", "org_a"), NewSurfaceHandler(store).Merge)
+
+			req := httptest.NewRequest(http.MethodPost, "/surfaces/merge", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var result dedupe.MergeResult
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+			assert.Equal(t, []string{"surface_101", "surface_201"}, result.Merged)
+			assert.Equal(t, []string{"booking_1"}, result.BookingsMoved)
+			assert.Equal(t, "user_1", store.merged.Actor)
+			assert.Equal(t, "org_a", store.merged.OrgID)
+		})
+	}
+}
+
+func TestSurfaceHandler_Revalidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	requestedAt := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+	store := &MockSurfaceStore{revalidations: []ingest.Revalidation{
+		{SurfaceID: "surface_001", TitleID: "1", Reason: ingest.ReasonCutDelivered, LastValidatedAt: requestedAt.AddDate(0, -1, 0), RequestedAt: &requestedAt, LiveBookings: 2},
+		{SurfaceID: "surface_101", TitleID: "2", Reason: ingest.ReasonAge, LastValidatedAt: requestedAt.AddDate(-1, 0, 0)},
+	}}
+	handler := NewSurfaceHandler(store)
+	router := gin.New()
+	router.GET("/surfaces/revalidation", handler.ListRevalidations)
+	router.POST("/titles/:title_id/revalidation", handler.RequestRevalidation)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/surfaces/revalidation?title_id=1", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var listed struct {
+		Surfaces   []ingest.Revalidation `json:"surfaces"`
+		TotalCount int                   `json:"total_count"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	require.Equal(t, 1, listed.TotalCount)
+	assert.Equal(t, "surface_001", listed.Surfaces[0].SurfaceID)
+	assert.Equal(t, ingest.ReasonCutDelivered, listed.Surfaces[0].Reason)
+	assert.Equal(t, 2, listed.Surfaces[0].LiveBookings)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/surfaces/revalidation?limit=1", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	assert.Equal(t, 1, listed.TotalCount, "The limit should be applied")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/titles/1/revalidation", nil))
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	assert.JSONEq(t, `{"title_id":"1","queued":3,"reason":"requested"}`, resp.Body.String())
+	assert.Equal(t, "1", store.queuedTitle)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/titles/9/revalidation", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	store.shouldError = true
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/surfaces/revalidation", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/taxonomy"
+	"github.com/sirupsen/logrus"
+)
+
+// TaxonomyReader loads an organization's aliases of surface types and
+// restriction categories
+type TaxonomyReader interface {
+	GetTaxonomy(orgID string) (*taxonomy.Taxonomy, error)
+}
+
+// TaxonomyStore persists organizations' aliases
+type TaxonomyStore interface {
+	TaxonomyReader
+	SetTaxonomyAliases(orgID, kind string, aliases taxonomy.Aliases, updatedBy string) error
+}
+
+// TaxonomyHandler lets an organization use its own terms for surface types
+// and restriction categories
+type TaxonomyHandler struct {
+	db TaxonomyStore
+}
+
+// NewTaxonomyHandler creates a taxonomy handler
+func NewTaxonomyHandler(store TaxonomyStore) *TaxonomyHandler {
+	return &TaxonomyHandler{db: store}
+}
+
+// GetTaxonomy handles GET /taxonomy, the caller's organization's aliases
+func (h *TaxonomyHandler) GetTaxonomy(c *gin.Context) {
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.db.GetTaxonomy(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get taxonomy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// SetAliases handles PUT /taxonomy/:kind, replacing the caller's
+// organization's aliases of one kind. Stored surfaces keep their canonical
+// terms, so a new alias applies at once to everything the organization reads.
+func (h *TaxonomyHandler) SetAliases(c *gin.Context) {
+	orgID, ok := callerOrg(c)
+	if !ok {
+		return
+	}
+	kind := c.Param("kind")
+	if !taxonomy.ValidKind(kind) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "kind must be surface_type or restriction"})
+		return
+	}
+
+	var req struct {
+		Aliases taxonomy.Aliases `json:"aliases"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Aliases.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.SetTaxonomyAliases(orgID, kind, req.Aliases, c.GetString("user_id")); err != nil {
+		logrus.WithError(err).Error("Failed to save taxonomy aliases")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":   "taxonomy",
+		"kind":    kind,
+		"aliases": len(req.Aliases),
+		"user_id": c.GetString("user_id"),
+		"org_id":  orgID,
+	}).Info("Set taxonomy aliases")
+
+	t, err := h.db.GetTaxonomy(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read back taxonomy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// callerTaxonomy loads the aliases of the caller's organization, writing a
+// 500 response and returning false when they cannot be read. Callers without
+// an organization, and handlers without a store, use canonical terms.
+func callerTaxonomy(c *gin.Context, store TaxonomyReader) (*taxonomy.Taxonomy, bool) {
+	orgID := c.GetString("org_id")
+	if store == nil || orgID == "" {
+		return nil, true
+	}
+	t, err := store.GetTaxonomy(orgID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get taxonomy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return t, true
+}
+
+// canonicalBatch translates the surface types and restrictions of an
+// ingestion batch into canonical terms
+func canonicalBatch(t *taxonomy.Taxonomy, batch *ingest.Batch) {
+	if t.Empty() {
+		return
+	}
+	for i := range batch.Surfaces {
+		s := &batch.Surfaces[i]
+		s.SurfaceType = t.Canonical(taxonomy.KindSurfaceType, s.SurfaceType)
+		s.Restrictions = t.CanonicalTerms(taxonomy.KindRestriction, s.Restrictions)
+	}
+}
+
+// aliasSurfaces translates the surface types and restrictions of surfaces
+// into the organization's aliases
+func aliasSurfaces(t *taxonomy.Taxonomy, surfaces []models.Surface) {
+	if t.Empty() {
+		return
+	}
+	for i := range surfaces {
+		aliasSurface(t, &surfaces[i])
+	}
+}
+
+// aliasSurface translates one surface's terms into the organization's aliases.
+// Restrictions that are not a list of terms are left as they are.
+func aliasSurface(t *taxonomy.Taxonomy, s *models.Surface) {
+	if t.Empty() {
+		return
+	}
+	s.SurfaceType = t.Alias(taxonomy.KindSurfaceType, s.SurfaceType)
+
+	var restrictions []string
+	if len(s.Restrictions) == 0 || json.Unmarshal(s.Restrictions, &restrictions) != nil {
+		return
+	}
+	if encoded, err := json.Marshal(t.AliasTerms(taxonomy.KindRestriction, restrictions)); err == nil {
+		s.Restrictions = encoded
+	}
+}
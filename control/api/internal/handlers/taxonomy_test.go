@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/taxonomy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTaxonomyStore struct {
+	aliases     map[string]map[string]taxonomy.Aliases
+	updatedBy   string
+	shouldError bool
+}
+
+func (m *MockTaxonomyStore) GetTaxonomy(orgID string) (*taxonomy.Taxonomy, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return taxonomy.New(orgID, m.aliases[orgID]), nil
+}
+
+func (m *MockTaxonomyStore) SetTaxonomyAliases(orgID, kind string, aliases taxonomy.Aliases, updatedBy string) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	if m.aliases == nil {
+		m.aliases = make(map[string]map[string]taxonomy.Aliases)
+	}
+	if m.aliases[orgID] == nil {
+		m.aliases[orgID] = make(map[string]taxonomy.Aliases)
+	}
+	m.aliases[orgID][kind] = aliases
+	m.updatedBy = updatedBy
+	return nil
+}
+
+// publisherTaxonomy is a store where org_1 calls walls "backdrops" and
+// family-friendly placements "all-ages"
+func publisherTaxonomy() *MockTaxonomyStore {
+	return &MockTaxonomyStore{aliases: map[string]map[string]taxonomy.Aliases{
+		"org_1": {
+			taxonomy.KindSurfaceType: {"backdrop": "wall"},
+			taxonomy.KindRestriction: {"all-ages": "family-friendly"},
+		},
+	}}
+}
+
+func TestTaxonomyHandler_SetAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		kind           string
+		orgID          string
+		body           map[string]interface{}
+		shouldError    bool
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "set surface type aliases",
+			kind:           "surface_type",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{"backdrop": "wall", "tabletop": "table"}},
+			expectedStatus: http.StatusOK,
+			description:    "Should replace the organization's surface type aliases",
+		},
+		{
+			name:           "clear aliases",
+			kind:           "restriction",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{}},
+			expectedStatus: http.StatusOK,
+			description:    "Should allow removing every alias of a kind",
+		},
+		{
+			name:           "unknown kind",
+			kind:           "genre",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{"drama": "serious"}},
+			expectedStatus: http.StatusNotFound,
+			description:    "Should only alias surface types and restrictions",
+		},
+		{
+			name:           "two aliases of one term",
+			kind:           "surface_type",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{"backdrop": "wall", "partition": "wall"}},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject aliases that cannot be translated back",
+		},
+		{
+			name:           "alias shadows a canonical term",
+			kind:           "surface_type",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{"wall": "screen", "backdrop": "wall"}},
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject an alias that is also a canonical term",
+		},
+		{
+			name:           "no organization",
+			kind:           "surface_type",
+			body:           map[string]interface{}{"aliases": map[string]string{"backdrop": "wall"}},
+			expectedStatus: http.StatusForbidden,
+			description:    "Should require a token scoped to an organization",
+		},
+		{
+			name:           "store error",
+			kind:           "surface_type",
+			orgID:          "org_1",
+			body:           map[string]interface{}{"aliases": map[string]string{"backdrop": "wall"}},
+			shouldError:    true,
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockTaxonomyStore{shouldError: tt.shouldError}
+			handler := NewTaxonomyHandler(store)
+			router := gin.New()
+			router.Use(withOrg("user_1", tt.orgID))
+			router.PUT("/taxonomy/:kind", handler.SetAliases)
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPut, "/taxonomy/"+tt.kind, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response taxonomy.Taxonomy
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.orgID, response.OrgID)
+			assert.Len(t, response.Aliases, len(taxonomy.Kinds), "Every kind should be listed")
+			assert.Len(t, response.Aliases[tt.kind], len(tt.body["aliases"].(map[string]string)))
+			assert.Equal(t, "user_1", store.updatedBy)
+		})
+	}
+}
+
+func TestSurfaceHandler_IngestAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	square := [][2]float64{{0.1, 0.1}, {0.4, 0.1}, {0.4, 0.3}, {0.1, 0.3}}
+	batch := map[string]interface{}{
+		"title_id": "1",
+		"surfaces": []map[string]interface{}{{
+			"surface_id":   "surface_101",
+			"shot_id":      "shot_001",
+			"surface_type": "backdrop",
+			"restrictions": []string{"all-ages", "no-alcohol"},
+			"polygon":      square,
+		}},
+	}
+
+	tests := []struct {
+		name                 string
+		orgID                string
+		expectedType         string
+		expectedRestrictions []string
+		description          string
+	}{
+		{
+			name:                 "publisher aliases",
+			orgID:                "org_1",
+			expectedType:         "wall",
+			expectedRestrictions: []string{"family-friendly", "no-alcohol"},
+			description:          "Should store the canonical terms of the publisher's aliases",
+		},
+		{
+			name:                 "organization without aliases",
+			orgID:                "org_2",
+			expectedType:         "backdrop",
+			expectedRestrictions: []string{"all-ages", "no-alcohol"},
+			description:          "Should store terms as sent when the organization has no aliases",
+		},
+		{
+			name:                 "no organization",
+			expectedType:         "backdrop",
+			expectedRestrictions: []string{"all-ages", "no-alcohol"},
+			description:          "Should store terms as sent for callers without an organization",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockSurfaceStore{}
+			handler := NewSurfaceHandler(store)
+			handler.SetTaxonomy(publisherTaxonomy())
+			router := gin.New()
+			router.Use(withOrg("user_1", tt.orgID))
+			router.POST("/surfaces", handler.Ingest)
+
+			body, _ := json.Marshal(batch)
+			req := httptest.NewRequest(http.MethodPost, "/surfaces", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusCreated, resp.Code, tt.description)
+			require.NotNil(t, store.ingested)
+			require.Len(t, store.ingested.Surfaces, 1)
+			assert.Equal(t, tt.expectedType, store.ingested.Surfaces[0].SurfaceType, tt.description)
+			assert.Equal(t, tt.expectedRestrictions, store.ingested.Surfaces[0].Restrictions, tt.description)
+		})
+	}
+}
+
+func TestSGIHandler_OpportunityAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	surface := func() models.Surface {
+		return models.Surface{
+			SurfaceID:    "surface_001",
+			TitleID:      "title_001",
+			SurfaceType:  "wall",
+			Restrictions: json.RawMessage(`["family-friendly","no-alcohol"]`),
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		orgID                string
+		expectedType         string
+		expectedRestrictions []string
+		description          string
+	}{
+		{
+			name:                 "publisher aliases",
+			orgID:                "org_1",
+			expectedType:         "backdrop",
+			expectedRestrictions: []string{"all-ages", "no-alcohol"},
+			description:          "Should show the publisher its own terms",
+		},
+		{
+			name:                 "organization without aliases",
+			orgID:                "org_2",
+			expectedType:         "wall",
+			expectedRestrictions: []string{"family-friendly", "no-alcohol"},
+			description:          "Should show other organizations the canonical terms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opportunity := surface()
+			handler := &SGIHandler{db: &MockDB{
+				opportunities: []models.Surface{surface()},
+				opportunity:   &opportunity,
+			}}
+			handler.SetTaxonomy(publisherTaxonomy())
+			router := gin.New()
+			router.Use(withOrg("user_1", tt.orgID))
+			router.GET("/opportunities", handler.ListOpportunities)
+			router.GET("/opportunities/:surface_id", handler.GetOpportunity)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/opportunities", nil))
+			require.Equal(t, http.StatusOK, resp.Code, tt.description)
+
+			var list struct {
+				Opportunities []struct {
+					SurfaceType  string   `json:"surface_type"`
+					Restrictions []string `json:"restrictions"`
+				} `json:"opportunities"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+			require.Len(t, list.Opportunities, 1)
+			assert.Equal(t, tt.expectedType, list.Opportunities[0].SurfaceType, tt.description)
+			assert.Equal(t, tt.expectedRestrictions, list.Opportunities[0].Restrictions, tt.description)
+
+			resp = httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/opportunities/surface_001", nil))
+			require.Equal(t, http.StatusOK, resp.Code, tt.description)
+
+			var single struct {
+				SurfaceType  string   `json:"surface_type"`
+				Restrictions []string `json:"restrictions"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &single))
+			assert.Equal(t, tt.expectedType, single.SurfaceType, tt.description)
+			assert.Equal(t, tt.expectedRestrictions, single.Restrictions, tt.description)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/thumbnail"
+	"github.com/sirupsen/logrus"
+)
+
+// thumbnailMaxAge is how long responses for a URL without the current
+// version may be cached, so a replaced frame shows up within the hour
+const thumbnailMaxAge = 3600
+
+// ThumbnailStore reads the reference frames recorded for surfaces
+type ThumbnailStore interface {
+	GetSurfaceThumbnail(surfaceID string) (*thumbnail.Source, error)
+}
+
+// ThumbnailHandler serves resized surface thumbnails. Variants are created
+// on first request and kept in object storage next to the source frame.
+type ThumbnailHandler struct {
+	db      ThumbnailStore
+	objects storage.Store
+}
+
+// NewThumbnailHandler creates a thumbnail handler
+func NewThumbnailHandler(store ThumbnailStore, objects storage.Store) *ThumbnailHandler {
+	return &ThumbnailHandler{db: store, objects: objects}
+}
+
+// thumbnailETag identifies one variant of one version of a surface's frame
+func thumbnailETag(version string, width int) string {
+	return fmt.Sprintf(`"%s-%d"`, version, width)
+}
+
+// GetThumbnail handles GET /opportunities/:surface_id/thumbnail. The w query
+// parameter picks the width, rounded up to a served width. URLs carrying the
+// current version in v, as returned in thumbnail_url, are cached indefinitely.
+func (h *ThumbnailHandler) GetThumbnail(c *gin.Context) {
+	width := thumbnail.DefaultWidth
+	if value := c.Query("w"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "w must be a positive width in pixels"})
+			return
+		}
+		width = thumbnail.Fit(parsed)
+	}
+
+	surfaceID := c.Param("surface_id")
+	src, err := h.db.GetSurfaceThumbnail(surfaceID)
+	if err != nil {
+		logrus.WithError(err).WithField("surface_id", surfaceID).Error("Failed to get surface thumbnail")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if src == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	etag := thumbnailETag(src.Version, width)
+	c.Header("ETag", etag)
+	if c.Query("v") == src.Version {
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, max-age="+strconv.Itoa(thumbnailMaxAge))
+	}
+	if strings.TrimPrefix(c.GetHeader("If-None-Match"), "W/") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, err := h.variant(c, src, width)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"surface_id": surfaceID,
+			"version":    src.Version,
+			"width":      width,
+		}).Error("Failed to serve surface thumbnail")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.Data(http.StatusOK, "image/jpeg", data)
+}
+
+// variant reads a resized variant from object storage, creating it from the
+// source frame if this is its first request
+func (h *ThumbnailHandler) variant(c *gin.Context, src *thumbnail.Source, width int) ([]byte, error) {
+	ctx := c.Request.Context()
+	key := thumbnail.VariantKey(src.SurfaceID, src.Version, width)
+	if data, err := h.read(c, key); !errors.Is(err, storage.ErrNotFound) {
+		return data, err
+	}
+
+	source, err := h.read(c, thumbnail.SourceKey(src.SurfaceID, src.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail source: %w", err)
+	}
+	data, err := thumbnail.Resize(source, width)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.objects.Put(ctx, key, data, "image/jpeg"); err != nil {
+		// The variant is still served; the next request resizes again
+		logrus.WithError(err).WithField("key", key).Warn("Failed to cache thumbnail variant")
+	}
+	return data, nil
+}
+
+// read returns the whole of an object
+func (h *ThumbnailHandler) read(c *gin.Context, key string) ([]byte, error) {
+	object, err := h.objects.Get(c.Request.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return io.ReadAll(object)
+}
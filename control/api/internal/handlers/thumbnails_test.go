@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/thumbnail"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFrame encodes a width x height PNG reference frame
+func testFrame(t *testing.T, width, height int, fill color.Color) string {
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			frame.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, frame))
+	return buf.String()
+}
+
+func newThumbnailRouter(t *testing.T, store *MockPipelineStore) (*gin.Engine, storage.Store) {
+	objects, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	pipeline := NewPipelineHandler(store, webhook.NewVerifier([]string{testPipelineSecret}, webhook.DefaultTolerance))
+	pipeline.SetThumbnailStorage(objects)
+	thumbnails := NewThumbnailHandler(store, objects)
+
+	router := gin.New()
+	router.PUT("/webhooks/pipeline/surfaces/:surface_id/thumbnail", pipeline.ThumbnailCallback)
+	router.GET("/opportunities/:surface_id/thumbnail", thumbnails.GetThumbnail)
+	return router, objects
+}
+
+// uploadThumbnail signs and sends a reference frame for a surface
+func uploadThumbnail(router *gin.Engine, surfaceID, nonce, frame string) *httptest.ResponseRecorder {
+	req := signedCallback(testPipelineSecret, nonce, time.Now(), frame)
+	req.Method = http.MethodPut
+	req.URL.Path = "/webhooks/pipeline/surfaces/" + surfaceID + "/thumbnail"
+	req.Header.Set("Content-Type", "image/png")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestPipelineHandler_ThumbnailCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		surfaceID      string
+		frame          string
+		expectedStatus int
+		description    string
+	}{
+		{"reference frame", "surface_001", testFrame(t, 64, 36, color.White), http.StatusOK, "Should store the frame"},
+		{"unknown surface", "surface_404", testFrame(t, 64, 36, color.White), http.StatusNotFound, "Should return 404 for unknown surfaces"},
+		{"not an image", "surface_001", `{"frame":"base64"}`, http.StatusBadRequest, "Should refuse bodies that are not JPEG or PNG"},
+		{"empty body", "surface_001", "", http.StatusBadRequest, "Should refuse empty uploads"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _ := newThumbnailRouter(t, newMockPipelineStore())
+
+			resp := uploadThumbnail(router, tt.surfaceID, "nonce-1", tt.frame)
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, true, response["applied"])
+			assert.Equal(t, "1", response["title_id"])
+			assert.Equal(t, float64(64), response["width"])
+			assert.Contains(t, response["thumbnail_url"], "/api/v1/opportunities/surface_001/thumbnail?v=")
+		})
+	}
+}
+
+func TestPipelineHandler_ThumbnailReplaced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockPipelineStore()
+	router, objects := newThumbnailRouter(t, store)
+
+	require.Equal(t, http.StatusOK, uploadThumbnail(router, "surface_001", "nonce-1", testFrame(t, 64, 36, color.White)).Code)
+	first := store.thumbnails["surface_001"].Version
+	require.Equal(t, http.StatusOK, uploadThumbnail(router, "surface_001", "nonce-2", testFrame(t, 64, 36, color.Black)).Code)
+	second := store.thumbnails["surface_001"].Version
+
+	assert.NotEqual(t, first, second, "Should version frames by content")
+	_, err := objects.Get(context.Background(), thumbnail.SourceKey("surface_001", first))
+	assert.ErrorIs(t, err, storage.ErrNotFound, "Should delete the replaced frame")
+}
+
+func TestThumbnailHandler_GetThumbnail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockPipelineStore()
+	router, _ := newThumbnailRouter(t, store)
+	require.Equal(t, http.StatusOK, uploadThumbnail(router, "surface_001", "nonce-1", testFrame(t, 1920, 1080, color.RGBA{R: 200, A: 255})).Code)
+	version := store.thumbnails["surface_001"].Version
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedWidth  int
+		immutable      bool
+		description    string
+	}{
+		{"default width", "/opportunities/surface_001/thumbnail", http.StatusOK, thumbnail.DefaultWidth, false, "Should serve the default width"},
+		{"rounded width", "/opportunities/surface_001/thumbnail?w=500", http.StatusOK, 640, false, "Should round widths up to a served width"},
+		{"versioned", "/opportunities/surface_001/thumbnail?w=160&v=" + version, http.StatusOK, 160, true, "Should cache versioned URLs indefinitely"},
+		{"stale version", "/opportunities/surface_001/thumbnail?v=0000", http.StatusOK, thumbnail.DefaultWidth, false, "Should serve the current frame briefly cached"},
+		{"no thumbnail", "/opportunities/surface_404/thumbnail", http.StatusNotFound, 0, false, "Should return 404 for surfaces without a frame"},
+		{"invalid width", "/opportunities/surface_001/thumbnail?w=wide", http.StatusBadRequest, 0, false, "Should validate the width"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "image/jpeg", resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.immutable, resp.Header().Get("Cache-Control") == "private, max-age=31536000, immutable", tt.description)
+
+			img, err := jpeg.Decode(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedWidth, img.Bounds().Dx(), tt.description)
+			assert.Equal(t, tt.expectedWidth*1080/1920, img.Bounds().Dy(), "Should keep the aspect ratio")
+		})
+	}
+}
+
+func TestThumbnailHandler_NotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockPipelineStore()
+	router, _ := newThumbnailRouter(t, store)
+	require.Equal(t, http.StatusOK, uploadThumbnail(router, "surface_001", "nonce-1", testFrame(t, 64, 36, color.White)).Code)
+
+	first := httptest.NewRecorder()
+	router.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/opportunities/surface_001/thumbnail", nil))
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/opportunities/surface_001/thumbnail", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotModified, resp.Code, "Should honour the entity tag")
+	assert.Empty(t, resp.Body.Bytes())
+
+	img, err := jpeg.Decode(first.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 64, img.Bounds().Dx(), "Should not enlarge small frames")
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// UserHandler lets admins manage the users who sign in with a password
+type UserHandler struct {
+	db UserStore
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(store UserStore) *UserHandler {
+	return &UserHandler{db: store}
+}
+
+// loadUser fetches the user named in the path
+func (h *UserHandler) loadUser(c *gin.Context) (*user.User, bool) {
+	u, err := h.db.GetUser(c.Param("username"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if u == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return u, true
+}
+
+// CreateUser handles POST /admin/users. Users created without a role are analysts.
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		OrgID    string `json:"org_id"`
+		Role     string `json:"role"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := user.ValidateUsername(req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := user.ValidatePassword(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = user.DefaultRole
+	}
+	if err := user.ValidateRole(req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := user.HashPassword(req.Password)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to hash password")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	u := &user.User{
+		Username:     req.Username,
+		OrgID:        req.OrgID,
+		Role:         req.Role,
+		PasswordHash: passwordHash,
+		CreatedBy:    c.GetString("user_id"),
+	}
+	if err := h.db.CreateUser(u); err != nil {
+		if errors.Is(err, db.ErrUserExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already in use"})
+			return
+		}
+		logrus.WithError(err).Error("Failed to create user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	created, ok := h.reload(c, u.Username)
+	if !ok {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "user",
+		"username": u.Username,
+		"org_id":   u.OrgID,
+		"role":     u.Role,
+		"user_id":  u.CreatedBy,
+	}).Info("Created user")
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListUsers handles GET /admin/users, only those of one organization with ?org_id=
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	users, err := h.db.ListUsers(c.Query("org_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list users")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"total_count": len(users),
+	})
+}
+
+// GetUser handles GET /admin/users/:username
+func (h *UserHandler) GetUser(c *gin.Context) {
+	u, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// UpdateUser handles PATCH /admin/users/:username. Fields left out are kept;
+// "unlock" clears failed logins and any lockout. A new role applies once the
+// user signs in again.
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var req struct {
+		OrgID    *string `json:"org_id"`
+		Role     *string `json:"role"`
+		Password *string `json:"password"`
+		Disabled *bool   `json:"disabled"`
+		Unlock   bool    `json:"unlock"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	if req.Disabled != nil && *req.Disabled && u.Username == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account"})
+		return
+	}
+	if req.Role != nil && *req.Role != u.Role && u.Username == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	if req.OrgID != nil {
+		u.OrgID = *req.OrgID
+	}
+	if req.Role != nil {
+		if err := user.ValidateRole(*req.Role); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		u.Role = *req.Role
+	}
+	if req.Password != nil {
+		if err := user.ValidatePassword(*req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		passwordHash, err := user.HashPassword(*req.Password)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to hash password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		u.PasswordHash = passwordHash
+	}
+	if req.Disabled != nil {
+		switch {
+		case *req.Disabled && u.DisabledAt == nil:
+			now := time.Now().UTC()
+			u.DisabledAt = &now
+		case !*req.Disabled:
+			u.DisabledAt = nil
+		}
+	}
+	// A new password also lifts a lockout, so a reset lets the user straight back in
+	if req.Unlock || req.Password != nil {
+		u.FailedLogins = 0
+		u.LockedUntil = nil
+	}
+
+	updated, err := h.db.UpdateUser(u)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to update user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	saved, ok := h.reload(c, u.Username)
+	if !ok {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":            "user",
+		"username":         u.Username,
+		"org_id":           u.OrgID,
+		"role":             u.Role,
+		"password_changed": req.Password != nil,
+		"disabled":         u.DisabledAt != nil,
+		"unlocked":         req.Unlock,
+		"user_id":          c.GetString("user_id"),
+	}).Info("Updated user")
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteUser handles DELETE /admin/users/:username. Tokens already issued to
+// the user stay valid until they expire.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	username := c.Param("username")
+	if username == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+
+	deleted, err := h.db.DeleteUser(username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":    "user",
+		"username": username,
+		"user_id":  c.GetString("user_id"),
+	}).Info("Deleted user")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"username": username,
+	})
+}
+
+// reload reads back a user just written, with the timestamps the database set
+func (h *UserHandler) reload(c *gin.Context, username string) (*user.User, bool) {
+	u, err := h.db.GetUser(username)
+	if err != nil || u == nil {
+		logrus.WithError(err).WithField("username", username).Error("Failed to read back user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return u, true
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUserRouter(handler *UserHandler) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "admin")
+		c.Next()
+	})
+	router.POST("/admin/users", handler.CreateUser)
+	router.GET("/admin/users", handler.ListUsers)
+	router.GET("/admin/users/:username", handler.GetUser)
+	router.PATCH("/admin/users/:username", handler.UpdateUser)
+	router.DELETE("/admin/users/:username", handler.DeleteUser)
+	return router
+}
+
+func sendUserRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestUserHandler_CreateUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	router := newUserRouter(NewUserHandler(store))
+
+	resp := sendUserRequest(router, http.MethodPost, "/admin/users", `{"username":"dana","password":"correct horse battery","org_id":"org_1"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.NotContains(t, resp.Body.String(), "password", "The password hash should never be returned")
+
+	created := store.users["dana"]
+	require.NotNil(t, created)
+	assert.Equal(t, "org_1", created.OrgID)
+	assert.Equal(t, user.RoleAnalyst, created.Role, "Users created without a role should be analysts")
+	assert.Equal(t, "admin", created.CreatedBy)
+	assert.NotEqual(t, "correct horse battery", created.PasswordHash)
+	assert.True(t, user.VerifyPassword("correct horse battery", created.PasswordHash))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"taken username", `{"username":"dana","password":"correct horse battery"}`, http.StatusConflict},
+		{"short password", `{"username":"erin","password":"hunter2"}`, http.StatusBadRequest},
+		{"invalid username", `{"username":"erin smith","password":"correct horse battery"}`, http.StatusBadRequest},
+		{"missing password", `{"username":"erin"}`, http.StatusBadRequest},
+		{"unknown role", `{"username":"erin","password":"correct horse battery","role":"owner"}`, http.StatusBadRequest},
+		{"advertiser", `{"username":"erin","password":"correct horse battery","role":"advertiser"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := sendUserRequest(router, http.MethodPost, "/admin/users", tt.body)
+			assert.Equal(t, tt.status, resp.Code, resp.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_ManageUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.addUser(t, "admin", "correct horse battery", "")
+	frank := store.addUser(t, "frank", "correct horse battery", "org_1")
+	store.addUser(t, "grace", "correct horse battery", "org_2")
+	lockedUntil := time.Now().Add(time.Minute)
+	frank.FailedLogins = 5
+	frank.LockedUntil = &lockedUntil
+	router := newUserRouter(NewUserHandler(store))
+
+	resp := sendUserRequest(router, http.MethodGet, "/admin/users?org_id=org_1", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var listed struct {
+		Users      []*user.User `json:"users"`
+		TotalCount int          `json:"total_count"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	require.Equal(t, 1, listed.TotalCount)
+	assert.Equal(t, "frank", listed.Users[0].Username)
+
+	assert.Equal(t, http.StatusNotFound, sendUserRequest(router, http.MethodGet, "/admin/users/nobody", "").Code)
+
+	// A password reset lifts the lockout
+	resp = sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"password":"a brand new passphrase","org_id":"org_3"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	frank = store.users["frank"]
+	assert.True(t, user.VerifyPassword("a brand new passphrase", frank.PasswordHash))
+	assert.Equal(t, "org_3", frank.OrgID)
+	assert.False(t, frank.Locked())
+	assert.Equal(t, 0, frank.FailedLogins)
+
+	resp = sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"disabled":true}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.NotNil(t, store.users["frank"].DisabledAt)
+	resp = sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"disabled":false}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Nil(t, store.users["frank"].DisabledAt)
+
+	resp = sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"role":"publisher"}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, user.RolePublisher, store.users["frank"].Role)
+
+	assert.Equal(t, http.StatusBadRequest, sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"password":"short"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, sendUserRequest(router, http.MethodPatch, "/admin/users/frank", `{"role":"owner"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, sendUserRequest(router, http.MethodPatch, "/admin/users/admin", `{"role":"advertiser"}`).Code,
+		"Admins should not demote themselves")
+	assert.Equal(t, http.StatusBadRequest, sendUserRequest(router, http.MethodPatch, "/admin/users/admin", `{"disabled":true}`).Code,
+		"Admins should not lock themselves out")
+	assert.Equal(t, http.StatusBadRequest, sendUserRequest(router, http.MethodDelete, "/admin/users/admin", "").Code)
+
+	assert.Equal(t, http.StatusOK, sendUserRequest(router, http.MethodDelete, "/admin/users/grace", "").Code)
+	assert.NotContains(t, store.users, "grace")
+	assert.Equal(t, http.StatusNotFound, sendUserRequest(router, http.MethodDelete, "/admin/users/grace", "").Code)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/watchlist"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchlistStore persists watchlists and books the surfaces on them
+type WatchlistStore interface {
+	ListWatchlists(ownerID string) ([]watchlist.Watchlist, error)
+	CreateWatchlist(w *watchlist.Watchlist) error
+	GetWatchlist(watchlistID string) (*watchlist.Watchlist, error)
+	DeleteWatchlist(watchlistID string) (bool, error)
+	SetWatchlistItem(watchlistID string, item *watchlist.Item) error
+	DeleteWatchlistItem(watchlistID, surfaceID string) (bool, error)
+	BookWatchlist(b *watchlist.Booking) error
+	GetPlacementOpportunity(surfaceID string) (*models.Surface, error)
+}
+
+// WatchlistHandler manages planners' shortlists of surfaces, compares
+// surfaces and turns shortlists into proposals and bookings
+type WatchlistHandler struct {
+	db       WatchlistStore
+	authz    *authz.Authorizer
+	taxonomy TaxonomyReader
+}
+
+// NewWatchlistHandler creates a watchlist handler
+func NewWatchlistHandler(store WatchlistStore) *WatchlistHandler {
+	return &WatchlistHandler{db: store}
+}
+
+// SetAuthorizer requires manage permission on the campaign a watchlist is booked for
+func (h *WatchlistHandler) SetAuthorizer(authorizer *authz.Authorizer) {
+	h.authz = authorizer
+}
+
+// SetTaxonomy shows compared surfaces' types in the caller's organization's aliases
+func (h *WatchlistHandler) SetTaxonomy(store TaxonomyReader) {
+	h.taxonomy = store
+}
+
+// watchlistRequest is the body of POST /watchlists
+type watchlistRequest struct {
+	Name string `json:"name"`
+}
+
+// watchlistItemRequest is the body of PUT /watchlists/:watchlist_id/surfaces/:surface_id
+type watchlistItemRequest struct {
+	Note string `json:"note"`
+}
+
+// convertRequest is the body of POST /watchlists/:watchlist_id/proposal and
+// POST /watchlists/:watchlist_id/bookings
+type convertRequest struct {
+	SurfaceIDs     []string `json:"surface_ids"` // Empty takes every surface on the watchlist
+	AdvertiserID   string   `json:"advertiser_id"`
+	CampaignID     string   `json:"campaign_id"`
+	BidAmountCPM   float64  `json:"bid_amount_cpm"`
+	MaxImpressions int      `json:"max_impressions"`
+}
+
+// ListWatchlists handles GET /watchlists
+func (h *WatchlistHandler) ListWatchlists(c *gin.Context) {
+	lists, err := h.db.ListWatchlists(c.GetString("user_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list watchlists")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"watchlists":  lists,
+		"total_count": len(lists),
+	})
+}
+
+// CreateWatchlist handles POST /watchlists
+func (h *WatchlistHandler) CreateWatchlist(c *gin.Context) {
+	var req watchlistRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w := &watchlist.Watchlist{
+		Name:    req.Name,
+		OwnerID: c.GetString("user_id"),
+		OrgID:   c.GetString("org_id"),
+		Items:   []watchlist.Item{},
+	}
+	if err := w.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if w.OwnerID == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Watchlists belong to a user"})
+		return
+	}
+	if err := h.db.CreateWatchlist(w); err != nil {
+		logrus.WithError(err).Error("Failed to create watchlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, w)
+}
+
+// GetWatchlist handles GET /watchlists/:watchlist_id
+func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
+	w, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// DeleteWatchlist handles DELETE /watchlists/:watchlist_id
+func (h *WatchlistHandler) DeleteWatchlist(c *gin.Context) {
+	w, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.DeleteWatchlist(w.WatchlistID); err != nil {
+		logrus.WithError(err).Error("Failed to delete watchlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetItem handles PUT /watchlists/:watchlist_id/surfaces/:surface_id. It adds
+// the surface, or replaces its note when it is already on the watchlist.
+func (h *WatchlistHandler) SetItem(c *gin.Context) {
+	var req watchlistItemRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item := &watchlist.Item{SurfaceID: c.Param("surface_id"), Note: req.Note}
+	if err := item.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.SetWatchlistItem(w.WatchlistID, item)
+	switch {
+	case errors.Is(err, watchlist.ErrWatchlistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return
+	case errors.Is(err, db.ErrSurfaceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Surface not found"})
+		return
+	case errors.Is(err, watchlist.ErrFull):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to save watchlist item")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// DeleteItem handles DELETE /watchlists/:watchlist_id/surfaces/:surface_id
+func (h *WatchlistHandler) DeleteItem(c *gin.Context) {
+	w, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteWatchlistItem(w.WatchlistID, c.Param("surface_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to delete watchlist item")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Surface is not on the watchlist"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Compare handles GET /opportunities/compare?surface_ids=a,b
+func (h *WatchlistHandler) Compare(c *gin.Context) {
+	surfaceIDs := splitIDs(c.Query("surface_ids"))
+	if len(surfaceIDs) < 2 || len(surfaceIDs) > watchlist.MaxCompare {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("surface_ids must list 2 to %d surfaces", watchlist.MaxCompare),
+		})
+		return
+	}
+
+	surfaces := make([]models.Surface, 0, len(surfaceIDs))
+	for _, surfaceID := range surfaceIDs {
+		surface, err := h.db.GetPlacementOpportunity(surfaceID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to get placement opportunity")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if surface == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Surface %s not found", surfaceID)})
+			return
+		}
+		surfaces = append(surfaces, *surface)
+	}
+
+	t, ok := callerTaxonomy(c, h.taxonomy)
+	if !ok {
+		return
+	}
+	aliasSurfaces(t, surfaces)
+	c.JSON(http.StatusOK, watchlist.Compare(surfaces, nil))
+}
+
+// CompareWatchlist handles GET /watchlists/:watchlist_id/compare. It compares
+// every surface on the watchlist, or those in surface_ids, with their notes.
+func (h *WatchlistHandler) CompareWatchlist(c *gin.Context) {
+	w, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	items, err := w.Select(splitIDs(c.Query("surface_ids")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(items) > watchlist.MaxCompare {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("the watchlist holds %d surfaces; pick at most %d with surface_ids", len(items), watchlist.MaxCompare),
+		})
+		return
+	}
+
+	surfaces := make([]models.Surface, 0, len(items))
+	notes := make(map[string]string, len(items))
+	for _, item := range items {
+		surfaces = append(surfaces, *item.Surface)
+		notes[item.SurfaceID] = item.Note
+	}
+
+	t, ok := callerTaxonomy(c, h.taxonomy)
+	if !ok {
+		return
+	}
+	aliasSurfaces(t, surfaces)
+	c.JSON(http.StatusOK, watchlist.Compare(surfaces, notes))
+}
+
+// Propose handles POST /watchlists/:watchlist_id/proposal. It returns the
+// booking requests the watchlist would make without booking anything.
+func (h *WatchlistHandler) Propose(c *gin.Context) {
+	w, items, terms, ok := h.convert(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist.Propose(w, items, terms))
+}
+
+// Book handles POST /watchlists/:watchlist_id/bookings. It books the
+// watchlist's surfaces, or those in surface_ids, at the same terms.
+func (h *WatchlistHandler) Book(c *gin.Context) {
+	w, items, terms, ok := h.convert(c)
+	if !ok {
+		return
+	}
+	if !h.authz.Authorize(c, labels.ResourceCampaign, terms.CampaignID, authz.PermissionManage) {
+		return
+	}
+
+	b := &watchlist.Booking{
+		WatchlistID: w.WatchlistID,
+		SurfaceIDs:  make([]string, 0, len(items)),
+		Terms:       terms,
+		CreatedBy:   c.GetString("user_id"),
+		OrgID:       c.GetString("org_id"),
+	}
+	for _, item := range items {
+		b.SurfaceIDs = append(b.SurfaceIDs, item.SurfaceID)
+	}
+
+	err := h.db.BookWatchlist(b)
+	switch {
+	case errors.Is(err, watchlist.ErrWatchlistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return
+	case errors.Is(err, watchlist.ErrNoSurfaces):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "skipped": b.Skipped})
+		return
+	case errors.Is(err, campaign.ErrNotBookable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to book watchlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":        "watchlist_booking",
+		"watchlist_id": b.WatchlistID,
+		"campaign_id":  b.CampaignID,
+		"bookings":     len(b.Bookings),
+		"skipped":      len(b.Skipped),
+		"user_id":      b.CreatedBy,
+		"org_id":       b.OrgID,
+	}).Info("Booked watchlist")
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// convert reads a proposal or booking request and selects the watchlist
+// items it covers. It writes the response when it returns false.
+func (h *WatchlistHandler) convert(c *gin.Context) (*watchlist.Watchlist, []watchlist.Item, watchlist.Terms, bool) {
+	var req convertRequest
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, watchlist.Terms{}, false
+	}
+	terms := watchlist.Terms{
+		AdvertiserID:   req.AdvertiserID,
+		CampaignID:     req.CampaignID,
+		BidAmountCPM:   req.BidAmountCPM,
+		MaxImpressions: req.MaxImpressions,
+	}
+	if err := terms.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, terms, false
+	}
+
+	w, ok := h.load(c)
+	if !ok {
+		return nil, nil, terms, false
+	}
+	items, err := w.Select(req.SurfaceIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, terms, false
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The watchlist has no surfaces"})
+		return nil, nil, terms, false
+	}
+	return w, items, terms, true
+}
+
+// load fetches the watchlist named in the path. Watchlists are private, so
+// another user's is reported as not found. It writes the response when it
+// returns false.
+func (h *WatchlistHandler) load(c *gin.Context) (*watchlist.Watchlist, bool) {
+	w, err := h.db.GetWatchlist(c.Param("watchlist_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get watchlist")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if w == nil || w.OwnerID != c.GetString("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return nil, false
+	}
+	return w, true
+}
+
+// splitIDs parses a comma-separated list of IDs, dropping blanks
+func splitIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
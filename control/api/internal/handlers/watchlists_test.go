@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/watchlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockWatchlistStore keeps watchlists over an in-memory surface catalog
+type MockWatchlistStore struct {
+	lists       map[string]*watchlist.Watchlist
+	surfaces    map[string]*models.Surface
+	heldBack    map[string]bool
+	booked      map[string]bool // Surfaces campaign_1 already has a live booking on
+	shouldError bool
+}
+
+func (m *MockWatchlistStore) ListWatchlists(ownerID string) ([]watchlist.Watchlist, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	lists := []watchlist.Watchlist{}
+	for _, w := range m.lists {
+		if w.OwnerID == ownerID {
+			summary := *w
+			summary.Items = nil
+			lists = append(lists, summary)
+		}
+	}
+	return lists, nil
+}
+
+func (m *MockWatchlistStore) CreateWatchlist(w *watchlist.Watchlist) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	w.WatchlistID = fmt.Sprintf("watchlist_%d", len(m.lists)+1)
+	w.CreatedAt = time.Now().UTC()
+	m.lists[w.WatchlistID] = w
+	return nil
+}
+
+func (m *MockWatchlistStore) GetWatchlist(watchlistID string) (*watchlist.Watchlist, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	w, ok := m.lists[watchlistID]
+	if !ok {
+		return nil, nil
+	}
+	for i := range w.Items {
+		w.Items[i].Surface = m.surfaces[w.Items[i].SurfaceID]
+	}
+	w.ItemCount = len(w.Items)
+	return w, nil
+}
+
+func (m *MockWatchlistStore) DeleteWatchlist(watchlistID string) (bool, error) {
+	_, ok := m.lists[watchlistID]
+	delete(m.lists, watchlistID)
+	return ok, nil
+}
+
+func (m *MockWatchlistStore) SetWatchlistItem(watchlistID string, item *watchlist.Item) error {
+	w, ok := m.lists[watchlistID]
+	if !ok {
+		return watchlist.ErrWatchlistNotFound
+	}
+	if _, ok := m.surfaces[item.SurfaceID]; !ok {
+		return db.ErrSurfaceNotFound
+	}
+	for i := range w.Items {
+		if w.Items[i].SurfaceID == item.SurfaceID {
+			w.Items[i].Note = item.Note
+			item.AddedAt = w.Items[i].AddedAt
+			return nil
+		}
+	}
+	if len(w.Items) >= watchlist.MaxItems {
+		return watchlist.ErrFull
+	}
+	item.AddedAt = time.Now().UTC()
+	w.Items = append(w.Items, *item)
+	return nil
+}
+
+func (m *MockWatchlistStore) DeleteWatchlistItem(watchlistID, surfaceID string) (bool, error) {
+	w := m.lists[watchlistID]
+	for i := range w.Items {
+		if w.Items[i].SurfaceID == surfaceID {
+			w.Items = append(w.Items[:i], w.Items[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockWatchlistStore) BookWatchlist(b *watchlist.Booking) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	b.Bookings = []watchlist.Booked{}
+	b.Skipped = []watchlist.Skip{}
+	for _, surfaceID := range b.SurfaceIDs {
+		switch {
+		case m.booked[surfaceID]:
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipBooked})
+		case m.heldBack[surfaceID]:
+			b.Skipped = append(b.Skipped, watchlist.Skip{SurfaceID: surfaceID, Reason: watchlist.SkipHeldBack})
+		default:
+			b.Bookings = append(b.Bookings, watchlist.Booked{BookingID: "booking_" + surfaceID, SurfaceID: surfaceID})
+		}
+	}
+	if len(b.Bookings) == 0 {
+		return watchlist.ErrNoSurfaces
+	}
+	return nil
+}
+
+func (m *MockWatchlistStore) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	return m.surfaces[surfaceID], nil
+}
+
+// newMockWatchlistStore has a watchlist of user_1 with three surfaces and one
+// of user_2
+func newMockWatchlistStore() *MockWatchlistStore {
+	area := 48000.0
+	surfaces := map[string]*models.Surface{
+		"surface_001": {SurfaceID: "surface_001", TitleID: "1", SurfaceType: "billboard", PRSScore: 90, VisibilityScore: 0.8, Duration: 6, AreaPixels: &area},
+		"surface_002": {SurfaceID: "surface_002", TitleID: "1", SurfaceType: "table", PRSScore: 70, VisibilityScore: 0.8, Duration: 12},
+		"surface_003": {SurfaceID: "surface_003", TitleID: "2", SurfaceType: "billboard", PRSScore: 80, VisibilityScore: 0.8, Duration: 9},
+		"surface_004": {SurfaceID: "surface_004", TitleID: "2", SurfaceType: "wall", PRSScore: 60, VisibilityScore: 0.5, Duration: 3},
+	}
+	return &MockWatchlistStore{
+		lists: map[string]*watchlist.Watchlist{
+			"watchlist_a": {WatchlistID: "watchlist_a", Name: "Q3 sports", OwnerID: "user_1", Items: []watchlist.Item{
+				{SurfaceID: "surface_001", Note: "Hero shot"},
+				{SurfaceID: "surface_002"},
+				{SurfaceID: "surface_003"},
+			}},
+			"watchlist_b": {WatchlistID: "watchlist_b", Name: "Other planner", OwnerID: "user_2", Items: []watchlist.Item{
+				{SurfaceID: "surface_004"},
+			}},
+		},
+		surfaces: surfaces,
+		heldBack: map[string]bool{"surface_003": true},
+		booked:   map[string]bool{},
+	}
+}
+
+func newWatchlistRouter(store *MockWatchlistStore, userID, orgID string) *gin.Engine {
+	handler := NewWatchlistHandler(store)
+	handler.SetAuthorizer(authz.NewAuthorizer(&MockGrantStore{owners: map[string]string{"campaign/campaign_1": "org_brand"}}))
+	router := gin.New()
+	router.Use(withOrg(userID, orgID))
+	router.GET("/watchlists", handler.ListWatchlists)
+	router.POST("/watchlists", handler.CreateWatchlist)
+	router.GET("/watchlists/:watchlist_id", handler.GetWatchlist)
+	router.DELETE("/watchlists/:watchlist_id", handler.DeleteWatchlist)
+	router.PUT("/watchlists/:watchlist_id/surfaces/:surface_id", handler.SetItem)
+	router.DELETE("/watchlists/:watchlist_id/surfaces/:surface_id", handler.DeleteItem)
+	router.GET("/watchlists/:watchlist_id/compare", handler.CompareWatchlist)
+	router.POST("/watchlists/:watchlist_id/proposal", handler.Propose)
+	router.POST("/watchlists/:watchlist_id/bookings", handler.Book)
+	router.GET("/opportunities/compare", handler.Compare)
+	return router
+}
+
+func serveWatchlist(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestWatchlistHandler_Items(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		description    string
+	}{
+		{"add surface", http.MethodPut, "/watchlists/watchlist_a/surfaces/surface_004", `{"note": "Cheap filler"}`, http.StatusOK, "Should add the surface with its note"},
+		{"update note", http.MethodPut, "/watchlists/watchlist_a/surfaces/surface_001", `{"note": "Hero shot, check clearance"}`, http.StatusOK, "Should replace the note of a listed surface"},
+		{"unknown surface", http.MethodPut, "/watchlists/watchlist_a/surfaces/surface_999", `{}`, http.StatusNotFound, "Should not list a surface that does not exist"},
+		{"long note", http.MethodPut, "/watchlists/watchlist_a/surfaces/surface_004", fmt.Sprintf(`{"note": "%s"}`, bytes.Repeat([]byte("x"), 2001)), http.StatusBadRequest, "Should bound notes"},
+		{"other user's watchlist", http.MethodPut, "/watchlists/watchlist_b/surfaces/surface_001", `{}`, http.StatusNotFound, "Should hide other users' watchlists"},
+		{"remove surface", http.MethodDelete, "/watchlists/watchlist_a/surfaces/surface_002", "", http.StatusNoContent, "Should remove the surface"},
+		{"remove unlisted surface", http.MethodDelete, "/watchlists/watchlist_a/surfaces/surface_004", "", http.StatusNotFound, "Should report a surface that is not listed"},
+		{"delete other user's watchlist", http.MethodDelete, "/watchlists/watchlist_b", "", http.StatusNotFound, "Should not delete other users' watchlists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serveWatchlist(newWatchlistRouter(newMockWatchlistStore(), "user_1", "org_brand"), tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+		})
+	}
+
+	t.Run("create and list", func(t *testing.T) {
+		store := newMockWatchlistStore()
+		router := newWatchlistRouter(store, "user_1", "org_brand")
+
+		resp := serveWatchlist(router, http.MethodPost, "/watchlists", `{"name": "Holiday push"}`)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var created watchlist.Watchlist
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, "user_1", created.OwnerID)
+
+		resp = serveWatchlist(router, http.MethodGet, "/watchlists", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var listed struct {
+			Watchlists []watchlist.Watchlist `json:"watchlists"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+		assert.Len(t, listed.Watchlists, 2, "Should list only the caller's watchlists")
+
+		resp = serveWatchlist(router, http.MethodPost, "/watchlists", `{"name": ""}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "Should require a name")
+	})
+}
+
+func TestWatchlistHandler_Compare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("watchlist", func(t *testing.T) {
+		router := newWatchlistRouter(newMockWatchlistStore(), "user_1", "org_brand")
+		resp := serveWatchlist(router, http.MethodGet, "/watchlists/watchlist_a/compare", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var comparison watchlist.Comparison
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &comparison))
+		require.Len(t, comparison.Surfaces, 3)
+		assert.Equal(t, "Hero shot", comparison.Surfaces[0].Note)
+
+		attrs := make(map[string]watchlist.Attribute)
+		for _, a := range comparison.Attributes {
+			attrs[a.Name] = a
+		}
+		prs := attrs["prs_score"]
+		assert.Equal(t, 1.0, *prs.Normalized[0], "Should score the best surface 1")
+		assert.Equal(t, 0.0, *prs.Normalized[1], "Should score the worst surface 0")
+		assert.Equal(t, 0.5, *prs.Normalized[2])
+		assert.Equal(t, "surface_001", prs.Best)
+		assert.Equal(t, "surface_002", attrs["duration"].Best)
+
+		visibility := attrs["visibility_score"]
+		assert.Equal(t, 1.0, *visibility.Normalized[1], "Should score equal values alike")
+		assert.Empty(t, visibility.Best)
+
+		area := attrs["area_pixels"]
+		assert.NotNil(t, area.Values[0])
+		assert.Nil(t, area.Values[1], "Should leave unmeasured values null")
+		assert.Nil(t, area.Normalized[1])
+	})
+
+	t.Run("surfaces", func(t *testing.T) {
+		router := newWatchlistRouter(newMockWatchlistStore(), "user_1", "org_brand")
+
+		resp := serveWatchlist(router, http.MethodGet, "/opportunities/compare?surface_ids=surface_002,surface_004", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var comparison watchlist.Comparison
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &comparison))
+		assert.Equal(t, "surface_004", comparison.Surfaces[1].SurfaceID)
+
+		resp = serveWatchlist(router, http.MethodGet, "/opportunities/compare?surface_ids=surface_002", "")
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "Should need two surfaces to compare")
+
+		resp = serveWatchlist(router, http.MethodGet, "/opportunities/compare?surface_ids=surface_002,surface_999", "")
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestWatchlistHandler_Convert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const terms = `"advertiser_id": "adv_1", "campaign_id": "campaign_1", "bid_amount_cpm": 12.5`
+
+	t.Run("proposal", func(t *testing.T) {
+		router := newWatchlistRouter(newMockWatchlistStore(), "user_1", "org_brand")
+		resp := serveWatchlist(router, http.MethodPost, "/watchlists/watchlist_a/proposal", `{`+terms+`, "surface_ids": ["surface_003", "surface_001"]}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var proposal watchlist.Proposal
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &proposal))
+		require.Len(t, proposal.Bookings, 2)
+		assert.Equal(t, "surface_001", proposal.Bookings[0].SurfaceID, "Should keep the watchlist's order")
+		assert.Equal(t, 12.5, proposal.Bookings[1].BidAmountCPM)
+		assert.Equal(t, "Hero shot", proposal.Notes["surface_001"])
+	})
+
+	tests := []struct {
+		name             string
+		orgID            string
+		body             string
+		booked           []string
+		expectedStatus   int
+		expectedBookings []string
+		expectedSkipped  []string
+		description      string
+	}{
+		{
+			name:             "whole watchlist",
+			body:             `{` + terms + `}`,
+			expectedStatus:   http.StatusCreated,
+			expectedBookings: []string{"surface_001", "surface_002"},
+			expectedSkipped:  []string{"surface_003"},
+			description:      "Should book every surface and skip held back ones",
+		},
+		{
+			name:            "nothing bookable",
+			body:            `{` + terms + `, "surface_ids": ["surface_001", "surface_003"]}`,
+			booked:          []string{"surface_001"},
+			expectedStatus:  http.StatusConflict,
+			expectedSkipped: []string{"surface_001", "surface_003"},
+			description:     "Should not book when every surface is skipped",
+		},
+		{
+			name:           "surface not listed",
+			body:           `{` + terms + `, "surface_ids": ["surface_004"]}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should only book surfaces on the watchlist",
+		},
+		{
+			name:           "no bid",
+			body:           `{"advertiser_id": "adv_1", "campaign_id": "campaign_1"}`,
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require a bid",
+		},
+		{
+			name:           "campaign of another org",
+			orgID:          "org_other",
+			body:           `{` + terms + `}`,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should require managing the campaign",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := tt.orgID
+			if orgID == "" {
+				orgID = "org_brand"
+			}
+			store := newMockWatchlistStore()
+			for _, surfaceID := range tt.booked {
+				store.booked[surfaceID] = true
+			}
+			resp := serveWatchlist(newWatchlistRouter(store, "user_1", orgID), http.MethodPost, "/watchlists/watchlist_a/bookings", tt.body)
+			require.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+			if tt.expectedBookings == nil && tt.expectedSkipped == nil {
+				return
+			}
+
+			var b watchlist.Booking
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &b))
+			booked := []string{}
+			for _, item := range b.Bookings {
+				booked = append(booked, item.SurfaceID)
+			}
+			skipped := []string{}
+			for _, skip := range b.Skipped {
+				skipped = append(skipped, skip.SurfaceID)
+			}
+			if tt.expectedBookings != nil {
+				assert.Equal(t, tt.expectedBookings, booked, tt.description)
+			}
+			assert.Equal(t, tt.expectedSkipped, skipped, tt.description)
+		})
+	}
+}
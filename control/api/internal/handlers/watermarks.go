@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/authz"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/watermark"
+	"github.com/sirupsen/logrus"
+)
+
+// maxVerifyBodyBytes bounds a verification request: every sample at its
+// largest, base64 encoded, and room for the rest of the JSON
+const maxVerifyBodyBytes = watermark.MaxSamples*watermark.MaxSampleBytes*4/3 + 64<<10
+
+// WatermarkStore resolves watermarks read from captured frames and logs
+// their verifications
+type WatermarkStore interface {
+	FindWatermark(scope tenant.Scope, watermarkID uint32) (*watermark.Mark, error)
+	RecordWatermarkVerification(v *watermark.Verification) error
+	ListWatermarkVerifications(bookingID string, limit, offset int) ([]watermark.Verification, error)
+}
+
+// WatermarkHandler verifies delivery from the watermarks of rendered
+// placements seen in captured frames
+type WatermarkHandler struct {
+	db  WatermarkStore
+	key string
+}
+
+// NewWatermarkHandler creates a watermark handler reading watermarks with
+// key, the key the render farm embeds them with. Verification is refused
+// when key is empty.
+func NewWatermarkHandler(store WatermarkStore, key string) *WatermarkHandler {
+	return &WatermarkHandler{db: store, key: key}
+}
+
+// verifyRequest carries captured-frame samples. Images are JPEG or PNG,
+// base64 encoded.
+type verifyRequest struct {
+	Source  string `json:"source" binding:"max=100"`
+	Samples []struct {
+		Image      []byte            `json:"image"`
+		CapturedAt time.Time         `json:"captured_at"`
+		Region     *watermark.Region `json:"region"`
+	} `json:"samples" binding:"required,min=1,max=10"`
+}
+
+// verificationResult is the outcome of one sample
+type verificationResult struct {
+	Index          int     `json:"index"`
+	Verified       bool    `json:"verified"`
+	VerificationID string  `json:"verification_id,omitempty"`
+	WatermarkID    uint32  `json:"watermark_id,omitempty"`
+	BookingID      string  `json:"booking_id,omitempty"`
+	JobID          string  `json:"job_id,omitempty"`
+	Confidence     float64 `json:"confidence,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// VerifyWatermarks handles POST /verifications/watermark. Each sample is
+// read on its own; a watermark whose booking is visible to the caller is
+// logged as a verification of that booking.
+func (h *WatermarkHandler) VerifyWatermarks(c *gin.Context) {
+	if h.key == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Watermark verification is not configured"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVerifyBodyBytes)
+	var req verifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := authz.Scope(c)
+	actor := authz.ActorFromContext(c)
+	results := make([]verificationResult, len(req.Samples))
+	verified := 0
+	for i, sample := range req.Samples {
+		results[i] = verificationResult{Index: i}
+		img, err := watermark.ReadSample(sample.Image, sample.Region)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		detection, err := watermark.Decode(img, h.key)
+		if err != nil {
+			results[i].Error = "No watermark found"
+			if errors.Is(err, watermark.ErrSampleTooSmall) {
+				results[i].Error = "Sample too small; capture at least " + strconv.Itoa(watermark.MinSampleSize) + " pixels a side"
+			}
+			continue
+		}
+
+		mark, err := h.db.FindWatermark(scope, detection.WatermarkID)
+		if err != nil {
+			logrus.WithError(err).WithField("watermark_id", detection.WatermarkID).Error("Failed to find watermark")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if mark == nil {
+			results[i].Error = "Unknown watermark"
+			continue
+		}
+
+		capturedAt := sample.CapturedAt
+		if capturedAt.IsZero() {
+			capturedAt = time.Now().UTC()
+		}
+		v := &watermark.Verification{
+			WatermarkID: mark.WatermarkID,
+			JobID:       mark.JobID,
+			BookingID:   mark.BookingID,
+			Source:      req.Source,
+			Confidence:  detection.Confidence,
+			CapturedAt:  capturedAt,
+			RecordedBy:  actor.UserID,
+		}
+		if err := h.db.RecordWatermarkVerification(v); err != nil {
+			logrus.WithError(err).WithField("booking_id", mark.BookingID).Error("Failed to record watermark verification")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"verification_id": v.VerificationID,
+			"booking_id":      v.BookingID,
+			"watermark_id":    v.WatermarkID,
+			"confidence":      v.Confidence,
+		}).Info("Verified placement watermark")
+		verified++
+		results[i] = verificationResult{
+			Index:          i,
+			Verified:       true,
+			VerificationID: v.VerificationID,
+			WatermarkID:    v.WatermarkID,
+			BookingID:      v.BookingID,
+			JobID:          v.JobID,
+			Confidence:     v.Confidence,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"verified_count": verified,
+		"failed_count":   len(results) - verified,
+		"results":        results,
+	})
+}
+
+// ListVerifications handles GET /bookings/:id/verifications
+func (h *WatermarkHandler) ListVerifications(c *gin.Context) {
+	bookingID := c.Param("id")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	verifications, err := h.db.ListWatermarkVerifications(bookingID, limit, offset)
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Error("Failed to list watermark verifications")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id":    bookingID,
+		"verifications": verifications,
+		"total_count":   len(verifications),
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
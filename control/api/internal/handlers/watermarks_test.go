@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/inscenium/inscenium/control/api/internal/watermark"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWatermarkKey = "test-watermark-key"
+
+// MockWatermarkStore keeps issued watermarks and verifications in memory
+type MockWatermarkStore struct {
+	marks         map[uint32]watermark.Mark
+	verifications []watermark.Verification
+	scope         tenant.Scope
+}
+
+func (m *MockWatermarkStore) FindWatermark(scope tenant.Scope, watermarkID uint32) (*watermark.Mark, error) {
+	m.scope = scope
+	mark, ok := m.marks[watermarkID]
+	if !ok {
+		return nil, nil
+	}
+	return &mark, nil
+}
+
+func (m *MockWatermarkStore) RecordWatermarkVerification(v *watermark.Verification) error {
+	v.VerificationID = "verification_" + v.BookingID
+	m.verifications = append(m.verifications, *v)
+	return nil
+}
+
+func (m *MockWatermarkStore) ListWatermarkVerifications(bookingID string, limit, offset int) ([]watermark.Verification, error) {
+	verifications := make([]watermark.Verification, 0)
+	for _, v := range m.verifications {
+		if v.BookingID == bookingID {
+			verifications = append(verifications, v)
+		}
+	}
+	return verifications, nil
+}
+
+// placementFrame renders a textured placement, watermarked with id unless it
+// is zero
+func placementFrame(width, height int, id uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), uint8((x^y)&0x3f + 90), 255})
+		}
+	}
+	if id != 0 {
+		watermark.Embed(img, id, testWatermarkKey, 0)
+	}
+	return img
+}
+
+func TestWatermarkHandler_VerifyWatermarks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &MockWatermarkStore{marks: map[uint32]watermark.Mark{
+		0xC0FFEE: {WatermarkID: 0xC0FFEE, JobID: "render_1", BookingID: "booking_123"},
+	}}
+	handler := NewWatermarkHandler(store, testWatermarkKey)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("org_id", "org_a"); c.Set("user_id", "vendor_key") })
+	router.POST("/verifications/watermark", handler.VerifyWatermarks)
+	router.GET("/bookings/:id/verifications", handler.ListVerifications)
+
+	var pngSample bytes.Buffer
+	require.NoError(t, png.Encode(&pngSample, placementFrame(320, 180, 0xC0FFEE)))
+
+	// A captured frame with the placement inside it, compressed on the way
+	frame := image.NewRGBA(image.Rect(0, 0, 640, 360))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.RGBA{20, 20, 20, 255}), image.Point{}, draw.Src)
+	draw.Draw(frame, image.Rect(100, 80, 420, 260), placementFrame(320, 180, 0xC0FFEE), image.Point{}, draw.Src)
+	var jpegFrame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegFrame, frame, &jpeg.Options{Quality: 80}))
+
+	var unmarked, unknown, small bytes.Buffer
+	require.NoError(t, png.Encode(&unmarked, placementFrame(320, 180, 0)))
+	require.NoError(t, png.Encode(&unknown, placementFrame(320, 180, 0xBAD)))
+	require.NoError(t, png.Encode(&small, placementFrame(40, 40, 0xC0FFEE)))
+
+	body, err := json.Marshal(gin.H{
+		"source": "acme-verify",
+		"samples": []gin.H{
+			{"image": pngSample.Bytes(), "captured_at": "2024-01-01T12:00:00Z"},
+			{"image": jpegFrame.Bytes(), "region": gin.H{"x": 100, "y": 80, "width": 320, "height": 180}},
+			{"image": unmarked.Bytes()},
+			{"image": unknown.Bytes()},
+			{"image": small.Bytes()},
+			{"image": []byte("not an image")},
+			{"image": pngSample.Bytes(), "region": gin.H{"x": 300, "y": 0, "width": 320, "height": 180}},
+		},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/verifications/watermark", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var response struct {
+		VerifiedCount int                  `json:"verified_count"`
+		FailedCount   int                  `json:"failed_count"`
+		Results       []verificationResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.VerifiedCount)
+	assert.Equal(t, 5, response.FailedCount)
+	require.Len(t, response.Results, 7)
+	assert.True(t, response.Results[0].Verified)
+	assert.Equal(t, "booking_123", response.Results[0].BookingID)
+	assert.Equal(t, "render_1", response.Results[0].JobID)
+	assert.Greater(t, response.Results[0].Confidence, watermark.MinConfidence)
+	assert.True(t, response.Results[1].Verified, "A placement cropped from a compressed frame should still read")
+	assert.Equal(t, "No watermark found", response.Results[2].Error)
+	assert.Equal(t, "Unknown watermark", response.Results[3].Error)
+	assert.Contains(t, response.Results[4].Error, "Sample too small")
+	assert.Contains(t, response.Results[5].Error, "JPEG or PNG")
+	assert.Contains(t, response.Results[6].Error, "region is outside")
+	assert.Equal(t, tenant.Of("org_a", ""), store.scope, "Watermarks should be resolved within the caller's scope")
+
+	require.Len(t, store.verifications, 2)
+	assert.Equal(t, "acme-verify", store.verifications[0].Source)
+	assert.Equal(t, "vendor_key", store.verifications[0].RecordedBy)
+	assert.Equal(t, "2024-01-01T12:00:00Z", store.verifications[0].CapturedAt.Format("2006-01-02T15:04:05Z07:00"))
+	assert.False(t, store.verifications[1].CapturedAt.IsZero(), "Samples without a capture time should be stamped on receipt")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/bookings/booking_123/verifications", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"total_count":2`)
+
+	req = httptest.NewRequest(http.MethodPost, "/verifications/watermark", bytes.NewBufferString(`{"samples": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "Should require samples")
+}
+
+func TestWatermarkHandler_VerifyWatermarksDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewWatermarkHandler(&MockWatermarkStore{}, "")
+	router := gin.New()
+	router.POST("/verifications/watermark", handler.VerifyWatermarks)
+
+	req := httptest.NewRequest(http.MethodPost, "/verifications/watermark", bytes.NewBufferString(`{"samples": [{"image": ""}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code, "Should refuse verification when no key is configured")
+}
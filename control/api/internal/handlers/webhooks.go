@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/query"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookStore persists webhook subscriptions and their delivery log
+type WebhookStore interface {
+	CreateWebhookSubscription(subscription *webhook.Subscription) error
+	GetWebhookSubscription(subscriptionID string) (*webhook.Subscription, error)
+	ListWebhookSubscriptions(orgID string) ([]*webhook.Subscription, error)
+	UpdateWebhookSubscription(subscription *webhook.Subscription) error
+	DeleteWebhookSubscription(subscriptionID string) error
+	ListWebhookDeliveries(subscriptionID, status string, page pagination.Page) ([]webhook.Delivery, error)
+}
+
+// WebhookHandler manages the caller's organization's webhook subscriptions
+type WebhookHandler struct {
+	db            WebhookStore
+	allowInsecure bool
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(store WebhookStore) *WebhookHandler {
+	return &WebhookHandler{db: store}
+}
+
+// SetAllowInsecure accepts plain HTTP subscription URLs, e.g. outside production
+func (h *WebhookHandler) SetAllowInsecure(allow bool) {
+	h.allowInsecure = allow
+}
+
+// loadSubscription fetches the subscription named in the path and checks it
+// belongs to the caller's organization
+func (h *WebhookHandler) loadSubscription(c *gin.Context) (*webhook.Subscription, bool) {
+	subscription, err := h.db.GetWebhookSubscription(c.Param("subscription_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get webhook subscription")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+
+	if subscription == nil || subscription.OrgID != c.GetString("org_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook subscription not found"})
+		return nil, false
+	}
+	return subscription, true
+}
+
+// CreateSubscription handles POST /webhooks. The signing secret is only
+// returned in this response.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req struct {
+		URL         string   `json:"url" binding:"required"`
+		EventTypes  []string `json:"event_types" binding:"required"`
+		Description string   `json:"description"`
+		Active      *bool    `json:"active"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := &webhook.Subscription{
+		OrgID:       c.GetString("org_id"),
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+		CreatedBy:   c.GetString("user_id"),
+	}
+	if err := subscription.Validate(h.allowInsecure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to generate webhook secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	subscription.Secret = secret
+
+	if err := h.db.CreateWebhookSubscription(subscription); err != nil {
+		logrus.WithError(err).Error("Failed to create webhook subscription")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook subscription"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":           "webhook_subscription",
+		"subscription_id": subscription.ID,
+		"user_id":         subscription.CreatedBy,
+		"org_id":          subscription.OrgID,
+		"event_types":     subscription.EventTypes,
+	}).Info("Created webhook subscription")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": subscription,
+		"secret":       secret,
+		"message":      "Store this secret now; it cannot be retrieved again",
+	})
+}
+
+// ListSubscriptions handles GET /webhooks
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.db.ListWebhookSubscriptions(c.GetString("org_id"))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list webhook subscriptions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"event_types":   webhook.EventTypes,
+		"total_count":   len(subscriptions),
+	})
+}
+
+// GetSubscription handles GET /webhooks/:subscription_id
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	subscription, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateSubscription handles PATCH /webhooks/:subscription_id. Fields left
+// out are kept; setting active to false pauses deliveries, and those already
+// queued fail without being sent.
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	subscription, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL         *string  `json:"url"`
+		EventTypes  []string `json:"event_types"`
+		Description *string  `json:"description"`
+		Active      *bool    `json:"active"`
+	}
+	if err := schema.BindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		subscription.EventTypes = req.EventTypes
+	}
+	if req.Description != nil {
+		subscription.Description = *req.Description
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := subscription.Validate(h.allowInsecure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.UpdateWebhookSubscription(subscription); err != nil {
+		logrus.WithError(err).Error("Failed to update webhook subscription")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":           "webhook_subscription",
+		"subscription_id": subscription.ID,
+		"user_id":         c.GetString("user_id"),
+		"event_types":     subscription.EventTypes,
+		"active":          subscription.Active,
+	}).Info("Updated webhook subscription")
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteSubscription handles DELETE /webhooks/:subscription_id
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	subscription, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteWebhookSubscription(subscription.ID); err != nil {
+		logrus.WithError(err).Error("Failed to delete webhook subscription")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":           "webhook_subscription",
+		"subscription_id": subscription.ID,
+		"user_id":         c.GetString("user_id"),
+	}).Info("Deleted webhook subscription")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"subscription_id": subscription.ID,
+	})
+}
+
+// ListDeliveries handles GET /webhooks/:subscription_id/deliveries, newest
+// first, optionally filtered by ?status=
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	subscription, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !validDeliveryStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter"})
+		return
+	}
+	page, ok := parsePage(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.db.ListWebhookDeliveries(subscription.ID, status, page)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if errors.Is(err, query.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list webhook deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	deliveries, next, prev := pagination.Window(deliveries, page, func(d webhook.Delivery) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(d.CreatedAt), ID: d.ID}
+	})
+
+	response := gin.H{
+		"subscription_id": subscription.ID,
+		"deliveries":      deliveries,
+		"count":           len(deliveries),
+	}
+	setCursors(response, next, prev)
+	c.JSON(http.StatusOK, response)
+}
+
+// validDeliveryStatus reports whether status names a delivery status
+func validDeliveryStatus(status string) bool {
+	for _, known := range webhook.DeliveryStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockWebhookStore keeps subscriptions and deliveries in memory
+type MockWebhookStore struct {
+	subscriptions map[string]*webhook.Subscription
+	deliveries    []webhook.Delivery
+	shouldError   bool
+}
+
+func newMockWebhookStore() *MockWebhookStore {
+	return &MockWebhookStore{subscriptions: map[string]*webhook.Subscription{}}
+}
+
+func (m *MockWebhookStore) CreateWebhookSubscription(subscription *webhook.Subscription) error {
+	if m.shouldError {
+		return assert.AnError
+	}
+	subscription.ID = "whsub_1"
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = subscription.CreatedAt
+	stored := *subscription
+	m.subscriptions[subscription.ID] = &stored
+	return nil
+}
+
+func (m *MockWebhookStore) GetWebhookSubscription(subscriptionID string) (*webhook.Subscription, error) {
+	if m.shouldError {
+		return nil, assert.AnError
+	}
+	subscription, ok := m.subscriptions[subscriptionID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (m *MockWebhookStore) ListWebhookSubscriptions(orgID string) ([]*webhook.Subscription, error) {
+	subscriptions := make([]*webhook.Subscription, 0)
+	for _, subscription := range m.subscriptions {
+		if subscription.OrgID == orgID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *MockWebhookStore) UpdateWebhookSubscription(subscription *webhook.Subscription) error {
+	stored := *subscription
+	m.subscriptions[subscription.ID] = &stored
+	return nil
+}
+
+func (m *MockWebhookStore) DeleteWebhookSubscription(subscriptionID string) error {
+	delete(m.subscriptions, subscriptionID)
+	return nil
+}
+
+func (m *MockWebhookStore) ListWebhookDeliveries(subscriptionID, status string, page pagination.Page) ([]webhook.Delivery, error) {
+	deliveries := make([]webhook.Delivery, 0)
+	for _, delivery := range m.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// addSubscription stores an active subscription of orgID
+func (m *MockWebhookStore) addSubscription(subscriptionID, orgID string, eventTypes ...string) {
+	m.subscriptions[subscriptionID] = &webhook.Subscription{
+		ID:         subscriptionID,
+		OrgID:      orgID,
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: eventTypes,
+		Active:     true,
+		Secret:     "whsec_test",
+	}
+}
+
+func TestWebhookHandler_CreateSubscription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		allowInsecure  bool
+		store          *MockWebhookStore
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:           "valid subscription",
+			body:           `{"url":"https://partner.example.com/hooks","event_types":["booking.confirmed","pacing.threshold"]}`,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusCreated,
+			description:    "Should create a subscription and return its secret once",
+		},
+		{
+			name:           "unknown event type",
+			body:           `{"url":"https://partner.example.com/hooks","event_types":["booking.deleted"]}`,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should reject event types that do not exist",
+		},
+		{
+			name:           "no event types",
+			body:           `{"url":"https://partner.example.com/hooks","event_types":[]}`,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require at least one event type",
+		},
+		{
+			name:           "relative url",
+			body:           `{"url":"/hooks","event_types":["booking.cancelled"]}`,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require an absolute URL",
+		},
+		{
+			name:           "plain http",
+			body:           `{"url":"http://partner.example.com/hooks","event_types":["booking.cancelled"]}`,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusBadRequest,
+			description:    "Should require https in production",
+		},
+		{
+			name:           "plain http outside production",
+			body:           `{"url":"http://localhost:9000/hooks","event_types":["booking.cancelled"]}`,
+			allowInsecure:  true,
+			store:          newMockWebhookStore(),
+			expectedStatus: http.StatusCreated,
+			description:    "Should accept http when insecure URLs are allowed",
+		},
+		{
+			name:           "store error",
+			body:           `{"url":"https://partner.example.com/hooks","event_types":["exposure.milestone"]}`,
+			store:          &MockWebhookStore{shouldError: true},
+			expectedStatus: http.StatusInternalServerError,
+			description:    "Should return 500 on store error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWebhookHandler(tt.store)
+			handler.SetAllowInsecure(tt.allowInsecure)
+			router := gin.New()
+			router.POST("/webhooks", withOrg("user_1", "org_a"), handler.CreateSubscription)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code, tt.description)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Subscription map[string]interface{} `json:"subscription"`
+					Secret       string                 `json:"secret"`
+				}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.True(t, strings.HasPrefix(response.Secret, webhook.SecretPrefix))
+				assert.NotContains(t, response.Subscription, "secret", "The secret should only be returned beside the subscription")
+				assert.Equal(t, true, response.Subscription["active"])
+
+				stored := tt.store.subscriptions["whsub_1"]
+				require.NotNil(t, stored)
+				assert.Equal(t, "org_a", stored.OrgID)
+				assert.Equal(t, response.Secret, stored.Secret)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_OtherOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockWebhookStore()
+	store.addSubscription("whsub_b", "org_b", webhook.EventBookingCancelled)
+	handler := NewWebhookHandler(store)
+
+	router := gin.New()
+	router.Use(withOrg("user_1", "org_a"))
+	router.GET("/webhooks", handler.ListSubscriptions)
+	router.GET("/webhooks/:subscription_id", handler.GetSubscription)
+	router.PATCH("/webhooks/:subscription_id", handler.UpdateSubscription)
+	router.DELETE("/webhooks/:subscription_id", handler.DeleteSubscription)
+	router.GET("/webhooks/:subscription_id/deliveries", handler.ListDeliveries)
+
+	for _, request := range []struct{ method, path, body string }{
+		{http.MethodGet, "/webhooks/whsub_b", ""},
+		{http.MethodPatch, "/webhooks/whsub_b", `{"active":false}`},
+		{http.MethodDelete, "/webhooks/whsub_b", ""},
+		{http.MethodGet, "/webhooks/whsub_b/deliveries", ""},
+	} {
+		req := httptest.NewRequest(request.method, request.path, strings.NewReader(request.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code, "%s %s should not reach another organization's subscription", request.method, request.path)
+	}
+	assert.True(t, store.subscriptions["whsub_b"].Active)
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var response struct {
+		Subscriptions []webhook.Subscription `json:"subscriptions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Empty(t, response.Subscriptions)
+}
+
+func TestWebhookHandler_UpdateSubscription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		check          func(t *testing.T, subscription *webhook.Subscription)
+	}{
+		{
+			name:           "pause",
+			body:           `{"active":false}`,
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, subscription *webhook.Subscription) {
+				assert.False(t, subscription.Active)
+				assert.Equal(t, []string{webhook.EventBookingConfirmed}, subscription.EventTypes, "Fields left out should be kept")
+			},
+		},
+		{
+			name:           "change event types",
+			body:           `{"event_types":["booking.cancelled","booking.completed"]}`,
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, subscription *webhook.Subscription) {
+				assert.Equal(t, []string{webhook.EventBookingCancelled, webhook.EventBookingCompleted}, subscription.EventTypes)
+				assert.True(t, subscription.Active)
+			},
+		},
+		{
+			name:           "duplicate event type",
+			body:           `{"event_types":["booking.cancelled","booking.cancelled"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid url",
+			body:           `{"url":"ftp://partner.example.com"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockWebhookStore()
+			store.addSubscription("whsub_a", "org_a", webhook.EventBookingConfirmed)
+			handler := NewWebhookHandler(store)
+
+			router := gin.New()
+			router.PATCH("/webhooks/:subscription_id", withOrg("user_1", "org_a"), handler.UpdateSubscription)
+
+			req := httptest.NewRequest(http.MethodPatch, "/webhooks/whsub_a", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.check != nil {
+				tt.check(t, store.subscriptions["whsub_a"])
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_ListDeliveries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockWebhookStore()
+	store.addSubscription("whsub_a", "org_a", webhook.EventPacingThreshold)
+	now := time.Now()
+	store.deliveries = []webhook.Delivery{
+		{ID: "whdel_2", SubscriptionID: "whsub_a", EventID: "evt_2", EventType: webhook.EventPacingThreshold, Payload: json.RawMessage(`{}`), Status: webhook.DeliveryFailed, Attempts: 8, ResponseStatus: 500, LastError: "endpoint returned 500", CreatedAt: now},
+		{ID: "whdel_1", SubscriptionID: "whsub_a", EventID: "evt_1", EventType: webhook.EventPacingThreshold, Payload: json.RawMessage(`{}`), Status: webhook.DeliveryDelivered, Attempts: 1, ResponseStatus: 204, CreatedAt: now.Add(-time.Minute)},
+	}
+	handler := NewWebhookHandler(store)
+
+	router := gin.New()
+	router.GET("/webhooks/:subscription_id/deliveries", withOrg("user_1", "org_a"), handler.ListDeliveries)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{name: "all", query: "", expectedStatus: http.StatusOK, expectedIDs: []string{"whdel_2", "whdel_1"}},
+		{name: "failed only", query: "?status=failed", expectedStatus: http.StatusOK, expectedIDs: []string{"whdel_2"}},
+		{name: "unknown status", query: "?status=lost", expectedStatus: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=not-a-cursor", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/webhooks/whsub_a/deliveries"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tt.expectedStatus, resp.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Deliveries []webhook.Delivery `json:"deliveries"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			ids := make([]string, 0, len(response.Deliveries))
+			for _, delivery := range response.Deliveries {
+				ids = append(ids, delivery.ID)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
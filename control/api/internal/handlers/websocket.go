@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/sendqueue"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// Push connection limits
+const (
+	// pushWriteTimeout bounds sending one message
+	pushWriteTimeout = 10 * time.Second
+	// pushMaxMessage bounds the size of a client's message
+	pushMaxMessage = 4 << 10
+	// defaultPushPingInterval is how often idle connections are pinged
+	defaultPushPingInterval = 30 * time.Second
+)
+
+// pushStream describes the push connections of one endpoint
+type pushStream struct {
+	name     string             // Labels the stream's metrics
+	protocol string             // WebSocket subprotocol clients must offer
+	resync   func() interface{} // Message telling a client that messages were dropped
+}
+
+// pushConn is a WebSocket connection the API pushes JSON messages over.
+// Messages are queued and written by a goroutine of their own, so pushing
+// never waits for a client.
+type pushConn struct {
+	conn   *websocket.Conn
+	stream pushStream
+	send   *sendqueue.Queue
+
+	closeOnce   sync.Once
+	closed      chan struct{}
+	closeCode   int
+	closeReason string
+	written     chan struct{}
+}
+
+// upgradePush answers the WebSocket handshake of c, speaking the stream's
+// protocol, and starts writing. Clients that answer nothing, not even a
+// ping, for two ping intervals are disconnected. ok is false if a response
+// was written instead.
+func upgradePush(c *gin.Context, stream pushStream, pingInterval time.Duration) (*pushConn, bool) {
+	if !websocket.IsUpgrade(c.Request) {
+		c.Header("Upgrade", "websocket")
+		c.JSON(http.StatusUpgradeRequired, gin.H{"error": c.Request.URL.Path + " must be opened as a WebSocket"})
+		return nil, false
+	}
+	conn, err := websocket.Upgrade(c.Writer, c.Request, []string{stream.protocol})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	conn.SetReadLimit(pushMaxMessage)
+	conn.SetIdleTimeout(2 * pingInterval)
+
+	p := &pushConn{
+		conn:    conn,
+		stream:  stream,
+		send:    sendqueue.New(stream.name, sendqueue.DefaultSize),
+		closed:  make(chan struct{}),
+		written: make(chan struct{}),
+	}
+	go p.write(pingInterval)
+	return p, true
+}
+
+// write sends queued messages and keepalive pings until the connection closes
+func (p *pushConn) write(pingInterval time.Duration) {
+	defer close(p.written)
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-p.closed:
+			p.conn.Close(p.closeCode, p.closeReason)
+			return
+		case message := <-p.send.Messages():
+			p.conn.SetWriteDeadline(time.Now().Add(pushWriteTimeout))
+			if p.send.Lagged() {
+				// Ahead of what is left of the queue, so the client reloads
+				// what it missed before applying newer messages
+				err = p.conn.WriteJSON(p.stream.resync())
+			}
+			if err == nil {
+				err = p.conn.WriteJSON(message)
+			}
+		case <-ticker.C:
+			p.conn.SetWriteDeadline(time.Now().Add(pushWriteTimeout))
+			err = p.conn.Ping()
+		}
+		if err != nil {
+			logrus.WithError(err).Debug("Failed to write to WebSocket")
+			p.close(websocket.CloseGoingAway, "")
+		}
+	}
+}
+
+// enqueue queues a message for the client and reports whether it was
+// queued. When the queue of a client too slow to keep up is full, its oldest
+// message is dropped to make room, and the client is sent the stream's
+// resync message so it reloads rather than silently missing messages.
+func (p *pushConn) enqueue(message interface{}) bool {
+	select {
+	case <-p.closed:
+		return false
+	default:
+	}
+	p.send.Push(message)
+	return true
+}
+
+// close asks the writer to close the connection with code
+func (p *pushConn) close(code int, reason string) {
+	p.closeOnce.Do(func() {
+		p.closeCode = code
+		p.closeReason = reason
+		close(p.closed)
+	})
+}
+
+// finish closes the connection once the client is gone and waits for the
+// writer to stop
+func (p *pushConn) finish() {
+	p.close(websocket.CloseNormal, "")
+	<-p.written
+}
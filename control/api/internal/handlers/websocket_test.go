@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/sendqueue"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushConn_DropsOldest(t *testing.T) {
+	stream := pushStream{
+		name:     "test",
+		protocol: "inscenium.test.v1",
+		resync:   func() interface{} { return map[string]string{"type": "resync"} },
+	}
+	dropped := func() float64 {
+		var m dto.Metric
+		require.NoError(t, metrics.PushFramesDropped.WithLabelValues(stream.name).Write(&m))
+		return m.GetCounter().GetValue()
+	}
+	before := dropped()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, []string{stream.protocol})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p := &pushConn{
+			conn:    conn,
+			stream:  stream,
+			send:    sendqueue.New(stream.name, 2),
+			closed:  make(chan struct{}),
+			written: make(chan struct{}),
+		}
+		// Queued before the writer starts, as for a client that stopped reading
+		for i := 1; i <= 3; i++ {
+			assert.True(t, p.enqueue(map[string]int{"seq": i}), "A full queue makes room rather than disconnecting")
+		}
+		go p.write(time.Minute)
+		conn.ReadMessage() // Until the client closes
+		p.finish()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil, []string{stream.protocol})
+	require.NoError(t, err)
+
+	var received []map[string]interface{}
+	for i := 0; i < 3; i++ {
+		var message map[string]interface{}
+		require.NoError(t, conn.ReadJSON(&message))
+		received = append(received, message)
+	}
+	conn.Close(websocket.CloseNormal, "")
+
+	assert.Equal(t, []map[string]interface{}{{"type": "resync"}, {"seq": 2.0}, {"seq": 3.0}}, received,
+		"The oldest message is dropped and the client told to resync before the rest")
+	assert.Equal(t, 1.0, dropped()-before)
+}
@@ -0,0 +1,71 @@
+package holdback
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHeldBack is returned when a booking would sell inventory reserved by a
+// hold-back rule
+var ErrHeldBack = errors.New("inventory is held back from sale")
+
+// maxReservedSurfaces bounds the surfaces one rule may reserve by ID
+const maxReservedSurfaces = 1000
+
+// Rule reserves part of a title's inventory from sale. Surfaces listed in
+// SurfaceIDs are never sold; Percent of the remaining surfaces is held back
+// by capping how many of them may be booked.
+type Rule struct {
+	TitleID    string    `json:"title_id"`
+	Percent    float64   `json:"percent"`
+	SurfaceIDs []string  `json:"surface_ids"`
+	Reason     string    `json:"reason,omitempty"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks that the rule is well formed
+func (r *Rule) Validate() error {
+	if r.Percent < 0 || r.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+	if r.Percent == 0 && len(r.SurfaceIDs) == 0 {
+		return fmt.Errorf("percent or surface_ids is required")
+	}
+	if len(r.SurfaceIDs) > maxReservedSurfaces {
+		return fmt.Errorf("at most %d surface_ids may be reserved", maxReservedSurfaces)
+	}
+	seen := make(map[string]bool, len(r.SurfaceIDs))
+	for _, id := range r.SurfaceIDs {
+		if id == "" {
+			return fmt.Errorf("surface_ids must not contain empty IDs")
+		}
+		if seen[id] {
+			return fmt.Errorf("surface %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Utilization reports how much of a title's sellable inventory is booked
+type Utilization struct {
+	TitleID          string  `json:"title_id"`
+	Percent          float64 `json:"percent"`
+	TotalSurfaces    int     `json:"total_surfaces"`
+	ReservedSurfaces int     `json:"reserved_surfaces"` // Reserved by ID
+	SellableCapacity int     `json:"sellable_capacity"` // Unreserved surfaces less percent, rounded down
+	BookedSurfaces   int     `json:"booked_surfaces"`   // Sellable surfaces with a live booking
+	ReservedBooked   int     `json:"reserved_booked"`   // Reserved surfaces booked before the rule
+	Utilization      float64 `json:"utilization"`       // BookedSurfaces / SellableCapacity
+	Exhausted        bool    `json:"exhausted"`
+}
+
+// Finish derives utilization and exhaustion from the counts
+func (u *Utilization) Finish() {
+	u.Exhausted = u.BookedSurfaces >= u.SellableCapacity
+	if u.SellableCapacity > 0 {
+		u.Utilization = float64(u.BookedSurfaces) / float64(u.SellableCapacity)
+	}
+}
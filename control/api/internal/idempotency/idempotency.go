@@ -0,0 +1,70 @@
+// Package idempotency lets clients retry POST requests without repeating
+// their effect. The first request carrying an Idempotency-Key reserves the
+// key; its response is stored and replayed to retries with the same key and
+// payload.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Header carries the client's key for a request
+const Header = "Idempotency-Key"
+
+// MaxKeyLength bounds the keys clients may send; UUIDs are 36 characters
+const MaxKeyLength = 255
+
+// ErrInvalidKey is returned for keys that are too long or not printable ASCII
+var ErrInvalidKey = errors.New("invalid idempotency key")
+
+// Response is a stored response replayed to retries
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Record is what a key holds: the fingerprint of the request that reserved
+// it and, once that request finished, its response
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"` // Nil while the request is in progress
+}
+
+// Store keeps idempotency records shared by every gateway instance
+type Store interface {
+	// Reserve claims key for the request with fingerprint until ttl passes.
+	// It returns nil if the key was free, or the record already under it.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	// Complete stores the response of the request that reserved key
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Release frees key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// CheckKey validates a client's key
+func CheckKey(key string) error {
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidKey, MaxKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return fmt.Errorf("%w: must be printable ASCII without spaces", ErrInvalidKey)
+		}
+	}
+	return nil
+}
+
+// Fingerprint identifies a request's method, URI and body, so a key reused
+// for a different request can be told apart from a retry
+func Fingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
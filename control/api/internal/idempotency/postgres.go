@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Database keeps idempotency records in Postgres for gateways without Redis
+type Database interface {
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// PostgresStore keeps idempotency records in the idempotency_keys table
+type PostgresStore struct {
+	db Database
+}
+
+// NewPostgresStore creates an idempotency store backed by Postgres
+func NewPostgresStore(db Database) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Reserve claims key unless it already holds an unexpired record
+func (p *PostgresStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	return p.db.ReserveIdempotencyKey(ctx, key, fingerprint, ttl)
+}
+
+// Complete stores the response under key for another ttl
+func (p *PostgresStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	return p.db.CompleteIdempotencyKey(ctx, key, record, ttl)
+}
+
+// Release deletes key
+func (p *PostgresStore) Release(ctx context.Context, key string) error {
+	return p.db.ReleaseIdempotencyKey(ctx, key)
+}
@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveScript returns the record under a key, or stores a new one and
+// returns nil if there is none.
+// KEYS: record. ARGV: new record, ttl in milliseconds.
+var reserveScript = redis.NewScript(`
+local record = redis.call('GET', KEYS[1])
+if record then
+	return record
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+// RedisStore keeps idempotency records in Redis, expiring with their ttl
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates an idempotency store backed by Redis
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func redisKey(key string) string {
+	return "idempotency:{" + key + "}"
+}
+
+// Reserve claims key unless it already holds a record
+func (r *RedisStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	reserved, err := json.Marshal(&Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+	value, err := reserveScript.Run(ctx, r.client, []string{redisKey(key)}, reserved, ttl.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("idempotency record for %s is corrupt: %w", key, err)
+	}
+	return &record, nil
+}
+
+// Complete stores the response under key for another ttl
+func (r *RedisStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+	if err := r.client.Set(ctx, redisKey(key), value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release deletes key
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, redisKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
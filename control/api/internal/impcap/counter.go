@@ -0,0 +1,235 @@
+package impcap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// errNotLoaded is returned when a booking's counter is not in Redis yet
+var errNotLoaded = errors.New("impression counter not loaded")
+
+// takeScript takes one impression, from the node's lease when it holds one
+// and from the shared pool otherwise. It returns nil when the counter is not
+// loaded, otherwise {1 if taken, 1 if from the lease}.
+// KEYS: remaining, served[, lease].
+var takeScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+if KEYS[3] and tonumber(redis.call('GET', KEYS[3]) or '0') > 0 then
+	redis.call('DECR', KEYS[3])
+	redis.call('INCR', KEYS[2])
+	return {1, 1}
+end
+if tonumber(value) < 1 then
+	return {0, 0}
+end
+redis.call('DECR', KEYS[1])
+redis.call('INCR', KEYS[2])
+return {1, 0}
+`)
+
+// giveBackScript returns an impression taken for a decision that was not recorded.
+// KEYS: remaining, served.
+var giveBackScript = redis.NewScript(`
+if not redis.call('GET', KEYS[1]) then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('DECR', KEYS[2])
+return 1
+`)
+
+// leaseScript moves up to the requested impressions from the shared pool to
+// a node's lease and extends it. It returns nil when the counter is not
+// loaded, otherwise {granted, held}.
+// KEYS: remaining, lease, nodes. ARGV: want, ttl ms, node, share.
+var leaseScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+local remaining = tonumber(value)
+local grant = math.min(tonumber(ARGV[1]), math.max(1, math.floor(remaining / tonumber(ARGV[4]))), remaining)
+if grant > 0 then
+	redis.call('DECRBY', KEYS[1], grant)
+	redis.call('INCRBY', KEYS[2], grant)
+end
+local held = tonumber(redis.call('GET', KEYS[2]) or '0')
+if held > 0 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+	redis.call('SADD', KEYS[3], ARGV[3])
+end
+return {grant, held}
+`)
+
+// releaseScript returns what a node's lease still holds to the shared pool.
+// KEYS: remaining, lease, nodes. ARGV: node.
+var releaseScript = redis.NewScript(`
+local held = tonumber(redis.call('GET', KEYS[2]) or '0')
+redis.call('DEL', KEYS[2])
+redis.call('SREM', KEYS[3], ARGV[1])
+if held > 0 and redis.call('GET', KEYS[1]) then
+	redis.call('INCRBY', KEYS[1], held)
+end
+return held
+`)
+
+// loadScript seeds a counter unless another instance already has.
+// KEYS: remaining, served. ARGV: remaining, served.
+var loadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('SET', KEYS[2], ARGV[2])
+return 1
+`)
+
+// stateScript reads a counter and, with ARGV given, resets it. Served is
+// raised to the count recorded in Postgres and the pool set to what is left
+// of the cap after it and the unexpired leases, or the leases recorded in
+// Postgres when those are more; expired leases are dropped, which returns
+// their unserved impressions. It returns nil when the counter is not
+// loaded, otherwise {remaining before, remaining, served, leased}.
+// KEYS: remaining, served, nodes. ARGV: lease key prefix[, max, served, leased].
+var stateScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+local remaining = tonumber(value)
+local served = tonumber(redis.call('GET', KEYS[2]) or '0')
+local leased = 0
+for _, node in ipairs(redis.call('SMEMBERS', KEYS[3])) do
+	local held = redis.call('GET', ARGV[1] .. node)
+	if held then
+		leased = leased + tonumber(held)
+	else
+		redis.call('SREM', KEYS[3], node)
+	end
+end
+if not ARGV[2] then
+	return {remaining, remaining, served, leased}
+end
+served = math.max(served, tonumber(ARGV[3]))
+local want = math.max(0, tonumber(ARGV[2]) - served - math.max(leased, tonumber(ARGV[4])))
+redis.call('SET', KEYS[2], served)
+redis.call('SET', KEYS[1], want)
+return {remaining, want, served, leased}
+`)
+
+// RedisCounter keeps each capped booking's unleased impressions, served
+// count and per-node leases in Redis. All keys of a booking share a hash
+// slot so every script runs atomically on Redis Cluster.
+type RedisCounter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCounter creates an impression counter backed by Redis
+func NewRedisCounter(client redis.UniversalClient) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+func keyPrefix(bookingID string) string {
+	return "impcap:{" + bookingID + "}:"
+}
+
+func remainingKey(bookingID string) string { return keyPrefix(bookingID) + "remaining" }
+func servedKey(bookingID string) string    { return keyPrefix(bookingID) + "served" }
+func nodesKey(bookingID string) string     { return keyPrefix(bookingID) + "nodes" }
+func leasePrefix(bookingID string) string  { return keyPrefix(bookingID) + "lease:" }
+
+// counterState is a booking's counter as read or reset by stateScript
+type counterState struct {
+	before    int64 // Pool before a reset
+	remaining int64
+	served    int64
+	leased    int64
+}
+
+// take takes one impression for a decision, from nodeID's lease when it
+// holds one. It reports whether an impression was left.
+func (c *RedisCounter) take(ctx context.Context, bookingID, nodeID string) (bool, bool, error) {
+	keys := []string{remainingKey(bookingID), servedKey(bookingID)}
+	if nodeID != "" {
+		keys = append(keys, leasePrefix(bookingID)+nodeID)
+	}
+	result, err := takeScript.Run(ctx, c.client, keys).Int64Slice()
+	if errors.Is(err, redis.Nil) {
+		return false, false, errNotLoaded
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to take impression: %w", err)
+	}
+	return result[0] == 1, result[1] == 1, nil
+}
+
+// giveBack returns one impression to a booking's shared pool
+func (c *RedisCounter) giveBack(ctx context.Context, bookingID string) error {
+	keys := []string{remainingKey(bookingID), servedKey(bookingID)}
+	if err := giveBackScript.Run(ctx, c.client, keys).Err(); err != nil {
+		return fmt.Errorf("failed to give back impression: %w", err)
+	}
+	return nil
+}
+
+// lease moves up to want impressions to nodeID's lease. It returns how many
+// were granted and how many the node now holds.
+func (c *RedisCounter) lease(ctx context.Context, bookingID, nodeID string, want int64, ttl int64) (int64, int64, error) {
+	keys := []string{remainingKey(bookingID), leasePrefix(bookingID) + nodeID, nodesKey(bookingID)}
+	result, err := leaseScript.Run(ctx, c.client, keys, want, ttl, nodeID, leaseShare).Int64Slice()
+	if errors.Is(err, redis.Nil) {
+		return 0, 0, errNotLoaded
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to lease impressions: %w", err)
+	}
+	return result[0], result[1], nil
+}
+
+// release returns nodeID's unserved lease to the pool and reports how much it held
+func (c *RedisCounter) release(ctx context.Context, bookingID, nodeID string) (int64, error) {
+	keys := []string{remainingKey(bookingID), leasePrefix(bookingID) + nodeID, nodesKey(bookingID)}
+	held, err := releaseScript.Run(ctx, c.client, keys, nodeID).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to release impression lease: %w", err)
+	}
+	return held, nil
+}
+
+// load seeds a booking's counter unless another instance already has
+func (c *RedisCounter) load(ctx context.Context, bookingID string, remaining, served int64) error {
+	keys := []string{remainingKey(bookingID), servedKey(bookingID)}
+	if err := loadScript.Run(ctx, c.client, keys, remaining, served).Err(); err != nil {
+		return fmt.Errorf("failed to load impression counter: %w", err)
+	}
+	return nil
+}
+
+// get reads a booking's counter. It returns nil when the counter is not loaded.
+func (c *RedisCounter) get(ctx context.Context, bookingID string) (*counterState, error) {
+	return c.state(ctx, bookingID, leasePrefix(bookingID))
+}
+
+// reset sets a booking's pool from its cap and the served count and leases
+// recorded in Postgres. It returns nil when the counter is not loaded.
+func (c *RedisCounter) reset(ctx context.Context, bookingID string, capped *Cap) (*counterState, error) {
+	return c.state(ctx, bookingID, leasePrefix(bookingID), capped.Max, capped.Served, capped.Leased)
+}
+
+func (c *RedisCounter) state(ctx context.Context, bookingID string, args ...interface{}) (*counterState, error) {
+	keys := []string{remainingKey(bookingID), servedKey(bookingID), nodesKey(bookingID)}
+	result, err := stateScript.Run(ctx, c.client, keys, args...).Int64Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read impression counter: %w", err)
+	}
+	return &counterState{before: result[0], remaining: result[1], served: result[2], leased: result[3]}, nil
+}
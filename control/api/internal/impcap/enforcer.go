@@ -0,0 +1,272 @@
+package impcap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Enforcer holds bookings to their max_impressions. Capped bookings keep a
+// shared counter in Redis seeded from Postgres; edge nodes lease small
+// quotas from it and serve them without a round trip, and every served
+// decision is taken from the node's lease or the shared pool. Leased
+// impressions leave the pool when granted, so the total served can never
+// exceed the cap however long an edge node works on its own.
+//
+// Without Redis, or when it fails, decisions are checked against the
+// deliveries and unexpired leases recorded in Postgres, which counts leased
+// impressions as already served. That check is not atomic across concurrent
+// decisions, and no new leases are granted until Redis is back.
+type Enforcer struct {
+	store    Store
+	counter  *RedisCounter
+	leaseTTL time.Duration
+}
+
+// NewEnforcer creates an impression cap enforcer. counter may be nil to
+// always check Postgres.
+func NewEnforcer(store Store, counter *RedisCounter, leaseTTL time.Duration) *Enforcer {
+	return &Enforcer{store: store, counter: counter, leaseTTL: leaseTTL}
+}
+
+// getCap loads a booking's cap, failing for unknown bookings
+func (e *Enforcer) getCap(bookingID string) (*Cap, error) {
+	c, err := e.store.GetImpressionCap(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrUnknownBooking
+	}
+	return c, nil
+}
+
+// Take takes one impression of a booking for a served decision reported by
+// nodeID, which may be empty for decisions made centrally. Uncapped bookings
+// are never refused and return a nil reservation.
+func (e *Enforcer) Take(ctx context.Context, bookingID, nodeID string) (*Reservation, error) {
+	c, err := e.getCap(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Capped() {
+		return nil, nil
+	}
+
+	if e.counter != nil {
+		r, err := e.takeCounter(ctx, c, nodeID)
+		if err == nil || errors.Is(err, ErrCapReached) {
+			return r, err
+		}
+		logrus.WithError(err).WithField("booking_id", bookingID).Warn("Impression counter unavailable, checking Postgres")
+	}
+
+	if c.Available() < 1 {
+		metrics.ImpressionCapTakes.WithLabelValues("postgres", "capped").Inc()
+		return nil, ErrCapReached
+	}
+	metrics.ImpressionCapTakes.WithLabelValues("postgres", "pool").Inc()
+	return &Reservation{BookingID: bookingID, NodeID: nodeID}, nil
+}
+
+// takeCounter takes an impression from the Redis counter, loading it on first use
+func (e *Enforcer) takeCounter(ctx context.Context, c *Cap, nodeID string) (*Reservation, error) {
+	ok, fromLease, err := e.counter.take(ctx, c.BookingID, nodeID)
+	if errors.Is(err, errNotLoaded) {
+		if err := e.counter.load(ctx, c.BookingID, c.Available(), c.Served); err != nil {
+			return nil, err
+		}
+		ok, fromLease, err = e.counter.take(ctx, c.BookingID, nodeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		metrics.ImpressionCapTakes.WithLabelValues("redis", "capped").Inc()
+		return nil, ErrCapReached
+	}
+	source := "pool"
+	if fromLease {
+		source = "lease"
+	}
+	metrics.ImpressionCapTakes.WithLabelValues("redis", source).Inc()
+	return &Reservation{BookingID: c.BookingID, NodeID: nodeID, counted: true}, nil
+}
+
+// GiveBack returns an impression whose decision could not be recorded
+func (e *Enforcer) GiveBack(ctx context.Context, r *Reservation) {
+	if r == nil || !r.counted {
+		return
+	}
+	if err := e.counter.giveBack(ctx, r.BookingID); err != nil {
+		// Reconciliation restores it from Postgres
+		logrus.WithError(err).WithField("booking_id", r.BookingID).Warn("Failed to give back impression")
+	}
+}
+
+// Lease grants nodeID up to want more impressions of a booking to serve
+// locally and extends its lease. Fewer, or none, are granted as the cap
+// runs out; an uncapped booking returns an unlimited lease.
+func (e *Enforcer) Lease(ctx context.Context, bookingID, nodeID string, want int64) (*Lease, error) {
+	c, err := e.getCap(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	lease := &Lease{BookingID: bookingID, NodeID: nodeID, ExpiresAt: now.Add(e.leaseTTL)}
+	if !c.Capped() {
+		lease.Unlimited = true
+		return lease, nil
+	}
+	if e.counter == nil {
+		return nil, ErrUnavailable
+	}
+
+	// The lease outlives what the node is told by the grace period, so
+	// decisions it reports late are still taken from it
+	ttl := (e.leaseTTL + LeaseGrace).Milliseconds()
+	granted, held, err := e.counter.lease(ctx, bookingID, nodeID, want, ttl)
+	if errors.Is(err, errNotLoaded) {
+		if err := e.counter.load(ctx, bookingID, c.Available(), c.Served); err != nil {
+			return nil, err
+		}
+		granted, held, err = e.counter.lease(ctx, bookingID, nodeID, want, ttl)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("booking_id", bookingID).Warn("Impression counter unavailable, refusing lease")
+		return nil, ErrUnavailable
+	}
+
+	// Postgres keeps the lease so a counter lost with Redis is reseeded
+	// without the impressions edge nodes may still serve
+	if err := e.store.SaveImpressionLease(bookingID, nodeID, held, now.Add(e.leaseTTL+LeaseGrace)); err != nil {
+		if _, releaseErr := e.counter.release(ctx, bookingID, nodeID); releaseErr != nil {
+			logrus.WithError(releaseErr).WithField("booking_id", bookingID).Warn("Failed to release unsaved impression lease")
+		}
+		return nil, err
+	}
+
+	metrics.ImpressionCapLeased.Add(float64(granted))
+	lease.Granted = granted
+	lease.Held = held
+	return lease, nil
+}
+
+// Release returns what nodeID's lease still holds to the booking's shared
+// pool, e.g. when the node shuts down. It reports how many impressions were returned.
+func (e *Enforcer) Release(ctx context.Context, bookingID, nodeID string) (int64, error) {
+	var held int64
+	if e.counter != nil {
+		var err error
+		if held, err = e.counter.release(ctx, bookingID, nodeID); err != nil {
+			return 0, err
+		}
+	}
+	if err := e.store.DeleteImpressionLease(bookingID, nodeID); err != nil {
+		return held, err
+	}
+	return held, nil
+}
+
+// Status returns a booking's cap with what its Redis counter holds, or a nil
+// counter when it is not loaded
+func (e *Enforcer) Status(ctx context.Context, bookingID string) (*Cap, *CounterStatus, error) {
+	c, err := e.store.GetImpressionCap(bookingID)
+	if err != nil || c == nil || e.counter == nil {
+		return c, nil, err
+	}
+	state, err := e.counter.get(ctx, bookingID)
+	if err != nil || state == nil {
+		return c, nil, err
+	}
+	return c, &CounterStatus{Remaining: state.remaining, Served: state.served, Leased: state.leased}, nil
+}
+
+// Refresh resets a booking's counter from Postgres after the booking was
+// changed, so a raised or lowered cap applies at once rather than at the next
+// reconciliation. Counters that are not loaded are left alone.
+func (e *Enforcer) Refresh(ctx context.Context, bookingID string) error {
+	if e.counter == nil {
+		return nil
+	}
+	c, err := e.store.GetImpressionCap(bookingID)
+	if err != nil || c == nil {
+		return err
+	}
+	_, err = e.counter.reset(ctx, bookingID, c)
+	return err
+}
+
+// CounterStatus is what a booking's Redis counter holds
+type CounterStatus struct {
+	Remaining int64 `json:"remaining"` // Unleased impressions in the shared pool
+	Served    int64 `json:"served"`
+	Leased    int64 `json:"leased"`
+}
+
+// Reconcile resets every loaded counter from Postgres. Served is only ever
+// raised to the recorded count, because decisions taken but not yet
+// recorded are missing from Postgres. Expired leases are dropped so their
+// unserved impressions return to the pool, while leases recorded in Postgres
+// but lost from Redis stay withheld until they expire.
+func (e *Enforcer) Reconcile(ctx context.Context) (int, error) {
+	if e.counter == nil {
+		return 0, nil
+	}
+	caps, err := e.store.ListImpressionCaps()
+	if err != nil {
+		return 0, err
+	}
+
+	corrected := 0
+	for i, c := range caps {
+		state, err := e.counter.reset(ctx, c.BookingID, &caps[i])
+		if err != nil {
+			return corrected, err
+		}
+		if state == nil || state.before == state.remaining {
+			continue
+		}
+		corrected++
+		metrics.ImpressionCapCorrections.Inc()
+		logrus.WithFields(logrus.Fields{
+			"booking_id": c.BookingID,
+			"before":     state.before,
+			"remaining":  state.remaining,
+			"leased":     state.leased,
+		}).Info("Reset drifted impression counter")
+	}
+	return corrected, nil
+}
+
+// Worker reconciles impression counters with Postgres on a schedule
+type Worker struct {
+	enforcer *Enforcer
+	interval time.Duration
+}
+
+// NewWorker creates an impression counter reconciliation worker
+func NewWorker(enforcer *Enforcer, interval time.Duration) *Worker {
+	return &Worker{enforcer: enforcer, interval: interval}
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.enforcer.Reconcile(ctx); err != nil {
+			logrus.WithError(err).Error("Impression counter reconciliation failed")
+		}
+	}
+}
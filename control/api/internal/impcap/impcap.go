@@ -0,0 +1,82 @@
+package impcap
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCapReached is returned when serving another impression would take a
+// booking past its max_impressions
+var ErrCapReached = errors.New("booking impression cap reached")
+
+// ErrUnknownBooking is returned when capping a booking that does not exist
+var ErrUnknownBooking = errors.New("booking not found")
+
+// ErrUnavailable is returned when leases cannot be granted because the
+// shared counter is not configured or not reachable
+var ErrUnavailable = errors.New("impression counter unavailable")
+
+// DefaultLeaseTTL is how long an edge node may serve from a lease before renewing it
+const DefaultLeaseTTL = 30 * time.Second
+
+// LeaseGrace is how long past its expiry a lease still absorbs decisions
+// reported late by its edge node
+const LeaseGrace = 10 * time.Second
+
+// MaxLeaseRequest bounds the impressions one lease request may ask for
+const MaxLeaseRequest = 10000
+
+// leaseShare divides what is left of a cap so one node never leases more
+// than a tenth of it and other nodes are not starved
+const leaseShare = 10
+
+// Cap is a booking's hard impression cap. Served counts the decisions
+// recorded in Postgres and Leased the unexpired quotas granted to edge
+// nodes, some of which may already be served.
+type Cap struct {
+	BookingID string `json:"booking_id"`
+	Max       int64  `json:"max_impressions"`
+	Served    int64  `json:"served"`
+	Leased    int64  `json:"leased"`
+}
+
+// Capped reports whether the booking has a cap; max_impressions of 0 is unlimited
+func (c *Cap) Capped() bool {
+	return c.Max > 0
+}
+
+// Available is what may still be served without the shared counter. It
+// counts leased impressions as taken, so it never exceeds what is left.
+func (c *Cap) Available() int64 {
+	available := c.Max - c.Served - c.Leased
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// Lease is a quota of impressions an edge node may serve locally until it expires
+type Lease struct {
+	BookingID string    `json:"booking_id"`
+	NodeID    string    `json:"node_id"`
+	Granted   int64     `json:"granted"`   // Added by this request
+	Held      int64     `json:"held"`      // Unserved impressions the node now holds
+	Unlimited bool      `json:"unlimited"` // Booking is uncapped; no quota is needed
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Reservation is one impression taken for a served decision. It is given
+// back if the decision cannot be recorded.
+type Reservation struct {
+	BookingID string
+	NodeID    string
+	counted   bool // Taken from the shared counter
+}
+
+// Store reads caps and persists the leases granted against them
+type Store interface {
+	GetImpressionCap(bookingID string) (*Cap, error)
+	ListImpressionCaps() ([]Cap, error)
+	SaveImpressionLease(bookingID, nodeID string, held int64, expiresAt time.Time) error
+	DeleteImpressionLease(bookingID, nodeID string) error
+}
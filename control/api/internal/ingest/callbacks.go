@@ -0,0 +1,221 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kinds of results the vision pipeline pushes through signed callbacks
+const (
+	CallbackShots      = "shots"
+	CallbackSurfaces   = "surfaces"
+	CallbackSceneGraph = "scene_graph"
+	CallbackQC         = "qc"
+	CallbackThumbnail  = "thumbnail"
+)
+
+// Bounds on a single callback
+const (
+	maxCallbackShots  = 20000
+	maxSceneNodes     = 2000
+	maxSceneEdges     = 10000
+	maxQCChecks       = 5000
+	maxCallbackIDSize = 100
+)
+
+// QC check statuses
+const (
+	QCPassed  = "passed"
+	QCWarning = "warning"
+	QCFailed  = "failed"
+)
+
+// ShotList is the shot boundaries one pipeline run detected in a title
+type ShotList struct {
+	TitleID string `json:"title_id"`
+	RunID   string `json:"run_id"`
+	Shots   []Shot `json:"shots"`
+}
+
+// SceneNode is an object detected in a shot. SurfaceID links objects that
+// are also placement surfaces.
+type SceneNode struct {
+	NodeID     string                 `json:"node_id"`
+	Label      string                 `json:"label"`
+	SurfaceID  string                 `json:"surface_id,omitempty"`
+	Confidence float64                `json:"confidence"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SceneEdge is a spatial or semantic relation between two objects, e.g.
+// a lamp "on" a table
+type SceneEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// SceneGraph is the objects of one shot and the relations between them
+type SceneGraph struct {
+	TitleID string      `json:"title_id"`
+	ShotID  string      `json:"shot_id"`
+	RunID   string      `json:"run_id"`
+	Nodes   []SceneNode `json:"nodes"`
+	Edges   []SceneEdge `json:"edges"`
+}
+
+// QCCheck is the outcome of one quality check, on the whole title or on a
+// shot or surface of it
+type QCCheck struct {
+	Check     string   `json:"check"`
+	Status    string   `json:"status"`
+	ShotID    string   `json:"shot_id,omitempty"`
+	SurfaceID string   `json:"surface_id,omitempty"`
+	Score     *float64 `json:"score,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// QCReport is the quality checks one pipeline run applied to a title
+type QCReport struct {
+	TitleID string    `json:"title_id"`
+	RunID   string    `json:"run_id"`
+	Checks  []QCCheck `json:"checks"`
+}
+
+// Validate checks that the shot list is well formed
+func (l *ShotList) Validate() error {
+	if err := validateIDs(l.TitleID, l.RunID); err != nil {
+		return err
+	}
+	if len(l.Shots) == 0 {
+		return fmt.Errorf("shots is required")
+	}
+	if len(l.Shots) > maxCallbackShots {
+		return fmt.Errorf("at most %d shots may be sent at once", maxCallbackShots)
+	}
+	return validateShots(l.Shots)
+}
+
+// Validate checks that the graph is well formed and its edges join known nodes
+func (g *SceneGraph) Validate() error {
+	if err := validateIDs(g.TitleID, g.RunID); err != nil {
+		return err
+	}
+	if g.ShotID == "" {
+		return fmt.Errorf("shot_id is required")
+	}
+	if len(g.Nodes) == 0 {
+		return fmt.Errorf("nodes is required")
+	}
+	if len(g.Nodes) > maxSceneNodes || len(g.Edges) > maxSceneEdges {
+		return fmt.Errorf("scene graphs are limited to %d nodes and %d edges", maxSceneNodes, maxSceneEdges)
+	}
+
+	nodes := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		if node.NodeID == "" {
+			return fmt.Errorf("nodes must have a node_id")
+		}
+		if nodes[node.NodeID] {
+			return fmt.Errorf("node %s is listed twice", node.NodeID)
+		}
+		if node.Label == "" {
+			return fmt.Errorf("node %s has no label", node.NodeID)
+		}
+		if math.IsNaN(node.Confidence) || node.Confidence < 0 || node.Confidence > 1 {
+			return fmt.Errorf("node %s confidence must be between 0 and 1", node.NodeID)
+		}
+		nodes[node.NodeID] = true
+	}
+	for _, edge := range g.Edges {
+		if !nodes[edge.From] || !nodes[edge.To] {
+			return fmt.Errorf("edge %s -> %s references an unknown node", edge.From, edge.To)
+		}
+		if edge.Relation == "" {
+			return fmt.Errorf("edge %s -> %s has no relation", edge.From, edge.To)
+		}
+	}
+	return nil
+}
+
+// SurfaceIDs lists the surfaces the graph's nodes link to
+func (g *SceneGraph) SurfaceIDs() []string {
+	ids := make([]string, 0)
+	for _, node := range g.Nodes {
+		if node.SurfaceID != "" {
+			ids = append(ids, node.SurfaceID)
+		}
+	}
+	return ids
+}
+
+// Validate checks that the report is well formed. A run reports each check
+// at most once per subject.
+func (r *QCReport) Validate() error {
+	if err := validateIDs(r.TitleID, r.RunID); err != nil {
+		return err
+	}
+	if r.RunID == "" {
+		return fmt.Errorf("run_id is required")
+	}
+	if len(r.Checks) == 0 {
+		return fmt.Errorf("checks is required")
+	}
+	if len(r.Checks) > maxQCChecks {
+		return fmt.Errorf("at most %d checks may be sent at once", maxQCChecks)
+	}
+
+	seen := make(map[string]bool, len(r.Checks))
+	for _, check := range r.Checks {
+		if check.Check == "" {
+			return fmt.Errorf("checks must name the check")
+		}
+		switch check.Status {
+		case QCPassed, QCWarning, QCFailed:
+		default:
+			return fmt.Errorf("check %s status must be passed, warning or failed", check.Check)
+		}
+		if check.Score != nil && (math.IsNaN(*check.Score) || math.IsInf(*check.Score, 0)) {
+			return fmt.Errorf("check %s score must be finite", check.Check)
+		}
+		key := check.Check + "/" + check.Subject()
+		if seen[key] {
+			return fmt.Errorf("check %s is reported twice for %q", check.Check, check.Subject())
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Failed counts the checks that failed
+func (r *QCReport) Failed() int {
+	failed := 0
+	for _, check := range r.Checks {
+		if check.Status == QCFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Subject is what the check applies to: a surface, a shot, or the whole
+// title when empty
+func (c *QCCheck) Subject() string {
+	switch {
+	case c.SurfaceID != "":
+		return "surface:" + c.SurfaceID
+	case c.ShotID != "":
+		return "shot:" + c.ShotID
+	}
+	return ""
+}
+
+func validateIDs(titleID, runID string) error {
+	if titleID == "" {
+		return fmt.Errorf("title_id is required")
+	}
+	if len(runID) > maxCallbackIDSize {
+		return fmt.Errorf("run_id is too long")
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package ingest
+
+import "time"
+
+// DefaultStaleAfter is how long a surface stays fresh after the pipeline last
+// validated it against the delivered master
+const DefaultStaleAfter = 90 * 24 * time.Hour
+
+// Reasons a surface is queued for re-validation
+const (
+	ReasonCutDelivered = "cut_delivered" // A new edit of its title was delivered
+	ReasonRequested    = "requested"     // Someone asked for its title to be re-validated
+	ReasonAge          = "age"           // Its last validation is older than the stale-after age
+)
+
+// Revalidation is a surface waiting for the pipeline to validate it again.
+// Re-ingesting the surface validates it and takes it off the queue.
+type Revalidation struct {
+	SurfaceID       string     `json:"surface_id"`
+	TitleID         string     `json:"title_id"`
+	Reason          string     `json:"reason"`
+	LastValidatedAt time.Time  `json:"last_validated_at"`
+	RequestedAt     *time.Time `json:"requested_at,omitempty"`
+	LiveBookings    int        `json:"live_bookings"` // Pending, confirmed or active bookings of the surface
+}
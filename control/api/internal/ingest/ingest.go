@@ -0,0 +1,190 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/dedupe"
+)
+
+// ErrInvalidBatch is returned when a batch conflicts with stored inventory
+var ErrInvalidBatch = errors.New("invalid ingestion batch")
+
+// maxBatchSurfaces bounds the surfaces one ingestion request may carry
+const maxBatchSurfaces = 5000
+
+// Shot is a shot boundary detected by the vision pipeline
+type Shot struct {
+	ShotID     string  `json:"shot_id"`
+	StartTime  float64 `json:"start_time"`
+	EndTime    float64 `json:"end_time"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Surface is a placement surface detected by the vision pipeline. Polygon
+// vertices are normalized frame coordinates. Audio slots have no polygon;
+// their start and end times bound the slot.
+type Surface struct {
+	SurfaceID       string          `json:"surface_id"`
+	ShotID          string          `json:"shot_id"`
+	StartTime       float64         `json:"start_time"`
+	EndTime         float64         `json:"end_time"`
+	PlacementType   string          `json:"placement_type,omitempty"` // Empty means visual
+	Polygon         [][2]float64    `json:"polygon"`
+	SurfaceType     string          `json:"surface_type"`
+	AreaPixels      float64         `json:"area_pixels"`
+	PRSScore        float64         `json:"prs_score"`
+	VisibilityScore float64         `json:"visibility_score"`
+	StabilityScore  float64         `json:"stability_score"`
+	Restrictions    []string        `json:"restrictions,omitempty"` // Restriction categories; kept as they were when omitted on re-ingest
+	Loudness        *audio.Loudness `json:"loudness,omitempty"`     // Audio slots only
+}
+
+// Batch is one pipeline run's shots and surfaces for a title
+type Batch struct {
+	TitleID  string    `json:"title_id"`
+	Shots    []Shot    `json:"shots"`
+	Surfaces []Surface `json:"surfaces"`
+}
+
+// Result reports what an ingestion stored. DuplicateWarnings lists ingested
+// surfaces that overlap another live surface of the title.
+type Result struct {
+	TitleID           string           `json:"title_id"`
+	Shots             int              `json:"shots"`
+	Surfaces          int              `json:"surfaces"`
+	DuplicateWarnings []dedupe.Warning `json:"duplicate_warnings"`
+}
+
+// Validate checks that the batch is well formed
+func (b *Batch) Validate() error {
+	if b.TitleID == "" {
+		return fmt.Errorf("title_id is required")
+	}
+	if len(b.Surfaces) == 0 {
+		return fmt.Errorf("surfaces is required")
+	}
+	if len(b.Surfaces) > maxBatchSurfaces {
+		return fmt.Errorf("at most %d surfaces may be ingested at once", maxBatchSurfaces)
+	}
+
+	if err := validateShots(b.Shots); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(b.Surfaces))
+	for _, s := range b.Surfaces {
+		if s.SurfaceID == "" {
+			return fmt.Errorf("surfaces must have a surface_id")
+		}
+		if seen[s.SurfaceID] {
+			return fmt.Errorf("surface %s is listed twice", s.SurfaceID)
+		}
+		seen[s.SurfaceID] = true
+		if s.ShotID == "" {
+			return fmt.Errorf("surface %s has no shot_id", s.SurfaceID)
+		}
+		if s.EndTime < s.StartTime {
+			return fmt.Errorf("surface %s ends before it starts", s.SurfaceID)
+		}
+		for _, restriction := range s.Restrictions {
+			if restriction == "" {
+				return fmt.Errorf("surface %s has an empty restriction", s.SurfaceID)
+			}
+		}
+		if err := audio.ValidatePlacementType(s.PlacementType); err != nil {
+			return fmt.Errorf("surface %s: %w", s.SurfaceID, err)
+		}
+		if s.Audio() {
+			if err := validateAudioSlot(&s); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.Loudness != nil {
+			return fmt.Errorf("surface %s: loudness applies to audio slots only", s.SurfaceID)
+		}
+		if len(s.Polygon) < 3 {
+			return fmt.Errorf("surface %s polygon needs at least 3 vertices", s.SurfaceID)
+		}
+		for _, vertex := range s.Polygon {
+			for _, coord := range vertex {
+				if math.IsNaN(coord) || math.IsInf(coord, 0) {
+					return fmt.Errorf("surface %s polygon has a non-finite vertex", s.SurfaceID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateAudioSlot checks that an audio slot has a length, no polygon and
+// sane loudness constraints
+func validateAudioSlot(s *Surface) error {
+	if s.EndTime <= s.StartTime {
+		return fmt.Errorf("audio surface %s must end after it starts", s.SurfaceID)
+	}
+	if len(s.Polygon) > 0 {
+		return fmt.Errorf("audio surface %s cannot have a polygon", s.SurfaceID)
+	}
+	if s.Loudness != nil {
+		if err := s.Loudness.Validate(); err != nil {
+			return fmt.Errorf("surface %s: %w", s.SurfaceID, err)
+		}
+	}
+	return nil
+}
+
+// validateShots checks that shots have unique IDs and sane bounds
+func validateShots(shots []Shot) error {
+	seen := make(map[string]bool, len(shots))
+	for _, shot := range shots {
+		if shot.ShotID == "" {
+			return fmt.Errorf("shots must have a shot_id")
+		}
+		if seen[shot.ShotID] {
+			return fmt.Errorf("shot %s is listed twice", shot.ShotID)
+		}
+		if shot.EndTime < shot.StartTime {
+			return fmt.Errorf("shot %s ends before it starts", shot.ShotID)
+		}
+		seen[shot.ShotID] = true
+	}
+	return nil
+}
+
+// SurfaceIDs lists the batch's surface IDs
+func (b *Batch) SurfaceIDs() []string {
+	ids := make([]string, 0, len(b.Surfaces))
+	for _, s := range b.Surfaces {
+		ids = append(ids, s.SurfaceID)
+	}
+	return ids
+}
+
+// Audio reports whether the surface is an audio slot
+func (s *Surface) Audio() bool {
+	return s.PlacementType == audio.PlacementAudio
+}
+
+// WKT renders the surface polygon as well-known text, closing the ring, or
+// returns "" for audio slots
+func (s *Surface) WKT() string {
+	if len(s.Polygon) == 0 {
+		return ""
+	}
+	ring := append([][2]float64{}, s.Polygon...)
+	if ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+
+	points := make([]string, 0, len(ring))
+	for _, vertex := range ring {
+		points = append(points, strconv.FormatFloat(vertex[0], 'f', -1, 64)+" "+strconv.FormatFloat(vertex[1], 'f', -1, 64))
+	}
+	return "POLYGON((" + strings.Join(points, ", ") + "))"
+}
@@ -0,0 +1,199 @@
+package ingest
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pipeline stages a title goes through, in order
+const (
+	StageUploaded          = "uploaded"
+	StageShotsDetected     = "shots_detected"
+	StageSurfacesExtracted = "surfaces_extracted"
+	StageScored            = "scored"
+	StagePublished         = "published"
+)
+
+// Stages lists the pipeline stages in order
+var Stages = []string{StageUploaded, StageShotsDetected, StageSurfacesExtracted, StageScored, StagePublished}
+
+// Stage statuses. Callbacks report running, completed or failed; stages
+// without a callback are pending.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Overall ingestion states of a title
+const (
+	StatePending    = "pending"
+	StateProcessing = "processing"
+	StateFailed     = "failed"
+	StateStuck      = "stuck"
+	StatePublished  = "published"
+)
+
+// DefaultStuckAfter is how long a stage may run, or wait for the previous
+// one, before it is reported stuck
+const DefaultStuckAfter = 2 * time.Hour
+
+// StageEvent is a pipeline callback reporting a stage of a title
+type StageEvent struct {
+	Stage      string    `json:"stage"`
+	Status     string    `json:"status"`
+	RunID      string    `json:"run_id"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// StageState is where a title stands in one stage
+type StageState struct {
+	Stage       string     `json:"stage"`
+	Status      string     `json:"status"`
+	RunID       string     `json:"run_id,omitempty"`
+	Message     string     `json:"message,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Stuck       bool       `json:"stuck,omitempty"`
+}
+
+// Progress is a title's ingestion status across every stage
+type Progress struct {
+	TitleID string `json:"title_id"`
+	State   string `json:"state"`
+	// CurrentStage is the first stage not completed; empty once published
+	CurrentStage    string       `json:"current_stage,omitempty"`
+	CompletedStages int          `json:"completed_stages"`
+	TotalStages     int          `json:"total_stages"`
+	Stages          []StageState `json:"stages"`
+	UpdatedAt       *time.Time   `json:"updated_at,omitempty"`
+}
+
+// StageIndex is the position of stage in the pipeline, or -1 if unknown
+func StageIndex(stage string) int {
+	for i, s := range Stages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that a callback names a known stage and status
+func (e *StageEvent) Validate() error {
+	if StageIndex(e.Stage) < 0 {
+		return fmt.Errorf("unknown stage %q", e.Stage)
+	}
+	switch e.Status {
+	case StatusRunning, StatusCompleted, StatusFailed:
+	default:
+		return fmt.Errorf("status must be running, completed or failed")
+	}
+	if len(e.RunID) > 100 {
+		return fmt.Errorf("run_id is too long")
+	}
+	return nil
+}
+
+// Restarts reports whether the event begins a new run of the title, which
+// resets the stages after uploaded
+func (e *StageEvent) Restarts(uploaded *StageState) bool {
+	if e.Stage != StageUploaded {
+		return false
+	}
+	return e.Status == StatusRunning || uploaded == nil || uploaded.RunID != e.RunID
+}
+
+// Superseded reports whether the event belongs to an earlier run than the
+// title's current upload, e.g. a late callback from a cancelled run
+func (e *StageEvent) Superseded(uploaded *StageState) bool {
+	if e.Stage == StageUploaded || uploaded == nil || e.RunID == "" || uploaded.RunID == "" {
+		return false
+	}
+	return e.RunID != uploaded.RunID
+}
+
+// Apply moves a stage to the state reported by event. current is nil for a
+// stage without callbacks yet.
+func Apply(current *StageState, event StageEvent) StageState {
+	occurredAt := event.OccurredAt
+	next := StageState{
+		Stage:     event.Stage,
+		Status:    event.Status,
+		RunID:     event.RunID,
+		Message:   event.Message,
+		UpdatedAt: &occurredAt,
+	}
+
+	// A stage keeps its start time while the same run reports on it, until
+	// it is started again after finishing or failing
+	restarted := event.Status == StatusRunning && current != nil && current.Status != StatusRunning
+	if current != nil && current.RunID == event.RunID && current.StartedAt != nil && !restarted {
+		next.StartedAt = current.StartedAt
+	} else if event.Status != StatusFailed {
+		next.StartedAt = &occurredAt
+	}
+	if event.Status == StatusCompleted {
+		next.CompletedAt = &occurredAt
+	}
+	return next
+}
+
+// NewProgress summarizes a title's stored stage states. Stages without
+// callbacks are pending; the current stage is stuck once it has run, or
+// waited for the previous stage, for longer than stuckAfter.
+func NewProgress(titleID string, stored []StageState, stuckAfter time.Duration, now time.Time) *Progress {
+	byStage := make(map[string]StageState, len(stored))
+	for _, state := range stored {
+		byStage[state.Stage] = state
+	}
+
+	progress := &Progress{TitleID: titleID, TotalStages: len(Stages), Stages: make([]StageState, 0, len(Stages))}
+	var previousDone *time.Time
+	for i, stage := range Stages {
+		state, ok := byStage[stage]
+		if !ok {
+			state = StageState{Stage: stage, Status: StatusPending}
+		}
+		if state.UpdatedAt != nil && (progress.UpdatedAt == nil || state.UpdatedAt.After(*progress.UpdatedAt)) {
+			progress.UpdatedAt = state.UpdatedAt
+		}
+
+		if state.Status == StatusCompleted {
+			progress.CompletedStages++
+			previousDone = state.CompletedAt
+		} else if progress.CurrentStage == "" {
+			progress.CurrentStage = stage
+			switch {
+			case state.Status == StatusRunning && state.StartedAt != nil:
+				state.Stuck = now.Sub(*state.StartedAt) > stuckAfter
+			case state.Status == StatusPending && i > 0 && previousDone != nil:
+				state.Stuck = now.Sub(*previousDone) > stuckAfter
+			}
+		}
+		progress.Stages = append(progress.Stages, state)
+	}
+
+	progress.State = progress.state()
+	return progress
+}
+
+// state derives the title's overall state from its stages
+func (p *Progress) state() string {
+	if p.CurrentStage == "" {
+		return StatePublished
+	}
+	current := p.Stages[StageIndex(p.CurrentStage)]
+	switch {
+	case current.Status == StatusFailed:
+		return StateFailed
+	case current.Stuck:
+		return StateStuck
+	case p.CompletedStages == 0 && current.Status == StatusPending:
+		return StatePending
+	}
+	return StateProcessing
+}
@@ -0,0 +1,76 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// workerTitleLimit bounds the unpublished titles checked per scheduled run
+const workerTitleLimit = 10000
+
+// ProgressStore lists the pipeline stages of titles not yet published
+type ProgressStore interface {
+	ListOpenIngestions(limit int) (map[string][]StageState, error)
+}
+
+// Worker checks unpublished titles on a schedule and exports how many are
+// stuck or failed in each stage, so stalled ingestion can be alerted on
+type Worker struct {
+	store      ProgressStore
+	interval   time.Duration
+	stuckAfter time.Duration
+}
+
+// NewWorker creates an ingestion progress worker
+func NewWorker(store ProgressStore, interval, stuckAfter time.Duration) *Worker {
+	return &Worker{store: store, interval: interval, stuckAfter: stuckAfter}
+}
+
+// Run checks immediately and then every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) check() {
+	titles, err := w.store.ListOpenIngestions(workerTitleLimit)
+	if err != nil {
+		logrus.WithError(err).Error("Ingestion progress check failed")
+		return
+	}
+
+	stuck := make(map[string]int, len(Stages))
+	failed := make(map[string]int, len(Stages))
+	now := time.Now()
+	for titleID, stages := range titles {
+		progress := NewProgress(titleID, stages, w.stuckAfter, now)
+		switch progress.State {
+		case StateStuck:
+			stuck[progress.CurrentStage]++
+			logrus.WithFields(logrus.Fields{
+				"title_id": titleID,
+				"stage":    progress.CurrentStage,
+			}).Warn("Title ingestion is stuck")
+		case StateFailed:
+			failed[progress.CurrentStage]++
+		}
+	}
+
+	for _, stage := range Stages {
+		metrics.IngestionStuckTitles.WithLabelValues(stage).Set(float64(stuck[stage]))
+		metrics.IngestionFailedTitles.WithLabelValues(stage).Set(float64(failed[stage]))
+	}
+}
@@ -0,0 +1,376 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler processes one job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+// QueueOptions controls how a pool consumes a queue
+type QueueOptions struct {
+	Concurrency int           // Jobs from this queue processed at once (default 1)
+	Priority    int           // Higher-priority queues are offered free workers first
+	Visibility  time.Duration // Lease length and per-job timeout (default 5 minutes)
+}
+
+// PoolConfig configures a worker pool
+type PoolConfig struct {
+	MaxWorkers     int                     // Jobs processed at once across all queues
+	PollInterval   time.Duration           // Wait between polls when every queue is empty
+	DepthInterval  time.Duration           // How often queue depth metrics are sampled
+	QueueOverrides map[string]QueueOptions // Deployment overrides for registered queues
+}
+
+// DefaultPoolConfig returns a pool configuration for a single gateway instance
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxWorkers:    8,
+		PollInterval:  time.Second,
+		DepthInterval: 15 * time.Second,
+	}
+}
+
+type poolQueue struct {
+	name    string
+	handler Handler
+	opts    QueueOptions
+	active  int
+}
+
+// Pool runs handlers for named queues. Each queue is capped at its own
+// concurrency and the pool at MaxWorkers; when workers are scarce, queues are
+// served in priority order so a flood of low-priority jobs cannot starve
+// high-priority ones.
+type Pool struct {
+	queue  Queue
+	config PoolConfig
+
+	mu      sync.Mutex
+	queues  []*poolQueue
+	active  int
+	started bool
+
+	wake       chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	dispatched chan struct{}
+	running    sync.WaitGroup
+
+	// jobCtx is cancelled when a shutdown deadline passes with jobs still running
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+}
+
+// NewPool creates a worker pool consuming from queue
+func NewPool(queue Queue, config PoolConfig) *Pool {
+	defaults := DefaultPoolConfig()
+	if config.MaxWorkers < 1 {
+		config.MaxWorkers = defaults.MaxWorkers
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.DepthInterval <= 0 {
+		config.DepthInterval = defaults.DepthInterval
+	}
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	return &Pool{
+		queue:      queue,
+		config:     config,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		dispatched: make(chan struct{}),
+		jobCtx:     jobCtx,
+		cancelJobs: cancelJobs,
+	}
+}
+
+// Register adds a handler for a queue. Deployment overrides for the queue
+// take precedence over opts. Register must be called before Start.
+func (p *Pool) Register(name string, handler Handler, opts QueueOptions) {
+	if override, ok := p.config.QueueOverrides[name]; ok {
+		if override.Concurrency > 0 {
+			opts.Concurrency = override.Concurrency
+		}
+		if override.Priority != 0 {
+			opts.Priority = override.Priority
+		}
+		if override.Visibility > 0 {
+			opts.Visibility = override.Visibility
+		}
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Visibility <= 0 {
+		opts.Visibility = 5 * time.Minute
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queues = append(p.queues, &poolQueue{name: name, handler: handler, opts: opts})
+	sort.SliceStable(p.queues, func(i, j int) bool {
+		return p.queues[i].opts.Priority > p.queues[j].opts.Priority
+	})
+}
+
+// Start begins claiming jobs in the background until Shutdown is called
+func (p *Pool) Start(ctx context.Context) {
+	p.mu.Lock()
+	p.started = true
+	p.mu.Unlock()
+
+	go p.sampleDepth(ctx)
+	go p.dispatch(ctx)
+}
+
+// Shutdown stops claiming new jobs and waits for running jobs to finish.
+// If ctx ends first, running jobs are cancelled; their leases expire and
+// they are retried elsewhere.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	if !started {
+		p.cancelJobs()
+		return nil
+	}
+	<-p.dispatched
+
+	drained := make(chan struct{})
+	go func() {
+		p.running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancelJobs()
+		logrus.Info("Job workers drained")
+		return nil
+	case <-ctx.Done():
+		p.cancelJobs()
+		logrus.Warn("Job workers did not drain in time, cancelling running jobs")
+		return ctx.Err()
+	}
+}
+
+// dispatch claims jobs whenever workers are free
+func (p *Pool) dispatch(ctx context.Context) {
+	defer close(p.dispatched)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-p.wake:
+		}
+
+		for p.claimNext(ctx) {
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.config.PollInterval)
+	}
+}
+
+// claimNext starts one job from the highest-priority queue with a free
+// worker and a ready job, reporting whether a job was started
+func (p *Pool) claimNext(ctx context.Context) bool {
+	p.mu.Lock()
+	if p.active >= p.config.MaxWorkers {
+		p.mu.Unlock()
+		return false
+	}
+	candidates := make([]*poolQueue, 0, len(p.queues))
+	for _, q := range p.queues {
+		if q.active < q.opts.Concurrency {
+			candidates = append(candidates, q)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, q := range candidates {
+		job, err := p.queue.Claim(ctx, q.name, q.opts.Visibility)
+		if err != nil {
+			logrus.WithError(err).WithField("queue", q.name).Error("Failed to claim job")
+			continue
+		}
+		if job == nil {
+			continue
+		}
+
+		p.mu.Lock()
+		p.active++
+		q.active++
+		p.mu.Unlock()
+
+		p.running.Add(1)
+		go p.process(q, job)
+		return true
+	}
+	return false
+}
+
+// process runs a job's handler and acks or nacks it
+func (p *Pool) process(q *poolQueue, job *Job) {
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		q.active--
+		p.mu.Unlock()
+		metrics.JobsInFlight.WithLabelValues(q.name).Dec()
+		p.running.Done()
+
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}()
+
+	metrics.JobsInFlight.WithLabelValues(q.name).Inc()
+	if !job.EnqueuedAt.IsZero() {
+		metrics.JobWaitSeconds.WithLabelValues(q.name).Observe(time.Since(job.EnqueuedAt).Seconds())
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"queue":   q.name,
+		"job_id":  job.ID,
+		"attempt": job.Attempts,
+	})
+
+	ctx, cancel := context.WithTimeout(p.jobCtx, q.opts.Visibility)
+	defer cancel()
+
+	started := time.Now()
+	err := runHandler(ctx, q.handler, job)
+	duration := time.Since(started).Seconds()
+
+	// Ack and nack use a fresh context so results are recorded even when
+	// the job context was cancelled by shutdown
+	ackCtx, ackCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer ackCancel()
+
+	if err == nil {
+		metrics.JobDurationSeconds.WithLabelValues(q.name, "succeeded").Observe(duration)
+		if err := p.queue.Ack(ackCtx, job); err != nil {
+			logger.WithError(err).Warn("Failed to ack job")
+		}
+		return
+	}
+
+	metrics.JobDurationSeconds.WithLabelValues(q.name, "failed").Observe(duration)
+	if job.Attempts >= job.MaxAttempts {
+		logger.WithError(err).Error("Job failed permanently, dead-lettering")
+	} else {
+		logger.WithError(err).Warn("Job failed, will retry")
+	}
+	if err := p.queue.Nack(ackCtx, job, err); err != nil {
+		logger.WithError(err).Warn("Failed to nack job")
+	}
+}
+
+// runHandler calls handler, converting a panic into an error
+func runHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// sampleDepth periodically records the depth of every registered queue
+func (p *Pool) sampleDepth(ctx context.Context) {
+	ticker := time.NewTicker(p.config.DepthInterval)
+	defer ticker.Stop()
+
+	for {
+		p.mu.Lock()
+		names := make([]string, 0, len(p.queues))
+		for _, q := range p.queues {
+			names = append(names, q.name)
+		}
+		p.mu.Unlock()
+
+		for _, name := range names {
+			depth, err := p.queue.Depth(ctx, name)
+			if err != nil {
+				logrus.WithError(err).WithField("queue", name).Debug("Failed to sample queue depth")
+				continue
+			}
+			metrics.JobQueueDepth.WithLabelValues(name).Set(float64(depth))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ParseQueueOverrides parses per-queue settings of the form
+// "exports=2:1,previews=8:10", i.e. name=concurrency[:priority].
+// An empty spec yields no overrides.
+func ParseQueueOverrides(spec string) (map[string]QueueOptions, error) {
+	overrides := make(map[string]QueueOptions)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, settings, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid queue setting %q, expected name=concurrency[:priority]", entry)
+		}
+
+		concurrencyValue, priorityValue, hasPriority := strings.Cut(settings, ":")
+		concurrency, err := strconv.Atoi(concurrencyValue)
+		if err != nil || concurrency < 1 {
+			return nil, fmt.Errorf("invalid concurrency for queue %s", name)
+		}
+
+		opts := QueueOptions{Concurrency: concurrency}
+		if hasPriority {
+			if opts.Priority, err = strconv.Atoi(priorityValue); err != nil {
+				return nil, fmt.Errorf("invalid priority for queue %s", name)
+			}
+		}
+		overrides[name] = opts
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,135 @@
+package jobqueue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+)
+
+// PostgresQueue stores jobs in the job_queue table. Claims use
+// FOR UPDATE SKIP LOCKED so any number of workers can poll concurrently.
+type PostgresQueue struct {
+	db      *sql.DB
+	backoff retry.Backoff
+}
+
+// NewPostgresQueue creates a queue backed by Postgres
+func NewPostgresQueue(db *sql.DB, backoff retry.Backoff) *PostgresQueue {
+	return &PostgresQueue{db: db, backoff: backoff}
+}
+
+// Enqueue adds a job to the queue
+func (q *PostgresQueue) Enqueue(ctx context.Context, queue string, payload interface{}, opts EnqueueOptions) (string, error) {
+	data, err := encodePayload(payload)
+	if err != nil {
+		return "", err
+	}
+
+	jobID := newID("job_")
+	query := `
+		INSERT INTO job_queue (job_id, queue, payload, status, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + make_interval(secs => $6))
+	`
+
+	if _, err := q.db.ExecContext(ctx, query, jobID, queue, data, StatusPending, maxAttempts(opts), opts.Delay.Seconds()); err != nil {
+		return "", fmt.Errorf("failed to enqueue %s job: %w", queue, err)
+	}
+	return jobID, nil
+}
+
+// Claim leases the oldest ready job, including running jobs whose visibility
+// timeout has passed. Expired jobs without attempts left are dead-lettered.
+func (q *PostgresQueue) Claim(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	deadLetter := `
+		UPDATE job_queue
+		SET status = $2, lease = NULL, last_error = COALESCE(last_error, 'visibility timeout expired'), updated_at = CURRENT_TIMESTAMP
+		WHERE queue = $1 AND status = $3 AND visible_at <= CURRENT_TIMESTAMP AND attempts >= max_attempts
+	`
+	if _, err := q.db.ExecContext(ctx, deadLetter, queue, StatusDead, StatusRunning); err != nil {
+		return nil, fmt.Errorf("failed to dead-letter expired %s jobs: %w", queue, err)
+	}
+
+	query := `
+		UPDATE job_queue
+		SET status = $2, attempts = attempts + 1, lease = $3,
+			visible_at = CURRENT_TIMESTAMP + make_interval(secs => $4),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = (
+			SELECT job_id FROM job_queue
+			WHERE queue = $1
+				AND ((status = $5 AND run_at <= CURRENT_TIMESTAMP)
+					OR (status = $2 AND visible_at <= CURRENT_TIMESTAMP))
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING job_id, queue, payload, attempts, max_attempts, COALESCE(last_error, ''), created_at
+	`
+
+	job := Job{Lease: newID("lease_")}
+	var payload []byte
+	err := q.db.QueryRowContext(ctx, query, queue, StatusRunning, job.Lease, visibility.Seconds(), StatusPending).Scan(
+		&job.ID, &job.Queue, &payload, &job.Attempts, &job.MaxAttempts, &job.LastError, &job.EnqueuedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // Nothing ready
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s job: %w", queue, err)
+	}
+	job.Payload = payload
+
+	return &job, nil
+}
+
+// Ack deletes a processed job
+func (q *PostgresQueue) Ack(ctx context.Context, job *Job) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM job_queue WHERE job_id = $1 AND lease = $2`, job.ID, job.Lease)
+	if err != nil {
+		return fmt.Errorf("failed to ack job %s: %w", job.ID, err)
+	}
+	return leaseResult(result)
+}
+
+// Nack schedules a retry after the backoff delay or dead-letters the job
+func (q *PostgresQueue) Nack(ctx context.Context, job *Job, cause error) error {
+	query := `
+		UPDATE job_queue
+		SET status = CASE WHEN attempts >= max_attempts THEN $3 ELSE $4 END,
+			run_at = CURRENT_TIMESTAMP + make_interval(secs => $5),
+			last_error = $6, lease = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1 AND lease = $2
+	`
+
+	result, err := q.db.ExecContext(ctx, query, job.ID, job.Lease, StatusDead, StatusPending,
+		q.backoff.Delay(job.Attempts).Seconds(), errorMessage(cause))
+	if err != nil {
+		return fmt.Errorf("failed to nack job %s: %w", job.ID, err)
+	}
+	return leaseResult(result)
+}
+
+// Depth counts pending jobs
+func (q *PostgresQueue) Depth(ctx context.Context, queue string) (int64, error) {
+	var depth int64
+	query := `SELECT COUNT(*) FROM job_queue WHERE queue = $1 AND status = $2`
+	if err := q.db.QueryRowContext(ctx, query, queue, StatusPending).Scan(&depth); err != nil {
+		return 0, fmt.Errorf("failed to count %s jobs: %w", queue, err)
+	}
+	return depth, nil
+}
+
+// leaseResult maps a lease-guarded write that matched no row to ErrLeaseLost
+func leaseResult(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check job lease: %w", err)
+	}
+	if affected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
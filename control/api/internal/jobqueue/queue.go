@@ -0,0 +1,137 @@
+package jobqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+	"github.com/redis/go-redis/v9"
+)
+
+// Job statuses
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDead    = "dead"
+)
+
+// DefaultMaxAttempts is used when a job is enqueued without a limit
+const DefaultMaxAttempts = 5
+
+// ErrLeaseLost is returned when acknowledging a job whose visibility timeout
+// expired and which may since have been claimed by another worker
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Job is a unit of background work claimed from a queue
+type Job struct {
+	ID          string          `json:"job_id"`
+	Queue       string          `json:"queue"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+
+	// Lease identifies this claim; Ack and Nack are rejected once another claim replaces it
+	Lease string `json:"-"`
+}
+
+// Decode unmarshals the job payload into v
+func (j *Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s job payload: %w", j.Queue, err)
+	}
+	return nil
+}
+
+// EnqueueOptions controls how a job is scheduled
+type EnqueueOptions struct {
+	Delay       time.Duration // Wait before the job becomes claimable
+	MaxAttempts int           // Claims before the job is dead-lettered (default DefaultMaxAttempts)
+}
+
+// Queue is a durable at-least-once job queue. A claimed job is invisible to
+// other workers until it is acknowledged or its visibility timeout passes, at
+// which point it is handed out again. Handlers must therefore be idempotent.
+type Queue interface {
+	// Enqueue adds a job whose payload is marshalled to JSON and returns its ID
+	Enqueue(ctx context.Context, queue string, payload interface{}, opts EnqueueOptions) (string, error)
+	// Claim leases the next ready job for visibility, or returns nil if there is none
+	Claim(ctx context.Context, queue string, visibility time.Duration) (*Job, error)
+	// Ack removes a successfully processed job
+	Ack(ctx context.Context, job *Job) error
+	// Nack records a failure and schedules a retry after the backoff delay,
+	// or dead-letters the job once it has used all its attempts
+	Nack(ctx context.Context, job *Job, cause error) error
+	// Depth counts jobs waiting to be claimed, including delayed retries
+	Depth(ctx context.Context, queue string) (int64, error)
+}
+
+// DefaultBackoff retries after roughly 5s, 10s, 20s, ... up to 10 minutes
+var DefaultBackoff = retry.Backoff{Base: 5 * time.Second, Max: 10 * time.Minute, Jitter: 0.2}
+
+// encodePayload marshals a job payload, passing raw JSON through unchanged
+func encodePayload(payload interface{}) ([]byte, error) {
+	if raw, ok := payload.(json.RawMessage); ok {
+		return raw, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	return data, nil
+}
+
+// maxAttempts applies the default attempt limit
+func maxAttempts(opts EnqueueOptions) int {
+	if opts.MaxAttempts < 1 {
+		return DefaultMaxAttempts
+	}
+	return opts.MaxAttempts
+}
+
+// newID returns a random identifier for jobs and leases
+func newID(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("jobqueue: failed to generate id: %v", err))
+	}
+	return prefix + hex.EncodeToString(buf)
+}
+
+// errorMessage returns the message recorded for a failed attempt
+func errorMessage(cause error) string {
+	if cause == nil {
+		return "unknown error"
+	}
+	return cause.Error()
+}
+
+// Queue backends
+const (
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+// New creates a queue for the named backend. Postgres suits small
+// deployments; Redis gives lower claim latency under heavy polling.
+func New(backend string, db *sql.DB, client redis.UniversalClient, backoff retry.Backoff) (Queue, error) {
+	switch backend {
+	case BackendPostgres, "":
+		return NewPostgresQueue(db, backoff), nil
+	case BackendRedis:
+		if client == nil {
+			return nil, errors.New("redis job queue requires a Redis connection")
+		}
+		return NewRedisQueue(client, backoff), nil
+	default:
+		return nil, fmt.Errorf("unknown job queue backend %q", backend)
+	}
+}
@@ -0,0 +1,215 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+	"github.com/redis/go-redis/v9"
+)
+
+// claimScript requeues claims whose visibility timeout passed (dead-lettering
+// those out of attempts), then moves the first ready job to the in-flight set.
+// KEYS: ready, inflight, dead. ARGV: now ms, visibility deadline ms, lease, job key prefix.
+//
+// Which job hashes it touches is only known once the sets are read, so they
+// cannot be declared in KEYS. They are built from the prefix and share the
+// queue's {queue} hash tag, so they live in the slot of the declared keys and
+// Redis Cluster runs the script on one node. This relies on Redis not
+// checking that scripts declare every key: servers that enforce it, such as
+// Dragonfly without allow-undeclared-keys, refuse the script. Ack and Nack
+// know their job and declare it.
+var claimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	local key = ARGV[4] .. id
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('HDEL', key, 'lease')
+	local attempts = tonumber(redis.call('HGET', key, 'attempts') or '0')
+	local max = tonumber(redis.call('HGET', key, 'max_attempts') or '0')
+	if attempts >= max then
+		redis.call('HSET', key, 'status', 'dead', 'last_error', 'visibility timeout expired')
+		redis.call('ZADD', KEYS[3], ARGV[1], id)
+	else
+		redis.call('HSET', key, 'status', 'pending')
+		redis.call('ZADD', KEYS[1], ARGV[1], id)
+	end
+end
+
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+
+local id = ids[1]
+local key = ARGV[4] .. id
+redis.call('ZREM', KEYS[1], id)
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+redis.call('HINCRBY', key, 'attempts', 1)
+redis.call('HSET', key, 'status', 'running', 'lease', ARGV[3])
+local fields = redis.call('HGETALL', key)
+table.insert(fields, 1, id)
+return fields
+`)
+
+// ackScript deletes a job if the caller still holds its lease.
+// KEYS: inflight, job. ARGV: job ID, lease.
+var ackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'lease') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+// nackScript reschedules or dead-letters a job if the caller still holds its lease.
+// KEYS: ready, inflight, dead, job. ARGV: job ID, lease, retry-at ms, error, now ms.
+var nackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[4], 'lease') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[4], 'lease')
+redis.call('HSET', KEYS[4], 'last_error', ARGV[4])
+local attempts = tonumber(redis.call('HGET', KEYS[4], 'attempts'))
+local max = tonumber(redis.call('HGET', KEYS[4], 'max_attempts'))
+if attempts >= max then
+	redis.call('HSET', KEYS[4], 'status', 'dead')
+	redis.call('ZADD', KEYS[3], ARGV[5], ARGV[1])
+else
+	redis.call('HSET', KEYS[4], 'status', 'pending')
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+end
+return 1
+`)
+
+// RedisQueue keeps each queue in sorted sets scored by time: ready jobs by
+// when they may run, in-flight jobs by visibility deadline, and dead jobs by
+// when they died. Job bodies are hashes. Every key of a queue shares the
+// {queue} hash tag so the scripts stay on one Redis Cluster slot.
+type RedisQueue struct {
+	client  redis.UniversalClient
+	backoff retry.Backoff
+}
+
+// NewRedisQueue creates a queue backed by Redis
+func NewRedisQueue(client redis.UniversalClient, backoff retry.Backoff) *RedisQueue {
+	return &RedisQueue{client: client, backoff: backoff}
+}
+
+func queueKey(queue, set string) string {
+	return "jobqueue:{" + queue + "}:" + set
+}
+
+func jobKeyPrefix(queue string) string {
+	return queueKey(queue, "job:")
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+// Enqueue adds a job to the queue
+func (q *RedisQueue) Enqueue(ctx context.Context, queue string, payload interface{}, opts EnqueueOptions) (string, error) {
+	data, err := encodePayload(payload)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	jobID := newID("job_")
+
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, jobKeyPrefix(queue)+jobID,
+			"payload", string(data),
+			"status", StatusPending,
+			"attempts", 0,
+			"max_attempts", maxAttempts(opts),
+			"enqueued_at", millis(now),
+		)
+		pipe.ZAdd(ctx, queueKey(queue, "ready"), redis.Z{Score: float64(millis(now.Add(opts.Delay))), Member: jobID})
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue %s job: %w", queue, err)
+	}
+	return jobID, nil
+}
+
+// Claim leases the next ready job
+func (q *RedisQueue) Claim(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	now := time.Now()
+	lease := newID("lease_")
+
+	keys := []string{queueKey(queue, "ready"), queueKey(queue, "inflight"), queueKey(queue, "dead")}
+	result, err := claimScript.Run(ctx, q.client, keys, millis(now), millis(now.Add(visibility)), lease, jobKeyPrefix(queue)).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil // Nothing ready
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s job: %w", queue, err)
+	}
+
+	job := &Job{ID: fmt.Sprint(result[0]), Queue: queue, Lease: lease}
+	for i := 1; i+1 < len(result); i += 2 {
+		value := fmt.Sprint(result[i+1])
+		switch fmt.Sprint(result[i]) {
+		case "payload":
+			job.Payload = []byte(value)
+		case "attempts":
+			job.Attempts, _ = strconv.Atoi(value)
+		case "max_attempts":
+			job.MaxAttempts, _ = strconv.Atoi(value)
+		case "last_error":
+			job.LastError = value
+		case "enqueued_at":
+			ms, _ := strconv.ParseInt(value, 10, 64)
+			job.EnqueuedAt = time.UnixMilli(ms)
+		}
+	}
+
+	return job, nil
+}
+
+// Ack deletes a processed job
+func (q *RedisQueue) Ack(ctx context.Context, job *Job) error {
+	keys := []string{queueKey(job.Queue, "inflight"), jobKeyPrefix(job.Queue) + job.ID}
+	acked, err := ackScript.Run(ctx, q.client, keys, job.ID, job.Lease).Int()
+	if err != nil {
+		return fmt.Errorf("failed to ack job %s: %w", job.ID, err)
+	}
+	if acked == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Nack schedules a retry after the backoff delay or dead-letters the job
+func (q *RedisQueue) Nack(ctx context.Context, job *Job, cause error) error {
+	now := time.Now()
+	retryAt := now.Add(q.backoff.Delay(job.Attempts))
+
+	keys := []string{queueKey(job.Queue, "ready"), queueKey(job.Queue, "inflight"), queueKey(job.Queue, "dead"), jobKeyPrefix(job.Queue) + job.ID}
+	nacked, err := nackScript.Run(ctx, q.client, keys,
+		job.ID, job.Lease, millis(retryAt), errorMessage(cause), millis(now)).Int()
+	if err != nil {
+		return fmt.Errorf("failed to nack job %s: %w", job.ID, err)
+	}
+	if nacked == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Depth counts jobs in the ready set
+func (q *RedisQueue) Depth(ctx context.Context, queue string) (int64, error) {
+	depth, err := q.client.ZCard(ctx, queueKey(queue, "ready")).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s jobs: %w", queue, err)
+	}
+	return depth, nil
+}
@@ -0,0 +1,155 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisQueue(t *testing.T, backoff retry.Backoff) (*RedisQueue, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisQueue(client, backoff), server
+}
+
+func TestRedisQueue_EnqueueClaimAck(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t, retry.Backoff{Base: time.Hour})
+
+	jobID, err := q.Enqueue(ctx, "exports", map[string]string{"export_id": "export_1"}, EnqueueOptions{})
+	require.NoError(t, err)
+	depth, err := q.Depth(ctx, "exports")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), depth)
+
+	job, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, jobID, job.ID)
+	assert.Equal(t, "exports", job.Queue)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, DefaultMaxAttempts, job.MaxAttempts)
+	var payload map[string]string
+	require.NoError(t, job.Decode(&payload))
+	assert.Equal(t, "export_1", payload["export_id"])
+	assert.Equal(t, StatusRunning, server.HGet(jobKeyPrefix("exports")+jobID, "status"))
+
+	again, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, again, "A claimed job is invisible to other workers")
+
+	require.NoError(t, q.Ack(ctx, job))
+	assert.False(t, server.Exists(jobKeyPrefix("exports")+jobID), "An acked job is deleted")
+	inflight, _ := server.ZMembers(queueKey("exports", "inflight"))
+	assert.Empty(t, inflight)
+	assert.ErrorIs(t, q.Ack(ctx, job), ErrLeaseLost, "A job is acked once")
+}
+
+func TestRedisQueue_Delay(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestRedisQueue(t, retry.Backoff{Base: time.Hour})
+
+	_, err := q.Enqueue(ctx, "exports", "later", EnqueueOptions{Delay: time.Hour})
+	require.NoError(t, err)
+	job, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, job, "A delayed job is not claimable before its delay")
+	depth, err := q.Depth(ctx, "exports")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), depth, "Delayed jobs count towards the depth")
+}
+
+func TestRedisQueue_NackBacksOff(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t, retry.Backoff{Base: time.Hour})
+
+	jobID, err := q.Enqueue(ctx, "exports", "payload", EnqueueOptions{})
+	require.NoError(t, err)
+	job, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+
+	before := time.Now()
+	require.NoError(t, q.Nack(ctx, job, errors.New("upstream unavailable")))
+	key := jobKeyPrefix("exports") + jobID
+	assert.Equal(t, StatusPending, server.HGet(key, "status"))
+	assert.Equal(t, "upstream unavailable", server.HGet(key, "last_error"))
+	assert.Empty(t, server.HGet(key, "lease"))
+
+	score, err := server.ZScore(queueKey("exports", "ready"), jobID)
+	require.NoError(t, err)
+	retryAt := time.UnixMilli(int64(score))
+	assert.WithinDuration(t, before.Add(time.Hour), retryAt, 5*time.Second, "The retry waits for the backoff delay")
+
+	next, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, next, "A retry is not claimable before its backoff")
+	assert.ErrorIs(t, q.Nack(ctx, job, errors.New("again")), ErrLeaseLost, "A released claim cannot be nacked twice")
+}
+
+func TestRedisQueue_DeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t, retry.Backoff{}) // Retries are claimable at once
+
+	jobID, err := q.Enqueue(ctx, "exports", "payload", EnqueueOptions{MaxAttempts: 2})
+	require.NoError(t, err)
+	key := jobKeyPrefix("exports") + jobID
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		job, err := q.Claim(ctx, "exports", time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, job, "attempt %d", attempt)
+		assert.Equal(t, attempt, job.Attempts)
+		require.NoError(t, q.Nack(ctx, job, errors.New("bad payload")))
+	}
+
+	assert.Equal(t, StatusDead, server.HGet(key, "status"))
+	dead, err := server.ZMembers(queueKey("exports", "dead"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{jobID}, dead)
+	job, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, job, "A dead job is not claimed again")
+}
+
+func TestRedisQueue_VisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t, retry.Backoff{})
+
+	jobID, err := q.Enqueue(ctx, "exports", "payload", EnqueueOptions{MaxAttempts: 2})
+	require.NoError(t, err)
+	key := jobKeyPrefix("exports") + jobID
+
+	// The first worker dies holding the job
+	lost, err := q.Claim(ctx, "exports", time.Millisecond)
+	require.NoError(t, err)
+	require.NotNil(t, lost)
+	time.Sleep(5 * time.Millisecond)
+
+	job, err := q.Claim(ctx, "exports", time.Millisecond)
+	require.NoError(t, err)
+	require.NotNil(t, job, "An expired claim is requeued and claimed again")
+	assert.Equal(t, jobID, job.ID)
+	assert.Equal(t, 2, job.Attempts)
+	assert.NotEqual(t, lost.Lease, job.Lease)
+	assert.ErrorIs(t, q.Ack(ctx, lost), ErrLeaseLost, "The expired claim can no longer ack")
+	time.Sleep(5 * time.Millisecond)
+
+	// Out of attempts, the second expiry dead-letters it
+	none, err := q.Claim(ctx, "exports", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+	assert.Equal(t, StatusDead, server.HGet(key, "status"))
+	assert.Equal(t, "visibility timeout expired", server.HGet(key, "last_error"))
+	dead, err := server.ZMembers(queueKey("exports", "dead"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{jobID}, dead)
+}
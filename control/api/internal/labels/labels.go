@@ -0,0 +1,105 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Resource types that accept labels
+const (
+	ResourceCampaign = "campaign"
+	ResourceBooking  = "booking"
+	ResourceCreative = "creative"
+	ResourceSurface  = "surface"
+)
+
+var resourceTypes = map[string]bool{
+	ResourceCampaign: true,
+	ResourceBooking:  true,
+	ResourceCreative: true,
+	ResourceSurface:  true,
+}
+
+// MaxPerResource bounds how many labels one resource may carry
+const MaxPerResource = 64
+
+// maxValueLength bounds the length of a label value
+const maxValueLength = 255
+
+// keyPattern accepts lowercase keys such as "team", "region" or "acme.io/tier"
+var keyPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,61}[a-z0-9])?$`)
+
+// Set is a collection of free-form key=value labels
+type Set map[string]string
+
+// ValidResourceType reports whether resources of the given type can be labelled
+func ValidResourceType(resourceType string) bool {
+	return resourceTypes[resourceType]
+}
+
+// ValidKey reports whether key is a well-formed label key
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Validate checks label keys, values and count
+func (s Set) Validate() error {
+	if len(s) > MaxPerResource {
+		return fmt.Errorf("too many labels (%d, max %d)", len(s), MaxPerResource)
+	}
+	for key, value := range s {
+		if !ValidKey(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if len(value) > maxValueLength {
+			return fmt.Errorf("label %q value exceeds %d characters", key, maxValueLength)
+		}
+	}
+	return nil
+}
+
+// Keys returns the label keys in sorted order
+func (s Set) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String formats the set as a selector, e.g. "region=emea,team=sports"
+func (s Set) String() string {
+	pairs := make([]string, 0, len(s))
+	for _, key := range s.Keys() {
+		pairs = append(pairs, key+"="+s[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ParseSelector parses a comma-separated list of key=value requirements.
+// An empty selector matches everything.
+func ParseSelector(selector string) (Set, error) {
+	set := Set{}
+	if strings.TrimSpace(selector) == "" {
+		return set, nil
+	}
+
+	for _, pair := range strings.Split(selector, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label selector %q, expected key=value", pair)
+		}
+		if _, dup := set[key]; dup {
+			return nil, fmt.Errorf("label %q appears more than once in selector", key)
+		}
+		set[key] = value
+	}
+
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
@@ -0,0 +1,227 @@
+// Package manifestcache keeps decorated VOD playlists, so one asked for
+// again with the same bookings is served without fetching and decorating
+// it again. Entries are keyed by the title, a hash of the placements the
+// playlist was decorated with, its program start and where it came from,
+// so an entry is never served once the title's bookings change. Dropping
+// a title's entries when Postgres announces a booking change frees them
+// early rather than keeping them until they expire.
+//
+// Live playlists change with every refresh and are not cached.
+package manifestcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/decorate"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultTTL is how long a decorated playlist is kept
+const DefaultTTL = 10 * time.Minute
+
+// ErrUnavailable is returned when the cache cannot be reached. Playlists
+// are then decorated as if nothing were cached.
+var ErrUnavailable = errors.New("manifest cache unavailable")
+
+// Entry is a decorated playlist
+type Entry struct {
+	Playlist   []byte   `json:"playlist"`
+	Placements []string `json:"placements"` // IDs of the placements signaled
+	ETag       string   `json:"etag"`
+}
+
+// NewEntry returns the entry of a decorated playlist, with an ETag of its
+// bytes
+func NewEntry(playlist []byte, placements []string) *Entry {
+	sum := sha256.Sum256(playlist)
+	return &Entry{Playlist: playlist, Placements: placements, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// BookingSet hashes the placements a title's playlists are decorated with
+func BookingSet(placements []decorate.Placement) string {
+	// Placements are plain values, which encoding/json cannot fail on
+	data, _ := json.Marshal(placements)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Source names a playlist fetched from url
+func Source(url string) string {
+	return "url:" + url
+}
+
+// BodySource names a playlist sent as the body, by its contents
+func BodySource(playlist []byte) string {
+	sum := sha256.Sum256(playlist)
+	return "body:" + hex.EncodeToString(sum[:])
+}
+
+// Key identifies a title's playlist from source decorated with bookingSet
+// for a program starting at programStart
+func Key(bookingSet string, programStart time.Time, source string) string {
+	sum := sha256.Sum256([]byte(bookingSet + "\x00" + programStart.UTC().Format(time.RFC3339Nano) + "\x00" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// Cache keeps decorated playlists by title
+type Cache interface {
+	// Get returns the entry of a title under key, or nil when there is none
+	Get(ctx context.Context, titleID, key string) (*Entry, error)
+	// Set keeps an entry of a title under key
+	Set(ctx context.Context, titleID, key string, entry *Entry) error
+	// Invalidate drops every entry of a title
+	Invalidate(ctx context.Context, titleID string) error
+}
+
+// RedisCache keeps entries in Redis, shared by every gateway instance. A
+// title's entries and the set indexing them share a hash slot, so it works
+// on Redis Cluster.
+type RedisCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewRedisCache creates a cache backed by Redis keeping entries for ttl
+func NewRedisCache(client redis.UniversalClient, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+// titleKey names the set of a title's entries in Redis
+func titleKey(titleID string) string {
+	return "manifestcache:{" + titleID + "}"
+}
+
+// entryKey names an entry in Redis
+func entryKey(titleID, key string) string {
+	return titleKey(titleID) + ":" + key
+}
+
+// Get returns an entry
+func (r *RedisCache) Get(ctx context.Context, titleID, key string) (*Entry, error) {
+	data, err := r.client.Get(ctx, entryKey(titleID, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest cache entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Set keeps an entry and indexes it under its title, whose index outlives
+// its newest entry
+func (r *RedisCache) Set(ctx context.Context, titleID, key string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest cache entry: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, entryKey(titleID, key), data, r.ttl)
+		pipe.SAdd(ctx, titleKey(titleID), key)
+		pipe.Expire(ctx, titleKey(titleID), r.ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Invalidate drops a title's entries and their index
+func (r *RedisCache) Invalidate(ctx context.Context, titleID string) error {
+	keys, err := r.client.SMembers(ctx, titleKey(titleID)).Result()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	names := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		names = append(names, entryKey(titleID, key))
+	}
+	names = append(names, titleKey(titleID))
+	if err := r.client.Del(ctx, names...).Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// MemoryCache keeps entries in process memory. Each gateway instance keeps
+// its own, so use RedisCache behind a load balancer.
+type MemoryCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]map[string]memoryEntry // By title and key
+	swept   time.Time
+}
+
+type memoryEntry struct {
+	entry     *Entry
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an in-memory cache keeping entries for ttl
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: make(map[string]map[string]memoryEntry)}
+}
+
+// Get returns an entry
+func (m *MemoryCache) Get(ctx context.Context, titleID, key string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[titleID][key]
+	if !ok || !time.Now().Before(e.expiresAt) {
+		return nil, nil
+	}
+	return e.entry, nil
+}
+
+// Set keeps an entry
+func (m *MemoryCache) Set(ctx context.Context, titleID, key string, entry *Entry) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	if m.entries[titleID] == nil {
+		m.entries[titleID] = make(map[string]memoryEntry)
+	}
+	m.entries[titleID][key] = memoryEntry{entry: entry, expiresAt: now.Add(m.ttl)}
+	return nil
+}
+
+// Invalidate drops a title's entries
+func (m *MemoryCache) Invalidate(ctx context.Context, titleID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, titleID)
+	return nil
+}
+
+// sweep drops expired entries, at most once a minute
+func (m *MemoryCache) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for titleID, entries := range m.entries {
+		for key, e := range entries {
+			if !now.Before(e.expiresAt) {
+				delete(entries, key)
+			}
+		}
+		if len(entries) == 0 {
+			delete(m.entries, titleID)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package manifestlog keeps a sample of the playlists the gateway decorates,
+// both the manifest it was given and the one it served, so support can
+// fetch the exact bytes a partner saw when they report a bad playlist.
+// Captures are sampled per playback session and expire after a short TTL,
+// when a janitor deletes them and their objects.
+package manifestlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	mathrand "math/rand"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Manifests kept for each capture
+const (
+	KindOriginal  = "original"  // As given to the gateway
+	KindDecorated = "decorated" // As served, with placements signaled
+)
+
+// Kinds lists the manifests kept for each capture
+var Kinds = []string{KindOriginal, KindDecorated}
+
+// DefaultTTL is how long captures are kept by default
+const DefaultTTL = 72 * time.Hour
+
+// PurgeInterval is how often expired captures are deleted
+const PurgeInterval = time.Hour
+
+// Config sets how many playlists are captured and for how long
+type Config struct {
+	Percent float64       // Share of playback sessions captured; 0 disables capture
+	TTL     time.Duration // How long captures are kept
+}
+
+// Enabled reports whether playlists are captured
+func (c Config) Enabled() bool {
+	return c.Percent > 0
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	if c.Percent < 0 || c.Percent > 100 {
+		return fmt.Errorf("manifest capture percent must be between 0 and 100")
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("manifest capture TTL must be positive")
+	}
+	return nil
+}
+
+// Capture is a decorated playlist kept for support
+type Capture struct {
+	CaptureID     string    `json:"capture_id"`
+	SessionID     string    `json:"session_id,omitempty"`
+	TitleID       string    `json:"title_id"`
+	OrgID         string    `json:"org_id,omitempty"`
+	ContentType   string    `json:"content_type"`
+	OriginalSize  int64     `json:"original_size_bytes"`
+	DecoratedSize int64     `json:"decorated_size_bytes"`
+	Placements    int       `json:"placements"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Query selects the captures of a playback session or title, newest first
+type Query struct {
+	SessionID string
+	TitleID   string
+	Limit     int
+}
+
+// ObjectKey returns the object storage key of one manifest of a capture
+func ObjectKey(captureID, kind string) string {
+	return "manifest-captures/" + captureID + "/" + kind
+}
+
+// Store indexes captures and finds the expired ones
+type Store interface {
+	CreateManifestCapture(c *Capture) error
+	ExpiredManifestCaptures(before time.Time, limit int) ([]string, error)
+	DeleteManifestCaptures(captureIDs []string) error
+}
+
+// Recorder captures a sample of decorated playlists. A nil Recorder
+// captures nothing.
+type Recorder struct {
+	config  Config
+	store   Store
+	objects storage.Store
+}
+
+// NewRecorder creates a recorder keeping its captures in objects, or nil
+// when capture is disabled
+func NewRecorder(config Config, store Store, objects storage.Store) *Recorder {
+	if !config.Enabled() {
+		return nil
+	}
+	return &Recorder{config: config, store: store, objects: objects}
+}
+
+// Sampled reports whether the playlists of a playback session are
+// captured. Sessions are sampled by a hash of their ID, so every playlist
+// of a captured session is kept; requests without one are sampled at
+// random.
+func (r *Recorder) Sampled(sessionID string) bool {
+	if r == nil {
+		return false
+	}
+	if sessionID == "" {
+		return mathrand.Float64()*100 < r.config.Percent
+	}
+	h := fnv.New32a()
+	h.Write([]byte(sessionID))
+	return float64(h.Sum32()%10000) < r.config.Percent*100
+}
+
+// Record keeps both manifests of a capture and indexes it, filling in its
+// ID, sizes and expiry
+func (r *Recorder) Record(ctx context.Context, c *Capture, original, decorated []byte) error {
+	if r == nil {
+		return nil
+	}
+	c.CaptureID = newCaptureID()
+	c.OriginalSize = int64(len(original))
+	c.DecoratedSize = int64(len(decorated))
+	c.CreatedAt = time.Now().UTC()
+	c.ExpiresAt = c.CreatedAt.Add(r.config.TTL)
+
+	manifests := map[string][]byte{KindOriginal: original, KindDecorated: decorated}
+	for _, kind := range Kinds {
+		if err := r.objects.Put(ctx, ObjectKey(c.CaptureID, kind), manifests[kind], c.ContentType); err != nil {
+			return fmt.Errorf("failed to store %s manifest: %w", kind, err)
+		}
+	}
+	return r.store.CreateManifestCapture(c)
+}
+
+// newCaptureID returns a random capture identifier
+func newCaptureID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("manifestlog: failed to generate id: %v", err))
+	}
+	return "capture_" + hex.EncodeToString(buf)
+}
+
+// janitorBatch bounds the captures deleted at once
+const janitorBatch = 500
+
+// Janitor deletes expired captures and their manifests
+type Janitor struct {
+	store    Store
+	objects  storage.Store
+	interval time.Duration
+}
+
+// NewJanitor creates a janitor
+func NewJanitor(store Store, objects storage.Store, interval time.Duration) *Janitor {
+	return &Janitor{store: store, objects: objects, interval: interval}
+}
+
+// Run deletes expired captures immediately and then every interval until
+// ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := j.Purge(ctx, time.Now().UTC())
+		if err != nil {
+			logrus.WithError(err).Error("Failed to delete expired manifest captures")
+		} else if deleted > 0 {
+			logrus.WithField("deleted", deleted).Info("Deleted expired manifest captures")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the captures expired before now and returns how many were
+// deleted. Manifests are deleted before their capture, so one whose
+// objects could not be deleted is tried again next time.
+func (j *Janitor) Purge(ctx context.Context, now time.Time) (int, error) {
+	deleted := 0
+	for ctx.Err() == nil {
+		expired, err := j.store.ExpiredManifestCaptures(now, janitorBatch)
+		if err != nil {
+			return deleted, err
+		}
+		if len(expired) == 0 {
+			break
+		}
+		for _, captureID := range expired {
+			for _, kind := range Kinds {
+				err := j.objects.Delete(ctx, ObjectKey(captureID, kind))
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return deleted, fmt.Errorf("failed to delete %s manifest of %s: %w", kind, captureID, err)
+				}
+			}
+		}
+		if err := j.store.DeleteManifestCaptures(expired); err != nil {
+			return deleted, err
+		}
+		deleted += len(expired)
+		if len(expired) < janitorBatch {
+			break
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,440 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// PlacementOpportunities counts placement decision requests per title.
+	// Per-surface rates are kept in decision_events, not as label values.
+	PlacementOpportunities = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "placement_opportunities_total",
+		Help:      "Placement decision opportunities reported by players and edge workers.",
+	}, []string{"title_id"})
+
+	// PlacementDecisionsServed counts opportunities that were filled with a booking
+	PlacementDecisionsServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "placement_decisions_served_total",
+		Help:      "Placement opportunities filled with a booked creative.",
+	}, []string{"title_id"})
+
+	// PlacementServeErrors counts opportunities that failed to serve, by one
+	// of a fixed set of error codes
+	PlacementServeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "placement_serve_errors_total",
+		Help:      "Placement opportunities that failed to serve, by error code (unknown codes as other).",
+	}, []string{"title_id", "error_code"})
+
+	// RenderCallbacks counts render farm callbacks by event and outcome
+	RenderCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "render_callbacks_total",
+		Help:      "Render worker callbacks by event type and outcome (applied, ignored, rejected).",
+	}, []string{"event", "outcome"})
+
+	// ApprovalDecisions counts creative approval decisions by source and
+	// outcome under the precedence policy
+	ApprovalDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "approval_decisions_total",
+		Help:      "Creative approval decisions by source (platform, delegated) and outcome (applied, overruled, stale, rejected).",
+	}, []string{"source", "outcome"})
+
+	// ExposureRollupBuckets counts hour and day buckets rewritten by the
+	// exposure rollup worker
+	ExposureRollupBuckets = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "exposure_rollup_buckets_total",
+		Help:      "Hourly and daily exposure rollup buckets recomputed from late or new events.",
+	})
+
+	// ExposureRollupThrough is when the events covered by the rollups were
+	// last received up to
+	ExposureRollupThrough = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "exposure_rollup_through_timestamp_seconds",
+		Help:      "Unix time up to which received exposure events are rolled up.",
+	})
+
+	// ReachRollupDays counts campaign day sketches rewritten by the reach
+	// rollup worker
+	ReachRollupDays = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "reach_rollup_days_total",
+		Help:      "Daily campaign reach sketches recomputed from late or new events.",
+	})
+
+	// ReachRollupThrough is when the events covered by the reach sketches
+	// were last received up to
+	ReachRollupThrough = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "reach_rollup_through_timestamp_seconds",
+		Help:      "Unix time up to which received exposure events are rolled up into reach sketches.",
+	})
+
+	// EventExportRows counts raw exposure events and log-level records exported by format and mode
+	EventExportRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "event_export_rows_total",
+		Help:      "Exposure events and log-level records exported by format (csv, parquet) and mode (stream, async, log_level).",
+	}, []string{"format", "mode"})
+
+	// ChangeNotifications counts row changes Postgres announced to this gateway by table
+	ChangeNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "change_notifications_total",
+		Help:      "Booking and surface row changes received over Postgres LISTEN/NOTIFY, by table.",
+	}, []string{"table"})
+
+	// ChangeFeedResyncs counts reconnections of the change notification
+	// listener, after which every cache it keeps fresh is dropped
+	ChangeFeedResyncs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "change_feed_resyncs_total",
+		Help:      "Times the change notification listener reconnected and caches were dropped, since changes may have been missed.",
+	})
+
+	// ManifestCacheLookups counts decorated playlists looked up in the
+	// manifest cache, by result: hit, miss or error
+	ManifestCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "manifest_cache_lookups_total",
+		Help:      "Decorated playlists looked up in the manifest cache, by result (hit, miss, error).",
+	}, []string{"result"})
+
+	// WatchConnections is the number of open booking watch connections
+	WatchConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "watch_connections",
+		Help:      "Open WebSocket connections watching bookings.",
+	})
+
+	// WatchEvents counts booking changes pushed to watch connections, by
+	// outcome: sent, or dropped because the connection was closing
+	WatchEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "watch_events_total",
+		Help:      "Booking changes pushed to watch connections, by outcome.",
+	}, []string{"outcome"})
+
+	// OpportunityStreams is the number of open opportunity stream connections
+	OpportunityStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "opportunity_streams",
+		Help:      "Open WebSocket connections streaming opportunity availability.",
+	})
+
+	// OpportunityAnnouncements counts availability changes sent to at least
+	// one opportunity stream, by change: available, withdrawn, booked, released
+	OpportunityAnnouncements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "opportunity_announcements_total",
+		Help:      "Availability changes sent to opportunity streams, by change.",
+	}, []string{"change"})
+
+	// JobQueueDepth is the number of jobs waiting in each background queue
+	JobQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "job_queue_depth",
+		Help:      "Background jobs waiting to be claimed, including delayed retries.",
+	}, []string{"queue"})
+
+	// JobsInFlight is the number of jobs currently being processed per queue
+	JobsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "jobs_in_flight",
+		Help:      "Background jobs currently being processed.",
+	}, []string{"queue"})
+
+	// JobWaitSeconds measures how long jobs waited between enqueue and claim
+	JobWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inscenium",
+		Name:      "job_wait_seconds",
+		Help:      "Time from enqueue to claim for background jobs.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600},
+	}, []string{"queue"})
+
+	// JobDurationSeconds measures job processing time by outcome
+	JobDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inscenium",
+		Name:      "job_duration_seconds",
+		Help:      "Background job processing time by outcome (succeeded, failed).",
+		Buckets:   []float64{0.05, 0.25, 1, 5, 15, 60, 300, 900},
+	}, []string{"queue", "outcome"})
+
+	// OutboundRequests counts outbound HTTP attempts per integration by outcome
+	OutboundRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "outbound_requests_total",
+		Help:      "Outbound HTTP attempts by client and outcome (2xx-5xx, error, timeout, circuit_open).",
+	}, []string{"client", "outcome"})
+
+	// OutboundRetries counts outbound HTTP retries per integration
+	OutboundRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "outbound_retries_total",
+		Help:      "Outbound HTTP requests retried after a failed attempt.",
+	}, []string{"client"})
+
+	// OutboundDurationSeconds measures outbound HTTP attempt latency per integration
+	OutboundDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inscenium",
+		Name:      "outbound_duration_seconds",
+		Help:      "Outbound HTTP attempt latency until response headers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"client"})
+
+	// DeprecatedAPIUsage counts calls that used a deprecated field, query parameter or route
+	DeprecatedAPIUsage = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "deprecated_api_usage_total",
+		Help:      "Calls using deprecated API names, by kind (field, query, route) and name.",
+	}, []string{"kind", "name"})
+
+	// ServiceAccountRequests counts requests authenticated with service-account
+	// keys by whether the key has been rotated out and is about to expire
+	ServiceAccountRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "service_account_requests_total",
+		Help:      "Requests authenticated with a service-account key, by key state (current, rotated).",
+	}, []string{"key"})
+
+	// ServiceAccountKeyExpiryWarnings counts rotated keys whose coming expiry was announced
+	ServiceAccountKeyExpiryWarnings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "service_account_key_expiry_warnings_total",
+		Help:      "Rotated service-account keys whose coming expiry was announced.",
+	})
+
+	// BookingReconciliationIssues is the number of live bookings drifting from inventory, by issue kind
+	BookingReconciliationIssues = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "booking_reconciliation_issues",
+		Help:      "Live bookings drifting from SGI inventory or ownership records, by issue kind.",
+	}, []string{"kind"})
+
+	// SchemaDriftFindings is the number of differences between the typed
+	// models and the database found by the last schema drift check, by severity
+	SchemaDriftFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "schema_drift_findings",
+		Help:      "Columns the typed models read that are missing from the database or of another type, by severity (critical, warning).",
+	}, []string{"severity"})
+
+	// BudgetCharges counts served decisions charged against campaign budgets,
+	// by the counter that decided (redis, postgres) and outcome
+	BudgetCharges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "budget_charges_total",
+		Help:      "Served decisions charged against campaign budgets by counter path (redis, postgres) and outcome (charged, exhausted).",
+	}, []string{"path", "outcome"})
+
+	// BudgetDrift measures how far Redis budget counters stray from Postgres spend
+	BudgetDrift = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "inscenium",
+		Name:      "budget_drift_amount",
+		Help:      "Absolute difference between a campaign's Redis budget counter and its Postgres spend at reconciliation, in currency units.",
+		Buckets:   []float64{0, 0.01, 0.1, 1, 10, 100, 1000},
+	})
+
+	// BudgetCorrections counts Redis budget counters reset from Postgres spend
+	BudgetCorrections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "budget_corrections_total",
+		Help:      "Redis budget counters reset to the spend recorded in Postgres.",
+	})
+
+	// ImpressionCapTakes counts served decisions checked against booking
+	// impression caps, by the counter that decided (redis, postgres) and
+	// where the impression came from
+	ImpressionCapTakes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "impression_cap_takes_total",
+		Help:      "Served decisions checked against booking impression caps by counter path (redis, postgres) and outcome (lease, pool, capped).",
+	}, []string{"path", "outcome"})
+
+	// ImpressionCapLeased counts impressions leased to edge nodes
+	ImpressionCapLeased = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "impression_cap_leased_total",
+		Help:      "Impressions of capped bookings leased to edge nodes.",
+	})
+
+	// ImpressionCapCorrections counts Redis impression counters reset from Postgres
+	ImpressionCapCorrections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "impression_cap_corrections_total",
+		Help:      "Redis impression counters reset from the deliveries and leases recorded in Postgres.",
+	})
+
+	// RateLimited counts requests refused by the rate limit or daily quota
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "rate_limited_requests_total",
+		Help:      "Authenticated requests refused with 429 by limit (rate, quota).",
+	}, []string{"limit"})
+
+	// IdempotentRequests counts requests carrying an Idempotency-Key by outcome
+	IdempotentRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "idempotent_requests_total",
+		Help:      "Requests with an Idempotency-Key by outcome (stored, replayed, in_progress, mismatched, released).",
+	}, []string{"outcome"})
+
+	// PIIViolations counts personal data found in inbound event fields, by
+	// the API key that sent it ("user" for people)
+	PIIViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "pii_violations_total",
+		Help:      "Personal data found in inbound event fields by API key, field, kind (email, phone, device_id) and action (reject, mask, report).",
+	}, []string{"key_id", "field", "kind", "action"})
+
+	// ReportPrivacyRows counts grouped report rows below their organization's
+	// minimum audience, by what happened to them
+	ReportPrivacyRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "report_privacy_rows_total",
+		Help:      "Grouped report rows below the minimum audience by outcome (suppressed, aggregated).",
+	}, []string{"outcome"})
+
+	// BookingLongPolls counts long polls of a booking's status by how they
+	// ended
+	BookingLongPolls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "booking_long_polls_total",
+		Help:      "Long polls of booking status by outcome (changed, timeout, disconnected).",
+	}, []string{"outcome"})
+
+	// PushFramesDropped counts messages dropped from the send queue of a
+	// streaming client too slow to keep up, by stream
+	PushFramesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "push_frames_dropped_total",
+		Help:      "Messages dropped from the send queues of slow streaming clients, by stream.",
+	}, []string{"stream"})
+
+	// DiscoveryReports counts health reports to service discovery by driver
+	// and outcome
+	DiscoveryReports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "discovery_reports_total",
+		Help:      "Health reports to service discovery by driver and outcome (healthy, unhealthy, failed).",
+	}, []string{"driver", "outcome"})
+
+	// IngestionStuckTitles counts unpublished titles stuck in each pipeline
+	// stage
+	IngestionStuckTitles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "ingestion_stuck_titles",
+		Help:      "Titles whose ingestion has been running or waiting in a stage for too long, by stage.",
+	}, []string{"stage"})
+
+	// IngestionFailedTitles counts unpublished titles whose pipeline failed
+	// in each stage
+	IngestionFailedTitles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "ingestion_failed_titles",
+		Help:      "Titles whose ingestion failed in a stage and has not been retried, by stage.",
+	}, []string{"stage"})
+
+	// PipelineCallbacks counts signed vision pipeline callbacks by kind and outcome
+	PipelineCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "pipeline_callbacks_total",
+		Help:      "Vision pipeline callbacks by kind and outcome (applied, invalid, rejected).",
+	}, []string{"kind", "outcome"})
+
+	// MirroredRequests counts read requests replayed against the shadow stack by route and outcome
+	MirroredRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "mirrored_requests_total",
+		Help:      "Read requests replayed against the shadow stack, by route and outcome (match, mismatch, error, dropped).",
+	}, []string{"route", "outcome"})
+
+	// DomainEventsPublished counts domain events published on the in-process event bus
+	DomainEventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "domain_events_published_total",
+		Help:      "Domain events published on the in-process event bus, by event.",
+	}, []string{"event"})
+
+	// DomainEventDeliveries counts domain events handed to subscribers by outcome
+	DomainEventDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inscenium",
+		Name:      "domain_event_deliveries_total",
+		Help:      "Domain events handed to subscribers by subscriber, event and outcome (delivered, failed, dropped).",
+	}, []string{"subscriber", "event", "outcome"})
+
+	// DomainEventDeliverySeconds measures how long subscribers take to handle an event
+	DomainEventDeliverySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inscenium",
+		Name:      "domain_event_delivery_seconds",
+		Help:      "Time subscribers take to handle a domain event.",
+		Buckets:   []float64{0.0001, 0.001, 0.01, 0.05, 0.25, 1, 5},
+	}, []string{"subscriber"})
+
+	// DomainEventQueueDepth is the number of events waiting for each asynchronous subscriber
+	DomainEventQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inscenium",
+		Name:      "domain_event_queue_depth",
+		Help:      "Domain events queued for an asynchronous subscriber.",
+	}, []string{"subscriber"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		PlacementOpportunities,
+		PlacementDecisionsServed,
+		PlacementServeErrors,
+		RenderCallbacks,
+		ApprovalDecisions,
+		ExposureRollupBuckets,
+		ExposureRollupThrough,
+		ReachRollupDays,
+		ReachRollupThrough,
+		EventExportRows,
+		ChangeNotifications,
+		ChangeFeedResyncs,
+		ManifestCacheLookups,
+		WatchConnections,
+		WatchEvents,
+		OpportunityStreams,
+		OpportunityAnnouncements,
+		JobQueueDepth,
+		JobsInFlight,
+		JobWaitSeconds,
+		JobDurationSeconds,
+		OutboundRequests,
+		OutboundRetries,
+		OutboundDurationSeconds,
+		DeprecatedAPIUsage,
+		ServiceAccountRequests,
+		ServiceAccountKeyExpiryWarnings,
+		BookingReconciliationIssues,
+		SchemaDriftFindings,
+		BudgetCharges,
+		BudgetDrift,
+		BudgetCorrections,
+		ImpressionCapTakes,
+		ImpressionCapLeased,
+		ImpressionCapCorrections,
+		RateLimited,
+		IdempotentRequests,
+		PIIViolations,
+		ReportPrivacyRows,
+		BookingLongPolls,
+		PushFramesDropped,
+		DiscoveryReports,
+		IngestionStuckTitles,
+		IngestionFailedTitles,
+		PipelineCallbacks,
+		MirroredRequests,
+		DomainEventsPublished,
+		DomainEventDeliveries,
+		DomainEventDeliverySeconds,
+		DomainEventQueueDepth,
+	)
+}
@@ -1,62 +1,314 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/serviceaccount"
+	"github.com/inscenium/inscenium/control/api/internal/user"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
 	"github.com/sirupsen/logrus"
 )
 
+// Principal types set as "principal_type" in the request context
+const (
+	PrincipalUser           = "user"
+	PrincipalServiceAccount = "service_account"
+)
+
+// ServiceAccountVerifier looks up service-account keys by key ID
+type ServiceAccountVerifier interface {
+	GetServiceAccountKey(keyID string) (*serviceaccount.Account, *serviceaccount.Key, error)
+	TouchServiceAccountKey(keyID string) error
+}
+
+// SessionRevocations lists sessions signed out before their access tokens expired
+type SessionRevocations interface {
+	Revoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// TokenAudience is the audience of the tokens issued at login
+const TokenAudience = "inscenium-api"
+
+// unauthorized rejects a request without valid credentials, with the
+// WWW-Authenticate challenge RFC 6750 asks for
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="inscenium-api"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
 // AuthRequired middleware validates JWT tokens
 func AuthRequired(jwtSecret string) gin.HandlerFunc {
+	return Authenticate(jwtSecret, nil, nil)
+}
+
+// ErrInvalidToken is returned by Verify for credentials that do not
+// authenticate a caller. Other errors are failures to check them.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity is the caller a bearer token authenticates
+type Identity struct {
+	PrincipalType    string
+	UserID           string
+	OrgID            string
+	Role             string // Users only
+	Scopes           []string
+	SessionID        string        // Users only, when sessions are checked
+	Claims           jwt.MapClaims // Users only
+	ServiceAccountID string        // Service accounts only
+	KeyID            string
+	KeyVersion       int
+	KeyExpiresAt     *time.Time // Rotated keys only
+	JSONCase         string
+}
+
+// Verify authenticates a bearer token: a JWT for users and, when accounts is
+// set, an API key for service accounts. When sessions is set, tokens must
+// belong to a session that has not been signed out. Authenticate uses it for
+// HTTP requests; other transports call it directly.
+func Verify(ctx context.Context, jwtSecret string, accounts ServiceAccountVerifier, sessions SessionRevocations, tokenString string) (*Identity, error) {
+	if accounts != nil && strings.HasPrefix(tokenString, serviceaccount.TokenPrefix) {
+		return verifyServiceAccount(accounts, tokenString)
+	}
+
+	// Parse and validate token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(jwtSecret), nil
+	}, jwt.WithAudience(TokenAudience))
+
+	if err != nil {
+		logrus.WithError(err).Warn("JWT token validation failed")
+		return nil, ErrInvalidToken
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// Extract claims. Tokens issued before users had roles carry none
+	// and must be replaced by signing in again.
+	identity := &Identity{PrincipalType: PrincipalUser}
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		role, _ := claims["role"].(string)
+		if user.ValidateRole(role) != nil {
+			logrus.WithField("user_id", claims["sub"]).Warn("JWT token has no valid role")
+			return nil, ErrInvalidToken
+		}
+		if sessions != nil {
+			if identity.SessionID, err = activeSession(ctx, sessions, claims); err != nil {
+				return nil, err
+			}
+		}
+		identity.UserID, _ = claims["sub"].(string)
+		identity.Role = role
+		identity.Scopes = user.Scopes(role)
+		identity.OrgID, _ = claims["org_id"].(string)
+		identity.Claims = claims
+	}
+	return identity, nil
+}
+
+// Authenticate validates JWT tokens for users and, when accounts is set,
+// API keys for service accounts, as Verify does, and sets the caller on the
+// request context. Browsers cannot set headers on a WebSocket handshake, so
+// there the token may be offered as a bearer.<token> subprotocol instead.
+func Authenticate(jwtSecret string, accounts ServiceAccountVerifier, sessions SessionRevocations) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if token := websocket.BearerToken(c.Request); authHeader == "" && token != "" {
+			authHeader = "Bearer " + token
+		}
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			unauthorized(c, "Authorization header required")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			unauthorized(c, "Invalid authorization header format")
 			return
 		}
 
-		tokenString := parts[1]
+		identity, err := Verify(c.Request.Context(), jwtSecret, accounts, sessions, parts[1])
+		if errors.Is(err, ErrInvalidToken) {
+			unauthorized(c, "Invalid token")
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+		c.Set("principal_type", identity.PrincipalType)
+		c.Set("user_id", identity.UserID)
+		c.Set("scopes", identity.Scopes)
+		if identity.OrgID != "" {
+			c.Set("org_id", identity.OrgID)
+		}
+		if identity.PrincipalType == PrincipalServiceAccount {
+			c.Set("service_account_id", identity.ServiceAccountID)
+			c.Set("key_id", identity.KeyID)
+			c.Set("key_version", identity.KeyVersion)
+			c.Set("json_case", identity.JSONCase)
+			// A rotated key still works until its overlap ends; tell its users when
+			if identity.KeyExpiresAt != nil {
+				c.Header("X-Inscenium-Key-Expires", identity.KeyExpiresAt.UTC().Format(time.RFC3339))
+			}
+		} else {
+			c.Set("role", identity.Role)
+			if identity.SessionID != "" {
+				c.Set("session_id", identity.SessionID)
+			}
+			if identity.Claims != nil {
+				c.Set("jwt_claims", identity.Claims)
 			}
-			return []byte(jwtSecret), nil
-		})
+		}
 
-		if err != nil {
-			logrus.WithError(err).Warn("JWT token validation failed")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Next()
+	}
+}
+
+// activeSession checks that a token's session has not been signed out and
+// returns its ID. Tokens issued before sessions carry no session ID and must
+// be replaced by signing in again.
+func activeSession(ctx context.Context, sessions SessionRevocations, claims jwt.MapClaims) (string, error) {
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		logrus.WithField("user_id", claims["sub"]).Warn("JWT token has no session")
+		return "", ErrInvalidToken
+	}
+
+	revoked, err := sessions.Revoked(ctx, sessionID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check session revocation")
+		return "", err
+	}
+	if revoked {
+		logrus.WithFields(logrus.Fields{
+			"user_id":    claims["sub"],
+			"session_id": sessionID,
+		}).Warn("JWT token of a revoked session")
+		return "", ErrInvalidToken
+	}
+	return sessionID, nil
+}
+
+// verifyServiceAccount validates a service-account API key and returns its
+// identity, organization, scopes and the key's version
+func verifyServiceAccount(accounts ServiceAccountVerifier, token string) (*Identity, error) {
+	keyID, secret, ok := serviceaccount.ParseToken(token)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	account, key, err := accounts.GetServiceAccountKey(keyID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to look up service account key")
+		return nil, err
+	}
+
+	now := time.Now()
+	if account == nil || key == nil || !key.Active(now) || account.DisabledAt != nil ||
+		!serviceaccount.VerifySecret(secret, key.SecretHash) {
+		fields := logrus.Fields{"key_id": keyID}
+		if key != nil && key.Expired(now) {
+			fields["expired_at"] = key.ExpiresAt
+		}
+		logrus.WithFields(fields).Warn("Service account authentication failed")
+		return nil, ErrInvalidToken
+	}
+
+	if err := accounts.TouchServiceAccountKey(keyID); err != nil {
+		logrus.WithError(err).WithField("key_id", keyID).Warn("Failed to record service account key use")
+	}
+
+	if key.ExpiresAt != nil {
+		metrics.ServiceAccountRequests.WithLabelValues("rotated").Inc()
+	} else {
+		metrics.ServiceAccountRequests.WithLabelValues("current").Inc()
+	}
+
+	return &Identity{
+		PrincipalType:    PrincipalServiceAccount,
+		UserID:           account.PrincipalID(),
+		OrgID:            account.OrgID,
+		Scopes:           account.Scopes,
+		ServiceAccountID: account.ID,
+		KeyID:            keyID,
+		KeyVersion:       key.Version,
+		KeyExpiresAt:     key.ExpiresAt,
+		JSONCase:         key.JSONCase,
+	}, nil
+}
+
+// RequireScope rejects callers lacking the given scope. Service accounts are
+// granted scopes directly and users through their role.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !serviceaccount.Allows(c.GetStringSlice("scopes"), scope) {
+			logrus.WithFields(logrus.Fields{
+				"audit":          "scope",
+				"user_id":        c.GetString("user_id"),
+				"principal_type": c.GetString("principal_type"),
+				"role":           c.GetString("role"),
+				"scope":          scope,
+				"path":           c.FullPath(),
+			}).Warn("Caller missing scope")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing required scope: " + scope})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+// RequireUser rejects service accounts, for routes only people may use
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("principal_type") == PrincipalServiceAccount {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available to service accounts"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["sub"])
-			c.Set("jwt_claims", claims)
+// RequireAdmin admits users with the admin role or listed in admins, and
+// service accounts with the admin:read scope, for operational endpoints
+func RequireAdmin(admins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		allowed[admin] = true
+	}
+	return func(c *gin.Context) {
+		ok := serviceaccount.Allows(c.GetStringSlice("scopes"), "admin:read")
+		if c.GetString("principal_type") != PrincipalServiceAccount {
+			ok = ok || allowed[c.GetString("user_id")]
+		}
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"audit":   "admin",
+				"user_id": c.GetString("user_id"),
+				"path":    c.FullPath(),
+			}).Warn("Non-admin denied access to admin endpoint")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
 		}
-
 		c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/origins"
+	"github.com/rs/cors"
+)
+
+// AllowedOrigins reports whether an organization allowed an origin to call a route group
+type AllowedOrigins interface {
+	Allowed(group, origin string) bool
+}
+
+// CORS answers preflight requests and sets CORS headers under the policy of
+// the route group a request's path falls in. Groups without a policy allow
+// no origins. Origins organizations allowed, if any, are added to every
+// group but admin.
+func CORS(policies map[string]origins.Policy, allowed AllowedOrigins) gin.HandlerFunc {
+	handlers := map[string]*cors.Cors{}
+	for _, group := range []string{origins.GroupPublic, origins.GroupAPI, origins.GroupAdmin} {
+		group, policy := group, policies[group]
+		handlers[group] = cors.New(cors.Options{
+			AllowOriginFunc: func(origin string) bool {
+				if policy.Allows(origin) {
+					return true
+				}
+				return allowed != nil && group != origins.GroupAdmin && allowed.Allowed(group, origin)
+			},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"*"},
+			ExposedHeaders:   append([]string{"X-Request-ID", ReplayedHeader}, RateLimitHeaders...),
+			AllowCredentials: policy.Credentials,
+			MaxAge:           300,
+		})
+	}
+
+	return func(c *gin.Context) {
+		handlers[origins.GroupOf(c.Request.URL.Path)].HandlerFunc(c.Writer, c.Request)
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Abort() // Preflight requests are answered here, before authentication
+			return
+		}
+		c.Next()
+	}
+}
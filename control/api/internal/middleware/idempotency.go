@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/idempotency"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ReplayedHeader marks responses replayed from an earlier request
+const ReplayedHeader = "Idempotent-Replayed"
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes requests carrying an Idempotency-Key safe to retry. Keys
+// are scoped to the caller and route. The response of the first request with
+// a key is kept for ttl and replayed to later requests with the same key and
+// payload; a different payload is refused with 422, and a retry arriving
+// while the first request runs gets 409. Server errors are not kept, so the
+// request can be retried. Requests without the header are unaffected.
+func Idempotency(store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientKey := c.GetHeader(idempotency.Header)
+		if clientKey == "" || store == nil {
+			c.Next()
+			return
+		}
+		if err := idempotency.CheckKey(clientKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		caller := c.GetString("user_id")
+		if caller == "" {
+			caller = "ip:" + c.ClientIP()
+		}
+		key := caller + ":" + c.Request.Method + " " + c.FullPath() + ":" + clientKey
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		ctx := c.Request.Context()
+		record, err := store.Reserve(ctx, key, fingerprint, ttl)
+		if err != nil {
+			// Running the request could repeat its effect; the client retries with the same key
+			logrus.WithError(err).Error("Idempotency store unavailable")
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Idempotency keys are unavailable, retry shortly"})
+			c.Abort()
+			return
+		}
+		if record != nil {
+			replay(c, record, fingerprint)
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// The handler failed or panicked; free the key for a retry
+			if err := store.Release(ctx, key); err != nil {
+				logrus.WithError(err).Warn("Failed to release idempotency key")
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			metrics.IdempotentRequests.WithLabelValues("released").Inc()
+			return
+		}
+		err = store.Complete(ctx, key, &idempotency.Record{
+			Fingerprint: fingerprint,
+			Response: &idempotency.Response{
+				Status:      status,
+				ContentType: writer.Header().Get("Content-Type"),
+				Body:        writer.body.Bytes(),
+			},
+		}, ttl)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to store idempotent response")
+			return
+		}
+		completed = true
+		metrics.IdempotentRequests.WithLabelValues("stored").Inc()
+	}
+}
+
+// replay answers a request whose key already holds a record
+func replay(c *gin.Context, record *idempotency.Record, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		metrics.IdempotentRequests.WithLabelValues("mismatched").Inc()
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+	case record.Response == nil:
+		metrics.IdempotentRequests.WithLabelValues("in_progress").Inc()
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+	default:
+		metrics.IdempotentRequests.WithLabelValues("replayed").Inc()
+		c.Header(ReplayedHeader, "true")
+		c.Data(record.Response.Status, record.Response.ContentType, record.Response.Body)
+	}
+}
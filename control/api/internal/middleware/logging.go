@@ -8,10 +8,12 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// RequestLogger middleware logs HTTP requests
+// RequestLogger middleware logs HTTP requests. Requests made with a
+// service-account key also log the key and its version, to follow clients
+// moving to a rotated key.
 func RequestLogger() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		logrus.WithFields(logrus.Fields{
+		fields := logrus.Fields{
 			"timestamp":    param.TimeStamp.Format(time.RFC3339),
 			"status":       param.StatusCode,
 			"latency":      param.Latency,
@@ -20,7 +22,12 @@ func RequestLogger() gin.HandlerFunc {
 			"path":         param.Path,
 			"user_agent":   param.Request.UserAgent(),
 			"request_id":   param.Request.Header.Get("X-Request-ID"),
-		}).Info("HTTP Request")
+		}
+		if keyID, ok := param.Keys["key_id"]; ok {
+			fields["key_id"] = keyID
+			fields["key_version"] = param.Keys["key_version"]
+		}
+		logrus.WithFields(fields).Info("HTTP Request")
 		return ""
 	})
 }
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/mirror"
+)
+
+// Mirror replays a sample of read requests against a shadow stack once they
+// have been answered. Replays are scrubbed of credentials and personal data
+// and sent in the background, so the primary response is never delayed or
+// changed. m may be nil, which mirrors nothing.
+func Mirror(m *mirror.Mirror) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m == nil || !m.Mirrors(c.Request) {
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		m.Enqueue(m.Capture(c.Request, c.FullPath(), c.Writer.Status()))
+	}
+}
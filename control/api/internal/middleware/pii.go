@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/sirupsen/logrus"
+)
+
+// PIIRecorder keeps per-key counts of personal data found in payloads
+type PIIRecorder interface {
+	RecordPIIViolations(orgID, principalID, keyID string, violations []pii.Violation) error
+}
+
+// ScanPII scans the fields of inbound JSON payloads named by policy for
+// personal data. Payloads with a field whose action is reject are refused
+// with 422; fields whose action is mask reach the handler hashed. Every
+// violation is counted against the caller's API key. recorder may be nil.
+func ScanPII(policy *pii.Policy, recorder PIIRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy.Empty() || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+
+		result, body, err := policy.Scan(body)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to scan payload for personal data")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+
+		if len(result.Violations) > 0 {
+			recordPIIViolations(c, recorder, result)
+		}
+		if result.Rejected {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "Payload contains personal data",
+				"violations": result.Violations,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// recordPIIViolations logs, counts and stores the violations of one request.
+// Values are never logged.
+func recordPIIViolations(c *gin.Context, recorder PIIRecorder, result *pii.Result) {
+	keyID := c.GetString("key_id")
+	keyLabel := keyID
+	if keyLabel == "" {
+		keyLabel = PrincipalUser
+	}
+	for _, v := range result.Violations {
+		metrics.PIIViolations.WithLabelValues(keyLabel, v.Field, v.Kind, v.Action).Inc()
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"audit":      "pii",
+		"user_id":    c.GetString("user_id"),
+		"key_id":     keyID,
+		"path":       c.FullPath(),
+		"violations": result.Violations,
+		"rejected":   result.Rejected,
+	}).Warn("Personal data found in payload")
+
+	if recorder == nil {
+		return
+	}
+	if err := recorder.RecordPIIViolations(c.GetString("org_id"), c.GetString("user_id"), keyID, result.Violations); err != nil {
+		logrus.WithError(err).Warn("Failed to record PII violations")
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitHeaders are the headers RateLimit sets, for CORS to expose
+var RateLimitHeaders = []string{
+	"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+	"X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+	"Retry-After",
+}
+
+// RateLimit takes each authenticated request from its caller's token bucket
+// and its organization's daily quota, reporting both in response headers
+// and refusing the request with 429 when either is used up. Either limiter
+// may be nil. A limiter that fails lets the request through without its headers.
+func RateLimit(bucket, quota ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := c.GetString("user_id")
+		if caller == "" {
+			caller = "ip:" + c.ClientIP()
+		}
+		account := c.GetString("org_id")
+		if account == "" {
+			account = caller
+		}
+
+		if !limit(c, bucket, "rate", caller, "X-RateLimit") || !limit(c, quota, "quota", account, "X-Quota") {
+			return
+		}
+		c.Next()
+	}
+}
+
+// limit takes a request from one limiter and sets its headers. It reports
+// false after refusing the request.
+func limit(c *gin.Context, limiter ratelimit.Limiter, name, key, prefix string) bool {
+	if limiter == nil {
+		return true
+	}
+	status, err := limiter.Take(c.Request.Context(), key)
+	if err != nil {
+		logrus.WithError(err).WithField("limit", name).Warn("Rate limiter unavailable, allowing request")
+		return true
+	}
+
+	c.Header(prefix+"-Limit", strconv.FormatInt(status.Limit, 10))
+	c.Header(prefix+"-Remaining", strconv.FormatInt(status.Remaining, 10))
+	c.Header(prefix+"-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+	if status.Allowed {
+		return true
+	}
+
+	metrics.RateLimited.WithLabelValues(name).Inc()
+	c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(status.RetryAfter.Seconds())), 10))
+	message := "Rate limit exceeded"
+	if name == "quota" {
+		message = "Daily request quota exceeded"
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": message})
+	c.Abort()
+	return false
+}
@@ -0,0 +1,225 @@
+// Package mirror replays a sample of production read requests against a
+// shadow stack, so new builds see production-shaped traffic before release.
+// Replays are asynchronous and best effort: they are dropped rather than
+// queued when the shadow falls behind, and never touch the primary response.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+	"github.com/inscenium/inscenium/control/api/internal/pii"
+	"github.com/inscenium/inscenium/control/api/internal/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// Header marks mirrored requests so the shadow stack can tell them apart
+const Header = "X-Inscenium-Mirrored"
+
+const (
+	// queueSize bounds the requests waiting to be replayed
+	queueSize = 1000
+	// workers replay requests concurrently
+	workers = 4
+	// timeout bounds one replay, long polls included
+	timeout = 10 * time.Second
+)
+
+// forwardedHeaders are the only request headers replayed. Credentials,
+// cookies and client addresses are never forwarded.
+var forwardedHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Content-Type",
+	"If-None-Match",
+	"If-Event-Newer-Than",
+	"X-Request-ID",
+}
+
+// Outcomes of a replay
+const (
+	OutcomeMatch    = "match"    // The shadow answered with the primary's status
+	OutcomeMismatch = "mismatch" // It answered with another status
+	OutcomeError    = "error"    // It could not be reached
+	OutcomeDropped  = "dropped"  // The queue was full
+)
+
+// Config selects the shadow stack and how much traffic it gets
+type Config struct {
+	URL     string  // Base URL of the shadow stack; empty disables mirroring
+	Percent float64 // Share of read requests replayed, above 0 and at most 100
+	Token   string  // Bearer token the shadow stack authenticates replays with
+}
+
+// Enabled reports whether requests are mirrored
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// Validate checks that the configuration is usable
+func (c Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("mirror URL must be an absolute http(s) URL")
+	}
+	if c.Percent <= 0 || c.Percent > 100 {
+		return fmt.Errorf("mirror percent must be above 0 and at most 100")
+	}
+	return nil
+}
+
+// Request is a scrubbed copy of a primary request waiting to be replayed
+type Request struct {
+	Method        string
+	URI           string // Path and query
+	Header        http.Header
+	Route         string // Route pattern, for metrics
+	PrimaryStatus int
+}
+
+// Mirror samples, scrubs and replays read requests
+type Mirror struct {
+	base    *url.URL
+	token   string
+	percent float64
+	policy  *pii.Policy
+	client  *outbound.Client
+	queue   chan Request
+}
+
+// New creates a mirror. Query parameters named by policy, and any carrying
+// personal data, are masked before a request leaves.
+func New(config Config, policy *pii.Policy) (*Mirror, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(strings.TrimRight(config.URL, "/"))
+
+	// One attempt only: a replay that fails is recorded, not retried
+	client := outbound.New("mirror", outbound.Policy{
+		Timeout:     timeout,
+		MaxAttempts: 1,
+		Breaker:     outbound.BreakerConfig{FailureThreshold: 5, OpenFor: 30 * time.Second},
+	})
+	return &Mirror{
+		base:    base,
+		token:   config.Token,
+		percent: config.Percent,
+		policy:  policy,
+		client:  client,
+		queue:   make(chan Request, queueSize),
+	}, nil
+}
+
+// Mirrors reports whether a request is a read request picked for replay
+func (m *Mirror) Mirrors(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get(Header) != "" {
+		return false // Never mirror a replay
+	}
+	if websocket.IsUpgrade(r) {
+		return false // A connection taken over for WebSocket cannot be replayed
+	}
+	return rand.Float64()*100 < m.percent
+}
+
+// Capture scrubs r into a request to replay
+func (m *Mirror) Capture(r *http.Request, route string, primaryStatus int) Request {
+	header := make(http.Header, len(forwardedHeaders)+2)
+	for _, name := range forwardedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	header.Set(Header, "true")
+	if m.token != "" {
+		header.Set("Authorization", "Bearer "+m.token)
+	}
+
+	uri := r.URL.EscapedPath()
+	if query := m.scrub(r.URL.Query()); len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return Request{Method: r.Method, URI: uri, Header: header, Route: route, PrimaryStatus: primaryStatus}
+}
+
+// scrub masks query values that are personal data or named by the policy
+func (m *Mirror) scrub(query url.Values) url.Values {
+	for key, values := range query {
+		covered := m.policy.Covers(key)
+		for i, value := range values {
+			if covered || len(pii.Detect(value)) > 0 {
+				values[i] = pii.Mask(value)
+			}
+		}
+	}
+	return query
+}
+
+// Enqueue hands req to the replay workers, dropping it if they are behind
+func (m *Mirror) Enqueue(req Request) {
+	select {
+	case m.queue <- req:
+	default:
+		metrics.MirroredRequests.WithLabelValues(req.Route, OutcomeDropped).Inc()
+	}
+}
+
+// Run replays queued requests until ctx is cancelled
+func (m *Mirror) Run(ctx context.Context) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-m.queue:
+					m.replay(ctx, req)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+}
+
+// replay sends req to the shadow stack and compares its status with the primary's
+func (m *Mirror) replay(ctx context.Context, req Request) {
+	target := m.base.String() + req.URI
+	shadow, err := http.NewRequestWithContext(ctx, req.Method, target, nil)
+	if err != nil {
+		metrics.MirroredRequests.WithLabelValues(req.Route, OutcomeError).Inc()
+		return
+	}
+	shadow.Header = req.Header
+
+	resp, err := m.client.Do(shadow)
+	if err != nil {
+		metrics.MirroredRequests.WithLabelValues(req.Route, OutcomeError).Inc()
+		logrus.WithError(err).WithField("route", req.Route).Debug("Mirrored request failed")
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	outcome := OutcomeMatch
+	if resp.StatusCode != req.PrimaryStatus {
+		outcome = OutcomeMismatch
+		logrus.WithFields(logrus.Fields{
+			"route":          req.Route,
+			"method":         req.Method,
+			"primary_status": req.PrimaryStatus,
+			"shadow_status":  resp.StatusCode,
+		}).Info("Mirrored request answered differently")
+	}
+	metrics.MirroredRequests.WithLabelValues(req.Route, outcome).Inc()
+}
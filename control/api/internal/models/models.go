@@ -0,0 +1,162 @@
+// Package models holds the placement inventory, booking and exposure records
+// shared by the database, analytics stores and handlers. The json tags are
+// the API representation; db tags name the column each field is read from.
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+)
+
+// Surface is a placement opportunity: a surface detected in one shot of a
+// title that can be booked
+type Surface struct {
+	SurfaceID       string          `json:"surface_id" db:"surface_id"`
+	TitleID         string          `json:"title_id" db:"title_id"`
+	ShotID          string          `json:"shot_id" db:"shot_id"`
+	StartTime       float64         `json:"start_time" db:"start_time"`
+	EndTime         float64         `json:"end_time" db:"end_time"`
+	Duration        float64         `json:"duration" db:"-"` // End minus start, computed by queries
+	SurfaceType     string          `json:"surface_type" db:"surface_type"`
+	PlacementType   string          `json:"placement_type" db:"placement_type"` // visual or audio
+	Audio           *audio.Slot     `json:"audio,omitempty"`                    // Slot of audio placements
+	PRSScore        float64         `json:"prs_score" db:"prs_score"`
+	VisibilityScore float64         `json:"visibility_score" db:"visibility_score"`
+	AreaPixels      *float64        `json:"area_pixels,omitempty" db:"area_pixels"`     // Single lookups only
+	AreaWorldM2     *float64        `json:"area_world_m2,omitempty" db:"area_world_m2"` // Single lookups only
+	Restrictions    json.RawMessage `json:"restrictions,omitempty" db:"restrictions"`   // Single lookups only
+	Geometry        *Geometry       `json:"geometry,omitempty"`                         // Single lookups only
+	ThumbnailURL    string          `json:"thumbnail_url,omitempty"`                    // Set once the pipeline uploads a reference frame
+	LastValidatedAt *time.Time      `json:"last_validated_at,omitempty"`                // Single lookups only
+	Stale           bool            `json:"stale,omitempty"`                            // Single lookups only; queued for or overdue re-validation
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	Labels          labels.Set      `json:"labels"`
+}
+
+// Geometry locates a surface in the scene
+type Geometry struct {
+	Bounds3D json.RawMessage `json:"bounds_3d" db:"bounds_3d"` // min_x..max_z of the bounding box
+}
+
+// Availability is whether a surface can be booked now, as announced to
+// opportunity streams
+type Availability struct {
+	SurfaceID   string  `json:"surface_id"`
+	TitleID     string  `json:"title_id"`
+	SurfaceType string  `json:"surface_type"`
+	PRSScore    float64 `json:"prs_score"`
+	Available   bool    `json:"available"` // Listed among opportunities: not held back, merged or stale
+	Booked      bool    `json:"booked"`    // Has a booking not yet completed, cancelled or expired
+}
+
+// Booking is a placement booking as stored
+type Booking struct {
+	BookingID            string            `json:"booking_id" db:"booking_id"`
+	SurfaceID            string            `json:"surface_id" db:"surface_id"`
+	AdvertiserID         string            `json:"advertiser_id" db:"advertiser_id"`
+	CampaignID           string            `json:"campaign_id" db:"campaign_id"`
+	BidAmountCPM         float64           `json:"bid_amount_cpm" db:"bid_amount_cpm"`
+	FinalCPMRate         *float64          `json:"final_cpm_rate,omitempty" db:"final_cpm_rate"`
+	EstimatedImpressions int64             `json:"estimated_impressions" db:"estimated_impressions"`
+	ActualImpressions    *int64            `json:"actual_impressions,omitempty" db:"actual_impressions"`
+	Status               string            `json:"status" db:"status"`
+	BookingTime          time.Time         `json:"booking_time" db:"booking_time"`
+	ConfirmationTime     *time.Time        `json:"confirmation_time,omitempty" db:"confirmation_time"`
+	BillingModel         string            `json:"billing_model,omitempty" db:"billing_model"`
+	BundleID             string            `json:"bundle_id,omitempty" db:"bundle_id"`
+	StartTime            *time.Time        `json:"start_time,omitempty" db:"start_time"` // Exposure window; open-ended when unset
+	EndTime              *time.Time        `json:"end_time,omitempty" db:"end_time"`
+	Pacing               string            `json:"pacing,omitempty" db:"pacing"` // asap or even
+	FrequencyCap         *freqcap.Cap      `json:"frequency_cap,omitempty"`      // Exposures per viewer of this booking
+	OrgID                string            `json:"org_id,omitempty" db:"org_id"` // Owning organization
+	Labels               labels.Set        `json:"labels"`
+	ExternalIDs          map[string]string `json:"external_ids,omitempty"`
+}
+
+// NewBooking holds the terms of a booking to create and who is creating it
+type NewBooking struct {
+	SurfaceID       string            `json:"surface_id" db:"surface_id"`
+	AdvertiserID    string            `json:"advertiser_id" db:"advertiser_id"`
+	CampaignID      string            `json:"campaign_id" db:"campaign_id"`
+	BidAmountCPM    float64           `json:"bid_amount_cpm" db:"bid_amount_cpm"`
+	MaxImpressions  int               `json:"max_impressions" db:"estimated_impressions"`
+	MinPRSScore     float64           `json:"min_prs_score" db:"min_prs_score"`
+	CreativeAssetID string            `json:"creative_asset_id,omitempty" db:"creative_asset_id"`
+	BillingModel    string            `json:"billing_model,omitempty" db:"billing_model"` // Empty means cpm
+	BundleID        string            `json:"bundle_id,omitempty" db:"bundle_id"`         // Bundle deal exempt from collision rules with the campaign's other bookings in it
+	StartTime       *time.Time        `json:"start_time,omitempty" db:"start_time"`       // Exposure window, which may not overlap that of a live booking of the surface
+	EndTime         *time.Time        `json:"end_time,omitempty" db:"end_time"`
+	Pacing          string            `json:"pacing,omitempty" db:"pacing"` // Empty means asap
+	FrequencyCap    *freqcap.Cap      `json:"frequency_cap,omitempty"`      // Exposures per viewer of this booking, besides the campaign's cap
+	Labels          labels.Set        `json:"labels,omitempty"`
+	ExternalIDs     map[string]string `json:"external_ids,omitempty"`
+	OrgID           string            `json:"-"` // Owner of the new booking, if any
+	UserID          string            `json:"-"` // Actor of the created event
+}
+
+// ExposureEvent is one viewer's exposure to a booked placement. Timestamp is
+// the skew-corrected time used for rollups; DeviceTimestamp keeps the raw
+// device-reported time for auditing.
+type ExposureEvent struct {
+	EventID          string     `json:"event_id" db:"event_id"`
+	BookingID        string     `json:"booking_id,omitempty" db:"booking_id"`
+	ViewerID         string     `json:"viewer_id" db:"viewer_id"`
+	ClientEventID    string     `json:"client_event_id,omitempty" db:"client_event_id"` // Player's ID for the beacon, kept across resends
+	Timestamp        time.Time  `json:"timestamp" db:"event_timestamp"`
+	DeviceTimestamp  *time.Time `json:"device_event_timestamp,omitempty" db:"device_event_timestamp"`
+	ReceivedAt       time.Time  `json:"-" db:"received_at"`
+	ClockSkewMS      int64      `json:"clock_skew_ms,omitempty" db:"clock_skew_ms"`
+	ExposureDuration float64    `json:"exposure_duration" db:"exposure_duration"`
+	ScreenCoverage   float64    `json:"screen_coverage" db:"screen_coverage_percentage"`
+	ListenThrough    *float64   `json:"listen_through,omitempty" db:"listen_through"` // Share of an audio slot heard; set when the event is recorded
+	AttentionScore   float64    `json:"attention_score" db:"attention_score"`
+	DeviceType       string     `json:"device_type,omitempty" db:"device_type"`
+	ConsentString    string     `json:"-" db:"consent_string"` // Never returned
+	CampaignID       string     `json:"-" db:"campaign_id"`    // Charged campaign of attention-billed exposures
+	Spend            float64    `json:"-" db:"spend"`          // What the exposure cost under its booking's billing model
+	OrgID            string     `json:"-" db:"org_id"`         // Organization of the booking, set when the event is recorded
+	Counted          bool       `json:"-" db:"counted"`        // Delivered and charged; false past the booking's cap or the campaign's budget
+}
+
+// BookingMetrics aggregates the exposure events recorded for a booking
+type BookingMetrics struct {
+	BookingID             string   `json:"booking_id" db:"booking_id"`
+	TotalImpressions      int64    `json:"total_impressions" db:"total_impressions"`
+	UniqueViewers         int64    `json:"unique_viewers" db:"unique_viewers"`
+	TotalExposureTime     float64  `json:"total_exposure_time" db:"total_exposure_time"`
+	AverageExposureTime   float64  `json:"average_exposure_time" db:"average_exposure_time"`
+	AveragePRSScore       float64  `json:"average_prs_score" db:"average_prs_score"`
+	AverageAttentionScore float64  `json:"average_attention_score" db:"average_attention_score"`
+	AverageScreenCoverage float64  `json:"average_screen_coverage" db:"average_screen_coverage"`
+	AverageListenThrough  *float64 `json:"average_listen_through,omitempty" db:"average_listen_through"` // Audio placements only
+
+	DataQuality *quality.Report `json:"data_quality,omitempty" db:"-"` // Set by the handler
+
+	// Set when the metrics were asked for a time range
+	From        *time.Time      `json:"from,omitempty" db:"-"`
+	To          *time.Time      `json:"to,omitempty" db:"-"`
+	Granularity string          `json:"granularity,omitempty" db:"-"`
+	Series      []MetricsBucket `json:"series,omitempty" db:"-"`
+	// RolledUpThrough is when the events in Series were received up to;
+	// unset when the store aggregates series on request
+	RolledUpThrough *time.Time `json:"rolled_up_through,omitempty" db:"-"`
+}
+
+// MetricsBucket aggregates a booking's counted exposure events within one
+// hour or day
+type MetricsBucket struct {
+	Bucket                time.Time `json:"bucket" db:"bucket"`
+	TotalImpressions      int64     `json:"total_impressions" db:"total_impressions"`
+	UniqueViewers         int64     `json:"unique_viewers" db:"unique_viewers"` // Within the bucket
+	TotalExposureTime     float64   `json:"total_exposure_time" db:"total_exposure_time"`
+	AverageExposureTime   float64   `json:"average_exposure_time" db:"average_exposure_time"`
+	AveragePRSScore       float64   `json:"average_prs_score" db:"average_prs_score"`
+	AverageAttentionScore float64   `json:"average_attention_score" db:"average_attention_score"`
+	AverageScreenCoverage float64   `json:"average_screen_coverage" db:"average_screen_coverage"`
+	AverageListenThrough  *float64  `json:"average_listen_through,omitempty" db:"average_listen_through"`
+}
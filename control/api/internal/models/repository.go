@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/quality"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// OpportunityRepository reads bookable surfaces
+type OpportunityRepository interface {
+	// GetPlacementOpportunities lists surfaces carrying every label in
+	// selector, best PRS score first, as a keyset page
+	GetPlacementOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]Surface, error)
+	// GetPlacementOpportunity returns nil when the surface does not exist
+	GetPlacementOpportunity(surfaceID string) (*Surface, error)
+}
+
+// BookingRepository creates and reads placement bookings. Reads only return
+// bookings within the caller's tenant scope.
+type BookingRepository interface {
+	CreatePlacementBooking(booking *NewBooking) (string, error)
+	// GetPlacementBooking returns nil when the booking does not exist or is
+	// outside scope
+	GetPlacementBooking(scope tenant.Scope, bookingID string) (*Booking, error)
+	ListPlacementBookings(scope tenant.Scope, campaignID string, selector labels.Set, limit, offset int) ([]Booking, error)
+}
+
+// ExposureRepository records exposure events, returning the stored event IDs.
+// RecordExposureEvents stores all of its events or none.
+type ExposureRepository interface {
+	RecordExposureEvent(event *ExposureEvent) (string, error)
+	RecordExposureEvents(events []*ExposureEvent) ([]string, error)
+}
+
+// AnalyticsRepository reads recorded exposures back for reporting, counting
+// only events within the caller's tenant scope. Both Postgres and ClickHouse
+// implement it.
+type AnalyticsRepository interface {
+	// GetBookingMetrics returns nil when the store has nothing for the
+	// booking. Zero times leave the event time range open.
+	GetBookingMetrics(scope tenant.Scope, bookingID string, from, to time.Time) (*BookingMetrics, error)
+	// GetBookingSeries aggregates a booking's events into the hour or day
+	// buckets from from to to that have any, and returns when the events
+	// aggregated were received up to if the store reads them from rollups
+	GetBookingSeries(scope tenant.Scope, bookingID, granularity string, from, to time.Time) ([]MetricsBucket, *time.Time, error)
+	// GetExposureEvents lists a booking's events newest first as a keyset page
+	GetExposureEvents(scope tenant.Scope, bookingID string, page pagination.Page) ([]ExposureEvent, error)
+	// GetDataQuality describes the events behind the figures of a filter
+	GetDataQuality(f quality.Filter) (*quality.Report, error)
+}
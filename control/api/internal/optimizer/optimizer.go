@@ -0,0 +1,292 @@
+// Package optimizer recommends the placement windows of a title that buy
+// the most qualified impressions for a budget. Viewers drop off as a title
+// plays, so a surface early in it is seen by more of them; each window is
+// projected from the title's historical retention curve, the share of
+// playback sessions still watching at each position.
+package optimizer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/audio"
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+)
+
+// ErrNoRetention is returned when planning a title without a retention curve
+var ErrNoRetention = errors.New("title has no retention curve")
+
+// MaxPoints bounds the points of one retention curve
+const MaxPoints = 2000
+
+// Defaults of a plan's request; the quality thresholds are a booking's
+const (
+	DefaultMinPRSScore           = 70.0
+	DefaultMinVisibilityDuration = 2.0
+	DefaultMaxWindows            = 10
+)
+
+// MaxWindows bounds the windows one plan may recommend
+const MaxWindows = 100
+
+// Point is the share of playback sessions still playing at a position
+type Point struct {
+	Position  float64 `json:"position"`  // Seconds into the title
+	Retention float64 `json:"retention"` // 0-1
+}
+
+// Curve is a title's historical audience retention
+type Curve struct {
+	TitleID     string     `json:"title_id"`
+	Starts      int64      `json:"starts"` // Playback sessions started over the period
+	Points      []Point    `json:"points"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Validate checks that the curve is well formed: it starts at position 0
+// and its positions increase
+func (c *Curve) Validate() error {
+	if c.Starts < 0 {
+		return fmt.Errorf("starts must not be negative")
+	}
+	if len(c.Points) == 0 || len(c.Points) > MaxPoints {
+		return fmt.Errorf("points must have 1 to %d entries", MaxPoints)
+	}
+	if c.Points[0].Position != 0 {
+		return fmt.Errorf("the first point must be at position 0")
+	}
+	for i, p := range c.Points {
+		if p.Retention < 0 || p.Retention > 1 || math.IsNaN(p.Retention) {
+			return fmt.Errorf("points[%d]: retention must be between 0 and 1", i)
+		}
+		if i > 0 && !(p.Position > c.Points[i-1].Position) {
+			return fmt.Errorf("points[%d]: positions must increase", i)
+		}
+		if math.IsInf(p.Position, 0) {
+			return fmt.Errorf("points[%d]: position must be finite", i)
+		}
+	}
+	if c.PeriodStart != nil && c.PeriodEnd != nil && !c.PeriodEnd.After(*c.PeriodStart) {
+		return fmt.Errorf("period_end must be after period_start")
+	}
+	return nil
+}
+
+// At returns the retention at position, interpolated between points. Past
+// the last point retention stays at its value.
+func (c *Curve) At(position float64) float64 {
+	points := c.Points
+	if len(points) == 0 {
+		return 0
+	}
+	i := sort.Search(len(points), func(i int) bool { return points[i].Position > position })
+	switch {
+	case i == 0:
+		return points[0].Retention
+	case i == len(points):
+		return points[i-1].Retention
+	}
+	before, after := points[i-1], points[i]
+	share := (position - before.Position) / (after.Position - before.Position)
+	return before.Retention + share*(after.Retention-before.Retention)
+}
+
+// Request asks for the windows of a title to book with a budget
+type Request struct {
+	Budget                float64 `json:"budget"` // Currency units
+	BidAmountCPM          float64 `json:"bid_amount_cpm"`
+	BillingModel          string  `json:"billing_model"`           // cpm or vcpm; empty means cpm
+	MinPRSScore           float64 `json:"min_prs_score"`           // DefaultMinPRSScore when zero
+	MinVisibilityDuration float64 `json:"min_visibility_duration"` // Seconds; DefaultMinVisibilityDuration when zero
+	MaxWindows            int     `json:"max_windows"`             // Bookings to spread the budget over; DefaultMaxWindows when zero
+}
+
+// Validate checks the request and fills in its defaults
+func (r *Request) Validate() error {
+	if r.Budget <= 0 || math.IsInf(r.Budget, 0) {
+		return fmt.Errorf("budget must be positive")
+	}
+	if r.BidAmountCPM <= 0 || math.IsInf(r.BidAmountCPM, 0) {
+		return fmt.Errorf("bid_amount_cpm must be positive")
+	}
+	switch r.BillingModel {
+	case "":
+		r.BillingModel = billing.ModelCPM
+	case billing.ModelCPM, billing.ModelVCPM:
+	default:
+		return fmt.Errorf("billing_model must be %s or %s", billing.ModelCPM, billing.ModelVCPM)
+	}
+	if r.MinPRSScore < 0 || r.MinPRSScore > 100 {
+		return fmt.Errorf("min_prs_score must be between 0 and 100")
+	}
+	if r.MinPRSScore == 0 {
+		r.MinPRSScore = DefaultMinPRSScore
+	}
+	if r.MinVisibilityDuration < 0 {
+		return fmt.Errorf("min_visibility_duration must not be negative")
+	}
+	if r.MinVisibilityDuration == 0 {
+		r.MinVisibilityDuration = DefaultMinVisibilityDuration
+	}
+	if r.MaxWindows < 0 || r.MaxWindows > MaxWindows {
+		return fmt.Errorf("max_windows must be between 1 and %d", MaxWindows)
+	}
+	if r.MaxWindows == 0 {
+		r.MaxWindows = DefaultMaxWindows
+	}
+	return nil
+}
+
+// Candidate is a surface of the title that may be booked
+type Candidate struct {
+	SurfaceID     string
+	SurfaceType   string
+	PlacementType string // visual or audio
+	StartTime     float64
+	EndTime       float64
+	PRSScore      float64
+}
+
+// Window is a surface recommended for booking
+type Window struct {
+	SurfaceID            string  `json:"surface_id"`
+	SurfaceType          string  `json:"surface_type"`
+	StartTime            float64 `json:"start_time"`
+	EndTime              float64 `json:"end_time"`
+	PRSScore             float64 `json:"prs_score"`
+	Retention            float64 `json:"retention"`             // At the start of the window
+	ProjectedImpressions int64   `json:"projected_impressions"` // Sessions reaching the window
+	ProjectedQualified   int64   `json:"projected_qualified_impressions"`
+	Cost                 float64 `json:"cost"`
+	MaxImpressions       int     `json:"max_impressions,omitempty"` // Cap to book it with when only part of it fits the budget
+}
+
+// Plan recommends the windows to book, largest first
+type Plan struct {
+	TitleID              string    `json:"title_id"`
+	Request              Request   `json:"request"`
+	Windows              []Window  `json:"windows"`
+	Spend                float64   `json:"spend"`
+	ProjectedImpressions int64     `json:"projected_impressions"`
+	ProjectedQualified   int64     `json:"projected_qualified_impressions"`
+	RetentionStarts      int64     `json:"retention_starts"`
+	RetentionUpdatedAt   time.Time `json:"retention_updated_at"`
+}
+
+// Optimize picks the candidates buying the most qualified impressions for
+// the request's budget in at most MaxWindows bookings. Each window is
+// projected at the curve's starts times the retention when it begins, and
+// qualifies for the viewers still watching after the minimum visibility
+// duration, or half an audio slot. A window cannot deliver more than the
+// sessions reaching it, so the budget goes furthest in the windows most
+// viewers reach: they are taken largest first, then by qualified
+// impressions per unit of cost, and the last one taken is capped to what
+// remains of the budget.
+func Optimize(curve *Curve, candidates []Candidate, req Request) *Plan {
+	plan := &Plan{
+		TitleID:            curve.TitleID,
+		Request:            req,
+		Windows:            make([]Window, 0),
+		RetentionStarts:    curve.Starts,
+		RetentionUpdatedAt: curve.UpdatedAt,
+	}
+
+	type option struct {
+		window    Window
+		served    float64
+		qualified float64
+	}
+	options := make([]option, 0, len(candidates))
+	for _, c := range candidates {
+		if c.PRSScore < req.MinPRSScore {
+			continue
+		}
+		duration := c.EndTime - c.StartTime
+		qualifyAfter := req.MinVisibilityDuration
+		if c.PlacementType == audio.PlacementAudio {
+			qualifyAfter = duration * audio.QualifyingListenThrough
+		}
+		if duration <= 0 || duration < qualifyAfter {
+			continue
+		}
+		retention := curve.At(c.StartTime)
+		served := float64(curve.Starts) * retention
+		qualified := float64(curve.Starts) * curve.At(c.StartTime+qualifyAfter)
+		if qualified < 1 {
+			continue
+		}
+		options = append(options, option{
+			window: Window{
+				SurfaceID:   c.SurfaceID,
+				SurfaceType: c.SurfaceType,
+				StartTime:   c.StartTime,
+				EndTime:     c.EndTime,
+				PRSScore:    c.PRSScore,
+				Retention:   retention,
+			},
+			served:    served,
+			qualified: qualified,
+		})
+	}
+
+	// Cost per thousand of what the billing model charges for
+	cost := func(o option) float64 {
+		if req.BillingModel == billing.ModelVCPM {
+			return o.qualified * req.BidAmountCPM / 1000
+		}
+		return o.served * req.BidAmountCPM / 1000
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if a.qualified != b.qualified {
+			return a.qualified > b.qualified
+		}
+		if ea, eb := a.qualified/cost(a), b.qualified/cost(b); ea != eb {
+			return ea > eb
+		}
+		return a.window.StartTime < b.window.StartTime
+	})
+
+	remaining := req.Budget
+	for _, o := range options {
+		if len(plan.Windows) == req.MaxWindows {
+			break
+		}
+		full := cost(o)
+		share := 1.0
+		if full > remaining {
+			share = remaining / full
+		}
+		w := o.window
+		w.ProjectedImpressions = int64(math.Floor(o.served * share))
+		w.ProjectedQualified = int64(math.Floor(o.qualified * share))
+		if w.ProjectedImpressions < 1 || w.ProjectedQualified < 1 {
+			break
+		}
+		w.Cost = roundCents(full * share)
+		if share < 1 {
+			w.MaxImpressions = int(w.ProjectedImpressions)
+		}
+		plan.Windows = append(plan.Windows, w)
+		plan.Spend += w.Cost
+		plan.ProjectedImpressions += w.ProjectedImpressions
+		plan.ProjectedQualified += w.ProjectedQualified
+		remaining -= full * share
+		if share < 1 {
+			break
+		}
+	}
+	plan.Spend = roundCents(plan.Spend)
+	return plan
+}
+
+// roundCents rounds an amount to hundredths
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
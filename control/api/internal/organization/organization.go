@@ -0,0 +1,58 @@
+// Package organization holds the tenants of the API: advertisers, agencies
+// and publishers whose bookings and metrics are kept apart. Users belong to
+// organizations through memberships and sign in to one of them at a time.
+package organization
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxNameLength matches the organizations.name column
+const maxNameLength = 255
+
+// idPattern matches the organization IDs tokens and grants already carry,
+// such as "org_acme" or "brand-42"
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{1,99}$`)
+
+// Organization is a tenant
+type Organization struct {
+	ID        string    `json:"org_id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the organization can be created
+func (o *Organization) Validate() error {
+	if err := ValidateID(o.ID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(o.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(o.Name) > maxNameLength {
+		return errors.New("name must be at most 255 characters")
+	}
+	return nil
+}
+
+// ValidateID checks an organization ID
+func ValidateID(orgID string) error {
+	if !idPattern.MatchString(orgID) {
+		return errors.New("org_id must be 2-100 letters, digits or . _ -, starting with a letter or digit")
+	}
+	return nil
+}
+
+// Member is a user who may sign in to an organization. The organization set
+// on the user is their default, and always one of their memberships.
+type Member struct {
+	OrgID     string    `json:"org_id"`
+	Username  string    `json:"username"`
+	Default   bool      `json:"default"` // The user's own organization, used when they sign in without choosing one
+	AddedBy   string    `json:"added_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
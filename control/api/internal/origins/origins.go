@@ -0,0 +1,171 @@
+// Package origins decides which browser origins may call the API. Routes
+// fall into groups with their own CORS policy: public beacon endpoints that
+// players on any site report to, the admin endpoints of operators' tools,
+// and the rest of the API used by dashboards. Besides the origins configured
+// for a group, admins allow origins for an organization's own dashboards and
+// players at runtime; those are cached, as every cross-origin request checks
+// them.
+package origins
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Route groups
+const (
+	GroupPublic = "public" // Exposure and decision beacons and edge leases
+	GroupAPI    = "api"    // Everything else under /api/v1
+	GroupAdmin  = "admin"  // /admin, which only configured origins may call
+)
+
+// DefaultCacheTTL is how long origins allowed at runtime are cached, and so
+// how long a change made on another instance takes to apply
+const DefaultCacheTTL = time.Minute
+
+// maxOriginLength matches the cors_origins.origin column
+const maxOriginLength = 255
+
+// GroupOf returns the route group of a request path
+func GroupOf(path string) string {
+	switch {
+	case path == "/admin" || strings.HasPrefix(path, "/admin/"):
+		return GroupAdmin
+	case path == "/status",
+		strings.HasPrefix(path, "/api/v1/events/"),
+		strings.HasPrefix(path, "/api/v1/edge/"):
+		return GroupPublic
+	}
+	return GroupAPI
+}
+
+// Normalize checks that origin is a browser origin, a scheme and host with an
+// optional port, and returns it lowercased
+func Normalize(origin string) (string, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("origin must be an http or https scheme and host, e.g. https://app.example.com")
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || strings.Contains(u.Host, "*") {
+		return "", errors.New("origin must not have credentials, a path, a query or wildcards")
+	}
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	if len(normalized) > maxOriginLength {
+		return "", errors.New("origin must be at most 255 characters")
+	}
+	return normalized, nil
+}
+
+// Origin is a browser origin allowed to call a route group for an organization
+type Origin struct {
+	OrgID     string    `json:"org_id"`
+	Origin    string    `json:"origin"`
+	Group     string    `json:"group"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate normalizes the origin and checks it can be allowed. Admin
+// endpoints are not an organization's to open.
+func (o *Origin) Validate() error {
+	if o.Group == "" {
+		o.Group = GroupAPI
+	}
+	if o.Group != GroupAPI && o.Group != GroupPublic {
+		return errors.New("group must be api or public")
+	}
+	origin, err := Normalize(o.Origin)
+	if err != nil {
+		return err
+	}
+	o.Origin = origin
+	return nil
+}
+
+// Policy is the CORS policy of a route group
+type Policy struct {
+	Origins     []string // Configured origins; "*" allows any, and one "*" may stand for part of a host, e.g. https://*.example.com
+	Credentials bool     // Whether browsers may send cookies and authorization with requests
+}
+
+// Allows reports whether a configured origin of the policy matches origin
+func (p Policy) Allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.Origins {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store lists the origins allowed at runtime across organizations
+type Store interface {
+	ListAllowedOrigins() ([]Origin, error)
+}
+
+// Cache holds the origins allowed at runtime by route group, reloading them
+// from the store once they are older than its TTL
+type Cache struct {
+	store Store
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	allowed  map[string]map[string]bool
+}
+
+// NewCache creates a cache of the store's origins
+func NewCache(store Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl}
+}
+
+// Allowed reports whether an organization allowed origin to call group. When
+// the store fails, the origins last loaded stay in use.
+func (c *Cache) Allowed(group, origin string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.loadedAt) >= c.ttl {
+		if err := c.load(); err != nil {
+			logrus.WithError(err).Warn("Failed to load allowed origins, keeping cached ones")
+			c.loadedAt = time.Now() // Retried once the TTL passes again
+		}
+	}
+	return c.allowed[group][strings.ToLower(origin)]
+}
+
+// load replaces the cached origins with the store's
+func (c *Cache) load() error {
+	list, err := c.store.ListAllowedOrigins()
+	if err != nil {
+		return err
+	}
+	allowed := map[string]map[string]bool{}
+	for _, o := range list {
+		if allowed[o.Group] == nil {
+			allowed[o.Group] = map[string]bool{}
+		}
+		allowed[o.Group][o.Origin] = true
+	}
+	c.allowed, c.loadedAt = allowed, time.Now()
+	return nil
+}
+
+// Invalidate makes the next check reload the origins, after this instance
+// changed them
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
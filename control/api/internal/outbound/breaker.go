@@ -0,0 +1,133 @@
+package outbound
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned without sending a request while a host's circuit is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Circuit states
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+// BreakerConfig controls per-host circuit breaking. After FailureThreshold
+// consecutive failed requests to a host, calls fail fast for OpenFor; then a
+// single probe request is let through and its result closes or reopens the circuit.
+type BreakerConfig struct {
+	FailureThreshold int
+	OpenFor          time.Duration
+}
+
+type circuit struct {
+	state     string
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// breakers tracks one circuit per host
+type breakers struct {
+	client string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newBreakers(client string, config BreakerConfig) *breakers {
+	return &breakers{
+		client:   client,
+		config:   config,
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// allow reports whether a request to host may be sent
+func (b *breakers) allow(host string) bool {
+	if b.config.FailureThreshold < 1 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		return true
+	}
+
+	switch c.state {
+	case circuitOpen:
+		if b.now().Before(c.openUntil) {
+			return false
+		}
+		c.state = circuitHalfOpen
+		c.probing = true
+		b.logTransition(host, circuitHalfOpen)
+		return true
+	case circuitHalfOpen:
+		// Only one probe at a time
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates host's circuit with the outcome of a request
+func (b *breakers) record(host string, success bool) {
+	if b.config.FailureThreshold < 1 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		if success {
+			return
+		}
+		c = &circuit{state: circuitClosed}
+		b.circuits[host] = c
+	}
+
+	if success {
+		if c.state != circuitClosed {
+			b.logTransition(host, circuitClosed)
+		}
+		delete(b.circuits, host)
+		return
+	}
+
+	c.failures++
+	c.probing = false
+	if c.state == circuitHalfOpen || c.failures >= b.config.FailureThreshold {
+		if c.state != circuitOpen {
+			b.logTransition(host, circuitOpen)
+		}
+		c.state = circuitOpen
+		c.openUntil = b.now().Add(b.config.OpenFor)
+	}
+}
+
+func (b *breakers) logTransition(host, state string) {
+	logrus.WithFields(logrus.Fields{
+		"client": b.client,
+		"host":   host,
+		"state":  state,
+	}).Warn("Outbound circuit state changed")
+}
@@ -0,0 +1,238 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// Policy controls timeouts and retries for an integration
+type Policy struct {
+	Timeout     time.Duration // Per attempt
+	MaxAttempts int           // Including the first attempt
+	Backoff     retry.Backoff
+	// RetryUnsafe allows retrying non-idempotent methods such as POST. Enable
+	// it only when the receiver deduplicates, as webhook consumers must.
+	RetryUnsafe bool
+	Breaker     BreakerConfig
+}
+
+// DefaultPolicy suits idempotent calls to internal services
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:     30 * time.Second,
+		MaxAttempts: 3,
+		Backoff:     retry.Backoff{Base: 200 * time.Millisecond, Max: 5 * time.Second, Jitter: 0.5},
+		Breaker:     BreakerConfig{FailureThreshold: 5, OpenFor: 30 * time.Second},
+	}
+}
+
+// WebhookPolicy suits deliveries to customer endpoints, which are slow and
+// flaky more often than internal services
+func WebhookPolicy() Policy {
+	return Policy{
+		Timeout:     10 * time.Second,
+		MaxAttempts: 4,
+		Backoff:     retry.Backoff{Base: time.Second, Max: 30 * time.Second, Jitter: 0.5},
+		RetryUnsafe: true,
+		Breaker:     BreakerConfig{FailureThreshold: 5, OpenFor: time.Minute},
+	}
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks requests made with ctx as safe to retry regardless of method
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// Client is the HTTP client every integration uses for outbound calls. It
+// adds per-attempt timeouts, retries with jittered backoff, per-host circuit
+// breaking and metrics labelled by the client name.
+type Client struct {
+	name     string
+	policy   Policy
+	http     *http.Client
+	breakers *breakers
+}
+
+// New creates an outbound client. name identifies the integration in metrics and logs.
+func New(name string, policy Policy) *Client {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Client{
+		name:     name,
+		policy:   policy,
+		http:     &http.Client{},
+		breakers: newBreakers(name, policy.Breaker),
+	}
+}
+
+// SetTransport replaces the transport, e.g. to trust a private CA
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.http.Transport = transport
+}
+
+// SetRedirectPolicy decides whether redirects are followed, e.g. to keep
+// them to allowed hosts; see http.Client.CheckRedirect
+func (c *Client) SetRedirectPolicy(check func(req *http.Request, via []*http.Request) error) {
+	c.http.CheckRedirect = check
+}
+
+// Do sends req, retrying connection failures, 429 and 5xx responses. The
+// final response is returned even when its status is an error; callers must
+// close its body. A request body is only resent if req.GetBody is set, which
+// http.NewRequest does for in-memory bodies.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	retryable := c.canRetry(req)
+
+	for attempt := 1; ; attempt++ {
+		if !c.breakers.allow(host) {
+			metrics.OutboundRequests.WithLabelValues(c.name, "circuit_open").Inc()
+			return nil, fmt.Errorf("%s request to %s: %w", c.name, host, ErrCircuitOpen)
+		}
+
+		resp, err := c.attempt(req, attempt)
+		failed := err != nil || retryableStatus(resp.StatusCode)
+		c.breakers.record(host, !failed)
+
+		if !failed || !retryable || attempt >= c.policy.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := c.policy.Backoff.Delay(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok && after < c.policy.Backoff.Max {
+				delay = after
+			}
+			drain(resp)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"client":  c.name,
+			"host":    host,
+			"attempt": attempt,
+			"delay":   delay.String(),
+		}).WithError(attemptError(resp, err)).Warn("Outbound request failed, retrying")
+		metrics.OutboundRetries.WithLabelValues(c.name).Inc()
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt sends one try of req with the per-attempt timeout
+func (c *Client) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	try := req
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind %s request body: %w", c.name, err)
+		}
+		try = req.Clone(req.Context())
+		try.Body = body
+	}
+
+	ctx := try.Context()
+	var cancel context.CancelFunc
+	if c.policy.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.policy.Timeout)
+		try = try.WithContext(ctx)
+	}
+
+	started := time.Now()
+	resp, err := c.http.Do(try)
+	metrics.OutboundDurationSeconds.WithLabelValues(c.name).Observe(time.Since(started).Seconds())
+	metrics.OutboundRequests.WithLabelValues(c.name, outcome(resp, err)).Inc()
+
+	if cancel != nil {
+		if err != nil {
+			cancel()
+		} else {
+			// The timeout must keep covering the body until the caller closes it
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		}
+	}
+	return resp, err
+}
+
+// canRetry reports whether req may be sent more than once
+func (c *Client) canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if c.policy.RetryUnsafe || req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	if idempotent, _ := req.Context().Value(idempotentKey{}).(bool); idempotent {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// outcome labels a request result for metrics
+func outcome(resp *http.Response, err error) string {
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "timeout"
+		}
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode/100) + "xx"
+}
+
+func attemptError(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("status %d", resp.StatusCode)
+}
+
+// drain discards and closes a response body so the connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// cancelOnClose releases a per-attempt timeout once the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
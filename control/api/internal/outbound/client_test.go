@@ -0,0 +1,264 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicy retries quickly and never opens the circuit
+func testPolicy() Policy {
+	return Policy{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     retry.Backoff{Base: time.Millisecond, Max: 5 * time.Millisecond},
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int // Answered in turn; the last one repeats
+		method           string
+		idempotencyKey   bool
+		retryUnsafe      bool
+		expectedStatus   int
+		expectedAttempts int32
+		description      string
+	}{
+		{
+			name:             "success",
+			statuses:         []int{http.StatusOK},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 1,
+			description:      "Should send a successful request once",
+		},
+		{
+			name:             "5xx then success",
+			statuses:         []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 3,
+			description:      "Should retry 5xx responses until one succeeds",
+		},
+		{
+			name:             "5xx exhausts attempts",
+			statuses:         []int{http.StatusInternalServerError},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedAttempts: 3,
+			description:      "Should return the last response once out of attempts",
+		},
+		{
+			name:             "429",
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+			description:      "Should retry rate limited requests",
+		},
+		{
+			name:             "4xx",
+			statuses:         []int{http.StatusBadRequest, http.StatusOK},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusBadRequest,
+			expectedAttempts: 1,
+			description:      "Should not retry client errors",
+		},
+		{
+			name:             "404",
+			statuses:         []int{http.StatusNotFound, http.StatusOK},
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusNotFound,
+			expectedAttempts: 1,
+			description:      "Should not retry missing resources",
+		},
+		{
+			name:             "unsafe method",
+			statuses:         []int{http.StatusBadGateway, http.StatusOK},
+			method:           http.MethodPost,
+			expectedStatus:   http.StatusBadGateway,
+			expectedAttempts: 1,
+			description:      "Should not resend a POST the receiver may not deduplicate",
+		},
+		{
+			name:             "unsafe method with idempotency key",
+			statuses:         []int{http.StatusBadGateway, http.StatusOK},
+			method:           http.MethodPost,
+			idempotencyKey:   true,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+			description:      "Should resend a POST carrying an Idempotency-Key",
+		},
+		{
+			name:             "unsafe method allowed by policy",
+			statuses:         []int{http.StatusBadGateway, http.StatusOK},
+			method:           http.MethodPost,
+			retryUnsafe:      true,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+			description:      "Should resend a POST when the policy allows it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&attempts, 1))
+				body, _ := io.ReadAll(r.Body)
+				if r.Method == http.MethodPost {
+					assert.Equal(t, `{"ok":true}`, string(body), "Should resend the whole body")
+				}
+				if n > len(tt.statuses) {
+					n = len(tt.statuses)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			policy := testPolicy()
+			policy.RetryUnsafe = tt.retryUnsafe
+			client := New("test", policy)
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(`{"ok":true}`))
+			require.NoError(t, err)
+			if tt.idempotencyKey {
+				req.Header.Set("Idempotency-Key", "key_1")
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err, tt.description)
+			resp.Body.Close()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, tt.description)
+			assert.Equal(t, tt.expectedAttempts, atomic.LoadInt32(&attempts), tt.description)
+		})
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	var attempts int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	policy := testPolicy()
+	policy.Timeout = 20 * time.Millisecond
+	policy.MaxAttempts = 2
+	client := New("test", policy)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	started := time.Now()
+	_, err = client.Do(req)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "Should time out each attempt, got %v", err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts), "Should retry an attempt that timed out")
+	assert.Less(t, time.Since(started), time.Second)
+
+	t.Run("caller cancels", func(t *testing.T) {
+		policy.Timeout = time.Minute
+		client := New("test", policy)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		before := atomic.LoadInt32(&attempts)
+		_, err = client.Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, before+1, atomic.LoadInt32(&attempts), "Should not retry once the caller gave up")
+	})
+}
+
+func TestClient_Breaker(t *testing.T) {
+	var attempts int32
+	status := int32(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	policy := testPolicy()
+	policy.MaxAttempts = 1
+	policy.Breaker = BreakerConfig{FailureThreshold: 3, OpenFor: time.Minute}
+	client := New("test", policy)
+	now := time.Now()
+	client.breakers.now = func() time.Time { return now }
+
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := get()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	_, err := get()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "Should fail fast after consecutive failures")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts), "Should not send while the circuit is open")
+
+	// Half open: one probe is let through and its failure reopens the circuit
+	now = now.Add(time.Minute)
+	resp, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+	_, err = get()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "A failed probe should reopen the circuit")
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&status, http.StatusOK)
+	resp, err = get()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = get()
+	require.NoError(t, err, "A successful probe should close the circuit")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(6), atomic.LoadInt32(&attempts))
+}
+
+func TestBreakers_SingleProbe(t *testing.T) {
+	b := newBreakers("test", BreakerConfig{FailureThreshold: 1, OpenFor: time.Minute})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.record("api.example.com", false)
+	assert.False(t, b.allow("api.example.com"))
+	assert.True(t, b.allow("other.example.com"), "Circuits are per host")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow("api.example.com"), "Should let a probe through once OpenFor passed")
+	assert.False(t, b.allow("api.example.com"), "Should let only one probe through at a time")
+	b.record("api.example.com", true)
+	assert.True(t, b.allow("api.example.com"))
+	assert.True(t, b.allow("api.example.com"))
+}
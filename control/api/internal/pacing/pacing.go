@@ -0,0 +1,128 @@
+// Package pacing spreads a booking's max_impressions over its window. ASAP
+// bookings are served as fast as decisions allow until their cap is
+// delivered; evenly paced bookings are only served while their delivered
+// impressions are behind a straight line from none at the start of their
+// window to the cap at its end.
+package pacing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Pacing curves
+const (
+	ASAP = "asap"
+	Even = "even"
+)
+
+// ErrAheadOfPace is returned when serving an evenly paced booking would take
+// its deliveries past its pace
+var ErrAheadOfPace = errors.New("booking is ahead of its even pace")
+
+// Curve returns the curve a booking is paced along; empty means ASAP
+func Curve(curve string) string {
+	if curve == "" {
+		return ASAP
+	}
+	return curve
+}
+
+// Validate checks a booking's pacing curve against its terms. Even pacing
+// needs a cap to spread and a window to spread it over.
+func Validate(curve string, maxImpressions int, start, end *time.Time) error {
+	switch curve {
+	case "", ASAP:
+		return nil
+	case Even:
+		if maxImpressions <= 0 {
+			return fmt.Errorf("even pacing requires max_impressions")
+		}
+		if start == nil || end == nil {
+			return fmt.Errorf("even pacing requires start_time and end_time")
+		}
+		return nil
+	}
+	return fmt.Errorf("pacing must be %s or %s", ASAP, Even)
+}
+
+// Plan is a booking's pacing and what it has delivered, counted from its
+// exposure events
+type Plan struct {
+	BookingID      string
+	Curve          string
+	MaxImpressions int64
+	Delivered      int64
+	Start          *time.Time
+	End            *time.Time
+}
+
+// Target is how many impressions the booking may have delivered by now. It
+// is rounded up, so an evenly paced booking may serve as soon as its window
+// opens. ASAP and uncapped bookings have no target and return -1.
+func (p Plan) Target(now time.Time) int64 {
+	if p.Curve != Even || p.MaxImpressions <= 0 || p.Start == nil || p.End == nil {
+		return -1
+	}
+	switch {
+	case now.Before(*p.Start):
+		return 0
+	case !now.Before(*p.End):
+		return p.MaxImpressions
+	}
+	share := float64(now.Sub(*p.Start)) / float64(p.End.Sub(*p.Start))
+	return int64(math.Ceil(float64(p.MaxImpressions) * share))
+}
+
+// Check returns ErrAheadOfPace when the booking has delivered its target
+func (p Plan) Check(now time.Time) error {
+	if target := p.Target(now); target >= 0 && p.Delivered >= target {
+		return ErrAheadOfPace
+	}
+	return nil
+}
+
+// Thresholds are the shares of max_impressions, in percent, at which a
+// booking's delivery is reported to webhook subscribers. Delivering the cap
+// completes the booking, which is reported on its own.
+var Thresholds = []int{25, 50, 75, 90}
+
+// ThresholdReached returns the highest threshold that delivering the
+// impression numbered delivered reaches, or 0 if it reaches none. Small caps
+// may reach several thresholds with one impression; only the highest is
+// returned. Uncapped bookings have no thresholds, and the impression
+// delivering the cap reaches none.
+func ThresholdReached(delivered, maxImpressions int64) int {
+	if maxImpressions <= 0 || delivered >= maxImpressions {
+		return 0
+	}
+	reached := 0
+	for _, percent := range Thresholds {
+		if thresholdImpressions(percent, maxImpressions) == delivered {
+			reached = percent
+		}
+	}
+	return reached
+}
+
+// thresholdImpressions is the impression at which percent of max is delivered
+func thresholdImpressions(percent int, maxImpressions int64) int64 {
+	return int64(math.Ceil(float64(maxImpressions) * float64(percent) / 100))
+}
+
+// FirstMilestone is the smallest delivery count reported as a milestone
+const FirstMilestone = 1000
+
+// Milestone reports whether delivered impressions is a milestone: 1,000 and
+// each further power of ten
+func Milestone(delivered int64) bool {
+	if delivered < FirstMilestone {
+		return false
+	}
+	for delivered%10 == 0 {
+		delivered /= 10
+	}
+	return delivered == 1
+}
@@ -0,0 +1,270 @@
+// Package pacinglog records what the decision controller saw of each live
+// booking - its pacing plan and its campaign's budget, both as Postgres
+// recorded it and as the Redis counter held it - in periodic snapshots,
+// and replays the controller's decisions over them, so an overspent
+// campaign can be traced back to the inputs that let it serve.
+package pacinglog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/pacing"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInterval is how often snapshots are taken by default
+const DefaultInterval = time.Minute
+
+// DefaultRetention is how long snapshots are kept by default
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Replay ranges
+const (
+	DefaultRange = 24 * time.Hour
+	MaxRange     = 7 * 24 * time.Hour
+)
+
+// Pacing outcomes of a replayed decision
+const (
+	PacingUnpaced = "unpaced"       // ASAP or uncapped, served as fast as decisions allow
+	PacingBehind  = "behind"        // Evenly paced and below its target
+	PacingAhead   = "ahead_of_pace" // Evenly paced and at its target, refused
+)
+
+// Budget outcomes of a replayed decision
+const (
+	BudgetUnlimited = "unlimited" // The campaign has no budget
+	BudgetCharged   = "charged"   // Enough remained for an impression
+	BudgetExhausted = "exhausted" // Too little remained, refused
+)
+
+// Budget sources a charge is checked against, as budget.Tracker does
+const (
+	SourceRedis    = "redis"    // The counter was loaded
+	SourcePostgres = "postgres" // No counter; the seed from Postgres
+)
+
+// Decisions of a replayed served decision
+const (
+	DecisionServe           = "serve"
+	DecisionAheadOfPace     = "ahead_of_pace"
+	DecisionBudgetExhausted = "budget_exhausted"
+)
+
+// Snapshot is a live booking's pacing and its campaign's budget at one time
+type Snapshot struct {
+	BookingID      string     `json:"booking_id"`
+	CampaignID     string     `json:"campaign_id,omitempty"`
+	TakenAt        time.Time  `json:"taken_at"`
+	Pacing         string     `json:"pacing"`
+	MaxImpressions int64      `json:"max_impressions"`
+	Delivered      int64      `json:"delivered"`
+	Start          *time.Time `json:"start_time,omitempty"`
+	End            *time.Time `json:"end_time,omitempty"`
+	// ImpressionCost is the budget that had to remain to serve an impression
+	ImpressionCost float64 `json:"impression_cost"`
+	// BudgetAmount is the campaign's budget, nil when it had none
+	BudgetAmount *float64 `json:"budget_amount,omitempty"`
+	// BudgetSpent is the authoritative spend recorded in Postgres
+	BudgetSpent float64 `json:"budget_spent"`
+	// BudgetSeed is what charges were checked against without the counter:
+	// the budget less the safety margin and the spend
+	BudgetSeed *float64 `json:"budget_seed,omitempty"`
+	// CounterRemaining is what the campaign's Redis counter held, nil when
+	// it was not loaded
+	CounterRemaining *float64 `json:"counter_remaining,omitempty"`
+
+	Terms billing.Terms `json:"-"` // What the impression cost is priced from
+}
+
+// Plan returns the pacing plan of the snapshot
+func (s Snapshot) Plan() pacing.Plan {
+	return pacing.Plan{
+		BookingID:      s.BookingID,
+		Curve:          pacing.Curve(s.Pacing),
+		MaxImpressions: s.MaxImpressions,
+		Delivered:      s.Delivered,
+		Start:          s.Start,
+		End:            s.End,
+	}
+}
+
+// Step is the decision the controller would have made on a served
+// decision of a booking at a snapshot
+type Step struct {
+	At              time.Time `json:"at"`
+	Inputs          Snapshot  `json:"inputs"`
+	Target          int64     `json:"target"` // -1 for unpaced bookings
+	Pacing          string    `json:"pacing"`
+	BudgetSource    string    `json:"budget_source,omitempty"`
+	BudgetRemaining *float64  `json:"budget_remaining,omitempty"` // What the charge was checked against
+	Budget          string    `json:"budget"`
+	Decision        string    `json:"decision"`
+	Overspent       bool      `json:"overspent"` // The campaign had spent more than its budget
+	// Served decisions recorded from this snapshot to the next, and their spend
+	Served int64   `json:"served"`
+	Spend  float64 `json:"spend"`
+}
+
+// Decide replays the pacing and budget checks of a served decision on the
+// inputs of a snapshot. Pacing is checked first, as the controller does.
+func Decide(s Snapshot) Step {
+	step := Step{At: s.TakenAt, Inputs: s, Decision: DecisionServe}
+
+	plan := s.Plan()
+	step.Target = plan.Target(s.TakenAt)
+	switch {
+	case step.Target < 0:
+		step.Pacing = PacingUnpaced
+	case errors.Is(plan.Check(s.TakenAt), pacing.ErrAheadOfPace):
+		step.Pacing = PacingAhead
+		step.Decision = DecisionAheadOfPace
+	default:
+		step.Pacing = PacingBehind
+	}
+
+	switch {
+	case s.BudgetAmount == nil:
+		step.Budget = BudgetUnlimited
+	default:
+		step.Overspent = s.BudgetSpent > *s.BudgetAmount
+		step.BudgetSource, step.BudgetRemaining = SourcePostgres, s.BudgetSeed
+		if s.CounterRemaining != nil {
+			step.BudgetSource, step.BudgetRemaining = SourceRedis, s.CounterRemaining
+		}
+		step.Budget = BudgetCharged
+		if step.BudgetRemaining != nil && budget.Micros(*step.BudgetRemaining) < budget.Micros(s.ImpressionCost) {
+			step.Budget = BudgetExhausted
+			if step.Decision == DecisionServe {
+				step.Decision = DecisionBudgetExhausted
+			}
+		}
+	}
+	return step
+}
+
+// Delivery is what a booking served from one snapshot to the next
+type Delivery struct {
+	Served int64
+	Spend  float64
+}
+
+// Replay decides on each snapshot, in order, with what was served from it
+// to the next, by index
+func Replay(snapshots []Snapshot, delivered []Delivery) []Step {
+	steps := make([]Step, 0, len(snapshots))
+	for i, s := range snapshots {
+		step := Decide(s)
+		if i < len(delivered) {
+			step.Served, step.Spend = delivered[i].Served, delivered[i].Spend
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// Store reads the inputs of snapshots and keeps them
+type Store interface {
+	// ListPacingInputs lists every confirmed and active booking with its
+	// pacing, billing terms and campaign budget, without a counter
+	ListPacingInputs() ([]Snapshot, error)
+	CreatePacingSnapshots(snapshots []Snapshot) error
+	DeletePacingSnapshots(before time.Time) (int, error)
+}
+
+// Budgets prices impressions and reads campaigns' Redis counters, as
+// budget.Tracker does
+type Budgets interface {
+	ImpressionCost(terms billing.Terms) float64
+	Seed(b *budget.Budget) float64
+	CounterRemaining(ctx context.Context, campaignID string) (float64, bool, error)
+}
+
+// Recorder takes snapshots of every live booking
+type Recorder struct {
+	store     Store
+	budgets   Budgets
+	interval  time.Duration
+	retention time.Duration
+}
+
+// NewRecorder creates a recorder taking snapshots every interval and
+// keeping them for retention
+func NewRecorder(store Store, budgets Budgets, interval, retention time.Duration) *Recorder {
+	return &Recorder{store: store, budgets: budgets, interval: interval, retention: retention}
+}
+
+// Snapshot records the state of every live booking at now and returns how
+// many were recorded. Each campaign's counter is read once.
+func (r *Recorder) Snapshot(ctx context.Context, now time.Time) (int, error) {
+	snapshots, err := r.store.ListPacingInputs()
+	if err != nil {
+		return 0, err
+	}
+
+	type counter struct {
+		remaining float64
+		loaded    bool
+	}
+	counters := make(map[string]counter)
+	for i := range snapshots {
+		s := &snapshots[i]
+		s.TakenAt = now
+		s.ImpressionCost = r.budgets.ImpressionCost(s.Terms)
+		if s.BudgetAmount == nil {
+			continue
+		}
+		seed := r.budgets.Seed(&budget.Budget{CampaignID: s.CampaignID, Amount: *s.BudgetAmount, Spent: s.BudgetSpent})
+		s.BudgetSeed = &seed
+
+		c, ok := counters[s.CampaignID]
+		if !ok {
+			remaining, loaded, err := r.budgets.CounterRemaining(ctx, s.CampaignID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read budget counter of %s: %w", s.CampaignID, err)
+			}
+			c = counter{remaining: remaining, loaded: loaded}
+			counters[s.CampaignID] = c
+		}
+		if c.loaded {
+			remaining := c.remaining
+			s.CounterRemaining = &remaining
+		}
+	}
+
+	if err := r.store.CreatePacingSnapshots(snapshots); err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// Run takes a snapshot every interval until ctx is cancelled, deleting
+// those older than the retention
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UTC()
+		if _, err := r.Snapshot(ctx, now); err != nil {
+			logrus.WithError(err).Error("Failed to snapshot pacing state")
+		}
+		deleted, err := r.store.DeletePacingSnapshots(now.Add(-r.retention))
+		if err != nil {
+			logrus.WithError(err).Error("Failed to delete old pacing snapshots")
+		} else if deleted > 0 {
+			logrus.WithField("deleted", deleted).Debug("Deleted old pacing snapshots")
+		}
+	}
+}
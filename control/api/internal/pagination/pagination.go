@@ -0,0 +1,114 @@
+// Package pagination implements opaque keyset cursors. A cursor holds the
+// sort key and ID of the row at the edge of a page, so rows inserted or
+// removed between requests neither repeat nor go missing as they do when
+// paging by offset.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by a listing
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the row a page starts after, or ends before
+type Cursor struct {
+	Before bool   `json:"b,omitempty"` // Page towards the start of the listing
+	Key    string `json:"k"`           // Sort key of the edge row
+	ID     string `json:"i"`           // Unique tiebreaker of the edge row
+}
+
+// Encode makes the cursor opaque to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor returned by Encode
+func Decode(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// FloatKey formats a numeric sort key so it parses back to the same value
+func FloatKey(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Float parses a numeric sort key
+func (c *Cursor) Float() (float64, error) {
+	v, err := strconv.ParseFloat(c.Key, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return v, nil
+}
+
+// TimeKey formats a time sort key
+func TimeKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Time parses a time sort key
+func (c *Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// Page selects a page of a listing. Stores return up to Limit+1 rows nearest
+// the cursor first, which is listing order unless Cursor.Before is set; the
+// extra row tells Window whether more follow.
+type Page struct {
+	Limit  int
+	Offset int     // Deprecated offset paging, only used without a cursor
+	Cursor *Cursor // Nil starts at the beginning of the listing
+}
+
+// Fetch is the number of rows a store should read for the page
+func (p Page) Fetch() int {
+	return p.Limit + 1
+}
+
+// Window trims the rows a store returned for page, puts them in listing order
+// and returns cursors to the next and previous pages, empty where there is none
+func Window[T any](rows []T, page Page, key func(T) Cursor) ([]T, string, string) {
+	more := len(rows) > page.Limit
+	if more {
+		rows = rows[:page.Limit]
+	}
+	before := page.Cursor != nil && page.Cursor.Before
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	var next, prev string
+	// Paging back always leaves rows after the page
+	if more || before {
+		next = key(rows[len(rows)-1]).Encode()
+	}
+	if before && more || !before && (page.Cursor != nil || page.Offset > 0) {
+		first := key(rows[0])
+		first.Before = true
+		prev = first.Encode()
+	}
+	return rows, next, prev
+}
@@ -0,0 +1,144 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Kinds of personal data the detector finds
+const (
+	KindEmail    = "email"
+	KindPhone    = "phone"
+	KindDeviceID = "device_id"
+)
+
+// MaskPrefix starts masked values
+const MaskPrefix = "masked:"
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9-]+(\.[a-z0-9-]+)*\.[a-z]{2,}`)
+	// phonePattern finds digit runs with optional +, spaces, dots, dashes and
+	// parentheses that are not part of a longer alphanumeric token
+	phonePattern = regexp.MustCompile(`(^|[^0-9A-Za-z])(\+?\(?[0-9][0-9 ().-]{8,}[0-9])($|[^0-9A-Za-z])`)
+	// Advertising IDs (IDFA, GAID) are UUIDs
+	advertisingIDPattern = regexp.MustCompile(`(?i)(^|[^0-9a-f-])([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})($|[^0-9a-f-])`)
+	macPattern           = regexp.MustCompile(`(?i)(^|[^0-9a-f:-])([0-9a-f]{2}(:[0-9a-f]{2}){5}|[0-9a-f]{2}(-[0-9a-f]{2}){5})($|[^0-9a-f:-])`)
+	imeiPattern          = regexp.MustCompile(`(^|[^0-9A-Za-z])([0-9]{15})($|[^0-9A-Za-z])`)
+	hashPattern          = regexp.MustCompile(`^(?:[A-Za-z0-9+/_]{32,}={0,2}|(?:sha256|sha1|md5|masked):[0-9a-f]+)$`)
+)
+
+// Detect returns the kinds of personal data found in value, each once.
+// Values that look like hashes are not scanned: a long run of hex or base64
+// with high entropy is what a properly anonymized identifier looks like, and
+// its digit runs would otherwise read as phone numbers.
+func Detect(value string) []string {
+	if value == "" || LooksHashed(value) {
+		return nil
+	}
+
+	kinds := make([]string, 0, 1)
+	if emailPattern.MatchString(value) {
+		kinds = append(kinds, KindEmail)
+	}
+
+	// IMEIs are checked first so a Luhn-valid 15-digit run is not also a phone number
+	deviceID := advertisingIDPattern.MatchString(value) || macPattern.MatchString(value)
+	phone := false
+	for _, m := range imeiPattern.FindAllStringSubmatch(value, -1) {
+		if luhn(m[2]) {
+			deviceID = true
+		} else {
+			phone = true
+		}
+	}
+	if !phone {
+		for _, m := range phonePattern.FindAllStringSubmatch(value, -1) {
+			digits := countDigits(m[2])
+			if digits >= 10 && digits <= 15 && !(digits == 15 && luhn(onlyDigits(m[2]))) {
+				phone = true
+				break
+			}
+		}
+	}
+
+	if phone {
+		kinds = append(kinds, KindPhone)
+	}
+	if deviceID {
+		kinds = append(kinds, KindDeviceID)
+	}
+	return kinds
+}
+
+// LooksHashed reports whether value has the shape and entropy of a hash,
+// e.g. a hex SHA-256 or a base64 token, rather than a raw identifier
+func LooksHashed(value string) bool {
+	if !hashPattern.MatchString(value) {
+		return false
+	}
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		value = value[i+1:]
+	}
+	return entropy(value) >= 3.0
+}
+
+// Mask replaces a value carrying personal data with a hash of it, so a
+// masked viewer ID still identifies one viewer without exposing who
+func Mask(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return MaskPrefix + hex.EncodeToString(sum[:])
+}
+
+// entropy is the Shannon entropy of value in bits per character
+func entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+	bits := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// luhn reports whether a digit string passes the Luhn checksum, as IMEIs do
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func countDigits(s string) int {
+	return len(onlyDigits(s))
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
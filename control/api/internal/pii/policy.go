@@ -0,0 +1,82 @@
+package pii
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions taken on a field carrying personal data
+const (
+	ActionReject = "reject" // Refuse the whole payload
+	ActionMask   = "mask"   // Replace the value with a hash of it
+	ActionReport = "report" // Accept the value, only record the violation
+)
+
+// DefaultFields are the event fields scanned unless configured otherwise
+const DefaultFields = "viewer_id,session_id,device_type,consent_string"
+
+// Policy lists the payload fields scanned for personal data and what to do
+// with each. Fields are matched by name at any depth, ignoring case and
+// underscores, so "viewer_id" also covers camelCase "viewerId".
+type Policy struct {
+	fields map[string]string // Normalized field name to action
+	names  map[string]string // Normalized field name to configured name
+}
+
+// ParsePolicy builds a policy from a comma-separated field list such as
+// "viewer_id=reject,device_type". Fields without an action take defaultAction.
+func ParsePolicy(fields, defaultAction string) (*Policy, error) {
+	if !validAction(defaultAction) {
+		return nil, fmt.Errorf("unknown PII action %q (use reject, mask or report)", defaultAction)
+	}
+
+	policy := &Policy{fields: make(map[string]string), names: make(map[string]string)}
+	for _, entry := range strings.Split(fields, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, action, hasAction := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		action = strings.ToLower(strings.TrimSpace(action))
+		if !hasAction {
+			action = defaultAction
+		}
+		if name == "" || !validAction(action) {
+			return nil, fmt.Errorf("invalid PII field policy %q", entry)
+		}
+		policy.fields[normalize(name)] = action
+		policy.names[normalize(name)] = name
+	}
+	return policy, nil
+}
+
+// Empty reports whether the policy scans no field
+func (p *Policy) Empty() bool {
+	return p == nil || len(p.fields) == 0
+}
+
+// Covers reports whether the policy scans fields named key
+func (p *Policy) Covers(key string) bool {
+	if p.Empty() {
+		return false
+	}
+	_, ok := p.fields[normalize(key)]
+	return ok
+}
+
+// lookup returns the configured name and action for a payload key
+func (p *Policy) lookup(key string) (name, action string, ok bool) {
+	n := normalize(key)
+	action, ok = p.fields[n]
+	return p.names[n], action, ok
+}
+
+func validAction(action string) bool {
+	return action == ActionReject || action == ActionMask || action == ActionReport
+}
+
+// normalize folds snake_case and camelCase spellings of a field together
+func normalize(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", ""))
+}
@@ -0,0 +1,115 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Violation is personal data found in one field of a payload
+type Violation struct {
+	Path   string `json:"path"`  // Location in the payload, e.g. "events[3].viewer_id"
+	Field  string `json:"field"` // Configured field name, e.g. "viewer_id"
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+}
+
+// DailyCount is how many violations of one kind in one field an API key
+// sent on one UTC day
+type DailyCount struct {
+	Day        string    `json:"day"` // YYYY-MM-DD
+	KeyID      string    `json:"key_id,omitempty"`
+	Field      string    `json:"field"`
+	Kind       string    `json:"kind"`
+	Action     string    `json:"action"`
+	Count      int64     `json:"count"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Result is the outcome of scanning a payload
+type Result struct {
+	Violations []Violation
+	Rejected   bool // At least one violation's action is reject
+	Masked     bool // Body was rewritten to mask values
+}
+
+// Scan checks the policy's fields in a JSON payload, masking values where
+// the policy says so. It returns the body to pass on, which is the original
+// unless values were masked. Bodies that are not JSON are returned as they
+// are for the handler to reject.
+func (p *Policy) Scan(body []byte) (*Result, []byte, error) {
+	result := &Result{}
+	if p.Empty() || len(bytes.TrimSpace(body)) == 0 {
+		return result, body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Numbers are re-encoded as sent
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return result, body, nil
+	}
+
+	payload = p.walk(payload, "", result)
+	if !result.Masked {
+		return result, body, nil
+	}
+
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rewrite masked payload: %w", err)
+	}
+	return result, rewritten, nil
+}
+
+// walk scans a decoded JSON value, returning it with masked values replaced
+func (p *Policy) walk(value interface{}, path string, result *Result) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Stable violation order
+		for _, key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			if s, ok := v[key].(string); ok {
+				if name, action, scanned := p.lookup(key); scanned {
+					v[key] = p.check(s, fieldPath, name, action, result)
+					continue
+				}
+			}
+			v[key] = p.walk(v[key], fieldPath, result)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = p.walk(v[i], fmt.Sprintf("%s[%d]", path, i), result)
+		}
+	}
+	return value
+}
+
+// check records the personal data in one scanned field and applies its action
+func (p *Policy) check(value, path, name, action string, result *Result) string {
+	kinds := Detect(value)
+	if len(kinds) == 0 {
+		return value
+	}
+
+	for _, kind := range kinds {
+		result.Violations = append(result.Violations, Violation{Path: path, Field: name, Kind: kind, Action: action})
+	}
+	switch action {
+	case ActionReject:
+		result.Rejected = true
+	case ActionMask:
+		result.Masked = true
+		return Mask(value)
+	}
+	return value
+}
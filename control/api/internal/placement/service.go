@@ -0,0 +1,377 @@
+// Package placement lists placement opportunities, books them and records
+// the exposures of bookings, independent of the transport serving them. The
+// HTTP handlers and the gRPC server parse and answer requests and leave the
+// stores to it, so both transports behave the same.
+//
+// Recording an exposure charges it to its campaign's budget, drops beacons
+// resent within the resend window, counts it against frequency caps and
+// mirrors it to the replication sink; a charge is refunded whenever the
+// event ends up not recorded or not counted.
+package placement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/exposurequeue"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/resend"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// Store is the subset of database operations used by Service
+type Store interface {
+	models.OpportunityRepository
+	models.BookingRepository
+	models.ExposureRepository
+}
+
+// Sink receives recorded exposure events for replication
+type Sink interface {
+	Enqueue(event models.ExposureEvent)
+}
+
+// Biller charges measured exposures of attention-billed bookings to their
+// campaign's budget
+type Biller interface {
+	ChargeExposure(ctx context.Context, bookingID string, exposure billing.Exposure) (*budget.Charge, error)
+	Refund(ctx context.Context, charge *budget.Charge)
+}
+
+// FrequencyCounter counts recorded exposures against per-viewer frequency caps
+type FrequencyCounter interface {
+	RecordExposure(ctx context.Context, viewerID, bookingID string) error
+}
+
+// Service serves placement opportunities, bookings and exposure events
+type Service struct {
+	store     Store
+	sink      Sink
+	biller    Biller
+	frequency FrequencyCounter
+	resends   resend.Window
+}
+
+// NewService creates a placement service backed by the given store
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// SetSink mirrors recorded exposure events to the given sink
+func (s *Service) SetSink(sink Sink) {
+	s.sink = sink
+}
+
+// SetBiller charges exposures of bookings billed on attention or
+// viewability as they are recorded
+func (s *Service) SetBiller(biller Biller) {
+	s.biller = biller
+}
+
+// SetFrequencyCounter counts recorded exposures against the frequency caps
+// of their booking and campaign
+func (s *Service) SetFrequencyCounter(counter FrequencyCounter) {
+	s.frequency = counter
+}
+
+// SetResendWindow drops exposure beacons whose client_event_id was seen for
+// the same booking and viewer within the window
+func (s *Service) SetResendWindow(window resend.Window) {
+	s.resends = window
+}
+
+// ListOpportunities lists surfaces of a title scoring at least minPRS and
+// carrying every label in selector, as a keyset page
+func (s *Service) ListOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]models.Surface, error) {
+	return s.store.GetPlacementOpportunities(titleID, minPRS, selector, page)
+}
+
+// CreateBooking books a surface and returns the new booking's ID. Bookings
+// overlapping a live booking of the surface are refused with a
+// booking.ConflictError.
+func (s *Service) CreateBooking(booking *models.NewBooking) (string, error) {
+	return s.store.CreatePlacementBooking(booking)
+}
+
+// ListBookings lists the bookings within scope, of one campaign unless
+// campaignID is empty, carrying every label in selector
+func (s *Service) ListBookings(scope tenant.Scope, campaignID string, selector labels.Set, limit, offset int) ([]models.Booking, error) {
+	return s.store.ListPlacementBookings(scope, campaignID, selector, limit, offset)
+}
+
+// GetBooking returns a booking as stored, or nil when it does not exist or
+// is outside scope
+func (s *Service) GetBooking(scope tenant.Scope, bookingID string) (*models.Booking, error) {
+	return s.store.GetPlacementBooking(scope, bookingID)
+}
+
+// RecordExposure prices and records a single exposure event, setting its
+// spend and campaign, and returns its event ID. A resend of an event already
+// recorded returns db.ErrDuplicateExposure and is not charged.
+func (s *Service) RecordExposure(ctx context.Context, event *models.ExposureEvent) (string, error) {
+	if s.CheckResends(ctx, []models.ExposureEvent{*event})[0] {
+		return "", db.ErrDuplicateExposure
+	}
+
+	charge, err := s.chargeExposure(ctx, event)
+	if err != nil {
+		s.ForgetResends(ctx, []models.ExposureEvent{*event})
+		return "", fmt.Errorf("failed to price exposure event: %w", err)
+	}
+	eventID, err := s.store.RecordExposureEvent(event)
+	s.settleExposure(ctx, charge, *event, err == nil)
+	if errors.Is(err, db.ErrDuplicateExposure) {
+		return "", err
+	}
+	if err != nil {
+		s.ForgetResends(ctx, []models.ExposureEvent{*event})
+		return "", err
+	}
+	event.EventID = eventID
+	s.countFrequency(ctx, *event)
+	s.mirrorExposure(*event)
+	return eventID, nil
+}
+
+// RecordedExposures are the outcome of RecordExposures: the events recorded,
+// with their IDs set, those that were not, and those already recorded before,
+// indexed into its input
+type RecordedExposures struct {
+	Events     []models.ExposureEvent
+	Failures   []exposurequeue.Failure
+	Duplicates []int
+}
+
+// RecordExposures prices and records validated exposure events in one
+// write. With isolate set, events that cannot be priced are skipped and a
+// failed write is retried event by event, so only the events at fault are
+// returned as failures; otherwise any failure refunds every charge and
+// returns the error with nothing recorded.
+func (s *Service) RecordExposures(ctx context.Context, events []models.ExposureEvent, isolate bool) (*RecordedExposures, error) {
+	result := &RecordedExposures{Events: make([]models.ExposureEvent, 0, len(events))}
+
+	priced := make([]*models.ExposureEvent, 0, len(events))
+	charges := make([]*budget.Charge, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	for i := range events {
+		event := events[i]
+		charge, err := s.chargeExposure(ctx, &event)
+		if err != nil {
+			if !isolate {
+				for j, charge := range charges {
+					s.settleExposure(ctx, charge, *priced[j], false)
+				}
+				s.ForgetResends(ctx, events)
+				return nil, fmt.Errorf("failed to price exposure event %d: %w", i, err)
+			}
+			logrus.WithError(err).WithField("index", i).Warn("Failed to price batch exposure event")
+			result.Failures = append(result.Failures, exposurequeue.Failure{Index: i, Error: "Failed to price exposure"})
+			continue
+		}
+		priced = append(priced, &event)
+		charges = append(charges, charge)
+		indexes = append(indexes, i)
+	}
+	if len(priced) == 0 {
+		s.forgetFailedResends(ctx, events, result.Failures)
+		return result, nil
+	}
+
+	eventIDs, err := s.store.RecordExposureEvents(priced)
+	if err == nil {
+		for i, event := range priced {
+			if eventIDs[i] == "" {
+				// Recorded before, by a resend the window missed
+				s.settleExposure(ctx, charges[i], *event, false)
+				result.Duplicates = append(result.Duplicates, indexes[i])
+				continue
+			}
+			event.EventID = eventIDs[i]
+			s.settleExposure(ctx, charges[i], *event, true)
+			s.countFrequency(ctx, *event)
+			s.mirrorExposure(*event)
+			result.Events = append(result.Events, *event)
+		}
+		s.forgetFailedResends(ctx, events, result.Failures)
+		return result, nil
+	}
+	if !isolate {
+		for i, event := range priced {
+			s.settleExposure(ctx, charges[i], *event, false)
+		}
+		s.ForgetResends(ctx, events)
+		return nil, err
+	}
+
+	// The write is all or nothing, so find the events at fault by writing
+	// each on its own
+	logrus.WithError(err).WithField("event_count", len(priced)).Warn("Failed to record exposure batch; recording events one by one")
+	for i, event := range priced {
+		eventID, err := s.store.RecordExposureEvent(event)
+		s.settleExposure(ctx, charges[i], *event, err == nil)
+		if errors.Is(err, db.ErrDuplicateExposure) {
+			result.Duplicates = append(result.Duplicates, indexes[i])
+			continue
+		}
+		if err != nil {
+			message := "Failed to record exposure"
+			if errors.Is(err, db.ErrBookingNotFound) {
+				message = "Unknown booking_id"
+			}
+			logrus.WithError(err).WithField("index", indexes[i]).Warn("Failed to record batch exposure event")
+			result.Failures = append(result.Failures, exposurequeue.Failure{Index: indexes[i], Error: message})
+			continue
+		}
+		event.EventID = eventID
+		s.countFrequency(ctx, *event)
+		s.mirrorExposure(*event)
+		result.Events = append(result.Events, *event)
+	}
+	sort.Slice(result.Failures, func(i, j int) bool { return result.Failures[i].Index < result.Failures[j].Index })
+	s.forgetFailedResends(ctx, events, result.Failures)
+	return result, nil
+}
+
+// RecordQueuedExposures records a batch taken off the exposure queue, as
+// exposurequeue.Recorder
+func (s *Service) RecordQueuedExposures(ctx context.Context, events []models.ExposureEvent, isolate bool) ([]exposurequeue.Failure, error) {
+	recorded, err := s.RecordExposures(ctx, events, isolate)
+	if err != nil {
+		return nil, err
+	}
+	return recorded.Failures, nil
+}
+
+// chargeExposure prices an exposure under its booking's billing model,
+// takes it from the campaign's budget and attaches the spend to the event.
+// An exposure past the budget is recorded uncounted and free. It returns nil
+// when there is nothing to refund should the event not be counted.
+func (s *Service) chargeExposure(ctx context.Context, event *models.ExposureEvent) (*budget.Charge, error) {
+	if s.biller == nil {
+		return nil, nil
+	}
+	charge, err := s.biller.ChargeExposure(ctx, event.BookingID, billing.Exposure{
+		Duration:       event.ExposureDuration,
+		AttentionScore: event.AttentionScore,
+	})
+	if errors.Is(err, budget.ErrUnknownBooking) {
+		return nil, nil // Recording the event reports the unknown booking
+	}
+	if errors.Is(err, budget.ErrExhausted) && charge != nil {
+		event.CampaignID = charge.CampaignID
+		event.Spend = 0
+		event.Counted = false
+		return nil, nil
+	}
+	if err != nil || charge == nil {
+		return nil, err
+	}
+	event.CampaignID = charge.CampaignID
+	event.Spend = charge.Amount()
+	return charge, nil
+}
+
+// settleExposure refunds an exposure's charge unless the event was recorded
+// and counted towards its booking
+func (s *Service) settleExposure(ctx context.Context, charge *budget.Charge, event models.ExposureEvent, recorded bool) {
+	if charge != nil && (!recorded || !event.Counted) {
+		s.biller.Refund(ctx, charge)
+	}
+}
+
+// countFrequency counts a recorded exposure against its viewer's frequency
+// caps. A failure is logged; the exposure stays recorded.
+func (s *Service) countFrequency(ctx context.Context, event models.ExposureEvent) {
+	if s.frequency == nil {
+		return
+	}
+	if err := s.frequency.RecordExposure(ctx, event.ViewerID, event.BookingID); err != nil {
+		logrus.WithError(err).WithField("booking_id", event.BookingID).Warn("Failed to count exposure against frequency caps")
+	}
+}
+
+// mirrorExposure hands a recorded exposure event to the replication sink, if any
+func (s *Service) mirrorExposure(event models.ExposureEvent) {
+	if s.sink == nil {
+		return
+	}
+	s.sink.Enqueue(event)
+}
+
+// resendKeys returns the resend key of each event, empty for events without
+// a client event ID
+func resendKeys(events []models.ExposureEvent) []string {
+	keys := make([]string, len(events))
+	for i, event := range events {
+		keys[i] = resend.Key(event.BookingID, event.ViewerID, event.ClientEventID)
+	}
+	return keys
+}
+
+// CheckResends marks the events' resend keys seen and reports which events
+// were seen within the window. When the window cannot be read nothing is
+// reported; recording catches duplicates by their stored key.
+func (s *Service) CheckResends(ctx context.Context, events []models.ExposureEvent) []bool {
+	duplicate := make([]bool, len(events))
+	if s.resends == nil {
+		return duplicate
+	}
+	keys, at := make([]string, 0, len(events)), make([]int, 0, len(events))
+	for i, key := range resendKeys(events) {
+		if key != "" {
+			keys, at = append(keys, key), append(at, i)
+		}
+	}
+	if len(keys) == 0 {
+		return duplicate
+	}
+	seen, err := s.resends.Seen(ctx, keys)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check exposure resend window")
+		return duplicate
+	}
+	for j, i := range at {
+		duplicate[i] = seen[j]
+	}
+	return duplicate
+}
+
+// ForgetResends drops the resend keys of events that were not recorded, so
+// the player can resend them
+func (s *Service) ForgetResends(ctx context.Context, events []models.ExposureEvent) {
+	if s.resends == nil {
+		return
+	}
+	keys := make([]string, 0, len(events))
+	for _, key := range resendKeys(events) {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.resends.Forget(ctx, keys); err != nil {
+		logrus.WithError(err).Warn("Failed to forget exposure resend keys")
+	}
+}
+
+// forgetFailedResends drops the resend keys of the events that failed
+func (s *Service) forgetFailedResends(ctx context.Context, events []models.ExposureEvent, failures []exposurequeue.Failure) {
+	if len(failures) == 0 {
+		return
+	}
+	failed := make([]models.ExposureEvent, len(failures))
+	for i, f := range failures {
+		failed[i] = events[f.Index]
+	}
+	s.ForgetResends(ctx, failed)
+}
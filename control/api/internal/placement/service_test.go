@@ -0,0 +1,149 @@
+package placement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/billing"
+	"github.com/inscenium/inscenium/control/api/internal/budget"
+	"github.com/inscenium/inscenium/control/api/internal/db"
+	"github.com/inscenium/inscenium/control/api/internal/exposurequeue"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/pagination"
+	"github.com/inscenium/inscenium/control/api/internal/resend"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStore struct {
+	failing map[string]bool // Bookings whose events fail to record
+	events  []models.ExposureEvent
+}
+
+func (m *mockStore) GetPlacementOpportunities(titleID string, minPRS float64, selector labels.Set, page pagination.Page) ([]models.Surface, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetPlacementOpportunity(surfaceID string) (*models.Surface, error) {
+	return nil, nil
+}
+
+func (m *mockStore) CreatePlacementBooking(booking *models.NewBooking) (string, error) {
+	return "booking_1", nil
+}
+
+func (m *mockStore) GetPlacementBooking(scope tenant.Scope, bookingID string) (*models.Booking, error) {
+	return nil, nil
+}
+
+func (m *mockStore) ListPlacementBookings(scope tenant.Scope, campaignID string, selector labels.Set, limit, offset int) ([]models.Booking, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RecordExposureEvent(event *models.ExposureEvent) (string, error) {
+	if m.failing[event.BookingID] {
+		return "", db.ErrBookingNotFound
+	}
+	m.events = append(m.events, *event)
+	return "event_" + event.ViewerID, nil
+}
+
+func (m *mockStore) RecordExposureEvents(events []*models.ExposureEvent) ([]string, error) {
+	for _, event := range events {
+		if m.failing[event.BookingID] {
+			return nil, db.ErrBookingNotFound
+		}
+	}
+	eventIDs := make([]string, len(events))
+	for i, event := range events {
+		eventIDs[i], _ = m.RecordExposureEvent(event)
+	}
+	return eventIDs, nil
+}
+
+type mockBiller struct {
+	charged  int
+	refunded int
+}
+
+func (m *mockBiller) ChargeExposure(ctx context.Context, bookingID string, exposure billing.Exposure) (*budget.Charge, error) {
+	m.charged++
+	return &budget.Charge{CampaignID: "campaign_1", Cost: 15000}, nil
+}
+
+func (m *mockBiller) Refund(ctx context.Context, charge *budget.Charge) {
+	m.refunded++
+}
+
+type mockSink struct {
+	events []models.ExposureEvent
+}
+
+func (m *mockSink) Enqueue(event models.ExposureEvent) {
+	m.events = append(m.events, event)
+}
+
+func TestService_RecordExposure(t *testing.T) {
+	ctx := context.Background()
+	store := &mockStore{failing: map[string]bool{"booking_gone": true}}
+	biller := &mockBiller{}
+	sink := &mockSink{}
+	s := NewService(store)
+	s.SetBiller(biller)
+	s.SetSink(sink)
+	s.SetResendWindow(resend.NewMemoryWindow(time.Minute))
+
+	event := &models.ExposureEvent{BookingID: "booking_1", ViewerID: "viewer_1", ClientEventID: "beacon_1", Counted: true}
+	eventID, err := s.RecordExposure(ctx, event)
+	require.NoError(t, err)
+	assert.Equal(t, "event_viewer_1", eventID)
+	assert.Equal(t, "campaign_1", event.CampaignID, "The charge's campaign is attached to the event")
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, eventID, sink.events[0].EventID, "Mirrored events carry their stored ID")
+
+	resent := &models.ExposureEvent{BookingID: "booking_1", ViewerID: "viewer_1", ClientEventID: "beacon_1", Counted: true}
+	_, err = s.RecordExposure(ctx, resent)
+	assert.ErrorIs(t, err, db.ErrDuplicateExposure)
+	assert.Equal(t, 1, biller.charged, "A resend within the window is not charged")
+	assert.Len(t, store.events, 1)
+
+	gone := &models.ExposureEvent{BookingID: "booking_gone", ViewerID: "viewer_2", ClientEventID: "beacon_2", Counted: true}
+	_, err = s.RecordExposure(ctx, gone)
+	assert.ErrorIs(t, err, db.ErrBookingNotFound)
+	assert.Equal(t, 1, biller.refunded, "An event not recorded is refunded")
+
+	delete(store.failing, "booking_gone")
+	_, err = s.RecordExposure(ctx, gone)
+	assert.NoError(t, err, "A failed event's resend key is forgotten so the player can resend it")
+}
+
+func TestService_RecordExposures(t *testing.T) {
+	ctx := context.Background()
+	store := &mockStore{failing: map[string]bool{"booking_gone": true}}
+	biller := &mockBiller{}
+	s := NewService(store)
+	s.SetBiller(biller)
+	s.SetResendWindow(resend.NewMemoryWindow(time.Minute))
+
+	events := []models.ExposureEvent{
+		{BookingID: "booking_1", ViewerID: "viewer_1", ClientEventID: "beacon_1", Counted: true},
+		{BookingID: "booking_gone", ViewerID: "viewer_2", ClientEventID: "beacon_2", Counted: true},
+	}
+	assert.Equal(t, []bool{false, false}, s.CheckResends(ctx, events))
+
+	_, err := s.RecordExposures(ctx, events, false)
+	assert.ErrorIs(t, err, db.ErrBookingNotFound)
+	assert.Empty(t, store.events, "Nothing is recorded unless isolating failures")
+	assert.Equal(t, 2, biller.refunded, "Every charge is refunded")
+	assert.Equal(t, []bool{false, false}, s.CheckResends(ctx, events), "Every resend key is forgotten")
+
+	recorded, err := s.RecordExposures(ctx, events, true)
+	require.NoError(t, err)
+	require.Len(t, recorded.Events, 1)
+	assert.Equal(t, "event_viewer_1", recorded.Events[0].EventID)
+	assert.Equal(t, []exposurequeue.Failure{{Index: 1, Error: "Unknown booking_id"}}, recorded.Failures)
+	assert.Equal(t, []bool{true, false}, s.CheckResends(ctx, events), "Only the failed event's resend key is forgotten")
+}
@@ -0,0 +1,266 @@
+package promotion
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+)
+
+// FormatVersion is the bundle format this build writes and reads
+const FormatVersion = 1
+
+// MaxBookings bounds the bookings one bundle may carry
+const MaxBookings = 1000
+
+// maxIDLength matches the campaign, creative and surface ID columns
+const maxIDLength = 100
+
+// maxExternalIDLength matches the external ID length accepted by the API
+const maxExternalIDLength = 255
+
+// Resource types of a bundle
+const (
+	ResourceCampaign = labels.ResourceCampaign
+	ResourceCreative = labels.ResourceCreative
+	ResourceBooking  = labels.ResourceBooking
+)
+
+// What an import does with each resource
+const (
+	ActionCreate = "create" // New in the target environment
+	ActionUpdate = "update" // Exists there; labels, partner IDs and budget in the bundle replace its own
+	ActionSkip   = "skip"   // A booking imported from the same source before
+)
+
+// Bundle is the portable description of campaigns, their live bookings and
+// the creatives those use, exported from one environment for import into
+// another. Spend, delivery and history stay behind.
+type Bundle struct {
+	FormatVersion int        `json:"format_version"`
+	Source        string     `json:"source"` // Environment exported from, e.g. "staging"
+	ExportedAt    time.Time  `json:"exported_at"`
+	Campaigns     []Campaign `json:"campaigns"`
+	Creatives     []Creative `json:"creatives"`
+	Bookings      []Booking  `json:"bookings"`
+}
+
+// Campaign carries a campaign's settings
+type Campaign struct {
+	CampaignID   string            `json:"campaign_id"`
+	AdvertiserID string            `json:"advertiser_id,omitempty"` // Must exist in the target environment
+	Name         string            `json:"name,omitempty"`
+	Budget       *float64          `json:"budget,omitempty"` // Amount only; spend is not carried over
+	Labels       labels.Set        `json:"labels,omitempty"`
+	ExternalIDs  map[string]string `json:"external_ids,omitempty"`
+}
+
+// Creative carries the metadata of a creative used by a booking
+type Creative struct {
+	CreativeID  string            `json:"creative_id"`
+	Labels      labels.Set        `json:"labels,omitempty"`
+	ExternalIDs map[string]string `json:"external_ids,omitempty"`
+}
+
+// Booking carries the terms of a live booking. TitleID and SurfaceType
+// describe the source surface to help map it onto the target's.
+type Booking struct {
+	BookingID       string            `json:"booking_id"`
+	SurfaceID       string            `json:"surface_id"`
+	TitleID         string            `json:"title_id,omitempty"`
+	SurfaceType     string            `json:"surface_type,omitempty"`
+	AdvertiserID    string            `json:"advertiser_id"`
+	CampaignID      string            `json:"campaign_id"`
+	CreativeAssetID string            `json:"creative_asset_id,omitempty"`
+	BidAmountCPM    float64           `json:"bid_amount_cpm"`
+	MaxImpressions  int               `json:"max_impressions"`
+	MinPRSScore     float64           `json:"min_prs_score"`
+	Labels          labels.Set        `json:"labels,omitempty"`
+	ExternalIDs     map[string]string `json:"external_ids,omitempty"`
+}
+
+// IDMap renames surfaces, campaigns and creatives from their source IDs to
+// the IDs they have in the target environment. IDs not in the map are kept.
+type IDMap struct {
+	Surfaces  map[string]string `json:"surfaces,omitempty"`
+	Campaigns map[string]string `json:"campaigns,omitempty"`
+	Creatives map[string]string `json:"creatives,omitempty"`
+}
+
+// Surface returns the target ID of a source surface
+func (m IDMap) Surface(id string) string {
+	return mapped(m.Surfaces, id)
+}
+
+// Campaign returns the target ID of a source campaign
+func (m IDMap) Campaign(id string) string {
+	return mapped(m.Campaigns, id)
+}
+
+// Creative returns the target ID of a source creative
+func (m IDMap) Creative(id string) string {
+	return mapped(m.Creatives, id)
+}
+
+func mapped(ids map[string]string, id string) string {
+	if target, ok := ids[id]; ok && target != "" {
+		return target
+	}
+	return id
+}
+
+// Import is a request to create a bundle's resources in this environment
+type Import struct {
+	Bundle     Bundle
+	IDMap      IDMap
+	DryRun     bool
+	OrgID      string
+	ImportedBy string
+}
+
+// Problem is a reason one resource of a bundle cannot be imported
+type Problem struct {
+	ResourceType string `json:"resource_type"`
+	SourceID     string `json:"source_id"`
+	Message      string `json:"message"`
+}
+
+// Mapping is what an import did, or on a dry run would do, with one resource
+type Mapping struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	Action   string `json:"action"`
+}
+
+// Manifest records how an import mapped source IDs onto target IDs. A
+// manifest with problems made no changes.
+type Manifest struct {
+	ImportID   string    `json:"import_id,omitempty"` // Empty on dry runs and failed imports
+	Source     string    `json:"source"`
+	DryRun     bool      `json:"dry_run"`
+	Campaigns  []Mapping `json:"campaigns"`
+	Creatives  []Mapping `json:"creatives"`
+	Bookings   []Mapping `json:"bookings"`
+	Problems   []Problem `json:"problems"`
+	ImportedBy string    `json:"imported_by,omitempty"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// NewManifest returns an empty manifest for an import
+func NewManifest(imp *Import) *Manifest {
+	return &Manifest{
+		Source:     imp.Bundle.Source,
+		DryRun:     imp.DryRun,
+		Campaigns:  make([]Mapping, 0, len(imp.Bundle.Campaigns)),
+		Creatives:  make([]Mapping, 0, len(imp.Bundle.Creatives)),
+		Bookings:   make([]Mapping, 0, len(imp.Bundle.Bookings)),
+		Problems:   make([]Problem, 0),
+		ImportedBy: imp.ImportedBy,
+		ImportedAt: time.Now().UTC(),
+	}
+}
+
+// AddProblem records a reason a resource cannot be imported
+func (m *Manifest) AddProblem(resourceType, sourceID, format string, args ...interface{}) {
+	m.Problems = append(m.Problems, Problem{ResourceType: resourceType, SourceID: sourceID, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks an import's bundle and ID map without looking at the
+// target environment, returning every problem found
+func (imp *Import) Validate() []Problem {
+	b := &imp.Bundle
+	m := &Manifest{Problems: make([]Problem, 0)}
+	if b.FormatVersion != FormatVersion {
+		m.AddProblem("bundle", "", "unsupported format_version %d (this environment reads %d)", b.FormatVersion, FormatVersion)
+		return m.Problems
+	}
+	if b.Source == "" {
+		m.AddProblem("bundle", "", "source is required")
+	}
+	if len(b.Bookings) > MaxBookings {
+		m.AddProblem("bundle", "", "bundle has %d bookings, more than %d", len(b.Bookings), MaxBookings)
+		return m.Problems
+	}
+
+	campaigns := make(map[string]bool, len(b.Campaigns))
+	for _, campaign := range b.Campaigns {
+		if !validID(campaign.CampaignID) || !validID(imp.IDMap.Campaign(campaign.CampaignID)) {
+			m.AddProblem(ResourceCampaign, campaign.CampaignID, "campaign_id must be 1 to %d characters", maxIDLength)
+		}
+		if campaigns[campaign.CampaignID] {
+			m.AddProblem(ResourceCampaign, campaign.CampaignID, "campaign appears more than once")
+		}
+		campaigns[campaign.CampaignID] = true
+		if campaign.Budget != nil && (*campaign.Budget <= 0 || math.IsInf(*campaign.Budget, 0) || math.IsNaN(*campaign.Budget)) {
+			m.AddProblem(ResourceCampaign, campaign.CampaignID, "budget must be positive")
+		}
+		validateMetadata(m, ResourceCampaign, campaign.CampaignID, campaign.Labels, campaign.ExternalIDs)
+	}
+
+	creatives := make(map[string]bool, len(b.Creatives))
+	for _, creative := range b.Creatives {
+		if !validID(creative.CreativeID) || !validID(imp.IDMap.Creative(creative.CreativeID)) {
+			m.AddProblem(ResourceCreative, creative.CreativeID, "creative_id must be 1 to %d characters", maxIDLength)
+		}
+		if creatives[creative.CreativeID] {
+			m.AddProblem(ResourceCreative, creative.CreativeID, "creative appears more than once")
+		}
+		creatives[creative.CreativeID] = true
+		validateMetadata(m, ResourceCreative, creative.CreativeID, creative.Labels, creative.ExternalIDs)
+	}
+
+	bookings := make(map[string]bool, len(b.Bookings))
+	// Booking IDs derive from the surface and time, so one import books each surface once
+	surfaces := make(map[string]string, len(b.Bookings))
+	for _, booking := range b.Bookings {
+		if booking.BookingID == "" || bookings[booking.BookingID] {
+			m.AddProblem(ResourceBooking, booking.BookingID, "booking_id must be present and unique")
+		}
+		bookings[booking.BookingID] = true
+		if !campaigns[booking.CampaignID] {
+			m.AddProblem(ResourceBooking, booking.BookingID, "campaign %q is not in the bundle", booking.CampaignID)
+		}
+		if booking.CreativeAssetID != "" && !creatives[booking.CreativeAssetID] {
+			m.AddProblem(ResourceBooking, booking.BookingID, "creative %q is not in the bundle", booking.CreativeAssetID)
+		}
+		if booking.AdvertiserID == "" {
+			m.AddProblem(ResourceBooking, booking.BookingID, "advertiser_id is required")
+		}
+		if booking.BidAmountCPM <= 0 || math.IsInf(booking.BidAmountCPM, 0) || math.IsNaN(booking.BidAmountCPM) {
+			m.AddProblem(ResourceBooking, booking.BookingID, "bid_amount_cpm must be positive")
+		}
+		if booking.MaxImpressions < 0 {
+			m.AddProblem(ResourceBooking, booking.BookingID, "max_impressions must not be negative")
+		}
+
+		surfaceID := imp.IDMap.Surface(booking.SurfaceID)
+		if !validID(surfaceID) {
+			m.AddProblem(ResourceBooking, booking.BookingID, "surface_id must be 1 to %d characters", maxIDLength)
+		} else if other, ok := surfaces[surfaceID]; ok {
+			m.AddProblem(ResourceBooking, booking.BookingID, "surface %s is also booked by %s; import one of them separately", surfaceID, other)
+		}
+		surfaces[surfaceID] = booking.BookingID
+		validateMetadata(m, ResourceBooking, booking.BookingID, booking.Labels, booking.ExternalIDs)
+	}
+	return m.Problems
+}
+
+// validateMetadata checks the labels and partner IDs of one resource
+func validateMetadata(m *Manifest, resourceType, sourceID string, set labels.Set, ids map[string]string) {
+	if err := set.Validate(); err != nil {
+		m.AddProblem(resourceType, sourceID, "%s", err)
+	}
+	for source, externalID := range ids {
+		if !labels.ValidKey(source) {
+			m.AddProblem(resourceType, sourceID, "invalid external ID source %q", source)
+		}
+		if externalID == "" || len(externalID) > maxExternalIDLength {
+			m.AddProblem(resourceType, sourceID, "external ID for source %q must be 1-%d characters", source, maxExternalIDLength)
+		}
+	}
+}
+
+func validID(id string) bool {
+	return id != "" && len(id) <= maxIDLength
+}
@@ -0,0 +1,110 @@
+// Package quality describes how clean and how complete the exposure events
+// behind an analytics figure are. Figures only count events that are valid
+// and not duplicates:
+//
+//   - An event is invalid when its exposure duration is not positive, its
+//     attention score is outside 0-1, its screen coverage is outside 0-100%
+//     or its skew-corrected time is more than FutureTolerance after it was
+//     received, which no real viewer can produce.
+//   - An event is a duplicate of an earlier one of its booking with the same
+//     viewer, device event time and exposure duration. Edge nodes resend
+//     batches whose acknowledgement was lost, storing their events again.
+//
+// Events arrive late, so figures for recent hours grow as edge nodes catch
+// up. Completeness estimates the share of an hour's events received so far
+// from how late the events of the same scope arrived over the reference
+// window.
+package quality
+
+import (
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+const (
+	// FutureTolerance is how far after its receipt an event may be timed
+	FutureTolerance = time.Minute
+	// RecentHours is how many hours up to now completeness is estimated for
+	RecentHours = 6
+	// ReferenceWindow is how far back the arrival delays of events are sampled
+	ReferenceWindow = 7 * 24 * time.Hour
+	// ReferenceDelay keeps events of the last day out of the sample, as some
+	// of them have yet to arrive. Delays longer than it are not captured.
+	ReferenceDelay = 24 * time.Hour
+)
+
+// Filter selects the events a figure was built from: those of a booking or
+// of every booking in a campaign, within the caller's tenant scope. Zero
+// times leave the event time range open.
+type Filter struct {
+	Tenant     tenant.Scope
+	BookingID  string
+	CampaignID string
+	From       time.Time
+	To         time.Time
+}
+
+// Report is the data_quality block of analytics responses
+type Report struct {
+	EventsConsidered  int64      `json:"events_considered"`  // Stored events matching the filter
+	InvalidExcluded   int64      `json:"invalid_excluded"`   // Events left out as invalid traffic
+	DuplicatesRemoved int64      `json:"duplicates_removed"` // Valid events left out as resent copies
+	EventsCounted     int64      `json:"events_counted"`     // Events the figures are built from
+	LastRollupAt      time.Time  `json:"last_rollup_at"`     // Figures are aggregated on request
+	LastReceivedAt    *time.Time `json:"last_received_at,omitempty"`
+	Completeness      []Hour     `json:"completeness"`
+}
+
+// Hour estimates the share of an hour's events received so far
+type Hour struct {
+	Hour     time.Time `json:"hour"`
+	Estimate *float64  `json:"estimate"` // Null without reference events
+}
+
+// NewReport counts the events left out of a figure aggregated at now
+func NewReport(considered, invalid, duplicates int64, lastReceivedAt *time.Time, now time.Time) *Report {
+	return &Report{
+		EventsConsidered:  considered,
+		InvalidExcluded:   invalid,
+		DuplicatesRemoved: duplicates,
+		EventsCounted:     considered - invalid - duplicates,
+		LastRollupAt:      now.UTC(),
+		LastReceivedAt:    lastReceivedAt,
+		Completeness:      []Hour{},
+	}
+}
+
+// RecentHours returns the starts of the last RecentHours hours up to now,
+// oldest first, that overlap the filter's time range
+func (f Filter) RecentHours(now time.Time) []time.Time {
+	now = now.UTC()
+	current := now.Truncate(time.Hour)
+	hours := make([]time.Time, 0, RecentHours)
+	for i := RecentHours - 1; i >= 0; i-- {
+		hour := current.Add(-time.Duration(i) * time.Hour)
+		if !f.From.IsZero() && !hour.Add(time.Hour).After(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !hour.Before(f.To) {
+			continue
+		}
+		hours = append(hours, hour)
+	}
+	return hours
+}
+
+// Elapsed is how long ago the middle of the part of an hour up to now was,
+// the delay an event of the hour has had to arrive on average
+func Elapsed(hour, now time.Time) time.Duration {
+	end := hour.Add(time.Hour)
+	if end.After(now) {
+		end = now
+	}
+	return now.Sub(hour.Add(end.Sub(hour) / 2))
+}
+
+// ReferenceRange returns the event time range arrival delays are sampled from
+func ReferenceRange(now time.Time) (time.Time, time.Time) {
+	return now.Add(-ReferenceWindow), now.Add(-ReferenceDelay)
+}
@@ -0,0 +1,179 @@
+// Package query composes the WHERE clauses of list queries. Column names and
+// condition SQL come from code; every value comes from a filter and is bound
+// as a numbered parameter, never spliced into the SQL, after checking that
+// Postgres will accept it.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// MaxValueLength caps the length of a bound string, well above any ID, label
+// or name the API accepts
+const MaxValueLength = 1024
+
+// Sort directions of a keyset-paged listing
+const (
+	Ascending  = "ASC"
+	Descending = "DESC"
+)
+
+// ErrInvalidFilter is returned for filter values that cannot be bound, such as
+// strings holding NUL bytes or numbers that are not finite
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// columnPattern matches column names, optionally qualified by a table
+var columnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// Builder collects the conditions of a WHERE clause and the values bound to
+// their placeholders
+type Builder struct {
+	first int
+	conds []string
+	args  []interface{}
+	err   error
+}
+
+// New returns a builder numbering placeholders from $1
+func New() *Builder {
+	return From(1)
+}
+
+// From returns a builder numbering placeholders from firstArg, for queries
+// whose earlier placeholders are bound elsewhere
+func From(firstArg int) *Builder {
+	return &Builder{first: firstArg}
+}
+
+// Bind binds a value and returns its placeholder. Values that cannot be bound
+// are replaced by NULL and reported by Err.
+func (b *Builder) Bind(value interface{}) string {
+	if err := validate(value); err != nil {
+		b.invalid(err)
+		value = nil
+	}
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", b.first+len(b.args)-1)
+}
+
+// Add adds a condition whose values were bound with Bind
+func (b *Builder) Add(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+// Where adds a condition written in SQL with a %s for each value, which is
+// bound in its place. A literal percent sign is written %%. The SQL must come
+// from code, never from a request.
+func (b *Builder) Where(sql string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = b.Bind(value)
+	}
+	b.Add(fmt.Sprintf(sql, placeholders...))
+}
+
+// Equal adds a condition that column equals value
+func (b *Builder) Equal(column string, value interface{}) {
+	b.Where(Column(column)+" = %s", value)
+}
+
+// AtLeast adds a condition that column is at least value
+func (b *Builder) AtLeast(column string, value interface{}) {
+	b.Where(Column(column)+" >= %s", value)
+}
+
+// AnyOf adds a condition that column equals one of values. No values match
+// no rows.
+func (b *Builder) AnyOf(column string, values []string) {
+	for _, value := range values {
+		if err := validate(value); err != nil {
+			b.invalid(err)
+			values = nil
+			break
+		}
+	}
+	b.Where(Column(column)+" = ANY(%s)", pq.Array(values))
+}
+
+// After adds a keyset condition for a page of a listing sorted on sortColumn
+// with idColumn as tiebreaker, starting after the row (key, id), and returns
+// the direction to sort in. Pages before the row are sorted the other way and
+// reversed by the caller.
+func (b *Builder) After(sortColumn, idColumn string, key interface{}, id string, before bool) string {
+	op, direction := "<", Descending
+	if before {
+		op, direction = ">", Ascending
+	}
+	b.Where(fmt.Sprintf("(%s, %s) %s (%%s, %%s)", Column(sortColumn), Column(idColumn), op), key, id)
+	return direction
+}
+
+// Clause returns the conditions joined with AND, or TRUE when there are none
+func (b *Builder) Clause() string {
+	if len(b.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(b.conds, "\n\t\t\tAND ")
+}
+
+// Args returns the bound values in placeholder order
+func (b *Builder) Args() []interface{} {
+	return b.args
+}
+
+// Err returns the first value that could not be bound, wrapping
+// ErrInvalidFilter. Queries must not be run when it is set.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// invalid records a value that could not be bound
+func (b *Builder) invalid(err error) {
+	if b.err == nil {
+		b.err = fmt.Errorf("%w: a value %v", ErrInvalidFilter, err)
+	}
+}
+
+// Column returns column if it is a plain or table-qualified column name.
+// Column names are constants of the caller, so any other name is a bug.
+func Column(column string) string {
+	if !columnPattern.MatchString(column) {
+		panic(fmt.Sprintf("query: invalid column name %q", column))
+	}
+	return column
+}
+
+// validate checks that Postgres will accept a value
+func validate(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return validateString(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("is not a finite number")
+		}
+	case float32:
+		return validate(float64(v))
+	}
+	return nil
+}
+
+// validateString checks a string value
+func validateString(s string) error {
+	switch {
+	case len(s) > MaxValueLength:
+		return fmt.Errorf("is longer than %d bytes", MaxValueLength)
+	case !utf8.ValidString(s):
+		return errors.New("is not valid UTF-8")
+	case strings.IndexByte(s, 0) >= 0:
+		return errors.New("contains a NUL byte")
+	}
+	return nil
+}
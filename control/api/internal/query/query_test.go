@@ -0,0 +1,104 @@
+package query
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// build composes a clause the way the listings do
+func build(firstArg int, titleID string, minPRS float64, key, value string, before bool) *Builder {
+	b := From(firstArg)
+	b.Equal("title_id", titleID)
+	b.AtLeast("prs_score", minPRS)
+	b.Where("(label_key = %s AND label_value = %s)", key, value)
+	b.AnyOf("surfaces.org_id", []string{key, value})
+	b.After("prs_score", "surface_id", minPRS, value, before)
+	return b
+}
+
+func bindable(s string) bool {
+	return len(s) <= MaxValueLength && utf8.ValidString(s) && !strings.ContainsRune(s, 0)
+}
+
+func TestBuilder(t *testing.T) {
+	assert.Equal(t, "TRUE", New().Clause())
+
+	b := build(4, "title_1", 0.5, "team", "sports", false)
+	require.NoError(t, b.Err())
+	assert.Equal(t, "title_id = $4\n\t\t\tAND prs_score >= $5\n\t\t\tAND (label_key = $6 AND label_value = $7)"+
+		"\n\t\t\tAND surfaces.org_id = ANY($8)\n\t\t\tAND (prs_score, surface_id) < ($9, $10)", b.Clause())
+	assert.Len(t, b.Args(), 7)
+	assert.Equal(t, Ascending, New().After("prs_score", "surface_id", 0.5, "surface_1", true))
+
+	b = build(1, "title\x00", math.NaN(), "team", "sports", false)
+	assert.True(t, errors.Is(b.Err(), ErrInvalidFilter))
+	assert.Nil(t, b.Args()[0], "Values that cannot be bound should not reach the database")
+
+	assert.Panics(t, func() { New().Equal("title_id; DROP TABLE surfaces", "x") })
+}
+
+// FuzzBuilder checks that filter values never change the SQL of a clause,
+// that placeholders match the bound values, and that only values Postgres
+// would reject are refused
+func FuzzBuilder(f *testing.F) {
+	f.Add(1, "title_1", 0.5, "team", "sports", false)
+	f.Add(4, "'; DROP TABLE surfaces; --", 0.0, "$1", "%s", true)
+	f.Add(2, "a\x00b", math.Inf(1), "\xff", "' OR '1'='1", false)
+	f.Add(6, strings.Repeat("x", MaxValueLength+1), math.NaN(), "%!d(MISSING)", "$$", true)
+
+	f.Fuzz(func(t *testing.T, firstArg int, titleID string, minPRS float64, key, value string, before bool) {
+		if firstArg < 1 || firstArg > 1000 {
+			return
+		}
+		b := build(firstArg, titleID, minPRS, key, value, before)
+
+		reference := build(firstArg, "title", 0, "key", "value", before)
+		require.NoError(t, reference.Err())
+		assert.Equal(t, reference.Clause(), b.Clause(), "Filter values should never change the SQL")
+
+		args := b.Args()
+		seen := map[int]bool{}
+		for _, match := range placeholderPattern.FindAllStringSubmatch(b.Clause(), -1) {
+			n, err := strconv.Atoi(match[1])
+			require.NoError(t, err)
+			require.True(t, n >= firstArg && n < firstArg+len(args), "Placeholder $%d has no bound value", n)
+			seen[n] = true
+		}
+		assert.Len(t, seen, len(args), "Every bound value should have a placeholder")
+
+		valid := bindable(titleID) && bindable(key) && bindable(value) && !math.IsNaN(minPRS) && !math.IsInf(minPRS, 0)
+		if valid {
+			assert.NoError(t, b.Err())
+		} else {
+			assert.True(t, errors.Is(b.Err(), ErrInvalidFilter), "Should refuse values Postgres would reject")
+		}
+	})
+}
+
+// FuzzColumn checks that only plain or table-qualified column names are
+// accepted
+func FuzzColumn(f *testing.F) {
+	f.Add("prs_score")
+	f.Add("placement_bookings.org_id")
+	f.Add("org_id) OR (1=1")
+	f.Add("a.b.c")
+
+	f.Fuzz(func(t *testing.T, column string) {
+		defer func() {
+			if recover() == nil {
+				assert.Regexp(t, `^[a-z0-9_]+(\.[a-z0-9_]+)?$`, column)
+			}
+		}()
+		Column(column)
+	})
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryBucket is a token bucket per caller kept in process memory. Each
+// gateway instance limits on its own, so use RedisBucket behind a load balancer.
+type MemoryBucket struct {
+	rate    float64
+	burst   int64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewMemoryBucket creates an in-memory token bucket limiter
+func NewMemoryBucket(rate float64, burst int64) *MemoryBucket {
+	return &MemoryBucket{rate: rate, burst: burst, buckets: make(map[string]*bucket)}
+}
+
+// Take takes a token from key's bucket
+func (m *MemoryBucket) Take(ctx context.Context, key string) (*Status, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(m.burst), at: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(m.burst), b.tokens+now.Sub(b.at).Seconds()*m.rate)
+	b.at = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketStatus(m.rate, m.burst, b.tokens, allowed, now), nil
+}
+
+// sweep drops buckets that have refilled, at most once a minute
+func (m *MemoryBucket) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*m.rate >= float64(m.burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// bucketStatus reports a bucket holding tokens after a request
+func bucketStatus(rate float64, burst int64, tokens float64, allowed bool, now time.Time) *Status {
+	status := &Status{
+		Limit:     burst,
+		Remaining: int64(math.Floor(tokens)),
+		Reset:     now.Add(time.Duration((float64(burst) - tokens) / rate * float64(time.Second))),
+		Allowed:   allowed,
+	}
+	if !allowed {
+		status.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return status
+}
+
+// MemoryQuota counts requests per caller per UTC day in process memory
+type MemoryQuota struct {
+	limit  int64
+	mu     sync.Mutex
+	day    time.Time
+	counts map[string]int64
+}
+
+// NewMemoryQuota creates an in-memory daily quota
+func NewMemoryQuota(limit int64) *MemoryQuota {
+	return &MemoryQuota{limit: limit, counts: make(map[string]int64)}
+}
+
+// Take counts a request against key's quota for today
+func (m *MemoryQuota) Take(ctx context.Context, key string) (*Status, error) {
+	reset := nextDay(time.Now())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !reset.Equal(m.day) {
+		m.day = reset
+		m.counts = make(map[string]int64)
+	}
+	used := m.counts[key]
+	if used >= m.limit {
+		return quotaStatus(m.limit, used, false, reset), nil
+	}
+	m.counts[key] = used + 1
+	return quotaStatus(m.limit, used+1, true, reset), nil
+}
+
+// quotaStatus reports a quota after a request brought its use to used
+func quotaStatus(limit, used int64, allowed bool, reset time.Time) *Status {
+	status := &Status{
+		Limit:     limit,
+		Remaining: limit - used,
+		Reset:     reset,
+		Allowed:   allowed,
+	}
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+	if !allowed {
+		status.RetryAfter = time.Until(reset)
+	}
+	return status
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Status is where a caller stands against a limit after a request
+type Status struct {
+	Limit     int64
+	Remaining int64
+	Reset     time.Time // When the limit is fully available again
+	Allowed   bool
+	// RetryAfter is how long a refused caller should wait before trying again
+	RetryAfter time.Duration
+}
+
+// Limiter takes one request from a caller's allowance
+type Limiter interface {
+	Take(ctx context.Context, key string) (*Status, error)
+}
+
+// Config sizes the per-caller token bucket and per-organization daily quota
+type Config struct {
+	Rate  float64 // Requests per second the bucket refills by
+	Burst int64   // Bucket size; 0 disables rate limiting
+	Quota int64   // Requests per organization per UTC day; 0 disables the quota
+}
+
+// nextDay is the start of the UTC day after now, when daily quotas reset
+func nextDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
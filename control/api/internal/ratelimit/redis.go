@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bucketScript refills a token bucket for the time since it was last used
+// and takes one token if it holds one. It returns {1 if taken, tokens left}
+// with tokens as a string to keep their fraction.
+// KEYS: bucket. ARGV: rate per second, burst.
+var bucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - at) * rate)
+local taken = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	taken = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(burst / rate) + 1)
+return {taken, tostring(tokens)}
+`)
+
+// quotaScript counts a request against a daily quota unless it is used up.
+// It returns {1 if counted, used}.
+// KEYS: counter. ARGV: limit, expiry as unix seconds.
+var quotaScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+if used >= tonumber(ARGV[1]) then
+	return {0, used}
+end
+used = redis.call('INCR', KEYS[1])
+if used == 1 then
+	redis.call('EXPIREAT', KEYS[1], ARGV[2])
+end
+return {1, used}
+`)
+
+// RedisBucket is a token bucket per caller shared by every gateway instance
+type RedisBucket struct {
+	client redis.UniversalClient
+	rate   float64
+	burst  int64
+}
+
+// NewRedisBucket creates a token bucket limiter backed by Redis
+func NewRedisBucket(client redis.UniversalClient, rate float64, burst int64) *RedisBucket {
+	return &RedisBucket{client: client, rate: rate, burst: burst}
+}
+
+// Take takes a token from key's bucket
+func (r *RedisBucket) Take(ctx context.Context, key string) (*Status, error) {
+	result, err := bucketScript.Run(ctx, r.client, []string{"ratelimit:{" + key + "}"}, r.rate, r.burst).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	taken, _ := result[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(result[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit bucket for %s is corrupt: %w", key, err)
+	}
+	return bucketStatus(r.rate, r.burst, tokens, taken == 1, time.Now()), nil
+}
+
+// RedisQuota counts requests per caller per UTC day in Redis
+type RedisQuota struct {
+	client redis.UniversalClient
+	limit  int64
+}
+
+// NewRedisQuota creates a daily quota backed by Redis
+func NewRedisQuota(client redis.UniversalClient, limit int64) *RedisQuota {
+	return &RedisQuota{client: client, limit: limit}
+}
+
+// Take counts a request against key's quota for today
+func (r *RedisQuota) Take(ctx context.Context, key string) (*Status, error) {
+	now := time.Now().UTC()
+	reset := nextDay(now)
+	counter := "quota:{" + key + "}:" + now.Format("20060102")
+	result, err := quotaScript.Run(ctx, r.client, []string{counter}, r.limit, reset.Unix()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count request quota: %w", err)
+	}
+	return quotaStatus(r.limit, result[1], result[0] == 1, reset), nil
+}
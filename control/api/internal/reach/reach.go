@@ -0,0 +1,268 @@
+// Package reach reports how many distinct viewers campaigns reached and how
+// often, over any range of days. Each campaign's viewers of a UTC day are
+// kept as a HyperLogLog sketch, recomputed like the exposure rollups when
+// late events arrive; a range's reach is the estimate of the day sketches
+// merged, and two campaigns overlap by the viewers their merged sketches
+// do not add to each other. Frequency distributions are counted from the
+// events themselves.
+package reach
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/reporting"
+	"github.com/inscenium/inscenium/control/api/internal/rollup"
+	"github.com/inscenium/inscenium/control/api/internal/sketch"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxCampaigns bounds the campaigns of one report
+	MaxCampaigns = 10
+	// MaxDays bounds the days of one report
+	MaxDays = 366
+	// MaxFrequency is the last frequency bucket, which counts viewers
+	// reached that many times or more
+	MaxFrequency = 10
+)
+
+// Query asks for the reach of campaigns over whole UTC days
+type Query struct {
+	CampaignIDs []string
+	From        time.Time // Start of the first day
+	To          time.Time // Start of the day after the last
+	Tenant      tenant.Scope
+	Privacy     *reporting.Privacy
+}
+
+// NewQuery aligns from down and to up to UTC days
+func NewQuery(campaignIDs []string, from, to time.Time) (Query, error) {
+	if len(campaignIDs) == 0 || len(campaignIDs) > MaxCampaigns {
+		return Query{}, fmt.Errorf("campaign_id must list 1 to %d campaigns", MaxCampaigns)
+	}
+	seen := make(map[string]bool, len(campaignIDs))
+	for _, id := range campaignIDs {
+		if id == "" || seen[id] {
+			return Query{}, fmt.Errorf("campaign_id must list distinct campaigns")
+		}
+		seen[id] = true
+	}
+	r, err := rollup.NewRange(from, to, rollup.GranularityDay)
+	if err != nil {
+		return Query{}, err
+	}
+	if r.Buckets() > MaxDays {
+		return Query{}, fmt.Errorf("range spans more than %d days", MaxDays)
+	}
+	return Query{CampaignIDs: campaignIDs, From: r.From, To: r.To}, nil
+}
+
+// Day is a campaign's counted impressions and viewers of one UTC day
+type Day struct {
+	CampaignID  string
+	Day         time.Time
+	Impressions int64
+	Viewers     *sketch.HLL
+}
+
+// DayReach is a campaign's delivery on one day of a report
+type DayReach struct {
+	Day             time.Time `json:"day"`
+	Impressions     int64     `json:"impressions"`
+	NewReach        int64     `json:"new_reach"` // Viewers first reached in the range that day
+	CumulativeReach int64     `json:"cumulative_reach"`
+}
+
+// FrequencyBucket counts the viewers reached a number of times
+type FrequencyBucket struct {
+	Frequency string  `json:"frequency"` // "1" to "9", then "10+"
+	Viewers   int64   `json:"viewers"`
+	Share     float64 `json:"share"` // Of the viewers counted
+}
+
+// CampaignReach is one campaign's reach over a report's range
+type CampaignReach struct {
+	CampaignID       string            `json:"campaign_id"`
+	Impressions      int64             `json:"impressions"`
+	Reach            int64             `json:"reach"`
+	AverageFrequency float64           `json:"average_frequency"`
+	Frequency        []FrequencyBucket `json:"frequency"`
+	Days             []DayReach        `json:"days"`
+}
+
+// Overlap is the audience two campaigns share
+type Overlap struct {
+	CampaignIDs [2]string `json:"campaign_ids"`
+	Reach       int64     `json:"reach"`   // Of either campaign
+	Overlap     int64     `json:"overlap"` // Viewers reached by both
+	Shares      []float64 `json:"shares"`  // Overlap as a share of each campaign's reach
+}
+
+// Report is the reach of campaigns over a range of days
+type Report struct {
+	From            time.Time                 `json:"from"`
+	To              time.Time                 `json:"to"`
+	Campaigns       []CampaignReach           `json:"campaigns"`
+	CombinedReach   int64                     `json:"combined_reach"` // Viewers reached by any of the campaigns
+	Overlaps        []Overlap                 `json:"overlaps"`
+	StandardError   float64                   `json:"standard_error"` // Relative, of reach estimates
+	RolledUpThrough *time.Time                `json:"rolled_up_through,omitempty"`
+	Privacy         *reporting.PrivacySummary `json:"privacy,omitempty"`
+}
+
+// Build assembles the report of q from its campaigns' day sketches and the
+// number of viewers reached each number of times, per campaign, frequencies
+// past MaxFrequency counted as MaxFrequency. Under a minimum audience,
+// frequency buckets and overlaps of fewer viewers are left out.
+func Build(q Query, days []Day, frequencies map[string]map[int]int64) *Report {
+	report := &Report{
+		From:          q.From,
+		To:            q.To,
+		Campaigns:     make([]CampaignReach, 0, len(q.CampaignIDs)),
+		Overlaps:      make([]Overlap, 0),
+		StandardError: sketch.StandardError,
+	}
+	var summary *reporting.PrivacySummary
+	if q.Privacy != nil && q.Privacy.Enabled() {
+		summary = &reporting.PrivacySummary{MinAudience: q.Privacy.MinAudience, Mode: reporting.PrivacySuppress}
+		report.Privacy = summary
+	}
+	small := func(viewers int64) bool {
+		if summary == nil || viewers >= int64(summary.MinAudience) {
+			return false
+		}
+		summary.SuppressedRows++
+		return true
+	}
+
+	byCampaign := make(map[string][]Day, len(q.CampaignIDs))
+	for _, d := range days {
+		byCampaign[d.CampaignID] = append(byCampaign[d.CampaignID], d)
+	}
+
+	combined := sketch.New()
+	merged := make([]*sketch.HLL, len(q.CampaignIDs))
+	for i, id := range q.CampaignIDs {
+		c := CampaignReach{CampaignID: id, Frequency: make([]FrequencyBucket, 0), Days: make([]DayReach, 0)}
+		campaignDays := byCampaign[id]
+		sort.Slice(campaignDays, func(a, b int) bool { return campaignDays[a].Day.Before(campaignDays[b].Day) })
+
+		merged[i] = sketch.New()
+		for _, d := range campaignDays {
+			before := merged[i].Estimate()
+			merged[i].Merge(d.Viewers)
+			after := merged[i].Estimate()
+			c.Impressions += d.Impressions
+			c.Days = append(c.Days, DayReach{
+				Day:             d.Day,
+				Impressions:     d.Impressions,
+				NewReach:        max(after-before, 0),
+				CumulativeReach: after,
+			})
+		}
+		c.Reach = merged[i].Estimate()
+		if c.Reach > 0 {
+			c.AverageFrequency = float64(c.Impressions) / float64(c.Reach)
+		}
+
+		counted := int64(0)
+		for _, viewers := range frequencies[id] {
+			counted += viewers
+		}
+		for f := 1; f <= MaxFrequency; f++ {
+			viewers := frequencies[id][f]
+			if viewers == 0 || small(viewers) {
+				continue
+			}
+			label := strconv.Itoa(f)
+			if f == MaxFrequency {
+				label += "+"
+			}
+			c.Frequency = append(c.Frequency, FrequencyBucket{Frequency: label, Viewers: viewers, Share: float64(viewers) / float64(counted)})
+		}
+
+		combined.Merge(merged[i])
+		report.Campaigns = append(report.Campaigns, c)
+	}
+	report.CombinedReach = combined.Estimate()
+
+	for i := range q.CampaignIDs {
+		for j := i + 1; j < len(q.CampaignIDs); j++ {
+			union := sketch.New()
+			union.Merge(merged[i])
+			union.Merge(merged[j])
+			reach := union.Estimate()
+			a, b := report.Campaigns[i].Reach, report.Campaigns[j].Reach
+			// Inclusion-exclusion, bounded by what the estimates allow
+			overlap := min(max(a+b-reach, 0), a, b)
+			if small(overlap) {
+				continue
+			}
+			o := Overlap{CampaignIDs: [2]string{q.CampaignIDs[i], q.CampaignIDs[j]}, Reach: reach, Overlap: overlap, Shares: []float64{0, 0}}
+			if a > 0 {
+				o.Shares[0] = float64(overlap) / float64(a)
+			}
+			if b > 0 {
+				o.Shares[1] = float64(overlap) / float64(b)
+			}
+			report.Overlaps = append(report.Overlaps, o)
+		}
+	}
+	return report
+}
+
+// Store recomputes the day sketches of the events received since its last
+// run up to through, returning how many it rewrote
+type Store interface {
+	RollUpReach(through time.Time) (int, error)
+}
+
+// Worker keeps the day sketches current
+type Worker struct {
+	store    Store
+	interval time.Duration
+}
+
+// NewWorker creates a reach rollup worker
+func NewWorker(store Store, interval time.Duration) *Worker {
+	return &Worker{store: store, interval: interval}
+}
+
+// Run rolls up immediately and then every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.rollUp()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) rollUp() {
+	started := time.Now().UTC()
+	through := started.Add(-rollup.Lag)
+	days, err := w.store.RollUpReach(through)
+	if err != nil {
+		logrus.WithError(err).Error("Reach rollup failed")
+		return
+	}
+	metrics.ReachRollupDays.Add(float64(days))
+	metrics.ReachRollupThrough.Set(float64(through.Unix()))
+	logrus.WithFields(logrus.Fields{
+		"days":     days,
+		"through":  through,
+		"duration": time.Since(started),
+	}).Debug("Rolled up campaign reach")
+}
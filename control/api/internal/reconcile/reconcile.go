@@ -0,0 +1,144 @@
+package reconcile
+
+import (
+	"time"
+)
+
+// AllOrganizations scopes a reconciliation to every organization's bookings
+const AllOrganizations = "*"
+
+// DefaultHoldTTL is how long a pending booking may hold a surface before it is reported
+const DefaultHoldTTL = 24 * time.Hour
+
+// Issue kinds. Surface issues mean SGI inventory changed under a live
+// booking; campaign issues mean ownership records no longer line up.
+const (
+	KindSurfaceMissing     = "surface_missing"
+	KindSurfaceWithdrawn   = "surface_withdrawn"
+	KindSurfaceBelowMinPRS = "surface_below_min_prs"
+	KindRightsExpired      = "surface_rights_expired"
+	KindSurfaceStale       = "surface_stale"
+	KindCampaignUnowned    = "campaign_unowned"
+	KindOwnerMismatch      = "campaign_owner_mismatch"
+	KindHoldExpired        = "hold_expired"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // The booking cannot deliver as sold
+	SeverityWarning = "warning" // The booking needs attention but may still deliver
+)
+
+// Remediation is a suggested fix for an issue
+type Remediation struct {
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+type kindInfo struct {
+	severity    string
+	remediation Remediation
+}
+
+var kinds = map[string]kindInfo{
+	KindSurfaceMissing: {SeverityError, Remediation{
+		Action:      "cancel_booking",
+		Description: "The surface no longer exists in SGI inventory. Cancel the booking and rebook a comparable surface.",
+	}},
+	KindSurfaceWithdrawn: {SeverityError, Remediation{
+		Action:      "rebook",
+		Description: "The surface was withdrawn from inventory. Move the booking to another surface or cancel it.",
+	}},
+	KindSurfaceBelowMinPRS: {SeverityWarning, Remediation{
+		Action:      "review_booking",
+		Description: "The surface's PRS score dropped below the booking's minimum. Lower the minimum with the advertiser's agreement or rebook.",
+	}},
+	KindRightsExpired: {SeverityError, Remediation{
+		Action:      "renew_rights",
+		Description: "No rights ledger entry for the surface is currently valid. Renew the rights or pause the booking.",
+	}},
+	KindSurfaceStale: {SeverityWarning, Remediation{
+		Action:      "revalidate_surface",
+		Description: "The surface is queued for or overdue re-validation, e.g. after a new cut of its title. Re-run the pipeline on the title before the booking delivers.",
+	}},
+	KindCampaignUnowned: {SeverityWarning, Remediation{
+		Action:      "assign_campaign_owner",
+		Description: "The booking's campaign has no owning organization. Restore the campaign's owner or cancel its bookings.",
+	}},
+	KindOwnerMismatch: {SeverityWarning, Remediation{
+		Action:      "transfer_booking",
+		Description: "The booking and its campaign belong to different organizations. Transfer the booking to the campaign owner.",
+	}},
+	KindHoldExpired: {SeverityWarning, Remediation{
+		Action:      "release_hold",
+		Description: "The booking has been pending past the hold TTL. Confirm it or cancel it to release the surface.",
+	}},
+}
+
+// Kinds lists every issue kind
+func Kinds() []string {
+	return []string{
+		KindSurfaceMissing, KindSurfaceWithdrawn, KindSurfaceBelowMinPRS, KindRightsExpired, KindSurfaceStale,
+		KindCampaignUnowned, KindOwnerMismatch, KindHoldExpired,
+	}
+}
+
+// Issue is a drift between a live booking and inventory or ownership records
+type Issue struct {
+	Kind        string      `json:"kind"`
+	Severity    string      `json:"severity"`
+	BookingID   string      `json:"booking_id"`
+	SurfaceID   string      `json:"surface_id"`
+	CampaignID  string      `json:"campaign_id"`
+	Status      string      `json:"status"`
+	BookedAt    time.Time   `json:"booked_at"`
+	Detail      string      `json:"detail,omitempty"`
+	Remediation Remediation `json:"remediation"`
+}
+
+// Report is the result of one reconciliation run
+type Report struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	HoldTTL     string         `json:"hold_ttl"`
+	Issues      []Issue        `json:"issues"`
+	Counts      map[string]int `json:"counts"`
+	Truncated   bool           `json:"truncated"`
+}
+
+// Store finds drift between live bookings and inventory
+type Store interface {
+	// FindBookingIssues returns issues for live (pending, confirmed or active)
+	// bookings owned by orgID or unowned, or for every booking when orgID is
+	// AllOrganizations. Returned issues carry no severity or remediation.
+	FindBookingIssues(orgID string, holdTTL time.Duration, limit int) ([]Issue, error)
+}
+
+// Run builds a reconciliation report. At most limit issues are listed.
+func Run(store Store, orgID string, holdTTL time.Duration, limit int) (*Report, error) {
+	// Fetch one extra issue to tell whether the report was cut short
+	issues, err := store.FindBookingIssues(orgID, holdTTL, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now().UTC(),
+		HoldTTL:     holdTTL.String(),
+		Issues:      make([]Issue, 0, len(issues)),
+		Counts:      make(map[string]int),
+	}
+	if len(issues) > limit {
+		issues = issues[:limit]
+		report.Truncated = true
+	}
+
+	for _, issue := range issues {
+		if info, ok := kinds[issue.Kind]; ok {
+			issue.Severity = info.severity
+			issue.Remediation = info.remediation
+		}
+		report.Issues = append(report.Issues, issue)
+		report.Counts[issue.Kind]++
+	}
+	return report, nil
+}
@@ -0,0 +1,67 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// workerIssueLimit bounds the issues fetched per scheduled run
+const workerIssueLimit = 10000
+
+// Worker reconciles every organization's bookings on a schedule and exports
+// the issue counts as metrics so drift can be alerted on
+type Worker struct {
+	store    Store
+	interval time.Duration
+	holdTTL  time.Duration
+}
+
+// NewWorker creates a reconciliation worker
+func NewWorker(store Store, interval, holdTTL time.Duration) *Worker {
+	return &Worker{store: store, interval: interval, holdTTL: holdTTL}
+}
+
+// Run reconciles immediately and then every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.reconcile()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) reconcile() {
+	report, err := Run(w.store, AllOrganizations, w.holdTTL, workerIssueLimit)
+	if err != nil {
+		logrus.WithError(err).Error("Booking reconciliation failed")
+		return
+	}
+
+	total := 0
+	fields := logrus.Fields{}
+	for _, kind := range Kinds() {
+		count := report.Counts[kind]
+		metrics.BookingReconciliationIssues.WithLabelValues(kind).Set(float64(count))
+		if count > 0 {
+			fields[kind] = count
+			total += count
+		}
+	}
+
+	if total == 0 {
+		logrus.Debug("Booking reconciliation found no issues")
+		return
+	}
+	fields["truncated"] = report.Truncated
+	logrus.WithFields(fields).Warn("Booking reconciliation found drift")
+}
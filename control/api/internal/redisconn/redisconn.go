@@ -0,0 +1,147 @@
+// Package redisconn connects to Redis run as a single server, as a master
+// and replicas watched by Sentinel, or as a Cluster. Every package taking a
+// redis.UniversalClient works with any of them, provided the keys a script
+// touches together share a hash slot: such keys carry their owner's ID as a
+// {hash tag}, e.g. impcap:{booking_123}:remaining and impcap:{booking_123}:served.
+package redisconn
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Deployment modes
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// Config selects and configures a Redis deployment
+type Config struct {
+	Mode string // standalone, sentinel or cluster
+
+	// URL is the standalone server, e.g. redis://localhost:6379/0
+	URL string
+	// Addrs are the sentinels, or the cluster nodes topology is discovered from
+	Addrs []string
+	// MasterName is the master set the sentinels monitor
+	MasterName string
+
+	// Username and Password authenticate to Redis itself, overriding those
+	// of URL when set
+	Username string
+	Password string
+	// SentinelPassword authenticates to the sentinels
+	SentinelPassword string
+	// DB selects the database of a standalone or sentinel deployment; a
+	// cluster only has database 0
+	DB int
+}
+
+// Enabled reports whether config names a deployment to connect to
+func (c Config) Enabled() bool {
+	switch c.Mode {
+	case ModeSentinel, ModeCluster:
+		return len(c.Addrs) > 0
+	default:
+		return c.URL != ""
+	}
+}
+
+// New creates a client for the deployment config selects. Nothing is dialed
+// until the first command. Sentinel clients dial whichever server the
+// sentinels name master and follow failovers; cluster clients follow MOVED
+// redirects and reload the slot map when masters change.
+func New(config Config) (redis.UniversalClient, error) {
+	switch config.Mode {
+	case ModeStandalone, "":
+		if config.URL == "" {
+			return nil, fmt.Errorf("standalone Redis requires a URL")
+		}
+		opts, err := redis.ParseURL(config.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		}
+		if config.Username != "" {
+			opts.Username = config.Username
+		}
+		if config.Password != "" {
+			opts.Password = config.Password
+		}
+		if config.DB != 0 {
+			opts.DB = config.DB
+		}
+		return redis.NewClient(opts), nil
+
+	case ModeSentinel:
+		if len(config.Addrs) == 0 || config.MasterName == "" {
+			return nil, fmt.Errorf("sentinel Redis requires sentinel addresses and a master name")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.Addrs,
+			SentinelPassword: config.SentinelPassword,
+			Username:         config.Username,
+			Password:         config.Password,
+			DB:               config.DB,
+		}), nil
+
+	case ModeCluster:
+		if len(config.Addrs) == 0 {
+			return nil, fmt.Errorf("cluster Redis requires node addresses")
+		}
+		if config.DB != 0 {
+			return nil, fmt.Errorf("cluster Redis only has database 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    config.Addrs,
+			Username: config.Username,
+			Password: config.Password,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q (use standalone, sentinel or cluster)", config.Mode)
+	}
+}
+
+// SlotCount is the number of hash slots a Redis Cluster divides keys among
+const SlotCount = 16384
+
+// Slot returns the cluster hash slot of key. When key holds a non-empty
+// {hash tag} only the tag is hashed, so keys with the same tag share a slot
+// and can be used together by one script or transaction.
+func Slot(key string) int {
+	for i := 0; i < len(key); i++ {
+		if key[i] != '{' {
+			continue
+		}
+		for j := i + 1; j < len(key); j++ {
+			if key[j] == '}' {
+				if j > i+1 {
+					key = key[i+1 : j]
+				}
+				return int(crc16(key)) % SlotCount
+			}
+		}
+		break
+	}
+	return int(crc16(key)) % SlotCount
+}
+
+// crc16 is the CRC-16/XMODEM checksum Redis Cluster hashes keys with
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,396 @@
+package redisconn
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlot(t *testing.T) {
+	assert.Equal(t, 12182, Slot("foo"))
+	assert.Equal(t, Slot("user1000"), Slot("{user1000}.following"))
+	assert.Equal(t, Slot("{user1000}.following"), Slot("{user1000}.followers"))
+	assert.Equal(t, Slot("bar"), Slot("foo{bar}{zap}"), "Only the first tag is hashed")
+	assert.Equal(t, Slot("{bar"), Slot("foo{{bar}}zap"))
+	assert.Equal(t, int(crc16("foo{}{bar}"))%SlotCount, Slot("foo{}{bar}"), "An empty tag hashes the whole key")
+
+	// Keys used together by one script share their owner's slot
+	assert.Equal(t, Slot("impcap:{booking_123}:remaining"), Slot("impcap:{booking_123}:lease:edge-1"))
+	assert.Equal(t, Slot("jobqueue:{event_export}:ready"), Slot("jobqueue:{event_export}:job:42"))
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+		description string
+	}{
+		{
+			name:        "standalone",
+			config:      Config{Mode: ModeStandalone, URL: "redis://localhost:6379/0"},
+			description: "Should connect to a single server",
+		},
+		{
+			name:        "standalone without url",
+			config:      Config{Mode: ModeStandalone},
+			expectError: true,
+			description: "Should require a URL",
+		},
+		{
+			name:        "sentinel",
+			config:      Config{Mode: ModeSentinel, Addrs: []string{"localhost:26379"}, MasterName: "inscenium"},
+			description: "Should follow the master named by the sentinels",
+		},
+		{
+			name:        "sentinel without master",
+			config:      Config{Mode: ModeSentinel, Addrs: []string{"localhost:26379"}},
+			expectError: true,
+			description: "Should require the master set's name",
+		},
+		{
+			name:        "cluster",
+			config:      Config{Mode: ModeCluster, Addrs: []string{"localhost:7000", "localhost:7001"}},
+			description: "Should discover the cluster from its seed nodes",
+		},
+		{
+			name:        "cluster database",
+			config:      Config{Mode: ModeCluster, Addrs: []string{"localhost:7000"}, DB: 2},
+			expectError: true,
+			description: "Should reject databases a cluster does not have",
+		},
+		{
+			name:        "unknown mode",
+			config:      Config{Mode: "replicated", URL: "redis://localhost:6379/0"},
+			expectError: true,
+			description: "Should reject unknown modes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.config)
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+				return
+			}
+			require.NoError(t, err, tt.description)
+			client.Close()
+		})
+	}
+}
+
+func TestSentinelFailover(t *testing.T) {
+	ctx := context.Background()
+	a := startData(t)
+	b := startData(t)
+	sentinel := startSentinel(t, "inscenium", a.Addr())
+
+	client, err := New(Config{Mode: ModeSentinel, Addrs: []string{sentinel.Addr()}, MasterName: "inscenium"})
+	require.NoError(t, err)
+	defer client.Close()
+
+	key := "session:refresh:{abc}"
+	require.NoError(t, client.Set(ctx, key, "session_1", 0).Err())
+	assert.Equal(t, "session_1", a.get(key))
+
+	// The master dies; the sentinels promote its replica
+	b.copyFrom(a)
+	a.Close()
+	sentinel.failover(b.Addr())
+
+	value, err := client.Get(ctx, key).Result()
+	require.NoError(t, err, "Should reconnect to the promoted master")
+	assert.Equal(t, "session_1", value)
+	require.NoError(t, client.Set(ctx, "session:revoked:{abc}", "1", 0).Err())
+	assert.Equal(t, "1", b.get("session:revoked:{abc}"))
+}
+
+func TestClusterFailover(t *testing.T) {
+	ctx := context.Background()
+	state := &clusterState{}
+	a := startClusterNode(t, state)
+	b := startClusterNode(t, state)
+	state.setOwner(a)
+
+	client, err := New(Config{Mode: ModeCluster, Addrs: []string{a.Addr(), b.Addr()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	key := "ratelimit:{key_1}"
+	require.NoError(t, client.Set(ctx, key, "10", 0).Err())
+	assert.Equal(t, "10", a.get(key))
+
+	// The replica takes over the slots; the old master redirects to it
+	b.copyFrom(a)
+	state.setOwner(b)
+
+	value, err := client.Get(ctx, key).Result()
+	require.NoError(t, err, "Should follow the redirect to the new master")
+	assert.Equal(t, "10", value)
+	require.NoError(t, client.Set(ctx, key, "9", 0).Err())
+	assert.Equal(t, "9", b.get(key))
+	assert.Equal(t, "10", a.get(key), "Should stop writing to the old master")
+}
+
+// fakeServer speaks enough RESP2 for go-redis to use it as a server or a
+// sentinel. handle answers each command unless it is a handshake.
+type fakeServer struct {
+	ln     net.Listener
+	handle func(conn *fakeConn, args []string)
+
+	mu    sync.Mutex
+	conns map[*fakeConn]bool
+	data  map[string]string
+}
+
+type fakeConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *fakeConn) reply(raw string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Write([]byte(raw))
+}
+
+func startFake(t *testing.T, handle func(s *fakeServer, conn *fakeConn, args []string)) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{ln: ln, conns: make(map[*fakeConn]bool), data: make(map[string]string)}
+	s.handle = func(conn *fakeConn, args []string) { handle(s, conn, args) }
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and drops its connections, as a crashed node would
+func (s *fakeServer) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeServer) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		conn := &fakeConn{Conn: c}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *fakeServer) serveConn(conn *fakeConn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			conn.reply("-ERR unknown command 'HELLO'\r\n")
+		case "CLIENT", "AUTH", "SELECT", "READONLY":
+			conn.reply("+OK\r\n")
+		case "PING":
+			conn.reply("+PONG\r\n")
+		case "COMMAND":
+			conn.reply("*0\r\n")
+		default:
+			s.handle(conn, args)
+		}
+	}
+}
+
+func (s *fakeServer) get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key]
+}
+
+// copyFrom replicates another server's keys, as a replica would have
+func (s *fakeServer) copyFrom(other *fakeServer) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range other.data {
+		s.data[k] = v
+	}
+}
+
+// serveData answers GET and SET
+func (s *fakeServer) serveData(conn *fakeConn, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		s.data[args[1]] = args[2]
+		conn.reply("+OK\r\n")
+	case "GET":
+		value, ok := s.data[args[1]]
+		if !ok {
+			conn.reply("$-1\r\n")
+			return
+		}
+		conn.reply(bulk(value))
+	default:
+		conn.reply("-ERR unknown command '" + args[0] + "'\r\n")
+	}
+}
+
+func startData(t *testing.T) *fakeServer {
+	return startFake(t, func(s *fakeServer, conn *fakeConn, args []string) {
+		s.serveData(conn, args)
+	})
+}
+
+// fakeSentinel names the master of one master set and announces failovers
+type fakeSentinel struct {
+	*fakeServer
+	name        string
+	master      string
+	subscribers []*fakeConn
+}
+
+func startSentinel(t *testing.T, name, master string) *fakeSentinel {
+	sentinel := &fakeSentinel{name: name, master: master}
+	sentinel.fakeServer = startFake(t, func(s *fakeServer, conn *fakeConn, args []string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch {
+		case strings.EqualFold(args[0], "SENTINEL") && strings.EqualFold(args[1], "get-master-addr-by-name"):
+			if args[2] != sentinel.name {
+				conn.reply("*-1\r\n")
+				return
+			}
+			host, port, _ := net.SplitHostPort(sentinel.master)
+			conn.reply("*2\r\n" + bulk(host) + bulk(port))
+		case strings.EqualFold(args[0], "SENTINEL"):
+			conn.reply("*0\r\n")
+		case strings.EqualFold(args[0], "SUBSCRIBE"):
+			for i, channel := range args[1:] {
+				conn.reply("*3\r\n" + bulk("subscribe") + bulk(channel) + ":" + strconv.Itoa(i+1) + "\r\n")
+			}
+			sentinel.subscribers = append(sentinel.subscribers, conn)
+		default:
+			conn.reply("-ERR unknown command '" + args[0] + "'\r\n")
+		}
+	})
+	return sentinel
+}
+
+// failover names a new master and publishes +switch-master
+func (s *fakeSentinel) failover(master string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	oldHost, oldPort, _ := net.SplitHostPort(s.master)
+	newHost, newPort, _ := net.SplitHostPort(master)
+	s.master = master
+	message := strings.Join([]string{s.name, oldHost, oldPort, newHost, newPort}, " ")
+	for _, conn := range s.subscribers {
+		conn.reply("*3\r\n" + bulk("message") + bulk("+switch-master") + bulk(message))
+	}
+}
+
+// clusterState is the slot map shared by a fake cluster's nodes; one node
+// owns every slot
+type clusterState struct {
+	mu    sync.Mutex
+	owner *fakeServer
+}
+
+func (c *clusterState) setOwner(node *fakeServer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owner = node
+}
+
+func (c *clusterState) getOwner() *fakeServer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.owner
+}
+
+func startClusterNode(t *testing.T, state *clusterState) *fakeServer {
+	return startFake(t, func(s *fakeServer, conn *fakeConn, args []string) {
+		owner := state.getOwner()
+		if strings.EqualFold(args[0], "CLUSTER") && strings.EqualFold(args[1], "SLOTS") {
+			host, port, _ := net.SplitHostPort(owner.Addr())
+			conn.reply(fmt.Sprintf("*1\r\n*3\r\n:0\r\n:%d\r\n*3\r\n%s:%s\r\n%s", SlotCount-1, bulk(host), port, bulk("node-"+port)))
+			return
+		}
+		if owner != s && len(args) > 1 {
+			conn.reply(fmt.Sprintf("-MOVED %d %s\r\n", Slot(args[1]), owner.Addr()))
+			return
+		}
+		s.serveData(conn, args)
+	})
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[0] != '*' {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,158 @@
+package render
+
+import (
+	"fmt"
+	"time"
+)
+
+// Render job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Callback event types sent by render workers
+const (
+	EventQueued    = "job.queued"
+	EventStarted   = "job.started"
+	EventProgress  = "job.progress"
+	EventCompleted = "job.completed"
+	EventFailed    = "job.failed"
+	EventCancelled = "job.cancelled"
+)
+
+// eventStatus maps each callback event onto the status it moves a job to
+var eventStatus = map[string]string{
+	EventQueued:    StatusQueued,
+	EventStarted:   StatusRunning,
+	EventProgress:  StatusRunning,
+	EventCompleted: StatusCompleted,
+	EventFailed:    StatusFailed,
+	EventCancelled: StatusCancelled,
+}
+
+// transitions lists the statuses reachable from each status. Terminal
+// statuses have no outgoing transitions.
+var transitions = map[string][]string{
+	StatusQueued:  {StatusQueued, StatusRunning, StatusCompleted, StatusFailed, StatusCancelled},
+	StatusRunning: {StatusRunning, StatusCompleted, StatusFailed, StatusCancelled},
+}
+
+// Job is a compositing job on the render farm
+type Job struct {
+	ID          string    `json:"job_id"`
+	BookingID   string    `json:"booking_id,omitempty"`
+	SurfaceID   string    `json:"surface_id,omitempty"`
+	WorkerID    string    `json:"worker_id,omitempty"`
+	Status      string    `json:"status"`
+	Progress    float64   `json:"progress"`
+	OutputURI   string    `json:"output_uri,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	WatermarkID uint32    `json:"watermark_id,omitempty"` // Embedded in the output, see package watermark
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	LastEventAt time.Time `json:"last_event_at"`
+}
+
+// Event is a render worker callback
+type Event struct {
+	Type       string    `json:"event"`
+	JobID      string    `json:"job_id"`
+	BookingID  string    `json:"booking_id"`
+	SurfaceID  string    `json:"surface_id"`
+	WorkerID   string    `json:"worker_id"`
+	Progress   *float64  `json:"progress"`
+	OutputURI  string    `json:"output_uri"`
+	Error      string    `json:"error"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// TransitionError is returned when an event cannot move a job from its current status
+type TransitionError struct {
+	From  string
+	Event string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot apply %s to a %s job", e.Event, e.From)
+}
+
+// ValidEvent reports whether eventType is a known callback event
+func ValidEvent(eventType string) bool {
+	_, ok := eventStatus[eventType]
+	return ok
+}
+
+// Terminal reports whether a job in status can no longer change
+func Terminal(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// CanTransition reports whether a job may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the job after applying event. A nil job means the first
+// callback for the job ID, which creates it. The input job is not modified.
+func Apply(job *Job, event Event) (*Job, error) {
+	status, ok := eventStatus[event.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown render event %q", event.Type)
+	}
+
+	next := Job{ID: event.JobID, Status: StatusQueued, CreatedAt: event.OccurredAt}
+	if job != nil {
+		next = *job
+	}
+	if !CanTransition(next.Status, status) {
+		return nil, &TransitionError{From: next.Status, Event: event.Type}
+	}
+
+	next.Status = status
+	next.LastEventAt = event.OccurredAt
+	if event.BookingID != "" {
+		next.BookingID = event.BookingID
+	}
+	if event.SurfaceID != "" {
+		next.SurfaceID = event.SurfaceID
+	}
+	if event.WorkerID != "" {
+		next.WorkerID = event.WorkerID
+	}
+	if event.Progress != nil {
+		next.Progress = clampProgress(*event.Progress)
+	}
+
+	switch status {
+	case StatusCompleted:
+		next.Progress = 1
+		next.OutputURI = event.OutputURI
+	case StatusFailed:
+		next.Error = event.Error
+		if next.Error == "" {
+			next.Error = "render failed"
+		}
+	}
+
+	return &next, nil
+}
+
+// clampProgress bounds progress to [0, 1]
+func clampProgress(progress float64) float64 {
+	if progress < 0 {
+		return 0
+	}
+	if progress > 1 {
+		return 1
+	}
+	return progress
+}
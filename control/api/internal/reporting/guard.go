@@ -0,0 +1,150 @@
+package reporting
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Limits bounds the cost of a single report query
+type Limits struct {
+	MaxRows   int64         // Estimated rows scanned
+	MaxSpan   time.Duration // Width of the queried time range
+	MaxGroups int64         // Estimated result groups (buckets x dimension values)
+	WarnRatio float64       // Fraction of a limit at which queries are logged
+}
+
+// DefaultLimits returns limits from environment variables, falling back to defaults
+func DefaultLimits() Limits {
+	return Limits{
+		MaxRows:   getEnvInt("REPORT_MAX_ROWS", 5000000),
+		MaxSpan:   time.Duration(getEnvInt("REPORT_MAX_SPAN_DAYS", 366)) * 24 * time.Hour,
+		MaxGroups: getEnvInt("REPORT_MAX_GROUPS", 100000),
+		WarnRatio: 0.8,
+	}
+}
+
+// AsyncLimits returns the looser limits applied to background report jobs
+func AsyncLimits() Limits {
+	return Limits{
+		MaxRows:   getEnvInt("REPORT_ASYNC_MAX_ROWS", 200000000),
+		MaxSpan:   time.Duration(getEnvInt("REPORT_ASYNC_MAX_SPAN_DAYS", 1096)) * 24 * time.Hour,
+		MaxGroups: getEnvInt("REPORT_ASYNC_MAX_GROUPS", 5000000),
+		WarnRatio: 0.8,
+	}
+}
+
+// CostEstimate describes the expected cost of a query
+type CostEstimate struct {
+	EstimatedRows   int64  `json:"estimated_rows"`
+	SpanHours       int64  `json:"span_hours"`
+	EstimatedGroups int64  `json:"estimated_groups"`
+	Granularity     string `json:"granularity"`
+	Downsampled     bool   `json:"downsampled"`
+}
+
+// LimitError is returned when a query exceeds a cost limit
+type LimitError struct {
+	Reason   string
+	Limit    int64
+	Estimate int64
+	Hint     string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("report query too expensive: %s (estimated %d, limit %d)", e.Reason, e.Estimate, e.Limit)
+}
+
+// Guard rejects or downsamples report queries that exceed cost limits
+type Guard struct {
+	limits Limits
+}
+
+// NewGuard creates a guard with the given limits
+func NewGuard(limits Limits) *Guard {
+	return &Guard{limits: limits}
+}
+
+// Check evaluates a query against the limits given an estimated row count.
+// When only the group count is too high the granularity is coarsened; the
+// returned query is the one that should actually run.
+func (g *Guard) Check(q Query, estimatedRows int64) (Query, CostEstimate, error) {
+	estimate := CostEstimate{
+		EstimatedRows: estimatedRows,
+		SpanHours:     int64(q.Span() / time.Hour),
+		Granularity:   q.Granularity,
+	}
+
+	if q.Span() > g.limits.MaxSpan {
+		return q, estimate, &LimitError{
+			Reason:   "date span",
+			Limit:    int64(g.limits.MaxSpan / time.Hour),
+			Estimate: estimate.SpanHours,
+			Hint:     fmt.Sprintf("narrow the range to at most %d days", int64(g.limits.MaxSpan/(24*time.Hour))),
+		}
+	}
+
+	if estimatedRows > g.limits.MaxRows {
+		return q, estimate, &LimitError{
+			Reason:   "row count",
+			Limit:    g.limits.MaxRows,
+			Estimate: estimatedRows,
+			Hint:     "narrow the date range or split the report across several requests",
+		}
+	}
+
+	cardinality, _ := groupCardinality(q.GroupBy)
+	groups := q.Buckets() * cardinality
+	for groups > g.limits.MaxGroups {
+		next, ok := coarser(q.Granularity)
+		if !ok {
+			estimate.EstimatedGroups = groups
+			return q, estimate, &LimitError{
+				Reason:   "group cardinality",
+				Limit:    g.limits.MaxGroups,
+				Estimate: groups,
+				Hint:     "use a coarser granularity, a lower-cardinality group_by, or a shorter range",
+			}
+		}
+		q.Granularity = next
+		estimate.Downsampled = true
+		groups = q.Buckets() * cardinality
+	}
+	estimate.EstimatedGroups = groups
+	estimate.Granularity = q.Granularity
+
+	g.logNearLimit(q, estimate)
+	return q, estimate, nil
+}
+
+// logNearLimit records queries that are allowed but close to a limit
+func (g *Guard) logNearLimit(q Query, estimate CostEstimate) {
+	near := float64(estimate.EstimatedRows) >= g.limits.WarnRatio*float64(g.limits.MaxRows) ||
+		float64(estimate.EstimatedGroups) >= g.limits.WarnRatio*float64(g.limits.MaxGroups) ||
+		float64(q.Span()) >= g.limits.WarnRatio*float64(g.limits.MaxSpan)
+	if !near && !estimate.Downsampled {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"booking_id":       q.BookingID,
+		"granularity":      estimate.Granularity,
+		"group_by":         q.GroupBy,
+		"estimated_rows":   estimate.EstimatedRows,
+		"estimated_groups": estimate.EstimatedGroups,
+		"span_hours":       estimate.SpanHours,
+		"downsampled":      estimate.Downsampled,
+	}).Warn("Report query near cost limit")
+}
+
+func getEnvInt(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
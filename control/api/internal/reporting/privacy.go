@@ -0,0 +1,191 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+)
+
+// Ways of handling report groups reaching fewer viewers than the minimum audience
+const (
+	PrivacySuppress  = "suppress"  // Drop the group
+	PrivacyAggregate = "aggregate" // Fold the group into its bucket's "other" group
+)
+
+// OtherGroup is the dimension value of the group small groups are folded into
+const OtherGroup = "__other__"
+
+// MaxMinAudience bounds configurable minimum audiences
+const MaxMinAudience = 10000
+
+// Privacy is the minimum audience (k) every group of a grouped report must
+// reach. A MinAudience of 0 or 1 disables the threshold.
+type Privacy struct {
+	MinAudience int    `json:"min_audience"`
+	Mode        string `json:"mode"`
+}
+
+// DefaultPrivacy returns the threshold of organizations without their own,
+// from environment variables
+func DefaultPrivacy() Privacy {
+	mode := PrivacyAggregate
+	if value := os.Getenv("REPORT_PRIVACY_MODE"); value != "" {
+		mode = strings.ToLower(value)
+	}
+	return Privacy{
+		MinAudience: int(getEnvInt("REPORT_MIN_AUDIENCE", 10)),
+		Mode:        mode,
+	}
+}
+
+// Validate checks a privacy setting
+func (p Privacy) Validate() error {
+	if p.MinAudience < 0 || p.MinAudience > MaxMinAudience {
+		return fmt.Errorf("min_audience must be between 0 and %d", MaxMinAudience)
+	}
+	if p.Mode != PrivacySuppress && p.Mode != PrivacyAggregate {
+		return fmt.Errorf("unsupported privacy mode %q (use suppress or aggregate)", p.Mode)
+	}
+	return nil
+}
+
+// Enabled reports whether the threshold removes any group
+func (p Privacy) Enabled() bool {
+	return p.MinAudience > 1
+}
+
+// PrivacySummary describes what a threshold did to a report
+type PrivacySummary struct {
+	MinAudience    int    `json:"min_audience"`
+	Mode           string `json:"mode"`
+	SuppressedRows int    `json:"suppressed_rows"`
+	AggregatedRows int    `json:"aggregated_rows"`
+}
+
+// CheckPrivacy rejects group-bys that cannot meet a threshold: every
+// viewer_id group is a single viewer
+func (q Query) CheckPrivacy() error {
+	if q.Privacy != nil && q.Privacy.Enabled() && q.GroupBy == "viewer_id" {
+		return fmt.Errorf("group_by viewer_id is not available with a minimum audience of %d", q.Privacy.MinAudience)
+	}
+	return nil
+}
+
+// ApplyPrivacy removes the groups of a grouped report that reach fewer than
+// the query's minimum audience. In aggregate mode they are folded into one
+// OtherGroup row per bucket, which is itself dropped if still below the
+// threshold so a single small group cannot be recovered from it. Ungrouped
+// reports are returned as they are.
+//
+// Rows are thresholded per page, so a bucket split across pages may carry
+// an "other" row on each. The unique viewers of an "other" row are summed
+// from its groups and may count a viewer seen in several of them twice.
+func ApplyPrivacy(q Query, rows []map[string]interface{}) ([]map[string]interface{}, *PrivacySummary) {
+	if q.Privacy == nil || q.GroupBy == "" {
+		return rows, nil
+	}
+	p := *q.Privacy
+	summary := &PrivacySummary{MinAudience: p.MinAudience, Mode: p.Mode}
+	if !p.Enabled() {
+		return rows, summary
+	}
+
+	kept := make([]map[string]interface{}, 0, len(rows))
+	var other *otherGroup
+	// flush closes a bucket's "other" group; rows arrive ordered by bucket
+	flush := func() {
+		if other == nil {
+			return
+		}
+		if other.uniqueViewers < float64(p.MinAudience) {
+			summary.SuppressedRows += other.rows
+		} else {
+			summary.AggregatedRows += other.rows
+			kept = append(kept, other.row(q.GroupBy))
+		}
+		other = nil
+	}
+
+	for _, row := range rows {
+		if other != nil && fmt.Sprint(row["bucket"]) != fmt.Sprint(other.bucket) {
+			flush()
+		}
+		if number(row["unique_viewers"]) >= float64(p.MinAudience) {
+			kept = append(kept, row)
+			continue
+		}
+		if p.Mode != PrivacyAggregate {
+			summary.SuppressedRows++
+			continue
+		}
+		if other == nil {
+			other = &otherGroup{bucket: row["bucket"]}
+		}
+		other.add(row)
+	}
+	flush()
+
+	metrics.ReportPrivacyRows.WithLabelValues("suppressed").Add(float64(summary.SuppressedRows))
+	metrics.ReportPrivacyRows.WithLabelValues("aggregated").Add(float64(summary.AggregatedRows))
+	return kept, summary
+}
+
+// otherGroup accumulates the small groups of one bucket
+type otherGroup struct {
+	bucket         interface{}
+	rows           int
+	impressions    float64
+	uniqueViewers  float64
+	totalExposure  float64
+	attentionTotal float64 // Attention score weighted by impressions
+}
+
+func (o *otherGroup) add(row map[string]interface{}) {
+	impressions := number(row["impressions"])
+	o.rows++
+	o.impressions += impressions
+	o.uniqueViewers += number(row["unique_viewers"])
+	o.totalExposure += number(row["total_exposure_time"])
+	o.attentionTotal += number(row["average_attention_score"]) * impressions
+}
+
+func (o *otherGroup) row(groupBy string) map[string]interface{} {
+	average := 0.0
+	if o.impressions > 0 {
+		average = o.attentionTotal / o.impressions
+	}
+	return map[string]interface{}{
+		"bucket":                  o.bucket,
+		groupBy:                   OtherGroup,
+		"impressions":             int64(o.impressions),
+		"unique_viewers":          int64(o.uniqueViewers),
+		"total_exposure_time":     o.totalExposure,
+		"average_attention_score": average,
+	}
+}
+
+// number reads a report metric whichever way the store decoded it;
+// ClickHouse returns 64-bit integers as JSON strings
+func number(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package reporting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+	"github.com/inscenium/inscenium/control/api/internal/tenant"
+)
+
+// Supported time granularities, from finest to coarsest
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+)
+
+var granularityOrder = []string{GranularityHour, GranularityDay, GranularityWeek}
+
+var granularityDurations = map[string]time.Duration{
+	GranularityHour: time.Hour,
+	GranularityDay:  24 * time.Hour,
+	GranularityWeek: 7 * 24 * time.Hour,
+}
+
+// dimensionCardinality holds rough upper bounds on distinct values per
+// group-by dimension, used when estimating result size
+var dimensionCardinality = map[string]int64{
+	"":             1,
+	"device_type":  10,
+	"country_code": 250,
+	"viewer_id":    1000000,
+}
+
+// LabelGroupPrefix marks a group_by on a booking label, e.g. "label:region"
+const LabelGroupPrefix = "label:"
+
+// labelCardinality is the assumed number of distinct values per label key
+const labelCardinality = 100
+
+// Query describes an exposure report over a booking (or every booking in a
+// campaign) and time range. Limit and Offset page through result rows; a
+// zero Limit returns all rows.
+type Query struct {
+	BookingID   string    `json:"booking_id,omitempty"`
+	CampaignID  string    `json:"campaign_id,omitempty"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Granularity string    `json:"granularity"`
+	GroupBy     string    `json:"group_by,omitempty"`
+	Limit       int       `json:"limit,omitempty"`
+	Offset      int       `json:"offset,omitempty"`
+	// Privacy is the minimum audience of the requesting organization,
+	// applied to the rows of grouped reports
+	Privacy *Privacy `json:"privacy,omitempty"`
+	// Tenant is the organizations whose exposure events the report counts,
+	// kept with async jobs so they run with the requester's scope
+	Tenant tenant.Scope `json:"tenant"`
+}
+
+// Validate checks the query shape before any cost estimation
+func (q Query) Validate() error {
+	if (q.BookingID == "") == (q.CampaignID == "") {
+		return fmt.Errorf("exactly one of booking_id or campaign_id is required")
+	}
+	if !q.To.After(q.From) {
+		return fmt.Errorf("to must be after from")
+	}
+	if _, ok := granularityDurations[q.Granularity]; !ok {
+		return fmt.Errorf("unsupported granularity %q (use hour, day or week)", q.Granularity)
+	}
+	if _, ok := groupCardinality(q.GroupBy); !ok {
+		return fmt.Errorf("unsupported group_by %q (use device_type, country_code, viewer_id or label:<key>)", q.GroupBy)
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit and offset must not be negative")
+	}
+	return nil
+}
+
+// Scope returns the booking or campaign ID the report covers
+func (q Query) Scope() string {
+	if q.BookingID != "" {
+		return q.BookingID
+	}
+	return q.CampaignID
+}
+
+// LabelKey returns the booking label key the query groups by, if any
+func (q Query) LabelKey() (string, bool) {
+	if !strings.HasPrefix(q.GroupBy, LabelGroupPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(q.GroupBy, LabelGroupPrefix), true
+}
+
+// groupCardinality returns the estimated distinct values for a group-by dimension
+func groupCardinality(groupBy string) (int64, bool) {
+	if key, ok := strings.CutPrefix(groupBy, LabelGroupPrefix); ok {
+		return labelCardinality, labels.ValidKey(key)
+	}
+	cardinality, ok := dimensionCardinality[groupBy]
+	return cardinality, ok
+}
+
+// Span returns the length of the queried time range
+func (q Query) Span() time.Duration {
+	return q.To.Sub(q.From)
+}
+
+// Buckets returns the number of time buckets the query produces
+func (q Query) Buckets() int64 {
+	step := granularityDurations[q.Granularity]
+	buckets := int64(q.Span() / step)
+	if q.Span()%step != 0 {
+		buckets++
+	}
+	return buckets
+}
+
+// coarser returns the next coarser granularity, or false if none exists
+func coarser(granularity string) (string, bool) {
+	for i, g := range granularityOrder {
+		if g == granularity && i+1 < len(granularityOrder) {
+			return granularityOrder[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,52 @@
+package reporting
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ResultContentType is the media type report results are stored as
+const ResultContentType = "application/x-ndjson"
+
+// ResultKey is the object storage key of a job's result rows
+func ResultKey(jobID string) string {
+	return "reports/" + jobID + ".jsonl"
+}
+
+// EncodeResult writes rows as JSON Lines, one row per line, so a page can
+// be read without decoding the whole result
+func EncodeResult(rows []map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return nil, fmt.Errorf("failed to encode report row: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadResultPage reads limit rows after skipping offset from a stored result
+func ReadResultPage(r io.Reader, limit, offset int) ([]map[string]interface{}, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	rows := make([]map[string]interface{}, 0, limit)
+	for line := 0; len(rows) < limit && scanner.Scan(); line++ {
+		if line < offset {
+			continue
+		}
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("failed to decode report row: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read report result: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,232 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// Report job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// DefaultClaimTimeout is how long a job may stay running before another
+// worker assumes its claimant died and claims it again
+const DefaultClaimTimeout = 30 * time.Minute
+
+// MaxJobAttempts is how many times a job is claimed before it is failed
+// rather than claimed again, so a job that kills its worker cannot loop
+const MaxJobAttempts = 3
+
+// Job is an asynchronous report request
+type Job struct {
+	ID            string     `json:"job_id"`
+	Status        string     `json:"status"`
+	Query         Query      `json:"query"`
+	WebhookURL    string     `json:"webhook_url,omitempty"`
+	WebhookSecret string     `json:"-"` // Signs the webhook POST; shown once when the job is created
+	Attempts      int        `json:"-"` // Times the job has been claimed
+	RequestedBy   string     `json:"requested_by,omitempty"`
+	RowCount      int        `json:"row_count"`
+	ResultKey     string     `json:"-"` // Object storage key of the result rows
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// JobStore persists report jobs and their results
+type JobStore interface {
+	ClaimReportJob(staleAfter time.Duration) (*Job, error)
+	CompleteReportJob(jobID, resultKey string, rowCount int) error
+	FailReportJob(jobID string, message string) error
+}
+
+// Source computes report rows
+type Source interface {
+	GetExposureReport(q Query) ([]map[string]interface{}, error)
+}
+
+// Worker computes pending report jobs in the background
+type Worker struct {
+	jobs         JobStore
+	source       Source
+	store        storage.Store
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	baseURL      string
+	httpClient   *outbound.Client
+}
+
+// NewWorker creates a report worker that keeps results in store. baseURL
+// prefixes download links sent to webhooks.
+func NewWorker(jobs JobStore, source Source, store storage.Store, baseURL string) *Worker {
+	return &Worker{
+		jobs:         jobs,
+		source:       source,
+		store:        store,
+		pollInterval: 2 * time.Second,
+		claimTimeout: DefaultClaimTimeout,
+		baseURL:      baseURL,
+		httpClient:   outbound.New("report_webhook", outbound.WebhookPolicy()),
+	}
+}
+
+// SetClaimTimeout sets how long a job may run before it is claimed again.
+// It should comfortably exceed the longest report the async guard allows.
+func (w *Worker) SetClaimTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		w.claimTimeout = timeout
+	}
+}
+
+// DownloadPath returns the API path serving a job's result rows
+func DownloadPath(jobID string) string {
+	return "/api/v1/analytics/reports/" + jobID + "/download"
+}
+
+// Run claims and computes jobs until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain all pending jobs before waiting for the next tick
+			for w.processNext() {
+			}
+		}
+	}
+}
+
+// processNext runs one pending job and reports whether one was found
+func (w *Worker) processNext() bool {
+	job, err := w.jobs.ClaimReportJob(w.claimTimeout)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to claim report job")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"job_id":     job.ID,
+		"booking_id": job.Query.BookingID,
+	})
+	if job.Attempts > MaxJobAttempts {
+		// Claimed again after its claimants stopped without finishing it
+		logger.WithField("attempts", job.Attempts).Error("Abandoning async report")
+		w.fail(job, fmt.Errorf("report abandoned after %d attempts", MaxJobAttempts))
+		return true
+	}
+	logger.Info("Computing async report")
+
+	rows, err := w.source.GetExposureReport(job.Query)
+	if err == nil {
+		rows, _ = ApplyPrivacy(job.Query, rows)
+		err = w.storeResult(job.ID, rows)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Async report failed")
+		w.fail(job, err)
+		return true
+	}
+	if err := w.jobs.CompleteReportJob(job.ID, ResultKey(job.ID), len(rows)); err != nil {
+		logger.WithError(err).Error("Failed to store report result")
+		return true
+	}
+	job.Status = JobCompleted
+	job.RowCount = len(rows)
+
+	if job.WebhookURL != "" {
+		w.notify(job)
+	}
+	return true
+}
+
+// fail marks a job failed and notifies its webhook
+func (w *Worker) fail(job *Job, cause error) {
+	if err := w.jobs.FailReportJob(job.ID, cause.Error()); err != nil {
+		logrus.WithError(err).WithField("job_id", job.ID).Error("Failed to mark report job failed")
+	}
+	job.Status = JobFailed
+	job.Error = cause.Error()
+
+	if job.WebhookURL != "" {
+		w.notify(job)
+	}
+}
+
+// storeResult writes a job's result rows to object storage
+func (w *Worker) storeResult(jobID string, rows []map[string]interface{}) error {
+	data, err := EncodeResult(rows)
+	if err != nil {
+		return err
+	}
+	return w.store.Put(context.Background(), ResultKey(jobID), data, ResultContentType)
+}
+
+// notify posts the job outcome to its webhook, signed with the job's secret
+// like webhook subscription deliveries. Delivery is best-effort; clients can
+// always fall back to polling the job status.
+func (w *Worker) notify(job *Job) {
+	payload := map[string]interface{}{
+		"job_id":    job.ID,
+		"status":    job.Status,
+		"row_count": job.RowCount,
+	}
+	if job.Status == JobCompleted {
+		payload["download_url"] = w.baseURL + DownloadPath(job.ID)
+	} else {
+		payload["error"] = job.Error
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, job.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", job.ID).Warn("Invalid report webhook URL")
+		return
+	}
+	if job.WebhookSecret != "" { // Jobs created before callbacks were signed have none
+		if err := webhook.SignRequest(req, job.WebhookSecret, body, time.Now()); err != nil {
+			logrus.WithError(err).WithField("job_id", job.ID).Warn("Failed to sign report webhook")
+			return
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Retried deliveries carry the same key so receivers can drop duplicates
+	req.Header.Set("Idempotency-Key", job.ID)
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", job.ID).Warn("Report webhook delivery failed")
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logrus.WithFields(logrus.Fields{
+			"job_id": job.ID,
+			"status": resp.StatusCode,
+		}).Warn("Report webhook returned non-success status")
+	}
+}
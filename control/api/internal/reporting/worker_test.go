@@ -0,0 +1,104 @@
+package reporting
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/storage"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockJobStore struct {
+	pending    []*Job
+	staleAfter time.Duration
+	completed  map[string]int
+	failed     map[string]string
+}
+
+func (m *mockJobStore) ClaimReportJob(staleAfter time.Duration) (*Job, error) {
+	m.staleAfter = staleAfter
+	if len(m.pending) == 0 {
+		return nil, nil
+	}
+	job := m.pending[0]
+	m.pending = m.pending[1:]
+	job.Attempts++
+	return job, nil
+}
+
+func (m *mockJobStore) CompleteReportJob(jobID, resultKey string, rowCount int) error {
+	m.completed[jobID] = rowCount
+	return nil
+}
+
+func (m *mockJobStore) FailReportJob(jobID string, message string) error {
+	m.failed[jobID] = message
+	return nil
+}
+
+type mockSource struct {
+	rows []map[string]interface{}
+}
+
+func (m *mockSource) GetExposureReport(q Query) ([]map[string]interface{}, error) {
+	return m.rows, nil
+}
+
+type callback struct {
+	header http.Header
+	body   []byte
+}
+
+func newTestWorker(t *testing.T, jobs *mockJobStore) (*Worker, chan callback, string) {
+	callbacks := make(chan callback, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- callback{header: r.Header.Clone(), body: body}
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	source := &mockSource{rows: []map[string]interface{}{{"impressions": 1}}}
+	return NewWorker(jobs, source, store, "https://api.example.com"), callbacks, server.URL
+}
+
+func TestWorker_SignsWebhook(t *testing.T) {
+	jobs := &mockJobStore{completed: map[string]int{}, failed: map[string]string{}}
+	worker, callbacks, url := newTestWorker(t, jobs)
+	worker.SetClaimTimeout(time.Hour)
+	jobs.pending = []*Job{{ID: "report_1", WebhookURL: url, WebhookSecret: "whsec_test"}}
+
+	require.True(t, worker.processNext())
+	assert.Equal(t, time.Hour, jobs.staleAfter, "Jobs running longer than the claim timeout are claimed again")
+	assert.Equal(t, 1, jobs.completed["report_1"])
+
+	got := <-callbacks
+	verifier := webhook.NewVerifier([]string{"whsec_test"}, webhook.DefaultTolerance)
+	_, err := verifier.Verify(got.header.Get(webhook.HeaderTimestamp), got.header.Get(webhook.HeaderNonce),
+		got.header.Get(webhook.HeaderSignature), got.body)
+	assert.NoError(t, err, "The callback is signed with the job's secret")
+	assert.Contains(t, string(got.body), `"download_url":"https://api.example.com/api/v1/analytics/reports/report_1/download"`)
+
+	assert.False(t, worker.processNext(), "Nothing left to claim")
+}
+
+func TestWorker_AbandonsJobAfterMaxAttempts(t *testing.T) {
+	jobs := &mockJobStore{completed: map[string]int{}, failed: map[string]string{}}
+	worker, callbacks, url := newTestWorker(t, jobs)
+	jobs.pending = []*Job{{ID: "report_1", WebhookURL: url, WebhookSecret: "whsec_test", Attempts: MaxJobAttempts}}
+
+	require.True(t, worker.processNext())
+	assert.Equal(t, DefaultClaimTimeout, jobs.staleAfter)
+	assert.NotContains(t, jobs.completed, "report_1", "A job whose claimants keep dying is not run again")
+	assert.Equal(t, "report abandoned after 3 attempts", jobs.failed["report_1"])
+
+	got := <-callbacks
+	assert.Contains(t, string(got.body), `"status":"failed"`)
+	assert.NotEmpty(t, got.header.Get(webhook.HeaderSignature))
+}
@@ -0,0 +1,164 @@
+// Package resend recognises exposure beacons an edge player sent again,
+// e.g. after losing the response on a flaky network. A beacon carrying a
+// client_event_id is keyed by its booking, viewer and client event ID; a key
+// seen again within the window is a duplicate and is not recorded. The
+// window slides: every sighting of a key extends it.
+//
+// The window is a fast first check kept in Redis. Exposure events also store
+// their key under a unique constraint, which catches duplicates arriving
+// after the window or while Redis is unavailable.
+package resend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultWindow is how long after its last sighting a key is remembered
+const DefaultWindow = 15 * time.Minute
+
+// MaxClientEventIDLength bounds client event IDs
+const MaxClientEventIDLength = 128
+
+// Key identifies an exposure beacon across resends, or is empty when the
+// beacon carries no client event ID. Viewer IDs are hashed into the key so
+// they are not kept in the clear.
+func Key(bookingID, viewerID, clientEventID string) string {
+	if clientEventID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(bookingID + "\x00" + viewerID + "\x00" + clientEventID))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrUnavailable is returned when the window cannot be read
+var ErrUnavailable = errors.New("resend window unavailable")
+
+// Window remembers keys for a sliding window
+type Window interface {
+	// Seen marks keys seen and reports for each whether it had been seen
+	// within the window, including earlier in keys
+	Seen(ctx context.Context, keys []string) ([]bool, error)
+	// Forget drops keys, so beacons that could not be recorded can be resent
+	Forget(ctx context.Context, keys []string) error
+}
+
+// RedisWindow keeps keys in Redis, shared by every gateway instance. Each key
+// is its own Redis key, so it works on Redis Cluster.
+type RedisWindow struct {
+	client redis.UniversalClient
+	window time.Duration
+}
+
+// NewRedisWindow creates a window backed by Redis
+func NewRedisWindow(client redis.UniversalClient, window time.Duration) *RedisWindow {
+	return &RedisWindow{client: client, window: window}
+}
+
+// redisKey names a key in Redis
+func redisKey(key string) string {
+	return "resend:" + key
+}
+
+// Seen marks keys seen, pipelining a SET ... GET per key so the window of a
+// key seen before restarts
+func (r *RedisWindow) Seen(ctx context.Context, keys []string) ([]bool, error) {
+	cmds := make([]*redis.StatusCmd, len(keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.SetArgs(ctx, redisKey(key), 1, redis.SetArgs{TTL: r.window, Get: true})
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	seen := make([]bool, len(keys))
+	for i, cmd := range cmds {
+		switch err := cmd.Err(); {
+		case err == nil:
+			seen[i] = true
+		case errors.Is(err, redis.Nil):
+		default:
+			return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+	}
+	return seen, nil
+}
+
+// Forget drops keys
+func (r *RedisWindow) Forget(ctx context.Context, keys []string) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, redisKey(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// MemoryWindow keeps keys in process memory. Each gateway instance remembers
+// its own, so use RedisWindow behind a load balancer.
+type MemoryWindow struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	keys  map[string]time.Time // Expiry of each key
+	swept time.Time
+}
+
+// NewMemoryWindow creates an in-memory window
+func NewMemoryWindow(window time.Duration) *MemoryWindow {
+	return &MemoryWindow{window: window, now: time.Now, keys: make(map[string]time.Time)}
+}
+
+// Seen marks keys seen
+func (m *MemoryWindow) Seen(ctx context.Context, keys []string) ([]bool, error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	seen := make([]bool, len(keys))
+	for i, key := range keys {
+		expiresAt, ok := m.keys[key]
+		seen[i] = ok && now.Before(expiresAt)
+		m.keys[key] = now.Add(m.window)
+	}
+	return seen, nil
+}
+
+// Forget drops keys
+func (m *MemoryWindow) Forget(ctx context.Context, keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.keys, key)
+	}
+	return nil
+}
+
+// sweep drops expired keys, at most once a minute
+func (m *MemoryWindow) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for key, expiresAt := range m.keys {
+		if !now.Before(expiresAt) {
+			delete(m.keys, key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package resend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	assert.Empty(t, Key("booking_1", "viewer_1", ""), "Beacons without a client event ID have no key")
+	key := Key("booking_1", "viewer_1", "evt_1")
+	assert.Len(t, key, 64)
+	assert.NotContains(t, key, "viewer_1")
+	assert.Equal(t, key, Key("booking_1", "viewer_1", "evt_1"))
+	assert.NotEqual(t, key, Key("booking_1", "viewer_2", "evt_1"))
+	assert.NotEqual(t, Key("booking_1", "viewer_1x", "evt"), Key("booking_1", "viewer_1", "xevt"), "Parts are separated")
+}
+
+func TestMemoryWindow(t *testing.T) {
+	ctx := context.Background()
+	w := NewMemoryWindow(time.Minute)
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	seen, err := w.Seen(ctx, []string{"a", "b", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, seen, "A key repeated in one batch is a duplicate")
+
+	now = now.Add(50 * time.Second)
+	seen, err = w.Seen(ctx, []string{"a", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, seen)
+
+	// b was last seen 70s ago; a 20s ago, which restarted its window
+	now = now.Add(20 * time.Second)
+	seen, err = w.Seen(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, seen, "The window slides with every sighting")
+
+	require.NoError(t, w.Forget(ctx, []string{"a", "unknown"}))
+	seen, err = w.Seen(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, seen, "A forgotten key can be resent")
+
+	now = now.Add(2 * time.Minute)
+	seen, err = w.Seen(ctx, []string{"d"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, seen)
+	assert.Len(t, w.keys, 1, "Expired keys are swept")
+}
+
+func TestRedisWindow(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	w := NewRedisWindow(client, time.Minute)
+
+	seen, err := w.Seen(ctx, []string{"a", "b", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, seen, "A key repeated in one batch is a duplicate")
+	assert.Equal(t, time.Minute, server.TTL(redisKey("a")))
+
+	server.FastForward(50 * time.Second)
+	seen, err = w.Seen(ctx, []string{"a", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, seen)
+	assert.Equal(t, time.Minute, server.TTL(redisKey("a")), "A sighting restarts the window")
+
+	server.FastForward(20 * time.Second)
+	seen, err = w.Seen(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, seen, "b expired 70s after its only sighting")
+
+	require.NoError(t, w.Forget(ctx, []string{"a", "unknown"}))
+	seen, err = w.Seen(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, seen, "A forgotten key can be resent")
+
+	seen, err = w.Seen(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, seen)
+
+	server.Close()
+	_, err = w.Seen(ctx, []string{"a"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, w.Forget(ctx, []string{"a"}), ErrUnavailable)
+}
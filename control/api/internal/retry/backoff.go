@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponential retry delays: Base doubles with every attempt
+// up to Max, then up to Jitter (a fraction of the delay) is subtracted at
+// random so that clients failing together do not retry in lockstep.
+type Backoff struct {
+	Base   time.Duration
+	Max    time.Duration
+	Jitter float64
+}
+
+// Delay returns the wait before the retry following the given failed attempt (1-based)
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	if b.Jitter > 0 && delay > 0 {
+		jitter := b.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+	return delay
+}
@@ -0,0 +1,52 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  Backoff
+		attempt  int
+		expected time.Duration
+	}{
+		{name: "first attempt", backoff: Backoff{Base: time.Second, Max: time.Minute}, attempt: 1, expected: time.Second},
+		{name: "doubles", backoff: Backoff{Base: time.Second, Max: time.Minute}, attempt: 4, expected: 8 * time.Second},
+		{name: "capped", backoff: Backoff{Base: time.Second, Max: time.Minute}, attempt: 10, expected: time.Minute},
+		{name: "without max", backoff: Backoff{Base: time.Second}, attempt: 3, expected: time.Second},
+		{name: "attempt below one", backoff: Backoff{Base: time.Second, Max: time.Minute}, attempt: 0, expected: time.Second},
+		{name: "no base", backoff: Backoff{Max: time.Minute, Jitter: 0.5}, attempt: 3, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.backoff.Delay(tt.attempt))
+		})
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		min     time.Duration
+		max     time.Duration
+	}{
+		{name: "half", backoff: Backoff{Base: 10 * time.Second, Max: time.Minute, Jitter: 0.5}, min: 5 * time.Second, max: 10 * time.Second},
+		{name: "over one", backoff: Backoff{Base: 10 * time.Second, Max: time.Minute, Jitter: 3}, min: 0, max: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				delay := tt.backoff.Delay(1)
+				assert.GreaterOrEqual(t, delay, tt.min)
+				assert.LessOrEqual(t, delay, tt.max, "Jitter only shortens the delay")
+			}
+		})
+	}
+}
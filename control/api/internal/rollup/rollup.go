@@ -0,0 +1,138 @@
+// Package rollup maintains hourly and daily aggregates of counted exposure
+// events, so booking metrics can be charted over time without scanning
+// every event.
+//
+// Events arrive late and out of order, so the rollups are not appended to.
+// Each run finds the events received since the previous run and recomputes
+// every hour and day bucket they fall in from the events themselves. Buckets
+// are UTC. A run stops Lag short of now, leaving time for transactions
+// still inserting events to commit; the rollups cover events received up to
+// the rolled-up-through time stored with them.
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Supported granularities
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+const (
+	// Lag keeps a run from rolling up events received in its last minute
+	Lag = time.Minute
+	// MaxBuckets bounds the buckets of one series
+	MaxBuckets = 2000
+)
+
+var steps = map[string]time.Duration{
+	GranularityHour: time.Hour,
+	GranularityDay:  24 * time.Hour,
+}
+
+// Range is the time range of a series, aligned to its granularity
+type Range struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+}
+
+// NewRange aligns from down and to up to the granularity's buckets
+func NewRange(from, to time.Time, granularity string) (Range, error) {
+	step, ok := steps[granularity]
+	if !ok {
+		return Range{}, fmt.Errorf("unsupported granularity %q (use hour or day)", granularity)
+	}
+	r := Range{From: from.UTC().Truncate(step), To: to.UTC().Truncate(step), Granularity: granularity}
+	if r.To.Before(to) {
+		r.To = r.To.Add(step)
+	}
+	if !r.To.After(r.From) {
+		return Range{}, fmt.Errorf("to must be after from")
+	}
+	if r.Buckets() > MaxBuckets {
+		return Range{}, fmt.Errorf("range spans more than %d %s buckets", MaxBuckets, granularity)
+	}
+	return r, nil
+}
+
+// Buckets returns the number of buckets in the range
+func (r Range) Buckets() int {
+	return int(r.To.Sub(r.From) / steps[r.Granularity])
+}
+
+// Fill returns a bucket for every step of the range, in order, taking the
+// aggregates of buckets that had events and zeros for the rest
+func Fill(r Range, buckets []models.MetricsBucket) []models.MetricsBucket {
+	byStart := make(map[time.Time]models.MetricsBucket, len(buckets))
+	for _, b := range buckets {
+		byStart[b.Bucket.UTC()] = b
+	}
+
+	step := steps[r.Granularity]
+	filled := make([]models.MetricsBucket, 0, r.Buckets())
+	for start := r.From; start.Before(r.To); start = start.Add(step) {
+		b := byStart[start]
+		b.Bucket = start
+		filled = append(filled, b)
+	}
+	return filled
+}
+
+// Store recomputes the rollup buckets of the events received since its last
+// run up to through, returning how many buckets it rewrote
+type Store interface {
+	RollUpExposures(through time.Time) (int, error)
+}
+
+// Worker keeps the rollups current
+type Worker struct {
+	store    Store
+	interval time.Duration
+}
+
+// NewWorker creates a rollup worker
+func NewWorker(store Store, interval time.Duration) *Worker {
+	return &Worker{store: store, interval: interval}
+}
+
+// Run rolls up immediately and then every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.rollUp()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) rollUp() {
+	started := time.Now().UTC()
+	through := started.Add(-Lag)
+	buckets, err := w.store.RollUpExposures(through)
+	if err != nil {
+		logrus.WithError(err).Error("Exposure rollup failed")
+		return
+	}
+	metrics.ExposureRollupBuckets.Add(float64(buckets))
+	metrics.ExposureRollupThrough.Set(float64(through.Unix()))
+	logrus.WithFields(logrus.Fields{
+		"buckets":  buckets,
+		"through":  through,
+		"duration": time.Since(started),
+	}).Debug("Rolled up exposure events")
+}
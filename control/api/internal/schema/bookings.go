@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/freqcap"
+	"github.com/inscenium/inscenium/control/api/internal/labels"
+)
+
+// BookingRequest is the body of POST /bookings
+type BookingRequest struct {
+	SurfaceID      string            `json:"surface_id" binding:"required"`
+	AdvertiserID   string            `json:"advertiser_id" binding:"required"`
+	CampaignID     string            `json:"campaign_id" binding:"required"`
+	BidAmountCPM   float64           `json:"bid_amount_cpm" binding:"required"`
+	MaxImpressions int               `json:"max_impressions"`
+	MinPRSScore    float64           `json:"min_prs_score"`
+	BillingModel   string            `json:"billing_model"` // cpm (default), attention_cpm or vcpm
+	BundleID       string            `json:"bundle_id"`     // Bundle deal exempting the campaign's other bookings in it from collision rules
+	Pacing         string            `json:"pacing"`        // asap (default) or even over the exposure window
+	FrequencyCap   *freqcap.Cap      `json:"frequency_cap"` // Exposures per viewer of this booking
+	StartTime      *time.Time        `json:"start_time"`    // Exposure window; open-ended when unset
+	EndTime        *time.Time        `json:"end_time"`
+	Labels         labels.Set        `json:"labels"`
+	ExternalIDs    map[string]string `json:"external_ids"`
+}
+
+// BookingConfirmation is the response to POST /bookings
+type BookingConfirmation struct {
+	BookingID            string       `json:"booking_id"`
+	Status               string       `json:"status"`
+	Message              string       `json:"message"`
+	ConfirmationTime     string       `json:"confirmation_time"`
+	FinalCPMRate         float64      `json:"final_cpm_rate" alias:"final_cmp_rate"`
+	BillingModel         string       `json:"billing_model"`
+	BundleID             string       `json:"bundle_id,omitempty"`
+	Pacing               string       `json:"pacing"`
+	FrequencyCap         *freqcap.Cap `json:"frequency_cap,omitempty"`
+	StartTime            *time.Time   `json:"start_time,omitempty"`
+	EndTime              *time.Time   `json:"end_time,omitempty"`
+	EstimatedImpressions int          `json:"estimated_impressions"`
+}
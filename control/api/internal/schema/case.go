@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Request body case conventions, configured per service-account key and set
+// as "json_case" in the request context. Responses are always snake_case.
+const (
+	CaseSnake = "snake" // Only canonical snake_case field names
+	CaseAny   = "any"   // camelCase and snake_case, mixed freely
+)
+
+// ValidCase reports whether c is a known case convention. Empty means CaseSnake.
+func ValidCase(c string) bool {
+	return c == "" || c == CaseSnake || c == CaseAny
+}
+
+// foldName reduces a field name to a form shared by its snake_case and
+// camelCase spellings, e.g. bid_amount_cpm, bidAmountCpm and bidAmountCPM
+func foldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// fieldNames maps the folded form of each JSON field of a struct type,
+// including deprecated aliases, to the spelling the type expects
+func fieldNames(t reflect.Type) map[string]string {
+	names := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[foldName(name)] = name
+		if old := field.Tag.Get("alias"); old != "" {
+			names[foldName(old)] = old
+		}
+	}
+	return names
+}
+
+// fieldType returns the type of the struct field with the given JSON name
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == "" {
+			tagName = field.Name
+		}
+		if tagName == name || field.Tag.Get("alias") == name {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// normalizeCase rewrites the object keys of data that are another spelling of
+// a field of t to the spelling t expects, recursing into nested structs and
+// slices. Map keys are data, not field names, and are left alone; so are keys
+// matching no field. When a payload mixes both spellings the canonical wins.
+func normalizeCase(data json.RawMessage, t reflect.Type) json.RawMessage {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) != nil || fields == nil {
+			return data // Not an object, e.g. a time.Time string
+		}
+
+		names := fieldNames(t)
+		normalized := make(map[string]json.RawMessage, len(fields))
+		for key, value := range fields {
+			name, ok := names[foldName(key)]
+			if !ok {
+				normalized[key] = value
+				continue
+			}
+			if key != name {
+				if _, exact := fields[name]; exact {
+					continue
+				}
+			}
+			if ft, ok := fieldType(t, name); ok {
+				value = normalizeCase(value, ft)
+			}
+			normalized[name] = value
+		}
+
+		out, err := json.Marshal(normalized)
+		if err != nil {
+			return data
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return data
+		}
+		for i := range items {
+			items[i] = normalizeCase(items[i], t.Elem())
+		}
+		out, err := json.Marshal(items)
+		if err != nil {
+			return data
+		}
+		return out
+
+	case reflect.Map:
+		var values map[string]json.RawMessage
+		if json.Unmarshal(data, &values) != nil || values == nil {
+			return data
+		}
+		for key, value := range values {
+			values[key] = normalizeCase(value, t.Elem())
+		}
+		out, err := json.Marshal(values)
+		if err != nil {
+			return data
+		}
+		return out
+
+	default:
+		return data
+	}
+}
@@ -0,0 +1,19 @@
+package schema
+
+import "time"
+
+// ExposureRequest is the payload of a single exposure event, alone or in a
+// batch. Exposures of audio slots are measured by exposure_duration, the
+// seconds of the slot heard; their screen_coverage is ignored.
+type ExposureRequest struct {
+	BookingID        string     `json:"booking_id" binding:"required"`
+	ViewerID         string     `json:"viewer_id" binding:"required"`
+	ClientEventID    string     `json:"client_event_id" binding:"max=128"` // Kept across resends, which are dropped as duplicates
+	ExposureDuration float64    `json:"exposure_duration" binding:"required"`
+	ScreenCoverage   float64    `json:"screen_coverage"`
+	AttentionScore   float64    `json:"attention_score"`
+	DeviceType       string     `json:"device_type"`
+	ConsentString    string     `json:"consent_string"`
+	Timestamp        *time.Time `json:"timestamp"`
+	DeviceClock      *time.Time `json:"device_clock"`
+}
@@ -0,0 +1,27 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+)
+
+// DeprecatedRoute marks responses served from an old route that is kept as an
+// alias for one API version. successor is the canonical route pattern; its
+// :params are filled in from the request so clients can follow the Link.
+func DeprecatedRoute(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		segments := strings.Split(successor, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = c.Param(segment[1:])
+			}
+		}
+
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+strings.Join(segments, "/")+`>; rel="successor-version"`)
+		metrics.DeprecatedAPIUsage.WithLabelValues("route", c.FullPath()).Inc()
+		c.Next()
+	}
+}
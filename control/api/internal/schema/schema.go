@@ -0,0 +1,173 @@
+// Package schema holds the canonical request and response shapes of the
+// public API. Field names come from json struct tags and are snake_case;
+// callers whose key allows it may send camelCase instead. A field renamed to fix
+// naming drift keeps its old name in an alias tag for one API version:
+//
+//	FinalCPMRate float64 `json:"final_cpm_rate" alias:"final_cmp_rate"`
+//
+// Render emits both names and BindJSON accepts either, and every use of an
+// old name is reported to the client in the X-Inscenium-Deprecated-Fields
+// header and counted in inscenium_deprecated_api_usage_total.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+)
+
+// DeprecatedFieldsHeader lists the deprecated fields a call used as old=new pairs
+const DeprecatedFieldsHeader = "X-Inscenium-Deprecated-Fields"
+
+// alias maps a deprecated field name to its canonical name
+type alias struct {
+	canonical string
+	old       string
+}
+
+var aliasCache sync.Map // reflect.Type -> []alias
+
+// aliases returns the alias-tagged fields of a struct type
+func aliases(t reflect.Type) []alias {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := aliasCache.Load(t); ok {
+		return cached.([]alias)
+	}
+
+	var found []alias
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		old := field.Tag.Get("alias")
+		if old == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		found = append(found, alias{canonical: name, old: old})
+	}
+
+	aliasCache.Store(t, found)
+	return found
+}
+
+// Render writes v as JSON, adding the deprecated name of every aliased field
+func Render(c *gin.Context, status int, v interface{}) {
+	fieldAliases := aliases(reflect.TypeOf(v))
+	if len(fieldAliases) == 0 {
+		c.JSON(status, v)
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.JSON(status, v) // Let gin report the encoding error
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		c.JSON(status, v)
+		return
+	}
+
+	for _, a := range fieldAliases {
+		value, ok := fields[a.canonical]
+		if !ok {
+			continue
+		}
+		fields[a.old] = value
+		Deprecate(c, "field", a.old, a.canonical)
+	}
+
+	c.JSON(status, fields)
+}
+
+// BindJSON decodes and validates the request body into v, accepting the
+// deprecated name of any aliased field, and camelCase field names when the
+// caller's key allows them. When both names are sent the canonical one wins.
+func BindJSON(c *gin.Context, v interface{}) error {
+	data, err := c.GetRawData()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	return Decode(c, data, v)
+}
+
+// Decode decodes and validates one JSON document of a request into v like
+// BindJSON, e.g. an element of a batch validated on its own
+func Decode(c *gin.Context, data []byte, v interface{}) error {
+	var err error
+	if c.GetString("json_case") == CaseAny {
+		data = normalizeCase(data, reflect.TypeOf(v))
+	}
+
+	if fieldAliases := aliases(reflect.TypeOf(v)); len(fieldAliases) > 0 {
+		var fields map[string]json.RawMessage
+		// Malformed bodies fall through so binding reports the error
+		if json.Unmarshal(data, &fields) == nil && fields != nil {
+			renamed := false
+			for _, a := range fieldAliases {
+				value, ok := fields[a.old]
+				if !ok {
+					continue
+				}
+				delete(fields, a.old)
+				if _, exists := fields[a.canonical]; !exists {
+					fields[a.canonical] = value
+				}
+				renamed = true
+				Deprecate(c, "field", a.old, a.canonical)
+			}
+			if renamed {
+				if data, err = json.Marshal(fields); err != nil {
+					return fmt.Errorf("failed to rewrite request body: %w", err)
+				}
+			}
+		}
+	}
+
+	return binding.JSON.BindBody(data, v)
+}
+
+// Validate checks a request decoded by another transport against its
+// binding tags, reporting what BindJSON would
+func Validate(v interface{}) error {
+	return binding.Validator.ValidateStruct(v)
+}
+
+// Query returns a query parameter, falling back to its deprecated name
+func Query(c *gin.Context, name, old, defaultValue string) string {
+	if value, ok := c.GetQuery(name); ok {
+		return value
+	}
+	if value, ok := c.GetQuery(old); ok {
+		Deprecate(c, "query", old, name)
+		return value
+	}
+	return defaultValue
+}
+
+// Deprecate records that a call used a deprecated name. kind is field, query or route.
+func Deprecate(c *gin.Context, kind, old, canonical string) {
+	pair := old + "=" + canonical
+	header := c.Writer.Header()
+	for _, existing := range header.Values(DeprecatedFieldsHeader) {
+		if existing == pair {
+			return
+		}
+	}
+	header.Add(DeprecatedFieldsHeader, pair)
+	metrics.DeprecatedAPIUsage.WithLabelValues(kind, old).Inc()
+}
@@ -0,0 +1,302 @@
+// Package schemadrift compares the columns the typed models are read from
+// with the columns the database actually has, so deployments whose schema
+// lags the binary are reported before queries fail to scan.
+package schemadrift
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+)
+
+// Modes of the startup check
+const (
+	ModeOff  = "off"  // Skip the check
+	ModeWarn = "warn" // Log drift and start anyway
+	ModeFail = "fail" // Refuse to start on critical drift
+)
+
+// ValidMode reports whether mode is a known startup check mode
+func ValidMode(mode string) bool {
+	return mode == ModeOff || mode == ModeWarn || mode == ModeFail
+}
+
+// Kinds of drift
+const (
+	MissingTable  = "missing_table"
+	MissingColumn = "missing_column"
+	TypeMismatch  = "type_mismatch"
+)
+
+// Severities of drift. Missing tables and columns fail every query reading
+// them; a column of another type only fails to scan some values.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Type families a column's data_type falls in
+const (
+	familyText    = "text"
+	familyInteger = "integer"
+	familyFloat   = "float"
+	familyBool    = "boolean"
+	familyTime    = "timestamp"
+	familyJSON    = "json"
+)
+
+// Model is a typed model read from a table
+type Model struct {
+	Table string
+	Model interface{}
+}
+
+// Models lists the typed models and the tables they are read from. Fields
+// are matched to columns by their db tags; fields without one, or tagged
+// "-", are not stored.
+var Models = []Model{
+	{Table: "surfaces", Model: models.Surface{}},
+	{Table: "surfaces", Model: models.Geometry{}},
+	{Table: "placement_bookings", Model: models.Booking{}},
+	{Table: "placement_bookings", Model: models.NewBooking{}},
+	{Table: "exposure_events", Model: models.ExposureEvent{}},
+}
+
+// Column is a column a model expects, with the type families it can be
+// scanned from
+type Column struct {
+	Table    string
+	Name     string
+	Field    string   // Go field, as Model.Field
+	Families []string // Acceptable type families, the first being the model's own
+}
+
+// Expected lists the columns of models, sorted by table and name. Columns
+// several models expect are listed once, with the families all of them
+// accept.
+func Expected(ms []Model) []Column {
+	byKey := make(map[string]*Column)
+	for _, m := range ms {
+		t := reflect.TypeOf(m.Model)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := f.Tag.Get("db")
+			if name == "" || name == "-" {
+				continue
+			}
+			families := goFamilies(f.Type)
+			if len(families) == 0 {
+				continue
+			}
+			key := m.Table + "." + name
+			if c, ok := byKey[key]; ok {
+				c.Families = intersect(c.Families, families)
+				continue
+			}
+			byKey[key] = &Column{Table: m.Table, Name: name, Field: t.Name() + "." + f.Name, Families: families}
+		}
+	}
+
+	columns := make([]Column, 0, len(byKey))
+	for _, c := range byKey {
+		columns = append(columns, *c)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Table != columns[j].Table {
+			return columns[i].Table < columns[j].Table
+		}
+		return columns[i].Name < columns[j].Name
+	})
+	return columns
+}
+
+// Tables lists the tables of columns, sorted
+func Tables(columns []Column) []string {
+	seen := make(map[string]bool)
+	tables := make([]string, 0)
+	for _, c := range columns {
+		if !seen[c.Table] {
+			seen[c.Table] = true
+			tables = append(tables, c.Table)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	jsonType = reflect.TypeOf(json.RawMessage{})
+)
+
+// goFamilies returns the type families a field of type t can be scanned
+// from. Strings also take integers, as several IDs are stored as integers.
+func goFamilies(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return []string{familyTime}
+	case t == jsonType:
+		return []string{familyJSON, familyText}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return []string{familyText, familyInteger}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{familyInteger}
+	case reflect.Float32, reflect.Float64:
+		return []string{familyFloat, familyInteger}
+	case reflect.Bool:
+		return []string{familyBool}
+	}
+	return nil
+}
+
+// Family returns the type family of an information_schema data_type, or
+// the data type itself for types no model field is scanned from
+func Family(dataType string) string {
+	switch dataType {
+	case "character varying", "character", "text", "uuid", "citext":
+		return familyText
+	case "smallint", "integer", "bigint":
+		return familyInteger
+	case "real", "double precision", "numeric":
+		return familyFloat
+	case "boolean":
+		return familyBool
+	case "timestamp without time zone", "timestamp with time zone", "date":
+		return familyTime
+	case "json", "jsonb":
+		return familyJSON
+	}
+	return dataType
+}
+
+func intersect(a, b []string) []string {
+	result := make([]string, 0, len(a))
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				result = append(result, x)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Finding is one difference between the models and the database
+type Finding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Field    string `json:"field,omitempty"`    // Model field reading the column
+	Expected string `json:"expected,omitempty"` // Type families the field can be scanned from
+	Actual   string `json:"actual,omitempty"`   // Data type of the column in the database
+}
+
+// String describes the finding for logs
+func (f Finding) String() string {
+	switch f.Kind {
+	case MissingTable:
+		return fmt.Sprintf("table %s is missing", f.Table)
+	case MissingColumn:
+		return fmt.Sprintf("column %s.%s read into %s is missing", f.Table, f.Column, f.Field)
+	default:
+		return fmt.Sprintf("column %s.%s is %s but %s expects %s", f.Table, f.Column, f.Actual, f.Field, f.Expected)
+	}
+}
+
+// Report is the outcome of a comparison
+type Report struct {
+	CheckedAt time.Time `json:"checked_at"`
+	OK        bool      `json:"ok"`      // The database has every expected column, with a compatible type
+	Tables    int       `json:"tables"`  // Tables checked
+	Columns   int       `json:"columns"` // Columns checked
+	Findings  []Finding `json:"findings"`
+	Critical  int       `json:"critical"` // Findings of SeverityCritical
+}
+
+// Compare checks the expected columns against actual, the data types of
+// the database's columns by table and column name. Columns the models do
+// not read are ignored.
+func Compare(expected []Column, actual map[string]map[string]string, now time.Time) *Report {
+	report := &Report{CheckedAt: now, Columns: len(expected), Findings: make([]Finding, 0)}
+	missingTables := make(map[string]bool)
+	for _, table := range Tables(expected) {
+		report.Tables++
+		if _, ok := actual[table]; !ok {
+			missingTables[table] = true
+			report.Findings = append(report.Findings, Finding{Kind: MissingTable, Severity: SeverityCritical, Table: table})
+		}
+	}
+
+	for _, c := range expected {
+		if missingTables[c.Table] {
+			continue
+		}
+		dataType, ok := actual[c.Table][c.Name]
+		if !ok {
+			report.Findings = append(report.Findings, Finding{
+				Kind: MissingColumn, Severity: SeverityCritical, Table: c.Table, Column: c.Name, Field: c.Field,
+				Expected: strings.Join(c.Families, " or "),
+			})
+			continue
+		}
+		family := Family(dataType)
+		compatible := false
+		for _, f := range c.Families {
+			compatible = compatible || f == family
+		}
+		if !compatible {
+			report.Findings = append(report.Findings, Finding{
+				Kind: TypeMismatch, Severity: SeverityWarning, Table: c.Table, Column: c.Name, Field: c.Field,
+				Expected: strings.Join(c.Families, " or "), Actual: dataType,
+			})
+		}
+	}
+
+	for _, f := range report.Findings {
+		if f.Severity == SeverityCritical {
+			report.Critical++
+		}
+	}
+	report.OK = len(report.Findings) == 0
+	return report
+}
+
+// ColumnStore reads the column types of the database
+type ColumnStore interface {
+	// ListColumnTypes returns the data types of the columns of tables, by
+	// table and column name, leaving out tables that do not exist
+	ListColumnTypes(tables []string) (map[string]map[string]string, error)
+}
+
+// Check compares Models with the database and exports the number of
+// findings by severity as metrics
+func Check(store ColumnStore) (*Report, error) {
+	expected := Expected(Models)
+	actual, err := store.ListColumnTypes(Tables(expected))
+	if err != nil {
+		return nil, err
+	}
+	report := Compare(expected, actual, time.Now())
+
+	counts := map[string]int{SeverityCritical: 0, SeverityWarning: 0}
+	for _, f := range report.Findings {
+		counts[f.Severity]++
+	}
+	for severity, n := range counts {
+		metrics.SchemaDriftFindings.WithLabelValues(severity).Set(float64(n))
+	}
+	return report, nil
+}
@@ -0,0 +1,108 @@
+package schemadrift
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaTypes reads the column data types of the tables created by the
+// schema file, as information_schema would report them
+func schemaTypes(t *testing.T, path string) map[string]map[string]string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	createTable := regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \(`)
+	column := regexp.MustCompile(`^    (\w+) ([A-Z]+(?: PRECISION| WITH TIME ZONE)?)`) // Not constraints' nested lines
+	dataTypes := map[string]string{
+		"VARCHAR": "character varying", "CHAR": "character", "TEXT": "text", "UUID": "uuid",
+		"SMALLINT": "smallint", "INTEGER": "integer", "INT": "integer", "SERIAL": "integer",
+		"BIGINT": "bigint", "BIGSERIAL": "bigint", "REAL": "real", "DOUBLE PRECISION": "double precision",
+		"DECIMAL": "numeric", "NUMERIC": "numeric", "BOOLEAN": "boolean",
+		"TIMESTAMP": "timestamp without time zone", "TIMESTAMP WITH TIME ZONE": "timestamp with time zone",
+		"TIMESTAMPTZ": "timestamp with time zone", "DATE": "date", "JSON": "json", "JSONB": "jsonb",
+	}
+
+	types := make(map[string]map[string]string)
+	var table string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := createTable.FindStringSubmatch(line); m != nil {
+			table = m[1]
+			types[table] = make(map[string]string)
+			continue
+		}
+		if strings.HasPrefix(line, ");") {
+			table = ""
+			continue
+		}
+		if m := column.FindStringSubmatch(line); table != "" && m != nil {
+			dataType, ok := dataTypes[m[2]]
+			if !ok {
+				dataType = "USER-DEFINED"
+			}
+			types[table][m[1]] = dataType
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return types
+}
+
+func TestModelsMatchSchema(t *testing.T) {
+	types := schemaTypes(t, "../../../../sgi/sgi_schema.sql")
+	require.NotEmpty(t, types["placement_bookings"])
+
+	report := Compare(Expected(Models), types, time.Now())
+	for _, f := range report.Findings {
+		t.Errorf("sgi_schema.sql drifts from the models: %s", f)
+	}
+	assert.True(t, report.OK)
+	assert.Equal(t, 3, report.Tables)
+}
+
+func TestCompare(t *testing.T) {
+	expected := Expected([]Model{
+		{Table: "widgets", Model: struct {
+			ID       string    `db:"widget_id"`
+			Size     float64   `db:"size"`
+			Count    *int64    `db:"count"`
+			Made     time.Time `db:"made_at"`
+			Computed float64   `db:"-"`
+			Untagged bool
+		}{}},
+		{Table: "gadgets", Model: struct {
+			Active bool `db:"active"`
+		}{}},
+	})
+	require.Len(t, expected, 5)
+
+	report := Compare(expected, map[string]map[string]string{
+		"widgets": {"widget_id": "integer", "size": "text", "count": "bigint", "extra": "jsonb"},
+	}, time.Now())
+
+	assert.False(t, report.OK)
+	assert.Equal(t, 2, report.Tables)
+	assert.Equal(t, 2, report.Critical, "Missing tables and columns are critical")
+	byKind := map[string][]Finding{}
+	for _, f := range report.Findings {
+		byKind[f.Kind] = append(byKind[f.Kind], f)
+	}
+	require.Len(t, byKind[MissingTable], 1)
+	assert.Equal(t, "gadgets", byKind[MissingTable][0].Table)
+	require.Len(t, byKind[MissingColumn], 1)
+	assert.Equal(t, "made_at", byKind[MissingColumn][0].Column)
+	require.Len(t, byKind[TypeMismatch], 1, "IDs stored as integers are not drift")
+	mismatch := byKind[TypeMismatch][0]
+	assert.Equal(t, "size", mismatch.Column)
+	assert.Equal(t, SeverityWarning, mismatch.Severity)
+	assert.Equal(t, "text", mismatch.Actual)
+	assert.Contains(t, mismatch.String(), "widgets.size is text")
+}
@@ -0,0 +1,63 @@
+// Package sendqueue buffers the messages a streaming endpoint pushes to one
+// client, so a stalled client cannot make the API buffer without bound. The
+// queue holds a fixed number of messages. When a client too slow to keep up
+// lets it fill, the oldest message is dropped to make room and counted in
+// inscenium_push_frames_dropped_total, and the queue remembers that it
+// lagged, so the stream can tell the client to reload what it missed.
+package sendqueue
+
+import (
+	"sync/atomic"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+)
+
+// DefaultSize is how many messages may wait for a slow client before the
+// oldest are dropped
+const DefaultSize = 64
+
+// Queue is the send queue of one streaming connection. Push may be called
+// from any goroutine; a single writer takes messages from Messages.
+type Queue struct {
+	stream   string
+	messages chan interface{}
+	lagged   atomic.Bool // Messages were dropped since Lagged last reported it
+}
+
+// New creates a queue holding up to size messages for a client of stream,
+// which labels the dropped-frames metric
+func New(stream string, size int) *Queue {
+	return &Queue{stream: stream, messages: make(chan interface{}, size)}
+}
+
+// Push queues a message without waiting for the client. When the queue is
+// full its oldest message is dropped to make room.
+func (q *Queue) Push(message interface{}) {
+	for {
+		select {
+		case q.messages <- message:
+			return
+		default:
+		}
+		select {
+		case <-q.messages:
+			q.lagged.Store(true)
+			metrics.PushFramesDropped.WithLabelValues(q.stream).Inc()
+		default:
+			// The writer made room meanwhile
+		}
+	}
+}
+
+// Messages returns the channel the connection's writer takes queued
+// messages from
+func (q *Queue) Messages() <-chan interface{} {
+	return q.messages
+}
+
+// Lagged reports whether messages were dropped since it last returned true.
+// The writer checks it before each message, so the client learns it missed
+// messages ahead of what is left of the queue.
+func (q *Queue) Lagged() bool {
+	return q.lagged.Swap(false)
+}
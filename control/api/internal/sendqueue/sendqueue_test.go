@@ -0,0 +1,23 @@
+package sendqueue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue_DropsOldest(t *testing.T) {
+	q := New("test", 2)
+	q.Push(1)
+	q.Push(2)
+	assert.False(t, q.Lagged(), "Nothing is dropped while the queue has room")
+
+	q.Push(3)
+	q.Push(4)
+	assert.True(t, q.Lagged(), "A full queue makes room by dropping its oldest messages")
+	assert.False(t, q.Lagged(), "Lagged is reported once per drop")
+
+	assert.Equal(t, 3, <-q.Messages())
+	assert.Equal(t, 4, <-q.Messages())
+	assert.Empty(t, q.Messages())
+}
@@ -0,0 +1,166 @@
+package series
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSeriesNotFound is returned when a series does not exist
+var ErrSeriesNotFound = errors.New("series not found")
+
+// ErrEpisodeTaken is returned when another title already has a season and episode number
+var ErrEpisodeTaken = errors.New("season and episode number are already taken")
+
+// ErrNoSurfaces is returned when a series booking matches no bookable surface
+var ErrNoSurfaces = errors.New("no bookable surfaces match the series booking")
+
+// MaxFanOut bounds the surface bookings one series booking may create
+const MaxFanOut = 1000
+
+// maxIDLength matches the series and series_bookings ID columns
+const maxIDLength = 100
+
+// Reasons a matching surface was left out of a series booking
+const (
+	SkipHeldBack = "held_back"
+	SkipBooked   = "already_booked" // The campaign already has a live booking on it
+	SkipCollides = "collides"       // Booking it would break its shot's collision rule
+)
+
+// Series groups the episodes of a show
+type Series struct {
+	SeriesID  string    `json:"series_id"`
+	Name      string    `json:"name"`
+	Episodes  []Episode `json:"episodes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the series is well formed
+func (s *Series) Validate() error {
+	if s.SeriesID == "" || len(s.SeriesID) > maxIDLength {
+		return fmt.Errorf("series_id must be 1 to %d characters", maxIDLength)
+	}
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+// Episode places a title in a series
+type Episode struct {
+	TitleID       string `json:"title_id"`
+	SeriesID      string `json:"series_id"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	Title         string `json:"title,omitempty"`
+}
+
+// Validate checks that the episode is well formed
+func (e *Episode) Validate() error {
+	if e.SeasonNumber < 1 {
+		return fmt.Errorf("season_number must be at least 1")
+	}
+	if e.EpisodeNumber < 1 {
+		return fmt.Errorf("episode_number must be at least 1")
+	}
+	return nil
+}
+
+// Booking books a class of surface across every episode of a series, or of
+// one season. It fans out to a placement booking per matching surface.
+type Booking struct {
+	SeriesBookingID string    `json:"series_booking_id"`
+	SeriesID        string    `json:"series_id"`
+	SeasonNumber    *int      `json:"season_number,omitempty"` // Unset books every season
+	SurfaceType     string    `json:"surface_type"`
+	MinPRSScore     float64   `json:"min_prs_score"`
+	AdvertiserID    string    `json:"advertiser_id"`
+	CampaignID      string    `json:"campaign_id"`
+	BidAmountCPM    float64   `json:"bid_amount_cpm"`
+	MaxImpressions  int       `json:"max_impressions"` // Per surface booking
+	CreatedBy       string    `json:"created_by,omitempty"`
+	OrgID           string    `json:"-"`
+	Bookings        []Item    `json:"bookings"`
+	Skipped         []Skip    `json:"skipped,omitempty"` // Only reported when the booking is made
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Validate checks that the booking request is well formed
+func (b *Booking) Validate() error {
+	if b.SurfaceType == "" {
+		return fmt.Errorf("surface_type is required")
+	}
+	if b.AdvertiserID == "" || b.CampaignID == "" {
+		return fmt.Errorf("advertiser_id and campaign_id are required")
+	}
+	if b.BidAmountCPM <= 0 {
+		return fmt.Errorf("bid_amount_cpm must be positive")
+	}
+	if b.MaxImpressions < 0 {
+		return fmt.Errorf("max_impressions must not be negative")
+	}
+	if b.MinPRSScore < 0 || b.MinPRSScore > 100 {
+		return fmt.Errorf("min_prs_score must be between 0 and 100")
+	}
+	if b.SeasonNumber != nil && *b.SeasonNumber < 1 {
+		return fmt.Errorf("season_number must be at least 1")
+	}
+	return nil
+}
+
+// Item is one surface booking made by a series booking
+type Item struct {
+	BookingID     string `json:"booking_id"`
+	SurfaceID     string `json:"surface_id"`
+	TitleID       string `json:"title_id"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+}
+
+// Skip is a matching surface a series booking left out
+type Skip struct {
+	SurfaceID string `json:"surface_id"`
+	TitleID   string `json:"title_id"`
+	Reason    string `json:"reason"`
+}
+
+// EpisodeDelivery is what a series booking delivered in one episode
+type EpisodeDelivery struct {
+	TitleID       string  `json:"title_id"`
+	SeasonNumber  int     `json:"season_number"`
+	EpisodeNumber int     `json:"episode_number"`
+	Bookings      int     `json:"bookings"`
+	Impressions   int64   `json:"impressions"`
+	UniqueViewers int64   `json:"unique_viewers"`
+	ExposureTime  float64 `json:"total_exposure_time"`
+}
+
+// Totals is a series booking's delivery summed over its episodes
+type Totals struct {
+	Bookings     int     `json:"bookings"`
+	Impressions  int64   `json:"impressions"`
+	ExposureTime float64 `json:"total_exposure_time"`
+}
+
+// Delivery breaks a series booking's delivery down by episode
+type Delivery struct {
+	SeriesBookingID string            `json:"series_booking_id"`
+	Episodes        []EpisodeDelivery `json:"episodes"`
+	Totals          Totals            `json:"totals"`
+}
+
+// Summarize builds a delivery report from per-episode rows. Unique viewers
+// are not totalled since a viewer may watch several episodes.
+func Summarize(seriesBookingID string, episodes []EpisodeDelivery) *Delivery {
+	delivery := &Delivery{SeriesBookingID: seriesBookingID, Episodes: episodes}
+	if delivery.Episodes == nil {
+		delivery.Episodes = []EpisodeDelivery{}
+	}
+	for _, e := range episodes {
+		delivery.Totals.Bookings += e.Bookings
+		delivery.Totals.Impressions += e.Impressions
+		delivery.Totals.ExposureTime += e.ExposureTime
+	}
+	return delivery
+}
@@ -9,7 +9,7 @@ import (
 // Config holds server configuration
 type Config struct {
 	Database    *sql.DB
-	Redis       *redis.Client
+	Redis       redis.UniversalClient
 	JWTSecret   string
 	Environment string
 }
@@ -18,7 +18,7 @@ type Config struct {
 type Server struct {
 	config *Config
 	db     *sql.DB
-	redis  *redis.Client
+	redis  redis.UniversalClient
 }
 
 // New creates a new server instance
@@ -36,7 +36,7 @@ func (s *Server) Database() *sql.DB {
 }
 
 // Redis returns the redis connection
-func (s *Server) Redis() *redis.Client {
+func (s *Server) Redis() redis.UniversalClient {
 	return s.redis
 }
 
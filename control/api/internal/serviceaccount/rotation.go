@@ -0,0 +1,101 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Rotation defaults
+const (
+	DefaultRotationOverlap = 7 * 24 * time.Hour  // How long a rotated key stays valid
+	MaxRotationOverlap     = 90 * 24 * time.Hour // Longest overlap a rotation may ask for
+	DefaultExpiryWarning   = 24 * time.Hour      // How long before expiry a rotated key is announced
+	ExpiryCheckInterval    = time.Minute
+)
+
+// ErrAlreadyRotated is returned when rotating a key that already has a replacement
+var ErrAlreadyRotated = errors.New("key has already been rotated")
+
+// Rotation sets how rotated keys are retired
+type Rotation struct {
+	Overlap time.Duration // Default overlap of a rotated key with its replacement
+	Warning time.Duration // How long before a rotated key expires its expiry is announced
+}
+
+// DefaultRotation keeps rotated keys for a week and announces their expiry a day ahead
+func DefaultRotation() Rotation {
+	return Rotation{Overlap: DefaultRotationOverlap, Warning: DefaultExpiryWarning}
+}
+
+// Validate checks the default overlap and the warning
+func (r Rotation) Validate() error {
+	if err := ValidateOverlap(r.Overlap); err != nil {
+		return err
+	}
+	if r.Warning < 0 {
+		return fmt.Errorf("key expiry warning must not be negative")
+	}
+	return nil
+}
+
+// ValidateOverlap checks an overlap asked for when rotating a key
+func ValidateOverlap(overlap time.Duration) error {
+	if overlap <= 0 || overlap > MaxRotationOverlap {
+		return fmt.Errorf("overlap must be positive and at most %s", MaxRotationOverlap)
+	}
+	return nil
+}
+
+// ExpiryStore finds rotated keys about to expire
+type ExpiryStore interface {
+	// WarnExpiringServiceAccountKeys marks the active rotated keys expiring
+	// by before that were not announced yet and publishes their expiry,
+	// returning how many there were
+	WarnExpiringServiceAccountKeys(before time.Time) (int, error)
+}
+
+// ExpiryWorker announces rotated keys' expiry ahead of time, so partners
+// still using an old key hear about it before their requests start failing
+type ExpiryWorker struct {
+	store    ExpiryStore
+	warning  time.Duration
+	interval time.Duration
+}
+
+// NewExpiryWorker creates a worker announcing keys expiring within warning
+func NewExpiryWorker(store ExpiryStore, warning, interval time.Duration) *ExpiryWorker {
+	return &ExpiryWorker{store: store, warning: warning, interval: interval}
+}
+
+// Run checks immediately and then every interval until ctx is cancelled
+func (w *ExpiryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ExpiryWorker) check() {
+	warned, err := w.store.WarnExpiringServiceAccountKeys(time.Now().Add(w.warning))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to announce expiring service account keys")
+		return
+	}
+	if warned > 0 {
+		metrics.ServiceAccountKeyExpiryWarnings.Add(float64(warned))
+		logrus.WithField("keys", warned).Info("Announced expiring service account keys")
+	}
+}
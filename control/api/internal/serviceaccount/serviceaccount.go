@@ -0,0 +1,143 @@
+package serviceaccount
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// TokenPrefix marks service-account API keys in the Authorization header
+const TokenPrefix = "isk_"
+
+// Scopes that can be granted to a service account. A scope of the form
+// "bookings:*" covers every action on bookings, and "*" covers everything.
+var Scopes = []string{
+	"sgi:read",
+	"sgi:write",
+	"bookings:read",
+	"bookings:write",
+	"events:write",
+	"analytics:read",
+	"logs:read",
+	"metadata:read",
+	"metadata:write",
+	"grants:manage",
+	"publisher:read",
+	"render:read",
+	"manifests:decorate",
+	"inventory:read",
+	"inventory:write",
+	"encryption:manage",
+	"webhooks:manage",
+	"admin:read",
+}
+
+var knownScopes = func() map[string]bool {
+	known := map[string]bool{"*": true}
+	for _, scope := range Scopes {
+		known[scope] = true
+		resource, _, _ := strings.Cut(scope, ":")
+		known[resource+":*"] = true
+	}
+	return known
+}()
+
+// Account is a non-human principal such as a CI system or render worker
+type Account struct {
+	ID         string     `json:"account_id"`
+	Name       string     `json:"name"`
+	OrgID      string     `json:"org_id,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// PrincipalID is the identity recorded for the account in audit logs
+func (a *Account) PrincipalID() string {
+	return "sa:" + a.ID
+}
+
+// Key is a credential for a service account. Keys are numbered per account
+// in the order they were issued. A rotated key stays valid alongside its
+// replacement until it expires, so clients can roll over without downtime;
+// other keys stay valid until revoked.
+type Key struct {
+	ID         string     `json:"key_id"`
+	AccountID  string     `json:"account_id"`
+	Version    int        `json:"version"`
+	SecretHash string     `json:"-"`
+	JSONCase   string     `json:"json_case"`             // Request body case convention, see schema.CaseSnake
+	ReplacedBy string     `json:"replaced_by,omitempty"` // Key issued when this one was rotated
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Set when the key is rotated
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Expired reports whether a rotated key's overlap with its replacement has ended
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Active reports whether the key may still authenticate
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && !k.Expired(now)
+}
+
+// ValidScope reports whether scope can be granted
+func ValidScope(scope string) bool {
+	return knownScopes[scope]
+}
+
+// Allows reports whether the granted scopes include required
+func Allows(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, scope := range granted {
+		if scope == "*" || scope == required || scope == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateKey creates a new key ID and secret, returning the token to hand to
+// the client once and the hash to store
+func GenerateKey() (keyID, token, secretHash string, err error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	keyID = hex.EncodeToString(idBytes)
+	secret := hex.EncodeToString(secretBytes)
+	return keyID, TokenPrefix + keyID + "." + secret, HashSecret(secret), nil
+}
+
+// ParseToken splits a service-account token into its key ID and secret
+func ParseToken(token string) (keyID, secret string, ok bool) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return "", "", false
+	}
+	keyID, secret, ok = strings.Cut(strings.TrimPrefix(token, TokenPrefix), ".")
+	return keyID, secret, ok && keyID != "" && secret != ""
+}
+
+// HashSecret hashes a key secret for storage. Secrets are random 256-bit
+// values, so a fast hash is sufficient.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecret compares a presented secret against a stored hash in constant time
+func VerifySecret(secret, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(secretHash)) == 1
+}
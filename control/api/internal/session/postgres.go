@@ -0,0 +1,44 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Database keeps sessions in Postgres for gateways without Redis
+type Database interface {
+	SaveRefreshToken(ctx context.Context, tokenHash string, s *Session) error
+	TakeRefreshToken(ctx context.Context, tokenHash string) (*Session, error)
+	RevokeSession(ctx context.Context, sessionID string, until time.Time) error
+	SessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// PostgresStore keeps sessions in the refresh_tokens and revoked_sessions tables
+type PostgresStore struct {
+	db Database
+}
+
+// NewPostgresStore creates a session store backed by Postgres
+func NewPostgresStore(db Database) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Save stores s under the hash of its refresh token until it expires
+func (p *PostgresStore) Save(ctx context.Context, tokenHash string, s *Session) error {
+	return p.db.SaveRefreshToken(ctx, tokenHash, s)
+}
+
+// Take removes and returns the session of a refresh token
+func (p *PostgresStore) Take(ctx context.Context, tokenHash string) (*Session, error) {
+	return p.db.TakeRefreshToken(ctx, tokenHash)
+}
+
+// Revoke lists a session as revoked until until
+func (p *PostgresStore) Revoke(ctx context.Context, sessionID string, until time.Time) error {
+	return p.db.RevokeSession(ctx, sessionID, until)
+}
+
+// Revoked reports whether a session is on the revocation list
+func (p *PostgresStore) Revoked(ctx context.Context, sessionID string) (bool, error) {
+	return p.db.SessionRevoked(ctx, sessionID)
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps sessions in Redis, expiring with them
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a session store backed by Redis
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func refreshKey(tokenHash string) string {
+	return "session:refresh:{" + tokenHash + "}"
+}
+
+func revokedKey(sessionID string) string {
+	return "session:revoked:{" + sessionID + "}"
+}
+
+// Save stores s under the hash of its refresh token until it expires
+func (r *RedisStore) Save(ctx context.Context, tokenHash string, s *Session) error {
+	value, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := r.client.Set(ctx, refreshKey(tokenHash), value, time.Until(s.ExpiresAt)).Err(); err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// Take removes and returns the session of a refresh token
+func (r *RedisStore) Take(ctx context.Context, tokenHash string) (*Session, error) {
+	value, err := r.client.GetDel(ctx, refreshKey(tokenHash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take refresh token: %w", err)
+	}
+
+	var s Session
+	if err := json.Unmarshal(value, &s); err != nil {
+		return nil, fmt.Errorf("session of refresh token is corrupt: %w", err)
+	}
+	if s.Expired(time.Now()) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// Revoke lists a session as revoked until until
+func (r *RedisStore) Revoke(ctx context.Context, sessionID string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := r.client.Set(ctx, revokedKey(sessionID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// Revoked reports whether a session is on the revocation list
+func (r *RedisStore) Revoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := r.client.Exists(ctx, revokedKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check session revocation: %w", err)
+	}
+	return n > 0, nil
+}
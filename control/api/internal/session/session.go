@@ -0,0 +1,106 @@
+// Package session keeps users signed in with short-lived access tokens and
+// long-lived refresh tokens. A login starts a session; its refresh token is
+// exchanged for a new pair of tokens until the session expires or the user
+// signs out, which revokes the session and so its access tokens as well.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenPrefix marks refresh tokens, which are opaque
+const TokenPrefix = "irt_"
+
+const (
+	// DefaultAccessTTL is how long an access token is valid
+	DefaultAccessTTL = 15 * time.Minute
+	// DefaultRefreshTTL is how long a session lasts from its login
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// Lifetimes of the tokens of a session
+type Lifetimes struct {
+	Access  time.Duration
+	Refresh time.Duration
+}
+
+// DefaultLifetimes issues 15-minute access tokens for sessions of 7 days
+func DefaultLifetimes() Lifetimes {
+	return Lifetimes{Access: DefaultAccessTTL, Refresh: DefaultRefreshTTL}
+}
+
+// Validate checks that refresh tokens outlive the access tokens they renew
+func (l Lifetimes) Validate() error {
+	if l.Access <= 0 {
+		return fmt.Errorf("access token lifetime must be positive")
+	}
+	if l.Refresh <= l.Access {
+		return fmt.Errorf("refresh token lifetime must be longer than the access token lifetime")
+	}
+	return nil
+}
+
+// Session is what a refresh token stands for
+type Session struct {
+	ID        string    `json:"session_id"`
+	Username  string    `json:"username"`
+	OrgID     string    `json:"org_id,omitempty"` // Organization chosen at login; empty for the user's own
+	ExpiresAt time.Time `json:"expires_at"`       // Fixed at login; refreshing does not extend it
+}
+
+// Expired reports whether the session has ended
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Store keeps sessions shared by every gateway instance
+type Store interface {
+	// Save stores s under the hash of its current refresh token until it expires
+	Save(ctx context.Context, tokenHash string, s *Session) error
+	// Take removes and returns the session of a refresh token, so each token
+	// is used once. It returns nil for unknown, used and expired tokens.
+	Take(ctx context.Context, tokenHash string) (*Session, error)
+	// Revoke lists a session as revoked until until, when the last of its
+	// access tokens has expired
+	Revoke(ctx context.Context, sessionID string, until time.Time) error
+	// Revoked reports whether a session is on the revocation list
+	Revoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// NewID creates a session ID
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateToken creates a refresh token, returning the token to hand to the
+// client and the hash to store
+func GenerateToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = TokenPrefix + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a refresh token for storage. Tokens are random 256-bit
+// values, so a fast hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidToken reports whether token looks like a refresh token
+func ValidToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix) && len(token) == len(TokenPrefix)+64
+}
@@ -0,0 +1,239 @@
+package settings
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of a configuration value, in increasing precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// redacted replaces secret values in Settings
+const redacted = "[redacted]"
+
+// secretMarkers flag keys whose values must never be shown
+var secretMarkers = []string{"SECRET", "PASSWORD", "TOKEN", "_KEY", "_KEYS"}
+
+// Setting is one resolved configuration value and where it came from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+	// Invalid is set when the configured value could not be parsed and the
+	// default was used instead
+	Invalid bool `json:"invalid,omitempty"`
+}
+
+// Loader resolves configuration from the environment, then an optional
+// KEY=VALUE file, then defaults, and remembers every value it resolved so
+// the effective configuration can be inspected at runtime
+type Loader struct {
+	path     string
+	file     map[string]string
+	exported map[string]bool
+	lookup   func(string) (string, bool)
+
+	mu       sync.Mutex
+	settings map[string]Setting
+}
+
+// Load reads the config file at path, if any, and resolves against the
+// process environment
+func Load(path string) (*Loader, error) {
+	file := map[string]string{}
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	loader := NewLoader(file, os.LookupEnv)
+	loader.path = path
+	return loader, nil
+}
+
+// NewLoader creates a loader over file values and an environment lookup
+func NewLoader(file map[string]string, lookup func(string) (string, bool)) *Loader {
+	return &Loader{file: file, exported: map[string]bool{}, lookup: lookup, settings: map[string]Setting{}}
+}
+
+// readFile parses KEY=VALUE lines; blank lines and # comments are skipped and
+// values may be quoted
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return values, nil
+}
+
+// Path is the config file the loader read, if any
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// Export copies file values into the process environment where it has none,
+// so packages that read their settings from the environment see them too
+func (l *Loader) Export() error {
+	for key, value := range l.file {
+		if current, ok := l.lookup(key); ok && current != "" {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to export %s: %w", key, err)
+		}
+		l.exported[key] = true
+	}
+	return nil
+}
+
+// resolve finds the configured value of key. Empty values count as unset,
+// as they always have for this service.
+func (l *Loader) resolve(key string) (string, string) {
+	if value, ok := l.lookup(key); ok && value != "" && !l.exported[key] {
+		return value, SourceEnv
+	}
+	if value := l.file[key]; value != "" {
+		return value, SourceFile
+	}
+	return "", SourceDefault
+}
+
+func (l *Loader) record(setting Setting) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings[setting.Key] = setting
+}
+
+// String resolves a string setting
+func (l *Loader) String(key, defaultValue string) string {
+	value, source := l.resolve(key)
+	if source == SourceDefault {
+		value = defaultValue
+	}
+	l.record(Setting{Key: key, Value: value, Source: source})
+	return value
+}
+
+// Int resolves an integer setting; unparsable values fall back to the default
+func (l *Loader) Int(key string, defaultValue int) int {
+	return parse(l, key, defaultValue, strconv.Atoi, strconv.Itoa)
+}
+
+// Int64 resolves a 64-bit integer setting
+func (l *Loader) Int64(key string, defaultValue int64) int64 {
+	return parse(l, key, defaultValue, func(s string) (int64, error) {
+		return strconv.ParseInt(s, 10, 64)
+	}, func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	})
+}
+
+// Float resolves a floating point setting
+func (l *Loader) Float(key string, defaultValue float64) float64 {
+	return parse(l, key, defaultValue, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	}, func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	})
+}
+
+// Duration resolves a duration setting such as "30s"
+func (l *Loader) Duration(key string, defaultValue time.Duration) time.Duration {
+	return parse(l, key, defaultValue, time.ParseDuration, time.Duration.String)
+}
+
+func parse[T any](l *Loader, key string, defaultValue T, parseValue func(string) (T, error), format func(T) string) T {
+	raw, source := l.resolve(key)
+	if source == SourceDefault {
+		l.record(Setting{Key: key, Value: format(defaultValue), Source: source})
+		return defaultValue
+	}
+	value, err := parseValue(raw)
+	if err != nil {
+		l.record(Setting{Key: key, Value: format(defaultValue), Source: SourceDefault, Invalid: true})
+		return defaultValue
+	}
+	l.record(Setting{Key: key, Value: format(value), Source: source})
+	return value
+}
+
+// Observe records a setting that another package reads from the
+// environment itself. Its default is not known here, so an unset value is
+// reported empty.
+func (l *Loader) Observe(keys ...string) {
+	for _, key := range keys {
+		value, source := l.resolve(key)
+		l.record(Setting{Key: key, Value: value, Source: source})
+	}
+}
+
+// Settings lists every value resolved so far, sorted by key, with secrets
+// redacted and passwords removed from URLs
+func (l *Loader) Settings() []Setting {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	settings := make([]Setting, 0, len(l.settings))
+	for _, setting := range l.settings {
+		if IsSecret(setting.Key) {
+			setting.Secret = true
+			if setting.Value != "" {
+				setting.Value = redacted
+			}
+		} else if u, err := url.Parse(setting.Value); err == nil && u.User != nil {
+			setting.Value = u.Redacted()
+		}
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// IsSecret reports whether the value of key must be redacted
+func IsSecret(key string) bool {
+	key = strings.ToUpper(key)
+	for _, marker := range secretMarkers {
+		if strings.Contains(key, marker) && !strings.HasSuffix(key, "_KEY_ID") {
+			return true
+		}
+	}
+	return false
+}
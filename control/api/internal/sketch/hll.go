@@ -0,0 +1,109 @@
+// Package sketch estimates how many distinct values a stream holds without
+// keeping them. A HyperLogLog is a few kilobytes whatever the stream's
+// size, and sketches of different streams merge into the sketch of their
+// union, so distinct viewers can be stored per day and counted over any
+// range of days.
+package sketch
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Precision is the number of hash bits choosing a register. 2^12 registers
+// give a standard error of about 1.6%.
+const Precision = 12
+
+// StandardError is the relative standard error of an estimate
+var StandardError = 1.04 / math.Sqrt(registers)
+
+const (
+	registers = 1 << Precision
+	version   = 1
+)
+
+// ErrInvalid is returned when decoding bytes that are not a sketch
+var ErrInvalid = errors.New("invalid sketch")
+
+// HLL is a HyperLogLog sketch of distinct strings. The zero value is not
+// usable; create sketches with New.
+type HLL struct {
+	registers []uint8
+}
+
+// New returns an empty sketch
+func New() *HLL {
+	return &HLL{registers: make([]uint8, registers)}
+}
+
+// Add adds a value to the sketch
+func (h *HLL) Add(value string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	x := mix(hasher.Sum64())
+
+	index := x >> (64 - Precision)
+	// Rank of the first set bit of the remaining bits, from 1
+	rank := uint8(bits.LeadingZeros64(x<<Precision|1<<(Precision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Merge folds other into the sketch, which then estimates their union
+func (h *HLL) Merge(other *HLL) {
+	for i, rank := range other.registers {
+		if rank > h.registers[i] {
+			h.registers[i] = rank
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct values added
+func (h *HLL) Estimate() int64 {
+	sum := 0.0
+	zeros := 0
+	for _, rank := range h.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+	m := float64(registers)
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Small cardinalities are counted more precisely by the empty registers
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// MarshalBinary encodes the sketch as a version byte and its registers
+func (h *HLL) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 1+registers)
+	data = append(data, version)
+	return append(data, h.registers...), nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary
+func (h *HLL) UnmarshalBinary(data []byte) error {
+	if len(data) != 1+registers || data[0] != version {
+		return ErrInvalid
+	}
+	h.registers = append(make([]uint8, 0, registers), data[1:]...)
+	return nil
+}
+
+// mix spreads FNV's bits over the whole word (the splitmix64 finalizer), as
+// HyperLogLog needs uniformly distributed hashes
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,58 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func fill(from, to int) *HLL {
+	h := New()
+	for i := from; i < to; i++ {
+		h.Add(fmt.Sprintf("viewer_%d", i))
+	}
+	return h
+}
+
+func TestEstimate(t *testing.T) {
+	if got := New().Estimate(); got != 0 {
+		t.Errorf("Expected an empty sketch to estimate 0, got %d", got)
+	}
+	for _, n := range []int{1, 10, 1000, 50000, 1000000} {
+		h := fill(0, n)
+		// Adding values again does not change the estimate
+		h.Merge(fill(0, n/2))
+		got := h.Estimate()
+		if err := math.Abs(float64(got)-float64(n)) / float64(n); err > 4*StandardError {
+			t.Errorf("%d values: estimated %d, %.2f%% off", n, got, err*100)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := fill(0, 60000), fill(40000, 100000)
+	union := New()
+	union.Merge(a)
+	union.Merge(b)
+	if err := math.Abs(float64(union.Estimate())-100000) / 100000; err > 4*StandardError {
+		t.Errorf("Expected the union of 100000 values, got %d", union.Estimate())
+	}
+	if a.Estimate() > union.Estimate() {
+		t.Error("Merging should not change the merged sketch")
+	}
+
+	data, err := union.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := New()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Estimate() != union.Estimate() {
+		t.Errorf("Expected %d after decoding, got %d", union.Estimate(), decoded.Estimate())
+	}
+	if err := decoded.UnmarshalBinary(data[:100]); err != ErrInvalid {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package status
+
+import (
+	"context"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/ingest"
+	"github.com/sirupsen/logrus"
+)
+
+// Thresholds at which components are degraded or out
+const (
+	// ingestionTitleLimit bounds the unpublished titles checked per probe
+	ingestionTitleLimit = 10000
+	// Share of unpublished titles stuck in a stage at which ingestion is out.
+	// Failed titles are left out: they wait on a fixed upload, not on us.
+	ingestionOutageShare = 0.5
+	ingestionMinTitles   = 5
+
+	// decisionWindow is how far back decision outcomes are counted
+	decisionWindow = 5 * time.Minute
+	// Shares of decisions ending in a serve error at which decisioning is
+	// degraded or out, over at least decisionMinCount decisions
+	decisionDegradedShare = 0.05
+	decisionOutageShare   = 0.5
+	decisionMinCount      = 20
+
+	// exportWindow is how far back finished report jobs are counted
+	exportWindow = time.Hour
+	// exportOverdueAfter is how long a report job may wait to be picked up
+	exportOverdueAfter = 15 * time.Minute
+	// Shares of finished report jobs that failed at which exports are
+	// degraded or out, over at least exportMinJobs jobs
+	exportDegradedShare = 0.1
+	exportOutageShare   = 0.5
+	exportMinJobs       = 2
+)
+
+// ReportJobCounts counts report jobs finished in a window and those overdue
+type ReportJobCounts struct {
+	Completed int
+	Failed    int
+	Overdue   int // Pending longer than they should be
+}
+
+// Store reads the internal signals component statuses are derived from
+type Store interface {
+	PingContext(ctx context.Context) error
+	ListOpenIngestions(limit int) (map[string][]ingest.StageState, error)
+	CountDecisionOutcomes(since time.Time) (total, errors int, err error)
+	CountReportJobs(since, overdueBefore time.Time) (*ReportJobCounts, error)
+}
+
+// Prober derives the status of every component from internal signals
+type Prober struct {
+	store      Store
+	redis      func(ctx context.Context) error
+	stuckAfter time.Duration
+}
+
+// NewProber creates a prober. Titles in a pipeline stage longer than
+// stuckAfter count as stuck.
+func NewProber(store Store, stuckAfter time.Duration) *Prober {
+	return &Prober{store: store, stuckAfter: stuckAfter}
+}
+
+// SetRedis checks Redis as part of decisioning, which keeps impression caps
+// and budgets there
+func (p *Prober) SetRedis(ping func(ctx context.Context) error) {
+	p.redis = ping
+}
+
+// Probe checks every component
+func (p *Prober) Probe(ctx context.Context, now time.Time) map[string]string {
+	return map[string]string{
+		ComponentAPI:         p.api(ctx),
+		ComponentIngestion:   p.ingestion(now),
+		ComponentDecisioning: p.decisioning(ctx, now),
+		ComponentExports:     p.exports(now),
+	}
+}
+
+func (p *Prober) api(ctx context.Context) string {
+	if err := p.store.PingContext(ctx); err != nil {
+		logrus.WithError(err).Warn("Status probe: database is unreachable")
+		return StatusOutage
+	}
+	return StatusOperational
+}
+
+func (p *Prober) ingestion(now time.Time) string {
+	titles, err := p.store.ListOpenIngestions(ingestionTitleLimit)
+	if err != nil {
+		logrus.WithError(err).Warn("Status probe: failed to list open ingestions")
+		return StatusOutage
+	}
+
+	stuck := 0
+	for titleID, stages := range titles {
+		if ingest.NewProgress(titleID, stages, p.stuckAfter, now).State == ingest.StateStuck {
+			stuck++
+		}
+	}
+
+	switch {
+	case len(titles) >= ingestionMinTitles && float64(stuck) >= ingestionOutageShare*float64(len(titles)):
+		logrus.WithFields(logrus.Fields{"stuck": stuck, "open": len(titles)}).Warn("Status probe: ingestion is out")
+		return StatusOutage
+	case stuck > 0:
+		logrus.WithFields(logrus.Fields{"stuck": stuck, "open": len(titles)}).Warn("Status probe: ingestion is degraded")
+		return StatusDegraded
+	}
+	return StatusOperational
+}
+
+func (p *Prober) decisioning(ctx context.Context, now time.Time) string {
+	total, errors, err := p.store.CountDecisionOutcomes(now.Add(-decisionWindow))
+	if err != nil {
+		logrus.WithError(err).Warn("Status probe: failed to count decision outcomes")
+		return StatusOutage
+	}
+
+	status := StatusOperational
+	if total >= decisionMinCount {
+		share := float64(errors) / float64(total)
+		switch {
+		case share >= decisionOutageShare:
+			status = StatusOutage
+		case share >= decisionDegradedShare:
+			status = StatusDegraded
+		}
+	}
+	if status != StatusOperational {
+		logrus.WithFields(logrus.Fields{"decisions": total, "errors": errors}).Warnf("Status probe: decisioning is %s", status)
+	}
+
+	if p.redis != nil && status == StatusOperational {
+		if err := p.redis(ctx); err != nil {
+			logrus.WithError(err).Warn("Status probe: Redis is unreachable")
+			status = StatusDegraded
+		}
+	}
+	return status
+}
+
+func (p *Prober) exports(now time.Time) string {
+	counts, err := p.store.CountReportJobs(now.Add(-exportWindow), now.Add(-exportOverdueAfter))
+	if err != nil {
+		logrus.WithError(err).Warn("Status probe: failed to count report jobs")
+		return StatusOutage
+	}
+
+	status := StatusOperational
+	finished := counts.Completed + counts.Failed
+	if finished >= exportMinJobs {
+		share := float64(counts.Failed) / float64(finished)
+		switch {
+		case share >= exportOutageShare:
+			status = StatusOutage
+		case share >= exportDegradedShare:
+			status = StatusDegraded
+		}
+	}
+	if counts.Overdue > 0 && status == StatusOperational {
+		status = StatusDegraded
+	}
+	if status != StatusOperational {
+		logrus.WithFields(logrus.Fields{
+			"completed": counts.Completed,
+			"failed":    counts.Failed,
+			"overdue":   counts.Overdue,
+		}).Warnf("Status probe: exports are %s", status)
+	}
+	return status
+}
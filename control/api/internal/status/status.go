@@ -0,0 +1,193 @@
+// Package status records the health of the platform's partner-facing
+// components over time and summarizes it for a public status page. Only
+// statuses are kept: what went wrong is logged, never published.
+package status
+
+import (
+	"sort"
+	"time"
+)
+
+// Components partners depend on, in the order they are listed
+const (
+	ComponentAPI         = "api"
+	ComponentIngestion   = "ingestion"
+	ComponentDecisioning = "decisioning"
+	ComponentExports     = "exports"
+)
+
+// Components lists the components a status page shows
+var Components = []string{ComponentAPI, ComponentIngestion, ComponentDecisioning, ComponentExports}
+
+// Descriptions of the components for partners
+var descriptions = map[string]string{
+	ComponentAPI:         "Booking, inventory and analytics API",
+	ComponentIngestion:   "Title processing from upload to published inventory",
+	ComponentDecisioning: "Placement decisions at delivery time",
+	ComponentExports:     "Asynchronous report exports",
+}
+
+// Statuses of a component, from best to worst. Unknown means no recent
+// check, e.g. while the history is empty.
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusOutage      = "outage"
+	StatusUnknown     = "unknown"
+)
+
+// Worse reports whether status a is worse than b
+func Worse(a, b string) bool {
+	return rank(a) > rank(b)
+}
+
+func rank(status string) int {
+	switch status {
+	case StatusOperational:
+		return 0
+	case StatusUnknown:
+		return 1
+	case StatusDegraded:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// UptimeWindows are the periods uptime is reported over, keyed by name
+var UptimeWindows = []Window{
+	{Name: "24h", Duration: 24 * time.Hour},
+	{Name: "7d", Duration: 7 * 24 * time.Hour},
+	{Name: "30d", Duration: 30 * 24 * time.Hour},
+}
+
+// Window is a period uptime is reported over
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Retention is how long checks are kept, the longest uptime window
+const Retention = 30 * 24 * time.Hour
+
+// IncidentWindow is how far back incidents are listed
+const IncidentWindow = 7 * 24 * time.Hour
+
+// Check is the status of a component at one point in time. Checks are
+// aligned to the check interval so instances checking the same component
+// share one record, which keeps the worst status reported.
+type Check struct {
+	Component string    `json:"component"`
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Tally counts a component's checks over a window
+type Tally struct {
+	Checks  int
+	Outages int
+}
+
+// Uptime is the share of checks not in outage, as a percentage. Degraded
+// service counts as up. Nil when there were no checks.
+func (t Tally) Uptime() *float64 {
+	if t.Checks == 0 {
+		return nil
+	}
+	uptime := 100 * float64(t.Checks-t.Outages) / float64(t.Checks)
+	return &uptime
+}
+
+// Incident is a run of checks in which a component was not operational
+type Incident struct {
+	Status     string     `json:"status"` // The worst status during the incident
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"` // Nil while ongoing
+}
+
+// Component is one component's current status and history
+type Component struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Status      string              `json:"status"`
+	CheckedAt   *time.Time          `json:"checked_at,omitempty"`
+	Uptime      map[string]*float64 `json:"uptime"` // Window name -> percentage, null without checks
+	Incidents   []Incident          `json:"incidents"`
+}
+
+// Summary is the status page: every component and the worst of their statuses
+type Summary struct {
+	Status      string      `json:"status"`
+	Components  []Component `json:"components"`
+	GeneratedAt time.Time   `json:"generated_at"`
+}
+
+// Summarize builds the status page from checks since IncidentWindow ago,
+// oldest first, and the tallies of each uptime window. A component whose last
+// check is older than staleAfter is unknown.
+func Summarize(checks []Check, tallies map[string]map[string]Tally, staleAfter time.Duration, now time.Time) *Summary {
+	byComponent := make(map[string][]Check, len(Components))
+	for _, check := range checks {
+		byComponent[check.Component] = append(byComponent[check.Component], check)
+	}
+
+	summary := &Summary{Status: StatusOperational, Components: make([]Component, 0, len(Components)), GeneratedAt: now}
+	for _, name := range Components {
+		history := byComponent[name]
+		sort.SliceStable(history, func(i, j int) bool { return history[i].CheckedAt.Before(history[j].CheckedAt) })
+
+		component := Component{
+			Name:        name,
+			Description: descriptions[name],
+			Status:      StatusUnknown,
+			Uptime:      make(map[string]*float64, len(UptimeWindows)),
+			Incidents:   incidents(history),
+		}
+		if len(history) > 0 {
+			last := history[len(history)-1]
+			component.CheckedAt = &last.CheckedAt
+			if now.Sub(last.CheckedAt) <= staleAfter {
+				component.Status = last.Status
+			}
+		}
+		for _, window := range UptimeWindows {
+			component.Uptime[window.Name] = tallies[window.Name][name].Uptime()
+		}
+
+		if Worse(component.Status, summary.Status) {
+			summary.Status = component.Status
+		}
+		summary.Components = append(summary.Components, component)
+	}
+	return summary
+}
+
+// incidents groups consecutive checks that were not operational, newest first
+func incidents(history []Check) []Incident {
+	list := make([]Incident, 0)
+	var open *Incident
+	for _, check := range history {
+		if check.Status == StatusOperational {
+			if open != nil {
+				resolvedAt := check.CheckedAt
+				open.ResolvedAt = &resolvedAt
+				list = append(list, *open)
+				open = nil
+			}
+			continue
+		}
+		if open == nil {
+			open = &Incident{Status: check.Status, StartedAt: check.CheckedAt}
+		} else if Worse(check.Status, open.Status) {
+			open.Status = check.Status
+		}
+	}
+	if open != nil {
+		list = append(list, *open)
+	}
+
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
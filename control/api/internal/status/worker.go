@@ -0,0 +1,85 @@
+package status
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxBuffered bounds the checks kept in memory while the history cannot be
+// written, e.g. during a database outage
+const maxBuffered = 10000
+
+// pruneInterval is how often checks older than Retention are removed
+const pruneInterval = time.Hour
+
+// HistoryStore keeps the checks of every component
+type HistoryStore interface {
+	RecordHealthChecks(checks []Check) error
+	PruneHealthChecks(before time.Time) (int64, error)
+}
+
+// Worker probes every component on a schedule and records the statuses in
+// the history the status page is built from
+type Worker struct {
+	prober   *Prober
+	history  HistoryStore
+	interval time.Duration
+	buffered []Check
+	prunedAt time.Time
+}
+
+// NewWorker creates a status worker
+func NewWorker(prober *Prober, history HistoryStore, interval time.Duration) *Worker {
+	return &Worker{prober: prober, history: history, interval: interval}
+}
+
+// Run probes immediately and then every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) check(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, w.interval)
+	defer cancel()
+
+	now := time.Now().UTC()
+	checkedAt := now.Truncate(w.interval)
+	statuses := w.prober.Probe(checkCtx, now)
+	for _, component := range Components {
+		w.buffered = append(w.buffered, Check{Component: component, Status: statuses[component], CheckedAt: checkedAt})
+	}
+	if len(w.buffered) > maxBuffered {
+		w.buffered = w.buffered[len(w.buffered)-maxBuffered:]
+	}
+
+	// Checks made while the history was unavailable are recorded once it is
+	// back, so outages of the database itself show in the history
+	if err := w.history.RecordHealthChecks(w.buffered); err != nil {
+		logrus.WithError(err).WithField("buffered", len(w.buffered)).Error("Failed to record component health checks")
+		return
+	}
+	w.buffered = w.buffered[:0]
+
+	if now.Sub(w.prunedAt) >= pruneInterval {
+		pruned, err := w.history.PruneHealthChecks(now.Add(-Retention))
+		if err != nil {
+			logrus.WithError(err).Error("Failed to prune component health checks")
+			return
+		}
+		w.prunedAt = now
+		logrus.WithField("pruned", pruned).Debug("Pruned component health checks")
+	}
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// azureVersion is the Blob service REST API version requests are made against
+const azureVersion = "2021-08-06"
+
+// Azure stores objects as block blobs in an Azure Blob Storage container.
+// Requests are signed with the account's Shared Key.
+type Azure struct {
+	client    *outbound.Client
+	endpoint  *url.URL
+	account   string
+	key       []byte
+	container string
+}
+
+// NewAzure creates an Azure Blob store. Without Endpoint it addresses
+// <account>.blob.core.windows.net.
+func NewAzure(config Config) (*Azure, error) {
+	if config.AzureAccount == "" || config.AzureKey == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("azure storage needs an account, key and container")
+	}
+	key, err := base64.StdEncoding.DecodeString(config.AzureKey)
+	if err != nil {
+		return nil, fmt.Errorf("azure storage key must be base64: %w", err)
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + config.AzureAccount + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	return &Azure{
+		client:    outbound.New("storage_azure", outbound.DefaultPolicy()),
+		endpoint:  u,
+		account:   config.AzureAccount,
+		key:       key,
+		container: config.Bucket,
+	}, nil
+}
+
+// do signs and sends a request for key
+func (a *Azure) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	u := *a.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + a.container + "/" + key
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.GetBody = nil
+		req.ContentLength = 0
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+	if method == http.MethodPut {
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	a.sign(req, &u)
+	return a.client.Do(req)
+}
+
+// sign adds a Shared Key Authorization header
+func (a *Azure) sign(req *http.Request, u *url.URL) {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(req.Header.Get(name)))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	toSign := strings.Join([]string{
+		req.Method,
+		"", // Content-Encoding
+		"", // Content-Language
+		length,
+		"", // Content-MD5
+		req.Header.Get("Content-Type"),
+		"",                 // Date; x-ms-date is used instead
+		"", "", "", "", "", // If-* and Range
+		strings.Join(msHeaders, "\n"),
+		"/" + a.account + u.EscapedPath(),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(toSign))
+	req.Header.Set("Authorization", "SharedKey "+a.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// Put uploads a block blob
+func (a *Azure) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if data == nil {
+		data = []byte{}
+	}
+	resp, err := a.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "store")
+}
+
+// Get downloads a blob
+func (a *Azure) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := a.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if err := checkStatus(resp, "read"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes a blob
+func (a *Azure) Delete(ctx context.Context, key string) error {
+	resp, err := a.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "delete")
+}
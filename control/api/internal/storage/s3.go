@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+)
+
+// unsignedPayload replaces the payload hash of bodies streamed rather than
+// hashed up front, and of presigned URLs
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// maxSignedURLExpiry is the longest validity Signature V4 allows a presigned URL
+const maxSignedURLExpiry = 7 * 24 * time.Hour
+
+// gcsEndpoint is the GCS XML API, which accepts S3-style requests signed with HMAC keys
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// S3 stores objects in an S3 bucket, or any service speaking the S3 API
+// such as GCS's XML API or MinIO. Requests are signed with Signature V4.
+type S3 struct {
+	client    *outbound.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool // Bucket in the path rather than the host
+}
+
+// NewS3 creates an S3 store. Without Endpoint it addresses
+// <bucket>.s3.<region>.amazonaws.com; with one it uses path-style URLs.
+func NewS3(config Config) (*S3, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 storage needs a bucket and access keys")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := config.Endpoint
+	pathStyle := endpoint != ""
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	return newS3(outbound.New("storage_s3", outbound.DefaultPolicy()), endpoint, pathStyle, region, config)
+}
+
+// NewGCS creates a Google Cloud Storage store. It uses the XML API with the
+// HMAC key of a service account, so no Google SDK is needed.
+func NewGCS(config Config) (*S3, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("gcs storage needs a bucket and an HMAC key")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = gcsEndpoint
+	}
+	return newS3(outbound.New("storage_gcs", outbound.DefaultPolicy()), endpoint, true, "auto", config)
+}
+
+func newS3(client *outbound.Client, endpoint string, pathStyle bool, region string, config Config) (*S3, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	return &S3{
+		client:    client,
+		endpoint:  u,
+		bucket:    config.Bucket,
+		region:    region,
+		accessKey: config.AccessKeyID,
+		secretKey: config.SecretAccessKey,
+		pathStyle: pathStyle,
+	}, nil
+}
+
+// objectURL addresses key in the bucket
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+// do signs and sends a request for key
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	u := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.GetBody = nil
+		req.ContentLength = 0
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, u, sha256Hex(body), time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds a Signature V4 Authorization header
+func (s *S3) sign(req *http.Request, u *url.URL, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		"",
+		"host:" + u.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	signature := s.signature(amzDate, date, scope, canonical)
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// signature signs a canonical request with the key derived for date
+func (s *S3) signature(amzDate, date, scope, canonical string) string {
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// SignedURL presigns a GET of key with Signature V4 query parameters
+func (s *S3) SignedURL(key string, expires time.Duration) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	if expires < time.Second || expires > maxSignedURLExpiry {
+		return "", fmt.Errorf("signed URLs must expire within %s", maxSignedURLExpiry)
+	}
+	return s.presign(key, expires, time.Now().UTC()), nil
+}
+
+// presign returns a Signature V4 presigned GET URL of key signed at now
+func (s *S3) presign(key string, expires time.Duration, now time.Time) string {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+
+	u := s.objectURL(key)
+	params := url.Values{}
+	params.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	params.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	params.Set("X-Amz-Date", amzDate)
+	params.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	params.Set("X-Amz-SignedHeaders", "host")
+	query := strings.ReplaceAll(params.Encode(), "+", "%20")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		query,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery = query + "&X-Amz-Signature=" + s.signature(amzDate, date, scope, canonical)
+	return u.String()
+}
+
+// Upload streams an object from r. The body is sent unsigned, so it is
+// read once rather than hashed up front.
+func (s *S3) Upload(ctx context.Context, key string, r io.ReaderAt, size int64, contentType string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	u := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(r, 0, size)), nil
+	}
+	if size == 0 {
+		req.Body = http.NoBody
+		req.GetBody = nil
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, u, unsignedPayload, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "store")
+}
+
+// Put uploads an object
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if data == nil {
+		data = []byte{}
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "store")
+}
+
+// Get downloads an object
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if err := checkStatus(resp, "read"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an object. S3 does not report missing objects on delete.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "delete")
+}
+
+// checkStatus turns a failed object request into an error
+func checkStatus(resp *http.Response, action string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to %s object: status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
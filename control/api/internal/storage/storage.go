@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when reading or deleting an object that does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrNotSignable is returned by SignedURL for drivers that cannot issue
+// signed URLs
+var ErrNotSignable = errors.New("storage driver cannot sign URLs")
+
+// Storage drivers
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+	DriverGCS   = "gcs"
+	DriverAzure = "azure"
+)
+
+// Store keeps blobs such as report exports, creatives, masks and previews
+// under slash-separated keys. Every module that stores files goes through
+// it so deployments can pick the backend for their cloud.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens an object for reading; callers must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Uploader is implemented by drivers that stream an object of known size
+// rather than holding it in memory
+type Uploader interface {
+	Upload(ctx context.Context, key string, r io.ReaderAt, size int64, contentType string) error
+}
+
+// URLSigner is implemented by drivers that issue time-limited URLs reading
+// an object without credentials
+type URLSigner interface {
+	SignedURL(key string, expires time.Duration) (string, error)
+}
+
+// Upload stores size bytes read from r, streaming them when the store's
+// driver can and reading them into memory otherwise
+func Upload(ctx context.Context, store Store, key string, r io.ReaderAt, size int64, contentType string) error {
+	if uploader, ok := store.(Uploader); ok {
+		return uploader.Upload(ctx, key, r, size, contentType)
+	}
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, 0); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	return store.Put(ctx, key, data, contentType)
+}
+
+// SignedURL returns a URL reading key until it expires, or ErrNotSignable
+func SignedURL(store Store, key string, expires time.Duration) (string, error) {
+	if signer, ok := store.(URLSigner); ok {
+		return signer.SignedURL(key, expires)
+	}
+	return "", ErrNotSignable
+}
+
+// Config selects and configures a storage driver
+type Config struct {
+	Driver string // local, s3, gcs or azure
+	Prefix string // Prepended to every key, e.g. "staging/"
+
+	// LocalDir is the root directory of the local driver
+	LocalDir string
+
+	// Bucket is the S3 or GCS bucket, or the Azure container
+	Bucket string
+	// Endpoint overrides the service URL, e.g. for MinIO or Azurite
+	Endpoint string
+	// Region of the S3 bucket
+	Region string
+	// AccessKeyID and SecretAccessKey sign S3 requests, and are the HMAC key
+	// of a GCS service account for the gcs driver
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// AzureAccount and AzureKey (base64) sign Azure Blob requests
+	AzureAccount string
+	AzureKey     string
+}
+
+// New creates the store selected by config
+func New(config Config) (Store, error) {
+	var store Store
+	var err error
+	switch config.Driver {
+	case DriverLocal, "":
+		store, err = NewLocal(config.LocalDir)
+	case DriverS3:
+		store, err = NewS3(config)
+	case DriverGCS:
+		store, err = NewGCS(config)
+	case DriverAzure:
+		store, err = NewAzure(config)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Prefix != "" {
+		store = &prefixed{Store: store, prefix: strings.Trim(config.Prefix, "/") + "/"}
+	}
+	return store, nil
+}
+
+// CheckKey rejects keys that could escape their store, such as absolute
+// paths or ones containing "..". Drivers call it on every key.
+func CheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	if path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
+
+// prefixed namespaces every key of a store
+type prefixed struct {
+	Store
+	prefix string
+}
+
+func (p *prefixed) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	return p.Store.Put(ctx, p.prefix+key, data, contentType)
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.Store.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.Store.Delete(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Upload(ctx context.Context, key string, r io.ReaderAt, size int64, contentType string) error {
+	return Upload(ctx, p.Store, p.prefix+key, r, size, contentType)
+}
+
+func (p *prefixed) SignedURL(key string, expires time.Duration) (string, error) {
+	return SignedURL(p.Store, p.prefix+key, expires)
+}
@@ -0,0 +1,83 @@
+// Package support describes the stitched views support staff troubleshoot
+// customers' problems with: what an ID names and the records around it,
+// what went wrong recently for an organization, and how its webhooks are
+// being delivered.
+package support
+
+import (
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/campaign"
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/render"
+	"github.com/inscenium/inscenium/control/api/internal/webhook"
+)
+
+// Kinds of record an ID is looked up as
+const (
+	KindBooking = "booking"
+	KindEvent   = "event"   // Exposure event
+	KindSurface = "surface" // Placement opportunity
+	KindViewer  = "viewer"  // Viewer hash of exposure events
+)
+
+// Kinds lists the kinds of record in the order an ID is looked up as them
+var Kinds = []string{KindBooking, KindEvent, KindSurface, KindViewer}
+
+// Lookup limits
+const (
+	RelatedLimit = 20 // Related records of each kind returned with a match
+)
+
+// Match is a record an ID names, stitched together with the records support
+// most often needs next to it. Only the fields of its kind are set.
+type Match struct {
+	Kind string `json:"kind"`
+
+	Booking    *models.Booking       `json:"booking,omitempty"`
+	Event      *models.ExposureEvent `json:"event,omitempty"`
+	Surface    *models.Surface       `json:"surface,omitempty"`
+	Campaign   *campaign.Campaign    `json:"campaign,omitempty"`
+	Advertiser *campaign.Advertiser  `json:"advertiser,omitempty"`
+
+	History    []booking.Event        `json:"history,omitempty"`            // Latest events of the booking's stream, in sequence order
+	Bookings   []models.Booking       `json:"bookings,omitempty"`           // Latest bookings of the surface
+	Events     []models.ExposureEvent `json:"exposure_events,omitempty"`    // Latest exposure events of the booking or viewer
+	RenderJobs []*render.Job          `json:"render_jobs,omitempty"`        // Latest render jobs of the booking
+	Deliveries []webhook.Delivery     `json:"webhook_deliveries,omitempty"` // Latest webhook deliveries about the booking
+}
+
+// Sources of an organization's errors
+const (
+	SourceWebhookDelivery = "webhook_delivery" // Delivery retrying or given up on
+	SourceEventExport     = "event_export"     // Failed exposure event export
+	SourceLogLevelExport  = "log_level_export" // Failed daily log-level file
+	SourceRenderJob       = "render_job"       // Failed render of a booking
+	SourcePIIRejection    = "pii_rejection"    // Requests rejected for carrying personal data
+)
+
+// Error is something that went wrong for an organization
+type Error struct {
+	Source     string    `json:"source"`
+	ID         string    `json:"id"` // Of the failed record in its source
+	BookingID  string    `json:"booking_id,omitempty"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Error listing defaults
+const (
+	DefaultErrorWindow = 24 * time.Hour
+	MaxErrorWindow     = 30 * 24 * time.Hour
+	DefaultErrorLimit  = 100
+	MaxErrorLimit      = 500
+)
+
+// WebhookStatus summarizes how a webhook subscription's deliveries are going
+type WebhookStatus struct {
+	Subscription    *webhook.Subscription `json:"subscription"`
+	Counts          map[string]int        `json:"counts"` // Deliveries created in the window, by status
+	LastDeliveredAt *time.Time            `json:"last_delivered_at,omitempty"`
+	LastFailure     *webhook.Delivery     `json:"last_failure,omitempty"` // Latest delivery retrying or given up on
+}
@@ -0,0 +1,170 @@
+// Package taxonomy lets an organization, typically a publisher, use its own
+// vocabulary for surface types and restriction categories. Aliases are
+// translated to canonical terms on ingest and back on output, so storage and
+// every other organization only ever see canonical terms.
+package taxonomy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of terms an organization can alias
+const (
+	KindSurfaceType = "surface_type" // e.g. "wall", "table", "screen"
+	KindRestriction = "restriction"  // e.g. "family-friendly"
+)
+
+// Kinds lists every kind of term that can be aliased
+var Kinds = []string{KindSurfaceType, KindRestriction}
+
+const (
+	// maxTermLength matches the surfaces.surface_type column
+	maxTermLength = 50
+	// maxAliases bounds the aliases of one kind an organization keeps
+	maxAliases = 200
+)
+
+// ValidKind reports whether terms of kind can be aliased
+func ValidKind(kind string) bool {
+	return kind == KindSurfaceType || kind == KindRestriction
+}
+
+// Aliases maps an organization's own terms of one kind to canonical terms
+type Aliases map[string]string
+
+// Validate checks that the aliases translate both ways: every canonical term
+// has at most one alias, and no alias is itself a canonical term
+func (a Aliases) Validate() error {
+	if len(a) > maxAliases {
+		return fmt.Errorf("at most %d aliases may be set", maxAliases)
+	}
+	aliasOf := make(map[string]string, len(a))
+	for alias, canonical := range a {
+		if err := validateTerm(alias); err != nil {
+			return fmt.Errorf("alias %q: %w", alias, err)
+		}
+		if err := validateTerm(canonical); err != nil {
+			return fmt.Errorf("canonical term of %q: %w", alias, err)
+		}
+		if alias == canonical {
+			return fmt.Errorf("alias %q maps to itself", alias)
+		}
+		if other, ok := aliasOf[canonical]; ok {
+			first, second := sortedPair(alias, other)
+			return fmt.Errorf("aliases %q and %q both map to %q", first, second, canonical)
+		}
+		aliasOf[canonical] = alias
+	}
+	for alias := range a {
+		if other, ok := aliasOf[alias]; ok {
+			return fmt.Errorf("alias %q is also the canonical term of %q", alias, other)
+		}
+	}
+	return nil
+}
+
+// validateTerm checks that a term can be stored
+func validateTerm(term string) error {
+	if strings.TrimSpace(term) != term || term == "" {
+		return fmt.Errorf("must be non-empty without surrounding spaces")
+	}
+	if len(term) > maxTermLength {
+		return fmt.Errorf("must be at most %d characters", maxTermLength)
+	}
+	return nil
+}
+
+// sortedPair orders two terms so errors read the same on every run
+func sortedPair(a, b string) (string, string) {
+	if b < a {
+		return b, a
+	}
+	return a, b
+}
+
+// Taxonomy is an organization's aliases of every kind. A nil Taxonomy
+// translates nothing.
+type Taxonomy struct {
+	OrgID   string             `json:"org_id"`
+	Aliases map[string]Aliases `json:"aliases"` // By kind; every kind is listed
+
+	canonical map[string]map[string]string // By kind, canonical term -> alias
+}
+
+// New builds an organization's taxonomy from its aliases by kind
+func New(orgID string, aliases map[string]Aliases) *Taxonomy {
+	t := &Taxonomy{
+		OrgID:     orgID,
+		Aliases:   make(map[string]Aliases, len(Kinds)),
+		canonical: make(map[string]map[string]string, len(Kinds)),
+	}
+	for _, kind := range Kinds {
+		t.Aliases[kind] = Aliases{}
+		t.canonical[kind] = map[string]string{}
+		for alias, canonical := range aliases[kind] {
+			t.Aliases[kind][alias] = canonical
+			t.canonical[kind][canonical] = alias
+		}
+	}
+	return t
+}
+
+// Empty reports whether the taxonomy translates nothing
+func (t *Taxonomy) Empty() bool {
+	if t == nil {
+		return true
+	}
+	for _, aliases := range t.Aliases {
+		if len(aliases) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical translates a term of kind into its canonical term. Terms without
+// an alias are already canonical.
+func (t *Taxonomy) Canonical(kind, term string) string {
+	if t == nil {
+		return term
+	}
+	if canonical, ok := t.Aliases[kind][term]; ok {
+		return canonical
+	}
+	return term
+}
+
+// Alias translates a canonical term of kind into the organization's alias
+// for it, or leaves it as it is when the organization has none
+func (t *Taxonomy) Alias(kind, canonical string) string {
+	if t == nil {
+		return canonical
+	}
+	if alias, ok := t.canonical[kind][canonical]; ok {
+		return alias
+	}
+	return canonical
+}
+
+// CanonicalTerms translates a list of terms of kind with Canonical
+func (t *Taxonomy) CanonicalTerms(kind string, terms []string) []string {
+	return translate(terms, func(term string) string { return t.Canonical(kind, term) })
+}
+
+// AliasTerms translates a list of canonical terms of kind with Alias
+func (t *Taxonomy) AliasTerms(kind string, terms []string) []string {
+	return translate(terms, func(term string) string { return t.Alias(kind, term) })
+}
+
+// translate maps every term, keeping a nil list nil
+func translate(terms []string, fn func(string) string) []string {
+	if terms == nil {
+		return nil
+	}
+	translated := make([]string, len(terms))
+	for i, term := range terms {
+		translated[i] = fn(term)
+	}
+	return translated
+}
@@ -0,0 +1,45 @@
+// Package tenant scopes repository queries to the organizations whose data a
+// caller may read. Handlers build a Scope from the authenticated caller and
+// pass it down, so the queries behind bookings and metrics filter rows by
+// organization themselves instead of relying on checks made earlier.
+package tenant
+
+// Scope is the organizations whose bookings, exposure events and metrics a
+// query may return. Data created before organizations existed belongs to
+// none and is in every scope.
+type Scope struct {
+	All  bool     `json:"all,omitempty"`  // Every organization's data, for admins and background jobs
+	Orgs []string `json:"orgs,omitempty"` // Usually the caller's organization, plus the owner of a resource a grant let it read
+}
+
+// All scopes a query to every organization's data
+func All() Scope {
+	return Scope{All: true}
+}
+
+// Of scopes a query to the given organizations, ignoring empty IDs. A scope
+// of no organization only reads data that belongs to none.
+func Of(orgIDs ...string) Scope {
+	s := Scope{Orgs: make([]string, 0, len(orgIDs))}
+	for _, orgID := range orgIDs {
+		if orgID != "" && !s.has(orgID) {
+			s.Orgs = append(s.Orgs, orgID)
+		}
+	}
+	return s
+}
+
+// Allows reports whether data of orgID is in the scope
+func (s Scope) Allows(orgID string) bool {
+	return s.All || orgID == "" || s.has(orgID)
+}
+
+// has reports whether orgID is listed in the scope
+func (s Scope) has(orgID string) bool {
+	for _, o := range s.Orgs {
+		if o == orgID {
+			return true
+		}
+	}
+	return false
+}
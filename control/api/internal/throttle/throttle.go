@@ -0,0 +1,103 @@
+// Package throttle is the ingestion policy edge nodes follow when sending
+// exposure batches. Operators tighten it during incidents so edge nodes send
+// smaller, less frequent batches and buffer the rest; nodes learn it from
+// every batch response and by polling, and batches larger than it allows are
+// refused so they are split.
+package throttle
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxBatchSize is the most events a batch may ever carry
+	MaxBatchSize = 1000
+	// MaxMinInterval bounds how long edge nodes can be told to wait between batches
+	MaxMinInterval = time.Hour
+	// CacheTTL is how long the stored policy is cached, and so how long a
+	// change made on another instance takes to apply
+	CacheTTL = 10 * time.Second
+	// maxReasonLength matches the ingestion_policy.reason column
+	maxReasonLength = 255
+)
+
+// Policy is what edge nodes may send. The zero MinIntervalMS lets them send
+// as often as they like.
+type Policy struct {
+	MaxBatchSize  int        `json:"max_batch_size"`
+	MinIntervalMS int64      `json:"min_interval_ms"` // Least time between batches of one node
+	Reason        string     `json:"reason,omitempty"`
+	Default       bool       `json:"default"` // No operator has set a policy
+	UpdatedBy     string     `json:"updated_by,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultPolicy lets edge nodes send full batches as often as they like
+func DefaultPolicy() Policy {
+	return Policy{MaxBatchSize: MaxBatchSize, Default: true}
+}
+
+// Validate checks a policy an operator sets
+func (p Policy) Validate() error {
+	if p.MaxBatchSize < 1 || p.MaxBatchSize > MaxBatchSize {
+		return errors.New("max_batch_size must be between 1 and 1000")
+	}
+	if p.MinIntervalMS < 0 || p.MinIntervalMS > MaxMinInterval.Milliseconds() {
+		return errors.New("min_interval_ms must be between 0 and 3600000")
+	}
+	if len(p.Reason) > maxReasonLength {
+		return errors.New("reason must be at most 255 characters")
+	}
+	return nil
+}
+
+// Store holds the policy operators set
+type Store interface {
+	GetIngestionPolicy() (*Policy, error) // nil when none is set
+}
+
+// Cache holds the current policy, reloading it once it is older than CacheTTL
+type Cache struct {
+	store Store
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	policy   Policy
+}
+
+// NewCache creates a cache of the store's policy
+func NewCache(store Store) *Cache {
+	return &Cache{store: store, policy: DefaultPolicy()}
+}
+
+// Current returns the policy in force. When the store fails, the policy last
+// loaded stays in force.
+func (c *Cache) Current() Policy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.loadedAt) >= CacheTTL {
+		policy, err := c.store.GetIngestionPolicy()
+		switch {
+		case err != nil:
+			logrus.WithError(err).Warn("Failed to load ingestion policy, keeping the cached one")
+		case policy == nil:
+			c.policy = DefaultPolicy()
+		default:
+			c.policy = *policy
+		}
+		c.loadedAt = time.Now()
+	}
+	return c.policy
+}
+
+// Invalidate makes the next read reload the policy, after this instance changed it
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
@@ -0,0 +1,169 @@
+package thumbnail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // Reference frames may be PNG
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Limits on reference frames uploaded by the pipeline
+const (
+	MaxSourceBytes  = 10 << 20
+	MaxSourcePixels = 40_000_000 // Comfortably above an 8K frame
+)
+
+// DefaultWidth is served when a request does not ask for a width
+const DefaultWidth = 320
+
+// Widths are the variant widths served. Requests are rounded up to the
+// next one so each surface has a handful of cached variants at most.
+var Widths = []int{160, 320, 640, 1280}
+
+// jpegQuality is used for every resized variant
+const jpegQuality = 82
+
+// ErrInvalidImage is returned for uploads that are not a usable JPEG or PNG
+var ErrInvalidImage = errors.New("invalid thumbnail image")
+
+// Source is the reference frame stored for a surface. Version is a digest
+// of the image, so URLs carrying it can be cached indefinitely.
+type Source struct {
+	SurfaceID   string    `json:"surface_id"`
+	TitleID     string    `json:"title_id"`
+	Version     string    `json:"version"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	ByteSize    int       `json:"byte_size"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// Inspect checks an uploaded reference frame and describes it
+func Inspect(surfaceID string, data []byte) (*Source, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty body", ErrInvalidImage)
+	}
+	if len(data) > MaxSourceBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidImage, MaxSourceBytes)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: must be a JPEG or PNG", ErrInvalidImage)
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > MaxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d is outside the supported size", ErrInvalidImage, config.Width, config.Height)
+	}
+
+	sum := sha256.Sum256(data)
+	return &Source{
+		SurfaceID:   surfaceID,
+		Version:     hex.EncodeToString(sum[:8]),
+		ContentType: "image/" + format,
+		Width:       config.Width,
+		Height:      config.Height,
+		ByteSize:    len(data),
+	}, nil
+}
+
+// Fit rounds a requested width up to the nearest served width
+func Fit(width int) int {
+	for _, w := range Widths {
+		if width <= w {
+			return w
+		}
+	}
+	return Widths[len(Widths)-1]
+}
+
+// Path returns the API path serving a surface's thumbnail at a version
+func Path(surfaceID, version string) string {
+	return "/api/v1/opportunities/" + url.PathEscape(surfaceID) + "/thumbnail?v=" + version
+}
+
+// SourceKey is the storage key of a surface's reference frame
+func SourceKey(surfaceID, version string) string {
+	return "thumbnails/" + url.PathEscape(surfaceID) + "/" + version + "/source"
+}
+
+// VariantKey is the storage key of a resized variant
+func VariantKey(surfaceID, version string, width int) string {
+	return "thumbnails/" + url.PathEscape(surfaceID) + "/" + version + "/" + strconv.Itoa(width) + ".jpg"
+}
+
+// Resize scales a reference frame down to width, keeping its aspect ratio,
+// and encodes it as JPEG. Frames narrower than width are not enlarged.
+// Transparent areas of PNG frames are flattened onto white.
+func Resize(data []byte, width int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail source: %w", err)
+	}
+
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	if width > flat.Rect.Dx() {
+		width = flat.Rect.Dx()
+	}
+	height := flat.Rect.Dy() * width / flat.Rect.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, shrink(flat, width, height), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// shrink box-filters src down to width x height, averaging the block of
+// source pixels each destination pixel covers
+func shrink(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if width == sw && height == sh {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := dy*sh/height, (dy+1)*sh/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := dx*sw/width, (dx+1)*sw/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, n int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					n++
+				}
+			}
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
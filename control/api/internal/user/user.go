@@ -0,0 +1,162 @@
+// Package user holds the people who sign in to the API with a username and
+// password. Passwords are stored as bcrypt hashes, and an account is locked
+// for a while after repeated failed logins. Each user holds a role that
+// decides what they may do.
+package user
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the shortest password accepted
+	MinPasswordLength = 12
+	// MaxPasswordLength is the longest password accepted; bcrypt ignores
+	// anything past 72 bytes
+	MaxPasswordLength = 72
+
+	// DefaultLockoutThreshold is how many failed logins in a row lock an account
+	DefaultLockoutThreshold = 5
+	// DefaultLockoutDuration is how long a locked account refuses logins
+	DefaultLockoutDuration = 15 * time.Minute
+)
+
+// Roles a user can hold. The role decides which scopes the user's tokens
+// carry, in the same syntax as service-account scopes.
+const (
+	RoleAdmin      = "admin"      // Everything, including cancelling any organization's bookings
+	RoleAdvertiser = "advertiser" // Books and manages placements for their organization
+	RolePublisher  = "publisher"  // Manages the titles, surfaces and inventory they publish
+	RoleAnalyst    = "analyst"    // Reads inventory, bookings and reports
+)
+
+// DefaultRole is given to users created without one. It can change nothing.
+const DefaultRole = RoleAnalyst
+
+// Roles lists every role, from most to least privileged
+var Roles = []string{RoleAdmin, RoleAdvertiser, RolePublisher, RoleAnalyst}
+
+// roleScopes are the scopes each role grants
+var roleScopes = map[string][]string{
+	RoleAdmin: {"*"},
+	RoleAdvertiser: {
+		"sgi:read", "inventory:read", "bookings:*", "analytics:read",
+		"metadata:*", "grants:manage", "render:read", "webhooks:manage",
+		"logs:read",
+	},
+	RolePublisher: {
+		"sgi:*", "inventory:*", "bookings:read", "analytics:read",
+		"metadata:*", "grants:manage", "publisher:read", "render:read",
+		"webhooks:manage", "manifests:decorate",
+	},
+	RoleAnalyst: {
+		"sgi:read", "inventory:read", "bookings:read", "analytics:read",
+		"metadata:read", "publisher:read", "render:read",
+	},
+}
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]{1,99}$`)
+
+// User is a person who signs in with a password. Their username is the
+// subject of the tokens they are issued, so it is what ADMIN_USERS lists.
+type User struct {
+	Username     string     `json:"username"`
+	OrgID        string     `json:"org_id,omitempty"`
+	Role         string     `json:"role"`
+	PasswordHash string     `json:"-"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"` // Set only while the account is locked
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+}
+
+// Locked reports whether the account refuses logins after too many failures
+func (u *User) Locked() bool {
+	return u.LockedUntil != nil
+}
+
+// Lockout locks an account after Threshold failed logins in a row, for Duration
+type Lockout struct {
+	Threshold int
+	Duration  time.Duration
+}
+
+// DefaultLockout locks an account for 15 minutes after 5 failed logins
+func DefaultLockout() Lockout {
+	return Lockout{Threshold: DefaultLockoutThreshold, Duration: DefaultLockoutDuration}
+}
+
+// Validate checks that the lockout can lock an account
+func (l Lockout) Validate() error {
+	if l.Threshold < 1 {
+		return fmt.Errorf("login lockout threshold must be at least 1")
+	}
+	if l.Duration <= 0 {
+		return fmt.Errorf("login lockout duration must be positive")
+	}
+	return nil
+}
+
+// ValidateUsername checks that a username can be created
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("username must be 2-100 letters, digits or . _ @ -, starting with a letter or digit")
+	}
+	return nil
+}
+
+// ValidateRole checks that a role exists
+func ValidateRole(role string) error {
+	if _, ok := roleScopes[role]; !ok {
+		return fmt.Errorf("role must be one of %s", strings.Join(Roles, ", "))
+	}
+	return nil
+}
+
+// Scopes returns the scopes a role grants, none for an unknown role
+func Scopes(role string) []string {
+	return roleScopes[role]
+}
+
+// ValidatePassword checks that a password is long enough to set
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", MaxPasswordLength)
+	}
+	return nil
+}
+
+// HashPassword hashes a password for storage
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyHash is compared against when a login names no usable account, so
+// unknown usernames take as long to refuse as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("inscenium-no-such-user"), bcrypt.DefaultCost)
+
+// VerifyPassword reports whether password matches a stored hash. An empty
+// hash never matches but costs as much to check as one that does not.
+func VerifyPassword(password, passwordHash string) bool {
+	if passwordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
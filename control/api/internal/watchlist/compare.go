@@ -0,0 +1,99 @@
+package watchlist
+
+import "github.com/inscenium/inscenium/control/api/internal/models"
+
+// Column heads the compared values of one surface
+type Column struct {
+	SurfaceID    string `json:"surface_id"`
+	TitleID      string `json:"title_id"`
+	SurfaceType  string `json:"surface_type"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+// Attribute is one measure of every compared surface, in column order
+type Attribute struct {
+	Name       string     `json:"name"`
+	Values     []*float64 `json:"values"`     // Null where the surface was not measured
+	Normalized []*float64 `json:"normalized"` // Scaled 0 to 1 across the compared surfaces, higher is better
+	Best       string     `json:"best,omitempty"`
+}
+
+// Comparison lays surfaces side by side
+type Comparison struct {
+	Surfaces   []Column    `json:"surfaces"`
+	Attributes []Attribute `json:"attributes"`
+}
+
+// measures are the compared attributes; higher is better for each
+var measures = []struct {
+	name  string
+	value func(s *models.Surface) *float64
+}{
+	{"prs_score", func(s *models.Surface) *float64 { return &s.PRSScore }},
+	{"visibility_score", func(s *models.Surface) *float64 { return &s.VisibilityScore }},
+	{"duration", func(s *models.Surface) *float64 { return &s.Duration }},
+	{"area_pixels", func(s *models.Surface) *float64 { return s.AreaPixels }},
+	{"area_world_m2", func(s *models.Surface) *float64 { return s.AreaWorldM2 }},
+}
+
+// Compare lays out surfaces side by side. Each attribute is min-max scaled
+// across them so differences read the same whatever the unit; when every
+// surface measures the same they all score 1. notes, which may be nil, adds
+// the planner's note to each column.
+func Compare(surfaces []models.Surface, notes map[string]string) *Comparison {
+	c := &Comparison{
+		Surfaces:   make([]Column, 0, len(surfaces)),
+		Attributes: make([]Attribute, 0, len(measures)),
+	}
+	for _, s := range surfaces {
+		c.Surfaces = append(c.Surfaces, Column{
+			SurfaceID:    s.SurfaceID,
+			TitleID:      s.TitleID,
+			SurfaceType:  s.SurfaceType,
+			ThumbnailURL: s.ThumbnailURL,
+			Note:         notes[s.SurfaceID],
+		})
+	}
+
+	for _, m := range measures {
+		attr := Attribute{
+			Name:       m.name,
+			Values:     make([]*float64, len(surfaces)),
+			Normalized: make([]*float64, len(surfaces)),
+		}
+		var low, high float64
+		best, measured := -1, 0
+		for i := range surfaces {
+			v := m.value(&surfaces[i])
+			if v == nil {
+				continue
+			}
+			value := *v
+			attr.Values[i] = &value
+			if measured == 0 || value < low {
+				low = value
+			}
+			if measured == 0 || value > high {
+				high = value
+				best = i
+			}
+			measured++
+		}
+		for i, v := range attr.Values {
+			if v == nil {
+				continue
+			}
+			scaled := 1.0
+			if high > low {
+				scaled = (*v - low) / (high - low)
+			}
+			attr.Normalized[i] = &scaled
+		}
+		if best >= 0 && measured > 1 && high > low {
+			attr.Best = surfaces[best].SurfaceID
+		}
+		c.Attributes = append(c.Attributes, attr)
+	}
+	return c
+}
@@ -0,0 +1,187 @@
+// Package watchlist lets planners shortlist surfaces before proposing them,
+// compare them side by side and turn the shortlist into booking requests.
+package watchlist
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/models"
+	"github.com/inscenium/inscenium/control/api/internal/schema"
+)
+
+// ErrWatchlistNotFound is returned when a watchlist does not exist
+var ErrWatchlistNotFound = errors.New("watchlist not found")
+
+// ErrFull is returned when adding a surface to a watchlist that has MaxItems
+var ErrFull = errors.New("watchlist is full")
+
+// ErrNoSurfaces is returned when booking a watchlist leaves every surface out
+var ErrNoSurfaces = errors.New("no bookable surfaces on the watchlist")
+
+// MaxItems bounds the surfaces on one watchlist
+const MaxItems = 200
+
+// MaxCompare bounds the surfaces compared at once
+const MaxCompare = 10
+
+const (
+	maxNameLength = 255
+	maxNoteLength = 2000
+)
+
+// Reasons a watched surface was left out of a booking
+const (
+	SkipHeldBack = "held_back"
+	SkipMerged   = "merged"
+	SkipCollides = "collides"       // Booking it would break its shot's collision rule
+	SkipBooked   = "already_booked" // The campaign already has a live booking on it
+)
+
+// Watchlist is a user's shortlist of surfaces
+type Watchlist struct {
+	WatchlistID string    `json:"watchlist_id"`
+	Name        string    `json:"name"`
+	OwnerID     string    `json:"owner_id"`
+	OrgID       string    `json:"-"`
+	ItemCount   int       `json:"item_count"`
+	Items       []Item    `json:"items,omitempty"` // Single lookups only
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks that the watchlist is well formed
+func (w *Watchlist) Validate() error {
+	if w.Name == "" || len(w.Name) > maxNameLength {
+		return fmt.Errorf("name must be 1 to %d characters", maxNameLength)
+	}
+	return nil
+}
+
+// Item is a surface on a watchlist with the planner's note about it
+type Item struct {
+	SurfaceID string          `json:"surface_id"`
+	Note      string          `json:"note,omitempty"`
+	AddedAt   time.Time       `json:"added_at"`
+	Surface   *models.Surface `json:"surface,omitempty"`
+}
+
+// Validate checks that the item is well formed
+func (i *Item) Validate() error {
+	if i.SurfaceID == "" {
+		return fmt.Errorf("surface_id is required")
+	}
+	if len(i.Note) > maxNoteLength {
+		return fmt.Errorf("note must be at most %d characters", maxNoteLength)
+	}
+	return nil
+}
+
+// Select returns the items for surfaceIDs in the watchlist's order, or every
+// item when surfaceIDs is empty. Surfaces not on the watchlist are an error.
+func (w *Watchlist) Select(surfaceIDs []string) ([]Item, error) {
+	if len(surfaceIDs) == 0 {
+		return w.Items, nil
+	}
+	wanted := make(map[string]bool, len(surfaceIDs))
+	for _, id := range surfaceIDs {
+		wanted[id] = true
+	}
+	selected := make([]Item, 0, len(surfaceIDs))
+	for _, item := range w.Items {
+		if wanted[item.SurfaceID] {
+			selected = append(selected, item)
+			delete(wanted, item.SurfaceID)
+		}
+	}
+	for _, id := range surfaceIDs {
+		if wanted[id] {
+			return nil, fmt.Errorf("surface %s is not on the watchlist", id)
+		}
+	}
+	return selected, nil
+}
+
+// Terms are what a watchlist is proposed or booked at
+type Terms struct {
+	AdvertiserID   string  `json:"advertiser_id"`
+	CampaignID     string  `json:"campaign_id"`
+	BidAmountCPM   float64 `json:"bid_amount_cpm"`
+	MaxImpressions int     `json:"max_impressions"` // Per surface booking
+}
+
+// Validate checks that the terms are well formed
+func (t *Terms) Validate() error {
+	if t.AdvertiserID == "" || t.CampaignID == "" {
+		return fmt.Errorf("advertiser_id and campaign_id are required")
+	}
+	if t.BidAmountCPM <= 0 {
+		return fmt.Errorf("bid_amount_cpm must be positive")
+	}
+	if t.MaxImpressions < 0 {
+		return fmt.Errorf("max_impressions must not be negative")
+	}
+	return nil
+}
+
+// Proposal lists the booking requests a watchlist would make, ready to review
+// and submit to POST /bookings
+type Proposal struct {
+	WatchlistID string `json:"watchlist_id"`
+	Name        string `json:"name"`
+	Terms
+	Bookings []schema.BookingRequest `json:"bookings"`
+	Notes    map[string]string       `json:"notes,omitempty"` // Surface ID -> the planner's note
+}
+
+// Propose builds the booking requests for items at terms
+func Propose(w *Watchlist, items []Item, terms Terms) *Proposal {
+	p := &Proposal{
+		WatchlistID: w.WatchlistID,
+		Name:        w.Name,
+		Terms:       terms,
+		Bookings:    make([]schema.BookingRequest, 0, len(items)),
+	}
+	for _, item := range items {
+		p.Bookings = append(p.Bookings, schema.BookingRequest{
+			SurfaceID:      item.SurfaceID,
+			AdvertiserID:   terms.AdvertiserID,
+			CampaignID:     terms.CampaignID,
+			BidAmountCPM:   terms.BidAmountCPM,
+			MaxImpressions: terms.MaxImpressions,
+		})
+		if item.Note != "" {
+			if p.Notes == nil {
+				p.Notes = make(map[string]string)
+			}
+			p.Notes[item.SurfaceID] = item.Note
+		}
+	}
+	return p
+}
+
+// Booking books the selected surfaces of a watchlist at the same terms in
+// one go
+type Booking struct {
+	WatchlistID string   `json:"watchlist_id"`
+	SurfaceIDs  []string `json:"-"`
+	Terms
+	CreatedBy string    `json:"created_by,omitempty"`
+	OrgID     string    `json:"-"`
+	Bookings  []Booked  `json:"bookings"`
+	Skipped   []Skip    `json:"skipped"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Booked is one placement booking made from a watchlist
+type Booked struct {
+	BookingID string `json:"booking_id"`
+	SurfaceID string `json:"surface_id"`
+}
+
+// Skip is a watched surface a booking left out
+type Skip struct {
+	SurfaceID string `json:"surface_id"`
+	Reason    string `json:"reason"`
+}
@@ -0,0 +1,66 @@
+package watermark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Captured frames may be JPEG
+	_ "image/png"  // or PNG
+)
+
+// Limits on captured-frame samples
+const (
+	MaxSamples      = 10
+	MaxSampleBytes  = 4 << 20
+	MaxSamplePixels = 40_000_000 // Comfortably above an 8K frame
+)
+
+// ErrInvalidSample is returned for samples that are not a usable JPEG or PNG
+var ErrInvalidSample = errors.New("invalid sample")
+
+// Region is the placement's rectangle within a captured frame, in pixels
+// from its top left corner
+type Region struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ReadSample decodes a captured frame, cropped to region when it is set
+func ReadSample(data []byte, region *Region) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty image", ErrInvalidSample)
+	}
+	if len(data) > MaxSampleBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidSample, MaxSampleBytes)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: must be a JPEG or PNG", ErrInvalidSample)
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > MaxSamplePixels {
+		return nil, fmt.Errorf("%w: %dx%d is outside the supported size", ErrInvalidSample, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSample, err)
+	}
+	if region == nil {
+		return img, nil
+	}
+
+	b := img.Bounds()
+	crop := image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height).Add(b.Min)
+	if region.Width < 1 || region.Height < 1 || !crop.In(b) {
+		return nil, fmt.Errorf("%w: region is outside the %dx%d frame", ErrInvalidSample, b.Dx(), b.Dy())
+	}
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot crop the image", ErrInvalidSample)
+	}
+	return sub.SubImage(crop), nil
+}
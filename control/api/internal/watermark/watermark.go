@@ -0,0 +1,282 @@
+// Package watermark embeds and reads the measurement watermark of rendered
+// placements, so delivery can be verified from captured frames independently
+// of player telemetry.
+//
+// A watermark carries a 32-bit ID followed by its CRC-16/CCITT-FALSE, 48
+// bits in all. The placement is divided into a Grid x Grid lattice of cells;
+// a key shared with the render farm assigns every cell one of the 48 bits
+// and a chip of +1 or -1. Rendering adds strength * chip * (+1 for a set
+// bit, -1 otherwise) luma levels to each pixel of a cell, a shift of a few
+// levels out of 255 that viewers do not notice. Reading averages the luma of
+// each cell of a sample, subtracts the average of its neighbours to remove
+// the picture itself, and sums the chip-weighted residuals of each bit.
+// Cells are relative to the sample, so scaled captures still read.
+package watermark
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// Grid is the number of cells along each side of a placement
+const Grid = 32
+
+// DefaultStrength is the luma shift, in levels out of 255, of each cell
+const DefaultStrength = 3
+
+// MinSampleSize is the smallest sample side, in pixels, that can be read
+const MinSampleSize = 2 * Grid
+
+// MinConfidence is the share of cells that must agree with the bits read
+// for a watermark to be found. Samples without a watermark agree about half
+// the time.
+const MinConfidence = 0.6
+
+// Bit counts of a watermark
+const (
+	idBits      = 32
+	checkBits   = 16
+	payloadBits = idBits + checkBits
+)
+
+// ErrNotFound is returned when a sample carries no readable watermark
+var ErrNotFound = errors.New("no watermark found")
+
+// ErrSampleTooSmall is returned for samples smaller than MinSampleSize
+var ErrSampleTooSmall = errors.New("sample too small to read a watermark")
+
+// Mark is the watermark issued to a render job
+type Mark struct {
+	WatermarkID uint32 `json:"watermark_id"`
+	JobID       string `json:"job_id"`
+	BookingID   string `json:"booking_id"`
+}
+
+// Detection is a watermark read from a sample
+type Detection struct {
+	WatermarkID uint32  `json:"watermark_id"`
+	Confidence  float64 `json:"confidence"` // Share of cells agreeing with the bits read
+}
+
+// Verification is a logged sighting of a placement's watermark
+type Verification struct {
+	VerificationID string    `json:"verification_id"`
+	WatermarkID    uint32    `json:"watermark_id"`
+	JobID          string    `json:"job_id"`
+	BookingID      string    `json:"booking_id"`
+	Source         string    `json:"source,omitempty"`
+	Confidence     float64   `json:"confidence"`
+	CapturedAt     time.Time `json:"captured_at"`
+	RecordedBy     string    `json:"recorded_by,omitempty"`
+	RecordedAt     time.Time `json:"recorded_at"`
+}
+
+// NewID returns a random, non-zero watermark ID
+func NewID() (uint32, error) {
+	var b [4]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		if id := binary.BigEndian.Uint32(b[:]); id != 0 {
+			return id, nil
+		}
+	}
+}
+
+// pattern assigns each cell a payload bit and a chip
+type pattern struct {
+	bit  [Grid * Grid]int
+	chip [Grid * Grid]float64
+}
+
+// newPattern derives the pattern of key
+func newPattern(key string) *pattern {
+	stream := keyStream{key: key}
+	p := &pattern{}
+
+	// Shuffle the cells, then deal the bits out in turn so each bit gets
+	// the same number of cells, spread over the placement
+	order := make([]int, Grid*Grid)
+	for i := range order {
+		order[i] = i
+	}
+	for i := len(order) - 1; i > 0; i-- {
+		j := int(stream.next() % uint32(i+1))
+		order[i], order[j] = order[j], order[i]
+	}
+	for i, cell := range order {
+		p.bit[cell] = i % payloadBits
+		p.chip[cell] = 1
+		if stream.next()&1 == 0 {
+			p.chip[cell] = -1
+		}
+	}
+	return p
+}
+
+// keyStream is a deterministic stream of numbers derived from a key
+type keyStream struct {
+	key     string
+	counter uint64
+	block   [sha256.Size]byte
+	used    int
+}
+
+func (s *keyStream) next() uint32 {
+	if s.counter == 0 || s.used == len(s.block) {
+		var c [8]byte
+		binary.BigEndian.PutUint64(c[:], s.counter)
+		s.block = sha256.Sum256(append([]byte(s.key), c[:]...))
+		s.counter++
+		s.used = 0
+	}
+	v := binary.BigEndian.Uint32(s.block[s.used:])
+	s.used += 4
+	return v
+}
+
+// payload returns the bits of a watermark, most significant first
+func payload(id uint32) [payloadBits]bool {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], id)
+	value := uint64(id)<<checkBits | uint64(crc16(b[:]))
+
+	var bits [payloadBits]bool
+	for i := range bits {
+		bits[i] = value&(1<<(payloadBits-1-i)) != 0
+	}
+	return bits
+}
+
+// crc16 is CRC-16/CCITT-FALSE
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// cellOf returns the cell of a pixel at offset (x, y) of a w x h placement
+func cellOf(x, y, w, h int) int {
+	return (y*Grid/h)*Grid + x*Grid/w
+}
+
+// Embed watermarks img with id. It is the reference the render farm's
+// compositor follows; strength is in luma levels, DefaultStrength when zero.
+func Embed(img draw.Image, id uint32, key string, strength float64) {
+	if strength == 0 {
+		strength = DefaultStrength
+	}
+	p := newPattern(key)
+	bits := payload(id)
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cell := cellOf(x, y, w, h)
+			delta := strength * p.chip[cell]
+			if !bits[p.bit[cell]] {
+				delta = -delta
+			}
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			c.R, c.G, c.B = shift(c.R, delta), shift(c.G, delta), shift(c.B, delta)
+			img.Set(b.Min.X+x, b.Min.Y+y, c)
+		}
+	}
+}
+
+// shift adds delta to a channel, clamped to its range
+func shift(v uint8, delta float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(float64(v)+delta))))
+}
+
+// Decode reads the watermark of a sample: a capture of the placement alone,
+// cropped from the frame it was seen in
+func Decode(img image.Image, key string) (*Detection, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < MinSampleSize || h < MinSampleSize {
+		return nil, ErrSampleTooSmall
+	}
+
+	var sums [Grid * Grid]float64
+	var counts [Grid * Grid]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			cell := cellOf(x, y, w, h)
+			sums[cell] += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+			counts[cell]++
+		}
+	}
+	var means [Grid * Grid]float64
+	for i := range means {
+		means[i] = sums[i] / float64(counts[i])
+	}
+
+	p := newPattern(key)
+	var residuals [Grid * Grid]float64
+	var soft [payloadBits]float64
+	for cy := 0; cy < Grid; cy++ {
+		for cx := 0; cx < Grid; cx++ {
+			var around float64
+			var n int
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := cx+dx, cy+dy
+					if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= Grid || ny >= Grid {
+						continue
+					}
+					around += means[ny*Grid+nx]
+					n++
+				}
+			}
+			cell := cy*Grid + cx
+			residuals[cell] = p.chip[cell] * (means[cell] - around/float64(n))
+			soft[p.bit[cell]] += residuals[cell]
+		}
+	}
+
+	var value uint64
+	for _, s := range soft {
+		value <<= 1
+		if s > 0 {
+			value |= 1
+		}
+	}
+	id := uint32(value >> checkBits)
+	var idBytes [4]byte
+	binary.BigEndian.PutUint32(idBytes[:], id)
+	if uint16(value) != crc16(idBytes[:]) {
+		return nil, ErrNotFound
+	}
+
+	agree := 0
+	for cell, r := range residuals {
+		if (r > 0) == (soft[p.bit[cell]] > 0) {
+			agree++
+		}
+	}
+	confidence := float64(agree) / float64(len(residuals))
+	if confidence < MinConfidence {
+		return nil, ErrNotFound
+	}
+	return &Detection{WatermarkID: id, Confidence: math.Round(confidence*1000) / 1000}, nil
+}
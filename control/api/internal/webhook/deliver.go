@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/inscenium/inscenium/control/api/internal/outbound"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers identifying a delivery, alongside the signature headers
+const (
+	HeaderEvent    = "X-Inscenium-Event"
+	HeaderDelivery = "X-Inscenium-Delivery"
+)
+
+// maxErrorBody caps how much of a failed response is kept in the delivery log
+const maxErrorBody = 512
+
+// DeliveryStore loads deliveries and records their attempts
+type DeliveryStore interface {
+	GetWebhookDelivery(deliveryID string) (*Delivery, error)
+	GetWebhookSubscription(subscriptionID string) (*Subscription, error)
+	RecordWebhookAttempt(deliveryID, status string, attempts, responseStatus int, lastError string, attemptedAt time.Time) error
+}
+
+// NewClient returns the client deliveries are posted with. It makes a single
+// attempt per job: retries are left to the job queue, whose backoff spreads
+// them over minutes rather than seconds and survives restarts.
+func NewClient() *outbound.Client {
+	policy := outbound.WebhookPolicy()
+	policy.MaxAttempts = 1
+	return outbound.New("webhook_subscription", policy)
+}
+
+// NewJobHandler returns a job handler that posts a delivery to its
+// subscription. A failed attempt returns an error so the queue retries it
+// with backoff; the delivery is marked failed once the job is out of
+// attempts. Receivers should drop repeats by event ID, since a delivery that
+// timed out may have arrived.
+func NewJobHandler(store DeliveryStore, client *outbound.Client) jobqueue.Handler {
+	return func(ctx context.Context, job *jobqueue.Job) error {
+		var payload Job
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		delivery, err := store.GetWebhookDelivery(payload.DeliveryID)
+		if err != nil {
+			return err
+		}
+		if delivery == nil || delivery.Status == DeliveryDelivered || delivery.Status == DeliveryFailed {
+			return nil // Deleted with its subscription, or settled by an earlier claim
+		}
+
+		now := time.Now()
+		subscription, err := store.GetWebhookSubscription(delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if subscription == nil || !subscription.Active {
+			return store.RecordWebhookAttempt(delivery.ID, DeliveryFailed, delivery.Attempts, 0, "subscription disabled", now)
+		}
+
+		responseStatus, sendErr := send(ctx, client, subscription, delivery, now)
+		if sendErr == nil {
+			if err := store.RecordWebhookAttempt(delivery.ID, DeliveryDelivered, job.Attempts, responseStatus, "", now); err != nil {
+				return err
+			}
+			logrus.WithFields(logrus.Fields{
+				"delivery_id":     delivery.ID,
+				"subscription_id": subscription.ID,
+				"event_type":      delivery.EventType,
+				"attempts":        job.Attempts,
+			}).Debug("Delivered webhook")
+			return nil
+		}
+
+		status := DeliveryRetrying
+		if job.Attempts >= job.MaxAttempts {
+			status = DeliveryFailed
+		}
+		if err := store.RecordWebhookAttempt(delivery.ID, status, job.Attempts, responseStatus, sendErr.Error(), now); err != nil {
+			logrus.WithError(err).WithField("delivery_id", delivery.ID).Warn("Failed to record webhook attempt")
+		}
+		logrus.WithError(sendErr).WithFields(logrus.Fields{
+			"delivery_id":     delivery.ID,
+			"subscription_id": subscription.ID,
+			"event_type":      delivery.EventType,
+			"attempts":        job.Attempts,
+			"status":          status,
+		}).Warn("Webhook delivery attempt failed")
+		return sendErr
+	}
+}
+
+// send posts one attempt of a delivery and returns the response status, if any
+func send(ctx context.Context, client *outbound.Client, subscription *Subscription, delivery *Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid subscription url: %w", err)
+	}
+
+	// Each attempt is signed afresh so its timestamp stays within the
+	// receiver's tolerance however long the retries take
+	if err := SignRequest(req, subscription.Secret, delivery.Payload, now); err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set("Idempotency-Key", delivery.EventID)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if len(body) > 0 {
+			return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+		}
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// SignRequest sets the timestamp, a fresh nonce and the signature of body
+// under secret on an outgoing request
+func SignRequest(req *http.Request, secret string, body []byte, now time.Time) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, nonce, body))
+	return nil
+}
+
+// newEventID returns a random identifier shared by an event's deliveries
+func newEventID() string {
+	id, err := randomHex(12)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("webhook: failed to generate event id: %v", err))
+	}
+	return "evt_" + id
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
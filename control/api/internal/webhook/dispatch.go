@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inscenium/inscenium/control/api/internal/booking"
+	"github.com/inscenium/inscenium/control/api/internal/eventbus"
+	"github.com/inscenium/inscenium/control/api/internal/jobqueue"
+	"github.com/sirupsen/logrus"
+)
+
+// Queue is the background job queue that posts events to subscriptions
+const Queue = "webhook_delivery"
+
+// DefaultMaxAttempts is how many times a delivery is tried before it fails.
+// With the job queue's backoff the last attempt comes about ten minutes after
+// the first.
+const DefaultMaxAttempts = 8
+
+// Job is the payload of a delivery job
+type Job struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// DispatchStore finds the subscriptions wanting an event and records deliveries
+type DispatchStore interface {
+	ListWebhookTargets(orgID, eventType string) ([]*Subscription, error)
+	CreateWebhookDelivery(delivery *Delivery) error
+	RecordWebhookAttempt(deliveryID, status string, attempts, responseStatus int, lastError string, attemptedAt time.Time) error
+}
+
+// Dispatcher turns committed booking and delivery changes, and expiring API
+// keys, into webhook deliveries. Each delivery is stored before its job is
+// enqueued, so the delivery log shows events whose jobs could not be
+// scheduled.
+type Dispatcher struct {
+	store       DispatchStore
+	queue       jobqueue.Queue
+	maxAttempts int
+	now         func() time.Time
+}
+
+// NewDispatcher creates a dispatcher. maxAttempts <= 0 uses DefaultMaxAttempts.
+func NewDispatcher(store DispatchStore, queue jobqueue.Queue, maxAttempts int) *Dispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	return &Dispatcher{store: store, queue: queue, maxAttempts: maxAttempts, now: time.Now}
+}
+
+// Subscribe dispatches the bus events subscriptions can ask for. Dispatch
+// runs off the publishing goroutine so slow lookups never hold up bookings
+// or exposure ingestion.
+func (d *Dispatcher) Subscribe(bus *eventbus.Bus) {
+	eventbus.SubscribeAsync(bus, "webhook_bookings", 256, func(ctx context.Context, event eventbus.BookingChanged) error {
+		eventType := BookingEventType(event)
+		if eventType == "" {
+			return nil
+		}
+		return d.Dispatch(ctx, eventType, event.OrgID, event.OccurredAt, event)
+	})
+	eventbus.SubscribeAsync(bus, "webhook_delivery_reached", 1024, func(ctx context.Context, event eventbus.DeliveryReached) error {
+		if event.ThresholdPercent > 0 {
+			if err := d.Dispatch(ctx, EventPacingThreshold, event.OrgID, event.OccurredAt, event); err != nil {
+				return err
+			}
+		}
+		if event.Milestone {
+			return d.Dispatch(ctx, EventExposureMilestone, event.OrgID, event.OccurredAt, event)
+		}
+		return nil
+	})
+	eventbus.SubscribeAsync(bus, "webhook_key_expiring", 64, func(ctx context.Context, event eventbus.KeyExpiring) error {
+		return d.Dispatch(ctx, EventAPIKeyExpiring, event.OrgID, event.OccurredAt, event)
+	})
+}
+
+// BookingEventType maps a booking change to the webhook event type it
+// raises, or "" if subscriptions cannot ask for it
+func BookingEventType(event eventbus.BookingChanged) string {
+	switch event.Type {
+	case booking.EventCreated, booking.EventApproved:
+		if event.Status == booking.StatusConfirmed {
+			return EventBookingConfirmed
+		}
+	case booking.EventCancelled:
+		return EventBookingCancelled
+	case booking.EventCompleted:
+		return EventBookingCompleted
+	}
+	return ""
+}
+
+// Dispatch stores a delivery of the event for every active subscription of
+// orgID asking for eventType and enqueues a job to post it
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType, orgID string, occurredAt time.Time, data interface{}) error {
+	targets, err := d.store.ListWebhookTargets(orgID, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to find %s webhook subscriptions: %w", eventType, err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if occurredAt.IsZero() {
+		occurredAt = d.now()
+	}
+	envelope := Envelope{
+		EventID:   newEventID(),
+		Type:      eventType,
+		CreatedAt: occurredAt.UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s webhook payload: %w", eventType, err)
+	}
+
+	for _, subscription := range targets {
+		delivery := &Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        envelope.EventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         DeliveryPending,
+		}
+		if err := d.store.CreateWebhookDelivery(delivery); err != nil {
+			return err
+		}
+		if _, err := d.queue.Enqueue(ctx, Queue, Job{DeliveryID: delivery.ID}, jobqueue.EnqueueOptions{MaxAttempts: d.maxAttempts}); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"delivery_id":     delivery.ID,
+				"subscription_id": subscription.ID,
+				"event_type":      eventType,
+			}).Warn("Failed to enqueue webhook delivery")
+			if err := d.store.RecordWebhookAttempt(delivery.ID, DeliveryFailed, 0, 0, "failed to schedule delivery", d.now()); err != nil {
+				logrus.WithError(err).WithField("delivery_id", delivery.ID).Warn("Failed to record unscheduled webhook delivery")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying a webhook signature
+const (
+	HeaderTimestamp = "X-Inscenium-Timestamp"
+	HeaderNonce     = "X-Inscenium-Nonce"
+	HeaderSignature = "X-Inscenium-Signature"
+)
+
+// signaturePrefix names the algorithm in the signature header
+const signaturePrefix = "sha256="
+
+// DefaultTolerance is how far a callback timestamp may drift from the server clock
+const DefaultTolerance = 5 * time.Minute
+
+// Verification errors
+var (
+	ErrMissingHeaders   = errors.New("missing signature headers")
+	ErrInvalidTimestamp = errors.New("invalid signature timestamp")
+	ErrStaleTimestamp   = errors.New("signature timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Sign returns the signature header value for a payload. The MAC covers the
+// timestamp and nonce so neither can be swapped onto another body.
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks webhook signatures against one or more shared secrets.
+// Several secrets may be active at once while senders rotate.
+type Verifier struct {
+	secrets   []string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier creates a verifier accepting signatures made with any of secrets
+func NewVerifier(secrets []string, tolerance time.Duration) *Verifier {
+	active := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			active = append(active, secret)
+		}
+	}
+	return &Verifier{secrets: active, tolerance: tolerance, now: time.Now}
+}
+
+// Enabled reports whether any secret is configured
+func (v *Verifier) Enabled() bool {
+	return len(v.secrets) > 0
+}
+
+// Verify checks the signature and timestamp of a callback and returns the
+// signed time. Replay protection additionally requires the caller to reject
+// nonces it has already seen within the tolerance window.
+func (v *Verifier) Verify(timestamp, nonce, signature string, body []byte) (time.Time, error) {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return time.Time{}, ErrMissingHeaders
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	signedAt := time.Unix(seconds, 0)
+
+	drift := v.now().Sub(signedAt)
+	if drift > v.tolerance || drift < -v.tolerance {
+		return signedAt, ErrStaleTimestamp
+	}
+
+	for _, secret := range v.secrets {
+		if hmac.Equal([]byte(Sign(secret, timestamp, nonce, body)), []byte(signature)) {
+			return signedAt, nil
+		}
+	}
+	return signedAt, ErrInvalidSignature
+}
+
+// Tolerance is the accepted clock drift, and so how long nonces must be remembered
+func (v *Verifier) Tolerance() time.Duration {
+	return v.tolerance
+}
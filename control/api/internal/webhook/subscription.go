@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Event types partners can subscribe to
+const (
+	EventBookingConfirmed  = "booking.confirmed"  // A booking was confirmed, on creation or approval
+	EventBookingCancelled  = "booking.cancelled"  // A booking was cancelled
+	EventBookingCompleted  = "booking.completed"  // A booking delivered its max_impressions
+	EventPacingThreshold   = "pacing.threshold"   // A booking delivered a share of its max_impressions
+	EventExposureMilestone = "exposure.milestone" // A booking delivered 1,000 impressions, or a further power of ten
+
+	EventCreativeApprovalRequested = "creative.approval_requested" // A booking was made with a creative awaiting approval
+	EventAPIKeyExpiring            = "api_key.expiring"            // A rotated service-account key expires soon
+)
+
+// EventTypes lists the event types in the order they are documented
+var EventTypes = []string{
+	EventBookingConfirmed,
+	EventBookingCancelled,
+	EventBookingCompleted,
+	EventPacingThreshold,
+	EventExposureMilestone,
+	EventCreativeApprovalRequested,
+	EventAPIKeyExpiring,
+}
+
+// SecretPrefix marks subscription signing secrets
+const SecretPrefix = "whsec_"
+
+// MaxDescriptionLength caps a subscription's description
+const MaxDescriptionLength = 255
+
+// Subscription asks for events of some types to be posted to a URL. Each
+// delivery is signed with the subscription's secret like inbound callbacks
+// are, see Sign.
+type Subscription struct {
+	ID          string    `json:"subscription_id"`
+	OrgID       string    `json:"org_id,omitempty"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"-"` // Shown once when created or rotated
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks the URL, event types and description. Deliveries carry
+// booking data, so plain HTTP is only accepted when allowInsecure is set,
+// e.g. in development.
+func (s *Subscription) Validate(allowInsecure bool) error {
+	if err := ValidateURL(s.URL, allowInsecure); err != nil {
+		return err
+	}
+	if len(s.EventTypes) == 0 {
+		return fmt.Errorf("event_types must name at least one event type")
+	}
+	seen := make(map[string]bool, len(s.EventTypes))
+	for _, eventType := range s.EventTypes {
+		if !ValidEventType(eventType) {
+			return fmt.Errorf("unknown event type %q, expected one of %s", eventType, strings.Join(EventTypes, ", "))
+		}
+		if seen[eventType] {
+			return fmt.Errorf("event type %q is listed twice", eventType)
+		}
+		seen[eventType] = true
+	}
+	if len(s.Description) > MaxDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", MaxDescriptionLength)
+	}
+	return nil
+}
+
+// ValidateURL checks that a URL can be posted signed callbacks: absolute,
+// without credentials, and https unless allowInsecure is set
+func ValidateURL(rawURL string, allowInsecure bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if u.Scheme == "http" && !allowInsecure {
+		return fmt.Errorf("url must use https")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	return nil
+}
+
+// Wants reports whether the subscription is active and subscribed to eventType
+func (s *Subscription) Wants(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidEventType reports whether eventType can be subscribed to
+func ValidEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateSecret returns a new signing secret
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return SecretPrefix + hex.EncodeToString(buf), nil
+}
+
+// Delivery statuses. A delivery is pending until its first attempt, retrying
+// after a failed attempt with attempts left, and failed once it has none.
+const (
+	DeliveryPending   = "pending"
+	DeliveryRetrying  = "retrying"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// DeliveryStatuses lists the delivery statuses
+var DeliveryStatuses = []string{DeliveryPending, DeliveryRetrying, DeliveryDelivered, DeliveryFailed}
+
+// Delivery is one event sent, or to be sent, to one subscription
+type Delivery struct {
+	ID             string          `json:"delivery_id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"` // Shared by the deliveries of one event to several subscriptions
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"` // HTTP status of the last attempt, if it got a response
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Envelope is the body posted for an event
+type Envelope struct {
+	EventID   string      `json:"event_id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,491 @@
+// Package websocket implements the parts of the WebSocket protocol (RFC
+// 6455) the API pushes live updates over: the opening handshake, text and
+// binary messages, pings and the closing handshake. Extensions such as
+// per-message compression are not negotiated.
+//
+// Browsers cannot set an Authorization header on the handshake, so clients
+// may offer their bearer token as a subprotocol, bearer.<token>, next to the
+// protocol they speak. The token is never selected or echoed back.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client's key to prove the server speaks WebSocket
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// BearerPrefix marks the subprotocol carrying a client's bearer token
+const BearerPrefix = "bearer."
+
+// DefaultReadLimit bounds the size of a message read when none is set
+const DefaultReadLimit = 64 << 10
+
+// Message types
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+// Frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+// ErrNotWebSocket is returned by Upgrade for requests that do not ask to
+// switch to WebSocket
+var ErrNotWebSocket = errors.New("not a WebSocket handshake")
+
+// CloseError is returned by reads once the peer has closed the connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. One goroutine may read while others
+// write; writes are serialized.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool // Clients mask the frames they send; servers must not
+
+	// Subprotocol is the protocol agreed in the handshake, if any
+	Subprotocol string
+
+	readLimit   int64
+	idleTimeout time.Duration
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// IsUpgrade reports whether r asks to switch to WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// BearerToken returns the token a client offered as a bearer.<token>
+// subprotocol, or "" if it offered none
+func BearerToken(r *http.Request) string {
+	if !IsUpgrade(r) {
+		return ""
+	}
+	for _, protocol := range Subprotocols(r) {
+		if strings.HasPrefix(protocol, BearerPrefix) {
+			return strings.TrimPrefix(protocol, BearerPrefix)
+		}
+	}
+	return ""
+}
+
+// Subprotocols lists the protocols a client offered, in its order of preference
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+// The first of the client's offered protocols found in protocols is
+// selected. Nothing has been written when an error is returned, so the
+// caller still answers the request.
+func Upgrade(w http.ResponseWriter, r *http.Request, protocols []string) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("unsupported WebSocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, fmt.Errorf("invalid Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("connection cannot be taken over")
+	}
+
+	var selected string
+	for _, offered := range Subprotocols(r) {
+		for _, protocol := range protocols {
+			if offered == protocol && selected == "" {
+				selected = protocol
+			}
+		}
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over connection: %w", err)
+	}
+	// Deadlines the HTTP server set for the request must not cut the connection short
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to clear deadlines: %w", err)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n"
+	if selected != "" {
+		response += "Sec-WebSocket-Protocol: " + selected + "\r\n"
+	}
+	if _, err := netConn.Write([]byte(response + "\r\n")); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to complete handshake: %w", err)
+	}
+
+	return &Conn{conn: netConn, reader: rw.Reader, Subprotocol: selected, readLimit: DefaultReadLimit}, nil
+}
+
+// Dial connects to the WebSocket server at rawURL (ws:// only), offering
+// protocols, as tests and tools do
+func Dial(rawURL string, header http.Header, protocols []string) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "ws" {
+		return nil, nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	netConn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	u.Scheme = "http"
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if len(protocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+	}
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		netConn.Close()
+		return nil, resp, fmt.Errorf("handshake failed with status %d", resp.StatusCode)
+	}
+
+	conn := &Conn{conn: netConn, reader: reader, client: true, readLimit: DefaultReadLimit}
+	conn.Subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	return conn, resp, nil
+}
+
+// AcceptKey is the Sec-WebSocket-Accept value answering a client's key
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// SetReadLimit bounds the size of a message; larger ones close the
+// connection with CloseMessageTooBig
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetIdleTimeout fails reads when the peer sends nothing, not even a pong,
+// for d. Zero waits forever.
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	c.idleTimeout = d
+}
+
+// ReadMessage returns the next text or binary message, answering pings
+// meanwhile. Once the peer closes the connection a *CloseError is returned.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType = -1
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return -1, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return -1, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: CloseNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(closeErr.Code, "")
+			return -1, nil, closeErr
+		case opText, opBinary:
+			if messageType != -1 {
+				c.Close(CloseProtocolError, "new message before the last was finished")
+				return -1, nil, fmt.Errorf("unexpected data frame")
+			}
+			messageType = int(opcode)
+		case opContinuation:
+			if messageType == -1 {
+				c.Close(CloseProtocolError, "continuation without a message")
+				return -1, nil, fmt.Errorf("unexpected continuation frame")
+			}
+		default:
+			c.Close(CloseProtocolError, "unknown opcode")
+			return -1, nil, fmt.Errorf("unknown opcode %d", opcode)
+		}
+
+		if int64(len(data)+len(payload)) > c.readLimit {
+			c.Close(CloseMessageTooBig, "message too big")
+			return -1, nil, fmt.Errorf("message exceeds %d bytes", c.readLimit)
+		}
+		data = append(data, payload...)
+		if fin {
+			return messageType, data, nil
+		}
+	}
+}
+
+// ReadJSON reads the next message into v
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage sends one text or binary message
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("unknown message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// WriteJSON sends v as a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// Ping asks the peer to prove it is still there
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// SetWriteDeadline fails writes not completed by t
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame, unless one was already sent, and closes the
+// connection. It is safe to call more than once.
+func (c *Conn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	if !c.closeSent {
+		c.closeSent = true
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.writeFrameLocked(opClose, payload)
+	}
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+// readFrame reads one frame, unmasking the payload of a client's frame
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.idleTimeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		c.Close(CloseProtocolError, "extensions are not supported")
+		return false, 0, nil, fmt.Errorf("reserved bits set")
+	}
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		c.Close(CloseProtocolError, "wrong masking")
+		return false, 0, nil, fmt.Errorf("frame masking does not match its sender")
+	}
+
+	length := int64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(extended[:]))
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		c.Close(CloseProtocolError, "invalid control frame")
+		return false, 0, nil, fmt.Errorf("invalid control frame")
+	}
+	if length < 0 || length > c.readLimit {
+		c.Close(CloseMessageTooBig, "message too big")
+		return false, 0, nil, fmt.Errorf("frame exceeds %d bytes", c.readLimit)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame sends one unfragmented frame
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked is writeFrame for callers holding writeMu
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := start; i < len(frame); i++ {
+			frame[i] ^= mask[(i-start)%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// headerContains reports whether a comma-separated header lists token
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// The example handshake of RFC 6455 section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestBearerToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/watch", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "inscenium.watch.v1, bearer.abc.def")
+	assert.Empty(t, BearerToken(req), "Should only read tokens from WebSocket handshakes")
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	assert.Equal(t, "abc.def", BearerToken(req))
+	assert.Equal(t, []string{"inscenium.watch.v1", "bearer.abc.def"}, Subprotocols(req))
+}
+
+func TestConn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, []string{"echo"})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conn.SetReadLimit(1024)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("echo", func(t *testing.T) {
+		conn, _, err := Dial(url, nil, []string{"bearer.secret", "echo"})
+		require.NoError(t, err)
+		defer conn.Close(CloseNormal, "")
+		assert.Equal(t, "echo", conn.Subprotocol, "Should select a supported protocol, never the token")
+
+		require.NoError(t, conn.Ping())
+		long := strings.Repeat("x", 300) // Needs an extended length
+		for _, message := range []string{"hello", long} {
+			require.NoError(t, conn.WriteMessage(TextMessage, []byte(message)))
+			messageType, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, TextMessage, messageType)
+			assert.Equal(t, message, string(data))
+		}
+	})
+
+	t.Run("too big", func(t *testing.T) {
+		conn, _, err := Dial(url, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(BinaryMessage, make([]byte, 2048)))
+		_, _, err = conn.ReadMessage()
+		var closeErr *CloseError
+		require.True(t, errors.As(err, &closeErr), "Should close rather than read an oversized message")
+		assert.Equal(t, CloseMessageTooBig, closeErr.Code)
+	})
+
+	t.Run("plain request", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,593 @@
+// Inscenium API Protocol Buffer Definitions
+// ========================================
+//
+// This file defines the gRPC service and message types for the Inscenium API.
+// It covers placement opportunities, booking requests, and exposure events.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: graph.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	InsceniumPlacementService_ListPlacementOpportunities_FullMethodName = "/inscenium.api.v1.InsceniumPlacementService/ListPlacementOpportunities"
+	InsceniumPlacementService_GetPlacementOpportunity_FullMethodName    = "/inscenium.api.v1.InsceniumPlacementService/GetPlacementOpportunity"
+	InsceniumPlacementService_BookPlacement_FullMethodName              = "/inscenium.api.v1.InsceniumPlacementService/BookPlacement"
+	InsceniumPlacementService_GetBookingStatus_FullMethodName           = "/inscenium.api.v1.InsceniumPlacementService/GetBookingStatus"
+	InsceniumPlacementService_CancelBooking_FullMethodName              = "/inscenium.api.v1.InsceniumPlacementService/CancelBooking"
+	InsceniumPlacementService_StreamPlacementUpdates_FullMethodName     = "/inscenium.api.v1.InsceniumPlacementService/StreamPlacementUpdates"
+)
+
+// InsceniumPlacementServiceClient is the client API for InsceniumPlacementService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type InsceniumPlacementServiceClient interface {
+	// Query available placement opportunities
+	ListPlacementOpportunities(ctx context.Context, in *ListPlacementOpportunitiesRequest, opts ...grpc.CallOption) (*ListPlacementOpportunitiesResponse, error)
+	GetPlacementOpportunity(ctx context.Context, in *GetPlacementOpportunityRequest, opts ...grpc.CallOption) (*PlacementOpportunity, error)
+	// Book and manage placements
+	BookPlacement(ctx context.Context, in *BookPlacementRequest, opts ...grpc.CallOption) (*BookPlacementResponse, error)
+	GetBookingStatus(ctx context.Context, in *GetBookingStatusRequest, opts ...grpc.CallOption) (*BookPlacementResponse, error)
+	CancelBooking(ctx context.Context, in *CancelBookingRequest, opts ...grpc.CallOption) (*CancelBookingResponse, error)
+	// Real-time updates
+	StreamPlacementUpdates(ctx context.Context, in *StreamPlacementUpdatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlacementOpportunity], error)
+}
+
+type insceniumPlacementServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInsceniumPlacementServiceClient(cc grpc.ClientConnInterface) InsceniumPlacementServiceClient {
+	return &insceniumPlacementServiceClient{cc}
+}
+
+func (c *insceniumPlacementServiceClient) ListPlacementOpportunities(ctx context.Context, in *ListPlacementOpportunitiesRequest, opts ...grpc.CallOption) (*ListPlacementOpportunitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPlacementOpportunitiesResponse)
+	err := c.cc.Invoke(ctx, InsceniumPlacementService_ListPlacementOpportunities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumPlacementServiceClient) GetPlacementOpportunity(ctx context.Context, in *GetPlacementOpportunityRequest, opts ...grpc.CallOption) (*PlacementOpportunity, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlacementOpportunity)
+	err := c.cc.Invoke(ctx, InsceniumPlacementService_GetPlacementOpportunity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumPlacementServiceClient) BookPlacement(ctx context.Context, in *BookPlacementRequest, opts ...grpc.CallOption) (*BookPlacementResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookPlacementResponse)
+	err := c.cc.Invoke(ctx, InsceniumPlacementService_BookPlacement_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumPlacementServiceClient) GetBookingStatus(ctx context.Context, in *GetBookingStatusRequest, opts ...grpc.CallOption) (*BookPlacementResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookPlacementResponse)
+	err := c.cc.Invoke(ctx, InsceniumPlacementService_GetBookingStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumPlacementServiceClient) CancelBooking(ctx context.Context, in *CancelBookingRequest, opts ...grpc.CallOption) (*CancelBookingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelBookingResponse)
+	err := c.cc.Invoke(ctx, InsceniumPlacementService_CancelBooking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumPlacementServiceClient) StreamPlacementUpdates(ctx context.Context, in *StreamPlacementUpdatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlacementOpportunity], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InsceniumPlacementService_ServiceDesc.Streams[0], InsceniumPlacementService_StreamPlacementUpdates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamPlacementUpdatesRequest, PlacementOpportunity]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsceniumPlacementService_StreamPlacementUpdatesClient = grpc.ServerStreamingClient[PlacementOpportunity]
+
+// InsceniumPlacementServiceServer is the server API for InsceniumPlacementService service.
+// All implementations must embed UnimplementedInsceniumPlacementServiceServer
+// for forward compatibility.
+type InsceniumPlacementServiceServer interface {
+	// Query available placement opportunities
+	ListPlacementOpportunities(context.Context, *ListPlacementOpportunitiesRequest) (*ListPlacementOpportunitiesResponse, error)
+	GetPlacementOpportunity(context.Context, *GetPlacementOpportunityRequest) (*PlacementOpportunity, error)
+	// Book and manage placements
+	BookPlacement(context.Context, *BookPlacementRequest) (*BookPlacementResponse, error)
+	GetBookingStatus(context.Context, *GetBookingStatusRequest) (*BookPlacementResponse, error)
+	CancelBooking(context.Context, *CancelBookingRequest) (*CancelBookingResponse, error)
+	// Real-time updates
+	StreamPlacementUpdates(*StreamPlacementUpdatesRequest, grpc.ServerStreamingServer[PlacementOpportunity]) error
+	mustEmbedUnimplementedInsceniumPlacementServiceServer()
+}
+
+// UnimplementedInsceniumPlacementServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInsceniumPlacementServiceServer struct{}
+
+func (UnimplementedInsceniumPlacementServiceServer) ListPlacementOpportunities(context.Context, *ListPlacementOpportunitiesRequest) (*ListPlacementOpportunitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlacementOpportunities not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) GetPlacementOpportunity(context.Context, *GetPlacementOpportunityRequest) (*PlacementOpportunity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPlacementOpportunity not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) BookPlacement(context.Context, *BookPlacementRequest) (*BookPlacementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BookPlacement not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) GetBookingStatus(context.Context, *GetBookingStatusRequest) (*BookPlacementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookingStatus not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) CancelBooking(context.Context, *CancelBookingRequest) (*CancelBookingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelBooking not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) StreamPlacementUpdates(*StreamPlacementUpdatesRequest, grpc.ServerStreamingServer[PlacementOpportunity]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPlacementUpdates not implemented")
+}
+func (UnimplementedInsceniumPlacementServiceServer) mustEmbedUnimplementedInsceniumPlacementServiceServer() {
+}
+func (UnimplementedInsceniumPlacementServiceServer) testEmbeddedByValue() {}
+
+// UnsafeInsceniumPlacementServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InsceniumPlacementServiceServer will
+// result in compilation errors.
+type UnsafeInsceniumPlacementServiceServer interface {
+	mustEmbedUnimplementedInsceniumPlacementServiceServer()
+}
+
+func RegisterInsceniumPlacementServiceServer(s grpc.ServiceRegistrar, srv InsceniumPlacementServiceServer) {
+	// If the following call pancis, it indicates UnimplementedInsceniumPlacementServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InsceniumPlacementService_ServiceDesc, srv)
+}
+
+func _InsceniumPlacementService_ListPlacementOpportunities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPlacementOpportunitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumPlacementServiceServer).ListPlacementOpportunities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumPlacementService_ListPlacementOpportunities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumPlacementServiceServer).ListPlacementOpportunities(ctx, req.(*ListPlacementOpportunitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumPlacementService_GetPlacementOpportunity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPlacementOpportunityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumPlacementServiceServer).GetPlacementOpportunity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumPlacementService_GetPlacementOpportunity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumPlacementServiceServer).GetPlacementOpportunity(ctx, req.(*GetPlacementOpportunityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumPlacementService_BookPlacement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BookPlacementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumPlacementServiceServer).BookPlacement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumPlacementService_BookPlacement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumPlacementServiceServer).BookPlacement(ctx, req.(*BookPlacementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumPlacementService_GetBookingStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookingStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumPlacementServiceServer).GetBookingStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumPlacementService_GetBookingStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumPlacementServiceServer).GetBookingStatus(ctx, req.(*GetBookingStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumPlacementService_CancelBooking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelBookingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumPlacementServiceServer).CancelBooking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumPlacementService_CancelBooking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumPlacementServiceServer).CancelBooking(ctx, req.(*CancelBookingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumPlacementService_StreamPlacementUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPlacementUpdatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InsceniumPlacementServiceServer).StreamPlacementUpdates(m, &grpc.GenericServerStream[StreamPlacementUpdatesRequest, PlacementOpportunity]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsceniumPlacementService_StreamPlacementUpdatesServer = grpc.ServerStreamingServer[PlacementOpportunity]
+
+// InsceniumPlacementService_ServiceDesc is the grpc.ServiceDesc for InsceniumPlacementService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InsceniumPlacementService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "inscenium.api.v1.InsceniumPlacementService",
+	HandlerType: (*InsceniumPlacementServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPlacementOpportunities",
+			Handler:    _InsceniumPlacementService_ListPlacementOpportunities_Handler,
+		},
+		{
+			MethodName: "GetPlacementOpportunity",
+			Handler:    _InsceniumPlacementService_GetPlacementOpportunity_Handler,
+		},
+		{
+			MethodName: "BookPlacement",
+			Handler:    _InsceniumPlacementService_BookPlacement_Handler,
+		},
+		{
+			MethodName: "GetBookingStatus",
+			Handler:    _InsceniumPlacementService_GetBookingStatus_Handler,
+		},
+		{
+			MethodName: "CancelBooking",
+			Handler:    _InsceniumPlacementService_CancelBooking_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPlacementUpdates",
+			Handler:       _InsceniumPlacementService_StreamPlacementUpdates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "graph.proto",
+}
+
+const (
+	InsceniumMeasurementService_RecordExposure_FullMethodName       = "/inscenium.api.v1.InsceniumMeasurementService/RecordExposure"
+	InsceniumMeasurementService_BatchRecordExposures_FullMethodName = "/inscenium.api.v1.InsceniumMeasurementService/BatchRecordExposures"
+	InsceniumMeasurementService_GetAggregateMetrics_FullMethodName  = "/inscenium.api.v1.InsceniumMeasurementService/GetAggregateMetrics"
+	InsceniumMeasurementService_GetExposureEvents_FullMethodName    = "/inscenium.api.v1.InsceniumMeasurementService/GetExposureEvents"
+	InsceniumMeasurementService_StreamMetrics_FullMethodName        = "/inscenium.api.v1.InsceniumMeasurementService/StreamMetrics"
+)
+
+// InsceniumMeasurementServiceClient is the client API for InsceniumMeasurementService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type InsceniumMeasurementServiceClient interface {
+	// Record exposure events
+	RecordExposure(ctx context.Context, in *ExposureEvent, opts ...grpc.CallOption) (*RecordExposureResponse, error)
+	BatchRecordExposures(ctx context.Context, in *BatchRecordExposuresRequest, opts ...grpc.CallOption) (*BatchRecordExposuresResponse, error)
+	// Retrieve measurement data
+	GetAggregateMetrics(ctx context.Context, in *GetAggregateMetricsRequest, opts ...grpc.CallOption) (*AggregateMetrics, error)
+	GetExposureEvents(ctx context.Context, in *GetExposureEventsRequest, opts ...grpc.CallOption) (*GetExposureEventsResponse, error)
+	// Real-time metrics
+	StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregateMetrics], error)
+}
+
+type insceniumMeasurementServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInsceniumMeasurementServiceClient(cc grpc.ClientConnInterface) InsceniumMeasurementServiceClient {
+	return &insceniumMeasurementServiceClient{cc}
+}
+
+func (c *insceniumMeasurementServiceClient) RecordExposure(ctx context.Context, in *ExposureEvent, opts ...grpc.CallOption) (*RecordExposureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordExposureResponse)
+	err := c.cc.Invoke(ctx, InsceniumMeasurementService_RecordExposure_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumMeasurementServiceClient) BatchRecordExposures(ctx context.Context, in *BatchRecordExposuresRequest, opts ...grpc.CallOption) (*BatchRecordExposuresResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchRecordExposuresResponse)
+	err := c.cc.Invoke(ctx, InsceniumMeasurementService_BatchRecordExposures_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumMeasurementServiceClient) GetAggregateMetrics(ctx context.Context, in *GetAggregateMetricsRequest, opts ...grpc.CallOption) (*AggregateMetrics, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregateMetrics)
+	err := c.cc.Invoke(ctx, InsceniumMeasurementService_GetAggregateMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumMeasurementServiceClient) GetExposureEvents(ctx context.Context, in *GetExposureEventsRequest, opts ...grpc.CallOption) (*GetExposureEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetExposureEventsResponse)
+	err := c.cc.Invoke(ctx, InsceniumMeasurementService_GetExposureEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insceniumMeasurementServiceClient) StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregateMetrics], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InsceniumMeasurementService_ServiceDesc.Streams[0], InsceniumMeasurementService_StreamMetrics_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMetricsRequest, AggregateMetrics]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsceniumMeasurementService_StreamMetricsClient = grpc.ServerStreamingClient[AggregateMetrics]
+
+// InsceniumMeasurementServiceServer is the server API for InsceniumMeasurementService service.
+// All implementations must embed UnimplementedInsceniumMeasurementServiceServer
+// for forward compatibility.
+type InsceniumMeasurementServiceServer interface {
+	// Record exposure events
+	RecordExposure(context.Context, *ExposureEvent) (*RecordExposureResponse, error)
+	BatchRecordExposures(context.Context, *BatchRecordExposuresRequest) (*BatchRecordExposuresResponse, error)
+	// Retrieve measurement data
+	GetAggregateMetrics(context.Context, *GetAggregateMetricsRequest) (*AggregateMetrics, error)
+	GetExposureEvents(context.Context, *GetExposureEventsRequest) (*GetExposureEventsResponse, error)
+	// Real-time metrics
+	StreamMetrics(*StreamMetricsRequest, grpc.ServerStreamingServer[AggregateMetrics]) error
+	mustEmbedUnimplementedInsceniumMeasurementServiceServer()
+}
+
+// UnimplementedInsceniumMeasurementServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInsceniumMeasurementServiceServer struct{}
+
+func (UnimplementedInsceniumMeasurementServiceServer) RecordExposure(context.Context, *ExposureEvent) (*RecordExposureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordExposure not implemented")
+}
+func (UnimplementedInsceniumMeasurementServiceServer) BatchRecordExposures(context.Context, *BatchRecordExposuresRequest) (*BatchRecordExposuresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchRecordExposures not implemented")
+}
+func (UnimplementedInsceniumMeasurementServiceServer) GetAggregateMetrics(context.Context, *GetAggregateMetricsRequest) (*AggregateMetrics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAggregateMetrics not implemented")
+}
+func (UnimplementedInsceniumMeasurementServiceServer) GetExposureEvents(context.Context, *GetExposureEventsRequest) (*GetExposureEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExposureEvents not implemented")
+}
+func (UnimplementedInsceniumMeasurementServiceServer) StreamMetrics(*StreamMetricsRequest, grpc.ServerStreamingServer[AggregateMetrics]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMetrics not implemented")
+}
+func (UnimplementedInsceniumMeasurementServiceServer) mustEmbedUnimplementedInsceniumMeasurementServiceServer() {
+}
+func (UnimplementedInsceniumMeasurementServiceServer) testEmbeddedByValue() {}
+
+// UnsafeInsceniumMeasurementServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InsceniumMeasurementServiceServer will
+// result in compilation errors.
+type UnsafeInsceniumMeasurementServiceServer interface {
+	mustEmbedUnimplementedInsceniumMeasurementServiceServer()
+}
+
+func RegisterInsceniumMeasurementServiceServer(s grpc.ServiceRegistrar, srv InsceniumMeasurementServiceServer) {
+	// If the following call pancis, it indicates UnimplementedInsceniumMeasurementServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InsceniumMeasurementService_ServiceDesc, srv)
+}
+
+func _InsceniumMeasurementService_RecordExposure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExposureEvent)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumMeasurementServiceServer).RecordExposure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumMeasurementService_RecordExposure_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumMeasurementServiceServer).RecordExposure(ctx, req.(*ExposureEvent))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumMeasurementService_BatchRecordExposures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRecordExposuresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumMeasurementServiceServer).BatchRecordExposures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumMeasurementService_BatchRecordExposures_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumMeasurementServiceServer).BatchRecordExposures(ctx, req.(*BatchRecordExposuresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumMeasurementService_GetAggregateMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAggregateMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumMeasurementServiceServer).GetAggregateMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumMeasurementService_GetAggregateMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumMeasurementServiceServer).GetAggregateMetrics(ctx, req.(*GetAggregateMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumMeasurementService_GetExposureEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExposureEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsceniumMeasurementServiceServer).GetExposureEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InsceniumMeasurementService_GetExposureEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsceniumMeasurementServiceServer).GetExposureEvents(ctx, req.(*GetExposureEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsceniumMeasurementService_StreamMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InsceniumMeasurementServiceServer).StreamMetrics(m, &grpc.GenericServerStream[StreamMetricsRequest, AggregateMetrics]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InsceniumMeasurementService_StreamMetricsServer = grpc.ServerStreamingServer[AggregateMetrics]
+
+// InsceniumMeasurementService_ServiceDesc is the grpc.ServiceDesc for InsceniumMeasurementService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InsceniumMeasurementService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "inscenium.api.v1.InsceniumMeasurementService",
+	HandlerType: (*InsceniumMeasurementServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RecordExposure",
+			Handler:    _InsceniumMeasurementService_RecordExposure_Handler,
+		},
+		{
+			MethodName: "BatchRecordExposures",
+			Handler:    _InsceniumMeasurementService_BatchRecordExposures_Handler,
+		},
+		{
+			MethodName: "GetAggregateMetrics",
+			Handler:    _InsceniumMeasurementService_GetAggregateMetrics_Handler,
+		},
+		{
+			MethodName: "GetExposureEvents",
+			Handler:    _InsceniumMeasurementService_GetExposureEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMetrics",
+			Handler:       _InsceniumMeasurementService_StreamMetrics_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "graph.proto",
+}
@@ -0,0 +1,390 @@
+package manifest
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DASH signaling. Placements are written into each MPD Period they start
+// in as the Events of an EventStream with SchemeIDURI, or carried in-band
+// as emsg boxes of the same scheme. Either way an event's message data is
+// the placement's EXT-X-DATERANGE attribute list, so it holds the same
+// ID, START-DATE, DURATION and X-INSCENIUM-* attributes as in HLS and is
+// read back with the same parser.
+const (
+	// SchemeIDURI identifies Inscenium placement events
+	SchemeIDURI = "urn:inscenium:placement"
+	// SchemeValue is the version of the event message data
+	SchemeValue = "1"
+	// EventTimescale is the timescale of written EventStreams, in ticks per second
+	EventTimescale = 1000
+)
+
+// ErrNotMPD is returned for documents that are not a DASH MPD
+var ErrNotMPD = errors.New("manifest: document is not a DASH MPD")
+
+// Period is a Period of an MPD
+type Period struct {
+	ID       string
+	Start    float64   // Seconds from the start of the presentation
+	Duration float64   // Seconds; 0 for the open-ended last period of a live MPD
+	Time     time.Time // When the period starts playing
+	insert   int       // Offset just after the Period start tag
+	indent   string
+}
+
+// End returns when the period stops playing, or the zero time when it is
+// open-ended
+func (p Period) End() time.Time {
+	if p.Duration == 0 {
+		return time.Time{}
+	}
+	return p.Time.Add(seconds(p.Duration))
+}
+
+// contains reports whether t falls within the period
+func (p Period) contains(t time.Time) bool {
+	return !t.Before(p.Time) && (p.Duration == 0 || t.Before(p.End()))
+}
+
+// MPDProcessor injects placement metadata into a DASH MPD
+type MPDProcessor struct {
+	mpd     string
+	periods []Period
+	live    bool
+}
+
+// NewMPDProcessor parses an MPD. Periods of dynamic (live) MPDs play at
+// their start after availabilityStartTime; those of static ones at their
+// start after start. Periods without a start follow the one before, and
+// the last period's duration defaults to the rest of
+// mediaPresentationDuration.
+func NewMPDProcessor(mpd string, start time.Time) (*MPDProcessor, error) {
+	mp := &MPDProcessor{mpd: mpd}
+	decoder := xml.NewDecoder(strings.NewReader(mpd))
+	var total float64
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manifest: invalid MPD: %w", err)
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root {
+			if element.Name.Local != "MPD" {
+				return nil, ErrNotMPD
+			}
+			root = false
+			mp.live = attribute(element, "type") == "dynamic"
+			if mp.live {
+				availability := attribute(element, "availabilityStartTime")
+				if start, err = time.Parse(time.RFC3339Nano, availability); err != nil {
+					return nil, fmt.Errorf("manifest: dynamic MPD has invalid availabilityStartTime %q", availability)
+				}
+			}
+			if value := attribute(element, "mediaPresentationDuration"); value != "" {
+				if total, err = parseXSDuration(value); err != nil {
+					return nil, fmt.Errorf("manifest: invalid mediaPresentationDuration: %w", err)
+				}
+			}
+			continue
+		}
+		if element.Name.Local != "Period" {
+			continue
+		}
+
+		period := Period{insert: int(decoder.InputOffset()), Start: -1}
+		if strings.HasSuffix(mpd[:period.insert], "/>") {
+			continue // An empty period has nothing to signal in
+		}
+		period.indent = lineIndent(mpd, period.insert)
+		for _, attr := range element.Attr {
+			switch attr.Name.Local {
+			case "id":
+				period.ID = attr.Value
+			case "start":
+				if period.Start, err = parseXSDuration(attr.Value); err != nil {
+					return nil, fmt.Errorf("manifest: period %q: invalid start: %w", period.ID, err)
+				}
+			case "duration":
+				if period.Duration, err = parseXSDuration(attr.Value); err != nil {
+					return nil, fmt.Errorf("manifest: period %q: invalid duration: %w", period.ID, err)
+				}
+			}
+		}
+		mp.periods = append(mp.periods, period)
+	}
+	if root {
+		return nil, ErrNotMPD
+	}
+	mp.timePeriods(start, total)
+	return mp, nil
+}
+
+// timePeriods fills in the periods' starts and durations and sets when
+// each plays
+func (mp *MPDProcessor) timePeriods(start time.Time, total float64) {
+	for i := range mp.periods {
+		p := &mp.periods[i]
+		if p.Start < 0 {
+			p.Start = 0
+			if i > 0 {
+				p.Start = mp.periods[i-1].Start + mp.periods[i-1].Duration
+			}
+		}
+		p.Time = start.Add(seconds(p.Start))
+	}
+	for i := range mp.periods {
+		p := &mp.periods[i]
+		if p.Duration > 0 {
+			continue
+		}
+		switch {
+		case i+1 < len(mp.periods):
+			p.Duration = mp.periods[i+1].Start - p.Start
+		case total > p.Start:
+			p.Duration = total - p.Start
+		}
+	}
+}
+
+// lineIndent returns the indentation of the line holding offset, plus a
+// level
+func lineIndent(s string, offset int) string {
+	start := strings.LastIndexByte(s[:offset], '\n') + 1
+	line := s[start:offset]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))] + "  "
+}
+
+// parseXSDuration reads an xs:duration such as PT1H2M3.5S, in seconds.
+// Years and months have no fixed length and are rejected.
+func parseXSDuration(value string) (float64, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(value), "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	total := 0.0
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexAny(rest, "YMWDHS")
+		if end <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		n, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		switch unit := rest[end]; {
+		case unit == 'D' && !inTime:
+			total += n * 86400
+		case unit == 'W' && !inTime:
+			total += n * 7 * 86400
+		case unit == 'H' && inTime:
+			total += n * 3600
+		case unit == 'M' && inTime:
+			total += n * 60
+		case unit == 'S' && inTime:
+			total += n
+		default:
+			return 0, fmt.Errorf("unsupported duration %q", value)
+		}
+		rest = rest[end+1:]
+	}
+	return total, nil
+}
+
+// Periods returns the MPD's periods in document order
+func (mp *MPDProcessor) Periods() []Period {
+	return append([]Period(nil), mp.periods...)
+}
+
+// Live reports whether the MPD is dynamic, i.e. a live one
+func (mp *MPDProcessor) Live() bool {
+	return mp.live
+}
+
+// PeriodFor returns the period a placement is signaled in: the one playing
+// when it starts. As with SegmentFor, in live MPDs a placement still
+// running when the first period starts is signaled in it. ok is false for
+// placements outside the MPD.
+func (mp *MPDProcessor) PeriodFor(p PlacementMetadata) (period Period, ok bool) {
+	i := mp.periodAt(p.StartTime, p.StartTime.Add(seconds(p.Duration)))
+	if i < 0 {
+		return Period{}, false
+	}
+	return mp.periods[i], true
+}
+
+// periodAt returns the index of the period playing at start, or -1
+func (mp *MPDProcessor) periodAt(start, end time.Time) int {
+	for i, p := range mp.periods {
+		if p.contains(start) {
+			return i
+		}
+	}
+	if mp.live && len(mp.periods) > 0 {
+		first := mp.periods[0]
+		if start.Before(first.Time) && end.After(first.Time) {
+			return 0
+		}
+	}
+	return -1
+}
+
+// InjectPlacementMetadata returns the MPD with an EventStream of
+// SchemeIDURI first in each period that placements are signaled in (see
+// PeriodFor), holding an Event per placement, earliest first. Event times
+// are relative to the period; a placement that started before its period
+// is given the part still to play. Placements outside the MPD are left
+// out and the MPD is otherwise unchanged.
+func (mp *MPDProcessor) InjectPlacementMetadata(placements []PlacementMetadata) (string, error) {
+	events := make(map[int][]PlacementMetadata) // Period index -> placements signaled in it
+	for _, placement := range placements {
+		if err := placement.Validate(); err != nil {
+			return "", err
+		}
+		i := mp.periodAt(placement.StartTime, placement.StartTime.Add(seconds(placement.Duration)))
+		if i < 0 {
+			continue
+		}
+		events[i] = append(events[i], placement)
+	}
+	if len(events) == 0 {
+		return mp.mpd, nil
+	}
+
+	var result strings.Builder
+	next := 0
+	for i, period := range mp.periods {
+		signaled := events[i]
+		if len(signaled) == 0 {
+			continue
+		}
+		sort.SliceStable(signaled, func(a, b int) bool {
+			return signaled[a].StartTime.Before(signaled[b].StartTime)
+		})
+		result.WriteString(mp.mpd[next:period.insert])
+		result.WriteString("\n" + period.indent + formatEventStream(period, signaled))
+		next = period.insert
+	}
+	result.WriteString(mp.mpd[next:])
+	return result.String(), nil
+}
+
+// formatEventStream writes placements as an EventStream of a period
+func formatEventStream(period Period, placements []PlacementMetadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<EventStream schemeIdUri="%s" value="%s" timescale="%d">`, SchemeIDURI, SchemeValue, EventTimescale)
+	for _, p := range placements {
+		at, duration := p.StartTime.Sub(period.Time), seconds(p.Duration)
+		if at < 0 {
+			duration += at
+			at = 0
+		}
+		fmt.Fprintf(&b, "\n%s  ", period.indent)
+		fmt.Fprintf(&b, `<Event presentationTime="%d" duration="%d" id="%d">%s</Event>`,
+			ticks(at), ticks(duration), EventID(p.ID), xmlText.Replace(FormatEventMessage(p)))
+	}
+	fmt.Fprintf(&b, "\n%s</EventStream>", period.indent)
+	return b.String()
+}
+
+// ticks converts a duration to EventTimescale ticks
+func ticks(d time.Duration) int64 {
+	return int64(math.Round(d.Seconds() * EventTimescale))
+}
+
+// xmlText escapes element text; quotes may stay as they are
+var xmlText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// EventID returns the numeric event id of a placement. DASH event ids are
+// unsigned integers, so the placement's own ID is carried in the message
+// data and the id is a hash of it, the same in every period and segment.
+func EventID(placementID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(placementID))
+	return h.Sum32()
+}
+
+// FormatEventMessage writes a placement as the message data of a DASH
+// event: the attribute list of its EXT-X-DATERANGE tag
+func FormatEventMessage(p PlacementMetadata) string {
+	return strings.TrimPrefix(FormatDateRange(p), TagDateRange)
+}
+
+// ParseEventMessage reads a placement from the message data of a DASH
+// event, or returns nil if it does not signal one
+func ParseEventMessage(data string) (*PlacementMetadata, error) {
+	return ParseDateRange(data)
+}
+
+// ExtractEventStreamMetadata reads the placements signaled in an MPD's
+// EventStreams of SchemeIDURI, in document order. Events of other
+// schemes, such as SCTE-35 splices, are skipped.
+func ExtractEventStreamMetadata(mpd string) ([]PlacementMetadata, error) {
+	decoder := xml.NewDecoder(strings.NewReader(mpd))
+	var placements []PlacementMetadata
+	inStream := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return placements, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manifest: invalid MPD: %w", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch {
+			case element.Name.Local == "EventStream":
+				inStream = attribute(element, "schemeIdUri") == SchemeIDURI
+			case element.Name.Local == "Event" && inStream:
+				var data string
+				if err := decoder.DecodeElement(&data, &element); err != nil {
+					return nil, fmt.Errorf("manifest: invalid event: %w", err)
+				}
+				placement, err := ParseEventMessage(strings.TrimSpace(data))
+				if err != nil {
+					return nil, fmt.Errorf("manifest: event %s: %w", attribute(element, "id"), err)
+				}
+				if placement != nil {
+					placements = append(placements, *placement)
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "EventStream" {
+				inStream = false
+			}
+		}
+	}
+}
+
+// attribute returns the value of an element's attribute, or ""
+func attribute(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package manifest
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const staticMPD = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT1M30S" profiles="urn:mpeg:dash:profile:isoff-on-demand:2011">
+  <Period id="main" duration="PT1M">
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="1080p" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="credits">
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="1080p" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestMPDProcessor(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	mp, err := NewMPDProcessor(staticMPD, start)
+	if err != nil {
+		t.Fatalf("NewMPDProcessor: %v", err)
+	}
+	periods := mp.Periods()
+	if len(periods) != 2 || periods[1].Start != 60 || periods[1].Duration != 30 || !periods[1].Time.Equal(start.Add(time.Minute)) {
+		t.Fatalf("Expected a second period from 60s for 30s, got %+v", periods)
+	}
+	if mp.Live() {
+		t.Error("Static MPDs are not live")
+	}
+
+	placements := []PlacementMetadata{
+		{ID: "placement_002", StartTime: start.Add(75 * time.Second), Duration: 4, SurfaceID: "surf_002", PRSScore: 92.1, PlacementType: "screen & sign"},
+		{ID: "placement_001", StartTime: start.Add(5500 * time.Millisecond), Duration: 5, SurfaceID: "surf_001", PRSScore: 87.5, PlacementType: "billboard"},
+		{ID: "placement_late", StartTime: start.Add(95 * time.Second), Duration: 5, SurfaceID: "surf_003"},
+	}
+	if _, ok := mp.PeriodFor(placements[2]); ok {
+		t.Error("Placements after the MPD should not be signaled")
+	}
+	decorated, err := mp.InjectPlacementMetadata(placements)
+	if err != nil {
+		t.Fatalf("InjectPlacementMetadata: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT1M30S" profiles="urn:mpeg:dash:profile:isoff-on-demand:2011">
+  <Period id="main" duration="PT1M">
+    <EventStream schemeIdUri="urn:inscenium:placement" value="1" timescale="1000">
+      <Event presentationTime="5500" duration="5000" id="` + itoa(EventID("placement_001")) + `">ID="placement_001",START-DATE="2024-01-15T10:30:05.5Z",DURATION=5,X-INSCENIUM-SURFACE-ID="surf_001",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="billboard"</Event>
+    </EventStream>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="1080p" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="credits">
+    <EventStream schemeIdUri="urn:inscenium:placement" value="1" timescale="1000">
+      <Event presentationTime="15000" duration="4000" id="` + itoa(EventID("placement_002")) + `">ID="placement_002",START-DATE="2024-01-15T10:31:15Z",DURATION=4,X-INSCENIUM-SURFACE-ID="surf_002",X-INSCENIUM-PRS="92.1",X-INSCENIUM-PLACEMENT-TYPE="screen &amp; sign"</Event>
+    </EventStream>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="1080p" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	if decorated != want {
+		t.Errorf("Unexpected MPD:\n%s\nwant:\n%s", decorated, want)
+	}
+
+	extracted, err := ExtractEventStreamMetadata(decorated)
+	if err != nil {
+		t.Fatalf("ExtractEventStreamMetadata: %v", err)
+	}
+	if len(extracted) != 2 {
+		t.Fatalf("Expected 2 placements, got %+v", extracted)
+	}
+	for i, p := range []PlacementMetadata{placements[1], placements[0]} {
+		got := extracted[i]
+		if got.ID != p.ID || !got.StartTime.Equal(p.StartTime) || got.Duration != p.Duration || got.PlacementType != p.PlacementType {
+			t.Errorf("Placement %d: expected %+v, got %+v", i, p, got)
+		}
+	}
+
+	if unchanged, _ := mp.InjectPlacementMetadata(placements[2:]); unchanged != staticMPD {
+		t.Error("An MPD without placements in it should be unchanged")
+	}
+}
+
+func TestMPDProcessor_Live(t *testing.T) {
+	mpd := `<MPD type="dynamic" availabilityStartTime="2024-01-15T10:00:00Z">
+<Period id="p1" start="PT1H"><AdaptationSet/></Period>
+</MPD>`
+	mp, err := NewMPDProcessor(mpd, time.Time{})
+	if err != nil {
+		t.Fatalf("NewMPDProcessor: %v", err)
+	}
+	if !mp.Live() || mp.Periods()[0].Duration != 0 {
+		t.Fatalf("Expected an open-ended live period, got %+v", mp.Periods())
+	}
+
+	periodStart := time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)
+	ongoing := PlacementMetadata{ID: "ongoing", StartTime: periodStart.Add(-2 * time.Second), Duration: 5, SurfaceID: "surf_001"}
+	later := PlacementMetadata{ID: "later", StartTime: periodStart.Add(3 * time.Hour), Duration: 5, SurfaceID: "surf_002"}
+	decorated, err := mp.InjectPlacementMetadata([]PlacementMetadata{later, ongoing})
+	if err != nil {
+		t.Fatalf("InjectPlacementMetadata: %v", err)
+	}
+	want := `<MPD type="dynamic" availabilityStartTime="2024-01-15T10:00:00Z">
+<Period id="p1" start="PT1H">
+  <EventStream schemeIdUri="urn:inscenium:placement" value="1" timescale="1000">
+    <Event presentationTime="0" duration="3000" id="` + itoa(EventID("ongoing")) + `">` + FormatEventMessage(ongoing) + `</Event>
+    <Event presentationTime="10800000" duration="5000" id="` + itoa(EventID("later")) + `">` + FormatEventMessage(later) + `</Event>
+  </EventStream><AdaptationSet/></Period>
+</MPD>`
+	if decorated != want {
+		t.Errorf("Unexpected MPD:\n%s\nwant:\n%s", decorated, want)
+	}
+}
+
+func TestMPDProcessor_Invalid(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	for name, mpd := range map[string]string{
+		"playlist":     "#EXTM3U\n#EXTINF:10,\nsegment.ts",
+		"not mpd":      `<Playlist/>`,
+		"availability": `<MPD type="dynamic"><Period/></MPD>`,
+		"duration":     `<MPD type="static" mediaPresentationDuration="P1M"><Period/></MPD>`,
+		"start":        `<MPD><Period start="90"></Period></MPD>`,
+		"unclosed":     `<MPD><Period>`,
+	} {
+		if _, err := NewMPDProcessor(mpd, start); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewMPDProcessor(`<Playlist/>`, start); !errors.Is(err, ErrNotMPD) {
+		t.Errorf("Expected ErrNotMPD, got %v", err)
+	}
+}
+
+func TestEmsg(t *testing.T) {
+	p := PlacementMetadata{
+		ID:            "placement_001",
+		StartTime:     time.Date(2024, 1, 15, 10, 30, 5, 0, time.UTC),
+		Duration:      5,
+		SurfaceID:     "surf_001",
+		PRSScore:      87.5,
+		PlacementType: "billboard",
+	}
+	box, err := FormatEmsg(p, 90000, 90000*125/10)
+	if err != nil {
+		t.Fatalf("FormatEmsg: %v", err)
+	}
+	got, at, err := ParseEmsg(box)
+	if err != nil {
+		t.Fatalf("ParseEmsg: %v", err)
+	}
+	if got == nil || got.ID != p.ID || !got.StartTime.Equal(p.StartTime) || got.Duration != p.Duration || got.SurfaceID != p.SurfaceID {
+		t.Errorf("Expected %+v, got %+v", p, got)
+	}
+	if at != 12500*time.Millisecond {
+		t.Errorf("Expected the event at 12.5s, got %v", at)
+	}
+
+	if _, _, err := ParseEmsg(box[:len(box)-1]); err == nil {
+		t.Error("Expected an error for a truncated box")
+	}
+	box[8] = 0
+	if _, _, err := ParseEmsg(box); !errors.Is(err, ErrNotEmsg) {
+		t.Errorf("Expected ErrNotEmsg for a version 0 box, got %v", err)
+	}
+}
+
+func itoa(id uint32) string {
+	return formatDecimal(float64(id))
+}
@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TagDateRange is the HLS tag placements are signaled with
+const TagDateRange = "#EXT-X-DATERANGE:"
+
+// Inscenium client attributes of EXT-X-DATERANGE tags
+const (
+	AttrSurfaceID     = "X-INSCENIUM-SURFACE-ID"
+	AttrPRS           = "X-INSCENIUM-PRS"
+	AttrPlacementType = "X-INSCENIUM-PLACEMENT-TYPE"
+)
+
+// HLS Interstitial attributes of EXT-X-DATERANGE tags
+const (
+	ClassInterstitial = "com.apple.hls.interstitial"
+	AttrAssetURI      = "X-ASSET-URI"
+	AttrResumeOffset  = "X-RESUME-OFFSET"
+)
+
+// FormatDateRange writes a placement as an EXT-X-DATERANGE tag. A
+// placement with an interstitial is also of the HLS Interstitial class,
+// with the asset and resume offset to play.
+func FormatDateRange(p PlacementMetadata) string {
+	tag := TagDateRange + `ID="` + p.ID + `",`
+	if p.Interstitial != nil {
+		tag += `CLASS="` + ClassInterstitial + `",`
+	}
+	tag += `START-DATE="` + p.StartTime.UTC().Format(time.RFC3339Nano) + `",` +
+		`DURATION=` + formatDecimal(p.Duration) + `,` +
+		AttrSurfaceID + `="` + p.SurfaceID + `",` +
+		AttrPRS + `="` + formatDecimal(p.PRSScore) + `",` +
+		AttrPlacementType + `="` + p.PlacementType + `"`
+	if p.Interstitial != nil {
+		tag += `,` + AttrAssetURI + `="` + p.Interstitial.AssetURI + `",` +
+			AttrResumeOffset + `=` + formatDecimal(p.Interstitial.ResumeOffset)
+	}
+	return tag
+}
+
+// formatDecimal writes a decimal-floating-point value in its shortest form
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ExtractDateRangeMetadata reads the placements signaled in a playlist, in
+// playlist order. EXT-X-DATERANGE tags without Inscenium attributes, such
+// as ad break signaling, are skipped.
+func ExtractDateRangeMetadata(manifest string) ([]PlacementMetadata, error) {
+	var placements []PlacementMetadata
+	for i, line := range strings.Split(manifest, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, TagDateRange) {
+			continue
+		}
+		placement, err := ParseDateRange(line)
+		if err != nil {
+			return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+		}
+		if placement != nil {
+			placements = append(placements, *placement)
+		}
+	}
+	return placements, nil
+}
+
+// ParseDateRange reads a placement from an EXT-X-DATERANGE tag, or returns
+// nil if the tag does not signal one
+func ParseDateRange(tag string) (*PlacementMetadata, error) {
+	attributes, err := ParseAttributeList(strings.TrimPrefix(strings.TrimSpace(tag), TagDateRange))
+	if err != nil {
+		return nil, err
+	}
+	surfaceID, ok := attributes[AttrSurfaceID]
+	if !ok {
+		return nil, nil
+	}
+
+	p := &PlacementMetadata{
+		ID:            attributes["ID"],
+		SurfaceID:     surfaceID,
+		PlacementType: attributes[AttrPlacementType],
+	}
+	if p.ID == "" {
+		return nil, fmt.Errorf("placement %s has no ID", surfaceID)
+	}
+	if p.StartTime, err = time.Parse(time.RFC3339Nano, attributes["START-DATE"]); err != nil {
+		return nil, fmt.Errorf("placement %s: invalid START-DATE %q", p.ID, attributes["START-DATE"])
+	}
+	if value, ok := attributes["DURATION"]; ok {
+		if p.Duration, err = strconv.ParseFloat(value, 64); err != nil || p.Duration < 0 {
+			return nil, fmt.Errorf("placement %s: invalid DURATION %q", p.ID, value)
+		}
+	}
+	if value, ok := attributes[AttrPRS]; ok {
+		if p.PRSScore, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("placement %s: invalid %s %q", p.ID, AttrPRS, value)
+		}
+	}
+	if attributes["CLASS"] == ClassInterstitial {
+		p.Interstitial = &Interstitial{AssetURI: attributes[AttrAssetURI]}
+		if value, ok := attributes[AttrResumeOffset]; ok {
+			if p.Interstitial.ResumeOffset, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("placement %s: invalid %s %q", p.ID, AttrResumeOffset, value)
+			}
+		}
+	}
+	return p, nil
+}
+
+// ParseAttributeList reads an HLS attribute list, NAME=VALUE pairs
+// separated by commas. Quoted-string values are returned without their
+// quotes and may contain commas.
+func ParseAttributeList(list string) (map[string]string, error) {
+	attributes := make(map[string]string)
+	for rest := strings.TrimSpace(list); rest != ""; {
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("attribute %q has no value", rest)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("attribute list %q has an unnamed value", list)
+		}
+		value = strings.TrimLeft(value, " ")
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.IndexByte(value[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("attribute %s has an unterminated quoted string", name)
+			}
+			attributes[name] = value[1 : end+1]
+			rest = strings.TrimSpace(value[end+2:])
+			if rest != "" && !strings.HasPrefix(rest, ",") {
+				return nil, fmt.Errorf("attribute %s is followed by %q instead of a comma", name, rest)
+			}
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			attributes[name] = strings.TrimSpace(value)
+			rest = "," + rest
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+	return attributes, nil
+}
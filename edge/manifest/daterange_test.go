@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractDateRangeMetadata(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:10
+
+#EXT-X-DATERANGE:ID="placement_001",START-DATE="2024-01-15T10:30:05Z",DURATION=5.0,X-INSCENIUM-SURFACE-ID="surf_001",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="billboard"
+#EXTINF:10.0,
+segment_000.m4s
+
+#EXT-X-DATERANGE:ID="ad_break",CLASS="com.example.break",START-DATE="2024-01-15T10:30:10Z",PLANNED-DURATION=30
+#EXT-X-DATERANGE:ID="placement_002",START-DATE="2024-01-15T10:30:15.250+01:00",DURATION=3.2,X-INSCENIUM-SURFACE-ID="surf_002",X-INSCENIUM-PRS=92.1,X-INSCENIUM-PLACEMENT-TYPE="screen, lit"
+#EXTINF:10.0,
+segment_001.m4s
+
+#EXT-X-ENDLIST`
+
+	placements, err := ExtractDateRangeMetadata(manifest)
+	if err != nil {
+		t.Fatalf("ExtractDateRangeMetadata: %v", err)
+	}
+	want := []PlacementMetadata{
+		{
+			ID:            "placement_001",
+			StartTime:     time.Date(2024, 1, 15, 10, 30, 5, 0, time.UTC),
+			Duration:      5,
+			SurfaceID:     "surf_001",
+			PRSScore:      87.5,
+			PlacementType: "billboard",
+		},
+		{
+			ID:            "placement_002",
+			StartTime:     time.Date(2024, 1, 15, 9, 30, 15, 250e6, time.UTC),
+			Duration:      3.2,
+			SurfaceID:     "surf_002",
+			PRSScore:      92.1,
+			PlacementType: "screen, lit",
+		},
+	}
+	if len(placements) != len(want) {
+		t.Fatalf("Expected %d placements, got %d: %+v", len(want), len(placements), placements)
+	}
+	for i := range want {
+		got := placements[i]
+		if got.ID != want[i].ID || !got.StartTime.Equal(want[i].StartTime) || got.Duration != want[i].Duration ||
+			got.SurfaceID != want[i].SurfaceID || got.PRSScore != want[i].PRSScore || got.PlacementType != want[i].PlacementType {
+			t.Errorf("Placement %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+}
+
+func TestExtractDateRangeMetadata_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"start date": `#EXT-X-DATERANGE:ID="p",START-DATE="yesterday",X-INSCENIUM-SURFACE-ID="s"`,
+		"duration":   `#EXT-X-DATERANGE:ID="p",START-DATE="2024-01-15T10:30:05Z",DURATION=5s,X-INSCENIUM-SURFACE-ID="s"`,
+		"prs":        `#EXT-X-DATERANGE:ID="p",START-DATE="2024-01-15T10:30:05Z",X-INSCENIUM-SURFACE-ID="s",X-INSCENIUM-PRS="high"`,
+		"no id":      `#EXT-X-DATERANGE:START-DATE="2024-01-15T10:30:05Z",X-INSCENIUM-SURFACE-ID="s"`,
+		"quoting":    `#EXT-X-DATERANGE:ID="p,START-DATE="2024-01-15T10:30:05Z"`,
+	}
+	for name, tag := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ExtractDateRangeMetadata("#EXTM3U\n" + tag); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestParseAttributeList(t *testing.T) {
+	tests := []struct {
+		list string
+		want map[string]string
+		ok   bool
+	}{
+		{`ID="a",DURATION=5.0`, map[string]string{"ID": "a", "DURATION": "5.0"}, true},
+		{`ID="a, b", CLASS="c"`, map[string]string{"ID": "a, b", "CLASS": "c"}, true},
+		{`BANDWIDTH=1280000,RESOLUTION=640x360,`, map[string]string{"BANDWIDTH": "1280000", "RESOLUTION": "640x360"}, true},
+		{`SCTE35-OUT=0xFC002F`, map[string]string{"SCTE35-OUT": "0xFC002F"}, true},
+		{``, map[string]string{}, true},
+		{`ID`, nil, false},
+		{`=5`, nil, false},
+		{`ID="a`, nil, false},
+		{`ID="a"b,C=1`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseAttributeList(tt.list)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Expected ok=%v, got error %v", tt.ok, err)
+			}
+			if tt.ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatDateRange(t *testing.T) {
+	p := PlacementMetadata{
+		ID:            "placement_001",
+		StartTime:     time.Date(2024, 1, 15, 11, 30, 5, 500e6, time.FixedZone("CET", 3600)),
+		Duration:      5,
+		SurfaceID:     "surf_001",
+		PRSScore:      87.5,
+		PlacementType: "billboard",
+	}
+	want := `#EXT-X-DATERANGE:ID="placement_001",START-DATE="2024-01-15T10:30:05.5Z",DURATION=5,X-INSCENIUM-SURFACE-ID="surf_001",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="billboard"`
+	if got := FormatDateRange(p); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	parsed, err := ParseDateRange(want)
+	if err != nil {
+		t.Fatalf("ParseDateRange: %v", err)
+	}
+	if !parsed.StartTime.Equal(p.StartTime) {
+		t.Errorf("Expected start %v, got %v", p.StartTime, parsed.StartTime)
+	}
+	if other, err := ParseDateRange(`#EXT-X-DATERANGE:ID="break",START-DATE="2024-01-15T10:30:05Z"`); err != nil || other != nil {
+		t.Errorf("Expected tags without Inscenium attributes to be skipped, got %+v, %v", other, err)
+	}
+}
+
+func TestFormatDateRange_Interstitial(t *testing.T) {
+	p := PlacementMetadata{
+		ID:            "booking_123",
+		StartTime:     time.Date(2024, 1, 15, 10, 30, 5, 0, time.UTC),
+		Duration:      6,
+		SurfaceID:     "surf_001",
+		PRSScore:      87.5,
+		PlacementType: "screen",
+		Interstitial:  &Interstitial{AssetURI: "https://cdn.example.com/creatives/booking_123/index.m3u8", ResumeOffset: 6},
+	}
+	want := `#EXT-X-DATERANGE:ID="booking_123",CLASS="com.apple.hls.interstitial",START-DATE="2024-01-15T10:30:05Z",DURATION=6,` +
+		`X-INSCENIUM-SURFACE-ID="surf_001",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="screen",` +
+		`X-ASSET-URI="https://cdn.example.com/creatives/booking_123/index.m3u8",X-RESUME-OFFSET=6`
+	if got := FormatDateRange(p); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	parsed, err := ParseDateRange(want)
+	if err != nil {
+		t.Fatalf("ParseDateRange: %v", err)
+	}
+	if parsed.Interstitial == nil || *parsed.Interstitial != *p.Interstitial {
+		t.Errorf("Expected interstitial %+v, got %+v", p.Interstitial, parsed.Interstitial)
+	}
+
+	p.Interstitial = &Interstitial{AssetURI: `https://cdn.example.com/"quoted".m3u8`}
+	if err := p.Validate(); err == nil {
+		t.Error("Expected an error for an asset URI with quotes")
+	}
+	p.Interstitial = &Interstitial{}
+	if err := p.Validate(); err == nil {
+		t.Error("Expected an error for an interstitial without an asset")
+	}
+}
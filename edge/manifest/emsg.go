@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotEmsg is returned for boxes that are not a version 1 emsg box
+var ErrNotEmsg = errors.New("manifest: not a version 1 emsg box")
+
+// emsgHeader is the size of a version 1 emsg box before its strings: box
+// size and type, version and flags, timescale, presentation time, event
+// duration and id
+const emsgHeader = 8 + 4 + 4 + 8 + 4 + 4
+
+// FormatEmsg writes a placement as a version 1 emsg box of SchemeIDURI,
+// for packagers signaling placements in-band in media segments. The
+// presentation time is the placement's start on the media timeline of the
+// track, in timescale ticks; MPDs announce such events with an
+// InbandEventStream of the scheme in each AdaptationSet carrying them.
+func FormatEmsg(p PlacementMetadata, timescale uint32, presentationTime uint64) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if timescale == 0 {
+		return nil, fmt.Errorf("manifest: placement %s: timescale must be positive", p.ID)
+	}
+	duration := uint64(p.Duration*float64(timescale) + 0.5)
+	if duration > 0xFFFFFFFF {
+		duration = 0xFFFFFFFF // Unknown duration
+	}
+
+	message := FormatEventMessage(p)
+	size := emsgHeader + len(SchemeIDURI) + 1 + len(SchemeValue) + 1 + len(message)
+	box := make([]byte, 0, size)
+	box = binary.BigEndian.AppendUint32(box, uint32(size))
+	box = append(box, "emsg"...)
+	box = binary.BigEndian.AppendUint32(box, 1<<24) // Version 1, no flags
+	box = binary.BigEndian.AppendUint32(box, timescale)
+	box = binary.BigEndian.AppendUint64(box, presentationTime)
+	box = binary.BigEndian.AppendUint32(box, uint32(duration))
+	box = binary.BigEndian.AppendUint32(box, EventID(p.ID))
+	box = append(box, SchemeIDURI+"\x00"+SchemeValue+"\x00"...)
+	return append(box, message...), nil
+}
+
+// ParseEmsg reads a placement from a version 1 emsg box, along with the
+// box's presentation time converted with its timescale. It returns a nil
+// placement for boxes of other schemes, such as SCTE-35.
+func ParseEmsg(box []byte) (*PlacementMetadata, time.Duration, error) {
+	if len(box) < emsgHeader || string(box[4:8]) != "emsg" || box[8] != 1 {
+		return nil, 0, ErrNotEmsg
+	}
+	if size := binary.BigEndian.Uint32(box[:4]); int(size) != len(box) {
+		return nil, 0, fmt.Errorf("manifest: emsg box of %d bytes has size %d", len(box), size)
+	}
+	timescale := binary.BigEndian.Uint32(box[12:16])
+	if timescale == 0 {
+		return nil, 0, errors.New("manifest: emsg box has no timescale")
+	}
+	ticks := binary.BigEndian.Uint64(box[16:24])
+	at := time.Duration(ticks/uint64(timescale))*time.Second +
+		time.Duration(ticks%uint64(timescale))*time.Second/time.Duration(timescale)
+
+	rest := box[emsgHeader:]
+	scheme, rest, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return nil, 0, errors.New("manifest: emsg box has an unterminated scheme_id_uri")
+	}
+	_, message, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return nil, 0, errors.New("manifest: emsg box has an unterminated value")
+	}
+	if string(scheme) != SchemeIDURI {
+		return nil, at, nil
+	}
+	placement, err := ParseEventMessage(string(message))
+	return placement, at, err
+}
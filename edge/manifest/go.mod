@@ -0,0 +1,3 @@
+module github.com/inscenium/inscenium/edge/manifest
+
+go 1.22
@@ -0,0 +1,364 @@
+// Package manifest signals Inscenium placements in HLS media playlists and
+// DASH MPDs. In playlists each placement becomes an EXT-X-DATERANGE tag
+// carrying X-INSCENIUM-* attributes, written just before the segment the
+// placement starts in, so players and edge workers know what to composite
+// and when. Segment times are read from each #EXTINF duration rather than
+// assumed, and segments dated with EXT-X-PROGRAM-DATE-TIME are placed by
+// wall clock, so live sliding-window playlists are decorated as well as
+// VOD ones.
+//
+// DASH MPDs are decorated by MPDProcessor, which writes the same
+// attributes as the message data of EventStream events (see dash.go);
+// FormatEmsg carries them in-band in media segments. Placements with an
+// Interstitial are also HLS Interstitials, which players supporting them
+// load the creative asset for. For broadcast
+// workflows, InjectSCTE35 signals placements as SCTE-35 cues instead (see
+// scte35.go).
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Playlist tags the processor reads
+const (
+	tagHeader          = "#EXTM3U"
+	tagSegment         = "#EXTINF:"
+	tagStreamInf       = "#EXT-X-STREAM-INF:"
+	tagMediaSequence   = "#EXT-X-MEDIA-SEQUENCE:"
+	tagPlaylistType    = "#EXT-X-PLAYLIST-TYPE:"
+	tagEndList         = "#EXT-X-ENDLIST"
+	tagProgramDateTime = "#EXT-X-PROGRAM-DATE-TIME:"
+)
+
+// programDateTimeLayouts are accepted for EXT-X-PROGRAM-DATE-TIME; some
+// packagers write the zone offset without a colon
+var programDateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"}
+
+// ErrMasterPlaylist is returned for master playlists, whose media
+// playlists must be decorated one by one (see ParseMasterPlaylist)
+var ErrMasterPlaylist = errors.New("manifest: master playlists cannot be decorated, decorate each media playlist")
+
+// PlacementMetadata describes a placement signaled in a playlist
+type PlacementMetadata struct {
+	ID            string    `json:"id"`
+	StartTime     time.Time `json:"start_time"`
+	Duration      float64   `json:"duration"` // Seconds
+	SurfaceID     string    `json:"surface_id"`
+	PRSScore      float64   `json:"prs_score"`
+	PlacementType string    `json:"placement_type"`
+	// Interstitial, if set, also has players that support HLS
+	// Interstitials play the placement's creative asset
+	Interstitial *Interstitial `json:"interstitial,omitempty"`
+}
+
+// Interstitial is the creative asset an HLS Interstitial plays for a
+// placement, written as the CLASS, X-ASSET-URI and X-RESUME-OFFSET
+// attributes of its EXT-X-DATERANGE tag
+type Interstitial struct {
+	AssetURI     string  `json:"asset_uri"`     // HLS playlist of the creative rendition
+	ResumeOffset float64 `json:"resume_offset"` // Seconds of primary content skipped when it ends; 0 resumes where it started
+}
+
+// Segment is a media segment of a playlist
+type Segment struct {
+	URI      string
+	Sequence uint64    // Media sequence number; wraps around past the largest uint64
+	Start    float64   // Seconds from the start of the playlist
+	Duration float64   // Seconds, from #EXTINF
+	Time     time.Time // When the segment plays
+	line     int       // Index of the #EXTINF line
+}
+
+// End returns when the segment stops playing
+func (s Segment) End() time.Time {
+	return s.Time.Add(seconds(s.Duration))
+}
+
+// ManifestProcessor injects placement metadata into an HLS media playlist
+type ManifestProcessor struct {
+	lines         []string
+	segments      []Segment
+	mediaSequence uint64
+	live          bool
+	dated         bool // Segments carry EXT-X-PROGRAM-DATE-TIME
+}
+
+// NewManifestProcessor parses a media playlist. Segments dated with
+// EXT-X-PROGRAM-DATE-TIME play at that wall-clock time, and the segments
+// after them at the date plus the durations in between; in playlists
+// without dates the first segment plays at start. Placements are
+// positioned by their start time against these times.
+func NewManifestProcessor(manifest string, start time.Time) (*ManifestProcessor, error) {
+	lines := strings.Split(manifest, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	if strings.TrimSpace(lines[0]) != tagHeader {
+		return nil, fmt.Errorf("manifest: playlist must start with %s", tagHeader)
+	}
+
+	mp := &ManifestProcessor{lines: lines, live: true}
+	offset := 0.0
+	var dates []time.Time // EXT-X-PROGRAM-DATE-TIME of each segment, zero when undated
+	var pending time.Time // Date applying to the next segment
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(line, tagStreamInf), strings.HasPrefix(line, tagIFrameStreamInf):
+			return nil, ErrMasterPlaylist
+		case strings.HasPrefix(line, tagMediaSequence):
+			sequence, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, tagMediaSequence)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("manifest: line %d: invalid media sequence %q", i+1, line)
+			}
+			mp.mediaSequence = sequence
+		case strings.HasPrefix(line, tagPlaylistType):
+			if strings.TrimSpace(strings.TrimPrefix(line, tagPlaylistType)) == "VOD" {
+				mp.live = false
+			}
+		case line == tagEndList:
+			mp.live = false
+		case strings.HasPrefix(line, tagProgramDateTime):
+			date, err := parseProgramDateTime(strings.TrimPrefix(line, tagProgramDateTime))
+			if err != nil {
+				return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+			}
+			pending = date
+			mp.dated = true
+		case strings.HasPrefix(line, tagSegment):
+			duration, err := parseSegmentDuration(line)
+			if err != nil {
+				return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+			}
+			segment := Segment{Start: offset, Duration: duration, line: i}
+			// The segment's URI is the next line that is neither blank nor a tag
+			for j := i + 1; j < len(lines); j++ {
+				if uri := strings.TrimSpace(lines[j]); uri != "" && !strings.HasPrefix(uri, "#") {
+					segment.URI = uri
+					break
+				}
+			}
+			mp.segments = append(mp.segments, segment)
+			dates = append(dates, pending)
+			pending = time.Time{}
+			offset += duration
+		}
+	}
+	mp.timeSegments(dates, start)
+	return mp, nil
+}
+
+// timeSegments numbers the segments and sets when each plays: at its
+// EXT-X-PROGRAM-DATE-TIME, or after the segment before it. Segments before
+// the first dated one play the durations in between before it.
+func (mp *ManifestProcessor) timeSegments(dates []time.Time, start time.Time) {
+	first := len(mp.segments)
+	for i, date := range dates {
+		if !date.IsZero() {
+			first = i
+			break
+		}
+	}
+	if first < len(mp.segments) {
+		start = dates[first].Add(-seconds(mp.segments[first].Start))
+	}
+
+	for i := range mp.segments {
+		s := &mp.segments[i]
+		s.Sequence = mp.mediaSequence + uint64(i)
+		switch {
+		case !dates[i].IsZero():
+			s.Time = dates[i]
+		case i == 0:
+			s.Time = start
+		default:
+			s.Time = mp.segments[i-1].End()
+		}
+	}
+}
+
+// parseProgramDateTime reads the date of an EXT-X-PROGRAM-DATE-TIME tag
+func parseProgramDateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range programDateTimeLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid program date-time %q", value)
+}
+
+// seconds converts a decimal number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// parseSegmentDuration reads the duration of an #EXTINF:<duration>,[<title>] tag
+func parseSegmentDuration(line string) (float64, error) {
+	value, _, _ := strings.Cut(strings.TrimPrefix(line, tagSegment), ",")
+	duration, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid segment duration %q", value)
+	}
+	return duration, nil
+}
+
+// Segments returns the playlist's media segments in playback order
+func (mp *ManifestProcessor) Segments() []Segment {
+	return append([]Segment(nil), mp.segments...)
+}
+
+// Duration returns the playlist's length in seconds
+func (mp *ManifestProcessor) Duration() float64 {
+	if len(mp.segments) == 0 {
+		return 0
+	}
+	last := mp.segments[len(mp.segments)-1]
+	return last.Start + last.Duration
+}
+
+// Live reports whether the playlist is a live one, which may still gain
+// segments and drop the oldest: it has no EXT-X-ENDLIST and is not of the
+// VOD type
+func (mp *ManifestProcessor) Live() bool {
+	return mp.live
+}
+
+// MediaSequence returns the sequence number of the playlist's first segment
+func (mp *ManifestProcessor) MediaSequence() uint64 {
+	return mp.mediaSequence
+}
+
+// SegmentFor returns the segment a placement is signaled before: the one
+// playing when it starts. In live playlists a placement that started
+// before the oldest segment and is still running is signaled before that
+// segment, so players joining mid-placement learn of it. ok is false for
+// placements outside the playlist.
+func (mp *ManifestProcessor) SegmentFor(p PlacementMetadata) (segment Segment, ok bool) {
+	i := mp.segmentAt(p.StartTime, p.StartTime.Add(seconds(p.Duration)))
+	if i < 0 {
+		return Segment{}, false
+	}
+	return mp.segments[i], true
+}
+
+// segmentAt returns the index of the segment playing at start, or -1
+// outside the playlist. Dated segments need not be contiguous, e.g. across
+// a discontinuity, so each is checked.
+func (mp *ManifestProcessor) segmentAt(start, end time.Time) int {
+	for i, s := range mp.segments {
+		if !start.Before(s.Time) && start.Before(s.End()) {
+			return i
+		}
+	}
+	if mp.live && len(mp.segments) > 0 {
+		first := mp.segments[0]
+		if start.Before(first.Time) && end.After(first.Time) {
+			return 0
+		}
+	}
+	return -1
+}
+
+// SequenceAfter reports whether media sequence number a comes after b,
+// using serial number arithmetic so that numbers past a wrap around to 0
+// still compare as later. Refreshes of a live playlist can be ordered
+// with it.
+func SequenceAfter(a, b uint64) bool {
+	return a != b && a-b < 1<<63
+}
+
+// InjectPlacementMetadata returns the playlist with an EXT-X-DATERANGE tag
+// for each placement written before the segment it is signaled in (see
+// SegmentFor), earliest first. Placements outside the playlist are left
+// out. Since players only act on EXT-X-DATERANGE tags in playlists with
+// dates, an EXT-X-PROGRAM-DATE-TIME tag is added to the first segment of
+// undated playlists that gain any. The playlist is otherwise unchanged.
+func (mp *ManifestProcessor) InjectPlacementMetadata(placements []PlacementMetadata) (string, error) {
+	return mp.inject(placements, func(p PlacementMetadata) (string, error) {
+		return FormatDateRange(p), nil
+	})
+}
+
+// inject writes the tag format returns for each placement before the
+// segment it is signaled in
+func (mp *ManifestProcessor) inject(placements []PlacementMetadata, format func(p PlacementMetadata) (string, error)) (string, error) {
+	tags := make(map[int][]PlacementMetadata) // Segment index -> placements signaled before it
+	for _, placement := range placements {
+		if err := placement.Validate(); err != nil {
+			return "", err
+		}
+		i := mp.segmentAt(placement.StartTime, placement.StartTime.Add(seconds(placement.Duration)))
+		if i < 0 {
+			continue
+		}
+		tags[i] = append(tags[i], placement)
+	}
+	if len(tags) == 0 {
+		return strings.Join(mp.lines, "\n"), nil
+	}
+
+	result := make([]string, 0, len(mp.lines)+len(placements)+1)
+	next := 0
+	for i, segment := range mp.segments {
+		starting := tags[i]
+		dateFirst := i == 0 && !mp.dated
+		if len(starting) == 0 && !dateFirst {
+			continue
+		}
+		result = append(result, mp.lines[next:segment.line]...)
+		sort.SliceStable(starting, func(a, b int) bool {
+			return starting[a].StartTime.Before(starting[b].StartTime)
+		})
+		for _, placement := range starting {
+			tag, err := format(placement)
+			if err != nil {
+				return "", err
+			}
+			result = append(result, tag)
+		}
+		if dateFirst {
+			result = append(result, tagProgramDateTime+segment.Time.UTC().Format(time.RFC3339Nano))
+		}
+		next = segment.line
+	}
+	result = append(result, mp.lines[next:]...)
+	return strings.Join(result, "\n"), nil
+}
+
+// Validate checks that a placement can be written as an EXT-X-DATERANGE
+// tag: quoted-string attributes may not hold double quotes or line breaks
+func (p PlacementMetadata) Validate() error {
+	if p.ID == "" {
+		return errors.New("manifest: placement id is required")
+	}
+	if p.SurfaceID == "" {
+		return fmt.Errorf("manifest: placement %s: surface_id is required", p.ID)
+	}
+	if p.Duration < 0 {
+		return fmt.Errorf("manifest: placement %s: duration must not be negative", p.ID)
+	}
+	values := []string{p.ID, p.SurfaceID, p.PlacementType}
+	if p.Interstitial != nil {
+		if p.Interstitial.AssetURI == "" {
+			return fmt.Errorf("manifest: placement %s: interstitial asset URI is required", p.ID)
+		}
+		if p.Interstitial.ResumeOffset < 0 {
+			return fmt.Errorf("manifest: placement %s: interstitial resume offset must not be negative", p.ID)
+		}
+		values = append(values, p.Interstitial.AssetURI)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\"\r\n") {
+			return fmt.Errorf("manifest: placement %s: attributes may not contain quotes or line breaks", p.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleHLSManifest = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+
+#EXTINF:10.0,
+segment_000.m4s
+
+#EXTINF:10.0,
+segment_001.m4s
+
+#EXTINF:10.0,
+segment_002.m4s
+
+#EXT-X-ENDLIST`
+
+// unevenManifest has segments of 4, 6.006 and 2.5 seconds
+const unevenManifest = "#EXTM3U\r\n#EXT-X-TARGETDURATION:7\r\n#EXTINF:4,intro\r\nseg0.ts\r\n#EXT-X-DISCONTINUITY\r\n#EXTINF:6.006,\r\nseg1.ts\r\n#EXTINF:2.5,\r\nseg2.ts\r\n#EXT-X-ENDLIST"
+
+var baseTime = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+func newProcessor(t *testing.T, manifest string) *ManifestProcessor {
+	t.Helper()
+	mp, err := NewManifestProcessor(manifest, baseTime)
+	if err != nil {
+		t.Fatalf("NewManifestProcessor: %v", err)
+	}
+	return mp
+}
+
+func inject(t *testing.T, mp *ManifestProcessor, placements ...PlacementMetadata) string {
+	t.Helper()
+	out, err := mp.InjectPlacementMetadata(placements)
+	if err != nil {
+		t.Fatalf("InjectPlacementMetadata: %v", err)
+	}
+	return out
+}
+
+func placementAt(id string, offset time.Duration) PlacementMetadata {
+	return PlacementMetadata{
+		ID:            id,
+		StartTime:     baseTime.Add(offset),
+		Duration:      5,
+		SurfaceID:     "surf_" + id,
+		PRSScore:      87.5,
+		PlacementType: "billboard",
+	}
+}
+
+func TestNewManifestProcessor(t *testing.T) {
+	mp := newProcessor(t, unevenManifest)
+	segments := mp.Segments()
+	if len(segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %d", len(segments))
+	}
+	want := []Segment{
+		{URI: "seg0.ts", Start: 0, Duration: 4},
+		{URI: "seg1.ts", Start: 4, Duration: 6.006},
+		{URI: "seg2.ts", Start: 10.006, Duration: 2.5},
+	}
+	for i, s := range segments {
+		if s.URI != want[i].URI || s.Start != want[i].Start || s.Duration != want[i].Duration {
+			t.Errorf("Segment %d: expected %+v, got %+v", i, want[i], s)
+		}
+	}
+	if got := mp.Duration(); got != 12.506 {
+		t.Errorf("Expected duration 12.506, got %v", got)
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		err      error
+	}{
+		{"no header", "#EXTINF:10,\nseg.ts", nil},
+		{"bad duration", "#EXTM3U\n#EXTINF:ten,\nseg.ts", nil},
+		{"negative duration", "#EXTM3U\n#EXTINF:-1,\nseg.ts", nil},
+		{"master playlist", "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1280000\nlow.m3u8", ErrMasterPlaylist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewManifestProcessor(tt.manifest, baseTime)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestInjectPlacementMetadata(t *testing.T) {
+	mp := newProcessor(t, sampleHLSManifest)
+	first := placementAt("placement_001", 5*time.Second)
+	second := placementAt("placement_002", 15*time.Second)
+	second.Duration, second.PRSScore, second.PlacementType = 3.2, 92.1, "screen"
+
+	out := inject(t, mp, second, first)
+	want := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+
+#EXT-X-DATERANGE:ID="placement_001",START-DATE="2024-01-15T10:30:05Z",DURATION=5,X-INSCENIUM-SURFACE-ID="surf_placement_001",X-INSCENIUM-PRS="87.5",X-INSCENIUM-PLACEMENT-TYPE="billboard"
+#EXT-X-PROGRAM-DATE-TIME:2024-01-15T10:30:00Z
+#EXTINF:10.0,
+segment_000.m4s
+
+#EXT-X-DATERANGE:ID="placement_002",START-DATE="2024-01-15T10:30:15Z",DURATION=3.2,X-INSCENIUM-SURFACE-ID="surf_placement_002",X-INSCENIUM-PRS="92.1",X-INSCENIUM-PLACEMENT-TYPE="screen"
+#EXTINF:10.0,
+segment_001.m4s
+
+#EXTINF:10.0,
+segment_002.m4s
+
+#EXT-X-ENDLIST`
+	if out != want {
+		t.Errorf("Unexpected manifest:\n%s", out)
+	}
+
+	// Several placements in one segment are written earliest first
+	out = inject(t, mp, placementAt("late", 29*time.Second), placementAt("early", 21*time.Second))
+	if strings.Index(out, `ID="early"`) > strings.Index(out, `ID="late"`) {
+		t.Errorf("Expected placements in start order:\n%s", out)
+	}
+	if !strings.Contains(out, `X-INSCENIUM-PLACEMENT-TYPE="billboard"`+"\n#EXTINF:10.0,\nsegment_002.m4s") {
+		t.Errorf("Expected both placements before the third segment:\n%s", out)
+	}
+}
+
+func TestInjectPlacementMetadata_SegmentDurations(t *testing.T) {
+	mp := newProcessor(t, unevenManifest)
+	tests := []struct {
+		offset  time.Duration
+		segment string // URI the tag must precede, empty when left out
+	}{
+		{0, "seg0.ts"},
+		{3999 * time.Millisecond, "seg0.ts"},
+		{4 * time.Second, "seg1.ts"},
+		{10 * time.Second, "seg1.ts"},
+		{10006 * time.Millisecond, "seg2.ts"},
+		{12500 * time.Millisecond, "seg2.ts"},
+		{12506 * time.Millisecond, ""},
+		{-time.Second, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.offset.String(), func(t *testing.T) {
+			out := inject(t, mp, placementAt("p", tt.offset))
+			lines := strings.Split(out, "\n")
+			tagged := ""
+			for i, line := range lines {
+				if strings.HasPrefix(line, TagDateRange) {
+					// Tags precede the #EXTINF line, whose segment URI follows it
+					for _, next := range lines[i+1:] {
+						if next != "" && !strings.HasPrefix(next, "#") {
+							tagged = next
+							break
+						}
+					}
+				}
+			}
+			if tagged != tt.segment {
+				t.Errorf("Expected the placement before %q, got %q:\n%s", tt.segment, tagged, out)
+			}
+		})
+	}
+}
+
+func TestInjectPlacementMetadata_Unchanged(t *testing.T) {
+	mp := newProcessor(t, sampleHLSManifest)
+	if out := inject(t, mp); out != sampleHLSManifest {
+		t.Error("Manifest with no placements should be unchanged")
+	}
+	future := placementAt("future_placement", 0)
+	future.StartTime = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if out := inject(t, mp, future); out != sampleHLSManifest {
+		t.Error("Should not inject placements outside the playlist")
+	}
+
+	out := inject(t, newProcessor(t, unevenManifest), placementAt("p", 0))
+	if strings.Contains(out, "\r") {
+		t.Error("Should normalize line endings")
+	}
+	lines := strings.Split(out, "\n")
+	if lines[0] != "#EXTM3U" || lines[len(lines)-1] != "#EXT-X-ENDLIST" {
+		t.Errorf("Should keep the header and end list:\n%s", out)
+	}
+	if got := strings.Count(out, ".ts"); got != 3 {
+		t.Errorf("Expected 3 segments, got %d", got)
+	}
+}
+
+// liveManifest is a sliding window of a live stream whose encoder
+// restarted after its second segment, skipping ahead four seconds
+const liveManifest = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:18446744073709551614
+#EXT-X-PROGRAM-DATE-TIME:2024-01-15T10:30:00.000+00:00
+#EXTINF:6.0,
+live_1000.ts
+#EXTINF:6.0,
+live_1001.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2024-01-15T10:30:16.000+0000
+#EXTINF:6.0,
+live_1002.ts`
+
+func TestLivePlaylist(t *testing.T) {
+	// Dated segments play at their date, whatever start is passed
+	mp, err := NewManifestProcessor(liveManifest, time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("NewManifestProcessor: %v", err)
+	}
+	if !mp.Live() {
+		t.Error("Expected a live playlist without EXT-X-ENDLIST")
+	}
+	if newProcessor(t, sampleHLSManifest).Live() {
+		t.Error("Expected a VOD playlist")
+	}
+
+	segments := mp.Segments()
+	want := []struct {
+		sequence uint64
+		time     time.Time
+	}{
+		{18446744073709551614, baseTime},
+		{18446744073709551615, baseTime.Add(6 * time.Second)},
+		{0, baseTime.Add(16 * time.Second)},
+	}
+	for i, s := range segments {
+		if s.Sequence != want[i].sequence || !s.Time.Equal(want[i].time) {
+			t.Errorf("Segment %d: expected sequence %d at %s, got %d at %s", i, want[i].sequence, want[i].time, s.Sequence, s.Time)
+		}
+	}
+	if !SequenceAfter(segments[2].Sequence, segments[1].Sequence) || SequenceAfter(segments[1].Sequence, segments[2].Sequence) {
+		t.Error("Expected sequence numbers to stay ordered across rollover")
+	}
+
+	tests := []struct {
+		name     string
+		offset   time.Duration
+		duration float64
+		segment  string // URI the tag must precede, empty when left out
+	}{
+		{"first segment", 2 * time.Second, 5, "live_1000.ts"},
+		{"after the discontinuity", 17 * time.Second, 5, "live_1002.ts"},
+		{"in the gap", 13 * time.Second, 1, ""},
+		{"still running", -10 * time.Second, 15, "live_1000.ts"},
+		{"already over", -10 * time.Second, 5, ""},
+		{"not yet in the window", 22 * time.Second, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := placementAt("p", tt.offset)
+			p.Duration = tt.duration
+			segment, ok := mp.SegmentFor(p)
+			if segment.URI != tt.segment || ok != (tt.segment != "") {
+				t.Errorf("Expected the placement before %q, got %q", tt.segment, segment.URI)
+			}
+		})
+	}
+
+	out := inject(t, mp, placementAt("p", 17*time.Second))
+	if !strings.Contains(out, "#EXT-X-PROGRAM-DATE-TIME:2024-01-15T10:30:16.000+0000\n"+`#EXT-X-DATERANGE:ID="p"`) {
+		t.Errorf("Expected the tag after the segment's date:\n%s", out)
+	}
+	if strings.Count(out, tagProgramDateTime) != 2 {
+		t.Errorf("Should not date a dated playlist again:\n%s", out)
+	}
+}
+
+func TestInjectPlacementMetadata_Invalid(t *testing.T) {
+	mp := newProcessor(t, sampleHLSManifest)
+	tests := []struct {
+		name   string
+		modify func(p *PlacementMetadata)
+	}{
+		{"no id", func(p *PlacementMetadata) { p.ID = "" }},
+		{"no surface", func(p *PlacementMetadata) { p.SurfaceID = "" }},
+		{"negative duration", func(p *PlacementMetadata) { p.Duration = -1 }},
+		{"quote", func(p *PlacementMetadata) { p.PlacementType = `bill"board` }},
+		{"line break", func(p *PlacementMetadata) { p.SurfaceID = "surf\n#EXT-X-ENDLIST" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := placementAt("p", time.Second)
+			tt.modify(&p)
+			if _, err := mp.InjectPlacementMetadata([]PlacementMetadata{p}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestInjectPlacementMetadata_RoundTrip(t *testing.T) {
+	mp := newProcessor(t, sampleHLSManifest)
+	var placements []PlacementMetadata
+	for i := 0; i < 100; i++ {
+		placements = append(placements, PlacementMetadata{
+			ID:            fmt.Sprintf("placement_%03d", i),
+			StartTime:     baseTime.Add(time.Duration(i*300) * time.Millisecond),
+			Duration:      float64(3 + i%5),
+			SurfaceID:     fmt.Sprintf("surf_%03d", i),
+			PRSScore:      80.0 + float64(i%20)/10,
+			PlacementType: []string{"billboard", "screen", "wall", "table"}[i%4],
+		})
+	}
+
+	out := inject(t, mp, placements...)
+	extracted, err := ExtractDateRangeMetadata(out)
+	if err != nil {
+		t.Fatalf("ExtractDateRangeMetadata: %v", err)
+	}
+	if len(extracted) != len(placements) {
+		t.Fatalf("Expected %d placements, got %d", len(placements), len(extracted))
+	}
+	for i, p := range extracted {
+		if p != placements[i] {
+			t.Errorf("Placement %d: expected %+v, got %+v", i, placements[i], p)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Master playlist tags that reference other playlists
+const (
+	tagMedia           = "#EXT-X-MEDIA:"
+	tagIFrameStreamInf = "#EXT-X-I-FRAME-STREAM-INF:"
+)
+
+// Kinds of playlist a master playlist references
+const (
+	RenditionVariant = "variant" // EXT-X-STREAM-INF, the URI on the next line
+	RenditionMedia   = "media"   // EXT-X-MEDIA alternative audio, subtitles or video
+	RenditionIFrame  = "iframe"  // EXT-X-I-FRAME-STREAM-INF
+)
+
+// Rendition is a media playlist referenced by a master playlist
+type Rendition struct {
+	Kind      string
+	URI       string
+	Bandwidth int64  // BANDWIDTH of variants and I-frame streams
+	Type      string // TYPE of EXT-X-MEDIA renditions
+	GroupID   string // GROUP-ID of EXT-X-MEDIA renditions
+	line      int    // Index of the line holding the URI
+}
+
+// MasterPlaylist is an HLS master (multivariant) playlist. Placements are
+// signaled in its media playlists, so decorating one means pointing its
+// renditions at decorated copies, all signaling the same placements.
+type MasterPlaylist struct {
+	lines      []string
+	renditions []Rendition
+}
+
+// IsMasterPlaylist reports whether manifest is an HLS master playlist
+func IsMasterPlaylist(manifest string) bool {
+	for _, line := range strings.Split(manifest, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, tagStreamInf) || strings.HasPrefix(line, tagIFrameStreamInf) {
+			return true
+		}
+		if strings.HasPrefix(line, tagSegment) {
+			return false
+		}
+	}
+	return false
+}
+
+// ParseMasterPlaylist parses a master playlist and the renditions it
+// references, in playlist order. EXT-X-MEDIA renditions without a URI,
+// whose media is in the variant streams, are not listed.
+func ParseMasterPlaylist(manifest string) (*MasterPlaylist, error) {
+	lines := strings.Split(manifest, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	if strings.TrimSpace(lines[0]) != tagHeader {
+		return nil, fmt.Errorf("manifest: playlist must start with %s", tagHeader)
+	}
+
+	m := &MasterPlaylist{lines: lines}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(line, tagSegment):
+			return nil, fmt.Errorf("manifest: line %d: media segment in a master playlist", i+1)
+		case strings.HasPrefix(line, tagStreamInf):
+			attributes, err := ParseAttributeList(strings.TrimPrefix(line, tagStreamInf))
+			if err != nil {
+				return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+			}
+			r := Rendition{Kind: RenditionVariant, Bandwidth: parseBandwidth(attributes), line: -1}
+			for j := i + 1; j < len(lines); j++ {
+				if uri := strings.TrimSpace(lines[j]); uri != "" && !strings.HasPrefix(uri, "#") {
+					r.URI, r.line = uri, j
+					i = j
+					break
+				}
+			}
+			if r.line < 0 {
+				return nil, fmt.Errorf("manifest: line %d: variant stream has no URI", i+1)
+			}
+			m.renditions = append(m.renditions, r)
+		case strings.HasPrefix(line, tagIFrameStreamInf), strings.HasPrefix(line, tagMedia):
+			tag := tagMedia
+			r := Rendition{Kind: RenditionMedia, line: i}
+			if strings.HasPrefix(line, tagIFrameStreamInf) {
+				tag, r.Kind = tagIFrameStreamInf, RenditionIFrame
+			}
+			attributes, err := ParseAttributeList(strings.TrimPrefix(line, tag))
+			if err != nil {
+				return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+			}
+			uri, ok := attributes["URI"]
+			if !ok {
+				if r.Kind == RenditionIFrame {
+					return nil, fmt.Errorf("manifest: line %d: I-frame stream has no URI", i+1)
+				}
+				continue
+			}
+			r.URI, r.Type, r.GroupID = uri, attributes["TYPE"], attributes["GROUP-ID"]
+			r.Bandwidth = parseBandwidth(attributes)
+			m.renditions = append(m.renditions, r)
+		}
+	}
+	if len(m.renditions) == 0 {
+		return nil, fmt.Errorf("manifest: master playlist references no media playlists")
+	}
+	return m, nil
+}
+
+// parseBandwidth reads the BANDWIDTH attribute, or 0
+func parseBandwidth(attributes map[string]string) int64 {
+	bandwidth, _ := strconv.ParseInt(attributes["BANDWIDTH"], 10, 64)
+	return bandwidth
+}
+
+// Renditions returns the media playlists the master playlist references
+func (m *MasterPlaylist) Renditions() []Rendition {
+	return append([]Rendition(nil), m.renditions...)
+}
+
+// RewriteURIs returns the master playlist with the URI of each rendition
+// replaced by rewrite's, such as that of a proxy serving it decorated. The
+// playlist is otherwise unchanged.
+func (m *MasterPlaylist) RewriteURIs(rewrite func(r Rendition) (string, error)) (string, error) {
+	lines := append([]string(nil), m.lines...)
+	for _, r := range m.renditions {
+		uri, err := rewrite(r)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(uri, "\"\r\n") {
+			return "", fmt.Errorf("manifest: rewritten URI %q may not contain quotes or line breaks", uri)
+		}
+		if r.Kind == RenditionVariant {
+			lines[r.line] = uri
+			continue
+		}
+		lines[r.line] = replaceURIAttribute(lines[r.line], r.URI, uri)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// replaceURIAttribute replaces the quoted URI attribute of a tag
+func replaceURIAttribute(tag, from, to string) string {
+	value := `URI="` + from + `"`
+	for offset := 0; ; {
+		i := strings.Index(tag[offset:], value)
+		if i < 0 {
+			return tag
+		}
+		i += offset
+		// URI must be a whole attribute name, not the end of another's
+		if before := strings.TrimRight(tag[:i], " "); strings.HasSuffix(before, ",") || strings.HasSuffix(before, ":") {
+			start := i + len(`URI="`)
+			return tag[:start] + to + tag[start+len(from):]
+		}
+		offset = i + len(value)
+	}
+}
+
+// InjectAcrossRenditions decorates the media playlists of one master
+// playlist so that players see identical signaling on any rendition: each
+// gets the placements every playlist can signal (see SegmentFor), under
+// the same IDs and dates. A placement some renditions' windows do not
+// reach yet, as live playlists refreshed at slightly different times can,
+// is left out of all of them until it does. It returns the decorated
+// playlists, in the order of processors, and the placements signaled.
+func InjectAcrossRenditions(processors []*ManifestProcessor, placements []PlacementMetadata) ([]string, []PlacementMetadata, error) {
+	signaled := make([]PlacementMetadata, 0, len(placements))
+	for _, placement := range placements {
+		if err := placement.Validate(); err != nil {
+			return nil, nil, err
+		}
+		everywhere := len(processors) > 0
+		for _, mp := range processors {
+			if _, ok := mp.SegmentFor(placement); !ok {
+				everywhere = false
+				break
+			}
+		}
+		if everywhere {
+			signaled = append(signaled, placement)
+		}
+	}
+
+	decorated := make([]string, len(processors))
+	for i, mp := range processors {
+		playlist, err := mp.InjectPlacementMetadata(signaled)
+		if err != nil {
+			return nil, nil, err
+		}
+		decorated[i] = playlist
+	}
+	return decorated, signaled, nil
+}
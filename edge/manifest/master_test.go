@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const masterPlaylist = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="Muxed",X-ALT-URI="audio/alt.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=640x360,AUDIO="aac"
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="aac"
+
+https://cdn.example.com/42/high/index.m3u8?token=abc
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000, URI="low/iframe.m3u8"`
+
+func TestMasterPlaylist(t *testing.T) {
+	if !IsMasterPlaylist(masterPlaylist) {
+		t.Error("Expected a master playlist")
+	}
+	if IsMasterPlaylist("#EXTM3U\n#EXTINF:10,\nsegment.ts") {
+		t.Error("Media playlists are not master playlists")
+	}
+	if _, err := NewManifestProcessor(masterPlaylist, time.Time{}); err != ErrMasterPlaylist {
+		t.Errorf("Expected ErrMasterPlaylist, got %v", err)
+	}
+
+	m, err := ParseMasterPlaylist(masterPlaylist)
+	if err != nil {
+		t.Fatalf("ParseMasterPlaylist: %v", err)
+	}
+	renditions := m.Renditions()
+	want := []Rendition{
+		{Kind: RenditionMedia, URI: "audio/en.m3u8", Type: "AUDIO", GroupID: "aac"},
+		{Kind: RenditionVariant, URI: "low/index.m3u8", Bandwidth: 1280000},
+		{Kind: RenditionVariant, URI: "https://cdn.example.com/42/high/index.m3u8?token=abc", Bandwidth: 5000000},
+		{Kind: RenditionIFrame, URI: "low/iframe.m3u8", Bandwidth: 86000},
+	}
+	if len(renditions) != len(want) {
+		t.Fatalf("Expected %d renditions, got %+v", len(want), renditions)
+	}
+	for i := range want {
+		got := renditions[i]
+		got.line = 0
+		if got != want[i] {
+			t.Errorf("Rendition %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+
+	base, _ := url.Parse("https://cdn.example.com/42/master.m3u8")
+	rewritten, err := m.RewriteURIs(func(r Rendition) (string, error) {
+		u, _ := base.Parse(r.URI)
+		return "/decorate?url=" + url.QueryEscape(u.String()), nil
+	})
+	if err != nil {
+		t.Fatalf("RewriteURIs: %v", err)
+	}
+	for _, line := range []string{
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",DEFAULT=YES,URI="/decorate?url=https%3A%2F%2Fcdn.example.com%2F42%2Faudio%2Fen.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="Muxed",X-ALT-URI="audio/alt.m3u8"`,
+		"/decorate?url=https%3A%2F%2Fcdn.example.com%2F42%2Flow%2Findex.m3u8",
+		"/decorate?url=https%3A%2F%2Fcdn.example.com%2F42%2Fhigh%2Findex.m3u8%3Ftoken%3Dabc",
+		`#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000, URI="/decorate?url=https%3A%2F%2Fcdn.example.com%2F42%2Flow%2Fiframe.m3u8"`,
+		`#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="aac"`,
+	} {
+		if !strings.Contains(rewritten, line+"\n") && !strings.HasSuffix(rewritten, line) {
+			t.Errorf("Expected the rewritten playlist to hold %s, got:\n%s", line, rewritten)
+		}
+	}
+	if _, err := m.RewriteURIs(func(r Rendition) (string, error) { return "a\"b", nil }); err == nil {
+		t.Error("Expected an error for a URI with quotes")
+	}
+
+	for name, playlist := range map[string]string{
+		"no uri":     "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n",
+		"no streams": "#EXTM3U\n#EXT-X-VERSION:6",
+		"segments":   "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nlow.m3u8\n#EXTINF:10,\nsegment.ts",
+	} {
+		if _, err := ParseMasterPlaylist(playlist); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestInjectAcrossRenditions(t *testing.T) {
+	date := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	playlist := func(sequence int, segments int) *ManifestProcessor {
+		var b strings.Builder
+		b.WriteString("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:" + formatDecimal(float64(sequence)) + "\n")
+		b.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + date.Add(time.Duration(sequence)*6*time.Second).Format(time.RFC3339) + "\n")
+		for i := 0; i < segments; i++ {
+			b.WriteString("#EXTINF:6.0,\nsegment.ts\n")
+		}
+		mp, err := NewManifestProcessor(b.String(), time.Time{})
+		if err != nil {
+			t.Fatalf("NewManifestProcessor: %v", err)
+		}
+		return mp
+	}
+	// The high rendition was refreshed a segment later than the low one
+	low, high := playlist(0, 5), playlist(1, 5)
+	placements := []PlacementMetadata{
+		{ID: "both", StartTime: date.Add(10 * time.Second), Duration: 2, SurfaceID: "surf_1"},
+		{ID: "low_only", StartTime: date.Add(2 * time.Second), Duration: 2, SurfaceID: "surf_2"},
+		{ID: "high_only", StartTime: date.Add(32 * time.Second), Duration: 2, SurfaceID: "surf_3"},
+	}
+
+	decorated, signaled, err := InjectAcrossRenditions([]*ManifestProcessor{low, high}, placements)
+	if err != nil {
+		t.Fatalf("InjectAcrossRenditions: %v", err)
+	}
+	if len(signaled) != 1 || signaled[0].ID != "both" {
+		t.Fatalf("Expected only the placement in both windows, got %+v", signaled)
+	}
+	for i, playlist := range decorated {
+		extracted, err := ExtractDateRangeMetadata(playlist)
+		if err != nil {
+			t.Fatalf("ExtractDateRangeMetadata: %v", err)
+		}
+		if len(extracted) != 1 || extracted[0].ID != "both" || !extracted[0].StartTime.Equal(placements[0].StartTime) {
+			t.Errorf("Rendition %d: expected the same placement, got %+v", i, extracted)
+		}
+	}
+}
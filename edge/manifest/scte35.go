@@ -0,0 +1,518 @@
+package manifest
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCTE-35 signaling, for broadcast workflows that consume SCTE-35 cues
+// rather than X-INSCENIUM-* attributes. A placement becomes a pair of
+// splice_info_sections (ANSI/SCTE 35), a cue-out at its start and a cue-in
+// at its end, carried hex-encoded in the SCTE35-OUT and SCTE35-IN
+// attributes of its EXT-X-DATERANGE tag. Each cue has a segmentation
+// descriptor of a provider placement opportunity whose UPID holds the
+// placement's ID and X-INSCENIUM-* attributes, so the placement can be
+// read back from the cues alone; other equipment sees an ordinary
+// placement opportunity.
+const (
+	SpliceInsert = "splice_insert" // Splice command 0x05, the classic avail cue
+	TimeSignal   = "time_signal"   // Splice command 0x06, carrying segmentation descriptors
+)
+
+// EXT-X-DATERANGE attributes carrying SCTE-35 cues
+const (
+	AttrSCTE35Out = "SCTE35-OUT"
+	AttrSCTE35In  = "SCTE35-IN"
+	AttrSCTE35Cmd = "SCTE35-CMD"
+)
+
+// SCTE35FormatIdentifier is the format_identifier of the MPU UPIDs holding
+// placements
+const SCTE35FormatIdentifier = "INSC"
+
+// Segmentation types of the descriptors written
+const (
+	SegmentationPlacementStart = 0x34 // Provider Placement Opportunity Start
+	SegmentationPlacementEnd   = 0x35 // Provider Placement Opportunity End
+)
+
+const (
+	spliceInfoTableID        = 0xFC
+	spliceCommandInsert      = 0x05
+	spliceCommandTimeSignal  = 0x06
+	segmentationDescriptor   = 0x02
+	segmentationUPIDMPU      = 0x0C
+	scte35Identifier         = "CUEI"
+	scte35Timescale          = 90000
+	maxSegmentationUPIDBytes = 255
+	// Bytes of a segmentation descriptor written around its UPID: tag,
+	// length, identifier, event id, cancel byte, flags, duration, UPID type
+	// and length, segmentation type, segment and sub-segment numbers
+	segmentationOverhead = 2 + 4 + 4 + 1 + 1 + 5 + 2 + 1 + 2 + 2
+)
+
+// ErrSCTE35CRC is returned for splice_info_sections failing their CRC
+var ErrSCTE35CRC = errors.New("manifest: SCTE-35 splice_info_section fails its CRC")
+
+// SCTE35Cue is a decoded splice_info_section
+type SCTE35Cue struct {
+	Command          string             // SpliceInsert or TimeSignal; other commands are left empty
+	EventID          uint32             // splice_event_id, or the first segmentation_event_id of a time_signal
+	Out              bool               // The cue starts a break or placement rather than ending it
+	Duration         float64            // Seconds of break_duration or segmentation_duration, 0 if not given
+	PTS              time.Duration      // Splice time, including pts_adjustment, when Timed
+	Timed            bool               // The cue has a splice time rather than splicing immediately
+	SegmentationType uint8              // segmentation_type_id of the first segmentation descriptor
+	Placement        *PlacementMetadata // Placement of an Inscenium UPID, without its start time
+}
+
+// FormatSCTE35 writes the start (out) or end of a placement as a SCTE-35
+// splice_info_section using command, SpliceInsert or TimeSignal. Cues
+// splice immediately: in HLS they are timed by their EXT-X-DATERANGE, and
+// splicers insert them at the splice point. Cue-outs carry the placement's
+// duration and a splice_insert cue-out returns automatically after it.
+func FormatSCTE35(p PlacementMetadata, command string, out bool) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	eventID := EventID(p.ID)
+	duration := uint64(p.Duration*scte35Timescale + 0.5)
+	if !out {
+		duration = 0
+	}
+	descriptor, err := formatSegmentationDescriptor(p, eventID, duration, out)
+	if err != nil {
+		return nil, err
+	}
+
+	var commandType byte
+	var body []byte
+	switch command {
+	case SpliceInsert:
+		commandType = spliceCommandInsert
+		body = binary.BigEndian.AppendUint32(body, eventID)
+		body = append(body, 0x7F)         // Not cancelled
+		flags := byte(0x40 | 0x10 | 0x0F) // Program splice, immediate, compliant
+		if out {
+			flags |= 0x80 | 0x20 // Out of network, with a break duration
+		}
+		body = append(body, flags)
+		if out {
+			body = appendTime(body, 0x80|0x7E, duration) // Auto return
+		}
+		body = append(body, 0, 0, 0, 0) // unique_program_id, avail_num, avails_expected
+	case TimeSignal:
+		commandType = spliceCommandTimeSignal
+		body = append(body, 0x7F) // No time specified
+	default:
+		return nil, fmt.Errorf("manifest: unknown SCTE-35 command %q, expected %s or %s", command, SpliceInsert, TimeSignal)
+	}
+
+	section := []byte{spliceInfoTableID, 0, 0} // section_length set below
+	section = append(section, 0)               // protocol_version
+	section = append(section, 0, 0, 0, 0, 0)   // Not encrypted, no pts_adjustment
+	section = append(section, 0)               // cw_index
+	commandLength := len(body)
+	section = append(section, 0xFF, 0xF0|byte(commandLength>>8), byte(commandLength), commandType)
+	section = append(section, body...)
+	section = binary.BigEndian.AppendUint16(section, uint16(len(descriptor)))
+	section = append(section, descriptor...)
+
+	length := len(section) + 4 - 3      // Bytes after section_length, CRC included
+	section[1] = 0x30 | byte(length>>8) // sap_type 3: not specified
+	section[2] = byte(length)
+	return binary.BigEndian.AppendUint32(section, crc32MPEG2(section)), nil
+}
+
+// formatSegmentationDescriptor writes the provider placement opportunity
+// descriptor of a placement's cue, its UPID holding the placement
+func formatSegmentationDescriptor(p PlacementMetadata, eventID uint32, duration uint64, out bool) ([]byte, error) {
+	upid := SCTE35FormatIdentifier + formatSCTE35Message(p)
+	if segmentationOverhead+len(upid) > 2+maxSegmentationUPIDBytes {
+		return nil, fmt.Errorf("manifest: placement %s: attributes too long for a SCTE-35 segmentation descriptor", p.ID)
+	}
+
+	d := []byte{segmentationDescriptor, 0}
+	d = append(d, scte35Identifier...)
+	d = binary.BigEndian.AppendUint32(d, eventID)
+	d = append(d, 0x7F) // Not cancelled
+	segmentationType := byte(SegmentationPlacementEnd)
+	if out {
+		segmentationType = SegmentationPlacementStart
+		d = append(d, 0x80|0x40|0x20|0x1F) // Program segmentation, with a duration, unrestricted
+		d = append(d, byte(duration>>32), byte(duration>>24), byte(duration>>16), byte(duration>>8), byte(duration))
+	} else {
+		d = append(d, 0x80|0x20|0x1F)
+	}
+	d = append(d, segmentationUPIDMPU, byte(len(upid)))
+	d = append(d, upid...)
+	d = append(d, segmentationType, 0, 0)
+	if out {
+		d = append(d, 0, 0) // sub_segment_num and sub_segments_expected of opportunity starts
+	}
+	d[1] = byte(len(d) - 2)
+	return d, nil
+}
+
+// formatSCTE35Message writes the attributes of a placement carried in its
+// UPID: those of its EXT-X-DATERANGE tag but for START-DATE and DURATION,
+// which the cues' timing and durations carry
+func formatSCTE35Message(p PlacementMetadata) string {
+	return `ID="` + p.ID + `",` +
+		AttrSurfaceID + `="` + p.SurfaceID + `",` +
+		AttrPRS + `="` + formatDecimal(p.PRSScore) + `",` +
+		AttrPlacementType + `="` + p.PlacementType + `"`
+}
+
+// appendTime appends a 33-bit 90 kHz time after the 7 bits of high
+func appendTime(b []byte, high byte, t uint64) []byte {
+	return append(b, high&0xFE|byte(t>>32)&1, byte(t>>24), byte(t>>16), byte(t>>8), byte(t))
+}
+
+// readTime reads a 33-bit 90 kHz time from 5 bytes
+func readTime(b []byte) uint64 {
+	return uint64(b[0]&1)<<32 | uint64(binary.BigEndian.Uint32(b[1:5]))
+}
+
+// ticksDuration converts 90 kHz ticks to a duration
+func ticksDuration(t uint64) time.Duration {
+	return time.Duration(t/scte35Timescale)*time.Second + time.Duration(t%scte35Timescale)*time.Second/scte35Timescale
+}
+
+// ParseSCTE35 decodes a splice_info_section. Cues of commands other than
+// splice_insert and time_signal are returned with an empty Command;
+// encrypted ones are refused.
+func ParseSCTE35(section []byte) (*SCTE35Cue, error) {
+	if len(section) < 20 || section[0] != spliceInfoTableID {
+		return nil, errors.New("manifest: not a SCTE-35 splice_info_section")
+	}
+	length := int(binary.BigEndian.Uint16(section[1:3]) & 0x0FFF)
+	if length+3 != len(section) {
+		return nil, fmt.Errorf("manifest: SCTE-35 section of %d bytes has section_length %d", len(section), length)
+	}
+	if crc32MPEG2(section[:len(section)-4]) != binary.BigEndian.Uint32(section[len(section)-4:]) {
+		return nil, ErrSCTE35CRC
+	}
+	if section[4]&0x80 != 0 {
+		return nil, errors.New("manifest: encrypted SCTE-35 sections are not supported")
+	}
+	adjustment := readTime(section[4:9])
+	commandLength := int(binary.BigEndian.Uint16(section[11:13]) & 0x0FFF)
+	commandType := section[13]
+	rest := section[14 : len(section)-4]
+
+	cue := &SCTE35Cue{}
+	r := &sectionReader{b: rest}
+	var pts uint64
+	switch commandType {
+	case spliceCommandInsert:
+		cue.Command = SpliceInsert
+		pts, cue.Timed = parseSpliceInsert(r, cue)
+	case spliceCommandTimeSignal:
+		cue.Command = TimeSignal
+		pts, cue.Timed = parseSpliceTime(r)
+	default:
+		if commandLength == 0xFFF {
+			return nil, fmt.Errorf("manifest: SCTE-35 command 0x%02X has no length", commandType)
+		}
+		r.skip(commandLength)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("manifest: truncated SCTE-35 %s", cue.Command)
+	}
+	if commandLength != 0xFFF && r.offset != commandLength {
+		return nil, fmt.Errorf("manifest: SCTE-35 splice_command_length %d does not match its command", commandLength)
+	}
+	if cue.Timed {
+		cue.PTS = ticksDuration((pts + adjustment) & (1<<33 - 1))
+	}
+
+	loop := int(r.uint16())
+	descriptors := r.bytes(loop)
+	if r.err != nil {
+		return nil, errors.New("manifest: truncated SCTE-35 descriptor loop")
+	}
+	if err := parseDescriptors(descriptors, cue); err != nil {
+		return nil, err
+	}
+	return cue, nil
+}
+
+// parseSpliceInsert reads a splice_insert command and returns its splice
+// time, that of its first component if it splices components separately
+func parseSpliceInsert(r *sectionReader, cue *SCTE35Cue) (pts uint64, timed bool) {
+	cue.EventID = r.uint32()
+	if r.byte()&0x80 != 0 { // Cancelled
+		return 0, false
+	}
+	flags := r.byte()
+	cue.Out = flags&0x80 != 0
+	program, hasDuration, immediate := flags&0x40 != 0, flags&0x20 != 0, flags&0x10 != 0
+	if program && !immediate {
+		pts, timed = parseSpliceTime(r)
+	}
+	if !program {
+		components := int(r.byte())
+		for i := 0; i < components; i++ {
+			r.skip(1) // component_tag
+			if !immediate {
+				if t, ok := parseSpliceTime(r); ok && !timed {
+					pts, timed = t, true
+				}
+			}
+		}
+	}
+	if hasDuration {
+		cue.Duration = ticksDuration(readTime(r.bytes(5))).Seconds()
+	}
+	r.skip(4) // unique_program_id, avail_num, avails_expected
+	return pts, timed
+}
+
+// parseSpliceTime reads a splice_time, returning its pts_time if it has one
+func parseSpliceTime(r *sectionReader) (uint64, bool) {
+	if r.peek()&0x80 == 0 {
+		r.skip(1)
+		return 0, false
+	}
+	return readTime(r.bytes(5)), true
+}
+
+// parseDescriptors reads the segmentation descriptors of a cue. The first
+// one sets the event, direction and duration of a time_signal; the first
+// with an Inscenium UPID sets the placement.
+func parseDescriptors(b []byte, cue *SCTE35Cue) error {
+	first := true
+	for r := (&sectionReader{b: b}); r.offset < len(b); {
+		tag, length := r.byte(), int(r.byte())
+		body := r.bytes(length)
+		if r.err != nil {
+			return errors.New("manifest: truncated SCTE-35 descriptor")
+		}
+		if tag != segmentationDescriptor || length < 4 || string(body[:4]) != scte35Identifier {
+			continue
+		}
+
+		d := &sectionReader{b: body, offset: 4}
+		eventID := d.uint32()
+		if d.byte()&0x80 != 0 { // Cancelled
+			continue
+		}
+		flags := d.byte()
+		if flags&0x80 == 0 { // Component segmentation
+			d.skip(int(d.byte()) * 6)
+		}
+		var duration float64
+		if flags&0x40 != 0 {
+			t := d.bytes(5) // 40 bits, unlike splice times
+			duration = ticksDuration(uint64(t[0])<<32 | uint64(binary.BigEndian.Uint32(t[1:]))).Seconds()
+		}
+		upidType, upid := d.byte(), d.bytes(int(d.byte()))
+		segmentationType := d.byte()
+		if d.err != nil {
+			return errors.New("manifest: truncated SCTE-35 segmentation descriptor")
+		}
+
+		if first {
+			first = false
+			cue.SegmentationType = segmentationType
+			if cue.Command != SpliceInsert {
+				cue.EventID = eventID
+				cue.Out = segmentationType >= 0x10 && segmentationType%2 == 0 // Starts are even
+				cue.Duration = duration
+			}
+		}
+		if cue.Placement == nil && upidType == segmentationUPIDMPU && strings.HasPrefix(string(upid), SCTE35FormatIdentifier) {
+			placement, err := parseSCTE35Message(strings.TrimPrefix(string(upid), SCTE35FormatIdentifier))
+			if err != nil {
+				return err
+			}
+			placement.Duration = max(duration, cue.Duration)
+			cue.Placement = placement
+		}
+	}
+	return nil
+}
+
+// parseSCTE35Message reads the placement carried in an Inscenium UPID
+func parseSCTE35Message(message string) (*PlacementMetadata, error) {
+	attributes, err := ParseAttributeList(message)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: SCTE-35 UPID: %w", err)
+	}
+	p := &PlacementMetadata{
+		ID:            attributes["ID"],
+		SurfaceID:     attributes[AttrSurfaceID],
+		PlacementType: attributes[AttrPlacementType],
+	}
+	if p.ID == "" || p.SurfaceID == "" {
+		return nil, errors.New("manifest: SCTE-35 UPID has no placement ID or surface")
+	}
+	if value, ok := attributes[AttrPRS]; ok {
+		if p.PRSScore, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("manifest: placement %s: invalid %s %q", p.ID, AttrPRS, value)
+		}
+	}
+	return p, nil
+}
+
+// FormatSCTE35DateRange writes a placement as an EXT-X-DATERANGE tag
+// carrying its cue-out and cue-in as SCTE35-OUT and SCTE35-IN, using
+// command, instead of X-INSCENIUM-* attributes
+func FormatSCTE35DateRange(p PlacementMetadata, command string) (string, error) {
+	out, err := FormatSCTE35(p, command, true)
+	if err != nil {
+		return "", err
+	}
+	in, err := FormatSCTE35(p, command, false)
+	if err != nil {
+		return "", err
+	}
+	return TagDateRange +
+		`ID="` + p.ID + `",` +
+		`START-DATE="` + p.StartTime.UTC().Format(time.RFC3339Nano) + `",` +
+		`DURATION=` + formatDecimal(p.Duration) + `,` +
+		AttrSCTE35Out + `=0x` + strings.ToUpper(hex.EncodeToString(out)) + `,` +
+		AttrSCTE35In + `=0x` + strings.ToUpper(hex.EncodeToString(in)), nil
+}
+
+// ParseSCTE35DateRange reads a placement from an EXT-X-DATERANGE tag whose
+// SCTE35-OUT, or SCTE35-CMD cue-out, holds an Inscenium UPID. It starts at
+// the tag's START-DATE and lasts the cue's duration, or else the tag's
+// DURATION or PLANNED-DURATION. It returns nil for tags signaling no
+// placement, such as cue-ins and ad breaks.
+func ParseSCTE35DateRange(tag string) (*PlacementMetadata, error) {
+	attributes, err := ParseAttributeList(strings.TrimPrefix(strings.TrimSpace(tag), TagDateRange))
+	if err != nil {
+		return nil, err
+	}
+	value, ok := attributes[AttrSCTE35Out]
+	if !ok {
+		if value, ok = attributes[AttrSCTE35Cmd]; !ok {
+			return nil, nil
+		}
+	}
+	section, err := parseHexSequence(value)
+	if err != nil {
+		return nil, fmt.Errorf("date range %s: %w", attributes["ID"], err)
+	}
+	cue, err := ParseSCTE35(section)
+	if err != nil {
+		return nil, fmt.Errorf("date range %s: %w", attributes["ID"], err)
+	}
+	if !cue.Out || cue.Placement == nil {
+		return nil, nil
+	}
+
+	p := cue.Placement
+	if p.StartTime, err = time.Parse(time.RFC3339Nano, attributes["START-DATE"]); err != nil {
+		return nil, fmt.Errorf("placement %s: invalid START-DATE %q", p.ID, attributes["START-DATE"])
+	}
+	for _, name := range []string{"DURATION", "PLANNED-DURATION"} {
+		if value, ok := attributes[name]; ok && p.Duration == 0 {
+			if p.Duration, err = strconv.ParseFloat(value, 64); err != nil || p.Duration < 0 {
+				return nil, fmt.Errorf("placement %s: invalid %s %q", p.ID, name, value)
+			}
+		}
+	}
+	return p, nil
+}
+
+// ExtractSCTE35Metadata reads the placements signaled with SCTE-35 cues in
+// a playlist, in playlist order, skipping other EXT-X-DATERANGE tags
+func ExtractSCTE35Metadata(manifest string) ([]PlacementMetadata, error) {
+	var placements []PlacementMetadata
+	for i, line := range strings.Split(manifest, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, TagDateRange) {
+			continue
+		}
+		placement, err := ParseSCTE35DateRange(line)
+		if err != nil {
+			return nil, fmt.Errorf("manifest: line %d: %w", i+1, err)
+		}
+		if placement != nil {
+			placements = append(placements, *placement)
+		}
+	}
+	return placements, nil
+}
+
+// InjectSCTE35 returns the playlist with an EXT-X-DATERANGE tag carrying
+// SCTE-35 cues of command for each placement, written where
+// InjectPlacementMetadata writes its tags
+func (mp *ManifestProcessor) InjectSCTE35(placements []PlacementMetadata, command string) (string, error) {
+	if command != SpliceInsert && command != TimeSignal {
+		return "", fmt.Errorf("manifest: unknown SCTE-35 command %q, expected %s or %s", command, SpliceInsert, TimeSignal)
+	}
+	return mp.inject(placements, func(p PlacementMetadata) (string, error) {
+		return FormatSCTE35DateRange(p, command)
+	})
+}
+
+// parseHexSequence reads an HLS hexadecimal-sequence, 0x followed by digits
+func parseHexSequence(value string) ([]byte, error) {
+	digits, ok := strings.CutPrefix(value, "0x")
+	if !ok {
+		digits, ok = strings.CutPrefix(value, "0X")
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid hexadecimal sequence %q", value)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hexadecimal sequence %q", value)
+	}
+	return b, nil
+}
+
+// crc32MPEG2 computes the CRC of MPEG-2 sections, which SCTE-35 sections end with
+func crc32MPEG2(b []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, c := range b {
+		crc ^= uint32(c) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// sectionReader reads a section's fields, remembering whether it ran out
+type sectionReader struct {
+	b      []byte
+	offset int
+	err    error
+}
+
+func (r *sectionReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.offset+n > len(r.b) {
+		r.err = errors.New("truncated")
+		r.offset = len(r.b)
+		return make([]byte, max(n, 0))
+	}
+	b := r.b[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *sectionReader) skip(n int)     { r.bytes(n) }
+func (r *sectionReader) byte() byte     { return r.bytes(1)[0] }
+func (r *sectionReader) uint16() uint16 { return binary.BigEndian.Uint16(r.bytes(2)) }
+func (r *sectionReader) uint32() uint32 { return binary.BigEndian.Uint32(r.bytes(4)) }
+
+func (r *sectionReader) peek() byte {
+	if r.offset >= len(r.b) {
+		return 0
+	}
+	return r.b[r.offset]
+}
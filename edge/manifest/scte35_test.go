@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCRC32MPEG2(t *testing.T) {
+	if crc := crc32MPEG2([]byte("123456789")); crc != 0x0376E6E7 {
+		t.Errorf("Expected the CRC-32/MPEG-2 check value, got %08X", crc)
+	}
+}
+
+func TestParseSCTE35(t *testing.T) {
+	// time_signal placement opportunity start from the SCTE 35 examples
+	section, _ := base64.StdEncoding.DecodeString("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg==")
+	cue, err := ParseSCTE35(section)
+	if err != nil {
+		t.Fatalf("ParseSCTE35: %v", err)
+	}
+	if cue.Command != TimeSignal || cue.EventID != 0x4800008E || !cue.Out || cue.Duration != 307 ||
+		cue.SegmentationType != SegmentationPlacementStart || cue.Placement != nil {
+		t.Errorf("Unexpected cue %+v", cue)
+	}
+	if want := 1924989008 * time.Second / 90000; !cue.Timed || cue.PTS-want > time.Microsecond || want-cue.PTS > time.Microsecond {
+		t.Errorf("Expected a splice time of %s, got %s", want, cue.PTS)
+	}
+
+	corrupt := append([]byte(nil), section...)
+	corrupt[20] ^= 0xFF
+	if _, err := ParseSCTE35(corrupt); !errors.Is(err, ErrSCTE35CRC) {
+		t.Errorf("Expected ErrSCTE35CRC, got %v", err)
+	}
+	if _, err := ParseSCTE35(section[:len(section)-1]); err == nil {
+		t.Error("Expected an error for a truncated section")
+	}
+}
+
+func TestFormatSCTE35(t *testing.T) {
+	placement := PlacementMetadata{
+		ID:            "booking_123",
+		StartTime:     time.Date(2024, 1, 15, 10, 30, 5, 0, time.UTC),
+		Duration:      12.5,
+		SurfaceID:     "surf_001",
+		PRSScore:      87.5,
+		PlacementType: "billboard",
+	}
+
+	for _, command := range []string{SpliceInsert, TimeSignal} {
+		out, err := FormatSCTE35(placement, command, true)
+		if err != nil {
+			t.Fatalf("%s: FormatSCTE35: %v", command, err)
+		}
+		cue, err := ParseSCTE35(out)
+		if err != nil {
+			t.Fatalf("%s: ParseSCTE35: %v", command, err)
+		}
+		if cue.Command != command || !cue.Out || cue.EventID != EventID(placement.ID) || cue.Duration != 12.5 || cue.Timed {
+			t.Errorf("%s: unexpected cue-out %+v", command, cue)
+		}
+		if p := cue.Placement; p == nil || p.ID != placement.ID || p.SurfaceID != placement.SurfaceID ||
+			p.PRSScore != placement.PRSScore || p.PlacementType != placement.PlacementType || p.Duration != 12.5 {
+			t.Errorf("%s: expected the placement in the UPID, got %+v", command, cue.Placement)
+		}
+
+		in, err := FormatSCTE35(placement, command, false)
+		if err != nil {
+			t.Fatalf("%s: FormatSCTE35: %v", command, err)
+		}
+		cue, err = ParseSCTE35(in)
+		if err != nil {
+			t.Fatalf("%s: ParseSCTE35: %v", command, err)
+		}
+		if cue.Out || cue.SegmentationType != SegmentationPlacementEnd || cue.Placement == nil {
+			t.Errorf("%s: unexpected cue-in %+v", command, cue)
+		}
+	}
+
+	if _, err := FormatSCTE35(placement, "splice_null", true); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+	long := placement
+	long.PlacementType = strings.Repeat("x", 200)
+	if _, err := FormatSCTE35(long, TimeSignal, true); err == nil {
+		t.Error("Expected an error for attributes too long for a UPID")
+	}
+}
+
+func TestInjectSCTE35_RoundTrip(t *testing.T) {
+	date := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	mp, err := NewManifestProcessor("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10.0,\nsegment_000.ts\n#EXTINF:10.0,\nsegment_001.ts\n#EXT-X-ENDLIST", date)
+	if err != nil {
+		t.Fatalf("NewManifestProcessor: %v", err)
+	}
+	placements := []PlacementMetadata{
+		{ID: "booking_1", StartTime: date.Add(2 * time.Second), Duration: 5, SurfaceID: "surf_1", PRSScore: 80, PlacementType: "wall"},
+		{ID: "booking_2", StartTime: date.Add(14500 * time.Millisecond), Duration: 3.25, SurfaceID: "surf_2", PRSScore: 91.5, PlacementType: "screen"},
+	}
+
+	for _, command := range []string{SpliceInsert, TimeSignal} {
+		decorated, err := mp.InjectSCTE35(placements, command)
+		if err != nil {
+			t.Fatalf("%s: InjectSCTE35: %v", command, err)
+		}
+		if strings.Contains(decorated, "X-INSCENIUM") || !strings.Contains(decorated, AttrSCTE35Out+"=0x") || !strings.Contains(decorated, AttrSCTE35In+"=0x") {
+			t.Errorf("%s: expected only SCTE-35 attributes, got:\n%s", command, decorated)
+		}
+		if others, _ := ExtractDateRangeMetadata(decorated); len(others) != 0 {
+			t.Errorf("%s: expected no X-INSCENIUM placements, got %+v", command, others)
+		}
+
+		extracted, err := ExtractSCTE35Metadata(decorated)
+		if err != nil {
+			t.Fatalf("%s: ExtractSCTE35Metadata: %v", command, err)
+		}
+		if len(extracted) != len(placements) {
+			t.Fatalf("%s: expected %d placements, got %+v", command, len(placements), extracted)
+		}
+		for i, want := range placements {
+			if got := extracted[i]; got != want {
+				t.Errorf("%s: placement %d: expected %+v, got %+v", command, i, want, got)
+			}
+		}
+	}
+
+	if _, err := mp.InjectSCTE35(placements, "splice_null"); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+func TestParseSCTE35DateRange(t *testing.T) {
+	// An ad break cue without an Inscenium UPID and a cue-in are not placements
+	for _, tag := range []string{
+		`#EXT-X-DATERANGE:ID="break",START-DATE="2024-01-15T10:30:00Z",PLANNED-DURATION=30,SCTE35-OUT=0xFC3034000000000000FFFFF00506FE72BD0050001E021C435545494800008E7FCF0001A599B00808000000002CA0A18A3402009AC9D17E`,
+		`#EXT-X-DATERANGE:ID="break",START-DATE="2024-01-15T10:30:00Z",SCTE35-IN=0xFC3034000000000000FFFFF00506FE72BD0050001E021C435545494800008E7FCF0001A599B00808000000002CA0A18A3402009AC9D17E`,
+		`#EXT-X-DATERANGE:ID="placement",START-DATE="2024-01-15T10:30:00Z",X-INSCENIUM-SURFACE-ID="surf_1"`,
+	} {
+		placement, err := ParseSCTE35DateRange(tag)
+		if err != nil || placement != nil {
+			t.Errorf("Expected no placement in %s, got %+v, %v", tag, placement, err)
+		}
+	}
+	for _, tag := range []string{
+		`#EXT-X-DATERANGE:ID="break",START-DATE="2024-01-15T10:30:00Z",SCTE35-OUT=FC30`,
+		`#EXT-X-DATERANGE:ID="break",START-DATE="2024-01-15T10:30:00Z",SCTE35-OUT=0xFC30`,
+	} {
+		if _, err := ParseSCTE35DateRange(tag); err == nil {
+			t.Errorf("Expected an error for %s", tag)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+-- Inscenium Analytics Schema (ClickHouse)
+-- =======================================
+-- Columnar mirror of exposure events for heavy reporting queries.
+-- Postgres remains the system of record; rows are replicated by the
+-- API gateway when ENABLE_CLICKHOUSE_SINK=true.
+
+CREATE DATABASE IF NOT EXISTS inscenium;
+
+CREATE TABLE IF NOT EXISTS inscenium.exposure_events (
+    event_id String,
+    booking_id String,
+    org_id LowCardinality(String) DEFAULT '', -- organization of the booking; '' before organizations
+    viewer_id String,
+
+    -- Temporal information
+    event_timestamp DateTime64(3, 'UTC'), -- skew-corrected, used for rollups
+    device_event_timestamp Nullable(DateTime64(3, 'UTC')),
+    received_at DateTime64(3, 'UTC'),
+    clock_skew_ms Int64 DEFAULT 0,
+
+    -- Measurement
+    exposure_duration Float64,
+    screen_coverage_percentage Nullable(Float64),
+    listen_through Nullable(Float64), -- audio placements only
+    attention_score Nullable(Float64),
+    instantaneous_prs Nullable(Float64),
+
+    -- Device and environment
+    device_type LowCardinality(String) DEFAULT '',
+    country_code LowCardinality(String) DEFAULT ''
+)
+ENGINE = ReplacingMergeTree
+PARTITION BY toYYYYMM(event_timestamp)
+ORDER BY (booking_id, event_timestamp, event_id);
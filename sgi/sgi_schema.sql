@@ -4,6 +4,7 @@
 -- Create extensions
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 CREATE EXTENSION IF NOT EXISTS "postgis" CASCADE;
+CREATE EXTENSION IF NOT EXISTS "btree_gist"; -- surface_id equality in booking exclusion constraints
 
 -- Titles table (video content)
 CREATE TABLE IF NOT EXISTS titles (
@@ -43,7 +44,11 @@ CREATE TABLE IF NOT EXISTS surfaces (
     start_time REAL NOT NULL,
     end_time REAL NOT NULL,
     
-    -- Spatial geometry (PostGIS)
+    -- Placement type: a surface on screen, or a slot in the audio track
+    -- bounded by start_time and end_time (podcasts, music videos)
+    placement_type VARCHAR(10) NOT NULL DEFAULT 'visual' CHECK (placement_type IN ('visual', 'audio')),
+    
+    -- Spatial geometry (PostGIS); NULL for audio slots
     geometry GEOMETRY(POLYGON, 4326),
     bounds_3d JSONB, -- 3D bounding box as JSON
     
@@ -53,6 +58,11 @@ CREATE TABLE IF NOT EXISTS surfaces (
     area_world_m2 REAL,
     normal_vector JSONB, -- Surface normal as [x, y, z]
     
+    -- Loudness constraints on creatives played in audio slots
+    loudness_target_lufs REAL,
+    loudness_tolerance_lu REAL,
+    max_true_peak_dbtp REAL,
+    
     -- Quality metrics
     prs_score REAL DEFAULT 0,
     visibility_score REAL DEFAULT 0,
@@ -69,6 +79,12 @@ CREATE TABLE IF NOT EXISTS surfaces (
     capabilities JSONB DEFAULT '[]',
     metadata JSONB DEFAULT '{}',
     
+    -- Freshness: set when the pipeline last ingested the surface, and when
+    -- it was queued for re-validation (e.g. a new cut of its title arrived)
+    last_validated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revalidation_requested_at TIMESTAMP,
+    revalidation_reason VARCHAR(50), -- "cut_delivered", "requested"
+    
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -142,18 +158,24 @@ CREATE TABLE IF NOT EXISTS placement_bookings (
     advertiser_id VARCHAR(100) NOT NULL,
     campaign_id VARCHAR(100) NOT NULL,
     creative_asset_id VARCHAR(100),
+    bundle_id VARCHAR(100), -- bundle deal; bookings of a campaign in one bundle are exempt from each other's collision rules
+    org_id VARCHAR(100), -- owning organization, that of the campaign; NULL for bookings made before organizations
     
     -- Financial terms
     bid_amount_cpm DECIMAL(10, 2) NOT NULL,
     final_cpm_rate DECIMAL(10, 2),
-    estimated_impressions INTEGER DEFAULT 0,
-    actual_impressions INTEGER DEFAULT 0,
+    billing_model VARCHAR(20) NOT NULL DEFAULT 'cpm' CHECK (billing_model IN ('cpm', 'attention_cpm', 'vcpm')), -- attention_cpm and vcpm are charged on exposure_events
+    estimated_impressions INTEGER DEFAULT 0, -- max_impressions; 0 is uncapped
+    actual_impressions INTEGER DEFAULT 0, -- counted exposure events; the booking completes when they reach estimated_impressions
+    pacing VARCHAR(10) NOT NULL DEFAULT 'asap' CHECK (pacing IN ('asap', 'even')), -- even spreads estimated_impressions over start_time to end_time
+    frequency_cap_max INTEGER CHECK (frequency_cap_max BETWEEN 1 AND 1000), -- exposures per viewer within frequency_cap_window_hours; NULL is uncapped
+    frequency_cap_window_hours INTEGER CHECK (frequency_cap_window_hours BETWEEN 1 AND 720),
     
     -- Booking lifecycle
-    status VARCHAR(20) DEFAULT 'pending', -- pending, confirmed, active, completed, cancelled
+    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'active', 'paused', 'completed', 'cancelled', 'expired')), -- projected from booking_events
     booking_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     confirmation_time TIMESTAMP,
-    start_time TIMESTAMP,
+    start_time TIMESTAMP, -- exposure window; NULL ends are open
     end_time TIMESTAMP,
     
     -- Creative requirements
@@ -166,7 +188,15 @@ CREATE TABLE IF NOT EXISTS placement_bookings (
     metadata JSONB DEFAULT '{}',
     
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (end_time IS NULL OR start_time IS NULL OR end_time > start_time),
+    CHECK ((frequency_cap_max IS NULL) = (frequency_cap_window_hours IS NULL)),
+    -- live bookings of a surface may not overlap in time
+    CONSTRAINT placement_bookings_no_overlap EXCLUDE USING gist (
+        surface_id WITH =,
+        tsrange(start_time, end_time) WITH &&
+    ) WHERE (status IN ('pending', 'confirmed', 'active', 'paused'))
 );
 
 -- Exposure events (for measurement)
@@ -176,11 +206,16 @@ CREATE TABLE IF NOT EXISTS exposure_events (
     booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
     
     -- Viewer information (anonymized)
-    viewer_id VARCHAR(100) NOT NULL, -- Anonymous hash
+    viewer_id TEXT NOT NULL, -- Anonymous hash, encrypted at rest when a KMS is configured
     session_id VARCHAR(100),
+    client_event_id VARCHAR(128), -- player's ID for the beacon, kept across resends
+    dedupe_key VARCHAR(64), -- SHA-256 of booking, viewer and client event ID; NULL without a client event ID
     
     -- Temporal information
-    event_timestamp TIMESTAMP NOT NULL,
+    event_timestamp TIMESTAMP NOT NULL, -- skew-corrected, used for rollups
+    device_event_timestamp TIMESTAMP, -- raw time reported by the device
+    received_at TIMESTAMP, -- gateway receive time
+    clock_skew_ms BIGINT DEFAULT 0, -- estimated server minus device clock
     exposure_duration REAL NOT NULL, -- seconds
     content_position REAL, -- position within video
     
@@ -188,7 +223,8 @@ CREATE TABLE IF NOT EXISTS exposure_events (
     viewing_angle_azimuth REAL,
     viewing_angle_elevation REAL,
     viewing_distance_meters REAL,
-    screen_coverage_percentage REAL,
+    screen_coverage_percentage REAL, -- NULL for audio placements
+    listen_through REAL CHECK (listen_through BETWEEN 0 AND 1), -- share of an audio slot heard; NULL for visual placements
     attention_score REAL, -- 0-1 from eye tracking
     
     -- Quality at exposure time
@@ -196,6 +232,14 @@ CREATE TABLE IF NOT EXISTS exposure_events (
     occlusion_level REAL,
     lighting_quality REAL,
     
+    -- Billing
+    campaign_id VARCHAR(100), -- charged campaign of attention-billed exposures
+    spend DECIMAL(14, 6) NOT NULL DEFAULT 0, -- what the exposure cost under its booking's billing model
+    counted BOOLEAN NOT NULL DEFAULT true, -- false past its booking's max_impressions or its campaign's budget; neither delivered nor charged
+    
+    -- Tenancy
+    org_id VARCHAR(100), -- organization of the booking when the event was recorded
+    
     -- Device and environment
     device_type VARCHAR(50), -- mobile, desktop, tv, etc.
     player_version VARCHAR(50),
@@ -204,6 +248,7 @@ CREATE TABLE IF NOT EXISTS exposure_events (
     
     -- Privacy and compliance
     consent_given BOOLEAN DEFAULT false,
+    consent_string TEXT, -- e.g. TCF string, encrypted at rest when a KMS is configured
     jurisdiction VARCHAR(10) DEFAULT 'US',
     privacy_flags JSONB DEFAULT '{}',
     
@@ -215,6 +260,874 @@ CREATE TABLE IF NOT EXISTS exposure_events (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Last acknowledgement sequence handed to each API key for batch exposure ingestion
+CREATE TABLE IF NOT EXISTS exposure_ack_streams (
+    key_id VARCHAR(100) PRIMARY KEY,
+    last_sequence BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Acknowledged exposure batches, the most recent of each API key; written
+-- after the batch's events, so an acknowledged batch is durably stored
+CREATE TABLE IF NOT EXISTS exposure_acks (
+    key_id VARCHAR(100) NOT NULL REFERENCES exposure_ack_streams(key_id) ON DELETE CASCADE,
+    sequence BIGINT NOT NULL,
+    sequence_token VARCHAR(200), -- edge node's own position in its buffer, echoed back
+    stored_count INTEGER NOT NULL,
+    failed_count INTEGER NOT NULL,
+    acked_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (key_id, sequence)
+);
+
+-- Free-form key=value labels on campaigns, bookings, creatives and surfaces
+CREATE TABLE IF NOT EXISTS resource_labels (
+    resource_type VARCHAR(20) NOT NULL, -- campaign, booking, creative, surface
+    resource_id VARCHAR(100) NOT NULL,
+    label_key VARCHAR(63) NOT NULL,
+    label_value VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (resource_type, resource_id, label_key)
+);
+
+-- Partner-assigned IDs, namespaced per source (e.g. gam, dv360)
+CREATE TABLE IF NOT EXISTS external_ids (
+    resource_type VARCHAR(20) NOT NULL, -- campaign, booking, creative
+    resource_id VARCHAR(100) NOT NULL,
+    source VARCHAR(63) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (resource_type, resource_id, source),
+    UNIQUE (resource_type, source, external_id)
+);
+
+-- Owning organization of campaigns, bookings and creatives.
+-- Resources without a row predate organizations and are unrestricted.
+CREATE TABLE IF NOT EXISTS resource_owners (
+    resource_type VARCHAR(20) NOT NULL,
+    resource_id VARCHAR(100) NOT NULL,
+    org_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (resource_type, resource_id)
+);
+
+-- Cross-organization grants (e.g. an agency managing a brand's campaign)
+CREATE TABLE IF NOT EXISTS resource_grants (
+    id SERIAL PRIMARY KEY,
+    grant_id VARCHAR(150) NOT NULL UNIQUE,
+    resource_type VARCHAR(20) NOT NULL,
+    resource_id VARCHAR(100) NOT NULL,
+    owner_org_id VARCHAR(100) NOT NULL,
+    grantee_org_id VARCHAR(100) NOT NULL,
+    permissions TEXT[] NOT NULL, -- view, manage
+    granted_by VARCHAR(100),
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- An organization's own terms for surface types and restriction categories;
+-- surfaces store only the canonical terms
+CREATE TABLE IF NOT EXISTS taxonomy_aliases (
+    org_id VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('surface_type', 'restriction')),
+    alias VARCHAR(50) NOT NULL,
+    canonical VARCHAR(50) NOT NULL,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (org_id, kind, alias),
+    UNIQUE (org_id, kind, canonical)
+);
+
+-- Non-human principals (CI systems, render workers) with scoped API keys
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    org_id VARCHAR(100),
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by VARCHAR(100),
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    disabled_at TIMESTAMP
+);
+
+-- Service account keys are valid until revoked, except that a rotated key
+-- expires once its overlap with the key replacing it ends
+CREATE TABLE IF NOT EXISTS service_account_keys (
+    key_id VARCHAR(32) PRIMARY KEY,
+    account_id VARCHAR(100) NOT NULL REFERENCES service_accounts(account_id),
+    version INTEGER NOT NULL DEFAULT 1, -- Issue order within the account
+    secret_hash TEXT NOT NULL, -- SHA-256 of the secret, encrypted at rest when a KMS is configured
+    json_case VARCHAR(10) NOT NULL DEFAULT 'snake', -- Request field naming accepted: snake or any (camelCase too)
+    replaced_by VARCHAR(32), -- Key issued when this one was rotated
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    expiry_warned_at TIMESTAMP, -- When the coming expiry was announced to webhook subscriptions
+    revoked_at TIMESTAMP,
+
+    UNIQUE (account_id, version)
+);
+
+-- People who sign in with a password; the username is the subject of their tokens
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    org_id VARCHAR(100),
+    role VARCHAR(20) NOT NULL DEFAULT 'analyst' CHECK (role IN ('admin', 'advertiser', 'publisher', 'analyst')),
+    password_hash TEXT NOT NULL, -- bcrypt
+    created_by VARCHAR(100),
+
+    -- Login lockout
+    failed_logins INTEGER NOT NULL DEFAULT 0, -- consecutive failures since the last login
+    locked_until TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    disabled_at TIMESTAMP
+);
+
+-- Organizations (tenants). Bookings, exposure events and metrics of one are
+-- never returned to another except through resource grants.
+CREATE TABLE IF NOT EXISTS organizations (
+    org_id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Organizations users may sign in to besides their own (users.org_id)
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id VARCHAR(100) NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+    username VARCHAR(100) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    added_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, username)
+);
+
+-- Browser origins admins allowed to call a route group for an organization,
+-- besides those configured for the group
+CREATE TABLE IF NOT EXISTS cors_origins (
+    org_id VARCHAR(100) NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+    route_group VARCHAR(20) NOT NULL CHECK (route_group IN ('api', 'public')),
+    origin VARCHAR(255) NOT NULL, -- lowercased scheme://host[:port]
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, route_group, origin)
+);
+
+-- Refresh tokens of signed-in users when Redis is not configured. Each is
+-- deleted when it is exchanged for a new one.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY, -- SHA-256 of the token
+    session_id VARCHAR(32) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    org_id VARCHAR(100), -- organization the user signed in to
+    expires_at TIMESTAMP NOT NULL, -- end of the session
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sessions signed out before they expired, whose access tokens are refused
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id VARCHAR(32) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL -- when the session's last access token expires
+);
+
+-- Render farm jobs, driven by signed worker callbacks
+CREATE TABLE IF NOT EXISTS render_jobs (
+    id SERIAL PRIMARY KEY,
+    job_id VARCHAR(100) NOT NULL UNIQUE,
+    booking_id VARCHAR(100),
+    surface_id VARCHAR(100),
+    worker_id VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, running, completed, failed, cancelled
+    progress DECIMAL(4,3) NOT NULL DEFAULT 0 CHECK (progress >= 0 AND progress <= 1),
+    output_uri TEXT,
+    error TEXT,
+    watermark_id BIGINT, -- measurement watermark embedded in the output, issued with the job's booking
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_event_at TIMESTAMP NOT NULL -- occurred_at of the latest applied callback
+);
+
+-- Sightings of rendered placements' watermarks in captured frames
+CREATE TABLE IF NOT EXISTS watermark_verifications (
+    id SERIAL PRIMARY KEY,
+    verification_id VARCHAR(100) NOT NULL UNIQUE,
+    watermark_id BIGINT NOT NULL,
+    job_id VARCHAR(100) NOT NULL,
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    source VARCHAR(100), -- e.g. the verification vendor or capture device
+    confidence DECIMAL(4,3) NOT NULL, -- share of watermark cells agreeing with the ID read
+    captured_at TIMESTAMP NOT NULL,
+    recorded_by VARCHAR(100), -- API key or user that submitted the sample
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Hourly and daily aggregates of counted exposure events, recomputed from the
+-- events by the rollup worker (control/api/internal/rollup) as they arrive
+CREATE TABLE IF NOT EXISTS exposure_rollups_hourly (
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL, -- UTC start of the hour
+    org_id VARCHAR(100),
+    impressions BIGINT NOT NULL,
+    unique_viewers BIGINT NOT NULL, -- within the bucket; not additive across buckets
+    exposure_time DOUBLE PRECISION NOT NULL,
+    prs_sum DOUBLE PRECISION NOT NULL DEFAULT 0, -- sums and counts of non-NULL values, for averages
+    prs_count BIGINT NOT NULL DEFAULT 0,
+    attention_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attention_count BIGINT NOT NULL DEFAULT 0,
+    coverage_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    coverage_count BIGINT NOT NULL DEFAULT 0,
+    listen_through_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    listen_through_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS exposure_rollups_daily (
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    bucket TIMESTAMP NOT NULL, -- UTC start of the day
+    org_id VARCHAR(100),
+    impressions BIGINT NOT NULL,
+    unique_viewers BIGINT NOT NULL, -- within the bucket; not additive across buckets
+    exposure_time DOUBLE PRECISION NOT NULL,
+    prs_sum DOUBLE PRECISION NOT NULL DEFAULT 0, -- sums and counts of non-NULL values, for averages
+    prs_count BIGINT NOT NULL DEFAULT 0,
+    attention_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attention_count BIGINT NOT NULL DEFAULT 0,
+    coverage_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    coverage_count BIGINT NOT NULL DEFAULT 0,
+    listen_through_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    listen_through_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, bucket)
+);
+
+-- Receipt time up to which exposure events are rolled up
+CREATE TABLE IF NOT EXISTS exposure_rollup_state (
+    name VARCHAR(50) PRIMARY KEY,
+    rolled_up_through TIMESTAMP NOT NULL DEFAULT '1970-01-01',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each campaign's counted impressions and distinct viewers of a UTC day, the
+-- viewers as a HyperLogLog sketch (control/api/internal/sketch) that merges
+-- with other days' into reach over any range; rolled up as "reach" in
+-- exposure_rollup_state
+CREATE TABLE IF NOT EXISTS campaign_reach_daily (
+    campaign_id VARCHAR(100) NOT NULL,
+    bucket TIMESTAMP NOT NULL, -- UTC start of the day
+    org_id VARCHAR(100),
+    impressions BIGINT NOT NULL,
+    viewers BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, bucket)
+);
+
+-- Standing approval of each creative a booking has run, by the precedence
+-- policy of control/api/internal/approval; absent means pending
+CREATE TABLE IF NOT EXISTS creative_approvals (
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    creative_asset_id VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('approved', 'rejected')),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('delegated', 'platform')),
+    reviewer VARCHAR(255),
+    reason TEXT,
+    external_ref VARCHAR(255), -- the delegated tool's own ID for the decision
+    decided_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, creative_asset_id)
+);
+
+-- Bookings whose placements are signaled as HLS Interstitials, playing the
+-- booked creative rendition; absent means they are only tagged
+CREATE TABLE IF NOT EXISTS booking_interstitials (
+    booking_id VARCHAR(100) PRIMARY KEY REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    asset_uri TEXT, -- HLS playlist of the creative; NULL plays the booking's latest completed render
+    resume_offset REAL CHECK (resume_offset >= 0), -- seconds of the title skipped; NULL skips the placement
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every approval decision received, with its outcome under the precedence policy
+CREATE TABLE IF NOT EXISTS creative_approval_decisions (
+    id SERIAL PRIMARY KEY,
+    decision_id VARCHAR(150) NOT NULL UNIQUE,
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    creative_asset_id VARCHAR(100) NOT NULL,
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('delegated', 'platform')),
+    reviewer VARCHAR(255),
+    reason TEXT,
+    external_ref VARCHAR(255),
+    decided_at TIMESTAMP NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('applied', 'overruled', 'stale')),
+    conflict BOOLEAN NOT NULL DEFAULT FALSE -- disagreed with the standing decision on arrival
+);
+
+-- Nonces of accepted webhook callbacks, kept for the timestamp tolerance window
+CREATE TABLE IF NOT EXISTS webhook_nonces (
+    source VARCHAR(50) NOT NULL,
+    nonce VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (source, nonce)
+);
+
+-- Outbound webhook subscriptions. secret signs deliveries and is sealed when
+-- encryption at rest is configured.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id SERIAL PRIMARY KEY,
+    subscription_id VARCHAR(100) NOT NULL UNIQUE,
+    org_id VARCHAR(100),
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL, -- booking.confirmed, booking.cancelled, booking.completed, pacing.threshold, exposure.milestone
+    description VARCHAR(255),
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every event sent, or to be sent, to a webhook subscription
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(150) NOT NULL UNIQUE,
+    subscription_id VARCHAR(100) NOT NULL REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    event_id VARCHAR(100) NOT NULL, -- shared by the deliveries of one event
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, retrying, delivered, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER, -- HTTP status of the last attempt
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+-- Background jobs for the Postgres job queue backend. Acked jobs are deleted;
+-- dead jobs stay for inspection.
+CREATE TABLE IF NOT EXISTS job_queue (
+    id SERIAL PRIMARY KEY,
+    job_id VARCHAR(100) NOT NULL UNIQUE,
+    queue VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, dead
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    lease VARCHAR(64), -- identifies the current claim
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- earliest time the job may be claimed
+    visible_at TIMESTAMP, -- visibility deadline of the current claim
+    last_error TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Placement decision outcomes (for fill-rate monitoring)
+CREATE TABLE IF NOT EXISTS decision_events (
+    id SERIAL PRIMARY KEY,
+    event_id VARCHAR(150) NOT NULL UNIQUE,
+    surface_id VARCHAR(100) NOT NULL,
+    title_id VARCHAR(100) NOT NULL,
+    booking_id VARCHAR(100), -- set when the opportunity was filled
+    campaign_id VARCHAR(100), -- charged campaign of served decisions
+    spend DECIMAL(14, 6) NOT NULL DEFAULT 0, -- authoritative budget spend
+
+    outcome VARCHAR(20) NOT NULL, -- served, unfilled, error
+    error_code VARCHAR(50),
+
+    event_timestamp TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Asynchronous report jobs
+CREATE TABLE IF NOT EXISTS report_jobs (
+    id SERIAL PRIMARY KEY,
+    job_id VARCHAR(150) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    params JSONB NOT NULL, -- report query
+    webhook_url TEXT,
+    webhook_secret TEXT, -- signs the webhook POST; sealed when encryption at rest is configured
+    requested_by VARCHAR(100),
+    attempts INTEGER NOT NULL DEFAULT 0, -- times claimed by a worker; a job running too long is claimed again
+
+    -- Outcome
+    result JSONB, -- report rows of jobs completed before results moved to object storage
+    result_key TEXT, -- object storage key of the result rows, as JSON Lines
+    row_count INTEGER DEFAULT 0,
+    error TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- Async raw event exports; files are kept in object storage
+CREATE TABLE IF NOT EXISTS event_exports (
+    id SERIAL PRIMARY KEY,
+    export_id VARCHAR(150) NOT NULL UNIQUE,
+    booking_id VARCHAR(100) NOT NULL,
+    format VARCHAR(20) NOT NULL, -- csv, parquet
+    from_time TIMESTAMP NOT NULL,
+    to_time TIMESTAMP NOT NULL,
+    tenant_scope JSONB NOT NULL, -- organizations whose events the requester may export
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed
+    requested_by VARCHAR(100),
+
+    -- Outcome
+    object_key TEXT, -- object storage key of the export file
+    row_count BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- Daily log-level files of each advertiser's decisions and impressions for
+-- auditors; files are kept in object storage
+CREATE TABLE IF NOT EXISTS log_level_exports (
+    id SERIAL PRIMARY KEY,
+    advertiser_id VARCHAR(100) NOT NULL,
+    day DATE NOT NULL, -- UTC day of the events
+    format VARCHAR(20) NOT NULL, -- csv, parquet
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, completed, failed; failed files are claimed again
+
+    -- Outcome
+    object_key TEXT, -- object storage key of the file
+    row_count BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+
+    UNIQUE (advertiser_id, day) -- claimed once however many gateways schedule it
+);
+
+-- Append-only booking lifecycle events. placement_bookings holds their
+-- projection; rows outlive the booking so its history stays auditable.
+CREATE TABLE IF NOT EXISTS booking_events (
+    id SERIAL PRIMARY KEY,
+    booking_id VARCHAR(100) NOT NULL,
+    sequence INTEGER NOT NULL, -- 1-based, no gaps per booking
+    event_type VARCHAR(20) NOT NULL, -- created, approved, activated, amended, paused, completed, expired, cancelled
+    actor VARCHAR(100), -- user or service account, 'system' for backfilled events
+    org_id VARCHAR(100),
+    data JSONB NOT NULL DEFAULT '{}', -- booking terms set by the event
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (booking_id, sequence)
+);
+
+-- Inventory held back from sale per title for editorial protection
+CREATE TABLE IF NOT EXISTS inventory_holdbacks (
+    title_id INTEGER PRIMARY KEY REFERENCES titles(id) ON DELETE CASCADE,
+    percent REAL NOT NULL DEFAULT 0 CHECK (percent >= 0 AND percent <= 100), -- of unreserved surfaces
+    surface_ids TEXT[] NOT NULL DEFAULT '{}', -- reserved surfaces, never sold
+    reason TEXT,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Limits on booked surfaces of a shot on screen at once; shot_id '' is the title's default
+CREATE TABLE IF NOT EXISTS collision_rules (
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    shot_id VARCHAR(100) NOT NULL DEFAULT '', -- pipeline shot ID, as in shots.shot_id
+    max_concurrent INTEGER NOT NULL DEFAULT 0 CHECK (max_concurrent >= 0), -- 0 is unlimited
+    min_separation REAL NOT NULL DEFAULT 0 CHECK (min_separation >= 0 AND min_separation <= 1), -- normalized frame units
+    reason TEXT,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (title_id, shot_id)
+);
+
+-- Near-duplicate surface clusters found by the dedupe job, replaced per title on each run
+CREATE TABLE IF NOT EXISTS surface_duplicates (
+    cluster_id VARCHAR(120) NOT NULL,
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    surface_id VARCHAR(100) NOT NULL REFERENCES surfaces(surface_id) ON DELETE CASCADE,
+    canonical BOOLEAN NOT NULL DEFAULT false, -- suggested surface to merge the others into
+    geometry_iou REAL NOT NULL DEFAULT 0,
+    time_overlap REAL NOT NULL DEFAULT 0,
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (cluster_id, surface_id)
+);
+
+-- Surfaces merged into a canonical surface. Merged surfaces are kept so their
+-- ended bookings and exposure history stay readable, but are no longer sold.
+CREATE TABLE IF NOT EXISTS surface_merges (
+    surface_id VARCHAR(100) PRIMARY KEY REFERENCES surfaces(surface_id) ON DELETE CASCADE,
+    merged_into VARCHAR(100) NOT NULL REFERENCES surfaces(surface_id) ON DELETE CASCADE,
+    merged_by VARCHAR(100),
+    bookings_moved INTEGER NOT NULL DEFAULT 0,
+    merged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Delivered edits of a title. Surfaces are aligned to the most recently applied cut.
+CREATE TABLE IF NOT EXISTS title_cuts (
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    cut_id VARCHAR(100) NOT NULL,
+    applied_at TIMESTAMP, -- when surfaces were aligned to this cut
+    last_remap JSONB, -- report of the remap that applied it
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (title_id, cut_id)
+);
+
+-- Per-shot perceptual hashes of each cut, uploaded by the pipeline
+CREATE TABLE IF NOT EXISTS shot_fingerprints (
+    title_id INTEGER NOT NULL,
+    cut_id VARCHAR(100) NOT NULL,
+    shot_id VARCHAR(50) NOT NULL,
+    start_time REAL NOT NULL,
+    end_time REAL NOT NULL,
+    phash CHAR(16) NOT NULL, -- 64-bit hash as hex
+
+    PRIMARY KEY (title_id, cut_id, shot_id),
+    FOREIGN KEY (title_id, cut_id) REFERENCES title_cuts(title_id, cut_id) ON DELETE CASCADE
+);
+
+-- Shows whose titles are episodes
+CREATE TABLE IF NOT EXISTS series (
+    series_id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A title's place in a series
+CREATE TABLE IF NOT EXISTS title_episodes (
+    title_id INTEGER PRIMARY KEY REFERENCES titles(id) ON DELETE CASCADE,
+    series_id VARCHAR(100) NOT NULL REFERENCES series(series_id) ON DELETE CASCADE,
+    season_number INTEGER NOT NULL CHECK (season_number >= 1),
+    episode_number INTEGER NOT NULL CHECK (episode_number >= 1),
+
+    UNIQUE (series_id, season_number, episode_number)
+);
+
+-- Bookings of a surface type across a series or season, fanned out to placement bookings
+CREATE TABLE IF NOT EXISTS series_bookings (
+    series_booking_id VARCHAR(100) PRIMARY KEY,
+    series_id VARCHAR(100) NOT NULL REFERENCES series(series_id) ON DELETE CASCADE,
+    season_number INTEGER, -- NULL books every season
+    surface_type VARCHAR(50) NOT NULL,
+    min_prs_score REAL NOT NULL DEFAULT 0,
+    advertiser_id VARCHAR(100) NOT NULL,
+    campaign_id VARCHAR(100) NOT NULL,
+    bid_amount_cpm DECIMAL(10, 2) NOT NULL,
+    max_impressions INTEGER NOT NULL DEFAULT 0, -- per placement booking
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Placement bookings made by a series booking, with the episode they were made in
+CREATE TABLE IF NOT EXISTS series_booking_items (
+    booking_id VARCHAR(100) PRIMARY KEY REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    series_booking_id VARCHAR(100) NOT NULL REFERENCES series_bookings(series_booking_id) ON DELETE CASCADE,
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    season_number INTEGER NOT NULL,
+    episode_number INTEGER NOT NULL
+);
+
+-- Brands that campaigns are run for
+CREATE TABLE IF NOT EXISTS advertisers (
+    advertiser_id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    org_id VARCHAR(100), -- owning organization; NULL for advertisers visible to all
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMP
+);
+
+-- Campaigns bookings are made in; the owning organization is in resource_owners
+-- and the spend cap in campaign_budgets
+CREATE TABLE IF NOT EXISTS campaigns (
+    campaign_id VARCHAR(100) PRIMARY KEY,
+    advertiser_id VARCHAR(100) NOT NULL REFERENCES advertisers(advertiser_id),
+    name VARCHAR(255) NOT NULL,
+    flight_start TIMESTAMP,
+    flight_end TIMESTAMP, -- bookings are refused from then on
+    frequency_cap_max INTEGER CHECK (frequency_cap_max BETWEEN 1 AND 1000), -- exposures per viewer across the campaign's bookings; NULL is uncapped
+    frequency_cap_window_hours INTEGER CHECK (frequency_cap_window_hours BETWEEN 1 AND 720),
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMP,
+
+    CHECK (flight_end IS NULL OR flight_start IS NULL OR flight_end > flight_start),
+    CHECK ((frequency_cap_max IS NULL) = (frequency_cap_window_hours IS NULL))
+);
+
+-- Campaign spend caps; spend is summed from served decision_events
+CREATE TABLE IF NOT EXISTS campaign_budgets (
+    campaign_id VARCHAR(100) PRIMARY KEY,
+    amount DECIMAL(14, 2) NOT NULL CHECK (amount > 0),
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Impression quotas leased to edge nodes; held is what the node had left
+-- when it last leased, so a lost Redis counter is reseeded without it
+CREATE TABLE IF NOT EXISTS impression_leases (
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    node_id VARCHAR(100) NOT NULL,
+    held INTEGER NOT NULL CHECK (held >= 0),
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, node_id)
+);
+
+-- Data encryption keys for sensitive columns, wrapped by a KMS master key.
+-- The newest unretired key encrypts; every key is kept so old values decrypt.
+CREATE TABLE IF NOT EXISTS encryption_keys (
+    key_id VARCHAR(64) PRIMARY KEY,
+    master_key_id VARCHAR(255) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rewrapped_at TIMESTAMP,
+    retired_at TIMESTAMP -- Set once rotation re-encrypted its values under a newer key
+);
+
+-- Minimum unique viewers per grouped report row, per organization.
+-- Organizations without a row get REPORT_MIN_AUDIENCE.
+CREATE TABLE IF NOT EXISTS report_privacy_settings (
+    org_id VARCHAR(100) PRIMARY KEY,
+    min_audience INTEGER NOT NULL CHECK (min_audience >= 0),
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('suppress', 'aggregate')),
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Columns an organization's data may not be exported with, per its data
+-- processing agreement. Every export of its events and log-level records
+-- strips or hashes them.
+CREATE TABLE IF NOT EXISTS export_policies (
+    org_id VARCHAR(100) PRIMARY KEY,
+    columns JSONB NOT NULL, -- Column name to action: strip or hash
+    hash_key TEXT NOT NULL, -- HMAC key of hashed columns, encrypted at rest when a KMS is configured
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The ingestion policy operators set for edge nodes' exposure batches, at most
+-- one row. Without it nodes may send full batches as often as they like.
+CREATE TABLE IF NOT EXISTS ingestion_policy (
+    singleton BOOLEAN PRIMARY KEY DEFAULT true CHECK (singleton),
+    max_batch_size INTEGER NOT NULL CHECK (max_batch_size BETWEEN 1 AND 1000),
+    min_interval_ms BIGINT NOT NULL DEFAULT 0 CHECK (min_interval_ms BETWEEN 0 AND 3600000),
+    reason VARCHAR(255),
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Daily counts of personal data found in inbound payloads, per API key.
+-- Only the field and kind are kept, never the values.
+CREATE TABLE IF NOT EXISTS pii_violations (
+    day DATE NOT NULL,
+    principal_id VARCHAR(100) NOT NULL,
+    key_id VARCHAR(32) NOT NULL DEFAULT '', -- Empty for users authenticated with JWTs
+    org_id VARCHAR(100) NOT NULL DEFAULT '',
+    field VARCHAR(63) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('reject', 'mask', 'report')),
+    count BIGINT NOT NULL DEFAULT 0,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (day, principal_id, key_id, field, kind, action)
+);
+
+-- Bundles of campaigns promoted into this environment from another, with
+-- the manifest mapping their source IDs onto the IDs created here
+CREATE TABLE IF NOT EXISTS promotion_imports (
+    import_id VARCHAR(150) PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    org_id VARCHAR(100),
+    imported_by VARCHAR(100),
+    manifest JSONB NOT NULL,
+    imported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Bookings created by promotion, so importing a bundle again skips them
+CREATE TABLE IF NOT EXISTS promoted_bookings (
+    source VARCHAR(100) NOT NULL,
+    source_booking_id VARCHAR(100) NOT NULL,
+    booking_id VARCHAR(100) NOT NULL REFERENCES placement_bookings(booking_id) ON DELETE CASCADE,
+    import_id VARCHAR(150) NOT NULL REFERENCES promotion_imports(import_id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, source_booking_id)
+);
+
+-- Where each title stands in the ingestion pipeline, one row per stage that
+-- the pipeline has reported on
+CREATE TABLE IF NOT EXISTS title_ingestion_stages (
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    stage VARCHAR(30) NOT NULL, -- uploaded, shots_detected, surfaces_extracted, scored, published
+    status VARCHAR(20) NOT NULL, -- running, completed, failed
+    run_id VARCHAR(100) NOT NULL DEFAULT '',
+    message TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL, -- occurred_at of the latest applied callback
+    PRIMARY KEY (title_id, stage)
+);
+
+-- Scene graphs pushed by the vision pipeline, one per shot; a later run
+-- replaces the earlier graph
+CREATE TABLE IF NOT EXISTS shot_scene_graphs (
+    title_id INTEGER NOT NULL,
+    shot_id VARCHAR(50) NOT NULL,
+    run_id VARCHAR(100) NOT NULL DEFAULT '',
+    nodes JSONB NOT NULL DEFAULT '[]',
+    edges JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (title_id, shot_id),
+    FOREIGN KEY (title_id, shot_id) REFERENCES shots(title_id, shot_id) ON DELETE CASCADE
+);
+
+-- Quality checks a pipeline run applied to a title. subject is
+-- 'surface:<id>', 'shot:<id>' or '' for the whole title.
+CREATE TABLE IF NOT EXISTS title_qc_results (
+    title_id INTEGER NOT NULL REFERENCES titles(id) ON DELETE CASCADE,
+    run_id VARCHAR(100) NOT NULL,
+    check_name VARCHAR(100) NOT NULL,
+    subject VARCHAR(160) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL, -- passed, warning, failed
+    score REAL,
+    message TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (title_id, run_id, check_name, subject)
+);
+
+-- Reference frame of each surface, uploaded by the pipeline. The image and
+-- its resized variants live in object storage under the version, a digest
+-- of the image.
+CREATE TABLE IF NOT EXISTS surface_thumbnails (
+    surface_id VARCHAR(100) PRIMARY KEY REFERENCES surfaces(surface_id) ON DELETE CASCADE,
+    version VARCHAR(64) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    byte_size INTEGER NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Idempotency keys of POST requests when Redis is not configured. status is
+-- NULL while the first request is in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(600) PRIMARY KEY, -- caller, route and the client's key
+    fingerprint VARCHAR(64) NOT NULL,
+    status INTEGER,
+    content_type VARCHAR(100),
+    body BYTEA,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Planners' private shortlists of surfaces
+CREATE TABLE IF NOT EXISTS watchlists (
+    watchlist_id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    owner_id VARCHAR(100) NOT NULL, -- user ID
+    org_id VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Surfaces on a watchlist with the planner's note about each
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    watchlist_id VARCHAR(100) NOT NULL REFERENCES watchlists(watchlist_id) ON DELETE CASCADE,
+    surface_id VARCHAR(100) NOT NULL REFERENCES surfaces(surface_id) ON DELETE CASCADE,
+    note TEXT,
+    added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (watchlist_id, surface_id)
+);
+
+-- Status history of partner-facing components, one row per component and
+-- check interval, kept 30 days
+CREATE TABLE IF NOT EXISTS component_health_checks (
+    component VARCHAR(50) NOT NULL, -- api, ingestion, decisioning, exports
+    checked_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL, -- operational, degraded, outage
+    PRIMARY KEY (component, checked_at)
+);
+
+-- Sampled playlists the gateway decorated, kept briefly so support can fetch
+-- the exact bytes a partner was given and served; the manifests themselves
+-- are kept in object storage
+CREATE TABLE IF NOT EXISTS manifest_captures (
+    capture_id VARCHAR(100) PRIMARY KEY,
+    session_id VARCHAR(255), -- Playback session the playlist was requested for, if known
+    title_id VARCHAR(100) NOT NULL,
+    org_id VARCHAR(100), -- Organization of the caller
+    content_type VARCHAR(100) NOT NULL,
+    original_size_bytes BIGINT NOT NULL,
+    decorated_size_bytes BIGINT NOT NULL,
+    placements INTEGER NOT NULL DEFAULT 0, -- Placements signaled in the decorated manifest
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL -- Deleted with its objects after this
+);
+
+-- Playlists personalized for one viewer, recording which placements were
+-- signaled and which were left out so exposures can be attributed to the
+-- manifest that carried them
+CREATE TABLE IF NOT EXISTS manifest_serves (
+    id SERIAL PRIMARY KEY,
+    serve_id VARCHAR(100) UNIQUE NOT NULL,
+    title_id VARCHAR(100) NOT NULL,
+    viewer_id VARCHAR(255), -- Viewer hash, only kept with the viewer's consent
+    session_id VARCHAR(255), -- Playback session the playlist was requested for, if known
+    org_id VARCHAR(100), -- Organization of the caller
+    consent BOOLEAN NOT NULL DEFAULT false,
+    booking_ids TEXT[] NOT NULL DEFAULT '{}', -- Placements signaled
+    filtered JSONB NOT NULL DEFAULT '{}', -- Reasons bookings were left out, by booking ID
+    served_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Periodic snapshots of each live booking's pacing and its campaign's
+-- budget, as Postgres recorded it and as the Redis counter held it, so the
+-- decision controller's choices can be replayed over a time range
+CREATE TABLE IF NOT EXISTS pacing_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id VARCHAR(100) NOT NULL,
+    campaign_id VARCHAR(100),
+    taken_at TIMESTAMP NOT NULL,
+    pacing VARCHAR(10) NOT NULL, -- 'asap' or 'even'
+    max_impressions BIGINT NOT NULL DEFAULT 0, -- The booking's estimated_impressions, paced toward
+    delivered BIGINT NOT NULL DEFAULT 0,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    impression_cost DECIMAL(14, 6) NOT NULL DEFAULT 0, -- Budget that had to remain to serve an impression
+    budget_amount DECIMAL(14, 2), -- NULL when the campaign had no budget
+    budget_spent DECIMAL(14, 6) NOT NULL DEFAULT 0, -- Authoritative spend recorded in Postgres
+    budget_seed DECIMAL(14, 6), -- Budget less the safety margin and the spend
+    counter_remaining DECIMAL(14, 6) -- What the Redis counter held, NULL when not loaded
+);
+
+-- Audience retention of each title, reported from publishers' player
+-- analytics and used to project the impressions of its placement windows
+CREATE TABLE IF NOT EXISTS title_retention (
+    title_id INTEGER PRIMARY KEY REFERENCES titles(id) ON DELETE CASCADE,
+    starts BIGINT NOT NULL CHECK (starts >= 0), -- playback sessions started over the period
+    points JSONB NOT NULL, -- [{position, retention}]: share of sessions still playing at each position, in seconds
+    period_start TIMESTAMP,
+    period_end TIMESTAMP,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_shots_title_id ON shots(title_id);
 CREATE INDEX IF NOT EXISTS idx_shots_time_range ON shots(title_id, start_time, end_time);
@@ -222,17 +1135,70 @@ CREATE INDEX IF NOT EXISTS idx_surfaces_shot_id ON surfaces(shot_id);
 CREATE INDEX IF NOT EXISTS idx_surfaces_prs_score ON surfaces(prs_score DESC);
 CREATE INDEX IF NOT EXISTS idx_surfaces_time_range ON surfaces(title_id, start_time, end_time);
 CREATE INDEX IF NOT EXISTS idx_surfaces_type ON surfaces(surface_type);
+CREATE INDEX IF NOT EXISTS idx_surfaces_last_validated ON surfaces(last_validated_at);
 CREATE INDEX IF NOT EXISTS idx_surface_tracks_title_id ON surface_tracks(title_id);
 CREATE INDEX IF NOT EXISTS idx_surface_tracks_time_range ON surface_tracks(first_appearance_time, last_appearance_time);
+CREATE INDEX IF NOT EXISTS idx_surface_duplicates_title_id ON surface_duplicates(title_id);
+CREATE INDEX IF NOT EXISTS idx_surface_merges_merged_into ON surface_merges(merged_into);
+CREATE INDEX IF NOT EXISTS idx_series_bookings_series_id ON series_bookings(series_id);
+CREATE INDEX IF NOT EXISTS idx_series_booking_items_series_booking_id ON series_booking_items(series_booking_id);
+CREATE INDEX IF NOT EXISTS idx_decision_events_campaign_id ON decision_events(campaign_id) WHERE campaign_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_decision_events_served_booking ON decision_events(booking_id) WHERE outcome = 'served';
 CREATE INDEX IF NOT EXISTS idx_rights_ledger_surface_id ON rights_ledger(surface_id);
 CREATE INDEX IF NOT EXISTS idx_rights_ledger_validity ON rights_ledger(valid_from, valid_until);
 CREATE INDEX IF NOT EXISTS idx_bookings_status ON placement_bookings(status);
 CREATE INDEX IF NOT EXISTS idx_bookings_surface_id ON placement_bookings(surface_id);
 CREATE INDEX IF NOT EXISTS idx_bookings_advertiser ON placement_bookings(advertiser_id);
 CREATE INDEX IF NOT EXISTS idx_bookings_time_range ON placement_bookings(start_time, end_time);
+CREATE INDEX IF NOT EXISTS idx_bookings_org ON placement_bookings(org_id, booking_time DESC);
+CREATE INDEX IF NOT EXISTS idx_advertisers_org ON advertisers(org_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_advertiser ON campaigns(advertiser_id);
 CREATE INDEX IF NOT EXISTS idx_exposure_events_booking_id ON exposure_events(booking_id);
 CREATE INDEX IF NOT EXISTS idx_exposure_events_timestamp ON exposure_events(event_timestamp);
+CREATE INDEX IF NOT EXISTS idx_exposure_events_received ON exposure_events(received_at); -- finds events to roll up
 CREATE INDEX IF NOT EXISTS idx_exposure_events_viewer_id ON exposure_events(viewer_id);
+CREATE INDEX IF NOT EXISTS idx_exposure_events_campaign_id ON exposure_events(campaign_id) WHERE campaign_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_exposure_events_org ON exposure_events(org_id, event_timestamp);
+CREATE INDEX IF NOT EXISTS idx_exposure_events_resend ON exposure_events(booking_id, viewer_id, device_event_timestamp) WHERE device_event_timestamp IS NOT NULL; -- finds resent copies left out of analytics
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exposure_events_dedupe_key ON exposure_events(dedupe_key) WHERE dedupe_key IS NOT NULL; -- rejects resent beacons past the Redis window
+CREATE INDEX IF NOT EXISTS idx_resource_labels_lookup ON resource_labels(resource_type, label_key, label_value);
+CREATE INDEX IF NOT EXISTS idx_resource_owners_org ON resource_owners(org_id, resource_type);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_resource_grants_active ON resource_grants(resource_type, resource_id, grantee_org_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_resource_grants_grantee ON resource_grants(grantee_org_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_service_accounts_org ON service_accounts(org_id);
+CREATE INDEX IF NOT EXISTS idx_service_account_keys_account ON service_account_keys(account_id);
+CREATE INDEX IF NOT EXISTS idx_service_account_keys_expiry ON service_account_keys(expires_at) WHERE expires_at IS NOT NULL AND expiry_warned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_org ON users(org_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_username ON organization_members(username);
+CREATE INDEX IF NOT EXISTS idx_pii_violations_principal ON pii_violations(principal_id, day DESC);
+CREATE INDEX IF NOT EXISTS idx_promotion_imports_org ON promotion_imports(org_id, imported_at DESC);
+CREATE INDEX IF NOT EXISTS idx_render_jobs_booking ON render_jobs(booking_id);
+CREATE INDEX IF NOT EXISTS idx_render_jobs_status ON render_jobs(status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_render_jobs_watermark ON render_jobs(watermark_id) WHERE watermark_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_watermark_verifications_booking ON watermark_verifications(booking_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_creative_approval_decisions_booking ON creative_approval_decisions(booking_id, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_nonces_expiry ON webhook_nonces(expires_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_org ON webhook_subscriptions(org_id) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC, delivery_id DESC);
+CREATE INDEX IF NOT EXISTS idx_watchlists_owner_id ON watchlists(owner_id);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expiry ON idempotency_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expiry ON refresh_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_expiry ON revoked_sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_component_health_checks_time ON component_health_checks(checked_at);
+CREATE INDEX IF NOT EXISTS idx_manifest_captures_session ON manifest_captures(session_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_manifest_captures_title ON manifest_captures(title_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_manifest_captures_expires ON manifest_captures(expires_at);
+CREATE INDEX IF NOT EXISTS idx_manifest_serves_viewer ON manifest_serves(viewer_id, served_at DESC);
+CREATE INDEX IF NOT EXISTS idx_manifest_serves_title ON manifest_serves(title_id, served_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pacing_snapshots_booking ON pacing_snapshots(booking_id, taken_at);
+CREATE INDEX IF NOT EXISTS idx_pacing_snapshots_time ON pacing_snapshots(taken_at);
+CREATE INDEX IF NOT EXISTS idx_job_queue_ready ON job_queue(queue, status, run_at);
+CREATE INDEX IF NOT EXISTS idx_job_queue_inflight ON job_queue(queue, visible_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_decision_events_title_time ON decision_events(title_id, event_timestamp);
+CREATE INDEX IF NOT EXISTS idx_decision_events_time ON decision_events(event_timestamp);
+CREATE INDEX IF NOT EXISTS idx_report_jobs_pending ON report_jobs(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_report_jobs_running ON report_jobs(started_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_booking_events_campaign ON booking_events((data->>'campaign_id'));
 
 -- Spatial index for surface geometry (PostGIS)
 CREATE INDEX IF NOT EXISTS idx_surfaces_geometry ON surfaces USING GIST(geometry);
@@ -261,6 +1227,57 @@ CREATE TRIGGER update_rights_ledger_updated_at BEFORE UPDATE ON rights_ledger
 CREATE TRIGGER update_placement_bookings_updated_at BEFORE UPDATE ON placement_bookings
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Committed booking and surface changes are announced on the serving_changes
+-- channel, so gateways drop what they cache about the row whichever
+-- gateway, service or migration changed it. Delivered impression counts and
+-- updated_at alone are not announced: exposures change them constantly and
+-- no cache depends on them.
+CREATE OR REPLACE FUNCTION notify_serving_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed_id TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_id := to_jsonb(OLD) ->> TG_ARGV[0];
+    ELSE
+        changed_id := to_jsonb(NEW) ->> TG_ARGV[0];
+    END IF;
+    PERFORM pg_notify('serving_changes', json_build_object(
+        'table', TG_TABLE_NAME,
+        'op', lower(TG_OP),
+        'id', changed_id
+    )::text);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER placement_bookings_notify_change AFTER INSERT OR DELETE ON placement_bookings
+    FOR EACH ROW EXECUTE FUNCTION notify_serving_change('booking_id');
+
+CREATE TRIGGER placement_bookings_notify_update AFTER UPDATE ON placement_bookings
+    FOR EACH ROW
+    WHEN ((to_jsonb(OLD) - 'actual_impressions' - 'updated_at') IS DISTINCT FROM (to_jsonb(NEW) - 'actual_impressions' - 'updated_at'))
+    EXECUTE FUNCTION notify_serving_change('booking_id');
+
+CREATE TRIGGER surfaces_notify_change AFTER INSERT OR DELETE ON surfaces
+    FOR EACH ROW EXECUTE FUNCTION notify_serving_change('surface_id');
+
+CREATE TRIGGER surfaces_notify_update AFTER UPDATE ON surfaces
+    FOR EACH ROW
+    WHEN ((to_jsonb(OLD) - 'updated_at') IS DISTINCT FROM (to_jsonb(NEW) - 'updated_at'))
+    EXECUTE FUNCTION notify_serving_change('surface_id');
+
+-- Booking events are immutable
+CREATE OR REPLACE FUNCTION reject_booking_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'booking_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER booking_events_append_only BEFORE UPDATE OR DELETE ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION reject_booking_event_change();
+
 -- Views for common queries
 CREATE OR REPLACE VIEW placement_opportunities AS
 SELECT 
@@ -288,6 +1305,31 @@ JOIN titles t ON s.title_id = t.id
 JOIN shots sh ON s.shot_id = sh.id
 WHERE s.prs_score > 0;
 
+-- Booked and sellable surfaces per title with a hold-back rule. Sellable
+-- capacity is the unreserved surfaces less percent of them, rounded down.
+CREATE OR REPLACE VIEW holdback_utilization AS
+SELECT
+    h.title_id,
+    h.percent,
+    COUNT(s.surface_id) AS total_surfaces,
+    COUNT(s.surface_id) FILTER (WHERE s.surface_id = ANY(h.surface_ids)) AS reserved_surfaces,
+    FLOOR(
+        (COUNT(s.surface_id) - COUNT(s.surface_id) FILTER (WHERE s.surface_id = ANY(h.surface_ids)))
+        * (100 - h.percent) / 100.0 + 1e-9
+    )::INTEGER AS sellable_capacity,
+    COUNT(s.surface_id) FILTER (WHERE NOT s.surface_id = ANY(h.surface_ids) AND s.booked) AS booked_surfaces,
+    COUNT(s.surface_id) FILTER (WHERE s.surface_id = ANY(h.surface_ids) AND s.booked) AS reserved_booked
+FROM inventory_holdbacks h
+LEFT JOIN (
+    SELECT surface_id, title_id, EXISTS(
+        SELECT 1 FROM placement_bookings pb
+        WHERE pb.surface_id = surfaces.surface_id AND pb.status IN ('pending', 'confirmed', 'active', 'paused')
+    ) AS booked
+    FROM surfaces
+    WHERE prs_score > 0
+) s ON s.title_id = h.title_id
+GROUP BY h.title_id, h.percent, h.surface_ids;
+
 COMMENT ON TABLE titles IS 'Video content titles and metadata';
 COMMENT ON TABLE shots IS 'Shot boundaries within video content';  
 COMMENT ON TABLE surfaces IS 'Placement surfaces identified within shots';
@@ -295,4 +1337,64 @@ COMMENT ON TABLE surface_tracks IS 'Tracks surfaces across multiple shots for id
 COMMENT ON TABLE rights_ledger IS 'Rights, restrictions and legal compliance for surfaces';
 COMMENT ON TABLE placement_bookings IS 'Commercial bookings for surface placements';
 COMMENT ON TABLE exposure_events IS 'Individual viewer exposure events for measurement';
+COMMENT ON TABLE exposure_ack_streams IS 'Last batch ingestion acknowledgement sequence per API key';
+COMMENT ON TABLE exposure_acks IS 'Recent acknowledged exposure batches edge nodes page through to truncate their buffers';
+COMMENT ON TABLE resource_labels IS 'Organization-defined key=value labels for filtering and report grouping';
+COMMENT ON TABLE external_ids IS 'Partner IDs for campaigns, bookings and creatives, unique per source';
+COMMENT ON TABLE resource_owners IS 'Owning organization per resource, used for authorization';
+COMMENT ON TABLE resource_grants IS 'Scoped cross-organization access to individual resources';
+COMMENT ON TABLE taxonomy_aliases IS 'Per-organization aliases of surface types and restriction categories';
+COMMENT ON TABLE service_accounts IS 'Non-human API principals with fine-grained scopes';
+COMMENT ON TABLE service_account_keys IS 'Versioned API keys for service accounts (hashed); rotated keys expire after an overlap';
+COMMENT ON TABLE organizations IS 'Tenants whose bookings, exposure events and metrics are kept apart';
+COMMENT ON TABLE organization_members IS 'Organizations users may sign in to besides their own';
+COMMENT ON TABLE cors_origins IS 'Browser origins allowed to call a route group for an organization at runtime';
+COMMENT ON TABLE users IS 'Password-authenticated API users with roles and login lockout';
+COMMENT ON TABLE refresh_tokens IS 'Refresh tokens of signed-in users when Redis is not configured';
+COMMENT ON TABLE revoked_sessions IS 'Signed-out sessions whose access tokens have not yet expired';
+COMMENT ON TABLE encryption_keys IS 'KMS-wrapped data keys for columns encrypted at rest';
+COMMENT ON TABLE report_privacy_settings IS 'Per-organization minimum audience of grouped analytics reports';
+COMMENT ON TABLE export_policies IS 'Per-organization columns stripped or hashed in every data export';
+COMMENT ON TABLE ingestion_policy IS 'Batch size and interval edge nodes are told to keep to when sending exposures';
+COMMENT ON TABLE pii_violations IS 'Daily counts of personal data found in inbound payloads per API key';
+COMMENT ON TABLE render_jobs IS 'Render farm compositing jobs and their current state';
+COMMENT ON TABLE booking_interstitials IS 'Bookings whose placements play their creative as HLS Interstitials';
+COMMENT ON TABLE webhook_nonces IS 'Replay protection for signed inbound webhooks';
+COMMENT ON TABLE webhook_subscriptions IS 'Partner endpoints notified of booking and delivery events';
+COMMENT ON TABLE webhook_deliveries IS 'Delivery log of outbound webhook events, with retry state';
+COMMENT ON TABLE job_queue IS 'Background job queue (Postgres backend)';
+COMMENT ON TABLE decision_events IS 'Placement decision outcomes per surface for fill-rate monitoring';
+COMMENT ON TABLE report_jobs IS 'Asynchronous analytics report jobs and their results';
+COMMENT ON TABLE log_level_exports IS 'Daily log-level files of advertisers'' decisions and impressions for auditors';
+COMMENT ON TABLE inventory_holdbacks IS 'Per-title inventory reserved from sale';
+COMMENT ON TABLE collision_rules IS 'Per-shot limits on concurrent and adjacent booked placements';
+COMMENT ON VIEW holdback_utilization IS 'Booked versus sellable surfaces per title with a hold-back';
+COMMENT ON TABLE booking_events IS 'Append-only booking lifecycle event stream';
+COMMENT ON TABLE surface_duplicates IS 'Near-duplicate surface clusters found by the dedupe job';
+COMMENT ON TABLE surface_merges IS 'Duplicate surfaces merged into a canonical surface';
+COMMENT ON TABLE title_cuts IS 'Delivered edits of a title that surfaces can be remapped between';
+COMMENT ON TABLE shot_fingerprints IS 'Per-shot perceptual hashes of each cut of a title';
+COMMENT ON TABLE series IS 'Shows whose titles are episodes';
+COMMENT ON TABLE title_episodes IS 'Season and episode number of titles in a series';
+COMMENT ON TABLE series_bookings IS 'Surface type bookings across a series or season';
+COMMENT ON TABLE series_booking_items IS 'Placement bookings fanned out from a series booking';
+COMMENT ON TABLE advertisers IS 'Brands that campaigns are run for';
+COMMENT ON TABLE campaigns IS 'Campaigns bookings must name, with their advertiser and flight dates';
+COMMENT ON TABLE campaign_budgets IS 'Campaign spend caps enforced at decision time';
+COMMENT ON TABLE impression_leases IS 'Impression quotas of capped bookings leased to edge nodes';
+COMMENT ON TABLE promotion_imports IS 'Campaign bundles imported from other environments';
+COMMENT ON TABLE promoted_bookings IS 'Bookings created by promotion, keyed by their source booking';
+COMMENT ON TABLE title_ingestion_stages IS 'Ingestion pipeline progress of each title, per stage';
+COMMENT ON TABLE shot_scene_graphs IS 'Objects of each shot and their relations, from the vision pipeline';
+COMMENT ON TABLE title_qc_results IS 'Quality checks each pipeline run applied to a title';
+COMMENT ON TABLE surface_thumbnails IS 'Reference frame of each surface, kept in object storage';
+COMMENT ON TABLE idempotency_keys IS 'Responses replayed to retried POST requests carrying an Idempotency-Key';
+COMMENT ON TABLE watchlists IS 'Private shortlists of surfaces planners compare before proposing';
+COMMENT ON TABLE watchlist_items IS 'Surfaces on a watchlist with notes';
+COMMENT ON TABLE component_health_checks IS 'Status history behind the public status page';
+COMMENT ON TABLE manifest_captures IS 'Sampled original and decorated playlists kept briefly for support';
+COMMENT ON TABLE manifest_serves IS 'Playlists personalized per viewer and the placements they signaled, for attribution';
+COMMENT ON TABLE pacing_snapshots IS 'Periodic pacing and budget inputs of live bookings, replayed to debug pacing and overspend';
+COMMENT ON TABLE title_retention IS 'Historical audience retention curve of each title';
+COMMENT ON TABLE campaign_reach_daily IS 'Daily distinct-viewer sketches of each campaign for reach and overlap reports';
 COMMENT ON VIEW placement_opportunities IS 'Available placement opportunities with key metrics';
\ No newline at end of file